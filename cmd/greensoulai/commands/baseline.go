@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/crew"
+)
+
+// BaselineChecker 运行Crew并与基线比较，基线不存在或要求更新时保存本次输出为新基线
+type BaselineChecker struct {
+	Runner  *CrewRunner
+	Path    string
+	Update  bool // 忽略已有基线，用本次输出覆盖
+	Options crew.BaselineCompareOptions
+	Timeout time.Duration
+	Out     io.Writer
+}
+
+// Check 运行一次Crew；捕获基线时返回nil报告，有任务回归时同时返回报告和错误
+func (b *BaselineChecker) Check(ctx context.Context, inputs map[string]interface{}) (*crew.RegressionReport, error) {
+	var baseline *crew.Baseline
+	if !b.Update {
+		loaded, err := crew.LoadBaseline(b.Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		baseline = loaded
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+	defer cancel()
+
+	c, err := b.Runner.Build()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	output, err := b.Runner.Kickoff(ctx, c, inputs)
+	if baseline == nil {
+		// 失败的运行不能作为基线
+		if err != nil {
+			return nil, fmt.Errorf("failed to run crew for baseline: %w", err)
+		}
+		captured, err := crew.NewBaseline(b.Runner.Config.Name, inputs, output)
+		if err != nil {
+			return nil, err
+		}
+		if err := captured.Save(b.Path); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(b.Out, "\n📌 已保存 %d 个任务的基线到: %s\n", len(captured.Tasks), b.Path)
+		return nil, nil
+	}
+
+	if output == nil {
+		return nil, err
+	}
+	if err != nil && output.Error == nil {
+		output.Error = err
+	}
+	report, err := crew.CompareBaseline(ctx, baseline, output, b.Options)
+	if err != nil {
+		return nil, err
+	}
+	printRegressionReport(b.Out, report)
+	if regressions := report.Regressions(); len(regressions) > 0 {
+		return report, fmt.Errorf("%d task(s) regressed against baseline %s", len(regressions), b.Path)
+	}
+	return report, nil
+}
+
+// printRegressionReport 输出每个任务与基线的比较结果，JSON字段差异逐行列出
+func printRegressionReport(w io.Writer, report *crew.RegressionReport) {
+	fmt.Fprintf(w, "\n🔍 与基线比较 (基线捕获于 %s，文本相似度: %s)\n",
+		report.BaselineCapturedAt.Local().Format("2006-01-02 15:04:05"), report.Similarity)
+	if report.TasksChanged {
+		fmt.Fprintf(w, "⚠️  任务描述与基线不同\n")
+	}
+	if report.KickoffError != "" {
+		fmt.Fprintf(w, "❌ 运行失败: %s\n", report.KickoffError)
+	}
+
+	rows := [][]string{{"任务", "结果", "类型", "相似度", "阈值"}}
+	for _, task := range report.Tasks {
+		similarity, threshold := "-", "-"
+		if task.Kind == "text" {
+			similarity = fmt.Sprintf("%.2f", task.Similarity)
+			threshold = fmt.Sprintf("%.2f", task.Threshold)
+		}
+		kind := task.Kind
+		if kind == "" {
+			kind = "-"
+		}
+		rows = append(rows, []string{task.Task, regressionStatusLabel(task.Status), kind, similarity, threshold})
+	}
+	printAligned(w, rows)
+
+	for _, task := range report.Tasks {
+		if task.Error != "" {
+			fmt.Fprintf(w, "\n%s: %s\n", task.Task, task.Error)
+		}
+		if len(task.FieldDiffs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s 的字段差异:\n", task.Task)
+		for _, diff := range task.FieldDiffs {
+			fmt.Fprintf(w, "  %-8s %s: %s -> %s\n", diff.Change, diff.Path, formatDiffValue(diff.Baseline), formatDiffValue(diff.Current))
+		}
+	}
+
+	if report.Passed {
+		fmt.Fprintf(w, "\n✅ 没有发现回归\n")
+		return
+	}
+	names := make([]string, 0, len(report.Tasks))
+	for _, task := range report.Regressions() {
+		names = append(names, task.Task)
+	}
+	fmt.Fprintf(w, "\n❌ 发现回归: %s\n", strings.Join(names, ", "))
+}
+
+func regressionStatusLabel(status crew.RegressionStatus) string {
+	switch status {
+	case crew.RegressionPassed:
+		return "通过"
+	case crew.RegressionFailed:
+		return "回归"
+	case crew.RegressionMissing:
+		return "缺失"
+	case crew.RegressionNew:
+		return "新增"
+	default:
+		return string(status)
+	}
+}
+
+func formatDiffValue(value interface{}) string {
+	if value == nil {
+		return "-"
+	}
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", value)
+}
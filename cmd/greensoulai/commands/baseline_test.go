@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+func TestBaselineCheckerCaptureAndCompare(t *testing.T) {
+	researcher := &scriptedLLM{model: "default", reply: "Go is a compiled language with fast builds."}
+	writer := &scriptedLLM{model: "writer-model", reply: "Go compiles quickly and has a simple syntax."}
+	runner, out := newTestCrewRunner(t, map[string]llm.LLM{"": researcher, "writer-model": writer})
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	checker := &BaselineChecker{
+		Runner:  runner,
+		Path:    path,
+		Options: crew.DefaultBaselineCompareOptions(),
+		Timeout: time.Minute,
+		Out:     out,
+	}
+	inputs := map[string]interface{}{"topic": "Go"}
+
+	// 第一次运行保存基线
+	report, err := checker.Check(context.Background(), inputs)
+	if err != nil || report != nil {
+		t.Fatalf("expected baseline capture, got %+v, %v", report, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("baseline not saved: %v", err)
+	}
+	if !strings.Contains(out.String(), "已保存 2 个任务的基线") {
+		t.Errorf("expected capture message, got:\n%s", out.String())
+	}
+
+	// 输出基本不变时通过
+	researcher.reply = "Go is a compiled language with very fast builds."
+	report, err = checker.Check(context.Background(), inputs)
+	if err != nil || !report.Passed {
+		t.Fatalf("expected comparison to pass, got %+v, %v", report, err)
+	}
+
+	// write任务的输出变化较大时返回错误
+	out.Reset()
+	writer.reply = "Rust offers memory safety without a garbage collector."
+	report, err = checker.Check(context.Background(), inputs)
+	if err == nil || !strings.Contains(err.Error(), "1 task(s) regressed") {
+		t.Fatalf("expected regression error, got %v", err)
+	}
+	if report.Passed || report.Tasks[1].Task != "write" || report.Tasks[1].Status != crew.RegressionFailed {
+		t.Errorf("expected write to regress, got %+v", report.Tasks)
+	}
+	for _, want := range []string{"research  通过", "write     回归", "发现回归: write"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	// 更新基线后再次通过
+	checker.Update = true
+	if report, err = checker.Check(context.Background(), inputs); err != nil || report != nil {
+		t.Fatalf("expected baseline update, got %+v, %v", report, err)
+	}
+	checker.Update = false
+	if report, err = checker.Check(context.Background(), inputs); err != nil || !report.Passed {
+		t.Errorf("expected comparison against updated baseline to pass, got %+v, %v", report, err)
+	}
+}
+
+func TestBaselineCompareOptions(t *testing.T) {
+	options, err := baselineCompareOptions(0.9, crew.SimilarityEmbedding, []string{"$.id"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if options.TextThreshold != 0.9 || options.Embedder == nil || options.IgnorePaths[0] != "$.id" {
+		t.Errorf("unexpected options: %+v", options)
+	}
+
+	if _, err := baselineCompareOptions(1.5, crew.SimilarityLevenshtein, nil); err == nil {
+		t.Error("expected error for threshold above 1")
+	}
+	if _, err := baselineCompareOptions(0.8, "jaccard", nil); err == nil {
+		t.Error("expected error for unsupported similarity")
+	}
+}
+
+func TestPrintRegressionReportFieldDiffs(t *testing.T) {
+	var b strings.Builder
+	printRegressionReport(&b, &crew.RegressionReport{
+		Similarity: crew.SimilarityLevenshtein,
+		Tasks: []crew.TaskRegression{{
+			Task: "extract", Status: crew.RegressionFailed, Kind: "json",
+			FieldDiffs: []crew.FieldDiff{
+				{Path: "$.total", Change: "changed", Baseline: float64(3), Current: float64(4)},
+				{Path: "$.currency", Change: "added", Current: "EUR"},
+			},
+		}},
+	})
+	for _, want := range []string{"extract  回归  json  -       -", "extract 的字段差异:", "changed  $.total: 3 -> 4", `added    $.currency: - -> "EUR"`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, b.String())
+		}
+	}
+}
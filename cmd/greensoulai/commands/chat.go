@@ -0,0 +1,533 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/internal/memory/storage"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// NewChatCommand 创建chat命令
+func NewChatCommand(log logger.Logger) *cobra.Command {
+	var (
+		configPath string
+		agentRole  string
+		sessionID  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "chat",
+		Short: "与项目智能体对话",
+		Long: `启动与项目智能体的交互式对话模式。
+读取项目的greensoulai.yaml构建LLM，可通过 --agent 按角色选择智能体；
+不在项目目录中时使用 OPENAI_API_KEY 直接与LLM对话。
+
+对话中可用的命令：
+  /reset          清空对话历史
+  /history        查看对话历史
+  /save <file>    保存对话历史为JSON
+  /agent <role>   切换智能体
+  /image <path>   附加图片到下一条消息（需要支持视觉输入的模型）
+  /exit           退出
+
+使用 --session <id> 时对话保存在记忆存储目录的会话数据库中，下次使用同一ID继续对话；
+较早的轮次会被合并为摘要。可用 reset-memories --session <id> 删除会话。
+
+生成回复时按 Ctrl+C 只会中断当前回复。`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			llmConfig, agents, err := loadChatConfig(configPath, log)
+			if err != nil {
+				return err
+			}
+
+			newLLM := func(model string) (llm.LLM, error) {
+				cfg := llmConfig
+				if model != "" {
+					cfg.Model = model
+				}
+				return config.NewLLM(cfg)
+			}
+
+			session, err := NewChatSession(newLLM, agents, os.Stdout, log)
+			if err != nil {
+				return err
+			}
+			defer session.Close()
+
+			if agentRole != "" {
+				if err := session.SelectAgent(agentRole); err != nil {
+					return err
+				}
+			}
+
+			if sessionID != "" {
+				sessions, err := openChatSessions()
+				if err != nil {
+					return err
+				}
+				defer sessions.Close()
+				if err := session.ResumeSession(cmd.Context(), sessions, sessionID); err != nil {
+					return err
+				}
+			}
+
+			// Ctrl+C只中断当前回复，不再由全局信号处理退出进程
+			signal.Reset(os.Interrupt)
+			interrupts := make(chan os.Signal, 1)
+			signal.Notify(interrupts, os.Interrupt)
+			defer signal.Stop(interrupts)
+
+			return session.Run(cmd.Context(), readLines(os.Stdin), interrupts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "配置文件路径")
+	cmd.Flags().StringVarP(&agentRole, "agent", "a", "", "对话的智能体角色")
+	cmd.Flags().StringVar(&sessionID, "session", "", "会话ID，保存对话并在之后继续")
+
+	return cmd
+}
+
+// loadChatConfig 加载项目的LLM和智能体配置，不在项目中时退回到OpenAI默认配置
+func loadChatConfig(configPath string, log logger.Logger) (config.LLMConfig, []config.AgentConfig, error) {
+	if configPath == "" {
+		projectRoot, err := config.GetProjectRoot()
+		if err != nil {
+			log.Info("未找到项目配置，使用默认LLM对话")
+			if os.Getenv("OPENAI_API_KEY") == "" {
+				return config.LLMConfig{}, nil, fmt.Errorf("not in a greensoulai project and OPENAI_API_KEY is not set")
+			}
+			return config.LLMConfig{Provider: "openai", Model: "gpt-4o-mini", Temperature: 0.7}, nil, nil
+		}
+		configPath = filepath.Join(projectRoot, "greensoulai.yaml")
+	}
+
+	projectConfig, err := config.LoadProjectConfig(configPath)
+	if err != nil {
+		return config.LLMConfig{}, nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	return projectConfig.LLM, projectConfig.Agents, nil
+}
+
+// openChatSessions 打开记忆存储目录中的会话数据库
+func openChatSessions() (*memory.SessionMemory, error) {
+	projectRoot, projectConfig, err := loadResetProject()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := memoryStorageDir(projectRoot, projectConfig, "")
+	if err != nil {
+		return nil, err
+	}
+	store, err := storage.NewSQLiteSessionStore(filepath.Join(dir, storage.SessionDBFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session store: %w", err)
+	}
+	config := memory.DefaultSessionMemoryConfig()
+	config.Store = store
+	return memory.NewSessionMemory(config), nil
+}
+
+// readLines 在后台读取输入行，输入结束（Ctrl+D）时关闭通道
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// ChatSession 交互式对话会话，维护对话历史和累计用量
+type ChatSession struct {
+	newLLM  func(model string) (llm.LLM, error)
+	llm     llm.LLM
+	agents  []config.AgentConfig
+	agent   *config.AgentConfig
+	history []llm.Message
+	total   llm.Usage
+	images  []llm.ContentPart // /image附加的图片，随下一条消息发送
+	out     io.Writer
+	log     logger.Logger
+
+	// 使用--session时保存对话的会话记忆
+	sessions  *memory.SessionMemory
+	sessionID string
+}
+
+// NewChatSession 创建对话会话，newLLM按模型名创建LLM，空模型名表示项目默认模型
+func NewChatSession(newLLM func(model string) (llm.LLM, error), agents []config.AgentConfig, out io.Writer, log logger.Logger) (*ChatSession, error) {
+	model, err := newLLM("")
+	if err != nil {
+		return nil, err
+	}
+	return &ChatSession{
+		newLLM: newLLM,
+		llm:    model,
+		agents: agents,
+		out:    out,
+		log:    log,
+	}, nil
+}
+
+// History 返回对话历史的副本
+func (s *ChatSession) History() []llm.Message {
+	return append([]llm.Message(nil), s.history...)
+}
+
+// TotalUsage 返回会话累计的token用量和费用
+func (s *ChatSession) TotalUsage() llm.Usage {
+	return s.total
+}
+
+// Close 释放LLM资源
+func (s *ChatSession) Close() error {
+	return s.llm.Close()
+}
+
+// ResumeSession 载入会话中之前的对话，之后完成的轮次都追加到该会话
+// 会话摘要作为系统消息，保留的轮次按原文恢复为对话历史
+func (s *ChatSession) ResumeSession(ctx context.Context, sessions *memory.SessionMemory, sessionID string) error {
+	state, err := sessions.Load(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	sessions.SetSummaryLLM(s.llm)
+
+	var resumed []llm.Message
+	if state.Summary != "" {
+		resumed = append(resumed, llm.Message{Role: llm.RoleSystem, Content: "Summary of earlier conversation: " + state.Summary})
+	}
+	for _, turn := range state.Turns {
+		resumed = append(resumed,
+			llm.Message{Role: llm.RoleUser, Content: turn.Input},
+			llm.Message{Role: llm.RoleAssistant, Content: turn.Output},
+		)
+	}
+
+	// 智能体设定保持为第一条消息
+	if len(s.history) > 0 && s.history[0].Role == llm.RoleSystem {
+		s.history = append(s.history[:1:1], append(resumed, s.history[1:]...)...)
+	} else {
+		s.history = append(resumed, s.history...)
+	}
+	s.sessions = sessions
+	s.sessionID = sessionID
+	if len(state.Turns) > 0 {
+		fmt.Fprintf(s.out, "📂 已恢复会话 %s（%d 轮）\n", sessionID, len(state.Turns))
+	}
+	return nil
+}
+
+// SelectAgent 按角色（或名称）切换智能体，智能体的设定作为系统消息，已有的对话历史保留
+func (s *ChatSession) SelectAgent(role string) error {
+	var selected *config.AgentConfig
+	for i := range s.agents {
+		if strings.EqualFold(s.agents[i].Role, role) || strings.EqualFold(s.agents[i].Name, role) {
+			selected = &s.agents[i]
+			break
+		}
+	}
+	if selected == nil {
+		roles := make([]string, len(s.agents))
+		for i, a := range s.agents {
+			roles[i] = a.Role
+		}
+		return fmt.Errorf("agent %q not found, available roles: %s", role, strings.Join(roles, ", "))
+	}
+
+	// 智能体指定了模型时切换LLM
+	if selected.LLM != "" || (s.agent != nil && s.agent.LLM != "") {
+		model, err := s.newLLM(selected.LLM)
+		if err != nil {
+			return err
+		}
+		s.llm.Close()
+		s.llm = model
+	}
+
+	s.agent = selected
+	system := llm.Message{Role: llm.RoleSystem, Content: agentSystemPrompt(*selected)}
+	if len(s.history) > 0 && s.history[0].Role == llm.RoleSystem {
+		s.history[0] = system
+	} else {
+		s.history = append([]llm.Message{system}, s.history...)
+	}
+	return nil
+}
+
+func agentSystemPrompt(a config.AgentConfig) string {
+	prompt := fmt.Sprintf("You are %s.\nYour goal: %s", a.Role, a.Goal)
+	if a.Backstory != "" {
+		prompt += "\n" + a.Backstory
+	}
+	return prompt
+}
+
+// Run 运行REPL直到输入结束或/exit，interrupts中的信号中断正在生成的回复
+func (s *ChatSession) Run(ctx context.Context, lines <-chan string, interrupts <-chan os.Signal) error {
+	fmt.Fprintf(s.out, "💬 GreenSoulAI 对话模式 (%s)\n", s.llm.GetModel())
+	if s.agent != nil {
+		fmt.Fprintf(s.out, "🤖 当前智能体: %s\n", s.agent.Role)
+	}
+	fmt.Fprintln(s.out, "输入 /exit 或按 Ctrl+D 退出，生成回复时按 Ctrl+C 中断")
+
+	for {
+		fmt.Fprint(s.out, "\n> ")
+
+		var line string
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-interrupts:
+			fmt.Fprintln(s.out, "\n（输入 /exit 或按 Ctrl+D 退出）")
+			continue
+		case l, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(s.out)
+				return nil
+			}
+			line = strings.TrimSpace(l)
+		}
+
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			exit, err := s.handleCommand(line)
+			if err != nil {
+				fmt.Fprintf(s.out, "❌ %v\n", err)
+			}
+			if exit {
+				return nil
+			}
+			continue
+		}
+
+		if err := s.send(ctx, line, interrupts); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(s.out, "\n❌ %v\n", err)
+		}
+	}
+}
+
+// handleCommand 处理斜杠命令，返回是否退出
+func (s *ChatSession) handleCommand(line string) (bool, error) {
+	fields := strings.Fields(line)
+	arg := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+
+	switch fields[0] {
+	case "/exit", "/quit":
+		return true, nil
+	case "/reset":
+		if len(s.history) > 0 && s.history[0].Role == llm.RoleSystem {
+			s.history = s.history[:1]
+		} else {
+			s.history = nil
+		}
+		s.images = nil
+		if s.sessions != nil {
+			if err := s.sessions.Clear(context.Background(), s.sessionID); err != nil {
+				return false, err
+			}
+		}
+		fmt.Fprintln(s.out, "🔄 对话历史已清空")
+	case "/history":
+		s.printHistory()
+	case "/save":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /save <file>")
+		}
+		if err := s.saveHistory(arg); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(s.out, "💾 对话历史已保存到 %s\n", arg)
+	case "/agent":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /agent <role>")
+		}
+		if err := s.SelectAgent(arg); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(s.out, "🤖 已切换到智能体: %s\n", s.agent.Role)
+	case "/image":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /image <path>")
+		}
+		image, err := llm.ImageFilePart(arg, llm.ImageDetailAuto)
+		if err != nil {
+			return false, err
+		}
+		s.images = append(s.images, image)
+		fmt.Fprintf(s.out, "🖼️  已附加图片 %s，将随下一条消息发送\n", arg)
+	default:
+		return false, fmt.Errorf("unknown command %s, available: /reset /history /save <file> /agent <role> /image <path> /exit", fields[0])
+	}
+	return false, nil
+}
+
+func (s *ChatSession) printHistory() {
+	if len(s.history) == 0 {
+		fmt.Fprintln(s.out, "（暂无对话历史）")
+		return
+	}
+	for _, msg := range s.history {
+		fmt.Fprintf(s.out, "[%s] %s\n", msg.Role, llm.ContentText(msg.Content))
+	}
+}
+
+func (s *ChatSession) saveHistory(path string) error {
+	data, err := json.MarshalIndent(s.history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
+
+// send 发送用户消息并流式输出回复，被中断或失败的轮次不计入历史
+// 有/image附加的图片时作为多部分内容随消息发送，轮次完成后清空
+func (s *ChatSession) send(ctx context.Context, text string, interrupts <-chan os.Signal) error {
+	turnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var content interface{} = text
+	if len(s.images) > 0 {
+		content = append([]llm.ContentPart{llm.TextPart(text)}, s.images...)
+	}
+	messages := append(s.History(), llm.Message{Role: llm.RoleUser, Content: content})
+	options := llm.DefaultCallOptions()
+	options.Stream = true
+	options.StreamOptions = map[string]interface{}{"include_usage": true}
+
+	stream, err := s.llm.CallStream(turnCtx, messages, options)
+	if errors.Is(err, llm.ErrStreamingNotSupported) {
+		return s.sendWithoutStreaming(turnCtx, messages, interrupts)
+	}
+	if err != nil {
+		return fmt.Errorf("LLM call failed: %w", err)
+	}
+
+	var reply strings.Builder
+	var usage *llm.Usage
+	for {
+		select {
+		case <-interrupts:
+			cancel()
+			drainStream(stream)
+			fmt.Fprintln(s.out, "\n⏹️  已中断")
+			return nil
+		case chunk, ok := <-stream:
+			if !ok {
+				s.finishTurn(messages, reply.String(), usage)
+				return nil
+			}
+			if chunk.Error != nil {
+				drainStream(stream)
+				return fmt.Errorf("stream failed: %w", chunk.Error)
+			}
+			if chunk.Delta != "" {
+				reply.WriteString(chunk.Delta)
+				fmt.Fprint(s.out, chunk.Delta)
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+		}
+	}
+}
+
+// sendWithoutStreaming LLM不支持流式输出时一次性输出回复
+func (s *ChatSession) sendWithoutStreaming(ctx context.Context, messages []llm.Message, interrupts <-chan os.Signal) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type callResult struct {
+		resp *llm.Response
+		err  error
+	}
+	done := make(chan callResult, 1)
+	go func() {
+		resp, err := s.llm.Call(ctx, messages, nil)
+		done <- callResult{resp, err}
+	}()
+
+	select {
+	case <-interrupts:
+		cancel()
+		fmt.Fprintln(s.out, "⏹️  已中断")
+		return nil
+	case result := <-done:
+		if result.err != nil {
+			return fmt.Errorf("LLM call failed: %w", result.err)
+		}
+		fmt.Fprint(s.out, result.resp.Content)
+		s.finishTurn(messages, result.resp.Content, &result.resp.Usage)
+		return nil
+	}
+}
+
+// finishTurn 把完成的轮次写入历史（使用会话时同时追加到会话）并显示用量
+func (s *ChatSession) finishTurn(messages []llm.Message, reply string, usage *llm.Usage) {
+	s.history = append(messages, llm.Message{Role: llm.RoleAssistant, Content: reply})
+	s.images = nil
+	fmt.Fprintln(s.out)
+
+	if s.sessions != nil {
+		input := llm.ContentText(messages[len(messages)-1].Content)
+		if _, err := s.sessions.Append(context.Background(), s.sessionID, input, reply); err != nil {
+			s.log.Warn("failed to save session turn",
+				logger.Field{Key: "session_id", Value: s.sessionID},
+				logger.Field{Key: "error", Value: err},
+			)
+		}
+	}
+
+	if usage == nil || usage.TotalTokens == 0 {
+		fmt.Fprintln(s.out, "📊 本轮用量: 未提供")
+		return
+	}
+	turn := *usage
+	if turn.Cost == 0 {
+		turn.Cost = llm.CalculateCost(s.llm.GetModel(), turn)
+	}
+	s.total.PromptTokens += turn.PromptTokens
+	s.total.CompletionTokens += turn.CompletionTokens
+	s.total.TotalTokens += turn.TotalTokens
+	s.total.Cost += turn.Cost
+
+	images := ""
+	if turn.ImageTokens > 0 {
+		images = fmt.Sprintf("，其中图片 %d", turn.ImageTokens)
+	}
+	fmt.Fprintf(s.out, "📊 本轮: %d tokens (输入 %d%s / 输出 %d), $%.6f | 累计: %d tokens, $%.6f\n",
+		turn.TotalTokens, turn.PromptTokens, images, turn.CompletionTokens, turn.Cost,
+		s.total.TotalTokens, s.total.Cost)
+}
+
+// drainStream 读完被取消的流，使生产方goroutine能够退出
+func drainStream(stream <-chan llm.StreamResponse) {
+	for range stream {
+	}
+}
@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// scriptedLLM 按词流式返回回复，并记录每次调用收到的消息
+type scriptedLLM struct {
+	model    string
+	reply    string
+	block    bool // 第一次调用输出第一个词后等待取消
+	started  chan struct{}
+	received [][]llm.Message
+}
+
+func (l *scriptedLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	return &llm.Response{Content: l.reply}, nil
+}
+
+func (l *scriptedLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	l.received = append(l.received, append([]llm.Message(nil), messages...))
+	block := l.block && len(l.received) == 1
+	ch := make(chan llm.StreamResponse)
+	go func() {
+		defer close(ch)
+		for i, word := range strings.Fields(l.reply) {
+			select {
+			case ch <- llm.StreamResponse{Delta: word + " "}:
+			case <-ctx.Done():
+				return
+			}
+			if block && i == 0 {
+				close(l.started)
+				<-ctx.Done()
+				ch <- llm.StreamResponse{Error: ctx.Err()}
+				return
+			}
+		}
+		ch <- llm.StreamResponse{FinishReason: "stop", Usage: &llm.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Cost: 0.001}}
+	}()
+	return ch, nil
+}
+
+func (l *scriptedLLM) GetModel() string                     { return l.model }
+func (l *scriptedLLM) SupportsFunctionCalling() bool        { return false }
+func (l *scriptedLLM) GetContextWindowSize() int            { return 8192 }
+func (l *scriptedLLM) GetTokenizer() llm.Tokenizer          { return llm.HeuristicTokenizer{} }
+func (l *scriptedLLM) SetEventBus(eventBus events.EventBus) {}
+func (l *scriptedLLM) Close() error                         { return nil }
+
+var chatAgents = []config.AgentConfig{
+	{Name: "researcher", Role: "Research Specialist", Goal: "Conduct thorough research"},
+	{Name: "writer", Role: "Content Writer", Goal: "Write articles", LLM: "writer-model"},
+}
+
+func newTestChatSession(t *testing.T, models map[string]*scriptedLLM) (*ChatSession, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	session, err := NewChatSession(func(model string) (llm.LLM, error) {
+		return models[model], nil
+	}, chatAgents, &out, logger.NewTestLogger())
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	return session, &out
+}
+
+// runLines 依次输入各行并运行会话直到输入结束
+func runLines(t *testing.T, session *ChatSession, interrupts chan os.Signal, lines ...string) {
+	t.Helper()
+	input := make(chan string)
+	go func() {
+		defer close(input)
+		for _, line := range lines {
+			input <- line
+		}
+	}()
+	if err := session.Run(context.Background(), input, interrupts); err != nil {
+		t.Fatalf("session failed: %v", err)
+	}
+}
+
+func TestChatSessionConversation(t *testing.T) {
+	defaultLLM := &scriptedLLM{model: "default", reply: "hello there"}
+	session, out := newTestChatSession(t, map[string]*scriptedLLM{"": defaultLLM})
+
+	runLines(t, session, nil, "hi", "how are you")
+
+	if len(defaultLLM.received) != 2 || len(defaultLLM.received[1]) != 3 {
+		t.Fatalf("expected the second turn to include the first exchange, got %v", defaultLLM.received)
+	}
+	history := session.History()
+	if len(history) != 4 || history[3].Role != llm.RoleAssistant || history[3].Content != "hello there " {
+		t.Errorf("unexpected history: %v", history)
+	}
+	if total := session.TotalUsage(); total.TotalTokens != 30 || total.Cost != 0.002 {
+		t.Errorf("expected cumulative usage of two turns, got %+v", total)
+	}
+	if !strings.Contains(out.String(), "hello there") || !strings.Contains(out.String(), "累计: 30 tokens") {
+		t.Errorf("expected streamed reply and usage in output, got: %s", out.String())
+	}
+}
+
+func TestChatSessionResume(t *testing.T) {
+	sessions := memory.NewSessionMemory(nil)
+	first, _ := newTestChatSession(t, map[string]*scriptedLLM{"": {model: "default", reply: "noted"}})
+	if err := first.ResumeSession(context.Background(), sessions, "trip"); err != nil {
+		t.Fatalf("failed to resume session: %v", err)
+	}
+	runLines(t, first, nil, "I am going to Kyoto")
+
+	// 新的对话（例如重新启动chat命令）继续同一会话
+	defaultLLM := &scriptedLLM{model: "default", reply: "Kyoto"}
+	second, out := newTestChatSession(t, map[string]*scriptedLLM{"": defaultLLM})
+	if err := second.SelectAgent("researcher"); err != nil {
+		t.Fatalf("failed to select agent: %v", err)
+	}
+	if err := second.ResumeSession(context.Background(), sessions, "trip"); err != nil {
+		t.Fatalf("failed to resume session: %v", err)
+	}
+	if !strings.Contains(out.String(), "已恢复会话 trip（1 轮）") {
+		t.Errorf("expected resume notice, got: %s", out.String())
+	}
+	runLines(t, second, nil, "Where am I going?")
+
+	messages := defaultLLM.received[0]
+	if len(messages) != 4 || messages[0].Role != llm.RoleSystem || messages[1].Content != "I am going to Kyoto" || messages[2].Content != "noted " {
+		t.Fatalf("expected the agent prompt followed by the resumed turn, got %v", messages)
+	}
+	state, _ := sessions.Load(context.Background(), "trip")
+	if len(state.Turns) != 2 || state.Turns[1].Input != "Where am I going?" {
+		t.Errorf("expected the new turn appended to the session, got %+v", state.Turns)
+	}
+
+	runLines(t, second, nil, "/reset")
+	if state, _ := sessions.Load(context.Background(), "trip"); !state.Empty() {
+		t.Errorf("expected /reset to clear the session, got %+v", state)
+	}
+}
+
+func TestChatSessionCommands(t *testing.T) {
+	defaultLLM := &scriptedLLM{model: "default", reply: "ok"}
+	writerLLM := &scriptedLLM{model: "writer-model", reply: "draft"}
+	session, out := newTestChatSession(t, map[string]*scriptedLLM{"": defaultLLM, "writer-model": writerLLM})
+	savePath := filepath.Join(t.TempDir(), "history.json")
+
+	runLines(t, session, nil,
+		"/agent research specialist",
+		"first question",
+		"/reset",
+		"/agent writer",
+		"write something",
+		"/save "+savePath,
+		"/history",
+		"/unknown",
+		"/exit",
+		"never sent",
+	)
+
+	if len(defaultLLM.received) != 1 || len(writerLLM.received) != 1 {
+		t.Fatalf("expected one call per agent LLM, got %d and %d", len(defaultLLM.received), len(writerLLM.received))
+	}
+	// /reset保留系统消息，切换智能体替换系统消息
+	messages := writerLLM.received[0]
+	if len(messages) != 2 || messages[0].Role != llm.RoleSystem || !strings.Contains(messages[0].Content.(string), "Content Writer") {
+		t.Errorf("expected writer system prompt and no earlier turns, got %v", messages)
+	}
+
+	data, err := os.ReadFile(savePath)
+	if err != nil {
+		t.Fatalf("expected history file: %v", err)
+	}
+	var saved []llm.Message
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved) != 3 {
+		t.Errorf("expected 3 saved messages, got %v (%v)", saved, err)
+	}
+	if !strings.Contains(out.String(), "[assistant] draft") || !strings.Contains(out.String(), "unknown command") {
+		t.Errorf("expected history listing and unknown command error, got: %s", out.String())
+	}
+
+	if err := session.SelectAgent("nobody"); err == nil || !strings.Contains(err.Error(), "Research Specialist") {
+		t.Errorf("expected unknown agent error listing roles, got: %v", err)
+	}
+}
+
+func TestChatSessionImage(t *testing.T) {
+	defaultLLM := &scriptedLLM{model: "default", reply: "a cat"}
+	session, out := newTestChatSession(t, map[string]*scriptedLLM{"": defaultLLM})
+	imagePath := filepath.Join(t.TempDir(), "cat.png")
+	if err := os.WriteFile(imagePath, []byte("\x89PNG\r\n\x1a\n"), 0644); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+
+	runLines(t, session, nil,
+		"/image "+filepath.Join(t.TempDir(), "missing.png"),
+		"/image "+imagePath,
+		"what is this?",
+		"and now?",
+		"/history",
+	)
+
+	if len(defaultLLM.received) != 2 {
+		t.Fatalf("expected two turns, got %d", len(defaultLLM.received))
+	}
+	parts, ok := defaultLLM.received[0][0].Content.([]llm.ContentPart)
+	if !ok || len(parts) != 2 || parts[0].Text != "what is this?" || !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/png;base64,") {
+		t.Fatalf("expected the image attached to the first message, got %#v", defaultLLM.received[0][0].Content)
+	}
+	// 图片只附加到下一条消息，之后随历史发送
+	second := defaultLLM.received[1]
+	if second[len(second)-1].Content != "and now?" {
+		t.Errorf("expected the next message without the image, got %#v", second[len(second)-1].Content)
+	}
+	if !strings.Contains(out.String(), "failed to read image") || !strings.Contains(out.String(), "[user] what is this?") {
+		t.Errorf("expected the missing file error and text history, got: %s", out.String())
+	}
+}
+
+func TestChatSessionInterrupt(t *testing.T) {
+	defaultLLM := &scriptedLLM{model: "default", reply: "a long answer", block: true, started: make(chan struct{})}
+	session, out := newTestChatSession(t, map[string]*scriptedLLM{"": defaultLLM})
+
+	interrupts := make(chan os.Signal, 1)
+	input := make(chan string)
+	done := make(chan error, 1)
+	go func() { done <- session.Run(context.Background(), input, interrupts) }()
+
+	input <- "question"
+	<-defaultLLM.started
+	interrupts <- os.Interrupt
+
+	// 中断后会话继续接收输入
+	input <- "again"
+	close(input)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("session failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("interrupt did not cancel the in-flight stream")
+	}
+
+	history := session.History()
+	if len(history) != 2 || history[0].Content != "again" {
+		t.Errorf("expected only the completed turn in history, got %v", history)
+	}
+	if !strings.Contains(out.String(), "已中断") {
+		t.Errorf("expected interrupt notice, got: %s", out.String())
+	}
+}
+
+func TestLoadChatConfigFallback(t *testing.T) {
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(t.TempDir())
+	log := logger.NewTestLogger()
+
+	t.Setenv("OPENAI_API_KEY", "")
+	if _, _, err := loadChatConfig("", log); err == nil {
+		t.Error("expected error without project config or OPENAI_API_KEY")
+	}
+
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	llmConfig, agents, err := loadChatConfig("", log)
+	if err != nil {
+		t.Fatalf("expected bare LLM fallback, got: %v", err)
+	}
+	if llmConfig.Provider != "openai" || agents != nil {
+		t.Errorf("expected openai fallback without agents, got %+v %v", llmConfig, agents)
+	}
+	if _, err := config.NewLLM(llmConfig); err != nil {
+		t.Errorf("expected fallback LLM to be created, got: %v", err)
+	}
+}
@@ -38,6 +38,9 @@ func newCreateCrewCommand(log logger.Logger) *cobra.Command {
 		outputDir   string
 		goModule    string
 		provider    string
+		process     string
+		localDev    string
+		withFlow    bool
 		skipPrompt  bool
 		interactive bool
 	)
@@ -107,6 +110,7 @@ func newCreateCrewCommand(log logger.Logger) *cobra.Command {
 
 			// 创建项目配置
 			projectConfig := config.DefaultCrewProjectConfig(projectName, goModule)
+			projectConfig.Process = process
 
 			// 如果是交互模式，允许用户自定义配置
 			if interactive {
@@ -128,6 +132,8 @@ func newCreateCrewCommand(log logger.Logger) *cobra.Command {
 
 			// 生成项目
 			gen := generator.NewCrewGenerator(projectConfig, absOutputDir)
+			gen.SetGreensoulaiRoot(localDev)
+			gen.SetWithFlow(withFlow)
 			if err := gen.Generate(); err != nil {
 				return fmt.Errorf("failed to generate project: %w", err)
 			}
@@ -163,6 +169,9 @@ Happy coding! 🎉
 	cmd.Flags().StringVarP(&outputDir, "output", "o", "", "输出目录 (默认为项目名)")
 	cmd.Flags().StringVarP(&goModule, "module", "m", "", "Go模块名 (例如: github.com/user/project)")
 	cmd.Flags().StringVarP(&provider, "provider", "p", "openai", "LLM提供商 (openai, anthropic)")
+	cmd.Flags().StringVar(&process, "process", "sequential", "执行流程 (sequential, hierarchical, parallel, consensus)")
+	cmd.Flags().BoolVar(&withFlow, "with-flow", false, "在工作流中运行Crew，便于添加前后作业")
+	addLocalDevFlag(cmd, &localDev)
 	cmd.Flags().BoolVar(&skipPrompt, "skip-prompt", false, "跳过确认提示")
 	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "交互式配置")
 
@@ -174,6 +183,7 @@ func newCreateFlowCommand(log logger.Logger) *cobra.Command {
 	var (
 		outputDir  string
 		goModule   string
+		localDev   string
 		skipPrompt bool
 	)
 
@@ -213,22 +223,35 @@ Flow项目专注于多阶段的工作流程，支持条件分支和并行执行
 				logger.Field{Key: "module", Value: goModule},
 			)
 
-			// TODO: 实现Flow项目生成逻辑
-			// 现在先创建基本的项目结构
 			absOutputDir, err := utils.FormatPath(outputDir)
 			if err != nil {
 				return fmt.Errorf("failed to format output directory: %w", err)
 			}
 
-			if err := utils.EnsureDirectoryExists(absOutputDir); err != nil {
-				return fmt.Errorf("failed to create output directory: %w", err)
+			exists, err := utils.CheckDirectoryExists(absOutputDir)
+			if err != nil {
+				return fmt.Errorf("failed to check output directory: %w", err)
+			}
+
+			if exists {
+				isEmpty, err := utils.IsDirectoryEmpty(absOutputDir)
+				if err != nil {
+					return fmt.Errorf("failed to check if directory is empty: %w", err)
+				}
+				if !isEmpty && !skipPrompt {
+					return fmt.Errorf("directory %s already exists and is not empty", absOutputDir)
+				}
 			}
 
-			// 创建基本的Flow项目配置
+			// 创建项目配置和默认工作流
 			projectConfig := config.DefaultFlowProjectConfig(projectName, goModule)
-			configPath := filepath.Join(absOutputDir, "greensoulai.yaml")
-			if err := projectConfig.SaveProjectConfig(configPath); err != nil {
-				return fmt.Errorf("failed to save project config: %w", err)
+			flowConfig := config.DefaultFlowConfig(projectName)
+
+			// 生成项目
+			gen := generator.NewFlowGenerator(projectConfig, flowConfig, absOutputDir)
+			gen.SetGreensoulaiRoot(localDev)
+			if err := gen.Generate(); err != nil {
+				return fmt.Errorf("failed to generate project: %w", err)
 			}
 
 			log.Info("Flow项目创建成功!", logger.Field{Key: "path", Value: absOutputDir})
@@ -239,12 +262,11 @@ Flow项目专注于多阶段的工作流程，支持条件分支和并行执行
 📁 项目目录: %s
 🔗 Go模块: %s
 
-⚠️  注意: Flow项目功能正在开发中，当前版本提供基础项目结构。
-
 🚀 下一步：
 1. 进入项目目录: cd %s
-2. 查看配置文件: greensoulai.yaml
-3. 关注项目更新获取完整Flow功能
+2. 查看作业和触发条件: flow.yaml
+3. 在 flows/ 中实现各个作业的逻辑
+4. 安装依赖并运行: make deps && make run
 
 `, projectName, absOutputDir, goModule, outputDir)
 
@@ -255,11 +277,18 @@ Flow项目专注于多阶段的工作流程，支持条件分支和并行执行
 	// 添加选项
 	cmd.Flags().StringVarP(&outputDir, "output", "o", "", "输出目录 (默认为项目名)")
 	cmd.Flags().StringVarP(&goModule, "module", "m", "", "Go模块名 (例如: github.com/user/project)")
+	addLocalDevFlag(cmd, &localDev)
 	cmd.Flags().BoolVar(&skipPrompt, "skip-prompt", false, "跳过确认提示")
 
 	return cmd
 }
 
+// addLocalDevFlag 添加--local-dev选项：不带值时使用当前目录，go.mod通过replace指向本地greensoulai模块
+func addLocalDevFlag(cmd *cobra.Command, localDev *string) {
+	cmd.Flags().StringVar(localDev, "local-dev", "", "使用本地greensoulai模块目录代替发布版本 (不带值时为当前目录)")
+	cmd.Flags().Lookup("local-dev").NoOptDefVal = "."
+}
+
 // newCreateAgentCommand 创建智能体命令
 func newCreateAgentCommand(log logger.Logger) *cobra.Command {
 	var (
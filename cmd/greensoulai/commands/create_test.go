@@ -299,6 +299,13 @@ func TestCreateFlowCommand(t *testing.T) {
 		t.Error("Flow project config file was not created")
 	}
 
+	// 检查工作流文件是否生成
+	for _, name := range []string{"flow.yaml", "go.mod", "cmd/main.go", "flows/fetch_data_job.go", "flows/report_job.go"} {
+		if _, err := os.Stat(filepath.Join("test-flow", name)); os.IsNotExist(err) {
+			t.Errorf("Flow project file %s was not created", name)
+		}
+	}
+
 	// 清理
 	if err := os.RemoveAll("test-flow"); err != nil {
 		t.Logf("Failed to clean up test-flow directory: %v", err)
@@ -2,12 +2,24 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math/rand"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/evaluation"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
@@ -16,384 +28,610 @@ func NewEvaluateCommand(log logger.Logger) *cobra.Command {
 	var (
 		iterations int
 		model      string
-		metric     string
+		inputs     []string
+		inputsFile string
 		outputFile string
-		parallel   bool
+		reportPath string
 		timeout    time.Duration
+		seed       int
+		strict     bool
+
+		baselinePath   string
+		updateBaseline bool
+		ignorePaths    []string
+		threshold      float64
+		similarity     string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "evaluate",
 		Short: "评估GreenSoulAI项目性能",
-		Long: `评估当前GreenSoulAI项目的性能和质量。
-通过多次运行项目并分析结果来评估智能体的表现，生成详细的评估报告。`,
+		Long: `评估当前GreenSoulAI Crew项目的任务执行质量。
+多次运行项目的Crew，由评估智能体为每个任务输出打出1-10分，
+最后输出每次运行的评分表和综合等级。
+
+指定--baseline时改为回归检查：运行一次Crew并与基线文件比较，
+JSON输出按字段精确比较，自由文本按相似度与阈值比较，有任务回归时以非零状态退出。
+基线文件不存在（或指定--update-baseline）时保存本次输出为基线。
+
+指定--report时为每次运行写入运行报告，多次运行时在文件名后加上运行序号（如report-1.md）。
+
+每次运行使用确定的种子并输出该种子，第i次运行的种子为--seed加i-1，未指定--seed时随机选择；
+使用 greensoulai run --seed <种子> 或 greensoulai evaluate -n 1 --seed <种子> 可以复现某次运行（需要模型支持seed参数）。`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if iterations < 1 {
+				return fmt.Errorf("iterations must be at least 1")
+			}
+
 			// 查找项目根目录
 			projectRoot, err := config.GetProjectRoot()
 			if err != nil {
 				return fmt.Errorf("not in a greensoulai project: %w", err)
 			}
 
-			// 加载项目配置
 			configPath := filepath.Join(projectRoot, "greensoulai.yaml")
-			projectConfig, err := config.LoadProjectConfig(configPath)
+			projectConfig, err := config.ValidateProjectFile(configPath, config.BuiltinTools().Names())
 			if err != nil {
-				return fmt.Errorf("failed to load project config: %w", err)
+				return fmt.Errorf("invalid project configuration:\n%w", err)
+			}
+			if projectConfig.Type != config.ProjectTypeCrew {
+				return fmt.Errorf("evaluate only supports crew projects, got %s", projectConfig.Type)
 			}
 
-			// 验证配置
-			if err := projectConfig.Validate(); err != nil {
-				return fmt.Errorf("invalid project configuration: %w", err)
+			crewInputs, err := parseInputs(inputs, inputsFile)
+			if err != nil {
+				return err
+			}
+
+			var baseSeed *int
+			if cmd.Flags().Changed("seed") {
+				baseSeed = &seed
+			}
+
+			newLLM := config.LLMFactory(projectConfig.LLM)
+			runner := &CrewRunner{
+				Config:      projectConfig,
+				ProjectRoot: projectRoot,
+				NewLLM:      newLLM,
+				EventBus:    events.NewEventBus(log),
+				Out:         os.Stdout,
+				Logger:      log,
+
+				Reproducibility: agent.ReproducibilityConfig{Strict: strict},
+			}
+
+			if baselinePath != "" {
+				runner.ReportPath = reportPath
+				runSeed := chooseSeed(baseSeed)
+				runner.Reproducibility.Seed = &runSeed
+				fmt.Printf("\n🎲 种子: %d（使用 --seed %d 重新运行）\n", runSeed, runSeed)
+				options, err := baselineCompareOptions(threshold, similarity, ignorePaths)
+				if err != nil {
+					return err
+				}
+				checker := &BaselineChecker{
+					Runner:  runner,
+					Path:    baselinePath,
+					Update:  updateBaseline,
+					Options: options,
+					Timeout: timeout,
+					Out:     os.Stdout,
+				}
+				report, checkErr := checker.Check(cmd.Context(), crewInputs)
+				if report != nil && outputFile != "" {
+					if err := writeJSONReport(outputFile, report); err != nil {
+						return fmt.Errorf("failed to save regression report: %w", err)
+					}
+					fmt.Printf("\n📁 回归报告已保存到: %s\n", outputFile)
+				}
+				return checkErr
+			}
+
+			evalLLM, err := newLLM(model)
+			if err != nil {
+				return fmt.Errorf("failed to create evaluation LLM: %w", err)
 			}
 
 			log.Info("开始评估项目",
 				logger.Field{Key: "name", Value: projectConfig.Name},
 				logger.Field{Key: "iterations", Value: iterations},
-				logger.Field{Key: "model", Value: model},
-				logger.Field{Key: "metric", Value: metric},
+				logger.Field{Key: "model", Value: evalLLM.GetModel()},
 			)
 
-			// 创建评估器
 			evaluator := &ProjectEvaluator{
-				Config:      projectConfig,
-				ProjectRoot: projectRoot,
-				Iterations:  iterations,
-				Model:       model,
-				Metric:      metric,
-				OutputFile:  outputFile,
-				Parallel:    parallel,
-				Timeout:     timeout,
-				Logger:      log,
+				Runner:     runner,
+				LLM:        evalLLM,
+				Iterations: iterations,
+				Timeout:    timeout,
+				ReportPath: reportPath,
+				Seed:       baseSeed,
+				Out:        os.Stdout,
+				Logger:     log,
+			}
+
+			report, err := evaluator.Evaluate(cmd.Context(), crewInputs)
+			if err != nil {
+				return err
 			}
 
-			// 执行评估
-			return evaluator.Evaluate(cmd.Context())
+			if outputFile != "" {
+				if err := report.Save(outputFile); err != nil {
+					return fmt.Errorf("failed to save evaluation report: %w", err)
+				}
+				fmt.Printf("\n📁 评估报告已保存到: %s\n", outputFile)
+			}
+			return nil
 		},
 	}
 
 	// 添加选项
 	cmd.Flags().IntVarP(&iterations, "iterations", "n", 3, "评估迭代次数")
-	cmd.Flags().StringVarP(&model, "model", "m", "", "评估用的LLM模型")
-	cmd.Flags().StringVar(&metric, "metric", "quality", "评估指标 (quality, performance, cost)")
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "评估报告输出文件")
-	cmd.Flags().BoolVar(&parallel, "parallel", false, "并行执行评估")
-	cmd.Flags().DurationVarP(&timeout, "timeout", "t", 15*time.Minute, "单次评估超时时间")
+	cmd.Flags().StringVarP(&model, "model", "m", "", "评估用的LLM模型，默认使用项目模型")
+	cmd.Flags().StringArrayVarP(&inputs, "input", "i", nil, "Crew输入，格式为key=value，可重复指定")
+	cmd.Flags().StringVar(&inputsFile, "inputs-file", "", "JSON格式的输入文件")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "评估报告输出文件（JSON）")
+	cmd.Flags().StringVar(&reportPath, "report", "", "每次运行的运行报告输出文件，.html为HTML格式，其余为Markdown")
+	cmd.Flags().DurationVarP(&timeout, "timeout", "t", 15*time.Minute, "单次迭代（运行和评估）的超时时间")
+	cmd.Flags().IntVar(&seed, "seed", 0, "第一次运行的随机种子，之后每次运行加1，默认随机选择")
+	cmd.Flags().BoolVar(&strict, "strict-reproducibility", false, "严格可复现模式：温度固定为0，破坏确定性时发出警告")
+	cmd.Flags().StringVar(&baselinePath, "baseline", "", "与基线文件比较做回归检查，文件不存在时保存本次输出为基线")
+	cmd.Flags().BoolVar(&updateBaseline, "update-baseline", false, "用本次输出覆盖--baseline指定的基线")
+	cmd.Flags().StringArrayVar(&ignorePaths, "ignore", nil, "比较JSON输出时忽略的路径，如$.created_at、$.items[*].id，可重复指定")
+	cmd.Flags().Float64Var(&threshold, "threshold", 0.8, "自由文本与基线的最低相似度（0-1）")
+	cmd.Flags().StringVar(&similarity, "similarity", crew.SimilarityLevenshtein, "自由文本的相似度计算方式：levenshtein或embedding")
 
 	return cmd
 }
 
-// ProjectEvaluator 项目评估器
-type ProjectEvaluator struct {
-	Config      *config.ProjectConfig
-	ProjectRoot string
-	Iterations  int
-	Model       string
-	Metric      string
-	OutputFile  string
-	Parallel    bool
-	Timeout     time.Duration
-	Logger      logger.Logger
+// baselineCompareOptions 根据命令行选项创建基线比较选项
+func baselineCompareOptions(threshold float64, similarity string, ignorePaths []string) (crew.BaselineCompareOptions, error) {
+	if threshold <= 0 || threshold > 1 {
+		return crew.BaselineCompareOptions{}, fmt.Errorf("threshold must be in (0, 1], got %v", threshold)
+	}
+	options := crew.DefaultBaselineCompareOptions()
+	options.TextThreshold = threshold
+	options.IgnorePaths = ignorePaths
+
+	switch similarity {
+	case crew.SimilarityLevenshtein:
+	case crew.SimilarityEmbedding:
+		embedder, err := memory.NewEmbedder(&memory.EmbedderConfig{Provider: "default"})
+		if err != nil {
+			return crew.BaselineCompareOptions{}, fmt.Errorf("failed to create embedder: %w", err)
+		}
+		options.Embedder = embedder
+	default:
+		return crew.BaselineCompareOptions{}, fmt.Errorf("unsupported similarity %q, expected %s or %s",
+			similarity, crew.SimilarityLevenshtein, crew.SimilarityEmbedding)
+	}
+	return options, nil
 }
 
-// EvaluationResult 评估结果
-type EvaluationResult struct {
-	ProjectName    string    `json:"project_name"`
-	EvaluationTime time.Time `json:"evaluation_time"`
-	Iterations     int       `json:"iterations"`
-	Model          string    `json:"model"`
-	Metric         string    `json:"metric"`
-
-	// 整体评分
-	OverallScore     float64 `json:"overall_score"`
-	QualityScore     float64 `json:"quality_score"`
-	PerformanceScore float64 `json:"performance_score"`
-	CostScore        float64 `json:"cost_score"`
-
-	// 详细结果
-	TaskResults  []TaskEvaluationResult  `json:"task_results"`
-	AgentResults []AgentEvaluationResult `json:"agent_results"`
-
-	// 统计信息
-	Statistics      EvaluationStatistics `json:"statistics"`
-	Recommendations []string             `json:"recommendations"`
+// writeJSONReport 以缩进的JSON格式保存报告
+func writeJSONReport(path string, report interface{}) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
 }
 
-// TaskEvaluationResult 任务评估结果
-type TaskEvaluationResult struct {
-	TaskName       string        `json:"task_name"`
-	AgentName      string        `json:"agent_name"`
-	QualityScore   float64       `json:"quality_score"`
-	CompletionRate float64       `json:"completion_rate"`
-	AverageTime    time.Duration `json:"average_time"`
-	SuccessRate    float64       `json:"success_rate"`
-	OutputQuality  string        `json:"output_quality"`
+// ProjectEvaluator 项目评估器：多次运行Crew，并把每个任务输出交给CrewEvaluator评分
+type ProjectEvaluator struct {
+	Runner     *CrewRunner
+	LLM        llm.LLM // 评估用的LLM
+	Iterations int
+	Timeout    time.Duration
+	ReportPath string // 非空时为每次运行写入运行报告，见iterationReportPath
+	Seed       *int   // 第一次运行的种子，之后每次运行加1，为nil时随机选择
+	Out        io.Writer
+	Logger     logger.Logger
 }
 
-// AgentEvaluationResult 智能体评估结果
-type AgentEvaluationResult struct {
-	AgentName    string  `json:"agent_name"`
-	Role         string  `json:"role"`
-	TaskCount    int     `json:"task_count"`
-	AverageScore float64 `json:"average_score"`
-	Reliability  float64 `json:"reliability"`
-	Efficiency   float64 `json:"efficiency"`
-	Consistency  float64 `json:"consistency"`
+// EvaluationReport 评估报告
+type EvaluationReport struct {
+	ProjectName  string             `json:"project_name"`
+	Model        string             `json:"model"`
+	Iterations   int                `json:"iterations"`
+	EvaluatedAt  time.Time          `json:"evaluated_at"`
+	Tasks        []TaskScores       `json:"tasks"`
+	RunDurations []float64          `json:"run_durations_seconds"` // 每次运行Crew的耗时（秒）
+	Seeds        []int              `json:"seeds"`                 // 每次运行使用的种子
+	Usage        *crew.UsageMetrics `json:"usage"`                 // 各次运行Crew的使用统计合计，不含评估模型的调用
+	AverageScore float64            `json:"average_score"`
+	Grade        string             `json:"grade"`
 }
 
-// EvaluationStatistics 评估统计
-type EvaluationStatistics struct {
-	TotalExecutions  int           `json:"total_executions"`
-	SuccessfulRuns   int           `json:"successful_runs"`
-	FailedRuns       int           `json:"failed_runs"`
-	AverageExecution time.Duration `json:"average_execution_time"`
-	MinExecutionTime time.Duration `json:"min_execution_time"`
-	MaxExecutionTime time.Duration `json:"max_execution_time"`
-	TotalCost        float64       `json:"total_cost"`
-	AverageCost      float64       `json:"average_cost"`
+// TaskScores 任务在每次运行中的评分，nil表示评分缺失（运行失败或评估失败）
+type TaskScores struct {
+	Task   string     `json:"task"`
+	Agent  string     `json:"agent"`
+	Scores []*float64 `json:"scores"`
 }
 
-// Evaluate 执行项目评估
-func (e *ProjectEvaluator) Evaluate(ctx context.Context) error {
-	e.Logger.Info("🎯 开始项目评估")
+// Average 计算非缺失评分的平均值
+func (ts TaskScores) Average() (float64, bool) {
+	return averageScores(ts.Scores)
+}
 
-	// 显示评估信息
-	e.printEvaluationHeader()
+// IterationAverage 计算第iteration次运行（从1开始）所有任务评分的平均值
+func (r *EvaluationReport) IterationAverage(iteration int) (float64, bool) {
+	scores := make([]*float64, 0, len(r.Tasks))
+	for _, task := range r.Tasks {
+		scores = append(scores, task.Scores[iteration-1])
+	}
+	return averageScores(scores)
+}
 
-	// 初始化评估结果
-	result := &EvaluationResult{
-		ProjectName:    e.Config.Name,
-		EvaluationTime: time.Now(),
-		Iterations:     e.Iterations,
-		Model:          e.Model,
-		Metric:         e.Metric,
-		TaskResults:    make([]TaskEvaluationResult, 0),
-		AgentResults:   make([]AgentEvaluationResult, 0),
+// finalize 计算综合评分和等级
+func (r *EvaluationReport) finalize() bool {
+	var scores []*float64
+	for _, task := range r.Tasks {
+		scores = append(scores, task.Scores...)
+	}
+	average, ok := averageScores(scores)
+	if !ok {
+		r.Grade = "-"
+		return false
 	}
+	r.AverageScore = average
+	r.Grade = evaluation.GradeFromScore(average)
+	return true
+}
 
-	// 执行评估迭代
-	for i := 1; i <= e.Iterations; i++ {
-		e.Logger.Info("执行评估迭代", logger.Field{Key: "iteration", Value: i})
+// Save 以JSON格式保存评估报告
+func (r *EvaluationReport) Save(path string) error {
+	return writeJSONReport(path, r)
+}
 
-		// 创建迭代上下文
-		iterCtx, cancel := context.WithTimeout(ctx, e.Timeout)
+// PrintTable 输出每次运行的评分表，缺失的评分显示为"-"
+func (r *EvaluationReport) PrintTable(w io.Writer) {
+	fmt.Fprintf(w, "\n📊 任务评分 (1-10，越高越好)\n")
 
-		// 执行单次评估
-		if err := e.executeEvaluationIteration(iterCtx, i, result); err != nil {
-			cancel()
-			e.Logger.Error("评估迭代失败",
-				logger.Field{Key: "iteration", Value: i},
-				logger.Field{Key: "error", Value: err})
-			continue
+	header := []string{"任务"}
+	for i := 1; i <= r.Iterations; i++ {
+		header = append(header, fmt.Sprintf("运行%d", i))
+	}
+	rows := [][]string{append(header, "平均", "智能体")}
+
+	for _, task := range r.Tasks {
+		row := []string{task.Task}
+		for _, score := range task.Scores {
+			row = append(row, formatScore(score))
 		}
-		cancel()
+		average, ok := task.Average()
+		rows = append(rows, append(row, formatAverage(average, ok), task.Agent))
+	}
 
-		fmt.Printf("✅ 迭代 %d/%d 完成\n", i, e.Iterations)
+	crewRow := []string{"Crew"}
+	durationRow := []string{"耗时(秒)"}
+	for i := 1; i <= r.Iterations; i++ {
+		average, ok := r.IterationAverage(i)
+		crewRow = append(crewRow, formatAverage(average, ok))
+		durationRow = append(durationRow, fmt.Sprintf("%.1f", r.RunDurations[i-1]))
+	}
+	rows = append(rows,
+		append(crewRow, formatAverage(r.AverageScore, r.Grade != "-"), ""),
+		append(durationRow, "", ""),
+	)
+	printAligned(w, rows)
+
+	if r.Usage != nil {
+		fmt.Fprintf(w, "\n💰 LLM调用 %d 次，使用 %d tokens，成本 $%.4f\n", r.Usage.LLMCalls, r.Usage.TotalTokens, r.Usage.TotalCost)
 	}
 
-	// 分析评估结果
-	e.analyzeResults(result)
+	if r.Grade == "-" {
+		fmt.Fprintf(w, "\n综合评分: - (没有任务完成评估)\n")
+		return
+	}
+	fmt.Fprintf(w, "\n综合评分: %.2f/10 (%s)\n", r.AverageScore, r.Grade)
+}
 
-	// 生成推荐
-	e.generateRecommendations(result)
+// Evaluate 运行Crew Iterations次并逐个评估任务输出
+// 单个任务的评估失败只记为缺失评分；所有任务都没有评分时返回错误
+func (e *ProjectEvaluator) Evaluate(ctx context.Context, inputs map[string]interface{}) (*EvaluationReport, error) {
+	cfg := e.Runner.Config
+	report := &EvaluationReport{
+		ProjectName:  cfg.Name,
+		Model:        e.LLM.GetModel(),
+		Iterations:   e.Iterations,
+		EvaluatedAt:  time.Now(),
+		Tasks:        make([]TaskScores, len(cfg.Tasks)),
+		RunDurations: make([]float64, e.Iterations),
+		Seeds:        make([]int, e.Iterations),
+		Usage:        &crew.UsageMetrics{},
+	}
 
-	// 保存评估报告
-	if err := e.saveEvaluationReport(result); err != nil {
-		return fmt.Errorf("failed to save evaluation report: %w", err)
+	roles := make(map[string]string, len(cfg.Agents))
+	for _, agentConfig := range cfg.Agents {
+		roles[agentConfig.Name] = agentConfig.Role
+	}
+	for i, taskConfig := range cfg.Tasks {
+		report.Tasks[i] = TaskScores{
+			Task:   taskConfig.Name,
+			Agent:  roles[taskConfig.Agent],
+			Scores: make([]*float64, e.Iterations),
+		}
 	}
 
-	// 显示评估总结
-	e.printEvaluationSummary(result)
+	fmt.Fprintf(e.Out, "\n🎯 评估 %s：运行 %d 次，评估模型 %s\n", cfg.Name, e.Iterations, report.Model)
 
-	return nil
-}
+	target := &evaluatedCrew{name: cfg.Name}
+	evaluator := evaluation.NewCrewEvaluator(target, e.LLM, nil, e.Runner.EventBus, e.Logger)
 
-// executeEvaluationIteration 执行单次评估迭代
-func (e *ProjectEvaluator) executeEvaluationIteration(ctx context.Context, iteration int, result *EvaluationResult) error {
-	// TODO: 这里应该实际运行项目并收集性能数据
-	// 现在用模拟数据
-
-	// 模拟任务评估
-	for _, taskCfg := range e.Config.Tasks {
-		taskResult := TaskEvaluationResult{
-			TaskName:       taskCfg.Name,
-			AgentName:      taskCfg.Agent,
-			QualityScore:   0.7 + float64(iteration%3)*0.1, // 模拟质量评分
-			CompletionRate: 0.9 + float64(iteration%2)*0.05,
-			AverageTime:    time.Duration(2+iteration%3) * time.Second,
-			SuccessRate:    0.85 + float64(iteration%4)*0.05,
-			OutputQuality:  "良好",
+	baseSeed := chooseSeed(e.Seed)
+	for i := 1; i <= e.Iterations; i++ {
+		report.Seeds[i-1] = baseSeed + i - 1
+		fmt.Fprintf(e.Out, "\n🔄 第 %d/%d 次运行\n", i, e.Iterations)
+		fmt.Fprintf(e.Out, "🎲 种子: %d（使用 --seed %d 重新运行）\n", report.Seeds[i-1], report.Seeds[i-1])
+		evaluator.SetIteration(i)
+		if err := e.runIteration(ctx, i, target, evaluator, report, inputs); err != nil {
+			return nil, err
 		}
-		result.TaskResults = append(result.TaskResults, taskResult)
-	}
-
-	// 模拟智能体评估
-	for _, agentCfg := range e.Config.Agents {
-		agentResult := AgentEvaluationResult{
-			AgentName:    agentCfg.Name,
-			Role:         agentCfg.Role,
-			TaskCount:    len(e.Config.Tasks),
-			AverageScore: 0.8 + float64(iteration%3)*0.05,
-			Reliability:  0.9 + float64(iteration%2)*0.03,
-			Efficiency:   0.75 + float64(iteration%4)*0.05,
-			Consistency:  0.85 + float64(iteration%3)*0.03,
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
-		result.AgentResults = append(result.AgentResults, agentResult)
 	}
 
-	return nil
+	scored := report.finalize()
+	report.PrintTable(e.Out)
+	if !scored {
+		return report, fmt.Errorf("no task output could be evaluated")
+	}
+	return report, nil
 }
 
-// analyzeResults 分析评估结果
-func (e *ProjectEvaluator) analyzeResults(result *EvaluationResult) {
-	// 计算整体评分
-	if len(result.TaskResults) > 0 {
-		var totalQuality float64
-		for _, taskResult := range result.TaskResults {
-			totalQuality += taskResult.QualityScore
-		}
-		result.QualityScore = totalQuality / float64(len(result.TaskResults))
+// runIteration 构建并运行一次Crew，然后同步评估每个任务输出
+// 只有构建Crew失败（配置问题，每次运行都会失败）时返回错误
+func (e *ProjectEvaluator) runIteration(ctx context.Context, iteration int, target *evaluatedCrew,
+	evaluator *evaluation.CrewEvaluatorImpl, report *EvaluationReport, inputs map[string]interface{}) error {
+
+	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+
+	runner := *e.Runner
+	runner.ReportPath = iterationReportPath(e.ReportPath, iteration, e.Iterations)
+	seed := report.Seeds[iteration-1]
+	runner.Reproducibility.Seed = &seed
+	c, err := runner.Build()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	startTime := time.Now()
+	output, err := runner.Kickoff(ctx, c, inputs)
+	report.RunDurations[iteration-1] = time.Since(startTime).Seconds()
+	if output != nil {
+		report.Usage.Add(output.TokenUsage)
+	}
+	if err != nil {
+		fmt.Fprintf(e.Out, "❌ 第 %d 次运行失败，未完成的任务评分记为缺失: %v\n", iteration, err)
+	}
+	if output == nil {
+		return nil
 	}
 
-	if len(result.AgentResults) > 0 {
-		var totalPerformance float64
-		for _, agentResult := range result.AgentResults {
-			totalPerformance += agentResult.Efficiency
+	target.setRun(c, output.TasksOutput)
+	tasks := c.GetTasks()
+	for _, taskOutput := range output.TasksOutput {
+		index := -1
+		for i, task := range tasks {
+			if task.GetID() == taskOutput.Task {
+				index = i
+				break
+			}
 		}
-		result.PerformanceScore = totalPerformance / float64(len(result.AgentResults))
+		if index < 0 || index >= len(report.Tasks) {
+			continue
+		}
+
+		result, err := evaluator.Evaluate(ctx, evaluation.FromAgentTaskOutput(taskOutput))
+		if err != nil {
+			e.Logger.Warn("任务评估失败",
+				logger.Field{Key: "task", Value: report.Tasks[index].Task},
+				logger.Field{Key: "iteration", Value: iteration},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+			fmt.Fprintf(e.Out, "⚠️  任务 %s 评估失败，评分记为缺失\n", report.Tasks[index].Task)
+			continue
+		}
+		quality := result.Quality
+		report.Tasks[index].Scores[iteration-1] = &quality
 	}
+	return nil
+}
 
-	// 成本评分（模拟）
-	result.CostScore = 0.8
+// maxRandomSeed 随机选择的种子的上限，加上运行序号后仍在各提供商接受的范围内
+const maxRandomSeed = 1 << 30
 
-	// 计算总体评分
-	result.OverallScore = (result.QualityScore + result.PerformanceScore + result.CostScore) / 3
+// chooseSeed 返回指定的种子，没有指定时随机选择
+func chooseSeed(seed *int) int {
+	if seed != nil {
+		return *seed
+	}
+	return rand.Intn(maxRandomSeed)
+}
 
-	// 更新统计信息
-	result.Statistics = EvaluationStatistics{
-		TotalExecutions:  e.Iterations,
-		SuccessfulRuns:   e.Iterations,
-		FailedRuns:       0,
-		AverageExecution: 5 * time.Second,
-		MinExecutionTime: 3 * time.Second,
-		MaxExecutionTime: 8 * time.Second,
-		TotalCost:        float64(e.Iterations) * 0.05,
-		AverageCost:      0.05,
+// iterationReportPath 返回第iteration次运行的报告文件，多次运行时在扩展名前加上运行序号
+func iterationReportPath(path string, iteration, iterations int) string {
+	if path == "" || iterations <= 1 {
+		return path
 	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), iteration, ext)
 }
 
-// generateRecommendations 生成改进建议
-func (e *ProjectEvaluator) generateRecommendations(result *EvaluationResult) {
-	recommendations := []string{}
+// evaluatedCrew 把最近一次运行的crew.Crew适配为评估用的Crew接口
+type evaluatedCrew struct {
+	name  string
+	crew  crew.Crew
+	tasks []evaluation.Task
+	mu    sync.RWMutex
+}
 
-	if result.QualityScore < 0.7 {
-		recommendations = append(recommendations, "建议优化智能体的提示词和角色定义")
+// setRun 切换到新一次运行的Crew，任务耗时取自该次运行的任务输出
+func (c *evaluatedCrew) setRun(current crew.Crew, outputs []*agent.TaskOutput) {
+	durations := make(map[string]time.Duration, len(outputs))
+	for _, output := range outputs {
+		durations[output.Task] = output.ExecutionTime
 	}
 
-	if result.PerformanceScore < 0.7 {
-		recommendations = append(recommendations, "建议优化任务分配和执行流程")
+	tasks := make([]evaluation.Task, 0, len(current.GetTasks()))
+	for _, task := range current.GetTasks() {
+		tasks = append(tasks, &evaluatedTask{task: task, duration: durations[task.GetID()]})
 	}
 
-	if result.CostScore < 0.7 {
-		recommendations = append(recommendations, "建议优化LLM调用频率和模型选择")
-	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.crew = current
+	c.tasks = tasks
+}
+
+func (c *evaluatedCrew) GetName() string { return c.name }
+
+func (c *evaluatedCrew) GetTasks() []evaluation.Task {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tasks
+}
 
-	if result.OverallScore > 0.85 {
-		recommendations = append(recommendations, "项目表现优秀，可以考虑增加更复杂的任务")
+func (c *evaluatedCrew) GetAgents() []agent.Agent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.crew == nil {
+		return nil
 	}
+	return c.crew.GetAgents()
+}
 
-	if len(recommendations) == 0 {
-		recommendations = append(recommendations, "项目整体表现良好，继续保持")
+func (c *evaluatedCrew) Execute(ctx context.Context, inputs map[string]interface{}) (*evaluation.CrewOutput, error) {
+	c.mu.RLock()
+	current := c.crew
+	c.mu.RUnlock()
+	if current == nil {
+		return nil, fmt.Errorf("crew %s has not been built", c.name)
 	}
 
-	result.Recommendations = recommendations
+	output, err := current.Kickoff(ctx, inputs)
+	if output == nil {
+		return nil, err
+	}
+	tasksOutput := make([]*evaluation.TaskOutput, 0, len(output.TasksOutput))
+	for _, taskOutput := range output.TasksOutput {
+		tasksOutput = append(tasksOutput, evaluation.FromAgentTaskOutput(taskOutput))
+	}
+	return &evaluation.CrewOutput{
+		Raw:         output.Raw,
+		JSONDict:    output.JSON,
+		Pydantic:    output.Pydantic,
+		TasksOutput: tasksOutput,
+		Metadata:    output.Metadata,
+	}, err
 }
 
-// printEvaluationHeader 打印评估头部信息
-func (e *ProjectEvaluator) printEvaluationHeader() {
-	fmt.Printf(`
-🎯 GreenSoulAI 项目评估
-==================================================
-📋 项目: %s (%s)
-🔄 评估次数: %d
-📊 评估指标: %s
-🤖 评估模型: %s
-⏱️  超时时间: %v
-
-评估进度：
-`, e.Config.Name, e.Config.Type, e.Iterations, e.Metric, e.Model, e.Timeout)
+func (c *evaluatedCrew) SetTaskCallback(callback func(*evaluation.TaskOutput)) error {
+	c.mu.RLock()
+	current := c.crew
+	c.mu.RUnlock()
+	if current == nil {
+		return fmt.Errorf("crew %s has not been built", c.name)
+	}
+	return current.AddTaskCallback(func(ctx context.Context, task agent.Task, output *agent.TaskOutput) error {
+		callback(evaluation.FromAgentTaskOutput(output))
+		return nil
+	})
 }
 
-// printEvaluationSummary 打印评估总结
-func (e *ProjectEvaluator) printEvaluationSummary(result *EvaluationResult) {
-	fmt.Printf(`
-🏁 评估完成！
-==================================================
-📊 评估结果:
-   整体评分: %.2f/1.00 (%s)
-   质量评分: %.2f/1.00
-   性能评分: %.2f/1.00
-   成本评分: %.2f/1.00
+// evaluatedTask 把agent.Task适配为评估用的Task接口
+type evaluatedTask struct {
+	task     agent.Task
+	duration time.Duration
+}
 
-📈 统计信息:
-   成功执行: %d/%d
-   平均耗时: %v
-   平均成本: $%.4f
+func (t *evaluatedTask) GetID() string                       { return t.task.GetID() }
+func (t *evaluatedTask) GetDescription() string              { return t.task.GetDescription() }
+func (t *evaluatedTask) GetExpectedOutput() string           { return t.task.GetExpectedOutput() }
+func (t *evaluatedTask) GetAgent() agent.Agent               { return t.task.GetAssignedAgent() }
+func (t *evaluatedTask) GetExecutionDuration() time.Duration { return t.duration }
 
-🎯 任务表现:
-`, result.OverallScore, e.getScoreLevel(result.OverallScore),
-		result.QualityScore, result.PerformanceScore, result.CostScore,
-		result.Statistics.SuccessfulRuns, result.Statistics.TotalExecutions,
-		result.Statistics.AverageExecution, result.Statistics.AverageCost)
+func (t *evaluatedTask) Execute(ctx context.Context) (*evaluation.TaskOutput, error) {
+	return t.ExecuteSync(ctx)
+}
 
-	for _, taskResult := range result.TaskResults {
-		fmt.Printf("   • %s: %.2f/1.00 (成功率: %.1f%%)\n",
-			taskResult.TaskName, taskResult.QualityScore, taskResult.SuccessRate*100)
+func (t *evaluatedTask) ExecuteSync(ctx context.Context) (*evaluation.TaskOutput, error) {
+	assigned := t.task.GetAssignedAgent()
+	if assigned == nil {
+		return nil, fmt.Errorf("task %s has no assigned agent", t.task.GetName())
 	}
-
-	fmt.Println("\n🤖 智能体表现:")
-	for _, agentResult := range result.AgentResults {
-		fmt.Printf("   • %s: %.2f/1.00 (可靠性: %.1f%%)\n",
-			agentResult.AgentName, agentResult.AverageScore, agentResult.Reliability*100)
+	output, err := assigned.Execute(ctx, t.task)
+	if err != nil {
+		return nil, err
 	}
+	return evaluation.FromAgentTaskOutput(output), nil
+}
 
-	fmt.Println("\n💡 改进建议:")
-	for i, rec := range result.Recommendations {
-		fmt.Printf("   %d. %s\n", i+1, rec)
+// averageScores 计算非缺失评分的平均值，全部缺失时返回false
+func averageScores(scores []*float64) (float64, bool) {
+	var sum float64
+	var count int
+	for _, score := range scores {
+		if score != nil {
+			sum += *score
+			count++
+		}
 	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
 
-	if e.OutputFile != "" {
-		fmt.Printf("\n📁 详细报告已保存到: %s\n", e.OutputFile)
+func formatScore(score *float64) string {
+	if score == nil {
+		return "-"
 	}
+	return fmt.Sprintf("%.1f", *score)
 }
 
-// getScoreLevel 获取评分等级
-func (e *ProjectEvaluator) getScoreLevel(score float64) string {
-	if score >= 0.9 {
-		return "优秀"
-	} else if score >= 0.8 {
-		return "良好"
-	} else if score >= 0.7 {
-		return "中等"
-	} else if score >= 0.6 {
-		return "一般"
-	} else {
-		return "需改进"
+func formatAverage(average float64, ok bool) string {
+	if !ok {
+		return "-"
 	}
+	return fmt.Sprintf("%.1f", average)
 }
 
-// saveEvaluationReport 保存评估报告
-func (e *ProjectEvaluator) saveEvaluationReport(result *EvaluationResult) error {
-	if e.OutputFile == "" {
-		e.OutputFile = fmt.Sprintf("%s_evaluation_%s.json",
-			e.Config.Name,
-			time.Now().Format("20060102_150405"))
+// printAligned 按显示宽度对齐输出表格，中文字符按两列计算
+func printAligned(w io.Writer, rows [][]string) {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if width := displayWidth(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
 	}
 
-	// TODO: 实际的JSON序列化和文件保存
-	e.Logger.Info("保存评估报告",
-		logger.Field{Key: "file", Value: e.OutputFile},
-		logger.Field{Key: "overall_score", Value: result.OverallScore},
-	)
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-displayWidth(cell)+2))
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+}
 
-	return nil
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
 }
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// evaluatorLLM 依次返回评分回复，回复为空字符串的调用返回错误
+type evaluatorLLM struct {
+	scriptedLLM
+	replies []string
+	calls   int
+}
+
+func (l *evaluatorLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	reply := l.replies[l.calls%len(l.replies)]
+	l.calls++
+	if reply == "" {
+		return nil, errors.New("evaluator unavailable")
+	}
+	return &llm.Response{Content: reply}, nil
+}
+
+func newTestProjectEvaluator(t *testing.T, evalLLM llm.LLM, iterations int) (*ProjectEvaluator, *bytes.Buffer) {
+	t.Helper()
+	runner, out := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &scriptedLLM{model: "default", reply: "research notes"},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "final article"},
+	})
+	return &ProjectEvaluator{
+		Runner:     runner,
+		LLM:        evalLLM,
+		Iterations: iterations,
+		Timeout:    time.Minute,
+		Out:        out,
+		Logger:     logger.NewTestLogger(),
+	}, out
+}
+
+func TestProjectEvaluatorEvaluate(t *testing.T) {
+	evalLLM := &evaluatorLLM{
+		scriptedLLM: scriptedLLM{model: "judge"},
+		replies:     []string{`{"quality": 8}`, "评估结果如下：\n```json\n{\"quality\": 6}\n```", `{"quality": 9}`, ""},
+	}
+	evaluator, out := newTestProjectEvaluator(t, evalLLM, 2)
+	reportDir := t.TempDir()
+	evaluator.ReportPath = filepath.Join(reportDir, "run.html")
+	seed := 100
+	evaluator.Seed = &seed
+
+	report, err := evaluator.Evaluate(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 第二次运行中write任务的评估失败，只记为缺失
+	research, write := report.Tasks[0], report.Tasks[1]
+	if formatScore(research.Scores[0]) != "8.0" || formatScore(research.Scores[1]) != "9.0" {
+		t.Errorf("unexpected research scores: %s %s", formatScore(research.Scores[0]), formatScore(research.Scores[1]))
+	}
+	if formatScore(write.Scores[0]) != "6.0" || write.Scores[1] != nil {
+		t.Errorf("expected write scores 6.0 and missing, got %s %s", formatScore(write.Scores[0]), formatScore(write.Scores[1]))
+	}
+	if report.AverageScore != 23.0/3 || report.Grade != "B" {
+		t.Errorf("expected average 7.67 with grade B, got %.2f %s", report.AverageScore, report.Grade)
+	}
+
+	// 评估模型的调用不计入Crew的使用统计
+	if report.Usage.Runs != 2 || report.Usage.SuccessfulTasks != 4 || report.Usage.LLMCalls != 4 {
+		t.Errorf("expected usage of two runs with two tasks each, got %+v", report.Usage)
+	}
+
+	table := out.String()
+	for _, want := range []string{"任务评分", "运行1", "运行2", "research  8.0    9.0    8.5   Researcher", "write     6.0    -      6.0   Writer", "Crew      7.0    9.0    7.7", "LLM调用 4 次", "综合评分: 7.67/10 (B)", "任务 write 评估失败"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, table)
+		}
+	}
+
+	// 每次运行的种子依次加1并输出，便于复现某次运行
+	if len(report.Seeds) != 2 || report.Seeds[0] != 100 || report.Seeds[1] != 101 {
+		t.Errorf("expected seeds 100 and 101, got %v", report.Seeds)
+	}
+	if !strings.Contains(table, "🎲 种子: 101（使用 --seed 101 重新运行）") {
+		t.Errorf("expected the seed of each run to be printed, got:\n%s", table)
+	}
+
+	for _, name := range []string{"run-1.html", "run-2.html"} {
+		if data, err := os.ReadFile(filepath.Join(reportDir, name)); err != nil || !strings.Contains(string(data), "test-crew 运行报告") {
+			t.Errorf("expected run report %s, got %v", name, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := report.Save(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved EvaluationReport
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("invalid report json: %v", err)
+	}
+	if saved.Model != "judge" || len(saved.Tasks) != 2 || saved.Tasks[1].Scores[1] != nil {
+		t.Errorf("unexpected saved report: %s", data)
+	}
+}
+
+func TestProjectEvaluatorAllEvaluationsFail(t *testing.T) {
+	evalLLM := &evaluatorLLM{scriptedLLM: scriptedLLM{model: "judge"}, replies: []string{""}}
+	evaluator, out := newTestProjectEvaluator(t, evalLLM, 1)
+
+	report, err := evaluator.Evaluate(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err == nil || !strings.Contains(err.Error(), "no task output could be evaluated") {
+		t.Fatalf("expected no-score error, got %v", err)
+	}
+	if evalLLM.calls != 2 {
+		t.Errorf("expected both tasks to be evaluated despite failures, got %d calls", evalLLM.calls)
+	}
+	if report.Grade != "-" || !strings.Contains(out.String(), "综合评分: -") {
+		t.Errorf("expected missing grade, got %q:\n%s", report.Grade, out.String())
+	}
+}
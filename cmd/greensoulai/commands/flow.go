@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/pkg/flow"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// defaultScheduleDir flow schedule保存计划记录和执行记录的默认目录，相对项目根目录
+const defaultScheduleDir = ".greensoulai/schedule"
+
+// NewFlowCommand 创建flow命令
+func NewFlowCommand(log logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flow",
+		Short: "Flow项目工具",
+		Long:  "查看Flow项目的作业和触发条件，项目根目录需要有 flow.yaml",
+	}
+
+	var (
+		configPath string
+		format     string
+		outputFile string
+	)
+	graph := &cobra.Command{
+		Use:   "graph",
+		Short: "导出作业依赖图",
+		Long: `按 flow.yaml 中的触发条件导出作业依赖图，支持Mermaid流程图和Graphviz DOT。
+永远不会被触发的作业会被标出；输出顺序固定，可以直接粘贴到PR描述或用于golden测试。
+
+示例：
+  greensoulai flow graph                       # Mermaid流程图
+  greensoulai flow graph --format dot | dot -Tsvg -o flow.svg`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configPath == "" {
+				projectRoot, err := config.GetProjectRoot()
+				if err != nil {
+					return fmt.Errorf("not in a greensoulai project: %w", err)
+				}
+				configPath = filepath.Join(projectRoot, config.FlowConfigFileName)
+			}
+
+			flowConfig, err := config.LoadFlowConfig(configPath)
+			if err != nil {
+				return err
+			}
+			// 无效的配置仍然导出，依赖图正是排查触发条件问题的手段
+			if err := flowConfig.Validate(); err != nil {
+				log.Warn("flow配置无效", logger.Field{Key: "error", Value: err})
+			}
+
+			out := cmd.OutOrStdout()
+			if outputFile != "" {
+				file, err := os.Create(outputFile)
+				if err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				defer file.Close()
+				out = file
+			}
+			return writeFlowGraph(out, cmd.ErrOrStderr(), flowConfig, format)
+		},
+	}
+	graph.Flags().StringVarP(&configPath, "config", "c", "", "flow.yaml路径，默认使用项目根目录中的flow.yaml")
+	graph.Flags().StringVarP(&format, "format", "f", "mermaid", "输出格式：mermaid或dot")
+	graph.Flags().StringVarP(&outputFile, "output", "o", "", "输出文件，默认输出到标准输出")
+
+	cmd.AddCommand(graph)
+	cmd.AddCommand(newFlowScheduleCommand(log))
+	return cmd
+}
+
+// newFlowScheduleCommand 创建flow schedule命令
+func newFlowScheduleCommand(log logger.Logger) *cobra.Command {
+	var (
+		configPath string
+		list       bool
+		count      int
+		runsFile   string
+		stateDir   string
+	)
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "按flow.yaml中的执行计划定时运行工作流",
+		Long: `按 flow.yaml 中 schedules 的执行计划重复运行Flow项目（go run cmd/main.go）。
+每次执行的初始状态为计划的state加上计划名称、计划时间和执行序号，通过FLOW_STATE环境变量传给项目；
+同一计划的执行不会重叠，overlap为skip时跳过、为queue时排队。按Ctrl+C停止，正在进行的执行会先完成。
+
+示例：
+  greensoulai flow schedule --list      # 查看每个计划接下来的执行时间
+  greensoulai flow schedule             # 开始定时执行，执行记录追加到 .greensoulai/schedule/runs.jsonl
+
+flow.yaml：
+  schedules:
+    - name: nightly
+      cron: "30 2 * * *"   # 或 every: 15m
+      overlap: skip
+      catch_up: 3          # 启动时最多补跑3次停止期间错过的执行
+      state:
+        region: eu`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectRoot, err := config.GetProjectRoot()
+			if err != nil && configPath == "" {
+				return fmt.Errorf("not in a greensoulai project: %w", err)
+			}
+			if configPath == "" {
+				configPath = filepath.Join(projectRoot, config.FlowConfigFileName)
+			}
+
+			flowConfig, err := config.LoadFlowConfig(configPath)
+			if err != nil {
+				return err
+			}
+			if err := flowConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid flow config: %w", err)
+			}
+			if len(flowConfig.Schedules) == 0 {
+				return fmt.Errorf("%s has no schedules", configPath)
+			}
+
+			if list {
+				return writeScheduleList(cmd.OutOrStdout(), flowConfig, time.Now(), count)
+			}
+			if projectRoot == "" {
+				projectRoot = filepath.Dir(configPath)
+			}
+			if stateDir == "" {
+				stateDir = filepath.Join(projectRoot, defaultScheduleDir)
+			}
+			if runsFile == "" {
+				runsFile = filepath.Join(stateDir, "runs.jsonl")
+			}
+			return runFlowSchedules(cmd.Context(), flowConfig, projectRoot, stateDir, runsFile, cmd.OutOrStdout(), log)
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "flow.yaml路径，默认使用项目根目录中的flow.yaml")
+	cmd.Flags().BoolVar(&list, "list", false, "只列出每个计划接下来的执行时间")
+	cmd.Flags().IntVar(&count, "count", 3, "--list时每个计划列出的执行次数")
+	cmd.Flags().StringVar(&runsFile, "runs-file", "", "执行记录JSONL文件，默认为<state-dir>/runs.jsonl")
+	cmd.Flags().StringVar(&stateDir, "state-dir", "", "保存每个计划上次执行时间的目录，默认为项目根目录下的"+defaultScheduleDir)
+	return cmd
+}
+
+// writeScheduleList 写出每个计划在now之后的count次执行时间
+func writeScheduleList(out io.Writer, flowConfig *config.FlowConfig, now time.Time, count int) error {
+	for _, sc := range flowConfig.Schedules {
+		schedule, err := sc.FlowSchedule()
+		if err != nil {
+			return fmt.Errorf("schedule %s: %w", sc.Name, err)
+		}
+		policy, _ := sc.OverlapPolicy()
+		fmt.Fprintf(out, "%s (%s, overlap=%s, catch_up=%d)\n", sc.Name, schedule, policy, sc.CatchUp)
+
+		next := now
+		for i := 0; i < count; i++ {
+			if next = schedule.Next(next); next.IsZero() {
+				break
+			}
+			fmt.Fprintf(out, "  %s\n", next.Format("2006-01-02 15:04:05 MST"))
+		}
+	}
+	return nil
+}
+
+// runFlowSchedules 定时运行项目直到收到中断信号，然后等待正在进行的执行完成
+func runFlowSchedules(ctx context.Context, flowConfig *config.FlowConfig, projectRoot, stateDir, runsFile string,
+	out io.Writer, log logger.Logger) error {
+
+	store, err := flow.NewFileScheduleStore(stateDir)
+	if err != nil {
+		return err
+	}
+	runs, err := flow.NewJSONLRunSink(runsFile)
+	if err != nil {
+		return err
+	}
+	defer runs.Close()
+
+	scheduler := flow.NewScheduler(
+		flow.WithRunSink(flow.NewLogRunSink(log)),
+		flow.WithRunSink(runs),
+		flow.WithScheduleStore(store),
+		flow.WithSchedulerLogger(log),
+	)
+	for _, sc := range flowConfig.Schedules {
+		schedule, err := sc.FlowSchedule()
+		if err != nil {
+			return fmt.Errorf("schedule %s: %w", sc.Name, err)
+		}
+		policy, err := sc.OverlapPolicy()
+		if err != nil {
+			return fmt.Errorf("schedule %s: %w", sc.Name, err)
+		}
+		workflow := flow.NewWorkflow(sc.Name, flow.WithLogger(log)).
+			AddJob(newProjectRunJob(projectRoot, out), flow.Immediately())
+		if err := scheduler.Add(sc.Name, workflow, schedule,
+			flow.WithStateTemplate(sc.State), flow.WithOverlapPolicy(policy), flow.WithCatchUp(sc.CatchUp)); err != nil {
+			return err
+		}
+	}
+
+	// 中断信号只停止调度，不取消正在进行的执行
+	if err := scheduler.Start(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "⏰ 已开始定时执行 %d 个计划，执行记录写入 %s，按Ctrl+C停止\n", len(flowConfig.Schedules), runsFile)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	select {
+	case <-interrupts:
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(out, "正在停止，等待进行中的执行完成...")
+	scheduler.Stop()
+	return nil
+}
+
+// newProjectRunJob 运行项目的cmd/main.go，本次执行的状态通过FLOW_STATE环境变量以JSON传入
+func newProjectRunJob(projectRoot string, out io.Writer) flow.Job {
+	return flow.NewStatefulJob("run", func(ctx context.Context, state flow.FlowState) (interface{}, error) {
+		data, err := json.Marshal(state.GetAll())
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal flow state: %w", err)
+		}
+
+		run := exec.CommandContext(ctx, "go", "run", "cmd/main.go")
+		run.Dir = projectRoot
+		run.Env = append(os.Environ(), "FLOW_STATE="+string(data))
+		run.Stdout = out
+		run.Stderr = out
+		if err := run.Run(); err != nil {
+			return nil, fmt.Errorf("flow project run failed: %w", err)
+		}
+		return nil, nil
+	})
+}
+
+// writeFlowGraph 按格式写出依赖图，不可达的作业提示写入warnings
+func writeFlowGraph(out, warnings io.Writer, flowConfig *config.FlowConfig, format string) error {
+	graph := flowConfig.Graph()
+
+	var content string
+	switch strings.ToLower(format) {
+	case "mermaid", "mmd":
+		content = graph.Mermaid()
+	case "dot", "graphviz":
+		content = graph.DOT()
+	default:
+		return fmt.Errorf("unsupported graph format %q: use mermaid or dot", format)
+	}
+
+	if _, err := io.WriteString(out, content); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+	if unreachable := graph.UnreachableJobs(); len(unreachable) > 0 {
+		fmt.Fprintf(warnings, "⚠️  永远不会被触发的作业: %s\n", strings.Join(unreachable, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+)
+
+func TestWriteFlowGraph(t *testing.T) {
+	flowConfig := config.DefaultFlowConfig("demo")
+	// 拼错的依赖让archive永远不会被触发
+	flowConfig.Jobs = append(flowConfig.Jobs, config.FlowJobConfig{
+		ID:      "archive",
+		Trigger: config.TriggerConfig{After: []string{"reprot"}},
+	})
+
+	var out, warnings bytes.Buffer
+	if err := writeFlowGraph(&out, &warnings, flowConfig, "dot"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		`digraph "demo"`,
+		`"fetch_data" -> "analyze" [label="after"]`,
+		`"analyze" -> "report" [label="all-of"]`,
+		`"archive" [label="archive\n(unreachable)"`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, out.String())
+		}
+	}
+	if !strings.Contains(warnings.String(), "archive, reprot") {
+		t.Errorf("expected unreachable jobs in warnings, got %q", warnings.String())
+	}
+
+	out.Reset()
+	if err := writeFlowGraph(&out, &warnings, flowConfig, "mermaid"); err != nil || !strings.HasPrefix(out.String(), "flowchart LR\n") {
+		t.Errorf("expected a mermaid flowchart, got %v:\n%s", err, out.String())
+	}
+	if err := writeFlowGraph(&out, &warnings, flowConfig, "svg"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestWriteScheduleList(t *testing.T) {
+	flowConfig := config.DefaultFlowConfig("demo")
+	flowConfig.Schedules = []config.FlowScheduleConfig{
+		{Name: "nightly", Cron: "30 2 * * *", CatchUp: 2},
+		{Name: "frequent", Every: "15m", Overlap: "queue"},
+	}
+
+	var out bytes.Buffer
+	now := time.Date(2026, 3, 14, 10, 5, 0, 0, time.UTC)
+	if err := writeScheduleList(&out, flowConfig, now, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `nightly (30 2 * * *, overlap=skip, catch_up=2)
+  2026-03-15 02:30:00 UTC
+  2026-03-16 02:30:00 UTC
+frequent (@every 15m0s, overlap=queue, catch_up=0)
+  2026-03-14 10:15:00 UTC
+  2026-03-14 10:30:00 UTC
+`
+	if out.String() != expected {
+		t.Errorf("unexpected schedule list:\n%s", out.String())
+	}
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+)
+
+// errDryRunLLMCall dry run时LLM不会被调用
+var errDryRunLLMCall = errors.New("LLM calls are disabled in dry run")
+
+// dryRunLLM 只携带模型名称的LLM，dry run用它估算token和成本，不需要API密钥，也不会调用模型接口
+type dryRunLLM struct {
+	model string
+}
+
+func (l *dryRunLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	return nil, errDryRunLLMCall
+}
+
+func (l *dryRunLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	return nil, errDryRunLLMCall
+}
+
+func (l *dryRunLLM) GetModel() string                     { return l.model }
+func (l *dryRunLLM) SupportsFunctionCalling() bool        { return true }
+func (l *dryRunLLM) GetContextWindowSize() int            { return 0 }
+func (l *dryRunLLM) GetTokenizer() llm.Tokenizer          { return llm.TokenizerForModel(l.model) }
+func (l *dryRunLLM) SetEventBus(eventBus events.EventBus) {}
+func (l *dryRunLLM) Close() error                         { return nil }
+
+// dryRunLLMFactory 与config.LLMFactory使用相同的模型选择，返回不可调用的dryRunLLM
+func dryRunLLMFactory(cfg config.LLMConfig) func(model string) (llm.LLM, error) {
+	return func(model string) (llm.LLM, error) {
+		if model == "" {
+			model = cfg.Model
+		}
+		return &dryRunLLM{model: model}, nil
+	}
+}
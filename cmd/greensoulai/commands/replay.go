@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// replayDir 返回项目的执行快照目录
+func replayDir(projectRoot string) string {
+	return filepath.Join(projectRoot, crew.DefaultReplayDir)
+}
+
+// NewReplayCommand 创建replay命令
+func NewReplayCommand(log logger.Logger) *cobra.Command {
+	var (
+		taskID     string
+		inputs     []string
+		inputsFile string
+		outputFile string
+		timeout    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay [kickoff-id]",
+		Short: "从某个任务重新执行Crew",
+		Long: `查看greensoulai run记录的执行快照，并从某个任务开始重新执行Crew。
+指定任务之前的任务不再执行，直接使用快照中保存的输出作为上下文。
+输入为原执行的输入，--input 和 --inputs-file 中的同名键覆盖原输入。
+项目的任务描述与原执行不一致时会输出警告，保存的输出可能与当前任务不匹配。
+
+示例：
+  greensoulai replay                                   # 列出记录的执行
+  greensoulai replay <kickoff-id>                      # 列出执行中的任务
+  greensoulai replay <kickoff-id> --task write_report  # 从write_report任务重新执行`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectRoot, err := config.GetProjectRoot()
+			if err != nil {
+				return fmt.Errorf("not in a greensoulai project: %w", err)
+			}
+			store := crew.NewReplayStore(replayDir(projectRoot))
+
+			if len(args) == 0 {
+				return listKickoffs(os.Stdout, store)
+			}
+			if taskID == "" {
+				return listKickoffTasks(os.Stdout, store, args[0])
+			}
+
+			configPath := filepath.Join(projectRoot, "greensoulai.yaml")
+			projectConfig, err := config.ValidateProjectFile(configPath, config.BuiltinTools().Names())
+			if err != nil {
+				return fmt.Errorf("invalid project configuration:\n%w", err)
+			}
+			if projectConfig.Type != config.ProjectTypeCrew {
+				return fmt.Errorf("replay only supports crew projects, got %s", projectConfig.Type)
+			}
+
+			overrides, err := parseInputs(inputs, inputsFile)
+			if err != nil {
+				return err
+			}
+
+			runner := &CrewRunner{
+				Config:      projectConfig,
+				ProjectRoot: projectRoot,
+				NewLLM:      config.LLMFactory(projectConfig.LLM),
+				ReplayDir:   store.Dir(),
+				EventBus:    events.NewEventBus(log),
+				Out:         os.Stdout,
+				Logger:      log,
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			log.Info("重新执行Crew...",
+				logger.Field{Key: "name", Value: projectConfig.Name},
+				logger.Field{Key: "kickoff_id", Value: args[0]},
+				logger.Field{Key: "task", Value: taskID},
+			)
+			startTime := time.Now()
+
+			output, err := runner.Replay(ctx, args[0], taskID, overrides)
+			if err != nil {
+				return err
+			}
+
+			log.Info("Crew执行完成", logger.Field{Key: "duration", Value: time.Since(startTime)})
+			fmt.Printf("\n📄 最终输出:\n%s\n", output.Raw)
+			if kickoffID, ok := output.Metadata["kickoff_id"].(string); ok {
+				fmt.Printf("\n🔁 执行快照: %s\n", kickoffID)
+			}
+
+			if outputFile != "" {
+				if err := os.WriteFile(outputFile, []byte(output.Raw), 0644); err != nil {
+					return fmt.Errorf("failed to write output file: %w", err)
+				}
+				fmt.Printf("\n💾 输出已保存到 %s\n", outputFile)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&taskID, "task", "t", "", "重新执行的起点任务，任务名称或快照中的任务ID")
+	cmd.Flags().StringArrayVarP(&inputs, "input", "i", nil, "覆盖原输入，格式为key=value，可重复指定")
+	cmd.Flags().StringVar(&inputsFile, "inputs-file", "", "JSON格式的输入文件，覆盖原输入")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "最终输出写入的文件")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "执行超时时间")
+
+	return cmd
+}
+
+// listKickoffs 输出记录的执行，最近的排在前面
+func listKickoffs(out io.Writer, store *crew.ReplayStore) error {
+	records, err := store.ListKickoffs()
+	if err != nil {
+		return fmt.Errorf("failed to list kickoffs: %w", err)
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(out, "没有记录的执行（%s）\n", store.Dir())
+		return nil
+	}
+
+	for _, record := range records {
+		line := fmt.Sprintf("%s  %s  %s  %d个任务", record.KickoffID,
+			record.StartedAt.Format("2006-01-02 15:04:05"), record.CrewName, len(record.Tasks))
+		if record.ReplayOf != "" {
+			line += fmt.Sprintf("  (重放自 %s)", record.ReplayOf)
+		}
+		fmt.Fprintln(out, line)
+	}
+	return nil
+}
+
+// listKickoffTasks 输出一次执行中的任务及其快照状态
+func listKickoffTasks(out io.Writer, store *crew.ReplayStore, kickoffID string) error {
+	record, snapshots, err := store.LoadKickoff(kickoffID)
+	if err != nil {
+		return err
+	}
+
+	completed := make(map[int]*crew.TaskSnapshot, len(snapshots))
+	for _, snapshot := range snapshots {
+		completed[snapshot.TaskIndex] = snapshot
+	}
+
+	fmt.Fprintf(out, "%s  %s  %s\n", record.KickoffID, record.StartedAt.Format("2006-01-02 15:04:05"), record.CrewName)
+	for i, task := range record.Tasks {
+		name := task.Name
+		if name == "" {
+			name = task.ID
+		}
+
+		snapshot, ok := completed[i]
+		switch {
+		case !ok:
+			fmt.Fprintf(out, "  %d. %s  未完成\n", i+1, name)
+		case snapshot.Replayed:
+			fmt.Fprintf(out, "  %d. %s  已复用  %s\n", i+1, name, outputPreview(snapshot))
+		default:
+			fmt.Fprintf(out, "  %d. %s  已完成  %s\n", i+1, name, outputPreview(snapshot))
+		}
+	}
+	return nil
+}
+
+// outputPreview 返回任务输出的单行预览
+func outputPreview(snapshot *crew.TaskSnapshot) string {
+	if snapshot.Output == nil {
+		return ""
+	}
+	preview := strings.Join(strings.Fields(snapshot.Output.Raw), " ")
+	if runes := []rune(preview); len(runes) > 60 {
+		preview = string(runes[:60]) + "..."
+	}
+	return preview
+}
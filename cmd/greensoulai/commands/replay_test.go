@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+func TestCrewRunnerReplay(t *testing.T) {
+	runner, _ := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &scriptedLLM{model: "default", reply: "research notes"},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "first article"},
+	})
+	runner.ReplayDir = replayDir(runner.ProjectRoot)
+
+	output, err := runner.Run(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kickoffID, _ := output.Metadata["kickoff_id"].(string)
+	if kickoffID == "" {
+		t.Fatalf("expected kickoff id in output metadata, got %v", output.Metadata)
+	}
+
+	// 重新执行时research任务不再调用LLM
+	replayer, out := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &failingLLM{scriptedLLM{model: "default"}},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "second article"},
+	})
+	replayer.ReplayDir = runner.ReplayDir
+
+	output, err = replayer.Replay(context.Background(), kickoffID, "write", map[string]interface{}{"topic": "Rust"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if aggregated := output.AggregateRaw("\n"); aggregated != "research notes\nsecond article" || output.Raw != "second article" {
+		t.Errorf("expected stored research output and new article, got %q", aggregated)
+	}
+	if !strings.Contains(out.String(), "[1/2] research 使用已保存的输出") || !strings.Contains(out.String(), "[2/2] write 完成") {
+		t.Errorf("unexpected progress:\n%s", out.String())
+	}
+
+	store := crew.NewReplayStore(runner.ReplayDir)
+	var list bytes.Buffer
+	if err := listKickoffs(&list, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(list.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "重放自 "+kickoffID) {
+		t.Errorf("expected the replay to be listed first, got:\n%s", list.String())
+	}
+
+	var tasks bytes.Buffer
+	if err := listKickoffTasks(&tasks, store, kickoffID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"1. research  已完成  research notes", "2. write  已完成  first article"} {
+		if !strings.Contains(tasks.String(), want) {
+			t.Errorf("expected %q in task list, got:\n%s", want, tasks.String())
+		}
+	}
+}
+
+func TestListKickoffsEmpty(t *testing.T) {
+	var out bytes.Buffer
+	if err := listKickoffs(&out, crew.NewReplayStore(t.TempDir())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "没有记录的执行") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
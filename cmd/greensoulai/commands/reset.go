@@ -0,0 +1,371 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/internal/memory/storage"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// MemoryKind 存储目录中的一类记忆数据
+type MemoryKind struct {
+	Name  string   // 对应的命令行选项名
+	Label string   // 显示名称
+	Paths []string // 相对存储目录的文件或目录
+}
+
+// MemoryKinds 存储目录中各类记忆数据的布局
+var MemoryKinds = []MemoryKind{
+	{Name: "long-term", Label: "长期记忆（包括Crew记忆）", Paths: []string{
+		storage.LTMDBFileName, storage.LTMDBFileName + "-wal", storage.LTMDBFileName + "-shm",
+	}},
+	{Name: "short-term", Label: "短期记忆", Paths: []string{"short_term"}},
+	{Name: "entities", Label: "实体记忆", Paths: []string{"entities"}},
+	{Name: "knowledge", Label: "知识库", Paths: []string{"knowledge"}},
+	{Name: "embedding-cache", Label: "嵌入缓存", Paths: []string{
+		storage.EmbeddingCacheDBFileName, storage.EmbeddingCacheDBFileName + "-wal", storage.EmbeddingCacheDBFileName + "-shm",
+	}},
+	{Name: "sessions", Label: "会话记忆", Paths: []string{
+		storage.SessionDBFileName, storage.SessionDBFileName + "-wal", storage.SessionDBFileName + "-shm",
+	}},
+}
+
+// memoryEntry 存储目录中找到的一项记忆数据
+type memoryEntry struct {
+	kind    MemoryKind
+	path    string
+	size    int64
+	files   int // 目录中的文件数
+	records int // 记录数，-1表示未知
+}
+
+// NewResetCommand 创建reset-memories命令
+func NewResetCommand(log logger.Logger) *cobra.Command {
+	var (
+		storageDir string
+		sessionID  string
+		force      bool
+		selected   = make(map[string]*bool, len(MemoryKinds))
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reset-memories",
+		Short: "重置智能体记忆",
+		Long: `重置当前项目中智能体的记忆数据。
+默认删除所有记忆，使用 --long-term、--short-term、--entities、--knowledge、--embedding-cache、--sessions 只删除指定的部分；
+使用 --session <id> 只删除一个会话的对话记录。
+
+存储目录按以下顺序确定：--storage-dir、GREENSOULAI_STORAGE_DIR 环境变量、
+greensoulai.yaml 中的 memory.storage_dir（相对项目根目录），默认为项目根目录下的 ./data。
+存储目录必须位于项目目录内。`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectRoot, projectConfig, err := loadResetProject()
+			if err != nil {
+				return err
+			}
+
+			dir, err := memoryStorageDir(projectRoot, projectConfig, storageDir)
+			if err != nil {
+				return err
+			}
+
+			var kinds []MemoryKind
+			for _, kind := range MemoryKinds {
+				if *selected[kind.Name] {
+					kinds = append(kinds, kind)
+				}
+			}
+
+			if sessionID != "" {
+				if len(kinds) > 0 {
+					return fmt.Errorf("--session cannot be combined with other memory selections")
+				}
+				return resetSession(cmd.Context(), projectRoot, dir, sessionID, force, cmd.InOrStdin(), cmd.OutOrStdout())
+			}
+			if len(kinds) == 0 {
+				kinds = MemoryKinds
+			}
+
+			log.Info("开始重置智能体记忆...", logger.Field{Key: "storage_dir", Value: dir})
+			removed, err := resetMemories(cmd.Context(), projectRoot, dir, kinds, force,
+				cmd.InOrStdin(), cmd.OutOrStdout(), log)
+			if err != nil {
+				return err
+			}
+
+			log.Info("智能体记忆重置完成",
+				logger.Field{Key: "storage_dir", Value: dir},
+				logger.Field{Key: "removed", Value: len(removed)},
+			)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&storageDir, "storage-dir", "", "记忆数据存储目录")
+	cmd.Flags().StringVar(&sessionID, "session", "", "只删除指定ID的会话")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "不提示确认直接删除")
+	for _, kind := range MemoryKinds {
+		selected[kind.Name] = cmd.Flags().Bool(kind.Name, false, "只重置"+kind.Label)
+	}
+
+	return cmd
+}
+
+// loadResetProject 返回项目根目录和配置，不在项目中时使用当前目录且配置为nil
+func loadResetProject() (string, *config.ProjectConfig, error) {
+	projectRoot, err := config.GetProjectRoot()
+	if err != nil {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get current directory: %w", err)
+		}
+		return cwd, nil, nil
+	}
+
+	projectConfig, err := config.LoadProjectConfig(filepath.Join(projectRoot, "greensoulai.yaml"))
+	if err != nil {
+		return "", nil, err
+	}
+	return projectRoot, projectConfig, nil
+}
+
+// memoryStorageDir 确定记忆存储目录的绝对路径
+// 优先级：override、GREENSOULAI_STORAGE_DIR、项目配置、默认目录；后两者相对项目根目录
+func memoryStorageDir(projectRoot string, projectConfig *config.ProjectConfig, override string) (string, error) {
+	dir := override
+	if dir == "" {
+		dir = os.Getenv(memory.StorageDirEnv)
+	}
+	if dir == "" {
+		dir = memory.DefaultStorageDir
+		if projectConfig != nil && projectConfig.Memory.StorageDir != "" {
+			dir = projectConfig.Memory.StorageDir
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(projectRoot, dir)
+		}
+	}
+	return filepath.Abs(dir)
+}
+
+// checkInsideProject 拒绝项目目录之外的路径（解析符号链接后比较）
+func checkInsideProject(projectRoot, path string) error {
+	root, err := filepath.EvalSymlinks(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing to reset memories in %s: storage directory must be inside the project directory %s", path, projectRoot)
+	}
+	return nil
+}
+
+// scanMemories 列出存储目录中属于kinds的记忆数据
+func scanMemories(ctx context.Context, dir string, kinds []MemoryKind, log logger.Logger) ([]memoryEntry, error) {
+	var entries []memoryEntry
+	for _, kind := range kinds {
+		for _, name := range kind.Paths {
+			path := filepath.Join(dir, name)
+			info, err := os.Lstat(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to inspect %s: %w", path, err)
+			}
+
+			entry := memoryEntry{kind: kind, path: path, size: info.Size(), files: 1, records: -1}
+			if info.IsDir() {
+				entry.size, entry.files, err = dirUsage(path)
+				if err != nil {
+					return nil, fmt.Errorf("failed to inspect %s: %w", path, err)
+				}
+			} else if name == storage.LTMDBFileName {
+				entry.records = countLTMRecords(ctx, path, log)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// countLTMRecords 统计长期记忆数据库中的记录数，失败时返回-1
+func countLTMRecords(ctx context.Context, path string, log logger.Logger) int {
+	ltm := storage.NewLTMSQLiteStorage(path, log)
+	defer ltm.Close()
+
+	count, err := ltm.CountWithFilter(ctx, nil)
+	if err != nil {
+		log.Warn("无法统计长期记忆记录数",
+			logger.Field{Key: "path", Value: path},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+		return -1
+	}
+	return count
+}
+
+// dirUsage 返回目录中文件的总大小和数量
+func dirUsage(dir string) (int64, int, error) {
+	var size int64
+	var files int
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		files++
+		return nil
+	})
+	return size, files, err
+}
+
+// resetMemories 列出并删除存储目录中的记忆数据，force为false时先请求确认
+// 返回实际删除的路径
+func resetMemories(ctx context.Context, projectRoot, dir string, kinds []MemoryKind, force bool,
+	in io.Reader, out io.Writer, log logger.Logger) ([]string, error) {
+
+	fmt.Fprintf(out, "🧠 GreenSoulAI 记忆重置\n📁 存储目录: %s\n\n", dir)
+
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(out, "ℹ️  存储目录不存在，没有需要重置的记忆")
+		return nil, nil
+	}
+	if err := checkInsideProject(projectRoot, dir); err != nil {
+		return nil, err
+	}
+
+	entries, err := scanMemories(ctx, dir, kinds, log)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "ℹ️  没有找到需要重置的记忆数据")
+		return nil, nil
+	}
+
+	fmt.Fprintln(out, "📋 找到以下记忆数据:")
+	for _, entry := range entries {
+		fmt.Fprintf(out, "  • %s: %s (%s", entry.kind.Label, entry.path, formatSize(entry.size))
+		if entry.records >= 0 {
+			fmt.Fprintf(out, ", %d 条记录", entry.records)
+		} else if entry.files != 1 {
+			fmt.Fprintf(out, ", %d 个文件", entry.files)
+		}
+		fmt.Fprintln(out, ")")
+	}
+
+	if !force && !confirmReset(in, out, "\n⚠️  确认删除以上数据？此操作不可恢复 [y/N]: ") {
+		return nil, nil
+	}
+
+	var removed []string
+	for _, entry := range entries {
+		if err := os.RemoveAll(entry.path); err != nil {
+			reportRemoved(out, removed)
+			return removed, fmt.Errorf("failed to remove %s: %w", entry.path, err)
+		}
+		removed = append(removed, entry.path)
+	}
+	reportRemoved(out, removed)
+	fmt.Fprintln(out, "\n✅ 记忆重置完成！")
+	return removed, nil
+}
+
+// confirmReset 显示提示并读取确认，未确认时输出取消提示
+func confirmReset(in io.Reader, out io.Writer, prompt string) bool {
+	fmt.Fprint(out, prompt)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		fmt.Fprintln(out, "已取消，未删除任何数据")
+		return false
+	}
+	return true
+}
+
+// resetSession 从会话数据库中删除一个会话，其他会话和记忆数据保持不变
+func resetSession(ctx context.Context, projectRoot, dir, sessionID string, force bool, in io.Reader, out io.Writer) error {
+	fmt.Fprintf(out, "🧠 GreenSoulAI 会话重置\n📁 存储目录: %s\n\n", dir)
+
+	dbPath := filepath.Join(dir, storage.SessionDBFileName)
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(out, "ℹ️  没有找到会话 %s\n", sessionID)
+		return nil
+	}
+	if err := checkInsideProject(projectRoot, dir); err != nil {
+		return err
+	}
+
+	store, err := storage.NewSQLiteSessionStore(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	state, ok, err := store.Load(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintf(out, "ℹ️  没有找到会话 %s\n", sessionID)
+		return nil
+	}
+	fmt.Fprintf(out, "📋 会话 %s: %d 轮对话，最后更新于 %s\n", sessionID, len(state.Turns), state.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
+
+	if !force && !confirmReset(in, out, "\n⚠️  确认删除该会话？此操作不可恢复 [y/N]: ") {
+		return nil
+	}
+	if err := store.Delete(ctx, sessionID); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n✅ 会话 %s 已删除\n", sessionID)
+	return nil
+}
+
+func reportRemoved(out io.Writer, removed []string) {
+	if len(removed) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\n🗑️  已删除:")
+	for _, path := range removed {
+		fmt.Fprintf(out, "  • %s\n", path)
+	}
+}
+
+// formatSize 以易读的单位格式化字节数
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
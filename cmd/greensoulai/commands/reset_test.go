@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/internal/memory/storage"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// newTestMemoryDir 创建包含长期记忆数据库和实体记忆目录的存储目录
+func newTestMemoryDir(t *testing.T, root string) string {
+	t.Helper()
+	log := logger.NewTestLogger()
+	dir := filepath.Join(root, "data")
+
+	ltm := storage.NewLTMSQLiteStorage(filepath.Join(dir, storage.LTMDBFileName), log)
+	for _, value := range []string{"first", "second"} {
+		if err := ltm.Save(context.Background(), memory.MemoryItem{ID: value, Value: value}); err != nil {
+			t.Fatalf("failed to save memory: %v", err)
+		}
+	}
+	ltm.Close()
+
+	entities := filepath.Join(dir, "entities")
+	if err := os.MkdirAll(entities, 0755); err != nil {
+		t.Fatalf("failed to create entities dir: %v", err)
+	}
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(entities, name), []byte("{}"), 0644); err != nil {
+			t.Fatalf("failed to write entity file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestMemoryStorageDir(t *testing.T) {
+	t.Setenv(memory.StorageDirEnv, "")
+	root := t.TempDir()
+
+	dir, _ := memoryStorageDir(root, nil, "")
+	if dir != filepath.Join(root, memory.DefaultStorageDir) {
+		t.Errorf("expected default storage dir, got %s", dir)
+	}
+
+	projectConfig := &config.ProjectConfig{Memory: config.MemoryConfig{StorageDir: ".greensoulai/memory"}}
+	dir, _ = memoryStorageDir(root, projectConfig, "")
+	if dir != filepath.Join(root, ".greensoulai", "memory") {
+		t.Errorf("expected configured storage dir, got %s", dir)
+	}
+
+	override := filepath.Join(root, "other")
+	dir, _ = memoryStorageDir(root, projectConfig, override)
+	if dir != override {
+		t.Errorf("expected override storage dir, got %s", dir)
+	}
+}
+
+func TestResetMemories(t *testing.T) {
+	root := t.TempDir()
+	dir := newTestMemoryDir(t, root)
+	log := logger.NewTestLogger()
+	out := &bytes.Buffer{}
+
+	// 未确认时不删除
+	removed, err := resetMemories(context.Background(), root, dir, MemoryKinds, false, strings.NewReader("n\n"), out, log)
+	if err != nil || len(removed) != 0 {
+		t.Fatalf("expected nothing removed without confirmation, got %v, %v", removed, err)
+	}
+	listing := out.String()
+	if !strings.Contains(listing, "2 条记录") || !strings.Contains(listing, "2 个文件") {
+		t.Errorf("expected record and file counts in listing, got:\n%s", listing)
+	}
+
+	// 只重置实体记忆
+	out.Reset()
+	kinds := []MemoryKind{MemoryKinds[2]}
+	removed, err = resetMemories(context.Background(), root, dir, kinds, false, strings.NewReader("y\n"), out, log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != filepath.Join(dir, "entities") {
+		t.Errorf("expected only entities removed, got %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, storage.LTMDBFileName)); err != nil {
+		t.Errorf("expected long-term memory to be kept: %v", err)
+	}
+
+	// --force 删除剩余数据
+	removed, err = resetMemories(context.Background(), root, dir, MemoryKinds, true, strings.NewReader(""), out, log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) == 0 || removed[0] != filepath.Join(dir, storage.LTMDBFileName) {
+		t.Errorf("expected long-term memory removed, got %v", removed)
+	}
+
+	out.Reset()
+	if removed, err := resetMemories(context.Background(), root, dir, MemoryKinds, true, nil, out, log); err != nil || removed != nil {
+		t.Errorf("expected nothing to reset, got %v, %v", removed, err)
+	}
+	if !strings.Contains(out.String(), "没有找到需要重置的记忆数据") {
+		t.Errorf("expected nothing-to-reset message, got:\n%s", out.String())
+	}
+}
+
+func TestResetMemoriesMissingDir(t *testing.T) {
+	root := t.TempDir()
+	out := &bytes.Buffer{}
+
+	removed, err := resetMemories(context.Background(), root, filepath.Join(root, "data"), MemoryKinds, true, nil, out, logger.NewTestLogger())
+	if err != nil || removed != nil {
+		t.Fatalf("expected missing dir to be handled, got %v, %v", removed, err)
+	}
+	if !strings.Contains(out.String(), "存储目录不存在") {
+		t.Errorf("expected missing dir message, got:\n%s", out.String())
+	}
+}
+
+func TestResetMemoriesOutsideProject(t *testing.T) {
+	root := t.TempDir()
+	dir := newTestMemoryDir(t, t.TempDir())
+
+	_, err := resetMemories(context.Background(), root, dir, MemoryKinds, true, nil, &bytes.Buffer{}, logger.NewTestLogger())
+	if err == nil || !strings.Contains(err.Error(), "must be inside the project directory") {
+		t.Fatalf("expected refusal for storage outside project, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, storage.LTMDBFileName)); err != nil {
+		t.Errorf("expected data outside project to be kept: %v", err)
+	}
+}
+
+func TestResetSession(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "data")
+	ctx := context.Background()
+
+	store, err := storage.NewSQLiteSessionStore(filepath.Join(dir, storage.SessionDBFileName))
+	if err != nil {
+		t.Fatalf("failed to open session store: %v", err)
+	}
+	sessions := memory.NewSessionMemory(&memory.SessionMemoryConfig{Store: store})
+	for _, id := range []string{"alice", "bob"} {
+		if _, err := sessions.Append(ctx, id, "Hello", "Hi "+id); err != nil {
+			t.Fatalf("failed to append turn: %v", err)
+		}
+	}
+	sessions.Close()
+
+	out := &bytes.Buffer{}
+	if err := resetSession(ctx, root, dir, "alice", false, strings.NewReader("n\n"), out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "1 轮对话") || !strings.Contains(out.String(), "已取消") {
+		t.Errorf("expected session listing and cancellation, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := resetSession(ctx, root, dir, "alice", true, nil, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := resetSession(ctx, root, dir, "alice", true, nil, out); err != nil || !strings.Contains(out.String(), "没有找到会话 alice") {
+		t.Errorf("expected the session to be gone, got %v:\n%s", err, out.String())
+	}
+
+	// 其他会话保留
+	store, err = storage.NewSQLiteSessionStore(filepath.Join(dir, storage.SessionDBFileName))
+	if err != nil {
+		t.Fatalf("failed to open session store: %v", err)
+	}
+	defer store.Close()
+	ids, err := store.List(ctx)
+	if err != nil || len(ids) != 1 || ids[0] != "bob" {
+		t.Errorf("expected only bob to remain, got %v, %v", ids, err)
+	}
+}
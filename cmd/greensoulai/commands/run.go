@@ -3,34 +3,56 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/internal/cli/config"
 	"github.com/ynl/greensoulai/internal/cli/utils"
+	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
 // NewRunCommand 创建run命令
 func NewRunCommand(log logger.Logger) *cobra.Command {
 	var (
-		configPath  string
-		verbose     bool
-		inputFile   string
-		outputDir   string
-		timeout     time.Duration
-		iterations  int
-		development bool
+		configPath   string
+		verbose      bool
+		inputs       []string
+		inputsFile   string
+		outputFile   string
+		timeout      time.Duration
+		iterations   int
+		development  bool
+		compiled     bool
+		trainingFile string
+		dryRun       bool
+		reportPath   string
+		seed         int
+		strict       bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "运行GreenSoulAI项目",
 		Long: `运行当前目录的GreenSoulAI项目。
-会自动检测项目类型（Crew或Flow）并执行相应的运行逻辑。`,
+Crew项目默认直接解释执行greensoulai.yaml：按agents和tasks配置构建智能体、任务和Crew并启动，
+使用 --compiled 改为编译运行项目生成的Go代码（项目使用自定义工具时需要）。
+使用 --dry-run 只校验配置并估算每个任务的token和成本，不调用LLM；有错误时以非零状态退出，可用于CI。
+使用 --report 在执行结束后写入运行报告（每个任务的智能体、耗时、token、成本、工具和输出），.html为HTML格式，其余为Markdown。
+使用 --seed 为LLM调用设置种子，每个任务使用由种子派生的种子，相同种子和输入的运行可以复现（需要模型支持seed参数）；
+--strict-reproducibility 把温度固定为0，并在非确定性工具、并列的记忆检索结果等破坏确定性时发出警告。
+
+示例：
+  greensoulai run --input topic=AI --input year=2025
+  greensoulai run --inputs-file inputs.json --output report.md
+  greensoulai run --input topic=AI --report run-report.html
+  greensoulai run --input topic=AI --seed 42 --strict-reproducibility
+  greensoulai run --dry-run --input topic=AI`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// 查找项目根目录
 			projectRoot, err := config.GetProjectRoot()
@@ -43,15 +65,14 @@ func NewRunCommand(log logger.Logger) *cobra.Command {
 				configPath = filepath.Join(projectRoot, "greensoulai.yaml")
 			}
 
-			// 加载项目配置
-			projectConfig, err := config.LoadProjectConfig(configPath)
-			if err != nil {
-				return fmt.Errorf("failed to load project config: %w", err)
+			// 加载并验证项目配置，解释执行时只能使用内置工具
+			var knownTools []string
+			if !compiled {
+				knownTools = config.BuiltinTools().Names()
 			}
-
-			// 验证配置
-			if err := projectConfig.Validate(); err != nil {
-				return fmt.Errorf("invalid project configuration: %w", err)
+			projectConfig, err := config.ValidateProjectFile(configPath, knownTools)
+			if err != nil {
+				return fmt.Errorf("invalid project configuration:\n%w", err)
 			}
 
 			log.Info("运行GreenSoulAI项目",
@@ -60,14 +81,40 @@ func NewRunCommand(log logger.Logger) *cobra.Command {
 				logger.Field{Key: "root", Value: projectRoot},
 			)
 
+			if compiled || dryRun || projectConfig.Type != config.ProjectTypeCrew {
+				for _, flag := range []string{"report", "seed", "strict-reproducibility"} {
+					if cmd.Flags().Changed(flag) {
+						return fmt.Errorf("--%s is only supported when interpreting crew projects", flag)
+					}
+				}
+			}
+			reproducibility := agent.ReproducibilityConfig{Strict: strict}
+			if cmd.Flags().Changed("seed") {
+				reproducibility.Seed = &seed
+			}
+
 			// 根据项目类型执行不同的运行逻辑
 			switch projectConfig.Type {
 			case config.ProjectTypeCrew:
+				if dryRun {
+					if compiled {
+						return fmt.Errorf("--dry-run interprets the project configuration and cannot be combined with --compiled")
+					}
+					return dryRunCrewProject(cmd.Context(), cmd.OutOrStdout(), projectConfig, projectRoot,
+						inputs, inputsFile, trainingFile, log)
+				}
+				if compiled {
+					return runCompiledCrewProject(cmd.Context(), projectConfig, projectRoot,
+						verbose, inputsFile, outputFile, timeout, log)
+				}
 				return runCrewProject(cmd.Context(), projectConfig, projectRoot,
-					verbose, inputFile, outputDir, timeout, development, log)
+					inputs, inputsFile, outputFile, trainingFile, reportPath, reproducibility, timeout, log)
 			case config.ProjectTypeFlow:
+				if dryRun {
+					return fmt.Errorf("--dry-run is only supported for crew projects")
+				}
 				return runFlowProject(cmd.Context(), projectConfig, projectRoot,
-					verbose, inputFile, outputDir, timeout, development, log)
+					verbose, inputsFile, outputFile, timeout, development, log)
 			default:
 				return fmt.Errorf("unsupported project type: %s", projectConfig.Type)
 			}
@@ -77,19 +124,107 @@ func NewRunCommand(log logger.Logger) *cobra.Command {
 	// 添加选项
 	cmd.Flags().StringVarP(&configPath, "config", "c", "", "配置文件路径")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "详细输出模式")
-	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "输入文件路径")
-	cmd.Flags().StringVarP(&outputDir, "output", "o", "", "输出目录")
+	cmd.Flags().StringArrayVarP(&inputs, "input", "i", nil, "Crew输入，格式为key=value，可重复指定")
+	cmd.Flags().StringVar(&inputsFile, "inputs-file", "", "JSON格式的输入文件")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "最终输出写入的文件")
 	cmd.Flags().DurationVarP(&timeout, "timeout", "t", 30*time.Minute, "执行超时时间")
 	cmd.Flags().IntVarP(&iterations, "iterations", "n", 1, "执行迭代次数")
 	cmd.Flags().BoolVarP(&development, "dev", "d", false, "开发模式（启用热重载）")
+	cmd.Flags().BoolVar(&compiled, "compiled", false, "编译运行项目的Go代码而不是解释执行配置")
+	cmd.Flags().StringVar(&trainingFile, "training-file", "", "greensoulai train生成的训练数据文件，把其中的改进指令应用到智能体")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "只校验配置并估算成本，不调用LLM")
+	cmd.Flags().StringVar(&reportPath, "report", "", "运行报告输出文件，.html为HTML格式，其余为Markdown")
+	cmd.Flags().IntVar(&seed, "seed", 0, "LLM调用的随机种子，相同种子和输入的运行可以复现")
+	cmd.Flags().BoolVar(&strict, "strict-reproducibility", false, "严格可复现模式：温度固定为0，破坏确定性时发出警告")
 
 	return cmd
 }
 
-// runCrewProject 运行Crew项目
-func runCrewProject(ctx context.Context, config *config.ProjectConfig,
-	projectRoot string, verbose bool, inputFile, outputDir string,
-	timeout time.Duration, development bool, log logger.Logger) error {
+// runCrewProject 解释执行Crew项目配置
+func runCrewProject(ctx context.Context, projectConfig *config.ProjectConfig,
+	projectRoot string, inputPairs []string, inputsFile, outputFile, trainingFile, reportPath string,
+	reproducibility agent.ReproducibilityConfig, timeout time.Duration, log logger.Logger) error {
+
+	inputs, err := parseInputs(inputPairs, inputsFile)
+	if err != nil {
+		return err
+	}
+
+	runner := &CrewRunner{
+		Config:       projectConfig,
+		ProjectRoot:  projectRoot,
+		NewLLM:       config.LLMFactory(projectConfig.LLM),
+		TrainingFile: trainingFile,
+		ReplayDir:    replayDir(projectRoot),
+		ReportPath:   reportPath,
+		EventBus:     events.NewEventBus(log),
+		Out:          os.Stdout,
+		Logger:       log,
+
+		Reproducibility: reproducibility,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Info("启动Crew执行...", logger.Field{Key: "name", Value: projectConfig.Name})
+	startTime := time.Now()
+
+	output, err := runner.Run(ctx, inputs)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Crew执行完成", logger.Field{Key: "duration", Value: time.Since(startTime)})
+	fmt.Printf("\n📄 最终输出:\n%s\n", output.Raw)
+	if kickoffID, ok := output.Metadata["kickoff_id"].(string); ok {
+		fmt.Printf("\n🔁 执行快照: %s（使用 greensoulai replay %s --task <任务> 从某个任务重新执行）\n", kickoffID, kickoffID)
+	}
+	if seed, ok := output.Metadata["random_seed"].(int); ok {
+		fmt.Printf("\n🎲 种子: %d（使用 --seed %d 重新运行）\n", seed, seed)
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(output.Raw), 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Printf("\n💾 输出已保存到 %s\n", outputFile)
+	}
+
+	return nil
+}
+
+// dryRunCrewProject 校验Crew项目配置并输出成本估算表，有校验错误时返回错误
+func dryRunCrewProject(ctx context.Context, out io.Writer, projectConfig *config.ProjectConfig,
+	projectRoot string, inputPairs []string, inputsFile, trainingFile string, log logger.Logger) error {
+
+	inputs, err := parseInputs(inputPairs, inputsFile)
+	if err != nil {
+		return err
+	}
+
+	runner := &CrewRunner{
+		Config:       projectConfig,
+		ProjectRoot:  projectRoot,
+		NewLLM:       dryRunLLMFactory(projectConfig.LLM),
+		TrainingFile: trainingFile,
+		EventBus:     events.NewEventBus(log),
+		Out:          out,
+		Logger:       log,
+	}
+
+	report, err := runner.DryRun(ctx, inputs)
+	if err != nil {
+		return err
+	}
+	report.PrintTable(out)
+	return report.Err()
+}
+
+// runCompiledCrewProject 编译运行项目生成的Go代码
+func runCompiledCrewProject(ctx context.Context, config *config.ProjectConfig,
+	projectRoot string, verbose bool, inputsFile, outputFile string,
+	timeout time.Duration, log logger.Logger) error {
 
 	log.Info("运行Crew项目", logger.Field{Key: "name", Value: config.Name})
 
@@ -152,11 +287,11 @@ func runCrewProject(ctx context.Context, config *config.ProjectConfig,
 	}
 
 	// 设置输入输出
-	if inputFile != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("INPUT_FILE=%s", inputFile))
+	if inputsFile != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("INPUT_FILE=%s", inputsFile))
 	}
-	if outputDir != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("OUTPUT_DIR=%s", outputDir))
+	if outputFile != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("OUTPUT_FILE=%s", outputFile))
 	}
 
 	// 设置标准输入输出
@@ -184,7 +319,7 @@ func runCrewProject(ctx context.Context, config *config.ProjectConfig,
 
 // runFlowProject 运行Flow项目
 func runFlowProject(ctx context.Context, config *config.ProjectConfig,
-	projectRoot string, verbose bool, inputFile, outputDir string,
+	projectRoot string, verbose bool, inputsFile, outputFile string,
 	timeout time.Duration, development bool, log logger.Logger) error {
 
 	log.Info("运行Flow项目", logger.Field{Key: "name", Value: config.Name})
@@ -226,6 +361,10 @@ func checkEnvironmentVariables(config *config.ProjectConfig, log logger.Logger)
 		requiredEnvVars["ANTHROPIC_API_KEY"] = "Anthropic API密钥"
 	case "openrouter":
 		requiredEnvVars["OPENROUTER_API_KEY"] = "OpenRouter API密钥"
+	case "gemini":
+		requiredEnvVars["GEMINI_API_KEY"] = "Google Gemini API密钥"
+	case "ollama":
+		// 本地Ollama模型无需API密钥
 	}
 
 	var missingVars []string
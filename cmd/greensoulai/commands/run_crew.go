@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// parseInputs 合并输入文件（JSON对象）和key=value参数，参数覆盖文件中的同名键
+func parseInputs(pairs []string, inputsFile string) (map[string]interface{}, error) {
+	inputs := make(map[string]interface{})
+
+	if inputsFile != "" {
+		data, err := os.ReadFile(inputsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read inputs file: %w", err)
+		}
+		if err := json.Unmarshal(data, &inputs); err != nil {
+			return nil, fmt.Errorf("inputs file %s must contain a JSON object: %w", inputsFile, err)
+		}
+	}
+
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid input %q, expected key=value", pair)
+		}
+		inputs[strings.TrimSpace(key)] = value
+	}
+
+	return inputs, nil
+}
+
+// CrewRunner 解释执行项目配置：按配置构建Agent、任务和Crew并启动
+type CrewRunner struct {
+	Config       *config.ProjectConfig
+	ProjectRoot  string
+	NewLLM       func(model string) (llm.LLM, error) // 空模型名表示项目默认模型
+	TrainingFile string                              // 训练数据文件，非空时把其中的改进指令应用到Agent
+	ReplayDir    string                              // 执行快照目录，非空时记录每个任务的快照，供greensoulai replay使用
+	ReportPath   string                              // 运行报告文件，非空时在每次执行结束后写入，.html为HTML格式，其余为Markdown
+	EventBus     events.EventBus
+	Out          io.Writer
+	Logger       logger.Logger
+
+	Reproducibility agent.ReproducibilityConfig // Seed作为Crew的RandomSeed派生每个任务的种子，Strict开启严格可复现模式
+}
+
+// taskFailure 任务级错误
+type taskFailure struct {
+	index int
+	agent string
+	err   string
+}
+
+// Build 按配置构建Crew
+func (r *CrewRunner) Build() (crew.Crew, error) {
+	crewConfig := crew.DefaultCrewConfig()
+	crewConfig.OutputDir = r.ProjectRoot
+	crewConfig.ReplayEnabled = r.ReplayDir != ""
+	crewConfig.ReplayDir = r.ReplayDir
+	crewConfig.RandomSeed = r.Reproducibility.Seed
+	crewConfig.StrictReproducibility = r.Reproducibility.Strict
+
+	c, err := config.BuildCrew(r.Config, config.CrewDeps{
+		NewLLM:     r.NewLLM,
+		EventBus:   r.EventBus,
+		Logger:     r.Logger,
+		CrewConfig: crewConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.TrainingFile != "" {
+		if err := c.LoadTraining(r.TrainingFile); err != nil {
+			return nil, fmt.Errorf("failed to load training file: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// Run 构建并启动Crew，每个任务开始和结束时输出一行进度
+// Crew失败时返回的错误包含各任务的错误汇总
+func (r *CrewRunner) Run(ctx context.Context, inputs map[string]interface{}) (*crew.CrewOutput, error) {
+	c, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	return r.Kickoff(ctx, c, inputs)
+}
+
+// DryRun 构建Crew并在不调用LLM的情况下校验配置、估算成本
+// 使用dryRunLLMFactory构建时不需要API密钥，也不会发出任何网络请求
+func (r *CrewRunner) DryRun(ctx context.Context, inputs map[string]interface{}) (*crew.ValidationReport, error) {
+	c, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	return c.Validate(ctx, inputs)
+}
+
+// Kickoff 启动已构建的Crew，每个任务开始和结束时输出一行进度，执行期间收到SIGUSR1（Unix）时输出Crew的当前状态
+func (r *CrewRunner) Kickoff(ctx context.Context, c crew.Crew, inputs map[string]interface{}) (*crew.CrewOutput, error) {
+	defer watchStatusSignal(r.Out, c)()
+	return r.execute(ctx, func(ctx context.Context) (<-chan crew.CrewProgress, error) {
+		return c.KickoffWithProgress(ctx, inputs)
+	})
+}
+
+// Replay 构建Crew并从记录的Kickoff中的任务开始重新执行，之前的任务复用保存的输出
+func (r *CrewRunner) Replay(ctx context.Context, kickoffID, taskID string, overrides map[string]interface{}) (*crew.CrewOutput, error) {
+	c, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	defer watchStatusSignal(r.Out, c)()
+
+	return r.execute(ctx, func(ctx context.Context) (<-chan crew.CrewProgress, error) {
+		return c.ReplayFromWithProgress(ctx, kickoffID, taskID, overrides)
+	})
+}
+
+// execute 启动执行并按进度通道输出任务进度，失败时返回各任务的错误汇总
+// 设置了ReportPath时，执行结束后（包括失败）把结果写成运行报告
+func (r *CrewRunner) execute(ctx context.Context, start func(ctx context.Context) (<-chan crew.CrewProgress, error)) (*crew.CrewOutput, error) {
+	var recorder *crew.ReportRecorder
+	if r.ReportPath != "" {
+		var err error
+		if recorder, err = crew.NewReportRecorder(r.EventBus); err != nil {
+			return nil, err
+		}
+		defer recorder.Close()
+	}
+
+	startTime := time.Now()
+	progress, err := start(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []taskFailure
+	for p := range progress {
+		label := fmt.Sprintf("[%d/%d] %s", p.TaskIndex+1, p.Total, r.taskName(p.TaskIndex))
+		switch p.Type {
+		case crew.ProgressTaskStarted:
+			fmt.Fprintf(r.Out, "▶️  %s 开始 (%s)\n", label, p.AgentRole)
+		case crew.ProgressTaskCompleted:
+			fmt.Fprintf(r.Out, "✅ %s 完成 (%dms, %.0f%%)\n", label, p.Duration.Milliseconds(), p.Percent)
+		case crew.ProgressTaskFailed:
+			fmt.Fprintf(r.Out, "❌ %s 失败: %s\n", label, p.Message)
+			failures = append(failures, taskFailure{index: p.TaskIndex, agent: p.AgentRole, err: p.Message})
+		case crew.ProgressTaskSkipped:
+			if p.Message == "replayed" {
+				fmt.Fprintf(r.Out, "⏭️  %s 使用已保存的输出\n", label)
+			} else {
+				fmt.Fprintf(r.Out, "⏭️  %s 已跳过\n", label)
+			}
+		case crew.ProgressKickoffFinished:
+			if p.Dropped > 0 {
+				fmt.Fprintf(r.Out, "⚠️  %d 条进度因输出过慢被丢弃\n", p.Dropped)
+			}
+			var runErr error
+			if p.Err != nil {
+				runErr = r.failureSummary(p.Err, failures)
+			}
+			if recorder != nil {
+				report := p.Output
+				if report == nil {
+					// 失败时流程可能没有产生输出，报告只包含失败信息
+					report = &crew.CrewOutput{Error: p.Err, CreatedAt: time.Now(), Duration: time.Since(startTime)}
+				}
+				if err := r.writeReport(report, recorder); err != nil {
+					return p.Output, errors.Join(runErr, err)
+				}
+			}
+			return p.Output, runErr
+		}
+	}
+	return nil, fmt.Errorf("crew execution ended without a result")
+}
+
+// writeReport 把执行结果和记录的事件写成运行报告
+func (r *CrewRunner) writeReport(output *crew.CrewOutput, recorder *crew.ReportRecorder) error {
+	recorder.Close()
+	err := crew.WriteReport(output, r.ReportPath, "",
+		crew.WithReportTitle(fmt.Sprintf("%s 运行报告", r.Config.Name)),
+		crew.WithReportEvents(recorder.Events()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write run report: %w", err)
+	}
+	fmt.Fprintf(r.Out, "\n📝 运行报告已保存到: %s\n", r.ReportPath)
+	return nil
+}
+
+func (r *CrewRunner) taskName(index int) string {
+	if index >= 0 && index < len(r.Config.Tasks) {
+		return r.Config.Tasks[index].Name
+	}
+	return fmt.Sprintf("task %d", index+1)
+}
+
+func (r *CrewRunner) failureSummary(err error, failures []taskFailure) error {
+	if len(failures) == 0 {
+		return fmt.Errorf("crew execution failed: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d task(s) failed:", len(failures))
+	for _, f := range failures {
+		fmt.Fprintf(&b, "\n  - %s (%s): %s", r.taskName(f.index), f.agent, f.err)
+	}
+	return fmt.Errorf("crew execution failed: %w\n%s", err, b.String())
+}
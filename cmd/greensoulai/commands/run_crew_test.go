@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// failingLLM 每次调用都返回错误
+type failingLLM struct {
+	scriptedLLM
+}
+
+func (l *failingLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	return nil, errors.New("rate limited")
+}
+
+func testCrewConfig() *config.ProjectConfig {
+	return &config.ProjectConfig{
+		Name: "test-crew",
+		Type: config.ProjectTypeCrew,
+		Agents: []config.AgentConfig{
+			{Name: "researcher", Role: "Researcher", Goal: "Research topics", Backstory: "Experienced researcher"},
+			{Name: "writer", Role: "Writer", Goal: "Write articles", Backstory: "Experienced writer", LLM: "writer-model"},
+		},
+		Tasks: []config.TaskConfig{
+			{Name: "research", Description: "Research {topic}", ExpectedOutput: "Notes", Agent: "researcher"},
+			{Name: "write", Description: "Write about {topic}", ExpectedOutput: "Article", Agent: "writer", Context: []string{"research"}},
+		},
+	}
+}
+
+func newTestCrewRunner(t *testing.T, models map[string]llm.LLM) (*CrewRunner, *bytes.Buffer) {
+	t.Helper()
+	log := logger.NewTestLogger()
+	out := &bytes.Buffer{}
+	return &CrewRunner{
+		Config:      testCrewConfig(),
+		ProjectRoot: t.TempDir(),
+		NewLLM: func(model string) (llm.LLM, error) {
+			return models[model], nil
+		},
+		EventBus: events.NewEventBus(log),
+		Out:      out,
+		Logger:   log,
+	}, out
+}
+
+func TestParseInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inputs.json")
+	if err := os.WriteFile(path, []byte(`{"topic": "Go", "year": 2025}`), 0644); err != nil {
+		t.Fatalf("failed to write inputs file: %v", err)
+	}
+
+	inputs, err := parseInputs([]string{"topic=AI agents", "query=a=b"}, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inputs["topic"] != "AI agents" || inputs["query"] != "a=b" || inputs["year"] != float64(2025) {
+		t.Errorf("unexpected inputs: %v", inputs)
+	}
+
+	if _, err := parseInputs([]string{"topic"}, ""); err == nil {
+		t.Error("expected error for input without '='")
+	}
+}
+
+func TestCrewRunnerRun(t *testing.T) {
+	runner, out := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &scriptedLLM{model: "default", reply: "research notes"},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "final article"},
+	})
+
+	output, err := runner.Run(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output.Raw, "final article") {
+		t.Errorf("expected final output from writer, got %q", output.Raw)
+	}
+
+	progress := out.String()
+	for _, want := range []string{"[1/2] research 开始 (Researcher)", "[1/2] research 完成", "[2/2] write 开始 (Writer)", "[2/2] write 完成"} {
+		if !strings.Contains(progress, want) {
+			t.Errorf("expected progress line %q, got:\n%s", want, progress)
+		}
+	}
+}
+
+func TestCrewRunnerFailureSummary(t *testing.T) {
+	runner, out := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &failingLLM{scriptedLLM{model: "default"}},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "final article"},
+	})
+
+	_, err := runner.Run(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err == nil {
+		t.Fatal("expected crew failure")
+	}
+	if !strings.Contains(err.Error(), "1 task(s) failed:\n  - research (Researcher): ") || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("expected task-level summary, got:\n%v", err)
+	}
+	if !strings.Contains(out.String(), "[1/2] research 失败") {
+		t.Errorf("expected failure progress line, got:\n%s", out.String())
+	}
+}
+
+func TestCrewRunnerWritesReport(t *testing.T) {
+	runner, out := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &failingLLM{scriptedLLM{model: "default"}},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "final article"},
+	})
+	runner.ReportPath = filepath.Join(t.TempDir(), "report.md")
+
+	// 失败的运行也写入报告
+	if _, err := runner.Run(context.Background(), map[string]interface{}{"topic": "Go"}); err == nil {
+		t.Fatal("expected crew failure")
+	}
+	data, err := os.ReadFile(runner.ReportPath)
+	if err != nil {
+		t.Fatalf("expected report to be written: %v", err)
+	}
+	report := string(data)
+	for _, want := range []string{"# test-crew 运行报告", "| 状态 | ❌ 失败 |", "## 失败的任务", "| 1 | Research {topic} | Researcher |", "rate limited"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+	if !strings.Contains(out.String(), "运行报告已保存到: "+runner.ReportPath) {
+		t.Errorf("expected report path in output, got:\n%s", out.String())
+	}
+}
+
+func TestCrewRunnerDryRun(t *testing.T) {
+	runner, out := newTestCrewRunner(t, nil)
+	runner.Config.LLM = config.LLMConfig{Provider: "openai", Model: "gpt-4o-mini"}
+	runner.NewLLM = dryRunLLMFactory(runner.Config.LLM)
+
+	report, err := runner.DryRun(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Err() != nil || len(report.Tasks) != 2 {
+		t.Fatalf("expected a valid report with two estimates, got %+v", report)
+	}
+	if report.Tasks[0].Model != "gpt-4o-mini" || report.Tasks[1].Model != "writer-model" {
+		t.Errorf("expected per-agent models, got %+v", report.Tasks)
+	}
+	if out.Len() != 0 {
+		t.Errorf("dry run should not print task progress, got:\n%s", out.String())
+	}
+
+	// 缺少输入时报告错误，CI据此失败
+	report, err = runner.DryRun(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "placeholder {topic} has no matching input") {
+		t.Errorf("expected missing input error, got %v", err)
+	}
+}
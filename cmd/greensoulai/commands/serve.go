@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/queue"
+	"github.com/ynl/greensoulai/pkg/server"
+)
+
+// apiKeysEnv 未通过--api-key指定时读取的API Key环境变量，多个Key用逗号分隔
+const apiKeysEnv = "GREENSOULAI_API_KEYS"
+
+// NewServeCommand 创建serve命令
+func NewServeCommand(log logger.Logger) *cobra.Command {
+	var (
+		configPath      string
+		addr            string
+		apiKeys         []string
+		maxConcurrent   int
+		timeout         time.Duration
+		shutdownTimeout time.Duration
+		trainingFile    string
+		queueDB         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "以HTTP服务发布Crew项目",
+		Long: `把当前目录的Crew项目发布为HTTP服务，每个请求在Crew的独立副本上执行。
+
+接口：
+  POST /kickoff              执行Crew，请求体为 {"inputs": {...}}，?async=true 时立即返回执行ID
+  GET  /kickoff/{id}         查询执行状态和已完成任务的输出
+  GET  /kickoff/{id}/events  以SSE推送执行进度（任务开始/完成/失败、智能体思考、工具调用、token用量）
+  GET  /healthz              健康检查
+
+指定 --queue-db 后 ?async=true 的kickoff加入SQLite队列，由 greensoulai worker 执行，
+查询接口从队列的结果存储读取状态和输出。
+
+指定API Key后请求需要携带 Authorization: Bearer <key> 或 X-API-Key 请求头，
+未指定 --api-key 时读取环境变量 ` + apiKeysEnv + `（逗号分隔）。
+收到中断信号后不再接受新的请求，等待执行中的kickoff完成，超过 --shutdown-timeout 后取消。
+
+示例：
+  greensoulai serve --addr :8080 --api-key secret --max-concurrent 8`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, projectConfig, err := buildProjectCrew(cmd.Name(), configPath, trainingFile, log)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			if len(apiKeys) == 0 {
+				apiKeys = splitAPIKeys(os.Getenv(apiKeysEnv))
+			}
+			serverConfig := server.DefaultServerConfig()
+			serverConfig.Addr = addr
+			serverConfig.APIKeys = apiKeys
+			serverConfig.MaxConcurrent = maxConcurrent
+			serverConfig.RequestTimeout = timeout
+			serverConfig.ShutdownTimeout = shutdownTimeout
+			if queueDB != "" {
+				q, err := queue.NewSQLiteQueue(queueDB, nil)
+				if err != nil {
+					return err
+				}
+				defer q.Close()
+				serverConfig.Queue = q
+				serverConfig.CrewName = projectConfig.Name
+			}
+
+			srv, err := server.NewServer(c, serverConfig, log)
+			if err != nil {
+				return err
+			}
+
+			if len(apiKeys) == 0 {
+				log.Warn("未配置API Key，服务不做鉴权")
+			}
+			log.Info("启动Crew服务",
+				logger.Field{Key: "name", Value: projectConfig.Name},
+				logger.Field{Key: "addr", Value: addr},
+				logger.Field{Key: "max_concurrent", Value: maxConcurrent},
+			)
+			return srv.ListenAndServe(cmd.Context())
+		},
+	}
+
+	defaults := server.DefaultServerConfig()
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "配置文件路径")
+	cmd.Flags().StringVar(&addr, "addr", defaults.Addr, "监听地址")
+	cmd.Flags().StringArrayVar(&apiKeys, "api-key", nil, "允许的API Key，可重复指定")
+	cmd.Flags().IntVar(&maxConcurrent, "max-concurrent", defaults.MaxConcurrent, "同时执行的kickoff上限，0表示不限")
+	cmd.Flags().DurationVarP(&timeout, "timeout", "t", defaults.RequestTimeout, "单次kickoff的执行超时时间")
+	cmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", defaults.ShutdownTimeout, "关闭时等待执行中的kickoff完成的时间")
+	cmd.Flags().StringVar(&trainingFile, "training-file", "", "greensoulai train生成的训练数据文件，把其中的改进指令应用到智能体")
+	cmd.Flags().StringVar(&queueDB, "queue-db", "", "SQLite队列数据库路径，设置后异步kickoff加入队列由worker执行")
+
+	return cmd
+}
+
+// buildProjectCrew 加载当前目录的Crew项目并构建Crew，供serve和worker命令使用
+func buildProjectCrew(command, configPath, trainingFile string, log logger.Logger) (crew.Crew, *config.ProjectConfig, error) {
+	projectRoot, err := config.GetProjectRoot()
+	if err != nil {
+		return nil, nil, fmt.Errorf("not in a greensoulai project: %w", err)
+	}
+	if configPath == "" {
+		configPath = filepath.Join(projectRoot, "greensoulai.yaml")
+	}
+
+	projectConfig, err := config.ValidateProjectFile(configPath, config.BuiltinTools().Names())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid project configuration:\n%w", err)
+	}
+	if projectConfig.Type != config.ProjectTypeCrew {
+		return nil, nil, fmt.Errorf("%s only supports crew projects, got %s", command, projectConfig.Type)
+	}
+
+	runner := &CrewRunner{
+		Config:       projectConfig,
+		ProjectRoot:  projectRoot,
+		NewLLM:       config.LLMFactory(projectConfig.LLM),
+		TrainingFile: trainingFile,
+		EventBus:     events.NewEventBus(log),
+		Out:          os.Stdout,
+		Logger:       log,
+	}
+	c, err := runner.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, projectConfig, nil
+}
+
+// splitAPIKeys 解析逗号分隔的API Key列表，忽略空项
+func splitAPIKeys(value string) []string {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
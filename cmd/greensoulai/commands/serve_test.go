@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/server"
+)
+
+func TestServeProjectCrew(t *testing.T) {
+	runner, _ := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &scriptedLLM{model: "default", reply: "research notes"},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "article"},
+	})
+	c, err := runner.Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv, err := server.NewServer(c, server.DefaultServerConfig(), logger.NewTestLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	httpServer := httptest.NewServer(srv.Handler())
+	defer httpServer.Close()
+
+	// 同一个项目Crew可以连续处理多个请求
+	for _, topic := range []string{"Go", "Rust"} {
+		resp, err := http.Post(httpServer.URL+"/kickoff", "application/json", strings.NewReader(`{"inputs": {"topic": "`+topic+`"}}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var execution server.ExecutionResponse
+		_ = json.NewDecoder(resp.Body).Decode(&execution)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || execution.Status != server.StatusCompleted {
+			t.Fatalf("expected kickoff for %s to complete, got %d %+v", topic, resp.StatusCode, execution)
+		}
+		if !strings.Contains(string(execution.Output), `"raw":"article"`) {
+			t.Errorf("unexpected output: %s", execution.Output)
+		}
+	}
+}
+
+func TestSplitAPIKeys(t *testing.T) {
+	if keys := splitAPIKeys(" a, ,b,"); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+	if keys := splitAPIKeys(""); keys != nil {
+		t.Errorf("expected no keys, got %v", keys)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package commands
+
+import (
+	"io"
+
+	"github.com/ynl/greensoulai/internal/crew"
+)
+
+// watchStatusSignal 没有SIGUSR1的平台上不监听信号
+func watchStatusSignal(out io.Writer, c crew.Crew) func() {
+	return func() {}
+}
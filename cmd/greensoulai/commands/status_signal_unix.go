@@ -0,0 +1,40 @@
+//go:build unix
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ynl/greensoulai/internal/crew"
+)
+
+// watchStatusSignal 收到SIGUSR1时把Crew的当前状态写到out，不用调试器也能查看卡住的执行
+// 返回的函数停止监听（kill -USR1 <pid>）
+func watchStatusSignal(out io.Writer, c crew.Crew) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-signals:
+				fmt.Fprintf(out, "\n%s\n", c.Status())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		<-stopped
+	}
+}
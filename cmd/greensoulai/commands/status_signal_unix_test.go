@@ -0,0 +1,54 @@
+//go:build unix
+
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// lockedBuffer 可以被信号处理goroutine并发写入的缓冲区
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchStatusSignal(t *testing.T) {
+	log := logger.NewTestLogger()
+	c := crew.NewBaseCrew(&crew.CrewConfig{Name: "demo"}, events.NewEventBus(log), log)
+
+	var out lockedBuffer
+	stop := watchStatusSignal(&out, c)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("failed to send SIGUSR1: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "📊 Crew demo 状态: 空闲") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the crew status after SIGUSR1, got %q", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/cli/tools"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// NewToolsCommand 创建tools命令
+func NewToolsCommand(log logger.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tools",
+		Short: "工具管理",
+		Long: `管理GreenSoulAI项目中的工具。
+已安装的工具记录在项目根目录的 tools.yaml 中，源码生成在 internal/tools/ 下。`,
+	}
+
+	var overwrite bool
+	install := &cobra.Command{
+		Use:   "install [tool-name]",
+		Short: "安装工具",
+		Long:  "按内置模板为当前项目生成工具源码，并记录到 tools.yaml",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registry, err := loadToolRegistry()
+			if err != nil {
+				return err
+			}
+
+			log.Info("安装工具", logger.Field{Key: "tool", Value: args[0]})
+			tool, err := registry.Install(args[0], overwrite)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "✅ 工具 '%s' 已安装: %s\n", tool.Name, tool.File)
+			fmt.Fprintln(cmd.OutOrStdout(), "💡 在 greensoulai.yaml 中把工具加入Agent的tools列表，并使用 'greensoulai run --compiled' 运行")
+			return nil
+		},
+	}
+	install.Flags().BoolVar(&overwrite, "overwrite", false, "覆盖已存在的工具源码")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "列出可用工具",
+			Long:  "列出内置工具模板和当前项目的自定义工具，以及使用每个工具的Agent",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				registry, err := loadToolRegistry()
+				if err != nil {
+					return err
+				}
+
+				infos, err := registry.List()
+				if err != nil {
+					return err
+				}
+				printToolList(cmd.OutOrStdout(), infos)
+				return nil
+			},
+		},
+		install,
+		&cobra.Command{
+			Use:   "remove [tool-name]",
+			Short: "移除工具",
+			Long:  "删除已安装工具的源码并更新 tools.yaml；仍被Agent或任务引用的工具不能移除",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				registry, err := loadToolRegistry()
+				if err != nil {
+					return err
+				}
+
+				log.Info("移除工具", logger.Field{Key: "tool", Value: args[0]})
+				if err := registry.Remove(args[0]); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "✅ 工具 '%s' 已移除\n", args[0])
+				return nil
+			},
+		},
+	)
+
+	return cmd
+}
+
+// loadToolRegistry 加载当前项目的工具注册表
+func loadToolRegistry() (*tools.Registry, error) {
+	projectRoot, err := config.GetProjectRoot()
+	if err != nil {
+		return nil, fmt.Errorf("not in a greensoulai project: %w", err)
+	}
+
+	projectConfig, err := config.LoadProjectConfig(filepath.Join(projectRoot, "greensoulai.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	return tools.NewRegistry(projectRoot, projectConfig)
+}
+
+// printToolList 按来源分组输出工具列表
+func printToolList(out io.Writer, infos []tools.ToolInfo) {
+	fmt.Fprintln(out, "🛠️  GreenSoulAI 工具列表")
+
+	for _, group := range []struct {
+		source tools.ToolSource
+		title  string
+	}{
+		{tools.SourceCatalog, "📋 内置工具模板:"},
+		{tools.SourceCustom, "📦 项目自定义工具:"},
+	} {
+		fmt.Fprintf(out, "\n%s\n", group.title)
+		count := 0
+		for _, info := range infos {
+			if info.Source != group.source {
+				continue
+			}
+			count++
+
+			status := "未安装"
+			switch {
+			case info.Installed:
+				status = "已安装"
+			case info.File != "":
+				status = "已有源码"
+			case info.Source == tools.SourceCustom:
+				status = "未找到源码"
+			}
+
+			fmt.Fprintf(out, "  • %-20s [%s]", info.Name, status)
+			if info.Description != "" {
+				fmt.Fprintf(out, " %s", info.Description)
+			}
+			if len(info.Agents) > 0 {
+				fmt.Fprintf(out, "\n      使用者: %s", strings.Join(info.Agents, ", "))
+			}
+			fmt.Fprintln(out)
+		}
+		if count == 0 {
+			fmt.Fprintln(out, "  (无)")
+		}
+	}
+
+	fmt.Fprintln(out, `
+💡 使用方法:
+  greensoulai tools install <tool_name>  # 安装工具
+  greensoulai tools remove <tool_name>   # 移除工具`)
+}
@@ -3,48 +3,70 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/training"
+	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
 // NewTrainCommand 创建train命令
 func NewTrainCommand(log logger.Logger) *cobra.Command {
 	var (
-		iterations   int
-		filename     string
-		outputDir    string
-		concurrency  int
-		timeout      time.Duration
-		saveInterval int
+		iterations      int
+		filename        string
+		outputDir       string
+		inputs          []string
+		inputsFile      string
+		feedbackFile    string
+		feedbackTimeout time.Duration
+		timeout         time.Duration
 	)
 
 	cmd := &cobra.Command{
 		Use:   "train",
 		Short: "训练GreenSoulAI项目",
-		Long: `对当前GreenSoulAI项目进行训练，通过多次运行来优化智能体性能。
-训练过程会记录每次执行的结果，并生成训练报告。`,
+		Long: `对当前GreenSoulAI Crew项目进行训练。
+每次迭代运行项目的Crew后收集人工反馈（质量评分和改进建议），
+迭代的输入、输出、反馈、耗时和token使用量在每次迭代后写入训练数据文件。
+按Ctrl+C中断时会保存已收集的数据。
+
+示例：
+  greensoulai train -n 3 --input topic=AI
+  greensoulai train -n 3 --feedback-file feedback.jsonl   # CI中使用预先准备的反馈`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if iterations < 1 {
+				return fmt.Errorf("iterations must be at least 1")
+			}
+
 			// 查找项目根目录
 			projectRoot, err := config.GetProjectRoot()
 			if err != nil {
 				return fmt.Errorf("not in a greensoulai project: %w", err)
 			}
 
-			// 加载项目配置
+			// 加载并验证项目配置
 			configPath := filepath.Join(projectRoot, "greensoulai.yaml")
-			projectConfig, err := config.LoadProjectConfig(configPath)
+			projectConfig, err := config.ValidateProjectFile(configPath, config.BuiltinTools().Names())
 			if err != nil {
-				return fmt.Errorf("failed to load project config: %w", err)
+				return fmt.Errorf("invalid project configuration:\n%w", err)
+			}
+			if projectConfig.Type != config.ProjectTypeCrew {
+				return fmt.Errorf("train only supports crew projects, got %s", projectConfig.Type)
 			}
 
-			// 验证配置
-			if err := projectConfig.Validate(); err != nil {
-				return fmt.Errorf("invalid project configuration: %w", err)
+			crewInputs, err := parseInputs(inputs, inputsFile)
+			if err != nil {
+				return err
 			}
 
 			// 设置默认训练文件名
@@ -59,6 +81,16 @@ func NewTrainCommand(log logger.Logger) *cobra.Command {
 				outputDir = filepath.Join(projectRoot, "training_data")
 			}
 
+			// 有反馈文件时非交互地读取反馈，否则在控制台询问
+			var collector training.FeedbackCollector
+			if feedbackFile != "" {
+				if collector, err = training.NewJSONLFeedbackCollector(feedbackFile, log); err != nil {
+					return err
+				}
+			} else {
+				collector = training.NewConsoleFeedbackCollector(agent.NewConsoleInputHandler(log), log)
+			}
+
 			log.Info("开始训练项目",
 				logger.Field{Key: "name", Value: projectConfig.Name},
 				logger.Field{Key: "iterations", Value: iterations},
@@ -68,19 +100,26 @@ func NewTrainCommand(log logger.Logger) *cobra.Command {
 
 			// 创建训练器
 			trainer := &ProjectTrainer{
-				Config:       projectConfig,
-				ProjectRoot:  projectRoot,
-				Iterations:   iterations,
-				Filename:     filename,
-				OutputDir:    outputDir,
-				Concurrency:  concurrency,
-				Timeout:      timeout,
-				SaveInterval: saveInterval,
-				Logger:       log,
+				Runner: &CrewRunner{
+					Config:      projectConfig,
+					ProjectRoot: projectRoot,
+					NewLLM:      config.LLMFactory(projectConfig.LLM),
+					EventBus:    events.NewEventBus(log),
+					Out:         os.Stdout,
+					Logger:      log,
+				},
+				Iterations:        iterations,
+				Filename:          filepath.Join(outputDir, filename),
+				Timeout:           timeout,
+				FeedbackCollector: collector,
+				FeedbackTimeout:   feedbackTimeout,
+				Out:               os.Stdout,
+				Logger:            log,
 			}
 
 			// 执行训练
-			return trainer.Train(cmd.Context())
+			_, err = trainer.Train(cmd.Context(), crewInputs)
+			return err
 		},
 	}
 
@@ -88,370 +127,164 @@ func NewTrainCommand(log logger.Logger) *cobra.Command {
 	cmd.Flags().IntVarP(&iterations, "iterations", "n", 5, "训练迭代次数")
 	cmd.Flags().StringVarP(&filename, "filename", "f", "", "训练数据文件名")
 	cmd.Flags().StringVarP(&outputDir, "output", "o", "", "输出目录")
-	cmd.Flags().IntVarP(&concurrency, "concurrency", "c", 1, "并发执行数量")
+	cmd.Flags().StringArrayVarP(&inputs, "input", "i", nil, "Crew输入，格式为key=value，可重复指定")
+	cmd.Flags().StringVar(&inputsFile, "inputs-file", "", "JSON格式的输入文件")
+	cmd.Flags().StringVar(&feedbackFile, "feedback-file", "", "JSONL格式的反馈文件，每行对应一次迭代，用于非交互训练")
+	cmd.Flags().DurationVar(&feedbackTimeout, "feedback-timeout", 5*time.Minute, "等待人工反馈的超时时间")
 	cmd.Flags().DurationVarP(&timeout, "timeout", "t", 10*time.Minute, "单次执行超时时间")
-	cmd.Flags().IntVar(&saveInterval, "save-interval", 1, "保存间隔（每N次迭代保存一次）")
 
 	return cmd
 }
 
-// ProjectTrainer 项目训练器
+// ProjectTrainer 项目训练器：多次运行Crew，每次迭代后收集反馈并保存训练数据
 type ProjectTrainer struct {
-	Config       *config.ProjectConfig
-	ProjectRoot  string
-	Iterations   int
-	Filename     string
-	OutputDir    string
-	Concurrency  int
-	Timeout      time.Duration
-	SaveInterval int
-	Logger       logger.Logger
-}
-
-// TrainingResult 训练结果
-type TrainingResult struct {
-	Iteration   int                    `json:"iteration"`
-	Success     bool                   `json:"success"`
-	Duration    time.Duration          `json:"duration"`
-	Output      string                 `json:"output,omitempty"`
-	Error       string                 `json:"error,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
-	Timestamp   time.Time              `json:"timestamp"`
-	TaskResults []TaskResult           `json:"task_results,omitempty"`
-}
-
-// TaskResult 任务结果
-type TaskResult struct {
-	TaskName  string                 `json:"task_name"`
-	AgentName string                 `json:"agent_name"`
-	Duration  time.Duration          `json:"duration"`
-	Success   bool                   `json:"success"`
-	Output    string                 `json:"output"`
-	Quality   float64                `json:"quality,omitempty"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
-}
-
-// TrainingReport 训练报告
-type TrainingReport struct {
-	ProjectName     string           `json:"project_name"`
-	ProjectType     string           `json:"project_type"`
-	TotalIterations int              `json:"total_iterations"`
-	SuccessfulRuns  int              `json:"successful_runs"`
-	FailedRuns      int              `json:"failed_runs"`
-	StartTime       time.Time        `json:"start_time"`
-	EndTime         time.Time        `json:"end_time"`
-	TotalDuration   time.Duration    `json:"total_duration"`
-	AverageRunTime  time.Duration    `json:"average_run_time"`
-	Results         []TrainingResult `json:"results"`
-
-	// 性能分析
-	Performance struct {
-		BestIteration  int     `json:"best_iteration"`
-		WorstIteration int     `json:"worst_iteration"`
-		SuccessRate    float64 `json:"success_rate"`
-		Improvement    float64 `json:"improvement"`
-	} `json:"performance"`
+	Runner            *CrewRunner
+	Iterations        int
+	Filename          string        // 训练数据文件路径
+	Timeout           time.Duration // 单次Crew执行的超时时间
+	FeedbackCollector training.FeedbackCollector
+	FeedbackTimeout   time.Duration
+	Out               io.Writer
+	Logger            logger.Logger
 }
 
-// Train 执行训练
-func (t *ProjectTrainer) Train(ctx context.Context) error {
-	// 创建输出目录
-	if err := os.MkdirAll(t.OutputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+// Train 执行训练，完成或中断后从训练数据文件生成并输出训练报告
+// 被中断时已收集的数据仍会保存，返回的错误包含上下文错误
+func (t *ProjectTrainer) Train(ctx context.Context, inputs map[string]interface{}) (*training.TrainingReport, error) {
+	if dir := filepath.Dir(t.Filename); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
 	}
 
-	// 初始化训练报告
-	report := &TrainingReport{
-		ProjectName:     t.Config.Name,
-		ProjectType:     string(t.Config.Type),
-		TotalIterations: t.Iterations,
-		StartTime:       time.Now(),
-		Results:         make([]TrainingResult, 0, t.Iterations),
+	handler := training.NewCrewTrainingHandler(t.Runner.EventBus, t.Logger)
+	handler.SetFeedbackCollector(t.FeedbackCollector)
+	if t.FeedbackCollector != nil {
+		// 训练完成时用项目默认模型把反馈总结为各智能体的改进指令
+		summaryLLM, err := t.Runner.NewLLM("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create summary LLM: %w", err)
+		}
+		handler.SetInstructionSummarizer(training.NewAgentInstructionSummarizer(summaryLLM, t.Logger))
 	}
 
-	t.Logger.Info("🚀 开始训练会话",
-		logger.Field{Key: "iterations", Value: t.Iterations},
-		logger.Field{Key: "concurrency", Value: t.Concurrency},
-	)
+	trainingConfig := training.DefaultTrainingConfig()
+	trainingConfig.Iterations = t.Iterations
+	trainingConfig.Filename = t.Filename
+	trainingConfig.Inputs = inputs
+	trainingConfig.CollectFeedback = t.FeedbackCollector != nil
+	trainingConfig.FeedbackTimeout = t.FeedbackTimeout
+	trainingConfig.SaveInterval = 1
+	trainingConfig.BackupCount = 0
+	trainingConfig.Verbose = false
 
-	// 显示训练进度头部
 	t.printTrainingHeader()
 
-	// 执行训练迭代
-	successCount := 0
-	var totalDuration time.Duration
-
-	for i := 1; i <= t.Iterations; i++ {
-		// 创建迭代上下文
-		iterCtx, cancel := context.WithTimeout(ctx, t.Timeout)
-
-		t.Logger.Info("执行迭代", logger.Field{Key: "iteration", Value: i})
-
-		// 执行单次训练
-		result := t.executeIteration(iterCtx, i)
-		cancel()
-
-		// 记录结果
-		report.Results = append(report.Results, result)
-
-		if result.Success {
-			successCount++
-			t.printSuccess(i, result.Duration)
-		} else {
-			t.printFailure(i, result.Duration, result.Error)
-		}
-
-		totalDuration += result.Duration
-
-		// 定期保存中间结果
-		if i%t.SaveInterval == 0 || i == t.Iterations {
-			if err := t.saveIntermediateResults(report, i); err != nil {
-				t.Logger.Warn("保存中间结果失败", logger.Field{Key: "error", Value: err})
-			}
-		}
-
-		// 检查是否被取消
-		select {
-		case <-ctx.Done():
-			t.Logger.Info("训练被用户中断")
-			return ctx.Err()
-		default:
-		}
+	utils := training.NewTrainingUtils(t.Logger)
+	_, trainErr := utils.RunTrainingSession(ctx, handler, trainingConfig, t.executeFunc())
+	interrupted := trainErr != nil && ctx.Err() != nil
+	if trainErr != nil && !interrupted {
+		return nil, trainErr
 	}
 
-	// 完成训练报告
-	report.EndTime = time.Now()
-	report.TotalDuration = totalDuration
-	report.SuccessfulRuns = successCount
-	report.FailedRuns = t.Iterations - successCount
-
-	if t.Iterations > 0 {
-		report.AverageRunTime = totalDuration / time.Duration(t.Iterations)
-		report.Performance.SuccessRate = float64(successCount) / float64(t.Iterations)
+	data, err := handler.LoadTrainingData(context.WithoutCancel(ctx), t.Filename)
+	if err != nil {
+		if interrupted {
+			fmt.Fprintf(t.Out, "\n⚠️  训练已中断，没有完成的迭代\n")
+			return nil, fmt.Errorf("training interrupted: %w", trainErr)
+		}
+		return nil, fmt.Errorf("failed to load training data: %w", err)
 	}
 
-	// 分析性能
-	t.analyzePerformance(report)
+	report := utils.GenerateTrainingReport(data)
+	t.printTrainingSummary(data)
 
-	// 保存最终训练报告
-	if err := t.saveFinalReport(report); err != nil {
-		return fmt.Errorf("failed to save training report: %w", err)
+	if interrupted {
+		return report, fmt.Errorf("training interrupted: %w", trainErr)
 	}
-
-	// 显示训练总结
-	t.printTrainingSummary(report)
-
-	return nil
+	return report, nil
 }
 
-// executeIteration 执行单次训练迭代
-func (t *ProjectTrainer) executeIteration(ctx context.Context, iteration int) TrainingResult {
-	start := time.Now()
+// executeFunc 每次迭代重新构建并运行Crew，返回可写入训练数据的输出
+func (t *ProjectTrainer) executeFunc() func(context.Context, map[string]interface{}) (interface{}, error) {
+	iteration := 0
+	return func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		iteration++
+		fmt.Fprintf(t.Out, "\n🔄 迭代 %d/%d\n", iteration, t.Iterations)
 
-	result := TrainingResult{
-		Iteration: iteration,
-		Timestamp: start,
-		Metadata:  make(map[string]interface{}),
-	}
+		iterCtx, cancel := context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+
+		start := time.Now()
+		c, err := t.Runner.Build()
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
 
-	// TODO: 这里应该调用实际的项目执行逻辑
-	// 现在先用模拟的执行过程
-
-	// 模拟执行时间（实际应该运行crew）
-	executionTime := time.Duration(2+iteration%3) * time.Second
-	select {
-	case <-time.After(executionTime):
-		// 模拟成功执行
-		result.Success = true
-		result.Output = fmt.Sprintf("Iteration %d completed successfully", iteration)
-
-		// 模拟任务结果
-		for _, taskCfg := range t.Config.Tasks {
-			taskResult := TaskResult{
-				TaskName:  taskCfg.Name,
-				AgentName: taskCfg.Agent,
-				Duration:  time.Duration(1+iteration%2) * time.Second,
-				Success:   iteration%4 != 0, // 模拟75%成功率
-				Output:    fmt.Sprintf("Task %s output for iteration %d", taskCfg.Name, iteration),
-				Quality:   0.7 + float64(iteration%3)*0.1, // 模拟质量评分
+		output, err := t.Runner.Kickoff(iterCtx, c, inputs)
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(t.Out, "❌ 迭代 %d/%d - 失败 (%.2fs)\n", iteration, t.Iterations, time.Since(start).Seconds())
 			}
-			result.TaskResults = append(result.TaskResults, taskResult)
+			return nil, err
 		}
 
-	case <-ctx.Done():
-		// 超时或被取消
-		result.Success = false
-		result.Error = "execution timeout or cancelled"
+		fmt.Fprintf(t.Out, "✅ 迭代 %d/%d - 成功 (%.2fs)\n", iteration, t.Iterations, time.Since(start).Seconds())
+		return crew.TrainingOutputs(output), nil
 	}
-
-	result.Duration = time.Since(start)
-
-	// 添加元数据
-	result.Metadata["go_version"] = t.Config.GoVersion
-	result.Metadata["llm_provider"] = t.Config.LLM.Provider
-	result.Metadata["llm_model"] = t.Config.LLM.Model
-
-	return result
 }
 
 // printTrainingHeader 打印训练头部信息
 func (t *ProjectTrainer) printTrainingHeader() {
-	fmt.Printf(`
+	cfg := t.Runner.Config
+	fmt.Fprintf(t.Out, `
 🎯 GreenSoulAI 训练会话
 ==================================================
 📋 项目: %s (%s)
 🔄 迭代次数: %d
 ⏱️  超时时间: %v
-📁 输出目录: %s
-⚡ 并发数: %d
-
-执行进度：
-`, t.Config.Name, t.Config.Type, t.Iterations, t.Timeout, t.OutputDir, t.Concurrency)
-}
-
-// printSuccess 打印成功信息
-func (t *ProjectTrainer) printSuccess(iteration int, duration time.Duration) {
-	fmt.Printf("✅ 迭代 %2d/%d - 成功 (%.2fs)\n",
-		iteration, t.Iterations, duration.Seconds())
-}
-
-// printFailure 打印失败信息
-func (t *ProjectTrainer) printFailure(iteration int, duration time.Duration, errorMsg string) {
-	fmt.Printf("❌ 迭代 %2d/%d - 失败 (%.2fs) - %s\n",
-		iteration, t.Iterations, duration.Seconds(), errorMsg)
-}
-
-// printTrainingSummary 打印训练总结
-func (t *ProjectTrainer) printTrainingSummary(report *TrainingReport) {
-	fmt.Printf(`
-🏁 训练完成！
-==================================================
-📊 总结统计:
-   总迭代次数: %d
-   成功次数: %d
-   失败次数: %d
-   成功率: %.1f%%
-   总耗时: %v
-   平均耗时: %v
-
-📈 性能分析:
-   最佳迭代: #%d
-   最差迭代: #%d
-
-📁 训练数据已保存到:
-   %s
-
-🚀 下一步:
-1. 查看训练报告分析性能
-2. 基于结果调整项目配置
-3. 运行评估验证改进效果
-
-`,
-		report.TotalIterations,
-		report.SuccessfulRuns,
-		report.FailedRuns,
-		report.Performance.SuccessRate*100,
-		report.TotalDuration,
-		report.AverageRunTime,
-		report.Performance.BestIteration,
-		report.Performance.WorstIteration,
-		filepath.Join(t.OutputDir, t.Filename),
-	)
+📁 训练数据: %s
+`, cfg.Name, cfg.Type, t.Iterations, t.Timeout, t.Filename)
 }
 
-// analyzePerformance 分析训练性能
-func (t *ProjectTrainer) analyzePerformance(report *TrainingReport) {
-	if len(report.Results) == 0 {
-		return
+// printTrainingSummary 打印每次迭代的结果和训练总结
+func (t *ProjectTrainer) printTrainingSummary(data *training.TrainingData) {
+	if data.StopReason == training.StopReasonInterrupted {
+		fmt.Fprintf(t.Out, "\n⚠️  训练已中断，已保存 %d 次迭代的数据\n", len(data.Iterations))
+	} else {
+		fmt.Fprintf(t.Out, "\n🏁 训练完成！\n")
 	}
+	fmt.Fprintln(t.Out, strings.Repeat("=", 50))
 
-	bestTime := report.Results[0].Duration
-	worstTime := report.Results[0].Duration
-	bestIdx := 0
-	worstIdx := 0
-
-	for i, result := range report.Results {
-		if result.Success && result.Duration < bestTime {
-			bestTime = result.Duration
-			bestIdx = i
+	rows := [][]string{{"迭代", "结果", "耗时", "Token", "评分", "改进建议"}}
+	for _, iteration := range data.Iterations {
+		status := "成功"
+		if !iteration.Success {
+			status = "失败"
 		}
-		if result.Duration > worstTime {
-			worstTime = result.Duration
-			worstIdx = i
+		score, suggestions := "-", ""
+		if iteration.Feedback != nil {
+			score = fmt.Sprintf("%.1f", iteration.Feedback.QualityScore)
+			suggestions = iteration.Feedback.Suggestions
 		}
+		rows = append(rows, []string{
+			strconv.Itoa(iteration.Index + 1),
+			status,
+			fmt.Sprintf("%.2fs", iteration.Duration.Seconds()),
+			strconv.Itoa(iteration.TokensUsed),
+			score,
+			suggestions,
+		})
 	}
+	printAligned(t.Out, rows)
 
-	report.Performance.BestIteration = bestIdx + 1
-	report.Performance.WorstIteration = worstIdx + 1
-
-	// 计算改进程度（简单的线性回归）
-	if len(report.Results) > 1 {
-		firstHalf := len(report.Results) / 2
-		firstAvg := t.calculateAverageDuration(report.Results[:firstHalf])
-		secondAvg := t.calculateAverageDuration(report.Results[firstHalf:])
-
-		if firstAvg > 0 {
-			report.Performance.Improvement = (firstAvg.Seconds() - secondAvg.Seconds()) / firstAvg.Seconds()
+	if summary := data.Summary; summary != nil && summary.TotalIterations > 0 {
+		fmt.Fprintf(t.Out, "\n📊 成功 %d/%d 次，平均耗时 %v，共使用 %d tokens\n",
+			summary.SuccessfulRuns, summary.TotalIterations, summary.AverageDuration.Round(time.Millisecond), summary.TotalTokens)
+		if summary.AverageFeedback > 0 {
+			fmt.Fprintf(t.Out, "⭐ 平均反馈评分: %.1f/10\n", summary.AverageFeedback)
 		}
 	}
-}
-
-// calculateAverageDuration 计算平均持续时间
-func (t *ProjectTrainer) calculateAverageDuration(results []TrainingResult) time.Duration {
-	if len(results) == 0 {
-		return 0
-	}
-
-	var total time.Duration
-	successCount := 0
-
-	for _, result := range results {
-		if result.Success {
-			total += result.Duration
-			successCount++
-		}
-	}
-
-	if successCount == 0 {
-		return 0
-	}
-
-	return total / time.Duration(successCount)
-}
-
-// saveIntermediateResults 保存中间结果
-func (t *ProjectTrainer) saveIntermediateResults(report *TrainingReport, currentIteration int) error {
-	filename := fmt.Sprintf("%s_progress_%d.json",
-		t.Config.Name, currentIteration)
-	filepath := filepath.Join(t.OutputDir, filename)
-
-	// 这里应该保存JSON格式的中间结果
-	// 为了简化，现在只记录日志
-	t.Logger.Info("保存中间训练结果",
-		logger.Field{Key: "iteration", Value: currentIteration},
-		logger.Field{Key: "file", Value: filepath},
-	)
-
-	return nil
-}
-
-// saveFinalReport 保存最终训练报告
-func (t *ProjectTrainer) saveFinalReport(report *TrainingReport) error {
-	filepath := filepath.Join(t.OutputDir, t.Filename)
-
-	// 这里应该保存JSON格式的完整报告
-	// 为了简化，现在只记录日志
-	t.Logger.Info("保存最终训练报告",
-		logger.Field{Key: "file", Value: filepath},
-		logger.Field{Key: "success_rate", Value: report.Performance.SuccessRate},
-	)
-
-	// TODO: 实际的JSON序列化和文件写入
-	// data, err := json.MarshalIndent(report, "", "  ")
-	// if err != nil {
-	//     return fmt.Errorf("failed to marshal report: %w", err)
-	// }
-	// return os.WriteFile(filepath, data, 0644)
-
-	return nil
+	fmt.Fprintf(t.Out, "\n📁 训练数据已保存到: %s\n", t.Filename)
 }
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/training"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func TestProjectTrainerTrain(t *testing.T) {
+	runner, out := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &scriptedLLM{model: "default", reply: "research notes"},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "final article"},
+	})
+
+	feedbackFile := filepath.Join(t.TempDir(), "feedback.jsonl")
+	feedback := `{"quality_score": 6, "suggestions": "补充数据来源"}
+{"quality_score": 8, "suggestions": "结构更清晰"}
+`
+	if err := os.WriteFile(feedbackFile, []byte(feedback), 0644); err != nil {
+		t.Fatal(err)
+	}
+	collector, err := training.NewJSONLFeedbackCollector(feedbackFile, logger.NewTestLogger())
+	if err != nil {
+		t.Fatalf("failed to create feedback collector: %v", err)
+	}
+
+	trainer := &ProjectTrainer{
+		Runner:            runner,
+		Iterations:        2,
+		Filename:          filepath.Join(t.TempDir(), "training_data", "demo.json"),
+		Timeout:           time.Minute,
+		FeedbackCollector: collector,
+		FeedbackTimeout:   time.Second,
+		Out:               out,
+		Logger:            logger.NewTestLogger(),
+	}
+
+	report, err := trainer.Train(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Status != "completed" || report.TotalIterations != 2 {
+		t.Errorf("unexpected report: status=%s iterations=%d", report.Status, report.TotalIterations)
+	}
+
+	data, err := training.NewCrewTrainingHandler(runner.EventBus, logger.NewTestLogger()).LoadTrainingData(context.Background(), trainer.Filename)
+	if err != nil {
+		t.Fatalf("failed to load training data: %v", err)
+	}
+	if len(data.Iterations) != 2 {
+		t.Fatalf("expected 2 iterations, got %d", len(data.Iterations))
+	}
+	first := data.Iterations[0]
+	outputs, _ := first.Outputs.(map[string]interface{})
+	raw, _ := outputs["raw"].(string)
+	if !strings.Contains(raw, "final article") || first.Inputs["topic"] != "Go" {
+		t.Errorf("unexpected iteration data: inputs=%v outputs=%v", first.Inputs, first.Outputs)
+	}
+	if first.Feedback == nil || first.Feedback.QualityScore != 6 || first.Feedback.Suggestions != "补充数据来源" {
+		t.Errorf("unexpected feedback: %+v", first.Feedback)
+	}
+
+	for _, want := range []string{"✅ 迭代 1/2 - 成功", "✅ 迭代 2/2 - 成功", "🏁 训练完成", "结构更清晰", "平均反馈评分: 7.0/10"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestProjectTrainerInterrupted(t *testing.T) {
+	runner, out := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &scriptedLLM{model: "default", reply: "research notes"},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "final article"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trainer := &ProjectTrainer{
+		Runner:            runner,
+		Iterations:        3,
+		Filename:          filepath.Join(t.TempDir(), "demo.json"),
+		Timeout:           time.Minute,
+		FeedbackCollector: &cancellingCollector{cancel: cancel},
+		Out:               out,
+		Logger:            logger.NewTestLogger(),
+	}
+
+	report, err := trainer.Train(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), "training interrupted") {
+		t.Fatalf("expected interrupted error, got %v", err)
+	}
+	if report == nil || report.StopReason != training.StopReasonInterrupted || report.TotalIterations != 1 {
+		t.Fatalf("expected report with one interrupted iteration, got %+v", report)
+	}
+	if !strings.Contains(out.String(), "训练已中断，已保存 1 次迭代的数据") {
+		t.Errorf("expected interrupted summary, got:\n%s", out.String())
+	}
+}
+
+// cancellingCollector 在收集第一次反馈时取消训练，模拟按下Ctrl+C
+type cancellingCollector struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancellingCollector) CollectFeedback(ctx context.Context, iterationID string, outputs interface{}, timeout time.Duration) (*training.HumanFeedback, error) {
+	c.cancel()
+	return nil, ctx.Err()
+}
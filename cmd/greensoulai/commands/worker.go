@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/queue"
+)
+
+// NewWorkerCommand 创建worker命令
+func NewWorkerCommand(log logger.Logger) *cobra.Command {
+	var (
+		configPath      string
+		queueDB         string
+		concurrency     int
+		timeout         time.Duration
+		shutdownTimeout time.Duration
+		maxRetries      int
+		trainingFile    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "worker",
+		Short: "从队列取出kickoff请求并执行Crew项目",
+		Long: `从队列取出 greensoulai serve --queue-db 加入的kickoff请求，在当前目录的Crew项目上执行，
+结果写入队列的结果存储，可以通过 GET /kickoff/{id} 查询。
+
+请求至少执行一次：worker崩溃时请求在可见性超时后重新投递，执行失败的请求最多重试 --max-retries 次，
+之后转入死信。收到中断信号后不再取出新的请求，等待执行中的kickoff完成，超过 --shutdown-timeout 后取消。
+
+示例：
+  greensoulai worker --queue-db ./queue.db --concurrency 4`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if queueDB == "" {
+				return fmt.Errorf("--queue-db is required")
+			}
+
+			c, projectConfig, err := buildProjectCrew(cmd.Name(), configPath, trainingFile, log)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			options := queue.DefaultOptions()
+			options.MaxRetries = maxRetries
+			if timeout > 0 {
+				// 可见性超时必须长于执行超时，否则执行中的请求会被投递给其他worker
+				options.VisibilityTimeout = timeout + time.Minute
+			}
+			q, err := queue.NewSQLiteQueue(queueDB, options)
+			if err != nil {
+				return err
+			}
+			defer q.Close()
+
+			workerConfig := crew.DefaultWorkerConfig()
+			workerConfig.Concurrency = concurrency
+			workerConfig.JobTimeout = timeout
+			workerConfig.ShutdownTimeout = shutdownTimeout
+			worker, err := crew.NewWorker(q, projectCrewFactory(projectConfig.Name, c), workerConfig, log)
+			if err != nil {
+				return err
+			}
+
+			log.Info("启动Crew worker",
+				logger.Field{Key: "name", Value: projectConfig.Name},
+				logger.Field{Key: "queue", Value: queueDB},
+				logger.Field{Key: "concurrency", Value: concurrency},
+			)
+			return worker.Run(cmd.Context())
+		},
+	}
+
+	defaults := crew.DefaultWorkerConfig()
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "配置文件路径")
+	cmd.Flags().StringVar(&queueDB, "queue-db", "", "SQLite队列数据库路径，与serve --queue-db相同")
+	cmd.Flags().IntVar(&concurrency, "concurrency", defaults.Concurrency, "同时执行的kickoff数")
+	cmd.Flags().DurationVarP(&timeout, "timeout", "t", defaults.JobTimeout, "单次kickoff的执行超时时间")
+	cmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", defaults.ShutdownTimeout, "停止时等待执行中的kickoff完成的时间")
+	cmd.Flags().IntVar(&maxRetries, "max-retries", queue.DefaultOptions().MaxRetries, "执行失败后的最大重试次数，超过后转入死信")
+	cmd.Flags().StringVar(&trainingFile, "training-file", "", "greensoulai train生成的训练数据文件，把其中的改进指令应用到智能体")
+
+	return cmd
+}
+
+// projectCrewFactory 为名称匹配项目名的请求返回项目Crew的副本，名称为空时同样使用项目Crew
+func projectCrewFactory(name string, c crew.Crew) crew.CrewFactory {
+	return func(ctx context.Context, requested string) (crew.Crew, error) {
+		if requested != "" && requested != name {
+			return nil, fmt.Errorf("this worker runs crew %s, cannot run %s", name, requested)
+		}
+		return c.Clone()
+	}
+}
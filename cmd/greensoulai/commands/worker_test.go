@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/queue"
+)
+
+func TestWorkerRunsQueuedProjectKickoffs(t *testing.T) {
+	runner, _ := newTestCrewRunner(t, map[string]llm.LLM{
+		"":             &scriptedLLM{model: "default", reply: "research notes"},
+		"writer-model": &scriptedLLM{model: "writer-model", reply: "article"},
+	})
+	c, err := runner.Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	factory := projectCrewFactory(runner.Config.Name, c)
+
+	if _, err := factory(context.Background(), "other-crew"); err == nil || !strings.Contains(err.Error(), "cannot run other-crew") {
+		t.Errorf("expected requests for other crews to be rejected, got %v", err)
+	}
+
+	q, err := queue.NewSQLiteQueue(filepath.Join(t.TempDir(), "queue.db"), &queue.Options{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer q.Close()
+	id, err := q.Enqueue(context.Background(), queue.KickoffRequest{CrewName: runner.Config.Name, Inputs: map[string]interface{}{"topic": "Go"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	worker, err := crew.NewWorker(q, factory, nil, logger.NewTestLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- worker.Run(ctx) }()
+
+	var result *queue.Result
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if found, ok, _ := q.GetResult(context.Background(), id); ok && found.Status == queue.ResultCompleted {
+			result = found
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if result == nil || !strings.Contains(string(result.Output), `"raw":"article"`) {
+		t.Fatalf("expected the queued kickoff to complete, got %+v", result)
+	}
+}
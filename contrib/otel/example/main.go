@@ -0,0 +1,75 @@
+// 把一次Crew执行的span导出到标准输出
+//
+// 运行前设置OPENAI_API_KEY，然后在contrib/otel目录下执行：
+//
+//	go run ./example
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	greensoulotel "github.com/ynl/greensoulai/contrib/otel"
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+func main() {
+	ctx := context.Background()
+
+	// 同步导出，每个span结束时立即打印
+	exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+	if err != nil {
+		log.Fatalf("failed to create stdout exporter: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() {
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Printf("failed to shut down tracer provider: %v", err)
+		}
+	}()
+	tracing.SetTracer(greensoulotel.NewTracer(provider))
+
+	baseLogger := logger.NewConsoleLogger()
+	eventBus := events.NewEventBus(baseLogger)
+	model := llm.NewOpenAILLM("gpt-4o-mini", llm.WithAPIKey(os.Getenv("OPENAI_API_KEY")))
+
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Technical Writer",
+		Goal:      "Explain technical topics clearly",
+		Backstory: "You write short, precise explanations for engineers.",
+		LLM:       model,
+		EventBus:  eventBus,
+		Logger:    baseLogger,
+	})
+	if err != nil {
+		log.Fatalf("failed to create agent: %v", err)
+	}
+
+	config := crew.DefaultCrewConfig()
+	config.Name = "tracing-demo"
+	team := crew.NewBaseCrew(config, eventBus, baseLogger)
+	team.AddAgent(writer)
+	team.AddTask(agent.NewTaskWithOptions(
+		"Explain what distributed tracing is in three sentences",
+		"Three sentences about distributed tracing",
+		agent.WithName("explain_tracing"),
+		agent.WithAssignedAgent(writer),
+	))
+
+	// 导出的span：crew.kickoff → task.execute → agent.execute → llm.call
+	output, err := team.Kickoff(ctx, nil)
+	if err != nil {
+		log.Fatalf("crew execution failed: %v", err)
+	}
+	fmt.Println(output.Raw)
+}
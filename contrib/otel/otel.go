@@ -0,0 +1,92 @@
+// Package otel 把greensoulai的追踪钩子接到OpenTelemetry
+//
+// 适配器放在独立模块中，只有引入该模块的程序才会依赖OpenTelemetry SDK：
+//
+//	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+//	tracing.SetTracer(otel.NewTracer(provider))
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+// InstrumentationName 创建OpenTelemetry Tracer时使用的instrumentation名称
+const InstrumentationName = "github.com/ynl/greensoulai"
+
+// Tracer 使用OpenTelemetry实现tracing.Tracer
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ tracing.Tracer = (*Tracer)(nil)
+
+// NewTracer 使用provider创建Tracer
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(InstrumentationName)}
+}
+
+// Start 实现tracing.Tracer，ctx中已有的OpenTelemetry span成为新span的父span
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(convertAttributes(attrs)...))
+	return ctx, &otelSpan{span: span}
+}
+
+// otelSpan 包装OpenTelemetry span
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttributes(attrs ...tracing.Attribute) {
+	s.span.SetAttributes(convertAttributes(attrs)...)
+}
+
+func (s *otelSpan) AddEvent(name string, attrs ...tracing.Attribute) {
+	s.span.AddEvent(name, trace.WithAttributes(convertAttributes(attrs)...))
+}
+
+func (s *otelSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+func (s *otelSpan) IsRecording() bool {
+	return s.span.IsRecording()
+}
+
+// convertAttributes 把tracing.Attribute转换为OpenTelemetry属性，不支持的类型转换为字符串
+func convertAttributes(attrs []tracing.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch value := attr.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(attr.Key, value))
+		case bool:
+			kvs = append(kvs, attribute.Bool(attr.Key, value))
+		case int:
+			kvs = append(kvs, attribute.Int(attr.Key, value))
+		case int64:
+			kvs = append(kvs, attribute.Int64(attr.Key, value))
+		case float64:
+			kvs = append(kvs, attribute.Float64(attr.Key, value))
+		case []string:
+			kvs = append(kvs, attribute.StringSlice(attr.Key, value))
+		default:
+			kvs = append(kvs, attribute.String(attr.Key, fmt.Sprint(value)))
+		}
+	}
+	return kvs
+}
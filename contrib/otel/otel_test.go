@@ -0,0 +1,108 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+func TestTracerExportsNestedSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	tracing.SetTracer(NewTracer(provider))
+	defer tracing.SetTracer(nil)
+
+	ctx, crewSpan := tracing.Start(context.Background(), tracing.SpanCrewKickoff,
+		tracing.String("crew.name", "demo"),
+		tracing.Int("crew.tasks", 2),
+		tracing.Int64("crew.budget", 1000),
+		tracing.Float64("crew.temperature", 0.5),
+		tracing.Bool("crew.verbose", true),
+		tracing.Strings("crew.agents", []string{"researcher", "writer"}),
+		tracing.Attribute{Key: "crew.timeout", Value: 2 * time.Second},
+	)
+	if !crewSpan.IsRecording() {
+		t.Fatal("expected a recording span")
+	}
+
+	taskCtx, taskSpan := tracing.Start(ctx, tracing.SpanTaskExecute)
+	tracing.AddEvent(taskCtx, tracing.EventGuardrailAttempt, tracing.Int("attempt", 1))
+	taskSpan.SetAttributes(tracing.String("task.name", "research"))
+	taskSpan.RecordError(errors.New("guardrail rejected output"))
+	taskSpan.RecordError(nil)
+	taskSpan.End()
+	crewSpan.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	task, crew := spans[0], spans[1]
+	if crew.Name() != tracing.SpanCrewKickoff || task.Name() != tracing.SpanTaskExecute {
+		t.Fatalf("unexpected span names: %s, %s", crew.Name(), task.Name())
+	}
+	if task.Parent().SpanID() != crew.SpanContext().SpanID() {
+		t.Error("expected task.execute to be a child of crew.kickoff")
+	}
+
+	expected := []attribute.KeyValue{
+		attribute.String("crew.name", "demo"),
+		attribute.Int("crew.tasks", 2),
+		attribute.Int64("crew.budget", 1000),
+		attribute.Float64("crew.temperature", 0.5),
+		attribute.Bool("crew.verbose", true),
+		attribute.StringSlice("crew.agents", []string{"researcher", "writer"}),
+		attribute.String("crew.timeout", "2s"),
+	}
+	attrs := crew.Attributes()
+	if len(attrs) != len(expected) {
+		t.Fatalf("expected %d attributes, got %v", len(expected), attrs)
+	}
+	for i, kv := range expected {
+		if attrs[i].Key != kv.Key || attrs[i].Value.Emit() != kv.Value.Emit() || attrs[i].Value.Type() != kv.Value.Type() {
+			t.Errorf("attribute %d: expected %v, got %v", i, kv, attrs[i])
+		}
+	}
+
+	if task.Status().Code != codes.Error || task.Status().Description != "guardrail rejected output" {
+		t.Errorf("expected an error status, got %+v", task.Status())
+	}
+	if crew.Status().Code != codes.Unset {
+		t.Errorf("expected the crew span to have no status, got %+v", crew.Status())
+	}
+	events := task.Events()
+	if len(events) != 2 || events[0].Name != tracing.EventGuardrailAttempt || events[1].Name != "exception" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if len(events[0].Attributes) != 1 || events[0].Attributes[0] != attribute.Int("attempt", 1) {
+		t.Errorf("unexpected event attributes: %v", events[0].Attributes)
+	}
+	if got := task.Attributes(); len(got) != 1 || got[0] != attribute.String("task.name", "research") {
+		t.Errorf("unexpected task attributes: %v", got)
+	}
+}
+
+// TestExampleBuilds 示例程序可以编译（离线使用本地模块缓存）
+func TestExampleBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go build of the example in short mode")
+	}
+
+	cmd := exec.Command("go", "build", "-o", os.DevNull, "./example")
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("example does not build: %v\n%s", err, out)
+	}
+}
@@ -10,7 +10,9 @@ import (
 
 	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/knowledge/source"
 	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/tools"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
@@ -22,19 +24,6 @@ func main() {
 	fmt.Println("===============================================")
 	fmt.Println()
 
-	// 检查OpenAI API密钥
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("❌ 错误：未设置 OPENAI_API_KEY 环境变量")
-		fmt.Println()
-		fmt.Println("请先设置您的OpenAI API密钥：")
-		fmt.Println("export OPENAI_API_KEY='your-api-key-here'")
-		fmt.Println()
-		fmt.Println("或者在程序中直接设置（不推荐用于生产环境）：")
-		fmt.Println("apiKey := \"your-api-key-here\"")
-		return
-	}
-
 	// 1. 初始化基础组件
 	fmt.Println("🔧 初始化系统组件...")
 	baseLogger := logger.NewConsoleLogger()
@@ -43,17 +32,9 @@ func main() {
 	// 设置事件监听器，展示完整的事件系统
 	setupEventListeners(eventBus)
 
-	// 2. 创建真实的OpenAI LLM
-	fmt.Println("🤖 创建OpenAI LLM实例...")
-	config := &llm.Config{
-		Provider:    "openai",
-		Model:       "gpt-4o-mini", // 使用成本较低的模型
-		APIKey:      apiKey,
-		Timeout:     30 * time.Second,
-		MaxRetries:  3,
-		Temperature: func() *float64 { t := 0.7; return &t }(),
-		MaxTokens:   func() *int { t := 1500; return &t }(),
-	}
+	// 2. 创建LLM：设置了OPENAI_API_KEY时使用OpenAI，否则使用本地Ollama模型
+	config := newLLMConfig()
+	fmt.Printf("🤖 创建%s LLM实例...\n", config.Provider)
 
 	llmInstance, err := llm.CreateLLM(config)
 	if err != nil {
@@ -93,7 +74,7 @@ func main() {
 	fmt.Println("   - Crew团队协作")
 	fmt.Println("   - 事件系统监控")
 	fmt.Println("   - 错误处理和恢复")
-	fmt.Println("   - 真实的OpenAI API调用")
+	fmt.Println("   - 真实的LLM API调用（OpenAI或本地Ollama）")
 }
 
 // 场景1: 单个Agent使用工具进行研究
@@ -110,6 +91,14 @@ func demonstrateSingleAgentWithTools(llmInstance llm.LLM, eventBus events.EventB
 		Logger:    baseLogger,
 	}
 
+	// 挂载本地研究笔记目录，相关内容会以"Relevant Knowledge"的形式注入提示词
+	if knowledgeDir := findKnowledgeDir(); knowledgeDir != "" {
+		fmt.Printf("📚 加载知识目录: %s\n", knowledgeDir)
+		researcherConfig.KnowledgeSources = []agent.KnowledgeSource{
+			source.NewDirectoryKnowledgeSource(knowledgeDir, source.DefaultFileSourceOptions(), baseLogger),
+		}
+	}
+
 	researcher, err := agent.NewBaseAgent(researcherConfig)
 	if err != nil {
 		return fmt.Errorf("创建研究员失败: %w", err)
@@ -120,6 +109,13 @@ func demonstrateSingleAgentWithTools(llmInstance llm.LLM, eventBus events.EventB
 		return fmt.Errorf("添加工具失败: %w", err)
 	}
 
+	// 注入当前日期，避免报告按模型训练数据的年份撰写
+	executionConfig := researcher.GetExecutionConfig()
+	executionConfig.ContextInjection = researchContextInjection()
+	if err := researcher.SetExecutionConfig(executionConfig); err != nil {
+		return fmt.Errorf("设置执行配置失败: %w", err)
+	}
+
 	// 初始化Agent
 	if err := researcher.Initialize(); err != nil {
 		return fmt.Errorf("初始化研究员失败: %w", err)
@@ -127,7 +123,7 @@ func demonstrateSingleAgentWithTools(llmInstance llm.LLM, eventBus events.EventB
 
 	// 创建研究任务
 	researchTask := agent.NewBaseTask(
-		"研究2024年大语言模型（LLMs）的现状和未来趋势。重点关注：1）最新的模型架构 2）性能改进 3）实际应用 4）挑战和限制",
+		"研究今年大语言模型（LLMs）的现状和未来趋势。重点关注：1）最新的模型架构 2）性能改进 3）实际应用 4）挑战和限制",
 		"一份全面的研究报告，涵盖LLMs的现状，包括最新发展、性能指标、应用领域和未来趋势。报告应该详细且结构清晰。",
 	)
 
@@ -162,15 +158,26 @@ func demonstrateSingleAgentWithTools(llmInstance llm.LLM, eventBus events.EventB
 	}
 
 	// 展示工具使用统计
-	tools := researcher.GetTools()
+	researcherTools := researcher.GetTools()
 	fmt.Printf("\n🔧 工具使用统计:\n")
-	for _, tool := range tools {
+	for _, tool := range researcherTools {
 		fmt.Printf("   - %s: %d次使用\n", tool.GetName(), tool.GetUsageCount())
 	}
 
 	return nil
 }
 
+// researchContextInjection 研究报告注入的日期和语言环境
+func researchContextInjection() agent.ContextInjection {
+	return agent.ContextInjection{
+		InjectDate: true,
+		DateFormat: "2006年1月2日 15:04 MST",
+		Timezone:   "Asia/Shanghai",
+		Language:   "中文",
+		Units:      "公制",
+	}
+}
+
 // 场景2: Crew协作研究
 func demonstrateCrewResearch(llmInstance llm.LLM, eventBus events.EventBus, baseLogger logger.Logger) error {
 	fmt.Println("👥 场景2: Crew团队协作进行技术调研")
@@ -186,6 +193,8 @@ func demonstrateCrewResearch(llmInstance llm.LLM, eventBus events.EventBus, base
 		Name:    "TechResearchCrew",
 		Process: crew.ProcessSequential,
 		Verbose: true,
+		// 所有Agent使用Kickoff开始时的同一日期和语言
+		ContextInjection: researchContextInjection(),
 	}
 	researchCrew := crew.NewBaseCrew(crewConfig, eventBus, baseLogger)
 
@@ -252,8 +261,12 @@ func demonstrateCrewResearch(llmInstance llm.LLM, eventBus events.EventBus, base
 	fmt.Printf("   - Agent数量: %d\n", len(researchCrew.GetAgents()))
 	fmt.Printf("   - 任务数量: %d\n", len(researchCrew.GetTasks()))
 	if metrics != nil {
-		fmt.Printf("   - 总Token使用: %d\n", metrics.TotalTokens)
+		fmt.Printf("   - 总Token使用: %d (提示: %d, 完成: %d)\n", metrics.TotalTokens, metrics.PromptTokens, metrics.CompletionTokens)
+		fmt.Printf("   - 总成本: $%.6f\n", metrics.TotalCost)
 		fmt.Printf("   - 成功任务数: %d\n", metrics.SuccessfulTasks)
+		for role, usage := range metrics.AgentUsage {
+			fmt.Printf("   - %s: %d tokens, $%.6f (%d个任务)\n", role, usage.TotalTokens, usage.TotalCost, usage.Tasks)
+		}
 	}
 
 	return nil
@@ -328,10 +341,16 @@ func demonstrateComplexWorkflow(llmInstance llm.LLM, eventBus events.EventBus, b
 	return nil
 }
 
+// useRealScraper 为true时使用内置的网页抓取工具读取真实网页，否则使用模拟的搜索结果
+const useRealScraper = false
+
 // 辅助函数：为Agent添加研究工具
 func addResearchTools(agent agent.Agent) error {
-	// 添加网络搜索工具（模拟）
+	// 添加网络搜索工具（模拟）或真实的网页抓取工具
 	searchTool := createWebSearchTool()
+	if useRealScraper {
+		searchTool = tools.NewScrapeWebsiteTool()
+	}
 	if err := agent.AddTool(searchTool); err != nil {
 		return err
 	}
@@ -594,6 +613,60 @@ func createProductManager(llmInstance llm.LLM, eventBus events.EventBus, baseLog
 	return pm, nil
 }
 
+// newLLMConfig 根据环境变量选择LLM配置
+// 未设置OPENAI_API_KEY时回退到本地Ollama（OLLAMA_MODEL、OLLAMA_BASE_URL可覆盖默认值），便于离线开发
+func newLLMConfig() *llm.Config {
+	temperature := 0.7
+	maxTokens := 1500
+
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		return &llm.Config{
+			Provider:    "openai",
+			Model:       "gpt-4o-mini", // 使用成本较低的模型
+			APIKey:      apiKey,
+			Timeout:     30 * time.Second,
+			MaxRetries:  3,
+			Temperature: &temperature,
+			MaxTokens:   &maxTokens,
+		}
+	}
+
+	fmt.Println("ℹ️  未设置 OPENAI_API_KEY，使用本地 Ollama 模型（需先运行 ollama serve）")
+	return &llm.Config{
+		Provider:    "ollama",
+		Model:       getEnvOrDefault("OLLAMA_MODEL", "llama3.2"),
+		BaseURL:     getEnvOrDefault("OLLAMA_BASE_URL", "http://localhost:11434"),
+		Timeout:     120 * time.Second, // 本地模型首次加载较慢
+		MaxRetries:  1,
+		Temperature: &temperature,
+		MaxTokens:   &maxTokens,
+	}
+}
+
+// getEnvOrDefault 读取环境变量，未设置时返回默认值
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// findKnowledgeDir 查找研究笔记目录：优先使用RESEARCH_KNOWLEDGE_DIR，其次是示例自带的knowledge目录
+// 同时兼容在仓库根目录和示例目录下运行，找不到时返回空字符串
+func findKnowledgeDir() string {
+	candidates := []string{"knowledge", "examples/complete/ai_research/knowledge"}
+	if dir := os.Getenv("RESEARCH_KNOWLEDGE_DIR"); dir != "" {
+		candidates = []string{dir}
+	}
+
+	for _, dir := range candidates {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
 // 设置事件监听器
 func setupEventListeners(eventBus events.EventBus) {
 	// 监听Agent执行事件
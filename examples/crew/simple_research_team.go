@@ -303,6 +303,10 @@ func (m *MockLLM) GetContextWindowSize() int {
 	return 4096
 }
 
+func (m *MockLLM) GetTokenizer() llm.Tokenizer {
+	return llm.HeuristicTokenizer{}
+}
+
 func (m *MockLLM) GetModel() string {
 	return "mock-gpt-3.5-turbo"
 }
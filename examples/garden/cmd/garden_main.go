@@ -6,6 +6,7 @@ import (
 	"time"
 
 	garden "github.com/ynl/greensoulai/examples/garden"
+	"github.com/ynl/greensoulai/internal/crew"
 )
 
 func main() {
@@ -19,6 +20,18 @@ func main() {
 	}
 
 	fmt.Printf("\n✅ Garden run success: tasks=%d\n\n", len(out.TasksOutput))
-	fmt.Println("--- Aggregated Output (Raw) ---")
-	fmt.Println(out.Raw)
+	fmt.Println("--- Aggregated Output ---")
+	fmt.Println(out.AggregateRaw(crew.TaskOutputSeparator))
+
+	if out.SynthesisError != nil {
+		fmt.Println("\n⚠️  未能生成结构化报告:", out.SynthesisError)
+		return
+	}
+	if report, ok := out.Parsed.(*garden.GardenReport); ok {
+		fmt.Printf("\n--- Garden Report ---\n分区: %d, 邻里组合: %d, 季节计划: %d, 养护要点: %d\n",
+			len(report.Zones), len(report.Neighbors), len(report.SeasonalPlan), len(report.CareNotes))
+		for _, zone := range report.Zones {
+			fmt.Printf("  • %s: %v（光照: %s, 排水: %s）\n", zone.Name, zone.Flowers, zone.Sunlight, zone.Drainage)
+		}
+	}
 }
@@ -15,6 +15,23 @@ import (
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
+// GardenReport 花园布局的最终报告，由Crew在所有任务完成后综合生成
+type GardenReport struct {
+	Zones        []GardenZone `json:"zones" description:"花园分区，每个分区种植的花和环境条件"`
+	Neighbors    []string     `json:"neighbors" description:"推荐的相邻种植组合及理由"`
+	SeasonalPlan []string     `json:"seasonal_plan" description:"按季节的种植与花期安排"`
+	CareNotes    []string     `json:"care_notes" description:"日常养护要点"`
+}
+
+// GardenZone 花园中的一个分区
+type GardenZone struct {
+	Name      string   `json:"name"`
+	Flowers   []string `json:"flowers"`
+	Sunlight  string   `json:"sunlight"`
+	Drainage  string   `json:"drainage"`
+	Rationale string   `json:"rationale,omitempty"`
+}
+
 // RunGarden 构建一个包含5种花朵（Agent）的花园（Crew），
 // 以顺序流程模拟“自我介绍-相互交流-资源协商-最终布局”的业务链路。
 func RunGarden(ctx context.Context) (*crew.CrewOutput, error) {
@@ -140,11 +157,19 @@ func RunGarden(ctx context.Context) (*crew.CrewOutput, error) {
 		}
 	}
 
+	// 所有任务完成后由向日葵把讨论综合为类型化的GardenReport（结果在CrewOutput.Parsed中）
+	reportSchema, err := agent.NewOutputSchemaFromStruct(GardenReport{})
+	if err != nil {
+		return nil, fmt.Errorf("create report schema: %w", err)
+	}
+
 	// 创建Crew（顺序流程）
 	c := crew.NewBaseCrew(&crew.CrewConfig{
-		Name:    "GardenCrew",
-		Process: crew.ProcessSequential,
-		Verbose: true,
+		Name:              "GardenCrew",
+		Process:           crew.ProcessSequential,
+		Verbose:           true,
+		FinalOutputSchema: reportSchema,
+		SynthesisAgent:    sunflower,
 	}, bus, baseLogger)
 
 	// 为对话增加实时感：以非阻塞方式收集“发言”，由独立协程按节奏输出
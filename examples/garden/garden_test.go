@@ -30,13 +30,20 @@ func TestRunGarden_MinimalFlow(t *testing.T) {
 		t.Fatalf("expected 17 task outputs, got %d", len(out.TasksOutput))
 	}
 
-	// 验证顺序流程合并Raw内容
-	if out.Raw == "" {
-		t.Fatalf("expected non-empty aggregated raw output")
+	// Raw为最后一个任务的输出
+	if out.Raw == "" || out.Raw != out.TasksOutput[len(out.TasksOutput)-1].Raw {
+		t.Fatalf("expected raw output of the final task")
 	}
 
 	// 基本成功标志
 	if !out.Success {
 		t.Fatalf("expected success=true, got false")
 	}
+
+	// 综合成功时Parsed为类型化的报告；综合失败不影响执行结果
+	if out.SynthesisError == nil {
+		if _, ok := out.Parsed.(*GardenReport); !ok {
+			t.Fatalf("expected *GardenReport in Parsed, got %T", out.Parsed)
+		}
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/ynl/greensoulai/internal/llm"
@@ -13,16 +14,26 @@ func main() {
 	fmt.Println("🚀 GreenSoulAI LLM 模块演示")
 	fmt.Println("=========================")
 
-	// 1. 创建LLM配置
+	// 1. 创建LLM配置：设置了OPENAI_API_KEY时使用OpenAI，否则使用本地Ollama模型
 	config := &llm.Config{
 		Provider:    "openai",
 		Model:       "gpt-4o-mini",
-		APIKey:      "your-api-key-here", // 在实际使用中设置真实的API密钥
+		APIKey:      os.Getenv("OPENAI_API_KEY"),
 		Timeout:     30 * time.Second,
 		MaxRetries:  3,
 		Temperature: func() *float64 { t := 0.7; return &t }(),
 		MaxTokens:   func() *int { t := 1000; return &t }(),
 	}
+	if config.APIKey == "" {
+		fmt.Println("ℹ️  未设置 OPENAI_API_KEY，使用本地 Ollama 模型（需先运行 ollama serve）")
+		config.Provider = "ollama"
+		config.Model = "llama3.2"
+		if model := os.Getenv("OLLAMA_MODEL"); model != "" {
+			config.Model = model
+		}
+		config.BaseURL = os.Getenv("OLLAMA_BASE_URL") // 为空时使用 http://localhost:11434
+		config.Timeout = 120 * time.Second            // 本地模型首次加载较慢
+	}
 
 	// 2. 创建LLM实例
 	llmInstance, err := llm.CreateLLM(config)
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
@@ -207,6 +208,8 @@ func (t *MockTask) HasTools() bool
 func (t *MockTask) Validate() error                                                     { return nil }
 func (t *MockTask) GetAssignedAgent() agent.Agent                                       { return nil }
 func (t *MockTask) SetAssignedAgent(agent agent.Agent) error                            { return nil }
+func (t *MockTask) GetAgentRole() string                                                { return "" }
+func (t *MockTask) SetAgentRole(role string)                                            {}
 func (t *MockTask) IsAsyncExecution() bool                                              { return false }
 func (t *MockTask) SetAsyncExecution(async bool)                                        {}
 func (t *MockTask) SetContext(context map[string]interface{})                           {}
@@ -214,17 +217,29 @@ func (t *MockTask) GetName() string
 func (t *MockTask) SetName(name string)                                                 {}
 func (t *MockTask) GetOutputFile() string                                               { return "" }
 func (t *MockTask) SetOutputFile(filename string) error                                 { return nil }
+func (t *MockTask) IsOutputFileAppend() bool                                            { return false }
+func (t *MockTask) SetOutputFileAppend(appendOutput bool)                               {}
 func (t *MockTask) GetCreateDirectory() bool                                            { return false }
 func (t *MockTask) SetCreateDirectory(create bool)                                      {}
 func (t *MockTask) GetCallback() func(context.Context, *agent.TaskOutput) error         { return nil }
 func (t *MockTask) SetCallback(callback func(context.Context, *agent.TaskOutput) error) {}
 func (t *MockTask) GetContextTasks() []agent.Task                                       { return nil }
 func (t *MockTask) SetContextTasks(tasks []agent.Task)                                  {}
+func (t *MockTask) GetOutputSchema() *agent.OutputSchema                                { return nil }
+func (t *MockTask) SetOutputSchema(schema *agent.OutputSchema)                          {}
+func (t *MockTask) GetDependsOn() []string                                              { return nil }
+func (t *MockTask) SetDependsOn(taskIDs []string)                                       {}
+func (t *MockTask) IsCacheDisabled() bool                                               { return false }
+func (t *MockTask) SetCacheDisabled(disabled bool)                                      {}
 func (t *MockTask) GetRetryCount() int                                                  { return 0 }
 func (t *MockTask) GetMaxRetries() int                                                  { return 0 }
 func (t *MockTask) SetMaxRetries(maxRetries int)                                        {}
 func (t *MockTask) IsMarkdownOutput() bool                                              { return false }
 func (t *MockTask) SetMarkdownOutput(markdown bool)                                     {}
+func (t *MockTask) IsApprovalRequired() bool                                            { return false }
+func (t *MockTask) SetApprovalRequired(required bool)                                   {}
+func (t *MockTask) AddImage(source string) error                                        { return nil }
+func (t *MockTask) GetImages() []llm.ContentPart                                        { return nil }
 func (t *MockTask) HasGuardrail() bool                                                  { return false }
 func (t *MockTask) GetGuardrail() agent.TaskGuardrail                                   { return nil }
 func (t *MockTask) SetGuardrail(guardrail agent.TaskGuardrail)                          {}
@@ -205,6 +205,7 @@ func (m *mockLLMImpl) CallStream(ctx context.Context, messages []llm.Message, op
 func (m *mockLLMImpl) GetModel() string                     { return "demo-model" }
 func (m *mockLLMImpl) SupportsFunctionCalling() bool        { return false }
 func (m *mockLLMImpl) GetContextWindowSize() int            { return 4096 }
+func (m *mockLLMImpl) GetTokenizer() llm.Tokenizer          { return llm.HeuristicTokenizer{} }
 func (m *mockLLMImpl) SetEventBus(eventBus events.EventBus) {}
 func (m *mockLLMImpl) Close() error                         { return nil }
 
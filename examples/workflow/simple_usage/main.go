@@ -5,9 +5,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/flow"
+	"github.com/ynl/greensoulai/pkg/flow/agentflow"
+	"github.com/ynl/greensoulai/pkg/logger"
 )
 
 func main() {
@@ -20,36 +27,49 @@ func main() {
 	workflow := flow.NewWorkflow("ai-processing")
 
 	// 定义作业 - 注意这里是Job，不是Task，避免与Agent系统冲突
-	dataCollection := flow.NewJob("data-collection", func(ctx context.Context) (interface{}, error) {
+	dataCollection := flow.NewStatefulJob("data-collection", func(ctx context.Context, state flow.FlowState) (interface{}, error) {
 		fmt.Println("   📥 数据收集作业执行中...")
 		time.Sleep(100 * time.Millisecond)
+		state.Set("records", "1000条用户评论")
 		return "收集了1000条数据", nil
 	})
 
-	// 三个可以并行执行的分析作业
-	qualityAnalysis := flow.NewJob("quality-analysis", func(ctx context.Context) (interface{}, error) {
-		fmt.Println("   🔍 质量分析作业执行中...")
-		time.Sleep(150 * time.Millisecond)
-		return "质量评分: 85%", nil
-	})
+	// 三个可以并行执行的分析作业 - 每个都由Agent执行（真实场景中替换为OpenAI等LLM）
+	mockLLM := &MockLLM{responses: map[string]string{
+		"quality":   "质量评分: 85%",
+		"sentiment": "正面情感: 78%",
+		"topics":    "主要话题: AI, 技术, 创新",
+	}}
+	recordsContext := func(state flow.FlowState) map[string]interface{} {
+		records, _ := state.GetString("records")
+		return map[string]interface{}{"records": records}
+	}
 
-	sentimentAnalysis := flow.NewJob("sentiment-analysis", func(ctx context.Context) (interface{}, error) {
-		fmt.Println("   😊 情感分析作业执行中...")
-		time.Sleep(120 * time.Millisecond)
-		return "正面情感: 78%", nil
-	})
+	qualityAnalysis := agentflow.NewAgentJob("quality-analysis",
+		newAnalyst("Quality Analyst", mockLLM),
+		agent.NewBaseTask("Assess the quality of the collected records", "A quality score"),
+	).WithContextFromState(recordsContext)
 
-	topicAnalysis := flow.NewJob("topic-analysis", func(ctx context.Context) (interface{}, error) {
-		fmt.Println("   📊 主题分析作业执行中...")
-		time.Sleep(180 * time.Millisecond)
-		return "主要话题: AI, 技术, 创新", nil
-	})
+	sentimentAnalysis := agentflow.NewAgentJob("sentiment-analysis",
+		newAnalyst("Sentiment Analyst", mockLLM),
+		agent.NewBaseTask("Measure the sentiment of the collected records", "A sentiment summary"),
+	).WithContextFromState(recordsContext)
+
+	topicAnalysis := agentflow.NewAgentJob("topic-analysis",
+		newAnalyst("Topic Analyst", mockLLM),
+		agent.NewBaseTask("Extract the main topics of the collected records", "A list of topics"),
+	).WithContextFromState(recordsContext)
 
 	// 报告生成作业
-	reportGeneration := flow.NewJob("report-generation", func(ctx context.Context) (interface{}, error) {
+	reportGeneration := flow.NewStatefulJob("report-generation", func(ctx context.Context, state flow.FlowState) (interface{}, error) {
 		fmt.Println("   📝 报告生成作业执行中...")
-		time.Sleep(80 * time.Millisecond)
-		return "AI分析报告已生成", nil
+		var findings []string
+		for _, id := range []string{"quality-analysis", "sentiment-analysis", "topic-analysis"} {
+			if output, ok := agentflow.GetTaskOutput(state, id); ok {
+				findings = append(findings, output.Raw)
+			}
+		}
+		return "AI分析报告: " + strings.Join(findings, "; "), nil
 	})
 
 	// 构建工作流 - API清晰表达执行逻辑
@@ -102,5 +122,47 @@ func main() {
 	fmt.Printf("\n   🏗️ **架构层次**:\n")
 	fmt.Printf("   Workflow (工作流) \n")
 	fmt.Printf("   └── Job (作业单元) \n")
-	fmt.Printf("       └── Agent Task (智能体任务) [未来集成]\n")
+	fmt.Printf("       └── Agent Task (智能体任务) [agentflow.NewAgentJob]\n")
+}
+
+// newAnalyst 创建执行分析任务的Agent
+func newAnalyst(role string, model llm.LLM) agent.Agent {
+	analyst, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      role,
+		Goal:      "Analyze the collected records",
+		Backstory: "You are an experienced data analyst",
+		LLM:       model,
+		Logger:    logger.NewConsoleLogger(),
+	})
+	if err != nil {
+		log.Fatalf("failed to create %s: %v", role, err)
+	}
+	return analyst
+}
+
+// MockLLM 按任务中的关键字返回预设分析结果的Mock实现
+type MockLLM struct {
+	responses map[string]string
 }
+
+func (m *MockLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	time.Sleep(100 * time.Millisecond) // 模拟模型延迟，便于观察并行效果
+	prompt, _ := messages[len(messages)-1].Content.(string)
+	for keyword, response := range m.responses {
+		if strings.Contains(prompt, keyword) {
+			return &llm.Response{Content: response, Model: m.GetModel(), FinishReason: "stop"}, nil
+		}
+	}
+	return &llm.Response{Content: "无法分析", Model: m.GetModel(), FinishReason: "stop"}, nil
+}
+
+func (m *MockLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	return nil, fmt.Errorf("streaming not supported by mock LLM")
+}
+
+func (m *MockLLM) SetEventBus(eventBus events.EventBus) {}
+func (m *MockLLM) GetContextWindowSize() int            { return 4096 }
+func (m *MockLLM) GetTokenizer() llm.Tokenizer          { return llm.HeuristicTokenizer{} }
+func (m *MockLLM) GetModel() string                     { return "mock-analyst" }
+func (m *MockLLM) SupportsFunctionCalling() bool        { return false }
+func (m *MockLLM) Close() error                         { return nil }
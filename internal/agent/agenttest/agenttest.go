@@ -0,0 +1,53 @@
+// Package agenttest 提供端到端运行BaseAgent的测试辅助函数，供其他包的测试共用
+package agenttest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// FirstPrompt 用记录提示词的LLM替换config.LLM，执行任务并返回发送给LLM的第一条用户提示词
+// 用于验证记忆、知识等上下文是否被注入到提示词中；未设置的Role/Goal/Backstory使用占位值
+func FirstPrompt(t testing.TB, config agent.AgentConfig, task agent.Task) string {
+	t.Helper()
+
+	recorder := llmtest.NewScriptedLLM().WithDefault(llmtest.Reply{Content: "Final Answer: done"})
+	config.LLM = recorder
+	if config.Role == "" {
+		config.Role = "tester"
+	}
+	if config.Goal == "" {
+		config.Goal = "test"
+	}
+	if config.Backstory == "" {
+		config.Backstory = "test agent"
+	}
+	if config.Logger == nil {
+		config.Logger = logger.NewTestLogger()
+	}
+	if config.EventBus == nil {
+		config.EventBus = events.NewEventBus(config.Logger)
+	}
+
+	a, err := agent.NewBaseAgent(config)
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	if err := a.Initialize(); err != nil {
+		t.Fatalf("failed to initialize agent: %v", err)
+	}
+	if _, err := a.Execute(context.Background(), task); err != nil {
+		t.Fatalf("agent execution failed: %v", err)
+	}
+
+	calls := recorder.Calls()
+	if len(calls) == 0 {
+		t.Fatal("expected the agent to call the LLM")
+	}
+	return calls[0].UserPrompt()
+}
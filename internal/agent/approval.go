@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// ApprovalPolicy 等待审批超时后的处理策略
+type ApprovalPolicy string
+
+const (
+	ApprovalPolicyApprove ApprovalPolicy = "approve" // 超时视为批准
+	ApprovalPolicyFail    ApprovalPolicy = "fail"    // 超时视为任务失败
+)
+
+// ErrApprovalRejected 任务输出在用尽修订次数后仍被审批人拒绝
+var ErrApprovalRejected = errors.New("task output rejected by reviewer")
+
+// requestApproval 请求人工审批任务输出
+// 批准时返回原输出；修改时用审批人的文本替换输出；拒绝时带着反馈重新执行，最多MaxApprovalRevisions次；
+// 等待超时时按ApprovalTimeoutPolicy批准或失败。审批结果记录在Metadata的"approval_status"和"approval_revisions"中
+func (a *BaseAgent) requestApproval(ctx context.Context, task Task, toolCtx *ToolExecutionContext, messages []llm.Message, callOptions *llm.CallOptions, output *TaskOutput) (*TaskOutput, error) {
+	if a.humanInputHandler == nil {
+		return nil, fmt.Errorf("task %s requires approval but no human input handler is configured", task.GetID())
+	}
+
+	// 复制消息，避免修改调用方的切片
+	messages = append([]llm.Message(nil), messages...)
+
+	var rejected llm.Usage
+	for revision := 0; ; revision++ {
+		response, err := a.awaitApproval(ctx, task, output, revision)
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+				return nil, fmt.Errorf("approval request failed for task %s: %w", task.GetID(), err)
+			}
+			if a.executionConfig.ApprovalTimeoutPolicy != ApprovalPolicyApprove {
+				return nil, fmt.Errorf("approval for task %s timed out: %w", task.GetID(), err)
+			}
+			a.logger.Warn("Approval timed out, approving output by policy",
+				logger.Field{Key: "task_id", Value: task.GetID()},
+			)
+			response = &HumanInputResponse{Option: ApprovalOptionApprove}
+			output.Metadata["approval_timed_out"] = true
+		}
+
+		switch response.Option {
+		case ApprovalOptionApprove:
+			output.Metadata["approval_status"] = "approved"
+
+		case ApprovalOptionEdit:
+			if strings.TrimSpace(response.Text) == "" {
+				return nil, fmt.Errorf("approval edit for task %s did not include the revised output", task.GetID())
+			}
+			output.Raw = response.Text
+			output.Summary = a.generateSummary(response.Text)
+			output.JSON = nil
+			output.Parsed = nil
+			output.Pydantic = nil
+			output.Metadata["approval_status"] = "edited"
+
+		case ApprovalOptionReject:
+			feedback := strings.TrimSpace(response.Text)
+			if feedback == "" {
+				feedback = "no feedback was given"
+			}
+			a.logger.Info("Task output rejected by reviewer",
+				logger.Field{Key: "task_id", Value: task.GetID()},
+				logger.Field{Key: "revision", Value: revision + 1},
+				logger.Field{Key: "feedback", Value: feedback},
+			)
+			if revision >= a.executionConfig.MaxApprovalRevisions {
+				return nil, fmt.Errorf("%w: task %s after %d revisions: %s", ErrApprovalRejected, task.GetID(), revision, feedback)
+			}
+
+			rejected.TotalTokens += output.TokensUsed
+			rejected.PromptTokens += output.PromptTokens
+			rejected.CompletionTokens += output.CompletionTokens
+			rejected.Cost += output.Cost
+
+			messages = append(messages,
+				llm.Message{Role: llm.RoleAssistant, Content: output.Raw},
+				llm.Message{Role: llm.RoleUser, Content: buildApprovalFeedbackPrompt(a.prompts, feedback)},
+			)
+			if output, err = a.generateValidatedOutput(ctx, task, toolCtx, messages, callOptions); err != nil {
+				return nil, err
+			}
+			continue
+
+		default:
+			return nil, fmt.Errorf("unrecognized approval decision %q for task %s", response.Option, task.GetID())
+		}
+
+		addUsageToOutput(output, rejected)
+		output.Metadata["approval_revisions"] = revision
+		return output, nil
+	}
+}
+
+// awaitApproval 向人工输入处理器发送审批请求，等待时间受ApprovalTimeout限制
+func (a *BaseAgent) awaitApproval(ctx context.Context, task Task, output *TaskOutput, revision int) (*HumanInputResponse, error) {
+	if timeout := a.executionConfig.ApprovalTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	request := HumanInputRequest{
+		Type: HumanInputRequestApproval,
+		Prompt: fmt.Sprintf("Please review the output of task %q:\n\n%s\n\n"+
+			"Approve it, reject it with feedback for the agent, or edit it.", task.GetDescription(), output.Raw),
+		Options: []string{ApprovalOptionApprove, ApprovalOptionReject, ApprovalOptionEdit},
+		Metadata: map[string]interface{}{
+			"task_id":  task.GetID(),
+			"agent":    a.role,
+			"output":   output.Raw,
+			"revision": revision,
+		},
+	}
+	return a.humanInputHandler.RequestInput(ctx, request)
+}
+
+// buildApprovalFeedbackPrompt 构建要求Agent根据审批反馈修改答案的提示
+func buildApprovalFeedbackPrompt(prompts PromptStrings, feedback string) string {
+	return fmt.Sprintf(prompts.ApprovalFeedback, feedback)
+}
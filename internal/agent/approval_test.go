@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// blockingInputHandler 直到上下文结束都不返回的输入处理器，用于测试审批超时
+type blockingInputHandler struct {
+	*NoInputHandler
+}
+
+func (b *blockingInputHandler) RequestInput(ctx context.Context, request HumanInputRequest) (*HumanInputResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newApprovalTestAgent(t *testing.T, mockLLM llm.LLM, handler HumanInputHandler, configure func(*ExecutionConfig)) *BaseAgent {
+	t.Helper()
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:              "Writer",
+		Goal:              "Write announcements",
+		Backstory:         "Careful",
+		LLM:               mockLLM,
+		Logger:            logger.NewTestLogger(),
+		HumanInputHandler: handler,
+	})
+	require.NoError(t, err)
+
+	config := DefaultExecutionConfig()
+	if configure != nil {
+		configure(&config)
+	}
+	require.NoError(t, agent.SetExecutionConfig(config))
+	return agent
+}
+
+// TestParseHumanInputResponse 测试文本输入解析为结构化响应
+func TestParseHumanInputResponse(t *testing.T) {
+	options := []string{ApprovalOptionApprove, ApprovalOptionReject, ApprovalOptionEdit}
+
+	assert.Equal(t, &HumanInputResponse{Option: "approve"}, ParseHumanInputResponse(" Approve ", options))
+	assert.Equal(t, &HumanInputResponse{Option: "reject"}, ParseHumanInputResponse("2", options))
+	assert.Equal(t, &HumanInputResponse{Option: "reject", Text: "too long: cut it"}, ParseHumanInputResponse("reject: too long: cut it", options))
+	assert.Equal(t, &HumanInputResponse{Text: "maybe later"}, ParseHumanInputResponse("maybe later", options))
+	assert.Equal(t, &HumanInputResponse{Text: "note: free text"}, ParseHumanInputResponse("note: free text", nil))
+}
+
+// TestAgentApprovalRejectThenApprove 测试拒绝后带着反馈重新执行，批准后返回新输出
+func TestAgentApprovalRejectThenApprove(t *testing.T) {
+	var prompts []string
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: "Draft announcement", Usage: llm.Usage{TotalTokens: 10}},
+		{Content: "Revised announcement", Usage: llm.Usage{TotalTokens: 7}},
+	}).WithCallHandler(func(messages []llm.Message) {
+		prompts = append(prompts, messages[len(messages)-1].Content.(string))
+	})
+	handler := NewMockInputHandler([]string{"reject: mention the release date", "1"}, logger.NewTestLogger())
+	agent := newApprovalTestAgent(t, mockLLM, handler, nil)
+
+	task := NewTaskWithOptions("Write an announcement", "An announcement", WithApproval(true))
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Revised announcement", output.Raw)
+	assert.Equal(t, "approved", output.Metadata["approval_status"])
+	assert.Equal(t, 1, output.Metadata["approval_revisions"])
+	assert.Equal(t, 17, output.TokensUsed)
+
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[1], "rejected your previous answer with this feedback: mention the release date")
+}
+
+// TestAgentApprovalEdit 测试审批人修改输出
+func TestAgentApprovalEdit(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: `{"title": "Draft"}`}})
+	handler := NewMockInputHandler([]string{"edit: Final announcement"}, logger.NewTestLogger())
+	agent := newApprovalTestAgent(t, mockLLM, handler, nil)
+
+	task := NewTaskWithOptions("Write an announcement", "An announcement", WithApproval(true))
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Final announcement", output.Raw)
+	assert.Nil(t, output.JSON)
+	assert.Equal(t, "edited", output.Metadata["approval_status"])
+	assert.Equal(t, 1, mockLLM.callCount)
+}
+
+// TestAgentApprovalRevisionsExhausted 测试修订次数耗尽后任务失败
+func TestAgentApprovalRevisionsExhausted(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "Draft"}})
+	handler := NewMockInputHandler([]string{"reject: no", "reject: still no"}, logger.NewTestLogger())
+	agent := newApprovalTestAgent(t, mockLLM, handler, func(config *ExecutionConfig) {
+		config.MaxApprovalRevisions = 1
+	})
+
+	_, err := agent.Execute(context.Background(), NewTaskWithOptions("Write", "Text", WithApproval(true)))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrApprovalRejected))
+	assert.Contains(t, err.Error(), "still no")
+	assert.Equal(t, 2, mockLLM.callCount)
+}
+
+// TestAgentApprovalTimeoutPolicy 测试审批超时后按策略批准或失败
+func TestAgentApprovalTimeoutPolicy(t *testing.T) {
+	handler := &blockingInputHandler{NoInputHandler: NewNoInputHandler(logger.NewTestLogger())}
+
+	approveAgent := newApprovalTestAgent(t, NewExtendedMockLLM([]llm.Response{{Content: "Draft"}}), handler, func(config *ExecutionConfig) {
+		config.ApprovalTimeout = 10 * time.Millisecond
+		config.ApprovalTimeoutPolicy = ApprovalPolicyApprove
+	})
+	output, err := approveAgent.Execute(context.Background(), NewTaskWithOptions("Write", "Text", WithApproval(true)))
+	require.NoError(t, err)
+	assert.Equal(t, "approved", output.Metadata["approval_status"])
+	assert.Equal(t, true, output.Metadata["approval_timed_out"])
+
+	failAgent := newApprovalTestAgent(t, NewExtendedMockLLM([]llm.Response{{Content: "Draft"}}), handler, func(config *ExecutionConfig) {
+		config.ApprovalTimeout = 10 * time.Millisecond
+		config.ApprovalTimeoutPolicy = ApprovalPolicyFail
+	})
+	_, err = failAgent.Execute(context.Background(), NewTaskWithOptions("Write", "Text", WithApproval(true)))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+// TestWebhookInputHandler 测试webhook处理器发送结构化请求并解析响应
+func TestWebhookInputHandler(t *testing.T) {
+	var received HumanInputRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		json.NewEncoder(w).Encode(HumanInputResponse{Option: ApprovalOptionEdit, Text: "Edited by reviewer"})
+	}))
+	defer server.Close()
+
+	handler := NewWebhookInputHandler(server.URL, logger.NewTestLogger())
+	handler.SetHeader("Authorization", "Bearer secret")
+	agent := newApprovalTestAgent(t, NewExtendedMockLLM([]llm.Response{{Content: "Draft"}}), handler, nil)
+
+	task := NewTaskWithOptions("Write", "Text", WithApproval(true))
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Edited by reviewer", output.Raw)
+	assert.Equal(t, HumanInputRequestApproval, received.Type)
+	assert.Equal(t, []string{"approve", "reject", "edit"}, received.Options)
+	assert.Equal(t, task.GetID(), received.Metadata["task_id"])
+	assert.Equal(t, "Draft", received.Metadata["output"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	_, err = NewWebhookInputHandler(failing.URL, logger.NewTestLogger()).RequestInput(context.Background(), HumanInputRequest{Prompt: "?"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 503")
+}
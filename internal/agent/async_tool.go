@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrToolTimeout 工具执行超过了工具声明的超时时间
+var ErrToolTimeout = errors.New("tool execution timed out")
+
+// ToolProgress 长时间运行的工具报告的一块进度
+// 最后一块的Final为true并携带Result；带Error的块表示执行失败，同样结束执行
+type ToolProgress struct {
+	Percent float64     `json:"percent"`           // 完成百分比，0-100
+	Message string      `json:"message,omitempty"` // 进度说明
+	Partial interface{} `json:"partial,omitempty"` // 目前为止的部分结果
+	Result  interface{} `json:"result,omitempty"`  // 最终结果，Final为true时有效
+	Error   error       `json:"-"`
+	Final   bool        `json:"final"`
+}
+
+// AsyncTool 执行时报告进度的长时间运行工具，如爬取、数据导出
+// Tool.ExecuteAsync返回单个结果，因此进度通过单独的ExecuteWithProgress方法提供。
+// 工具必须以一个Final块或带Error的块结束，然后关闭通道；ctx取消时应尽快停止
+type AsyncTool interface {
+	Tool
+	ExecuteWithProgress(ctx context.Context, args map[string]interface{}) (<-chan ToolProgress, error)
+}
+
+// TimeoutTool 声明单次执行超时时间的工具，超时后执行被取消，LLM收到超时的观察
+type TimeoutTool interface {
+	Tool
+	ExecutionTimeout() time.Duration // <=0表示不限制
+}
+
+// AsAsyncTool 返回报告进度的工具；同步工具被适配为只产生一个Final块，行为不变
+func AsAsyncTool(tool Tool) AsyncTool {
+	if asyncTool, ok := tool.(AsyncTool); ok {
+		return asyncTool
+	}
+	return syncToolAdapter{tool}
+}
+
+// syncToolAdapter 把同步工具的结果作为唯一的Final块
+type syncToolAdapter struct {
+	Tool
+}
+
+func (a syncToolAdapter) ExecuteWithProgress(ctx context.Context, args map[string]interface{}) (<-chan ToolProgress, error) {
+	progress := make(chan ToolProgress, 1)
+	go func() {
+		defer close(progress)
+		result, err := a.Tool.Execute(ctx, args)
+		progress <- ToolProgress{Percent: 100, Result: result, Error: err, Final: true}
+	}()
+	return progress, nil
+}
+
+// runTool 执行工具并消费进度块，非最终的块交给onProgress，返回最终块的结果
+// 工具声明了超时时间时超时后取消执行并返回ErrToolTimeout；调用方的ctx取消时同样取消工具
+func runTool(ctx context.Context, tool Tool, args map[string]interface{}, onProgress func(ToolProgress)) (interface{}, error) {
+	execCtx := ctx
+	var timeout time.Duration
+	if timeoutTool, ok := tool.(TimeoutTool); ok {
+		timeout = timeoutTool.ExecutionTimeout()
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	progress, err := AsAsyncTool(tool).ExecuteWithProgress(execCtx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case chunk, ok := <-progress:
+			if !ok {
+				return nil, fmt.Errorf("tool '%s' finished without a result", tool.GetName())
+			}
+			if chunk.Final || chunk.Error != nil {
+				return chunk.Result, chunk.Error
+			}
+			if onProgress != nil {
+				onProgress(chunk)
+			}
+		case <-execCtx.Done():
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("tool '%s' cancelled: %w", tool.GetName(), ctx.Err())
+			}
+			return nil, fmt.Errorf("%w: tool '%s' did not finish within %s", ErrToolTimeout, tool.GetName(), timeout)
+		}
+	}
+}
+
+// ProgressReporter 长时间运行的工具报告进度的函数
+type ProgressReporter func(progress ToolProgress)
+
+type progressReporterKey struct{}
+
+// BaseAsyncTool 报告进度的基础工具，使用计数、缓存和超时设置与BaseTool相同
+// 处理函数通过report报告进度，返回值作为最终结果；以Execute同步调用时进度被丢弃
+type BaseAsyncTool struct {
+	*BaseTool
+}
+
+var _ AsyncTool = (*BaseAsyncTool)(nil)
+
+// NewBaseAsyncTool 创建报告进度的基础工具
+func NewBaseAsyncTool(name, description string, handler func(ctx context.Context, args map[string]interface{}, report ProgressReporter) (interface{}, error)) *BaseAsyncTool {
+	return &BaseAsyncTool{BaseTool: NewBaseTool(name, description, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		report, ok := ctx.Value(progressReporterKey{}).(ProgressReporter)
+		if !ok {
+			report = func(ToolProgress) {}
+		}
+		return handler(ctx, args, report)
+	})}
+}
+
+// ExecuteWithProgress 在后台执行工具，进度和最终结果依次写入返回的通道
+// 调用方停止读取并取消ctx后，尚未送出的进度会被丢弃
+func (t *BaseAsyncTool) ExecuteWithProgress(ctx context.Context, args map[string]interface{}) (<-chan ToolProgress, error) {
+	progress := make(chan ToolProgress, 16)
+	report := ProgressReporter(func(chunk ToolProgress) {
+		chunk.Final = false
+		select {
+		case progress <- chunk:
+		case <-ctx.Done():
+		}
+	})
+
+	go func() {
+		defer close(progress)
+		result, err := t.BaseTool.Execute(context.WithValue(ctx, progressReporterKey{}, report), args)
+		select {
+		case progress <- ToolProgress{Percent: 100, Result: result, Error: err, Final: true}:
+		case <-ctx.Done():
+		}
+	}()
+	return progress, nil
+}
+
+// WithTimeout 设置单次执行的超时时间
+func (t *BaseAsyncTool) WithTimeout(timeout time.Duration) *BaseAsyncTool {
+	t.BaseTool.WithTimeout(timeout)
+	return t
+}
+
+// Clone 返回使用计数清零的副本，副本仍然报告进度
+func (t *BaseAsyncTool) Clone() Tool {
+	return &BaseAsyncTool{BaseTool: t.BaseTool.Clone().(*BaseTool)}
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// newCrawlTool 每爬取一页报告一次进度，最后返回页数
+func newCrawlTool(pages int, delay time.Duration) *BaseAsyncTool {
+	return NewBaseAsyncTool("crawl", "Crawl a site", func(ctx context.Context, args map[string]interface{}, report ProgressReporter) (interface{}, error) {
+		for i := 1; i <= pages; i++ {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			report(ToolProgress{Percent: float64(i) * 100 / float64(pages), Message: "crawled page", Partial: i})
+		}
+		return "crawled all pages", nil
+	})
+}
+
+func toolCall(name string) llm.ToolCall {
+	return llm.ToolCall{ID: "call_1", Type: "function", Function: llm.ToolCallFunction{Name: name, Arguments: "{}"}}
+}
+
+// TestAsyncToolProgressForwarded 测试进度转发为事件和步骤回调，最终结果作为观察返回给LLM
+func TestAsyncToolProgressForwarded(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{ToolCalls: []llm.ToolCall{toolCall("crawl")}},
+		{Content: "Crawl finished"},
+	})
+	mockLLM.WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, append([]llm.Message(nil), messages...))
+	})
+
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+	var mu sync.Mutex
+	var progressEvents []*AgentToolUsageProgressEvent
+	_, err := eventBus.SubscribeWithOptions(events.EventTypeToolUsageProgress, func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		progressEvents = append(progressEvents, event.(*AgentToolUsageProgressEvent))
+		return nil
+	}, events.WithSyncDelivery())
+	require.NoError(t, err)
+
+	agent := newToolLoopTestAgent(t, mockLLM, eventBus, newCrawlTool(3, time.Millisecond))
+	var steps []*AgentStep
+	agent.SetStepCallback(func(ctx context.Context, step *AgentStep) error {
+		steps = append(steps, step)
+		return nil
+	})
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Crawl the docs", "A summary"))
+	require.NoError(t, err)
+	assert.Equal(t, "Crawl finished", output.Raw)
+
+	require.Len(t, progressEvents, 3)
+	assert.Equal(t, "crawl", progressEvents[0].ToolName)
+	assert.InDelta(t, 100, progressEvents[2].Progress.Percent, 1e-9)
+	assert.Equal(t, 3, progressEvents[2].Payload["partial"])
+
+	require.Len(t, steps, 4)
+	assert.Equal(t, "tool_progress", steps[0].StepType)
+	assert.Equal(t, 1, steps[0].Output)
+	assert.Equal(t, "tool_call", steps[3].StepType)
+
+	require.Len(t, calls, 2)
+	last := calls[1][len(calls[1])-1]
+	assert.Equal(t, "crawled all pages", last.Content)
+}
+
+// TestToolTimeoutObservation 测试超时的工具被取消，LLM收到超时的观察
+func TestToolTimeoutObservation(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{ToolCalls: []llm.ToolCall{toolCall("crawl")}},
+		{Content: "Gave up"},
+	})
+	mockLLM.WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, append([]llm.Message(nil), messages...))
+	})
+
+	cancelled := make(chan struct{})
+	slow := NewBaseAsyncTool("crawl", "Crawl a site", func(ctx context.Context, args map[string]interface{}, report ProgressReporter) (interface{}, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}).WithTimeout(20 * time.Millisecond)
+
+	agent := newToolLoopTestAgent(t, mockLLM, nil, slow)
+	output, err := agent.Execute(context.Background(), NewBaseTask("Crawl the docs", "A summary"))
+	require.NoError(t, err)
+	assert.Equal(t, "Gave up", output.Raw)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the tool's context to be cancelled")
+	}
+	require.Len(t, calls, 2)
+	last := calls[1][len(calls[1])-1]
+	assert.Contains(t, last.Content, "timed out")
+	assert.Contains(t, last.Content, "20ms")
+}
+
+// TestRunToolSyncAdapterAndCancellation 测试同步工具经适配器执行，调用方取消时工具被取消
+func TestRunToolSyncAdapterAndCancellation(t *testing.T) {
+	calc := NewCalculatorTool()
+	result, err := runTool(context.Background(), calc, map[string]interface{}{"operation": "add", "a": 2.0, "b": 3.0}, func(ToolProgress) {
+		t.Error("sync tools must not report progress")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, result)
+	assert.Equal(t, 1, calc.GetUsageCount())
+
+	// 同步工具同样遵守超时
+	blocking := NewBaseTool("wait", "Waits", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}).WithTimeout(10 * time.Millisecond)
+	_, err = runTool(context.Background(), blocking, nil, nil)
+	assert.True(t, errors.Is(err, ErrToolTimeout), "expected a timeout, got %v", err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err = runTool(ctx, newCrawlTool(100, 5*time.Millisecond), nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, errors.Is(err, ErrToolTimeout))
+
+	// 克隆保留进度报告和超时设置
+	clone := newCrawlTool(1, 0).WithTimeout(time.Minute).Clone()
+	_, isAsync := clone.(AsyncTool)
+	assert.True(t, isAsync)
+	assert.Equal(t, time.Minute, clone.(TimeoutTool).ExecutionTimeout())
+}
@@ -2,10 +2,12 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,8 +29,17 @@ type BaseAgent struct {
 	llmProvider       llm.LLM
 	tools             []Tool
 	memory            Memory
+	memorySuite       MemorySuite
 	knowledgeSources  []KnowledgeSource
+	toolProviders     []ToolProvider
+	providedTools     map[string]bool // 由ToolProvider加入的工具名，Close时移除
 	humanInputHandler HumanInputHandler
+	rpmController     *RPMController    // 速率控制器，可在Crew内多个Agent间共享
+	responseCache     llm.ResponseCache // LLM响应缓存，可在Crew内多个Agent间共享
+	toolCache         ToolCache         // 工具结果缓存，由Crew注入
+
+	// 训练得到的改进指令，对应Python版本trained_agents_data中的suggestions
+	trainedInstructions []string
 
 	// 配置
 	executionConfig ExecutionConfig
@@ -39,10 +50,18 @@ type BaseAgent struct {
 	logger   logger.Logger
 
 	// 模板和配置
-	systemTemplate string
-	promptTemplate string
-	callbacks      []func(context.Context, *TaskOutput) error
-	stepCallback   func(context.Context, *AgentStep) error // 对标Python的step_callback
+	systemTemplate   string
+	promptTemplate   string
+	systemTmpl       *template.Template // 编译后的SystemTemplate，为nil时使用语言默认的系统提示
+	promptTmpl       *template.Template // 编译后的PromptTemplate，为nil时直接使用内置逻辑构建的用户提示
+	promptLocale     PromptLocale
+	promptVars       map[string]interface{}
+	prompts          PromptStrings // 当前语言的内置提示词
+	callbacks        []func(context.Context, *TaskOutput) error
+	outputProcessors []OutputProcessor                       // 在回调之前按顺序处理最终输出
+	moderation       *ModerationConfig                       // 内容审核，为nil时使用ctx中Crew的设置
+	promptInjection  *PromptInjectionConfig                  // 提示注入防护，为nil时使用ctx中Crew的设置
+	stepCallback     func(context.Context, *AgentStep) error // 对标Python的step_callback
 
 	// 新增Python版本对标功能
 	reasoningHandler ReasoningHandler // 推理处理器
@@ -54,8 +73,10 @@ type BaseAgent struct {
 
 	// 统计和状态
 	stats         ExecutionStats
+	usage         *UsageMetrics // 按任务输出累加的使用统计，与Crew的统计口径一致
 	isInitialized bool
 	mu            sync.RWMutex
+	currentSteps  map[string]*StepStatus // 按任务ID记录正在执行的LLM调用或工具调用，见CurrentStep
 
 	// 私有状态
 	timesExecuted     int
@@ -80,15 +101,34 @@ func NewBaseAgent(config AgentConfig) (*BaseAgent, error) {
 		agentLogger = logger.NewConsoleLogger()
 	}
 
-	secConfig := config.SecurityConfig
-	if secConfig.Fingerprint == nil {
-		secConfig = *security.NewSecurityConfig()
-	}
+	secConfig := newSecurityConfig(config.SecurityConfig, config.FingerprintSeed)
 
 	execConfig := config.ExecutionConfig
 	if execConfig.MaxIterations == 0 {
 		execConfig = DefaultExecutionConfig()
 	}
+	if config.AllowDelegation {
+		execConfig.AllowDelegation = true
+	}
+
+	// 模板在构造时编译，避免执行任务时才发现模板错误
+	prompts, err := GetPromptStrings(config.PromptLocale)
+	if err != nil {
+		return nil, err
+	}
+	systemTmpl, err := compilePromptTemplate("system", config.SystemTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid system template: %w", err)
+	}
+	if systemTmpl == nil {
+		if systemTmpl, err = compilePromptTemplate("system", prompts.SystemTemplate); err != nil {
+			return nil, fmt.Errorf("invalid system template for prompt locale %q: %w", config.PromptLocale, err)
+		}
+	}
+	promptTmpl, err := compilePromptTemplate("prompt", config.PromptTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
 
 	agent := &BaseAgent{
 		id:                uuid.New().String(),
@@ -98,7 +138,9 @@ func NewBaseAgent(config AgentConfig) (*BaseAgent, error) {
 		llmProvider:       config.LLM,
 		tools:             config.Tools,
 		memory:            config.Memory,
+		memorySuite:       config.MemorySuite,
 		knowledgeSources:  config.KnowledgeSources,
+		toolProviders:     config.ToolProviders,
 		humanInputHandler: config.HumanInputHandler,
 		executionConfig:   execConfig,
 		securityConfig:    secConfig,
@@ -106,7 +148,15 @@ func NewBaseAgent(config AgentConfig) (*BaseAgent, error) {
 		logger:            agentLogger,
 		systemTemplate:    config.SystemTemplate,
 		promptTemplate:    config.PromptTemplate,
+		systemTmpl:        systemTmpl,
+		promptTmpl:        promptTmpl,
+		promptLocale:      config.PromptLocale,
+		promptVars:        config.PromptVars,
+		prompts:           prompts,
 		callbacks:         config.Callbacks,
+		outputProcessors:  append([]OutputProcessor(nil), config.OutputProcessors...),
+		moderation:        config.Moderation,
+		promptInjection:   config.PromptInjection,
 		stepCallback:      config.StepCallback, // 新增步骤回调
 
 		// 初始化ReAct组件
@@ -124,14 +174,28 @@ func NewBaseAgent(config AgentConfig) (*BaseAgent, error) {
 			ToolsUsed:            make(map[string]int),
 			CreatedAt:            time.Now(),
 		},
+		usage:             &UsageMetrics{},
 		isInitialized:     false,
 		timesExecuted:     0,
 		lastExecutionTime: time.Time{},
 	}
 
+	// LLM尚未设置事件总线时传入Agent的事件总线，使LLM调用事件进入同一总线
+	if config.LLM != nil && config.EventBus != nil && llmEventBus(config.LLM) == nil {
+		config.LLM.SetEventBus(config.EventBus)
+	}
+
 	return agent, nil
 }
 
+// llmEventBus 返回LLM已设置的事件总线，LLM不提供GetEventBus时返回nil
+func llmEventBus(provider llm.LLM) events.EventBus {
+	if getter, ok := provider.(interface{ GetEventBus() events.EventBus }); ok {
+		return getter.GetEventBus()
+	}
+	return nil
+}
+
 // Initialize 初始化Agent
 func (a *BaseAgent) Initialize() error {
 	a.mu.Lock()
@@ -148,7 +212,7 @@ func (a *BaseAgent) Initialize() error {
 
 	// 设置事件总线到LLM（如果LLM支持）
 	if a.eventBus != nil {
-		// Note: LLM模块有自己的事件总线集成，这里不需要设置
+		// LLM的事件总线已在NewBaseAgent中设置
 		a.logger.Debug("Event bus available for agent",
 			logger.Field{Key: "agent_id", Value: a.id},
 		)
@@ -166,6 +230,11 @@ func (a *BaseAgent) Initialize() error {
 		}
 	}
 
+	// 连接外部工具来源并加入其工具
+	if err := a.loadProviderTools(context.Background()); err != nil {
+		return err
+	}
+
 	a.isInitialized = true
 	a.logger.Info("Agent initialized successfully",
 		logger.Field{Key: "id", Value: a.id},
@@ -178,7 +247,7 @@ func (a *BaseAgent) Initialize() error {
 }
 
 // Execute 执行任务的核心方法
-func (a *BaseAgent) Execute(ctx context.Context, task Task) (*TaskOutput, error) {
+func (a *BaseAgent) Execute(ctx context.Context, task Task) (output *TaskOutput, err error) {
 	if err := a.ensureInitialized(); err != nil {
 		return nil, fmt.Errorf("agent initialization failed: %w", err)
 	}
@@ -191,6 +260,14 @@ func (a *BaseAgent) Execute(ctx context.Context, task Task) (*TaskOutput, error)
 	a.mu.Unlock()
 
 	startTime := time.Now()
+	ctx, span := a.startExecutionSpan(ctx, task, executionID, false)
+	defer func() { endExecutionSpan(span, output, err) }()
+
+	// 开始钩子可以向ctx放入本次执行使用的资源，开始钩子成功后结束钩子总会执行
+	if ctx, err = a.runExecuteStartHook(ctx, task); err != nil {
+		return nil, err
+	}
+	defer func(ctx context.Context) { a.runExecuteEndHook(ctx, task, output) }(ctx)
 
 	// 发射开始事件
 	if a.eventBus != nil {
@@ -201,6 +278,7 @@ func (a *BaseAgent) Execute(ctx context.Context, task Task) (*TaskOutput, error)
 			task.GetDescription(),
 			executionID,
 		)
+		a.stampFingerprints(&startEvent.BaseEvent, task)
 		if err := a.eventBus.Emit(ctx, a, startEvent); err != nil {
 			a.logger.Error("Failed to emit agent execution started event",
 				logger.Field{Key: "error", Value: err})
@@ -221,8 +299,20 @@ func (a *BaseAgent) Execute(ctx context.Context, task Task) (*TaskOutput, error)
 	}
 
 	// 执行核心任务逻辑
-	output, err := a.executeCore(ctx, task)
+	ctx, callStats := withCallStatsCollector(ctx)
+	ctx, _ = withSourceCollector(ctx)
+	ctx, injected := a.withInjectedContext(ctx)
+	ctx, reproducibility := a.withReproducibility(ctx, task)
+	ctx, injectionGuard := a.withInjectionGuard(ctx, task)
+	output, err = a.executeCore(ctx, task)
 	duration := time.Since(startTime)
+	callStats.apply(output)
+	injected.apply(output)
+	reproducibility.apply(output)
+	injectionGuard.apply(output)
+
+	// 记录产生输出的Agent和Crew的指纹
+	a.stampOutputFingerprints(output, task)
 
 	// 更新统计信息
 	a.updateStats(output, err, duration)
@@ -239,6 +329,7 @@ func (a *BaseAgent) Execute(ctx context.Context, task Task) (*TaskOutput, error)
 			err == nil,
 			output,
 		)
+		a.stampFingerprints(&completedEvent.BaseEvent, task)
 		if emitErr := a.eventBus.Emit(ctx, a, completedEvent); emitErr != nil {
 			a.logger.Error("Failed to emit agent execution completed event",
 				logger.Field{Key: "error", Value: emitErr})
@@ -308,6 +399,89 @@ func (a *BaseAgent) ExecuteWithTimeout(ctx context.Context, task Task, timeout t
 
 // executeCore 执行任务的核心逻辑
 func (a *BaseAgent) executeCore(ctx context.Context, task Task) (*TaskOutput, error) {
+	// 1-5. 准备工具、推理、提示、消息和调用选项
+	toolCtx, messages, callOptions, err := a.prepareLLMRequest(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	// 内容审核：发送给LLM之前审核提示
+	flags, err := a.moderateInput(ctx, task, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// 6-9. 调用LLM、执行工具调用循环、构建输出并进行护栏验证
+	output, err := a.generateValidatedOutput(ctx, task, toolCtx, messages, callOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// 内容审核：审核答案，按配置失败或要求重写
+	if output, err = a.moderateOutput(ctx, task, toolCtx, messages, callOptions, output, flags, true); err != nil {
+		return nil, err
+	}
+
+	// 10. 人工审批，拒绝时带着反馈重新执行
+	if task.IsApprovalRequired() {
+		if output, err = a.requestApproval(ctx, task, toolCtx, messages, callOptions, output); err != nil {
+			return nil, err
+		}
+	}
+
+	// 输出处理器（引用、脱敏等）
+	a.runOutputProcessors(ctx, task, output)
+
+	// 写入任务输出文件
+	a.writeOutputFile(ctx, task, output)
+
+	// 执行回调
+	if err := a.executeCallbacks(ctx, output); err != nil {
+		a.logger.Error("Callback execution failed",
+			logger.Field{Key: "error", Value: err},
+		)
+		// 回调失败不应该阻止任务完成
+	}
+
+	return output, nil
+}
+
+// generateValidatedOutput 生成任务输出，任务设置了护栏时未通过验证的输出会带着反馈重新执行
+func (a *BaseAgent) generateValidatedOutput(ctx context.Context, task Task, toolCtx *ToolExecutionContext, messages []llm.Message, callOptions *llm.CallOptions) (*TaskOutput, error) {
+	output, err := a.generateOutput(ctx, task, toolCtx, messages, callOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// 9. 护栏验证
+	if task.HasGuardrail() {
+		if guardrail := task.GetGuardrail(); guardrail != nil {
+			return a.enforceGuardrail(ctx, task, guardrail, toolCtx, messages, callOptions, output)
+		}
+	}
+	return output, nil
+}
+
+// generateOutput 调用LLM并执行工具调用循环，构建任务输出并校验结构化输出
+func (a *BaseAgent) generateOutput(ctx context.Context, task Task, toolCtx *ToolExecutionContext, messages []llm.Message, callOptions *llm.CallOptions) (*TaskOutput, error) {
+	// 6. 调用LLM并执行工具调用循环
+	loopResult, err := a.runToolCallingLoop(ctx, task, toolCtx, messages, callOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// 7. 处理响应并构建输出
+	output := a.buildToolLoopOutput(task, loopResult)
+
+	// 8. 校验结构化输出，不符合模式时请求LLM修正
+	if schema := task.GetOutputSchema(); schema != nil {
+		a.enforceOutputSchema(ctx, task, schema, messages, callOptions, output)
+	}
+	return output, nil
+}
+
+// prepareLLMRequest 准备任务执行所需的工具上下文、LLM消息和调用选项
+func (a *BaseAgent) prepareLLMRequest(ctx context.Context, task Task) (*ToolExecutionContext, []llm.Message, *llm.CallOptions, error) {
 	// 1. 工具系统集成 - 选择和准备工具
 	toolCtx := NewToolExecutionContext(a, task)
 
@@ -319,7 +493,7 @@ func (a *BaseAgent) executeCore(ctx context.Context, task Task) (*TaskOutput, er
 	)
 
 	// 2. 检查是否启用推理功能，对标Python的reasoning
-	if a.executionConfig.EnableReasoning && a.reasoningHandler != nil {
+	if a.executionConfig.EnableReasoning {
 		if err := a.handleReasoning(ctx, task); err != nil {
 			a.logger.Error("Reasoning process failed",
 				logger.Field{Key: "task_id", Value: task.GetID()},
@@ -332,94 +506,174 @@ func (a *BaseAgent) executeCore(ctx context.Context, task Task) (*TaskOutput, er
 	// 3. 构建任务提示（包含工具信息）
 	prompt, err := a.buildTaskPromptWithTools(ctx, task, toolCtx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build task prompt: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to build task prompt: %w", err)
 	}
 
 	// 4. 准备LLM消息
-	messages := a.buildMessages(prompt)
-
-	// 5. 准备LLM调用选项（包含工具模式）
-	callOptions := a.buildLLMCallOptionsWithTools(toolCtx)
-
-	// 6. 调用LLM
-	response, err := a.llmProvider.Call(ctx, messages, callOptions)
+	messages, err := a.buildMessages(task, toolCtx, prompt)
 	if err != nil {
-		return nil, fmt.Errorf("LLM call failed: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to build system prompt: %w", err)
 	}
+	if injected := InjectedContextFrom(ctx); injected != nil {
+		messages = injected.inject(messages, a.prompts)
+	}
+	messages = injectionGuardFrom(ctx).addInstructions(messages)
 
-	// 7. 处理响应并构建输出
-	output := a.buildTaskOutput(task, response)
-
-	// 执行回调
-	if err := a.executeCallbacks(ctx, output); err != nil {
-		a.logger.Error("Callback execution failed",
-			logger.Field{Key: "error", Value: err},
-		)
-		// 回调失败不应该阻止任务完成
+	// 5. 准备LLM调用选项（包含工具模式），结构化输出优先使用LLM原生的JSON Schema模式
+	callOptions := a.buildLLMCallOptionsWithTools(toolCtx)
+	reproducibilityFrom(ctx).applyCallOptions(callOptions)
+	if schema := task.GetOutputSchema(); schema != nil {
+		callOptions.ResponseFormat = a.nativeResponseFormat(schema)
 	}
 
-	return output, nil
+	return toolCtx, messages, callOptions, nil
 }
 
 // buildTaskPromptWithTools 构建包含工具信息的任务提示
+// 设置了PromptTemplate时，内置逻辑构建的提示作为{{.Prompt}}交给模板渲染；
+// 开启提示注入防护时，记忆和每个知识条目分别检测并包在分隔标记中
 func (a *BaseAgent) buildTaskPromptWithTools(ctx context.Context, task Task, toolCtx *ToolExecutionContext) (string, error) {
+	prompt := a.buildBaseTaskPrompt(task, toolCtx)
+	guard := injectionGuardFrom(ctx)
+
+	// 查询记忆系统
+	if a.memory != nil || a.memorySuite != nil {
+		memoryContext, err := a.queryMemory(ctx, task)
+		if err != nil {
+			a.logger.Warn("Failed to query memory",
+				logger.Field{Key: "error", Value: err},
+			)
+		} else if memoryContext != "" {
+			memoryContext = guard.screen(ctx, SourceKindMemory, "", memoryContext)
+			prompt += fmt.Sprintf("\n\n%s\n%s", a.prompts.RelevantMemory, memoryContext)
+		}
+	}
+
+	// 查询知识源
+	if len(a.knowledgeSources) > 0 {
+		chunks, err := a.queryKnowledge(ctx, task)
+		if err != nil {
+			a.logger.Warn("Failed to query knowledge sources",
+				logger.Field{Key: "error", Value: err},
+			)
+		} else if len(chunks) > 0 {
+			knowledge := make([]string, len(chunks))
+			for i, chunk := range chunks {
+				if guard != nil {
+					knowledge[i] = guard.screen(ctx, SourceKindKnowledge, chunk.citation, chunk.content)
+				} else {
+					knowledge[i] = fmt.Sprintf("[%s] %s", chunk.citation, chunk.content)
+				}
+			}
+			prompt += fmt.Sprintf("\n\n%s\n%s", a.prompts.RelevantKnowledge, strings.Join(knowledge, "\n"))
+		}
+	}
+
+	return a.renderTaskPrompt(task, toolCtx, prompt)
+}
+
+// buildBaseTaskPrompt 构建不依赖记忆和知识源的任务提示：描述、期望输出、格式说明、上下文和工具
+func (a *BaseAgent) buildBaseTaskPrompt(task Task, toolCtx *ToolExecutionContext) string {
 	prompt := task.GetDescription()
 
 	// 添加期望输出
 	if expectedOutput := task.GetExpectedOutput(); expectedOutput != "" {
-		prompt += fmt.Sprintf("\n\nExpected Output: %s", expectedOutput)
+		prompt += "\n\n" + fmt.Sprintf(a.prompts.ExpectedOutput, expectedOutput)
+	}
+
+	// 添加结构化输出格式说明
+	if schema := task.GetOutputSchema(); schema != nil {
+		prompt += "\n\n" + schema.formatInstructions(a.prompts)
+	} else if task.IsMarkdownOutput() {
+		prompt += "\n\n" + a.prompts.Markdown
 	}
 
 	// 添加人工输入（如果有）
 	if task.IsHumanInputRequired() && task.GetHumanInput() != "" {
-		prompt += fmt.Sprintf("\n\nHuman Input: %s", task.GetHumanInput())
+		prompt += "\n\n" + fmt.Sprintf(a.prompts.HumanInput, task.GetHumanInput())
+	}
+
+	// 添加Crew注入的任务上下文（前序任务输出、crew信息、初始输入）
+	if contextSection := renderTaskContext(task.GetContext(), a.executionConfig.MaxContextLength, a.prompts); contextSection != "" {
+		prompt += "\n\n" + contextSection
 	}
 
 	// 添加工具信息（使用工具执行上下文）
 	if toolCtx.HasTools() {
 		toolsDesc := toolCtx.GetToolsDescription()
-		prompt += fmt.Sprintf("\n\nAvailable Tools:\n%s", toolsDesc)
+		prompt += fmt.Sprintf("\n\n%s\n%s", a.prompts.AvailableTools, toolsDesc)
 
 		// 添加工具使用指导
-		prompt += "\n\nTo use a tool, respond with a JSON object in the following format:"
-		prompt += "\n{\"tool_name\": \"<tool_name>\", \"arguments\": {\"arg1\": \"value1\", \"arg2\": \"value2\"}}"
-		prompt += "\nIf no tool is needed, provide your response directly."
+		prompt += "\n\n" + a.prompts.ToolUsage
 	}
+	return prompt
+}
 
-	// 查询记忆系统
-	if a.memory != nil {
-		memoryContext, err := a.queryMemory(ctx, task)
-		if err != nil {
-			a.logger.Warn("Failed to query memory",
-				logger.Field{Key: "error", Value: err},
-			)
-		} else if memoryContext != "" {
-			prompt += fmt.Sprintf("\n\nRelevant Memory:\n%s", memoryContext)
-		}
+// renderTaskPrompt 设置了PromptTemplate时把内置逻辑构建的提示交给模板渲染
+func (a *BaseAgent) renderTaskPrompt(task Task, toolCtx *ToolExecutionContext, prompt string) (string, error) {
+	if a.promptTmpl == nil {
+		return prompt, nil
 	}
+	data := a.promptData(task, toolCtx)
+	data.Prompt = prompt
+	return renderPromptTemplate(a.promptTmpl, data)
+}
 
-	// 查询知识源
-	if len(a.knowledgeSources) > 0 {
-		knowledgeContext, err := a.queryKnowledge(ctx, task)
-		if err != nil {
-			a.logger.Warn("Failed to query knowledge sources",
-				logger.Field{Key: "error", Value: err},
-			)
-		} else if knowledgeContext != "" {
-			prompt += fmt.Sprintf("\n\nRelevant Knowledge:\n%s", knowledgeContext)
-		}
+// PreviewRequest 构建执行任务时发送给LLM的消息和调用选项，但不调用LLM
+// 不查询记忆和知识源、不执行推理，因此不会发出任何网络请求，用于dry run估算提示规模
+func (a *BaseAgent) PreviewRequest(task Task) ([]llm.Message, *llm.CallOptions, error) {
+	toolCtx := NewToolExecutionContext(a, task)
+	prompt, err := a.renderTaskPrompt(task, toolCtx, a.buildBaseTaskPrompt(task, toolCtx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build task prompt: %w", err)
+	}
+	messages, err := a.buildMessages(task, toolCtx, prompt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build system prompt: %w", err)
 	}
+	return messages, a.buildLLMCallOptionsWithTools(toolCtx), nil
+}
 
-	return prompt, nil
+// promptData 构建渲染模板使用的数据
+func (a *BaseAgent) promptData(task Task, toolCtx *ToolExecutionContext) PromptData {
+	data := PromptData{
+		Role:      a.role,
+		Goal:      a.goal,
+		Backstory: a.backstory,
+		Context:   map[string]interface{}{},
+		Vars:      a.promptVars,
+	}
+	if data.Vars == nil {
+		data.Vars = map[string]interface{}{}
+	}
+	if task != nil {
+		data.Task = task.GetDescription()
+		data.ExpectedOutput = task.GetExpectedOutput()
+		if taskContext := task.GetContext(); taskContext != nil {
+			data.Context = taskContext
+		}
+	}
+	if toolCtx != nil && toolCtx.HasTools() {
+		for _, tool := range toolCtx.Tools {
+			if tool != nil {
+				data.Tools = append(data.Tools, tool.GetName())
+			}
+		}
+		data.ToolsDescription = toolCtx.GetToolsDescription()
+	}
+	return data
 }
 
 // buildMessages 构建LLM消息
-func (a *BaseAgent) buildMessages(prompt string) []llm.Message {
+func (a *BaseAgent) buildMessages(task Task, toolCtx *ToolExecutionContext, prompt string) ([]llm.Message, error) {
 	messages := []llm.Message{}
 
 	// 系统消息
 	if a.executionConfig.UseSystemPrompt {
-		systemPrompt := a.buildSystemPrompt()
+		systemPrompt, err := a.buildSystemPrompt(task, toolCtx)
+		if err != nil {
+			return nil, err
+		}
 		messages = append(messages, llm.Message{
 			Role:    llm.RoleSystem,
 			Content: systemPrompt,
@@ -427,34 +681,36 @@ func (a *BaseAgent) buildMessages(prompt string) []llm.Message {
 	}
 
 	// 用户消息
-	messages = append(messages, llm.Message{
-		Role:    llm.RoleUser,
-		Content: prompt,
-	})
+	messages = append(messages, taskUserMessage(task, prompt))
 
-	return messages
+	return messages, nil
 }
 
-// buildSystemPrompt 构建系统提示
-func (a *BaseAgent) buildSystemPrompt() string {
-	if a.systemTemplate != "" {
-		// 使用自定义模板
-		template := a.systemTemplate
-		template = strings.ReplaceAll(template, "{role}", a.role)
-		template = strings.ReplaceAll(template, "{goal}", a.goal)
-		template = strings.ReplaceAll(template, "{backstory}", a.backstory)
-		return template
+// taskUserMessage 构建任务的用户消息，任务带有图片附件时作为多部分内容发送
+func taskUserMessage(task Task, prompt string) llm.Message {
+	if task != nil {
+		if images := task.GetImages(); len(images) > 0 {
+			return llm.Message{Role: llm.RoleUser, Content: append([]llm.ContentPart{llm.TextPart(prompt)}, images...)}
+		}
 	}
+	return llm.Message{Role: llm.RoleUser, Content: prompt}
+}
 
-	// 默认系统提示
-	return fmt.Sprintf(`You are %s.
-
-Your goal: %s
-
-Your backstory: %s
+// buildSystemPrompt 构建系统提示，有训练得到的改进指令时附加在最后
+// 未设置SystemTemplate时使用当前语言的默认系统提示
+func (a *BaseAgent) buildSystemPrompt(task Task, toolCtx *ToolExecutionContext) (string, error) {
+	prompt, err := renderPromptTemplate(a.systemTmpl, a.promptData(task, toolCtx))
+	if err != nil {
+		return "", err
+	}
 
-You are working with a team of other agents to complete complex tasks. Always provide detailed, accurate responses based on your role and expertise. Use the available tools when necessary and be precise in your reasoning.`,
-		a.role, a.goal, a.backstory)
+	if len(a.trainedInstructions) > 0 {
+		prompt += "\n\n" + a.prompts.TrainedInstructions
+		for _, instruction := range a.trainedInstructions {
+			prompt += "\n- " + instruction
+		}
+	}
+	return prompt, nil
 }
 
 // buildLLMCallOptionsWithTools 构建包含工具信息的LLM调用选项
@@ -478,7 +734,8 @@ func (a *BaseAgent) buildLLMCallOptionsWithTools(toolCtx *ToolExecutionContext)
 	}
 
 	// 添加工具信息到LLM调用选项
-	if toolCtx != nil && toolCtx.HasTools() {
+	// 不支持原生函数调用的模型（如多数本地模型）只依赖提示中的JSON工具调用格式
+	if toolCtx != nil && toolCtx.HasTools() && a.llmProvider.SupportsFunctionCalling() {
 		// 将Agent工具转换为LLM可理解的工具格式
 		llmTools := make([]llm.Tool, 0, len(toolCtx.Tools))
 		for _, tool := range toolCtx.Tools {
@@ -489,7 +746,7 @@ func (a *BaseAgent) buildLLMCallOptionsWithTools(toolCtx *ToolExecutionContext)
 					Function: llm.ToolSchema{
 						Name:        schema.Name,
 						Description: schema.Description,
-						Parameters:  schema.Parameters,
+						Parameters:  schema.JSONSchema(),
 					},
 				}
 				llmTools = append(llmTools, llmTool)
@@ -504,20 +761,23 @@ func (a *BaseAgent) buildLLMCallOptionsWithTools(toolCtx *ToolExecutionContext)
 // buildTaskOutput 构建任务输出
 func (a *BaseAgent) buildTaskOutput(task Task, response *llm.Response) *TaskOutput {
 	output := &TaskOutput{
-		Raw:            response.Content,
-		Agent:          a.role,
-		Task:           task.GetID(),
-		Description:    task.GetDescription(),
-		ExpectedOutput: task.GetExpectedOutput(),
-		OutputFormat:   task.GetOutputFormat(),
-		ExecutionTime:  0, // 将在上层设置
-		CreatedAt:      time.Now(),
-		TokensUsed:     response.Usage.TotalTokens,
-		Cost:           response.Usage.Cost,
-		Model:          response.Model,
-		IsValid:        true,       // 默认有效，可以后续添加验证逻辑
-		ToolsUsed:      []string{}, // TODO: 从响应中提取工具使用信息
-		Metadata:       make(map[string]interface{}),
+		Raw:              response.Content,
+		Agent:            a.role,
+		Task:             task.GetID(),
+		Name:             task.GetName(),
+		Description:      task.GetDescription(),
+		ExpectedOutput:   task.GetExpectedOutput(),
+		OutputFormat:     task.GetOutputFormat(),
+		ExecutionTime:    0, // 将在上层设置
+		CreatedAt:        time.Now(),
+		TokensUsed:       response.Usage.TotalTokens,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		Cost:             response.Usage.Cost,
+		Model:            response.Model,
+		IsValid:          true, // 默认有效，可以后续添加验证逻辑
+		ToolsUsed:        []string{},
+		Metadata:         make(map[string]interface{}),
 	}
 
 	// 尝试解析JSON输出
@@ -542,6 +802,15 @@ func (a *BaseAgent) buildTaskOutput(task Task, response *llm.Response) *TaskOutp
 	output.Metadata["prompt_tokens"] = response.Usage.PromptTokens
 	output.Metadata["completion_tokens"] = response.Usage.CompletionTokens
 	output.Metadata["agent_id"] = a.id
+	if len(response.ToolCalls) > 0 {
+		output.Metadata["tool_calls"] = response.ToolCalls
+	}
+	if response.Refusal != "" {
+		output.Metadata[refusalMetadataKey] = response.Refusal
+	}
+	if response.SystemFingerprint != "" {
+		output.Metadata[SystemFingerprintMetadataKey] = response.SystemFingerprint
+	}
 
 	return output
 }
@@ -561,12 +830,20 @@ func (a *BaseAgent) handleHumanInput(ctx context.Context, task Task) error {
 		return fmt.Errorf("human input required but no handler configured")
 	}
 
-	prompt := fmt.Sprintf("Task requires your input: %s", task.GetDescription())
-	input, err := a.humanInputHandler.RequestInput(ctx, prompt, nil)
+	request := HumanInputRequest{
+		Type:   HumanInputRequestInput,
+		Prompt: fmt.Sprintf("Task requires your input: %s", task.GetDescription()),
+		Metadata: map[string]interface{}{
+			"task_id": task.GetID(),
+			"agent":   a.role,
+		},
+	}
+	response, err := a.humanInputHandler.RequestInput(ctx, request)
 	if err != nil {
 		return fmt.Errorf("human input request failed: %w", err)
 	}
 
+	input := response.Text
 	task.SetHumanInput(input)
 	a.logger.Info("Received human input",
 		logger.Field{Key: "task_id", Value: task.GetID()},
@@ -588,6 +865,10 @@ func (a *BaseAgent) executeCallbacks(ctx context.Context, output *TaskOutput) er
 
 // queryMemory 查询记忆系统
 func (a *BaseAgent) queryMemory(ctx context.Context, task Task) (string, error) {
+	// 组合记忆优先，由其负责查询各记忆来源并格式化上下文
+	if a.memorySuite != nil {
+		return a.memorySuite.BuildContextForTask(ctx, task, "")
+	}
 	if a.memory == nil {
 		return "", nil
 	}
@@ -598,6 +879,7 @@ func (a *BaseAgent) queryMemory(ctx context.Context, task Task) (string, error)
 	if err != nil {
 		return "", err
 	}
+	reproducibilityFrom(ctx).orderMemoryItems(items)
 
 	if len(items) == 0 {
 		return "", nil
@@ -613,34 +895,67 @@ func (a *BaseAgent) queryMemory(ctx context.Context, task Task) (string, error)
 	return strings.Join(contexts, "\n"), nil
 }
 
-// queryKnowledge 查询知识源
-func (a *BaseAgent) queryKnowledge(ctx context.Context, task Task) (string, error) {
+// knowledgeChunk 注入提示的一个知识条目
+type knowledgeChunk struct {
+	citation string // 条目的来源，提示中作为引用
+	content  string
+}
+
+// queryKnowledge 查询知识源，开启查询改写时按改写后的每个查询分别检索
+func (a *BaseAgent) queryKnowledge(ctx context.Context, task Task) ([]knowledgeChunk, error) {
 	if len(a.knowledgeSources) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
-	query := task.GetDescription()
+	queries := a.knowledgeQueries(ctx, task)
 	options := DefaultQueryOptions()
-	options.Limit = 3 // 每个知识源获取3个结果
+	options.Limit = 3 // 每个查询在每个知识源获取3个结果
+
+	// 多个查询或知识源返回的相同内容只保留第一次出现
+	seen := make(map[[sha256.Size]byte]bool)
+	var allKnowledge []knowledgeChunk
+	for _, query := range queries {
+		for _, source := range a.knowledgeSources {
+			items, err := source.Query(ctx, query, options)
+			if err != nil {
+				a.logger.Warn("Knowledge source query failed",
+					logger.Field{Key: "source", Value: source.GetName()},
+					logger.Field{Key: "error", Value: err},
+				)
+				continue
+			}
 
-	var allKnowledge []string
-	for _, source := range a.knowledgeSources {
-		items, err := source.Query(ctx, query, options)
-		if err != nil {
-			a.logger.Warn("Knowledge source query failed",
-				logger.Field{Key: "source", Value: source.GetName()},
-				logger.Field{Key: "error", Value: err},
-			)
-			continue
-		}
+			for _, item := range items {
+				hash := knowledgeContentHash(item.Content)
+				if seen[hash] {
+					continue
+				}
+				seen[hash] = true
+
+				// 优先引用条目自身的来源（如目录知识源中的具体文件）
+				citation := item.Source
+				if citation == "" {
+					citation = source.GetName()
+				}
 
-		for _, item := range items {
-			allKnowledge = append(allKnowledge,
-				fmt.Sprintf("[%s] %s", source.GetName(), item.Content))
+				// 记录注入的条目，供输出处理器生成引用
+				id := item.ID
+				if id == "" {
+					id = fmt.Sprintf("%s:%x", citation, hash[:8])
+				}
+				sourcesFrom(ctx).add(OutputSource{
+					ID:       id,
+					Kind:     SourceKindKnowledge,
+					Name:     citation,
+					Content:  item.Content,
+					Metadata: item.Metadata,
+				})
+				allKnowledge = append(allKnowledge, knowledgeChunk{citation: citation, content: item.Content})
+			}
 		}
 	}
 
-	return strings.Join(allKnowledge, "\n"), nil
+	return allKnowledge, nil
 }
 
 // updateStats 更新执行统计
@@ -655,9 +970,9 @@ func (a *BaseAgent) updateStats(output *TaskOutput, err error, duration time.Dur
 			a.stats.TokensUsed += output.TokensUsed
 			a.stats.TotalCost += output.Cost
 
-			// 更新工具使用统计
-			for _, tool := range output.ToolsUsed {
-				a.stats.ToolsUsed[tool]++
+			// 更新工具使用统计，按每次调用计数
+			for tool, count := range toolUsageCounts(output) {
+				a.stats.ToolsUsed[tool] += count
 			}
 		}
 	} else {
@@ -671,6 +986,27 @@ func (a *BaseAgent) updateStats(output *TaskOutput, err error, duration time.Dur
 	if output != nil {
 		output.ExecutionTime = duration
 	}
+	if err != nil {
+		a.usage.RecordTask(nil)
+	} else {
+		a.usage.RecordTask(output)
+	}
+}
+
+// toolUsageMetadataKey 任务输出元数据中记录每个工具调用次数的键
+const toolUsageMetadataKey = "tool_usage"
+
+// toolUsageCounts 返回任务输出中每个工具的调用次数
+// 没有调用次数记录时，ToolsUsed中的每个工具按一次计
+func toolUsageCounts(output *TaskOutput) map[string]int {
+	if counts, ok := output.Metadata[toolUsageMetadataKey].(map[string]int); ok {
+		return counts
+	}
+	counts := make(map[string]int, len(output.ToolsUsed))
+	for _, tool := range output.ToolsUsed {
+		counts[tool]++
+	}
+	return counts
 }
 
 // ensureInitialized 确保Agent已初始化
@@ -726,6 +1062,30 @@ func (a *BaseAgent) GetHumanInputHandler() HumanInputHandler {
 	return a.humanInputHandler
 }
 
+func (a *BaseAgent) GetRPMController() *RPMController {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.rpmController
+}
+
+func (a *BaseAgent) GetResponseCache() llm.ResponseCache {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.responseCache
+}
+
+func (a *BaseAgent) GetToolCache() ToolCache {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.toolCache
+}
+
+func (a *BaseAgent) GetTrainedInstructions() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]string(nil), a.trainedInstructions...)
+}
+
 func (a *BaseAgent) GetEventBus() events.EventBus {
 	return a.eventBus
 }
@@ -740,6 +1100,14 @@ func (a *BaseAgent) GetExecutionStats() ExecutionStats {
 	return a.stats
 }
 
+// GetUsageMetrics 返回Agent使用统计的快照
+func (a *BaseAgent) GetUsageMetrics() *UsageMetrics {
+	a.mu.RLock()
+	usage := a.usage
+	a.mu.RUnlock()
+	return usage.Snapshot()
+}
+
 // Setter方法实现
 func (a *BaseAgent) SetLLM(llmProvider llm.LLM) error {
 	a.mu.Lock()
@@ -759,6 +1127,23 @@ func (a *BaseAgent) AddTool(tool Tool) error {
 	return nil
 }
 
+// RemoveTool 按名称移除工具
+func (a *BaseAgent) RemoveTool(name string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, tool := range a.tools {
+		if tool != nil && tool.GetName() == name {
+			a.tools = append(a.tools[:i:i], a.tools[i+1:]...)
+			a.logger.Info("Tool removed from agent",
+				logger.Field{Key: "agent", Value: a.role},
+				logger.Field{Key: "tool", Value: name},
+			)
+			return nil
+		}
+	}
+	return fmt.Errorf("tool %s not found", name)
+}
+
 func (a *BaseAgent) SetMemory(memory Memory) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -766,6 +1151,13 @@ func (a *BaseAgent) SetMemory(memory Memory) error {
 	return nil
 }
 
+// SetMemorySuite 设置组合记忆
+func (a *BaseAgent) SetMemorySuite(suite MemorySuite) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.memorySuite = suite
+}
+
 func (a *BaseAgent) SetKnowledgeSources(sources []KnowledgeSource) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -787,6 +1179,31 @@ func (a *BaseAgent) SetHumanInputHandler(handler HumanInputHandler) error {
 	return nil
 }
 
+func (a *BaseAgent) SetRPMController(controller *RPMController) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rpmController = controller
+}
+
+func (a *BaseAgent) SetResponseCache(cache llm.ResponseCache) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.responseCache = cache
+}
+
+func (a *BaseAgent) SetToolCache(cache ToolCache) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.toolCache = cache
+}
+
+// SetTrainedInstructions 设置训练得到的改进指令，空列表表示未训练
+func (a *BaseAgent) SetTrainedInstructions(instructions []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.trainedInstructions = append([]string(nil), instructions...)
+}
+
 func (a *BaseAgent) SetEventBus(eventBus events.EventBus) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -817,6 +1234,7 @@ func (a *BaseAgent) ResetStats() error {
 		CreatedAt:            time.Now(),
 	}
 
+	a.usage = &UsageMetrics{}
 	a.timesExecuted = 0
 	a.lastExecutionTime = time.Time{}
 	return nil
@@ -837,6 +1255,8 @@ func (a *BaseAgent) Close() error {
 		}
 	}
 
+	a.closeToolProviders()
+
 	// 关闭LLM（如果支持）
 	if a.llmProvider != nil {
 		if err := a.llmProvider.Close(); err != nil {
@@ -852,9 +1272,26 @@ func (a *BaseAgent) Close() error {
 
 // Clone 创建Agent的副本
 func (a *BaseAgent) Clone() Agent {
+	return a.CloneWithOptions()
+}
+
+// CloneWithOptions 按选项克隆Agent，默认为副本生成新的指纹
+// 副本拥有新的ID和清零的统计，工具通过Tool.Clone复制以隔离使用计数；
+// 记忆实现了NamespacedMemory时副本使用以自身ID为命名空间的记忆，除非指定WithSharedMemory
+func (a *BaseAgent) CloneWithOptions(opts ...CloneOption) Agent {
+	var options cloneOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
+	securityConfig := a.securityConfig
+	if !options.preserveFingerprint {
+		securityConfig.Fingerprint = security.NewFingerprint()
+	}
+
 	config := AgentConfig{
 		Role:              a.role,
 		Goal:              a.goal,
@@ -862,30 +1299,54 @@ func (a *BaseAgent) Clone() Agent {
 		LLM:               a.llmProvider,
 		Tools:             make([]Tool, len(a.tools)),
 		ExecutionConfig:   a.executionConfig,
-		Memory:            a.memory,
+		MemorySuite:       a.memorySuite,
 		KnowledgeSources:  make([]KnowledgeSource, len(a.knowledgeSources)),
 		HumanInputHandler: a.humanInputHandler,
 		EventBus:          a.eventBus,
 		Logger:            a.logger,
-		SecurityConfig:    a.securityConfig,
+		SecurityConfig:    securityConfig,
 		SystemTemplate:    a.systemTemplate,
 		PromptTemplate:    a.promptTemplate,
+		PromptLocale:      a.promptLocale,
+		PromptVars:        a.promptVars,
 		Callbacks:         make([]func(context.Context, *TaskOutput) error, len(a.callbacks)),
+		StepCallback:      a.stepCallback,
+		OutputProcessors:  a.outputProcessors,
+		Moderation:        a.moderation,
+		PromptInjection:   a.promptInjection,
 	}
 
-	// 复制切片
-	copy(config.Tools, a.tools)
+	// 工具各自复制，知识源只读，可以共享
+	for i, tool := range a.tools {
+		config.Tools[i] = tool.Clone()
+	}
 	copy(config.KnowledgeSources, a.knowledgeSources)
 	copy(config.Callbacks, a.callbacks)
 
 	clonedAgent, _ := NewBaseAgent(config)
+	if clonedAgent != nil {
+		clonedAgent.memory = a.memory
+		if namespaced, ok := a.memory.(NamespacedMemory); ok && !options.shareMemory {
+			clonedAgent.memory = namespaced.WithNamespace(clonedAgent.id)
+		}
+
+		// 副本与原Agent共享速率限制、响应缓存和工具缓存
+		clonedAgent.rpmController = a.rpmController
+		clonedAgent.responseCache = a.responseCache
+		clonedAgent.toolCache = a.toolCache
+		clonedAgent.reasoningHandler = a.reasoningHandler
+		clonedAgent.trainedInstructions = append([]string(nil), a.trainedInstructions...)
+	}
 	return clonedAgent
 }
 
 // handleReasoning 处理推理逻辑，对标Python版本的reasoning功能
+// 未设置推理处理器时使用DefaultReasoningHandler；计划追加到任务描述中，
+// 未准备好时仍然追加当前计划并照常执行
 func (a *BaseAgent) handleReasoning(ctx context.Context, task Task) error {
-	if a.reasoningHandler == nil {
-		return fmt.Errorf("reasoning handler not configured")
+	handler := a.GetReasoningHandler()
+	if handler == nil {
+		handler = NewDefaultReasoningHandler(nil)
 	}
 
 	a.logger.Info("Starting reasoning process",
@@ -903,7 +1364,7 @@ func (a *BaseAgent) handleReasoning(ctx context.Context, task Task) error {
 	}
 
 	startTime := time.Now()
-	reasoningOutput, err := a.reasoningHandler.HandleReasoning(ctx, task, a)
+	reasoningOutput, err := handler.HandleReasoning(ctx, task, a)
 	duration := time.Since(startTime)
 
 	if err != nil {
@@ -918,7 +1379,10 @@ func (a *BaseAgent) handleReasoning(ctx context.Context, task Task) error {
 		return fmt.Errorf("reasoning failed: %w", err)
 	}
 
-	if reasoningOutput.Success && reasoningOutput.FinalReady {
+	// 推理调用的token计入本次任务输出
+	callStatsFrom(ctx).addUsage(reasoningOutput.Usage)
+
+	if reasoningOutput.Success && reasoningOutput.Plan.Plan != "" {
 		// 将推理计划添加到任务描述中，完全对标Python逻辑
 		originalDescription := task.GetDescription()
 		enhancedDescription := fmt.Sprintf("%s\n\nReasoning Plan:\n%s",
@@ -928,22 +1392,23 @@ func (a *BaseAgent) handleReasoning(ctx context.Context, task Task) error {
 		if setter, ok := task.(interface{ SetDescription(string) }); ok {
 			setter.SetDescription(enhancedDescription)
 		}
+	}
 
-		a.logger.Info("Reasoning completed successfully",
-			logger.Field{Key: "agent", Value: a.role},
-			logger.Field{Key: "task_id", Value: task.GetID()},
-			logger.Field{Key: "duration", Value: duration},
-			logger.Field{Key: "iterations", Value: reasoningOutput.Iterations},
-		)
+	a.logger.Info("Reasoning completed",
+		logger.Field{Key: "agent", Value: a.role},
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "duration", Value: duration},
+		logger.Field{Key: "iterations", Value: reasoningOutput.Iterations},
+		logger.Field{Key: "ready", Value: reasoningOutput.FinalReady},
+	)
 
-		// 发射推理完成事件
-		if a.eventBus != nil {
-			completedEvent := NewAgentReasoningCompletedEvent(
-				a.id, a.role, task.GetID(), duration, reasoningOutput.Iterations, true)
-			if err := a.eventBus.Emit(ctx, a, completedEvent); err != nil {
-				a.logger.Error("Failed to emit agent reasoning completed event",
-					logger.Field{Key: "error", Value: err})
-			}
+	// 发射推理完成事件
+	if a.eventBus != nil {
+		completedEvent := NewAgentReasoningCompletedEvent(
+			a.id, a.role, task.GetID(), duration, reasoningOutput.Iterations, reasoningOutput.Success && reasoningOutput.FinalReady)
+		if err := a.eventBus.Emit(ctx, a, completedEvent); err != nil {
+			a.logger.Error("Failed to emit agent reasoning completed event",
+				logger.Field{Key: "error", Value: err})
 		}
 	}
 
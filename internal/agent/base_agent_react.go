@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/tracing"
 )
 
 // 确保BaseAgent实现ReActAgent接口
@@ -80,62 +81,90 @@ func (a *BaseAgent) ExecuteWithReAct(ctx context.Context, task Task) (*TaskOutpu
 	// 记录执行开始
 	a.mu.Lock()
 	a.timesExecuted++
+	executionID := a.timesExecuted
 	a.mu.Unlock()
 
+	ctx, span := a.startExecutionSpan(ctx, task, executionID, false)
+	span.SetAttributes(tracing.String("agent.mode", "react"))
+
+	// 开始钩子可以向ctx放入本次执行使用的资源，开始钩子成功后结束钩子总会执行
+	ctx, err := a.runExecuteStartHook(ctx, task)
+	if err != nil {
+		endExecutionSpan(span, nil, err)
+		return nil, nil, err
+	}
+	var output *TaskOutput
+	defer func(ctx context.Context) { a.runExecuteEndHook(ctx, task, output) }(ctx)
+
 	// 发送开始执行事件
 	if a.eventBus != nil {
 		startEvent := NewAgentExecutionStartedEvent(a.id, a.role, task.GetID(), task.GetDescription(), a.timesExecuted)
+		a.stampFingerprints(&startEvent.BaseEvent, task)
 		if err := a.eventBus.Emit(ctx, a, startEvent); err != nil {
 			a.logger.Warn("Failed to publish start event", logger.Field{Key: "error", Value: err})
 		}
 	}
 
 	// 使用ReAct执行器执行任务
+	ctx, callStats := withCallStatsCollector(ctx)
+	ctx, _ = withSourceCollector(ctx)
+	ctx, reproducibility := a.withReproducibility(ctx, task)
+	ctx, injectionGuard := a.withInjectionGuard(ctx, task)
 	trace, err := a.reactExecutor.ExecuteReAct(ctx, a, task)
 	if err != nil {
 		// 记录失败
 		a.mu.Lock()
 		a.stats.FailedExecutions++
+		a.usage.RecordTask(nil)
 		a.mu.Unlock()
 
 		if a.eventBus != nil {
 			errorEvent := NewAgentExecutionFailedEvent(a.id, a.role, task.GetID(), task.GetDescription(), a.timesExecuted, 0, err)
+			a.stampFingerprints(&errorEvent.BaseEvent, task)
 			if pubErr := a.eventBus.Emit(ctx, a, errorEvent); pubErr != nil {
 				a.logger.Warn("Failed to publish error event", logger.Field{Key: "error", Value: pubErr})
 			}
 		}
 
-		return nil, trace, fmt.Errorf("ReAct execution failed: %w", err)
+		err = fmt.Errorf("ReAct execution failed: %w", err)
+		endExecutionSpan(span, nil, err)
+		return nil, trace, err
 	}
 
 	// 保存轨迹
 	a.mu.Lock()
 	a.lastReActTrace = trace
 	a.stats.SuccessfulExecutions++
+	a.stats.TokensUsed += trace.Usage.TotalTokens
+	a.stats.TotalCost += trace.Usage.Cost
 	a.mu.Unlock()
 
 	// 构建任务输出
-	output := &TaskOutput{
-		Raw:            trace.FinalOutput,
-		Agent:          a.role,
-		Task:           task.GetID(),
-		Description:    task.GetDescription(),
-		Summary:        a.generateSummaryFromTrace(trace),
-		ExpectedOutput: task.GetExpectedOutput(),
-		OutputFormat:   OutputFormatRAW,
-		ExecutionTime:  trace.TotalDuration,
-		CreatedAt:      trace.EndTime,
-		TokensUsed:     0, // TODO: 从LLM响应中获取
-		Cost:           0, // TODO: 计算成本
-		Model:          a.getLLMModelName(),
-		IsValid:        trace.IsCompleted && len(trace.FinalOutput) > 0,
-		ToolsUsed:      a.extractToolsFromTrace(trace),
+	output = &TaskOutput{
+		Raw:              trace.FinalOutput,
+		Agent:            a.role,
+		Task:             task.GetID(),
+		Name:             task.GetName(),
+		Description:      task.GetDescription(),
+		Summary:          a.generateSummaryFromTrace(trace),
+		ExpectedOutput:   task.GetExpectedOutput(),
+		OutputFormat:     OutputFormatRAW,
+		ExecutionTime:    trace.TotalDuration,
+		CreatedAt:        trace.EndTime,
+		TokensUsed:       trace.Usage.TotalTokens,
+		PromptTokens:     trace.Usage.PromptTokens,
+		CompletionTokens: trace.Usage.CompletionTokens,
+		Cost:             trace.Usage.Cost,
+		Model:            a.getLLMModelName(),
+		IsValid:          trace.IsCompleted && len(trace.FinalOutput) > 0,
+		ToolsUsed:        a.extractToolsFromTrace(trace),
 		Metadata: map[string]interface{}{
-			"mode":            "react",
-			"trace_id":        trace.TraceID,
-			"iteration_count": trace.IterationCount,
-			"total_duration":  trace.TotalDuration,
-			"steps_count":     len(trace.Steps),
+			"mode":               "react",
+			"trace_id":           trace.TraceID,
+			"iteration_count":    trace.IterationCount,
+			"total_duration":     trace.TotalDuration,
+			"steps_count":        len(trace.Steps),
+			toolUsageMetadataKey: a.countToolUsageInTrace(trace),
 		},
 	}
 
@@ -147,11 +176,30 @@ func (a *BaseAgent) ExecuteWithReAct(ctx context.Context, task Task) (*TaskOutpu
 				output.IsValid = false
 			} else if !result.Valid {
 				output.ValidationError = result.Error
+				if output.ValidationError == "" {
+					output.ValidationError = result.Feedback
+				}
 				output.IsValid = false
 			}
 		}
 	}
 
+	callStats.apply(output)
+	reproducibility.apply(output)
+	injectionGuard.apply(output)
+	a.mu.Lock()
+	a.usage.RecordTask(output)
+	a.mu.Unlock()
+
+	// 记录产生输出的Agent和Crew的指纹
+	a.stampOutputFingerprints(output, task)
+
+	// 输出处理器（引用、脱敏等）
+	a.runOutputProcessors(ctx, task, output)
+
+	// 写入任务输出文件
+	a.writeOutputFile(ctx, task, output)
+
 	// 执行回调
 	if err := a.executeCallbacks(ctx, output); err != nil {
 		a.logger.Warn("Callback execution failed", logger.Field{Key: "error", Value: err})
@@ -160,11 +208,13 @@ func (a *BaseAgent) ExecuteWithReAct(ctx context.Context, task Task) (*TaskOutpu
 	// 发送完成事件
 	if a.eventBus != nil {
 		completedEvent := NewAgentExecutionCompletedEvent(a.id, a.role, task.GetID(), task.GetDescription(), a.timesExecuted, trace.TotalDuration, true, output)
+		a.stampFingerprints(&completedEvent.BaseEvent, task)
 		if err := a.eventBus.Emit(ctx, a, completedEvent); err != nil {
 			a.logger.Warn("Failed to publish completion event", logger.Field{Key: "error", Value: err})
 		}
 	}
 
+	endExecutionSpan(span, output, nil)
 	return output, trace, nil
 }
 
@@ -211,6 +261,20 @@ func (a *BaseAgent) extractToolsFromTrace(trace *ReActTrace) []string {
 	return tools
 }
 
+// countToolUsageInTrace 统计ReAct轨迹中每个工具的调用次数
+func (a *BaseAgent) countToolUsageInTrace(trace *ReActTrace) map[string]int {
+	counts := make(map[string]int)
+	if trace == nil {
+		return counts
+	}
+	for _, step := range trace.Steps {
+		if step.Action != "" {
+			counts[step.Action]++
+		}
+	}
+	return counts
+}
+
 // getLLMModelName 获取LLM模型名称
 func (a *BaseAgent) getLLMModelName() string {
 	if a.llmProvider != nil {
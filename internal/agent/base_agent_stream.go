@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// ExecuteStream 流式执行任务
+// 通过llmProvider.CallStream逐块转发LLM输出，最后一个块携带完整的TaskOutput。
+// 当LLM不支持流式调用或任务需要工具调用循环时，回退为包含完整结果的单块流
+func (a *BaseAgent) ExecuteStream(ctx context.Context, task Task) (<-chan AgentStreamChunk, error) {
+	if err := a.ensureInitialized(); err != nil {
+		return nil, fmt.Errorf("agent initialization failed: %w", err)
+	}
+
+	chunks := make(chan AgentStreamChunk, 16)
+
+	go func() {
+		defer close(chunks)
+
+		a.mu.Lock()
+		a.timesExecuted++
+		executionID := a.timesExecuted
+		a.lastExecutionTime = time.Now()
+		a.mu.Unlock()
+
+		startTime := time.Now()
+		ctx, span := a.startExecutionSpan(ctx, task, executionID, true)
+
+		// 开始钩子可以向ctx放入本次执行使用的资源，开始钩子成功后结束钩子总会执行
+		var output *TaskOutput
+		ctx, err := a.runExecuteStartHook(ctx, task)
+		if err != nil {
+			endExecutionSpan(span, nil, err)
+			select {
+			case chunks <- AgentStreamChunk{Done: true, Error: err}:
+			case <-ctx.Done():
+			}
+			return
+		}
+		hookCtx := ctx
+
+		// 发射开始事件
+		if a.eventBus != nil {
+			startEvent := NewAgentExecutionStartedEvent(a.id, a.role, task.GetID(), task.GetDescription(), executionID)
+			if err := a.eventBus.Emit(ctx, a, startEvent); err != nil {
+				a.logger.Error("Failed to emit agent execution started event",
+					logger.Field{Key: "error", Value: err})
+			}
+		}
+
+		a.logger.Info("Starting streaming task execution",
+			logger.Field{Key: "agent", Value: a.role},
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "execution_id", Value: executionID},
+		)
+
+		if task.IsHumanInputRequired() {
+			if hiErr := a.handleHumanInput(ctx, task); hiErr != nil {
+				err = fmt.Errorf("human input handling failed: %w", hiErr)
+			}
+		}
+		ctx, callStats := withCallStatsCollector(ctx)
+		ctx, _ = withSourceCollector(ctx)
+		ctx, injected := a.withInjectedContext(ctx)
+		ctx, reproducibility := a.withReproducibility(ctx, task)
+		ctx, injectionGuard := a.withInjectionGuard(ctx, task)
+		if err == nil {
+			output, err = a.executeStreamCore(ctx, task, chunks)
+		}
+		duration := time.Since(startTime)
+		callStats.apply(output)
+		injected.apply(output)
+		reproducibility.apply(output)
+		injectionGuard.apply(output)
+
+		// 更新统计信息
+		a.updateStats(output, err, duration)
+		endExecutionSpan(span, output, err)
+
+		// 发射完成事件
+		if a.eventBus != nil {
+			completedEvent := NewAgentExecutionCompletedEvent(a.id, a.role, task.GetID(), task.GetDescription(), executionID, duration, err == nil, output)
+			if emitErr := a.eventBus.Emit(ctx, a, completedEvent); emitErr != nil {
+				a.logger.Error("Failed to emit agent execution completed event",
+					logger.Field{Key: "error", Value: emitErr})
+			}
+		}
+
+		a.runExecuteEndHook(hookCtx, task, output)
+
+		final := AgentStreamChunk{Done: true, Output: output, Error: err}
+		if output != nil {
+			final.Content = output.Raw
+		}
+		// 最终块总是发送，缓冲区不足时等待消费者或上下文取消
+		select {
+		case chunks <- final:
+		case <-ctx.Done():
+		}
+	}()
+
+	return chunks, nil
+}
+
+// executeStreamCore 执行流式任务的核心逻辑
+func (a *BaseAgent) executeStreamCore(ctx context.Context, task Task, chunks chan<- AgentStreamChunk) (*TaskOutput, error) {
+	toolCtx, messages, callOptions, err := a.prepareLLMRequest(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := a.moderateInput(ctx, task, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// 工具调用循环需要完整响应，回退为单块流
+	if toolCtx.HasTools() {
+		return a.executeSingleChunk(ctx, task, toolCtx, messages, callOptions, flags, chunks)
+	}
+
+	// 先裁剪上下文，以便把裁剪信息记录到输出中
+	trim, err := a.fitContextWindow(ctx, task, messages, callOptions)
+	if err != nil {
+		return nil, err
+	}
+	messages = trim.Messages
+	var contextStats contextTrimStats
+	contextStats.add(trim)
+
+	callOptions.Stream = true
+	stream, err := a.callLLMStreamWithRetry(ctx, task, messages, callOptions)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("task execution cancelled: %w", ctx.Err())
+		}
+		// 客户端错误等不可恢复的错误直接返回，只有不支持流式或暂时性错误才回退为非流式调用
+		if !errors.Is(err, llm.ErrStreamingNotSupported) && !isTransientLLMError(err) {
+			return nil, err
+		}
+		a.logger.Debug("LLM streaming unavailable, falling back to single chunk",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "error", Value: err},
+		)
+		callOptions.Stream = false
+		return a.executeSingleChunk(ctx, task, toolCtx, messages, callOptions, flags, chunks)
+	}
+
+	var content strings.Builder
+	response := &llm.Response{Model: a.getLLMModelName()}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("task execution cancelled: %w", ctx.Err())
+		case chunk, ok := <-stream:
+			if !ok {
+				response.Content = content.String()
+				output := a.buildTaskOutput(task, response)
+				contextStats.apply(output)
+				if schema := task.GetOutputSchema(); schema != nil {
+					a.enforceOutputSchema(ctx, task, schema, messages, callOptions, output)
+				}
+				// 答案已经流式发出，被拦截时无法重写，直接失败
+				output, err := a.moderateOutput(ctx, task, toolCtx, messages, callOptions, output, flags, false)
+				if err != nil {
+					return nil, err
+				}
+				a.runOutputProcessors(ctx, task, output)
+				a.writeOutputFile(ctx, task, output)
+				if err := a.executeCallbacks(ctx, output); err != nil {
+					a.logger.Error("Callback execution failed",
+						logger.Field{Key: "error", Value: err},
+					)
+				}
+				return output, nil
+			}
+
+			if chunk.Error != nil {
+				return nil, fmt.Errorf("LLM stream failed: %w", chunk.Error)
+			}
+			if chunk.Usage != nil {
+				response.Usage = *chunk.Usage
+			}
+			if chunk.FinishReason != "" {
+				response.FinishReason = chunk.FinishReason
+			}
+			response.Refusal += chunk.Refusal
+			if chunk.Delta == "" {
+				continue
+			}
+
+			content.WriteString(chunk.Delta)
+			if err := sendStreamChunk(ctx, chunks, AgentStreamChunk{
+				Delta:   chunk.Delta,
+				Content: content.String(),
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// executeSingleChunk 以非流式方式执行任务，并将结果作为单个块发送
+// flags为输入审核的标记记录，答案在审核之后才发送，因此可以按配置重写
+func (a *BaseAgent) executeSingleChunk(ctx context.Context, task Task, toolCtx *ToolExecutionContext, messages []llm.Message, callOptions *llm.CallOptions, flags []ModerationAnnotation, chunks chan<- AgentStreamChunk) (*TaskOutput, error) {
+	loopResult, err := a.runToolCallingLoop(ctx, task, toolCtx, messages, callOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	output := a.buildToolLoopOutput(task, loopResult)
+	if schema := task.GetOutputSchema(); schema != nil {
+		a.enforceOutputSchema(ctx, task, schema, messages, callOptions, output)
+	}
+	if output, err = a.moderateOutput(ctx, task, toolCtx, messages, callOptions, output, flags, true); err != nil {
+		return nil, err
+	}
+
+	a.runOutputProcessors(ctx, task, output)
+	a.writeOutputFile(ctx, task, output)
+	if err := a.executeCallbacks(ctx, output); err != nil {
+		a.logger.Error("Callback execution failed",
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+
+	if output.Raw != "" {
+		if err := sendStreamChunk(ctx, chunks, AgentStreamChunk{Delta: output.Raw, Content: output.Raw}); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// sendStreamChunk 发送输出块，并在上下文取消时返回错误
+func sendStreamChunk(ctx context.Context, chunks chan<- AgentStreamChunk, chunk AgentStreamChunk) error {
+	select {
+	case chunks <- chunk:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("task execution cancelled: %w", ctx.Err())
+	}
+}
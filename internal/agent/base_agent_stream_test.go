@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// StreamingMockLLM 按块返回预设增量的模拟LLM
+type StreamingMockLLM struct {
+	*ExtendedMockLLM
+	deltas         []string
+	usage          *llm.Usage
+	streamErr      error
+	streamAttempts int
+}
+
+func (m *StreamingMockLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	m.streamAttempts++
+	if m.streamErr != nil {
+		return nil, m.streamErr
+	}
+
+	ch := make(chan llm.StreamResponse, len(m.deltas)+1)
+	for _, delta := range m.deltas {
+		ch <- llm.StreamResponse{Delta: delta}
+	}
+	ch <- llm.StreamResponse{Usage: m.usage, FinishReason: "stop"}
+	close(ch)
+	return ch, nil
+}
+
+// collectStreamChunks 收集流中的所有块
+func collectStreamChunks(t *testing.T, chunks <-chan AgentStreamChunk) []AgentStreamChunk {
+	t.Helper()
+
+	var collected []AgentStreamChunk
+	for chunk := range chunks {
+		collected = append(collected, chunk)
+	}
+	require.NotEmpty(t, collected)
+	return collected
+}
+
+func newStreamTestAgent(t *testing.T, mockLLM llm.LLM) *BaseAgent {
+	t.Helper()
+
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Streaming Agent",
+		Goal:      "Stream answers",
+		Backstory: "I answer token by token",
+		LLM:       mockLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+	return agent
+}
+
+// TestExecuteStream 测试流式执行转发LLM增量
+func TestExecuteStream(t *testing.T) {
+	mockLLM := &StreamingMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM(nil),
+		deltas:          []string{"Hello", ", ", "world"},
+		usage:           &llm.Usage{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13},
+	}
+	agent := newStreamTestAgent(t, mockLLM)
+
+	chunks, err := agent.ExecuteStream(context.Background(), NewBaseTask("Say hello", "A greeting"))
+	require.NoError(t, err)
+
+	collected := collectStreamChunks(t, chunks)
+	require.Len(t, collected, 4)
+
+	assert.Equal(t, "Hello", collected[0].Delta)
+	assert.Equal(t, "Hello, ", collected[1].Content)
+	assert.Equal(t, "Hello, world", collected[2].Content)
+
+	final := collected[3]
+	assert.True(t, final.Done)
+	require.NoError(t, final.Error)
+	require.NotNil(t, final.Output)
+	assert.Equal(t, "Hello, world", final.Output.Raw)
+	assert.Equal(t, 13, final.Output.TokensUsed)
+
+	stats := agent.GetExecutionStats()
+	assert.Equal(t, 1, stats.SuccessfulExecutions)
+	assert.Equal(t, 13, stats.TokensUsed)
+	assert.Equal(t, 0, mockLLM.callCount)
+}
+
+// TestExecuteStreamFallback 测试LLM不支持流式时回退为单块流
+func TestExecuteStreamFallback(t *testing.T) {
+	mockLLM := &StreamingMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{{Content: "Full answer", Usage: llm.Usage{TotalTokens: 7}}}),
+		streamErr:       llm.ErrStreamingNotSupported,
+	}
+	agent := newStreamTestAgent(t, mockLLM)
+
+	chunks, err := agent.ExecuteStream(context.Background(), NewBaseTask("Answer", "An answer"))
+	require.NoError(t, err)
+
+	collected := collectStreamChunks(t, chunks)
+	require.Len(t, collected, 2)
+	assert.Equal(t, "Full answer", collected[0].Delta)
+	assert.True(t, collected[1].Done)
+	require.NotNil(t, collected[1].Output)
+	assert.Equal(t, "Full answer", collected[1].Output.Raw)
+	assert.Equal(t, 7, collected[1].Output.TokensUsed)
+	assert.Equal(t, 1, mockLLM.callCount)
+}
+
+// TestExecuteStreamWithTools 测试有工具时通过工具调用循环执行
+func TestExecuteStreamWithTools(t *testing.T) {
+	mockLLM := &StreamingMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{
+			{Content: `{"tool_name": "calculator", "arguments": {"operation": "add", "a": 2, "b": 2}}`},
+			{Content: "4"},
+		}),
+		deltas: []string{"should not stream"},
+	}
+	agent := newStreamTestAgent(t, mockLLM)
+	require.NoError(t, agent.AddTool(NewCalculatorTool()))
+
+	chunks, err := agent.ExecuteStream(context.Background(), NewBaseTask("Add 2 and 2", "The sum"))
+	require.NoError(t, err)
+
+	collected := collectStreamChunks(t, chunks)
+	final := collected[len(collected)-1]
+	assert.True(t, final.Done)
+	require.NotNil(t, final.Output)
+	assert.Equal(t, "4", final.Output.Raw)
+	assert.Equal(t, []string{"calculator"}, final.Output.ToolsUsed)
+}
+
+// TestExecuteStreamError 测试流中的错误通过最终块返回
+func TestExecuteStreamError(t *testing.T) {
+	mockLLM := NewExtendedMockLLM(nil).WithFailure(true)
+	agent := newStreamTestAgent(t, &StreamingMockLLM{
+		ExtendedMockLLM: mockLLM,
+		streamErr:       llm.ErrStreamingNotSupported,
+	})
+
+	chunks, err := agent.ExecuteStream(context.Background(), NewBaseTask("Fail", "Nothing"))
+	require.NoError(t, err)
+
+	collected := collectStreamChunks(t, chunks)
+	final := collected[len(collected)-1]
+	assert.True(t, final.Done)
+	assert.Error(t, final.Error)
+	assert.Nil(t, final.Output)
+	assert.Equal(t, 1, agent.GetExecutionStats().FailedExecutions)
+}
+
+// TestExecuteStreamRetriesTransientErrors 测试打开流的暂时性错误按重试策略重试，重试耗尽后回退为单块流
+func TestExecuteStreamRetriesTransientErrors(t *testing.T) {
+	mockLLM := &StreamingMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{{Content: "Full answer"}}),
+		streamErr:       errors.New("HTTP error 503: service unavailable"),
+	}
+	agent := newRetryTestAgent(t, mockLLM, nil, fastRetryPolicy(2))
+
+	chunks, err := agent.ExecuteStream(context.Background(), NewBaseTask("Answer", "An answer"))
+	require.NoError(t, err)
+
+	final := collectStreamChunks(t, chunks)
+	require.NoError(t, final[len(final)-1].Error)
+	assert.Equal(t, "Full answer", final[len(final)-1].Output.Raw)
+	assert.Equal(t, 3, mockLLM.streamAttempts)
+	assert.Equal(t, 1, mockLLM.callCount)
+}
+
+// TestExecuteStreamClientErrorDoesNotFallBack 测试客户端错误直接返回，不回退为非流式调用
+func TestExecuteStreamClientErrorDoesNotFallBack(t *testing.T) {
+	mockLLM := &StreamingMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{{Content: "Full answer"}}),
+		streamErr:       errors.New("HTTP error 400: invalid request"),
+	}
+	agent := newRetryTestAgent(t, mockLLM, nil, fastRetryPolicy(2))
+
+	chunks, err := agent.ExecuteStream(context.Background(), NewBaseTask("Answer", "An answer"))
+	require.NoError(t, err)
+
+	final := collectStreamChunks(t, chunks)
+	assert.ErrorContains(t, final[len(final)-1].Error, "HTTP error 400")
+	assert.Equal(t, 1, mockLLM.streamAttempts)
+	assert.Equal(t, 0, mockLLM.callCount)
+}
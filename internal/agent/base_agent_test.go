@@ -2,10 +2,12 @@ package agent
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
@@ -356,6 +358,17 @@ func TestBaseAgent_ToolManagement(t *testing.T) {
 	if tools[0].GetName() != calculator.GetName() {
 		t.Errorf("expected tool %s, got %s", calculator.GetName(), tools[0].GetName())
 	}
+
+	// 移除工具
+	if err := agent.RemoveTool(calculator.GetName()); err != nil {
+		t.Errorf("failed to remove tool: %v", err)
+	}
+	if len(agent.GetTools()) != 0 {
+		t.Errorf("expected 0 tools after removal, got %d", len(agent.GetTools()))
+	}
+	if err := agent.RemoveTool(calculator.GetName()); err == nil {
+		t.Error("expected error when removing a missing tool")
+	}
 }
 
 // 测试执行统计
@@ -820,3 +833,116 @@ func TestBaseAgent_ReasoningFeatures(t *testing.T) {
 }
 
 // MockReasoningHandler现在在 testing_mocks.go 中定义
+
+// 测试训练得到的改进指令被加入系统提示
+func TestBaseAgent_TrainedInstructions(t *testing.T) {
+	var systemPrompt string
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "Trained answer"}}).
+		WithCallHandler(func(messages []llm.Message) {
+			if len(messages) > 0 && messages[0].Role == llm.RoleSystem {
+				systemPrompt, _ = messages[0].Content.(string)
+			}
+		})
+
+	agent, err := createTestAgent(mockLLM)
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	instructions := []string{"Always cite sources", "Keep answers under 200 words"}
+	agent.SetTrainedInstructions(instructions)
+	instructions[0] = "modified"
+
+	if got := agent.GetTrainedInstructions(); len(got) != 2 || got[0] != "Always cite sources" {
+		t.Errorf("expected instructions to be copied, got %v", got)
+	}
+
+	if _, err := agent.Execute(context.Background(), NewBaseTask("Test task", "Expected output")); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+
+	for _, want := range []string{"Lessons from previous training", "- Always cite sources", "- Keep answers under 200 words"} {
+		if !strings.Contains(systemPrompt, want) {
+			t.Errorf("expected system prompt to contain %q, got:\n%s", want, systemPrompt)
+		}
+	}
+
+	cloned := agent.Clone()
+	if got := cloned.GetTrainedInstructions(); len(got) != 2 {
+		t.Errorf("expected cloned agent to keep trained instructions, got %v", got)
+	}
+}
+
+// 测试任务的图片附件与任务提示一起作为多部分用户消息发送
+func TestBaseAgent_TaskImages(t *testing.T) {
+	var userContent interface{}
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "A bar chart", Usage: llm.Usage{PromptTokens: 900, CompletionTokens: 10, TotalTokens: 910, ImageTokens: 765}}}).
+		WithCallHandler(func(messages []llm.Message) {
+			userContent = messages[len(messages)-1].Content
+		})
+
+	agent, err := createTestAgent(mockLLM)
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	task := NewBaseTask("Describe the chart", "One sentence")
+	if err := task.AddImage("https://example.com/chart.png"); err != nil {
+		t.Fatalf("failed to add image: %v", err)
+	}
+	output, err := agent.Execute(context.Background(), task)
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+
+	parts, ok := userContent.([]llm.ContentPart)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("expected a text part and an image part, got %#v", userContent)
+	}
+	if parts[0].Type != llm.ContentPartText || !strings.Contains(parts[0].Text, "Describe the chart") {
+		t.Errorf("expected the task prompt as the text part, got %+v", parts[0])
+	}
+	if parts[1].Type != llm.ContentPartImage || parts[1].ImageURL.URL != "https://example.com/chart.png" {
+		t.Errorf("expected the attached image, got %+v", parts[1])
+	}
+
+	// 图片token包含在提示token中
+	if output.PromptTokens != 900 || output.TokensUsed != 910 {
+		t.Errorf("expected the image tokens to be counted, got prompt %d total %d", output.PromptTokens, output.TokensUsed)
+	}
+}
+
+// 测试通过AgentConfig构建时事件总线传给LLM
+func TestNewBaseAgent_SharesEventBusWithLLM(t *testing.T) {
+	bus := events.NewEventBus(logger.NewTestLogger())
+	openAI := llm.NewOpenAILLM("gpt-4")
+
+	_, err := NewBaseAgent(AgentConfig{
+		Role:      "Test Agent",
+		Goal:      "Test goal",
+		Backstory: "Test backstory",
+		LLM:       openAI,
+		EventBus:  bus,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	if openAI.GetEventBus() != bus {
+		t.Error("expected the agent's event bus to be set on the LLM")
+	}
+
+	// 已设置的事件总线不被覆盖
+	_, err = NewBaseAgent(AgentConfig{
+		Role:      "Other Agent",
+		Goal:      "Test goal",
+		Backstory: "Test backstory",
+		LLM:       openAI,
+		EventBus:  events.NewEventBus(logger.NewTestLogger()),
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	if openAI.GetEventBus() != bus {
+		t.Error("expected the LLM's existing event bus to be kept")
+	}
+}
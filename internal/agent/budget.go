@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 使用限制和预算的名称，记录在budget_exceeded事件和TaskOutput.Metadata["budget_limits_hit"]中
+const (
+	LimitMaxUsagePerTask          = "max_usage_per_task" // 记录为max_usage_per_task:<工具名>
+	LimitMaxToolCallsPerExecution = "max_tool_calls_per_execution"
+	LimitMaxTotalTokens           = "max_total_tokens"
+	LimitMaxTotalCostUSD          = "max_total_cost_usd"
+	LimitHardCostCeilingUSD       = "hard_cost_ceiling_usd"
+)
+
+// BudgetLimitsHitMetadataKey 本次执行触发的限制列表在TaskOutput.Metadata中的键
+const BudgetLimitsHitMetadataKey = "budget_limits_hit"
+
+// TaskLimitedTool 限制单次任务执行中调用次数的工具，BaseTool通过WithMaxUsagePerTask设置
+// 与GetUsageLimit不同，计数在每次执行任务时重新开始
+type TaskLimitedTool interface {
+	Tool
+	MaxUsagePerTask() int // <=0表示不限制
+}
+
+// BudgetLimits 一次Crew执行（所有Agent和任务合计）的开销预算，<=0表示不限制
+// MaxTotalTokens和MaxTotalCostUSD是软限制：达到后Agent停止调用工具并给出最终答案；
+// HardCostCeilingUSD是硬限制：超过后当前LLM调用返回BudgetExceededError，执行中止
+type BudgetLimits struct {
+	MaxTotalTokens     int     `json:"max_total_tokens"`
+	MaxTotalCostUSD    float64 `json:"max_total_cost_usd"`
+	HardCostCeilingUSD float64 `json:"hard_cost_ceiling_usd"`
+}
+
+// IsZero 判断是否没有设置任何预算
+func (l BudgetLimits) IsZero() bool {
+	return l.MaxTotalTokens <= 0 && l.MaxTotalCostUSD <= 0 && l.HardCostCeilingUSD <= 0
+}
+
+// BudgetExceededError 开销超过硬性成本上限时返回的错误，带有截至目前的开销
+type BudgetExceededError struct {
+	Limit       string  `json:"limit"`
+	CeilingUSD  float64 `json:"ceiling_usd"`
+	SpentUSD    float64 `json:"spent_usd"`
+	SpentTokens int     `json:"spent_tokens"`
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded: spent $%.4f (%d tokens), hard cost ceiling is $%.4f", e.SpentUSD, e.SpentTokens, e.CeilingUSD)
+}
+
+// Budget 累计一次执行中所有LLM调用的开销，通过ctx在Agent和任务之间共享，并发安全
+// nil Budget的方法都是空操作
+type Budget struct {
+	limits BudgetLimits
+	mu     sync.Mutex
+	tokens int
+	cost   float64
+}
+
+// NewBudget 创建预算
+func NewBudget(limits BudgetLimits) *Budget {
+	return &Budget{limits: limits}
+}
+
+type budgetKey struct{}
+
+// WithBudget 返回带有预算的ctx，之后的Agent执行都把LLM开销计入该预算
+func WithBudget(ctx context.Context, budget *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, budget)
+}
+
+// BudgetFrom 返回ctx中的预算，没有时返回nil
+func BudgetFrom(ctx context.Context) *Budget {
+	budget, _ := ctx.Value(budgetKey{}).(*Budget)
+	return budget
+}
+
+// Limits 返回预算的限制
+func (b *Budget) Limits() BudgetLimits {
+	if b == nil {
+		return BudgetLimits{}
+	}
+	return b.limits
+}
+
+// Spent 返回截至目前的token数和成本
+func (b *Budget) Spent() (tokens int, costUSD float64) {
+	if b == nil {
+		return 0, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens, b.cost
+}
+
+// Record 计入一次调用的开销，超过硬性成本上限时返回*BudgetExceededError
+func (b *Budget) Record(tokens int, costUSD float64) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += tokens
+	b.cost += costUSD
+	if b.limits.HardCostCeilingUSD > 0 && b.cost > b.limits.HardCostCeilingUSD {
+		return &BudgetExceededError{
+			Limit:       LimitHardCostCeilingUSD,
+			CeilingUSD:  b.limits.HardCostCeilingUSD,
+			SpentUSD:    b.cost,
+			SpentTokens: b.tokens,
+		}
+	}
+	return nil
+}
+
+// Exhausted 返回已经达到的软限制及其当前值和阈值，没有达到时limit为空
+func (b *Budget) Exhausted() (limit string, value, threshold float64) {
+	if b == nil {
+		return "", 0, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limits.MaxTotalTokens > 0 && b.tokens >= b.limits.MaxTotalTokens {
+		return LimitMaxTotalTokens, float64(b.tokens), float64(b.limits.MaxTotalTokens)
+	}
+	if b.limits.MaxTotalCostUSD > 0 && b.cost >= b.limits.MaxTotalCostUSD {
+		return LimitMaxTotalCostUSD, b.cost, b.limits.MaxTotalCostUSD
+	}
+	return "", 0, 0
+}
+
+// recordBudgetSpend 把一次LLM调用的开销计入ctx中的预算，超过硬性上限时发出事件并返回错误
+func (a *BaseAgent) recordBudgetSpend(ctx context.Context, task Task, usage llm.Usage) error {
+	err := BudgetFrom(ctx).Record(usage.TotalTokens, usage.Cost)
+	if exceeded, ok := err.(*BudgetExceededError); ok {
+		a.logger.Error("Hard cost ceiling exceeded, aborting execution",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "spent_usd", Value: exceeded.SpentUSD},
+			logger.Field{Key: "ceiling_usd", Value: exceeded.CeilingUSD},
+		)
+		a.emitBudgetExceeded(ctx, task, exceeded.Limit, exceeded.SpentUSD, exceeded.CeilingUSD)
+	}
+	return err
+}
+
+// emitBudgetExceeded 发出budget_exceeded事件
+func (a *BaseAgent) emitBudgetExceeded(ctx context.Context, task Task, limit string, value, threshold float64) {
+	if a.eventBus == nil {
+		return
+	}
+	event := NewBudgetExceededEvent(a.id, a.role, task.GetID(), limit, value, threshold)
+	if err := a.eventBus.Emit(ctx, a, event); err != nil {
+		a.logger.Error("Failed to emit budget exceeded event",
+			logger.Field{Key: "error", Value: err})
+	}
+}
+
+// toolUsageLimiter 单次执行中的工具使用限制，记录已触发的限制
+type toolUsageLimiter struct {
+	perTool   map[string]int // 按工具名的单次任务调用上限
+	maxCalls  int            // 单次执行的工具调用总数上限
+	limitsHit []string
+}
+
+// newToolUsageLimiter 合并工具声明的MaxUsagePerTask和ExecutionConfig.ToolUsageLimits，后者优先
+func newToolUsageLimiter(tools []Tool, config ExecutionConfig) *toolUsageLimiter {
+	limiter := &toolUsageLimiter{perTool: make(map[string]int), maxCalls: config.MaxToolCallsPerExecution}
+	for _, tool := range tools {
+		if limited, ok := tool.(TaskLimitedTool); ok && limited.MaxUsagePerTask() > 0 {
+			limiter.perTool[tool.GetName()] = limited.MaxUsagePerTask()
+		}
+	}
+	for name, limit := range config.ToolUsageLimits {
+		if limit > 0 {
+			limiter.perTool[name] = limit
+		} else {
+			delete(limiter.perTool, name)
+		}
+	}
+	return limiter
+}
+
+// check 判断再调用一次call是否会超过限制，超过时返回限制名称、当前值和阈值
+func (l *toolUsageLimiter) check(result *toolLoopResult, call toolCallRequest) (limit string, value, threshold float64) {
+	if l.maxCalls > 0 && result.ToolCalls >= l.maxCalls {
+		return LimitMaxToolCallsPerExecution, float64(result.ToolCalls), float64(l.maxCalls)
+	}
+	if maxUsage, ok := l.perTool[call.Name]; ok && result.ToolUsage[call.Name] >= maxUsage {
+		return LimitMaxUsagePerTask + ":" + call.Name, float64(result.ToolUsage[call.Name]), float64(maxUsage)
+	}
+	return "", 0, 0
+}
+
+// hit 记录触发的限制，重复的限制只记录一次，返回是否是第一次触发
+func (l *toolUsageLimiter) hit(limit string) bool {
+	for _, existing := range l.limitsHit {
+		if existing == limit {
+			return false
+		}
+	}
+	l.limitsHit = append(l.limitsHit, limit)
+	return true
+}
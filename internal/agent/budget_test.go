@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// loopingLLM 提供工具时总是请求调用search，没有工具时给出最终答案，每次调用消耗固定的token和成本
+type loopingLLM struct {
+	*ExtendedMockLLM
+	calls   [][]llm.Message
+	noTools int
+	usage   llm.Usage
+}
+
+func newLoopingLLM(usage llm.Usage) *loopingLLM {
+	return &loopingLLM{ExtendedMockLLM: NewExtendedMockLLM(nil), usage: usage}
+}
+
+func (m *loopingLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	m.calls = append(m.calls, append([]llm.Message(nil), messages...))
+	if options == nil || len(options.Tools) == 0 {
+		m.noTools++
+		return &llm.Response{Content: "Best answer so far", Usage: m.usage}, nil
+	}
+	return &llm.Response{ToolCalls: []llm.ToolCall{toolCall("search"), toolCall("search")}, Usage: m.usage}, nil
+}
+
+func newSearchTool(count *int) *BaseTool {
+	return NewBaseTool("search", "Search the web", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		*count++
+		return "results", nil
+	})
+}
+
+// captureBudgetEvents 同步订阅budget_exceeded事件
+func captureBudgetEvents(t *testing.T, eventBus events.EventBus) func() []*BudgetExceededEvent {
+	var mu sync.Mutex
+	var captured []*BudgetExceededEvent
+	subscription, err := eventBus.SubscribeWithOptions("budget_exceeded", func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		captured = append(captured, event.(*BudgetExceededEvent))
+		return nil
+	}, events.WithSyncDelivery())
+	require.NoError(t, err)
+	t.Cleanup(func() { subscription.Unsubscribe() })
+	return func() []*BudgetExceededEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*BudgetExceededEvent(nil), captured...)
+	}
+}
+
+// TestToolMaxUsagePerTask 测试达到工具的单次任务限制后不再执行工具，LLM被要求直接给出最终答案
+func TestToolMaxUsagePerTask(t *testing.T) {
+	mockLLM := newLoopingLLM(llm.Usage{})
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+	budgetEvents := captureBudgetEvents(t, eventBus)
+
+	executed := 0
+	agent := newToolLoopTestAgent(t, mockLLM, eventBus, newSearchTool(&executed).WithMaxUsagePerTask(3))
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Research the topic", "A summary"))
+	require.NoError(t, err)
+	assert.Equal(t, "Best answer so far", output.Raw)
+	assert.Equal(t, 3, executed)
+	assert.Equal(t, 1, mockLLM.noTools)
+	assert.Equal(t, []string{"max_usage_per_task:search"}, output.Metadata[BudgetLimitsHitMetadataKey])
+
+	// 最后一次调用带有预算用尽的指示，未执行的调用也有对应的观察
+	final := mockLLM.calls[len(mockLLM.calls)-1]
+	assert.Contains(t, final[len(final)-1].Content, "max_usage_per_task:search")
+	skipped := 0
+	for _, message := range final {
+		if message.Role == llm.RoleTool && strings.Contains(fmt.Sprint(message.Content), "not executed") {
+			skipped++
+		}
+	}
+	assert.Equal(t, 1, skipped)
+
+	captured := budgetEvents()
+	require.Len(t, captured, 1)
+	assert.Equal(t, "max_usage_per_task:search", captured[0].Limit)
+	assert.Equal(t, float64(3), captured[0].Value)
+	assert.Equal(t, float64(3), captured[0].Threshold)
+
+	// 计数在每次执行时重新开始
+	executed = 0
+	_, err = agent.Execute(context.Background(), NewBaseTask("Research another topic", "A summary"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, executed)
+}
+
+// TestExecutionConfigToolLimits 测试ExecutionConfig中的工具限制覆盖工具自身的设置，并限制调用总数
+func TestExecutionConfigToolLimits(t *testing.T) {
+	mockLLM := newLoopingLLM(llm.Usage{})
+	executed := 0
+	agent := newToolLoopTestAgent(t, mockLLM, nil, newSearchTool(&executed).WithMaxUsagePerTask(1))
+
+	config := agent.GetExecutionConfig()
+	config.ToolUsageLimits = map[string]int{"search": 0}
+	config.MaxToolCallsPerExecution = 5
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Research the topic", "A summary"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, executed)
+	assert.Equal(t, []string{LimitMaxToolCallsPerExecution}, output.Metadata[BudgetLimitsHitMetadataKey])
+	assert.Equal(t, 5, output.Metadata["tool_calls"])
+}
+
+// TestBudgetSoftAndHardLimits 测试ctx中的预算：软限制让Agent停止调用工具，硬上限中止执行
+func TestBudgetSoftAndHardLimits(t *testing.T) {
+	mockLLM := newLoopingLLM(llm.Usage{TotalTokens: 100, Cost: 0.01})
+	executed := 0
+	agent := newToolLoopTestAgent(t, mockLLM, nil, newSearchTool(&executed))
+
+	budget := NewBudget(BudgetLimits{MaxTotalTokens: 250})
+	output, err := agent.Execute(WithBudget(context.Background(), budget), NewBaseTask("Research the topic", "A summary"))
+	require.NoError(t, err)
+	assert.Equal(t, "Best answer so far", output.Raw)
+	assert.Equal(t, []string{LimitMaxTotalTokens}, output.Metadata[BudgetLimitsHitMetadataKey])
+	tokens, cost := budget.Spent()
+	assert.Equal(t, 400, tokens)
+	assert.InDelta(t, 0.04, cost, 1e-9)
+
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+	budgetEvents := captureBudgetEvents(t, eventBus)
+	agent = newToolLoopTestAgent(t, newLoopingLLM(llm.Usage{TotalTokens: 100, Cost: 0.01}), eventBus, newSearchTool(&executed))
+	budget = NewBudget(BudgetLimits{HardCostCeilingUSD: 0.025})
+	_, err = agent.Execute(WithBudget(context.Background(), budget), NewBaseTask("Research the topic", "A summary"))
+
+	var exceeded *BudgetExceededError
+	require.True(t, errors.As(err, &exceeded), "unexpected error: %v", err)
+	assert.Equal(t, 300, exceeded.SpentTokens)
+	assert.InDelta(t, 0.03, exceeded.SpentUSD, 1e-9)
+	assert.Equal(t, 0.025, exceeded.CeilingUSD)
+
+	captured := budgetEvents()
+	require.Len(t, captured, 1)
+	assert.Equal(t, LimitHardCostCeilingUSD, captured[0].Limit)
+}
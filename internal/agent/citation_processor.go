@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultCitationMinSpanTokens 输出与来源连续相同的最少词数，中日韩文字按字计
+const defaultCitationMinSpanTokens = 8
+
+// CitationProcessor 把输出与本次执行注入的知识条目和工具结果比对，填充TaskOutput.Citations
+// 输出中有一段与来源连续相同（忽略大小写和标点）时引用该来源，Span为输出中的这段原文；
+// 输出以"[来源名]"形式提到知识来源时也会引用。每个来源最多引用一次，按在输出中出现的位置排序
+type CitationProcessor struct {
+	MinSpanTokens int // 连续匹配的最少词数，<=0时使用默认值
+}
+
+var _ OutputProcessor = (*CitationProcessor)(nil)
+
+// NewCitationProcessor 创建使用默认匹配长度的引用处理器
+func NewCitationProcessor() *CitationProcessor {
+	return &CitationProcessor{MinSpanTokens: defaultCitationMinSpanTokens}
+}
+
+// Name 实现OutputProcessor接口
+func (p *CitationProcessor) Name() string {
+	return "citations"
+}
+
+// Process 实现OutputProcessor接口
+func (p *CitationProcessor) Process(ctx context.Context, task Task, sources []OutputSource, output *TaskOutput) error {
+	minTokens := p.MinSpanTokens
+	if minTokens <= 0 {
+		minTokens = defaultCitationMinSpanTokens
+	}
+
+	outputTokens := citationTokens(output.Raw)
+	type located struct {
+		citation Citation
+		offset   int
+	}
+	var found []located
+	for _, source := range sources {
+		citation := Citation{
+			SourceID: source.ID,
+			Kind:     source.Kind,
+			Source:   source.Name,
+			Location: sourceLocation(source),
+		}
+
+		start, end, length := longestCommonRun(outputTokens, citationTokens(source.Content))
+		if length >= minTokens {
+			citation.Span = output.Raw[start:end]
+			found = append(found, located{citation, start})
+			continue
+		}
+
+		// 以知识注入时的"[来源]"格式显式引用
+		if source.Kind == SourceKindKnowledge && source.Name != "" {
+			marker := "[" + source.Name + "]"
+			if offset := strings.Index(output.Raw, marker); offset >= 0 {
+				citation.Span = marker
+				found = append(found, located{citation, offset})
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].offset < found[j].offset
+	})
+	for _, item := range found {
+		output.Citations = append(output.Citations, item.citation)
+	}
+	return nil
+}
+
+// citationToken 归一化的词及其在原文中的字节范围
+type citationToken struct {
+	text       string
+	start, end int
+}
+
+// citationTokens 把文本切分为小写的词，标点和空白作为分隔；中日韩文字每个字单独成词
+func citationTokens(text string) []citationToken {
+	var tokens []citationToken
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, citationToken{text: strings.ToLower(text[start:end]), start: start, end: end})
+			start = -1
+		}
+	}
+
+	for i, r := range text {
+		switch {
+		case isIdeograph(r):
+			flush(i)
+			end := i + utf8.RuneLen(r)
+			tokens = append(tokens, citationToken{text: text[i:end], start: i, end: end})
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			flush(i)
+		}
+	}
+	flush(len(text))
+	return tokens
+}
+
+// isIdeograph 是否为不以空格分词的文字
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// longestCommonRun 返回output和source中最长的连续相同词序列在output原文中的字节范围及词数
+func longestCommonRun(output, source []citationToken) (int, int, int) {
+	if len(output) == 0 || len(source) == 0 {
+		return 0, 0, 0
+	}
+
+	best, bestEnd := 0, 0
+	previous := make([]int, len(source)+1)
+	current := make([]int, len(source)+1)
+	for i := 1; i <= len(output); i++ {
+		for j := 1; j <= len(source); j++ {
+			if output[i-1].text == source[j-1].text {
+				current[j] = previous[j-1] + 1
+				if current[j] > best {
+					best, bestEnd = current[j], i
+				}
+			} else {
+				current[j] = 0
+			}
+		}
+		previous, current = current, previous
+	}
+	if best == 0 {
+		return 0, 0, 0
+	}
+	return output[bestEnd-best].start, output[bestEnd-1].end, best
+}
+
+// sourceLocation 根据来源的元数据生成位置：PDF的页码、CSV的行号、网页的URL
+func sourceLocation(source OutputSource) string {
+	metadata := source.Metadata
+	if len(metadata) == 0 {
+		return ""
+	}
+
+	var parts []string
+	if page, ok := metadata["page"]; ok {
+		parts = append(parts, fmt.Sprintf("page %v", page))
+	}
+	if rowStart, ok := metadata["row_start"]; ok {
+		rowEnd, hasEnd := metadata["row_end"]
+		if !hasEnd || fmt.Sprint(rowEnd) == fmt.Sprint(rowStart) {
+			parts = append(parts, fmt.Sprintf("row %v", rowStart))
+		} else {
+			parts = append(parts, fmt.Sprintf("rows %v-%v", rowStart, rowEnd))
+		}
+	} else if row, ok := metadata["row"]; ok {
+		parts = append(parts, fmt.Sprintf("row %v", row))
+	}
+	for _, key := range []string{"url", "link"} {
+		if url, ok := metadata[key].(string); ok && url != "" {
+			parts = append(parts, url)
+			break
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// namespacedTestMemory 按命名空间隔离的内存记忆，多个命名空间共享同一份存储
+type namespacedTestMemory struct {
+	namespace string
+	store     map[string]map[string]interface{}
+}
+
+func newNamespacedTestMemory() *namespacedTestMemory {
+	return &namespacedTestMemory{store: make(map[string]map[string]interface{})}
+}
+
+func (m *namespacedTestMemory) WithNamespace(namespace string) Memory {
+	return &namespacedTestMemory{namespace: namespace, store: m.store}
+}
+
+func (m *namespacedTestMemory) Store(ctx context.Context, key string, value interface{}) error {
+	if m.store[m.namespace] == nil {
+		m.store[m.namespace] = make(map[string]interface{})
+	}
+	m.store[m.namespace][key] = value
+	return nil
+}
+
+func (m *namespacedTestMemory) Retrieve(ctx context.Context, key string) (interface{}, error) {
+	value, ok := m.store[m.namespace][key]
+	if !ok {
+		return nil, fmt.Errorf("memory not found: %s", key)
+	}
+	return value, nil
+}
+
+func (m *namespacedTestMemory) Search(ctx context.Context, query string, limit int) ([]MemoryItem, error) {
+	return nil, nil
+}
+
+func (m *namespacedTestMemory) Clear(ctx context.Context) error {
+	delete(m.store, m.namespace)
+	return nil
+}
+
+func (m *namespacedTestMemory) GetStats() MemoryStats {
+	return MemoryStats{TotalItems: len(m.store[m.namespace])}
+}
+
+// TestBaseAgentCloneIsolatesState 测试克隆的Agent不与原Agent共享工具计数、记忆和统计
+func TestBaseAgentCloneIsolatesState(t *testing.T) {
+	tool := NewBaseTool("lookup", "Look something up", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "found", nil
+	})
+	tool.SetUsageLimit(5)
+	mem := newNamespacedTestMemory()
+
+	original, err := NewBaseAgent(AgentConfig{
+		Role:      "Researcher",
+		Goal:      "Find facts",
+		Backstory: "Careful researcher",
+		LLM:       NewMockLLM(&llm.Response{Content: "done", Usage: llm.Usage{TotalTokens: 10}}, false),
+		Tools:     []Tool{tool},
+		Memory:    mem,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	_, err = original.Execute(context.Background(), NewBaseTask("Research", "Notes"))
+	require.NoError(t, err)
+	_, err = tool.Execute(context.Background(), nil)
+	require.NoError(t, err)
+	require.NoError(t, original.GetMemory().Store(context.Background(), "topic", "original"))
+
+	clone := original.Clone()
+	assert.NotEqual(t, original.GetID(), clone.GetID())
+	assert.NotEqual(t, original.GetFingerprint().GetUUID(), clone.GetFingerprint().GetUUID())
+	assert.Zero(t, clone.GetExecutionStats().TotalExecutions)
+	assert.Zero(t, clone.GetUsageMetrics().TotalTasks)
+
+	// 工具被复制，使用计数清零但保留限制
+	clonedTool := clone.GetTools()[0]
+	assert.NotSame(t, tool, clonedTool)
+	assert.Zero(t, clonedTool.GetUsageCount())
+	assert.Equal(t, 5, clonedTool.GetUsageLimit())
+	_, err = clonedTool.Execute(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tool.GetUsageCount())
+
+	// 副本在以自身ID为命名空间的记忆中读写
+	_, err = clone.GetMemory().Retrieve(context.Background(), "topic")
+	assert.Error(t, err)
+	require.NoError(t, clone.GetMemory().Store(context.Background(), "topic", "clone"))
+	value, err := original.GetMemory().Retrieve(context.Background(), "topic")
+	require.NoError(t, err)
+	assert.Equal(t, "original", value)
+	assert.Contains(t, mem.store, clone.GetID())
+
+	// WithSharedMemory保留原记忆实例
+	shared := original.CloneWithOptions(WithSharedMemory())
+	assert.Same(t, mem, shared.GetMemory())
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 条件任务的便捷条件构造函数，条件基于上一个任务的输出
+
+// JSONPathCondition 构造基于JSON路径的条件
+// path形如"sentiment.score"或"items[0].name"（可带"$."前缀），路径不存在时条件不满足。
+// JSON取自输出的JSON字段，其次是结构化解析结果，最后尝试从原始输出中解析
+func JSONPathCondition(path string, predicate func(value interface{}) bool) func(*TaskOutput) bool {
+	return func(output *TaskOutput) bool {
+		value, ok := lookupJSONPath(outputJSON(output), path)
+		return ok && predicate(value)
+	}
+}
+
+// JSONPathNumberCondition 构造基于JSON路径数值的条件，数值字符串也会被解析
+// 例如JSONPathNumberCondition("sentiment", func(v float64) bool { return v < 0.3 })
+func JSONPathNumberCondition(path string, predicate func(value float64) bool) func(*TaskOutput) bool {
+	return JSONPathCondition(path, func(value interface{}) bool {
+		number, ok := toFloat(value)
+		return ok && predicate(number)
+	})
+}
+
+// RegexCondition 构造原始输出匹配正则表达式时满足的条件
+func RegexCondition(pattern string) (func(*TaskOutput) bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition pattern: %w", err)
+	}
+	return func(output *TaskOutput) bool {
+		return output != nil && re.MatchString(output.Raw)
+	}, nil
+}
+
+// outputJSON 返回任务输出的JSON对象，无法获得时返回nil
+func outputJSON(output *TaskOutput) map[string]interface{} {
+	if output == nil {
+		return nil
+	}
+	if output.JSON != nil {
+		return output.JSON
+	}
+	if parsed, ok := output.Parsed.(map[string]interface{}); ok {
+		return parsed
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(extractJSONPayload(output.Raw)), &data); err != nil {
+		return nil
+	}
+	return data
+}
+
+// lookupJSONPath 按路径在JSON数据中取值
+func lookupJSONPath(data interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if data == nil || path == "" {
+		return nil, false
+	}
+
+	current := data
+	for _, segment := range strings.Split(path, ".") {
+		// 拆分"items[0][1]"形式的键和数组下标
+		key := segment
+		var indexes []string
+		if open := strings.Index(segment, "["); open >= 0 {
+			key = segment[:open]
+			for _, part := range strings.Split(segment[open:], "[")[1:] {
+				if !strings.HasSuffix(part, "]") {
+					return nil, false
+				}
+				indexes = append(indexes, strings.TrimSuffix(part, "]"))
+			}
+		}
+
+		if key != "" {
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = object[key]; !ok {
+				return nil, false
+			}
+		}
+
+		for _, index := range indexes {
+			i, err := strconv.Atoi(index)
+			array, ok := current.([]interface{})
+			if err != nil || !ok || i < 0 || i >= len(array) {
+				return nil, false
+			}
+			current = array[i]
+		}
+	}
+	return current, true
+}
+
+// toFloat 将JSON数值或数值字符串转换为float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
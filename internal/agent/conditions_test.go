@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestJSONPathCondition(t *testing.T) {
+	highRisk := JSONPathNumberCondition("analysis.risk[0].score", func(v float64) bool { return v > 0.5 })
+
+	cases := []struct {
+		name     string
+		output   *TaskOutput
+		expected bool
+	}{
+		{"json field", &TaskOutput{JSON: map[string]interface{}{
+			"analysis": map[string]interface{}{"risk": []interface{}{map[string]interface{}{"score": 0.8}}},
+		}}, true},
+		{"raw json in code fence", &TaskOutput{Raw: "```json\n{\"analysis\": {\"risk\": [{\"score\": \"0.9\"}]}}\n```"}, true},
+		{"below threshold", &TaskOutput{Raw: `{"analysis": {"risk": [{"score": 0.2}]}}`}, false},
+		{"missing path", &TaskOutput{Raw: `{"analysis": {"risk": []}}`}, false},
+		{"not json", &TaskOutput{Raw: "no structured output"}, false},
+		{"nil output", nil, false},
+	}
+	for _, tc := range cases {
+		if got := highRisk(tc.output); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+
+	approved := JSONPathCondition("$.status", func(v interface{}) bool { return v == "approved" })
+	if !approved(&TaskOutput{Raw: `{"status": "approved"}`}) {
+		t.Error("expected status condition to match")
+	}
+}
+
+func TestRegexCondition(t *testing.T) {
+	condition, err := RegexCondition(`(?i)\bescalate\b`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !condition(&TaskOutput{Raw: "Decision: ESCALATE to tier 2"}) {
+		t.Error("expected regex condition to match")
+	}
+	if condition(&TaskOutput{Raw: "Resolved"}) || condition(nil) {
+		t.Error("expected regex condition not to match")
+	}
+
+	if _, err := RegexCondition("("); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestConditionalTaskSkippedOutput(t *testing.T) {
+	condition, _ := RegexCondition("escalate")
+	task := WrapConditionalTask(NewBaseTask("Escalate ticket", "Escalation report"), condition)
+
+	execute, err := task.ShouldExecute(context.Background(), nil)
+	if err != nil || !execute {
+		t.Error("task should execute when there is no previous output")
+	}
+	execute, _ = task.ShouldExecute(context.Background(), &TaskOutput{Raw: "resolved"})
+	if execute {
+		t.Error("task should not execute when condition is not met")
+	}
+
+	skipped := task.GetSkippedTaskOutput()
+	if !IsSkippedOutput(skipped) {
+		t.Error("expected skipped output to be marked as skipped")
+	}
+	if !strings.HasPrefix(skipped.Raw, "[SKIPPED]") || !strings.Contains(skipped.Raw, "Escalate ticket") {
+		t.Errorf("unexpected skipped output: %q", skipped.Raw)
+	}
+	if IsSkippedOutput(&TaskOutput{Raw: "done"}) {
+		t.Error("regular output should not be marked as skipped")
+	}
+
+	cloned, ok := task.Clone().(*BaseConditionalTask)
+	if !ok || cloned.GetCondition() == nil {
+		t.Error("clone should keep the condition")
+	}
+}
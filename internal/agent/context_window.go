@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// contextTrimStats 一次任务执行中上下文裁剪的累计信息
+type contextTrimStats struct {
+	Strategy        llm.TrimStrategy
+	Trims           int
+	TokensTrimmed   int
+	MessagesRemoved int
+}
+
+// add 累计一次裁剪结果
+func (s *contextTrimStats) add(result *llm.TrimResult) {
+	if result == nil || !result.Trimmed() {
+		return
+	}
+	s.Strategy = result.Strategy
+	s.Trims++
+	s.TokensTrimmed += result.TokensTrimmed()
+	s.MessagesRemoved += result.MessagesRemoved
+}
+
+// apply 把裁剪信息累加到任务输出的元数据，没有裁剪时不写入
+// 输出修正等后续调用的裁剪与工具调用循环的裁剪计入同一组字段
+func (s *contextTrimStats) apply(output *TaskOutput) {
+	if s.Trims == 0 {
+		return
+	}
+	output.Metadata["context_strategy"] = string(s.Strategy)
+	for key, value := range map[string]int{
+		"context_trims":            s.Trims,
+		"context_tokens_trimmed":   s.TokensTrimmed,
+		"context_messages_removed": s.MessagesRemoved,
+	} {
+		previous, _ := output.Metadata[key].(int)
+		output.Metadata[key] = previous + value
+	}
+}
+
+// fitContextWindow 保证消息不超出LLM的上下文窗口
+// 开启RespectContextWindow时按ContextStrategy裁剪，否则超出时返回所需和可用token数的错误；
+// 未知窗口大小时原样返回
+func (a *BaseAgent) fitContextWindow(ctx context.Context, task Task, messages []llm.Message, callOptions *llm.CallOptions) (*llm.TrimResult, error) {
+	window := a.llmProvider.GetContextWindowSize()
+	if window <= 0 {
+		return &llm.TrimResult{Messages: messages}, nil
+	}
+
+	config := a.executionConfig
+	tokenizer := config.Tokenizer
+	if tokenizer == nil {
+		tokenizer = a.llmProvider.GetTokenizer()
+	}
+	budget := llm.ContextBudget(window, callOptions, config.ReservedCompletionTokens)
+	manager := llm.NewContextManager(
+		llm.WithTokenizer(tokenizer),
+		llm.WithTrimStrategy(config.ContextStrategy),
+		llm.WithSummarizer(a.llmProvider),
+	)
+
+	if !config.RespectContextWindow {
+		if err := manager.Check(messages, budget); err != nil {
+			return nil, err
+		}
+		return &llm.TrimResult{Messages: messages}, nil
+	}
+
+	result, err := manager.Fit(ctx, messages, budget)
+	if err != nil {
+		a.logger.Error("Messages do not fit the context window",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "strategy", Value: manager.Strategy()},
+			logger.Field{Key: "context_window", Value: window},
+			logger.Field{Key: "error", Value: err},
+		)
+		return nil, err
+	}
+
+	if result.Trimmed() {
+		a.logger.Info("Trimmed messages to fit the context window",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "strategy", Value: result.Strategy},
+			logger.Field{Key: "budget", Value: budget},
+			logger.Field{Key: "tokens_before", Value: result.TokensBefore},
+			logger.Field{Key: "tokens_after", Value: result.TokensAfter},
+			logger.Field{Key: "tokens_trimmed", Value: result.TokensTrimmed()},
+			logger.Field{Key: "messages_removed", Value: result.MessagesRemoved},
+		)
+	}
+	return result, nil
+}
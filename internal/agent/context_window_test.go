@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// TestAgentContextWindow 测试超出上下文窗口的提示按配置裁剪或直接失败
+func TestAgentContextWindow(t *testing.T) {
+	// ExtendedMockLLM的窗口为4096，默认MaxTokens预留一半，提示预算为2048个token
+	longTask := "Summarize the report:\n" + strings.Repeat("revenue grew in every region. ", 400)
+
+	newAgent := func(t *testing.T, configure func(*ExecutionConfig)) (*BaseAgent, *[]string) {
+		var prompts []string
+		mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "Revenue grew everywhere."}}).
+			WithCallHandler(func(messages []llm.Message) {
+				prompts = append(prompts, messages[len(messages)-1].Content.(string))
+			})
+		agent, err := NewBaseAgent(AgentConfig{
+			Role:      "Analyst",
+			Goal:      "Summarize reports",
+			Backstory: "Concise writer",
+			LLM:       mockLLM,
+			Logger:    logger.NewTestLogger(),
+		})
+		require.NoError(t, err)
+
+		config := DefaultExecutionConfig()
+		configure(&config)
+		require.NoError(t, agent.SetExecutionConfig(config))
+		return agent, &prompts
+	}
+
+	t.Run("middle out", func(t *testing.T) {
+		agent, prompts := newAgent(t, func(*ExecutionConfig) {})
+
+		output, err := agent.Execute(context.Background(), NewTaskWithOptions(longTask, "A summary"))
+		require.NoError(t, err)
+
+		require.Len(t, *prompts, 1)
+		assert.Contains(t, (*prompts)[0], "tokens truncated")
+		assert.True(t, strings.HasPrefix((*prompts)[0], "Summarize the report:"))
+		assert.Equal(t, "middle_out", output.Metadata["context_strategy"])
+		assert.Greater(t, output.Metadata["context_tokens_trimmed"], 0)
+	})
+
+	t.Run("counts each call once", func(t *testing.T) {
+		var sent, counted int
+		mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "Revenue grew everywhere."}}).
+			WithCallHandler(func(messages []llm.Message) { sent += len(messages) })
+		agent, err := NewBaseAgent(AgentConfig{
+			Role:      "Analyst",
+			Goal:      "Summarize reports",
+			Backstory: "Concise writer",
+			LLM:       mockLLM,
+			Logger:    logger.NewTestLogger(),
+		})
+		require.NoError(t, err)
+		config := DefaultExecutionConfig()
+		config.Tokenizer = llm.TokenizerFunc(func(text string) int {
+			counted++
+			return len(text) / 4
+		})
+		require.NoError(t, agent.SetExecutionConfig(config))
+
+		_, err = agent.Execute(context.Background(), NewTaskWithOptions("Summarize briefly", "A summary"))
+		require.NoError(t, err)
+		// 每条发送的消息只计数一次，工具调用循环和重试调用不会重复裁剪
+		assert.Equal(t, sent, counted)
+	})
+
+	t.Run("fits without trimming", func(t *testing.T) {
+		agent, _ := newAgent(t, func(*ExecutionConfig) {})
+
+		output, err := agent.Execute(context.Background(), NewTaskWithOptions("Summarize briefly", "A summary"))
+		require.NoError(t, err)
+		assert.NotContains(t, output.Metadata, "context_strategy")
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		agent, prompts := newAgent(t, func(config *ExecutionConfig) {
+			config.RespectContextWindow = false
+		})
+
+		_, err := agent.Execute(context.Background(), NewTaskWithOptions(longTask, "A summary"))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, llm.ErrContextWindowExceeded))
+		assert.Contains(t, err.Error(), "have 2048")
+		assert.Empty(t, *prompts)
+	})
+}
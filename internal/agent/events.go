@@ -64,6 +64,16 @@ type AgentToolUsageCompletedEvent struct {
 	Error    string        `json:"error,omitempty"`
 }
 
+// AgentToolUsageProgressEvent 代表长时间运行的工具报告的进度
+type AgentToolUsageProgressEvent struct {
+	events.BaseEvent
+	AgentID  string       `json:"agent_id"`
+	Agent    string       `json:"agent"`
+	TaskID   string       `json:"task_id"`
+	ToolName string       `json:"tool_name"`
+	Progress ToolProgress `json:"progress"`
+}
+
 // AgentMemoryRetrievalStartedEvent 代表Agent开始检索记忆的事件
 type AgentMemoryRetrievalStartedEvent struct {
 	events.BaseEvent
@@ -217,7 +227,7 @@ func NewAgentExecutionFailedEvent(agentID, agent, taskID, task string, execution
 func NewAgentToolUsageStartedEvent(agentID, agent, taskID, toolName string, args map[string]interface{}) *AgentToolUsageStartedEvent {
 	return &AgentToolUsageStartedEvent{
 		BaseEvent: events.BaseEvent{
-			Type:      "agent_tool_usage_started",
+			Type:      events.EventTypeToolUsageStarted,
 			Timestamp: time.Now(),
 			Source:    agent,
 			Payload: map[string]interface{}{
@@ -249,7 +259,7 @@ func NewAgentToolUsageCompletedEvent(agentID, agent, taskID, toolName string, du
 
 	event := &AgentToolUsageCompletedEvent{
 		BaseEvent: events.BaseEvent{
-			Type:      "agent_tool_usage_completed",
+			Type:      events.EventTypeToolUsageFinished,
 			Timestamp: time.Now(),
 			Source:    agent,
 			Payload:   payload,
@@ -275,6 +285,37 @@ func NewAgentToolUsageCompletedEvent(agentID, agent, taskID, toolName string, du
 	return event
 }
 
+// NewAgentToolUsageProgressEvent 创建Agent工具执行进度事件
+func NewAgentToolUsageProgressEvent(agentID, agent, taskID, toolName string, progress ToolProgress) *AgentToolUsageProgressEvent {
+	payload := map[string]interface{}{
+		"agent_id":  agentID,
+		"agent":     agent,
+		"task_id":   taskID,
+		"tool_name": toolName,
+		"percent":   progress.Percent,
+	}
+	if progress.Message != "" {
+		payload["message"] = progress.Message
+	}
+	if progress.Partial != nil {
+		payload["partial"] = progress.Partial
+	}
+
+	return &AgentToolUsageProgressEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      events.EventTypeToolUsageProgress,
+			Timestamp: time.Now(),
+			Source:    agent,
+			Payload:   payload,
+		},
+		AgentID:  agentID,
+		Agent:    agent,
+		TaskID:   taskID,
+		ToolName: toolName,
+		Progress: progress,
+	}
+}
+
 // NewAgentMemoryRetrievalStartedEvent 创建Agent记忆检索开始事件
 func NewAgentMemoryRetrievalStartedEvent(agentID, agent, taskID, query string) *AgentMemoryRetrievalStartedEvent {
 	return &AgentMemoryRetrievalStartedEvent{
@@ -564,3 +605,229 @@ func NewAgentStepExecutedEvent(agentID, agent, taskID, stepID, stepType, descrip
 
 	return event
 }
+
+// AgentExecutionRetryEvent 代表Agent因可重试错误重新调用LLM的事件
+type AgentExecutionRetryEvent struct {
+	events.BaseEvent
+	AgentID    string        `json:"agent_id"`
+	Agent      string        `json:"agent"`
+	TaskID     string        `json:"task_id"`
+	Attempt    int           `json:"attempt"`
+	MaxRetries int           `json:"max_retries"`
+	ErrorClass string        `json:"error_class"`
+	Backoff    time.Duration `json:"backoff"`
+	Error      string        `json:"error"`
+}
+
+// NewAgentExecutionRetryEvent 创建Agent执行重试事件
+func NewAgentExecutionRetryEvent(agentID, agent, taskID string, attempt, maxRetries int, errorClass RetryErrorClass, backoff time.Duration, err error) *AgentExecutionRetryEvent {
+	return &AgentExecutionRetryEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "agent_execution_retry",
+			Timestamp: time.Now(),
+			Source:    agent,
+			Payload: map[string]interface{}{
+				"agent_id":    agentID,
+				"agent":       agent,
+				"task_id":     taskID,
+				"attempt":     attempt,
+				"max_retries": maxRetries,
+				"error_class": string(errorClass),
+				"backoff_ms":  backoff.Milliseconds(),
+				"error":       err.Error(),
+			},
+		},
+		AgentID:    agentID,
+		Agent:      agent,
+		TaskID:     taskID,
+		Attempt:    attempt,
+		MaxRetries: maxRetries,
+		ErrorClass: string(errorClass),
+		Backoff:    backoff,
+		Error:      err.Error(),
+	}
+}
+
+// AgentGuardrailTriggeredEvent 代表任务输出未通过护栏验证的事件
+type AgentGuardrailTriggeredEvent struct {
+	events.BaseEvent
+	AgentID    string `json:"agent_id"`
+	Agent      string `json:"agent"`
+	TaskID     string `json:"task_id"`
+	Attempt    int    `json:"attempt"`
+	MaxRetries int    `json:"max_retries"`
+	Feedback   string `json:"feedback"`
+}
+
+// NewAgentGuardrailTriggeredEvent 创建护栏触发事件
+func NewAgentGuardrailTriggeredEvent(agentID, agent, taskID string, attempt, maxRetries int, feedback string) *AgentGuardrailTriggeredEvent {
+	return &AgentGuardrailTriggeredEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "guardrail_triggered",
+			Timestamp: time.Now(),
+			Source:    agent,
+			Payload: map[string]interface{}{
+				"agent_id":    agentID,
+				"agent":       agent,
+				"task_id":     taskID,
+				"attempt":     attempt,
+				"max_retries": maxRetries,
+				"feedback":    feedback,
+			},
+		},
+		AgentID:    agentID,
+		Agent:      agent,
+		TaskID:     taskID,
+		Attempt:    attempt,
+		MaxRetries: maxRetries,
+		Feedback:   feedback,
+	}
+}
+
+// AgentModerationFlaggedEvent 代表提示或答案被内容审核标记但放行的事件
+type AgentModerationFlaggedEvent struct {
+	events.BaseEvent
+	AgentID    string   `json:"agent_id"`
+	Agent      string   `json:"agent"`
+	TaskID     string   `json:"task_id"`
+	Stage      string   `json:"stage"`
+	Categories []string `json:"categories"`
+	Reason     string   `json:"reason"`
+}
+
+// NewAgentModerationFlaggedEvent 创建内容审核标记事件
+func NewAgentModerationFlaggedEvent(agentID, agent, taskID, stage string, categories []string, reason string) *AgentModerationFlaggedEvent {
+	return &AgentModerationFlaggedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "moderation_flagged",
+			Timestamp: time.Now(),
+			Source:    agent,
+			Payload: map[string]interface{}{
+				"agent_id":   agentID,
+				"agent":      agent,
+				"task_id":    taskID,
+				"stage":      stage,
+				"categories": categories,
+				"reason":     reason,
+			},
+		},
+		AgentID:    agentID,
+		Agent:      agent,
+		TaskID:     taskID,
+		Stage:      stage,
+		Categories: categories,
+		Reason:     reason,
+	}
+}
+
+// PromptInjectionDetectedEvent 代表工具结果、知识条目或记忆被判定为提示注入的事件
+type PromptInjectionDetectedEvent struct {
+	events.BaseEvent
+	AgentID string   `json:"agent_id"`
+	Agent   string   `json:"agent"`
+	TaskID  string   `json:"task_id"`
+	Source  string   `json:"source"`
+	Name    string   `json:"name"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+	Action  string   `json:"action"`
+}
+
+// NewPromptInjectionDetectedEvent 创建提示注入检测事件
+func NewPromptInjectionDetectedEvent(agentID, agent, taskID string, detection InjectionDetection) *PromptInjectionDetectedEvent {
+	return &PromptInjectionDetectedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "prompt_injection_detected",
+			Timestamp: time.Now(),
+			Source:    agent,
+			Payload: map[string]interface{}{
+				"agent_id": agentID,
+				"agent":    agent,
+				"task_id":  taskID,
+				"source":   detection.Source,
+				"name":     detection.Name,
+				"score":    detection.Score,
+				"reasons":  detection.Reasons,
+				"action":   string(detection.Action),
+			},
+		},
+		AgentID: agentID,
+		Agent:   agent,
+		TaskID:  taskID,
+		Source:  detection.Source,
+		Name:    detection.Name,
+		Score:   detection.Score,
+		Reasons: detection.Reasons,
+		Action:  string(detection.Action),
+	}
+}
+
+// TaskOutputWriteFailedEvent 代表任务输出写入输出文件失败的事件
+type TaskOutputWriteFailedEvent struct {
+	events.BaseEvent
+	AgentID    string `json:"agent_id"`
+	Agent      string `json:"agent"`
+	TaskID     string `json:"task_id"`
+	OutputFile string `json:"output_file"`
+	Error      string `json:"error"`
+}
+
+// NewTaskOutputWriteFailedEvent 创建任务输出文件写入失败事件
+func NewTaskOutputWriteFailedEvent(agentID, agent, taskID, outputFile string, err error) *TaskOutputWriteFailedEvent {
+	return &TaskOutputWriteFailedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "task_output_write_failed",
+			Timestamp: time.Now(),
+			Source:    agent,
+			Payload: map[string]interface{}{
+				"agent_id":    agentID,
+				"agent":       agent,
+				"task_id":     taskID,
+				"output_file": outputFile,
+				"error":       err.Error(),
+			},
+		},
+		AgentID:    agentID,
+		Agent:      agent,
+		TaskID:     taskID,
+		OutputFile: outputFile,
+		Error:      err.Error(),
+	}
+}
+
+// BudgetExceededEvent 代表执行达到工具使用限制或开销预算的事件
+// Limit为限制名称（见Limit*常量），Value为当前值，Threshold为限制值
+type BudgetExceededEvent struct {
+	events.BaseEvent
+	AgentID   string  `json:"agent_id"`
+	Agent     string  `json:"agent"`
+	TaskID    string  `json:"task_id"`
+	Limit     string  `json:"limit"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+// NewBudgetExceededEvent 创建预算超出事件
+func NewBudgetExceededEvent(agentID, agent, taskID, limit string, value, threshold float64) *BudgetExceededEvent {
+	return &BudgetExceededEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "budget_exceeded",
+			Timestamp: time.Now(),
+			Source:    agent,
+			Payload: map[string]interface{}{
+				"agent_id":  agentID,
+				"agent":     agent,
+				"task_id":   taskID,
+				"limit":     limit,
+				"value":     value,
+				"threshold": threshold,
+			},
+		},
+		AgentID:   agentID,
+		Agent:     agent,
+		TaskID:    taskID,
+		Limit:     limit,
+		Value:     value,
+		Threshold: threshold,
+	}
+}
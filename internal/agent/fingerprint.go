@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/security"
+)
+
+// 指纹记录在TaskOutput.Metadata中的键
+const (
+	MetadataKeyFingerprint     = "fingerprint"      // 执行任务的Agent的指纹
+	MetadataKeyCrewFingerprint = "crew_fingerprint" // 任务所属Crew的指纹
+)
+
+// CloneOption 配置Agent的克隆行为
+type CloneOption func(*cloneOptions)
+
+type cloneOptions struct {
+	preserveFingerprint bool
+	shareMemory         bool
+}
+
+// WithPreservedFingerprint 克隆时保留原Agent的指纹，默认为副本生成新指纹
+func WithPreservedFingerprint() CloneOption {
+	return func(o *cloneOptions) {
+		o.preserveFingerprint = true
+	}
+}
+
+// WithSharedMemory 克隆时与原Agent共享同一个记忆实例
+// 默认情况下，实现了NamespacedMemory的记忆会为副本派生出隔离的命名空间
+func WithSharedMemory() CloneOption {
+	return func(o *cloneOptions) {
+		o.shareMemory = true
+	}
+}
+
+// GetFingerprint 返回Agent的安全指纹
+func (a *BaseAgent) GetFingerprint() *security.Fingerprint {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.securityConfig.Fingerprint
+}
+
+// newSecurityConfig 返回Agent使用的安全配置，未提供指纹时按种子生成确定性指纹或随机指纹
+func newSecurityConfig(config security.SecurityConfig, seed string) security.SecurityConfig {
+	if config.Fingerprint != nil {
+		return config
+	}
+	if seed != "" {
+		return *security.NewSecurityConfigWithFingerprint(security.GenerateDeterministic(seed))
+	}
+	return *security.NewSecurityConfig()
+}
+
+// crewFingerprint 返回Crew注入任务上下文的Crew指纹
+func crewFingerprint(task Task) string {
+	if fingerprint, ok := task.GetContext()[contextKeyCrewFingerprint]; ok {
+		return fmt.Sprint(fingerprint)
+	}
+	return ""
+}
+
+// stampFingerprints 把Agent指纹和任务所属Crew的指纹加入事件
+func (a *BaseAgent) stampFingerprints(event *events.BaseEvent, task Task) {
+	event.SetSourceFingerprint("agent", a.GetFingerprint())
+	if fingerprint := crewFingerprint(task); fingerprint != "" {
+		if event.Payload == nil {
+			event.Payload = make(map[string]interface{})
+		}
+		event.Payload[contextKeyCrewFingerprint] = fingerprint
+	}
+}
+
+// stampOutputFingerprints 在任务输出的Metadata中记录产生该输出的Agent和Crew的指纹
+func (a *BaseAgent) stampOutputFingerprints(output *TaskOutput, task Task) {
+	if output == nil {
+		return
+	}
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+	if fingerprint := a.GetFingerprint(); fingerprint != nil {
+		output.Metadata[MetadataKeyFingerprint] = fingerprint.GetUUID()
+	}
+	if fingerprint := crewFingerprint(task); fingerprint != "" {
+		output.Metadata[MetadataKeyCrewFingerprint] = fingerprint
+	}
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/security"
+)
+
+func newFingerprintTestAgent(t *testing.T, seed string, eventBus events.EventBus) *BaseAgent {
+	t.Helper()
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:            "Researcher",
+		Goal:            "Find facts",
+		Backstory:       "Careful analyst",
+		LLM:             NewExtendedMockLLM([]llm.Response{{Content: "facts"}}),
+		EventBus:        eventBus,
+		Logger:          logger.NewTestLogger(),
+		FingerprintSeed: seed,
+	})
+	require.NoError(t, err)
+	return agent
+}
+
+func TestAgentDeterministicFingerprint(t *testing.T) {
+	first := newFingerprintTestAgent(t, "research-team/researcher", nil)
+	second := newFingerprintTestAgent(t, "research-team/researcher", nil)
+	assert.Equal(t, first.GetFingerprint().GetUUID(), second.GetFingerprint().GetUUID())
+	assert.NotEqual(t, first.GetFingerprint().GetUUID(), newFingerprintTestAgent(t, "", nil).GetFingerprint().GetUUID())
+
+	// 显式提供的指纹优先于种子
+	provided := security.NewFingerprint()
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:            "Researcher",
+		Goal:            "Find facts",
+		Backstory:       "Careful analyst",
+		SecurityConfig:  *security.NewSecurityConfigWithFingerprint(provided),
+		FingerprintSeed: "research-team/researcher",
+	})
+	require.NoError(t, err)
+	assert.Same(t, provided, agent.GetFingerprint())
+}
+
+func TestAgentFingerprintInEventsAndOutput(t *testing.T) {
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+	var mu sync.Mutex
+	var received []events.Event
+	for _, eventType := range []string{"agent_execution_started", "agent_execution_completed"} {
+		_, err := eventBus.SubscribeWithOptions(eventType, func(ctx context.Context, event events.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, event)
+			return nil
+		}, events.WithSyncDelivery())
+		require.NoError(t, err)
+	}
+
+	agent := newFingerprintTestAgent(t, "researcher", eventBus)
+	fingerprint := agent.GetFingerprint().GetUUID()
+	task := NewTaskWithOptions("Research Go", "Facts",
+		WithContext(map[string]interface{}{"crew_fingerprint": "crew-fp"}))
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, output.Metadata[MetadataKeyFingerprint])
+	assert.Equal(t, "crew-fp", output.Metadata[MetadataKeyCrewFingerprint])
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2)
+	for _, event := range received {
+		assert.Equal(t, fingerprint, event.GetSourceFingerprint())
+		assert.Equal(t, "agent", event.GetSourceType())
+		assert.Equal(t, "researcher", event.GetFingerprintMetadata()["seed"])
+		assert.Equal(t, fingerprint, event.GetPayload()["agent_fingerprint"])
+		assert.Equal(t, "crew-fp", event.GetPayload()["crew_fingerprint"])
+	}
+}
+
+func TestAgentCloneFingerprint(t *testing.T) {
+	agent := newFingerprintTestAgent(t, "researcher", nil)
+
+	clone := agent.Clone()
+	assert.NotEqual(t, agent.GetFingerprint().GetUUID(), clone.GetFingerprint().GetUUID())
+
+	preserved := agent.CloneWithOptions(WithPreservedFingerprint())
+	assert.Equal(t, agent.GetFingerprint().GetUUID(), preserved.GetFingerprint().GetUUID())
+}
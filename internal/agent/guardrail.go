@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+// 确保Guardrail函数实现了TaskGuardrail接口
+var _ TaskGuardrail = Guardrail(nil)
+
+// Guardrail 函数形式的任务护栏，对标Python版本Task的guardrail函数
+// 返回输出是否通过验证，未通过时第二个返回值为反馈给Agent的原因
+type Guardrail func(ctx context.Context, output *TaskOutput) (bool, string)
+
+// Validate 实现TaskGuardrail接口
+func (g Guardrail) Validate(ctx context.Context, output *TaskOutput) (*GuardrailResult, error) {
+	valid, feedback := g(ctx, output)
+	return &GuardrailResult{
+		Success:  valid,
+		Valid:    valid,
+		Feedback: feedback,
+		Metadata: make(map[string]interface{}),
+	}, nil
+}
+
+// GetDescription 获取护栏描述
+func (g Guardrail) GetDescription() string {
+	return "function guardrail"
+}
+
+// GetType 获取护栏类型
+func (g Guardrail) GetType() string {
+	return "Custom"
+}
+
+// GuardrailError 任务输出在用尽重试次数后仍未通过护栏验证
+type GuardrailError struct {
+	TaskID   string   // 任务ID
+	Attempts int      // 执行次数（含首次执行）
+	Feedback []string // 每轮验证失败的反馈
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("task %s output rejected by guardrail after %d attempts: %s",
+		e.TaskID, e.Attempts, strings.Join(e.Feedback, "; "))
+}
+
+// enforceGuardrail 使用护栏验证任务输出
+// 验证失败时将反馈追加到对话中并重新执行，最多重试MaxGuardrailRetries次，之后返回GuardrailError。
+// 返回的输出在Metadata["guardrail_iterations"]中记录通过验证所用的执行次数，token和成本包含所有被拒绝的执行
+func (a *BaseAgent) enforceGuardrail(ctx context.Context, task Task, guardrail TaskGuardrail, toolCtx *ToolExecutionContext, messages []llm.Message, callOptions *llm.CallOptions, output *TaskOutput) (*TaskOutput, error) {
+	maxRetries := a.executionConfig.MaxGuardrailRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	// 复制消息，避免修改调用方的切片
+	messages = append([]llm.Message(nil), messages...)
+
+	var feedback []string
+	var rejected llm.Usage
+	for attempt := 1; ; attempt++ {
+		result, err := guardrail.Validate(ctx, output)
+		if err != nil {
+			return nil, fmt.Errorf("guardrail validation failed for task %s: %w", task.GetID(), err)
+		}
+		if result.Valid {
+			tracing.AddEvent(ctx, tracing.EventGuardrailAttempt,
+				tracing.Int("guardrail.attempt", attempt),
+				tracing.Bool("guardrail.passed", true),
+			)
+			addUsageToOutput(output, rejected)
+			output.Metadata["guardrail_iterations"] = attempt
+			return output, nil
+		}
+
+		reason := result.Feedback
+		if reason == "" {
+			reason = result.Error
+		}
+		if reason == "" {
+			reason = "the output did not pass validation"
+		}
+		feedback = append(feedback, reason)
+		tracing.AddEvent(ctx, tracing.EventGuardrailAttempt,
+			tracing.Int("guardrail.attempt", attempt),
+			tracing.Bool("guardrail.passed", false),
+			tracing.String("guardrail.feedback", reason),
+		)
+
+		a.logger.Warn("Task output rejected by guardrail",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "attempt", Value: attempt},
+			logger.Field{Key: "max_retries", Value: maxRetries},
+			logger.Field{Key: "feedback", Value: reason},
+		)
+		if a.eventBus != nil {
+			event := NewAgentGuardrailTriggeredEvent(a.id, a.role, task.GetID(), attempt, maxRetries, reason)
+			if err := a.eventBus.Emit(ctx, a, event); err != nil {
+				a.logger.Warn("Failed to emit guardrail triggered event", logger.Field{Key: "error", Value: err})
+			}
+		}
+
+		if attempt > maxRetries {
+			return nil, &GuardrailError{TaskID: task.GetID(), Attempts: attempt, Feedback: feedback}
+		}
+
+		rejected.TotalTokens += output.TokensUsed
+		rejected.PromptTokens += output.PromptTokens
+		rejected.CompletionTokens += output.CompletionTokens
+		rejected.Cost += output.Cost
+
+		messages = append(messages,
+			llm.Message{Role: llm.RoleAssistant, Content: output.Raw},
+			llm.Message{Role: llm.RoleUser, Content: buildGuardrailFeedbackPrompt(a.prompts, reason)},
+		)
+		if output, err = a.generateOutput(ctx, task, toolCtx, messages, callOptions); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// addUsageToOutput 将额外的token用量和成本累加到任务输出
+func addUsageToOutput(output *TaskOutput, usage llm.Usage) {
+	output.TokensUsed += usage.TotalTokens
+	output.PromptTokens += usage.PromptTokens
+	output.CompletionTokens += usage.CompletionTokens
+	output.Cost += usage.Cost
+	if promptTokens, ok := output.Metadata["prompt_tokens"].(int); ok {
+		output.Metadata["prompt_tokens"] = promptTokens + usage.PromptTokens
+	}
+	if completionTokens, ok := output.Metadata["completion_tokens"].(int); ok {
+		output.Metadata["completion_tokens"] = completionTokens + usage.CompletionTokens
+	}
+}
+
+// buildGuardrailFeedbackPrompt 构建要求Agent根据护栏反馈重新回答的提示
+func buildGuardrailFeedbackPrompt(prompts PromptStrings, reason string) string {
+	return fmt.Sprintf(prompts.GuardrailFeedback, reason)
+}
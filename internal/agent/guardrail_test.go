@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// wordLimitGuardrail 拒绝超过limit个单词的输出
+func wordLimitGuardrail(limit int) Guardrail {
+	return func(ctx context.Context, output *TaskOutput) (bool, string) {
+		if words := len(strings.Fields(output.Raw)); words > limit {
+			return false, fmt.Sprintf("the answer must be at most %d words", limit)
+		}
+		return true, ""
+	}
+}
+
+func newGuardrailTestAgent(t *testing.T, mockLLM llm.LLM, eventBus events.EventBus, maxRetries int) *BaseAgent {
+	t.Helper()
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write concise answers",
+		Backstory: "Brief",
+		LLM:       mockLLM,
+		EventBus:  eventBus,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	config := DefaultExecutionConfig()
+	config.MaxGuardrailRetries = maxRetries
+	require.NoError(t, agent.SetExecutionConfig(config))
+	return agent
+}
+
+// TestAgentGuardrailRetriesWithFeedback 测试护栏拒绝后带着反馈重新执行
+func TestAgentGuardrailRetriesWithFeedback(t *testing.T) {
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+	var mu sync.Mutex
+	var attempts []int
+	require.NoError(t, eventBus.Subscribe("guardrail_triggered", func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, event.(*AgentGuardrailTriggeredEvent).Attempt)
+		return nil
+	}))
+
+	var prompts []string
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: "Go is a statically typed compiled language", Usage: llm.Usage{PromptTokens: 8, CompletionTokens: 4, TotalTokens: 12}},
+		{Content: "Typed compiled language", Usage: llm.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}},
+	}).WithCallHandler(func(messages []llm.Message) {
+		prompts = append(prompts, messages[len(messages)-1].Content.(string))
+	})
+	agent := newGuardrailTestAgent(t, mockLLM, eventBus, 3)
+
+	task := NewTaskWithOptions("Describe Go", "A short description", WithGuardrail(wordLimitGuardrail(3)))
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Typed compiled language", output.Raw)
+	assert.Equal(t, 2, output.Metadata["guardrail_iterations"])
+	assert.Equal(t, 24, output.TokensUsed)
+	assert.Equal(t, 18, output.PromptTokens)
+
+	require.Len(t, prompts, 2)
+	assert.Equal(t, "Your previous answer was rejected because: the answer must be at most 3 words\n"+
+		"Please address this feedback and provide your complete final answer again.", prompts[1])
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(attempts) == 1
+	}, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []int{1}, attempts)
+	mu.Unlock()
+}
+
+// TestAgentGuardrailExhausted 测试重试次数耗尽后返回包含所有反馈的GuardrailError
+func TestAgentGuardrailExhausted(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "far too many words in this answer"}})
+	agent := newGuardrailTestAgent(t, mockLLM, nil, 2)
+
+	task := NewBaseTask("Describe Go", "A short description")
+	task.SetGuardrail(wordLimitGuardrail(3))
+
+	output, err := agent.Execute(context.Background(), task)
+	require.Error(t, err)
+	assert.Nil(t, output)
+	assert.Equal(t, 3, mockLLM.callCount)
+
+	var guardrailErr *GuardrailError
+	require.True(t, errors.As(err, &guardrailErr))
+	assert.Equal(t, task.GetID(), guardrailErr.TaskID)
+	assert.Equal(t, 3, guardrailErr.Attempts)
+	assert.Len(t, guardrailErr.Feedback, 3)
+}
+
+// TestAgentGuardrailPassesFirstTime 测试首次通过时只执行一次
+func TestAgentGuardrailPassesFirstTime(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "Fast"}})
+	agent := newGuardrailTestAgent(t, mockLLM, nil, 3)
+
+	task := NewTaskWithOptions("Describe Go", "A short description", WithGuardrail(wordLimitGuardrail(3)))
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, mockLLM.callCount)
+	assert.Equal(t, 1, output.Metadata["guardrail_iterations"])
+	assert.NotNil(t, task.Clone().GetGuardrail(), "clone should keep the guardrail")
+}
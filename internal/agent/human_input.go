@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,20 +31,21 @@ func NewConsoleInputHandler(log logger.Logger) *ConsoleInputHandler {
 }
 
 // RequestInput 请求用户输入
-func (c *ConsoleInputHandler) RequestInput(ctx context.Context, prompt string, options []string) (string, error) {
+// 有选项时可以输入选项序号或名称，需要附带文本时使用"选项: 文本"形式
+func (c *ConsoleInputHandler) RequestInput(ctx context.Context, request HumanInputRequest) (*HumanInputResponse, error) {
 	// 创建超时上下文
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
 	// 显示提示信息
-	fmt.Printf("\n🤖 %s\n", prompt)
+	fmt.Printf("\n🤖 %s\n", request.Prompt)
 
-	if len(options) > 0 {
+	if len(request.Options) > 0 {
 		fmt.Println("\n选项:")
-		for i, option := range options {
+		for i, option := range request.Options {
 			fmt.Printf("  %d) %s\n", i+1, option)
 		}
-		fmt.Println()
+		fmt.Println("\n需要附带说明时请输入\"选项: 内容\"")
 	}
 
 	fmt.Print("请输入: ")
@@ -74,20 +76,20 @@ func (c *ConsoleInputHandler) RequestInput(ctx context.Context, prompt string, o
 		c.logger.Info("Human input received",
 			logger.Field{Key: "input_length", Value: len(input)},
 		)
-		return input, nil
+		return ParseHumanInputResponse(input, request.Options), nil
 
 	case err := <-errorChan:
 		c.logger.Error("Human input error",
 			logger.Field{Key: "error", Value: err},
 		)
-		return "", err
+		return nil, err
 
 	case <-ctx.Done():
 		fmt.Println("\n⏰ 输入超时")
 		c.logger.Warn("Human input timeout",
 			logger.Field{Key: "timeout", Value: c.timeout},
 		)
-		return "", fmt.Errorf("input timeout after %v: %w", c.timeout, ctx.Err())
+		return nil, fmt.Errorf("input timeout after %v: %w", c.timeout, ctx.Err())
 	}
 }
 
@@ -128,15 +130,15 @@ func NewMockInputHandler(responses []string, log logger.Logger) *MockInputHandle
 	}
 }
 
-// RequestInput 返回预设的响应
-func (m *MockInputHandler) RequestInput(ctx context.Context, prompt string, options []string) (string, error) {
+// RequestInput 返回预设的响应，响应文本按ParseHumanInputResponse解析
+func (m *MockInputHandler) RequestInput(ctx context.Context, request HumanInputRequest) (*HumanInputResponse, error) {
 	m.logger.Info("Mock input requested",
-		logger.Field{Key: "prompt", Value: prompt},
-		logger.Field{Key: "options_count", Value: len(options)},
+		logger.Field{Key: "prompt", Value: request.Prompt},
+		logger.Field{Key: "options_count", Value: len(request.Options)},
 	)
 
 	if m.index >= len(m.responses) {
-		return "", fmt.Errorf("no more mock responses available")
+		return nil, fmt.Errorf("no more mock responses available")
 	}
 
 	response := m.responses[m.index]
@@ -146,7 +148,7 @@ func (m *MockInputHandler) RequestInput(ctx context.Context, prompt string, opti
 		logger.Field{Key: "response", Value: response},
 	)
 
-	return response, nil
+	return ParseHumanInputResponse(response, request.Options), nil
 }
 
 // IsInteractive 返回是否为交互式
@@ -209,19 +211,19 @@ func NewPrefilledInputHandler(inputMap map[string]string, log logger.Logger) *Pr
 	}
 }
 
-// RequestInput 根据提示返回预填充的输入
-func (p *PrefilledInputHandler) RequestInput(ctx context.Context, prompt string, options []string) (string, error) {
-	response, exists := p.inputMap[prompt]
+// RequestInput 根据提示返回预填充的输入，响应文本按ParseHumanInputResponse解析
+func (p *PrefilledInputHandler) RequestInput(ctx context.Context, request HumanInputRequest) (*HumanInputResponse, error) {
+	response, exists := p.inputMap[request.Prompt]
 	if !exists {
-		return "", fmt.Errorf("no prefilled input found for prompt: %s", prompt)
+		return nil, fmt.Errorf("no prefilled input found for prompt: %s", request.Prompt)
 	}
 
 	p.logger.Info("Prefilled input provided",
-		logger.Field{Key: "prompt", Value: prompt},
+		logger.Field{Key: "prompt", Value: request.Prompt},
 		logger.Field{Key: "response", Value: response},
 	)
 
-	return response, nil
+	return ParseHumanInputResponse(response, request.Options), nil
 }
 
 // IsInteractive 返回是否为交互式
@@ -279,11 +281,11 @@ func NewNoInputHandler(log logger.Logger) *NoInputHandler {
 }
 
 // RequestInput 总是返回错误，因为不支持输入
-func (n *NoInputHandler) RequestInput(ctx context.Context, prompt string, options []string) (string, error) {
+func (n *NoInputHandler) RequestInput(ctx context.Context, request HumanInputRequest) (*HumanInputResponse, error) {
 	n.logger.Warn("Input requested but not supported",
-		logger.Field{Key: "prompt", Value: prompt},
+		logger.Field{Key: "prompt", Value: request.Prompt},
 	)
-	return "", fmt.Errorf("human input is not supported by this handler")
+	return nil, fmt.Errorf("human input is not supported by this handler")
 }
 
 // IsInteractive 返回是否为交互式
@@ -301,12 +303,37 @@ func (n *NoInputHandler) GetTimeout() time.Duration {
 	return n.timeout
 }
 
+// ParseHumanInputResponse 将文本输入解析为结构化响应
+// 请求有选项时，输入可以是选项序号、选项名称或"选项: 文本"形式（不区分大小写）；
+// 无法匹配任何选项的输入整体作为Text
+func ParseHumanInputResponse(input string, options []string) *HumanInputResponse {
+	input = strings.TrimSpace(input)
+	response := &HumanInputResponse{Text: input}
+	if len(options) == 0 {
+		return response
+	}
+
+	choice, text := input, ""
+	if i := strings.Index(input, ":"); i >= 0 {
+		choice, text = strings.TrimSpace(input[:i]), strings.TrimSpace(input[i+1:])
+	}
+	for i, option := range options {
+		if strings.EqualFold(choice, option) || choice == strconv.Itoa(i+1) {
+			response.Option = option
+			response.Text = text
+			break
+		}
+	}
+	return response
+}
+
 // 确保所有处理器都实现了HumanInputHandler接口
 var (
 	_ HumanInputHandler = (*ConsoleInputHandler)(nil)
 	_ HumanInputHandler = (*MockInputHandler)(nil)
 	_ HumanInputHandler = (*PrefilledInputHandler)(nil)
 	_ HumanInputHandler = (*NoInputHandler)(nil)
+	_ HumanInputHandler = (*WebhookInputHandler)(nil)
 )
 
 // InputHandlerFactory 输入处理器工厂
@@ -358,6 +385,12 @@ func (f *InputHandlerFactory) CreateHandler(handlerType string, config interface
 	case "none":
 		return f.CreateNoInputHandler(log), nil
 
+	case "webhook":
+		if url, ok := config.(string); ok {
+			return NewWebhookInputHandler(url, log), nil
+		}
+		return nil, fmt.Errorf("invalid config for webhook handler: expected URL string")
+
 	default:
 		return nil, fmt.Errorf("unknown handler type: %s", handlerType)
 	}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// InjectedContextMetadataKey 注入的日期和语言环境在TaskOutput.Metadata中的键
+const InjectedContextMetadataKey = "injected_context"
+
+// DefaultInjectedDateFormat 未指定DateFormat时注入日期使用的格式
+const DefaultInjectedDateFormat = "Monday, 2006-01-02 15:04 MST"
+
+// Clock 提供注入的当前时间，测试中可以替换为固定的时钟
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock 返回系统当前时间的时钟
+type SystemClock struct{}
+
+// Now 返回系统当前时间
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// FixedClock 总是返回同一时间的时钟
+type FixedClock time.Time
+
+// Now 返回固定的时间
+func (c FixedClock) Now() time.Time { return time.Time(c) }
+
+// ContextInjection 注入到系统提示开头的当前日期和语言环境提示，避免模型按训练数据的年份作答
+// Crew设置时所有Agent使用Kickoff开始时解析的同一组值，优先于Agent自身的设置
+type ContextInjection struct {
+	InjectDate bool   `json:"inject_date"`
+	DateFormat string `json:"date_format"` // Go时间格式，为空时使用DefaultInjectedDateFormat
+	Timezone   string `json:"timezone"`    // IANA时区名，如Asia/Shanghai，为空时使用本地时区
+	Language   string `json:"language"`    // 输出语言提示，如"简体中文"、"English"
+	Units      string `json:"units"`       // 单位制提示，如"metric"、"imperial"
+	Clock      Clock  `json:"-"`           // 为nil时使用系统时钟
+}
+
+// IsZero 判断是否没有需要注入的内容
+func (c ContextInjection) IsZero() bool {
+	return !c.InjectDate && c.Language == "" && c.Units == ""
+}
+
+// Resolve 读取时钟并格式化注入的值；时区无效时返回错误，同时按时钟自身的时区格式化日期
+func (c ContextInjection) Resolve() (*InjectedContext, error) {
+	injected := &InjectedContext{Language: c.Language, Units: c.Units}
+	if !c.InjectDate {
+		return injected, nil
+	}
+
+	clock := c.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	now := clock.Now()
+
+	var err error
+	if c.Timezone != "" {
+		location, loadErr := time.LoadLocation(c.Timezone)
+		if loadErr != nil {
+			err = fmt.Errorf("invalid timezone %q: %w", c.Timezone, loadErr)
+		} else {
+			now = now.In(location)
+		}
+	}
+
+	format := c.DateFormat
+	if format == "" {
+		format = DefaultInjectedDateFormat
+	}
+	injected.Date = now.Format(format)
+	injected.Timezone = now.Location().String()
+	return injected, err
+}
+
+// InjectedContext 一次执行实际注入的值
+type InjectedContext struct {
+	Date     string `json:"date,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	Language string `json:"language,omitempty"`
+	Units    string `json:"units,omitempty"`
+}
+
+type injectedContextKey struct{}
+
+// WithInjectedContext 返回带有注入值的ctx，之后的Agent执行都使用这组值而不是自身的设置
+func WithInjectedContext(ctx context.Context, injected *InjectedContext) context.Context {
+	return context.WithValue(ctx, injectedContextKey{}, injected)
+}
+
+// InjectedContextFrom 返回ctx中的注入值，没有时返回nil
+func InjectedContextFrom(ctx context.Context) *InjectedContext {
+	injected, _ := ctx.Value(injectedContextKey{}).(*InjectedContext)
+	return injected
+}
+
+// render 按当前语言生成注入的文本，每项一行
+func (c *InjectedContext) render(prompts PromptStrings) string {
+	var lines []string
+	if c.Date != "" {
+		lines = append(lines, fmt.Sprintf(prompts.CurrentDate, c.Date))
+	}
+	if c.Language != "" {
+		lines = append(lines, fmt.Sprintf(prompts.OutputLanguage, c.Language))
+	}
+	if c.Units != "" {
+		lines = append(lines, fmt.Sprintf(prompts.MeasurementUnits, c.Units))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inject 把注入的文本放在系统消息开头，没有系统消息时插入一条
+// 消息只在执行开始时构建一次，护栏和工具循环的重新提示沿用同一组消息，因此注入的文本只出现一次
+func (c *InjectedContext) inject(messages []llm.Message, prompts PromptStrings) []llm.Message {
+	text := c.render(prompts)
+	if text == "" {
+		return messages
+	}
+	if len(messages) > 0 && messages[0].Role == llm.RoleSystem {
+		if content, ok := messages[0].Content.(string); ok {
+			messages[0].Content = text + "\n\n" + content
+			return messages
+		}
+	}
+	return append([]llm.Message{{Role: llm.RoleSystem, Content: text}}, messages...)
+}
+
+// apply 把注入的值记录到输出的Metadata中
+func (c *InjectedContext) apply(output *TaskOutput) {
+	if c == nil || output == nil {
+		return
+	}
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+	metadata := map[string]string{}
+	for key, value := range map[string]string{
+		"date": c.Date, "timezone": c.Timezone, "language": c.Language, "units": c.Units,
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+	output.Metadata[InjectedContextMetadataKey] = metadata
+}
+
+// withInjectedContext 确定本次执行注入的值：ctx中已有（Crew设置）时沿用，否则按ExecutionConfig.ContextInjection解析
+func (a *BaseAgent) withInjectedContext(ctx context.Context) (context.Context, *InjectedContext) {
+	if injected := InjectedContextFrom(ctx); injected != nil {
+		return ctx, injected
+	}
+	injection := a.executionConfig.ContextInjection
+	if injection.IsZero() {
+		return ctx, nil
+	}
+	injected, err := injection.Resolve()
+	if err != nil {
+		a.logger.Warn("Failed to resolve injected context, using clock timezone",
+			logger.Field{Key: "agent", Value: a.role},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+	return WithInjectedContext(ctx, injected), injected
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+var frozenNow = time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+
+func TestContextInjectionResolve(t *testing.T) {
+	injection := ContextInjection{
+		InjectDate: true,
+		DateFormat: "2006-01-02 15:04 MST",
+		Timezone:   "Asia/Shanghai",
+		Language:   "简体中文",
+		Units:      "metric",
+		Clock:      FixedClock(frozenNow),
+	}
+	injected, err := injection.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "2025-03-14 17:30 CST", injected.Date)
+	assert.Equal(t, "Asia/Shanghai", injected.Timezone)
+	assert.Equal(t, "Current date and time: 2025-03-14 17:30 CST\nWrite your answers in 简体中文.\nUse metric units.",
+		injected.render(defaultPromptStrings()))
+
+	// 无效时区返回错误，仍按时钟的时区注入日期
+	injection.Timezone = "Mars/Olympus"
+	injected, err = injection.Resolve()
+	assert.Error(t, err)
+	assert.Equal(t, "2025-03-14 09:30 UTC", injected.Date)
+
+	assert.True(t, ContextInjection{DateFormat: "2006"}.IsZero())
+}
+
+// TestInjectedContextOncePerExecution 测试注入的日期只出现在系统提示开头一次，护栏重新提示时也不重复，并记录在Metadata中
+func TestInjectedContextOncePerExecution(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: "The 2023 report is far too long to accept"},
+		{Content: "Report 2025"},
+	}).WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, append([]llm.Message(nil), messages...))
+	})
+	agent := newGuardrailTestAgent(t, mockLLM, nil, 2)
+
+	config := agent.GetExecutionConfig()
+	config.ContextInjection = ContextInjection{InjectDate: true, Timezone: "UTC", Clock: FixedClock(frozenNow)}
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	task := NewTaskWithOptions("Write the yearly report", "A title", WithGuardrail(wordLimitGuardrail(3)))
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, "Report 2025", output.Raw)
+
+	require.Len(t, calls, 2)
+	for _, messages := range calls {
+		system := fmt.Sprint(messages[0].Content)
+		assert.Equal(t, llm.RoleSystem, messages[0].Role)
+		assert.True(t, strings.HasPrefix(system, "Current date and time: Friday, 2025-03-14 09:30 UTC\n\nYou are"), system)
+
+		occurrences := 0
+		for _, message := range messages {
+			occurrences += strings.Count(fmt.Sprint(message.Content), "Current date and time")
+		}
+		assert.Equal(t, 1, occurrences)
+	}
+	assert.Equal(t, map[string]string{"date": "Friday, 2025-03-14 09:30 UTC", "timezone": "UTC"},
+		output.Metadata[InjectedContextMetadataKey])
+}
+
+// TestInjectedContextFromContext 测试ctx中的注入值优先于Agent自身的设置，没有系统提示时插入一条系统消息
+func TestInjectedContextFromContext(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "Done"}}).WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, messages)
+	})
+	agent := newToolLoopTestAgent(t, mockLLM, nil)
+	config := agent.GetExecutionConfig()
+	config.UseSystemPrompt = false
+	config.ContextInjection = ContextInjection{Language: "English"}
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	ctx := WithInjectedContext(context.Background(), &InjectedContext{Language: "Deutsch", Units: "metric"})
+	output, err := agent.Execute(ctx, NewBaseTask("Describe the weather", "A forecast"))
+	require.NoError(t, err)
+
+	require.Len(t, calls, 1)
+	require.Len(t, calls[0], 2)
+	assert.Equal(t, llm.Message{Role: llm.RoleSystem, Content: "Write your answers in Deutsch.\nUse metric units."}, calls[0][0])
+	assert.Equal(t, map[string]string{"language": "Deutsch", "units": "metric"}, output.Metadata[InjectedContextMetadataKey])
+
+	// 没有设置注入时不记录
+	config.ContextInjection = ContextInjection{}
+	require.NoError(t, agent.SetExecutionConfig(config))
+	output, err = agent.Execute(context.Background(), NewBaseTask("Describe the weather", "A forecast"))
+	require.NoError(t, err)
+	assert.NotContains(t, output.Metadata, InjectedContextMetadataKey)
+}
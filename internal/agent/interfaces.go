@@ -16,15 +16,18 @@ type Agent interface {
 	Execute(ctx context.Context, task Task) (*TaskOutput, error)
 	ExecuteAsync(ctx context.Context, task Task) (<-chan TaskResult, error)
 	ExecuteWithTimeout(ctx context.Context, task Task, timeout time.Duration) (*TaskOutput, error)
+	ExecuteStream(ctx context.Context, task Task) (<-chan AgentStreamChunk, error)
 
 	// 基础属性获取
 	GetID() string
 	GetRole() string
 	GetGoal() string
 	GetBackstory() string
+	GetFingerprint() *security.Fingerprint // Agent的安全指纹，出现在执行事件和任务输出中
 
 	// 配置和工具管理
 	AddTool(tool Tool) error
+	RemoveTool(name string) error // 按名称移除工具，如Crew执行结束后移除注入的委托工具
 	GetTools() []Tool
 	SetLLM(llm llm.LLM) error
 	GetLLM() llm.LLM
@@ -40,6 +43,14 @@ type Agent interface {
 	GetExecutionConfig() ExecutionConfig
 	SetHumanInputHandler(handler HumanInputHandler) error
 	GetHumanInputHandler() HumanInputHandler
+	SetRPMController(controller *RPMController) // 设置共享的速率控制器，nil表示不限制
+	GetRPMController() *RPMController
+	SetResponseCache(cache llm.ResponseCache) // 设置LLM响应缓存，nil表示不缓存
+	GetResponseCache() llm.ResponseCache
+	SetToolCache(cache ToolCache) // 设置工具结果缓存，nil表示不缓存工具结果
+	GetToolCache() ToolCache
+	SetTrainedInstructions(instructions []string) // 设置训练得到的改进指令，加入系统提示
+	GetTrainedInstructions() []string
 
 	// 事件和监控
 	SetEventBus(eventBus events.EventBus) error
@@ -54,6 +65,7 @@ type Agent interface {
 
 	// 统计和监控
 	GetExecutionStats() ExecutionStats
+	GetUsageMetrics() *UsageMetrics
 	ResetStats() error
 }
 
@@ -68,6 +80,8 @@ type Task interface {
 	SetHumanInput(input string)
 	GetHumanInput() string
 	GetOutputFormat() OutputFormat
+	GetOutputSchema() *OutputSchema // 结构化输出模式，对标Python版本的output_json/output_pydantic
+	SetOutputSchema(schema *OutputSchema)
 	GetTools() []Tool
 	AddTool(tool Tool) error
 	SetTools(tools []Tool) error
@@ -77,6 +91,8 @@ type Task interface {
 	// 支持任务预分配Agent，对标Python版本的task.agent
 	GetAssignedAgent() Agent
 	SetAssignedAgent(agent Agent) error
+	GetAgentRole() string // 按角色或ID指定的Agent，没有预分配Agent时由Crew在成员中查找
+	SetAgentRole(role string)
 
 	// 支持异步执行，对标Python版本的task.async_execution
 	IsAsyncExecution() bool
@@ -88,14 +104,20 @@ type Task interface {
 	// 新增Python版本对标功能
 	GetName() string
 	SetName(name string)
-	GetOutputFile() string
+	GetOutputFile() string // 执行成功后写入输出的文件，支持{date}、{task_name}、{crew_name}模板变量
 	SetOutputFile(filename string) error
+	IsOutputFileAppend() bool // 为true时追加写入输出文件，否则覆盖
+	SetOutputFileAppend(appendOutput bool)
 	GetCreateDirectory() bool
 	SetCreateDirectory(create bool)
 	GetCallback() func(context.Context, *TaskOutput) error
 	SetCallback(callback func(context.Context, *TaskOutput) error)
 	GetContextTasks() []Task // 对标Python的context: List[Task]
 	SetContextTasks(tasks []Task)
+	GetDependsOn() []string // 依赖的任务ID，Crew据此进行拓扑调度
+	SetDependsOn(taskIDs []string)
+	IsCacheDisabled() bool // 为true时跳过LLM响应缓存和工具结果缓存，适用于时效性要求高的任务
+	SetCacheDisabled(disabled bool)
 	GetRetryCount() int
 	GetMaxRetries() int
 	SetMaxRetries(maxRetries int)
@@ -104,6 +126,12 @@ type Task interface {
 	GetGuardrail() TaskGuardrail
 	IsMarkdownOutput() bool
 	SetMarkdownOutput(markdown bool)
+	IsApprovalRequired() bool // 为true时输出需经HumanInputHandler审批
+	SetApprovalRequired(required bool)
+
+	// 图片附件，Agent构建消息时与任务提示一起作为多部分内容发送
+	AddImage(source string) error // source为http(s)或data URL，或本地图片文件路径
+	GetImages() []llm.ContentPart
 }
 
 // Tool 代表工具的接口
@@ -117,6 +145,7 @@ type Tool interface {
 	GetUsageLimit() int
 	ResetUsage()
 	IsUsageLimitExceeded() bool
+	Clone() Tool // 返回使用计数清零的副本，克隆Agent时使用，避免副本之间共享使用计数
 }
 
 // Memory 代表记忆系统的接口
@@ -128,6 +157,19 @@ type Memory interface {
 	GetStats() MemoryStats
 }
 
+// NamespacedMemory 可以派生出隔离命名空间的记忆
+// 克隆Agent时如果记忆实现了该接口，副本使用以副本ID为命名空间的记忆，副本之间的记忆互不可见
+type NamespacedMemory interface {
+	Memory
+	WithNamespace(namespace string) Memory
+}
+
+// MemorySuite 组合多种记忆来源并为任务构建上下文的接口（如contextual.ContextualMemory）
+// 设置后BaseAgent构建提示词时优先使用它，而不是单一的Memory
+type MemorySuite interface {
+	BuildContextForTask(ctx context.Context, task Task, context string) (string, error)
+}
+
 // KnowledgeSource 代表知识源的接口
 type KnowledgeSource interface {
 	GetName() string
@@ -136,11 +178,51 @@ type KnowledgeSource interface {
 	Initialize() error
 	Close() error
 	GetStats() KnowledgeStats
+	// Refresh 重新加载变化的内容，刷新期间查询继续使用原有索引，失败时保留原有索引
+	Refresh(ctx context.Context) (KnowledgeRefresh, error)
+}
+
+// ToolProvider 代表外部工具的来源，如MCP服务器
+// Agent初始化时调用DiscoverTools加入工具，关闭时调用Close断开连接
+type ToolProvider interface {
+	GetName() string
+	DiscoverTools(ctx context.Context) ([]Tool, error)
+	Close() error
+}
+
+// HumanInputRequestType 人工输入请求类型
+type HumanInputRequestType string
+
+const (
+	HumanInputRequestInput    HumanInputRequestType = "input"    // 任务执行前收集输入
+	HumanInputRequestApproval HumanInputRequestType = "approval" // 任务执行后审批输出
+)
+
+// 审批请求的选项
+const (
+	ApprovalOptionApprove = "approve"
+	ApprovalOptionReject  = "reject" // Text为拒绝的反馈
+	ApprovalOptionEdit    = "edit"   // Text为修改后的输出
+)
+
+// HumanInputRequest 发送给人工输入处理器的结构化请求
+type HumanInputRequest struct {
+	Type     HumanInputRequestType  `json:"type"`
+	Prompt   string                 `json:"prompt"`
+	Options  []string               `json:"options,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"` // 任务ID、Agent角色、待审批的输出等
+}
+
+// HumanInputResponse 人工输入处理器返回的结构化响应
+type HumanInputResponse struct {
+	Option   string                 `json:"option,omitempty"` // 选择的选项，请求没有选项时为空
+	Text     string                 `json:"text,omitempty"`   // 输入的文本、拒绝反馈或修改后的输出
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // HumanInputHandler 代表人工输入处理器的接口
 type HumanInputHandler interface {
-	RequestInput(ctx context.Context, prompt string, options []string) (string, error)
+	RequestInput(ctx context.Context, request HumanInputRequest) (*HumanInputResponse, error)
 	IsInteractive() bool
 	SetTimeout(timeout time.Duration)
 	GetTimeout() time.Duration
@@ -169,12 +251,56 @@ type ExecutionConfig struct {
 	Temperature      float64       `json:"temperature"`
 	CacheEnabled     bool          `json:"cache_enabled"`
 	MaxRetryLimit    int           `json:"max_retry_limit"`
+	MaxContextLength int           `json:"max_context_length"` // 注入提示的单个上下文值最大长度（字符），<=0表示不截断
+	RetryPolicy      RetryPolicy   `json:"retry_policy"`       // LLM调用的Agent级重试策略
+
+	MaxOutputFixAttempts int `json:"max_output_fix_attempts"` // 输出不符合任务OutputSchema时请求LLM修正的最大次数
+	MaxGuardrailRetries  int `json:"max_guardrail_retries"`   // 输出未通过任务护栏时带着反馈重新执行的最大次数
+
+	// 上下文窗口管理：开启时超出LLM上下文窗口的消息按ContextStrategy裁剪，
+	// 关闭时直接返回"needed X, have Y"错误
+	RespectContextWindow     bool             `json:"respect_context_window"`
+	ContextStrategy          llm.TrimStrategy `json:"context_strategy"`           // 为空时使用middle_out
+	ReservedCompletionTokens int              `json:"reserved_completion_tokens"` // 为回复预留的token数，<=0表示使用MaxTokens（最多为窗口的一半）
+	Tokenizer                llm.Tokenizer    `json:"-"`                          // 为空时使用LLM的GetTokenizer
+
+	// 需要审批的任务：审批人拒绝时带着反馈重新执行的最大次数、等待审批的超时时间和超时后的处理策略
+	MaxApprovalRevisions  int            `json:"max_approval_revisions"`
+	ApprovalTimeout       time.Duration  `json:"approval_timeout"` // <=0表示只受处理器自身超时限制
+	ApprovalTimeoutPolicy ApprovalPolicy `json:"approval_timeout_policy"`
 
 	// 新增Python版本对标功能
-	EnableReasoning    bool    `json:"enable_reasoning"` // 对标Python的reasoning
+	EnableReasoning    bool    `json:"enable_reasoning"` // 对标Python的reasoning，未设置推理处理器时使用DefaultReasoningHandler
 	Verbose            bool    `json:"verbose"`          // 对标Python的verbose
 	FunctionCallingLLM llm.LLM `json:"-"`                // 对标Python的function_calling_llm
 
+	// 推理时制定和改进计划的最大次数，<=0表示使用默认的3次
+	MaxReasoningAttempts int `json:"max_reasoning_attempts"`
+
+	// 知识查询改写：开启时检索知识源前先由LLM把任务描述和上下文改写为1-3个检索查询，
+	// RewriteLLM为空时使用Agent的LLM，改写失败时回退为任务描述
+	EnableKnowledgeQueryRewrite bool    `json:"enable_knowledge_query_rewrite"`
+	RewriteLLM                  llm.LLM `json:"-"`
+
+	// 工具使用限制，<=0表示不限制：ToolUsageLimits按工具名覆盖工具声明的MaxUsagePerTask，
+	// MaxToolCallsPerExecution限制单次执行的工具调用总数。达到限制时不再执行工具，
+	// Agent被要求根据已有信息给出最终答案，触发的限制记录在输出的Metadata["budget_limits_hit"]中
+	ToolUsageLimits          map[string]int `json:"tool_usage_limits,omitempty"`
+	MaxToolCallsPerExecution int            `json:"max_tool_calls_per_execution"`
+
+	// 注入到系统提示开头的当前日期和语言环境提示，注入的值记录在输出的Metadata["injected_context"]中
+	ContextInjection ContextInjection `json:"context_injection"`
+
+	// 可复现执行：Seed传给支持种子的LLM，优先于Crew按任务派生的种子，记录在输出的Metadata["seed"]中；
+	// 严格模式下温度固定为0，破坏确定性的组件记录在Metadata["reproducibility_warnings"]中
+	Seed                  *int `json:"seed,omitempty"`
+	StrictReproducibility bool `json:"strict_reproducibility"`
+
+	// 执行生命周期钩子：OnExecuteStart可以向ctx放入本次执行使用的资源，工具通过同一个ctx取出；
+	// OnExecuteStart成功后OnExecuteEnd总会执行，包括执行失败时
+	OnExecuteStart ExecuteStartHook `json:"-"`
+	OnExecuteEnd   ExecuteEndHook   `json:"-"`
+
 	// ReAct模式支持
 	Mode        AgentMode    `json:"mode"`         // Agent执行模式
 	ReActConfig *ReActConfig `json:"react_config"` // ReAct模式配置
@@ -182,24 +308,39 @@ type ExecutionConfig struct {
 
 // TaskOutput 代表任务执行的输出
 type TaskOutput struct {
-	Raw             string                 `json:"raw"`
-	JSON            map[string]interface{} `json:"json,omitempty"`
-	Pydantic        interface{}            `json:"pydantic,omitempty"`
-	Agent           string                 `json:"agent"`
-	Task            string                 `json:"task"`
-	Description     string                 `json:"description"`
-	Summary         string                 `json:"summary"`
-	ExpectedOutput  string                 `json:"expected_output"`
-	OutputFormat    OutputFormat           `json:"output_format"`
-	ExecutionTime   time.Duration          `json:"execution_time"`
-	CreatedAt       time.Time              `json:"created_at"`
-	TokensUsed      int                    `json:"tokens_used"`
-	Cost            float64                `json:"cost"`
-	Model           string                 `json:"model"`
-	IsValid         bool                   `json:"is_valid"`
-	ValidationError string                 `json:"validation_error,omitempty"`
-	ToolsUsed       []string               `json:"tools_used"`
-	Metadata        map[string]interface{} `json:"metadata"`
+	Raw              string                 `json:"raw"`
+	JSON             map[string]interface{} `json:"json,omitempty"`
+	Pydantic         interface{}            `json:"pydantic,omitempty"`
+	Parsed           interface{}            `json:"parsed,omitempty"` // 按任务OutputSchema解析后的值
+	Agent            string                 `json:"agent"`
+	Task             string                 `json:"task"`
+	Name             string                 `json:"name,omitempty"` // 任务名称
+	Description      string                 `json:"description"`
+	Summary          string                 `json:"summary"`
+	ExpectedOutput   string                 `json:"expected_output"`
+	OutputFormat     OutputFormat           `json:"output_format"`
+	ExecutionTime    time.Duration          `json:"execution_time"`
+	CreatedAt        time.Time              `json:"created_at"`
+	TokensUsed       int                    `json:"tokens_used"`
+	PromptTokens     int                    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int                    `json:"completion_tokens,omitempty"`
+	Cost             float64                `json:"cost"`
+	Model            string                 `json:"model"`
+	LLMStats         LLMCallStats           `json:"llm_stats"` // 本次执行的LLM调用、重试、缓存和限流统计
+	IsValid          bool                   `json:"is_valid"`
+	ValidationError  string                 `json:"validation_error,omitempty"`
+	ToolsUsed        []string               `json:"tools_used"`
+	Citations        []Citation             `json:"citations,omitempty"` // 由CitationProcessor填充的引用
+	Metadata         map[string]interface{} `json:"metadata"`
+}
+
+// Citation 输出中引用的知识条目或工具结果
+type Citation struct {
+	SourceID string `json:"source_id"`          // 知识条目的chunk ID或工具结果ID
+	Kind     string `json:"kind"`               // SourceKindKnowledge或SourceKindTool
+	Source   string `json:"source"`             // 来源名称（文件、知识源或工具名）
+	Location string `json:"location,omitempty"` // 页码、行号或URL
+	Span     string `json:"span"`               // 输出中与来源匹配的文本
 }
 
 // TaskResult 代表异步任务执行结果
@@ -208,6 +349,16 @@ type TaskResult struct {
 	Error  error
 }
 
+// AgentStreamChunk 代表流式执行中的一个输出块
+// 最后一个块的Done为true，并携带完整的TaskOutput（失败时携带Error）
+type AgentStreamChunk struct {
+	Delta   string      `json:"delta"`            // 本次增量文本
+	Content string      `json:"content"`          // 截至目前累积的内容
+	Done    bool        `json:"done"`             // 是否为最终块
+	Output  *TaskOutput `json:"output,omitempty"` // 最终块携带的任务输出
+	Error   error       `json:"-"`                // 执行错误
+}
+
 // ToolSchema 定义工具的模式
 type ToolSchema struct {
 	Name        string                 `json:"name"`
@@ -269,6 +420,9 @@ type ExecutionStats struct {
 	TokensUsed           int            `json:"tokens_used"`
 	TotalCost            float64        `json:"total_cost"`
 	ToolsUsed            map[string]int `json:"tools_used"`
+	RetriedExecutions    int            `json:"retried_executions"` // 因可重试错误重新调用LLM的次数
+	CacheHits            int            `json:"cache_hits"`         // LLM响应缓存命中次数
+	CacheMisses          int            `json:"cache_misses"`       // LLM响应缓存未命中次数
 	CreatedAt            time.Time      `json:"created_at"`
 }
 
@@ -290,6 +444,18 @@ type KnowledgeStats struct {
 	IndexSize    int64     `json:"index_size"`
 }
 
+// KnowledgeRefresh 一次知识源刷新中变化的内容块数
+type KnowledgeRefresh struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+}
+
+// Changed 返回刷新是否改变了索引内容
+func (r KnowledgeRefresh) Changed() bool {
+	return r.Added > 0 || r.Updated > 0 || r.Removed > 0
+}
+
 // AgentRole 定义预设的Agent角色
 type AgentRole string
 
@@ -314,34 +480,53 @@ type AgentConfig struct {
 	LLM               llm.LLM                                    `json:"-"`
 	Tools             []Tool                                     `json:"-"`
 	ExecutionConfig   ExecutionConfig                            `json:"execution_config"`
+	AllowDelegation   bool                                       `json:"allow_delegation"` // 为true时加入Crew后可以向同事提问或委托工作
 	Memory            Memory                                     `json:"-"`
+	MemorySuite       MemorySuite                                `json:"-"`
 	KnowledgeSources  []KnowledgeSource                          `json:"-"`
+	ToolProviders     []ToolProvider                             `json:"-"` // 外部工具来源（如MCP服务器），Initialize时发现工具
 	HumanInputHandler HumanInputHandler                          `json:"-"`
 	EventBus          events.EventBus                            `json:"-"`
 	Logger            logger.Logger                              `json:"-"`
 	SecurityConfig    security.SecurityConfig                    `json:"security_config"`
-	SystemTemplate    string                                     `json:"system_template"`
-	PromptTemplate    string                                     `json:"prompt_template"`
+	FingerprintSeed   string                                     `json:"fingerprint_seed"` // SecurityConfig未提供指纹时，按种子生成确定性指纹，重启后保持不变
+	SystemTemplate    string                                     `json:"system_template"`  // text/template系统提示模板，兼容{role}/{goal}/{backstory}占位符
+	PromptTemplate    string                                     `json:"prompt_template"`  // 包装用户提示的text/template模板，{{.Prompt}}为内置逻辑构建的提示
+	PromptLocale      PromptLocale                               `json:"prompt_locale"`    // 内置提示词的语言，默认为英文
+	PromptVars        map[string]interface{}                     `json:"prompt_vars"`      // 模板中以{{.Vars.name}}引用的自定义变量
 	Callbacks         []func(context.Context, *TaskOutput) error `json:"-"`
+	OutputProcessors  []OutputProcessor                          `json:"-"` // 在回调之前按顺序处理最终输出，如CitationProcessor、RedactionProcessor
+	Moderation        *ModerationConfig                          `json:"-"` // 审核发送给LLM的提示和LLM的答案，优先于Crew的设置
+	PromptInjection   *PromptInjectionConfig                     `json:"-"` // 分隔并检测工具结果、知识条目和记忆中的提示注入，优先于Crew的设置
 	StepCallback      func(context.Context, *AgentStep) error    `json:"-"` // 对标Python的step_callback
 }
 
 // DefaultExecutionConfig 返回默认的执行配置
 func DefaultExecutionConfig() ExecutionConfig {
 	return ExecutionConfig{
-		MaxIterations:    25,
-		MaxRPM:           60,
-		Timeout:          30 * time.Minute,
-		MaxExecutionTime: 10 * time.Minute,
-		AllowDelegation:  false,
-		VerboseLogging:   false,
-		HumanInput:       false,
-		UseSystemPrompt:  true,
-		MaxTokens:        4096,
-		Temperature:      0.7,
-		CacheEnabled:     true,
-		MaxRetryLimit:    3,
-		Mode:             ModeJSON, // 默认使用JSON模式以保持向后兼容
+		MaxIterations:         25,
+		MaxRPM:                60,
+		Timeout:               30 * time.Minute,
+		MaxExecutionTime:      10 * time.Minute,
+		AllowDelegation:       false,
+		VerboseLogging:        false,
+		HumanInput:            false,
+		UseSystemPrompt:       true,
+		MaxTokens:             4096,
+		Temperature:           0.7,
+		CacheEnabled:          true,
+		MaxRetryLimit:         3,
+		MaxContextLength:      8000,
+		RetryPolicy:           DefaultRetryPolicy(),
+		MaxOutputFixAttempts:  2,
+		MaxGuardrailRetries:   3,
+		RespectContextWindow:  true,
+		ContextStrategy:       llm.TrimMiddleOut,
+		MaxApprovalRevisions:  3,
+		ApprovalTimeout:       30 * time.Minute,
+		ApprovalTimeoutPolicy: ApprovalPolicyFail,
+		MaxReasoningAttempts:  3,
+		Mode:                  ModeJSON, // 默认使用JSON模式以保持向后兼容
 	}
 }
 
@@ -385,6 +570,7 @@ type ReasoningOutput struct {
 	Duration   time.Duration          `json:"duration"`
 	Iterations int                    `json:"iterations"`
 	FinalReady bool                   `json:"final_ready"`
+	Usage      llm.Usage              `json:"usage"` // 推理调用的token用量，计入任务输出
 	Metadata   map[string]interface{} `json:"metadata"`
 	CreatedAt  time.Time              `json:"created_at"`
 }
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const (
+	// maxKnowledgeQueries 查询改写最多生成的检索查询数
+	maxKnowledgeQueries = 3
+	// knowledgeRewriteContextLength 查询改写提示中单个上下文值的最大长度，保持改写调用足够便宜
+	knowledgeRewriteContextLength = 500
+	// knowledgeRewriteMaxTokens 查询改写调用的最大回复token数
+	knowledgeRewriteMaxTokens = 200
+)
+
+// listMarkerPattern 匹配行首的列表标记，如"- "、"1. "、"2) "
+var listMarkerPattern = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+
+// knowledgeQueries 返回查询知识源使用的检索查询
+// 开启EnableKnowledgeQueryRewrite时由LLM把任务描述和上下文改写为1-3个检索查询，
+// 改写失败时静默回退为任务描述
+func (a *BaseAgent) knowledgeQueries(ctx context.Context, task Task) []string {
+	description := task.GetDescription()
+	if !a.executionConfig.EnableKnowledgeQueryRewrite {
+		return []string{description}
+	}
+
+	queries, err := a.rewriteKnowledgeQuery(ctx, task)
+	if err != nil || len(queries) == 0 {
+		a.logger.Debug("Knowledge query rewrite unavailable, using task description",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "error", Value: err},
+		)
+		return []string{description}
+	}
+
+	a.logger.Debug("Rewrote knowledge query",
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "queries", Value: queries},
+	)
+	return queries
+}
+
+// rewriteKnowledgeQuery 调用LLM把任务描述和Crew上下文改写为检索查询
+// 使用RewriteLLM，未设置时使用Agent的LLM；调用的token计入本次任务输出
+func (a *BaseAgent) rewriteKnowledgeQuery(ctx context.Context, task Task) ([]string, error) {
+	rewriteLLM := a.executionConfig.RewriteLLM
+	if rewriteLLM == nil {
+		rewriteLLM = a.GetLLM()
+	}
+	if rewriteLLM == nil {
+		return nil, fmt.Errorf("no LLM available for knowledge query rewrite")
+	}
+
+	if err := a.acquireRequest(ctx); err != nil {
+		return nil, err
+	}
+
+	temperature := 0.0
+	maxTokens := knowledgeRewriteMaxTokens
+	messages := []llm.Message{{Role: llm.RoleUser, Content: buildKnowledgeQueryPrompt(task)}}
+	options := &llm.CallOptions{Temperature: &temperature, MaxTokens: &maxTokens}
+	reproducibilityFrom(ctx).applyCallOptions(options)
+	response, err := rewriteLLM.Call(ctx, messages, options)
+	if err != nil {
+		return nil, err
+	}
+	callStatsFrom(ctx).addUsage(response.Usage)
+
+	return parseKnowledgeQueries(response.Content), nil
+}
+
+// buildKnowledgeQueryPrompt 构建查询改写请求
+func buildKnowledgeQueryPrompt(task Task) string {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Rewrite the task below into 1 to %d short, focused search queries for retrieving relevant knowledge. "+
+		"Each query should target one distinct piece of information the task needs.\n\n", maxKnowledgeQueries)
+	fmt.Fprintf(&prompt, "Task: %s\n", task.GetDescription())
+	if expectedOutput := task.GetExpectedOutput(); expectedOutput != "" {
+		fmt.Fprintf(&prompt, "Expected Output: %s\n", expectedOutput)
+	}
+	if contextSection := renderTaskContext(task.GetContext(), knowledgeRewriteContextLength, defaultPromptStrings()); contextSection != "" {
+		fmt.Fprintf(&prompt, "\n%s\n", contextSection)
+	}
+	prompt.WriteString("\nReturn only a JSON array of query strings, for example [\"first query\", \"second query\"].")
+	return prompt.String()
+}
+
+// parseKnowledgeQueries 解析改写结果，支持JSON数组或每行一个查询，最多返回maxKnowledgeQueries个不重复的查询
+func parseKnowledgeQueries(content string) []string {
+	content = strings.TrimSpace(content)
+
+	var candidates []string
+	if start, end := strings.Index(content, "["), strings.LastIndex(content, "]"); start >= 0 && end > start {
+		if err := json.Unmarshal([]byte(content[start:end+1]), &candidates); err != nil {
+			candidates = nil
+		}
+	}
+	if candidates == nil {
+		for _, line := range strings.Split(content, "\n") {
+			candidates = append(candidates, listMarkerPattern.ReplaceAllString(strings.TrimSpace(line), ""))
+		}
+	}
+
+	queries := make([]string, 0, maxKnowledgeQueries)
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		query := strings.Trim(strings.TrimSpace(candidate), "\"'`")
+		if query == "" || strings.HasPrefix(query, "```") || seen[strings.ToLower(query)] {
+			continue
+		}
+		seen[strings.ToLower(query)] = true
+		queries = append(queries, query)
+		if len(queries) == maxKnowledgeQueries {
+			break
+		}
+	}
+	return queries
+}
+
+// knowledgeContentHash 知识条目内容的哈希，用于去除多个查询或多个知识源返回的重复条目
+func knowledgeContentHash(content string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.TrimSpace(content)))
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// stubKnowledgeSource 按查询返回预设条目并记录收到的查询
+type stubKnowledgeSource struct {
+	name    string
+	items   map[string][]KnowledgeItem
+	queries []string
+}
+
+func (s *stubKnowledgeSource) GetName() string        { return s.name }
+func (s *stubKnowledgeSource) GetDescription() string { return "stub knowledge source" }
+func (s *stubKnowledgeSource) Initialize() error      { return nil }
+func (s *stubKnowledgeSource) Close() error           { return nil }
+func (s *stubKnowledgeSource) GetStats() KnowledgeStats {
+	return KnowledgeStats{TotalQueries: len(s.queries)}
+}
+func (s *stubKnowledgeSource) Refresh(ctx context.Context) (KnowledgeRefresh, error) {
+	return KnowledgeRefresh{}, nil
+}
+
+func (s *stubKnowledgeSource) Query(ctx context.Context, query string, options QueryOptions) ([]KnowledgeItem, error) {
+	s.queries = append(s.queries, query)
+	return s.items[query], nil
+}
+
+func newKnowledgeTestAgent(t *testing.T, mainLLM llm.LLM, rewriteLLM llm.LLM, source KnowledgeSource) *BaseAgent {
+	t.Helper()
+
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Analyst",
+		Goal:      "Answer with knowledge",
+		Backstory: "I read the docs first",
+		LLM:       mainLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, agent.SetKnowledgeSources([]KnowledgeSource{source}))
+
+	config := DefaultExecutionConfig()
+	config.EnableKnowledgeQueryRewrite = true
+	config.RewriteLLM = rewriteLLM
+	require.NoError(t, agent.SetExecutionConfig(config))
+	return agent
+}
+
+// TestParseKnowledgeQueries 测试解析JSON数组和逐行的改写结果
+func TestParseKnowledgeQueries(t *testing.T) {
+	assert.Equal(t, []string{"go generics", "type sets"},
+		parseKnowledgeQueries("```json\n[\"go generics\", \"Go Generics\", \"type sets\"]\n```"))
+	assert.Equal(t, []string{"2024 revenue", "pricing model", "churn"},
+		parseKnowledgeQueries("1. 2024 revenue\n- pricing model\n\n* churn\n4) extra"))
+	assert.Empty(t, parseKnowledgeQueries("  "))
+}
+
+// TestQueryKnowledgeRewritesAndDeduplicates 测试按改写后的查询分别检索并按内容去重
+func TestQueryKnowledgeRewritesAndDeduplicates(t *testing.T) {
+	source := &stubKnowledgeSource{
+		name: "handbook",
+		items: map[string][]KnowledgeItem{
+			"refund policy": {{Content: "Refunds within 30 days"}, {Content: "Store credit after 30 days"}},
+			"refund window": {{Content: "Refunds within 30 days "}, {Content: "Receipts are required", Source: "faq.md"}},
+		},
+	}
+	var rewritePrompt string
+	rewriteLLM := NewExtendedMockLLM([]llm.Response{{
+		Content: `["refund policy", "refund window"]`,
+		Usage:   llm.Usage{PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40, Cost: 0.01},
+	}}).WithCallHandler(func(messages []llm.Message) { rewritePrompt = messages[0].Content.(string) })
+	var taskPrompt string
+	mainLLM := NewExtendedMockLLM([]llm.Response{{
+		Content: "answer",
+		Usage:   llm.Usage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100},
+	}}).WithCallHandler(func(messages []llm.Message) { taskPrompt = messages[len(messages)-1].Content.(string) })
+
+	agent := newKnowledgeTestAgent(t, mainLLM, rewriteLLM, source)
+	task := NewBaseTask("Answer the customer's refund question", "A short answer")
+	task.SetContext(map[string]interface{}{"customer_message": "Can I return shoes bought last month?"})
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Contains(t, rewritePrompt, "Answer the customer's refund question")
+	assert.Contains(t, rewritePrompt, "Can I return shoes bought last month?")
+	assert.Equal(t, []string{"refund policy", "refund window"}, source.queries)
+	assert.Equal(t, 1, strings.Count(taskPrompt, "Refunds within 30 days"))
+	assert.Contains(t, taskPrompt, "[handbook] Store credit after 30 days")
+	assert.Contains(t, taskPrompt, "[faq.md] Receipts are required")
+
+	// 改写调用的token计入任务输出和Agent统计
+	assert.Equal(t, 140, output.TokensUsed)
+	assert.Equal(t, 110, output.PromptTokens)
+	assert.InDelta(t, 0.01, output.Cost, 1e-9)
+	assert.Equal(t, 140, agent.GetExecutionStats().TokensUsed)
+	assert.Equal(t, 140, agent.GetUsageMetrics().TotalTokens)
+}
+
+// TestQueryKnowledgeRewriteFallback 测试改写失败时静默回退为任务描述
+func TestQueryKnowledgeRewriteFallback(t *testing.T) {
+	source := &stubKnowledgeSource{name: "handbook"}
+	mainLLM := NewExtendedMockLLM([]llm.Response{{Content: "answer", Usage: llm.Usage{TotalTokens: 100}}})
+	rewriteLLM := NewExtendedMockLLM(nil).WithFailure(true)
+
+	agent := newKnowledgeTestAgent(t, mainLLM, rewriteLLM, source)
+	task := NewBaseTask("Summarize the onboarding guide", "A summary")
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Summarize the onboarding guide"}, source.queries)
+	assert.Equal(t, 100, output.TokensUsed)
+}
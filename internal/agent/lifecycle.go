@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// ExecuteEndHookErrorMetadataKey OnExecuteEnd返回错误时记录在TaskOutput.Metadata中的键
+const ExecuteEndHookErrorMetadataKey = "execute_end_hook_error"
+
+// ExecuteStartHook 任务执行开始前调用，返回的ctx用于本次执行（包括工具调用）；
+// 返回错误时不执行任务
+type ExecuteStartHook func(ctx context.Context, task Task) (context.Context, error)
+
+// ExecuteEndHook 任务执行结束后调用，执行失败时output为nil；
+// 返回的错误只记录日志并写入输出的Metadata，不影响执行结果
+type ExecuteEndHook func(ctx context.Context, task Task, output *TaskOutput) error
+
+// ContextKey 按类型存取ctx中的值，用于在生命周期钩子中放入共享资源（数据库连接池、带认证的HTTP客户端等），
+// 在工具中取出。每个ContextKey是独立的键，名称只用于调试
+//
+//	var DBKey = agent.NewContextKey[*sql.DB]("db")
+//	ctx = DBKey.WithValue(ctx, db)     // OnKickoffStart或OnExecuteStart中
+//	db, ok := DBKey.Value(ctx)          // 工具的Execute中
+type ContextKey[T any] struct {
+	name string
+}
+
+// NewContextKey 创建类型为T的ctx键
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+// WithValue 返回带有value的ctx
+func (k *ContextKey[T]) WithValue(ctx context.Context, value T) context.Context {
+	return context.WithValue(ctx, k, value)
+}
+
+// Value 取出ctx中的值，没有设置时返回零值和false
+func (k *ContextKey[T]) Value(ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(k).(T)
+	return value, ok
+}
+
+// MustValue 取出ctx中的值，没有设置时panic，用于钩子一定会设置的资源
+func (k *ContextKey[T]) MustValue(ctx context.Context) T {
+	value, ok := k.Value(ctx)
+	if !ok {
+		panic(fmt.Sprintf("context value %s is not set", k.name))
+	}
+	return value
+}
+
+func (k *ContextKey[T]) String() string { return "agent.ContextKey(" + k.name + ")" }
+
+// runExecuteStartHook 调用OnExecuteStart，未设置时原样返回ctx
+func (a *BaseAgent) runExecuteStartHook(ctx context.Context, task Task) (context.Context, error) {
+	hook := a.executionConfig.OnExecuteStart
+	if hook == nil {
+		return ctx, nil
+	}
+	hookCtx, err := hook(ctx, task)
+	if err != nil {
+		a.logger.Error("execute start hook failed",
+			logger.Field{Key: "agent", Value: a.role},
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "error", Value: err})
+		return ctx, fmt.Errorf("execute start hook failed: %w", err)
+	}
+	if hookCtx == nil {
+		hookCtx = ctx
+	}
+	return hookCtx, nil
+}
+
+// runExecuteEndHook 调用OnExecuteEnd，错误记录日志并写入输出的Metadata
+func (a *BaseAgent) runExecuteEndHook(ctx context.Context, task Task, output *TaskOutput) {
+	hook := a.executionConfig.OnExecuteEnd
+	if hook == nil {
+		return
+	}
+	if err := hook(ctx, task, output); err != nil {
+		a.logger.Error("execute end hook failed",
+			logger.Field{Key: "agent", Value: a.role},
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "error", Value: err})
+		if output != nil {
+			if output.Metadata == nil {
+				output.Metadata = make(map[string]interface{})
+			}
+			output.Metadata[ExecuteEndHookErrorMetadataKey] = err.Error()
+		}
+	}
+}
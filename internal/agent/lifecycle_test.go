@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// apiClient 测试用的共享资源
+type apiClient struct {
+	token  string
+	closed bool
+}
+
+var apiClientKey = NewContextKey[*apiClient]("api_client")
+
+func TestContextKey(t *testing.T) {
+	ctx := apiClientKey.WithValue(context.Background(), &apiClient{token: "secret"})
+
+	client, ok := apiClientKey.Value(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "secret", client.token)
+	assert.Same(t, client, apiClientKey.MustValue(ctx))
+
+	// 同名的不同键互不影响
+	other := NewContextKey[*apiClient]("api_client")
+	_, ok = other.Value(ctx)
+	assert.False(t, ok)
+	assert.PanicsWithValue(t, "context value api_client is not set", func() { other.MustValue(ctx) })
+}
+
+// TestExecuteLifecycleHooks 测试开始钩子放入的资源可以在工具中取出，结束钩子在执行后释放资源
+func TestExecuteLifecycleHooks(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{ToolCalls: []llm.ToolCall{toolCall("fetch")}},
+		{Content: "Fetched"},
+	})
+	var toolToken string
+	fetch := NewBaseTool("fetch", "Fetch with the shared client", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		client, ok := apiClientKey.Value(ctx)
+		if !ok {
+			return nil, errors.New("no client")
+		}
+		toolToken = client.token
+		return "ok", nil
+	})
+	agent := newToolLoopTestAgent(t, mockLLM, nil, fetch)
+
+	client := &apiClient{token: "secret"}
+	var endOutput *TaskOutput
+	config := agent.GetExecutionConfig()
+	config.OnExecuteStart = func(ctx context.Context, task Task) (context.Context, error) {
+		return apiClientKey.WithValue(ctx, client), nil
+	}
+	config.OnExecuteEnd = func(ctx context.Context, task Task, output *TaskOutput) error {
+		apiClientKey.MustValue(ctx).closed = true
+		endOutput = output
+		return errors.New("close failed")
+	}
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Fetch the data", "The data"))
+	require.NoError(t, err)
+	assert.Equal(t, "Fetched", output.Raw)
+	assert.Equal(t, "secret", toolToken)
+	assert.True(t, client.closed)
+	assert.Same(t, output, endOutput)
+	// 结束钩子的错误只记录在Metadata中
+	assert.Equal(t, "close failed", output.Metadata[ExecuteEndHookErrorMetadataKey])
+}
+
+func TestExecuteStartHookError(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "unused"}})
+	agent := newToolLoopTestAgent(t, mockLLM, nil)
+
+	endCalled := false
+	config := agent.GetExecutionConfig()
+	config.OnExecuteStart = func(ctx context.Context, task Task) (context.Context, error) {
+		return nil, errors.New("database unavailable")
+	}
+	config.OnExecuteEnd = func(ctx context.Context, task Task, output *TaskOutput) error {
+		endCalled = true
+		return nil
+	}
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	_, err := agent.Execute(context.Background(), NewBaseTask("Fetch the data", "The data"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "execute start hook failed: database unavailable")
+	assert.Equal(t, 0, mockLLM.GetCallCount())
+	assert.False(t, endCalled, "end hook should not run when start hook fails")
+}
+
+func TestExecuteEndHookRunsOnFailure(t *testing.T) {
+	mockLLM := NewExtendedMockLLM(nil).WithFailure(true)
+	agent := newToolLoopTestAgent(t, mockLLM, nil)
+
+	client := &apiClient{}
+	endCalled := false
+	config := agent.GetExecutionConfig()
+	config.OnExecuteStart = func(ctx context.Context, task Task) (context.Context, error) {
+		return apiClientKey.WithValue(ctx, client), nil
+	}
+	config.OnExecuteEnd = func(ctx context.Context, task Task, output *TaskOutput) error {
+		endCalled = true
+		assert.Nil(t, output)
+		apiClientKey.MustValue(ctx).closed = true
+		return nil
+	}
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	_, err := agent.Execute(context.Background(), NewBaseTask("Fetch the data", "The data"))
+	require.Error(t, err)
+	assert.True(t, endCalled)
+	assert.True(t, client.closed)
+}
+
+func TestExecuteStreamLifecycleHooks(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "Streamed"}})
+	agent := newToolLoopTestAgent(t, mockLLM, nil)
+
+	var seen bool
+	config := agent.GetExecutionConfig()
+	config.OnExecuteStart = func(ctx context.Context, task Task) (context.Context, error) {
+		return apiClientKey.WithValue(ctx, &apiClient{}), nil
+	}
+	config.OnExecuteEnd = func(ctx context.Context, task Task, output *TaskOutput) error {
+		_, seen = apiClientKey.Value(ctx)
+		return errors.New("close failed")
+	}
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	chunks, err := agent.ExecuteStream(context.Background(), NewBaseTask("Stream it", "Text"))
+	require.NoError(t, err)
+	var final AgentStreamChunk
+	for chunk := range chunks {
+		final = chunk
+	}
+	require.NoError(t, final.Error)
+	assert.True(t, seen)
+	assert.Equal(t, "close failed", final.Output.Metadata[ExecuteEndHookErrorMetadataKey])
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ynl/greensoulai/internal/llm"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/security"
 )
 
 // ===== Mock LLM =====
@@ -66,6 +67,7 @@ func (m *MockLLM) GetEventBus() events.EventBus         { return nil }
 func (m *MockLLM) GetCustomHeaders() map[string]string  { return nil }
 func (m *MockLLM) SupportsFunctionCalling() bool        { return true }
 func (m *MockLLM) GetContextWindowSize() int            { return 4096 }
+func (m *MockLLM) GetTokenizer() llm.Tokenizer          { return llm.HeuristicTokenizer{} }
 func (m *MockLLM) SetEventBus(eventBus events.EventBus) {}
 func (m *MockLLM) Close() error                         { return nil }
 
@@ -170,6 +172,7 @@ func (m *ExtendedMockLLM) GetEventBus() events.EventBus         { return nil }
 func (m *ExtendedMockLLM) GetCustomHeaders() map[string]string  { return nil }
 func (m *ExtendedMockLLM) SupportsFunctionCalling() bool        { return true }
 func (m *ExtendedMockLLM) GetContextWindowSize() int            { return 4096 }
+func (m *ExtendedMockLLM) GetTokenizer() llm.Tokenizer          { return llm.HeuristicTokenizer{} }
 func (m *ExtendedMockLLM) SetEventBus(eventBus events.EventBus) {}
 func (m *ExtendedMockLLM) Close() error                         { return nil }
 
@@ -283,6 +286,12 @@ func (m *MockTool) IsUsageLimitExceeded() bool {
 	return m.usageLimit >= 0 && m.usageCount > m.usageLimit
 }
 
+func (m *MockTool) Clone() Tool {
+	clone := *m
+	clone.usageCount = 0
+	return &clone
+}
+
 // 确保MockTool实现了Tool接口
 var _ Tool = (*MockTool)(nil)
 
@@ -308,17 +317,28 @@ func NewMockAgent(role, goal, backstory string) *MockAgent {
 	}
 }
 
-func (m *MockAgent) GetID() string        { return m.id }
-func (m *MockAgent) GetRole() string      { return m.role }
-func (m *MockAgent) GetGoal() string      { return m.goal }
-func (m *MockAgent) GetBackstory() string { return m.backstory }
-func (m *MockAgent) GetTools() []Tool     { return m.tools }
+func (m *MockAgent) GetID() string                         { return m.id }
+func (m *MockAgent) GetRole() string                       { return m.role }
+func (m *MockAgent) GetGoal() string                       { return m.goal }
+func (m *MockAgent) GetBackstory() string                  { return m.backstory }
+func (m *MockAgent) GetFingerprint() *security.Fingerprint { return nil }
+func (m *MockAgent) GetTools() []Tool                      { return m.tools }
 
 func (m *MockAgent) AddTool(tool Tool) error {
 	m.tools = append(m.tools, tool)
 	return nil
 }
 
+func (m *MockAgent) RemoveTool(name string) error {
+	for i, tool := range m.tools {
+		if tool.GetName() == name {
+			m.tools = append(m.tools[:i], m.tools[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("tool %s not found", name)
+}
+
 // 实现Agent接口的其他必需方法（简化版）
 func (m *MockAgent) Execute(ctx context.Context, task Task) (*TaskOutput, error) {
 	return &TaskOutput{
@@ -337,6 +357,21 @@ func (m *MockAgent) ExecuteAsync(ctx context.Context, task Task) (<-chan TaskRes
 	return resultChan, nil
 }
 
+func (m *MockAgent) ExecuteStream(ctx context.Context, task Task) (<-chan AgentStreamChunk, error) {
+	chunks := make(chan AgentStreamChunk, 1)
+	go func() {
+		defer close(chunks)
+		output, err := m.Execute(ctx, task)
+		chunk := AgentStreamChunk{Done: true, Output: output, Error: err}
+		if output != nil {
+			chunk.Delta = output.Raw
+			chunk.Content = output.Raw
+		}
+		chunks <- chunk
+	}()
+	return chunks, nil
+}
+
 // 其他必需的方法（空实现）
 func (m *MockAgent) ExecuteWithTimeout(ctx context.Context, task Task, timeout time.Duration) (*TaskOutput, error) {
 	return m.Execute(ctx, task)
@@ -351,6 +386,14 @@ func (m *MockAgent) SetExecutionConfig(config ExecutionConfig) error
 func (m *MockAgent) GetExecutionConfig() ExecutionConfig                                 { return ExecutionConfig{} }
 func (m *MockAgent) SetHumanInputHandler(handler HumanInputHandler) error                { return nil }
 func (m *MockAgent) GetHumanInputHandler() HumanInputHandler                             { return nil }
+func (m *MockAgent) SetRPMController(controller *RPMController)                          {}
+func (m *MockAgent) GetRPMController() *RPMController                                    { return nil }
+func (m *MockAgent) SetResponseCache(cache llm.ResponseCache)                            {}
+func (m *MockAgent) GetResponseCache() llm.ResponseCache                                 { return nil }
+func (m *MockAgent) SetToolCache(cache ToolCache)                                        {}
+func (m *MockAgent) GetToolCache() ToolCache                                             { return nil }
+func (m *MockAgent) SetTrainedInstructions(instructions []string)                        {}
+func (m *MockAgent) GetTrainedInstructions() []string                                    { return nil }
 func (m *MockAgent) SetEventBus(eventBus events.EventBus) error                          { return nil }
 func (m *MockAgent) GetEventBus() events.EventBus                                        { return nil }
 func (m *MockAgent) SetLogger(logger logger.Logger) error                                { return nil }
@@ -372,6 +415,7 @@ func (m *MockAgent) Clone() Agent {
 	return &clone
 }
 func (m *MockAgent) GetExecutionStats() ExecutionStats            { return ExecutionStats{} }
+func (m *MockAgent) GetUsageMetrics() *UsageMetrics               { return &UsageMetrics{} }
 func (m *MockAgent) ResetStats() error                            { return nil }
 func (m *MockAgent) SetReasoningHandler(handler ReasoningHandler) {}
 func (m *MockAgent) GetReasoningHandler() ReasoningHandler        { return nil }
@@ -0,0 +1,313 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// DefaultModerationTimeout 单次审核的默认超时时间
+const DefaultModerationTimeout = 10 * time.Second
+
+// ModerationMetadataKey TaskOutput.Metadata中记录被标记内容的键，值为[]ModerationAnnotation
+const ModerationMetadataKey = "moderation"
+
+// ErrContentModerated 内容被审核拦截，可用errors.Is判断ModerationError
+var ErrContentModerated = errors.New("content blocked by moderation")
+
+// ModerationAction 审核决定
+type ModerationAction string
+
+const (
+	ModerationAllow ModerationAction = "allow" // 放行
+	ModerationFlag  ModerationAction = "flag"  // 放行，但在输出Metadata中标注并发射moderation_flagged事件
+	ModerationBlock ModerationAction = "block" // 拦截
+)
+
+// ModerationStage 审核发生的阶段
+type ModerationStage string
+
+const (
+	ModerationStageInput  ModerationStage = "input"  // 发送给LLM之前的提示
+	ModerationStageOutput ModerationStage = "output" // LLM返回的最终答案
+)
+
+// ModerationOutputPolicy 输出被拦截时的处理方式
+type ModerationOutputPolicy string
+
+const (
+	ModerationOutputFail    ModerationOutputPolicy = "fail"    // 任务失败，返回ModerationError
+	ModerationOutputRewrite ModerationOutputPolicy = "rewrite" // 要求LLM去掉违规内容重新回答，仍被拦截时失败
+)
+
+// ModerationDecision 一次审核的结果
+type ModerationDecision struct {
+	Action     ModerationAction   `json:"action"`
+	Categories []string           `json:"categories,omitempty"` // 命中的违规类别
+	Reason     string             `json:"reason,omitempty"`
+	Scores     map[string]float64 `json:"scores,omitempty"` // 各类别的分数，由实现决定是否提供
+}
+
+// Moderator 内容审核器，在提示发送给LLM之前和收到LLM的答案之后调用
+type Moderator interface {
+	CheckInput(ctx context.Context, text string) (ModerationDecision, error)
+	CheckOutput(ctx context.Context, text string) (ModerationDecision, error)
+}
+
+// ModerationConfig 内容审核配置
+type ModerationConfig struct {
+	Moderator    Moderator              // 为nil时不审核
+	OutputPolicy ModerationOutputPolicy // 输出被拦截时的处理方式，为空时为ModerationOutputFail
+	MaxRewrites  int                    // OutputPolicy为rewrite时最多重写的次数，<=0时为1
+	Timeout      time.Duration          // 单次审核的超时时间，<=0时使用DefaultModerationTimeout
+	FailOpen     bool                   // 审核出错或超时时放行并记录警告，默认按拦截处理
+}
+
+// DefaultModerationConfig 返回使用指定审核器的默认配置
+func DefaultModerationConfig(moderator Moderator) *ModerationConfig {
+	return &ModerationConfig{
+		Moderator:    moderator,
+		OutputPolicy: ModerationOutputFail,
+		MaxRewrites:  1,
+		Timeout:      DefaultModerationTimeout,
+	}
+}
+
+// ModerationAnnotation 被标记但放行的内容，记录在TaskOutput.Metadata["moderation"]中
+type ModerationAnnotation struct {
+	Stage      ModerationStage `json:"stage"`
+	Categories []string        `json:"categories,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+}
+
+// ModerationError 提示或答案被审核拦截
+type ModerationError struct {
+	TaskID     string          // 任务ID
+	Stage      ModerationStage // 拦截发生的阶段
+	Categories []string        // 命中的违规类别
+	Reason     string          // 审核器给出的原因，审核出错时为错误信息
+}
+
+func (e *ModerationError) Error() string {
+	msg := fmt.Sprintf("task %s %s blocked by moderation", e.TaskID, e.Stage)
+	if len(e.Categories) > 0 {
+		msg += fmt.Sprintf(" (categories: %s)", strings.Join(e.Categories, ", "))
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Is 使errors.Is(err, ErrContentModerated)成立
+func (e *ModerationError) Is(target error) bool {
+	return target == ErrContentModerated
+}
+
+type moderationKey struct{}
+
+// WithModeration 返回带有审核配置的ctx，之后执行的Agent在自身没有设置审核时使用该配置
+func WithModeration(ctx context.Context, config *ModerationConfig) context.Context {
+	return context.WithValue(ctx, moderationKey{}, config)
+}
+
+// ModerationFrom 返回ctx中的审核配置，没有时返回nil
+func ModerationFrom(ctx context.Context) *ModerationConfig {
+	config, _ := ctx.Value(moderationKey{}).(*ModerationConfig)
+	return config
+}
+
+// moderationConfig 返回本次执行使用的审核配置，Agent自身的设置优先于ctx中的设置，都没有时返回nil
+func (a *BaseAgent) moderationConfig(ctx context.Context) *ModerationConfig {
+	if a.moderation != nil && a.moderation.Moderator != nil {
+		return a.moderation
+	}
+	if config := ModerationFrom(ctx); config != nil && config.Moderator != nil {
+		return config
+	}
+	return nil
+}
+
+// moderateInput 审核将要发送给LLM的用户消息，被拦截时返回ModerationError，被标记时返回标记记录
+func (a *BaseAgent) moderateInput(ctx context.Context, task Task, messages []llm.Message) ([]ModerationAnnotation, error) {
+	config := a.moderationConfig(ctx)
+	if config == nil {
+		return nil, nil
+	}
+
+	var parts []string
+	for _, msg := range messages {
+		if msg.Role != llm.RoleUser {
+			continue
+		}
+		if text := llm.ContentText(msg.Content); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	decision, err := a.checkModeration(ctx, config, ModerationStageInput, strings.Join(parts, "\n\n"))
+	if err != nil {
+		return nil, moderationCheckError(ctx, task, ModerationStageInput, err)
+	}
+	switch decision.Action {
+	case ModerationBlock:
+		return nil, &ModerationError{TaskID: task.GetID(), Stage: ModerationStageInput, Categories: decision.Categories, Reason: decision.Reason}
+	case ModerationFlag:
+		return []ModerationAnnotation{a.flagModeration(ctx, task, ModerationStageInput, decision)}, nil
+	}
+	return nil, nil
+}
+
+// moderateOutput 审核任务的最终答案，flags为输入阶段的标记记录，一并写入输出的Metadata
+// 被拦截时按OutputPolicy失败或追加重写提示重新生成，allowRewrite为false（答案已经流式发出）时总是失败。
+// 重写产生的token和成本计入返回的输出
+func (a *BaseAgent) moderateOutput(ctx context.Context, task Task, toolCtx *ToolExecutionContext, messages []llm.Message, callOptions *llm.CallOptions, output *TaskOutput, flags []ModerationAnnotation, allowRewrite bool) (*TaskOutput, error) {
+	config := a.moderationConfig(ctx)
+	if config == nil {
+		return output, nil
+	}
+
+	maxRewrites := 0
+	if allowRewrite && config.OutputPolicy == ModerationOutputRewrite {
+		maxRewrites = config.MaxRewrites
+		if maxRewrites <= 0 {
+			maxRewrites = 1
+		}
+	}
+
+	// 复制消息，避免修改调用方的切片
+	messages = append([]llm.Message(nil), messages...)
+
+	var rejected llm.Usage
+	for rewrite := 0; ; rewrite++ {
+		decision, err := a.checkModeration(ctx, config, ModerationStageOutput, output.Raw)
+		if err != nil {
+			return nil, moderationCheckError(ctx, task, ModerationStageOutput, err)
+		}
+		if decision.Action != ModerationBlock {
+			if decision.Action == ModerationFlag {
+				flags = append(flags, a.flagModeration(ctx, task, ModerationStageOutput, decision))
+			}
+			addUsageToOutput(output, rejected)
+			if len(flags) > 0 {
+				output.Metadata[ModerationMetadataKey] = flags
+			}
+			if rewrite > 0 {
+				output.Metadata["moderation_rewrites"] = rewrite
+			}
+			return output, nil
+		}
+
+		a.logger.Warn("Task output blocked by moderation",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "categories", Value: decision.Categories},
+			logger.Field{Key: "rewrite", Value: rewrite},
+			logger.Field{Key: "max_rewrites", Value: maxRewrites},
+		)
+		if rewrite >= maxRewrites {
+			return nil, &ModerationError{TaskID: task.GetID(), Stage: ModerationStageOutput, Categories: decision.Categories, Reason: decision.Reason}
+		}
+
+		rejected.TotalTokens += output.TokensUsed
+		rejected.PromptTokens += output.PromptTokens
+		rejected.CompletionTokens += output.CompletionTokens
+		rejected.Cost += output.Cost
+
+		messages = append(messages,
+			llm.Message{Role: llm.RoleAssistant, Content: output.Raw},
+			llm.Message{Role: llm.RoleUser, Content: buildModerationRewritePrompt(a.prompts, decision.Categories)},
+		)
+		if output, err = a.generateOutput(ctx, task, toolCtx, messages, callOptions); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// checkModeration 在Timeout内执行一次审核，审核器不响应ctx取消时也按时返回
+// 审核出错或超时时，FailOpen为true则放行，否则返回错误
+func (a *BaseAgent) checkModeration(ctx context.Context, config *ModerationConfig, stage ModerationStage, text string) (ModerationDecision, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultModerationTimeout
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type checkResult struct {
+		decision ModerationDecision
+		err      error
+	}
+	resultChan := make(chan checkResult, 1)
+	go func() {
+		var result checkResult
+		if stage == ModerationStageInput {
+			result.decision, result.err = config.Moderator.CheckInput(checkCtx, text)
+		} else {
+			result.decision, result.err = config.Moderator.CheckOutput(checkCtx, text)
+		}
+		resultChan <- result
+	}()
+
+	var result checkResult
+	select {
+	case result = <-resultChan:
+	case <-checkCtx.Done():
+		result.err = fmt.Errorf("moderation timed out after %v: %w", timeout, checkCtx.Err())
+	}
+	if result.err == nil {
+		return result.decision, nil
+	}
+
+	if ctx.Err() != nil {
+		return ModerationDecision{}, fmt.Errorf("task execution cancelled: %w", ctx.Err())
+	}
+	if config.FailOpen {
+		a.logger.Warn("Moderation check failed, allowing content",
+			logger.Field{Key: "stage", Value: stage},
+			logger.Field{Key: "error", Value: result.err},
+		)
+		return ModerationDecision{Action: ModerationAllow}, nil
+	}
+	return ModerationDecision{}, fmt.Errorf("moderation check failed: %w", result.err)
+}
+
+// moderationCheckError 审核失败时按拦截处理返回ModerationError，任务被取消时直接返回错误
+func moderationCheckError(ctx context.Context, task Task, stage ModerationStage, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return &ModerationError{TaskID: task.GetID(), Stage: stage, Reason: err.Error()}
+}
+
+// flagModeration 记录被标记的内容并发射moderation_flagged事件
+func (a *BaseAgent) flagModeration(ctx context.Context, task Task, stage ModerationStage, decision ModerationDecision) ModerationAnnotation {
+	a.logger.Info("Content flagged by moderation",
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "stage", Value: stage},
+		logger.Field{Key: "categories", Value: decision.Categories},
+	)
+	if a.eventBus != nil {
+		event := NewAgentModerationFlaggedEvent(a.id, a.role, task.GetID(), string(stage), decision.Categories, decision.Reason)
+		if err := a.eventBus.Emit(ctx, a, event); err != nil {
+			a.logger.Warn("Failed to emit moderation flagged event", logger.Field{Key: "error", Value: err})
+		}
+	}
+	return ModerationAnnotation{Stage: stage, Categories: decision.Categories, Reason: decision.Reason}
+}
+
+// buildModerationRewritePrompt 构建要求Agent去掉违规内容重新回答的提示
+func buildModerationRewritePrompt(prompts PromptStrings, categories []string) string {
+	list := strings.Join(categories, ", ")
+	if list == "" {
+		list = "unspecified"
+	}
+	return fmt.Sprintf(prompts.ModerationRewrite, list)
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ModerationRule 一条本地审核规则，Pattern和Terms任一命中即视为命中
+type ModerationRule struct {
+	Category string           `json:"category"` // 命中时报告的违规类别
+	Pattern  string           `json:"pattern"`  // 正则表达式，可为空
+	Terms    []string         `json:"terms"`    // 不区分大小写的禁用词，可为空
+	Action   ModerationAction `json:"action"`   // 命中时的处理，为空时为ModerationBlock
+	Stage    ModerationStage  `json:"stage"`    // 只审核该阶段，为空时审核输入和输出
+}
+
+// compiledModerationRule 编译后的审核规则
+type compiledModerationRule struct {
+	category string
+	pattern  *regexp.Regexp
+	action   ModerationAction
+	stage    ModerationStage
+}
+
+// DenylistModerator 按正则和禁用词在本地审核内容，不依赖外部服务
+// 同时命中多条规则时，任一规则为block则拦截，否则标记
+type DenylistModerator struct {
+	rules []compiledModerationRule
+}
+
+var _ Moderator = (*DenylistModerator)(nil)
+
+// NewDenylistModerator 创建本地审核器
+func NewDenylistModerator(rules ...ModerationRule) (*DenylistModerator, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("denylist moderator requires at least one rule")
+	}
+
+	moderator := &DenylistModerator{}
+	for _, rule := range rules {
+		if rule.Category == "" {
+			return nil, fmt.Errorf("moderation rule category cannot be empty")
+		}
+
+		var alternatives []string
+		if rule.Pattern != "" {
+			alternatives = append(alternatives, "(?:"+rule.Pattern+")")
+		}
+		for _, term := range rule.Terms {
+			if term = strings.TrimSpace(term); term != "" {
+				alternatives = append(alternatives, `(?i:\b`+regexp.QuoteMeta(term)+`\b)`)
+			}
+		}
+		if len(alternatives) == 0 {
+			return nil, fmt.Errorf("moderation rule %s needs a pattern or terms", rule.Category)
+		}
+		pattern, err := regexp.Compile(strings.Join(alternatives, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for moderation rule %s: %w", rule.Category, err)
+		}
+
+		action := rule.Action
+		if action == "" {
+			action = ModerationBlock
+		}
+		if action != ModerationBlock && action != ModerationFlag {
+			return nil, fmt.Errorf("invalid action %q for moderation rule %s", action, rule.Category)
+		}
+		moderator.rules = append(moderator.rules, compiledModerationRule{category: rule.Category, pattern: pattern, action: action, stage: rule.Stage})
+	}
+	return moderator, nil
+}
+
+// CheckInput 实现Moderator接口
+func (m *DenylistModerator) CheckInput(ctx context.Context, text string) (ModerationDecision, error) {
+	return m.check(ModerationStageInput, text), nil
+}
+
+// CheckOutput 实现Moderator接口
+func (m *DenylistModerator) CheckOutput(ctx context.Context, text string) (ModerationDecision, error) {
+	return m.check(ModerationStageOutput, text), nil
+}
+
+// check 按规则审核一段文本
+func (m *DenylistModerator) check(stage ModerationStage, text string) ModerationDecision {
+	decision := ModerationDecision{Action: ModerationAllow}
+	for _, rule := range m.rules {
+		if rule.stage != "" && rule.stage != stage {
+			continue
+		}
+		if !rule.pattern.MatchString(text) {
+			continue
+		}
+		if !containsString(decision.Categories, rule.category) {
+			decision.Categories = append(decision.Categories, rule.category)
+		}
+		if rule.action == ModerationBlock || decision.Action == ModerationAllow {
+			decision.Action = rule.action
+		}
+	}
+	if decision.Action != ModerationAllow {
+		sort.Strings(decision.Categories)
+		decision.Reason = "matched denylist rules"
+	}
+	return decision
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// 确保OpenAIModerator实现了Moderator接口
+var _ Moderator = (*OpenAIModerator)(nil)
+
+const (
+	// DefaultOpenAIModerationModel OpenAI审核接口的默认模型
+	DefaultOpenAIModerationModel = "omni-moderation-latest"
+	defaultOpenAIModerationURL   = "https://api.openai.com/v1"
+)
+
+// OpenAIModerator 使用OpenAI moderation API审核内容
+// 接口标记为违规的内容按FlaggedAction处理，命中的类别为接口返回的flagged类别
+type OpenAIModerator struct {
+	APIKey        string           // 为空时读取OPENAI_API_KEY环境变量
+	BaseURL       string           // 为空时使用https://api.openai.com/v1
+	Model         string           // 为空时使用DefaultOpenAIModerationModel
+	FlaggedAction ModerationAction // 违规内容的处理，为空时为ModerationBlock
+	HTTPClient    *http.Client     // 为nil时使用llm包共享连接池的客户端
+}
+
+// NewOpenAIModerator 创建OpenAI审核器，apiKey为空时读取OPENAI_API_KEY环境变量
+func NewOpenAIModerator(apiKey string) *OpenAIModerator {
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return &OpenAIModerator{APIKey: apiKey, Model: DefaultOpenAIModerationModel, FlaggedAction: ModerationBlock}
+}
+
+// CheckInput 实现Moderator接口
+func (m *OpenAIModerator) CheckInput(ctx context.Context, text string) (ModerationDecision, error) {
+	return m.check(ctx, text)
+}
+
+// CheckOutput 实现Moderator接口
+func (m *OpenAIModerator) CheckOutput(ctx context.Context, text string) (ModerationDecision, error) {
+	return m.check(ctx, text)
+}
+
+// openAIModerationResponse moderation API的响应
+type openAIModerationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// check 调用moderation API审核一段文本
+func (m *OpenAIModerator) check(ctx context.Context, text string) (ModerationDecision, error) {
+	apiKey := m.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if apiKey == "" {
+		return ModerationDecision{}, fmt.Errorf("openai moderator requires an api key or OPENAI_API_KEY")
+	}
+	model := m.Model
+	if model == "" {
+		model = DefaultOpenAIModerationModel
+	}
+	baseURL := m.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIModerationURL
+	}
+	client := m.HTTPClient
+	if client == nil {
+		client = llm.NewSharedHTTPClient(0)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"model": model, "input": text})
+	if err != nil {
+		return ModerationDecision{}, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(baseURL, "/")+"/moderations", bytes.NewReader(payload))
+	if err != nil {
+		return ModerationDecision{}, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return ModerationDecision{}, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return ModerationDecision{}, fmt.Errorf("failed to read moderation response: %w", err)
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return ModerationDecision{}, fmt.Errorf("moderation API returned status %d: %s", httpResp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var response openAIModerationResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ModerationDecision{}, fmt.Errorf("invalid moderation response: %w", err)
+	}
+
+	decision := ModerationDecision{Action: ModerationAllow, Scores: make(map[string]float64)}
+	flagged := false
+	for _, result := range response.Results {
+		flagged = flagged || result.Flagged
+		for category, hit := range result.Categories {
+			if hit && !containsString(decision.Categories, category) {
+				decision.Categories = append(decision.Categories, category)
+			}
+		}
+		for category, score := range result.CategoryScores {
+			decision.Scores[category] = max(decision.Scores[category], score)
+		}
+	}
+	if !flagged {
+		return decision, nil
+	}
+
+	sort.Strings(decision.Categories)
+	decision.Action = m.FlaggedAction
+	if decision.Action == "" {
+		decision.Action = ModerationBlock
+	}
+	decision.Reason = "flagged by OpenAI moderation"
+	return decision, nil
+}
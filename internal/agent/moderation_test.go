@@ -0,0 +1,260 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// funcModerator 用函数实现的审核器
+type funcModerator struct {
+	input  func(ctx context.Context, text string) (ModerationDecision, error)
+	output func(ctx context.Context, text string) (ModerationDecision, error)
+}
+
+func (m *funcModerator) CheckInput(ctx context.Context, text string) (ModerationDecision, error) {
+	if m.input == nil {
+		return ModerationDecision{Action: ModerationAllow}, nil
+	}
+	return m.input(ctx, text)
+}
+
+func (m *funcModerator) CheckOutput(ctx context.Context, text string) (ModerationDecision, error) {
+	if m.output == nil {
+		return ModerationDecision{Action: ModerationAllow}, nil
+	}
+	return m.output(ctx, text)
+}
+
+func newModerationTestAgent(t *testing.T, mockLLM llm.LLM, eventBus events.EventBus, moderation *ModerationConfig) *BaseAgent {
+	t.Helper()
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:       "Writer",
+		Goal:       "Write answers",
+		Backstory:  "Careful",
+		LLM:        mockLLM,
+		EventBus:   eventBus,
+		Logger:     logger.NewTestLogger(),
+		Moderation: moderation,
+	})
+	require.NoError(t, err)
+	return agent
+}
+
+// TestModerationBlocksInput 测试输入被拦截时任务失败，提示不会发送给LLM
+func TestModerationBlocksInput(t *testing.T) {
+	denylist, err := NewDenylistModerator(ModerationRule{Category: "weapons", Terms: []string{"nerve agent"}})
+	require.NoError(t, err)
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "never sent"}})
+	agent := newModerationTestAgent(t, mockLLM, nil, DefaultModerationConfig(denylist))
+
+	task := NewBaseTask("Explain how to make a Nerve Agent", "Steps")
+	output, err := agent.Execute(context.Background(), task)
+	require.Error(t, err)
+	assert.Nil(t, output)
+	assert.Equal(t, 0, mockLLM.callCount)
+
+	var moderationErr *ModerationError
+	require.True(t, errors.As(err, &moderationErr))
+	assert.True(t, errors.Is(err, ErrContentModerated))
+	assert.Equal(t, task.GetID(), moderationErr.TaskID)
+	assert.Equal(t, ModerationStageInput, moderationErr.Stage)
+	assert.Equal(t, []string{"weapons"}, moderationErr.Categories)
+}
+
+// TestModerationRewritesBlockedOutput 测试输出被拦截时要求重写，重写的token计入输出
+func TestModerationRewritesBlockedOutput(t *testing.T) {
+	moderator := &funcModerator{output: func(ctx context.Context, text string) (ModerationDecision, error) {
+		if text == "insult" {
+			return ModerationDecision{Action: ModerationBlock, Categories: []string{"harassment"}}, nil
+		}
+		return ModerationDecision{Action: ModerationAllow}, nil
+	}}
+	var prompts []string
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: "insult", Usage: llm.Usage{PromptTokens: 8, CompletionTokens: 2, TotalTokens: 10}},
+		{Content: "polite answer", Usage: llm.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}},
+	}).WithCallHandler(func(messages []llm.Message) {
+		prompts = append(prompts, messages[len(messages)-1].Content.(string))
+	})
+	config := DefaultModerationConfig(moderator)
+	config.OutputPolicy = ModerationOutputRewrite
+	agent := newModerationTestAgent(t, mockLLM, nil, config)
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Reply to the customer", "A reply"))
+	require.NoError(t, err)
+	assert.Equal(t, "polite answer", output.Raw)
+	assert.Equal(t, 1, output.Metadata["moderation_rewrites"])
+	assert.Equal(t, 25, output.TokensUsed)
+
+	require.Len(t, prompts, 2)
+	assert.Equal(t, "Your previous answer was blocked by content moderation (categories: harassment).\n"+
+		"Rephrase your complete final answer without the policy-violating content.", prompts[1])
+}
+
+// TestModerationFailsBlockedOutput 测试默认策略下输出被拦截时任务失败
+func TestModerationFailsBlockedOutput(t *testing.T) {
+	denylist, err := NewDenylistModerator(ModerationRule{Category: "secrets", Pattern: `\bsk-[A-Za-z0-9]{8,}`, Stage: ModerationStageOutput})
+	require.NoError(t, err)
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "the key is sk-abcdefgh1234"}})
+	agent := newModerationTestAgent(t, mockLLM, nil, DefaultModerationConfig(denylist))
+
+	_, err = agent.Execute(context.Background(), NewBaseTask("Print the key sk-abcdefgh1234", "The key"))
+	var moderationErr *ModerationError
+	require.True(t, errors.As(err, &moderationErr), "input rule is limited to output, got %v", err)
+	assert.Equal(t, ModerationStageOutput, moderationErr.Stage)
+	assert.Equal(t, []string{"secrets"}, moderationErr.Categories)
+	assert.Equal(t, 1, mockLLM.callCount)
+}
+
+// TestModerationFlagAnnotatesOutput 测试标记的内容放行，记录在Metadata中并发射事件
+func TestModerationFlagAnnotatesOutput(t *testing.T) {
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+	var mu sync.Mutex
+	var stages []string
+	require.NoError(t, eventBus.Subscribe("moderation_flagged", func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		stages = append(stages, event.(*AgentModerationFlaggedEvent).Stage)
+		return nil
+	}))
+
+	denylist, err := NewDenylistModerator(ModerationRule{Category: "medical", Terms: []string{"dosage"}, Action: ModerationFlag})
+	require.NoError(t, err)
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "Ask a doctor about the dosage."}})
+	agent := newModerationTestAgent(t, mockLLM, eventBus, DefaultModerationConfig(denylist))
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("What dosage should I take?", "Advice"))
+	require.NoError(t, err)
+	assert.Equal(t, "Ask a doctor about the dosage.", output.Raw)
+	assert.Equal(t, []ModerationAnnotation{
+		{Stage: ModerationStageInput, Categories: []string{"medical"}, Reason: "matched denylist rules"},
+		{Stage: ModerationStageOutput, Categories: []string{"medical"}, Reason: "matched denylist rules"},
+	}, output.Metadata[ModerationMetadataKey])
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(stages) == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+// TestModerationTimeout 测试不响应取消的审核器也受超时限制，FailOpen时放行
+func TestModerationTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := &funcModerator{input: func(ctx context.Context, text string) (ModerationDecision, error) {
+		<-release
+		return ModerationDecision{Action: ModerationAllow}, nil
+	}}
+
+	config := DefaultModerationConfig(slow)
+	config.Timeout = 20 * time.Millisecond
+	agent := newModerationTestAgent(t, NewExtendedMockLLM([]llm.Response{{Content: "ok"}}), nil, config)
+
+	start := time.Now()
+	_, err := agent.Execute(context.Background(), NewBaseTask("Summarize", "Summary"))
+	assert.Less(t, time.Since(start), 2*time.Second)
+	require.True(t, errors.Is(err, ErrContentModerated), "expected a moderation error, got %v", err)
+	assert.Contains(t, err.Error(), "moderation timed out")
+
+	config.FailOpen = true
+	output, err := agent.Execute(context.Background(), NewBaseTask("Summarize", "Summary"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", output.Raw)
+}
+
+// TestModerationFromContext 测试Agent没有设置审核时使用ctx中的配置
+func TestModerationFromContext(t *testing.T) {
+	denylist, err := NewDenylistModerator(ModerationRule{Category: "spam", Terms: []string{"buy now"}})
+	require.NoError(t, err)
+	agent := newModerationTestAgent(t, NewExtendedMockLLM([]llm.Response{{Content: "Buy now!"}}), nil, nil)
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Write a slogan", "A slogan"))
+	require.NoError(t, err)
+	assert.Equal(t, "Buy now!", output.Raw)
+
+	ctx := WithModeration(context.Background(), DefaultModerationConfig(denylist))
+	_, err = agent.Execute(ctx, NewBaseTask("Write a slogan", "A slogan"))
+	assert.True(t, errors.Is(err, ErrContentModerated))
+}
+
+// TestDenylistModeratorRules 测试规则的组合和无效的规则
+func TestDenylistModeratorRules(t *testing.T) {
+	moderator, err := NewDenylistModerator(
+		ModerationRule{Category: "profanity", Terms: []string{"darn"}, Action: ModerationFlag},
+		ModerationRule{Category: "violence", Pattern: `(?i)\bkill\b`},
+	)
+	require.NoError(t, err)
+
+	decision, err := moderator.CheckOutput(context.Background(), "darn, kill the process")
+	require.NoError(t, err)
+	assert.Equal(t, ModerationBlock, decision.Action)
+	assert.Equal(t, []string{"profanity", "violence"}, decision.Categories)
+
+	decision, _ = moderator.CheckInput(context.Background(), "Darn it")
+	assert.Equal(t, ModerationFlag, decision.Action)
+
+	decision, _ = moderator.CheckInput(context.Background(), "darning socks")
+	assert.Equal(t, ModerationAllow, decision.Action, "terms match whole words only")
+
+	_, err = NewDenylistModerator(ModerationRule{Category: "empty"})
+	assert.Error(t, err)
+	_, err = NewDenylistModerator(ModerationRule{Category: "bad", Pattern: "("})
+	assert.Error(t, err)
+	_, err = NewDenylistModerator(ModerationRule{Category: "bad", Terms: []string{"x"}, Action: ModerationAllow})
+	assert.Error(t, err)
+}
+
+// TestOpenAIModerator 测试OpenAI审核器的请求和响应解析
+func TestOpenAIModerator(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/moderations", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if request["input"] == "fine" {
+			w.Write([]byte(`{"results":[{"flagged":false,"categories":{"violence":false},"category_scores":{"violence":0.01}}]}`))
+			return
+		}
+		w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"harassment":true,"sexual":false},"category_scores":{"violence":0.9}}]}`))
+	}))
+	defer server.Close()
+
+	moderator := NewOpenAIModerator("test-key")
+	moderator.BaseURL = server.URL + "/v1"
+
+	decision, err := moderator.CheckInput(context.Background(), "fine")
+	require.NoError(t, err)
+	assert.Equal(t, ModerationAllow, decision.Action)
+	assert.Equal(t, DefaultOpenAIModerationModel, request["model"])
+
+	decision, err = moderator.CheckOutput(context.Background(), "threat")
+	require.NoError(t, err)
+	assert.Equal(t, ModerationBlock, decision.Action)
+	assert.Equal(t, []string{"harassment", "violence"}, decision.Categories)
+	assert.Equal(t, 0.9, decision.Scores["violence"])
+
+	moderator.FlaggedAction = ModerationFlag
+	decision, _ = moderator.CheckOutput(context.Background(), "threat")
+	assert.Equal(t, ModerationFlag, decision.Action)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	moderator.BaseURL = failing.URL
+	_, err = moderator.CheckInput(context.Background(), "fine")
+	assert.ErrorContains(t, err, "status 503")
+}
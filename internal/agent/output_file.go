@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 输出文件写入结果记录在TaskOutput.Metadata中的键
+const (
+	MetadataKeyOutputFile      = "output_file"
+	MetadataKeyOutputFileError = "output_file_error"
+)
+
+// ExpandOutputFilePath 展开输出文件路径中的{date}、{task_name}、{crew_name}模板变量
+// 变量值中的路径分隔符会被替换为下划线，避免写到预期目录之外
+func ExpandOutputFilePath(pattern string, taskName, crewName string, now time.Time) string {
+	sanitize := strings.NewReplacer("/", "_", "\\", "_")
+	return strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{task_name}", sanitize.Replace(taskName),
+		"{crew_name}", sanitize.Replace(crewName),
+	).Replace(pattern)
+}
+
+// resolveOutputFilePath 计算任务输出文件的实际路径
+// 相对路径基于Crew注入的输出目录，没有输出目录时基于当前工作目录
+func resolveOutputFilePath(task Task) string {
+	taskContext := task.GetContext()
+
+	taskName := task.GetName()
+	if taskName == "" {
+		taskName = task.GetID()
+	}
+	var crewName string
+	if name, ok := taskContext[contextKeyCrewName]; ok {
+		crewName = fmt.Sprint(name)
+	}
+
+	path := ExpandOutputFilePath(task.GetOutputFile(), taskName, crewName, time.Now())
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if dir, ok := taskContext[contextKeyOutputDirectory]; ok && fmt.Sprint(dir) != "" {
+		return filepath.Join(fmt.Sprint(dir), path)
+	}
+	return path
+}
+
+// outputFileContent 返回写入文件的内容，JSON格式的任务写入格式化后的JSON
+func outputFileContent(task Task, output *TaskOutput) ([]byte, error) {
+	if task.GetOutputFormat() == OutputFormatJSON && output.JSON != nil {
+		data, err := json.MarshalIndent(output.JSON, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON output: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+	return []byte(output.Raw), nil
+}
+
+// writeOutputFile 任务设置了输出文件时把输出写入文件
+// 写入失败不影响任务结果，只记录到Metadata并发射task_output_write_failed事件
+func (a *BaseAgent) writeOutputFile(ctx context.Context, task Task, output *TaskOutput) {
+	if task.GetOutputFile() == "" || output == nil {
+		return
+	}
+
+	path := resolveOutputFilePath(task)
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+
+	if err := writeTaskOutputFile(task, output, path); err != nil {
+		output.Metadata[MetadataKeyOutputFileError] = err.Error()
+		a.logger.Warn("Failed to write task output file",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "output_file", Value: path},
+			logger.Field{Key: "error", Value: err},
+		)
+		if a.eventBus != nil {
+			event := NewTaskOutputWriteFailedEvent(a.id, a.role, task.GetID(), path, err)
+			if emitErr := a.eventBus.Emit(ctx, a, event); emitErr != nil {
+				a.logger.Error("Failed to emit task output write failed event",
+					logger.Field{Key: "error", Value: emitErr})
+			}
+		}
+		return
+	}
+
+	output.Metadata[MetadataKeyOutputFile] = path
+	a.logger.Info("Task output written to file",
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "output_file", Value: path},
+		logger.Field{Key: "append", Value: task.IsOutputFileAppend()},
+	)
+}
+
+// WriteOutputFile 把不是由Agent执行直接产生的输出（如Consensus流程评审后的输出）写入任务的输出文件
+// 任务没有配置输出文件时什么也不做；写入的路径或错误与Agent写入时一样记录在output.Metadata中
+func WriteOutputFile(task Task, output *TaskOutput) error {
+	if task.GetOutputFile() == "" || output == nil {
+		return nil
+	}
+
+	path := resolveOutputFilePath(task)
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+	if err := writeTaskOutputFile(task, output, path); err != nil {
+		output.Metadata[MetadataKeyOutputFileError] = err.Error()
+		return err
+	}
+	output.Metadata[MetadataKeyOutputFile] = path
+	return nil
+}
+
+// writeTaskOutputFile 按任务配置覆盖或追加写入输出文件
+func writeTaskOutputFile(task Task, output *TaskOutput, path string) error {
+	content, err := outputFileContent(task, output)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); task.GetCreateDirectory() {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	if !task.IsOutputFileAppend() {
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	// 追加的输出与已有内容之间以换行分隔，并以换行结尾，避免多次执行的内容连在一起
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			content = append([]byte{'\n'}, content...)
+		}
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append output file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close output file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func TestExpandOutputFilePath(t *testing.T) {
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	path := ExpandOutputFilePath("reports/{crew_name}/{date}_{task_name}.md", "a/b", "research", now)
+	assert.Equal(t, "reports/research/2024-03-05_a_b.md", path)
+	assert.Equal(t, "plain.md", ExpandOutputFilePath("plain.md", "task", "crew", now))
+}
+
+func TestAgentWritesOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "first report"}, {Content: "second report"}})
+	agent, err := createTestAgent(mockLLM)
+	require.NoError(t, err)
+
+	task := NewTaskWithOptions("Write a report", "Report",
+		WithOutputFile("{crew_name}/{task_name}.md"),
+		WithContext(map[string]interface{}{"crew_name": "research", "output_directory": dir}),
+	)
+	task.SetName("report")
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "research", "report.md")
+	assert.Equal(t, path, output.Metadata[MetadataKeyOutputFile])
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first report", string(content))
+
+	// 追加模式下保留之前的内容
+	task.SetOutputFileAppend(true)
+	_, err = agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first report\nsecond report\n", string(content))
+}
+
+func TestAgentWritesJSONOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result.json")
+	agent, err := createTestAgent(NewExtendedMockLLM([]llm.Response{{Content: `{"score":8}`}}))
+	require.NoError(t, err)
+
+	task := NewTaskWithOptions("Score the report", "JSON score",
+		WithOutputFormat(OutputFormatJSON), WithOutputFile(path))
+	_, err = agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"score\": 8\n}\n", string(content))
+}
+
+func TestAgentOutputFileWriteFailure(t *testing.T) {
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+	var mu sync.Mutex
+	var failed []*TaskOutputWriteFailedEvent
+	_, err := eventBus.SubscribeWithOptions("task_output_write_failed", func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, event.(*TaskOutputWriteFailedEvent))
+		return nil
+	}, events.WithSyncDelivery())
+	require.NoError(t, err)
+
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write reports",
+		Backstory: "Writes",
+		LLM:       NewExtendedMockLLM([]llm.Response{{Content: "report"}}),
+		EventBus:  eventBus,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	// 不自动创建目录时写入不存在的目录会失败，但任务结果仍然有效
+	path := filepath.Join(t.TempDir(), "missing", "report.md")
+	task := NewTaskWithOptions("Write a report", "Report", WithOutputFile(path))
+	task.SetCreateDirectory(false)
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, "report", output.Raw)
+	assert.True(t, output.IsValid)
+	assert.Contains(t, output.Metadata[MetadataKeyOutputFileError], "failed to write output file")
+	assert.NotContains(t, output.Metadata, MetadataKeyOutputFile)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, failed, 1)
+	assert.Equal(t, path, failed[0].OutputFile)
+	assert.Equal(t, task.GetID(), failed[0].TaskID)
+}
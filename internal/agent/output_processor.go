@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 输出来源的类型
+const (
+	SourceKindKnowledge = "knowledge"
+	SourceKindTool      = "tool"
+)
+
+// outputProcessorErrorsMetadataKey 处理失败的输出处理器及错误，按处理器名称索引
+const outputProcessorErrorsMetadataKey = "output_processor_errors"
+
+// OutputSource 本次执行中注入提示的知识条目或LLM收到的工具结果，供输出处理器溯源
+type OutputSource struct {
+	ID       string                 `json:"id"`   // 知识条目的chunk ID；工具结果为"tool:<工具名>:<序号>"
+	Kind     string                 `json:"kind"` // SourceKindKnowledge或SourceKindTool
+	Name     string                 `json:"name"` // 知识来源（文件或知识源名称）或工具名
+	Content  string                 `json:"content"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"` // 知识条目的元数据（page、row_start、url等）或工具调用参数
+}
+
+// OutputProcessor 在LLM给出最终答案之后、写入输出文件和执行回调之前处理任务输出
+// 多个处理器按注册顺序执行，返回错误只记录日志，不影响任务输出
+type OutputProcessor interface {
+	// Name 处理器名称，用于日志和元数据
+	Name() string
+
+	// Process 处理输出，sources为本次执行使用过的知识条目和工具结果
+	Process(ctx context.Context, task Task, sources []OutputSource, output *TaskOutput) error
+}
+
+// sourceCollector 收集一次执行中注入提示的知识条目和工具结果
+type sourceCollector struct {
+	mu      sync.Mutex
+	sources []OutputSource
+	seen    map[string]bool
+}
+
+type sourceCollectorKey struct{}
+
+// withSourceCollector 返回带有新收集器的ctx，嵌套执行（如委托给同事）各自收集
+func withSourceCollector(ctx context.Context) (context.Context, *sourceCollector) {
+	collector := &sourceCollector{seen: make(map[string]bool)}
+	return context.WithValue(ctx, sourceCollectorKey{}, collector), collector
+}
+
+// sourcesFrom 返回ctx中的收集器，没有时返回nil
+func sourcesFrom(ctx context.Context) *sourceCollector {
+	collector, _ := ctx.Value(sourceCollectorKey{}).(*sourceCollector)
+	return collector
+}
+
+// add 记录来源，相同ID只记录一次
+func (c *sourceCollector) add(source OutputSource) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen[source.ID] {
+		return
+	}
+	c.seen[source.ID] = true
+	c.sources = append(c.sources, source)
+}
+
+// addToolResult 记录一次成功的工具调用结果，按工具的调用序号生成ID
+func (c *sourceCollector) addToolResult(name string, arguments map[string]interface{}, content string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	count := 0
+	for _, source := range c.sources {
+		if source.Kind == SourceKindTool && source.Name == name {
+			count++
+		}
+	}
+	c.mu.Unlock()
+
+	c.add(OutputSource{
+		ID:       fmt.Sprintf("tool:%s:%d", name, count+1),
+		Kind:     SourceKindTool,
+		Name:     name,
+		Content:  content,
+		Metadata: arguments,
+	})
+}
+
+// list 返回收集到的来源副本
+func (c *sourceCollector) list() []OutputSource {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]OutputSource(nil), c.sources...)
+}
+
+// AddOutputProcessor 在处理器列表末尾加入输出处理器
+func (a *BaseAgent) AddOutputProcessor(processor OutputProcessor) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.outputProcessors = append(a.outputProcessors, processor)
+}
+
+// GetOutputProcessors 返回输出处理器列表的副本
+func (a *BaseAgent) GetOutputProcessors() []OutputProcessor {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]OutputProcessor(nil), a.outputProcessors...)
+}
+
+// runOutputProcessors 按顺序执行输出处理器，失败或panic的处理器只记录日志和元数据，其余处理器继续执行
+func (a *BaseAgent) runOutputProcessors(ctx context.Context, task Task, output *TaskOutput) {
+	processors := a.GetOutputProcessors()
+	if len(processors) == 0 || output == nil {
+		return
+	}
+
+	sources := sourcesFrom(ctx).list()
+	for _, processor := range processors {
+		raw := output.Raw
+		if err := runOutputProcessor(ctx, processor, task, sources, output); err != nil {
+			// 失败的处理器不能丢掉答案
+			if output.Raw == "" {
+				output.Raw = raw
+			}
+			a.logger.Warn("Output processor failed",
+				logger.Field{Key: "task_id", Value: task.GetID()},
+				logger.Field{Key: "processor", Value: processor.Name()},
+				logger.Field{Key: "error", Value: err},
+			)
+			if output.Metadata == nil {
+				output.Metadata = make(map[string]interface{})
+			}
+			failures, _ := output.Metadata[outputProcessorErrorsMetadataKey].(map[string]string)
+			if failures == nil {
+				failures = make(map[string]string)
+				output.Metadata[outputProcessorErrorsMetadataKey] = failures
+			}
+			failures[processor.Name()] = err.Error()
+		}
+	}
+}
+
+// runOutputProcessor 执行单个处理器，把panic转为错误
+func runOutputProcessor(ctx context.Context, processor OutputProcessor, task Task, sources []OutputSource, output *TaskOutput) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return processor.Process(ctx, task, sources, output)
+}
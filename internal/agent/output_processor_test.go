@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// funcOutputProcessor 用函数实现的输出处理器
+type funcOutputProcessor struct {
+	name    string
+	process func(output *TaskOutput) error
+}
+
+func (p *funcOutputProcessor) Name() string { return p.name }
+func (p *funcOutputProcessor) Process(ctx context.Context, task Task, sources []OutputSource, output *TaskOutput) error {
+	return p.process(output)
+}
+
+// TestOutputProcessorsCiteSourcesAndRedact 测试引用来自注入的知识条目和工具结果，脱敏在回调之前完成
+func TestOutputProcessorsCiteSourcesAndRedact(t *testing.T) {
+	const description = "Summarize the pricing changes"
+	source := &stubKnowledgeSource{name: "docs", items: map[string][]KnowledgeItem{
+		description: {{
+			ID:       "pricing.pdf#3",
+			Content:  "Starting in March the team plan costs twelve dollars per seat each month.",
+			Source:   "pricing.pdf",
+			Metadata: map[string]interface{}{"page": 3},
+		}},
+	}}
+	fetch := NewBaseTool("fetch", "Fetch a web page", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "The enterprise tier now includes single sign-on and audit logs for every workspace.", nil
+	})
+
+	answer := "Starting in March the team plan costs twelve dollars per seat each month. " +
+		"The enterprise tier now includes single sign-on and audit logs for every workspace. " +
+		"Questions go to billing@example.com."
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{ToolCalls: []llm.ToolCall{{ID: "call_1", Type: "function", Function: llm.ToolCallFunction{
+			Name: "fetch", Arguments: `{"url": "https://example.com/pricing"}`,
+		}}}},
+		{Content: answer},
+	})
+
+	redaction, err := NewRedactionProcessor()
+	require.NoError(t, err)
+	var seenByCallback *TaskOutput
+	agent, err := NewBaseAgent(AgentConfig{
+		Role: "Analyst", Goal: "Answer with sources", Backstory: "I cite everything",
+		LLM: mockLLM, Logger: logger.NewTestLogger(),
+		Callbacks: []func(context.Context, *TaskOutput) error{func(ctx context.Context, output *TaskOutput) error {
+			seenByCallback = output
+			return nil
+		}},
+		OutputProcessors: []OutputProcessor{
+			&funcOutputProcessor{name: "broken", process: func(output *TaskOutput) error {
+				output.Raw = ""
+				return errors.New("boom")
+			}},
+			NewCitationProcessor(),
+			&funcOutputProcessor{name: "panicky", process: func(output *TaskOutput) error { panic("bad processor") }},
+			redaction,
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, agent.SetKnowledgeSources([]KnowledgeSource{source}))
+	require.NoError(t, agent.AddTool(fetch))
+
+	output, err := agent.Execute(context.Background(), NewBaseTask(description, "A short summary"))
+	require.NoError(t, err)
+
+	// 失败的处理器不会丢掉答案
+	assert.Contains(t, output.Raw, "twelve dollars per seat")
+	assert.Contains(t, output.Raw, "[REDACTED:email]")
+	assert.NotContains(t, output.Raw, "billing@example.com")
+	assert.Equal(t, map[string]int{"email": 1}, output.Metadata[redactionsMetadataKey])
+	failures := output.Metadata[outputProcessorErrorsMetadataKey].(map[string]string)
+	assert.Equal(t, "boom", failures["broken"])
+	assert.Contains(t, failures["panicky"], "bad processor")
+
+	require.Len(t, output.Citations, 2)
+	assert.Equal(t, Citation{
+		SourceID: "pricing.pdf#3", Kind: SourceKindKnowledge, Source: "pricing.pdf", Location: "page 3",
+		Span: "Starting in March the team plan costs twelve dollars per seat each month",
+	}, output.Citations[0])
+	assert.Equal(t, "tool:fetch:1", output.Citations[1].SourceID)
+	assert.Equal(t, SourceKindTool, output.Citations[1].Kind)
+	assert.Equal(t, "https://example.com/pricing", output.Citations[1].Location)
+
+	require.NotNil(t, seenByCallback)
+	assert.Len(t, seenByCallback.Citations, 2)
+	assert.NotContains(t, seenByCallback.Raw, "billing@example.com")
+}
+
+// TestCitationProcessorMatching 测试中文按字匹配、显式的[来源]引用和过短的重合不引用
+func TestCitationProcessorMatching(t *testing.T) {
+	sources := []OutputSource{
+		{ID: "faq#1", Kind: SourceKindKnowledge, Name: "faq.md", Content: "退货需要在签收后七天内提交申请并保留原包装。"},
+		{ID: "sales.csv#2", Kind: SourceKindKnowledge, Name: "sales.csv", Content: "region,total\nnorth,120",
+			Metadata: map[string]interface{}{"row_start": 2, "row_end": 5}},
+		{ID: "tool:search:1", Kind: SourceKindTool, Name: "search", Content: "the weather is nice today in Paris"},
+	}
+	output := &TaskOutput{Raw: "根据规定，退货需要在签收后七天内提交申请。北方地区的销量见[sales.csv]。The weather is nice."}
+
+	require.NoError(t, NewCitationProcessor().Process(context.Background(), nil, sources, output))
+	require.Len(t, output.Citations, 2)
+	assert.Equal(t, "退货需要在签收后七天内提交申请", output.Citations[0].Span)
+	assert.Equal(t, Citation{SourceID: "sales.csv#2", Kind: SourceKindKnowledge, Source: "sales.csv", Location: "rows 2-5", Span: "[sales.csv]"},
+		output.Citations[1])
+}
+
+// TestRedactionProcessorRules 测试自定义规则、JSON中的字符串和无效的规则
+func TestRedactionProcessorRules(t *testing.T) {
+	processor, err := NewRedactionProcessor(
+		RedactionRule{Name: "ticket", Pattern: `TICKET-\d+`, Replacement: "TICKET-***"},
+		RedactionRule{Name: "api_key", Pattern: `sk-[A-Za-z0-9]{8,}`},
+	)
+	require.NoError(t, err)
+
+	output := &TaskOutput{
+		Raw:  `{"note": "see TICKET-42 and TICKET-43", "keys": ["sk-abcdef123456"]}`,
+		JSON: map[string]interface{}{"note": "see TICKET-42 and TICKET-43", "keys": []interface{}{"sk-abcdef123456"}},
+	}
+	require.NoError(t, processor.Process(context.Background(), nil, nil, output))
+	assert.Equal(t, `{"note": "see TICKET-*** and TICKET-***", "keys": ["[REDACTED:api_key]"]}`, output.Raw)
+	assert.Equal(t, "see TICKET-*** and TICKET-***", output.JSON["note"])
+	assert.Equal(t, []interface{}{"[REDACTED:api_key]"}, output.JSON["keys"])
+	assert.Equal(t, map[string]int{"ticket": 4, "api_key": 2}, output.Metadata[redactionsMetadataKey])
+
+	_, err = NewRedactionProcessor(RedactionRule{Name: "bad", Pattern: "("})
+	assert.Error(t, err)
+}
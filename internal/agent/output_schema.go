@@ -0,0 +1,578 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// OutputSchema 任务结构化输出的模式定义
+// 对标Python版本的output_json / output_pydantic：既可以由Go结构体通过反射生成，
+// 也可以直接使用JSON Schema map
+type OutputSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+
+	// goType 由结构体生成时记录的类型，用于将输出解析为类型化的值
+	goType reflect.Type
+}
+
+// 结构化输出的约束方式，记录在TaskOutput.Metadata["format_enforcement"]中
+const (
+	FormatEnforcementNative = "native_json_schema" // LLM原生的JSON Schema模式
+	FormatEnforcementPrompt = "prompt"             // 提示中的格式说明加修正重试
+)
+
+// refusalMetadataKey 模型拒绝回答时记录拒绝说明的元数据键
+const refusalMetadataKey = "refusal"
+
+// NewOutputSchema 使用JSON Schema创建输出模式
+func NewOutputSchema(name string, schema map[string]interface{}) *OutputSchema {
+	return &OutputSchema{Name: name, Schema: schema}
+}
+
+// NewOutputSchemaFromStruct 通过反射从Go结构体生成输出模式
+// 字段名取自json标签，未标记omitempty的非指针字段视为必填，description标签作为字段描述
+func NewOutputSchemaFromStruct(v interface{}) (*OutputSchema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("output schema requires a struct type, got %T", v)
+	}
+
+	return &OutputSchema{
+		Name:   t.Name(),
+		Schema: schemaForType(t),
+		goType: t,
+	}, nil
+}
+
+// FormatInstructions 返回追加到任务提示中的格式说明
+func (s *OutputSchema) FormatInstructions() string {
+	return s.formatInstructions(defaultPromptStrings())
+}
+
+// formatInstructions 按指定语言的提示词返回格式说明
+func (s *OutputSchema) formatInstructions(prompts PromptStrings) string {
+	schemaJSON, err := json.MarshalIndent(s.Schema, "", "  ")
+	if err != nil {
+		schemaJSON = []byte("{}")
+	}
+	return prompts.OutputFormat + "\n" + string(schemaJSON)
+}
+
+// Parse 解析并校验LLM输出
+// 返回类型化的值（结构体模式下为指向该结构体的指针，否则为解码后的JSON值）、
+// JSON对象（若输出为对象）以及校验错误列表
+func (s *OutputSchema) Parse(content string) (interface{}, map[string]interface{}, []string) {
+	cleaned := extractJSONPayload(content)
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(cleaned), &data); err != nil {
+		return nil, nil, []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	if errs := validateSchemaValue(data, s.Schema, "$"); len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	jsonMap, _ := data.(map[string]interface{})
+
+	if s.goType == nil {
+		return data, jsonMap, nil
+	}
+
+	typed := reflect.New(s.goType)
+	if err := json.Unmarshal([]byte(cleaned), typed.Interface()); err != nil {
+		return nil, nil, []string{fmt.Sprintf("cannot decode into %s: %v", s.goType.Name(), err)}
+	}
+	return typed.Interface(), jsonMap, nil
+}
+
+// extractJSONPayload 去除markdown代码块，并在有多余文字时截取JSON主体
+func extractJSONPayload(content string) string {
+	content = strings.TrimSpace(content)
+
+	// 代码块可能出现在说明文字之后
+	if idx := strings.Index(content, "```"); idx > 0 {
+		content = content[idx:]
+	}
+	content = stripCodeFence(content)
+
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		return content
+	}
+
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return content
+	}
+	closing := "}"
+	if content[start] == '[' {
+		closing = "]"
+	}
+	if end := strings.LastIndex(content, closing); end > start {
+		return content[start : end+1]
+	}
+	return content
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaForType 为Go类型生成JSON Schema
+func schemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaForType(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		required := make([]string, 0)
+		collectStructFields(t, properties, &required)
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		// interface{}等任意类型不做约束
+		return map[string]interface{}{}
+	}
+}
+
+// collectStructFields 收集结构体字段（包括匿名嵌入字段）到properties中
+func collectStructFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue // 未导出字段
+		}
+
+		name, omitEmpty, skip := parseJSONTag(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectStructFields(embedded, properties, required)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := schemaForType(field.Type)
+		if description := field.Tag.Get("description"); description != "" {
+			fieldSchema["description"] = description
+		}
+		properties[name] = fieldSchema
+
+		if !omitEmpty && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// parseJSONTag 解析json标签，返回字段名、是否omitempty以及是否跳过
+func parseJSONTag(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, false
+}
+
+// validateSchemaValue 按JSON Schema的常用子集校验值
+// 支持type、properties、required、additionalProperties、items和enum
+func validateSchemaValue(value interface{}, schema map[string]interface{}, path string) []string {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	var errs []string
+
+	if expected, ok := schema["type"]; ok {
+		types := schemaTypes(expected)
+		if len(types) > 0 && !matchesAnyType(value, types) {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))}
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		found := false
+		for _, candidate := range enum {
+			if reflect.DeepEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: value %v is not one of %v", path, value, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+
+		for _, name := range stringList(schema["required"]) {
+			if _, present := v[name]; !present {
+				errs = append(errs, fmt.Sprintf("%s: missing required field %q", path, name))
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fieldPath := path + "." + key
+			if propSchema, ok := properties[key].(map[string]interface{}); ok {
+				errs = append(errs, validateSchemaValue(v[key], propSchema, fieldPath)...)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					errs = append(errs, fmt.Sprintf("%s: unexpected field", fieldPath))
+				}
+			case map[string]interface{}:
+				errs = append(errs, validateSchemaValue(v[key], additional, fieldPath)...)
+			}
+		}
+	case []interface{}:
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				errs = append(errs, validateSchemaValue(item, itemSchema, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// schemaTypes 读取schema中的type（字符串或字符串数组）
+func schemaTypes(value interface{}) []string {
+	switch t := value.(type) {
+	case string:
+		return []string{t}
+	default:
+		return stringList(value)
+	}
+}
+
+// stringList 将[]string或[]interface{}转换为字符串列表
+func stringList(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// matchesAnyType 判断值是否匹配任一JSON类型
+func matchesAnyType(value interface{}, types []string) bool {
+	actual := jsonTypeName(value)
+	for _, expected := range types {
+		if expected == actual {
+			return true
+		}
+		if expected == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeName 返回解码后JSON值的类型名称
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case float32:
+		return jsonTypeName(float64(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		// 直接从Go代码传入的整数参数
+		return "integer"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// nativeResponseFormat LLM支持原生JSON Schema模式时返回对应的响应格式，否则返回nil使用提示约束
+// 只有模式满足严格模式的要求时才开启strict
+func (a *BaseAgent) nativeResponseFormat(schema *OutputSchema) *llm.ResponseFormat {
+	supporter, ok := a.llmProvider.(llm.ResponseFormatSupporter)
+	if !ok || !supporter.SupportsResponseFormat(llm.ResponseFormatJSONSchema) {
+		return nil
+	}
+	return llm.JSONSchemaResponseFormat(responseFormatName(schema.Name), schema.Schema, isStrictSchema(schema.Schema))
+}
+
+// responseFormatName 把模式名称转换为原生模式允许的名称（字母、数字、下划线和连字符，最长64个字符）
+func responseFormatName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "output"
+	}
+	if b.Len() > 64 {
+		return b.String()[:64]
+	}
+	return b.String()
+}
+
+// isStrictSchema 判断模式是否满足严格模式：所有对象都禁止额外属性且所有属性都是必填
+func isStrictSchema(schema map[string]interface{}) bool {
+	if len(schema) == 0 {
+		return false
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		if additional, ok := schema["additionalProperties"].(bool); !ok || additional {
+			return false
+		}
+		required := make(map[string]bool)
+		for _, name := range schemaRequiredFields(schema["required"]) {
+			required[name] = true
+		}
+		for name, property := range properties {
+			propertySchema, ok := property.(map[string]interface{})
+			if !required[name] || !ok || !isStrictSchema(propertySchema) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		return isStrictSchema(items)
+	}
+	if schema["type"] == "object" {
+		// 没有固定属性的对象（如map）无法满足严格模式
+		return false
+	}
+	return schema["type"] != nil || schema["enum"] != nil
+}
+
+// schemaRequiredFields 返回required字段列表，兼容[]string和[]interface{}
+func schemaRequiredFields(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// formatEnforcement 返回调用选项使用的结构化输出约束方式
+func formatEnforcement(callOptions *llm.CallOptions) string {
+	if callOptions != nil {
+		if format, err := llm.ParseResponseFormat(callOptions.ResponseFormat); err == nil && format != nil && format.Type == llm.ResponseFormatJSONSchema {
+			return FormatEnforcementNative
+		}
+	}
+	return FormatEnforcementPrompt
+}
+
+// enforceOutputSchema 按任务的输出模式校验输出，失败时请求LLM修正JSON
+// 对标Python版本converter的重试逻辑：每次修正都会引用校验错误，最多重试MaxOutputFixAttempts次
+// 模型拒绝回答时不再修正，直接把输出标记为无效
+func (a *BaseAgent) enforceOutputSchema(ctx context.Context, task Task, schema *OutputSchema, messages []llm.Message, callOptions *llm.CallOptions, output *TaskOutput) {
+	output.Metadata["format_enforcement"] = formatEnforcement(callOptions)
+	if refusal, _ := output.Metadata[refusalMetadataKey].(string); refusal != "" {
+		a.markRefused(task, schema, output, refusal, 0)
+		return
+	}
+
+	content := output.Raw
+	parsed, jsonMap, errs := schema.Parse(content)
+
+	// 修正调用不需要工具
+	var fixOptions *llm.CallOptions
+	if callOptions != nil {
+		optionsCopy := *callOptions
+		optionsCopy.Tools = nil
+		optionsCopy.ToolChoice = nil
+		optionsCopy.Stream = false
+		fixOptions = &optionsCopy
+	}
+
+	attempts := 0
+	var contextStats contextTrimStats
+	defer contextStats.apply(output)
+	for len(errs) > 0 && attempts < a.executionConfig.MaxOutputFixAttempts {
+		attempts++
+
+		a.logger.Warn("Task output does not match schema, requesting a fix",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "schema", Value: schema.Name},
+			logger.Field{Key: "attempt", Value: attempts},
+			logger.Field{Key: "errors", Value: errs},
+		)
+
+		fixMessages := make([]llm.Message, len(messages), len(messages)+2)
+		copy(fixMessages, messages)
+		fixMessages = append(fixMessages,
+			llm.Message{Role: llm.RoleAssistant, Content: content},
+			llm.Message{Role: llm.RoleUser, Content: buildOutputFixPrompt(a.prompts, schema, errs)},
+		)
+
+		response, trim, err := a.callLLMWithRetry(ctx, task, fixMessages, fixOptions)
+		if err != nil {
+			a.logger.Error("Output fix call failed",
+				logger.Field{Key: "task_id", Value: task.GetID()},
+				logger.Field{Key: "error", Value: err},
+			)
+			break
+		}
+
+		addUsageToOutput(output, response.Usage)
+		contextStats.add(trim)
+
+		if response.Refusal != "" {
+			a.markRefused(task, schema, output, response.Refusal, attempts)
+			return
+		}
+
+		content = response.Content
+		parsed, jsonMap, errs = schema.Parse(content)
+	}
+
+	output.Raw = content
+	output.Summary = a.generateSummary(content)
+	output.Metadata["output_schema"] = schema.Name
+	output.Metadata["output_fix_attempts"] = attempts
+
+	if len(errs) > 0 {
+		output.IsValid = false
+		output.ValidationError = strings.Join(errs, "; ")
+		return
+	}
+
+	output.IsValid = true
+	output.ValidationError = ""
+	output.Parsed = parsed
+	if jsonMap != nil {
+		output.JSON = jsonMap
+		output.OutputFormat = OutputFormatJSON
+	}
+	if task.GetOutputFormat() == OutputFormatPydantic {
+		output.Pydantic = parsed
+		output.OutputFormat = OutputFormatPydantic
+	}
+}
+
+// markRefused 模型拒绝按模式回答时把输出标记为无效，拒绝说明作为原始输出
+func (a *BaseAgent) markRefused(task Task, schema *OutputSchema, output *TaskOutput, refusal string, attempts int) {
+	a.logger.Warn("Model refused to produce structured output",
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "schema", Value: schema.Name},
+		logger.Field{Key: "refusal", Value: refusal},
+	)
+
+	output.Raw = refusal
+	output.Summary = a.generateSummary(refusal)
+	output.Metadata[refusalMetadataKey] = refusal
+	output.Metadata["output_schema"] = schema.Name
+	output.Metadata["output_fix_attempts"] = attempts
+	output.IsValid = false
+	output.ValidationError = "model refused to respond: " + refusal
+	output.Parsed = nil
+}
+
+// buildOutputFixPrompt 构建要求LLM修正JSON的提示
+func buildOutputFixPrompt(prompts PromptStrings, schema *OutputSchema, errs []string) string {
+	var b strings.Builder
+	b.WriteString(prompts.OutputFixErrors + "\n")
+	for _, err := range errs {
+		b.WriteString("- ")
+		b.WriteString(err)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(schema.formatInstructions(prompts))
+	return b.String()
+}
@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+type schemaTestAuthor struct {
+	Name string `json:"name"`
+}
+
+type schemaTestReport struct {
+	Title    string             `json:"title" description:"Report title"`
+	Score    int                `json:"score"`
+	Tags     []string           `json:"tags,omitempty"`
+	Authors  []schemaTestAuthor `json:"authors"`
+	Reviewer *schemaTestAuthor  `json:"reviewer"`
+	internal string
+}
+
+// TestNewOutputSchemaFromStruct 测试从结构体生成JSON Schema
+func TestNewOutputSchemaFromStruct(t *testing.T) {
+	schema, err := NewOutputSchemaFromStruct(&schemaTestReport{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "schemaTestReport", schema.Name)
+	assert.Equal(t, "object", schema.Schema["type"])
+	assert.Equal(t, []string{"title", "score", "authors"}, schema.Schema["required"])
+
+	properties := schema.Schema["properties"].(map[string]interface{})
+	assert.Len(t, properties, 5)
+	assert.Equal(t, map[string]interface{}{"type": "string", "description": "Report title"}, properties["title"])
+	assert.Equal(t, "integer", properties["score"].(map[string]interface{})["type"])
+
+	authors := properties["authors"].(map[string]interface{})
+	assert.Equal(t, "array", authors["type"])
+	assert.Equal(t, "object", authors["items"].(map[string]interface{})["type"])
+
+	_, err = NewOutputSchemaFromStruct("not a struct")
+	assert.Error(t, err)
+}
+
+// TestOutputSchemaParse 测试输出解析与校验
+func TestOutputSchemaParse(t *testing.T) {
+	schema, err := NewOutputSchemaFromStruct(schemaTestReport{})
+	require.NoError(t, err)
+
+	t.Run("code fence", func(t *testing.T) {
+		parsed, jsonMap, errs := schema.Parse("Here you go:\n```json\n{\"title\": \"Go\", \"score\": 9, \"authors\": [{\"name\": \"Rob\"}]}\n```")
+		require.Empty(t, errs)
+
+		report, ok := parsed.(*schemaTestReport)
+		require.True(t, ok)
+		assert.Equal(t, "Go", report.Title)
+		assert.Equal(t, 9, report.Score)
+		assert.Equal(t, "Rob", report.Authors[0].Name)
+		assert.Equal(t, "Go", jsonMap["title"])
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		_, _, errs := schema.Parse(`{"title": 5, "score": 1.5, "authors": [{"name": true}]}`)
+		assert.ElementsMatch(t, []string{
+			"$.authors[0].name: expected string, got boolean",
+			"$.score: expected integer, got number",
+			"$.title: expected string, got integer",
+		}, errs)
+	})
+
+	t.Run("missing required", func(t *testing.T) {
+		_, _, errs := schema.Parse(`{"title": "Go"}`)
+		assert.Equal(t, []string{`$: missing required field "score"`, `$: missing required field "authors"`}, errs)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, _, errs := schema.Parse(`{"title": "Go",`)
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0], "invalid JSON")
+	})
+
+	t.Run("json schema map", func(t *testing.T) {
+		mapSchema := NewOutputSchema("sentiment", map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"label": map[string]interface{}{"type": "string", "enum": []interface{}{"positive", "negative"}},
+			},
+			"required":             []interface{}{"label"},
+			"additionalProperties": false,
+		})
+
+		parsed, _, errs := mapSchema.Parse(`{"label": "positive"}`)
+		require.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{"label": "positive"}, parsed)
+
+		_, _, errs = mapSchema.Parse(`{"label": "neutral", "extra": 1}`)
+		assert.Len(t, errs, 2)
+	})
+}
+
+// TestAgentEnforcesOutputSchema 测试Agent在输出不符合模式时请求修正
+func TestAgentEnforcesOutputSchema(t *testing.T) {
+	var prompts []string
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: `{"title": "Go", "score": "high"}`, Usage: llm.Usage{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10}},
+		{Content: "```json\n{\"title\": \"Go\", \"score\": 9, \"authors\": []}\n```", Usage: llm.Usage{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6}},
+	}).WithCallHandler(func(messages []llm.Message) {
+		prompts = append(prompts, messages[len(messages)-1].Content.(string))
+	})
+
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Analyst",
+		Goal:      "Produce reports",
+		Backstory: "Structured thinker",
+		LLM:       mockLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	schema, err := NewOutputSchemaFromStruct(schemaTestReport{})
+	require.NoError(t, err)
+	task := NewTaskWithOptions("Write a report", "A report", WithOutputSchema(schema))
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[0], "JSON Schema")
+	assert.Contains(t, prompts[1], "$.score: expected integer, got string")
+	assert.Contains(t, prompts[1], `missing required field "authors"`)
+
+	assert.True(t, output.IsValid)
+	assert.Empty(t, output.ValidationError)
+	report, ok := output.Parsed.(*schemaTestReport)
+	require.True(t, ok)
+	assert.Equal(t, 9, report.Score)
+	assert.Equal(t, float64(9), output.JSON["score"])
+	assert.Equal(t, 16, output.TokensUsed)
+	assert.Equal(t, 11, output.PromptTokens)
+	assert.Equal(t, 5, output.CompletionTokens)
+	assert.Equal(t, 1, output.Metadata["output_fix_attempts"])
+	assert.Equal(t, FormatEnforcementPrompt, output.Metadata["format_enforcement"])
+}
+
+// TestAgentOutputSchemaFixExhausted 测试修正次数耗尽后输出标记为无效
+func TestAgentOutputSchemaFixExhausted(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "not json at all"}})
+
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Analyst",
+		Goal:      "Produce reports",
+		Backstory: "Structured thinker",
+		LLM:       mockLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	config := DefaultExecutionConfig()
+	config.MaxOutputFixAttempts = 2
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	schema, err := NewOutputSchemaFromStruct(schemaTestReport{})
+	require.NoError(t, err)
+	task := NewTaskWithOptions("Write a report", "A report", WithOutputSchema(schema))
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, mockLLM.callCount)
+	assert.False(t, output.IsValid)
+	assert.Contains(t, output.ValidationError, "invalid JSON")
+	assert.Nil(t, output.Parsed)
+}
+
+// structuredOutputLLM 支持原生JSON Schema模式的模拟LLM，记录每次调用的选项
+type structuredOutputLLM struct {
+	*ExtendedMockLLM
+	options []*llm.CallOptions
+}
+
+func (m *structuredOutputLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	m.options = append(m.options, options)
+	return m.ExtendedMockLLM.Call(ctx, messages, options)
+}
+
+func (m *structuredOutputLLM) SupportsResponseFormat(formatType string) bool {
+	return formatType == llm.ResponseFormatJSONSchema
+}
+
+// TestAgentNativeOutputFormat 测试LLM支持时使用原生JSON Schema模式
+func TestAgentNativeOutputFormat(t *testing.T) {
+	sentiment := NewOutputSchema("sentiment result", map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{"label": map[string]interface{}{"type": "string", "enum": []interface{}{"positive", "negative"}}},
+		"required":             []interface{}{"label"},
+		"additionalProperties": false,
+	})
+
+	newAgent := func(t *testing.T, responses []llm.Response) (*BaseAgent, *structuredOutputLLM) {
+		mockLLM := &structuredOutputLLM{ExtendedMockLLM: NewExtendedMockLLM(responses)}
+		agent, err := NewBaseAgent(AgentConfig{
+			Role:      "Analyst",
+			Goal:      "Classify feedback",
+			Backstory: "Structured thinker",
+			LLM:       mockLLM,
+			Logger:    logger.NewTestLogger(),
+		})
+		require.NoError(t, err)
+		return agent, mockLLM
+	}
+
+	t.Run("strict schema", func(t *testing.T) {
+		agent, mockLLM := newAgent(t, []llm.Response{{Content: `{"label": "positive"}`}})
+		task := NewTaskWithOptions("Classify: great product", "A label", WithOutputSchema(sentiment))
+
+		output, err := agent.Execute(context.Background(), task)
+		require.NoError(t, err)
+
+		require.Len(t, mockLLM.options, 1)
+		format, ok := mockLLM.options[0].ResponseFormat.(*llm.ResponseFormat)
+		require.True(t, ok)
+		assert.Equal(t, llm.ResponseFormatJSONSchema, format.Type)
+		assert.Equal(t, "sentiment_result", format.JSONSchema.Name)
+		assert.True(t, format.JSONSchema.Strict)
+
+		assert.True(t, output.IsValid)
+		assert.Equal(t, "positive", output.JSON["label"])
+		assert.Equal(t, FormatEnforcementNative, output.Metadata["format_enforcement"])
+	})
+
+	t.Run("generated schema is not strict", func(t *testing.T) {
+		agent, mockLLM := newAgent(t, []llm.Response{{Content: `{"title": "Go", "score": 9, "authors": []}`}})
+		schema, err := NewOutputSchemaFromStruct(schemaTestReport{})
+		require.NoError(t, err)
+
+		_, err = agent.Execute(context.Background(), NewTaskWithOptions("Write a report", "A report", WithOutputSchema(schema)))
+		require.NoError(t, err)
+		format := mockLLM.options[0].ResponseFormat.(*llm.ResponseFormat)
+		assert.False(t, format.JSONSchema.Strict)
+	})
+
+	t.Run("refusal", func(t *testing.T) {
+		agent, mockLLM := newAgent(t, []llm.Response{{Refusal: "I can't classify this content."}})
+		task := NewTaskWithOptions("Classify: something harmful", "A label", WithOutputSchema(sentiment))
+
+		output, err := agent.Execute(context.Background(), task)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, mockLLM.callCount, "refusals are not retried")
+		assert.False(t, output.IsValid)
+		assert.Equal(t, "model refused to respond: I can't classify this content.", output.ValidationError)
+		assert.Equal(t, "I can't classify this content.", output.Raw)
+		assert.Equal(t, "I can't classify this content.", output.Metadata["refusal"])
+		assert.Nil(t, output.Parsed)
+	})
+}
+
+// TestIsStrictSchema 测试严格模式的判断
+func TestIsStrictSchema(t *testing.T) {
+	strictObject := map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+		"required":             []string{"name"},
+		"additionalProperties": false,
+	}
+	assert.True(t, isStrictSchema(strictObject))
+	assert.True(t, isStrictSchema(map[string]interface{}{"type": "array", "items": strictObject}))
+
+	optional := map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+		"additionalProperties": false,
+	}
+	assert.False(t, isStrictSchema(optional))
+	assert.False(t, isStrictSchema(map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}}))
+	assert.False(t, isStrictSchema(map[string]interface{}{}))
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Crew注入任务上下文时使用的键
+const (
+	contextKeyAggregated      = "aggregated_context"
+	contextKeyPreviousContext = "previous_tasks_context"
+	contextKeyPreviousOutput  = "previous_tasks_output"
+	contextKeyLastOutput      = "last_task_output"
+	contextKeyLastJSON        = "last_task_json"
+	contextKeyCrewName        = "crew_name"
+	contextKeyCrewProcess     = "crew_process"
+	contextKeyTotalTasks      = "total_tasks"
+	contextKeyCompletedTasks  = "completed_tasks"
+	contextKeyOutputDirectory = "output_directory"
+	contextKeyCrewFingerprint = "crew_fingerprint"
+	contextKeyConversation    = "conversation_history"
+	contextKeyCrewMemories    = "crew_memories"
+)
+
+// crewContextKeys 由Crew维护的上下文键，不作为普通输入渲染
+var crewContextKeys = map[string]bool{
+	contextKeyAggregated:      true,
+	contextKeyPreviousContext: true,
+	contextKeyPreviousOutput:  true,
+	contextKeyLastOutput:      true,
+	contextKeyLastJSON:        true,
+	contextKeyCrewName:        true,
+	contextKeyCrewProcess:     true,
+	contextKeyTotalTasks:      true,
+	contextKeyCompletedTasks:  true,
+	contextKeyOutputDirectory: true,
+	contextKeyCrewFingerprint: true,
+	contextKeyConversation:    true,
+	contextKeyCrewMemories:    true,
+}
+
+const (
+	contextSectionBegin = "=== BEGIN CONTEXT ==="
+	contextSectionEnd   = "=== END CONTEXT ==="
+	truncatedMarker     = "... [truncated]"
+)
+
+// renderTaskContext 将任务上下文渲染为提示中的Context部分，标题使用prompts中的文本
+// 包含crew信息、任务进度、会话历史、Crew记忆、初始输入和前序任务输出，超长的值按maxLength截断
+func renderTaskContext(taskContext map[string]interface{}, maxLength int, prompts PromptStrings) string {
+	if len(taskContext) == 0 {
+		return ""
+	}
+
+	var sections []string
+
+	// crew信息
+	var crewLines []string
+	if name, ok := taskContext[contextKeyCrewName]; ok && fmt.Sprint(name) != "" {
+		line := fmt.Sprintf(prompts.Crew, name)
+		if process, ok := taskContext[contextKeyCrewProcess]; ok {
+			line += fmt.Sprintf(prompts.CrewProcess, process)
+		}
+		crewLines = append(crewLines, line)
+	}
+	if completed, ok := taskContext[contextKeyCompletedTasks]; ok {
+		if total, ok := taskContext[contextKeyTotalTasks]; ok {
+			crewLines = append(crewLines, fmt.Sprintf(prompts.CompletedTasksOf, completed, total))
+		} else {
+			crewLines = append(crewLines, fmt.Sprintf(prompts.CompletedTasks, completed))
+		}
+	}
+	if len(crewLines) > 0 {
+		sections = append(sections, strings.Join(crewLines, "\n"))
+	}
+
+	// 会话中之前的对话
+	if conversation, ok := taskContext[contextKeyConversation].(string); ok && conversation != "" {
+		sections = append(sections, prompts.Conversation+"\n"+conversation)
+	}
+
+	// 之前执行保存的Crew记忆
+	if memories, ok := taskContext[contextKeyCrewMemories].(string); ok && memories != "" {
+		sections = append(sections, prompts.CrewMemories+"\n"+memories)
+	}
+
+	// 初始输入及其他上下文
+	inputKeys := make([]string, 0, len(taskContext))
+	for key := range taskContext {
+		if !crewContextKeys[key] {
+			inputKeys = append(inputKeys, key)
+		}
+	}
+	sort.Strings(inputKeys)
+	if len(inputKeys) > 0 {
+		var builder strings.Builder
+		builder.WriteString(prompts.Inputs)
+		for _, key := range inputKeys {
+			builder.WriteString(fmt.Sprintf("\n- %s: %s", key, truncateContextValue(formatContextValue(taskContext[key]), maxLength)))
+		}
+		sections = append(sections, builder.String())
+	}
+
+	// 前序任务输出
+	previous := ""
+	if aggregated, ok := taskContext[contextKeyAggregated].(string); ok {
+		previous = aggregated
+	} else if last, ok := taskContext[contextKeyLastOutput].(string); ok {
+		previous = last
+	}
+	if previous != "" {
+		sections = append(sections, prompts.PreviousTaskOutputs+"\n"+truncateContextValue(previous, maxLength))
+	}
+
+	if len(sections) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s\n%s\n%s\n%s", prompts.Context, contextSectionBegin, strings.Join(sections, "\n\n"), contextSectionEnd)
+}
+
+// formatContextValue 将上下文值格式化为字符串
+func formatContextValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// truncateContextValue 按字符数截断上下文值，maxLength<=0表示不截断
+func truncateContextValue(value string, maxLength int) string {
+	if maxLength <= 0 {
+		return value
+	}
+
+	runes := []rune(value)
+	if len(runes) <= maxLength {
+		return value
+	}
+	return string(runes[:maxLength]) + truncatedMarker
+}
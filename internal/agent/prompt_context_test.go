@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// TestRenderTaskContext 测试任务上下文渲染
+func TestRenderTaskContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		context     map[string]interface{}
+		maxLength   int
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:    "empty context",
+			context: map[string]interface{}{},
+		},
+		{
+			name: "crew context",
+			context: map[string]interface{}{
+				"crew_name":          "research_crew",
+				"crew_process":       "sequential",
+				"total_tasks":        3,
+				"completed_tasks":    1,
+				"aggregated_context": "first output",
+				"last_task_output":   "first output",
+				"topic":              "AI",
+			},
+			contains: []string{
+				"Context:",
+				contextSectionBegin,
+				"Crew: research_crew (sequential process)",
+				"Completed Tasks: 1 of 3",
+				"Inputs:\n- topic: AI",
+				"Previous Task Outputs:\nfirst output",
+				contextSectionEnd,
+			},
+			notContains: []string{"last_task_output", "aggregated_context"},
+		},
+		{
+			name: "structured input values",
+			context: map[string]interface{}{
+				"filters": map[string]interface{}{"lang": "go"},
+			},
+			contains: []string{`- filters: {"lang":"go"}`},
+		},
+		{
+			name: "truncated values",
+			context: map[string]interface{}{
+				"aggregated_context": strings.Repeat("x", 50),
+				"notes":              strings.Repeat("y", 50),
+			},
+			maxLength: 10,
+			contains: []string{
+				strings.Repeat("x", 10) + truncatedMarker,
+				strings.Repeat("y", 10) + truncatedMarker,
+			},
+			notContains: []string{strings.Repeat("x", 11), strings.Repeat("y", 11)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered := renderTaskContext(tt.context, tt.maxLength, defaultPromptStrings())
+			if len(tt.contains) == 0 {
+				assert.Empty(t, rendered)
+			}
+			for _, s := range tt.contains {
+				assert.Contains(t, rendered, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, rendered, s)
+			}
+		})
+	}
+}
+
+// TestTaskContextInPrompt 测试任务上下文被注入到提示中
+func TestTaskContextInPrompt(t *testing.T) {
+	var capturedPrompt string
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "done"}})
+	mockLLM.WithCallHandler(func(messages []llm.Message) {
+		capturedPrompt, _ = messages[len(messages)-1].Content.(string)
+	})
+
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Context Agent",
+		Goal:      "Use context",
+		Backstory: "I read my context carefully",
+		LLM:       mockLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	config := DefaultExecutionConfig()
+	config.MaxContextLength = 20
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	task := NewBaseTask("Summarize the research", "A summary")
+	task.SetContext(map[string]interface{}{
+		"crew_name":          "crew",
+		"completed_tasks":    1,
+		"total_tasks":        2,
+		"aggregated_context": "The research found that " + strings.Repeat("a", 100),
+	})
+
+	_, err = agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Contains(t, capturedPrompt, "Summarize the research")
+	assert.Contains(t, capturedPrompt, "Completed Tasks: 1 of 2")
+	assert.Contains(t, capturedPrompt, "The research found t"+truncatedMarker)
+}
@@ -0,0 +1,340 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// DefaultInjectionThreshold 检测分数达到该值时视为提示注入
+const DefaultInjectionThreshold = 0.5
+
+// PromptInjectionMetadataKey TaskOutput.Metadata中记录注入检测结果的键，值为[]InjectionDetection
+const PromptInjectionMetadataKey = "prompt_injection"
+
+// SourceKindMemory 记忆系统检索到的外部内容
+const SourceKindMemory = "memory"
+
+// droppedExternalContent 整段丢弃的外部内容在提示中的占位文本
+const droppedExternalContent = "[content removed: possible prompt injection]"
+
+// PromptInjectionPolicy 外部内容被判定为提示注入时的处理方式
+type PromptInjectionPolicy string
+
+const (
+	PromptInjectionFlag  PromptInjectionPolicy = "flag"  // 保留内容，记录检测结果并发射prompt_injection_detected事件
+	PromptInjectionStrip PromptInjectionPolicy = "strip" // 删除检测器给出的片段，没有片段时整段丢弃
+	PromptInjectionDrop  PromptInjectionPolicy = "drop"  // 整段丢弃
+)
+
+// InjectionVerdict 一次注入检测的结果
+type InjectionVerdict struct {
+	Score   float64  `json:"score"`             // 0-1，越高越像写给模型的指令
+	Reasons []string `json:"reasons,omitempty"` // 命中的规则或分类器给出的原因
+	Spans   []string `json:"spans,omitempty"`   // 像指令的原文片段，strip策略删除这些片段
+}
+
+// InjectionDetector 检测工具结果、知识条目和记忆中试图操纵Agent的指令
+type InjectionDetector interface {
+	Detect(ctx context.Context, content string) (InjectionVerdict, error)
+}
+
+// PromptInjectionConfig 提示注入防护配置
+// 设置后工具结果、知识条目和记忆都包在<external_content>标记中，并在系统提示中声明标记内的内容只是数据
+type PromptInjectionConfig struct {
+	Detectors []InjectionDetector   // 依次检测每段外部内容并取最高分，为空时只加分隔标记；检测出错时记录警告并放行
+	Policy    PromptInjectionPolicy // 判定为注入时的处理方式，为空时为PromptInjectionFlag
+	Threshold float64               // 分数达到该值时判定为注入，<=0时使用DefaultInjectionThreshold
+}
+
+// DefaultPromptInjectionConfig 返回使用内置正则规则、只标记不删除的默认配置
+func DefaultPromptInjectionConfig() *PromptInjectionConfig {
+	return &PromptInjectionConfig{
+		Detectors: []InjectionDetector{defaultRegexInjectionDetector},
+		Policy:    PromptInjectionFlag,
+		Threshold: DefaultInjectionThreshold,
+	}
+}
+
+// InjectionDetection 被判定为注入的外部内容，记录在TaskOutput.Metadata["prompt_injection"]中
+type InjectionDetection struct {
+	Source  string                `json:"source"`         // SourceKindMemory、SourceKindKnowledge或SourceKindTool
+	Name    string                `json:"name,omitempty"` // 知识来源或工具名
+	Score   float64               `json:"score"`
+	Reasons []string              `json:"reasons,omitempty"`
+	Action  PromptInjectionPolicy `json:"action"` // 对内容采取的处理
+}
+
+type promptInjectionKey struct{}
+
+// WithPromptInjection 返回带有注入防护配置的ctx，之后执行的Agent在自身没有设置时使用该配置
+func WithPromptInjection(ctx context.Context, config *PromptInjectionConfig) context.Context {
+	return context.WithValue(ctx, promptInjectionKey{}, config)
+}
+
+// PromptInjectionFrom 返回ctx中的注入防护配置，没有时返回nil
+func PromptInjectionFrom(ctx context.Context) *PromptInjectionConfig {
+	config, _ := ctx.Value(promptInjectionKey{}).(*PromptInjectionConfig)
+	return config
+}
+
+// promptInjectionConfig 返回本次执行使用的注入防护配置，Agent自身的设置优先于ctx中的设置，都没有时返回nil
+func (a *BaseAgent) promptInjectionConfig(ctx context.Context) *PromptInjectionConfig {
+	if a.promptInjection != nil {
+		return a.promptInjection
+	}
+	return PromptInjectionFrom(ctx)
+}
+
+// InjectionRule 一条注入检测规则
+type InjectionRule struct {
+	Name    string `json:"name"`    // 命中时报告的原因
+	Pattern string `json:"pattern"` // 正则表达式
+}
+
+// defaultInjectionRules 内置的注入检测规则，覆盖常见的"忽略之前的指令"、角色改写、伪造角色标记和数据外泄话术
+var defaultInjectionRules = []InjectionRule{
+	{Name: "ignore_instructions", Pattern: `(?i)\b(?:ignore|disregard|forget|override)\b[^.\n]{0,40}?\b(?:previous|prior|above|earlier|preceding|all|your|the)\b[^.\n]{0,20}?\b(?:instructions?|prompts?|rules|directions|guidelines)\b`},
+	{Name: "ignore_instructions", Pattern: `忽略(?:之前|以上|前面|上面|所有)[^。\n]{0,10}(?:指令|指示|提示|规则)`},
+	{Name: "new_instructions", Pattern: `(?i)\b(?:new|updated|real|actual) (?:system )?instructions?\s*:`},
+	{Name: "role_override", Pattern: `(?i)\byou are now\b|\bfrom now on,? you (?:are|will|must|should)\b`},
+	{Name: "fake_role_marker", Pattern: `(?im)^\s*(?:\[(?:system|assistant)\]|(?:system|assistant)\s*:)|<\|im_start\|>|<\|system\|>`},
+	{Name: "prompt_leak", Pattern: `(?i)\b(?:reveal|print|show|repeat|output|disclose)\b[^.\n]{0,30}?\b(?:system prompt|hidden instructions|initial instructions|your instructions)\b`},
+	{Name: "exfiltration", Pattern: `(?i)\b(?:send|post|upload|forward|email|leak|exfiltrate)\b[^.\n]{0,60}?\b(?:api[ _-]?keys?|passwords?|credentials|secrets?|access tokens?|conversation history|system prompt)\b|\bexfiltrat\w*`},
+}
+
+// DefaultInjectionRules 返回内置检测规则的副本，可以在此基础上增删后传给NewRegexInjectionDetector
+func DefaultInjectionRules() []InjectionRule {
+	return append([]InjectionRule(nil), defaultInjectionRules...)
+}
+
+// RegexInjectionDetector 按正则规则在本地检测注入，不依赖外部服务
+// 命中任一规则时分数为1，命中的整行作为strip策略删除的片段
+type RegexInjectionDetector struct {
+	names    []string
+	patterns []*regexp.Regexp
+}
+
+var _ InjectionDetector = (*RegexInjectionDetector)(nil)
+
+// defaultRegexInjectionDetector 使用内置规则的检测器
+var defaultRegexInjectionDetector = mustRegexInjectionDetector(defaultInjectionRules)
+
+// NewRegexInjectionDetector 创建正则检测器，不传规则时使用内置规则
+func NewRegexInjectionDetector(rules ...InjectionRule) (*RegexInjectionDetector, error) {
+	if len(rules) == 0 {
+		rules = defaultInjectionRules
+	}
+
+	detector := &RegexInjectionDetector{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("injection rule name cannot be empty")
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for injection rule %s: %w", rule.Name, err)
+		}
+		detector.names = append(detector.names, rule.Name)
+		detector.patterns = append(detector.patterns, pattern)
+	}
+	return detector, nil
+}
+
+func mustRegexInjectionDetector(rules []InjectionRule) *RegexInjectionDetector {
+	detector, err := NewRegexInjectionDetector(rules...)
+	if err != nil {
+		panic(err)
+	}
+	return detector
+}
+
+// Detect 实现InjectionDetector接口
+func (d *RegexInjectionDetector) Detect(_ context.Context, content string) (InjectionVerdict, error) {
+	var verdict InjectionVerdict
+	seenReasons := make(map[string]bool)
+	seenSpans := make(map[string]bool)
+	for i, pattern := range d.patterns {
+		matches := pattern.FindAllStringIndex(content, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		verdict.Score = 1
+		if name := d.names[i]; !seenReasons[name] {
+			seenReasons[name] = true
+			verdict.Reasons = append(verdict.Reasons, name)
+		}
+		for _, match := range matches {
+			if span := lineAround(content, match[0], match[1]); span != "" && !seenSpans[span] {
+				seenSpans[span] = true
+				verdict.Spans = append(verdict.Spans, span)
+			}
+		}
+	}
+	return verdict, nil
+}
+
+// lineAround 返回包含[start, end)的完整行
+func lineAround(content string, start, end int) string {
+	lineStart := strings.LastIndexByte(content[:start], '\n') + 1
+	lineEnd := len(content)
+	if i := strings.IndexByte(content[end:], '\n'); i >= 0 {
+		lineEnd = end + i
+	}
+	return strings.TrimSpace(content[lineStart:lineEnd])
+}
+
+// externalContentTagPattern 匹配外部内容中伪造的分隔标记，避免内容提前闭合标记
+var externalContentTagPattern = regexp.MustCompile(`(?i)<\s*/?\s*external_content\b[^>]*>`)
+
+// delimitExternalContent 把外部内容包在<external_content>标记中，内容里的同名标记被替换掉
+func delimitExternalContent(kind, name, content string) string {
+	content = externalContentTagPattern.ReplaceAllString(content, "[external_content tag removed]")
+	attributes := fmt.Sprintf("source=%q", kind)
+	if name != "" {
+		attributes += fmt.Sprintf(" name=%q", name)
+	}
+	return fmt.Sprintf("<external_content %s>\n%s\n</external_content>", attributes, content)
+}
+
+// injectionGuard 一次执行的注入防护：分隔、检测外部内容并收集检测结果
+type injectionGuard struct {
+	agent  *BaseAgent
+	task   Task
+	config *PromptInjectionConfig
+
+	mu         sync.Mutex
+	detections []InjectionDetection
+}
+
+type injectionGuardKey struct{}
+
+// withInjectionGuard 配置了注入防护时返回带有本次执行防护的ctx，否则返回nil
+func (a *BaseAgent) withInjectionGuard(ctx context.Context, task Task) (context.Context, *injectionGuard) {
+	config := a.promptInjectionConfig(ctx)
+	if config == nil {
+		return ctx, nil
+	}
+	guard := &injectionGuard{agent: a, task: task, config: config}
+	return context.WithValue(ctx, injectionGuardKey{}, guard), guard
+}
+
+// injectionGuardFrom 返回ctx中的防护，没有时返回nil
+func injectionGuardFrom(ctx context.Context) *injectionGuard {
+	guard, _ := ctx.Value(injectionGuardKey{}).(*injectionGuard)
+	return guard
+}
+
+// screen 检测一段外部内容并按策略处理，返回包在分隔标记中的内容；没有防护时原样返回
+func (g *injectionGuard) screen(ctx context.Context, kind, name, content string) string {
+	if g == nil {
+		return content
+	}
+
+	verdict := g.detect(ctx, content)
+	if verdict.Score >= g.threshold() {
+		policy := g.config.Policy
+		if policy == "" {
+			policy = PromptInjectionFlag
+		}
+		switch policy {
+		case PromptInjectionStrip:
+			if len(verdict.Spans) == 0 {
+				content = droppedExternalContent
+			} else {
+				for _, span := range verdict.Spans {
+					content = strings.ReplaceAll(content, span, "[removed]")
+				}
+			}
+		case PromptInjectionDrop:
+			content = droppedExternalContent
+		}
+		g.record(ctx, InjectionDetection{Source: kind, Name: name, Score: verdict.Score, Reasons: verdict.Reasons, Action: policy})
+	}
+	return delimitExternalContent(kind, name, content)
+}
+
+// detect 依次调用检测器，返回分数最高的结果，原因和片段合并
+func (g *injectionGuard) detect(ctx context.Context, content string) InjectionVerdict {
+	var result InjectionVerdict
+	for _, detector := range g.config.Detectors {
+		if detector == nil {
+			continue
+		}
+		verdict, err := detector.Detect(ctx, content)
+		if err != nil {
+			g.agent.logger.Warn("Prompt injection detector failed, content is not screened by it",
+				logger.Field{Key: "task_id", Value: g.task.GetID()},
+				logger.Field{Key: "error", Value: err},
+			)
+			continue
+		}
+		result.Score = max(result.Score, verdict.Score)
+		result.Reasons = append(result.Reasons, verdict.Reasons...)
+		result.Spans = append(result.Spans, verdict.Spans...)
+	}
+	return result
+}
+
+func (g *injectionGuard) threshold() float64 {
+	if g.config.Threshold <= 0 {
+		return DefaultInjectionThreshold
+	}
+	return g.config.Threshold
+}
+
+// record 记录检测结果并发射prompt_injection_detected事件
+func (g *injectionGuard) record(ctx context.Context, detection InjectionDetection) {
+	g.mu.Lock()
+	g.detections = append(g.detections, detection)
+	g.mu.Unlock()
+
+	a := g.agent
+	a.logger.Warn("Possible prompt injection in external content",
+		logger.Field{Key: "task_id", Value: g.task.GetID()},
+		logger.Field{Key: "source", Value: detection.Source},
+		logger.Field{Key: "name", Value: detection.Name},
+		logger.Field{Key: "reasons", Value: detection.Reasons},
+		logger.Field{Key: "action", Value: detection.Action},
+	)
+	if a.eventBus != nil {
+		event := NewPromptInjectionDetectedEvent(a.id, a.role, g.task.GetID(), detection)
+		if err := a.eventBus.Emit(ctx, a, event); err != nil {
+			a.logger.Warn("Failed to emit prompt injection detected event", logger.Field{Key: "error", Value: err})
+		}
+	}
+}
+
+// addInstructions 在系统提示中声明分隔标记内的内容只是数据，没有系统消息时插入一条
+func (g *injectionGuard) addInstructions(messages []llm.Message) []llm.Message {
+	if g == nil {
+		return messages
+	}
+	instructions := g.agent.prompts.ExternalContent
+	if len(messages) > 0 && messages[0].Role == llm.RoleSystem {
+		messages[0].Content = llm.ContentText(messages[0].Content) + "\n\n" + instructions
+		return messages
+	}
+	return append([]llm.Message{{Role: llm.RoleSystem, Content: instructions}}, messages...)
+}
+
+// apply 把检测结果写入输出的Metadata
+func (g *injectionGuard) apply(output *TaskOutput) {
+	if g == nil || output == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.detections) == 0 {
+		return
+	}
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+	output.Metadata[PromptInjectionMetadataKey] = append([]InjectionDetection(nil), g.detections...)
+}
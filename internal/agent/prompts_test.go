@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// executeAndCapture 执行任务并返回发送给LLM的系统提示和用户提示
+func executeAndCapture(t *testing.T, config AgentConfig, task Task) (string, string) {
+	t.Helper()
+
+	var system, user string
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "done"}})
+	mockLLM.WithCallHandler(func(messages []llm.Message) {
+		system, _ = messages[0].Content.(string)
+		user, _ = messages[len(messages)-1].Content.(string)
+	})
+
+	config.Goal = "Explain things"
+	config.Backstory = "Seasoned writer"
+	config.LLM = mockLLM
+	config.Logger = logger.NewTestLogger()
+	agent, err := NewBaseAgent(config)
+	require.NoError(t, err)
+
+	_, err = agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	return system, user
+}
+
+func TestPromptDefaultsUnchanged(t *testing.T) {
+	system, user := executeAndCapture(t, AgentConfig{Role: "Writer"}, NewBaseTask("Write a haiku", "A haiku"))
+
+	assert.Equal(t, "You are Writer.\n\nYour goal: Explain things\n\nYour backstory: Seasoned writer\n\n"+
+		"You are working with a team of other agents to complete complex tasks. Always provide detailed, accurate responses "+
+		"based on your role and expertise. Use the available tools when necessary and be precise in your reasoning.", system)
+	assert.Equal(t, "Write a haiku\n\nExpected Output: A haiku", user)
+}
+
+func TestPromptMarkdownInstruction(t *testing.T) {
+	_, user := executeAndCapture(t, AgentConfig{Role: "Writer"}, NewTaskWithOptions("Write a haiku", "A haiku", WithMarkdown(true)))
+	assert.Equal(t, "Write a haiku\n\nExpected Output: A haiku\n\nFormat your final answer in Markdown.", user)
+}
+
+func TestPromptTemplates(t *testing.T) {
+	t.Run("legacy placeholders and custom vars", func(t *testing.T) {
+		system, _ := executeAndCapture(t, AgentConfig{
+			Role:           "Writer",
+			SystemTemplate: "{role} writing for {{.Vars.audience}} about {{.Task}}",
+			PromptVars:     map[string]interface{}{"audience": "children"},
+		}, NewBaseTask("space", "A story"))
+
+		assert.Equal(t, "Writer writing for children about space", system)
+	})
+
+	t.Run("prompt template wraps the built prompt", func(t *testing.T) {
+		task := NewBaseTask("Write a haiku", "A haiku")
+		task.SetContext(map[string]interface{}{"season": "autumn"})
+		_, user := executeAndCapture(t, AgentConfig{
+			Role:           "Writer",
+			PromptTemplate: "<user>{{.Prompt}}</user> season={{.Context.season}}",
+		}, task)
+
+		assert.Equal(t, "<user>Write a haiku\n\nExpected Output: A haiku\n\nContext:\n"+contextSectionBegin+
+			"\nInputs:\n- season: autumn\n"+contextSectionEnd+"</user> season=autumn", user)
+	})
+
+	t.Run("invalid templates fail at construction", func(t *testing.T) {
+		base := AgentConfig{Role: "Writer", Goal: "Explain things", Backstory: "Seasoned writer", LLM: NewMockLLM(nil, false)}
+
+		config := base
+		config.SystemTemplate = "You are {{.Role"
+		_, err := NewBaseAgent(config)
+		assert.ErrorContains(t, err, "invalid system template")
+
+		config = base
+		config.PromptTemplate = "{{.Unknown}}"
+		_, err = NewBaseAgent(config)
+		assert.ErrorContains(t, err, "invalid prompt template")
+
+		config = base
+		config.PromptLocale = "fr"
+		_, err = NewBaseAgent(config)
+		assert.ErrorContains(t, err, "unsupported prompt locale")
+	})
+}
+
+func TestPromptLocaleZH(t *testing.T) {
+	task := NewBaseTask("写一首俳句", "一首俳句")
+	task.SetContext(map[string]interface{}{"completed_tasks": 1, "total_tasks": 2})
+	system, user := executeAndCapture(t, AgentConfig{Role: "作家", PromptLocale: PromptLocaleZH}, task)
+
+	assert.Contains(t, system, "你是作家。")
+	assert.Contains(t, user, "期望输出：一首俳句")
+	assert.Contains(t, user, "已完成任务：1/2")
+	assert.NotContains(t, user, "Expected Output")
+}
+
+func TestRegisterPromptLocale(t *testing.T) {
+	require.NoError(t, RegisterPromptLocale("test-locale", PromptStrings{ExpectedOutput: "Attendu : %s"}))
+
+	prompts, err := GetPromptStrings("test-locale")
+	require.NoError(t, err)
+	assert.Equal(t, "Attendu : %s", prompts.ExpectedOutput)
+	assert.Equal(t, defaultPromptStrings().AvailableTools, prompts.AvailableTools)
+
+	assert.Error(t, RegisterPromptLocale("broken", PromptStrings{SystemTemplate: "{{.Role"}))
+}
@@ -127,6 +127,9 @@ type ReActTrace struct {
 
 	// IterationCount 实际迭代次数
 	IterationCount int `json:"iteration_count"`
+
+	// Usage 所有LLM调用累计的token用量和成本
+	Usage llm.Usage `json:"usage"`
 }
 
 // ReActParser ReAct格式解析器接口
@@ -379,7 +382,7 @@ func (e *StandardReActExecutor) ExecuteReAct(ctx context.Context, agent Agent, t
 		}
 
 		// 调用LLM
-		response, err := e.callLLM(ctx, agent, initialPrompt, trace)
+		response, err := e.callLLM(ctx, agent, task, initialPrompt, trace)
 		if err != nil {
 			return trace, fmt.Errorf("LLM call failed at iteration %d: %w", trace.IterationCount, err)
 		}
@@ -458,9 +461,9 @@ func (e *StandardReActExecutor) ExecuteStep(ctx context.Context, agent Agent, st
 		return fmt.Errorf("tool execution failed: %w", err)
 	}
 
-	// 将结果转换为观察结果
+	// 将结果转换为观察结果，开启提示注入防护时检测并包在分隔标记中
 	if result != nil {
-		step.Observation = fmt.Sprintf("%v", result)
+		step.Observation = injectionGuardFrom(ctx).screen(ctx, SourceKindTool, step.Action, fmt.Sprintf("%v", result))
 	} else {
 		step.Observation = "Tool executed successfully with no output"
 	}
@@ -534,25 +537,42 @@ func (e *StandardReActExecutor) buildReActPrompt(task Task, toolCtx *ToolExecuti
 }
 
 // callLLM 调用LLM获取响应
-func (e *StandardReActExecutor) callLLM(ctx context.Context, agent Agent, prompt string, trace *ReActTrace) (string, error) {
+func (e *StandardReActExecutor) callLLM(ctx context.Context, agent Agent, task Task, prompt string, trace *ReActTrace) (string, error) {
 	llmProvider := agent.GetLLM()
 	if llmProvider == nil {
 		return "", fmt.Errorf("no LLM provider available")
 	}
 
-	// 构建消息
-	messages := []llm.Message{
-		{
-			Role:    llm.RoleUser,
-			Content: prompt,
-		},
+	// 构建消息，开启提示注入防护时附加说明分隔标记的系统消息
+	messages := injectionGuardFrom(ctx).addInstructions([]llm.Message{taskUserMessage(task, prompt)})
+
+	wait, err := agent.GetRPMController().Acquire(ctx)
+	if err != nil {
+		return "", err
 	}
+	callStatsFrom(ctx).record(func(stats *LLMCallStats) {
+		stats.Calls++
+		if wait > 0 {
+			stats.ThrottledRequests++
+			stats.RateLimitWait += wait
+		}
+	})
 
 	// 调用LLM
-	response, err := llmProvider.Call(ctx, messages, &llm.CallOptions{})
+	options := &llm.CallOptions{}
+	reproducibilityFrom(ctx).applyCallOptions(options)
+	response, err := llmProvider.Call(ctx, messages, options)
 	if err != nil {
 		return "", err
 	}
+	reproducibilityFrom(ctx).observeResponse(response)
+
+	if trace != nil {
+		trace.Usage.PromptTokens += response.Usage.PromptTokens
+		trace.Usage.CompletionTokens += response.Usage.CompletionTokens
+		trace.Usage.TotalTokens += response.Usage.TotalTokens
+		trace.Usage.Cost += response.Usage.Cost
+	}
 
 	return response.Content, nil
 }
@@ -22,7 +22,7 @@ func TestReActBasicIntegration(t *testing.T) {
 		{
 			Content: "Thought: This is a simple test\nFinal Answer: Integration test completed successfully",
 			Model:   "test-model",
-			Usage:   llm.Usage{TotalTokens: 30},
+			Usage:   llm.Usage{PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30, Cost: 0.02},
 		},
 	})
 
@@ -84,6 +84,14 @@ func TestReActBasicIntegration(t *testing.T) {
 		assert.Equal(t, "Integration test completed successfully", trace.FinalOutput)
 		assert.Contains(t, output.Metadata, "mode")
 		assert.Equal(t, "react", output.Metadata["mode"])
+
+		// token用量和成本从LLM响应累计
+		assert.Equal(t, 30, trace.Usage.TotalTokens)
+		assert.Equal(t, 30, output.TokensUsed)
+		assert.InDelta(t, 0.02, output.Cost, 1e-9)
+		stats := agent.GetExecutionStats()
+		assert.Equal(t, 30, stats.TokensUsed)
+		assert.InDelta(t, 0.02, stats.TotalCost, 1e-9)
 	})
 }
 
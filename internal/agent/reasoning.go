@@ -0,0 +1,239 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// defaultMaxReasoningAttempts ExecutionConfig.MaxReasoningAttempts未设置时的最大规划次数
+const defaultMaxReasoningAttempts = 3
+
+// readinessPattern 匹配计划末尾的自我评估，如"READY: ..."或"NOT READY: 缺少..."
+var readinessPattern = regexp.MustCompile(`(?im)^[ \t*#>-]*(NOT[ \t]+READY|READY)\b[ \t*:：-]*(.*)$`)
+
+// DefaultReasoningHandler 默认的推理处理器，对标Python的AgentReasoning
+// 执行任务前让Agent的LLM制定分步计划并评估是否已准备好执行（READY/NOT READY），
+// 未准备好时带着缺少的信息改进计划，最多尝试ExecutionConfig.MaxReasoningAttempts次；
+// 达到次数仍未准备好时照常执行，FinalReady为false
+type DefaultReasoningHandler struct {
+	LLM llm.LLM // 可选，为空时使用Agent的LLM
+}
+
+// NewDefaultReasoningHandler 创建默认推理处理器，model为nil时使用Agent的LLM
+func NewDefaultReasoningHandler(model llm.LLM) *DefaultReasoningHandler {
+	return &DefaultReasoningHandler{LLM: model}
+}
+
+// reasoningSession 一次HandleReasoning中的任务和Agent，RefinePlan通过ctx获取
+type reasoningSession struct {
+	task  Task
+	agent Agent
+	usage *llm.Usage
+}
+
+type reasoningSessionKey struct{}
+
+func reasoningSessionFrom(ctx context.Context) *reasoningSession {
+	session, _ := ctx.Value(reasoningSessionKey{}).(*reasoningSession)
+	return session
+}
+
+// HandleReasoning 制定计划并迭代改进，直到准备好或达到最大尝试次数
+func (h *DefaultReasoningHandler) HandleReasoning(ctx context.Context, task Task, agent Agent) (*ReasoningOutput, error) {
+	startTime := time.Now()
+	session := &reasoningSession{task: task, agent: agent, usage: &llm.Usage{}}
+	ctx = context.WithValue(ctx, reasoningSessionKey{}, session)
+
+	maxAttempts := agent.GetExecutionConfig().MaxReasoningAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxReasoningAttempts
+	}
+
+	plan, err := h.CreatePlan(ctx, task, agent)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 2; !h.IsReady(plan) && attempt <= maxAttempts; attempt++ {
+		if eventBus := agent.GetEventBus(); eventBus != nil {
+			event := NewAgentReasoningStartedEvent(agent.GetID(), agent.GetRole(), task.GetID(), attempt)
+			if err := eventBus.Emit(ctx, agent, event); err != nil {
+				agent.GetLogger().Error("Failed to emit agent reasoning started event",
+					logger.Field{Key: "error", Value: err})
+			}
+		}
+
+		feedback, _ := plan.Metadata["missing"].(string)
+		if plan, err = h.RefinePlan(ctx, plan, feedback); err != nil {
+			return nil, err
+		}
+	}
+
+	if !plan.Ready {
+		agent.GetLogger().Warn("Agent is not ready after reasoning, proceeding with the current plan",
+			logger.Field{Key: "agent", Value: agent.GetRole()},
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "attempts", Value: plan.Iterations},
+			logger.Field{Key: "missing", Value: plan.Metadata["missing"]},
+		)
+	}
+
+	return &ReasoningOutput{
+		Plan:       *plan,
+		Success:    true,
+		Duration:   time.Since(startTime),
+		Iterations: plan.Iterations,
+		FinalReady: plan.Ready,
+		Usage:      *session.usage,
+		Metadata:   map[string]interface{}{"max_attempts": maxAttempts},
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// CreatePlan 让LLM为任务制定第一版计划
+func (h *DefaultReasoningHandler) CreatePlan(ctx context.Context, task Task, agent Agent) (*ReasoningPlan, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "You are %s.\nGoal: %s\nBackstory: %s\n\n", agent.GetRole(), agent.GetGoal(), agent.GetBackstory())
+	prompt.WriteString("Before starting the task below, create a step-by-step plan for how you will complete it.\n\n")
+	writeReasoningTask(&prompt, task, agent)
+	prompt.WriteString(reasoningReadinessInstruction)
+
+	plan, err := h.callPlanner(ctx, agent, prompt.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reasoning plan: %w", err)
+	}
+	plan.Iterations = 1
+	return plan, nil
+}
+
+// RefinePlan 根据未准备好的原因改进计划，只能在HandleReasoning中调用
+func (h *DefaultReasoningHandler) RefinePlan(ctx context.Context, plan *ReasoningPlan, feedback string) (*ReasoningPlan, error) {
+	session := reasoningSessionFrom(ctx)
+	if session == nil {
+		return nil, fmt.Errorf("refining a plan requires the context passed by HandleReasoning")
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "You are %s.\n\n", session.agent.GetRole())
+	writeReasoningTask(&prompt, session.task, session.agent)
+	fmt.Fprintf(&prompt, "\nYour current plan:\n%s\n\n", plan.Plan)
+	if feedback != "" {
+		fmt.Fprintf(&prompt, "You were not ready to execute it because: %s\n\n", feedback)
+	}
+	prompt.WriteString("Refine the plan so that it addresses what is missing, filling gaps with reasonable assumptions where needed.\n")
+	prompt.WriteString(reasoningReadinessInstruction)
+
+	refined, err := h.callPlanner(ctx, session.agent, prompt.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to refine reasoning plan: %w", err)
+	}
+	refined.Refined = true
+	refined.Iterations = plan.Iterations + 1
+	return refined, nil
+}
+
+// IsReady 检查计划是否已准备好执行
+func (h *DefaultReasoningHandler) IsReady(plan *ReasoningPlan) bool {
+	return plan != nil && plan.Ready
+}
+
+// GetPlanSteps 返回计划中的编号步骤
+func (h *DefaultReasoningHandler) GetPlanSteps(plan *ReasoningPlan) []ReasoningStep {
+	if plan == nil {
+		return nil
+	}
+	return plan.Steps
+}
+
+// reasoningReadinessInstruction 要求计划以准备情况的自我评估结尾
+const reasoningReadinessInstruction = "\nWrite the plan as a numbered list of steps. " +
+	"Then, on the last line, assess whether you are ready to execute the task: " +
+	"write \"READY\" if you have everything you need, or \"NOT READY: <what is missing>\" if you are not."
+
+// writeReasoningTask 写入任务描述、期望输出和可用工具
+func writeReasoningTask(prompt *strings.Builder, task Task, agent Agent) {
+	fmt.Fprintf(prompt, "Task: %s\n", task.GetDescription())
+	if expectedOutput := task.GetExpectedOutput(); expectedOutput != "" {
+		fmt.Fprintf(prompt, "Expected Output: %s\n", expectedOutput)
+	}
+	tools := task.GetTools()
+	if len(tools) == 0 {
+		tools = agent.GetTools()
+	}
+	if len(tools) > 0 {
+		prompt.WriteString("Available tools:\n")
+		for _, tool := range tools {
+			fmt.Fprintf(prompt, "- %s: %s\n", tool.GetName(), tool.GetDescription())
+		}
+	}
+}
+
+// callPlanner 调用规划LLM并解析计划，token用量累加到本次推理
+func (h *DefaultReasoningHandler) callPlanner(ctx context.Context, agent Agent, prompt string) (*ReasoningPlan, error) {
+	model := h.LLM
+	if model == nil {
+		model = agent.GetLLM()
+	}
+	if model == nil {
+		return nil, fmt.Errorf("no LLM available for reasoning")
+	}
+
+	if err := acquireRPMRequest(ctx, agent.GetRPMController()); err != nil {
+		return nil, err
+	}
+	temperature := 0.0
+	options := &llm.CallOptions{Temperature: &temperature}
+	reproducibilityFrom(ctx).applyCallOptions(options)
+	response, err := model.Call(ctx, []llm.Message{{Role: llm.RoleUser, Content: prompt}}, options)
+	if err != nil {
+		return nil, err
+	}
+	if session := reasoningSessionFrom(ctx); session != nil {
+		session.usage.PromptTokens += response.Usage.PromptTokens
+		session.usage.CompletionTokens += response.Usage.CompletionTokens
+		session.usage.TotalTokens += response.Usage.TotalTokens
+		session.usage.Cost += response.Usage.Cost
+	}
+
+	return parseReasoningPlan(response.Content), nil
+}
+
+// parseReasoningPlan 拆分计划正文和最后的准备情况评估，没有评估时视为未准备好
+func parseReasoningPlan(content string) *ReasoningPlan {
+	plan := &ReasoningPlan{
+		Plan:      strings.TrimSpace(content),
+		Metadata:  make(map[string]interface{}),
+		CreatedAt: time.Now(),
+	}
+
+	matches := readinessPattern.FindAllStringSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		plan.Metadata["missing"] = "the plan did not include a readiness assessment"
+	} else {
+		last := matches[len(matches)-1]
+		verdict := strings.ToUpper(strings.Join(strings.Fields(content[last[2]:last[3]]), " "))
+		plan.Ready = verdict == "READY"
+		if !plan.Ready {
+			plan.Metadata["missing"] = strings.TrimSpace(content[last[4]:last[5]])
+		}
+		plan.Plan = strings.TrimSpace(content[:last[0]])
+	}
+
+	for i, line := range strings.Split(plan.Plan, "\n") {
+		line = strings.TrimSpace(line)
+		if !listMarkerPattern.MatchString(line) {
+			continue
+		}
+		plan.Steps = append(plan.Steps, ReasoningStep{
+			ID:          fmt.Sprintf("step_%d", len(plan.Steps)+1),
+			Description: listMarkerPattern.ReplaceAllString(line, ""),
+			Metadata:    map[string]interface{}{"line": i + 1},
+		})
+	}
+	return plan
+}
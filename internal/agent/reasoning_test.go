@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// warnRecordingLogger 记录警告消息的测试日志
+type warnRecordingLogger struct {
+	logger.Logger
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (l *warnRecordingLogger) Warn(msg string, fields ...logger.Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, msg)
+}
+
+// newReasoningTestAgent 创建开启推理的Agent，返回每次LLM调用的用户提示
+func newReasoningTestAgent(t *testing.T, responses []llm.Response, maxAttempts int, log logger.Logger) (*BaseAgent, *[]string) {
+	t.Helper()
+
+	var prompts []string
+	model := NewExtendedMockLLM(responses).WithCallHandler(func(messages []llm.Message) {
+		prompts = append(prompts, messages[len(messages)-1].Content.(string))
+	})
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write release notes",
+		Backstory: "Writes for developers",
+		LLM:       model,
+		Logger:    log,
+	})
+	require.NoError(t, err)
+
+	config := DefaultExecutionConfig()
+	config.EnableReasoning = true
+	config.MaxReasoningAttempts = maxAttempts
+	require.NoError(t, agent.SetExecutionConfig(config))
+	return agent, &prompts
+}
+
+// TestParseReasoningPlan 测试拆分计划正文、步骤和准备情况评估
+func TestParseReasoningPlan(t *testing.T) {
+	plan := parseReasoningPlan("1. Collect the merged PRs\n2) Group them by area\n\n**NOT READY:** need the release version")
+	assert.False(t, plan.Ready)
+	assert.Equal(t, "need the release version", plan.Metadata["missing"])
+	assert.Equal(t, "1. Collect the merged PRs\n2) Group them by area", plan.Plan)
+	require.Len(t, plan.Steps, 2)
+	assert.Equal(t, "Group them by area", plan.Steps[1].Description)
+
+	plan = parseReasoningPlan("1. Ready the changelog\n2. Publish\nREADY")
+	assert.True(t, plan.Ready)
+	assert.Equal(t, "1. Ready the changelog\n2. Publish", plan.Plan)
+
+	plan = parseReasoningPlan("Just do it")
+	assert.False(t, plan.Ready)
+	assert.Equal(t, "Just do it", plan.Plan)
+}
+
+// TestReasoningPlanReachesLLM 测试计划追加到任务描述中并且推理的token计入统计
+func TestReasoningPlanReachesLLM(t *testing.T) {
+	agent, prompts := newReasoningTestAgent(t, []llm.Response{
+		{Content: "1. Collect the merged PRs\n2. Summarize them\nREADY", Usage: llm.Usage{PromptTokens: 40, CompletionTokens: 20, TotalTokens: 60}},
+		{Content: "Release notes", Usage: llm.Usage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100}},
+	}, 3, logger.NewTestLogger())
+
+	task := NewTaskWithOptions("Write the release notes", "Markdown notes")
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	require.Len(t, *prompts, 2)
+	assert.Contains(t, (*prompts)[0], "Task: Write the release notes")
+	assert.Contains(t, (*prompts)[1], "Write the release notes\n\nReasoning Plan:\n1. Collect the merged PRs\n2. Summarize them")
+	assert.NotContains(t, (*prompts)[1], "READY")
+
+	assert.Equal(t, 160, output.TokensUsed)
+	assert.Equal(t, 160, agent.GetExecutionStats().TokensUsed)
+	assert.Equal(t, 160, agent.GetUsageMetrics().TotalTokens)
+}
+
+// TestReasoningProceedsWhenNotReady 测试达到最大次数仍未准备好时带着当前计划继续执行
+func TestReasoningProceedsWhenNotReady(t *testing.T) {
+	log := &warnRecordingLogger{Logger: logger.NewTestLogger()}
+	agent, prompts := newReasoningTestAgent(t, []llm.Response{
+		{Content: "1. Guess the audience\nNOT READY: need the target audience"},
+		{Content: "1. Assume developers\nNOT READY: still missing the version"},
+		{Content: "Release notes"},
+	}, 2, log)
+
+	task := NewTaskWithOptions("Write the release notes", "Markdown notes")
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, "Release notes", output.Raw)
+
+	require.Len(t, *prompts, 3)
+	assert.Contains(t, (*prompts)[1], "You were not ready to execute it because: need the target audience")
+	assert.Contains(t, (*prompts)[2], "Reasoning Plan:\n1. Assume developers")
+	assert.Contains(t, log.warnings, "Agent is not ready after reasoning, proceeding with the current plan")
+
+	// 直接调用处理器时返回未准备好的结果
+	agent, _ = newReasoningTestAgent(t, []llm.Response{
+		{Content: "1. Guess\nNOT READY: need more"},
+	}, 2, logger.NewTestLogger())
+	result, err := NewDefaultReasoningHandler(nil).HandleReasoning(context.Background(), NewTaskWithOptions("Write", "Notes"), agent)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.False(t, result.FinalReady)
+	assert.Equal(t, 2, result.Iterations)
+	assert.True(t, result.Plan.Refined)
+}
+
+// TestReasoningRefinePlanRequiresSession 测试在HandleReasoning之外改进计划时返回错误
+func TestReasoningRefinePlanRequiresSession(t *testing.T) {
+	_, err := NewDefaultReasoningHandler(nil).RefinePlan(context.Background(), &ReasoningPlan{Plan: "1. Start"}, "")
+	assert.Error(t, err)
+}
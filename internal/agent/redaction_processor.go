@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// redactionsMetadataKey 脱敏记录在输出元数据中的键，值为每条规则替换的次数
+const redactionsMetadataKey = "redactions"
+
+// RedactionRule 一条脱敏规则
+type RedactionRule struct {
+	Name        string `json:"name"`        // 规则名称，记录在元数据中
+	Pattern     string `json:"pattern"`     // 正则表达式
+	Replacement string `json:"replacement"` // 替换文本，为空时使用"[REDACTED:<规则名称>]"
+}
+
+// DefaultRedactionRules 返回常见密钥和个人信息的脱敏规则
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{
+		{Name: "email", Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+		{Name: "openai_api_key", Pattern: `\bsk-[A-Za-z0-9_-]{20,}`},
+		{Name: "aws_access_key", Pattern: `\bAKIA[0-9A-Z]{16}\b`},
+		{Name: "bearer_token", Pattern: `(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`},
+		{Name: "private_key", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	}
+}
+
+// compiledRedactionRule 编译后的脱敏规则
+type compiledRedactionRule struct {
+	name        string
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// RedactionProcessor 按正则替换输出中的密钥和个人信息
+// 处理Raw、Summary、JSON中的字符串和引用的Span，元数据只记录每条规则的替换次数，不记录原文
+type RedactionProcessor struct {
+	rules []compiledRedactionRule
+}
+
+var _ OutputProcessor = (*RedactionProcessor)(nil)
+
+// NewRedactionProcessor 创建脱敏处理器，没有提供规则时使用DefaultRedactionRules
+func NewRedactionProcessor(rules ...RedactionRule) (*RedactionProcessor, error) {
+	if len(rules) == 0 {
+		rules = DefaultRedactionRules()
+	}
+
+	processor := &RedactionProcessor{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("redaction rule name cannot be empty")
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for redaction rule %s: %w", rule.Name, err)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED:" + rule.Name + "]"
+		}
+		processor.rules = append(processor.rules, compiledRedactionRule{name: rule.Name, pattern: pattern, replacement: replacement})
+	}
+	return processor, nil
+}
+
+// Name 实现OutputProcessor接口
+func (p *RedactionProcessor) Name() string {
+	return "redaction"
+}
+
+// Process 实现OutputProcessor接口
+func (p *RedactionProcessor) Process(ctx context.Context, task Task, sources []OutputSource, output *TaskOutput) error {
+	counts := make(map[string]int)
+	redact := func(text string) string {
+		for _, rule := range p.rules {
+			if matches := len(rule.pattern.FindAllStringIndex(text, -1)); matches > 0 {
+				counts[rule.name] += matches
+				text = rule.pattern.ReplaceAllLiteralString(text, rule.replacement)
+			}
+		}
+		return text
+	}
+
+	output.Raw = redact(output.Raw)
+	output.Summary = redact(output.Summary)
+	if output.JSON != nil {
+		output.JSON = redactValue(output.JSON, redact).(map[string]interface{})
+	}
+	for i := range output.Citations {
+		output.Citations[i].Span = redact(output.Citations[i].Span)
+	}
+
+	if len(counts) == 0 {
+		return nil
+	}
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+	recorded, _ := output.Metadata[redactionsMetadataKey].(map[string]int)
+	if recorded == nil {
+		recorded = make(map[string]int)
+		output.Metadata[redactionsMetadataKey] = recorded
+	}
+	for name, count := range counts {
+		recorded[name] += count
+	}
+	return nil
+}
+
+// redactValue 递归替换JSON值中的字符串
+func redactValue(value interface{}, redact func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redact(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = redactValue(item, redact)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item, redact)
+		}
+		return v
+	}
+	return value
+}
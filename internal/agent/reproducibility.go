@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 可复现执行相关的输出元数据键
+const (
+	SeedMetadataKey                    = "seed"                     // 本次执行发送给LLM的种子
+	SystemFingerprintMetadataKey       = "system_fingerprint"       // 提供商返回的系统指纹，相同种子只在指纹不变时可复现
+	ReproducibilityWarningsMetadataKey = "reproducibility_warnings" // 严格模式下破坏确定性的组件
+)
+
+// ReproducibilityConfig 可复现执行配置
+// Seed传给支持种子的LLM（如OpenAI的seed参数）；严格模式下温度固定为0，
+// 并在非确定性工具、得分相同的记忆检索结果或变化的系统指纹破坏确定性时发出警告
+type ReproducibilityConfig struct {
+	Seed   *int `json:"seed,omitempty"` // 为nil时不设置种子
+	Strict bool `json:"strict"`
+}
+
+// IsZero 没有设置种子也没有开启严格模式
+func (c ReproducibilityConfig) IsZero() bool {
+	return c.Seed == nil && !c.Strict
+}
+
+// NondeterministicTool 声明输出不确定的工具（如网页抓取、当前时间），严格模式下使用时发出警告
+// BaseTool通过WithNondeterministic声明
+type NondeterministicTool interface {
+	Tool
+	IsNondeterministic() bool
+}
+
+// DeriveSeed 由基础种子和序号确定性地派生种子，Crew用它为每个任务生成不同但可复现的种子
+// 结果在[0, 2^31)范围内，各提供商都可以接受
+func DeriveSeed(seed, index int) int {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(seed))
+	binary.BigEndian.PutUint64(buf[8:], uint64(index))
+	hash := fnv.New64a()
+	hash.Write(buf[:])
+	return int(hash.Sum64() & 0x7fffffff)
+}
+
+type reproducibilityKey struct{}
+
+// WithReproducibility 返回带有可复现配置的ctx，之后执行的Agent与自身的ExecutionConfig合并使用
+func WithReproducibility(ctx context.Context, config *ReproducibilityConfig) context.Context {
+	return context.WithValue(ctx, reproducibilityKey{}, config)
+}
+
+// ReproducibilityFrom 返回ctx中的可复现配置，没有时返回nil
+func ReproducibilityFrom(ctx context.Context) *ReproducibilityConfig {
+	config, _ := ctx.Value(reproducibilityKey{}).(*ReproducibilityConfig)
+	return config
+}
+
+// reproducibilitySession 一次任务执行的可复现设置和收集到的警告，通过ctx传给各执行路径
+// nil会话的方法都是空操作
+type reproducibilitySession struct {
+	config ReproducibilityConfig
+	logger logger.Logger
+	taskID string
+
+	mu          sync.Mutex
+	fingerprint string
+	warnings    []string
+}
+
+type reproducibilitySessionKey struct{}
+
+// withReproducibility 确定本次执行的可复现设置：ExecutionConfig中的种子优先于ctx（Crew按任务派生）中的种子，
+// 任一方开启严格模式即为严格模式；都没有设置时返回nil会话
+func (a *BaseAgent) withReproducibility(ctx context.Context, task Task) (context.Context, *reproducibilitySession) {
+	config := ReproducibilityConfig{Seed: a.executionConfig.Seed, Strict: a.executionConfig.StrictReproducibility}
+	if inherited := ReproducibilityFrom(ctx); inherited != nil {
+		if config.Seed == nil {
+			config.Seed = inherited.Seed
+		}
+		config.Strict = config.Strict || inherited.Strict
+	}
+	if config.IsZero() {
+		return ctx, nil
+	}
+
+	session := &reproducibilitySession{config: config, logger: a.logger, taskID: task.GetID()}
+	if config.Strict && config.Seed == nil {
+		session.warn("no seed configured, LLM sampling is not reproducible")
+	}
+	return context.WithValue(ctx, reproducibilitySessionKey{}, session), session
+}
+
+// reproducibilityFrom 返回ctx中的会话，没有时返回nil
+func reproducibilityFrom(ctx context.Context) *reproducibilitySession {
+	session, _ := ctx.Value(reproducibilitySessionKey{}).(*reproducibilitySession)
+	return session
+}
+
+// strict 是否开启严格模式
+func (s *reproducibilitySession) strict() bool {
+	return s != nil && s.config.Strict
+}
+
+// applyCallOptions 设置LLM调用的种子，严格模式下温度固定为0
+func (s *reproducibilitySession) applyCallOptions(options *llm.CallOptions) {
+	if s == nil || options == nil {
+		return
+	}
+	if s.config.Seed != nil {
+		seed := *s.config.Seed
+		options.Seed = &seed
+	}
+	if s.config.Strict {
+		temperature := 0.0
+		options.Temperature = &temperature
+	}
+}
+
+// observeResponse 记录响应的系统指纹，严格模式下指纹变化时警告
+func (s *reproducibilitySession) observeResponse(response *llm.Response) {
+	if s == nil || response == nil || response.SystemFingerprint == "" {
+		return
+	}
+	s.mu.Lock()
+	previous := s.fingerprint
+	s.fingerprint = response.SystemFingerprint
+	s.mu.Unlock()
+
+	if s.config.Strict && previous != "" && previous != response.SystemFingerprint {
+		s.warn(fmt.Sprintf("system fingerprint changed from %s to %s, the provider backend changed during execution",
+			previous, response.SystemFingerprint))
+	}
+}
+
+// toolUsed 严格模式下使用非确定性工具时警告
+func (s *reproducibilitySession) toolUsed(tool Tool) {
+	if !s.strict() {
+		return
+	}
+	if nondeterministic, ok := tool.(NondeterministicTool); ok && nondeterministic.IsNondeterministic() {
+		s.warn(fmt.Sprintf("tool %s produces nondeterministic output", tool.GetName()))
+	}
+}
+
+// orderMemoryItems 严格模式下把得分相同的相邻记忆按Key排序，使检索结果的顺序确定，存在并列时警告
+func (s *reproducibilitySession) orderMemoryItems(items []MemoryItem) {
+	if !s.strict() {
+		return
+	}
+	tied := false
+	for start := 0; start < len(items); {
+		end := start + 1
+		for end < len(items) && items[end].Score == items[start].Score {
+			end++
+		}
+		if end-start > 1 {
+			tied = true
+			group := items[start:end]
+			sort.SliceStable(group, func(i, j int) bool { return group[i].Key < group[j].Key })
+		}
+		start = end
+	}
+	if tied {
+		s.warn("memory search returned items with equal scores, ties are ordered by key")
+	}
+}
+
+// warn 记录警告，相同的警告只记录一次
+func (s *reproducibilitySession) warn(message string) {
+	s.mu.Lock()
+	for _, existing := range s.warnings {
+		if existing == message {
+			s.mu.Unlock()
+			return
+		}
+	}
+	s.warnings = append(s.warnings, message)
+	s.mu.Unlock()
+
+	s.logger.Warn("Reproducibility warning",
+		logger.Field{Key: "task_id", Value: s.taskID},
+		logger.Field{Key: "warning", Value: message},
+	)
+}
+
+// apply 把种子和警告记录到输出的元数据中
+func (s *reproducibilitySession) apply(output *TaskOutput) {
+	if s == nil || output == nil {
+		return
+	}
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+	if s.config.Seed != nil {
+		output.Metadata[SeedMetadataKey] = *s.config.Seed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.warnings) > 0 {
+		output.Metadata[ReproducibilityWarningsMetadataKey] = append([]string(nil), s.warnings...)
+	}
+}
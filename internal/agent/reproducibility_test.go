@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// seededLLM 记录每次调用的选项
+type seededLLM struct {
+	*ExtendedMockLLM
+	options []*llm.CallOptions
+}
+
+func (m *seededLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	m.options = append(m.options, options)
+	return m.ExtendedMockLLM.Call(ctx, messages, options)
+}
+
+func TestDeriveSeed(t *testing.T) {
+	seeds := make(map[int]bool)
+	for index := 0; index < 100; index++ {
+		seed := DeriveSeed(42, index)
+		assert.Equal(t, seed, DeriveSeed(42, index), "derived seeds must be deterministic")
+		assert.GreaterOrEqual(t, seed, 0)
+		assert.Less(t, seed, 1<<31)
+		seeds[seed] = true
+	}
+	assert.Len(t, seeds, 100, "each task should get a different seed")
+	assert.NotEqual(t, DeriveSeed(42, 0), DeriveSeed(43, 0))
+}
+
+// TestReproducibilityStrictMode 测试严格模式下种子和温度0发送给LLM，非确定性工具和变化的系统指纹记录为警告
+func TestReproducibilityStrictMode(t *testing.T) {
+	mockLLM := &seededLLM{ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{
+		{ToolCalls: []llm.ToolCall{toolCall("scrape")}, SystemFingerprint: "fp_1"},
+		{Content: "Summary", SystemFingerprint: "fp_2"},
+	})}
+	scrape := NewBaseTool("scrape", "Scrape a page", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "page", nil
+	}).WithNondeterministic()
+	agent := newToolLoopTestAgent(t, mockLLM, nil, scrape)
+
+	seed := 7
+	config := agent.GetExecutionConfig()
+	config.Temperature = 0.9
+	config.Seed = &seed
+	config.StrictReproducibility = true
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Summarize the page", "A summary"))
+	require.NoError(t, err)
+
+	require.Len(t, mockLLM.options, 2)
+	for _, options := range mockLLM.options {
+		require.NotNil(t, options.Seed)
+		assert.Equal(t, 7, *options.Seed)
+		require.NotNil(t, options.Temperature)
+		assert.Equal(t, 0.0, *options.Temperature)
+	}
+
+	assert.Equal(t, 7, output.Metadata[SeedMetadataKey])
+	assert.Equal(t, "fp_2", output.Metadata[SystemFingerprintMetadataKey])
+	assert.Equal(t, []string{
+		"tool scrape produces nondeterministic output",
+		"system fingerprint changed from fp_1 to fp_2, the provider backend changed during execution",
+	}, output.Metadata[ReproducibilityWarningsMetadataKey])
+}
+
+// TestReproducibilityFromContext 测试ctx中的种子在Agent没有设置时使用，Agent自身的种子优先
+func TestReproducibilityFromContext(t *testing.T) {
+	newAgent := func(t *testing.T) (*BaseAgent, *seededLLM) {
+		mockLLM := &seededLLM{ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{{Content: "done"}})}
+		agent, err := NewBaseAgent(AgentConfig{
+			Role:      "Writer",
+			Goal:      "Write",
+			Backstory: "Brief",
+			LLM:       mockLLM,
+			Logger:    logger.NewTestLogger(),
+		})
+		require.NoError(t, err)
+		return agent, mockLLM
+	}
+
+	crewSeed := DeriveSeed(1, 0)
+	ctx := WithReproducibility(context.Background(), &ReproducibilityConfig{Seed: &crewSeed})
+
+	t.Run("context seed", func(t *testing.T) {
+		agent, mockLLM := newAgent(t)
+		output, err := agent.Execute(ctx, NewBaseTask("Write", "Text"))
+		require.NoError(t, err)
+		require.NotNil(t, mockLLM.options[0].Seed)
+		assert.Equal(t, crewSeed, *mockLLM.options[0].Seed)
+		assert.Equal(t, agent.GetExecutionConfig().Temperature, *mockLLM.options[0].Temperature, "temperature is only forced in strict mode")
+		assert.Equal(t, crewSeed, output.Metadata[SeedMetadataKey])
+		assert.NotContains(t, output.Metadata, ReproducibilityWarningsMetadataKey)
+	})
+
+	t.Run("agent seed wins", func(t *testing.T) {
+		agent, mockLLM := newAgent(t)
+		seed := 99
+		config := agent.GetExecutionConfig()
+		config.Seed = &seed
+		require.NoError(t, agent.SetExecutionConfig(config))
+
+		_, err := agent.Execute(ctx, NewBaseTask("Write", "Text"))
+		require.NoError(t, err)
+		assert.Equal(t, 99, *mockLLM.options[0].Seed)
+	})
+
+	t.Run("strict without seed", func(t *testing.T) {
+		agent, mockLLM := newAgent(t)
+		strictCtx := WithReproducibility(context.Background(), &ReproducibilityConfig{Strict: true})
+		output, err := agent.Execute(strictCtx, NewBaseTask("Write", "Text"))
+		require.NoError(t, err)
+		assert.Nil(t, mockLLM.options[0].Seed)
+		assert.Equal(t, []string{"no seed configured, LLM sampling is not reproducible"},
+			output.Metadata[ReproducibilityWarningsMetadataKey])
+	})
+
+	t.Run("no settings", func(t *testing.T) {
+		agent, mockLLM := newAgent(t)
+		output, err := agent.Execute(context.Background(), NewBaseTask("Write", "Text"))
+		require.NoError(t, err)
+		assert.Nil(t, mockLLM.options[0].Seed)
+		assert.NotContains(t, output.Metadata, SeedMetadataKey)
+	})
+}
+
+// TestReproducibilityOrdersTiedMemoryItems 测试严格模式下得分相同的记忆按Key排序并发出警告
+func TestReproducibilityOrdersTiedMemoryItems(t *testing.T) {
+	items := []MemoryItem{
+		{Key: "c", Score: 0.9},
+		{Key: "e", Score: 0.5},
+		{Key: "b", Score: 0.5},
+		{Key: "d", Score: 0.5},
+		{Key: "a", Score: 0.1},
+	}
+
+	var lenient *reproducibilitySession
+	lenient.orderMemoryItems(items)
+	assert.Equal(t, "e", items[1].Key, "items are left untouched outside strict mode")
+
+	session := &reproducibilitySession{config: ReproducibilityConfig{Strict: true}, logger: logger.NewTestLogger()}
+	session.orderMemoryItems(items)
+	var keys []string
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	assert.Equal(t, []string{"c", "b", "d", "e", "a"}, keys)
+	assert.Len(t, session.warnings, 1)
+
+	session.orderMemoryItems(items)
+	assert.Len(t, session.warnings, 1, "repeated warnings are recorded once")
+}
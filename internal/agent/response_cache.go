@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// responseCacheKey 返回本次LLM调用使用的缓存及缓存键
+// 未配置缓存、任务禁用缓存或流式调用时返回nil，表示不使用缓存
+func (a *BaseAgent) responseCacheKey(task Task, messages []llm.Message, callOptions *llm.CallOptions) (llm.ResponseCache, string) {
+	cache := a.GetResponseCache()
+	if cache == nil || (task != nil && task.IsCacheDisabled()) || (callOptions != nil && callOptions.Stream) {
+		return nil, ""
+	}
+
+	key, err := llm.CacheKey(a.llmProvider.GetModel(), messages, callOptions)
+	if err != nil {
+		a.logger.Debug("LLM response cache bypassed",
+			logger.Field{Key: "error", Value: err},
+		)
+		return nil, ""
+	}
+	return cache, key
+}
+
+// lookupCachedResponse 查询缓存的LLM响应
+// 命中时返回的响应Usage为零，因为本次没有实际消耗token
+func (a *BaseAgent) lookupCachedResponse(ctx context.Context, cache llm.ResponseCache, key string) (*llm.Response, bool) {
+	response, ok, err := cache.Get(ctx, key)
+	if err != nil {
+		a.logger.Warn("LLM response cache lookup failed",
+			logger.Field{Key: "error", Value: err},
+		)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	response.Usage = llm.Usage{}
+	if response.Metadata == nil {
+		response.Metadata = make(map[string]interface{})
+	}
+	response.Metadata["cache_hit"] = true
+
+	a.logger.Debug("LLM response served from cache",
+		logger.Field{Key: "agent", Value: a.role},
+		logger.Field{Key: "cache_key", Value: key},
+	)
+	return response, true
+}
+
+// storeCachedResponse 写入缓存，失败只记录日志
+func (a *BaseAgent) storeCachedResponse(ctx context.Context, cache llm.ResponseCache, key string, response *llm.Response) {
+	if err := cache.Set(ctx, key, response, 0); err != nil {
+		a.logger.Warn("LLM response cache store failed",
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// TestAgentResponseCache 测试相同请求命中LLM响应缓存
+func TestAgentResponseCache(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: "first answer", Usage: llm.Usage{TotalTokens: 12}},
+		{Content: "second answer", Usage: llm.Usage{TotalTokens: 8}},
+	})
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Researcher",
+		Goal:      "Research",
+		Backstory: "Curious",
+		LLM:       mockLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+	agent.SetResponseCache(llm.NewLRUCache(10, 0))
+
+	first, err := agent.Execute(context.Background(), NewTaskWithOptions("Summarize Go", "Summary"))
+	require.NoError(t, err)
+	second, err := agent.Execute(context.Background(), NewTaskWithOptions("Summarize Go", "Summary"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, mockLLM.callCount)
+	assert.Equal(t, "first answer", second.Raw)
+	assert.Equal(t, 12, first.TokensUsed)
+	assert.Equal(t, 0, second.TokensUsed)
+
+	// 禁用缓存的任务总是调用LLM
+	fresh, err := agent.Execute(context.Background(), NewTaskWithOptions("Summarize Go", "Summary", WithCacheDisabled()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, mockLLM.callCount)
+	assert.Equal(t, "second answer", fresh.Raw)
+
+	stats := agent.GetExecutionStats()
+	assert.Equal(t, 1, stats.CacheHits)
+	assert.Equal(t, 1, stats.CacheMisses)
+}
+
+// TestAgentStreamBypassesResponseCache 测试流式调用不使用缓存
+func TestAgentStreamBypassesResponseCache(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "streamed"}})
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Researcher",
+		Goal:      "Research",
+		Backstory: "Curious",
+		LLM:       mockLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+	cache := llm.NewLRUCache(10, 0)
+	agent.SetResponseCache(cache)
+
+	chunks, err := agent.ExecuteStream(context.Background(), NewTaskWithOptions("Summarize Go", "Summary"))
+	require.NoError(t, err)
+	for chunk := range chunks {
+		require.NoError(t, chunk.Error)
+	}
+
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, 0, agent.GetExecutionStats().CacheMisses)
+}
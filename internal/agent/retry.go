@@ -0,0 +1,316 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+// RetryErrorClass 可重试的LLM错误类别
+type RetryErrorClass string
+
+const (
+	RetryOnRateLimit   RetryErrorClass = "rate_limit"   // 429 / 速率限制
+	RetryOnTimeout     RetryErrorClass = "timeout"      // 请求超时
+	RetryOnServerError RetryErrorClass = "server_error" // 5xx 服务端错误
+
+	// 以下类别永远不会重试
+	errorClassInvalidRequest  RetryErrorClass = "invalid_request"
+	errorClassContextExceeded RetryErrorClass = "context_length_exceeded"
+	errorClassUnknown         RetryErrorClass = "unknown"
+)
+
+// RetryPolicy Agent级LLM调用重试策略
+// 与llm.Config中传输层的MaxRetries不同，该策略作用于完整的LLM调用；
+// MaxRetries大于0时Agent发起的调用会关闭传输层重试，两者不会相乘
+type RetryPolicy struct {
+	MaxRetries     int               `json:"max_retries"`     // 最大重试次数，0表示不重试
+	InitialBackoff time.Duration     `json:"initial_backoff"` // 首次重试前的等待时间
+	MaxBackoff     time.Duration     `json:"max_backoff"`     // 单次等待时间上限
+	RetryOn        []RetryErrorClass `json:"retry_on"`        // 需要重试的错误类别
+}
+
+// DefaultRetryPolicy 返回默认的重试策略
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		RetryOn:        []RetryErrorClass{RetryOnRateLimit, RetryOnTimeout, RetryOnServerError},
+	}
+}
+
+// shouldRetry 判断错误类别是否在重试列表中
+func (p RetryPolicy) shouldRetry(class RetryErrorClass) bool {
+	for _, c := range p.RetryOn {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 计算第attempt次重试（从1开始）的等待时间：指数退避加随机抖动
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	base := p.InitialBackoff
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			delay = p.MaxBackoff
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	// 抖动：在[delay/2, delay]范围内随机
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+var httpStatusPattern = regexp.MustCompile(`(?i)(?:HTTP error|status(?: code)?)[:\s]+(\d{3})`)
+
+// classifyLLMError 将LLM错误归类，用于决定是否重试
+func classifyLLMError(err error) RetryErrorClass {
+	if err == nil {
+		return errorClassUnknown
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	// 不可重试的错误优先判断
+	if strings.Contains(msg, "context_length_exceeded") ||
+		strings.Contains(msg, "context length exceeded") ||
+		strings.Contains(msg, "maximum context length") {
+		return errorClassContextExceeded
+	}
+	if strings.Contains(msg, "invalid_request") || strings.Contains(msg, "invalid request") {
+		return errorClassInvalidRequest
+	}
+
+	if match := httpStatusPattern.FindStringSubmatch(err.Error()); len(match) == 2 {
+		if status, convErr := strconv.Atoi(match[1]); convErr == nil {
+			switch {
+			case status == 429:
+				return RetryOnRateLimit
+			case status == 408:
+				return RetryOnTimeout
+			case status >= 500:
+				return RetryOnServerError
+			case status >= 400:
+				return errorClassInvalidRequest
+			}
+		}
+	}
+
+	if strings.Contains(msg, "rate limit") || strings.Contains(msg, "rate_limit") || strings.Contains(msg, "too many requests") {
+		return RetryOnRateLimit
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) ||
+		strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out") {
+		return RetryOnTimeout
+	}
+
+	if strings.Contains(msg, "server error") || strings.Contains(msg, "server_error") ||
+		strings.Contains(msg, "service unavailable") || strings.Contains(msg, "bad gateway") {
+		return RetryOnServerError
+	}
+
+	return errorClassUnknown
+}
+
+// isTransientLLMError 判断错误是否属于暂时性错误（速率限制、超时、服务端错误）
+func isTransientLLMError(err error) bool {
+	switch classifyLLMError(err) {
+	case RetryOnRateLimit, RetryOnTimeout, RetryOnServerError:
+		return true
+	}
+	return false
+}
+
+// retryContext 启用Agent级重试时关闭传输层重试，避免两层重试次数相乘
+func (p RetryPolicy) retryContext(ctx context.Context) context.Context {
+	if p.MaxRetries > 0 {
+		return llm.WithoutTransportRetries(ctx)
+	}
+	return ctx
+}
+
+// callLLMWithRetry 按上下文窗口裁剪消息后按ExecutionConfig.RetryPolicy调用LLM
+// 可重试错误按指数退避等待后重试，并发射agent_execution_retry事件；不可重试错误立即返回
+// 返回的裁剪结果包含实际发送的消息，调用方用它累计裁剪信息，不需要自己再裁剪
+func (a *BaseAgent) callLLMWithRetry(ctx context.Context, task Task, messages []llm.Message, callOptions *llm.CallOptions) (*llm.Response, *llm.TrimResult, error) {
+	trim, err := a.fitContextWindow(ctx, task, messages, callOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages = trim.Messages
+
+	cache, cacheKey := a.responseCacheKey(task, messages, callOptions)
+	if cache != nil {
+		if response, ok := a.lookupCachedResponse(ctx, cache, cacheKey); ok {
+			a.mu.Lock()
+			a.stats.CacheHits++
+			a.mu.Unlock()
+			callStatsFrom(ctx).record(func(stats *LLMCallStats) { stats.CacheHits++ })
+			return response, trim, nil
+		}
+		a.mu.Lock()
+		a.stats.CacheMisses++
+		a.mu.Unlock()
+		callStatsFrom(ctx).record(func(stats *LLMCallStats) { stats.CacheMisses++ })
+	}
+
+	callCtx := a.executionConfig.RetryPolicy.retryContext(ctx)
+	for attempt := 0; ; attempt++ {
+		if err := a.acquireRequest(ctx); err != nil {
+			return nil, nil, fmt.Errorf("LLM call failed: %w", err)
+		}
+
+		endStep := a.beginStep(task, StepStatus{Kind: StepKindLLMCall, Attempt: attempt + 1})
+		response, err := a.llmProvider.Call(llm.WithRetryAttempt(callCtx, attempt), messages, callOptions)
+		endStep()
+		if err == nil {
+			reproducibilityFrom(ctx).observeResponse(response)
+			if cache != nil {
+				a.storeCachedResponse(ctx, cache, cacheKey, response)
+			}
+			if err := a.recordBudgetSpend(ctx, task, response.Usage); err != nil {
+				return nil, nil, err
+			}
+			return response, trim, nil
+		}
+
+		if retryErr := a.waitForRetry(ctx, task, attempt, err); retryErr != nil {
+			return nil, nil, retryErr
+		}
+	}
+}
+
+// callLLMStreamWithRetry 按ExecutionConfig.RetryPolicy打开LLM流
+// 只重试打开流时的错误；流开始后的错误由调用方处理，避免重复发送已转发的增量
+// 调用方先用fitContextWindow裁剪消息并记录裁剪信息，这里不再裁剪
+func (a *BaseAgent) callLLMStreamWithRetry(ctx context.Context, task Task, messages []llm.Message, callOptions *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	callCtx := a.executionConfig.RetryPolicy.retryContext(ctx)
+	for attempt := 0; ; attempt++ {
+		if err := a.acquireRequest(ctx); err != nil {
+			return nil, fmt.Errorf("LLM call failed: %w", err)
+		}
+
+		stream, err := a.llmProvider.CallStream(llm.WithRetryAttempt(callCtx, attempt), messages, callOptions)
+		if err == nil {
+			return stream, nil
+		}
+		if errors.Is(err, llm.ErrStreamingNotSupported) {
+			return nil, err
+		}
+
+		if retryErr := a.waitForRetry(ctx, task, attempt, err); retryErr != nil {
+			return nil, retryErr
+		}
+	}
+}
+
+// acquireRequest 等待速率控制器的许可，并把本次请求和限流等待计入ctx中的调用统计
+func (a *BaseAgent) acquireRequest(ctx context.Context) error {
+	return acquireRPMRequest(ctx, a.GetRPMController())
+}
+
+// acquireRPMRequest 从速率控制器获取一次请求配额并记录到ctx中的调用统计，controller为nil时不限制
+func acquireRPMRequest(ctx context.Context, controller *RPMController) error {
+	wait, err := controller.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	callStatsFrom(ctx).record(func(stats *LLMCallStats) {
+		stats.Calls++
+		if wait > 0 {
+			stats.ThrottledRequests++
+			stats.RateLimitWait += wait
+		}
+	})
+	return nil
+}
+
+// waitForRetry 处理第attempt次（从0开始）调用失败的错误
+// 错误可重试且未超过重试次数时记录统计、发射重试事件并等待退避时间后返回nil，否则返回最终错误
+func (a *BaseAgent) waitForRetry(ctx context.Context, task Task, attempt int, err error) error {
+	policy := a.executionConfig.RetryPolicy
+
+	// 调用方取消或超时时不再重试
+	if ctx.Err() != nil {
+		return fmt.Errorf("LLM call failed: %w", err)
+	}
+
+	class := classifyLLMError(err)
+	if attempt >= policy.MaxRetries || !policy.shouldRetry(class) {
+		if attempt > 0 {
+			return fmt.Errorf("LLM call failed after %d retries: %w", attempt, err)
+		}
+		return fmt.Errorf("LLM call failed: %w", err)
+	}
+
+	retry := attempt + 1
+	delay := policy.backoff(retry)
+
+	a.mu.Lock()
+	a.stats.RetriedExecutions++
+	a.mu.Unlock()
+	callStatsFrom(ctx).record(func(stats *LLMCallStats) { stats.Retries++ })
+
+	a.logger.Warn("Retrying LLM call after retryable error",
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "attempt", Value: retry},
+		logger.Field{Key: "max_retries", Value: policy.MaxRetries},
+		logger.Field{Key: "error_class", Value: string(class)},
+		logger.Field{Key: "backoff", Value: delay},
+		logger.Field{Key: "error", Value: err},
+	)
+
+	tracing.AddEvent(ctx, tracing.EventLLMRetry,
+		tracing.String("retry.source", "agent"),
+		tracing.Int("retry.attempt", retry),
+		tracing.Int("retry.max_retries", policy.MaxRetries),
+		tracing.String("retry.error_class", string(class)),
+		tracing.Int64("retry.backoff_ms", delay.Milliseconds()),
+		tracing.String("retry.error", err.Error()),
+	)
+
+	if a.eventBus != nil {
+		retryEvent := NewAgentExecutionRetryEvent(a.id, a.role, task.GetID(), retry, policy.MaxRetries, class, delay, err)
+		if emitErr := a.eventBus.Emit(ctx, a, retryEvent); emitErr != nil {
+			a.logger.Error("Failed to emit agent execution retry event",
+				logger.Field{Key: "error", Value: emitErr})
+		}
+	}
+
+	timer := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return fmt.Errorf("task execution cancelled during retry backoff: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// FlakyMockLLM 先返回指定错误若干次，然后返回正常响应的模拟LLM
+type FlakyMockLLM struct {
+	*ExtendedMockLLM
+	failures int
+	err      error
+	attempts int
+}
+
+func (m *FlakyMockLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	m.attempts++
+	if m.attempts <= m.failures {
+		return nil, m.err
+	}
+	return m.ExtendedMockLLM.Call(ctx, messages, options)
+}
+
+func newRetryTestAgent(t *testing.T, mockLLM llm.LLM, eventBus events.EventBus, policy RetryPolicy) *BaseAgent {
+	t.Helper()
+
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Retry Agent",
+		Goal:      "Survive flaky providers",
+		Backstory: "I retry when the provider is noisy",
+		LLM:       mockLLM,
+		EventBus:  eventBus,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	config := DefaultExecutionConfig()
+	config.RetryPolicy = policy
+	require.NoError(t, agent.SetExecutionConfig(config))
+	return agent
+}
+
+func fastRetryPolicy(maxRetries int) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = maxRetries
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+// TestClassifyLLMError 测试LLM错误分类
+func TestClassifyLLMError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected RetryErrorClass
+	}{
+		{errors.New("HTTP error 429: Too Many Requests"), RetryOnRateLimit},
+		{errors.New("OpenAI API error: Rate limit reached (type: requests, code: rate_limit_exceeded)"), RetryOnRateLimit},
+		{errors.New("HTTP error 503: Service Unavailable"), RetryOnServerError},
+		{errors.New("HTTP error 500: internal"), RetryOnServerError},
+		{errors.New("HTTP request failed: net/http: request canceled (Client.Timeout exceeded)"), RetryOnTimeout},
+		{context.DeadlineExceeded, RetryOnTimeout},
+		{errors.New("HTTP error 400: bad request"), errorClassInvalidRequest},
+		{errors.New("OpenAI API error: too long (type: invalid_request_error, code: context_length_exceeded)"), errorClassContextExceeded},
+		{errors.New("something odd happened"), errorClassUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifyLLMError(tt.err))
+		})
+	}
+}
+
+// TestRetryPolicyBackoff 测试指数退避计算
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt, expectedMax := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		delay := policy.backoff(attempt)
+		assert.LessOrEqual(t, delay, expectedMax, "attempt %d", attempt)
+		assert.GreaterOrEqual(t, delay, expectedMax/2, "attempt %d", attempt)
+	}
+
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.backoff(1))
+}
+
+// TestAgentRetriesRetryableErrors 测试可重试错误会重试并最终成功
+func TestAgentRetriesRetryableErrors(t *testing.T) {
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+
+	var mu sync.Mutex
+	var attempts []int
+	require.NoError(t, eventBus.Subscribe("agent_execution_retry", func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, event.(*AgentExecutionRetryEvent).Attempt)
+		return nil
+	}))
+
+	mockLLM := &FlakyMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{{Content: "recovered", Usage: llm.Usage{TotalTokens: 5}}}),
+		failures:        2,
+		err:             errors.New("HTTP error 429: rate limited"),
+	}
+	agent := newRetryTestAgent(t, mockLLM, eventBus, fastRetryPolicy(3))
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Flaky task", "Some output"))
+	require.NoError(t, err)
+	assert.Equal(t, "recovered", output.Raw)
+	assert.Equal(t, 3, mockLLM.attempts)
+	assert.Equal(t, 2, agent.GetExecutionStats().RetriedExecutions)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(attempts) == 2
+	}, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	assert.ElementsMatch(t, []int{1, 2}, attempts)
+	mu.Unlock()
+}
+
+// TestAgentRetryExhausted 测试重试次数耗尽后返回错误
+func TestAgentRetryExhausted(t *testing.T) {
+	mockLLM := &FlakyMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM(nil),
+		failures:        10,
+		err:             errors.New("HTTP error 502: bad gateway"),
+	}
+	agent := newRetryTestAgent(t, mockLLM, nil, fastRetryPolicy(2))
+
+	_, err := agent.Execute(context.Background(), NewBaseTask("Always failing", "Nothing"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 2 retries")
+	assert.Equal(t, 3, mockLLM.attempts)
+	assert.Equal(t, 2, agent.GetExecutionStats().RetriedExecutions)
+}
+
+// TestAgentNonRetryableErrorFailsImmediately 测试不可重试错误立即失败
+func TestAgentNonRetryableErrorFailsImmediately(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		policy RetryPolicy
+	}{
+		{"context length exceeded", errors.New("code: context_length_exceeded"), fastRetryPolicy(3)},
+		{"invalid request", errors.New("HTTP error 400: invalid request"), fastRetryPolicy(3)},
+		{"class not enabled", errors.New("HTTP error 500: boom"), RetryPolicy{MaxRetries: 3, RetryOn: []RetryErrorClass{RetryOnRateLimit}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLLM := &FlakyMockLLM{ExtendedMockLLM: NewExtendedMockLLM(nil), failures: 10, err: tt.err}
+			agent := newRetryTestAgent(t, mockLLM, nil, tt.policy)
+
+			_, err := agent.Execute(context.Background(), NewBaseTask("Bad request", "Nothing"))
+			require.Error(t, err)
+			assert.Equal(t, 1, mockLLM.attempts)
+			assert.Equal(t, 0, agent.GetExecutionStats().RetriedExecutions)
+		})
+	}
+}
+
+// TestAgentRetryRespectsCancellation 测试退避等待期间取消上下文
+func TestAgentRetryRespectsCancellation(t *testing.T) {
+	mockLLM := &FlakyMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM(nil),
+		failures:        10,
+		err:             errors.New("HTTP error 429: rate limited"),
+	}
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Minute
+	policy.MaxBackoff = time.Minute
+	agent := newRetryTestAgent(t, mockLLM, nil, policy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := agent.Execute(ctx, NewBaseTask("Cancelled", "Nothing"))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, 1, mockLLM.attempts)
+}
+
+// TestAgentRetryDisablesTransportRetries 测试启用Agent级重试时传输层不再重试，两者不会相乘
+func TestAgentRetryDisablesTransportRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "upstream unavailable")
+	}))
+	defer server.Close()
+
+	provider := llm.NewAnthropicLLM("claude-3-haiku-20240307", llm.WithBaseURL(server.URL), llm.WithMaxRetries(3))
+	agent := newRetryTestAgent(t, provider, nil, fastRetryPolicy(1))
+
+	_, err := agent.Execute(context.Background(), NewBaseTask("Unavailable", "Nothing"))
+	require.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RPMStats 速率控制器的统计信息
+type RPMStats struct {
+	MaxRPM             int           `json:"max_rpm"`
+	RequestsLastMinute int           `json:"requests_last_minute"`
+	Utilization        float64       `json:"utilization"` // 最近一分钟请求数 / MaxRPM
+	TotalRequests      int           `json:"total_requests"`
+	ThrottledRequests  int           `json:"throttled_requests"`
+	TotalWait          time.Duration `json:"total_wait"`
+}
+
+// ThrottleHandler 请求被限流时的回调，wait为需要等待的时长
+type ThrottleHandler func(ctx context.Context, wait time.Duration)
+
+// RPMController 每分钟请求数控制器，对标Python版本的RPMController
+// 使用令牌桶实现：容量为maxRPM，按maxRPM/分钟的速率补充。可以在多个Agent之间共享，并发安全
+type RPMController struct {
+	maxRPM     int
+	tokens     float64
+	lastRefill time.Time
+	recent     []time.Time // 最近一分钟内获得许可的时间，用于计算利用率
+
+	totalRequests     int
+	throttledRequests int
+	totalWait         time.Duration
+	onThrottle        ThrottleHandler
+
+	mu sync.Mutex
+}
+
+// NewRPMController 创建速率控制器
+// maxRPM<=0表示不限制，此时返回nil；nil控制器的所有方法都是无开销的空操作
+func NewRPMController(maxRPM int) *RPMController {
+	if maxRPM <= 0 {
+		return nil
+	}
+	return &RPMController{
+		maxRPM:     maxRPM,
+		tokens:     float64(maxRPM),
+		lastRefill: time.Now(),
+	}
+}
+
+// SetThrottleHandler 设置限流回调
+func (c *RPMController) SetThrottleHandler(handler ThrottleHandler) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onThrottle = handler
+}
+
+// GetMaxRPM 返回每分钟最大请求数，nil控制器返回0
+func (c *RPMController) GetMaxRPM() int {
+	if c == nil {
+		return 0
+	}
+	return c.maxRPM
+}
+
+// Wait 阻塞直到获得一次请求许可，或上下文被取消
+func (c *RPMController) Wait(ctx context.Context) error {
+	_, err := c.Acquire(ctx)
+	return err
+}
+
+// Acquire 与Wait相同，同时返回本次请求被限流等待的时长，用于按任务统计限流开销
+func (c *RPMController) Acquire(ctx context.Context) (time.Duration, error) {
+	if c == nil {
+		return 0, nil
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	c.refill(now)
+
+	// 预留一个令牌；令牌不足时余额为负，表示需要等待补充
+	c.tokens--
+	var wait time.Duration
+	if c.tokens < 0 {
+		wait = time.Duration(-c.tokens / c.ratePerSecond() * float64(time.Second))
+	}
+	c.totalRequests++
+	handler := c.onThrottle
+	if wait > 0 {
+		c.throttledRequests++
+		c.totalWait += wait
+	}
+	c.mu.Unlock()
+
+	if wait > 0 {
+		if handler != nil {
+			handler(ctx, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			// 归还未使用的令牌，取消的请求不计入请求数和限流统计
+			c.mu.Lock()
+			c.tokens++
+			c.totalRequests--
+			c.throttledRequests--
+			c.totalWait -= wait
+			c.mu.Unlock()
+			return 0, fmt.Errorf("rate limit wait cancelled: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	c.mu.Lock()
+	c.recent = append(c.recent, time.Now())
+	c.mu.Unlock()
+	return wait, nil
+}
+
+// Stats 返回当前统计信息
+func (c *RPMController) Stats() RPMStats {
+	if c == nil {
+		return RPMStats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pruneRecent(time.Now())
+	return RPMStats{
+		MaxRPM:             c.maxRPM,
+		RequestsLastMinute: len(c.recent),
+		Utilization:        float64(len(c.recent)) / float64(c.maxRPM),
+		TotalRequests:      c.totalRequests,
+		ThrottledRequests:  c.throttledRequests,
+		TotalWait:          c.totalWait,
+	}
+}
+
+// refill 按经过的时间补充令牌，调用方需持有锁
+func (c *RPMController) refill(now time.Time) {
+	elapsed := now.Sub(c.lastRefill).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	c.tokens += elapsed * c.ratePerSecond()
+	if c.tokens > float64(c.maxRPM) {
+		c.tokens = float64(c.maxRPM)
+	}
+	c.lastRefill = now
+	c.pruneRecent(now)
+}
+
+// pruneRecent 移除一分钟之前的请求记录，调用方需持有锁
+func (c *RPMController) pruneRecent(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(c.recent) && c.recent[i].Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		c.recent = append(c.recent[:0], c.recent[i:]...)
+	}
+}
+
+// ratePerSecond 每秒补充的令牌数
+func (c *RPMController) ratePerSecond() float64 {
+	return float64(c.maxRPM) / 60.0
+}
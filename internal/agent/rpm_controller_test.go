@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// TestRPMControllerUnlimited 测试MaxRPM为0时不做限制
+func TestRPMControllerUnlimited(t *testing.T) {
+	controller := NewRPMController(0)
+	assert.Nil(t, controller)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, controller.Wait(context.Background()))
+	}
+	assert.Equal(t, RPMStats{}, controller.Stats())
+	assert.Equal(t, 0, controller.GetMaxRPM())
+}
+
+// TestRPMControllerThrottles 测试令牌耗尽后等待补充
+func TestRPMControllerThrottles(t *testing.T) {
+	// 1200 RPM：容量1200，每50ms补充一个令牌
+	controller := NewRPMController(1200)
+	require.NotNil(t, controller)
+
+	var waits []time.Duration
+	controller.SetThrottleHandler(func(ctx context.Context, wait time.Duration) {
+		waits = append(waits, wait)
+	})
+
+	for i := 0; i < 1200; i++ {
+		require.NoError(t, controller.Wait(context.Background()))
+	}
+	assert.Empty(t, waits)
+
+	start := time.Now()
+	require.NoError(t, controller.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+	require.Len(t, waits, 1)
+	assert.LessOrEqual(t, waits[0], 50*time.Millisecond)
+
+	stats := controller.Stats()
+	assert.Equal(t, 1200, stats.MaxRPM)
+	assert.Equal(t, 1201, stats.TotalRequests)
+	assert.Equal(t, 1201, stats.RequestsLastMinute)
+	assert.Equal(t, 1, stats.ThrottledRequests)
+	assert.Greater(t, stats.Utilization, 1.0)
+	assert.Equal(t, waits[0], stats.TotalWait)
+}
+
+// TestRPMControllerWaitCancelled 测试等待期间上下文取消
+func TestRPMControllerWaitCancelled(t *testing.T) {
+	controller := NewRPMController(1)
+	require.NoError(t, controller.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := controller.Wait(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 取消的请求归还令牌，不计入请求数和限流统计
+	stats := controller.Stats()
+	assert.Equal(t, 1, stats.TotalRequests)
+	assert.Equal(t, 1, stats.RequestsLastMinute)
+	assert.Zero(t, stats.ThrottledRequests)
+	assert.Zero(t, stats.TotalWait)
+}
+
+// TestRPMControllerConcurrentWait 测试并发获取许可
+func TestRPMControllerConcurrentWait(t *testing.T) {
+	controller := NewRPMController(1200)
+	for i := 0; i < 1195; i++ {
+		require.NoError(t, controller.Wait(context.Background()))
+	}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, controller.Wait(context.Background()))
+		}()
+	}
+	wg.Wait()
+
+	// 超出容量的5个请求依次等待，最后一个约需250ms
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	stats := controller.Stats()
+	assert.Equal(t, 1205, stats.TotalRequests)
+	assert.Equal(t, 5, stats.ThrottledRequests)
+}
+
+// TestAgentUsesRPMController 测试Agent在调用LLM前获取许可
+func TestAgentUsesRPMController(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "done"}})
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write",
+		Backstory: "Writes",
+		LLM:       mockLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	controller := NewRPMController(1)
+	agent.SetRPMController(controller)
+	assert.Same(t, controller, agent.Clone().GetRPMController())
+
+	_, err = agent.Execute(context.Background(), NewTaskWithOptions("First", "Output"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = agent.Execute(ctx, NewTaskWithOptions("Second", "Output"))
+	require.Error(t, err)
+	assert.Equal(t, 1, mockLLM.callCount)
+}
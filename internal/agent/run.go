@@ -0,0 +1,215 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// Run和RunStructured创建的临时Agent的默认设定
+const (
+	DefaultRunRole           = "Assistant"
+	DefaultRunGoal           = "Complete the request accurately and concisely"
+	DefaultRunBackstory      = "You are a capable assistant who uses the available tools when they help"
+	DefaultRunExpectedOutput = "A complete and accurate answer to the request"
+)
+
+// RunInfo 一次Run的执行信息
+type RunInfo struct {
+	TokensUsed       int           `json:"tokens_used"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	Cost             float64       `json:"cost"`
+	Duration         time.Duration `json:"duration"`
+	ToolsUsed        []string      `json:"tools_used"`
+	Model            string        `json:"model"`
+	Output           *TaskOutput   `json:"-"` // 完整的任务输出，执行失败时为nil
+}
+
+// RunOption 配置Run和RunStructured
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	llm              llm.LLM
+	role             string
+	goal             string
+	backstory        string
+	expectedOutput   string
+	outputSchema     *OutputSchema
+	tools            []Tool
+	temperature      float64
+	timeout          time.Duration
+	memory           Memory
+	knowledgeSources []KnowledgeSource
+	eventBus         events.EventBus
+	logger           logger.Logger
+}
+
+// WithRunLLM 设置执行使用的LLM，必须设置
+func WithRunLLM(model llm.LLM) RunOption {
+	return func(c *runConfig) { c.llm = model }
+}
+
+// WithRunPersona 设置临时Agent的角色、目标和背景，空值使用默认设定
+func WithRunPersona(role, goal, backstory string) RunOption {
+	return func(c *runConfig) {
+		if role != "" {
+			c.role = role
+		}
+		if goal != "" {
+			c.goal = goal
+		}
+		if backstory != "" {
+			c.backstory = backstory
+		}
+	}
+}
+
+// WithRunExpectedOutput 设置期望输出的描述
+func WithRunExpectedOutput(expectedOutput string) RunOption {
+	return func(c *runConfig) { c.expectedOutput = expectedOutput }
+}
+
+// WithRunOutputSchema 要求输出符合模式，RunStructured按类型参数生成模式，不需要设置
+func WithRunOutputSchema(schema *OutputSchema) RunOption {
+	return func(c *runConfig) { c.outputSchema = schema }
+}
+
+// WithRunTools 添加可以调用的工具
+func WithRunTools(tools ...Tool) RunOption {
+	return func(c *runConfig) { c.tools = append(c.tools, tools...) }
+}
+
+// WithRunTemperature 设置温度，<=0时使用DefaultExecutionConfig的温度
+func WithRunTemperature(temperature float64) RunOption {
+	return func(c *runConfig) { c.temperature = temperature }
+}
+
+// WithRunTimeout 设置整个执行（包括工具调用）的超时时间
+func WithRunTimeout(timeout time.Duration) RunOption {
+	return func(c *runConfig) { c.timeout = timeout }
+}
+
+// WithRunMemory 设置记忆，执行前查询相关记忆，执行后保存结果
+func WithRunMemory(memory Memory) RunOption {
+	return func(c *runConfig) { c.memory = memory }
+}
+
+// WithRunKnowledge 添加知识源，相关内容注入提示
+func WithRunKnowledge(sources ...KnowledgeSource) RunOption {
+	return func(c *runConfig) { c.knowledgeSources = append(c.knowledgeSources, sources...) }
+}
+
+// WithRunEventBus 设置事件总线，不设置时不发出事件
+func WithRunEventBus(eventBus events.EventBus) RunOption {
+	return func(c *runConfig) { c.eventBus = eventBus }
+}
+
+// WithRunLogger 设置日志记录器，不设置时使用控制台日志
+func WithRunLogger(log logger.Logger) RunOption {
+	return func(c *runConfig) { c.logger = log }
+}
+
+// Run 用临时的Agent和任务执行一次提示，返回最终答案
+// 不需要创建Task或Crew，执行过程与Agent.Execute相同：工具调用循环、重试、上下文窗口管理等都会生效
+func Run(ctx context.Context, prompt string, opts ...RunOption) (string, *RunInfo, error) {
+	output, info, err := run(ctx, prompt, opts)
+	if err != nil {
+		return "", info, err
+	}
+	return output.Raw, info, nil
+}
+
+// RunStructured 执行一次提示并把输出解析为T，T必须是结构体（或结构体指针），
+// 输出模式由T的json和description标签生成，不符合模式时先请求LLM修正，仍不符合时返回错误
+func RunStructured[T any](ctx context.Context, prompt string, opts ...RunOption) (T, *RunInfo, error) {
+	var zero T
+	schema, err := NewOutputSchemaFromStruct(zero)
+	if err != nil {
+		return zero, nil, err
+	}
+
+	output, info, err := run(ctx, prompt, append(opts[:len(opts):len(opts)], WithRunOutputSchema(schema)))
+	if err != nil {
+		return zero, info, err
+	}
+	if !output.IsValid {
+		return zero, info, fmt.Errorf("output does not match %s: %s", schema.Name, output.ValidationError)
+	}
+	switch parsed := output.Parsed.(type) {
+	case T:
+		return parsed, info, nil
+	case *T:
+		return *parsed, info, nil
+	}
+	return zero, info, fmt.Errorf("unexpected parsed output type %T", output.Parsed)
+}
+
+// run 创建临时Agent和任务并执行
+func run(ctx context.Context, prompt string, opts []RunOption) (*TaskOutput, *RunInfo, error) {
+	config := runConfig{
+		role:           DefaultRunRole,
+		goal:           DefaultRunGoal,
+		backstory:      DefaultRunBackstory,
+		expectedOutput: DefaultRunExpectedOutput,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.llm == nil {
+		return nil, nil, errors.New("run requires an LLM, use WithRunLLM")
+	}
+
+	executionConfig := DefaultExecutionConfig()
+	if config.temperature > 0 {
+		executionConfig.Temperature = config.temperature
+	}
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:             config.role,
+		Goal:             config.goal,
+		Backstory:        config.backstory,
+		LLM:              config.llm,
+		Tools:            config.tools,
+		ExecutionConfig:  executionConfig,
+		Memory:           config.memory,
+		KnowledgeSources: config.knowledgeSources,
+		EventBus:         config.eventBus,
+		Logger:           config.logger,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	var taskOptions []TaskOption
+	if config.outputSchema != nil {
+		taskOptions = append(taskOptions, WithOutputSchema(config.outputSchema))
+	}
+	task := NewTaskWithOptions(prompt, config.expectedOutput, taskOptions...)
+
+	if config.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	output, err := agent.Execute(ctx, task)
+	info := &RunInfo{Duration: time.Since(start), Model: config.llm.GetModel(), Output: output}
+	if err != nil {
+		return nil, info, err
+	}
+	info.TokensUsed = output.TokensUsed
+	info.PromptTokens = output.PromptTokens
+	info.CompletionTokens = output.CompletionTokens
+	info.Cost = output.Cost
+	info.ToolsUsed = output.ToolsUsed
+	if output.Model != "" {
+		info.Model = output.Model
+	}
+	return output, info, nil
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func TestRunWithTools(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{ToolCalls: []llm.ToolCall{toolCall("weather")}, Usage: llm.Usage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25, Cost: 0.001}},
+		{Content: "It is sunny in Paris", Model: "mock-1", Usage: llm.Usage{PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40, Cost: 0.002}},
+	}).WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, messages)
+	})
+	weather := NewBaseTool("weather", "Get the weather", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "sunny", nil
+	})
+
+	answer, info, err := Run(context.Background(), "What is the weather in Paris?",
+		WithRunLLM(mockLLM), WithRunTools(weather), WithRunLogger(logger.NewTestLogger()),
+		WithRunPersona("Forecaster", "", ""), WithRunExpectedOutput("One sentence"))
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny in Paris", answer)
+	assert.Equal(t, 65, info.TokensUsed)
+	assert.Equal(t, 50, info.PromptTokens)
+	assert.InDelta(t, 0.003, info.Cost, 1e-9)
+	assert.Equal(t, []string{"weather"}, info.ToolsUsed)
+	assert.Equal(t, "mock-1", info.Model)
+	assert.Positive(t, info.Duration)
+	require.NotNil(t, info.Output)
+
+	require.Len(t, calls, 2)
+	assert.Contains(t, calls[0][0].Content, "You are Forecaster")
+	assert.Contains(t, calls[0][0].Content, DefaultRunGoal)
+	assert.Contains(t, calls[0][1].Content, "What is the weather in Paris?")
+	assert.Contains(t, calls[0][1].Content, "One sentence")
+}
+
+type cityFacts struct {
+	City       string `json:"city" description:"Name of the city"`
+	Population int    `json:"population"`
+}
+
+func TestRunStructured(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: "Here you go: {\"city\": \"Paris\"}"},
+		{Content: "```json\n{\"city\": \"Paris\", \"population\": 2100000}\n```"},
+	})
+
+	facts, info, err := RunStructured[cityFacts](context.Background(), "Give facts about Paris",
+		WithRunLLM(mockLLM), WithRunLogger(logger.NewTestLogger()))
+	require.NoError(t, err)
+	assert.Equal(t, cityFacts{City: "Paris", Population: 2100000}, facts)
+	assert.Equal(t, 2, mockLLM.GetCallCount())
+	assert.True(t, info.Output.IsValid)
+
+	pointer, _, err := RunStructured[*cityFacts](context.Background(), "Give facts about Paris",
+		WithRunLLM(NewExtendedMockLLM([]llm.Response{{Content: `{"city": "Lyon", "population": 500000}`}})),
+		WithRunLogger(logger.NewTestLogger()))
+	require.NoError(t, err)
+	assert.Equal(t, "Lyon", pointer.City)
+
+	// 修正后仍不符合模式时返回错误
+	_, _, err = RunStructured[cityFacts](context.Background(), "Give facts about Paris",
+		WithRunLLM(NewExtendedMockLLM([]llm.Response{{Content: "I don't know"}})), WithRunLogger(logger.NewTestLogger()))
+	assert.ErrorContains(t, err, "output does not match cityFacts")
+
+	_, _, err = RunStructured[string](context.Background(), "Say hi", WithRunLLM(mockLLM))
+	assert.ErrorContains(t, err, "requires a struct type")
+}
+
+// blockingLLM 一直等到ctx取消
+type blockingLLM struct {
+	*ExtendedMockLLM
+}
+
+func (m *blockingLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(time.Second):
+		return &llm.Response{Content: "too late"}, nil
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	_, info, err := Run(context.Background(), "Hello")
+	assert.ErrorContains(t, err, "requires an LLM")
+	assert.Nil(t, info)
+
+	slow := &blockingLLM{NewExtendedMockLLM(nil)}
+	_, info, err = Run(context.Background(), "Hello", WithRunLLM(slow), WithRunTimeout(20*time.Millisecond),
+		WithRunLogger(logger.NewTestLogger()))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, info.Duration, time.Second)
+}
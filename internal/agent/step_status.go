@@ -0,0 +1,68 @@
+package agent
+
+import "time"
+
+// Agent当前步骤的类型
+const (
+	StepKindLLMCall  = "llm_call"
+	StepKindToolCall = "tool_call"
+)
+
+// StepStatus Agent正在执行的步骤快照，用于诊断卡住的执行
+type StepStatus struct {
+	TaskID    string        `json:"task_id"`
+	Kind      string        `json:"kind"`              // llm_call或tool_call
+	Tool      string        `json:"tool,omitempty"`    // tool_call的工具名称
+	Attempt   int           `json:"attempt,omitempty"` // llm_call的尝试次数，从1开始
+	StartedAt time.Time     `json:"started_at"`
+	Elapsed   time.Duration `json:"elapsed"` // 快照时步骤已经执行的时长
+}
+
+// StepReporter 能报告正在执行的步骤的Agent，BaseAgent实现了该接口
+type StepReporter interface {
+	// CurrentStep 返回任务当前的步骤，任务不在LLM调用或工具调用中时返回false
+	CurrentStep(taskID string) (StepStatus, bool)
+}
+
+// 确保BaseAgent实现了StepReporter接口
+var _ StepReporter = (*BaseAgent)(nil)
+
+// CurrentStep 返回任务当前的步骤
+func (a *BaseAgent) CurrentStep(taskID string) (StepStatus, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	step, ok := a.currentSteps[taskID]
+	if !ok {
+		return StepStatus{}, false
+	}
+	status := *step
+	status.Elapsed = time.Since(status.StartedAt)
+	return status, true
+}
+
+// beginStep 记录任务开始的步骤，返回结束该步骤的函数
+// 同一任务并行的工具调用只保留最近开始的步骤，结束较早的步骤不会清除较新的步骤
+func (a *BaseAgent) beginStep(task Task, step StepStatus) func() {
+	if task == nil {
+		return func() {}
+	}
+	step.TaskID = task.GetID()
+	step.StartedAt = time.Now()
+	current := &step
+
+	a.mu.Lock()
+	if a.currentSteps == nil {
+		a.currentSteps = make(map[string]*StepStatus)
+	}
+	a.currentSteps[step.TaskID] = current
+	a.mu.Unlock()
+
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.currentSteps[step.TaskID] == current {
+			delete(a.currentSteps, step.TaskID)
+		}
+	}
+}
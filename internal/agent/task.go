@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ynl/greensoulai/internal/llm"
 )
 
 // BaseTask 实现了Task接口的基础结构
@@ -19,22 +20,29 @@ type BaseTask struct {
 	humanInput         string
 	humanInputRequired bool
 	outputFormat       OutputFormat
+	outputSchema       *OutputSchema
 	tools              []Tool
 
 	// 新增字段，对标Python版本
-	assignedAgent  Agent // 对标Python版本的task.agent
-	asyncExecution bool  // 对标Python版本的task.async_execution
+	assignedAgent  Agent  // 对标Python版本的task.agent
+	agentRole      string // 按角色或ID指定执行的Agent，由Crew在成员中查找
+	asyncExecution bool   // 对标Python版本的task.async_execution
 
 	// Python版本对标的高级功能
 	name            string                                   // 对标Python的name
 	outputFile      string                                   // 对标Python的output_file
 	createDirectory bool                                     // 对标Python的create_directory
+	appendOutput    bool                                     // 为true时追加写入输出文件而不是覆盖
 	callback        func(context.Context, *TaskOutput) error // 对标Python的callback
 	contextTasks    []Task                                   // 对标Python的context: List[Task]
+	dependsOn       []string                                 // 依赖的任务ID列表
+	cacheDisabled   bool                                     // 是否跳过LLM响应缓存
 	retryCount      int                                      // 对标Python的retry_count
 	maxRetries      int                                      // 对标Python的max_retries
 	guardrail       TaskGuardrail                            // 对标Python的_guardrail
 	markdownOutput  bool                                     // 对标Python的markdown
+	requireApproval bool                                     // 输出是否需要人工审批
+	images          []llm.ContentPart                        // 图片附件
 
 	// 并发安全
 	mu sync.RWMutex
@@ -60,6 +68,7 @@ func NewBaseTask(description, expectedOutput string) *BaseTask {
 		createDirectory: true, // 对标Python默认值
 		callback:        nil,
 		contextTasks:    make([]Task, 0),
+		dependsOn:       make([]string, 0),
 		retryCount:      0,
 		maxRetries:      3, // 对标Python默认重试次数
 		guardrail:       nil,
@@ -67,14 +76,27 @@ func NewBaseTask(description, expectedOutput string) *BaseTask {
 	}
 }
 
+// taskIDNamespace 由任务名称派生任务ID时使用的UUID命名空间
+var taskIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/ynl/greensoulai/task"))
+
+// TaskIDFromName 返回由任务名称派生的稳定ID，同名任务在每次运行中得到相同的ID
+func TaskIDFromName(name string) string {
+	return uuid.NewSHA1(taskIDNamespace, []byte(name)).String()
+}
+
 // NewTaskWithOptions 使用选项创建任务
+// 通过WithName设置了名称且没有用WithID指定ID时，任务ID由名称派生，重放和评估可以跨运行按ID查找任务
 func NewTaskWithOptions(description, expectedOutput string, options ...TaskOption) *BaseTask {
 	task := NewBaseTask(description, expectedOutput)
+	randomID := task.id
 
 	for _, option := range options {
 		option(task)
 	}
 
+	if task.name != "" && task.id == randomID {
+		task.id = TaskIDFromName(task.name)
+	}
 	return task
 }
 
@@ -88,6 +110,20 @@ func WithContext(context map[string]interface{}) TaskOption {
 	}
 }
 
+// WithName 设置任务名称，未指定ID时任务ID由名称派生
+func WithName(name string) TaskOption {
+	return func(t *BaseTask) {
+		t.name = name
+	}
+}
+
+// WithMarkdown 设置是否要求Agent以Markdown格式输出最终答案
+func WithMarkdown(markdown bool) TaskOption {
+	return func(t *BaseTask) {
+		t.markdownOutput = markdown
+	}
+}
+
 // WithHumanInput 设置任务需要人工输入
 func WithHumanInput(required bool) TaskOption {
 	return func(t *BaseTask) {
@@ -102,6 +138,20 @@ func WithOutputFormat(format OutputFormat) TaskOption {
 	}
 }
 
+// WithOutputSchema 设置结构化输出模式
+func WithOutputSchema(schema *OutputSchema) TaskOption {
+	return func(t *BaseTask) {
+		t.outputSchema = schema
+	}
+}
+
+// WithCacheDisabled 禁用该任务的LLM响应缓存
+func WithCacheDisabled() TaskOption {
+	return func(t *BaseTask) {
+		t.cacheDisabled = true
+	}
+}
+
 // WithTools 设置任务专用工具
 func WithTools(tools ...Tool) TaskOption {
 	return func(t *BaseTask) {
@@ -109,6 +159,41 @@ func WithTools(tools ...Tool) TaskOption {
 	}
 }
 
+// WithDependsOn 设置任务依赖的任务ID
+func WithDependsOn(taskIDs ...string) TaskOption {
+	return func(t *BaseTask) {
+		t.dependsOn = append(t.dependsOn, taskIDs...)
+	}
+}
+
+// WithGuardrail 设置函数形式的任务护栏
+func WithGuardrail(guardrail Guardrail) TaskOption {
+	return func(t *BaseTask) {
+		t.guardrail = guardrail
+	}
+}
+
+// WithApproval 设置任务输出是否需要人工审批
+func WithApproval(required bool) TaskOption {
+	return func(t *BaseTask) {
+		t.requireApproval = required
+	}
+}
+
+// WithOutputFile 设置输出文件路径，支持{date}、{task_name}、{crew_name}模板变量
+func WithOutputFile(filename string) TaskOption {
+	return func(t *BaseTask) {
+		t.outputFile = filename
+	}
+}
+
+// WithOutputFileAppend 设置是否追加写入输出文件
+func WithOutputFileAppend(appendOutput bool) TaskOption {
+	return func(t *BaseTask) {
+		t.appendOutput = appendOutput
+	}
+}
+
 // WithID 设置任务ID (用于测试或特殊情况)
 func WithID(id string) TaskOption {
 	return func(t *BaseTask) {
@@ -248,26 +333,69 @@ func (t *BaseTask) SetOutputFormat(format OutputFormat) {
 	t.outputFormat = format
 }
 
+// GetOutputSchema 获取结构化输出模式
+func (t *BaseTask) GetOutputSchema() *OutputSchema {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.outputSchema
+}
+
+// SetOutputSchema 设置结构化输出模式
+func (t *BaseTask) SetOutputSchema(schema *OutputSchema) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.outputSchema = schema
+}
+
 // Clone 创建任务副本
+// 副本使用新的ID（由名称派生的ID保持不变），重试计数清零；上下文、工具、依赖和上下文任务列表各自复制，
+// 推理或规划修改副本的描述和上下文不会影响原任务。预分配的Agent和上下文任务仍指向原对象，
+// 需要整体复制时由调用方（如Crew.Clone）重新映射
 func (t *BaseTask) Clone() Task {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	clone := &BaseTask{
-		id:                 uuid.New().String(), // 新的ID
+		id:                 uuid.New().String(),
 		description:        t.description,
 		expectedOutput:     t.expectedOutput,
-		context:            make(map[string]interface{}),
+		context:            make(map[string]interface{}, len(t.context)),
 		humanInput:         t.humanInput,
 		humanInputRequired: t.humanInputRequired,
 		outputFormat:       t.outputFormat,
+		outputSchema:       t.outputSchema,
 		tools:              make([]Tool, len(t.tools)),
+		assignedAgent:      t.assignedAgent,
+		agentRole:          t.agentRole,
+		asyncExecution:     t.asyncExecution,
+		cacheDisabled:      t.cacheDisabled,
+		guardrail:          t.guardrail,
+		requireApproval:    t.requireApproval,
+		name:               t.name,
+		outputFile:         t.outputFile,
+		createDirectory:    t.createDirectory,
+		appendOutput:       t.appendOutput,
+		callback:           t.callback,
+		maxRetries:         t.maxRetries,
+		markdownOutput:     t.markdownOutput,
+	}
+
+	if t.name != "" && t.id == TaskIDFromName(t.name) {
+		clone.id = t.id
 	}
 
-	// 深拷贝上下文
+	// 复制上下文
 	for k, v := range t.context {
 		clone.context[k] = v
 	}
 
-	// 拷贝工具切片
-	copy(clone.tools, t.tools)
+	// 工具各自复制，避免副本之间共享使用计数
+	for i, tool := range t.tools {
+		clone.tools[i] = tool.Clone()
+	}
+	clone.dependsOn = append([]string{}, t.dependsOn...)
+	clone.contextTasks = append([]Task{}, t.contextTasks...)
+	clone.images = append([]llm.ContentPart(nil), t.images...)
 
 	return clone
 }
@@ -279,6 +407,7 @@ func (t *BaseTask) String() string {
 }
 
 // BaseConditionalTask 条件任务的基础实现，实现ConditionalTask接口
+// 包装一个BaseTask，Crew执行前用条件函数检查最近一个任务的输出，条件不满足时跳过该任务
 type BaseConditionalTask struct {
 	*BaseTask
 	condition         func(*TaskOutput) bool
@@ -287,21 +416,14 @@ type BaseConditionalTask struct {
 }
 
 // NewConditionalTask 创建条件任务实例
+// condition接收由上一个任务输出构造的map：包含输出JSON的各字段，以及"raw"（原始输出）和"agent"
 func NewConditionalTask(description, expectedOutput string, condition func(context map[string]interface{}) bool) *BaseConditionalTask {
-	// 适配函数签名，从map转换为TaskOutput检查
-	adaptedCondition := func(output *TaskOutput) bool {
+	task := WrapConditionalTask(NewBaseTask(description, expectedOutput), func(output *TaskOutput) bool {
 		if condition == nil {
 			return true
 		}
-		// 为了保持向后兼容，我们直接使用一个简单的实现
-		// 实际上我们需要把原始的condition函数存储起来，在ShouldExecuteSimple中使用
-		return true // 这里暂时返回true，实际逻辑在ShouldExecuteSimple中
-	}
-
-	task := &BaseConditionalTask{
-		BaseTask:  NewBaseTask(description, expectedOutput),
-		condition: adaptedCondition,
-	}
+		return condition(outputConditionContext(output))
+	})
 
 	// 保存原始条件函数以供ShouldExecuteSimple使用
 	task.originalCondition = condition
@@ -309,18 +431,44 @@ func NewConditionalTask(description, expectedOutput string, condition func(conte
 	return task
 }
 
+// WrapConditionalTask 将已有任务包装为条件任务，condition为nil时总是执行
+func WrapConditionalTask(task *BaseTask, condition func(*TaskOutput) bool) *BaseConditionalTask {
+	return &BaseConditionalTask{
+		BaseTask:  task,
+		condition: condition,
+	}
+}
+
+// outputConditionContext 将任务输出转换为map形式的条件上下文
+func outputConditionContext(output *TaskOutput) map[string]interface{} {
+	context := make(map[string]interface{})
+	if output == nil {
+		return context
+	}
+	for key, value := range outputJSON(output) {
+		context[key] = value
+	}
+	context["raw"] = output.Raw
+	context["agent"] = output.Agent
+	return context
+}
+
 // 实现ConditionalTask接口的方法
 
 // ShouldExecute 检查是否应该执行任务
+// 没有上一个任务的输出（如条件任务是第一个任务）时总是执行
 func (bct *BaseConditionalTask) ShouldExecute(ctx context.Context, context *TaskOutput) (bool, error) {
-	if bct.condition == nil {
+	condition := bct.GetCondition()
+	if condition == nil || context == nil {
 		return true, nil
 	}
-	return bct.condition(context), nil
+	return condition(context), nil
 }
 
 // GetCondition 获取条件函数
 func (bct *BaseConditionalTask) GetCondition() func(*TaskOutput) bool {
+	bct.mu.RLock()
+	defer bct.mu.RUnlock()
 	return bct.condition
 }
 
@@ -331,20 +479,35 @@ func (bct *BaseConditionalTask) SetCondition(condition func(*TaskOutput) bool) {
 	bct.condition = condition
 }
 
+// SetSkippedTaskOutput 设置跳过任务时使用的输出
+func (bct *BaseConditionalTask) SetSkippedTaskOutput(output *TaskOutput) {
+	bct.mu.Lock()
+	defer bct.mu.Unlock()
+	bct.skippedOutput = output
+}
+
 // GetSkippedTaskOutput 获取跳过任务时的默认输出
+// 默认输出的Raw明确说明任务被跳过，避免下游Agent把缺失的结果当作已有结果
 func (bct *BaseConditionalTask) GetSkippedTaskOutput() *TaskOutput {
+	bct.mu.RLock()
+	defer bct.mu.RUnlock()
+
 	if bct.skippedOutput != nil {
 		return bct.skippedOutput
 	}
 
 	// 返回默认的跳过输出
 	return &TaskOutput{
-		Raw:            "Task skipped due to condition not met",
+		Raw: fmt.Sprintf("[SKIPPED] The task %q was skipped because its condition was not met. "+
+			"It produced no output; do not assume or invent its results.", bct.description),
 		Agent:          "",
+		Task:           bct.id,
+		Name:           bct.name,
 		Description:    bct.description,
 		ExpectedOutput: bct.expectedOutput,
 		OutputFormat:   OutputFormatRAW,
 		CreatedAt:      time.Now(),
+		IsValid:        true,
 		Metadata: map[string]interface{}{
 			"skipped": true,
 			"reason":  "condition_not_met",
@@ -352,6 +515,20 @@ func (bct *BaseConditionalTask) GetSkippedTaskOutput() *TaskOutput {
 	}
 }
 
+// Clone 创建条件任务副本，保留条件函数和跳过输出
+func (bct *BaseConditionalTask) Clone() Task {
+	bct.mu.RLock()
+	condition, originalCondition, skippedOutput := bct.condition, bct.originalCondition, bct.skippedOutput
+	bct.mu.RUnlock()
+
+	return &BaseConditionalTask{
+		BaseTask:          bct.BaseTask.Clone().(*BaseTask),
+		condition:         condition,
+		originalCondition: originalCondition,
+		skippedOutput:     skippedOutput,
+	}
+}
+
 // 为了向后兼容，保留原有的ShouldExecute方法签名
 func (bct *BaseConditionalTask) ShouldExecuteSimple(context map[string]interface{}) bool {
 	if bct.originalCondition == nil {
@@ -361,6 +538,15 @@ func (bct *BaseConditionalTask) ShouldExecuteSimple(context map[string]interface
 	return bct.originalCondition(context)
 }
 
+// IsSkippedOutput 判断任务输出是否来自被跳过的条件任务
+func IsSkippedOutput(output *TaskOutput) bool {
+	if output == nil {
+		return false
+	}
+	skipped, _ := output.Metadata["skipped"].(bool)
+	return skipped
+}
+
 // TaskBuilder 任务构建器
 type TaskBuilder struct {
 	task *BaseTask
@@ -582,6 +768,20 @@ func (t *BaseTask) SetAssignedAgent(agent Agent) error {
 	return nil
 }
 
+// GetAgentRole 获取指定执行该任务的Agent角色或ID
+func (t *BaseTask) GetAgentRole() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.agentRole
+}
+
+// SetAgentRole 按角色或ID指定执行该任务的Agent，适用于任务定义时还没有Agent实例的场景
+func (t *BaseTask) SetAgentRole(role string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.agentRole = role
+}
+
 // IsAsyncExecution 检查任务是否为异步执行（对标Python的task.async_execution）
 func (t *BaseTask) IsAsyncExecution() bool {
 	return t.asyncExecution
@@ -649,6 +849,20 @@ func (t *BaseTask) SetCreateDirectory(create bool) {
 	t.createDirectory = create
 }
 
+// IsOutputFileAppend 检查是否追加写入输出文件
+func (t *BaseTask) IsOutputFileAppend() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.appendOutput
+}
+
+// SetOutputFileAppend 设置是否追加写入输出文件，默认覆盖
+func (t *BaseTask) SetOutputFileAppend(appendOutput bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.appendOutput = appendOutput
+}
+
 // GetCallback 获取回调函数
 func (t *BaseTask) GetCallback() func(context.Context, *TaskOutput) error {
 	t.mu.RLock()
@@ -681,6 +895,38 @@ func (t *BaseTask) SetContextTasks(tasks []Task) {
 	copy(t.contextTasks, tasks)
 }
 
+// GetDependsOn 获取依赖的任务ID列表
+// 与ContextTasks一起构成Crew调度时的依赖关系
+func (t *BaseTask) GetDependsOn() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	result := make([]string, len(t.dependsOn))
+	copy(result, t.dependsOn)
+	return result
+}
+
+// SetDependsOn 设置依赖的任务ID列表
+func (t *BaseTask) SetDependsOn(taskIDs []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dependsOn = make([]string, len(taskIDs))
+	copy(t.dependsOn, taskIDs)
+}
+
+// IsCacheDisabled 是否跳过LLM响应缓存和工具结果缓存
+func (t *BaseTask) IsCacheDisabled() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.cacheDisabled
+}
+
+// SetCacheDisabled 设置是否跳过LLM响应缓存和工具结果缓存
+func (t *BaseTask) SetCacheDisabled(disabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cacheDisabled = disabled
+}
+
 // GetRetryCount 获取当前重试次数
 func (t *BaseTask) GetRetryCount() int {
 	t.mu.RLock()
@@ -737,6 +983,48 @@ func (t *BaseTask) SetMarkdownOutput(markdown bool) {
 	t.markdownOutput = markdown
 }
 
+// IsApprovalRequired 检查任务输出是否需要人工审批
+func (t *BaseTask) IsApprovalRequired() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.requireApproval
+}
+
+// SetApprovalRequired 设置任务输出是否需要人工审批
+func (t *BaseTask) SetApprovalRequired(required bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requireApproval = required
+}
+
+// AddImage 添加图片附件，执行时随任务提示一起发送给模型
+// source为http(s)或data URL时直接引用，否则作为本地文件立即读取并以base64内嵌
+func (t *BaseTask) AddImage(source string) error {
+	if source == "" {
+		return fmt.Errorf("image source cannot be empty")
+	}
+
+	image := llm.ImageURLPart(source, llm.ImageDetailAuto)
+	if !llm.IsImageURL(source) {
+		var err error
+		if image, err = llm.ImageFilePart(source, llm.ImageDetailAuto); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.images = append(t.images, image)
+	return nil
+}
+
+// GetImages 获取图片附件
+func (t *BaseTask) GetImages() []llm.ContentPart {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]llm.ContentPart(nil), t.images...)
+}
+
 // 新增任务选项，支持Agent预分配和异步执行
 
 // WithAssignedAgent 设置任务预分配的Agent
@@ -746,6 +1034,13 @@ func WithAssignedAgent(agent Agent) TaskOption {
 	}
 }
 
+// WithAgentRole 按角色或ID指定执行任务的Agent
+func WithAgentRole(role string) TaskOption {
+	return func(task *BaseTask) {
+		task.agentRole = role
+	}
+}
+
 // WithAsyncExecution 设置任务为异步执行
 func WithAsyncExecution(async bool) TaskOption {
 	return func(task *BaseTask) {
@@ -1,6 +1,9 @@
 package agent
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -79,6 +82,32 @@ func TestTaskWithOptions(t *testing.T) {
 	}
 }
 
+func TestTaskNameDerivesStableID(t *testing.T) {
+	first := NewTaskWithOptions("Research", "Notes", WithName("research"), WithMarkdown(true))
+	second := NewTaskWithOptions("Research again", "Notes", WithName("research"))
+
+	if first.GetName() != "research" {
+		t.Errorf("expected name research, got %s", first.GetName())
+	}
+	if first.GetID() != TaskIDFromName("research") || second.GetID() != first.GetID() {
+		t.Errorf("expected IDs derived from the name, got %s and %s", first.GetID(), second.GetID())
+	}
+	if !first.IsMarkdownOutput() || second.IsMarkdownOutput() {
+		t.Error("expected only the first task to request markdown output")
+	}
+	if clone := first.Clone(); clone.GetID() != first.GetID() {
+		t.Errorf("expected clone of a named task to keep its ID, got %s", clone.GetID())
+	}
+
+	// 显式指定的ID优先，未命名的任务使用随机ID
+	if task := NewTaskWithOptions("Research", "Notes", WithID("custom"), WithName("research")); task.GetID() != "custom" {
+		t.Errorf("expected explicit ID to win, got %s", task.GetID())
+	}
+	if NewTaskWithOptions("Research", "Notes").GetID() == NewTaskWithOptions("Research", "Notes").GetID() {
+		t.Error("expected unnamed tasks to get distinct IDs")
+	}
+}
+
 func TestTaskValidation(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -241,6 +270,52 @@ func TestTaskTools(t *testing.T) {
 	}
 }
 
+func TestTaskImages(t *testing.T) {
+	task := NewBaseTask("Describe the chart", "A description")
+
+	if err := task.AddImage("https://example.com/chart.png"); err != nil {
+		t.Fatalf("failed to add image URL: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("\xff\xd8\xff\xe0"), 0o644); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+	if err := task.AddImage(path); err != nil {
+		t.Fatalf("failed to add image file: %v", err)
+	}
+
+	images := task.GetImages()
+	if len(images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(images))
+	}
+	if images[0].ImageURL.URL != "https://example.com/chart.png" {
+		t.Errorf("expected the URL to be referenced, got %s", images[0].ImageURL.URL)
+	}
+	if !strings.HasPrefix(images[1].ImageURL.URL, "data:image/jpeg;base64,") {
+		t.Errorf("expected the file to be embedded as a data URL, got %s", images[1].ImageURL.URL)
+	}
+
+	// 不存在的文件和非图片文件返回错误
+	if err := task.AddImage(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	textPath := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(textPath, []byte("not an image"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := task.AddImage(textPath); err == nil {
+		t.Error("expected an error for a file that is not an image")
+	}
+
+	// 克隆的任务保留图片附件
+	if cloned := task.Clone(); len(cloned.GetImages()) != 2 {
+		t.Errorf("expected the clone to keep 2 images, got %d", len(cloned.GetImages()))
+	}
+	if len(task.GetImages()) != 2 {
+		t.Errorf("expected failed additions to be ignored, got %d images", len(task.GetImages()))
+	}
+}
+
 func TestTaskClone(t *testing.T) {
 	originalBase := NewTaskWithOptions(
 		"Original task",
@@ -251,6 +326,7 @@ func TestTaskClone(t *testing.T) {
 		}),
 		WithHumanInput(true),
 		WithOutputFormat(OutputFormatJSON),
+		WithDependsOn("upstream"),
 	)
 	original := Task(originalBase)
 
@@ -295,6 +371,15 @@ func TestTaskClone(t *testing.T) {
 		t.Error("cloned task should have different ID")
 	}
 
+	// 验证依赖被复制且互不影响
+	if deps := cloned.GetDependsOn(); len(deps) != 1 || deps[0] != "upstream" {
+		t.Errorf("cloned dependencies mismatch: expected [upstream], got %v", deps)
+	}
+	cloned.SetDependsOn([]string{"other"})
+	if deps := original.GetDependsOn(); len(deps) != 1 || deps[0] != "upstream" {
+		t.Errorf("original dependencies should be unaffected, got %v", deps)
+	}
+
 	// 验证上下文
 	originalContext := original.GetContext()
 	clonedContext := cloned.GetContext()
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CacheFunc 判断一次工具调用的结果是否可以缓存，对应Python版本工具的cache_function
+type CacheFunc func(args map[string]interface{}, result interface{}) bool
+
+// ToolCache 工具结果缓存接口，Crew为其所有Agent注入同一个实例
+type ToolCache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, result interface{}, ttl time.Duration)
+	Clear()
+}
+
+// CacheableTool 支持结果缓存的工具，BaseTool通过WithCache开启
+type CacheableTool interface {
+	Tool
+	CacheTTL() time.Duration // <=0表示不缓存
+	ShouldCache(args map[string]interface{}, result interface{}) bool
+	RecordCacheHit()
+	GetCacheHits() int
+}
+
+// ToolResultCache 内存中的工具结果缓存，条目按写入时指定的TTL过期
+type ToolResultCache struct {
+	mu      sync.Mutex
+	entries map[string]toolCacheEntry
+}
+
+type toolCacheEntry struct {
+	result    interface{}
+	expiresAt time.Time
+}
+
+// NewToolResultCache 创建内存工具结果缓存
+func NewToolResultCache() *ToolResultCache {
+	return &ToolResultCache{entries: make(map[string]toolCacheEntry)}
+}
+
+// Get 返回未过期的缓存结果
+func (c *ToolResultCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// Set 写入缓存结果
+func (c *ToolResultCache) Set(key string, result interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = toolCacheEntry{result: result, expiresAt: time.Now().Add(ttl)}
+}
+
+// Clear 清空缓存
+func (c *ToolResultCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]toolCacheEntry)
+}
+
+// toolCacheKey 由工具名和规范化的参数JSON组成缓存键，map按键排序序列化
+func toolCacheKey(toolName string, args map[string]interface{}) (string, bool) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return toolName + ":" + string(data), true
+}
+
+// executeWithCache 执行工具，工具开启缓存且Agent配置了工具缓存时先查询缓存
+// 只有成功且通过CacheFunc判断的结果才会写入缓存；命中缓存不计入工具的使用次数
+func (ctx *ToolExecutionContext) executeWithCache(execCtx context.Context, tool Tool, args map[string]interface{}, onProgress func(ToolProgress)) (interface{}, error) {
+	cacheable, ok := tool.(CacheableTool)
+	if !ok || cacheable.CacheTTL() <= 0 || ctx.Agent == nil {
+		return runTool(execCtx, tool, args, onProgress)
+	}
+	cache := ctx.Agent.GetToolCache()
+	if cache == nil || (ctx.Task != nil && ctx.Task.IsCacheDisabled()) {
+		return runTool(execCtx, tool, args, onProgress)
+	}
+	key, ok := toolCacheKey(tool.GetName(), args)
+	if !ok {
+		return runTool(execCtx, tool, args, onProgress)
+	}
+
+	if result, hit := cache.Get(key); hit {
+		cacheable.RecordCacheHit()
+		return result, nil
+	}
+
+	result, err := runTool(execCtx, tool, args, onProgress)
+	if err == nil && cacheable.ShouldCache(args, result) {
+		cache.Set(key, result, cacheable.CacheTTL())
+	}
+	return result, err
+}
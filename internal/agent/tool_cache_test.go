@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCachedSearchTool(calls *int) *BaseTool {
+	return NewBaseToolWithSchema("web_search", "Search the web",
+		NewToolSchema("", "", ToolParameter{Name: "query", Type: "string", Required: true}),
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			*calls++
+			return fmt.Sprintf("results for %s #%d", args["query"], *calls), nil
+		},
+	)
+}
+
+// TestToolResultCaching 测试开启缓存的工具命中缓存时不再执行
+func TestToolResultCaching(t *testing.T) {
+	calls := 0
+	tool := newCachedSearchTool(&calls).WithCache(time.Minute)
+
+	agent, err := createTestAgent(NewExtendedMockLLM(nil))
+	require.NoError(t, err)
+	require.NoError(t, agent.AddTool(tool))
+	agent.SetToolCache(NewToolResultCache())
+	toolCtx := NewToolExecutionContext(agent, NewBaseTask("research", "results"))
+
+	first, err := toolCtx.ExecuteTool(context.Background(), "web_search", map[string]interface{}{"query": "go"})
+	require.NoError(t, err)
+	second, err := toolCtx.ExecuteTool(context.Background(), "web_search", map[string]interface{}{"query": "go"})
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	_, err = toolCtx.ExecuteTool(context.Background(), "web_search", map[string]interface{}{"query": "rust"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, tool.GetUsageCount())
+	assert.Equal(t, 1, tool.GetCacheHits())
+
+	// 任务禁用缓存时直接执行
+	noCacheTask := NewBaseTask("fresh research", "results")
+	noCacheTask.SetCacheDisabled(true)
+	_, err = NewToolExecutionContext(agent, noCacheTask).ExecuteTool(context.Background(), "web_search", map[string]interface{}{"query": "go"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+// TestToolCacheFuncAndExpiry 测试CacheFunc拒绝的结果和过期的结果不会被复用
+func TestToolCacheFuncAndExpiry(t *testing.T) {
+	calls := 0
+	tool := newCachedSearchTool(&calls).WithCache(time.Minute).
+		WithCacheFunc(func(args map[string]interface{}, result interface{}) bool {
+			return args["query"] != "news"
+		})
+
+	agent, err := createTestAgent(NewExtendedMockLLM(nil))
+	require.NoError(t, err)
+	require.NoError(t, agent.AddTool(tool))
+	agent.SetToolCache(NewToolResultCache())
+	toolCtx := NewToolExecutionContext(agent, NewBaseTask("research", "results"))
+
+	for i := 0; i < 2; i++ {
+		_, err := toolCtx.ExecuteTool(context.Background(), "web_search", map[string]interface{}{"query": "news"})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 0, tool.GetCacheHits())
+
+	cache := NewToolResultCache()
+	cache.Set("key", "value", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok := cache.Get("key")
+	assert.False(t, ok)
+}
+
+// TestToolCacheKeyCanonicalArgs 测试参数顺序不影响缓存键
+func TestToolCacheKeyCanonicalArgs(t *testing.T) {
+	first, ok := toolCacheKey("search", map[string]interface{}{"a": 1, "b": map[string]interface{}{"y": 2, "x": 1}})
+	require.True(t, ok)
+	second, ok := toolCacheKey("search", map[string]interface{}{"b": map[string]interface{}{"x": 1, "y": 2}, "a": 1})
+	require.True(t, ok)
+	assert.Equal(t, first, second)
+
+	other, _ := toolCacheKey("lookup", map[string]interface{}{"a": 1, "b": map[string]interface{}{"y": 2, "x": 1}})
+	assert.NotEqual(t, first, other)
+}
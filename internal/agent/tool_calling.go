@@ -0,0 +1,446 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// toolCallRequest LLM请求的一次工具调用
+type toolCallRequest struct {
+	ID        string
+	Name      string
+	Arguments map[string]interface{}
+	Native    bool // 是否来自LLM原生的tool_calls（否则来自JSON回退格式）
+}
+
+// toolLoopResult 工具调用循环的执行结果
+type toolLoopResult struct {
+	Response      *llm.Response
+	Usage         llm.Usage
+	ToolsUsed     []string
+	ToolUsage     map[string]int // 每个工具的调用次数
+	ToolCalls     int
+	Iterations    int
+	MaxIterations bool
+	LimitsHit     []string         // 触发的工具使用限制和预算
+	Context       contextTrimStats // 上下文窗口裁剪信息
+}
+
+// runToolCallingLoop 执行Agent的工具调用循环
+// 对标 crewAI Python版本 CrewAgentExecutor 的 invoke 循环：
+// 调用LLM -> 检测工具调用 -> 执行工具 -> 将结果作为观察追加到消息 -> 再次调用LLM，
+// 直到LLM给出最终答案或达到ExecutionConfig.MaxIterations。
+// 达到工具使用限制或ctx中预算的软限制时不再执行工具，由finishWithinLimits要求LLM直接给出最终答案
+func (a *BaseAgent) runToolCallingLoop(ctx context.Context, task Task, toolCtx *ToolExecutionContext, messages []llm.Message, callOptions *llm.CallOptions) (*toolLoopResult, error) {
+	maxIterations := a.executionConfig.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = 1
+	}
+
+	result := &toolLoopResult{ToolsUsed: []string{}, ToolUsage: make(map[string]int)}
+	limiter := newToolUsageLimiter(toolCtx.Tools, a.executionConfig)
+
+	for result.Iterations < maxIterations {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("task execution cancelled: %w", err)
+		}
+
+		response, trim, err := a.callLLMWithRetry(ctx, task, messages, callOptions)
+		if err != nil {
+			return nil, err
+		}
+		// 裁剪后的消息作为后续迭代的基础，避免每次迭代重复裁剪
+		messages = trim.Messages
+		result.Context.add(trim)
+		result.Iterations++
+		result.Response = response
+		result.Usage.PromptTokens += response.Usage.PromptTokens
+		result.Usage.CompletionTokens += response.Usage.CompletionTokens
+		result.Usage.TotalTokens += response.Usage.TotalTokens
+		result.Usage.Cost += response.Usage.Cost
+
+		// 没有可用工具时，响应内容即为最终答案
+		if !toolCtx.HasTools() {
+			return result, nil
+		}
+
+		calls := parseToolCalls(response)
+		if len(calls) == 0 {
+			return result, nil
+		}
+
+		// 之前的调用已经用完预算时不再执行工具
+		if limit, value, threshold := BudgetFrom(ctx).Exhausted(); limit != "" {
+			a.toolLimitHit(ctx, task, limiter, limit, value, threshold)
+			messages = appendSkippedToolCalls(messages, response, calls, limit)
+			return a.finishWithinLimits(ctx, task, result, limiter, messages, callOptions, limit)
+		}
+
+		if result.Iterations >= maxIterations {
+			result.MaxIterations = true
+			a.logger.Warn("Max iterations reached while agent was still requesting tools",
+				logger.Field{Key: "task_id", Value: task.GetID()},
+				logger.Field{Key: "max_iterations", Value: maxIterations},
+			)
+			return result, nil
+		}
+
+		messages = append(messages, llm.Message{
+			Role:      llm.RoleAssistant,
+			Content:   response.Content,
+			ToolCalls: response.ToolCalls,
+		})
+
+		// 达到限制后同一批次剩余的调用也不再执行，但每个调用都需要对应的观察
+		var stopped string
+		for _, call := range calls {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("task execution cancelled: %w", err)
+			}
+
+			if stopped == "" {
+				if limit, value, threshold := limiter.check(result, call); limit != "" {
+					a.toolLimitHit(ctx, task, limiter, limit, value, threshold)
+					stopped = limit
+				}
+			}
+			if stopped != "" {
+				messages = append(messages, buildObservationMessage(call, skippedToolObservation(stopped)))
+				continue
+			}
+
+			observation, invoked := a.invokeToolCall(ctx, task, toolCtx, call)
+			if invoked {
+				result.ToolCalls++
+				if result.ToolUsage[call.Name] == 0 {
+					result.ToolsUsed = append(result.ToolsUsed, call.Name)
+				}
+				result.ToolUsage[call.Name]++
+			}
+
+			messages = append(messages, buildObservationMessage(call, observation))
+		}
+
+		if stopped != "" {
+			return a.finishWithinLimits(ctx, task, result, limiter, messages, callOptions, stopped)
+		}
+	}
+
+	return result, nil
+}
+
+// toolLimitHit 记录触发的限制，第一次触发时记录日志并发出budget_exceeded事件
+func (a *BaseAgent) toolLimitHit(ctx context.Context, task Task, limiter *toolUsageLimiter, limit string, value, threshold float64) {
+	if !limiter.hit(limit) {
+		return
+	}
+	a.logger.Warn("Tool usage limit reached, requesting final answer",
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "limit", Value: limit},
+		logger.Field{Key: "value", Value: value},
+		logger.Field{Key: "threshold", Value: threshold},
+	)
+	a.emitBudgetExceeded(ctx, task, limit, value, threshold)
+}
+
+// finishWithinLimits 告知LLM预算已用尽，不提供工具再调用一次，响应作为最终答案
+func (a *BaseAgent) finishWithinLimits(ctx context.Context, task Task, result *toolLoopResult, limiter *toolUsageLimiter,
+	messages []llm.Message, callOptions *llm.CallOptions, limit string) (*toolLoopResult, error) {
+
+	result.LimitsHit = limiter.limitsHit
+	messages = append(messages, llm.Message{
+		Role:    llm.RoleUser,
+		Content: fmt.Sprintf(a.prompts.BudgetExhausted, limit),
+	})
+
+	var finalOptions *llm.CallOptions
+	if callOptions != nil {
+		options := *callOptions
+		options.Tools = nil
+		options.ToolChoice = nil
+		finalOptions = &options
+	}
+
+	response, trim, err := a.callLLMWithRetry(ctx, task, messages, finalOptions)
+	if err != nil {
+		return nil, err
+	}
+	result.Context.add(trim)
+	result.Iterations++
+	result.Response = response
+	result.Usage.PromptTokens += response.Usage.PromptTokens
+	result.Usage.CompletionTokens += response.Usage.CompletionTokens
+	result.Usage.TotalTokens += response.Usage.TotalTokens
+	result.Usage.Cost += response.Usage.Cost
+	return result, nil
+}
+
+// appendSkippedToolCalls 追加请求工具的响应和每个调用未执行的观察
+func appendSkippedToolCalls(messages []llm.Message, response *llm.Response, calls []toolCallRequest, limit string) []llm.Message {
+	messages = append(messages, llm.Message{
+		Role:      llm.RoleAssistant,
+		Content:   response.Content,
+		ToolCalls: response.ToolCalls,
+	})
+	for _, call := range calls {
+		messages = append(messages, buildObservationMessage(call, skippedToolObservation(limit)))
+	}
+	return messages
+}
+
+// skippedToolObservation 因达到限制而未执行的工具调用的观察
+func skippedToolObservation(limit string) string {
+	return fmt.Sprintf("Error: tool call not executed, the %s limit has been reached", limit)
+}
+
+// buildToolLoopOutput 根据工具调用循环的结果构建任务输出，token和成本为所有迭代的累计值
+// 达到最大迭代次数时最后的响应仍是工具调用，输出改为明确的停止说明并标记为无效
+func (a *BaseAgent) buildToolLoopOutput(task Task, result *toolLoopResult) *TaskOutput {
+	response := result.Response
+	if result.MaxIterations {
+		stopped := *response
+		stopped.Content = fmt.Sprintf("Agent stopped after reaching the maximum of %d iterations without a final answer", result.Iterations)
+		response = &stopped
+	}
+
+	output := a.buildTaskOutput(task, response)
+	output.TokensUsed = result.Usage.TotalTokens
+	output.PromptTokens = result.Usage.PromptTokens
+	output.CompletionTokens = result.Usage.CompletionTokens
+	output.Cost = result.Usage.Cost
+	output.ToolsUsed = result.ToolsUsed
+	output.Metadata[toolUsageMetadataKey] = result.ToolUsage
+	output.Metadata["prompt_tokens"] = result.Usage.PromptTokens
+	output.Metadata["completion_tokens"] = result.Usage.CompletionTokens
+	output.Metadata["iterations"] = result.Iterations
+	output.Metadata["tool_calls"] = result.ToolCalls
+	result.Context.apply(output)
+	if len(result.LimitsHit) > 0 {
+		output.Metadata[BudgetLimitsHitMetadataKey] = result.LimitsHit
+	}
+	if result.MaxIterations {
+		output.Metadata["max_iterations_reached"] = true
+		output.IsValid = false
+		output.ValidationError = output.Raw
+	}
+	return output
+}
+
+// invokeToolCall 执行单次工具调用并返回观察结果
+// 未知工具和工具执行错误都作为观察返回给LLM，而不是终止整个任务
+func (a *BaseAgent) invokeToolCall(ctx context.Context, task Task, toolCtx *ToolExecutionContext, call toolCallRequest) (string, bool) {
+	tool, found := findToolByName(toolCtx.Tools, call.Name)
+	if !found {
+		a.logger.Warn("LLM requested unknown tool",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "tool_name", Value: call.Name},
+		)
+		return fmt.Sprintf("Error: tool '%s' not found. Available tools: %s", call.Name, toolCtx.GetToolNames()), false
+	}
+	reproducibilityFrom(ctx).toolUsed(tool)
+
+	if a.eventBus != nil {
+		startEvent := NewAgentToolUsageStartedEvent(a.id, a.role, task.GetID(), call.Name, call.Arguments)
+		if err := a.eventBus.Emit(ctx, a, startEvent); err != nil {
+			a.logger.Error("Failed to emit tool usage started event",
+				logger.Field{Key: "error", Value: err})
+		}
+	}
+
+	startTime := time.Now()
+	endStep := a.beginStep(task, StepStatus{Kind: StepKindToolCall, Tool: call.Name})
+	output, err := toolCtx.ExecuteToolWithProgress(ctx, call.Name, call.Arguments, func(progress ToolProgress) {
+		a.reportToolProgress(ctx, task, call, progress)
+	})
+	endStep()
+	duration := time.Since(startTime)
+
+	if a.eventBus != nil {
+		finishedEvent := NewAgentToolUsageCompletedEvent(a.id, a.role, task.GetID(), call.Name, duration, err == nil, output, err)
+		if emitErr := a.eventBus.Emit(ctx, a, finishedEvent); emitErr != nil {
+			a.logger.Error("Failed to emit tool usage finished event",
+				logger.Field{Key: "error", Value: emitErr})
+		}
+	}
+
+	if a.stepCallback != nil {
+		step := &AgentStep{
+			StepID:      fmt.Sprintf("%s-%s", task.GetID(), call.Name),
+			StepType:    "tool_call",
+			Description: fmt.Sprintf("Execute tool %s", call.Name),
+			Input:       call.Arguments,
+			Output:      output,
+			Duration:    duration,
+			Success:     err == nil,
+			Error:       err,
+			ToolUsed:    call.Name,
+			Metadata:    make(map[string]interface{}),
+			Timestamp:   time.Now(),
+		}
+		if cbErr := a.stepCallback(ctx, step); cbErr != nil {
+			a.logger.Warn("Step callback failed", logger.Field{Key: "error", Value: cbErr})
+		}
+	}
+
+	if err != nil {
+		a.logger.Warn("Tool execution failed",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "tool_name", Value: call.Name},
+			logger.Field{Key: "error", Value: err},
+		)
+		return fmt.Sprintf("Error executing tool '%s': %v", call.Name, err), true
+	}
+
+	observation := formatToolOutput(output)
+	sourcesFrom(ctx).addToolResult(call.Name, call.Arguments, observation)
+	return injectionGuardFrom(ctx).screen(ctx, SourceKindTool, call.Name, observation), true
+}
+
+// reportToolProgress 把长时间运行的工具报告的进度转发为事件和步骤回调
+func (a *BaseAgent) reportToolProgress(ctx context.Context, task Task, call toolCallRequest, progress ToolProgress) {
+	if a.eventBus != nil {
+		event := NewAgentToolUsageProgressEvent(a.id, a.role, task.GetID(), call.Name, progress)
+		if err := a.eventBus.Emit(ctx, a, event); err != nil {
+			a.logger.Error("Failed to emit tool usage progress event",
+				logger.Field{Key: "error", Value: err})
+		}
+	}
+
+	if a.stepCallback != nil {
+		step := &AgentStep{
+			StepID:      fmt.Sprintf("%s-%s-progress", task.GetID(), call.Name),
+			StepType:    "tool_progress",
+			Description: fmt.Sprintf("Tool %s progress: %.0f%%", call.Name, progress.Percent),
+			Input:       call.Arguments,
+			Output:      progress.Partial,
+			Success:     true,
+			ToolUsed:    call.Name,
+			Metadata: map[string]interface{}{
+				"percent": progress.Percent,
+				"message": progress.Message,
+			},
+			Timestamp: time.Now(),
+		}
+		if cbErr := a.stepCallback(ctx, step); cbErr != nil {
+			a.logger.Warn("Step callback failed", logger.Field{Key: "error", Value: cbErr})
+		}
+	}
+}
+
+// buildObservationMessage 将工具观察结果构建为LLM消息
+func buildObservationMessage(call toolCallRequest, observation string) llm.Message {
+	if call.Native {
+		return llm.Message{
+			Role:       llm.RoleTool,
+			Content:    observation,
+			Name:       call.Name,
+			ToolCallID: call.ID,
+		}
+	}
+
+	return llm.Message{
+		Role:    llm.RoleUser,
+		Content: fmt.Sprintf("Observation (%s): %s", call.Name, observation),
+	}
+}
+
+// formatToolOutput 将工具输出格式化为字符串
+func formatToolOutput(output interface{}) string {
+	switch v := output.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+
+	if data, err := json.Marshal(output); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%v", output)
+}
+
+// parseToolCalls 从LLM响应中解析工具调用
+// 优先使用原生的tool_calls，否则回退到提示中约定的JSON格式：
+// {"tool_name": "<tool_name>", "arguments": {...}}
+func parseToolCalls(response *llm.Response) []toolCallRequest {
+	if response == nil {
+		return nil
+	}
+
+	if len(response.ToolCalls) > 0 {
+		calls := make([]toolCallRequest, 0, len(response.ToolCalls))
+		for _, tc := range response.ToolCalls {
+			if tc.Function.Name == "" {
+				continue
+			}
+			args := tc.Args
+			if args == nil {
+				args = make(map[string]interface{})
+				if tc.Function.Arguments != "" {
+					if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+						args = map[string]interface{}{"input": tc.Function.Arguments}
+					}
+				}
+			}
+			calls = append(calls, toolCallRequest{
+				ID:        tc.ID,
+				Name:      tc.Function.Name,
+				Arguments: args,
+				Native:    true,
+			})
+		}
+		return calls
+	}
+
+	if call, ok := parseJSONToolCall(response.Content); ok {
+		return []toolCallRequest{call}
+	}
+	return nil
+}
+
+// parseJSONToolCall 解析JSON回退格式的工具调用
+func parseJSONToolCall(content string) (toolCallRequest, bool) {
+	content = stripCodeFence(strings.TrimSpace(content))
+	if !strings.HasPrefix(content, "{") || !strings.HasSuffix(content, "}") {
+		return toolCallRequest{}, false
+	}
+
+	var payload struct {
+		ToolName  string                 `json:"tool_name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(content), &payload); err != nil || payload.ToolName == "" {
+		return toolCallRequest{}, false
+	}
+
+	if payload.Arguments == nil {
+		payload.Arguments = make(map[string]interface{})
+	}
+
+	return toolCallRequest{
+		Name:      payload.ToolName,
+		Arguments: payload.Arguments,
+	}, true
+}
+
+// stripCodeFence 去除Markdown代码块标记
+func stripCodeFence(content string) string {
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+
+	content = strings.TrimPrefix(content, "```")
+	if idx := strings.Index(content, "\n"); idx >= 0 {
+		content = content[idx+1:]
+	}
+	content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	return strings.TrimSpace(content)
+}
@@ -0,0 +1,357 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// newToolLoopTestAgent 创建用于工具调用循环测试的Agent
+func newToolLoopTestAgent(t *testing.T, mockLLM llm.LLM, eventBus events.EventBus, tools ...Tool) *BaseAgent {
+	t.Helper()
+
+	agent, err := NewBaseAgent(AgentConfig{
+		Role:      "Tool Loop Agent",
+		Goal:      "Use tools to complete tasks",
+		Backstory: "I call tools until I have a final answer",
+		LLM:       mockLLM,
+		EventBus:  eventBus,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	for _, tool := range tools {
+		require.NoError(t, agent.AddTool(tool))
+	}
+	return agent
+}
+
+// TestToolCallingLoopJSONFallback 测试JSON回退格式的工具调用循环
+func TestToolCallingLoopJSONFallback(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{
+			Content: "```json\n{\"tool_name\": \"calculator\", \"arguments\": {\"operation\": \"add\", \"a\": 5, \"b\": 3}}\n```",
+			Usage:   llm.Usage{PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30, Cost: 0.01},
+			Model:   "mock-model",
+		},
+		{
+			Content: "The answer is 8",
+			Usage:   llm.Usage{PromptTokens: 40, CompletionTokens: 5, TotalTokens: 45, Cost: 0.02},
+			Model:   "mock-model",
+		},
+	})
+	mockLLM.WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, append([]llm.Message(nil), messages...))
+	})
+
+	agent := newToolLoopTestAgent(t, mockLLM, nil, NewCalculatorTool())
+	task := NewBaseTask("Calculate 5 + 3", "The sum")
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Equal(t, "The answer is 8", output.Raw)
+	assert.Equal(t, []string{"calculator"}, output.ToolsUsed)
+	assert.Equal(t, 75, output.TokensUsed)
+	assert.InDelta(t, 0.03, output.Cost, 1e-9)
+	assert.Equal(t, 2, output.Metadata["iterations"])
+	assert.Equal(t, 1, output.Metadata["tool_calls"])
+
+	require.Len(t, calls, 2)
+	last := calls[1][len(calls[1])-1]
+	assert.Equal(t, llm.RoleUser, last.Role)
+	assert.Contains(t, last.Content, "Observation (calculator): 8")
+
+	stats := agent.GetExecutionStats()
+	assert.Equal(t, 1, stats.ToolsUsed["calculator"])
+}
+
+// TestToolCallingLoopNativeToolCalls 测试LLM原生tool_calls
+func TestToolCallingLoopNativeToolCalls(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{
+			ToolCalls: []llm.ToolCall{
+				{
+					ID:   "call_1",
+					Type: "function",
+					Function: llm.ToolCallFunction{
+						Name:      "calculator",
+						Arguments: `{"operation": "multiply", "a": 6, "b": 7}`,
+					},
+				},
+			},
+			Usage: llm.Usage{TotalTokens: 10},
+			Model: "mock-model",
+		},
+		{Content: "42", Usage: llm.Usage{TotalTokens: 10}, Model: "mock-model"},
+	})
+	mockLLM.WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, append([]llm.Message(nil), messages...))
+	})
+
+	agent := newToolLoopTestAgent(t, mockLLM, nil, NewCalculatorTool())
+	output, err := agent.Execute(context.Background(), NewBaseTask("Multiply 6 by 7", "The product"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "42", output.Raw)
+	assert.Equal(t, []string{"calculator"}, output.ToolsUsed)
+
+	require.Len(t, calls, 2)
+	last := calls[1][len(calls[1])-1]
+	assert.Equal(t, llm.RoleTool, last.Role)
+	assert.Equal(t, "calculator", last.Name)
+	assert.Equal(t, "42", last.Content)
+}
+
+// TestToolCallingLoopUnknownTool 测试未知工具作为错误观察返回给LLM
+func TestToolCallingLoopUnknownTool(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: `{"tool_name": "web_search", "arguments": {"query": "go"}}`, Usage: llm.Usage{TotalTokens: 10}},
+		{Content: "I could not search, here is my best answer", Usage: llm.Usage{TotalTokens: 10}},
+	})
+	mockLLM.WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, append([]llm.Message(nil), messages...))
+	})
+
+	agent := newToolLoopTestAgent(t, mockLLM, nil, NewCalculatorTool())
+	output, err := agent.Execute(context.Background(), NewBaseTask("Search for go", "Search results"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "I could not search, here is my best answer", output.Raw)
+	assert.Empty(t, output.ToolsUsed)
+
+	require.Len(t, calls, 2)
+	last := calls[1][len(calls[1])-1]
+	assert.Contains(t, last.Content, "tool 'web_search' not found")
+	assert.Contains(t, last.Content, "calculator")
+}
+
+// TestToolCallingLoopToolError 测试工具执行错误作为观察返回给LLM
+func TestToolCallingLoopToolError(t *testing.T) {
+	var calls [][]llm.Message
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: `{"tool_name": "calculator", "arguments": {"operation": "divide", "a": 1, "b": 0}}`},
+		{Content: "Division by zero is undefined"},
+	})
+	mockLLM.WithCallHandler(func(messages []llm.Message) {
+		calls = append(calls, append([]llm.Message(nil), messages...))
+	})
+
+	agent := newToolLoopTestAgent(t, mockLLM, nil, NewCalculatorTool())
+	output, err := agent.Execute(context.Background(), NewBaseTask("Divide 1 by 0", "The quotient"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Division by zero is undefined", output.Raw)
+	assert.Equal(t, []string{"calculator"}, output.ToolsUsed)
+
+	require.Len(t, calls, 2)
+	assert.Contains(t, calls[1][len(calls[1])-1].Content, "division by zero")
+}
+
+// TestToolCallingLoopMaxIterations 测试达到最大迭代次数时停止循环
+func TestToolCallingLoopMaxIterations(t *testing.T) {
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: `{"tool_name": "calculator", "arguments": {"operation": "add", "a": 1, "b": 1}}`, Usage: llm.Usage{TotalTokens: 5}},
+	})
+
+	agent := newToolLoopTestAgent(t, mockLLM, nil, NewCalculatorTool())
+	config := DefaultExecutionConfig()
+	config.MaxIterations = 3
+	require.NoError(t, agent.SetExecutionConfig(config))
+
+	output, err := agent.Execute(context.Background(), NewBaseTask("Loop forever", "Never finishes"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, mockLLM.callCount)
+	assert.Equal(t, 3, output.Metadata["iterations"])
+	assert.Equal(t, 2, output.Metadata["tool_calls"])
+	assert.Equal(t, true, output.Metadata["max_iterations_reached"])
+	assert.Equal(t, 15, output.TokensUsed)
+	assert.Equal(t, "Agent stopped after reaching the maximum of 3 iterations without a final answer", output.Raw)
+	assert.False(t, output.IsValid)
+	assert.Nil(t, output.JSON)
+
+	// 每次工具调用都计入统计
+	assert.Equal(t, map[string]int{"calculator": 2}, output.Metadata["tool_usage"])
+	assert.Equal(t, 2, agent.GetExecutionStats().ToolsUsed["calculator"])
+}
+
+// TestToolCallingLoopContextCancellation 测试循环中途取消上下文
+func TestToolCallingLoopContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cancellingTool := NewBaseTool("cancel_tool", "Cancels the context", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		cancel()
+		return "cancelled", nil
+	})
+
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: `{"tool_name": "cancel_tool", "arguments": {}}`},
+		{Content: "should not be reached"},
+	})
+
+	agent := newToolLoopTestAgent(t, mockLLM, nil, cancellingTool)
+	output, err := agent.Execute(ctx, NewBaseTask("Cancel me", "Nothing"))
+
+	require.Error(t, err)
+	assert.Nil(t, output)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, mockLLM.callCount)
+}
+
+// TestToolCallingLoopEvents 测试工具使用事件的发射
+func TestToolCallingLoopEvents(t *testing.T) {
+	eventBus := events.NewEventBus(logger.NewTestLogger())
+
+	var mu sync.Mutex
+	received := make(map[string]events.Event)
+	done := make(chan struct{}, 2)
+	handler := func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		received[event.GetType()] = event
+		mu.Unlock()
+		done <- struct{}{}
+		return nil
+	}
+	require.NoError(t, eventBus.Subscribe(events.EventTypeToolUsageStarted, handler))
+	require.NoError(t, eventBus.Subscribe(events.EventTypeToolUsageFinished, handler))
+
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: `{"tool_name": "calculator", "arguments": {"operation": "subtract", "a": 9, "b": 4}}`},
+		{Content: "5"},
+	})
+
+	agent := newToolLoopTestAgent(t, mockLLM, eventBus, NewCalculatorTool())
+	_, err := agent.Execute(context.Background(), NewBaseTask("Subtract 4 from 9", "The difference"))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for tool usage events")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	started, ok := received[events.EventTypeToolUsageStarted].(*AgentToolUsageStartedEvent)
+	require.True(t, ok)
+	assert.Equal(t, "calculator", started.ToolName)
+	assert.Equal(t, "subtract", started.Args["operation"])
+
+	finished, ok := received[events.EventTypeToolUsageFinished].(*AgentToolUsageCompletedEvent)
+	require.True(t, ok)
+	assert.True(t, finished.Success)
+	assert.Equal(t, float64(5), finished.Output)
+}
+
+// TestParseToolCalls 测试工具调用解析
+func TestParseToolCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *llm.Response
+		expected []string
+	}{
+		{
+			name:     "plain text",
+			response: &llm.Response{Content: "Just a final answer"},
+			expected: nil,
+		},
+		{
+			name:     "json without tool_name",
+			response: &llm.Response{Content: `{"result": 42}`},
+			expected: nil,
+		},
+		{
+			name:     "json fallback",
+			response: &llm.Response{Content: `{"tool_name": "calculator", "arguments": {"a": 1}}`},
+			expected: []string{"calculator"},
+		},
+		{
+			name:     "code fenced json fallback",
+			response: &llm.Response{Content: "```json\n{\"tool_name\": \"json_parser\"}\n```"},
+			expected: []string{"json_parser"},
+		},
+		{
+			name: "native tool calls",
+			response: &llm.Response{
+				Content: `{"tool_name": "ignored"}`,
+				ToolCalls: []llm.ToolCall{
+					{ID: "1", Function: llm.ToolCallFunction{Name: "file_reader", Arguments: `{"filepath": "a.txt"}`}},
+					{ID: "2", Function: llm.ToolCallFunction{Name: "text_analyzer"}},
+				},
+			},
+			expected: []string{"file_reader", "text_analyzer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := parseToolCalls(tt.response)
+			var names []string
+			for _, call := range calls {
+				names = append(names, call.Name)
+				assert.NotNil(t, call.Arguments)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+// TestToolCallingLoopWithoutFunctionCalling 测试不支持原生函数调用的本地模型走JSON回退格式
+func TestToolCallingLoopWithoutFunctionCalling(t *testing.T) {
+	var requests []map[string]interface{}
+	replies := []string{
+		`{"tool_name": "calculator", "arguments": {"operation": "add", "a": 2, "b": 2}}`,
+		"The answer is 4",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+
+		reply := replies[len(requests)-1]
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":             "llama3.2",
+			"message":           map[string]interface{}{"role": "assistant", "content": reply},
+			"done":              true,
+			"done_reason":       "stop",
+			"prompt_eval_count": 10,
+			"eval_count":        5,
+		})
+	}))
+	defer server.Close()
+
+	ollama := llm.NewOllamaLLM("llama3.2", llm.WithBaseURL(server.URL), llm.WithMaxRetries(0))
+	require.False(t, ollama.SupportsFunctionCalling())
+
+	agent := newToolLoopTestAgent(t, ollama, nil, NewCalculatorTool())
+	output, err := agent.Execute(context.Background(), NewBaseTask("Calculate 2 + 2", "The sum"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "The answer is 4", output.Raw)
+	assert.Equal(t, []string{"calculator"}, output.ToolsUsed)
+	assert.Equal(t, 30, output.TokensUsed)
+
+	require.Len(t, requests, 2)
+	for _, request := range requests {
+		assert.NotContains(t, request, "tools", "native tools must not be sent to models without function calling")
+	}
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// prefixedTool 以新名称暴露工具，用于解决外部工具与已有工具的重名
+type prefixedTool struct {
+	Tool
+	name string
+}
+
+func (t *prefixedTool) GetName() string {
+	return t.name
+}
+
+func (t *prefixedTool) GetSchema() ToolSchema {
+	schema := t.Tool.GetSchema()
+	schema.Name = t.name
+	return schema
+}
+
+// loadProviderTools 从所有ToolProvider发现工具并加入Agent，调用方需持有a.mu
+// 工具名与已有工具冲突时加上来源名称作为前缀，如github_search
+func (a *BaseAgent) loadProviderTools(ctx context.Context) error {
+	if len(a.toolProviders) == 0 {
+		return nil
+	}
+
+	existing := make(map[string]bool, len(a.tools))
+	for _, tool := range a.tools {
+		existing[tool.GetName()] = true
+	}
+	if a.providedTools == nil {
+		a.providedTools = make(map[string]bool)
+	}
+
+	for _, provider := range a.toolProviders {
+		tools, err := provider.DiscoverTools(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to discover tools from %s: %w", provider.GetName(), err)
+		}
+
+		for _, tool := range tools {
+			name := tool.GetName()
+			if existing[name] {
+				name = provider.GetName() + "_" + name
+				if existing[name] {
+					return fmt.Errorf("tool %s from %s conflicts with an existing tool", tool.GetName(), provider.GetName())
+				}
+				tool = &prefixedTool{Tool: tool, name: name}
+			}
+			existing[name] = true
+			a.providedTools[name] = true
+			a.tools = append(a.tools, tool)
+		}
+
+		a.logger.Info("tools discovered from provider",
+			logger.Field{Key: "agent_id", Value: a.id},
+			logger.Field{Key: "provider", Value: provider.GetName()},
+			logger.Field{Key: "tools_count", Value: len(tools)},
+		)
+	}
+	return nil
+}
+
+// closeToolProviders 关闭所有ToolProvider并移除它们提供的工具，调用方需持有a.mu
+func (a *BaseAgent) closeToolProviders() {
+	for _, provider := range a.toolProviders {
+		if err := provider.Close(); err != nil {
+			a.logger.Error("Failed to close tool provider",
+				logger.Field{Key: "provider", Value: provider.GetName()},
+				logger.Field{Key: "error", Value: err},
+			)
+		}
+	}
+
+	if len(a.providedTools) == 0 {
+		return
+	}
+	tools := make([]Tool, 0, len(a.tools))
+	for _, tool := range a.tools {
+		if !a.providedTools[tool.GetName()] {
+			tools = append(tools, tool)
+		}
+	}
+	a.tools = tools
+	a.providedTools = nil
+}
@@ -0,0 +1,282 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ToolParameter 声明工具的一个参数
+type ToolParameter struct {
+	Name        string
+	Type        string // JSON Schema类型：string、number、integer、boolean、array、object
+	Description string
+	Required    bool
+	Enum        []interface{} // 可选的取值范围
+}
+
+// NewToolSchema 根据参数声明构建工具模式，Parameters为完整的JSON Schema对象
+func NewToolSchema(name, description string, params ...ToolParameter) ToolSchema {
+	properties := make(map[string]interface{}, len(params))
+	required := make([]string, 0, len(params))
+	for _, param := range params {
+		property := map[string]interface{}{"type": param.Type}
+		if param.Description != "" {
+			property["description"] = param.Description
+		}
+		if len(param.Enum) > 0 {
+			property["enum"] = param.Enum
+		}
+		properties[param.Name] = property
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+
+	return ToolSchema{
+		Name:        name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		},
+		Required: required,
+	}
+}
+
+// JSONSchema 返回发送给LLM的参数JSON Schema
+// 缺少type和properties时补全为空对象模式，Required合并到required中
+func (s ToolSchema) JSONSchema() map[string]interface{} {
+	schema := make(map[string]interface{}, len(s.Parameters)+3)
+	for key, value := range s.Parameters {
+		schema[key] = value
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	if _, ok := schema["properties"]; !ok {
+		schema["properties"] = map[string]interface{}{}
+	}
+
+	required := append([]string(nil), stringList(schema["required"])...)
+	for _, name := range s.Required {
+		if !containsString(required, name) {
+			required = append(required, name)
+		}
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// toolNamePattern 主流LLM提供商接受的函数名称
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ValidateToolSchema 检查工具能否构建出LLM接受的函数声明：名称和描述、参数模式可以序列化、
+// 顶层为object、properties为对象且required中的参数都已声明
+// 不通过检查的工具在执行时会被静默过滤，dry run时据此提前报告
+func ValidateToolSchema(tool Tool) error {
+	if err := validateToolSchema(tool); err != nil {
+		return err
+	}
+	name := tool.GetName()
+	if !toolNamePattern.MatchString(name) {
+		return fmt.Errorf("tool name %q must be 1-64 letters, digits, '_' or '-'", name)
+	}
+
+	schema := tool.GetSchema().JSONSchema()
+	if _, err := json.Marshal(schema); err != nil {
+		return fmt.Errorf("tool %s: parameters are not valid JSON: %w", name, err)
+	}
+	if schemaType, _ := schema["type"].(string); schemaType != "object" {
+		return fmt.Errorf("tool %s: parameters must be an object schema, got type %v", name, schema["type"])
+	}
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("tool %s: properties must be an object", name)
+	}
+	for _, required := range stringList(schema["required"]) {
+		if _, declared := properties[required]; !declared {
+			return fmt.Errorf("tool %s: required parameter %q is not declared in properties", name, required)
+		}
+	}
+	return nil
+}
+
+// ToolArgumentsError 工具参数未通过模式校验
+// 错误信息列出所有问题和期望的参数，作为观察返回给LLM以便修正后重试
+type ToolArgumentsError struct {
+	Tool     string
+	Problems []string
+	Expected []string // 期望的参数说明，如 "a (number, required): First operand"
+}
+
+func (e *ToolArgumentsError) Error() string {
+	message := fmt.Sprintf("invalid arguments for tool '%s': %s", e.Tool, strings.Join(e.Problems, "; "))
+	if len(e.Expected) > 0 {
+		message += ". Expected parameters: " + strings.Join(e.Expected, "; ")
+	}
+	return message
+}
+
+// validateToolArguments 按工具模式校验参数并转换可以明确转换的基本类型
+// 返回转换后的参数副本；模式没有声明参数时原样返回
+func validateToolArguments(tool Tool, args map[string]interface{}) (map[string]interface{}, error) {
+	schema := tool.GetSchema()
+	jsonSchema := schema.JSONSchema()
+	properties, _ := jsonSchema["properties"].(map[string]interface{})
+	required := stringList(jsonSchema["required"])
+	if len(properties) == 0 && len(required) == 0 {
+		return args, nil
+	}
+
+	coerced := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		if propSchema, ok := properties[key].(map[string]interface{}); ok {
+			value = coerceToolArgument(value, propSchema)
+		}
+		coerced[key] = value
+	}
+
+	var problems []string
+	for _, name := range required {
+		if value, present := coerced[name]; !present || value == nil {
+			problems = append(problems, fmt.Sprintf("missing required parameter %q", name))
+		}
+	}
+
+	names := make([]string, 0, len(coerced))
+	for name := range coerced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propSchema, ok := properties[name].(map[string]interface{})
+		if !ok || coerced[name] == nil {
+			continue
+		}
+		problems = append(problems, validateSchemaValue(coerced[name], propSchema, name)...)
+	}
+
+	if len(problems) > 0 {
+		return nil, &ToolArgumentsError{
+			Tool:     tool.GetName(),
+			Problems: problems,
+			Expected: describeToolParameters(properties, required),
+		}
+	}
+	return coerced, nil
+}
+
+// coerceToolArgument 把字符串参数转换为模式要求的类型，只在转换结果明确时转换
+// LLM经常把数字、布尔值或JSON结构作为字符串传入；Go代码传入的整数统一为float64，与JSON解码结果一致
+func coerceToolArgument(value interface{}, schema map[string]interface{}) interface{} {
+	types := schemaTypes(schema["type"])
+	if len(types) != 1 {
+		return value
+	}
+	if number, ok := toFloat64(value); ok && (types[0] == "number" || types[0] == "integer") {
+		return number
+	}
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	trimmed := strings.TrimSpace(text)
+	switch types[0] {
+	case "number":
+		if number, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return number
+		}
+	case "integer":
+		if number, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+			return float64(number)
+		}
+	case "boolean":
+		switch strings.ToLower(trimmed) {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	case "array", "object":
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(trimmed), &decoded); err == nil && jsonTypeName(decoded) == types[0] {
+			return decoded
+		}
+	}
+	return value
+}
+
+// describeToolParameters 生成参数说明列表，按参数名排序
+func describeToolParameters(properties map[string]interface{}, required []string) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	descriptions := make([]string, 0, len(names))
+	for _, name := range names {
+		propSchema, _ := properties[name].(map[string]interface{})
+		details := strings.Join(schemaTypes(propSchema["type"]), " or ")
+		if details == "" {
+			details = "any"
+		}
+		if containsString(required, name) {
+			details += ", required"
+		}
+		description := fmt.Sprintf("%s (%s)", name, details)
+		if text, ok := propSchema["description"].(string); ok && text != "" {
+			description += ": " + text
+		}
+		descriptions = append(descriptions, description)
+	}
+	return descriptions
+}
+
+// containsString 判断列表中是否包含指定字符串
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// toFloat64 把Go数值类型转换为float64
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSchemaTestTool() *BaseTool {
+	return NewBaseToolWithSchema("search", "Search documents",
+		NewToolSchema("", "",
+			ToolParameter{Name: "query", Type: "string", Description: "Search query", Required: true},
+			ToolParameter{Name: "limit", Type: "integer", Description: "Maximum results"},
+			ToolParameter{Name: "exact", Type: "boolean"},
+			ToolParameter{Name: "mode", Type: "string", Enum: []interface{}{"fast", "full"}},
+		),
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return args, nil
+		},
+	)
+}
+
+// TestNewBaseToolWithSchema 测试工具模式输出为完整的JSON Schema
+func TestNewBaseToolWithSchema(t *testing.T) {
+	schema := newSchemaTestTool().GetSchema()
+
+	assert.Equal(t, "search", schema.Name)
+	assert.Equal(t, "Search documents", schema.Description)
+	assert.Equal(t, "object", schema.Parameters["type"])
+	assert.Equal(t, []string{"query"}, schema.Parameters["required"])
+
+	properties := schema.Parameters["properties"].(map[string]interface{})
+	assert.Len(t, properties, 4)
+	assert.Equal(t, map[string]interface{}{"type": "string", "description": "Search query"}, properties["query"])
+
+	// 没有声明参数的工具也返回合法的空对象模式
+	empty := NewBaseTool("noop", "No parameters", nil).GetSchema()
+	assert.Equal(t, map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}, empty.Parameters)
+}
+
+// TestValidateToolArguments 测试参数校验和基本类型转换
+func TestValidateToolArguments(t *testing.T) {
+	tool := newSchemaTestTool()
+
+	args, err := validateToolArguments(tool, map[string]interface{}{
+		"query": "golang",
+		"limit": "5",
+		"exact": "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, float64(5), args["limit"])
+	assert.Equal(t, true, args["exact"])
+
+	// 无法明确转换的值保持原样并报告类型错误
+	_, err = validateToolArguments(tool, map[string]interface{}{
+		"limit": "five",
+		"mode":  "slow",
+	})
+	require.Error(t, err)
+
+	var argsErr *ToolArgumentsError
+	require.True(t, errors.As(err, &argsErr))
+	assert.Equal(t, "search", argsErr.Tool)
+	assert.Len(t, argsErr.Problems, 3)
+	assert.Contains(t, err.Error(), `missing required parameter "query"`)
+	assert.Contains(t, err.Error(), "query (string, required): Search query")
+}
+
+// TestToolExecutionContextValidatesArguments 测试Agent执行工具前校验参数
+func TestToolExecutionContextValidatesArguments(t *testing.T) {
+	agent := NewMockAgent("test-agent", "test goal", "test backstory")
+	agent.AddTool(NewCalculatorTool())
+	toolCtx := NewToolExecutionContext(agent, NewBaseTask("test task", "expected output"))
+
+	// LLM以字符串传入的数字会被转换
+	result, err := toolCtx.ExecuteTool(context.Background(), "calculator", map[string]interface{}{
+		"operation": "multiply",
+		"a":         "6",
+		"b":         7,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), result)
+
+	_, err = toolCtx.ExecuteTool(context.Background(), "calculator", map[string]interface{}{
+		"operation": "power",
+		"a":         2,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid arguments for tool 'calculator'")
+	assert.Contains(t, err.Error(), `missing required parameter "b"`)
+}
+
+// TestValidateToolSchemaForLLM 测试无法构建函数声明的工具模式被报告
+func TestValidateToolSchemaForLLM(t *testing.T) {
+	assert.NoError(t, ValidateToolSchema(newSchemaTestTool()))
+	assert.NoError(t, ValidateToolSchema(NewCalculatorTool()))
+
+	for name, tool := range map[string]Tool{
+		"nil tool":         nil,
+		"invalid name":     NewBaseTool("web search", "Search the web", nil),
+		"non-object":       NewBaseToolWithSchema("lookup", "Look up", ToolSchema{Parameters: map[string]interface{}{"type": "string"}}, nil),
+		"bad properties":   NewBaseToolWithSchema("lookup", "Look up", ToolSchema{Parameters: map[string]interface{}{"properties": []string{"q"}}}, nil),
+		"undeclared param": NewBaseToolWithSchema("lookup", "Look up", ToolSchema{Required: []string{"query"}}, nil),
+		"not serializable": NewBaseToolWithSchema("lookup", "Look up", ToolSchema{Parameters: map[string]interface{}{"default": func() {}}}, nil),
+	} {
+		assert.Error(t, ValidateToolSchema(tool), name)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/tracing"
 )
 
 // parseTools 将原始工具转换为结构化工具
@@ -67,18 +70,16 @@ func renderTextDescriptionAndArgs(tools []Tool) string {
 
 		// 添加参数信息（如果有）
 		schema := tool.GetSchema()
-		if len(schema.Parameters) > 0 {
+		if properties, _ := schema.Parameters["properties"].(map[string]interface{}); len(properties) > 0 {
 			builder.WriteString("\n  Parameters:")
 
 			// 处理参数
-			if properties, ok := schema.Parameters["properties"].(map[string]interface{}); ok {
-				for paramName, paramInfo := range properties {
-					if paramMap, ok := paramInfo.(map[string]interface{}); ok {
-						if desc, exists := paramMap["description"]; exists {
-							builder.WriteString(fmt.Sprintf("\n    - %s: %v", paramName, desc))
-						} else {
-							builder.WriteString(fmt.Sprintf("\n    - %s", paramName))
-						}
+			for paramName, paramInfo := range properties {
+				if paramMap, ok := paramInfo.(map[string]interface{}); ok {
+					if desc, exists := paramMap["description"]; exists {
+						builder.WriteString(fmt.Sprintf("\n    - %s: %v", paramName, desc))
+					} else {
+						builder.WriteString(fmt.Sprintf("\n    - %s", paramName))
 					}
 				}
 			}
@@ -230,13 +231,30 @@ func (ctx *ToolExecutionContext) HasTools() bool {
 	return len(ctx.Tools) > 0
 }
 
-// ExecuteTool 执行指定工具
+// ExecuteTool 执行指定工具，并在ctx中的span上记录tool.call事件
 func (ctx *ToolExecutionContext) ExecuteTool(execCtx context.Context, toolName string, args map[string]interface{}) (interface{}, error) {
+	return ctx.ExecuteToolWithProgress(execCtx, toolName, args, nil)
+}
+
+// ExecuteToolWithProgress 执行指定工具，AsyncTool报告的进度交给onProgress，返回最终结果
+func (ctx *ToolExecutionContext) ExecuteToolWithProgress(execCtx context.Context, toolName string, args map[string]interface{}, onProgress func(ToolProgress)) (result interface{}, err error) {
+	defer func(start time.Time) {
+		tracing.AddEvent(execCtx, tracing.EventToolCall,
+			tracing.String("tool.name", toolName),
+			tracing.Bool("tool.success", err == nil),
+			tracing.Int64("tool.duration_ms", time.Since(start).Milliseconds()),
+		)
+	}(time.Now())
+
 	tool, found := findToolByName(ctx.Tools, toolName)
 	if !found {
 		return nil, fmt.Errorf("tool '%s' not found. Available tools: %s", toolName, ctx.GetToolNames())
 	}
 
-	// 这里可以添加执行前的验证和日志记录
-	return tool.Execute(execCtx, args)
+	// 执行前按工具模式校验参数，校验错误作为观察返回给LLM
+	validated, err := validateToolArguments(tool, args)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.executeWithCache(execCtx, tool, validated, onProgress)
 }
@@ -12,13 +12,19 @@ import (
 
 // BaseTool 实现了Tool接口的基础结构
 type BaseTool struct {
-	name        string
-	description string
-	schema      ToolSchema
-	handler     func(ctx context.Context, args map[string]interface{}) (interface{}, error)
-	usageCount  int
-	usageLimit  int
-	mu          sync.RWMutex
+	name             string
+	description      string
+	schema           ToolSchema
+	handler          func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+	usageCount       int
+	usageLimit       int
+	maxPerTask       int           // >0时限制单次任务执行中的调用次数
+	cacheTTL         time.Duration // >0时结果按TTL缓存
+	cacheFunc        CacheFunc
+	cacheHits        int
+	timeout          time.Duration // >0时单次执行超时后被取消
+	nondeterministic bool          // 输出不确定，严格可复现模式下使用时发出警告
+	mu               sync.RWMutex
 }
 
 // NewBaseTool 创建基础工具
@@ -38,6 +44,20 @@ func NewBaseTool(name, description string, handler func(ctx context.Context, arg
 	}
 }
 
+// NewBaseToolWithSchema 创建声明了参数模式的工具
+// Agent执行工具前按模式校验参数，并通过函数调用把参数定义发送给LLM
+func NewBaseToolWithSchema(name, description string, schema ToolSchema, handler func(ctx context.Context, args map[string]interface{}) (interface{}, error)) *BaseTool {
+	tool := NewBaseTool(name, description, handler)
+	if schema.Name == "" {
+		schema.Name = name
+	}
+	if schema.Description == "" {
+		schema.Description = description
+	}
+	tool.SetSchema(schema)
+	return tool
+}
+
 // GetName 返回工具名称
 func (t *BaseTool) GetName() string {
 	return t.name
@@ -48,9 +68,11 @@ func (t *BaseTool) GetDescription() string {
 	return t.description
 }
 
-// GetSchema 返回工具模式
+// GetSchema 返回工具模式，Parameters为完整的JSON Schema对象（包含required）
 func (t *BaseTool) GetSchema() ToolSchema {
-	return t.schema
+	schema := t.schema
+	schema.Parameters = schema.JSONSchema()
+	return schema
 }
 
 // Execute 执行工具
@@ -115,6 +137,7 @@ func (t *BaseTool) ResetUsage() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.usageCount = 0
+	t.cacheHits = 0
 }
 
 // IsUsageLimitExceeded 检查是否超出使用限制
@@ -124,6 +147,24 @@ func (t *BaseTool) IsUsageLimitExceeded() bool {
 	return t.usageLimit >= 0 && t.usageCount > t.usageLimit
 }
 
+// Clone 返回使用计数和缓存命中计数清零的副本，保留处理函数、使用限制和缓存设置
+func (t *BaseTool) Clone() Tool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return &BaseTool{
+		name:             t.name,
+		description:      t.description,
+		schema:           t.schema,
+		handler:          t.handler,
+		usageLimit:       t.usageLimit,
+		maxPerTask:       t.maxPerTask,
+		cacheTTL:         t.cacheTTL,
+		cacheFunc:        t.cacheFunc,
+		timeout:          t.timeout,
+		nondeterministic: t.nondeterministic,
+	}
+}
+
 // SetUsageLimit 设置使用限制
 func (t *BaseTool) SetUsageLimit(limit int) {
 	t.mu.Lock()
@@ -131,6 +172,97 @@ func (t *BaseTool) SetUsageLimit(limit int) {
 	t.usageLimit = limit
 }
 
+// WithMaxUsagePerTask 限制单次任务执行中的调用次数，达到后Agent不再执行该工具并给出最终答案
+func (t *BaseTool) WithMaxUsagePerTask(limit int) *BaseTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxPerTask = limit
+	return t
+}
+
+// MaxUsagePerTask 返回单次任务执行中的调用次数上限，0表示不限制
+func (t *BaseTool) MaxUsagePerTask() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.maxPerTask
+}
+
+// WithTimeout 设置单次执行的超时时间，超时后工具的ctx被取消，LLM收到超时的观察
+func (t *BaseTool) WithTimeout(timeout time.Duration) *BaseTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timeout = timeout
+	return t
+}
+
+// ExecutionTimeout 返回单次执行的超时时间，0表示不限制
+func (t *BaseTool) ExecutionTimeout() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.timeout
+}
+
+// WithCache 开启结果缓存，相同参数的调用在ttl内直接返回缓存结果
+// 缓存由执行工具的Agent提供，Crew为其Agent注入共享的工具缓存
+func (t *BaseTool) WithCache(ttl time.Duration) *BaseTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cacheTTL = ttl
+	return t
+}
+
+// WithCacheFunc 设置判断结果是否可缓存的函数，未设置时所有成功结果都可缓存
+func (t *BaseTool) WithCacheFunc(fn CacheFunc) *BaseTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cacheFunc = fn
+	return t
+}
+
+// CacheTTL 返回结果缓存时间，0表示不缓存
+func (t *BaseTool) CacheTTL() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.cacheTTL
+}
+
+// ShouldCache 判断本次结果是否可以缓存
+func (t *BaseTool) ShouldCache(args map[string]interface{}, result interface{}) bool {
+	t.mu.RLock()
+	fn := t.cacheFunc
+	t.mu.RUnlock()
+	return fn == nil || fn(args, result)
+}
+
+// RecordCacheHit 记录一次缓存命中
+func (t *BaseTool) RecordCacheHit() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cacheHits++
+}
+
+// GetCacheHits 返回缓存命中次数，命中不计入GetUsageCount
+func (t *BaseTool) GetCacheHits() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.cacheHits
+}
+
+// WithNondeterministic 声明工具的输出不确定（如依赖外部网页或当前时间），严格可复现模式下使用时发出警告
+func (t *BaseTool) WithNondeterministic() *BaseTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nondeterministic = true
+	return t
+}
+
+// IsNondeterministic 返回工具的输出是否不确定
+func (t *BaseTool) IsNondeterministic() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.nondeterministic
+}
+
 // SetSchema 设置工具模式
 func (t *BaseTool) SetSchema(schema ToolSchema) {
 	t.schema = schema
@@ -140,9 +272,16 @@ func (t *BaseTool) SetSchema(schema ToolSchema) {
 
 // NewCalculatorTool 创建计算器工具
 func NewCalculatorTool() Tool {
-	return NewBaseTool(
+	const description = "Perform basic mathematical calculations (add, subtract, multiply, divide)"
+	return NewBaseToolWithSchema(
 		"calculator",
-		"Perform basic mathematical calculations (add, subtract, multiply, divide)",
+		description,
+		NewToolSchema("calculator", description,
+			ToolParameter{Name: "operation", Type: "string", Description: "The operation to perform", Required: true,
+				Enum: []interface{}{"add", "subtract", "multiply", "divide"}},
+			ToolParameter{Name: "a", Type: "number", Description: "First operand", Required: true},
+			ToolParameter{Name: "b", Type: "number", Description: "Second operand", Required: true},
+		),
 		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 			operation, ok := args["operation"].(string)
 			if !ok {
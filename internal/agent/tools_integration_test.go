@@ -66,7 +66,10 @@ func TestAgentWithToolsIntegration(t *testing.T) {
 	assert.NotEmpty(t, output.Raw)
 	assert.Equal(t, "Math Assistant", output.Agent)
 	assert.Greater(t, output.TokensUsed, 0)
-	assert.Contains(t, output.Raw, "calculator") // 应该包含工具调用信息
+	assert.Equal(t, []string{"calculator"}, output.ToolsUsed)
+	// LLM一直请求工具，达到最大迭代次数后输出明确的停止说明
+	assert.Equal(t, true, output.Metadata["max_iterations_reached"])
+	assert.Contains(t, output.Raw, "maximum of")
 }
 
 // TestTaskSpecificToolsOverrideAgentTools 测试任务级工具覆盖Agent工具
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+// startExecutionSpan 开始agent.execute span，未启用追踪时返回原ctx和空操作span
+func (a *BaseAgent) startExecutionSpan(ctx context.Context, task Task, executionID int, stream bool) (context.Context, tracing.Span) {
+	if !tracing.Enabled() {
+		return tracing.Start(ctx, tracing.SpanAgentExecute)
+	}
+
+	toolNames := make([]string, 0, len(a.tools))
+	for _, tool := range a.tools {
+		toolNames = append(toolNames, tool.GetName())
+	}
+	model := ""
+	if a.llmProvider != nil {
+		model = a.llmProvider.GetModel()
+	}
+
+	return tracing.Start(ctx, tracing.SpanAgentExecute,
+		tracing.String("agent.id", a.id),
+		tracing.String("agent.role", a.role),
+		tracing.String("task.id", task.GetID()),
+		tracing.String("task.name", task.GetName()),
+		tracing.Int("agent.execution_id", executionID),
+		tracing.Bool("agent.stream", stream),
+		tracing.String("llm.model", model),
+		tracing.Strings("agent.tools", toolNames),
+	)
+}
+
+// endExecutionSpan 记录执行结果并结束span
+func endExecutionSpan(span tracing.Span, output *TaskOutput, err error) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(tracing.Bool("agent.success", err == nil))
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if output != nil {
+		span.SetAttributes(
+			tracing.Int("llm.total_tokens", output.TokensUsed),
+			tracing.Int("llm.prompt_tokens", output.PromptTokens),
+			tracing.Int("llm.completion_tokens", output.CompletionTokens),
+			tracing.Float64("llm.cost", output.Cost),
+			tracing.Int("llm.calls", output.LLMStats.Calls),
+			tracing.Int("llm.retries", output.LLMStats.Retries),
+			tracing.Strings("agent.tools_used", output.ToolsUsed),
+		)
+	}
+}
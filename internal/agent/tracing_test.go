@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+// useRecorder 在测试期间安装内存Recorder
+func useRecorder(t *testing.T) *tracing.Recorder {
+	t.Helper()
+	recorder := tracing.NewRecorder()
+	tracing.SetTracer(recorder)
+	t.Cleanup(func() { tracing.SetTracer(nil) })
+	return recorder
+}
+
+func TestAgentExecutionSpan(t *testing.T) {
+	recorder := useRecorder(t)
+
+	mockLLM := &FlakyMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{{Content: "recovered", Usage: llm.Usage{TotalTokens: 5}}}),
+		failures:        1,
+		err:             errors.New("HTTP error 429: rate limited"),
+	}
+	agent := newRetryTestAgent(t, mockLLM, nil, fastRetryPolicy(3))
+
+	parentCtx, parent := tracing.Start(context.Background(), "test.parent")
+	task := NewTaskWithOptions("Flaky task", "Some output", WithName("flaky"))
+	_, err := agent.Execute(parentCtx, task)
+	require.NoError(t, err)
+	parent.End()
+
+	spans := recorder.SpansNamed(tracing.SpanAgentExecute)
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "test.parent", span.Parent.Name)
+	assert.True(t, span.Ended)
+	assert.Equal(t, "Retry Agent", span.Attributes["agent.role"])
+	assert.Equal(t, task.GetID(), span.Attributes["task.id"])
+	assert.Equal(t, "flaky", span.Attributes["task.name"])
+	assert.Equal(t, true, span.Attributes["agent.success"])
+	assert.Equal(t, 5, span.Attributes["llm.total_tokens"])
+	assert.Equal(t, 1, span.Attributes["llm.retries"])
+
+	retries := span.EventsNamed(tracing.EventLLMRetry)
+	require.Len(t, retries, 1)
+	assert.Equal(t, 1, retries[0].Attributes["retry.attempt"])
+	assert.Equal(t, string(RetryOnRateLimit), retries[0].Attributes["retry.error_class"])
+}
+
+func TestAgentExecutionSpanRecordsGuardrailAttempts(t *testing.T) {
+	recorder := useRecorder(t)
+
+	mockLLM := NewExtendedMockLLM([]llm.Response{
+		{Content: "Go is a statically typed compiled language"},
+		{Content: "Typed compiled language"},
+	})
+	agent := newGuardrailTestAgent(t, mockLLM, nil, 3)
+
+	task := NewTaskWithOptions("Describe Go", "A short description", WithGuardrail(wordLimitGuardrail(3)))
+	_, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	spans := recorder.SpansNamed(tracing.SpanAgentExecute)
+	require.Len(t, spans, 1)
+	attempts := spans[0].EventsNamed(tracing.EventGuardrailAttempt)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, false, attempts[0].Attributes["guardrail.passed"])
+	assert.Equal(t, "the answer must be at most 3 words", attempts[0].Attributes["guardrail.feedback"])
+	assert.Equal(t, true, attempts[1].Attributes["guardrail.passed"])
+	assert.Equal(t, 2, attempts[1].Attributes["guardrail.attempt"])
+}
+
+func TestAgentExecutionSpanRecordsFailure(t *testing.T) {
+	recorder := useRecorder(t)
+
+	mockLLM := NewExtendedMockLLM([]llm.Response{{Content: "far too many words in this answer"}})
+	agent := newGuardrailTestAgent(t, mockLLM, nil, 0)
+
+	_, err := agent.Execute(context.Background(), NewTaskWithOptions("Describe Go", "A short description", WithGuardrail(wordLimitGuardrail(3))))
+	require.Error(t, err)
+
+	spans := recorder.SpansNamed(tracing.SpanAgentExecute)
+	require.Len(t, spans, 1)
+	assert.Equal(t, false, spans[0].Attributes["agent.success"])
+	assert.ErrorAs(t, spans[0].Err, new(*GuardrailError))
+}
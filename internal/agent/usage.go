@@ -0,0 +1,409 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// LLMCallStats 一次任务执行中LLM调用的统计
+type LLMCallStats struct {
+	Calls             int           `json:"calls"`   // 实际发出的LLM请求数，不含缓存命中
+	Retries           int           `json:"retries"` // 因可重试错误重新发出的请求数
+	CacheHits         int           `json:"cache_hits"`
+	CacheMisses       int           `json:"cache_misses"`
+	ThrottledRequests int           `json:"throttled_requests"` // 被速率控制器限流的请求数
+	RateLimitWait     time.Duration `json:"rate_limit_wait"`    // 等待速率控制器的总时长
+}
+
+// add 累加另一份调用统计
+func (s *LLMCallStats) add(other LLMCallStats) {
+	s.Calls += other.Calls
+	s.Retries += other.Retries
+	s.CacheHits += other.CacheHits
+	s.CacheMisses += other.CacheMisses
+	s.ThrottledRequests += other.ThrottledRequests
+	s.RateLimitWait += other.RateLimitWait
+}
+
+// callStatsCollector 收集一次任务执行中的LLM调用统计，通过ctx传给各执行路径
+// nil收集器的方法都是空操作
+type callStatsCollector struct {
+	mu    sync.Mutex
+	stats LLMCallStats
+	usage llm.Usage // 不产生任务输出的辅助调用（如知识查询改写）的token用量，计入任务输出
+}
+
+type callStatsCollectorKey struct{}
+
+// withCallStatsCollector 返回带有新收集器的ctx，嵌套执行（如委托给同事）各自统计
+func withCallStatsCollector(ctx context.Context) (context.Context, *callStatsCollector) {
+	collector := &callStatsCollector{}
+	return context.WithValue(ctx, callStatsCollectorKey{}, collector), collector
+}
+
+// callStatsFrom 返回ctx中的收集器，没有时返回nil
+func callStatsFrom(ctx context.Context) *callStatsCollector {
+	collector, _ := ctx.Value(callStatsCollectorKey{}).(*callStatsCollector)
+	return collector
+}
+
+// record 在锁内修改统计
+func (c *callStatsCollector) record(update func(stats *LLMCallStats)) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&c.stats)
+}
+
+// addUsage 记录辅助调用的token用量
+func (c *callStatsCollector) addUsage(usage llm.Usage) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage.PromptTokens += usage.PromptTokens
+	c.usage.CompletionTokens += usage.CompletionTokens
+	c.usage.TotalTokens += usage.TotalTokens
+	c.usage.Cost += usage.Cost
+}
+
+// RecordNestedUsage 把工具内部产生的开销（如作为工具执行的Crew）计入当前任务的输出和调用统计
+// 不在Agent执行任务期间调用时不做任何事
+func RecordNestedUsage(ctx context.Context, usage llm.Usage, llmCalls int) {
+	collector := callStatsFrom(ctx)
+	collector.addUsage(usage)
+	collector.record(func(stats *LLMCallStats) { stats.Calls += llmCalls })
+}
+
+// apply 把收集到的统计和辅助调用的token用量写入任务输出
+func (c *callStatsCollector) apply(output *TaskOutput) {
+	if c == nil || output == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	output.LLMStats.add(c.stats)
+	addUsageToOutput(output, c.usage)
+}
+
+// UsageMetrics 使用统计的累加器
+// 任务计数、token、成本和LLM调用统计都从任务输出汇总，并按Agent角色和模型分别统计，
+// Crew、Agent以及训练和评估流程都通过RecordTask和Add记录开销，保证各处的数字一致。
+// 所有方法并发安全；读取正在累加的统计时使用Snapshot返回的副本
+type UsageMetrics struct {
+	Runs             int           `json:"runs"`        // 汇总的执行次数（Kickoff或训练、评估的迭代）
+	TotalTasks       int           `json:"total_tasks"` // 记录的任务数，Crew的统计中为Crew的任务总数，包括未执行的任务
+	SuccessfulTasks  int           `json:"successful_tasks"`
+	FailedTasks      int           `json:"failed_tasks"`
+	SkippedTasks     int           `json:"skipped_tasks"` // 条件不满足而跳过的条件任务，不计为失败
+	TotalTokens      int           `json:"total_tokens"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	TotalCost        float64       `json:"total_cost"`
+	ExecutionTime    time.Duration `json:"execution_time"` // 各次执行的总耗时
+	TaskTime         time.Duration `json:"task_time"`      // 各任务执行耗时之和，用于计算平均任务耗时
+
+	// LLM调用统计
+	LLMCalls          int           `json:"llm_calls"`
+	Retries           int           `json:"retries"`
+	CacheHits         int           `json:"cache_hits"`
+	CacheMisses       int           `json:"cache_misses"`
+	ThrottledRequests int           `json:"throttled_requests"`
+	RateLimitWait     time.Duration `json:"rate_limit_wait"`
+
+	// AgentUsage 按Agent角色统计，用于定位开销最大的Agent
+	AgentUsage map[string]AgentUsage `json:"agent_usage,omitempty"`
+	// ModelUsage 按模型统计
+	ModelUsage map[string]ModelUsage `json:"model_usage,omitempty"`
+
+	// 速率控制器的实时状态，由Crew的GetUsageMetrics填充，不参与累加
+	MaxRPM             int     `json:"max_rpm,omitempty"`
+	RequestsLastMinute int     `json:"requests_last_minute,omitempty"`
+	RPMUtilization     float64 `json:"rpm_utilization,omitempty"`
+
+	mu sync.Mutex
+}
+
+// AgentUsage 单个Agent的使用统计
+type AgentUsage struct {
+	Tasks            int           `json:"tasks"`
+	DelegatedTasks   int           `json:"delegated_tasks,omitempty"` // 通过委托工具执行的任务数
+	LLMCalls         int           `json:"llm_calls"`
+	TotalTokens      int           `json:"total_tokens"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	TotalCost        float64       `json:"total_cost"`
+	TaskTime         time.Duration `json:"task_time"`
+}
+
+// add 累加另一份Agent统计
+func (u *AgentUsage) add(other AgentUsage) {
+	u.Tasks += other.Tasks
+	u.DelegatedTasks += other.DelegatedTasks
+	u.LLMCalls += other.LLMCalls
+	u.TotalTokens += other.TotalTokens
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalCost += other.TotalCost
+	u.TaskTime += other.TaskTime
+}
+
+// ModelUsage 单个模型的使用统计
+type ModelUsage struct {
+	Tasks            int     `json:"tasks"`
+	LLMCalls         int     `json:"llm_calls"`
+	TotalTokens      int     `json:"total_tokens"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalCost        float64 `json:"total_cost"`
+}
+
+// add 累加另一份模型统计
+func (u *ModelUsage) add(other ModelUsage) {
+	u.Tasks += other.Tasks
+	u.LLMCalls += other.LLMCalls
+	u.TotalTokens += other.TotalTokens
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalCost += other.TotalCost
+}
+
+// RecordTask 记录一个任务的结果并计入其开销
+// output为nil表示任务失败且没有输出；跳过的条件任务只计数，校验失败的输出计为失败但仍计入开销
+func (u *UsageMetrics) RecordTask(output *TaskOutput) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.TotalTasks++
+	switch {
+	case output == nil:
+		u.FailedTasks++
+		return
+	case IsSkippedOutput(output):
+		u.SkippedTasks++
+		return
+	case output.IsValid:
+		u.SuccessfulTasks++
+	default:
+		u.FailedTasks++
+	}
+	u.addTaskOutputLocked(output)
+}
+
+// AddTaskOutput 只计入任务输出的token、成本和LLM调用统计，不改变任务计数
+// 重放时复用的输出（元数据中带有replayed_from）没有产生新的开销，不计入
+func (u *UsageMetrics) AddTaskOutput(output *TaskOutput) {
+	if output == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.addTaskOutputLocked(output)
+}
+
+func (u *UsageMetrics) addTaskOutputLocked(output *TaskOutput) {
+	if _, replayed := output.Metadata["replayed_from"]; replayed {
+		return
+	}
+
+	u.TotalTokens += output.TokensUsed
+	u.PromptTokens += output.PromptTokens
+	u.CompletionTokens += output.CompletionTokens
+	u.TotalCost += output.Cost
+	u.TaskTime += output.ExecutionTime
+	u.addCallStatsLocked(output.LLMStats)
+
+	u.addAgentUsageLocked(output.Agent, AgentUsage{
+		Tasks:            1,
+		LLMCalls:         output.LLMStats.Calls,
+		TotalTokens:      output.TokensUsed,
+		PromptTokens:     output.PromptTokens,
+		CompletionTokens: output.CompletionTokens,
+		TotalCost:        output.Cost,
+		TaskTime:         output.ExecutionTime,
+	})
+	if output.Model != "" {
+		u.addModelUsageLocked(output.Model, ModelUsage{
+			Tasks:            1,
+			LLMCalls:         output.LLMStats.Calls,
+			TotalTokens:      output.TokensUsed,
+			PromptTokens:     output.PromptTokens,
+			CompletionTokens: output.CompletionTokens,
+			TotalCost:        output.Cost,
+		})
+	}
+}
+
+// AddDelegation 计入委托给另一个Agent执行的工作的开销，usage.DelegatedTasks为委托次数
+func (u *UsageMetrics) AddDelegation(role string, usage AgentUsage) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.TotalTokens += usage.TotalTokens
+	u.PromptTokens += usage.PromptTokens
+	u.CompletionTokens += usage.CompletionTokens
+	u.TotalCost += usage.TotalCost
+	u.LLMCalls += usage.LLMCalls
+	u.addAgentUsageLocked(role, usage)
+}
+
+// AddRun 记录一次执行（Kickoff或迭代）的耗时
+func (u *UsageMetrics) AddRun(duration time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Runs++
+	u.ExecutionTime += duration
+}
+
+// Add 合并另一份统计，包括按Agent和按模型的统计；速率控制器的实时状态不参与合并
+func (u *UsageMetrics) Add(other *UsageMetrics) {
+	if other == nil || other == u {
+		return
+	}
+	snapshot := other.Snapshot()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.Runs += snapshot.Runs
+	u.TotalTasks += snapshot.TotalTasks
+	u.SuccessfulTasks += snapshot.SuccessfulTasks
+	u.FailedTasks += snapshot.FailedTasks
+	u.SkippedTasks += snapshot.SkippedTasks
+	u.TotalTokens += snapshot.TotalTokens
+	u.PromptTokens += snapshot.PromptTokens
+	u.CompletionTokens += snapshot.CompletionTokens
+	u.TotalCost += snapshot.TotalCost
+	u.ExecutionTime += snapshot.ExecutionTime
+	u.TaskTime += snapshot.TaskTime
+	u.addCallStatsLocked(LLMCallStats{
+		Calls:             snapshot.LLMCalls,
+		Retries:           snapshot.Retries,
+		CacheHits:         snapshot.CacheHits,
+		CacheMisses:       snapshot.CacheMisses,
+		ThrottledRequests: snapshot.ThrottledRequests,
+		RateLimitWait:     snapshot.RateLimitWait,
+	})
+
+	for role, usage := range snapshot.AgentUsage {
+		u.addAgentUsageLocked(role, usage)
+	}
+	for model, usage := range snapshot.ModelUsage {
+		u.addModelUsageLocked(model, usage)
+	}
+}
+
+// AddUsageMetrics 与Add相同
+func (u *UsageMetrics) AddUsageMetrics(other *UsageMetrics) {
+	u.Add(other)
+}
+
+// Snapshot 返回统计的深拷贝，副本不会随后续的累加变化，可以安全地并发读取
+func (u *UsageMetrics) Snapshot() *UsageMetrics {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	snapshot := &UsageMetrics{
+		Runs:               u.Runs,
+		TotalTasks:         u.TotalTasks,
+		SuccessfulTasks:    u.SuccessfulTasks,
+		FailedTasks:        u.FailedTasks,
+		SkippedTasks:       u.SkippedTasks,
+		TotalTokens:        u.TotalTokens,
+		PromptTokens:       u.PromptTokens,
+		CompletionTokens:   u.CompletionTokens,
+		TotalCost:          u.TotalCost,
+		ExecutionTime:      u.ExecutionTime,
+		TaskTime:           u.TaskTime,
+		LLMCalls:           u.LLMCalls,
+		Retries:            u.Retries,
+		CacheHits:          u.CacheHits,
+		CacheMisses:        u.CacheMisses,
+		ThrottledRequests:  u.ThrottledRequests,
+		RateLimitWait:      u.RateLimitWait,
+		MaxRPM:             u.MaxRPM,
+		RequestsLastMinute: u.RequestsLastMinute,
+		RPMUtilization:     u.RPMUtilization,
+	}
+	if u.AgentUsage != nil {
+		snapshot.AgentUsage = make(map[string]AgentUsage, len(u.AgentUsage))
+		for role, usage := range u.AgentUsage {
+			snapshot.AgentUsage[role] = usage
+		}
+	}
+	if u.ModelUsage != nil {
+		snapshot.ModelUsage = make(map[string]ModelUsage, len(u.ModelUsage))
+		for model, usage := range u.ModelUsage {
+			snapshot.ModelUsage[model] = usage
+		}
+	}
+	return snapshot
+}
+
+// AverageTaskTime 返回产生输出的任务的平均耗时，跳过和没有输出的任务不计入
+func (u *UsageMetrics) AverageTaskTime() time.Duration {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	executed := 0
+	for _, usage := range u.AgentUsage {
+		executed += usage.Tasks
+	}
+	if executed == 0 {
+		return 0
+	}
+	return u.TaskTime / time.Duration(executed)
+}
+
+// AverageRunTime 返回每次执行的平均耗时
+func (u *UsageMetrics) AverageRunTime() time.Duration {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.Runs == 0 {
+		return 0
+	}
+	return u.ExecutionTime / time.Duration(u.Runs)
+}
+
+// CacheHitRate 返回LLM响应缓存的命中率，没有缓存查询时为0
+func (u *UsageMetrics) CacheHitRate() float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	lookups := u.CacheHits + u.CacheMisses
+	if lookups == 0 {
+		return 0
+	}
+	return float64(u.CacheHits) / float64(lookups)
+}
+
+func (u *UsageMetrics) addCallStatsLocked(stats LLMCallStats) {
+	u.LLMCalls += stats.Calls
+	u.Retries += stats.Retries
+	u.CacheHits += stats.CacheHits
+	u.CacheMisses += stats.CacheMisses
+	u.ThrottledRequests += stats.ThrottledRequests
+	u.RateLimitWait += stats.RateLimitWait
+}
+
+func (u *UsageMetrics) addAgentUsageLocked(role string, usage AgentUsage) {
+	if u.AgentUsage == nil {
+		u.AgentUsage = make(map[string]AgentUsage)
+	}
+	current := u.AgentUsage[role]
+	current.add(usage)
+	u.AgentUsage[role] = current
+}
+
+func (u *UsageMetrics) addModelUsageLocked(model string, usage ModelUsage) {
+	if u.ModelUsage == nil {
+		u.ModelUsage = make(map[string]ModelUsage)
+	}
+	current := u.ModelUsage[model]
+	current.add(usage)
+	u.ModelUsage[model] = current
+}
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func testUsageOutput(role, model string, tokens int, valid bool) *TaskOutput {
+	return &TaskOutput{
+		Agent:            role,
+		Model:            model,
+		IsValid:          valid,
+		TokensUsed:       tokens,
+		PromptTokens:     tokens / 2,
+		CompletionTokens: tokens - tokens/2,
+		Cost:             float64(tokens) / 1000,
+		ExecutionTime:    time.Second,
+		LLMStats:         LLMCallStats{Calls: 1, CacheMisses: 1, ThrottledRequests: 1, RateLimitWait: time.Millisecond},
+	}
+}
+
+// TestUsageMetricsRecordTask 测试按任务结果计数并汇总开销
+func TestUsageMetricsRecordTask(t *testing.T) {
+	usage := &UsageMetrics{}
+	usage.RecordTask(testUsageOutput("Writer", "gpt-4o", 100, true))
+	usage.RecordTask(testUsageOutput("Writer", "gpt-4o-mini", 40, false))
+	usage.RecordTask(nil)
+	usage.RecordTask(&TaskOutput{Agent: "Writer", Metadata: map[string]interface{}{"skipped": true}})
+
+	replayed := testUsageOutput("Editor", "gpt-4o", 500, true)
+	replayed.Metadata = map[string]interface{}{"replayed_from": "kickoff-1"}
+	usage.RecordTask(replayed)
+
+	assert.Equal(t, 5, usage.TotalTasks)
+	assert.Equal(t, 2, usage.SuccessfulTasks)
+	assert.Equal(t, 2, usage.FailedTasks)
+	assert.Equal(t, 1, usage.SkippedTasks)
+	assert.Equal(t, 140, usage.TotalTokens)
+	assert.Equal(t, 70, usage.PromptTokens)
+	assert.Equal(t, 70, usage.CompletionTokens)
+	assert.InDelta(t, 0.14, usage.TotalCost, 1e-9)
+	assert.Equal(t, 2, usage.LLMCalls)
+	assert.Equal(t, 2, usage.ThrottledRequests)
+	assert.Equal(t, 2*time.Millisecond, usage.RateLimitWait)
+	assert.Equal(t, 2, usage.AgentUsage["Writer"].Tasks)
+	assert.NotContains(t, usage.AgentUsage, "Editor", "replayed outputs should not be counted")
+	assert.Equal(t, 100, usage.ModelUsage["gpt-4o"].TotalTokens)
+	assert.Equal(t, 40, usage.ModelUsage["gpt-4o-mini"].TotalTokens)
+	assert.Equal(t, time.Second, usage.AverageTaskTime())
+	assert.Equal(t, 0.0, usage.CacheHitRate())
+}
+
+// TestUsageMetricsAdd 测试合并统计，包括按Agent和按模型的统计
+func TestUsageMetricsAdd(t *testing.T) {
+	first := &UsageMetrics{}
+	first.RecordTask(testUsageOutput("Writer", "gpt-4o", 100, true))
+	first.AddDelegation("Researcher", AgentUsage{DelegatedTasks: 1, LLMCalls: 2, TotalTokens: 30, TotalCost: 0.03})
+	first.AddRun(2 * time.Second)
+
+	second := &UsageMetrics{MaxRPM: 60, RPMUtilization: 0.5}
+	second.RecordTask(testUsageOutput("Writer", "gpt-4o", 50, true))
+	second.RecordTask(testUsageOutput("Editor", "claude-3", 20, true))
+	second.AddRun(4 * time.Second)
+
+	total := &UsageMetrics{}
+	total.Add(first)
+	total.Add(second)
+	total.Add(nil)
+
+	assert.Equal(t, 2, total.Runs)
+	assert.Equal(t, 3, total.TotalTasks)
+	assert.Equal(t, 3, total.SuccessfulTasks)
+	assert.Equal(t, 200, total.TotalTokens)
+	assert.Equal(t, 5, total.LLMCalls)
+	assert.Equal(t, 3, total.CacheMisses)
+	assert.Equal(t, 3*time.Second, total.AverageRunTime())
+	writer := total.AgentUsage["Writer"]
+	assert.Equal(t, 2, writer.Tasks)
+	assert.Equal(t, 2, writer.LLMCalls)
+	assert.Equal(t, 150, writer.TotalTokens)
+	assert.Equal(t, 75, writer.PromptTokens)
+	assert.Equal(t, 2*time.Second, writer.TaskTime)
+	assert.InDelta(t, 0.15, writer.TotalCost, 1e-9)
+	assert.Equal(t, 1, total.AgentUsage["Researcher"].DelegatedTasks)
+	assert.Equal(t, 2, total.ModelUsage["gpt-4o"].Tasks)
+	assert.Equal(t, 20, total.ModelUsage["claude-3"].TotalTokens)
+	assert.Zero(t, total.MaxRPM, "rate limiter gauges should not be merged")
+
+	// AddUsageMetrics与Add相同
+	legacy := &UsageMetrics{}
+	legacy.AddUsageMetrics(second)
+	assert.Equal(t, second.Snapshot().TotalTokens, legacy.TotalTokens)
+}
+
+// TestUsageMetricsSnapshot 测试快照不随后续累加变化
+func TestUsageMetricsSnapshot(t *testing.T) {
+	usage := &UsageMetrics{}
+	usage.RecordTask(testUsageOutput("Writer", "gpt-4o", 100, true))
+
+	snapshot := usage.Snapshot()
+	usage.RecordTask(testUsageOutput("Writer", "gpt-4o", 100, true))
+	snapshot.AgentUsage["Editor"] = AgentUsage{Tasks: 1}
+
+	assert.Equal(t, 100, snapshot.TotalTokens)
+	assert.Equal(t, 1, snapshot.AgentUsage["Writer"].Tasks)
+	assert.Equal(t, 1, snapshot.ModelUsage["gpt-4o"].Tasks)
+	assert.Equal(t, 2, usage.AgentUsage["Writer"].Tasks)
+	assert.NotContains(t, usage.AgentUsage, "Editor")
+}
+
+// TestUsageMetricsConcurrent 测试并发累加与读取
+func TestUsageMetricsConcurrent(t *testing.T) {
+	usage := &UsageMetrics{}
+	other := &UsageMetrics{}
+	other.RecordTask(testUsageOutput("Editor", "gpt-4o", 10, true))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			usage.RecordTask(testUsageOutput("Writer", "gpt-4o", 10, true))
+		}()
+		go func() {
+			defer wg.Done()
+			usage.Add(other)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = usage.Snapshot()
+		}()
+	}
+	wg.Wait()
+
+	snapshot := usage.Snapshot()
+	assert.Equal(t, 100, snapshot.SuccessfulTasks)
+	assert.Equal(t, 1000, snapshot.TotalTokens)
+	assert.Equal(t, 100, snapshot.ModelUsage["gpt-4o"].Tasks)
+}
+
+// TestAgentExecuteRecordsCallStats 测试Agent执行时记录LLM调用、重试和缓存统计
+func TestAgentExecuteRecordsCallStats(t *testing.T) {
+	mockLLM := &FlakyMockLLM{
+		ExtendedMockLLM: NewExtendedMockLLM([]llm.Response{{Content: "answer", Model: "mock-model", Usage: llm.Usage{TotalTokens: 12}}}),
+		failures:        1,
+		err:             errors.New("HTTP error 503: service unavailable"),
+	}
+	agent := newRetryTestAgent(t, mockLLM, events.NewEventBus(logger.NewTestLogger()), fastRetryPolicy(2))
+	agent.SetResponseCache(llm.NewLRUCache(10, 0))
+	task := NewBaseTask("Cached task", "Some output")
+
+	output, err := agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, LLMCallStats{Calls: 2, Retries: 1, CacheMisses: 1}, output.LLMStats)
+
+	output, err = agent.Execute(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, LLMCallStats{CacheHits: 1}, output.LLMStats)
+
+	usage := agent.GetUsageMetrics()
+	assert.Equal(t, 2, usage.SuccessfulTasks)
+	assert.Equal(t, 2, usage.LLMCalls)
+	assert.Equal(t, 1, usage.Retries)
+	assert.Equal(t, 0.5, usage.CacheHitRate())
+	assert.Equal(t, 2, usage.AgentUsage["Retry Agent"].Tasks)
+
+	require.NoError(t, agent.ResetStats())
+	assert.Zero(t, agent.GetUsageMetrics().TotalTasks)
+}
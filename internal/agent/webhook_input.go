@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// WebhookInputHandler 通过HTTP webhook收集人工输入，适用于非交互式运行的Crew
+// 每个请求以JSON形式POST到webhook地址，服务端在审批人作出决定后以HumanInputResponse的JSON作为响应体返回。
+// 服务端可以阻塞直到得到决定，等待时间受超时限制
+type WebhookInputHandler struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	timeout time.Duration
+	logger  logger.Logger
+}
+
+// NewWebhookInputHandler 创建webhook输入处理器
+func NewWebhookInputHandler(url string, log logger.Logger) *WebhookInputHandler {
+	if log == nil {
+		log = logger.NewConsoleLogger()
+	}
+
+	return &WebhookInputHandler{
+		url:     url,
+		headers: make(map[string]string),
+		client:  &http.Client{},
+		timeout: 30 * time.Minute, // 等待人工决定，默认30分钟超时
+		logger:  log,
+	}
+}
+
+// RequestInput 将请求发送到webhook并等待响应
+func (w *WebhookInputHandler) RequestInput(ctx context.Context, request HumanInputRequest) (*HumanInputResponse, error) {
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal human input request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for key, value := range w.headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	w.logger.Info("Sending human input request to webhook",
+		logger.Field{Key: "url", Value: w.url},
+		logger.Field{Key: "type", Value: request.Type},
+	)
+
+	httpResp, err := w.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("webhook input timeout after %v: %w", w.timeout, ctx.Err())
+		}
+		return nil, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook response: %w", err)
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webhook returned status %d: %s", httpResp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var response HumanInputResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid webhook response: %w", err)
+	}
+
+	w.logger.Info("Human input received from webhook",
+		logger.Field{Key: "option", Value: response.Option},
+		logger.Field{Key: "text_length", Value: len(response.Text)},
+	)
+	return &response, nil
+}
+
+// IsInteractive 返回是否为交互式
+func (w *WebhookInputHandler) IsInteractive() bool {
+	return false
+}
+
+// SetTimeout 设置超时时间
+func (w *WebhookInputHandler) SetTimeout(timeout time.Duration) {
+	w.timeout = timeout
+}
+
+// GetTimeout 获取超时时间
+func (w *WebhookInputHandler) GetTimeout() time.Duration {
+	return w.timeout
+}
+
+// SetHeader 设置随请求发送的HTTP头，如认证信息
+func (w *WebhookInputHandler) SetHeader(key, value string) {
+	w.headers[key] = value
+}
+
+// SetHTTPClient 设置发送请求使用的HTTP客户端
+func (w *WebhookInputHandler) SetHTTPClient(client *http.Client) {
+	w.client = client
+}
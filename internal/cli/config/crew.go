@@ -0,0 +1,331 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// apiKeyEnvVars 各LLM提供商的API密钥环境变量
+var apiKeyEnvVars = map[string]string{
+	"openai":     "OPENAI_API_KEY",
+	"anthropic":  "ANTHROPIC_API_KEY",
+	"openrouter": "OPENROUTER_API_KEY",
+	"gemini":     "GEMINI_API_KEY",
+}
+
+const openRouterBaseURL = "https://openrouter.ai/api/v1"
+
+// ToolRegistry 工具名称到构造函数的映射，每次构造返回新的实例，避免Agent之间共享使用计数
+type ToolRegistry map[string]func() (agent.Tool, error)
+
+// BuiltinTools 返回内置基础工具（计算器、文件读写、JSON、文本分析）的注册表
+func BuiltinTools() ToolRegistry {
+	collection := agent.NewToolCollection()
+	_ = collection.LoadBasicTools()
+
+	registry := make(ToolRegistry)
+	for _, name := range collection.List() {
+		tool, _ := collection.Get(name)
+		registry[name] = func() (agent.Tool, error) {
+			return tool.Clone(), nil
+		}
+	}
+	return registry
+}
+
+// Names 返回按名称排序的工具名称
+func (r ToolRegistry) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New 按名称创建工具
+func (r ToolRegistry) New(names []string) ([]agent.Tool, error) {
+	tools := make([]agent.Tool, 0, len(names))
+	for _, name := range names {
+		constructor, ok := r[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tool: %s", name)
+		}
+		tool, err := constructor()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tool %s: %w", name, err)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// CrewDeps 按项目配置构建Crew时使用的依赖，零值字段使用默认值
+type CrewDeps struct {
+	NewLLM     func(model string) (llm.LLM, error) // 按模型名创建LLM，空模型名表示llm配置中的模型；为nil时使用LLMFactory
+	Tools      ToolRegistry                        // 可用的工具，为nil时使用BuiltinTools
+	EventBus   events.EventBus                     // 为nil时创建新的事件总线
+	Logger     logger.Logger                       // 为nil时使用控制台日志
+	CrewConfig *crew.CrewConfig                    // 基础配置，名称、流程等由项目配置覆盖；为nil时使用crew.DefaultCrewConfig
+}
+
+// LoadCrewFromYAML 加载greensoulai.yaml并构建可直接Kickoff的Crew
+// 配置错误以FileErrors返回，每条错误带有行列和YAML路径（如agents[2].goal）；
+// 任务输出文件的相对路径基于配置文件所在目录，除非deps.CrewConfig指定了OutputDir
+func LoadCrewFromYAML(path string, deps CrewDeps) (crew.Crew, error) {
+	if deps.Tools == nil {
+		deps.Tools = BuiltinTools()
+	}
+
+	cfg, err := ValidateProjectFile(path, deps.Tools.Names())
+	if err != nil {
+		return nil, err
+	}
+
+	crewConfig := crew.DefaultCrewConfig()
+	if deps.CrewConfig != nil {
+		copied := *deps.CrewConfig
+		crewConfig = &copied
+	}
+	if crewConfig.OutputDir == "" {
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve config directory: %w", err)
+		}
+		crewConfig.OutputDir = dir
+	}
+	deps.CrewConfig = crewConfig
+
+	return BuildCrew(cfg, deps)
+}
+
+// BuildCrew 按已验证的项目配置构建Agent、任务和Crew
+func BuildCrew(cfg *ProjectConfig, deps CrewDeps) (*crew.BaseCrew, error) {
+	process, err := ParseProcess(cfg.Process)
+	if err != nil {
+		return nil, err
+	}
+	if deps.NewLLM == nil {
+		deps.NewLLM = LLMFactory(cfg.LLM)
+	}
+	if deps.Tools == nil {
+		deps.Tools = BuiltinTools()
+	}
+	if deps.Logger == nil {
+		deps.Logger = logger.NewConsoleLogger()
+	}
+	if deps.EventBus == nil {
+		deps.EventBus = events.NewEventBus(deps.Logger)
+	}
+
+	defaultLLM, err := deps.NewLLM("")
+	if err != nil {
+		return nil, err
+	}
+
+	crewConfig := crew.DefaultCrewConfig()
+	if deps.CrewConfig != nil {
+		copied := *deps.CrewConfig
+		crewConfig = &copied
+	}
+	crewConfig.Name = cfg.Name
+	crewConfig.Process = process
+	crewConfig.Verbose = crewConfig.Verbose || cfg.Verbose
+	crewConfig.MemoryEnabled = crewConfig.MemoryEnabled || cfg.Memory.Enabled
+	switch process {
+	case crew.ProcessHierarchical:
+		crewConfig.ManagerLLM = defaultLLM
+	case crew.ProcessConsensus:
+		crewConfig.JudgeLLM = defaultLLM
+	}
+	c := crew.NewBaseCrew(crewConfig, deps.EventBus, deps.Logger)
+
+	agents := make(map[string]agent.Agent, len(cfg.Agents))
+	for _, agentConfig := range cfg.Agents {
+		a, err := buildAgent(agentConfig, defaultLLM, deps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create agent %s: %w", agentConfig.Name, err)
+		}
+		if err := c.AddAgent(a); err != nil {
+			return nil, fmt.Errorf("failed to add agent %s: %w", agentConfig.Name, err)
+		}
+		agents[agentConfig.Name] = a
+	}
+
+	tasks := make(map[string]*agent.BaseTask, len(cfg.Tasks))
+	for _, taskConfig := range cfg.Tasks {
+		task, err := buildTask(taskConfig, agents, deps.Tools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create task %s: %w", taskConfig.Name, err)
+		}
+		tasks[taskConfig.Name] = task
+	}
+	for _, taskConfig := range cfg.Tasks {
+		task := tasks[taskConfig.Name]
+		if len(taskConfig.Context) > 0 {
+			contextTasks := make([]agent.Task, 0, len(taskConfig.Context))
+			for _, name := range taskConfig.Context {
+				contextTasks = append(contextTasks, tasks[name])
+			}
+			task.SetContextTasks(contextTasks)
+		}
+		if err := c.AddTask(task); err != nil {
+			return nil, fmt.Errorf("failed to add task %s: %w", taskConfig.Name, err)
+		}
+	}
+
+	return c, nil
+}
+
+func buildAgent(cfg AgentConfig, defaultLLM llm.LLM, deps CrewDeps) (agent.Agent, error) {
+	model := defaultLLM
+	if cfg.LLM != "" {
+		var err error
+		if model, err = deps.NewLLM(cfg.LLM); err != nil {
+			return nil, err
+		}
+	}
+
+	tools, err := deps.Tools.New(cfg.Tools)
+	if err != nil {
+		return nil, err
+	}
+
+	executionConfig := agent.DefaultExecutionConfig()
+	executionConfig.Verbose = cfg.Verbose
+
+	return agent.NewBaseAgent(agent.AgentConfig{
+		Role:            cfg.Role,
+		Goal:            cfg.Goal,
+		Backstory:       cfg.Backstory,
+		LLM:             model,
+		Tools:           tools,
+		ExecutionConfig: executionConfig,
+		AllowDelegation: cfg.AllowDelegation,
+		EventBus:        deps.EventBus,
+		Logger:          deps.Logger,
+	})
+}
+
+func buildTask(cfg TaskConfig, agents map[string]agent.Agent, registry ToolRegistry) (*agent.BaseTask, error) {
+	// 任务ID由名称派生，重放时重新构建的任务与记录中的ID一致
+	task := agent.NewTaskWithOptions(cfg.Description, cfg.ExpectedOutput,
+		agent.WithName(cfg.Name),
+		agent.WithOutputFormat(taskOutputFormat(cfg.OutputFormat)),
+		agent.WithMarkdown(cfg.OutputFormat == "markdown"),
+	)
+
+	if cfg.Agent != "" {
+		if err := task.SetAssignedAgent(agents[cfg.Agent]); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.Tools) > 0 {
+		tools, err := registry.New(cfg.Tools)
+		if err != nil {
+			return nil, err
+		}
+		if err := task.SetTools(tools); err != nil {
+			return nil, err
+		}
+	}
+
+	// 相对路径由Crew的输出目录解析，模板变量在写入时展开
+	if cfg.OutputFile != "" {
+		if err := task.SetOutputFile(cfg.OutputFile); err != nil {
+			return nil, err
+		}
+		task.SetOutputFileAppend(cfg.OutputAppend)
+	}
+
+	return task, nil
+}
+
+// taskOutputFormat 把配置中的output_format映射为任务输出格式，markdown按原始文本输出
+func taskOutputFormat(format string) agent.OutputFormat {
+	if format == "json" {
+		return agent.OutputFormatJSON
+	}
+	return agent.OutputFormatRAW
+}
+
+// ParseProcess 把配置中的流程名称转换为crew.Process，空值为顺序执行
+func ParseProcess(name string) (crew.Process, error) {
+	switch name {
+	case "", "sequential":
+		return crew.ProcessSequential, nil
+	case "hierarchical":
+		return crew.ProcessHierarchical, nil
+	case "parallel":
+		return crew.ProcessParallel, nil
+	case "consensus":
+		return crew.ProcessConsensus, nil
+	default:
+		return 0, fmt.Errorf("unknown process: %s", name)
+	}
+}
+
+// NewLLM 根据项目LLM配置创建LLM
+// 配置值中的${VAR}引用会展开为环境变量；没有配置api_key时从提供商对应的环境变量读取
+func NewLLM(cfg LLMConfig) (llm.LLM, error) {
+	cfg.Provider = os.ExpandEnv(cfg.Provider)
+	cfg.Model = os.ExpandEnv(cfg.Model)
+	cfg.BaseURL = os.ExpandEnv(cfg.BaseURL)
+	cfg.APIKey = os.ExpandEnv(cfg.APIKey)
+
+	llmConfig := &llm.Config{
+		Provider: cfg.Provider,
+		Model:    cfg.Model,
+		BaseURL:  cfg.BaseURL,
+		APIKey:   cfg.APIKey,
+	}
+	if cfg.Temperature != 0 {
+		temperature := cfg.Temperature
+		llmConfig.Temperature = &temperature
+	}
+	if cfg.MaxTokens > 0 {
+		maxTokens := cfg.MaxTokens
+		llmConfig.MaxTokens = &maxTokens
+	}
+
+	if envVar, ok := apiKeyEnvVars[cfg.Provider]; ok && llmConfig.APIKey == "" {
+		llmConfig.APIKey = os.Getenv(envVar)
+		if llmConfig.APIKey == "" {
+			return nil, fmt.Errorf("%s is required for provider %s", envVar, cfg.Provider)
+		}
+	}
+
+	// OpenRouter兼容OpenAI接口
+	if cfg.Provider == "openrouter" {
+		llmConfig.Provider = "openai"
+		if llmConfig.BaseURL == "" {
+			llmConfig.BaseURL = openRouterBaseURL
+		}
+	}
+
+	model, err := llm.CreateLLM(llmConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LLM: %w", err)
+	}
+	return model, nil
+}
+
+// LLMFactory 返回按模型名创建LLM的函数，空模型名使用配置中的模型，其余设置相同
+func LLMFactory(cfg LLMConfig) func(model string) (llm.LLM, error) {
+	return func(model string) (llm.LLM, error) {
+		modelCfg := cfg
+		if model != "" {
+			modelCfg.Model = model
+		}
+		return NewLLM(modelCfg)
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const loaderProjectYAML = `name: loader-project
+type: crew
+go_module: github.com/test/loader
+process: sequential
+verbose: true
+memory:
+  storage_dir: .memory
+llm:
+  provider: openai
+  model: gpt-4o-mini
+  api_key: ${LOADER_TEST_KEY}
+agents:
+  - name: researcher
+    role: Researcher
+    goal: Research {topic}
+    backstory: Thorough
+    tools:
+      - lookup
+  - name: writer
+    role: Writer
+    goal: Write about {topic}
+    backstory: Concise
+    llm: gpt-4o
+tasks:
+  - name: research
+    description: Research {topic}
+    expected_output: Notes
+    agent: researcher
+  - name: write
+    description: Write a summary of {topic}
+    expected_output: A summary
+    agent: writer
+    context:
+      - research
+    output_file: out/{task_name}.md
+`
+
+func TestLoadCrewFromYAML(t *testing.T) {
+	path := writeConfig(t, loaderProjectYAML)
+	researcherLLM := llmtest.NewScriptedLLM(llmtest.Replies("Go was released in 2009.")...)
+	writerLLM := llmtest.NewScriptedLLM(llmtest.Replies("Go is a language from 2009.")...)
+
+	var models []string
+	lookupCalls := 0
+	c, err := LoadCrewFromYAML(path, CrewDeps{
+		NewLLM: func(model string) (llm.LLM, error) {
+			models = append(models, model)
+			if model == "gpt-4o" {
+				return writerLLM, nil
+			}
+			return researcherLLM, nil
+		},
+		Tools: ToolRegistry{"lookup": func() (agent.Tool, error) {
+			lookupCalls++
+			return agent.NewBaseTool("lookup", "Look up facts", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				return "2009", nil
+			}), nil
+		}},
+		Logger: logger.NewTestLogger(),
+	})
+	if err != nil {
+		t.Fatalf("failed to load crew: %v", err)
+	}
+	defer c.Close()
+
+	if strings.Join(models, ",") != ",gpt-4o" || lookupCalls != 1 {
+		t.Errorf("expected the default and the writer's model and one lookup tool, got models %q and %d tools", models, lookupCalls)
+	}
+	if c.GetProcess() != crew.ProcessSequential || len(c.GetAgents()) != 2 || len(c.GetTasks()) != 2 {
+		t.Fatalf("unexpected crew: process %s, %d agents, %d tasks", c.GetProcess(), len(c.GetAgents()), len(c.GetTasks()))
+	}
+	write := c.GetTasks()[1]
+	if write.GetAssignedAgent().GetRole() != "Writer" || len(write.GetContextTasks()) != 1 {
+		t.Errorf("expected the write task assigned to the writer with research as context")
+	}
+
+	result, err := c.Kickoff(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	if result.Raw != "Go is a language from 2009." {
+		t.Errorf("unexpected final output: %q", result.Raw)
+	}
+
+	// 输出文件的相对路径基于配置文件所在目录
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(path), "out", "write.md"))
+	if err != nil {
+		t.Fatalf("expected the output file next to the config: %v", err)
+	}
+	if !strings.Contains(string(content), "Go is a language from 2009.") {
+		t.Errorf("unexpected output file content: %q", content)
+	}
+}
+
+func TestLoadCrewFromYAMLReportsPaths(t *testing.T) {
+	content := strings.Replace(loaderProjectYAML, "    goal: Write about {topic}\n", "", 1)
+	content = strings.Replace(content, "      - lookup\n", "      - lookup\n      - teleport\n", 1)
+	path := writeConfig(t, content)
+
+	_, err := LoadCrewFromYAML(path, CrewDeps{
+		NewLLM: func(model string) (llm.LLM, error) { return llmtest.NewScriptedLLM(), nil },
+		Tools:  ToolRegistry{"lookup": func() (agent.Tool, error) { return nil, nil }},
+		Logger: logger.NewTestLogger(),
+	})
+	var fileErrs FileErrors
+	if !errors.As(err, &fileErrs) {
+		t.Fatalf("expected FileErrors, got %v", err)
+	}
+
+	paths := make([]string, 0, len(fileErrs))
+	for _, e := range fileErrs {
+		paths = append(paths, e.Path)
+	}
+	if strings.Join(paths, " ") != "agents[0].tools[1] agents[1].goal" {
+		t.Errorf("unexpected error paths %v:\n%v", paths, err)
+	}
+	if !strings.Contains(err.Error(), ": agents[1].goal: agent 2: goal is required") {
+		t.Errorf("expected the YAML path in the message, got:\n%v", err)
+	}
+}
+
+func TestNewLLMExpandsEnvironment(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("LOADER_TEST_KEY", "sk-from-env")
+	t.Setenv("LOADER_TEST_MODEL", "gpt-4o")
+
+	model, err := NewLLM(LLMConfig{Provider: "openai", Model: "${LOADER_TEST_MODEL}", APIKey: "${LOADER_TEST_KEY}"})
+	if err != nil {
+		t.Fatalf("expected the API key from the expanded reference, got: %v", err)
+	}
+	if model.GetModel() != "gpt-4o" {
+		t.Errorf("expected the expanded model, got %s", model.GetModel())
+	}
+
+	if _, err := NewLLM(LLMConfig{Provider: "openai", Model: "gpt-4o-mini", APIKey: "${LOADER_TEST_MISSING}"}); err == nil ||
+		!strings.Contains(err.Error(), "OPENAI_API_KEY") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+}
+
+func TestParseProcess(t *testing.T) {
+	for name, want := range map[string]crew.Process{
+		"": crew.ProcessSequential, "hierarchical": crew.ProcessHierarchical,
+		"parallel": crew.ProcessParallel, "consensus": crew.ProcessConsensus,
+	} {
+		if got, err := ParseProcess(name); err != nil || got != want {
+			t.Errorf("ParseProcess(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseProcess("round_robin"); err == nil {
+		t.Error("expected an error for an unknown process")
+	}
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ynl/greensoulai/pkg/flow"
+	"gopkg.in/yaml.v3"
+)
+
+// FlowConfigFileName Flow项目中描述作业和触发条件的配置文件
+const FlowConfigFileName = "flow.yaml"
+
+// jobIDPattern 作业ID同时用于生成Go标识符和文件名
+var jobIDPattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// FlowConfig Flow配置
+type FlowConfig struct {
+	Name        string          `yaml:"name"`
+	Description string          `yaml:"description,omitempty"`
+	Jobs        []FlowJobConfig `yaml:"jobs"`
+
+	// Schedules greensoulai flow schedule按这些执行计划重复运行工作流
+	Schedules []FlowScheduleConfig `yaml:"schedules,omitempty"`
+}
+
+// FlowJobConfig 作业配置
+type FlowJobConfig struct {
+	ID          string        `yaml:"id"`
+	Description string        `yaml:"description,omitempty"`
+	Trigger     TriggerConfig `yaml:"trigger"`
+}
+
+// TriggerConfig 作业触发条件，immediately、after、after_any三者必须且只能设置一个
+type TriggerConfig struct {
+	Immediately bool     `yaml:"immediately,omitempty"` // 工作流开始时立即执行
+	After       []string `yaml:"after,omitempty"`       // 列出的作业全部完成后执行
+	AfterAny    []string `yaml:"after_any,omitempty"`   // 列出的任一作业完成后执行
+}
+
+// FlowScheduleConfig 执行计划配置，cron和every必须且只能设置一个
+type FlowScheduleConfig struct {
+	Name    string                 `yaml:"name"`
+	Cron    string                 `yaml:"cron,omitempty"`     // cron表达式或@daily等描述符
+	Every   string                 `yaml:"every,omitempty"`    // 固定间隔，如30m
+	Overlap string                 `yaml:"overlap,omitempty"`  // 上一次执行未结束时的处理：skip（默认）或queue
+	CatchUp int                    `yaml:"catch_up,omitempty"` // 启动时最多补跑多少次停止期间错过的执行
+	State   map[string]interface{} `yaml:"state,omitempty"`    // 每次执行的初始状态
+}
+
+// FlowSchedule 返回对应的pkg/flow执行计划
+func (s FlowScheduleConfig) FlowSchedule() (flow.Schedule, error) {
+	if s.Every != "" {
+		return flow.ParseSchedule("@every " + s.Every)
+	}
+	return flow.ParseSchedule(s.Cron)
+}
+
+// OverlapPolicy 返回对应的pkg/flow重叠策略
+func (s FlowScheduleConfig) OverlapPolicy() (flow.OverlapPolicy, error) {
+	return flow.ParseOverlapPolicy(s.Overlap)
+}
+
+// Dependencies 返回触发条件引用的作业ID
+func (t TriggerConfig) Dependencies() []string {
+	if len(t.After) > 0 {
+		return t.After
+	}
+	return t.AfterAny
+}
+
+// FlowTrigger 返回对应的pkg/flow触发器，与生成的cmd/main.go中的调用一致
+func (t TriggerConfig) FlowTrigger() flow.Trigger {
+	switch {
+	case t.Immediately:
+		return flow.Immediately()
+	case len(t.After) == 1:
+		return flow.After(t.After[0])
+	case len(t.After) > 1:
+		return flow.AfterJobs(t.After...)
+	default:
+		return flow.AfterAnyJob(t.AfterAny...)
+	}
+}
+
+// Graph 按作业和触发条件描述工作流结构，不需要作业的实现代码
+func (fc *FlowConfig) Graph() *flow.WorkflowGraph {
+	wf := flow.NewWorkflow(fc.Name)
+	for _, job := range fc.Jobs {
+		wf.AddJob(flow.NewJob(job.ID, nil), job.Trigger.FlowTrigger())
+	}
+	return wf.Describe()
+}
+
+// LoadFlowConfig 加载Flow配置
+func LoadFlowConfig(configPath string) (*FlowConfig, error) {
+	if configPath == "" {
+		configPath = FlowConfigFileName
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flow config: %w", err)
+	}
+
+	var config FlowConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse flow config: %w", err)
+	}
+	return &config, nil
+}
+
+// SaveFlowConfig 保存Flow配置
+func (fc *FlowConfig) SaveFlowConfig(configPath string) error {
+	if configPath == "" {
+		configPath = FlowConfigFileName
+	}
+
+	data, err := yaml.Marshal(fc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal flow config: %w", err)
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write flow config: %w", err)
+	}
+	return nil
+}
+
+// Validate 验证作业ID和触发条件：引用的作业必须已定义，且依赖之间不能成环
+func (fc *FlowConfig) Validate() error {
+	if fc.Name == "" {
+		return fmt.Errorf("flow name is required")
+	}
+	if len(fc.Jobs) == 0 {
+		return fmt.Errorf("flow %s has no jobs", fc.Name)
+	}
+
+	jobs := make(map[string]FlowJobConfig, len(fc.Jobs))
+	for _, job := range fc.Jobs {
+		if !jobIDPattern.MatchString(job.ID) {
+			return fmt.Errorf("invalid job id %q: use lowercase letters, digits and single underscores", job.ID)
+		}
+		if _, ok := jobs[job.ID]; ok {
+			return fmt.Errorf("duplicate job id: %s", job.ID)
+		}
+		jobs[job.ID] = job
+	}
+
+	hasEntry := false
+	for _, job := range fc.Jobs {
+		kinds := 0
+		if job.Trigger.Immediately {
+			kinds++
+			hasEntry = true
+		}
+		if len(job.Trigger.After) > 0 {
+			kinds++
+		}
+		if len(job.Trigger.AfterAny) > 0 {
+			kinds++
+		}
+		if kinds != 1 {
+			return fmt.Errorf("job %s must set exactly one of trigger.immediately, trigger.after or trigger.after_any", job.ID)
+		}
+
+		for _, dep := range job.Trigger.Dependencies() {
+			if dep == job.ID {
+				return fmt.Errorf("job %s cannot trigger after itself", job.ID)
+			}
+			if _, ok := jobs[dep]; !ok {
+				return fmt.Errorf("job %s trigger references unknown job: %s", job.ID, dep)
+			}
+		}
+	}
+	if !hasEntry {
+		return fmt.Errorf("flow %s needs at least one job with trigger.immediately", fc.Name)
+	}
+
+	if err := checkJobCycles(fc.Jobs, jobs); err != nil {
+		return err
+	}
+	return fc.validateSchedules()
+}
+
+// validateSchedules 验证执行计划的名称、计划表达式、重叠策略和补跑次数
+func (fc *FlowConfig) validateSchedules() error {
+	names := make(map[string]bool, len(fc.Schedules))
+	for _, schedule := range fc.Schedules {
+		if schedule.Name == "" {
+			return fmt.Errorf("schedule name is required")
+		}
+		if names[schedule.Name] {
+			return fmt.Errorf("duplicate schedule name: %s", schedule.Name)
+		}
+		names[schedule.Name] = true
+
+		if (schedule.Cron == "") == (schedule.Every == "") {
+			return fmt.Errorf("schedule %s must set exactly one of cron or every", schedule.Name)
+		}
+		if _, err := schedule.FlowSchedule(); err != nil {
+			return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+		if _, err := schedule.OverlapPolicy(); err != nil {
+			return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+		if schedule.CatchUp < 0 {
+			return fmt.Errorf("schedule %s: catch_up must not be negative", schedule.Name)
+		}
+	}
+	return nil
+}
+
+// checkJobCycles 检查触发依赖中的环，环中的作业永远不会就绪
+func checkJobCycles(order []FlowJobConfig, jobs map[string]FlowJobConfig) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	marks := make(map[string]int, len(jobs))
+
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		switch marks[id] {
+		case visiting:
+			return fmt.Errorf("job triggers form a cycle: %s", strings.Join(append(path, id), " -> "))
+		case done:
+			return nil
+		}
+		marks[id] = visiting
+		for _, dep := range jobs[id].Trigger.Dependencies() {
+			if err := visit(dep, append(path, id)); err != nil {
+				return err
+			}
+		}
+		marks[id] = done
+		return nil
+	}
+
+	for _, job := range order {
+		if err := visit(job.ID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultFlowConfig 默认Flow配置：获取数据后并行分析和摘要，最后汇总报告
+func DefaultFlowConfig(name string) *FlowConfig {
+	return &FlowConfig{
+		Name:        name,
+		Description: fmt.Sprintf("%s workflow", name),
+		Jobs: []FlowJobConfig{
+			{ID: "fetch_data", Description: "获取输入数据", Trigger: TriggerConfig{Immediately: true}},
+			{ID: "analyze", Description: "分析数据", Trigger: TriggerConfig{After: []string{"fetch_data"}}},
+			{ID: "summarize", Description: "生成数据摘要", Trigger: TriggerConfig{After: []string{"fetch_data"}}},
+			{ID: "report", Description: "汇总分析和摘要生成报告", Trigger: TriggerConfig{After: []string{"analyze", "summarize"}}},
+		},
+	}
+}
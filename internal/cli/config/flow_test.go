@@ -0,0 +1,160 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/flow"
+)
+
+func TestFlowConfigValidate(t *testing.T) {
+	if err := DefaultFlowConfig("demo").Validate(); err != nil {
+		t.Fatalf("expected default flow config to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(fc *FlowConfig)
+		errMsg string
+	}{
+		{
+			name:   "unknown trigger reference",
+			modify: func(fc *FlowConfig) { fc.Jobs[3].Trigger.After = []string{"analyze", "translate"} },
+			errMsg: "job report trigger references unknown job: translate",
+		},
+		{
+			name:   "duplicate job id",
+			modify: func(fc *FlowConfig) { fc.Jobs[2].ID = "analyze" },
+			errMsg: "duplicate job id: analyze",
+		},
+		{
+			name:   "invalid job id",
+			modify: func(fc *FlowConfig) { fc.Jobs[1].ID = "Analyze-Data" },
+			errMsg: `invalid job id "Analyze-Data"`,
+		},
+		{
+			name: "multiple trigger kinds",
+			modify: func(fc *FlowConfig) {
+				fc.Jobs[1].Trigger.Immediately = true
+			},
+			errMsg: "job analyze must set exactly one of",
+		},
+		{
+			name:   "no entry job",
+			modify: func(fc *FlowConfig) { fc.Jobs[0].Trigger = TriggerConfig{AfterAny: []string{"report"}} },
+			errMsg: "needs at least one job with trigger.immediately",
+		},
+		{
+			name: "cycle",
+			modify: func(fc *FlowConfig) {
+				fc.Jobs[1].Trigger = TriggerConfig{After: []string{"report"}}
+			},
+			errMsg: "job triggers form a cycle: analyze -> report -> analyze",
+		},
+		{
+			name: "schedule without spec",
+			modify: func(fc *FlowConfig) {
+				fc.Schedules = []FlowScheduleConfig{{Name: "nightly"}}
+			},
+			errMsg: "schedule nightly must set exactly one of cron or every",
+		},
+		{
+			name: "schedule with cron and every",
+			modify: func(fc *FlowConfig) {
+				fc.Schedules = []FlowScheduleConfig{{Name: "nightly", Cron: "@daily", Every: "1h"}}
+			},
+			errMsg: "schedule nightly must set exactly one of cron or every",
+		},
+		{
+			name: "invalid cron",
+			modify: func(fc *FlowConfig) {
+				fc.Schedules = []FlowScheduleConfig{{Name: "nightly", Cron: "0 25 * * *"}}
+			},
+			errMsg: "schedule nightly: invalid schedule",
+		},
+		{
+			name: "invalid overlap",
+			modify: func(fc *FlowConfig) {
+				fc.Schedules = []FlowScheduleConfig{{Name: "nightly", Every: "1h", Overlap: "parallel"}}
+			},
+			errMsg: "invalid overlap policy",
+		},
+		{
+			name: "duplicate schedule name",
+			modify: func(fc *FlowConfig) {
+				fc.Schedules = []FlowScheduleConfig{{Name: "nightly", Every: "1h"}, {Name: "nightly", Cron: "@daily"}}
+			},
+			errMsg: "duplicate schedule name: nightly",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := DefaultFlowConfig("demo")
+			tt.modify(fc)
+			err := fc.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestFlowConfigSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FlowConfigFileName)
+	if err := DefaultFlowConfig("demo").SaveFlowConfig(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	loaded, err := LoadFlowConfig(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(loaded.Jobs) != 4 || !loaded.Jobs[0].Trigger.Immediately || strings.Join(loaded.Jobs[3].Trigger.After, ",") != "analyze,summarize" {
+		t.Errorf("unexpected loaded config: %+v", loaded)
+	}
+}
+
+func TestFlowConfigSchedules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FlowConfigFileName)
+	fc := DefaultFlowConfig("demo")
+	fc.Schedules = []FlowScheduleConfig{
+		{Name: "nightly", Cron: "30 2 * * *", CatchUp: 3, State: map[string]interface{}{"region": "eu"}},
+		{Name: "frequent", Every: "15m", Overlap: "queue"},
+	}
+	if err := fc.SaveFlowConfig(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	loaded, err := LoadFlowConfig(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("expected schedules to be valid, got %v", err)
+	}
+	if len(loaded.Schedules) != 2 || loaded.Schedules[0].CatchUp != 3 || loaded.Schedules[0].State["region"] != "eu" {
+		t.Fatalf("unexpected schedules: %+v", loaded.Schedules)
+	}
+
+	base := time.Date(2026, 3, 14, 10, 5, 0, 0, time.UTC)
+	nightly, err := loaded.Schedules[0].FlowSchedule()
+	if err != nil {
+		t.Fatalf("FlowSchedule failed: %v", err)
+	}
+	if next := nightly.Next(base); !next.Equal(time.Date(2026, 3, 15, 2, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected nightly next run: %v", next)
+	}
+	frequent, err := loaded.Schedules[1].FlowSchedule()
+	if err != nil {
+		t.Fatalf("FlowSchedule failed: %v", err)
+	}
+	if next := frequent.Next(base); !next.Equal(time.Date(2026, 3, 14, 10, 15, 0, 0, time.UTC)) {
+		t.Errorf("unexpected frequent next run: %v", next)
+	}
+	if policy, _ := loaded.Schedules[1].OverlapPolicy(); policy != flow.OverlapQueue {
+		t.Errorf("expected queue policy, got %s", policy)
+	}
+}
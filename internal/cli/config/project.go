@@ -30,12 +30,17 @@ type ProjectConfig struct {
 	GoVersion string `yaml:"go_version"`
 
 	// Crew特定配置
-	Agents []AgentConfig `yaml:"agents,omitempty"`
-	Tasks  []TaskConfig  `yaml:"tasks,omitempty"`
+	Process string        `yaml:"process,omitempty"` // sequential（默认）、hierarchical、parallel或consensus
+	Verbose bool          `yaml:"verbose,omitempty"`
+	Agents  []AgentConfig `yaml:"agents,omitempty"`
+	Tasks   []TaskConfig  `yaml:"tasks,omitempty"`
 
 	// LLM配置
 	LLM LLMConfig `yaml:"llm"`
 
+	// 记忆配置
+	Memory MemoryConfig `yaml:"memory,omitempty"`
+
 	// 依赖配置
 	Dependencies []string `yaml:"dependencies,omitempty"`
 }
@@ -49,6 +54,8 @@ type AgentConfig struct {
 	Tools     []string `yaml:"tools,omitempty"`
 	LLM       string   `yaml:"llm,omitempty"`
 	Verbose   bool     `yaml:"verbose,omitempty"`
+
+	AllowDelegation bool `yaml:"allow_delegation,omitempty"` // 为true时可以向同一Crew中的其他智能体提问或委托工作
 }
 
 // TaskConfig Task配置
@@ -60,7 +67,8 @@ type TaskConfig struct {
 	Context        []string `yaml:"context,omitempty"`
 	Tools          []string `yaml:"tools,omitempty"`
 	OutputFormat   string   `yaml:"output_format,omitempty"`
-	OutputFile     string   `yaml:"output_file,omitempty"`
+	OutputFile     string   `yaml:"output_file,omitempty"`   // 支持{date}、{task_name}、{crew_name}模板变量，相对路径基于项目根目录
+	OutputAppend   bool     `yaml:"output_append,omitempty"` // 为true时追加写入output_file而不是覆盖
 }
 
 // LLMConfig LLM配置
@@ -70,6 +78,13 @@ type LLMConfig struct {
 	Temperature float64 `yaml:"temperature,omitempty"`
 	MaxTokens   int     `yaml:"max_tokens,omitempty"`
 	BaseURL     string  `yaml:"base_url,omitempty"`
+	APIKey      string  `yaml:"api_key,omitempty"` // 通常写为${OPENAI_API_KEY}形式的环境变量引用，为空时读取提供商对应的环境变量
+}
+
+// MemoryConfig 记忆存储配置
+type MemoryConfig struct {
+	Enabled    bool   `yaml:"enabled,omitempty"`     // 为true时Crew启用短期、长期和实体记忆
+	StorageDir string `yaml:"storage_dir,omitempty"` // 相对路径基于项目根目录
 }
 
 // LoadProjectConfig 加载项目配置
@@ -140,6 +155,10 @@ func (pc *ProjectConfig) Validate() error {
 		return fmt.Errorf("go module is required")
 	}
 
+	if _, err := ParseProcess(pc.Process); err != nil {
+		return err
+	}
+
 	// 验证Agent配置
 	agentNames := make(map[string]bool)
 	for _, agent := range pc.Agents {
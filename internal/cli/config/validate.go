@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Processes 项目配置中可用的执行流程
+var Processes = []string{"sequential", "hierarchical", "parallel", "consensus"}
+
+// FileError 配置文件中某一位置的错误
+type FileError struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string // 出错字段在YAML中的路径，如agents[2].goal
+	Message string
+	Source  string // 出错的源码行
+}
+
+func (e FileError) Error() string {
+	location := e.File
+	if e.Line != 0 {
+		location = fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+	}
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s: %s", location, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", location, e.Message)
+}
+
+// FileErrors 配置文件中的全部错误，按位置排序
+type FileErrors []FileError
+
+// Error 逐条列出错误，并附带出错的源码行和列位置标记
+func (errs FileErrors) Error() string {
+	var b strings.Builder
+	for i, e := range errs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(e.Error())
+		if e.Source != "" {
+			fmt.Fprintf(&b, "\n    %s\n    %s^", e.Source, strings.Repeat(" ", max(e.Column-1, 0)))
+		}
+	}
+	return b.String()
+}
+
+// ValidateProjectFile 加载并验证项目配置，错误带有所在的行列
+// knownTools为可用的工具名称，为nil时不检查工具
+func ValidateProjectFile(configPath string, knownTools []string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	config, err := LoadProjectConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &fileValidator{
+		file:  configPath,
+		lines: strings.Split(string(data), "\n"),
+	}
+	if len(root.Content) > 0 {
+		v.validate(root.Content[0], config, knownTools)
+	}
+	if len(v.errs) > 0 {
+		sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Line < v.errs[j].Line })
+		return nil, v.errs
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, FileErrors{{File: configPath, Message: err.Error()}}
+	}
+	return config, nil
+}
+
+type fileValidator struct {
+	file  string
+	lines []string
+	errs  FileErrors
+}
+
+func (v *fileValidator) addError(node *yaml.Node, path string, format string, args ...interface{}) {
+	e := FileError{File: v.file, Path: path, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		e.Line, e.Column = node.Line, node.Column
+		if node.Line > 0 && node.Line <= len(v.lines) {
+			e.Source = strings.TrimRight(v.lines[node.Line-1], "\r")
+		}
+	}
+	v.errs = append(v.errs, e)
+}
+
+func (v *fileValidator) validate(doc *yaml.Node, config *ProjectConfig, knownTools []string) {
+	if process := mappingValue(doc, "process"); process != nil && !contains(Processes, process.Value) {
+		v.addError(process, "process", "unknown process %q, expected one of: %s", process.Value, strings.Join(Processes, ", "))
+	}
+
+	tools := make(map[string]bool, len(knownTools))
+	for _, tool := range knownTools {
+		tools[tool] = true
+	}
+
+	agentNodes := sequenceItems(mappingValue(doc, "agents"))
+	agentNames := make(map[string]bool)
+	for i, a := range config.Agents {
+		node := itemAt(agentNodes, i)
+		for _, field := range []struct{ key, value string }{
+			{"name", a.Name}, {"role", a.Role}, {"goal", a.Goal}, {"backstory", a.Backstory},
+		} {
+			if field.value == "" {
+				v.addError(fieldNode(node, field.key), fmt.Sprintf("agents[%d].%s", i, field.key), "agent %d: %s is required", i+1, field.key)
+			}
+		}
+		if a.Name != "" {
+			if agentNames[a.Name] {
+				v.addError(mappingValue(node, "name"), fmt.Sprintf("agents[%d].name", i), "duplicate agent name: %s", a.Name)
+			}
+			agentNames[a.Name] = true
+		}
+		if knownTools != nil {
+			toolNodes := sequenceItems(mappingValue(node, "tools"))
+			for j, tool := range a.Tools {
+				if !tools[tool] {
+					v.addError(itemAt(toolNodes, j), fmt.Sprintf("agents[%d].tools[%d]", i, j), "agent %s uses unknown tool %q, available tools: %s",
+						a.Name, tool, strings.Join(knownTools, ", "))
+				}
+			}
+		}
+	}
+
+	taskNodes := sequenceItems(mappingValue(doc, "tasks"))
+	taskNames := make(map[string]bool)
+	for _, t := range config.Tasks {
+		taskNames[t.Name] = true
+	}
+	seenTasks := make(map[string]bool)
+	for i, t := range config.Tasks {
+		node := itemAt(taskNodes, i)
+		if t.Name == "" {
+			v.addError(fieldNode(node, "name"), fmt.Sprintf("tasks[%d].name", i), "task %d: name is required", i+1)
+		} else if seenTasks[t.Name] {
+			v.addError(mappingValue(node, "name"), fmt.Sprintf("tasks[%d].name", i), "duplicate task name: %s", t.Name)
+		}
+		seenTasks[t.Name] = true
+		if t.Description == "" {
+			v.addError(fieldNode(node, "description"), fmt.Sprintf("tasks[%d].description", i), "task %s: description is required", t.Name)
+		}
+		if t.Agent != "" && !agentNames[t.Agent] {
+			v.addError(mappingValue(node, "agent"), fmt.Sprintf("tasks[%d].agent", i), "task %s references unknown agent: %s", t.Name, t.Agent)
+		}
+		if knownTools != nil {
+			toolNodes := sequenceItems(mappingValue(node, "tools"))
+			for j, tool := range t.Tools {
+				if !tools[tool] {
+					v.addError(itemAt(toolNodes, j), fmt.Sprintf("tasks[%d].tools[%d]", i, j), "task %s uses unknown tool %q, available tools: %s",
+						t.Name, tool, strings.Join(knownTools, ", "))
+				}
+			}
+		}
+		contextNodes := sequenceItems(mappingValue(node, "context"))
+		for j, name := range t.Context {
+			if !taskNames[name] {
+				v.addError(itemAt(contextNodes, j), fmt.Sprintf("tasks[%d].context[%d]", i, j), "task %s references unknown context task: %s", t.Name, name)
+			}
+		}
+	}
+}
+
+// mappingValue 返回映射节点中键对应的值节点
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// fieldNode 返回映射节点中键对应的值节点，键不存在时返回映射节点本身
+func fieldNode(node *yaml.Node, key string) *yaml.Node {
+	if value := mappingValue(node, key); value != nil {
+		return value
+	}
+	return node
+}
+
+func sequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+func itemAt(nodes []*yaml.Node, i int) *yaml.Node {
+	if i < len(nodes) {
+		return nodes[i]
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
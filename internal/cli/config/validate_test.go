@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const invalidProjectYAML = `name: test-project
+type: crew
+go_module: github.com/test/project
+process: round_robin
+agents:
+  - name: researcher
+    role: Researcher
+    goal: Research topics
+    backstory: Experienced researcher
+    tools:
+      - calculator
+      - web_magic
+tasks:
+  - name: research
+    description: Research the topic
+    agent: researcher
+  - name: write
+    description: Write the article
+    agent: writer
+    context:
+      - research
+      - outline
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "greensoulai.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestValidateProjectFile(t *testing.T) {
+	path := writeConfig(t, invalidProjectYAML)
+
+	_, err := ValidateProjectFile(path, []string{"calculator", "text_analyzer"})
+	var fileErrs FileErrors
+	if !errors.As(err, &fileErrs) {
+		t.Fatalf("expected FileErrors, got %v", err)
+	}
+
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{4, `unknown process "round_robin"`},
+		{12, `unknown tool "web_magic"`},
+		{19, "unknown agent: writer"},
+		{22, "unknown context task: outline"},
+	}
+	if len(fileErrs) != len(expected) {
+		t.Fatalf("expected %d errors, got %d:\n%v", len(expected), len(fileErrs), err)
+	}
+	for i, want := range expected {
+		if fileErrs[i].Line != want.line || !strings.Contains(fileErrs[i].Message, want.message) {
+			t.Errorf("error %d: expected line %d %q, got %d %q", i, want.line, want.message, fileErrs[i].Line, fileErrs[i].Message)
+		}
+	}
+
+	// 错误信息带有文件位置和源码行
+	if !strings.Contains(err.Error(), path+":19:12: tasks[1].agent: task write references unknown agent: writer\n        agent: writer\n") {
+		t.Errorf("expected source context in error, got:\n%v", err)
+	}
+}
+
+func TestValidateProjectFileValid(t *testing.T) {
+	content := strings.Replace(invalidProjectYAML, "process: round_robin", "process: sequential", 1)
+	content = strings.Replace(content, "      - web_magic\n", "", 1)
+	content = strings.Replace(content, "agent: writer", "agent: researcher", 1)
+	content = strings.Replace(content, "      - outline\n", "", 1)
+	path := writeConfig(t, content)
+
+	config, err := ValidateProjectFile(path, []string{"calculator"})
+	if err != nil {
+		t.Fatalf("expected valid config, got: %v", err)
+	}
+	if config.Process != "sequential" || len(config.Tasks) != 2 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	// 不检查工具时未知工具不报错
+	content = strings.Replace(content, "      - calculator\n", "      - custom_tool\n", 1)
+	if _, err := ValidateProjectFile(writeConfig(t, content), nil); err != nil {
+		t.Errorf("expected tools to be unchecked, got: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package generator
 
 import (
 	"fmt"
+	"go/format"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,10 +11,15 @@ import (
 	"github.com/ynl/greensoulai/internal/cli/config"
 )
 
+// GreensoulaiVersion 生成项目的go.mod默认依赖的greensoulai版本
+const GreensoulaiVersion = "v0.1.0"
+
 // CrewGenerator Crew项目生成器
 type CrewGenerator struct {
-	config *config.ProjectConfig
-	output string
+	config          *config.ProjectConfig
+	output          string
+	greensoulaiRoot string
+	withFlow        bool
 }
 
 // NewCrewGenerator 创建Crew项目生成器
@@ -24,6 +30,17 @@ func NewCrewGenerator(cfg *config.ProjectConfig, outputDir string) *CrewGenerato
 	}
 }
 
+// SetGreensoulaiRoot 设置本地greensoulai模块目录（create --local-dev），
+// 设置后go.mod通过replace指向该目录，否则依赖发布的GreensoulaiVersion版本
+func (g *CrewGenerator) SetGreensoulaiRoot(dir string) {
+	g.greensoulaiRoot = dir
+}
+
+// SetWithFlow 设置是否把Crew包装为pkg/flow工作流中的作业运行（create --with-flow）
+func (g *CrewGenerator) SetWithFlow(withFlow bool) {
+	g.withFlow = withFlow
+}
+
 // Generate 生成Crew项目
 func (g *CrewGenerator) Generate() error {
 	// 创建项目目录
@@ -117,29 +134,32 @@ func (g *CrewGenerator) generateConfig() error {
 
 // generateGoMod 生成go.mod文件
 func (g *CrewGenerator) generateGoMod() error {
-	// 获取当前工作目录作为greensoulai项目根路径
-	greensoulaiRoot, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
+	return writeGoMod(g.output, g.config, g.greensoulaiRoot)
+}
 
+// writeGoMod 生成项目的go.mod：默认依赖发布的greensoulai版本，
+// greensoulaiRoot非空时额外通过replace指向本地模块，用于本地开发
+func writeGoMod(output string, cfg *config.ProjectConfig, greensoulaiRoot string) error {
 	content := fmt.Sprintf(`module %s
 
 go %s
 
-require (
-	github.com/ynl/greensoulai v0.0.0-00010101000000-000000000000
-	github.com/spf13/cobra v1.8.0
-	github.com/joho/godotenv v1.5.1
-	gopkg.in/yaml.v3 v3.0.1
-)
+require github.com/ynl/greensoulai %s
+`, cfg.GoModule, cfg.GoVersion, GreensoulaiVersion)
 
+	if greensoulaiRoot != "" {
+		root, err := filepath.Abs(greensoulaiRoot)
+		if err != nil {
+			return fmt.Errorf("failed to resolve greensoulai root: %w", err)
+		}
+		// go.mod中的路径统一使用正斜杠，Windows上同样有效
+		content += fmt.Sprintf(`
 // 用于本地开发，指向本地的greensoulai模块
 replace github.com/ynl/greensoulai => %s
-`, g.config.GoModule, g.config.GoVersion, greensoulaiRoot)
+`, filepath.ToSlash(root))
+	}
 
-	path := filepath.Join(g.output, "go.mod")
-	return os.WriteFile(path, []byte(content), 0644)
+	return os.WriteFile(filepath.Join(output, "go.mod"), []byte(content), 0644)
 }
 
 // generateMain 生成主文件
@@ -147,31 +167,57 @@ func (g *CrewGenerator) generateMain() error {
 	content := fmt.Sprintf(`package main
 
 import (
+	"bufio"
 	"log"
 	"os"
+	"strings"
 
-	"github.com/joho/godotenv"
-	
 	"%s/internal/crew"
 )
 
 func main() {
 	// 加载环境变量
-	if err := godotenv.Load(); err != nil {
+	if err := loadDotEnv(".env"); err != nil {
 		log.Println("Warning: .env file not found")
 	}
-	
+
 	// 创建并运行crew
 	c, err := crew.New%sCrew()
 	if err != nil {
 		log.Fatalf("Failed to create crew: %%v", err)
 	}
-	
+
 	// 运行crew
 	if err := c.Run(); err != nil {
 		log.Fatalf("Failed to run crew: %%v", err)
 	}
 }
+
+// loadDotEnv 读取KEY=VALUE格式的环境变量文件，已设置的环境变量不会被覆盖
+func loadDotEnv(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, strings.Trim(strings.TrimSpace(value), "\"'"))
+		}
+	}
+	return scanner.Err()
+}
 `, g.config.GoModule, toPascalCase(g.config.Name))
 
 	path := filepath.Join(g.output, "cmd", "main.go")
@@ -195,65 +241,55 @@ func (g *CrewGenerator) generateAgents() error {
 
 // GenerateAgentCode 生成单个Agent的代码
 func (g *CrewGenerator) GenerateAgentCode(agentCfg config.AgentConfig) string {
-	toolsImports := ""
-	toolsSetup := ""
+	toolsImport := ""
+	toolsField := ""
 
 	if len(agentCfg.Tools) > 0 {
-		toolsImports = fmt.Sprintf("\n\t\"%s/internal/tools\"", g.config.GoModule)
+		toolsImport = fmt.Sprintf("\n\t\"%s/internal/tools\"", g.config.GoModule)
 
-		var toolSetups []string
-		for _, tool := range agentCfg.Tools {
-			toolSetups = append(toolSetups, fmt.Sprintf("\t\ttools.New%sTool()", toPascalCase(tool)))
-		}
-		toolsSetup = fmt.Sprintf(`
-	// 添加工具
-	tools := []agent.Tool{
-%s,
-	}
-	
-	for _, tool := range tools {
-		if err := a.AddTool(tool); err != nil {
-			return nil, fmt.Errorf("failed to add tool: %%w", err)
+		toolSetups := make([]string, len(agentCfg.Tools))
+		for i, tool := range agentCfg.Tools {
+			toolSetups[i] = fmt.Sprintf("\t\t\ttools.New%sTool(),", toPascalCase(tool))
 		}
-	}`, strings.Join(toolSetups, ",\n"))
+		toolsField = fmt.Sprintf("\n\t\tTools: []agent.Tool{\n%s\n\t\t},", strings.Join(toolSetups, "\n"))
 	}
 
-	return fmt.Sprintf(`package agents
+	code := fmt.Sprintf(`package agents
 
 import (
 	"fmt"
-	
-	"github.com/ynl/greensoulai/internal/agent"
-	"github.com/ynl/greensoulai/internal/llm"
+
+	"github.com/ynl/greensoulai/pkg/agent"
 	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/llm"
 	"github.com/ynl/greensoulai/pkg/logger"%s
 )
 
 // New%sAgent 创建%s智能体
 func New%sAgent(llmProvider llm.LLM, eventBus events.EventBus, log logger.Logger) (agent.Agent, error) {
-	config := agent.AgentConfig{
-		Role:      "%s",
-		Goal:      "%s", 
-		Backstory: "%s",
-		LLM:       llmProvider,
-		EventBus:  eventBus,
-		Logger:    log,
-		ExecutionConfig: agent.ExecutionConfig{
-			MaxIterations:   25,
-			Timeout:        30 * time.Minute,
-			VerboseLogging: %t,
-		},
-	}
-	
-	a, err := agent.NewBaseAgent(config)
+	executionConfig := agent.DefaultExecutionConfig()
+	executionConfig.VerboseLogging = %t
+
+	a, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:            %q,
+		Goal:            %q,
+		Backstory:       %q,
+		LLM:             llmProvider,%s
+		ExecutionConfig: executionConfig,
+		AllowDelegation: %t,
+		EventBus:        eventBus,
+		Logger:          log,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %%w", err)
-	}%s
-	
+	}
+
 	return a, nil
 }
-`, toolsImports, toPascalCase(agentCfg.Name), agentCfg.Name, toPascalCase(agentCfg.Name),
-		agentCfg.Role, agentCfg.Goal, agentCfg.Backstory, agentCfg.Verbose, toolsSetup)
+`, toolsImport, toPascalCase(agentCfg.Name), agentCfg.Name, toPascalCase(agentCfg.Name),
+		agentCfg.Verbose, agentCfg.Role, agentCfg.Goal, agentCfg.Backstory, toolsField, agentCfg.AllowDelegation)
+
+	return formatGoSource(code)
 }
 
 // generateTasks 生成Task文件
@@ -272,30 +308,45 @@ func (g *CrewGenerator) generateTasks() error {
 }
 
 // GenerateTaskCode 生成单个Task的代码
+// 任务通过NewTaskWithOptions创建，任务ID由名称派生，每次运行保持不变
 func (g *CrewGenerator) GenerateTaskCode(taskCfg config.TaskConfig) string {
-	return fmt.Sprintf(`package tasks
+	options := []string{fmt.Sprintf("agent.WithName(%q),", taskCfg.Name)}
+	switch taskCfg.OutputFormat {
+	case "json":
+		options = append(options, "agent.WithOutputFormat(agent.OutputFormatJSON),")
+	case "markdown":
+		options = append(options, "agent.WithMarkdown(true),")
+	}
+	if taskCfg.OutputFile != "" {
+		options = append(options, fmt.Sprintf("agent.WithOutputFile(%q),", taskCfg.OutputFile))
+		if taskCfg.OutputAppend {
+			options = append(options, "agent.WithOutputFileAppend(true),")
+		}
+	}
+
+	code := fmt.Sprintf(`package tasks
 
 import (
-	"github.com/ynl/greensoulai/internal/agent"
-	"github.com/ynl/greensoulai/pkg/events"
-	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/agent"
 )
 
 // New%sTask 创建%s任务
-func New%sTask(eventBus events.EventBus, log logger.Logger) (agent.Task, error) {
-	task := &agent.BaseTask{
-		Name:           "%s",
-		Description:    "%s",
-		ExpectedOutput: "%s",
-		OutputFile:     "%s",
-		EventBus:       eventBus,
-		Logger:         log,
+func New%sTask() (*agent.BaseTask, error) {
+	task := agent.NewTaskWithOptions(
+		%q,
+		%q,
+		%s
+	)
+	if err := task.Validate(); err != nil {
+		return nil, err
 	}
-	
+
 	return task, nil
 }
 `, toPascalCase(taskCfg.Name), taskCfg.Name, toPascalCase(taskCfg.Name),
-		taskCfg.Name, taskCfg.Description, taskCfg.ExpectedOutput, taskCfg.OutputFile)
+		taskCfg.Description, taskCfg.ExpectedOutput, strings.Join(options, "\n\t\t"))
+
+	return formatGoSource(code)
 }
 
 // generateCrew 生成Crew文件
@@ -307,59 +358,79 @@ func (g *CrewGenerator) generateCrew() error {
 
 // generateCrewCode 生成Crew代码
 func (g *CrewGenerator) generateCrewCode() string {
-	agentImports := make([]string, len(g.config.Agents))
-	taskImports := make([]string, len(g.config.Tasks))
+	var imports []string
+	if len(g.config.Agents) > 0 {
+		imports = append(imports, fmt.Sprintf("%q", g.config.GoModule+"/internal/agents"))
+	}
+	if len(g.config.Tasks) > 0 {
+		imports = append(imports, fmt.Sprintf("%q", g.config.GoModule+"/internal/tasks"))
+	}
 
 	agentCreations := make([]string, len(g.config.Agents))
-	taskCreations := make([]string, len(g.config.Tasks))
-
+	agentsList := make([]string, len(g.config.Agents))
 	for i, agentCfg := range g.config.Agents {
-		agentImports[i] = fmt.Sprintf("\"%s/internal/agents\"", g.config.GoModule)
-		agentCreations[i] = fmt.Sprintf(`	%sAgent, err := agents.New%sAgent(llmProvider, eventBus, log)
+		name := toCamelCase(agentCfg.Name) + "Agent"
+		agentCreations[i] = fmt.Sprintf(`	%s, err := agents.New%sAgent(llmProvider, eventBus, log)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create %s agent: %%w", err)
-	}`, agentCfg.Name, toPascalCase(agentCfg.Name), agentCfg.Name)
+	}`, name, toPascalCase(agentCfg.Name), agentCfg.Name)
+		agentsList[i] = name
 	}
 
+	taskCreations := make([]string, len(g.config.Tasks))
+	tasksList := make([]string, len(g.config.Tasks))
+	var taskAssignments []string
 	for i, taskCfg := range g.config.Tasks {
-		taskImports[i] = fmt.Sprintf("\"%s/internal/tasks\"", g.config.GoModule)
-		taskCreations[i] = fmt.Sprintf(`	%sTask, err := tasks.New%sTask(eventBus, log)
+		name := toCamelCase(taskCfg.Name) + "Task"
+		taskCreations[i] = fmt.Sprintf(`	%s, err := tasks.New%sTask()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create %s task: %%w", err)
-	}`, taskCfg.Name, toPascalCase(taskCfg.Name), taskCfg.Name)
-	}
-
-	// 任务分配逻辑
-	taskAssignments := make([]string, len(g.config.Tasks))
-	agentsList := make([]string, len(g.config.Agents))
-	tasksList := make([]string, len(g.config.Tasks))
+	}`, name, toPascalCase(taskCfg.Name), taskCfg.Name)
+		tasksList[i] = name
 
-	for i, taskCfg := range g.config.Tasks {
 		if taskCfg.Agent != "" {
-			taskAssignments[i] = fmt.Sprintf(`	// 分配任务给Agent
-	if err := %sTask.SetAssignedAgent(%sAgent); err != nil {
-		return nil, fmt.Errorf("failed to assign task to agent: %%w", err)
-	}`, taskCfg.Name, taskCfg.Agent)
+			taskAssignments = append(taskAssignments, fmt.Sprintf(`	if err := %s.SetAssignedAgent(%sAgent); err != nil {
+		return nil, fmt.Errorf("failed to assign task %s: %%w", err)
+	}`, name, toCamelCase(taskCfg.Agent), taskCfg.Name))
+		}
+		if len(taskCfg.Context) > 0 {
+			contextTasks := make([]string, len(taskCfg.Context))
+			for j, contextName := range taskCfg.Context {
+				contextTasks[j] = toCamelCase(contextName) + "Task"
+			}
+			taskAssignments = append(taskAssignments, fmt.Sprintf("\t%s.SetContextTasks([]agent.Task{%s})",
+				name, strings.Join(contextTasks, ", ")))
 		}
-		tasksList[i] = fmt.Sprintf("%sTask", taskCfg.Name)
 	}
 
-	for i, agentCfg := range g.config.Agents {
-		agentsList[i] = fmt.Sprintf("%sAgent", agentCfg.Name)
+	if g.withFlow {
+		imports = append(imports, `"github.com/ynl/greensoulai/pkg/flow"`, `"github.com/ynl/greensoulai/pkg/flow/agentflow"`)
 	}
 
-	return fmt.Sprintf(`package crew
+	// 层级模式由管理者分配任务，共识模式由评审选出结果，都使用项目的LLM
+	processCode, processLLM := "crew.ProcessSequential", ""
+	switch g.config.Process {
+	case "hierarchical":
+		processCode, processLLM = "crew.ProcessHierarchical", "\n\t\tManagerLLM: llmProvider,"
+	case "parallel":
+		processCode = "crew.ProcessParallel"
+	case "consensus":
+		processCode, processLLM = "crew.ProcessConsensus", "\n\t\tJudgeLLM: llmProvider,"
+	}
+
+	code := fmt.Sprintf(`package crew
 
 import (
 	"context"
 	"fmt"
 	"os"
-	
-	"github.com/ynl/greensoulai/internal/crew"
-	"github.com/ynl/greensoulai/internal/llm"
+
+	"github.com/ynl/greensoulai/pkg/agent"
+	"github.com/ynl/greensoulai/pkg/crew"
 	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/llm"
 	"github.com/ynl/greensoulai/pkg/logger"
-	%s
+
 	%s
 )
 
@@ -373,87 +444,115 @@ type %sCrew struct {
 func New%sCrew() (*%sCrew, error) {
 	// 创建日志器
 	log := logger.NewConsoleLogger()
-	
+
 	// 创建事件总线
 	eventBus := events.NewEventBus(log)
-	
+
 	// 创建LLM提供商
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required")
 	}
-	
-	llmProvider := llm.NewOpenAILLM("%s", llm.WithAPIKey(apiKey))
-	
+	llmOptions := []llm.BaseLLMOption{llm.WithAPIKey(apiKey)}
+	if baseURL := os.Getenv("OPENAI_BASE_URL"); baseURL != "" {
+		llmOptions = append(llmOptions, llm.WithBaseURL(baseURL))
+	}
+	llmProvider := llm.NewOpenAILLM(%q, llmOptions...)
+
 	// 创建Agents
 %s
-	
+
 	// 创建Tasks
 %s
-	
+
+	// 分配Agent和上下文任务
 %s
-	
-	// 创建Crew配置
-	config := &crew.CrewConfig{
-		Name:    "%s",
-		Process: crew.ProcessSequential,
-		Verbose: true,
-	}
-	
+
 	// 创建Crew
-	c, err := crew.NewBaseCrew(config, eventBus, log)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create crew: %%w", err)
-	}
-	
+	c := crew.NewBaseCrew(&crew.CrewConfig{
+		Name:    %q,
+		Process: %s,%s
+		Verbose: true,
+	}, eventBus, log)
+
 	// 添加Agents
-	agents := []agent.Agent{%s}
-	for _, a := range agents {
+	for _, a := range []agent.Agent{%s} {
 		if err := c.AddAgent(a); err != nil {
 			return nil, fmt.Errorf("failed to add agent: %%w", err)
 		}
 	}
-	
+
 	// 添加Tasks
-	tasks := []agent.Task{%s}
-	for _, t := range tasks {
+	for _, t := range []agent.Task{%s} {
 		if err := c.AddTask(t); err != nil {
 			return nil, fmt.Errorf("failed to add task: %%w", err)
 		}
 	}
-	
+
 	return &%sCrew{
 		crew: c,
 		log:  log,
 	}, nil
 }
 
-// Run 运行Crew
+%s`, strings.Join(imports, "\n\t"),
+		toPascalCase(g.config.Name), toPascalCase(g.config.Name),
+		toPascalCase(g.config.Name), g.config.Name, toPascalCase(g.config.Name), toPascalCase(g.config.Name),
+		g.config.LLM.Model,
+		strings.Join(agentCreations, "\n\n"), strings.Join(taskCreations, "\n\n"),
+		strings.Join(taskAssignments, "\n"),
+		g.config.Name, processCode, processLLM,
+		strings.Join(agentsList, ", "), strings.Join(tasksList, ", "),
+		toPascalCase(g.config.Name), g.generateRunCode())
+
+	return formatGoSource(code)
+}
+
+// generateRunCode 生成Crew的Run方法；开启withFlow时Crew作为工作流中的作业运行，便于在前后添加其他作业
+func (g *CrewGenerator) generateRunCode() string {
+	name := toPascalCase(g.config.Name)
+	if !g.withFlow {
+		return fmt.Sprintf(`// Run 运行Crew
 func (c *%sCrew) Run() error {
+	defer c.crew.Close()
 	c.log.Info("启动%s团队...")
-	
-	ctx := context.Background()
-	inputs := make(map[string]interface{})
-	
-	output, err := c.crew.Kickoff(ctx, inputs)
+
+	output, err := c.crew.Kickoff(context.Background(), map[string]interface{}{})
 	if err != nil {
 		return fmt.Errorf("crew execution failed: %%w", err)
 	}
-	
+
 	c.log.Info("团队执行完成")
 	c.log.Info("执行结果", logger.Field{Key: "output", Value: output.Raw})
-	
+
 	return nil
-}
-`, strings.Join(removeDuplicates(agentImports), "\n\t"), strings.Join(removeDuplicates(taskImports), "\n\t"),
-		toPascalCase(g.config.Name), toPascalCase(g.config.Name),
-		toPascalCase(g.config.Name), g.config.Name, toPascalCase(g.config.Name), toPascalCase(g.config.Name),
-		g.config.LLM.Model,
-		strings.Join(agentCreations, "\n\n"), strings.Join(taskCreations, "\n\n"),
-		strings.Join(taskAssignments, "\n"),
-		g.config.Name,
-		strings.Join(agentsList, ", "), strings.Join(tasksList, ", "),
-		toPascalCase(g.config.Name), toPascalCase(g.config.Name), g.config.Name)
+}`, name, g.config.Name)
+	}
+
+	return fmt.Sprintf(`// Run 在工作流中运行Crew：crew作业执行团队任务，report作业读取团队输出
+// 可以在crew作业前后添加其他作业，例如准备输入或发布结果
+func (c *%sCrew) Run() error {
+	defer c.crew.Close()
+	c.log.Info("启动%s工作流...")
+
+	wf := flow.NewWorkflow(%q, flow.WithLogger(c.log))
+	wf.AddJob(agentflow.NewCrewJob("crew", c.crew, nil), flow.Immediately())
+	wf.AddJob(flow.NewStatefulJob("report", func(ctx context.Context, state flow.FlowState) (interface{}, error) {
+		output, ok := agentflow.GetCrewOutput(state, "crew")
+		if !ok {
+			return nil, fmt.Errorf("crew output not found")
+		}
+		c.log.Info("执行结果", logger.Field{Key: "output", Value: output.Raw})
+		return output.Raw, nil
+	}), flow.After("crew"))
+
+	if _, err := wf.Run(context.Background()); err != nil {
+		return fmt.Errorf("workflow execution failed: %%w", err)
+	}
+
+	c.log.Info("工作流执行完成")
+	return nil
+}`, name, g.config.Name, g.config.Name)
 }
 
 // generateTools 生成工具文件
@@ -466,8 +565,15 @@ func (g *CrewGenerator) generateTools() error {
 		}
 	}
 
-	// 为每个工具生成代码
+	// 为每个工具生成代码，内置工具使用对应的模板
 	for toolName := range toolSet {
+		if tmpl, ok := FindToolTemplate(toolName); ok {
+			if _, err := NewToolGenerator(g.output).Generate(tmpl); err != nil {
+				return err
+			}
+			continue
+		}
+
 		content := g.GenerateToolCode(toolName)
 		filename := fmt.Sprintf("%s.go", strings.ToLower(toolName))
 		path := filepath.Join(g.output, "internal", "tools", filename)
@@ -488,7 +594,7 @@ import (
 	"context"
 	"fmt"
 	
-	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/agent"
 )
 
 // New%sTool 创建%s工具
@@ -842,17 +948,20 @@ func toPascalCase(s string) string {
 	return result.String()
 }
 
-// removeDuplicates 移除重复字符串
-func removeDuplicates(slice []string) []string {
-	keys := make(map[string]bool)
-	var result []string
-
-	for _, item := range slice {
-		if !keys[item] {
-			keys[item] = true
-			result = append(result, item)
-		}
+// toCamelCase 转换为首字母小写的驼峰命名，用作生成代码中的变量名
+func toCamelCase(s string) string {
+	pascal := toPascalCase(s)
+	if pascal == "" {
+		return pascal
 	}
+	return strings.ToLower(pascal[:1]) + pascal[1:]
+}
 
-	return result
+// formatGoSource 格式化生成的Go源码，格式化失败时返回原始代码，便于用户定位问题
+func formatGoSource(code string) string {
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return code
+	}
+	return string(formatted)
 }
@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// testCrewConfig 测试项目使用与greensoulai无关的模块路径，确保生成的代码只依赖公开的pkg包
+func testCrewConfig() *config.ProjectConfig {
+	cfg := config.DefaultCrewProjectConfig("demo-crew", "example.com/demo-crew")
+	cfg.Agents = append(cfg.Agents, config.AgentConfig{
+		Name:      "writer",
+		Role:      "技术作家",
+		Goal:      "把研究结果写成\"易读\"的文章",
+		Backstory: "你擅长写作。",
+		Tools:     []string{"file_tool"},
+	})
+	cfg.Tasks = append(cfg.Tasks, config.TaskConfig{
+		Name:           "write_task",
+		Description:    "根据研究报告撰写文章",
+		ExpectedOutput: "一篇文章",
+		Agent:          "writer",
+		Context:        []string{"research_task"},
+		OutputFormat:   "json",
+	})
+	return cfg
+}
+
+func TestCrewGeneratorTaskCode(t *testing.T) {
+	gen := NewCrewGenerator(testCrewConfig(), t.TempDir())
+	code := gen.GenerateTaskCode(testCrewConfig().Tasks[0])
+
+	for _, want := range []string{
+		`agent.WithName("research_task")`,
+		`agent.WithMarkdown(true)`,
+		`agent.WithOutputFile("research_report.md")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated task code to contain %s:\n%s", want, code)
+		}
+	}
+}
+
+func TestCrewGeneratorGoMod(t *testing.T) {
+	output := t.TempDir()
+	if err := NewCrewGenerator(testCrewConfig(), output).Generate(); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(output, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 默认依赖发布版本，不指向生成器所在的目录
+	if !strings.Contains(string(data), "require github.com/ynl/greensoulai "+GreensoulaiVersion) || strings.Contains(string(data), "replace") {
+		t.Errorf("expected a versioned require without replace:\n%s", data)
+	}
+
+	local := t.TempDir()
+	gen := NewCrewGenerator(testCrewConfig(), local)
+	gen.SetGreensoulaiRoot(filepath.Join(output, "..", filepath.Base(output)))
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(local, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "replace github.com/ynl/greensoulai => "+filepath.ToSlash(output)+"\n") {
+		t.Errorf("expected a replace to the cleaned local root:\n%s", data)
+	}
+}
+
+func TestCrewGeneratorBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go build of generated project in short mode")
+	}
+
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		process  string
+		withFlow bool
+		expected string
+	}{
+		{name: "sequential", expected: "crew.ProcessSequential"},
+		{name: "hierarchical", process: "hierarchical", expected: "ManagerLLM: llmProvider"},
+		{name: "with flow", process: "consensus", withFlow: true, expected: `agentflow.NewCrewJob("crew", c.crew, nil)`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			output := t.TempDir()
+			cfg := testCrewConfig()
+			cfg.Process = tc.process
+
+			gen := NewCrewGenerator(cfg, output)
+			gen.SetGreensoulaiRoot(root)
+			gen.SetWithFlow(tc.withFlow)
+			if err := gen.Generate(); err != nil {
+				t.Fatalf("generate failed: %v", err)
+			}
+			crewCode, err := os.ReadFile(filepath.Join(output, "internal", "crew", "crew.go"))
+			if err != nil || !strings.Contains(string(crewCode), tc.expected) {
+				t.Fatalf("expected generated crew to contain %s (%v):\n%s", tc.expected, err, crewCode)
+			}
+
+			// 生成的项目可以编译（离线使用本地模块缓存）
+			env := append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod", "GOWORK=off")
+			tidy := exec.Command("go", "mod", "tidy")
+			tidy.Dir = output
+			tidy.Env = env
+			if out, err := tidy.CombinedOutput(); err != nil {
+				t.Fatalf("go mod tidy failed: %v\n%s", err, out)
+			}
+
+			for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+				cmd := exec.Command("go", args...)
+				cmd.Dir = output
+				cmd.Env = env
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("generated project fails go %s: %v\n%s", args[0], err, out)
+				}
+			}
+		})
+	}
+}
+
+// TestCrewGeneratorConfigLoads 生成的greensoulai.yaml可以直接加载为Crew并执行
+func TestCrewGeneratorConfigLoads(t *testing.T) {
+	output := t.TempDir()
+	if err := NewCrewGenerator(testCrewConfig(), output).Generate(); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	// 生成的项目在internal/tools中实现这些工具，这里用同名的桩工具代替
+	tools := config.BuiltinTools()
+	for _, name := range []string{"search_tool", "analysis_tool", "file_tool"} {
+		name := name
+		tools[name] = func() (agent.Tool, error) {
+			return agent.NewBaseTool(name, "Stub "+name, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				return "ok", nil
+			}), nil
+		}
+	}
+	model := llmtest.NewScriptedLLM(llmtest.Replies("研究报告：Go并发", `{"title": "Go并发"}`)...)
+
+	c, err := config.LoadCrewFromYAML(filepath.Join(output, "greensoulai.yaml"), config.CrewDeps{
+		NewLLM: func(string) (llm.LLM, error) { return model, nil },
+		Tools:  tools,
+		Logger: logger.NewTestLogger(),
+	})
+	if err != nil {
+		t.Fatalf("failed to load generated config: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	if len(result.TasksOutput) != 2 || result.TasksOutput[1].JSON["title"] != "Go并发" {
+		t.Errorf("unexpected task outputs: %+v", result.TasksOutput)
+	}
+	report, err := os.ReadFile(filepath.Join(output, "research_report.md"))
+	if err != nil || !strings.Contains(string(report), "研究报告：Go并发") {
+		t.Errorf("expected the research report in the project directory, got %q (%v)", report, err)
+	}
+}
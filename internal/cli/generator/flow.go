@@ -0,0 +1,341 @@
+package generator
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+)
+
+// FlowGenerator Flow项目生成器，生成基于pkg/flow的工作流项目
+type FlowGenerator struct {
+	config          *config.ProjectConfig
+	flow            *config.FlowConfig
+	output          string
+	greensoulaiRoot string
+}
+
+// NewFlowGenerator 创建Flow项目生成器
+func NewFlowGenerator(cfg *config.ProjectConfig, flowCfg *config.FlowConfig, outputDir string) *FlowGenerator {
+	return &FlowGenerator{
+		config: cfg,
+		flow:   flowCfg,
+		output: outputDir,
+	}
+}
+
+// SetGreensoulaiRoot 设置本地greensoulai模块目录（create --local-dev），
+// 设置后go.mod通过replace指向该目录，否则依赖发布的GreensoulaiVersion版本
+func (g *FlowGenerator) SetGreensoulaiRoot(dir string) {
+	g.greensoulaiRoot = dir
+}
+
+// Generate 生成Flow项目
+func (g *FlowGenerator) Generate() error {
+	// 先验证触发条件，避免生成无法运行的项目
+	if err := g.validate(); err != nil {
+		return fmt.Errorf("invalid flow configuration: %w", err)
+	}
+
+	if err := g.createDirectories(); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	if err := g.generateConfig(); err != nil {
+		return fmt.Errorf("failed to generate config: %w", err)
+	}
+
+	if err := g.generateGoMod(); err != nil {
+		return fmt.Errorf("failed to generate go.mod: %w", err)
+	}
+
+	if err := g.generateMain(); err != nil {
+		return fmt.Errorf("failed to generate main.go: %w", err)
+	}
+
+	if err := g.generateJobs(); err != nil {
+		return fmt.Errorf("failed to generate jobs: %w", err)
+	}
+
+	if err := g.generateReadme(); err != nil {
+		return fmt.Errorf("failed to generate README: %w", err)
+	}
+
+	if err := g.generateMakefile(); err != nil {
+		return fmt.Errorf("failed to generate Makefile: %w", err)
+	}
+
+	return nil
+}
+
+// validate 验证Flow配置，并确认作业ID生成的Go标识符不冲突
+func (g *FlowGenerator) validate() error {
+	if err := g.flow.Validate(); err != nil {
+		return err
+	}
+
+	names := make(map[string]string, len(g.flow.Jobs))
+	for _, job := range g.flow.Jobs {
+		name := toPascalCase(job.ID)
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("job ids %s and %s both generate New%sJob", other, job.ID, name)
+		}
+		names[name] = job.ID
+	}
+	return nil
+}
+
+// createDirectories 创建项目目录结构
+func (g *FlowGenerator) createDirectories() error {
+	dirs := []string{
+		g.output,
+		filepath.Join(g.output, "cmd"),
+		filepath.Join(g.output, "flows"),
+	}
+
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// generateConfig 生成项目配置文件和flow.yaml
+func (g *FlowGenerator) generateConfig() error {
+	g.config.CreatedAt = time.Now().Format(time.RFC3339)
+	if err := g.config.SaveProjectConfig(filepath.Join(g.output, "greensoulai.yaml")); err != nil {
+		return err
+	}
+	return g.flow.SaveFlowConfig(filepath.Join(g.output, config.FlowConfigFileName))
+}
+
+// generateGoMod 生成go.mod文件
+func (g *FlowGenerator) generateGoMod() error {
+	return writeGoMod(g.output, g.config, g.greensoulaiRoot)
+}
+
+// generateMain 生成主文件：按flow.yaml中的触发条件组装工作流并运行
+func (g *FlowGenerator) generateMain() error {
+	jobs := make([]string, len(g.flow.Jobs))
+	for i, job := range g.flow.Jobs {
+		jobs[i] = fmt.Sprintf("\twf.AddJob(flows.New%sJob(), %s)", toPascalCase(job.ID), triggerCode(job.Trigger))
+	}
+
+	content := fmt.Sprintf(`package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/ynl/greensoulai/pkg/flow"
+	"github.com/ynl/greensoulai/pkg/logger"
+
+	"%s/flows"
+)
+
+func main() {
+	log := logger.NewConsoleLogger()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// greensoulai flow schedule通过FLOW_STATE传入每次执行的初始状态
+	if data := os.Getenv("FLOW_STATE"); data != "" {
+		var state map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid FLOW_STATE: %%v\n", err)
+			os.Exit(1)
+		}
+		ctx = flow.WithInitialState(ctx, state)
+	}
+
+	// 组装工作流，触发条件与flow.yaml保持一致
+	wf := flow.NewWorkflow(%q, flow.WithLogger(log))
+%s
+
+	result, err := wf.Run(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "workflow failed: %%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("完成的作业: %%v\n", result.SucceededJobs)
+	fmt.Printf("最终结果: %%v\n", result.FinalResult)
+}
+`, g.config.GoModule, g.flow.Name, strings.Join(jobs, "\n"))
+
+	return writeGoFile(filepath.Join(g.output, "cmd", "main.go"), content)
+}
+
+// triggerCode 生成触发条件对应的pkg/flow调用
+func triggerCode(trigger config.TriggerConfig) string {
+	switch {
+	case trigger.Immediately:
+		return "flow.Immediately()"
+	case len(trigger.After) == 1:
+		return fmt.Sprintf("flow.After(%q)", trigger.After[0])
+	case len(trigger.After) > 1:
+		return fmt.Sprintf("flow.AfterJobs(%s)", quoteAll(trigger.After))
+	default:
+		return fmt.Sprintf("flow.AfterAnyJob(%s)", quoteAll(trigger.AfterAny))
+	}
+}
+
+// generateJobs 为每个作业生成一个StatefulJob
+func (g *FlowGenerator) generateJobs() error {
+	for _, job := range g.flow.Jobs {
+		path := filepath.Join(g.output, "flows", job.ID+"_job.go")
+		if err := writeGoFile(path, g.GenerateJobCode(job)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateJobCode 生成作业代码：读取上游作业写入状态的结果，并把自己的结果写入状态
+func (g *FlowGenerator) GenerateJobCode(job config.FlowJobConfig) string {
+	description := strings.Join(strings.Fields(job.Description), " ")
+	if description == "" {
+		description = job.ID + "作业"
+	}
+
+	inputs := `	inputs := make(map[string]interface{})`
+	if deps := job.Trigger.Dependencies(); len(deps) > 0 {
+		inputs = fmt.Sprintf(`	// 读取上游作业的结果
+	inputs := make(map[string]interface{})
+	for _, dep := range []string{%s} {
+		if value, ok := state.Get(dep); ok {
+			inputs[dep] = value
+		}
+	}`, quoteAll(deps))
+	}
+
+	return fmt.Sprintf(`package flows
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ynl/greensoulai/pkg/flow"
+)
+
+// New%sJob 创建%s作业：%s
+func New%sJob() flow.StatefulJob {
+	return flow.NewStatefulJob(%q, func(ctx context.Context, state flow.FlowState) (interface{}, error) {
+	%s
+
+		// TODO: 实现%s作业的具体逻辑
+		result := fmt.Sprintf("%s完成，输入: %%v", inputs)
+
+		state.Set(%q, result)
+		return result, nil
+	})
+}
+`, toPascalCase(job.ID), job.ID, description, toPascalCase(job.ID), job.ID,
+		inputs, job.ID, job.ID, job.ID)
+}
+
+// generateReadme 生成README文件
+func (g *FlowGenerator) generateReadme() error {
+	jobs := make([]string, len(g.flow.Jobs))
+	for i, job := range g.flow.Jobs {
+		trigger := "立即执行"
+		switch {
+		case len(job.Trigger.After) > 0:
+			trigger = "在 " + strings.Join(job.Trigger.After, "、") + " 全部完成后执行"
+		case len(job.Trigger.AfterAny) > 0:
+			trigger = "在 " + strings.Join(job.Trigger.AfterAny, "、") + " 任一完成后执行"
+		}
+		jobs[i] = fmt.Sprintf("- **%s**：%s（%s）", job.ID, job.Description, trigger)
+	}
+
+	content := fmt.Sprintf(`# %s
+
+%s
+
+基于 GreenSoulAI 工作流引擎（pkg/flow）的 Flow 项目。没有依赖关系的作业会并行执行。
+
+## 作业
+
+%s
+
+## 项目结构
+
+- `+"`flow.yaml`"+`：作业及其触发条件
+- `+"`cmd/main.go`"+`：按触发条件组装并运行工作流
+- `+"`flows/`"+`：每个作业一个文件，作业之间通过工作流状态传递结果
+
+修改 flow.yaml 中的触发条件后，请同步更新 cmd/main.go 中对应的 AddJob 调用。
+
+## 运行
+
+`+"```bash"+`
+make deps
+make run
+`+"```"+`
+`, g.config.Name, g.config.Description, strings.Join(jobs, "\n"))
+
+	path := filepath.Join(g.output, "README.md")
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// generateMakefile 生成Makefile
+func (g *FlowGenerator) generateMakefile() error {
+	content := fmt.Sprintf(`.PHONY: build run test clean deps
+
+# 项目参数
+BINARY_NAME=%s
+MAIN_PATH=./cmd/main.go
+
+# 构建
+build:
+	go build -o $(BINARY_NAME) $(MAIN_PATH)
+
+# 运行
+run:
+	go run $(MAIN_PATH)
+
+# 测试
+test:
+	go test -v ./...
+
+# 清理
+clean:
+	go clean
+	rm -f $(BINARY_NAME)
+
+# 依赖管理
+deps:
+	go mod tidy
+`, g.config.Name)
+
+	path := filepath.Join(g.output, "Makefile")
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// writeGoFile 格式化并写入Go源码
+func writeGoFile(path, content string) error {
+	formatted, err := format.Source([]byte(content))
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", path, err)
+	}
+	return os.WriteFile(path, formatted, 0644)
+}
+
+// quoteAll 把字符串列表转换为Go字符串字面量列表
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return strings.Join(quoted, ", ")
+}
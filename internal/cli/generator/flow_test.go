@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+)
+
+var update = flag.Bool("update", false, "更新golden文件")
+
+// flowGoldenFiles 与golden文件比较的生成结果（greensoulai.yaml含创建时间，不参与比较）
+var flowGoldenFiles = []string{
+	"go.mod",
+	"flow.yaml",
+	"cmd/main.go",
+	"flows/fetch_data_job.go",
+	"flows/analyze_job.go",
+	"flows/summarize_job.go",
+	"flows/report_job.go",
+	"flows/notify_job.go",
+	"README.md",
+	"Makefile",
+}
+
+func testFlowConfig() *config.FlowConfig {
+	flowCfg := config.DefaultFlowConfig("demo-flow")
+	flowCfg.Jobs = append(flowCfg.Jobs, config.FlowJobConfig{
+		ID:          "notify",
+		Description: "任一结果就绪时发送通知",
+		Trigger:     config.TriggerConfig{AfterAny: []string{"analyze", "summarize"}},
+	})
+	return flowCfg
+}
+
+func TestFlowGeneratorGolden(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+
+	gen := NewFlowGenerator(config.DefaultFlowProjectConfig("demo-flow", "example.com/demo-flow"), testFlowConfig(), output)
+	gen.SetGreensoulaiRoot(root)
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	for _, name := range flowGoldenFiles {
+		data, err := os.ReadFile(filepath.Join(output, name))
+		if err != nil {
+			t.Fatalf("expected generated file %s: %v", name, err)
+		}
+		got := strings.ReplaceAll(string(data), root, "GREENSOULAI_ROOT")
+
+		golden := filepath.Join("testdata", "flow", name+".golden")
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("failed to read golden file (run with -update to create): %v", err)
+		}
+		if got != string(want) {
+			t.Errorf("%s does not match %s:\n%s", name, golden, got)
+		}
+	}
+
+	if testing.Short() {
+		t.Skip("skipping go build of generated project in short mode")
+	}
+
+	// 生成的项目可以编译（离线使用本地模块缓存）
+	env := append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod", "GOWORK=off")
+	tidy := exec.Command("go", "mod", "tidy")
+	tidy.Dir = output
+	tidy.Env = env
+	if out, err := tidy.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy failed: %v\n%s", err, out)
+	}
+
+	build := exec.Command("go", "build", "./...")
+	build.Dir = output
+	build.Env = env
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("generated project does not build: %v\n%s", err, out)
+	}
+}
+
+func TestFlowGeneratorRejectsUnknownTrigger(t *testing.T) {
+	flowCfg := testFlowConfig()
+	flowCfg.Jobs[3].Trigger.After = []string{"analyze", "translate"}
+
+	output := filepath.Join(t.TempDir(), "demo-flow")
+	gen := NewFlowGenerator(config.DefaultFlowProjectConfig("demo-flow", "example.com/demo-flow"), flowCfg, output)
+	err := gen.Generate()
+	if err == nil || !strings.Contains(err.Error(), "job report trigger references unknown job: translate") {
+		t.Fatalf("expected unknown job error, got %v", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be generated for invalid config")
+	}
+}
@@ -0,0 +1,360 @@
+package generator
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+)
+
+// ToolTemplate 内置工具模板
+type ToolTemplate struct {
+	Name        string
+	Description string
+	// code 工具源码的格式串，%[1]s为帕斯卡命名，%[2]s为工具名
+	code string
+}
+
+// ToolTemplates 内置工具模板目录
+var ToolTemplates = []ToolTemplate{
+	{Name: "file_tool", Description: "文件操作工具（在工作目录内读取、写入和列出文件）", code: fileToolCode},
+	{Name: "api_client_tool", Description: "API客户端工具（发送HTTP请求并返回状态码和响应体）", code: apiClientToolCode},
+	{Name: "web_scraper_tool", Description: "网页抓取工具（获取网页标题和正文文本）", code: webScraperToolCode},
+}
+
+// FindToolTemplate 按名称查找内置工具模板
+func FindToolTemplate(name string) (ToolTemplate, bool) {
+	for _, tmpl := range ToolTemplates {
+		if tmpl.Name == name {
+			return tmpl, true
+		}
+	}
+	return ToolTemplate{}, false
+}
+
+// ToolGenerator 工具代码生成器，生成的文件位于项目的internal/tools目录
+type ToolGenerator struct {
+	output string
+}
+
+// NewToolGenerator 创建工具代码生成器
+func NewToolGenerator(projectRoot string) *ToolGenerator {
+	return &ToolGenerator{output: projectRoot}
+}
+
+// ToolFile 返回工具源码文件相对项目根目录的路径
+func ToolFile(name string) string {
+	return filepath.Join("internal", "tools", name+".go")
+}
+
+// GenerateToolCode 按模板生成格式化后的工具源码
+func (g *ToolGenerator) GenerateToolCode(tmpl ToolTemplate) (string, error) {
+	code := fmt.Sprintf(tmpl.code, toPascalCase(tmpl.Name), tmpl.Name)
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return "", fmt.Errorf("failed to format tool %s: %w", tmpl.Name, err)
+	}
+	return string(formatted), nil
+}
+
+// Generate 生成工具源码文件，返回写入的路径
+func (g *ToolGenerator) Generate(tmpl ToolTemplate) (string, error) {
+	content, err := g.GenerateToolCode(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(g.output, ToolFile(tmpl.Name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create tools directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write tool file %s: %w", path, err)
+	}
+	return path, nil
+}
+
+const fileToolCode = `package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ynl/greensoulai/pkg/agent"
+)
+
+// %[1]sBaseDir %[2]s工具可访问的根目录，路径不能越出此目录
+var %[1]sBaseDir = "."
+
+// New%[1]sTool 创建%[2]s工具：在根目录内读取、写入和列出文件
+func New%[1]sTool() agent.Tool {
+	tool := agent.NewBaseTool(
+		"%[2]s",
+		"Read, write or list files. Arguments: action (read|write|list), path, content (for write)",
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			action, _ := args["action"].(string)
+			path, _ := args["path"].(string)
+			if path == "" {
+				path = "."
+			}
+
+			fullPath, err := resolve%[1]sPath(path)
+			if err != nil {
+				return nil, err
+			}
+
+			switch action {
+			case "read":
+				data, err := os.ReadFile(fullPath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read %%s: %%w", path, err)
+				}
+				return string(data), nil
+			case "write":
+				content, ok := args["content"].(string)
+				if !ok {
+					return nil, fmt.Errorf("content is required for write")
+				}
+				if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+					return nil, fmt.Errorf("failed to create directory for %%s: %%w", path, err)
+				}
+				if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+					return nil, fmt.Errorf("failed to write %%s: %%w", path, err)
+				}
+				return fmt.Sprintf("wrote %%d bytes to %%s", len(content), path), nil
+			case "list":
+				entries, err := os.ReadDir(fullPath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list %%s: %%w", path, err)
+				}
+				names := make([]string, 0, len(entries))
+				for _, entry := range entries {
+					name := entry.Name()
+					if entry.IsDir() {
+						name += "/"
+					}
+					names = append(names, name)
+				}
+				return strings.Join(names, "\n"), nil
+			default:
+				return nil, fmt.Errorf("unsupported action %%q, expected read, write or list", action)
+			}
+		},
+	)
+
+	tool.SetSchema(agent.ToolSchema{
+		Name:        "%[2]s",
+		Description: "Read, write or list files",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"action":  map[string]interface{}{"type": "string", "enum": []string{"read", "write", "list"}},
+				"path":    map[string]interface{}{"type": "string", "description": "Path relative to the base directory"},
+				"content": map[string]interface{}{"type": "string", "description": "Content to write"},
+			},
+		},
+		Required: []string{"action", "path"},
+	})
+
+	return tool
+}
+
+// resolve%[1]sPath 把路径解析到根目录内，拒绝越出根目录的路径
+func resolve%[1]sPath(path string) (string, error) {
+	base, err := filepath.Abs(%[1]sBaseDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve base directory: %%w", err)
+	}
+
+	fullPath := filepath.Join(base, path)
+	rel, err := filepath.Rel(base, fullPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %%s is outside the base directory", path)
+	}
+	return fullPath, nil
+}
+`
+
+const apiClientToolCode = `package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/agent"
+)
+
+// %[1]sMaxBody 返回给智能体的响应体最大字节数
+const %[1]sMaxBody = 64 * 1024
+
+// New%[1]sTool 创建%[2]s工具：发送HTTP请求并返回状态码和响应体
+func New%[1]sTool() agent.Tool {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	tool := agent.NewBaseTool(
+		"%[2]s",
+		"Send an HTTP request. Arguments: url, method (default GET), headers (object), body",
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			url, ok := args["url"].(string)
+			if !ok || url == "" {
+				return nil, fmt.Errorf("url is required and must be a string")
+			}
+			method, _ := args["method"].(string)
+			if method == "" {
+				method = http.MethodGet
+			}
+			body, _ := args["body"].(string)
+
+			req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), url, strings.NewReader(body))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %%w", err)
+			}
+			if headers, ok := args["headers"].(map[string]interface{}); ok {
+				for key, value := range headers {
+					req.Header.Set(key, fmt.Sprint(value))
+				}
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, fmt.Errorf("request failed: %%w", err)
+			}
+			defer resp.Body.Close()
+
+			data, err := io.ReadAll(io.LimitReader(resp.Body, %[1]sMaxBody))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response: %%w", err)
+			}
+
+			return map[string]interface{}{
+				"status_code":  resp.StatusCode,
+				"content_type": resp.Header.Get("Content-Type"),
+				"body":         string(data),
+			}, nil
+		},
+	)
+
+	tool.SetSchema(agent.ToolSchema{
+		Name:        "%[2]s",
+		Description: "Send an HTTP request and return the status code and response body",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"url":     map[string]interface{}{"type": "string"},
+				"method":  map[string]interface{}{"type": "string", "description": "HTTP method, default GET"},
+				"headers": map[string]interface{}{"type": "object"},
+				"body":    map[string]interface{}{"type": "string"},
+			},
+		},
+		Required: []string{"url"},
+	})
+
+	return tool
+}
+`
+
+const webScraperToolCode = `package tools
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/agent"
+)
+
+var (
+	%[1]sTitle    = regexp.MustCompile(` + "`(?is)<title[^>]*>(.*?)</title>`" + `)
+	%[1]sNoise    = regexp.MustCompile(` + "`(?is)<(script|style|noscript)[^>]*>.*?</(script|style|noscript)>`" + `)
+	%[1]sTags     = regexp.MustCompile(` + "`(?s)<[^>]*>`" + `)
+	%[1]sSpaces   = regexp.MustCompile(` + "`\\s+`" + `)
+	%[1]sMaxBytes int64 = 2 << 20
+)
+
+// New%[1]sTool 创建%[2]s工具：获取网页并提取标题和正文文本
+func New%[1]sTool() agent.Tool {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	tool := agent.NewBaseTool(
+		"%[2]s",
+		"Fetch a web page and extract its title and text. Arguments: url, max_length (default 8000)",
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			url, ok := args["url"].(string)
+			if !ok || url == "" {
+				return nil, fmt.Errorf("url is required and must be a string")
+			}
+			maxLength := 8000
+			if value, ok := args["max_length"].(float64); ok && value > 0 {
+				maxLength = int(value)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %%w", err)
+			}
+			req.Header.Set("User-Agent", "greensoulai-%[2]s/1.0")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch %%s: %%w", url, err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode >= 400 {
+				return nil, fmt.Errorf("failed to fetch %%s: status %%d", url, resp.StatusCode)
+			}
+
+			data, err := io.ReadAll(io.LimitReader(resp.Body, %[1]sMaxBytes))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %%s: %%w", url, err)
+			}
+			page := string(data)
+
+			title := ""
+			if match := %[1]sTitle.FindStringSubmatch(page); match != nil {
+				title = strings.TrimSpace(html.UnescapeString(match[1]))
+			}
+
+			text := %[1]sNoise.ReplaceAllString(page, " ")
+			text = %[1]sTags.ReplaceAllString(text, " ")
+			text = html.UnescapeString(text)
+			text = strings.TrimSpace(%[1]sSpaces.ReplaceAllString(text, " "))
+			if runes := []rune(text); len(runes) > maxLength {
+				text = string(runes[:maxLength])
+			}
+
+			return map[string]interface{}{
+				"url":   url,
+				"title": title,
+				"text":  text,
+			}, nil
+		},
+	)
+
+	tool.SetSchema(agent.ToolSchema{
+		Name:        "%[2]s",
+		Description: "Fetch a web page and extract its title and text",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"url":        map[string]interface{}{"type": "string"},
+				"max_length": map[string]interface{}{"type": "number", "description": "Maximum number of characters returned"},
+			},
+		},
+		Required: []string{"url"},
+	})
+
+	return tool
+}
+`
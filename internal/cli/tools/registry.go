@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+	"github.com/ynl/greensoulai/internal/cli/generator"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFileName 项目中记录已安装工具的清单文件
+const ManifestFileName = "tools.yaml"
+
+var (
+	// ErrToolExists 工具已存在且未指定覆盖
+	ErrToolExists = errors.New("tool already exists")
+	// ErrToolNotInstalled 工具未通过清单安装
+	ErrToolNotInstalled = errors.New("tool is not installed")
+	// ErrToolInUse 工具仍被Agent或任务引用
+	ErrToolInUse = errors.New("tool is still in use")
+)
+
+// InstalledTool 清单中的已安装工具
+type InstalledTool struct {
+	Name        string `yaml:"name"`
+	Template    string `yaml:"template"`
+	File        string `yaml:"file"` // 相对项目根目录
+	InstalledAt string `yaml:"installed_at"`
+}
+
+// Manifest 工具清单（tools.yaml）
+type Manifest struct {
+	Tools []InstalledTool `yaml:"tools"`
+}
+
+// ToolSource 工具来源
+type ToolSource string
+
+const (
+	SourceCatalog ToolSource = "catalog" // 内置模板
+	SourceCustom  ToolSource = "custom"  // 项目自定义
+)
+
+// ToolInfo 工具列表中的一项
+type ToolInfo struct {
+	Name        string
+	Description string
+	Source      ToolSource
+	Installed   bool
+	File        string   // 源码文件，相对项目根目录；不存在时为空
+	Agents      []string // 使用该工具的Agent
+}
+
+// Registry 项目工具注册表，合并内置模板目录和项目中的工具
+type Registry struct {
+	root     string
+	config   *config.ProjectConfig
+	manifest *Manifest
+}
+
+// NewRegistry 加载项目的工具清单并创建注册表
+func NewRegistry(projectRoot string, cfg *config.ProjectConfig) (*Registry, error) {
+	manifest, err := LoadManifest(filepath.Join(projectRoot, ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{root: projectRoot, config: cfg, manifest: manifest}, nil
+}
+
+// LoadManifest 加载工具清单，文件不存在时返回空清单
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse tool manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Save 保存工具清单
+func (m *Manifest) Save(path string) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tool manifest: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tool manifest: %w", err)
+	}
+	return nil
+}
+
+// Find 返回清单中的工具
+func (m *Manifest) Find(name string) (*InstalledTool, bool) {
+	for i := range m.Tools {
+		if m.Tools[i].Name == name {
+			return &m.Tools[i], true
+		}
+	}
+	return nil, false
+}
+
+func (m *Manifest) remove(name string) {
+	tools := m.Tools[:0]
+	for _, tool := range m.Tools {
+		if tool.Name != name {
+			tools = append(tools, tool)
+		}
+	}
+	m.Tools = tools
+}
+
+// Manifest 返回当前清单
+func (r *Registry) Manifest() *Manifest {
+	return r.manifest
+}
+
+// List 列出内置模板和项目自定义工具，以及使用每个工具的Agent
+func (r *Registry) List() ([]ToolInfo, error) {
+	users := r.toolUsers()
+	seen := make(map[string]bool)
+
+	var infos []ToolInfo
+	for _, tmpl := range generator.ToolTemplates {
+		info := ToolInfo{
+			Name:        tmpl.Name,
+			Description: tmpl.Description,
+			Source:      SourceCatalog,
+			Agents:      users[tmpl.Name],
+		}
+		if _, ok := r.manifest.Find(tmpl.Name); ok {
+			info.Installed = true
+		}
+		if r.fileExists(generator.ToolFile(tmpl.Name)) {
+			info.File = generator.ToolFile(tmpl.Name)
+		}
+		infos = append(infos, info)
+		seen[tmpl.Name] = true
+	}
+
+	// 项目自定义工具：清单之外的internal/tools源码和Agent引用的工具
+	custom := make(map[string]bool)
+	files, err := filepath.Glob(filepath.Join(r.root, "internal", "tools", "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if name := strings.TrimSuffix(filepath.Base(file), ".go"); !strings.HasSuffix(name, "_test") {
+			custom[name] = true
+		}
+	}
+	for _, tool := range r.manifest.Tools {
+		custom[tool.Name] = true
+	}
+	for name := range users {
+		custom[name] = true
+	}
+
+	var names []string
+	for name := range custom {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		info := ToolInfo{Name: name, Source: SourceCustom, Agents: users[name]}
+		if _, ok := r.manifest.Find(name); ok {
+			info.Installed = true
+		}
+		if r.fileExists(generator.ToolFile(name)) {
+			info.File = generator.ToolFile(name)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Install 按内置模板生成工具源码并记录到清单
+// 工具已安装或源码文件已存在时返回ErrToolExists，除非overwrite为true
+func (r *Registry) Install(name string, overwrite bool) (*InstalledTool, error) {
+	tmpl, ok := generator.FindToolTemplate(name)
+	if !ok {
+		names := make([]string, 0, len(generator.ToolTemplates))
+		for _, t := range generator.ToolTemplates {
+			names = append(names, t.Name)
+		}
+		return nil, fmt.Errorf("unknown tool %q, available tools: %s", name, strings.Join(names, ", "))
+	}
+
+	file := generator.ToolFile(name)
+	if !overwrite {
+		if _, installed := r.manifest.Find(name); installed || r.fileExists(file) {
+			return nil, fmt.Errorf("%w: %s (use --overwrite to replace %s)", ErrToolExists, name, file)
+		}
+	}
+
+	if _, err := generator.NewToolGenerator(r.root).Generate(tmpl); err != nil {
+		return nil, err
+	}
+
+	r.manifest.remove(name)
+	r.manifest.Tools = append(r.manifest.Tools, InstalledTool{
+		Name:        name,
+		Template:    tmpl.Name,
+		File:        file,
+		InstalledAt: time.Now().Format(time.RFC3339),
+	})
+	if err := r.manifest.Save(filepath.Join(r.root, ManifestFileName)); err != nil {
+		return nil, err
+	}
+
+	tool, _ := r.manifest.Find(name)
+	return tool, nil
+}
+
+// Remove 删除已安装工具的源码并更新清单
+// 仍有Agent或任务引用该工具时返回ErrToolInUse
+func (r *Registry) Remove(name string) error {
+	tool, ok := r.manifest.Find(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrToolNotInstalled, name)
+	}
+
+	if users := r.references(name); len(users) > 0 {
+		return fmt.Errorf("%w: %s is referenced in greensoulai.yaml by %s", ErrToolInUse, name, strings.Join(users, ", "))
+	}
+
+	path := filepath.Join(r.root, tool.File)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+
+	r.manifest.remove(name)
+	return r.manifest.Save(filepath.Join(r.root, ManifestFileName))
+}
+
+// toolUsers 返回每个工具被哪些Agent使用
+func (r *Registry) toolUsers() map[string][]string {
+	users := make(map[string][]string)
+	if r.config == nil {
+		return users
+	}
+	for _, a := range r.config.Agents {
+		for _, tool := range a.Tools {
+			users[tool] = append(users[tool], a.Name)
+		}
+	}
+	return users
+}
+
+// references 返回引用工具的Agent和任务
+func (r *Registry) references(name string) []string {
+	var refs []string
+	if r.config == nil {
+		return refs
+	}
+	for _, a := range r.config.Agents {
+		for _, tool := range a.Tools {
+			if tool == name {
+				refs = append(refs, "agent "+a.Name)
+			}
+		}
+	}
+	for _, t := range r.config.Tasks {
+		for _, tool := range t.Tools {
+			if tool == name {
+				refs = append(refs, "task "+t.Name)
+			}
+		}
+	}
+	return refs
+}
+
+func (r *Registry) fileExists(file string) bool {
+	_, err := os.Stat(filepath.Join(r.root, file))
+	return err == nil
+}
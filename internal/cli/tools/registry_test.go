@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/cli/config"
+)
+
+func newTestRegistry(t *testing.T, cfg *config.ProjectConfig) (*Registry, string) {
+	t.Helper()
+	root := t.TempDir()
+	registry, err := NewRegistry(root, cfg)
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+	return registry, root
+}
+
+func TestRegistryInstall(t *testing.T) {
+	registry, root := newTestRegistry(t, &config.ProjectConfig{})
+
+	tool, err := registry.Install("file_tool", false)
+	if err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	if tool.File != filepath.Join("internal", "tools", "file_tool.go") || tool.Template != "file_tool" {
+		t.Errorf("unexpected installed tool: %+v", tool)
+	}
+
+	// 生成的源码是合法的Go代码
+	if _, err := parser.ParseFile(token.NewFileSet(), filepath.Join(root, tool.File), nil, 0); err != nil {
+		t.Errorf("generated code does not parse: %v", err)
+	}
+
+	// 清单已保存
+	manifest, err := LoadManifest(filepath.Join(root, ManifestFileName))
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if _, ok := manifest.Find("file_tool"); !ok || len(manifest.Tools) != 1 {
+		t.Errorf("expected file_tool in manifest, got %+v", manifest.Tools)
+	}
+
+	// 重复安装需要--overwrite
+	if _, err := registry.Install("file_tool", false); !errors.Is(err, ErrToolExists) {
+		t.Errorf("expected ErrToolExists, got %v", err)
+	}
+	if _, err := registry.Install("file_tool", true); err != nil {
+		t.Errorf("overwrite failed: %v", err)
+	}
+	if len(registry.Manifest().Tools) != 1 {
+		t.Errorf("expected overwrite to keep one manifest entry, got %+v", registry.Manifest().Tools)
+	}
+
+	if _, err := registry.Install("teleport_tool", false); err == nil || !strings.Contains(err.Error(), "available tools") {
+		t.Errorf("expected unknown tool error, got %v", err)
+	}
+}
+
+func TestRegistryInstallExistingFile(t *testing.T) {
+	registry, root := newTestRegistry(t, &config.ProjectConfig{})
+	path := filepath.Join(root, "internal", "tools", "api_client_tool.go")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("package tools\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := registry.Install("api_client_tool", false); !errors.Is(err, ErrToolExists) {
+		t.Errorf("expected ErrToolExists for existing source file, got %v", err)
+	}
+}
+
+func TestRegistryList(t *testing.T) {
+	cfg := &config.ProjectConfig{
+		Agents: []config.AgentConfig{
+			{Name: "researcher", Tools: []string{"web_scraper_tool", "search_tool"}},
+			{Name: "writer", Tools: []string{"web_scraper_tool"}},
+		},
+	}
+	registry, root := newTestRegistry(t, cfg)
+	if _, err := registry.Install("web_scraper_tool", false); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "internal", "tools", "analysis_tool.go"), []byte("package tools\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := registry.List()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	byName := make(map[string]ToolInfo)
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+
+	scraper := byName["web_scraper_tool"]
+	if scraper.Source != SourceCatalog || !scraper.Installed || strings.Join(scraper.Agents, ",") != "researcher,writer" {
+		t.Errorf("unexpected web_scraper_tool: %+v", scraper)
+	}
+	if fileTool := byName["file_tool"]; fileTool.Installed || fileTool.Source != SourceCatalog {
+		t.Errorf("expected file_tool to be an uninstalled catalog tool: %+v", fileTool)
+	}
+	if analysis := byName["analysis_tool"]; analysis.Source != SourceCustom || analysis.File == "" {
+		t.Errorf("expected analysis_tool as custom tool with source file: %+v", analysis)
+	}
+	if search := byName["search_tool"]; search.Source != SourceCustom || search.File != "" || len(search.Agents) != 1 {
+		t.Errorf("expected search_tool as referenced custom tool without source: %+v", search)
+	}
+}
+
+func TestRegistryRemove(t *testing.T) {
+	cfg := &config.ProjectConfig{
+		Tasks: []config.TaskConfig{{Name: "fetch", Tools: []string{"api_client_tool"}}},
+	}
+	registry, root := newTestRegistry(t, cfg)
+	tool, err := registry.Install("api_client_tool", false)
+	if err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	// 仍被任务引用
+	if err := registry.Remove("api_client_tool"); !errors.Is(err, ErrToolInUse) || !strings.Contains(err.Error(), "task fetch") {
+		t.Errorf("expected ErrToolInUse, got %v", err)
+	}
+
+	cfg.Tasks = nil
+	if err := registry.Remove("api_client_tool"); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, tool.File)); !os.IsNotExist(err) {
+		t.Errorf("expected tool source to be deleted, got %v", err)
+	}
+	manifest, _ := LoadManifest(filepath.Join(root, ManifestFileName))
+	if len(manifest.Tools) != 0 {
+		t.Errorf("expected empty manifest, got %+v", manifest.Tools)
+	}
+
+	if err := registry.Remove("api_client_tool"); !errors.Is(err, ErrToolNotInstalled) {
+		t.Errorf("expected ErrToolNotInstalled, got %v", err)
+	}
+}
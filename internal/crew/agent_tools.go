@@ -0,0 +1,344 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const (
+	// DelegateWorkToolName 委托工作工具名称，对标Python版本的DelegateWorkTool
+	DelegateWorkToolName = "delegate_work"
+	// AskQuestionToolName 提问工具名称，对标Python版本的AskQuestionTool
+	AskQuestionToolName = "ask_question"
+
+	delegatedTaskExpectedOutput = "Your best answer to your coworker asking you this, accounting for the context shared."
+
+	// DefaultMaxDelegationDepth 默认的最大委托深度，同事之间的委托链超过该深度时拒绝继续委托
+	DefaultMaxDelegationDepth = 3
+)
+
+// DelegationRecord 记录一次委托
+type DelegationRecord struct {
+	Tool     string        `json:"tool"`
+	Coworker string        `json:"coworker"`
+	AgentID  string        `json:"agent_id"`
+	Request  string        `json:"request"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Chain    []string      `json:"chain"` // 委托链上的角色，从最初的委托者到本次的同事
+
+	// 同事执行委托任务的开销，由recordTaskUsage计入该同事的统计
+	LLMCalls         int     `json:"llm_calls"`
+	TokensUsed       int     `json:"tokens_used"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// AgentTools 为委托者（如管理器Agent）提供委托工具
+// 对标Python版本的AgentTools：delegate_work 将任务交给指定角色的同事执行，
+// ask_question 向指定角色的同事提问
+type AgentTools struct {
+	delegator   agent.Agent
+	agents      []agent.Agent
+	logger      logger.Logger
+	delegations []DelegationRecord
+	maxDepth    int
+	mu          sync.Mutex
+}
+
+// delegationChainKey 委托链在context中的键，同事执行委托任务时再次委托会延续该链
+type delegationChainKey struct{}
+
+// delegationCollectorKey 委托记录收集器在context中的键
+type delegationCollectorKey struct{}
+
+// delegationCollector 收集一次任务执行期间（包括嵌套委托）产生的全部委托记录
+type delegationCollector struct {
+	mu      sync.Mutex
+	records []DelegationRecord
+}
+
+// withDelegationCollector 返回带有新委托记录收集器的context
+func withDelegationCollector(ctx context.Context) (context.Context, *delegationCollector) {
+	collector := &delegationCollector{}
+	return context.WithValue(ctx, delegationCollectorKey{}, collector), collector
+}
+
+func (dc *delegationCollector) add(record DelegationRecord) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.records = append(dc.records, record)
+}
+
+// take 返回收集到的委托记录并清空
+func (dc *delegationCollector) take() []DelegationRecord {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	records := dc.records
+	dc.records = nil
+	return records
+}
+
+// delegationChainFromContext 返回context中的委托链
+func delegationChainFromContext(ctx context.Context) []string {
+	chain, _ := ctx.Value(delegationChainKey{}).([]string)
+	return chain
+}
+
+// NewAgentTools 创建委托工具集合
+func NewAgentTools(delegator agent.Agent, agents []agent.Agent, log logger.Logger) *AgentTools {
+	if log == nil {
+		log = logger.NewConsoleLogger()
+	}
+	return &AgentTools{
+		delegator: delegator,
+		agents:    agents,
+		logger:    log,
+		maxDepth:  DefaultMaxDelegationDepth,
+	}
+}
+
+// SetMaxDepth 设置最大委托深度，<=0时使用DefaultMaxDelegationDepth
+func (at *AgentTools) SetMaxDepth(depth int) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	if depth <= 0 {
+		depth = DefaultMaxDelegationDepth
+	}
+	at.maxDepth = depth
+}
+
+// SetAgents 更新可委托的同事列表
+func (at *AgentTools) SetAgents(agents []agent.Agent) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.agents = agents
+}
+
+// GetTools 返回委托工具
+func (at *AgentTools) GetTools() []agent.Tool {
+	return []agent.Tool{
+		at.newDelegationTool(
+			DelegateWorkToolName,
+			"Delegate a specific task to one of the following coworkers: %s. "+
+				"The input should include the task, all necessary context, and the exact role of the coworker.",
+			"task",
+			"The task to delegate",
+		),
+		at.newDelegationTool(
+			AskQuestionToolName,
+			"Ask a specific question to one of the following coworkers: %s. "+
+				"The input should include the question, all necessary context, and the exact role of the coworker.",
+			"question",
+			"The question to ask",
+		),
+	}
+}
+
+// TakeDelegations 返回自上次调用以来的委托记录并清空
+func (at *AgentTools) TakeDelegations() []DelegationRecord {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	records := at.delegations
+	at.delegations = nil
+	return records
+}
+
+// newDelegationTool 创建委托类工具
+func (at *AgentTools) newDelegationTool(name, descriptionFormat, requestArg, requestDesc string) agent.Tool {
+	description := fmt.Sprintf(descriptionFormat, strings.Join(at.coworkerRoles(), ", "))
+
+	tool := agent.NewBaseTool(name, description, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		request, _ := args[requestArg].(string)
+		coworker, _ := args["coworker"].(string)
+		taskContext, _ := args["context"].(string)
+		return at.delegate(ctx, name, request, taskContext, coworker)
+	})
+
+	tool.SetSchema(agent.ToolSchema{
+		Name:        name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				requestArg: map[string]interface{}{
+					"type":        "string",
+					"description": requestDesc,
+				},
+				"context": map[string]interface{}{
+					"type":        "string",
+					"description": "All the context necessary to complete the request",
+				},
+				"coworker": map[string]interface{}{
+					"type":        "string",
+					"description": "The role of the coworker",
+				},
+			},
+		},
+		Required: []string{requestArg, "coworker"},
+	})
+
+	return tool
+}
+
+// delegate 将请求交给指定角色的同事执行
+func (at *AgentTools) delegate(ctx context.Context, toolName, request, taskContext, coworkerRole string) (interface{}, error) {
+	if strings.TrimSpace(request) == "" {
+		return nil, fmt.Errorf("a non-empty task or question is required")
+	}
+
+	coworker, err := at.findCoworker(coworkerRole)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := at.extendChain(ctx, coworker)
+	if err != nil {
+		return nil, err
+	}
+
+	task := agent.NewBaseTask(request, delegatedTaskExpectedOutput)
+	if taskContext != "" {
+		task.SetContext(map[string]interface{}{"context": taskContext})
+	}
+
+	at.logger.Info("delegating work to coworker",
+		logger.Field{Key: "tool", Value: toolName},
+		logger.Field{Key: "coworker", Value: coworker.GetRole()},
+		logger.Field{Key: "chain", Value: strings.Join(chain, " -> ")},
+	)
+
+	start := time.Now()
+	output, execErr := coworker.Execute(context.WithValue(ctx, delegationChainKey{}, chain), task)
+	record := DelegationRecord{
+		Tool:     toolName,
+		Coworker: coworker.GetRole(),
+		AgentID:  coworker.GetID(),
+		Request:  request,
+		Success:  execErr == nil,
+		Duration: time.Since(start),
+		Chain:    chain,
+	}
+	if execErr != nil {
+		record.Error = execErr.Error()
+	} else if output != nil {
+		record.LLMCalls = output.LLMStats.Calls
+		record.TokensUsed = output.TokensUsed
+		record.PromptTokens = output.PromptTokens
+		record.CompletionTokens = output.CompletionTokens
+		record.Cost = output.Cost
+	}
+
+	// Crew执行任务时由收集器统一收集，嵌套委托的记录也归入最初的任务
+	if collector, ok := ctx.Value(delegationCollectorKey{}).(*delegationCollector); ok {
+		collector.add(record)
+	} else {
+		at.mu.Lock()
+		at.delegations = append(at.delegations, record)
+		at.mu.Unlock()
+	}
+
+	if execErr != nil {
+		return nil, fmt.Errorf("coworker %s failed: %w", coworker.GetRole(), execErr)
+	}
+	if output == nil {
+		return "", nil
+	}
+	return output.Raw, nil
+}
+
+// extendChain 返回加入该同事后的委托链，委托链超过最大深度或同事已在链上时拒绝委托
+func (at *AgentTools) extendChain(ctx context.Context, coworker agent.Agent) ([]string, error) {
+	chain := delegationChainFromContext(ctx)
+	if len(chain) == 0 && at.delegator != nil {
+		chain = []string{at.delegator.GetRole()}
+	}
+
+	at.mu.Lock()
+	maxDepth := at.maxDepth
+	at.mu.Unlock()
+
+	// 链上的第一个角色是最初的委托者，其余每个角色对应一层委托
+	if len(chain) > maxDepth {
+		return nil, fmt.Errorf("maximum delegation depth %d reached: %s. Complete the task yourself",
+			maxDepth, strings.Join(chain, " -> "))
+	}
+	for _, role := range chain {
+		if normalizeRole(role) == normalizeRole(coworker.GetRole()) {
+			return nil, fmt.Errorf("circular delegation is not allowed: %s -> %s",
+				strings.Join(chain, " -> "), coworker.GetRole())
+		}
+	}
+
+	return append(append([]string(nil), chain...), coworker.GetRole()), nil
+}
+
+// findCoworker 按角色查找同事，并阻止委托给委托者自身
+func (at *AgentTools) findCoworker(role string) (agent.Agent, error) {
+	normalized := normalizeRole(role)
+	if normalized == "" {
+		return nil, fmt.Errorf("coworker is required. Available coworkers: %s", strings.Join(at.coworkerRoles(), ", "))
+	}
+
+	if at.delegator != nil && normalizeRole(at.delegator.GetRole()) == normalized {
+		return nil, fmt.Errorf("circular delegation is not allowed: %s cannot delegate to itself. Available coworkers: %s",
+			at.delegator.GetRole(), strings.Join(at.coworkerRoles(), ", "))
+	}
+
+	at.mu.Lock()
+	agents := at.agents
+	at.mu.Unlock()
+
+	for _, a := range agents {
+		if a == nil || normalizeRole(a.GetRole()) != normalized {
+			continue
+		}
+		if at.delegator != nil && a.GetID() == at.delegator.GetID() {
+			return nil, fmt.Errorf("circular delegation is not allowed: %s cannot delegate to itself", a.GetRole())
+		}
+		return a, nil
+	}
+
+	return nil, fmt.Errorf("coworker '%s' not found. Available coworkers: %s", role, strings.Join(at.coworkerRoles(), ", "))
+}
+
+// coworkerRoles 返回可委托同事的角色列表（不含委托者自身）
+func (at *AgentTools) coworkerRoles() []string {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	roles := make([]string, 0, len(at.agents))
+	for _, a := range at.agents {
+		if a == nil {
+			continue
+		}
+		if at.delegator != nil && a.GetID() == at.delegator.GetID() {
+			continue
+		}
+		roles = append(roles, a.GetRole())
+	}
+	return roles
+}
+
+// normalizeRole 规范化角色名称用于匹配
+func normalizeRole(role string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Trim(role, "\"'")))
+}
+
+// isDelegationTool 判断是否为委托工具
+func isDelegationTool(tool agent.Tool) bool {
+	if tool == nil {
+		return false
+	}
+	name := tool.GetName()
+	return name == DelegateWorkToolName || name == AskQuestionToolName
+}
@@ -0,0 +1,311 @@
+package crew
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func findTool(tools []agent.Tool, name string) agent.Tool {
+	for _, tool := range tools {
+		if tool.GetName() == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+func TestAgentToolsDelegateWork(t *testing.T) {
+	manager := &MockAgent{id: "manager", role: "Project Manager"}
+	developer := &MockAgent{id: "dev", role: "Developer"}
+	tester := &MockAgent{id: "qa", role: "Tester"}
+
+	agentTools := NewAgentTools(manager, []agent.Agent{manager, developer, tester}, logger.NewTestLogger())
+	tools := agentTools.GetTools()
+
+	delegateTool := findTool(tools, DelegateWorkToolName)
+	if delegateTool == nil {
+		t.Fatal("expected delegate_work tool")
+	}
+	if findTool(tools, AskQuestionToolName) == nil {
+		t.Fatal("expected ask_question tool")
+	}
+
+	if !strings.Contains(delegateTool.GetDescription(), "Developer, Tester") {
+		t.Errorf("tool description should list coworkers, got %q", delegateTool.GetDescription())
+	}
+	if strings.Contains(delegateTool.GetDescription(), "Project Manager") {
+		t.Error("tool description should not list the delegator itself")
+	}
+
+	result, err := delegateTool.Execute(context.Background(), map[string]interface{}{
+		"task":     "Implement login",
+		"context":  "Use OAuth",
+		"coworker": " developer ",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "Mock agent output for: Implement login" {
+		t.Errorf("unexpected delegation result: %v", result)
+	}
+
+	records := agentTools.TakeDelegations()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 delegation record, got %d", len(records))
+	}
+	if records[0].Coworker != "Developer" || !records[0].Success {
+		t.Errorf("unexpected delegation record: %+v", records[0])
+	}
+	if len(agentTools.TakeDelegations()) != 0 {
+		t.Error("TakeDelegations should clear records")
+	}
+}
+
+func TestAgentToolsDelegationErrors(t *testing.T) {
+	manager := &MockAgent{id: "manager", role: "Project Manager"}
+	developer := &MockAgent{id: "dev", role: "Developer"}
+
+	agentTools := NewAgentTools(manager, []agent.Agent{developer}, logger.NewTestLogger())
+	askTool := findTool(agentTools.GetTools(), AskQuestionToolName)
+
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		errContains string
+	}{
+		{
+			name:        "unknown coworker",
+			args:        map[string]interface{}{"question": "Status?", "coworker": "Designer"},
+			errContains: "coworker 'Designer' not found. Available coworkers: Developer",
+		},
+		{
+			name:        "circular delegation",
+			args:        map[string]interface{}{"question": "Status?", "coworker": "Project Manager"},
+			errContains: "circular delegation is not allowed",
+		},
+		{
+			name:        "missing coworker",
+			args:        map[string]interface{}{"question": "Status?"},
+			errContains: "coworker is required",
+		},
+		{
+			name:        "missing question",
+			args:        map[string]interface{}{"coworker": "Developer"},
+			errContains: "non-empty task or question",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := askTool.Execute(context.Background(), tt.args)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+			}
+		})
+	}
+
+	if len(agentTools.TakeDelegations()) != 0 {
+		t.Error("failed lookups should not be recorded as delegations")
+	}
+}
+
+func TestHierarchicalProcessDelegatesToWorkers(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.Process = ProcessHierarchical
+	config.ManagerLLM = NewMockLLM(
+		`{"tool_name": "delegate_work", "arguments": {"task": "Implement feature", "context": "user auth", "coworker": "Developer"}}`,
+		"The developer implemented the feature.",
+	)
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	developer := &MockAgent{id: "worker1", role: "Developer", goal: "Write code", backstory: "Experienced developer"}
+	tester := &MockAgent{id: "worker2", role: "Tester", goal: "Test code", backstory: "QA expert"}
+	crew.AddAgent(developer)
+	crew.AddAgent(tester)
+	crew.AddTask(&MockTask{id: "t1", description: "Implement feature", expectedOutput: "Working code"})
+
+	result, err := crew.Kickoff(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	output := result.TasksOutput[0]
+	if output.Raw != "The developer implemented the feature." {
+		t.Errorf("expected manager's synthesized answer, got %q", output.Raw)
+	}
+	if len(output.ToolsUsed) != 1 || output.ToolsUsed[0] != DelegateWorkToolName {
+		t.Errorf("expected delegate_work to be used, got %v", output.ToolsUsed)
+	}
+
+	workers, ok := output.Metadata["delegated_to"].([]string)
+	if !ok || len(workers) != 1 || workers[0] != "Developer" {
+		t.Errorf("expected delegated_to [Developer], got %v", output.Metadata["delegated_to"])
+	}
+
+	// 再次执行不应重复添加委托工具
+	if _, err := crew.Kickoff(context.Background(), map[string]interface{}{}); err != nil {
+		t.Fatalf("second kickoff failed: %v", err)
+	}
+	if tools := crew.managerAgent.GetTools(); len(tools) != 2 {
+		t.Errorf("expected manager to keep exactly 2 delegation tools, got %d", len(tools))
+	}
+}
+
+func TestHierarchicalProcessCountsDelegatedUsage(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.Process = ProcessHierarchical
+	config.ManagerLLM = NewMockLLM(
+		`{"tool_name": "delegate_work", "arguments": {"task": "Implement feature", "coworker": "Developer"}}`,
+		"The developer implemented the feature.",
+	)
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	developer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Developer",
+		Goal:      "Write code",
+		Backstory: "Experienced developer",
+		LLM:       NewMockLLM("implemented"),
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(developer)
+	crew.AddTask(&MockTask{id: "t1", description: "Implement feature", expectedOutput: "Working code"})
+
+	result, err := crew.Kickoff(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	metrics := crew.GetUsageMetrics()
+	dev := metrics.AgentUsage["Developer"]
+	if dev.DelegatedTasks != 1 || dev.TotalTokens != 16 || dev.TotalCost != 0.001 {
+		t.Errorf("expected delegated work to be attributed to the developer, got %+v", dev)
+	}
+
+	var total int
+	for _, usage := range metrics.AgentUsage {
+		total += usage.TotalTokens
+	}
+	if metrics.TotalTokens != total {
+		t.Errorf("expected total tokens %d to equal the per-agent sum %d", metrics.TotalTokens, total)
+	}
+	if metrics.TotalTokens != result.TasksOutput[0].TokensUsed+dev.TotalTokens {
+		t.Errorf("expected delegated tokens to be included in total, got %d", metrics.TotalTokens)
+	}
+}
+
+func TestAgentToolsMaxDelegationDepth(t *testing.T) {
+	writer := &MockAgent{id: "writer", role: "Writer"}
+	researcher := &MockAgent{id: "researcher", role: "Researcher"}
+	editor := &MockAgent{id: "editor", role: "Editor"}
+
+	agentTools := NewAgentTools(researcher, []agent.Agent{writer, researcher, editor}, logger.NewTestLogger())
+	agentTools.SetMaxDepth(1)
+	askTool := findTool(agentTools.GetTools(), AskQuestionToolName)
+
+	// Writer已委托给Researcher，Researcher再委托会超过最大深度
+	ctx := context.WithValue(context.Background(), delegationChainKey{}, []string{"Writer", "Researcher"})
+	_, err := askTool.Execute(ctx, map[string]interface{}{"question": "Check facts", "coworker": "Editor"})
+	if err == nil || !strings.Contains(err.Error(), "maximum delegation depth 1 reached: Writer -> Researcher") {
+		t.Errorf("expected max depth error, got %v", err)
+	}
+
+	// 委托回链上已有的角色会形成循环
+	agentTools.SetMaxDepth(3)
+	_, err = askTool.Execute(ctx, map[string]interface{}{"question": "Check facts", "coworker": "Writer"})
+	if err == nil || !strings.Contains(err.Error(), "circular delegation is not allowed: Writer -> Researcher -> Writer") {
+		t.Errorf("expected circular delegation error, got %v", err)
+	}
+
+	if _, err := askTool.Execute(ctx, map[string]interface{}{"question": "Check facts", "coworker": "Editor"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := agentTools.TakeDelegations()
+	if len(records) != 1 || strings.Join(records[0].Chain, " -> ") != "Writer -> Researcher -> Editor" {
+		t.Errorf("expected delegation chain to be recorded, got %+v", records)
+	}
+}
+
+func TestSequentialCrewCoworkerDelegation(t *testing.T) {
+	logger := logger.NewTestLogger()
+	crew := NewBaseCrew(DefaultCrewConfig(), events.NewEventBus(logger), logger)
+
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write articles",
+		Backstory: "Tech writer",
+		LLM: NewMockLLM(
+			`{"tool_name": "ask_question", "arguments": {"question": "What is new in Go 1.21?", "context": "Article about Go", "coworker": "Researcher"}}`,
+			"Article based on the research.",
+		),
+		AllowDelegation: true,
+		Logger:          logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	researcherLLM := NewMockLLM("Go 1.21 adds min and max builtins.")
+	researcher, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Researcher",
+		Goal:      "Find facts",
+		Backstory: "Careful analyst",
+		LLM:       researcherLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create researcher: %v", err)
+	}
+
+	crew.AddAgent(writer)
+	crew.AddAgent(researcher)
+	crew.AddTask(agent.NewTaskWithOptions("Write an article about Go 1.21", "Article", agent.WithAssignedAgent(writer)))
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	// Writer的工具调用实际执行了Researcher
+	if len(researcherLLM.Prompts()) != 1 || !strings.Contains(researcherLLM.Prompts()[0], "What is new in Go 1.21?") {
+		t.Fatalf("expected the question to reach the researcher, got %v", researcherLLM.Prompts())
+	}
+	if researcher.GetExecutionStats().TotalExecutions != 1 {
+		t.Errorf("expected researcher to execute once, got %d", researcher.GetExecutionStats().TotalExecutions)
+	}
+
+	output := result.TasksOutput[0]
+	if output.Raw != "Article based on the research." {
+		t.Errorf("expected writer's final answer, got %q", output.Raw)
+	}
+	records, ok := output.Metadata["delegations"].([]DelegationRecord)
+	if !ok || len(records) != 1 {
+		t.Fatalf("expected 1 delegation record, got %v", output.Metadata["delegations"])
+	}
+	if strings.Join(records[0].Chain, " -> ") != "Writer -> Researcher" || !records[0].Success {
+		t.Errorf("unexpected delegation record: %+v", records[0])
+	}
+
+	// 同事工具只在执行期间存在，未开启委托的Agent不会获得工具
+	if len(writer.GetTools()) != 0 {
+		t.Errorf("expected coworker tools to be removed after the run, got %d tools", len(writer.GetTools()))
+	}
+	if len(researcher.GetTools()) != 0 {
+		t.Errorf("expected researcher without delegation to have no tools, got %d", len(researcher.GetTools()))
+	}
+}
@@ -0,0 +1,272 @@
+package crew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// AssignmentReasonMetadataKey 分配理由记录在TaskOutput.Metadata中的键
+const AssignmentReasonMetadataKey = "assignment_reason"
+
+// AssignmentStrategy 为没有指定Agent的任务选择执行者
+// 层级模式下任务总由管理者执行；任务通过SetAssignedAgent或SetAgentRole指定了Agent时也不经过分配策略
+type AssignmentStrategy interface {
+	Name() string
+	Assign(ctx context.Context, req AssignmentRequest) (*AssignmentDecision, error)
+}
+
+// AssignmentRequest 分配策略的输入
+type AssignmentRequest struct {
+	Task      agent.Task
+	TaskIndex int
+	Agents    []agent.Agent
+	Load      map[string]int // 本次Kickoff中每个Agent（按ID）已分配的任务数
+	DryRun    bool           // Validate演练时为true，策略不应调用LLM
+}
+
+// AssignmentDecision 分配结果，Reason记录到TaskOutput.Metadata["assignment_reason"]
+type AssignmentDecision struct {
+	Agent  agent.Agent
+	Reason string
+}
+
+// RoundRobinAssignment 按任务顺序轮流分配Agent，是默认的分配策略
+type RoundRobinAssignment struct{}
+
+// Name 返回策略名称
+func (RoundRobinAssignment) Name() string { return "round_robin" }
+
+// Assign 把第i个任务分配给第i % len(agents)个Agent
+func (RoundRobinAssignment) Assign(ctx context.Context, req AssignmentRequest) (*AssignmentDecision, error) {
+	if len(req.Agents) == 0 {
+		return nil, fmt.Errorf("no agents available")
+	}
+	index := req.TaskIndex % len(req.Agents)
+	return &AssignmentDecision{
+		Agent:  req.Agents[index],
+		Reason: fmt.Sprintf("round robin: task %d assigned to agent %d of %d", req.TaskIndex+1, index+1, len(req.Agents)),
+	}, nil
+}
+
+// ExplicitAssignment 要求每个任务都指定了Agent或Agent角色，没有指定时返回错误而不是猜测
+type ExplicitAssignment struct{}
+
+// Name 返回策略名称
+func (ExplicitAssignment) Name() string { return "explicit" }
+
+// Assign 只有没有指定Agent的任务才会到达这里，因此总是返回错误
+func (ExplicitAssignment) Assign(ctx context.Context, req AssignmentRequest) (*AssignmentDecision, error) {
+	name := req.Task.GetName()
+	if name == "" {
+		name = req.Task.GetID()
+	}
+	return nil, fmt.Errorf("task %s has no assigned agent or agent role, explicit assignment requires one", name)
+}
+
+// BestMatchAssignment 按任务与Agent角色、目标、背景的匹配程度选择Agent
+// 默认按关键词重合计分（角色中的词权重更高）；设置LLM后用一次分类调用打分，调用失败时退回关键词计分。
+// 同分时选择本次Kickoff中已分配任务最少的Agent，仍相同时选择靠前的Agent
+type BestMatchAssignment struct {
+	LLM llm.LLM // 可选，用于分类的低成本模型
+}
+
+// NewBestMatchAssignment 创建BestMatch分配策略，model为nil时只使用关键词计分
+func NewBestMatchAssignment(model llm.LLM) *BestMatchAssignment {
+	return &BestMatchAssignment{LLM: model}
+}
+
+// Name 返回策略名称
+func (s *BestMatchAssignment) Name() string { return "best_match" }
+
+// Assign 给每个Agent打分并选出得分最高的Agent
+func (s *BestMatchAssignment) Assign(ctx context.Context, req AssignmentRequest) (*AssignmentDecision, error) {
+	if len(req.Agents) == 0 {
+		return nil, fmt.Errorf("no agents available")
+	}
+
+	method := "keyword match"
+	var scores []float64
+	if s.LLM != nil && !req.DryRun {
+		var err error
+		if scores, err = s.classify(ctx, req); err != nil {
+			method = fmt.Sprintf("keyword match (LLM classification failed: %v)", err)
+			scores = nil
+		} else {
+			method = "LLM classification"
+		}
+	}
+	if scores == nil {
+		scores = keywordScores(req.Task, req.Agents)
+	}
+
+	best := pickBestMatch(scores, req.Agents, req.Load)
+	selected := req.Agents[best]
+
+	reason := fmt.Sprintf("best match by %s: %s scored %s", method, selected.GetRole(), formatScore(scores[best]))
+	tied := 0
+	for i, score := range scores {
+		if i != best && score == scores[best] {
+			tied++
+		}
+	}
+	if tied > 0 {
+		reason += fmt.Sprintf(", tied with %d other agent(s), chosen for the fewest assigned tasks (%d)", tied, req.Load[selected.GetID()])
+	}
+	return &AssignmentDecision{Agent: selected, Reason: reason}, nil
+}
+
+// pickBestMatch 返回得分最高的Agent下标，同分时取已分配任务最少的，再相同时取靠前的
+func pickBestMatch(scores []float64, agents []agent.Agent, load map[string]int) int {
+	best := 0
+	for i := 1; i < len(agents); i++ {
+		switch {
+		case scores[i] > scores[best]:
+			best = i
+		case scores[i] == scores[best] && load[agents[i].GetID()] < load[agents[best].GetID()]:
+			best = i
+		}
+	}
+	return best
+}
+
+// classify 让LLM给每个Agent打0-10分，返回与Agent顺序一致的分数
+func (s *BestMatchAssignment) classify(ctx context.Context, req AssignmentRequest) ([]float64, error) {
+	var b strings.Builder
+	b.WriteString("Score how well each agent fits the task below from 0 (unsuitable) to 10 (ideal).\n\n")
+	fmt.Fprintf(&b, "Task: %s\n", req.Task.GetDescription())
+	if expected := req.Task.GetExpectedOutput(); expected != "" {
+		fmt.Fprintf(&b, "Expected output: %s\n", expected)
+	}
+	b.WriteString("\nAgents:\n")
+	for i, member := range req.Agents {
+		fmt.Fprintf(&b, "%d. %s: %s\n", i+1, member.GetRole(), member.GetGoal())
+	}
+	b.WriteString("\nRespond with only a JSON object: {\"scores\": [{\"agent\": <number>, \"score\": <0-10>}]}")
+
+	response, err := s.LLM.Call(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: "You match tasks to the team members best suited to do them."},
+		{Role: llm.RoleUser, Content: b.String()},
+	}, &llm.CallOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Scores []struct {
+			Agent int     `json:"agent"`
+			Score float64 `json:"score"`
+		} `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(extractJSONObject(response.Content)), &result); err != nil {
+		return nil, fmt.Errorf("invalid classification response: %w", err)
+	}
+	if len(result.Scores) == 0 {
+		return nil, fmt.Errorf("classification response has no scores")
+	}
+
+	scores := make([]float64, len(req.Agents))
+	for _, score := range result.Scores {
+		if score.Agent >= 1 && score.Agent <= len(scores) {
+			scores[score.Agent-1] = score.Score
+		}
+	}
+	return scores, nil
+}
+
+// keywordScores 按任务关键词与Agent描述的重合计分，角色中的词计2分，目标和背景中的词计1分
+func keywordScores(task agent.Task, agents []agent.Agent) []float64 {
+	taskWords := keywords(task.GetDescription() + " " + task.GetExpectedOutput())
+	scores := make([]float64, len(agents))
+	for i, member := range agents {
+		roleWords := keywords(member.GetRole())
+		profileWords := keywords(member.GetGoal() + " " + member.GetBackstory())
+		for _, word := range taskWords {
+			switch {
+			case matchesKeyword(word, roleWords):
+				scores[i] += 2
+			case matchesKeyword(word, profileWords):
+				scores[i]++
+			}
+		}
+	}
+	return scores
+}
+
+// assignmentStopWords 不参与匹配的常见词
+var assignmentStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "into": true, "that": true,
+	"this": true, "your": true, "you": true, "are": true, "all": true, "about": true, "based": true,
+	"each": true, "its": true, "their": true, "them": true, "will": true, "should": true, "must": true,
+	"make": true, "sure": true, "using": true, "use": true, "give": true, "provide": true, "output": true,
+}
+
+// keywords 提取小写的关键词，去掉停用词和少于3个字符的词，按出现顺序去重
+func keywords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(fields))
+	words := make([]string, 0, len(fields))
+	for _, word := range fields {
+		if len([]rune(word)) < 3 || assignmentStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// matchesKeyword 检查word是否出现在candidates中；长度至少4的词按词干前缀匹配（如test和tester、document和documentation）
+func matchesKeyword(word string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if word == candidate {
+			return true
+		}
+		shorter, longer := word, candidate
+		if len(shorter) > len(longer) {
+			shorter, longer = longer, shorter
+		}
+		if len(shorter) >= 4 && strings.HasPrefix(longer, shorter) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatScore 整数分数不带小数
+func formatScore(score float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", score), "0"), ".")
+}
+
+// findAgentByRole 在成员中按ID或角色（不区分大小写）查找Agent
+func findAgentByRole(agents []agent.Agent, role string) agent.Agent {
+	for _, member := range agents {
+		if member.GetID() == role {
+			return member
+		}
+	}
+	for _, member := range agents {
+		if strings.EqualFold(strings.TrimSpace(member.GetRole()), strings.TrimSpace(role)) {
+			return member
+		}
+	}
+	return nil
+}
+
+// agentRoles 返回成员的角色列表，用于错误信息
+func agentRoles(agents []agent.Agent) []string {
+	roles := make([]string, 0, len(agents))
+	for _, member := range agents {
+		roles = append(roles, member.GetRole())
+	}
+	sort.Strings(roles)
+	return roles
+}
@@ -0,0 +1,217 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newAssignmentTestAgent(t *testing.T, role, goal string, model llm.LLM, eventBus events.EventBus, log logger.Logger) agent.Agent {
+	t.Helper()
+	a, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: role, Goal: goal, Backstory: "Experienced",
+		LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent %s: %v", role, err)
+	}
+	return a
+}
+
+func TestBestMatchAssignmentPicksMatchingAgent(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	tester := llmtest.NewScriptedLLM(llmtest.Replies("All tests pass.")...)
+	writer := llmtest.NewScriptedLLM(llmtest.Replies("API documentation.")...)
+
+	c := NewBaseCrew(&CrewConfig{Name: "match-crew", AssignmentStrategy: NewBestMatchAssignment(nil)}, eventBus, log)
+	c.AddAgent(newAssignmentTestAgent(t, "Tester", "Test the code and report bugs", tester, eventBus, log))
+	c.AddAgent(newAssignmentTestAgent(t, "Technical Writer", "Write clear documentation", writer, eventBus, log))
+	c.AddTask(agent.NewTaskWithOptions("Write documentation for the REST API", "A markdown document"))
+	c.AddTask(agent.NewTaskWithOptions("Run the tests and report failing ones", "A test report"))
+
+	result, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+
+	if result.TasksOutput[0].Agent != "Technical Writer" || result.TasksOutput[1].Agent != "Tester" {
+		t.Errorf("expected the writer to document and the tester to test, got %s and %s",
+			result.TasksOutput[0].Agent, result.TasksOutput[1].Agent)
+	}
+	reason, _ := result.TasksOutput[0].Metadata[AssignmentReasonMetadataKey].(string)
+	if !strings.HasPrefix(reason, "best match by keyword match: Technical Writer scored") {
+		t.Errorf("unexpected assignment reason: %q", reason)
+	}
+}
+
+func TestBestMatchAssignmentBreaksTiesByLoad(t *testing.T) {
+	strategy := NewBestMatchAssignment(nil)
+	agents := []agent.Agent{
+		&MockAgent{id: "a", role: "Alpha", goal: "Help", backstory: "Generalist"},
+		&MockAgent{id: "b", role: "Beta", goal: "Help", backstory: "Generalist"},
+		&MockAgent{id: "c", role: "Gamma", goal: "Help", backstory: "Generalist"},
+	}
+	task := &MockTask{id: "t", description: "Summarize quarterly revenue", expectedOutput: "Summary"}
+
+	for _, tc := range []struct {
+		load     map[string]int
+		expected string
+	}{
+		{map[string]int{}, "Alpha"},                      // 全部同分且没有负载时选择靠前的
+		{map[string]int{"a": 1}, "Beta"},                 // 选择任务最少的
+		{map[string]int{"a": 2, "b": 1, "c": 1}, "Beta"}, // 负载也相同时选择靠前的
+		{map[string]int{"a": 1, "b": 1}, "Gamma"},
+	} {
+		decision, err := strategy.Assign(context.Background(), AssignmentRequest{Task: task, Agents: agents, Load: tc.load})
+		if err != nil {
+			t.Fatalf("assign failed: %v", err)
+		}
+		if decision.Agent.GetRole() != tc.expected {
+			t.Errorf("load %v: expected %s, got %s", tc.load, tc.expected, decision.Agent.GetRole())
+		}
+		if !strings.Contains(decision.Reason, "tied with 2 other agent(s)") {
+			t.Errorf("expected the tie in the reason, got %q", decision.Reason)
+		}
+	}
+
+	// 分数高的Agent即使负载更高也优先
+	agents[2] = &MockAgent{id: "c", role: "Revenue Analyst", goal: "Summarize revenue", backstory: "Finance"}
+	decision, err := strategy.Assign(context.Background(), AssignmentRequest{Task: task, Agents: agents, Load: map[string]int{"c": 5}})
+	if err != nil || decision.Agent.GetRole() != "Revenue Analyst" {
+		t.Errorf("expected the best scored agent regardless of load, got %v, %v", decision, err)
+	}
+}
+
+func TestBestMatchAssignmentSpreadsTiedTasksDuringKickoff(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	c := NewBaseCrew(&CrewConfig{Name: "spread-crew", AssignmentStrategy: NewBestMatchAssignment(nil)}, eventBus, log)
+	c.AddAgent(newAssignmentTestAgent(t, "Alpha", "Help", llmtest.NewScriptedLLM(llmtest.Replies("a1", "a2")...), eventBus, log))
+	c.AddAgent(newAssignmentTestAgent(t, "Beta", "Help", llmtest.NewScriptedLLM(llmtest.Replies("b1")...), eventBus, log))
+	for _, description := range []string{"Count apples", "Count pears", "Count plums"} {
+		c.AddTask(agent.NewTaskWithOptions(description, "A number"))
+	}
+
+	result, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	var got []string
+	for _, output := range result.TasksOutput {
+		got = append(got, output.Agent)
+	}
+	if strings.Join(got, ",") != "Alpha,Beta,Alpha" {
+		t.Errorf("expected tied tasks to alternate by load, got %v", got)
+	}
+}
+
+func TestBestMatchAssignmentUsesClassifier(t *testing.T) {
+	agents := []agent.Agent{
+		&MockAgent{id: "a", role: "Alpha", goal: "Help"},
+		&MockAgent{id: "b", role: "Beta", goal: "Help"},
+	}
+	task := &MockTask{id: "t", description: "Plan the launch", expectedOutput: "A plan"}
+
+	classifier := llmtest.NewScriptedLLM(llmtest.Replies(`{"scores": [{"agent": 1, "score": 3}, {"agent": 2, "score": 8}]}`)...)
+	decision, err := NewBestMatchAssignment(classifier).Assign(context.Background(), AssignmentRequest{Task: task, Agents: agents})
+	if err != nil {
+		t.Fatalf("assign failed: %v", err)
+	}
+	if decision.Agent.GetRole() != "Beta" || decision.Reason != "best match by LLM classification: Beta scored 8" {
+		t.Errorf("unexpected decision: %s (%q)", decision.Agent.GetRole(), decision.Reason)
+	}
+	prompt := classifier.Calls()[0].UserPrompt()
+	if !strings.Contains(prompt, "Plan the launch") || !strings.Contains(prompt, "2. Beta: Help") {
+		t.Errorf("unexpected classification prompt:\n%s", prompt)
+	}
+
+	// 分类失败时退回关键词计分
+	failing := llmtest.NewScriptedLLM(llmtest.Reply{Err: errors.New("rate limited")})
+	decision, err = NewBestMatchAssignment(failing).Assign(context.Background(), AssignmentRequest{Task: task, Agents: agents})
+	if err != nil {
+		t.Fatalf("expected the keyword fallback, got %v", err)
+	}
+	if decision.Agent.GetRole() != "Alpha" || !strings.Contains(decision.Reason, "LLM classification failed") {
+		t.Errorf("unexpected fallback decision: %s (%q)", decision.Agent.GetRole(), decision.Reason)
+	}
+
+	// 演练时不调用分类模型
+	unused := llmtest.NewScriptedLLM()
+	if _, err := NewBestMatchAssignment(unused).Assign(context.Background(), AssignmentRequest{Task: task, Agents: agents, DryRun: true}); err != nil || unused.CallCount() != 0 {
+		t.Errorf("dry run must not call the classifier (%d calls, %v)", unused.CallCount(), err)
+	}
+}
+
+func TestExplicitAssignment(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	c := NewBaseCrew(&CrewConfig{Name: "explicit-crew", AssignmentStrategy: ExplicitAssignment{}}, eventBus, log)
+	researcher := newAssignmentTestAgent(t, "Researcher", "Research", llmtest.NewScriptedLLM(), eventBus, log)
+	writer := newAssignmentTestAgent(t, "Writer", "Write", llmtest.NewScriptedLLM(llmtest.Replies("draft", "final", "draft", "final")...), eventBus, log)
+	c.AddAgent(researcher)
+	c.AddAgent(writer)
+	c.AddTask(agent.NewTaskWithOptions("Draft the post", "A draft", agent.WithAgentRole("writer")))
+	c.AddTask(agent.NewTaskWithOptions("Polish the post", "A post", agent.WithAgentRole(writer.GetID())))
+
+	result, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	for _, output := range result.TasksOutput {
+		if output.Agent != "Writer" {
+			t.Errorf("expected the named writer, got %s", output.Agent)
+		}
+	}
+	if reason := result.TasksOutput[0].Metadata[AssignmentReasonMetadataKey]; reason != `agent role "writer" named by the task` {
+		t.Errorf("unexpected assignment reason: %v", reason)
+	}
+
+	c.AddTask(agent.NewTaskWithOptions("Publish the post", "A link", agent.WithName("publish")))
+	c.AddTask(agent.NewTaskWithOptions("Review the post", "Comments", agent.WithName("review"), agent.WithAgentRole("Editor")))
+	if _, err := c.Kickoff(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "publish has no assigned agent") {
+		t.Errorf("expected a missing assignment error, got %v", err)
+	}
+
+	report, err := c.Validate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	var failed []string
+	for _, issue := range report.Issues {
+		if issue.Code == IssueAssignmentFailed {
+			failed = append(failed, issue.Task)
+		}
+	}
+	if strings.Join(failed, ",") != "publish,review" {
+		t.Errorf("expected assignment issues for publish and review, got %+v", report.Issues)
+	}
+}
+
+func TestRoundRobinAssignmentIsDefault(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	c := NewBaseCrew(&CrewConfig{Name: "default-crew"}, eventBus, log)
+	c.AddAgent(newAssignmentTestAgent(t, "Tester", "Test", llmtest.NewScriptedLLM(llmtest.Replies("tested")...), eventBus, log))
+	c.AddAgent(newAssignmentTestAgent(t, "Writer", "Write", llmtest.NewScriptedLLM(llmtest.Replies("written")...), eventBus, log))
+	c.AddTask(agent.NewTaskWithOptions("Write documentation", "Docs"))
+	c.AddTask(agent.NewTaskWithOptions("Run the tests", "Report"))
+
+	result, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	if result.TasksOutput[0].Agent != "Tester" || result.TasksOutput[1].Agent != "Writer" {
+		t.Errorf("expected round robin order, got %s and %s", result.TasksOutput[0].Agent, result.TasksOutput[1].Agent)
+	}
+	if reason := result.TasksOutput[1].Metadata[AssignmentReasonMetadataKey]; reason != "round robin: task 2 assigned to agent 2 of 2" {
+		t.Errorf("unexpected assignment reason: %v", reason)
+	}
+}
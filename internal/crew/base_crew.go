@@ -2,12 +2,17 @@ package crew
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/internal/training"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 	"github.com/ynl/greensoulai/pkg/security"
@@ -15,38 +20,81 @@ import (
 
 // BaseCrew 实现Crew接口的基础结构
 type BaseCrew struct {
-	id               string
-	name             string
-	agents           []agent.Agent
-	tasks            []agent.Task
-	process          Process
-	verbose          bool
-	memoryEnabled    bool
-	cacheEnabled     bool
-	maxRPM           int
-	shareCrewEnabled bool
-	planningEnabled  bool
-	maxExecutionTime time.Duration
-	fullOutput       bool
+	id                 string
+	name               string
+	agents             []agent.Agent
+	tasks              []agent.Task
+	process            Process
+	verbose            bool
+	memoryEnabled      bool
+	cacheEnabled       bool
+	maxRPM             int
+	maxConcurrency     int
+	rpmController      *agent.RPMController // 所有Agent共享的速率控制器，maxRPM<=0时为nil
+	budget             agent.BudgetLimits   // 每次Kickoff的token和成本预算
+	memorySettings     crewMemorySettings   // 开启记忆时Kickoff之间的记忆写回和检索
+	shareCrewEnabled   bool
+	planningEnabled    bool
+	planningLLM        llm.LLM
+	planningStrict     bool
+	maxDelegationDepth int // 同事之间委托链的最大深度
+	maxExecutionTime   time.Duration
+	fullOutput         bool
+	outputDir          string // 任务输出文件相对路径的基础目录
+	replayEnabled      bool   // 每个任务完成后写入执行快照
+	replayDir          string // 执行快照的存储目录，为空时使用DefaultReplayDir
+	finalOutputSchema  *agent.OutputSchema
+	synthesisAgent     agent.Agent
+
+	// Consensus模式
+	consensusAgentsPerTask int     // 每个任务的候选Agent数，<=0表示所有Agent
+	judgeLLM               llm.LLM // 评审候选输出的LLM，为nil时由管理器Agent评审
+	consensusRubric        string  // 评审提示模板
+
+	// 任务分配
+	assignmentStrategy AssignmentStrategy // 为没有指定Agent的任务选择执行者，为nil时轮流分配
+	assignmentLoad     map[string]int     // 本次Kickoff中每个Agent（按ID）已分配的任务数
+	assignmentMu       sync.Mutex
+
+	streamOutput bool // KickoffWithProgress时流式执行任务
+
+	contextInjection agent.ContextInjection       // 注入到所有Agent的日期和语言环境提示
+	moderation       *agent.ModerationConfig      // 没有设置审核的Agent使用的内容审核
+	promptInjection  *agent.PromptInjectionConfig // 没有设置注入防护的Agent使用的提示注入防护
+
+	randomSeed            *int // 派生每个任务种子的基础种子
+	strictReproducibility bool
+
+	// originalDescriptions 规划前的任务描述，按任务ID索引，重复规划时不会叠加旧计划
+	originalDescriptions map[string]string
 
 	// 回调函数
 	beforeKickoffCallbacks []KickoffCallback
 	afterKickoffCallbacks  []KickoffCallback
 	taskCallback           TaskCallback
 	stepCallback           StepCallback
+	beforeTaskHooks        []BeforeTaskHook
+	afterTaskHooks         []AfterTaskHook
+	onKickoffStart         KickoffStartHook
+	onKickoffEnd           KickoffEndHook
 
 	// 管理器相关
 	managerAgent       agent.Agent
 	managerLLM         interface{}
+	agentTools         *AgentTools // 管理器Agent的委托工具
 	functionCallingLLM interface{}
 	chatLLM            interface{}
 
 	// 基础设施
-	eventBus       events.EventBus
-	logger         logger.Logger
-	securityConfig security.SecurityConfig
-	memory         Memory
-	cache          Cache
+	eventBus         events.EventBus
+	logger           logger.Logger
+	securityConfig   security.SecurityConfig
+	memory           Memory
+	memoryManager    *MemoryManager // memoryEnabled时在首次执行前创建
+	cache            Cache
+	toolCache        agent.ToolCache
+	persistToolCache bool                  // 工具缓存在多次Kickoff之间保留
+	sessionMemory    *memory.SessionMemory // WithSession的Kickoff读取和追加的会话记忆，副本共享
 
 	// 执行统计
 	usageMetrics       *UsageMetrics
@@ -57,6 +105,7 @@ type BaseCrew struct {
 	// 并发控制
 	mu        sync.RWMutex
 	executing bool
+	status    *statusTracker // 当前或最近一次Kickoff的执行状态，见Status
 }
 
 // NewBaseCrew 创建新的BaseCrew实例
@@ -65,7 +114,7 @@ func NewBaseCrew(config *CrewConfig, eventBus events.EventBus, logger logger.Log
 		config = DefaultCrewConfig()
 	}
 
-	return &BaseCrew{
+	crew := &BaseCrew{
 		id:                     uuid.New().String(),
 		name:                   config.Name,
 		agents:                 make([]agent.Agent, 0),
@@ -75,13 +124,36 @@ func NewBaseCrew(config *CrewConfig, eventBus events.EventBus, logger logger.Log
 		memoryEnabled:          config.MemoryEnabled,
 		cacheEnabled:           config.CacheEnabled,
 		maxRPM:                 config.MaxRPM,
+		maxConcurrency:         config.MaxConcurrency,
 		shareCrewEnabled:       config.ShareCrew,
 		planningEnabled:        config.PlanningEnabled,
+		planningLLM:            config.PlanningLLM,
+		planningStrict:         config.PlanningStrict,
+		maxDelegationDepth:     config.MaxDelegationDepth,
+		originalDescriptions:   make(map[string]string),
 		maxExecutionTime:       config.MaxExecutionTime,
 		fullOutput:             config.FullOutput,
+		outputDir:              config.OutputDir,
+		replayEnabled:          config.ReplayEnabled,
+		replayDir:              config.ReplayDir,
+		finalOutputSchema:      config.FinalOutputSchema,
+		synthesisAgent:         config.SynthesisAgent,
+		consensusAgentsPerTask: config.ConsensusAgentsPerTask,
+		judgeLLM:               config.JudgeLLM,
+		consensusRubric:        config.ConsensusRubric,
+		assignmentStrategy:     config.AssignmentStrategy,
+		assignmentLoad:         make(map[string]int),
+		streamOutput:           config.StreamOutput,
+		contextInjection:       config.ContextInjection,
+		moderation:             config.Moderation,
+		promptInjection:        config.PromptInjection,
+		randomSeed:             config.RandomSeed,
+		strictReproducibility:  config.StrictReproducibility,
 		beforeKickoffCallbacks: make([]KickoffCallback, 0),
 		afterKickoffCallbacks:  make([]KickoffCallback, 0),
 		taskCallback:           config.TaskCallback,
+		onKickoffStart:         config.OnKickoffStart,
+		onKickoffEnd:           config.OnKickoffEnd,
 		stepCallback:           config.StepCallback,
 		managerAgent:           config.ManagerAgent,
 		managerLLM:             config.ManagerLLM,
@@ -89,15 +161,184 @@ func NewBaseCrew(config *CrewConfig, eventBus events.EventBus, logger logger.Log
 		chatLLM:                config.ChatLLM,
 		eventBus:               eventBus,
 		logger:                 logger,
-		securityConfig:         *security.NewSecurityConfig(),
+		securityConfig:         newCrewSecurityConfig(config.FingerprintSeed),
 		usageMetrics:           &UsageMetrics{},
 		executionCount:         0,
 		executing:              false,
 	}
+	crew.setRPMController(agent.NewRPMController(config.MaxRPM))
+	crew.budget = agent.BudgetLimits{
+		MaxTotalTokens:     config.MaxTotalTokens,
+		MaxTotalCostUSD:    config.MaxTotalCostUSD,
+		HardCostCeilingUSD: config.HardCostCeilingUSD,
+	}
+	crew.memorySettings = crewMemorySettings{
+		scope:              config.Name,
+		store:              config.MemoryStore,
+		relevanceThreshold: config.MemoryRelevanceThreshold,
+		maxContextTokens:   config.MaxMemoryContextTokens,
+	}
+	crew.cache = newResponseCache(config.Cache)
+	crew.toolCache = config.ToolCache
+	if crew.toolCache == nil {
+		crew.toolCache = agent.NewToolResultCache()
+	}
+	crew.persistToolCache = config.PersistToolCache
+	crew.sessionMemory = config.SessionMemory
+	if crew.sessionMemory == nil {
+		crew.sessionMemory = memory.NewSessionMemory(nil)
+	}
+
+	return crew
+}
+
+// newCrewSecurityConfig 创建crew的安全配置，提供种子时生成确定性指纹，重启后保持不变
+func newCrewSecurityConfig(seed string) security.SecurityConfig {
+	if seed != "" {
+		return *security.NewSecurityConfigWithFingerprint(security.GenerateDeterministic(seed))
+	}
+	return *security.NewSecurityConfig()
+}
+
+// stampFingerprints 把crew指纹和执行任务的Agent的指纹加入事件
+func (c *BaseCrew) stampFingerprints(event *events.BaseEvent, executor agent.Agent) {
+	event.SetSourceFingerprint("crew", c.GetFingerprint())
+	if executor == nil {
+		return
+	}
+	if fingerprint := executor.GetFingerprint(); fingerprint != nil {
+		event.Payload["agent_fingerprint"] = fingerprint.GetUUID()
+	}
+}
+
+// setRPMController 设置速率控制器，并在限流时发射crew_rate_limited事件
+func (c *BaseCrew) setRPMController(controller *agent.RPMController) {
+	c.rpmController = controller
+	controller.SetThrottleHandler(func(ctx context.Context, wait time.Duration) {
+		c.logger.Debug("LLM call rate limited",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "max_rpm", Value: controller.GetMaxRPM()},
+			logger.Field{Key: "wait", Value: wait},
+		)
+		c.eventBus.Emit(ctx, c, NewCrewRateLimitedEvent(c.id, c.name, controller.GetMaxRPM(), wait))
+	})
+}
+
+// startBudget 设置了预算时为本次Kickoff创建新的预算，所有Agent的LLM调用都计入其中
+func (c *BaseCrew) startBudget(ctx context.Context) context.Context {
+	if c.budget.IsZero() {
+		return ctx
+	}
+	return agent.WithBudget(ctx, agent.NewBudget(c.budget))
+}
+
+// startInjectedContext 设置了ContextInjection时解析一次注入的值，本次Kickoff的所有Agent使用相同的日期和语言环境
+func (c *BaseCrew) startInjectedContext(ctx context.Context) context.Context {
+	if c.contextInjection.IsZero() {
+		return ctx
+	}
+	injected, err := c.contextInjection.Resolve()
+	if err != nil {
+		c.logger.Warn("failed to resolve injected context, using clock timezone",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+	return agent.WithInjectedContext(ctx, injected)
+}
+
+// kickoffSeed 返回派生任务种子的基础种子，严格模式下没有设置时使用0
+func (c *BaseCrew) kickoffSeed() (int, bool) {
+	if c.randomSeed != nil {
+		return *c.randomSeed, true
+	}
+	return 0, c.strictReproducibility
+}
+
+// startReproducibility 设置了种子或严格模式时，本次Kickoff的所有Agent（包括管理器和评审）使用基础种子，
+// 任务执行时由runTask替换为按任务序号派生的种子
+func (c *BaseCrew) startReproducibility(ctx context.Context) context.Context {
+	seed, ok := c.kickoffSeed()
+	if !ok {
+		return ctx
+	}
+	return agent.WithReproducibility(ctx, &agent.ReproducibilityConfig{Seed: &seed, Strict: c.strictReproducibility})
+}
+
+// withTaskSeed 为第index个任务设置由基础种子派生的种子，任务顺序不变时每次Kickoff的种子相同
+func (c *BaseCrew) withTaskSeed(ctx context.Context, index int) context.Context {
+	seed, ok := c.kickoffSeed()
+	if !ok {
+		return ctx
+	}
+	taskSeed := agent.DeriveSeed(seed, index)
+	return agent.WithReproducibility(ctx, &agent.ReproducibilityConfig{Seed: &taskSeed, Strict: c.strictReproducibility})
+}
+
+// configureAgents 将共享的速率控制器、响应缓存、工具缓存和记忆注入所有Agent（包括管理器）
+func (c *BaseCrew) configureAgents() {
+	c.mu.RLock()
+	agents := append([]agent.Agent{}, c.agents...)
+	if c.managerAgent != nil {
+		agents = append(agents, c.managerAgent)
+	}
+	cacheEnabled := c.cacheEnabled
+	memoryEnabled := c.memoryEnabled
+	c.mu.RUnlock()
+
+	var memoryManager *MemoryManager
+	if memoryEnabled {
+		memoryManager = c.getMemoryManager()
+	}
+
+	for _, a := range agents {
+		if c.rpmController != nil {
+			a.SetRPMController(c.rpmController)
+		}
+		if cacheEnabled {
+			a.SetResponseCache(c.cache)
+		} else if a.GetResponseCache() == c.cache {
+			// 仅移除本Crew注入的缓存，保留Agent自行配置的缓存
+			a.SetResponseCache(nil)
+		}
+		a.SetToolCache(c.toolCache)
+		if memoryManager != nil {
+			if err := memoryManager.ConfigureAgent(a); err != nil {
+				c.logger.Warn("failed to configure agent memory",
+					logger.Field{Key: "agent_role", Value: a.GetRole()},
+					logger.Field{Key: "error", Value: err},
+				)
+			}
+		}
+	}
+}
+
+// getMemoryManager 返回Crew的记忆管理器，首次调用时使用默认配置创建
+func (c *BaseCrew) getMemoryManager() *MemoryManager {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.memoryManager == nil {
+		c.memoryManager = NewMemoryManager(c, DefaultMemoryManagerConfig(), c.eventBus, c.logger)
+	}
+	return c.memoryManager
 }
 
 // Kickoff 启动Crew执行
 func (c *BaseCrew) Kickoff(ctx context.Context, inputs map[string]interface{}) (*CrewOutput, error) {
+	return c.kickoff(ctx, inputs, nil)
+}
+
+// kickoff 在crew.kickoff span中执行Kickoff，replay为ReplayFrom准备的重放状态，普通Kickoff时为nil
+func (c *BaseCrew) kickoff(ctx context.Context, inputs map[string]interface{}, replay *replaySession) (*CrewOutput, error) {
+	ctx, span := c.startKickoffSpan(ctx, replay != nil)
+	result, err := c.runKickoff(ctx, inputs, replay)
+	endKickoffSpan(span, result, err)
+	return result, err
+}
+
+// runKickoff 执行Kickoff的完整流程：校验、回调、规划和按流程执行任务
+func (c *BaseCrew) runKickoff(ctx context.Context, inputs map[string]interface{}, replay *replaySession) (result *CrewOutput, err error) {
 	c.mu.Lock()
 	if c.executing {
 		c.mu.Unlock()
@@ -115,8 +356,12 @@ func (c *BaseCrew) Kickoff(ctx context.Context, inputs map[string]interface{}) (
 		c.mu.Unlock()
 	}()
 
+	ctx, finishStatus := c.startStatus(ctx, executionID)
+	defer finishStatus()
+
 	// 发射开始事件
 	startEvent := NewCrewKickoffStartedEvent(c.id, c.name, executionID, c.process.String())
+	c.stampFingerprints(&startEvent.BaseEvent, nil)
 	c.eventBus.Emit(ctx, c, startEvent)
 
 	c.logger.Info("crew kickoff started",
@@ -136,6 +381,36 @@ func (c *BaseCrew) Kickoff(ctx context.Context, inputs map[string]interface{}) (
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
 
+	// 开始钩子可以向ctx放入本次执行共享的资源，开始钩子成功后结束钩子总会执行
+	if ctx, err = c.runKickoffStartHook(ctx); err != nil {
+		return nil, err
+	}
+	defer func(ctx context.Context) { c.runKickoffEndHook(ctx, result) }(ctx)
+
+	ctx = c.startCrewExecution(ctx, executionID)
+	ctx, session := c.startReplaySession(ctx, inputs, replay)
+	ctx = c.startConversation(ctx)
+	ctx = c.startBudget(ctx)
+	ctx = c.startInjectedContext(ctx)
+	if c.moderation != nil {
+		ctx = agent.WithModeration(ctx, c.moderation)
+	}
+	if c.promptInjection != nil {
+		ctx = agent.WithPromptInjection(ctx, c.promptInjection)
+	}
+	ctx = c.startReproducibility(ctx)
+	ctx = c.startCrewMemory(ctx, inputs)
+
+	c.configureAgents()
+	if !c.persistToolCache {
+		c.toolCache.Clear()
+	}
+	c.resetAssignmentLoad()
+
+	// 允许委托的Agent在本次执行期间获得同事工具，执行结束后移除
+	detachCoworkerTools := c.attachCoworkerTools()
+	defer detachCoworkerTools()
+
 	// 执行前回调
 	for _, callback := range c.beforeKickoffCallbacks {
 		if _, err := callback(ctx, c, nil); err != nil {
@@ -146,30 +421,44 @@ func (c *BaseCrew) Kickoff(ctx context.Context, inputs map[string]interface{}) (
 		}
 	}
 
-	// 规划处理
+	// 规划处理，非严格模式下规划失败只记录警告
 	if c.planningEnabled {
 		if err := c.handleCrewPlanning(ctx, inputs); err != nil {
-			c.logger.Error("crew planning failed",
+			if c.planningStrict {
+				c.logger.Error("crew planning failed",
+					logger.Field{Key: "error", Value: err},
+				)
+				return nil, fmt.Errorf("crew planning failed: %w", err)
+			}
+			c.logger.Warn("crew planning failed, continuing without plan",
+				logger.Field{Key: "crew_name", Value: c.name},
 				logger.Field{Key: "error", Value: err},
 			)
-			return nil, fmt.Errorf("crew planning failed: %w", err)
 		}
 	}
 
 	// 执行任务
 	start := time.Now()
-	var result *CrewOutput
-	var err error
 
 	switch c.process {
 	case ProcessSequential:
 		result, err = c.runSequentialProcess(ctx, inputs)
 	case ProcessHierarchical:
 		result, err = c.runHierarchicalProcess(ctx, inputs)
+	case ProcessParallel:
+		result, err = c.runParallelProcess(ctx, inputs)
+	case ProcessConsensus:
+		result, err = c.runConsensusProcess(ctx, inputs)
 	default:
 		err = fmt.Errorf("unsupported process: %v", c.process)
 	}
 
+	if result != nil && err == nil {
+		c.synthesizeFinalOutput(ctx, result)
+		c.finishConversation(ctx, inputs, result)
+		c.finishCrewMemory(ctx, result)
+	}
+
 	duration := time.Since(start)
 	c.mu.Lock()
 	c.totalExecutionTime += duration
@@ -180,6 +469,18 @@ func (c *BaseCrew) Kickoff(ctx context.Context, inputs map[string]interface{}) (
 		result.Success = err == nil
 		result.Error = err
 		result.CreatedAt = time.Now()
+		if fingerprint := c.GetFingerprint(); fingerprint != nil {
+			result.Fingerprint = fingerprint.GetUUID()
+		}
+		if session != nil && session.store != nil {
+			result.Metadata["kickoff_id"] = session.kickoffID
+		}
+		if session != nil && session.replayOf != "" {
+			result.Metadata["replay_of"] = session.replayOf
+		}
+		if seed, ok := c.kickoffSeed(); ok {
+			result.Metadata["random_seed"] = seed
+		}
 	}
 
 	// 执行后回调
@@ -197,6 +498,7 @@ func (c *BaseCrew) Kickoff(ctx context.Context, inputs map[string]interface{}) (
 
 	// 发射完成事件
 	completedEvent := NewCrewKickoffCompletedEvent(c.id, c.name, executionID, duration, err == nil)
+	c.stampFingerprints(&completedEvent.BaseEvent, nil)
 	c.eventBus.Emit(ctx, c, completedEvent)
 
 	if err != nil {
@@ -251,47 +553,21 @@ func (c *BaseCrew) KickoffWithTimeout(ctx context.Context, inputs map[string]int
 	}
 }
 
-// KickoffForEach 为每个输入执行Crew
+// KickoffForEach 依次为每个输入执行Crew，第一组输入失败时停止并返回之前完成的输出
 func (c *BaseCrew) KickoffForEach(ctx context.Context, inputsList []map[string]interface{}) ([]*CrewOutput, error) {
-	results := make([]*CrewOutput, 0, len(inputsList))
-	totalUsageMetrics := &UsageMetrics{}
-
-	for i, inputs := range inputsList {
-		c.logger.Info("executing crew for input set",
-			logger.Field{Key: "crew_name", Value: c.name},
-			logger.Field{Key: "input_index", Value: i},
-			logger.Field{Key: "total_inputs", Value: len(inputsList)},
-		)
-
-		// 创建crew副本以避免状态冲突
-		crewCopy, err := c.Clone()
-		if err != nil {
-			return results, fmt.Errorf("failed to clone crew for input %d: %w", i, err)
-		}
-
-		output, err := crewCopy.Kickoff(ctx, inputs)
-		if err != nil {
-			c.logger.Error("crew execution failed for input set",
-				logger.Field{Key: "input_index", Value: i},
-				logger.Field{Key: "error", Value: err},
-			)
-			return results, fmt.Errorf("execution failed for input %d: %w", i, err)
-		}
-
-		results = append(results, output)
+	results, err := c.KickoffForEachConcurrent(ctx, inputsList, KickoffForEachOptions{
+		MaxConcurrency: 1,
+		ErrorPolicy:    BatchFailFast,
+	})
 
-		// 累计使用统计
-		if crewUsage := crewCopy.GetUsageMetrics(); crewUsage != nil {
-			totalUsageMetrics.AddUsageMetrics(crewUsage)
+	outputs := make([]*CrewOutput, 0, len(results))
+	for _, result := range results {
+		if result.Error != nil {
+			break
 		}
+		outputs = append(outputs, result.Output)
 	}
-
-	// 更新总的使用统计
-	c.mu.Lock()
-	c.usageMetrics = totalUsageMetrics
-	c.mu.Unlock()
-
-	return results, nil
+	return outputs, err
 }
 
 // KickoffForEachAsync 异步为每个输入执行Crew
@@ -312,27 +588,37 @@ func (c *BaseCrew) KickoffForEachAsync(ctx context.Context, inputsList []map[str
 	return resultChan, nil
 }
 
-// Train 训练Crew模型
+// Train 训练Crew模型，每次迭代后在控制台收集人工反馈并保存训练数据
 func (c *BaseCrew) Train(ctx context.Context, nIterations int, filename string, inputs map[string]interface{}) error {
-	// 需要导入训练包，但为了避免循环依赖，这里使用接口
 	return c.TrainWithConfig(ctx, &TrainingConfig{
-		Iterations: nIterations,
-		Filename:   filename,
-		Inputs:     inputs,
+		Iterations:      nIterations,
+		Filename:        filename,
+		Inputs:          inputs,
+		CollectFeedback: true,
+		MetricsEnabled:  true,
+		AutoSave:        true,
 	})
 }
 
-// TrainingConfig 训练配置（临时定义，实际应该使用training包的配置）
+// TrainingConfig Crew训练配置，对应training.TrainingConfig中与Crew相关的部分
 type TrainingConfig struct {
 	Iterations      int
 	Filename        string
 	Inputs          map[string]interface{}
 	CollectFeedback bool
 	MetricsEnabled  bool
-	AutoSave        bool
+	AutoSave        bool // 为true时每次迭代后保存训练数据到Filename
+
+	// FeedbackCollector 为空时使用控制台收集器
+	FeedbackCollector training.FeedbackCollector
+	FeedbackTimeout   time.Duration
+
+	// InstructionLLM 训练结束时把反馈总结为Agent改进指令的LLM，为空时使用第一个配置了LLM的Agent
+	InstructionLLM llm.LLM
 }
 
 // TrainWithConfig 使用配置进行训练
+// 上下文被取消时保存已收集的数据，会话标记为interrupted并返回上下文错误
 func (c *BaseCrew) TrainWithConfig(ctx context.Context, config *TrainingConfig) error {
 	c.logger.Info("starting crew training with config",
 		logger.Field{Key: "crew_name", Value: c.name},
@@ -345,94 +631,158 @@ func (c *BaseCrew) TrainWithConfig(ctx context.Context, config *TrainingConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create training copy: %w", err)
 	}
+	// 训练的每次迭代都需要真实的LLM输出，不能重放缓存的回答
+	trainCrew.SetCacheEnabled(false)
+
+	trainingConfig := training.DefaultTrainingConfig()
+	trainingConfig.Iterations = config.Iterations
+	trainingConfig.Filename = config.Filename
+	trainingConfig.Inputs = config.Inputs
+	trainingConfig.CollectFeedback = config.CollectFeedback
+	trainingConfig.MetricsEnabled = config.MetricsEnabled
+	trainingConfig.AutoSave = config.AutoSave && config.Filename != ""
+	trainingConfig.SaveInterval = 1
+	trainingConfig.BackupCount = 0
+	if config.FeedbackTimeout > 0 {
+		trainingConfig.FeedbackTimeout = config.FeedbackTimeout
+	}
 
-	// 设置默认配置
-	if !config.CollectFeedback {
-		config.CollectFeedback = true
+	eventBus := c.eventBus
+	if eventBus == nil {
+		eventBus = events.NewEventBus(c.logger)
 	}
-	if !config.MetricsEnabled {
-		config.MetricsEnabled = true
+	handler := training.NewCrewTrainingHandler(eventBus, c.logger)
+	if config.FeedbackCollector != nil {
+		handler.SetFeedbackCollector(config.FeedbackCollector)
 	}
-	if !config.AutoSave {
-		config.AutoSave = true
+	if config.CollectFeedback {
+		if instructionLLM := c.instructionLLM(config.InstructionLLM); instructionLLM != nil {
+			handler.SetInstructionSummarizer(training.NewAgentInstructionSummarizer(instructionLLM, c.logger))
+		}
 	}
 
-	// 创建执行函数
+	// 执行函数返回可序列化的输出，tokens_used用于记录每次迭代的token使用量
 	executeFunc := func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
 		output, err := trainCrew.Kickoff(ctx, inputs)
-		return output, err
+		if err != nil {
+			return nil, err
+		}
+		return TrainingOutputs(output), nil
 	}
 
-	// 执行训练迭代
-	var trainingErrors []error
-	for iteration := 0; iteration < config.Iterations; iteration++ {
-		c.logger.Info("executing training iteration",
-			logger.Field{Key: "iteration", Value: iteration},
-			logger.Field{Key: "total_iterations", Value: config.Iterations},
-		)
+	summary, err := training.NewTrainingUtils(c.logger).RunTrainingSession(ctx, handler, trainingConfig, executeFunc)
+	if err != nil {
+		return err
+	}
 
-		// 模拟训练处理器行为（实际使用时应该创建真正的训练处理器）
-		start := time.Now()
-		output, err := executeFunc(ctx, config.Inputs)
-		duration := time.Since(start)
+	c.logger.Info("crew training completed",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "total_iterations", Value: summary.TotalIterations},
+		logger.Field{Key: "successful_runs", Value: summary.SuccessfulRuns},
+		logger.Field{Key: "failed_iterations", Value: summary.FailedRuns},
+	)
 
-		if err != nil {
-			c.logger.Error("training iteration failed",
-				logger.Field{Key: "iteration", Value: iteration},
-				logger.Field{Key: "error", Value: err},
-			)
-			trainingErrors = append(trainingErrors, err)
-			continue
-		}
+	if summary.SuccessfulRuns == 0 {
+		return fmt.Errorf("all training iterations failed")
+	}
 
-		c.logger.Info("training iteration completed",
-			logger.Field{Key: "iteration", Value: iteration},
-			logger.Field{Key: "duration", Value: duration},
-			logger.Field{Key: "success", Value: output != nil},
-		)
+	return nil
+}
 
-		// 模拟反馈收集（实际使用时应该调用训练处理器）
-		if config.CollectFeedback {
-			c.logger.Debug("feedback collection would occur here",
-				logger.Field{Key: "iteration", Value: iteration},
-			)
+// instructionLLM 总结改进指令使用的LLM
+func (c *BaseCrew) instructionLLM(configured llm.LLM) llm.LLM {
+	if configured != nil {
+		return configured
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, a := range c.agents {
+		if agentLLM := a.GetLLM(); agentLLM != nil {
+			return agentLLM
 		}
+	}
+	return nil
+}
+
+// LoadTraining 加载训练数据文件，把其中的改进指令按角色应用到Crew的Agent上，对应Python版本的trained_agents_data
+// 文件不存在、没有改进指令或角色不匹配时只记录警告，Crew以未训练状态继续运行
+func (c *BaseCrew) LoadTraining(filename string) error {
+	data, err := training.ReadTrainingData(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.logger.Warn("training file not found, running untrained",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "filename", Value: filename},
+		)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data.TrainedAgents) == 0 {
+		c.logger.Warn("training file contains no trained agent instructions, running untrained",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "filename", Value: filename},
+		)
+		return nil
+	}
 
-		// 模拟性能分析（实际使用时应该调用训练处理器）
-		if config.MetricsEnabled {
-			c.logger.Debug("metrics analysis would occur here",
-				logger.Field{Key: "iteration", Value: iteration},
-				logger.Field{Key: "execution_time", Value: duration},
+	c.mu.RLock()
+	agents := append([]agent.Agent(nil), c.agents...)
+	c.mu.RUnlock()
+
+	applied := make(map[string]bool)
+	for _, a := range agents {
+		if trained, ok := data.TrainedAgents[a.GetRole()]; ok {
+			a.SetTrainedInstructions(trained.Suggestions)
+			applied[a.GetRole()] = true
+			c.logger.Info("trained instructions applied",
+				logger.Field{Key: "agent_role", Value: a.GetRole()},
+				logger.Field{Key: "instructions", Value: len(trained.Suggestions)},
 			)
 		}
+	}
 
-		// 模拟自动保存（实际使用时应该调用训练处理器）
-		if config.AutoSave && iteration%5 == 0 {
-			c.logger.Debug("auto-save would occur here",
-				logger.Field{Key: "iteration", Value: iteration},
-				logger.Field{Key: "filename", Value: config.Filename},
+	for role := range data.TrainedAgents {
+		if !applied[role] {
+			c.logger.Warn("trained agent role not found in crew",
+				logger.Field{Key: "crew_name", Value: c.name},
+				logger.Field{Key: "agent_role", Value: role},
 			)
 		}
 	}
+	if len(applied) == 0 {
+		c.logger.Warn("no agent in crew matches the training file, running untrained",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "filename", Value: filename},
+		)
+	}
 
-	// 训练完成统计
-	successRate := float64(config.Iterations-len(trainingErrors)) / float64(config.Iterations) * 100
-	c.logger.Info("crew training completed",
-		logger.Field{Key: "crew_name", Value: c.name},
-		logger.Field{Key: "total_iterations", Value: config.Iterations},
-		logger.Field{Key: "success_rate", Value: successRate},
-		logger.Field{Key: "failed_iterations", Value: len(trainingErrors)},
-	)
-
-	// TODO: 最终保存训练数据
-	// TODO: 生成训练报告
-	// TODO: 应用训练结果到crew
+	return nil
+}
 
-	if len(trainingErrors) > 0 && len(trainingErrors) == config.Iterations {
-		return fmt.Errorf("all training iterations failed")
+// TrainingOutputs 把Crew输出转换为训练数据中保存的迭代输出，包含最终输出、各任务输出和使用统计
+func TrainingOutputs(output *CrewOutput) map[string]interface{} {
+	tasks := make([]map[string]interface{}, 0, len(output.TasksOutput))
+	for _, taskOutput := range output.TasksOutput {
+		if taskOutput == nil {
+			continue
+		}
+		tasks = append(tasks, map[string]interface{}{
+			"description": taskOutput.Description,
+			"agent":       taskOutput.Agent,
+			"raw":         taskOutput.Raw,
+		})
 	}
 
-	return nil
+	outputs := map[string]interface{}{
+		"raw":   output.Raw,
+		"tasks": tasks,
+	}
+	if output.TokenUsage != nil {
+		outputs["tokens_used"] = output.TokenUsage.TotalTokens
+		outputs["usage"] = output.TokenUsage.Snapshot()
+	}
+	return outputs
 }
 
 // validateConfiguration 验证Crew配置
@@ -452,6 +802,11 @@ func (c *BaseCrew) validateConfiguration() error {
 		)
 	}
 
+	// 验证任务依赖图，确保在任何LLM调用之前发现依赖环
+	if _, err := newTaskGraph(c.tasks); err != nil {
+		return err
+	}
+
 	// 验证层级模式配置
 	if c.process == ProcessHierarchical {
 		if c.managerAgent == nil && c.managerLLM == nil {
@@ -459,27 +814,16 @@ func (c *BaseCrew) validateConfiguration() error {
 		}
 	}
 
-	return nil
-}
-
-// handleCrewPlanning 处理Crew规划
-func (c *BaseCrew) handleCrewPlanning(ctx context.Context, inputs map[string]interface{}) error {
-	c.logger.Info("handling crew planning",
-		logger.Field{Key: "crew_name", Value: c.name},
-		logger.Field{Key: "tasks_count", Value: len(c.tasks)},
-	)
-
-	// TODO: 实现规划逻辑
-	// 这里应该包括：
-	// 1. 分析任务依赖关系
-	// 2. 优化执行顺序
-	// 3. 资源分配
-	// 4. 风险评估
+	// 验证共识模式配置
+	if c.process == ProcessConsensus && c.judgeLLM == nil && c.managerAgent == nil && c.managerLLM == nil {
+		return fmt.Errorf("consensus process requires a judge LLM, a manager agent or a manager LLM")
+	}
 
 	return nil
 }
 
 // calculateUsageMetrics 计算使用统计
+// 流程执行期间已汇总统计时（如Parallel流程）直接使用，否则按任务输出汇总
 func (c *BaseCrew) calculateUsageMetrics(result *CrewOutput) {
 	if result == nil {
 		return
@@ -488,22 +832,16 @@ func (c *BaseCrew) calculateUsageMetrics(result *CrewOutput) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	metrics := &UsageMetrics{
-		TotalTasks:    len(c.tasks),
-		ExecutionTime: result.Duration,
-	}
-
-	// 统计任务结果
-	for _, taskOutput := range result.TasksOutput {
-		if taskOutput != nil && taskOutput.IsValid {
-			metrics.SuccessfulTasks++
-		} else {
-			metrics.FailedTasks++
+	metrics := result.TokenUsage
+	if metrics == nil {
+		metrics = &UsageMetrics{}
+		for _, taskOutput := range result.TasksOutput {
+			recordTaskUsage(metrics, taskOutput)
 		}
-
-		// TODO: 从任务输出中提取token使用情况
-		// 这需要与LLM模块集成
+		metrics.AddTaskOutput(result.SynthesisOutput)
 	}
+	metrics.TotalTasks = len(c.tasks)
+	metrics.AddRun(result.Duration)
 
 	c.usageMetrics = metrics
 	result.TokenUsage = metrics
@@ -560,6 +898,13 @@ func (c *BaseCrew) SetCacheEnabled(enabled bool) {
 	c.cacheEnabled = enabled
 }
 
+// SetOutputDir 设置任务输出文件相对路径的基础目录
+func (c *BaseCrew) SetOutputDir(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.outputDir = dir
+}
+
 func (c *BaseCrew) AddBeforeKickoffCallback(callback KickoffCallback) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -588,7 +933,40 @@ func (c *BaseCrew) AddStepCallback(callback StepCallback) error {
 	return nil
 }
 
+// AddBeforeTaskHook 添加任务执行前的钩子，多个钩子按注册顺序执行
+func (c *BaseCrew) AddBeforeTaskHook(hook BeforeTaskHook) error {
+	if hook == nil {
+		return fmt.Errorf("before task hook cannot be nil")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.beforeTaskHooks = append(c.beforeTaskHooks, hook)
+	return nil
+}
+
+// AddAfterTaskHook 添加任务执行后的钩子，多个钩子按注册顺序执行
+func (c *BaseCrew) AddAfterTaskHook(hook AfterTaskHook) error {
+	if hook == nil {
+		return fmt.Errorf("after task hook cannot be nil")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.afterTaskHooks = append(c.afterTaskHooks, hook)
+	return nil
+}
+
 // 状态查询方法
+
+// GetFingerprint 返回crew的安全指纹
+func (c *BaseCrew) GetFingerprint() *security.Fingerprint {
+	return c.securityConfig.Fingerprint
+}
+
+// GetID 返回crew的唯一ID，创建后不变，因此无需加锁（事件Sink在crew持锁时也会调用）
+func (c *BaseCrew) GetID() string {
+	return c.id
+}
+
 func (c *BaseCrew) GetAgents() []agent.Agent {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -633,45 +1011,104 @@ func (c *BaseCrew) GetUsageMetrics() *UsageMetrics {
 		return &UsageMetrics{}
 	}
 
-	// 返回副本以避免并发修改
-	metrics := *c.usageMetrics
-	return &metrics
+	// 返回快照以避免并发修改，速率控制器的实时状态在副本上填充
+	metrics := c.usageMetrics.Snapshot()
+	if c.rpmController != nil {
+		stats := c.rpmController.Stats()
+		metrics.MaxRPM = stats.MaxRPM
+		metrics.RequestsLastMinute = stats.RequestsLastMinute
+		metrics.RPMUtilization = stats.Utilization
+	}
+	return metrics
 }
 
-// Clone 创建Crew的副本
+// Clone 创建Crew的副本，用于同时执行多组输入（如KickoffForEach）
+// Agent和任务都被复制：副本的Agent有新的ID和指纹、独立的工具使用计数和统计，
+// 推理或规划对任务描述的修改也不会影响原Crew
 func (c *BaseCrew) Clone() (Crew, error) {
+	return c.cloneWithEventBus(c.eventBus), nil
+}
+
+// cloneWithEventBus 创建使用指定事件总线的副本，作为工具执行的Crew用它把事件转发给调用方的Crew
+func (c *BaseCrew) cloneWithEventBus(eventBus events.EventBus) *BaseCrew {
+	// 副本共享记忆管理器，原Crew还没有执行过时先创建，避免每个副本各自打开存储
+	if c.IsMemoryEnabled() {
+		c.getMemoryManager()
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	agentMapping := make(map[agent.Agent]agent.Agent, len(c.agents)+1)
 	config := &CrewConfig{
 		Name:               c.name + "_clone",
 		Process:            c.process,
 		Verbose:            c.verbose,
 		MemoryEnabled:      c.memoryEnabled,
+		MemoryStore:        c.memorySettings.store,
 		CacheEnabled:       c.cacheEnabled,
 		MaxRPM:             c.maxRPM,
+		MaxConcurrency:     c.maxConcurrency,
+		MaxTotalTokens:     c.budget.MaxTotalTokens,
+		MaxTotalCostUSD:    c.budget.MaxTotalCostUSD,
+		HardCostCeilingUSD: c.budget.HardCostCeilingUSD,
 		ShareCrew:          c.shareCrewEnabled,
 		PlanningEnabled:    c.planningEnabled,
+		PlanningLLM:        c.planningLLM,
+		PlanningStrict:     c.planningStrict,
+		MaxDelegationDepth: c.maxDelegationDepth,
+		OutputDir:          c.outputDir,
+		ReplayEnabled:      c.replayEnabled,
+		ReplayDir:          c.replayDir,
 		MaxExecutionTime:   c.maxExecutionTime,
 		FullOutput:         c.fullOutput,
 		TaskCallback:       c.taskCallback,
 		StepCallback:       c.stepCallback,
-		ManagerAgent:       c.managerAgent,
+		OnKickoffStart:     c.onKickoffStart,
+		OnKickoffEnd:       c.onKickoffEnd,
+		ManagerAgent:       cloneCrewAgent(c.managerAgent, agentMapping),
+		FinalOutputSchema:  c.finalOutputSchema,
 		ManagerLLM:         c.managerLLM,
 		FunctionCallingLLM: c.functionCallingLLM,
 		ChatLLM:            c.chatLLM,
+		ToolCache:          c.sharedToolCache(),
+		PersistToolCache:   c.persistToolCache,
+		SessionMemory:      c.sessionMemory,
+
+		ConsensusAgentsPerTask: c.consensusAgentsPerTask,
+		JudgeLLM:               c.judgeLLM,
+		ConsensusRubric:        c.consensusRubric,
+		AssignmentStrategy:     c.assignmentStrategy,
+		StreamOutput:           c.streamOutput,
+		ContextInjection:       c.contextInjection,
+		Moderation:             c.moderation,
+		PromptInjection:        c.promptInjection,
+		RandomSeed:             c.randomSeed,
+		StrictReproducibility:  c.strictReproducibility,
+
+		MemoryRelevanceThreshold: c.memorySettings.relevanceThreshold,
+		MaxMemoryContextTokens:   c.memorySettings.maxContextTokens,
 	}
 
-	clone := NewBaseCrew(config, c.eventBus, c.logger)
+	clone := NewBaseCrew(config, eventBus, c.logger)
+	// 副本共享速率控制器，保证并发执行时整体不超过MaxRPM
+	clone.rpmController = c.rpmController
+	clone.cache = c.cache
+	// 副本按原Crew的名称读写Crew记忆
+	clone.memoryManager = c.memoryManager
+	clone.memorySettings.scope = c.memorySettings.scope
 
-	// 复制agents和tasks
+	// 复制agents和tasks，任务的预分配Agent、上下文任务和依赖指向副本中对应的对象
 	for _, agentToCopy := range c.agents {
-		// TODO: 实现agent的Clone方法
-		clone.AddAgent(agentToCopy)
+		clone.AddAgent(cloneCrewAgent(agentToCopy, agentMapping))
 	}
+	clone.synthesisAgent = cloneCrewAgent(c.synthesisAgent, agentMapping)
 
-	for _, task := range c.tasks {
-		// TODO: 实现task的Clone方法
+	for i, task := range cloneCrewTasks(c.tasks, agentMapping) {
+		// 副本从规划前的描述开始，不带原Crew执行时加入的计划
+		if description, ok := c.originalDescriptions[c.tasks[i].GetID()]; ok && task != c.tasks[i] {
+			task.SetDescription(description)
+		}
 		clone.AddTask(task)
 	}
 
@@ -682,7 +1119,81 @@ func (c *BaseCrew) Clone() (Crew, error) {
 	clone.afterKickoffCallbacks = make([]KickoffCallback, len(c.afterKickoffCallbacks))
 	copy(clone.afterKickoffCallbacks, c.afterKickoffCallbacks)
 
-	return clone, nil
+	clone.beforeTaskHooks = append([]BeforeTaskHook(nil), c.beforeTaskHooks...)
+	clone.afterTaskHooks = append([]AfterTaskHook(nil), c.afterTaskHooks...)
+
+	return clone
+}
+
+// cloneCrewAgent 复制Agent并记录原Agent到副本的映射
+// 委托工具指向原Crew的Agent，从副本中移除，执行时按副本的Agent重新注入
+func cloneCrewAgent(original agent.Agent, mapping map[agent.Agent]agent.Agent) agent.Agent {
+	if original == nil {
+		return nil
+	}
+	if cloned, ok := mapping[original]; ok {
+		return cloned
+	}
+
+	cloned := original.Clone()
+	for _, tool := range append([]agent.Tool(nil), cloned.GetTools()...) {
+		if isDelegationTool(tool) {
+			_ = cloned.RemoveTool(tool.GetName())
+		}
+	}
+	mapping[original] = cloned
+	return cloned
+}
+
+// cloneCrewTasks 复制任务列表，并把预分配Agent、上下文任务和依赖ID映射到副本
+// 不支持Clone的任务实现原样共享
+func cloneCrewTasks(tasks []agent.Task, agentMapping map[agent.Agent]agent.Agent) []agent.Task {
+	cloned := make([]agent.Task, len(tasks))
+	taskMapping := make(map[agent.Task]agent.Task, len(tasks))
+	taskIDs := make(map[string]string, len(tasks))
+	for i, task := range tasks {
+		cloned[i] = task
+		if cloneable, ok := task.(interface{ Clone() agent.Task }); ok {
+			cloned[i] = cloneable.Clone()
+		}
+		taskMapping[task] = cloned[i]
+		taskIDs[task.GetID()] = cloned[i].GetID()
+	}
+
+	for i, task := range cloned {
+		if task == tasks[i] {
+			continue
+		}
+		if assigned, ok := agentMapping[task.GetAssignedAgent()]; ok {
+			_ = task.SetAssignedAgent(assigned)
+		}
+
+		contextTasks := task.GetContextTasks()
+		for j, contextTask := range contextTasks {
+			if mapped, ok := taskMapping[contextTask]; ok {
+				contextTasks[j] = mapped
+			}
+		}
+		task.SetContextTasks(contextTasks)
+
+		dependsOn := task.GetDependsOn()
+		for j, id := range dependsOn {
+			if mapped, ok := taskIDs[id]; ok {
+				dependsOn[j] = mapped
+			}
+		}
+		task.SetDependsOn(dependsOn)
+	}
+	return cloned
+}
+
+// sharedToolCache 返回副本使用的工具缓存，只有持久化的工具缓存在副本间共享
+// 否则副本的Kickoff会清空原Crew正在使用的缓存
+func (c *BaseCrew) sharedToolCache() agent.ToolCache {
+	if c.persistToolCache {
+		return c.toolCache
+	}
+	return nil
 }
 
 // Copy 创建Crew的浅拷贝，用于并行执行
@@ -695,21 +1206,56 @@ func (c *BaseCrew) Copy() (Crew, error) {
 		Process:            c.process,
 		Verbose:            c.verbose,
 		MemoryEnabled:      c.memoryEnabled,
+		MemoryStore:        c.memorySettings.store,
 		CacheEnabled:       c.cacheEnabled,
 		MaxRPM:             c.maxRPM,
+		MaxConcurrency:     c.maxConcurrency,
+		MaxTotalTokens:     c.budget.MaxTotalTokens,
+		MaxTotalCostUSD:    c.budget.MaxTotalCostUSD,
+		HardCostCeilingUSD: c.budget.HardCostCeilingUSD,
 		ShareCrew:          c.shareCrewEnabled,
 		PlanningEnabled:    c.planningEnabled,
+		PlanningLLM:        c.planningLLM,
+		PlanningStrict:     c.planningStrict,
+		MaxDelegationDepth: c.maxDelegationDepth,
+		OutputDir:          c.outputDir,
+		ReplayEnabled:      c.replayEnabled,
+		ReplayDir:          c.replayDir,
 		MaxExecutionTime:   c.maxExecutionTime,
 		FullOutput:         c.fullOutput,
 		TaskCallback:       c.taskCallback,
 		StepCallback:       c.stepCallback,
+		OnKickoffStart:     c.onKickoffStart,
+		OnKickoffEnd:       c.onKickoffEnd,
 		ManagerAgent:       c.managerAgent,
+		FinalOutputSchema:  c.finalOutputSchema,
+		SynthesisAgent:     c.synthesisAgent,
 		ManagerLLM:         c.managerLLM,
 		FunctionCallingLLM: c.functionCallingLLM,
 		ChatLLM:            c.chatLLM,
+		ToolCache:          c.sharedToolCache(),
+		PersistToolCache:   c.persistToolCache,
+
+		ConsensusAgentsPerTask: c.consensusAgentsPerTask,
+		JudgeLLM:               c.judgeLLM,
+		ConsensusRubric:        c.consensusRubric,
+		AssignmentStrategy:     c.assignmentStrategy,
+		StreamOutput:           c.streamOutput,
+		ContextInjection:       c.contextInjection,
+		Moderation:             c.moderation,
+		PromptInjection:        c.promptInjection,
+		RandomSeed:             c.randomSeed,
+		StrictReproducibility:  c.strictReproducibility,
+
+		MemoryRelevanceThreshold: c.memorySettings.relevanceThreshold,
+		MaxMemoryContextTokens:   c.memorySettings.maxContextTokens,
 	}
 
 	crewCopy := NewBaseCrew(config, c.eventBus, c.logger)
+	crewCopy.rpmController = c.rpmController
+	crewCopy.cache = c.cache
+	crewCopy.memoryManager = c.memoryManager
+	crewCopy.memorySettings.scope = c.memorySettings.scope
 
 	// 直接复制agents和tasks切片（浅拷贝）
 	crewCopy.agents = make([]agent.Agent, len(c.agents))
@@ -725,6 +1271,9 @@ func (c *BaseCrew) Copy() (Crew, error) {
 	crewCopy.afterKickoffCallbacks = make([]KickoffCallback, len(c.afterKickoffCallbacks))
 	copy(crewCopy.afterKickoffCallbacks, c.afterKickoffCallbacks)
 
+	crewCopy.beforeTaskHooks = append([]BeforeTaskHook(nil), c.beforeTaskHooks...)
+	crewCopy.afterTaskHooks = append([]AfterTaskHook(nil), c.afterTaskHooks...)
+
 	// 重置执行状态
 	crewCopy.executing = false
 	crewCopy.executionCount = 0
@@ -746,7 +1295,20 @@ func (c *BaseCrew) Close() error {
 		_ = agentToClose
 	}
 
+	// 刷新事件Sink，保证已发射的事件落盘
+	if c.eventBus != nil {
+		if err := c.eventBus.FlushSinks(); err != nil {
+			c.logger.Warn("failed to flush event sinks", logger.Field{Key: "error", Value: err})
+		}
+	}
+
 	// 清理memory和cache
+	if c.memoryManager != nil {
+		if err := c.memoryManager.Close(); err != nil {
+			c.logger.Warn("failed to close memory manager", logger.Field{Key: "error", Value: err})
+		}
+		c.memoryManager = nil
+	}
 	if c.memory != nil {
 		// TODO: 清理memory
 		c.logger.Debug("Memory system cleanup required")
@@ -2,13 +2,22 @@ package crew
 
 import (
 	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/internal/training"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/security"
 )
 
 // MockAgent 用于测试的Mock Agent
@@ -42,6 +51,21 @@ func (m *MockAgent) ExecuteAsync(ctx context.Context, task agent.Task) (<-chan a
 	return resultChan, nil
 }
 
+func (m *MockAgent) ExecuteStream(ctx context.Context, task agent.Task) (<-chan agent.AgentStreamChunk, error) {
+	chunks := make(chan agent.AgentStreamChunk, 1)
+	go func() {
+		defer close(chunks)
+		output, err := m.Execute(ctx, task)
+		chunk := agent.AgentStreamChunk{Done: true, Output: output, Error: err}
+		if output != nil {
+			chunk.Delta = output.Raw
+			chunk.Content = output.Raw
+		}
+		chunks <- chunk
+	}()
+	return chunks, nil
+}
+
 func (m *MockAgent) ExecuteWithTimeout(ctx context.Context, task agent.Task, timeout time.Duration) (*agent.TaskOutput, error) {
 	return m.Execute(ctx, task)
 }
@@ -66,10 +90,18 @@ func (m *MockAgent) GetBackstory() string {
 	return m.backstory
 }
 
+func (m *MockAgent) GetFingerprint() *security.Fingerprint {
+	return nil
+}
+
 func (m *MockAgent) AddTool(tool agent.Tool) error {
 	return nil
 }
 
+func (m *MockAgent) RemoveTool(name string) error {
+	return nil
+}
+
 func (m *MockAgent) GetTools() []agent.Tool {
 	return []agent.Tool{}
 }
@@ -107,6 +139,34 @@ func (m *MockAgent) GetHumanInputHandler() agent.HumanInputHandler {
 	return nil
 }
 
+func (m *MockAgent) SetRPMController(controller *agent.RPMController) {
+}
+
+func (m *MockAgent) GetRPMController() *agent.RPMController {
+	return nil
+}
+
+func (m *MockAgent) SetResponseCache(cache llm.ResponseCache) {
+}
+
+func (m *MockAgent) GetResponseCache() llm.ResponseCache {
+	return nil
+}
+
+func (m *MockAgent) SetToolCache(cache agent.ToolCache) {
+}
+
+func (m *MockAgent) GetToolCache() agent.ToolCache {
+	return nil
+}
+
+func (m *MockAgent) SetTrainedInstructions(instructions []string) {
+}
+
+func (m *MockAgent) GetTrainedInstructions() []string {
+	return nil
+}
+
 func (m *MockAgent) SetExecutionConfig(config agent.ExecutionConfig) error {
 	return nil
 }
@@ -119,6 +179,10 @@ func (m *MockAgent) GetExecutionStats() agent.ExecutionStats {
 	return agent.ExecutionStats{}
 }
 
+func (m *MockAgent) GetUsageMetrics() *agent.UsageMetrics {
+	return &agent.UsageMetrics{}
+}
+
 func (m *MockAgent) ResetExecutionStats() {
 }
 
@@ -127,7 +191,7 @@ func (m *MockAgent) ResetStats() error {
 }
 
 func (m *MockAgent) Clone() agent.Agent {
-	return &MockAgent{id: m.id, role: m.role, goal: m.goal, backstory: m.backstory}
+	return &MockAgent{id: m.id, role: m.role, goal: m.goal, backstory: m.backstory, llm: m.llm}
 }
 
 func (m *MockAgent) GetEventBus() events.EventBus {
@@ -157,6 +221,7 @@ type MockTask struct {
 	expectedOutput string
 	humanInput     string
 	tools          []agent.Tool
+	dependsOn      []string
 }
 
 func (m *MockTask) GetID() string {
@@ -227,6 +292,14 @@ func (m *MockTask) SetAssignedAgent(agent agent.Agent) error {
 	return nil // Mock实现，不做实际存储
 }
 
+func (m *MockTask) GetAgentRole() string {
+	return ""
+}
+
+func (m *MockTask) SetAgentRole(role string) {
+	// Mock实现，不做实际存储
+}
+
 func (m *MockTask) IsAsyncExecution() bool {
 	return false // Mock Task默认同步执行
 }
@@ -255,6 +328,14 @@ func (m *MockTask) SetOutputFile(filename string) error {
 	return nil
 }
 
+func (m *MockTask) IsOutputFileAppend() bool {
+	return false
+}
+
+func (m *MockTask) SetOutputFileAppend(appendOutput bool) {
+	// Mock implementation
+}
+
 func (m *MockTask) GetCreateDirectory() bool {
 	return false
 }
@@ -279,6 +360,30 @@ func (m *MockTask) SetContextTasks(tasks []agent.Task) {
 	// Mock implementation
 }
 
+func (m *MockTask) GetOutputSchema() *agent.OutputSchema {
+	return nil
+}
+
+func (m *MockTask) SetOutputSchema(schema *agent.OutputSchema) {
+	// Mock implementation
+}
+
+func (m *MockTask) GetDependsOn() []string {
+	return m.dependsOn
+}
+
+func (m *MockTask) SetDependsOn(taskIDs []string) {
+	m.dependsOn = taskIDs
+}
+
+func (m *MockTask) IsCacheDisabled() bool {
+	return false
+}
+
+func (m *MockTask) SetCacheDisabled(disabled bool) {
+	// Mock implementation
+}
+
 func (m *MockTask) GetRetryCount() int {
 	return 0
 }
@@ -311,6 +416,22 @@ func (m *MockTask) SetMarkdownOutput(markdown bool) {
 	// Mock implementation
 }
 
+func (m *MockTask) IsApprovalRequired() bool {
+	return false
+}
+
+func (m *MockTask) SetApprovalRequired(required bool) {
+	// Mock implementation
+}
+
+func (m *MockTask) AddImage(source string) error {
+	return nil
+}
+
+func (m *MockTask) GetImages() []llm.ContentPart {
+	return nil
+}
+
 func TestNewBaseCrew(t *testing.T) {
 	logger := logger.NewTestLogger()
 	eventBus := events.NewEventBus(logger)
@@ -632,6 +753,109 @@ func TestBaseCrew_Clone(t *testing.T) {
 	}
 }
 
+// 两个副本同时执行时不共享Agent、工具计数、统计和任务
+func TestBaseCrew_CloneIsolatesConcurrentKickoffs(t *testing.T) {
+	logger := logger.NewTestLogger()
+	original := NewBaseCrew(nil, events.NewEventBus(logger), logger)
+
+	lookup := agent.NewBaseTool("lookup", "Look something up", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "found", nil
+	})
+	newAgent := func(role string, tools ...agent.Tool) *agent.BaseAgent {
+		a, err := agent.NewBaseAgent(agent.AgentConfig{
+			Role:      role,
+			Goal:      "Do the work",
+			Backstory: "Reliable teammate",
+			LLM:       NewMockLLM(),
+			Tools:     tools,
+			Logger:    logger,
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		return a
+	}
+	researcher := newAgent("Researcher", lookup)
+	writer := newAgent("Writer")
+	original.AddAgent(researcher)
+	original.AddAgent(writer)
+
+	research := agent.NewTaskWithOptions("Research {topic}", "Notes", agent.WithAssignedAgent(researcher))
+	write := agent.NewTaskWithOptions("Write about {topic}", "Article", agent.WithAssignedAgent(writer))
+	write.SetContextTasks([]agent.Task{research})
+	write.SetDependsOn([]string{research.GetID()})
+	original.AddTask(research)
+	original.AddTask(write)
+	lookup.Execute(context.Background(), nil)
+
+	clones := make([]*BaseCrew, 2)
+	for i := range clones {
+		clone, err := original.Clone()
+		if err != nil {
+			t.Fatalf("failed to clone crew: %v", err)
+		}
+		clones[i] = clone.(*BaseCrew)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(clones))
+	for i, clone := range clones {
+		wg.Add(1)
+		go func(i int, clone *BaseCrew) {
+			defer wg.Done()
+			_, errs[i] = clone.Kickoff(context.Background(), map[string]interface{}{"topic": []string{"Go", "Rust"}[i]})
+		}(i, clone)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{researcher.GetID(): true, writer.GetID(): true}
+	for i, clone := range clones {
+		if errs[i] != nil {
+			t.Fatalf("clone %d kickoff failed: %v", i, errs[i])
+		}
+
+		agents := clone.GetAgents()
+		for _, a := range agents {
+			if seen[a.GetID()] {
+				t.Errorf("clone %d shares agent %s", i, a.GetRole())
+			}
+			seen[a.GetID()] = true
+			if executions := a.GetExecutionStats().TotalExecutions; executions != 1 {
+				t.Errorf("clone %d agent %s: expected 1 execution, got %d", i, a.GetRole(), executions)
+			}
+		}
+		if tool := agents[0].GetTools()[0]; tool == agent.Tool(lookup) || tool.GetUsageCount() != 0 {
+			t.Errorf("clone %d should have its own tool with a fresh usage count", i)
+		}
+
+		tasks := clone.GetTasks()
+		if tasks[0] == agent.Task(research) || tasks[1] == agent.Task(write) {
+			t.Fatalf("clone %d shares tasks with the original crew", i)
+		}
+		if tasks[0].GetAssignedAgent() != agents[0] || tasks[1].GetAssignedAgent() != agents[1] {
+			t.Errorf("clone %d tasks should be assigned to the cloned agents", i)
+		}
+		if deps := tasks[1].GetDependsOn(); len(deps) != 1 || deps[0] != tasks[0].GetID() {
+			t.Errorf("clone %d dependencies should point at the cloned task, got %v", i, deps)
+		}
+		if contextTasks := tasks[1].GetContextTasks(); len(contextTasks) != 1 || contextTasks[0] != tasks[0] {
+			t.Errorf("clone %d context tasks should point at the cloned task", i)
+		}
+	}
+
+	for _, a := range []agent.Agent{researcher, writer} {
+		if executions := a.GetExecutionStats().TotalExecutions; executions != 0 {
+			t.Errorf("original agent %s should not have executed, got %d", a.GetRole(), executions)
+		}
+	}
+	if lookup.GetUsageCount() != 1 {
+		t.Errorf("original tool usage should be unaffected, got %d", lookup.GetUsageCount())
+	}
+	if research.GetDescription() != "Research {topic}" || write.GetDescription() != "Write about {topic}" {
+		t.Errorf("original task descriptions should be unaffected, got %q and %q", research.GetDescription(), write.GetDescription())
+	}
+}
+
 func TestBaseCrew_Callbacks(t *testing.T) {
 	logger := logger.NewTestLogger()
 	eventBus := events.NewEventBus(logger)
@@ -723,3 +947,736 @@ func TestBaseCrew_UsageMetrics(t *testing.T) {
 		t.Error("expected token usage in result")
 	}
 }
+
+func TestCrewRateLimiting(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	var mutex sync.Mutex
+	var rateLimited []*CrewRateLimitedEvent
+	eventBus.Subscribe("crew_rate_limited", func(ctx context.Context, event events.Event) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if e, ok := event.(*CrewRateLimitedEvent); ok {
+			rateLimited = append(rateLimited, e)
+		}
+		return nil
+	})
+
+	config := DefaultCrewConfig()
+	config.MaxRPM = 1200
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Worker",
+		Goal:      "Work",
+		Backstory: "Works",
+		LLM:       NewMockLLM("done"),
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(worker)
+	crew.AddTask(agent.NewTaskWithOptions("Task", "Output"))
+
+	// 耗尽令牌桶，使下一次LLM调用被限流
+	for i := 0; i < 1200; i++ {
+		if err := crew.rpmController.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected wait error: %v", err)
+		}
+	}
+
+	// 副本共享同一速率控制器
+	clone, _ := crew.Clone()
+	if clone.(*BaseCrew).rpmController != crew.rpmController {
+		t.Error("clone should share the rate limiter")
+	}
+
+	if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	if worker.GetRPMController() != crew.rpmController {
+		t.Error("agent should use the crew rate limiter")
+	}
+
+	metrics := crew.GetUsageMetrics()
+	if metrics.MaxRPM != 1200 || metrics.ThrottledRequests != 1 || metrics.RateLimitWait <= 0 {
+		t.Errorf("unexpected rate limit metrics: %+v", metrics)
+	}
+	if metrics.RPMUtilization <= 1.0 {
+		t.Errorf("expected utilization above 1.0, got %f", metrics.RPMUtilization)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mutex.Lock()
+		count := len(rateLimited)
+		mutex.Unlock()
+		if count > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(rateLimited) != 1 {
+		t.Fatalf("expected one crew_rate_limited event, got %d", len(rateLimited))
+	}
+	if rateLimited[0].MaxRPM != 1200 || rateLimited[0].Wait <= 0 {
+		t.Errorf("unexpected event: %+v", rateLimited[0])
+	}
+}
+
+func TestCrewUnlimitedRPM(t *testing.T) {
+	config := DefaultCrewConfig()
+	config.MaxRPM = 0
+	crew := NewBaseCrew(config, events.NewEventBus(logger.NewTestLogger()), logger.NewTestLogger())
+
+	if crew.rpmController != nil {
+		t.Error("MaxRPM of 0 should not create a rate limiter")
+	}
+	if metrics := crew.GetUsageMetrics(); metrics.MaxRPM != 0 || metrics.RPMUtilization != 0 {
+		t.Errorf("unexpected rate limit metrics: %+v", metrics)
+	}
+}
+
+func TestCrewResponseCache(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.CacheEnabled = true
+	config.Cache = llm.NewLRUCache(10, 0)
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	mockLLM := NewMockLLM("cached answer", "fresh answer")
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Worker",
+		Goal:      "Work",
+		Backstory: "Works",
+		LLM:       mockLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(worker)
+	crew.AddTask(agent.NewTaskWithOptions("Task", "Output"))
+
+	// 第一次执行未命中缓存并调用LLM，第二次命中缓存
+	usage := &UsageMetrics{}
+	for i := 0; i < 2; i++ {
+		result, err := crew.Kickoff(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("crew execution failed: %v", err)
+		}
+		if result.Raw != "cached answer" {
+			t.Errorf("unexpected output: %q", result.Raw)
+		}
+		usage.Add(result.TokenUsage)
+	}
+
+	if mockLLM.CallCount() != 1 {
+		t.Errorf("expected one LLM call, got %d", mockLLM.CallCount())
+	}
+	if metrics := crew.GetUsageMetrics(); metrics.CacheHits != 1 || metrics.CacheMisses != 0 || metrics.LLMCalls != 0 {
+		t.Errorf("expected the last kickoff to hit the cache, got %+v", metrics)
+	}
+	if usage.CacheHits != 1 || usage.CacheMisses != 1 || usage.LLMCalls != 1 || usage.Runs != 2 {
+		t.Errorf("expected 1 hit, 1 miss and 1 LLM call over two kickoffs, got %+v", usage)
+	}
+
+	// 关闭缓存后移除注入的缓存
+	crew.SetCacheEnabled(false)
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	if result.Raw != "fresh answer" {
+		t.Errorf("expected fresh LLM output, got %q", result.Raw)
+	}
+	if worker.GetResponseCache() != nil {
+		t.Error("expected crew cache to be removed from agent")
+	}
+	if mockLLM.CallCount() != 2 {
+		t.Errorf("expected LLM to be called with cache disabled, got %d calls", mockLLM.CallCount())
+	}
+}
+
+func TestCrewToolResultCache(t *testing.T) {
+	toolCall := `{"tool_name": "web_search", "arguments": {"query": "go generics"}}`
+
+	for _, persist := range []bool{false, true} {
+		logger := logger.NewTestLogger()
+		config := DefaultCrewConfig()
+		config.PersistToolCache = persist
+		crew := NewBaseCrew(config, events.NewEventBus(logger), logger)
+
+		calls := 0
+		search := agent.NewBaseToolWithSchema("web_search", "Search the web",
+			agent.NewToolSchema("", "", agent.ToolParameter{Name: "query", Type: "string", Required: true}),
+			func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				calls++
+				return "generics landed in Go 1.18", nil
+			},
+		).WithCache(time.Minute)
+
+		worker, err := agent.NewBaseAgent(agent.AgentConfig{
+			Role:      "Researcher",
+			Goal:      "Find facts",
+			Backstory: "Careful analyst",
+			LLM:       NewMockLLM(toolCall, toolCall, "Notes", toolCall, "Notes again"),
+			Tools:     []agent.Tool{search},
+			Logger:    logger,
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		crew.AddAgent(worker)
+		crew.AddTask(agent.NewTaskWithOptions("Research Go generics", "Notes"))
+
+		for i := 0; i < 2; i++ {
+			if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+				t.Fatalf("crew execution failed: %v", err)
+			}
+		}
+
+		// 同一次Kickoff内重复调用命中缓存；未持久化时每次Kickoff前清空缓存
+		expectedCalls, expectedHits := 2, 1
+		if persist {
+			expectedCalls, expectedHits = 1, 2
+		}
+		if calls != expectedCalls || search.GetUsageCount() != expectedCalls {
+			t.Errorf("persist=%v: expected %d real invocations, got %d (usage count %d)", persist, expectedCalls, calls, search.GetUsageCount())
+		}
+		if search.GetCacheHits() != expectedHits {
+			t.Errorf("persist=%v: expected %d cache hits, got %d", persist, expectedHits, search.GetCacheHits())
+		}
+	}
+}
+
+func TestCrewTrainingBypassesResponseCache(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.CacheEnabled = true
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	mockLLM := NewMockLLM("first", "second", "third")
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Worker",
+		Goal:      "Work",
+		Backstory: "Works",
+		LLM:       mockLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(worker)
+	crew.AddTask(agent.NewTaskWithOptions("Task", "Output"))
+
+	if err := crew.TrainWithConfig(context.Background(), &TrainingConfig{Iterations: 3}); err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+	if mockLLM.CallCount() != 3 {
+		t.Errorf("expected every training iteration to call the LLM, got %d calls", mockLLM.CallCount())
+	}
+}
+
+func TestDefaultCrewConfigCacheDisabled(t *testing.T) {
+	if DefaultCrewConfig().CacheEnabled {
+		t.Error("response cache should be opt-in")
+	}
+}
+
+func TestCrewUsageMetricsAggregation(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.CacheEnabled = false
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	newAgent := func(role string, responses ...string) agent.Agent {
+		a, err := agent.NewBaseAgent(agent.AgentConfig{
+			Role:      role,
+			Goal:      "Work",
+			Backstory: "Works",
+			LLM:       NewMockLLM(responses...),
+			Logger:    logger,
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		return a
+	}
+	researcher := newAgent("Researcher", "findings", "more findings")
+	writer := newAgent("Writer", "draft", "final")
+	crew.AddAgent(researcher)
+	crew.AddAgent(writer)
+	crew.AddTask(agent.NewTaskWithOptions("Research", "Findings", agent.WithAssignedAgent(researcher)))
+	crew.AddTask(agent.NewTaskWithOptions("Write", "Article", agent.WithAssignedAgent(writer)))
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	// MockLLM: 5个prompt tokens + 回复长度的completion tokens，每次调用成本0.001
+	metrics := crew.GetUsageMetrics()
+	if metrics.PromptTokens != 10 || metrics.CompletionTokens != 13 || metrics.TotalTokens != 23 {
+		t.Errorf("unexpected token totals: prompt=%d completion=%d total=%d",
+			metrics.PromptTokens, metrics.CompletionTokens, metrics.TotalTokens)
+	}
+	if math.Abs(metrics.TotalCost-0.002) > 1e-9 {
+		t.Errorf("expected total cost 0.002, got %f", metrics.TotalCost)
+	}
+	if result.TokenUsage.TotalTokens != metrics.TotalTokens {
+		t.Errorf("expected crew output usage to match metrics, got %d", result.TokenUsage.TotalTokens)
+	}
+
+	research := metrics.AgentUsage["Researcher"]
+	if research.Tasks != 1 || research.TotalTokens != 13 || research.CompletionTokens != 8 {
+		t.Errorf("unexpected researcher usage: %+v", research)
+	}
+	if write := metrics.AgentUsage["Writer"]; write.Tasks != 1 || write.TotalTokens != 10 {
+		t.Errorf("unexpected writer usage: %+v", write)
+	}
+
+	// 返回的是副本，修改不影响Crew内部统计
+	metrics.AgentUsage["Writer"] = AgentUsage{}
+	if crew.GetUsageMetrics().AgentUsage["Writer"].TotalTokens != 10 {
+		t.Error("expected GetUsageMetrics to return a deep copy")
+	}
+
+	// KickoffForEach 跨输入累加，包括按Agent的统计
+	if _, err := crew.KickoffForEach(context.Background(), []map[string]interface{}{{}, {}}); err != nil {
+		t.Fatalf("kickoff for each failed: %v", err)
+	}
+	total := crew.GetUsageMetrics()
+	if total.SuccessfulTasks != 4 || total.AgentUsage["Researcher"].Tasks != 2 || total.AgentUsage["Writer"].Tasks != 2 {
+		t.Errorf("unexpected aggregated usage: %+v", total)
+	}
+	if total.TotalTokens != total.AgentUsage["Researcher"].TotalTokens+total.AgentUsage["Writer"].TotalTokens {
+		t.Errorf("agent breakdown does not sum to total: %+v", total)
+	}
+}
+
+func TestCrewCloseFlushesEventSinks(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	path := filepath.Join(t.TempDir(), "crew_events.jsonl")
+	sink, err := events.NewJSONLFileSink(path)
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	defer sink.Close()
+	if err := eventBus.AttachSink(sink); err != nil {
+		t.Fatalf("failed to attach sink: %v", err)
+	}
+
+	crew := NewBaseCrew(nil, eventBus, logger)
+	crew.AddAgent(&MockAgent{id: "agent1", role: "developer", goal: "write code", backstory: "experienced developer"})
+	crew.AddTask(&MockTask{id: "task1", description: "implement feature", expectedOutput: "working code"})
+	if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	crewID := crew.GetID()
+
+	if err := crew.Close(); err != nil {
+		t.Fatalf("failed to close crew: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open event log: %v", err)
+	}
+	defer file.Close()
+
+	var kickoffEvents int
+	err = events.Replay(file, func(ctx context.Context, event events.Event) error {
+		if event.GetType() == "crew_kickoff_started" || event.GetType() == "crew_kickoff_completed" {
+			kickoffEvents++
+			if event.GetSource() != crewID {
+				t.Errorf("expected crew ID as source, got %v", event.GetSource())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if kickoffEvents != 2 {
+		t.Errorf("expected kickoff events to be flushed on Close, got %d", kickoffEvents)
+	}
+}
+
+// fixedFeedbackCollector 每次迭代返回相同的人工反馈
+type fixedFeedbackCollector struct {
+	feedback training.HumanFeedback
+}
+
+func (f *fixedFeedbackCollector) CollectFeedback(ctx context.Context, iterationID string, outputs interface{}, timeout time.Duration) (*training.HumanFeedback, error) {
+	feedback := f.feedback
+	feedback.IterationID = iterationID
+	return &feedback, nil
+}
+
+func newTrainingTestCrew(t *testing.T, role string, workerLLM llm.LLM) (*BaseCrew, agent.Agent) {
+	t.Helper()
+	logger := logger.NewTestLogger()
+	crew := NewBaseCrew(nil, events.NewEventBus(logger), logger)
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      role,
+		Goal:      "Write reports",
+		Backstory: "Writes",
+		LLM:       workerLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(worker)
+	crew.AddTask(agent.NewTaskWithOptions("Write a report", "Report", agent.WithAssignedAgent(worker)))
+	return crew, worker
+}
+
+func TestCrewTrainAndLoadTraining(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "trained.json")
+
+	trainCrew, _ := newTrainingTestCrew(t, "Worker", NewMockLLM("report one", "report two"))
+	summaryLLM := NewMockLLM(`{"suggestions": ["Always cite sources"], "quality": 6.5, "final_summary": "Missing sources"}`)
+	err := trainCrew.TrainWithConfig(context.Background(), &TrainingConfig{
+		Iterations:        2,
+		Filename:          filename,
+		CollectFeedback:   true,
+		AutoSave:          true,
+		FeedbackCollector: &fixedFeedbackCollector{feedback: training.HumanFeedback{QualityScore: 6, Suggestions: "cite sources"}},
+		InstructionLLM:    summaryLLM,
+	})
+	if err != nil {
+		t.Fatalf("training failed: %v", err)
+	}
+
+	data, err := training.ReadTrainingData(filename)
+	if err != nil {
+		t.Fatalf("failed to read training data: %v", err)
+	}
+	trained := data.TrainedAgents["Worker"]
+	if trained == nil || len(trained.Suggestions) != 1 || trained.Quality != 6.5 {
+		t.Fatalf("unexpected trained agents: %+v", data.TrainedAgents)
+	}
+
+	// 新的Crew加载训练文件后，指令出现在发给LLM的系统提示中
+	recordingLLM := NewMockLLM("trained report")
+	crew, worker := newTrainingTestCrew(t, "Worker", recordingLLM)
+	if err := crew.LoadTraining(filename); err != nil {
+		t.Fatalf("failed to load training: %v", err)
+	}
+	if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	if len(worker.GetTrainedInstructions()) != 1 || recordingLLM.CallCount() == 0 {
+		t.Fatalf("expected trained instructions to be applied, got %v", worker.GetTrainedInstructions())
+	}
+	prompt := recordingLLM.Calls()[0].SystemPrompt()
+	if !strings.Contains(prompt, "Lessons from previous training") || !strings.Contains(prompt, "- Always cite sources") {
+		t.Errorf("expected trained instructions in system prompt, got:\n%s", prompt)
+	}
+}
+
+func TestCrewLoadTrainingRunsUntrained(t *testing.T) {
+	dir := t.TempDir()
+	mismatched := filepath.Join(dir, "mismatched.json")
+	content := `{"session_id": "s1", "trained_agents": {"Reviewer": {"suggestions": ["Be strict"]}}}`
+	if err := os.WriteFile(mismatched, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	crew, worker := newTrainingTestCrew(t, "Worker", NewMockLLM("report"))
+	for _, filename := range []string{filepath.Join(dir, "missing.json"), mismatched} {
+		if err := crew.LoadTraining(filename); err != nil {
+			t.Errorf("expected %s to be skipped, got %v", filepath.Base(filename), err)
+		}
+	}
+	if len(worker.GetTrainedInstructions()) != 0 {
+		t.Errorf("expected agent to stay untrained, got %v", worker.GetTrainedInstructions())
+	}
+
+	if err := crew.LoadTraining(invalid); err == nil {
+		t.Error("expected error for invalid training file")
+	}
+}
+
+func TestCrewOutputDirResolvesTaskOutputFiles(t *testing.T) {
+	logger := logger.NewTestLogger()
+	dir := t.TempDir()
+
+	config := DefaultCrewConfig()
+	config.Name = "research"
+	config.OutputDir = dir
+	crew := NewBaseCrew(config, events.NewEventBus(logger), logger)
+
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Worker",
+		Goal:      "Write reports",
+		Backstory: "Writes",
+		LLM:       NewMockLLM("final report"),
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	task := agent.NewTaskWithOptions("Write a report", "Report", agent.WithOutputFile("out/{crew_name}_{task_name}.md"))
+	task.SetName("report")
+	crew.AddAgent(worker)
+	crew.AddTask(task)
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	path := filepath.Join(dir, "out", "research_report.md")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected output file to be written: %v", err)
+	}
+	if string(content) != "final report" {
+		t.Errorf("unexpected output file content: %q", content)
+	}
+	if got := result.TasksOutput[0].Metadata[agent.MetadataKeyOutputFile]; got != path {
+		t.Errorf("expected output file in metadata, got %v", got)
+	}
+}
+
+func TestCrewFingerprintPropagation(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	var mu sync.Mutex
+	received := make(map[string]events.Event)
+	for _, eventType := range []string{"crew_kickoff_started", "task_execution_started", "agent_execution_started"} {
+		_, err := eventBus.SubscribeWithOptions(eventType, func(ctx context.Context, event events.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received[event.GetType()] = event
+			return nil
+		}, events.WithSyncDelivery())
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+	}
+
+	config := DefaultCrewConfig()
+	config.FingerprintSeed = "research-crew"
+	crew := NewBaseCrew(config, eventBus, logger)
+	if NewBaseCrew(config, eventBus, logger).GetFingerprint().GetUUID() != crew.GetFingerprint().GetUUID() {
+		t.Fatal("expected crews with the same seed to share a fingerprint")
+	}
+
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Researcher",
+		Goal:      "Find facts",
+		Backstory: "Careful analyst",
+		LLM:       NewMockLLM("facts"),
+		EventBus:  eventBus,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(worker)
+	crew.AddTask(agent.NewBaseTask("Research Go", "Facts"))
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	crewFingerprint := crew.GetFingerprint().GetUUID()
+	agentFingerprint := worker.GetFingerprint().GetUUID()
+	if result.Fingerprint != crewFingerprint {
+		t.Errorf("expected crew output fingerprint %s, got %s", crewFingerprint, result.Fingerprint)
+	}
+	metadata := result.TasksOutput[0].Metadata
+	if metadata["fingerprint"] != agentFingerprint || metadata["crew_fingerprint"] != crewFingerprint {
+		t.Errorf("expected agent and crew fingerprints in task output metadata, got %v", metadata)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if event := received["crew_kickoff_started"]; event == nil || event.GetSourceFingerprint() != crewFingerprint {
+		t.Errorf("expected crew fingerprint on kickoff event, got %+v", event)
+	}
+	taskEvent := received["task_execution_started"]
+	if taskEvent == nil || taskEvent.GetPayload()["crew_fingerprint"] != crewFingerprint ||
+		taskEvent.GetPayload()["agent_fingerprint"] != agentFingerprint {
+		t.Errorf("expected both fingerprints on task event, got %+v", taskEvent)
+	}
+	agentEvent := received["agent_execution_started"]
+	if agentEvent == nil || agentEvent.GetSourceFingerprint() != agentFingerprint ||
+		agentEvent.GetPayload()["crew_fingerprint"] != crewFingerprint {
+		t.Errorf("expected both fingerprints on agent event, got %+v", agentEvent)
+	}
+}
+
+// tickingClock 每次读取前进一天，用于确认一次Kickoff只读取一次时钟
+type tickingClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.AddDate(0, 0, 1)
+	return now
+}
+
+func TestCrewContextInjection(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	model := llmtest.NewScriptedLLM(llmtest.Replies("Sources", "Report")...)
+
+	newAgent := func(role string) agent.Agent {
+		a, err := agent.NewBaseAgent(agent.AgentConfig{
+			Role: role, Goal: "Report", Backstory: "Writes reports", LLM: model, EventBus: eventBus, Logger: log,
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		return a
+	}
+	researcher, writer := newAgent("Researcher"), newAgent("Writer")
+	// Crew的设置优先于Agent自身的设置
+	config := writer.GetExecutionConfig()
+	config.ContextInjection = agent.ContextInjection{InjectDate: true, Language: "English"}
+	if err := writer.SetExecutionConfig(config); err != nil {
+		t.Fatalf("failed to set execution config: %v", err)
+	}
+
+	clock := &tickingClock{now: time.Date(2025, 6, 30, 23, 0, 0, 0, time.UTC)}
+	c := NewBaseCrew(&CrewConfig{
+		Name: "report-crew",
+		ContextInjection: agent.ContextInjection{
+			InjectDate: true,
+			DateFormat: "2006-01-02 15:04",
+			Timezone:   "Asia/Tokyo",
+			Language:   "日本語",
+			Clock:      clock,
+		},
+	}, eventBus, log)
+	c.AddAgent(researcher)
+	c.AddAgent(writer)
+	c.AddTask(agent.NewTaskWithOptions("Collect sources", "Sources", agent.WithAssignedAgent(researcher)))
+	c.AddTask(agent.NewTaskWithOptions("Write the report", "A report", agent.WithAssignedAgent(writer)))
+
+	output, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	model.Verify(t)
+
+	want := "Current date and time: 2025-07-01 08:00\nWrite your answers in 日本語.\n\n"
+	for i, call := range model.Calls() {
+		if system := call.SystemPrompt(); !strings.HasPrefix(system, want) {
+			t.Errorf("call %d: expected the crew context at the start of the system prompt, got %q", i, system)
+		}
+	}
+	for _, taskOutput := range output.TasksOutput {
+		injected, _ := taskOutput.Metadata[agent.InjectedContextMetadataKey].(map[string]string)
+		if injected["date"] != "2025-07-01 08:00" || injected["timezone"] != "Asia/Tokyo" || injected["language"] != "日本語" {
+			t.Errorf("unexpected injected context metadata: %v", taskOutput.Metadata[agent.InjectedContextMetadataKey])
+		}
+	}
+}
+
+func TestCrewModeration(t *testing.T) {
+	denylist, err := agent.NewDenylistModerator(
+		agent.ModerationRule{Category: "violence", Terms: []string{"attack"}},
+		agent.ModerationRule{Category: "finance", Terms: []string{"stocks"}, Action: agent.ModerationFlag},
+	)
+	if err != nil {
+		t.Fatalf("failed to create moderator: %v", err)
+	}
+	model := llmtest.NewScriptedLLM(llmtest.Reply{Content: "Stocks rose today"})
+	c := newCrewToolTestCrew(t, "news", "Reporter", model)
+	c.moderation = agent.DefaultModerationConfig(denylist)
+
+	output, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	model.Verify(t)
+	annotations, _ := output.TasksOutput[0].Metadata[agent.ModerationMetadataKey].([]agent.ModerationAnnotation)
+	if len(annotations) != 1 || annotations[0].Stage != agent.ModerationStageOutput || annotations[0].Categories[0] != "finance" {
+		t.Errorf("expected the flagged output to be annotated, got %v", output.TasksOutput[0].Metadata[agent.ModerationMetadataKey])
+	}
+
+	// 被拦截的输入不会发送给LLM
+	blocked := llmtest.NewScriptedLLM()
+	c = newCrewToolTestCrew(t, "news", "Reporter", blocked)
+	c.moderation = agent.DefaultModerationConfig(denylist)
+	if _, err := c.Kickoff(context.Background(), map[string]interface{}{"topic": "plan an attack"}); !errors.Is(err, agent.ErrContentModerated) {
+		t.Errorf("expected the kickoff to be blocked by moderation, got %v", err)
+	}
+	if blocked.CallCount() != 0 {
+		t.Errorf("expected no LLM calls, got %d", blocked.CallCount())
+	}
+}
+
+func TestCrewReproducibility(t *testing.T) {
+	seed := 42
+	model := llmtest.NewScriptedLLM(
+		llmtest.Reply{Content: "Sources", Fingerprint: "fp_a"},
+		llmtest.Reply{Content: "Report", Fingerprint: "fp_a"},
+	)
+	c := newCrewToolTestCrew(t, "report", "Writer", model)
+	c.AddTask(agent.NewTaskWithOptions("Write the report", "A report", agent.WithAssignedAgent(c.GetAgents()[0])))
+	c.randomSeed = &seed
+	c.strictReproducibility = true
+
+	output, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	model.Verify(t)
+
+	if output.Metadata["random_seed"] != 42 {
+		t.Errorf("expected the crew seed in the output metadata, got %v", output.Metadata["random_seed"])
+	}
+	for i, call := range model.Calls() {
+		want := agent.DeriveSeed(seed, i)
+		if call.Options.Seed == nil || *call.Options.Seed != want {
+			t.Errorf("call %d: expected seed %d, got %v", i, want, call.Options.Seed)
+		}
+		if call.Options.Temperature == nil || *call.Options.Temperature != 0 {
+			t.Errorf("call %d: expected temperature 0 in strict mode, got %v", i, call.Options.Temperature)
+		}
+		taskOutput := output.TasksOutput[i]
+		if taskOutput.Metadata[agent.SeedMetadataKey] != want || taskOutput.Metadata[agent.SystemFingerprintMetadataKey] != "fp_a" {
+			t.Errorf("task %d: unexpected reproducibility metadata %v", i, taskOutput.Metadata)
+		}
+	}
+
+	// 严格模式下没有设置种子时使用0
+	model = llmtest.NewScriptedLLM(llmtest.Replies("Sources")...)
+	c = newCrewToolTestCrew(t, "report", "Writer", model)
+	c.strictReproducibility = true
+	if output, err = c.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	if seed := model.Calls()[0].Options.Seed; seed == nil || *seed != agent.DeriveSeed(0, 0) || output.Metadata["random_seed"] != 0 {
+		t.Errorf("expected seeds derived from 0, got %v", seed)
+	}
+}
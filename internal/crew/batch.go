@@ -0,0 +1,180 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// BatchErrorPolicy 批量执行中某组输入失败时的处理策略
+type BatchErrorPolicy string
+
+const (
+	// BatchFailFast 第一组输入失败后取消正在执行的输入，不再开始新的输入
+	BatchFailFast BatchErrorPolicy = "fail_fast"
+	// BatchCollectAll 失败只记录在对应的结果中，其余输入继续执行
+	BatchCollectAll BatchErrorPolicy = "collect_all"
+)
+
+// ErrBatchAborted 快速失败时未开始执行的输入的错误
+var ErrBatchAborted = errors.New("batch aborted before the input started")
+
+// KickoffForEachOptions KickoffForEachConcurrent的选项
+type KickoffForEachOptions struct {
+	MaxConcurrency int              // 同时执行的输入数，<=0表示不限制
+	Timeout        time.Duration    // 单组输入的超时时间，<=0表示不限制
+	ErrorPolicy    BatchErrorPolicy // 为空时使用BatchCollectAll
+}
+
+// DefaultKickoffForEachOptions 返回默认的批量执行选项
+func DefaultKickoffForEachOptions() KickoffForEachOptions {
+	return KickoffForEachOptions{
+		MaxConcurrency: 4,
+		ErrorPolicy:    BatchCollectAll,
+	}
+}
+
+// CrewRunResult 一组输入的执行结果
+// 执行失败时Output仍可能包含已完成任务的部分输出
+type CrewRunResult struct {
+	Index    int           `json:"index"` // 输入在列表中的序号
+	Output   *CrewOutput   `json:"output,omitempty"`
+	Error    error         `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// KickoffForEachConcurrent 用工作池为每组输入执行Crew的副本，结果按输入顺序返回
+// 分发在工作者空闲时才进行，执行较慢时不会堆积已开始的输入。每组输入结束时发出batch_item_completed事件，
+// 各组的使用统计汇总后作为Crew的使用统计。有输入失败时返回错误：快速失败时为第一个失败，否则汇总所有失败
+func (c *BaseCrew) KickoffForEachConcurrent(ctx context.Context, inputsList []map[string]interface{}, opts KickoffForEachOptions) ([]CrewRunResult, error) {
+	total := len(inputsList)
+	results := make([]CrewRunResult, total)
+	for i := range results {
+		results[i].Index = i
+	}
+	if total == 0 {
+		return results, nil
+	}
+
+	workers := opts.MaxConcurrency
+	if workers <= 0 || workers > total {
+		workers = total
+	}
+	failFast := opts.ErrorPolicy == BatchFailFast
+
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// UsageMetrics并发安全，各工作者直接累加
+	usage := &UsageMetrics{}
+	var (
+		mu        sync.Mutex
+		remaining = total
+		firstErr  error
+		wg        sync.WaitGroup
+	)
+	jobs := make(chan int)
+	started := make([]bool, total)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				// 取消后分发的输入不再开始
+				if batchCtx.Err() != nil {
+					continue
+				}
+				started[index] = true
+				result := c.runBatchItem(batchCtx, index, total, inputsList[index], opts.Timeout)
+				results[index] = result
+				if result.Output != nil {
+					usage.Add(result.Output.TokenUsage)
+				}
+
+				mu.Lock()
+				remaining--
+				left := remaining
+				if result.Error != nil && failFast && firstErr == nil {
+					firstErr = fmt.Errorf("execution failed for input %d: %w", index, result.Error)
+					cancel()
+				}
+				mu.Unlock()
+
+				c.eventBus.Emit(ctx, c, NewBatchItemCompletedEvent(c.name, index, left, total, result.Duration, result.Error))
+			}
+		}()
+	}
+
+dispatch:
+	for i := range inputsList {
+		select {
+		case jobs <- i:
+		case <-batchCtx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var errs []error
+	for i := range results {
+		if !started[i] {
+			results[i].Error = ErrBatchAborted
+			if ctx.Err() != nil {
+				results[i].Error = ctx.Err()
+			}
+		}
+		if results[i].Error != nil {
+			errs = append(errs, fmt.Errorf("execution failed for input %d: %w", i, results[i].Error))
+		}
+	}
+
+	c.mu.Lock()
+	c.usageMetrics = usage
+	c.mu.Unlock()
+
+	if failFast && firstErr != nil {
+		return results, firstErr
+	}
+	return results, errors.Join(errs...)
+}
+
+// runBatchItem 克隆Crew并执行一组输入，避免各组输入之间的状态冲突
+func (c *BaseCrew) runBatchItem(ctx context.Context, index, total int, inputs map[string]interface{}, timeout time.Duration) CrewRunResult {
+	c.logger.Info("executing crew for input set",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "input_index", Value: index},
+		logger.Field{Key: "total_inputs", Value: total},
+	)
+
+	result := CrewRunResult{Index: index}
+	start := time.Now()
+
+	crewCopy, err := c.Clone()
+	if err != nil {
+		result.Error = fmt.Errorf("failed to clone crew: %w", err)
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result.Output, result.Error = crewCopy.Kickoff(ctx, inputs)
+	result.Duration = time.Since(start)
+	if result.Error != nil {
+		c.logger.Error("crew execution failed for input set",
+			logger.Field{Key: "input_index", Value: index},
+			logger.Field{Key: "error", Value: result.Error},
+		)
+	}
+	return result
+}
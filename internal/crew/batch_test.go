@@ -0,0 +1,185 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// batchLLM 按提示上下文中的topic输入回复："fail"返回错误，"slow"一直等到ctx取消，并记录同时进行的调用数
+type batchLLM struct {
+	*llmtest.ScriptedLLM
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (l *batchLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	l.mu.Lock()
+	l.inFlight++
+	l.maxInFlight = max(l.maxInFlight, l.inFlight)
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.inFlight--
+		l.mu.Unlock()
+	}()
+
+	prompt := llmtest.Call{Messages: messages}.LastMessage()
+	switch {
+	case strings.Contains(prompt, "- topic: fail"):
+		return nil, errors.New("model unavailable")
+	case strings.Contains(prompt, "- topic: slow"):
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	time.Sleep(10 * time.Millisecond)
+	start := strings.Index(prompt, "- topic: ")
+	topic := strings.Fields(prompt[start+len("- topic: "):])[0]
+	return &llm.Response{Content: "notes on " + topic, FinishReason: "stop"}, nil
+}
+
+func newBatchTestCrew(t *testing.T) (*BaseCrew, *batchLLM, events.EventBus) {
+	t.Helper()
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	model := &batchLLM{ScriptedLLM: llmtest.NewScriptedLLM()}
+	researcher, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: "Researcher", Goal: "Research", Backstory: "Researches", LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	c := NewBaseCrew(&CrewConfig{Name: "batch-crew"}, eventBus, log)
+	c.AddAgent(researcher)
+	c.AddTask(agent.NewTaskWithOptions("Research the topic", "Notes",
+		agent.WithName("research"), agent.WithAssignedAgent(researcher)))
+	return c, model, eventBus
+}
+
+func batchInputs(topics ...string) []map[string]interface{} {
+	inputs := make([]map[string]interface{}, len(topics))
+	for i, topic := range topics {
+		inputs[i] = map[string]interface{}{"topic": topic}
+	}
+	return inputs
+}
+
+func TestKickoffForEachConcurrentCollectAll(t *testing.T) {
+	c, model, eventBus := newBatchTestCrew(t)
+
+	var mu sync.Mutex
+	var completed []*BatchItemCompletedEvent
+	subscription, err := eventBus.SubscribeWithOptions("batch_item_completed", func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		completed = append(completed, event.(*BatchItemCompletedEvent))
+		return nil
+	}, events.WithSyncDelivery())
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer subscription.Unsubscribe()
+
+	opts := DefaultKickoffForEachOptions()
+	opts.MaxConcurrency = 2
+	results, err := c.KickoffForEachConcurrent(context.Background(), batchInputs("go", "rust", "fail", "zig", "java"), opts)
+	if err == nil || !strings.Contains(err.Error(), "execution failed for input 2") {
+		t.Fatalf("expected the failure of input 2 to be reported, got %v", err)
+	}
+
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	for i, topic := range []string{"go", "rust", "", "zig", "java"} {
+		result := results[i]
+		if result.Index != i || result.Duration <= 0 {
+			t.Errorf("unexpected result %d: %+v", i, result)
+		}
+		if topic == "" {
+			if result.Error == nil {
+				t.Errorf("expected input %d to fail", i)
+			}
+			continue
+		}
+		if result.Error != nil || result.Output == nil || result.Output.Raw != "notes on "+topic {
+			t.Errorf("expected input %d to produce notes on %s, got %+v", i, topic, result)
+		}
+	}
+
+	if model.maxInFlight > 2 {
+		t.Errorf("expected at most 2 concurrent runs, got %d", model.maxInFlight)
+	}
+	if usage := c.GetUsageMetrics(); usage.SuccessfulTasks != 4 {
+		t.Errorf("expected usage of the 4 successful runs, got %+v", usage)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var indexes, remaining []int
+	for _, event := range completed {
+		indexes = append(indexes, event.Index)
+		remaining = append(remaining, event.Remaining)
+		if event.Total != 5 || event.Success != (event.Index != 2) {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	}
+	sort.Ints(indexes)
+	sort.Ints(remaining)
+	if fmt.Sprint(indexes) != "[0 1 2 3 4]" || fmt.Sprint(remaining) != "[0 1 2 3 4]" {
+		t.Errorf("expected one event per input with decreasing remaining count, got %v %v", indexes, remaining)
+	}
+}
+
+func TestKickoffForEachConcurrentFailFast(t *testing.T) {
+	c, _, _ := newBatchTestCrew(t)
+
+	results, err := c.KickoffForEachConcurrent(context.Background(), batchInputs("go", "fail", "zig"),
+		KickoffForEachOptions{MaxConcurrency: 1, ErrorPolicy: BatchFailFast})
+	if err == nil || !strings.Contains(err.Error(), "execution failed for input 1") {
+		t.Fatalf("expected the first failure, got %v", err)
+	}
+	if results[0].Error != nil || results[0].Output.Raw != "notes on go" {
+		t.Errorf("completed work must be kept: %+v", results[0])
+	}
+	if !errors.Is(results[2].Error, ErrBatchAborted) || results[2].Output != nil {
+		t.Errorf("expected input 2 not to start, got %+v", results[2])
+	}
+
+	// KickoffForEach保持顺序执行、遇错停止的行为
+	outputs, err := c.KickoffForEach(context.Background(), batchInputs("go", "fail", "zig"))
+	if err == nil || len(outputs) != 1 || outputs[0].Raw != "notes on go" {
+		t.Errorf("expected one output before the failure, got %d outputs, %v", len(outputs), err)
+	}
+}
+
+func TestKickoffForEachConcurrentTimeout(t *testing.T) {
+	c, _, _ := newBatchTestCrew(t)
+
+	start := time.Now()
+	results, err := c.KickoffForEachConcurrent(context.Background(), batchInputs("slow", "go"),
+		KickoffForEachOptions{Timeout: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected the slow input to time out")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("timeout was not applied, took %v", time.Since(start))
+	}
+	if results[0].Error == nil {
+		t.Errorf("expected slow input to fail, got %+v", results[0])
+	}
+	if results[1].Error != nil || results[1].Output.Raw != "notes on go" {
+		t.Errorf("timeout of one input must not affect others: %+v", results[1])
+	}
+}
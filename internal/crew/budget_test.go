@@ -0,0 +1,100 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newBudgetTestCrew(t *testing.T, config *CrewConfig, model llm.LLM) *BaseCrew {
+	t.Helper()
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	researcher, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: "Researcher", Goal: "Research", Backstory: "Researches", LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	search := agent.NewBaseTool("search", "Search the web", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "results", nil
+	})
+	if err := researcher.AddTool(search); err != nil {
+		t.Fatalf("failed to add tool: %v", err)
+	}
+
+	c := NewBaseCrew(config, eventBus, log)
+	c.AddAgent(researcher)
+	c.AddTask(agent.NewTaskWithOptions("Collect sources", "Sources", agent.WithName("collect"), agent.WithAssignedAgent(researcher)))
+	c.AddTask(agent.NewTaskWithOptions("Write the report", "A report", agent.WithName("report"), agent.WithAssignedAgent(researcher)))
+	return c
+}
+
+func searchCall() llm.ToolCall {
+	return llm.ToolCall{ID: "call_1", Type: "function", Function: llm.ToolCallFunction{Name: "search", Arguments: "{}"}}
+}
+
+func TestCrewTokenBudget(t *testing.T) {
+	usage := llm.Usage{TotalTokens: 100, Cost: 0.01}
+	model := llmtest.NewScriptedLLM(
+		llmtest.Reply{ToolCalls: []llm.ToolCall{searchCall()}, Usage: usage},
+		llmtest.Reply{Content: "Three sources", Usage: usage},
+		// 第二个任务开始时预算已经用完，请求的工具不会执行
+		llmtest.Reply{ToolCalls: []llm.ToolCall{searchCall()}, Usage: usage},
+		llmtest.Reply{Content: "Report from the sources", Usage: usage},
+	).WithFunctionCalling(true)
+
+	c := newBudgetTestCrew(t, &CrewConfig{Name: "budget-crew", MaxTotalTokens: 200}, model)
+	output, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("soft budget must not fail the kickoff: %v", err)
+	}
+	model.Verify(t)
+
+	report := output.TasksOutput[1]
+	if report.Raw != "Report from the sources" {
+		t.Errorf("unexpected report: %q", report.Raw)
+	}
+	if hit := fmt.Sprint(report.Metadata[agent.BudgetLimitsHitMetadataKey]); hit != "[max_total_tokens]" {
+		t.Errorf("expected the token budget to be recorded, got %s", hit)
+	}
+	if _, ok := output.TasksOutput[0].Metadata[agent.BudgetLimitsHitMetadataKey]; ok {
+		t.Errorf("first task finished within the budget: %v", output.TasksOutput[0].Metadata)
+	}
+	if calls := model.Calls(); len(calls[3].Options.Tools) != 0 {
+		t.Errorf("final call after the budget ran out must not offer tools")
+	}
+}
+
+func TestCrewHardCostCeiling(t *testing.T) {
+	usage := llm.Usage{TotalTokens: 100, Cost: 0.02}
+	model := llmtest.NewScriptedLLM(
+		llmtest.Reply{Content: "Three sources", Usage: usage},
+		llmtest.Reply{Content: "Report", Usage: usage},
+	)
+
+	c := newBudgetTestCrew(t, &CrewConfig{Name: "budget-crew", HardCostCeilingUSD: 0.03}, model)
+	_, err := c.Kickoff(context.Background(), nil)
+
+	var exceeded *agent.BudgetExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("expected a BudgetExceededError, got %v", err)
+	}
+	if exceeded.SpentTokens != 200 || exceeded.SpentUSD < 0.039 || exceeded.CeilingUSD != 0.03 {
+		t.Errorf("unexpected spend: %+v", exceeded)
+	}
+
+	// 每次Kickoff的预算重新计算
+	c = newBudgetTestCrew(t, &CrewConfig{Name: "budget-crew", HardCostCeilingUSD: 0.05},
+		llmtest.NewScriptedLLM(llmtest.Reply{Content: "Three sources", Usage: usage}, llmtest.Reply{Content: "Report", Usage: usage}))
+	if _, err := c.Kickoff(context.Background(), nil); err != nil {
+		t.Errorf("kickoff within the ceiling failed: %v", err)
+	}
+}
@@ -157,6 +157,11 @@ func HierarchicalCrew(name string, agents []agent.Agent, tasks []agent.Task) (Cr
 	return QuickCrew(name, ProcessHierarchical, agents, tasks)
 }
 
+// ParallelCrew 创建并行执行的crew
+func ParallelCrew(name string, agents []agent.Agent, tasks []agent.Task) (Crew, error) {
+	return QuickCrew(name, ProcessParallel, agents, tasks)
+}
+
 // VerboseCrew 创建详细输出模式的crew
 func VerboseCrew(name string, process Process, agents []agent.Agent, tasks []agent.Task) (Crew, error) {
 	builder := CrewBuilderWithDefaults().
@@ -0,0 +1,22 @@
+package crew
+
+import (
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// 未配置缓存时默认内存LRU缓存的容量和过期时间
+const (
+	defaultCacheCapacity = 1000
+	defaultCacheTTL      = time.Hour
+)
+
+// newResponseCache 返回Crew注入Agent的响应缓存，cache为nil时使用默认的内存LRU缓存
+// 命中与未命中次数由Agent按任务记录在TaskOutput.LLMStats中，汇总到UsageMetrics
+func newResponseCache(cache Cache) Cache {
+	if cache == nil {
+		cache = llm.NewLRUCache(defaultCacheCapacity, defaultCacheTTL)
+	}
+	return cache
+}
@@ -0,0 +1,389 @@
+package crew
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// ConsensusJudgeTaskName 由管理器Agent评审时评审任务的名称
+const ConsensusJudgeTaskName = "consensus_judge"
+
+// 评审结果记录在正式输出Metadata中的键
+const (
+	ConsensusCandidatesMetadataKey = "candidates"            // 值为[]ConsensusCandidate
+	consensusJudgeMetadataKey      = "consensus_judge"       // 评审者：JudgeLLM的模型或管理器Agent的角色
+	consensusMergedMetadataKey     = "consensus_merged"      // 为true时正式输出是评审合并的答案
+	consensusJudgeErrorMetadataKey = "consensus_judge_error" // 评审失败时退回第一个成功的候选
+)
+
+// DefaultConsensusRubric 评审提示的默认模板，模板数据为ConsensusRubricData
+// 回复的JSON格式说明由Crew追加在模板之后，自定义模板只需描述评分标准
+const DefaultConsensusRubric = `You are judging several candidate answers to the same task.
+
+Task:
+{{.Task}}
+
+Expected output:
+{{.ExpectedOutput}}
+{{range .Candidates}}
+## Candidate {{.Index}} (by {{.Agent}})
+{{.Raw}}
+{{end}}
+Score every candidate from 0 to 10 for correctness, completeness and how closely it matches the expected output.`
+
+// consensusResponseFormat 追加在评审提示之后的回复格式说明
+const consensusResponseFormat = `
+
+Respond with only a JSON object in this format:
+{"scores": [{"candidate": <candidate number>, "score": <0-10>, "reason": "<one sentence>"}], "winner": <number of the best candidate>, "merged": "<a combined answer only when merging candidates is clearly better than any single one, otherwise an empty string>"}`
+
+// ConsensusCandidate Consensus流程中一个Agent对任务的候选输出及其评分
+type ConsensusCandidate struct {
+	Index      int     `json:"index"` // 从1开始的候选编号，评审按编号打分
+	Agent      string  `json:"agent"`
+	Raw        string  `json:"raw,omitempty"`
+	Score      float64 `json:"score"`
+	Reason     string  `json:"reason,omitempty"`
+	Selected   bool    `json:"selected"`        // 是否被选为正式输出（合并时为评审指定的最佳候选）
+	Error      string  `json:"error,omitempty"` // 执行失败的原因，失败的候选不参与评审
+	TokensUsed int     `json:"tokens_used"`
+	Cost       float64 `json:"cost"`
+}
+
+// ConsensusRubricData 评审提示模板的数据
+type ConsensusRubricData struct {
+	Task           string
+	ExpectedOutput string
+	Candidates     []ConsensusCandidate // 只包含执行成功的候选
+}
+
+// consensusVerdict 评审的JSON回复
+type consensusVerdict struct {
+	Scores []struct {
+		Candidate int     `json:"candidate"`
+		Score     float64 `json:"score"`
+		Reason    string  `json:"reason"`
+	} `json:"scores"`
+	Winner int    `json:"winner"`
+	Merged string `json:"merged"`
+}
+
+// consensusCandidateTask 候选执行看到的任务，不写输出文件，正式输出确定后由Crew写入
+type consensusCandidateTask struct {
+	agent.Task
+}
+
+func (t consensusCandidateTask) GetOutputFile() string {
+	return ""
+}
+
+// runConsensusProcess 执行共识流程
+// 任务按与Sequential相同的依赖规则执行，每个任务由多个Agent并发执行，
+// 评审（JudgeLLM，未设置时为管理器Agent）按评分标准打分后选出或合并出正式输出
+func (c *BaseCrew) runConsensusProcess(ctx context.Context, inputs map[string]interface{}) (*CrewOutput, error) {
+	c.logger.Info("starting consensus process execution",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "tasks_count", Value: len(c.tasks)},
+		logger.Field{Key: "agents_count", Value: len(c.agents)},
+		logger.Field{Key: "agents_per_task", Value: c.consensusAgentsPerTask},
+		logger.Field{Key: "has_judge_llm", Value: c.judgeLLM != nil},
+	)
+
+	// 没有JudgeLLM时由管理器Agent评审
+	if c.judgeLLM == nil {
+		if err := c.createManagerAgent(); err != nil {
+			return nil, fmt.Errorf("consensus process requires a judge LLM, a manager agent or a manager LLM: %w", err)
+		}
+		c.configureAgents()
+	}
+
+	return c.executeTasks(ctx, c.tasks, inputs)
+}
+
+// consensusAgents 返回执行任务的候选Agent：lead在前，其余按加入Crew的顺序，数量受ConsensusAgentsPerTask限制
+func (c *BaseCrew) consensusAgents(lead agent.Agent) []agent.Agent {
+	candidates := []agent.Agent{lead}
+	for _, member := range c.agents {
+		if member != lead && member != c.managerAgent {
+			candidates = append(candidates, member)
+		}
+	}
+	if limit := c.consensusAgentsPerTask; limit > 0 && limit < len(candidates) {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// executeConsensusRound 让候选Agent并发执行任务并评审，返回正式输出
+// 只要有一个候选成功就不会失败；正式输出的token和成本包含所有候选和评审的开销
+func (c *BaseCrew) executeConsensusRound(ctx context.Context, task agent.Task, index int, lead agent.Agent) (*agent.TaskOutput, error) {
+	members := c.consensusAgents(lead)
+	start := time.Now()
+
+	outputs := make([]*agent.TaskOutput, len(members))
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func(i int, member agent.Agent) {
+			defer wg.Done()
+			outputs[i], errs[i] = member.Execute(ctx, consensusCandidateTask{task})
+			if errs[i] == nil && outputs[i] == nil {
+				errs[i] = fmt.Errorf("agent returned no output")
+			}
+		}(i, member)
+	}
+	wg.Wait()
+
+	candidates := make([]ConsensusCandidate, len(members))
+	var succeeded []ConsensusCandidate
+	var failures []error
+	for i, member := range members {
+		candidates[i] = ConsensusCandidate{Index: i + 1, Agent: member.GetRole()}
+		if errs[i] != nil {
+			candidates[i].Error = errs[i].Error()
+			failures = append(failures, fmt.Errorf("%s: %w", member.GetRole(), errs[i]))
+			c.logger.Warn("consensus candidate failed",
+				logger.Field{Key: "task_index", Value: index},
+				logger.Field{Key: "agent_role", Value: member.GetRole()},
+				logger.Field{Key: "error", Value: errs[i]},
+			)
+			continue
+		}
+		candidates[i].Raw = outputs[i].Raw
+		candidates[i].TokensUsed = outputs[i].TokensUsed
+		candidates[i].Cost = outputs[i].Cost
+		succeeded = append(succeeded, candidates[i])
+	}
+	if len(succeeded) == 0 {
+		return nil, fmt.Errorf("all %d consensus candidates failed: %w", len(members), errors.Join(failures...))
+	}
+
+	// 只有一个候选成功时无需评审
+	winner := succeeded[0].Index
+	var verdict *consensusVerdict
+	var judgeUsage *agent.TaskOutput
+	var judgeErr error
+	judgeName := ""
+	if len(succeeded) > 1 {
+		judgeName = c.consensusJudgeName()
+		verdict, judgeUsage, judgeErr = c.judgeCandidates(ctx, task, succeeded)
+		if judgeErr != nil {
+			c.logger.Warn("consensus judge failed, using the first successful candidate",
+				logger.Field{Key: "task_index", Value: index},
+				logger.Field{Key: "judge", Value: judgeName},
+				logger.Field{Key: "error", Value: judgeErr},
+			)
+			verdict = nil
+		}
+	}
+	if verdict != nil {
+		applyConsensusVerdict(candidates, verdict)
+		winner = selectConsensusWinner(candidates, verdict.Winner)
+	}
+	candidates[winner-1].Selected = true
+
+	official := *outputs[winner-1]
+	official.Metadata = make(map[string]interface{}, len(outputs[winner-1].Metadata)+4)
+	for key, value := range outputs[winner-1].Metadata {
+		official.Metadata[key] = value
+	}
+	if verdict != nil && strings.TrimSpace(verdict.Merged) != "" {
+		mergeConsensusOutput(&official, task, verdict.Merged)
+		official.Metadata[consensusMergedMetadataKey] = true
+	}
+
+	// 正式输出的开销包含所有候选和评审
+	official.TokensUsed, official.PromptTokens, official.CompletionTokens, official.Cost = 0, 0, 0, 0
+	official.LLMStats = agent.LLMCallStats{}
+	for _, output := range append(outputs, judgeUsage) {
+		addConsensusUsage(&official, output)
+	}
+	official.ExecutionTime = time.Since(start)
+	official.Metadata[ConsensusCandidatesMetadataKey] = candidates
+	if judgeName != "" {
+		official.Metadata[consensusJudgeMetadataKey] = judgeName
+	}
+	if judgeErr != nil {
+		official.Metadata[consensusJudgeErrorMetadataKey] = judgeErr.Error()
+	}
+
+	if err := agent.WriteOutputFile(task, &official); err != nil {
+		c.logger.Warn("failed to write consensus output file",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+
+	c.eventBus.Emit(ctx, c, NewConsensusRoundCompletedEvent(c.name, index, task.GetID(), len(members), len(succeeded),
+		candidates[winner-1].Agent, official.Metadata[consensusMergedMetadataKey] == true))
+	c.logger.Info("consensus round completed",
+		logger.Field{Key: "task_index", Value: index},
+		logger.Field{Key: "candidates", Value: len(members)},
+		logger.Field{Key: "succeeded", Value: len(succeeded)},
+		logger.Field{Key: "winner", Value: candidates[winner-1].Agent},
+		logger.Field{Key: "tokens_used", Value: official.TokensUsed},
+	)
+
+	return &official, nil
+}
+
+// consensusJudgeName 返回评审者的名称
+func (c *BaseCrew) consensusJudgeName() string {
+	if c.judgeLLM != nil {
+		return c.judgeLLM.GetModel()
+	}
+	if c.managerAgent != nil {
+		return c.managerAgent.GetRole()
+	}
+	return ""
+}
+
+// judgeCandidates 让评审给成功的候选打分，返回评审结果和评审的开销
+func (c *BaseCrew) judgeCandidates(ctx context.Context, task agent.Task, candidates []ConsensusCandidate) (*consensusVerdict, *agent.TaskOutput, error) {
+	prompt, err := c.consensusPrompt(task, candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var content string
+	var usage *agent.TaskOutput
+	switch {
+	case c.judgeLLM != nil:
+		response, err := c.judgeLLM.Call(ctx, []llm.Message{
+			{Role: llm.RoleSystem, Content: "You are an impartial judge comparing answers produced by different agents."},
+			{Role: llm.RoleUser, Content: prompt},
+		}, &llm.CallOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to call judge LLM: %w", err)
+		}
+		content = response.Content
+		usage = &agent.TaskOutput{
+			TokensUsed:       response.Usage.TotalTokens,
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			Cost:             response.Usage.Cost,
+			LLMStats:         agent.LLMCallStats{Calls: 1},
+		}
+	case c.managerAgent != nil:
+		judgeTask := agent.NewTaskWithOptions(prompt,
+			"A JSON object with a score for every candidate and the number of the best candidate",
+			agent.WithName(ConsensusJudgeTaskName),
+			agent.WithAssignedAgent(c.managerAgent),
+		)
+		output, err := c.managerAgent.Execute(ctx, judgeTask)
+		if err != nil {
+			return nil, nil, fmt.Errorf("manager agent failed to judge candidates: %w", err)
+		}
+		content = output.Raw
+		usage = output
+	default:
+		return nil, nil, fmt.Errorf("consensus process requires a judge LLM or a manager agent")
+	}
+
+	var verdict consensusVerdict
+	if err := json.Unmarshal([]byte(extractJSONObject(content)), &verdict); err != nil {
+		return nil, usage, fmt.Errorf("invalid judge response: %w", err)
+	}
+	return &verdict, usage, nil
+}
+
+// consensusPrompt 用评分标准模板渲染评审提示，并追加回复格式说明
+func (c *BaseCrew) consensusPrompt(task agent.Task, candidates []ConsensusCandidate) (string, error) {
+	rubric := c.consensusRubric
+	if rubric == "" {
+		rubric = DefaultConsensusRubric
+	}
+	tmpl, err := template.New("consensus_rubric").Parse(rubric)
+	if err != nil {
+		return "", fmt.Errorf("invalid consensus rubric: %w", err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, ConsensusRubricData{
+		Task:           task.GetDescription(),
+		ExpectedOutput: task.GetExpectedOutput(),
+		Candidates:     candidates,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render consensus rubric: %w", err)
+	}
+	b.WriteString(consensusResponseFormat)
+	return b.String(), nil
+}
+
+// applyConsensusVerdict 把评审的分数和理由记录到对应编号的候选上，失败的候选不计分
+func applyConsensusVerdict(candidates []ConsensusCandidate, verdict *consensusVerdict) {
+	for _, score := range verdict.Scores {
+		i := score.Candidate - 1
+		if i < 0 || i >= len(candidates) || candidates[i].Error != "" {
+			continue
+		}
+		candidates[i].Score = score.Score
+		candidates[i].Reason = score.Reason
+	}
+}
+
+// selectConsensusWinner 返回评审选出的候选编号；编号无效时选分数最高的成功候选，同分取编号小的
+func selectConsensusWinner(candidates []ConsensusCandidate, winner int) int {
+	if winner >= 1 && winner <= len(candidates) && candidates[winner-1].Error == "" {
+		return winner
+	}
+	best := 0
+	for i, candidate := range candidates {
+		if candidate.Error != "" {
+			continue
+		}
+		if best == 0 || candidate.Score > candidates[best-1].Score {
+			best = i + 1
+		}
+	}
+	return best
+}
+
+// mergeConsensusOutput 用评审合并的答案替换正式输出的内容
+func mergeConsensusOutput(output *agent.TaskOutput, task agent.Task, merged string) {
+	output.Raw = merged
+	output.JSON, output.Parsed, output.Pydantic = nil, nil, nil
+	output.Citations = nil
+	if task.GetOutputFormat() == agent.OutputFormatJSON {
+		var value map[string]interface{}
+		if err := json.Unmarshal([]byte(extractJSONObject(merged)), &value); err == nil {
+			output.JSON = value
+		}
+	}
+
+	words := strings.Fields(merged)
+	if len(words) > 15 {
+		output.Summary = strings.Join(words[:15], " ") + "..."
+	} else {
+		output.Summary = merged
+	}
+}
+
+// addConsensusUsage 把一次候选执行或评审的开销计入正式输出
+func addConsensusUsage(total *agent.TaskOutput, output *agent.TaskOutput) {
+	if output == nil {
+		return
+	}
+	total.TokensUsed += output.TokensUsed
+	total.PromptTokens += output.PromptTokens
+	total.CompletionTokens += output.CompletionTokens
+	total.Cost += output.Cost
+	total.LLMStats.Calls += output.LLMStats.Calls
+	total.LLMStats.Retries += output.LLMStats.Retries
+	total.LLMStats.CacheHits += output.LLMStats.CacheHits
+	total.LLMStats.CacheMisses += output.LLMStats.CacheMisses
+	total.LLMStats.ThrottledRequests += output.LLMStats.ThrottledRequests
+	total.LLMStats.RateLimitWait += output.LLMStats.RateLimitWait
+}
@@ -0,0 +1,220 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newConsensusTestAgent(t *testing.T, role string, model llm.LLM, eventBus events.EventBus, log logger.Logger) agent.Agent {
+	t.Helper()
+	a, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: role, Goal: "Answer the question", Backstory: "Careful",
+		LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent %s: %v", role, err)
+	}
+	return a
+}
+
+func scriptedReply(content string, tokens int) llmtest.Reply {
+	return llmtest.Reply{Content: content, Usage: llm.Usage{PromptTokens: tokens - 5, CompletionTokens: 5, TotalTokens: tokens}}
+}
+
+func TestConsensusProcessSelectsJudgedWinner(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	judge := llmtest.NewScriptedLLM(scriptedReply(
+		"Here is my verdict:\n"+`{"scores": [{"candidate": 1, "score": 4, "reason": "vague"}, {"candidate": 3, "score": 9, "reason": "precise"}], "winner": 3, "merged": ""}`, 30))
+
+	c := NewBaseCrew(&CrewConfig{Name: "consensus-crew", Process: ProcessConsensus, JudgeLLM: judge}, eventBus, log)
+	first := newConsensusTestAgent(t, "First", llmtest.NewScriptedLLM(scriptedReply("Paris, probably", 10)), eventBus, log)
+	broken := newConsensusTestAgent(t, "Broken", llmtest.NewScriptedLLM(llmtest.Reply{Err: errors.New("model unavailable")}), eventBus, log)
+	third := newConsensusTestAgent(t, "Third", llmtest.NewScriptedLLM(scriptedReply("Paris is the capital of France.", 20)), eventBus, log)
+	for _, a := range []agent.Agent{first, broken, third} {
+		c.AddAgent(a)
+	}
+	c.AddTask(agent.NewTaskWithOptions("What is the capital of France?", "The capital city",
+		agent.WithName("capital"), agent.WithAssignedAgent(first)))
+
+	rounds := make(chan *ConsensusRoundCompletedEvent, 1)
+	eventBus.Subscribe("consensus_round_completed", func(ctx context.Context, event events.Event) error {
+		if round, ok := event.(*ConsensusRoundCompletedEvent); ok {
+			rounds <- round
+		}
+		return nil
+	})
+
+	result, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+
+	output := result.TasksOutput[0]
+	if output.Raw != "Paris is the capital of France." || output.Agent != "Third" {
+		t.Errorf("expected the judged winner as the official output, got %q by %s", output.Raw, output.Agent)
+	}
+	candidates, ok := output.Metadata[ConsensusCandidatesMetadataKey].([]ConsensusCandidate)
+	if !ok || len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates in metadata, got %#v", output.Metadata[ConsensusCandidatesMetadataKey])
+	}
+	if candidates[0].Score != 4 || candidates[2].Score != 9 || !candidates[2].Selected || candidates[0].Selected {
+		t.Errorf("unexpected candidate scores: %+v", candidates)
+	}
+	if candidates[1].Error == "" || candidates[1].Raw != "" {
+		t.Errorf("expected the broken candidate to record its error: %+v", candidates[1])
+	}
+	if output.Metadata[consensusJudgeMetadataKey] != judge.GetModel() {
+		t.Errorf("expected the judge model in metadata, got %v", output.Metadata[consensusJudgeMetadataKey])
+	}
+
+	// 所有候选和评审的开销都计入任务和Crew的统计
+	if output.TokensUsed != 60 || output.CompletionTokens != 15 {
+		t.Errorf("expected 60 tokens (10 + 20 + judge 30), got %d (completion %d)", output.TokensUsed, output.CompletionTokens)
+	}
+	if result.TokenUsage.TotalTokens != 60 {
+		t.Errorf("expected crew usage to include all candidate runs, got %d", result.TokenUsage.TotalTokens)
+	}
+
+	prompt := judge.Calls()[0].UserPrompt()
+	for _, expected := range []string{"What is the capital of France?", "## Candidate 1 (by First)", "## Candidate 3 (by Third)", `"winner"`} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("expected %q in the judge prompt:\n%s", expected, prompt)
+		}
+	}
+	if strings.Contains(prompt, "Candidate 2") {
+		t.Errorf("failed candidates must not be judged:\n%s", prompt)
+	}
+
+	select {
+	case round := <-rounds:
+		if round.Winner != "Third" || round.Succeeded != 2 || round.Candidates != 3 {
+			t.Errorf("unexpected consensus event: %+v", round)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a consensus_round_completed event")
+	}
+}
+
+func TestConsensusProcessMergesAndWritesOutputFileOnce(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	judge := llmtest.NewScriptedLLM(llmtest.Replies(
+		`{"scores": [{"candidate": 1, "score": 7}, {"candidate": 2, "score": 8}], "winner": 2, "merged": "Go has goroutines and channels."}`)...)
+	rubric := "Prefer the shortest answer to: {{.Task}}{{range .Candidates}}\n[{{.Index}}] {{.Raw}}{{end}}"
+
+	dir := t.TempDir()
+	c := NewBaseCrew(&CrewConfig{
+		Name: "merge-crew", Process: ProcessConsensus, JudgeLLM: judge,
+		ConsensusAgentsPerTask: 2, ConsensusRubric: rubric, OutputDir: dir,
+	}, eventBus, log)
+	unused := llmtest.NewScriptedLLM()
+	for _, a := range []agent.Agent{
+		newConsensusTestAgent(t, "A", llmtest.NewScriptedLLM(llmtest.Replies("Go has goroutines.")...), eventBus, log),
+		newConsensusTestAgent(t, "B", llmtest.NewScriptedLLM(llmtest.Replies("Go has channels.")...), eventBus, log),
+		newConsensusTestAgent(t, "C", unused, eventBus, log),
+	} {
+		c.AddAgent(a)
+	}
+	task := agent.NewTaskWithOptions("Describe Go concurrency", "One sentence", agent.WithName("describe"))
+	if err := task.SetOutputFile("answer.md"); err != nil {
+		t.Fatalf("failed to set output file: %v", err)
+	}
+	task.SetOutputFileAppend(true)
+	c.AddTask(task)
+
+	result, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+
+	if unused.CallCount() != 0 {
+		t.Errorf("ConsensusAgentsPerTask must limit the candidates, third agent was called %d times", unused.CallCount())
+	}
+	prompt := judge.Calls()[0].UserPrompt()
+	if !strings.HasPrefix(prompt, "Prefer the shortest answer to: Describe Go concurrency\n[1] Go has goroutines.\n[2] Go has channels.") {
+		t.Errorf("expected the custom rubric in the judge prompt:\n%s", prompt)
+	}
+
+	output := result.TasksOutput[0]
+	if output.Raw != "Go has goroutines and channels." || output.Metadata[consensusMergedMetadataKey] != true {
+		t.Errorf("expected the merged answer, got %q (%v)", output.Raw, output.Metadata)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "answer.md"))
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if string(content) != "Go has goroutines and channels.\n" {
+		t.Errorf("expected only the official output in the file, got %q", content)
+	}
+}
+
+func TestConsensusJudgeFailureFallsBackToFirstCandidate(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	judge := llmtest.NewScriptedLLM(llmtest.Replies("I cannot decide")...)
+
+	c := NewBaseCrew(&CrewConfig{Name: "fallback-crew", Process: ProcessConsensus, JudgeLLM: judge}, eventBus, log)
+	c.AddAgent(newConsensusTestAgent(t, "A", llmtest.NewScriptedLLM(llmtest.Replies("first answer")...), eventBus, log))
+	c.AddAgent(newConsensusTestAgent(t, "B", llmtest.NewScriptedLLM(llmtest.Replies("second answer")...), eventBus, log))
+	c.AddTask(agent.NewTaskWithOptions("Answer", "An answer"))
+
+	result, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	output := result.TasksOutput[0]
+	if output.Raw != "first answer" {
+		t.Errorf("expected the first candidate when the judge fails, got %q", output.Raw)
+	}
+	if _, ok := output.Metadata[consensusJudgeErrorMetadataKey]; !ok {
+		t.Errorf("expected the judge error in metadata: %v", output.Metadata)
+	}
+}
+
+func TestConsensusProcessRequiresJudge(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	c := NewBaseCrew(&CrewConfig{Name: "no-judge", Process: ProcessConsensus}, eventBus, log)
+	c.AddAgent(newConsensusTestAgent(t, "A", llmtest.NewScriptedLLM(), eventBus, log))
+	c.AddTask(agent.NewTaskWithOptions("Answer", "An answer"))
+
+	if _, err := c.Kickoff(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "judge") {
+		t.Errorf("expected a missing judge error, got %v", err)
+	}
+	report, err := c.Validate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if report.Valid() || report.Issues[0].Code != IssueMissingJudge {
+		t.Errorf("expected a missing_judge issue, got %+v", report.Issues)
+	}
+}
+
+func TestSelectConsensusWinner(t *testing.T) {
+	candidates := []ConsensusCandidate{
+		{Index: 1, Score: 6},
+		{Index: 2, Score: 9, Error: "failed"},
+		{Index: 3, Score: 8},
+	}
+	if got := selectConsensusWinner(candidates, 1); got != 1 {
+		t.Errorf("expected the judge's winner, got %d", got)
+	}
+	if got := selectConsensusWinner(candidates, 2); got != 3 {
+		t.Errorf("a failed candidate cannot win, expected the best scored candidate, got %d", got)
+	}
+	if got := selectConsensusWinner(candidates, 7); got != 3 {
+		t.Errorf("expected the best scored candidate for an unknown winner, got %d", got)
+	}
+}
@@ -0,0 +1,290 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// CrewMemoryContextKey 之前执行留下的Crew记忆注入第一个任务上下文时使用的键
+const CrewMemoryContextKey = "crew_memories"
+
+// CrewMemoryType Crew记忆在长期记忆metadata中的memory_type
+const CrewMemoryType = "crew_memory"
+
+// DefaultMaxMemoryContextTokens 未设置MaxMemoryContextTokens时注入的Crew记忆的token上限
+const DefaultMaxMemoryContextTokens = 500
+
+const (
+	// crewMemorySearchLimit 每次Kickoff从长期记忆中取出的候选记忆数量
+	crewMemorySearchLimit = 20
+	// fallbackDistillTokens 提炼失败时保存的原始输出的token上限
+	fallbackDistillTokens = 200
+)
+
+// CrewMemoryStore 成功的Kickoff之后保存哪些任务输出
+type CrewMemoryStore string
+
+const (
+	// CrewMemoryStoreAllTasks 保存每个任务的输出
+	CrewMemoryStoreAllTasks CrewMemoryStore = "all_tasks"
+	// CrewMemoryStoreFinalOutput 只保存最后一个任务的输出
+	CrewMemoryStoreFinalOutput CrewMemoryStore = "final_output"
+)
+
+// crewMemorySettings Crew记忆的写回和检索设置
+type crewMemorySettings struct {
+	scope              string          // 记忆按此名称隔离，副本沿用原Crew的名称
+	store              CrewMemoryStore // 为空时保存所有任务
+	relevanceThreshold float64         // 查询词命中比例低于该值的记忆不注入
+	maxContextTokens   int             // <=0时使用DefaultMaxMemoryContextTokens
+}
+
+// kickoffMemory 本次Kickoff检索到的Crew记忆
+type kickoffMemory struct {
+	context string
+}
+
+type kickoffMemoryKey struct{}
+
+// kickoffMemoryFrom 返回ctx中本次Kickoff检索到的Crew记忆，没有时返回nil
+func kickoffMemoryFrom(ctx context.Context) *kickoffMemory {
+	recalled, _ := ctx.Value(kickoffMemoryKey{}).(*kickoffMemory)
+	return recalled
+}
+
+// startCrewMemory 开启记忆时按输入检索之前执行保存的Crew记忆，供第一个任务使用
+// 检索失败只记录警告，本次Kickoff不带记忆执行
+func (c *BaseCrew) startCrewMemory(ctx context.Context, inputs map[string]interface{}) context.Context {
+	c.mu.RLock()
+	enabled := c.memoryEnabled
+	settings := c.memorySettings
+	var firstTask agent.Task
+	if len(c.tasks) > 0 {
+		firstTask = c.tasks[0]
+	}
+	c.mu.RUnlock()
+	if !enabled {
+		return ctx
+	}
+
+	query := crewMemoryQuery(inputs, firstTask)
+	if query == "" {
+		return ctx
+	}
+	items, err := c.getMemoryManager().searchCrewMemories(ctx, settings.scope, query)
+	if err != nil {
+		c.logger.Warn("failed to retrieve crew memories, continuing without them",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "error", Value: err},
+		)
+		return ctx
+	}
+
+	recalled := renderCrewMemories(items, query, settings)
+	if recalled == "" {
+		return ctx
+	}
+	c.logger.Debug("crew memories recalled",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "candidates", Value: len(items)},
+	)
+	return context.WithValue(ctx, kickoffMemoryKey{}, &kickoffMemory{context: recalled})
+}
+
+// finishCrewMemory Kickoff成功后把任务输出提炼为简短的记忆保存到长期记忆
+// 使用第一个Agent的LLM提炼；单个任务保存失败只记录警告
+func (c *BaseCrew) finishCrewMemory(ctx context.Context, result *CrewOutput) {
+	c.mu.RLock()
+	enabled := c.memoryEnabled
+	settings := c.memorySettings
+	var distillLLM llm.LLM
+	for _, a := range c.agents {
+		if distillLLM = a.GetLLM(); distillLLM != nil {
+			break
+		}
+	}
+	c.mu.RUnlock()
+	if !enabled || result == nil || len(result.TasksOutput) == 0 {
+		return
+	}
+
+	outputs := result.TasksOutput
+	if settings.store == CrewMemoryStoreFinalOutput {
+		outputs = outputs[len(outputs)-1:]
+	}
+
+	// 执行超时或被取消时仍然保存本次的记忆
+	ctx = context.WithoutCancel(ctx)
+	memoryManager := c.getMemoryManager()
+	for _, output := range outputs {
+		if output == nil || strings.TrimSpace(output.Raw) == "" {
+			continue
+		}
+		distilled := distillTaskOutput(ctx, distillLLM, output)
+		if err := memoryManager.saveCrewMemory(ctx, settings.scope, output, distilled); err != nil {
+			c.logger.Warn("failed to save crew memory",
+				logger.Field{Key: "crew_name", Value: c.name},
+				logger.Field{Key: "agent_role", Value: output.Agent},
+				logger.Field{Key: "error", Value: err},
+			)
+		}
+	}
+}
+
+// distillTaskOutput 用LLM把任务输出提炼为以后执行可以参考的几句话，失败时截取原始输出的开头
+func distillTaskOutput(ctx context.Context, model llm.LLM, output *agent.TaskOutput) string {
+	if model != nil {
+		prompt := "Distill the task result below into a short memory for future runs of the same crew. " +
+			"Keep the facts, decisions and conclusions that would help with similar tasks; drop formatting and filler. " +
+			"Reply with the memory only, in at most three sentences.\n\n" +
+			"Task: " + output.Description + "\nAgent: " + output.Agent + "\n\nResult:\n" + output.Raw
+		response, err := model.Call(ctx, []llm.Message{{Role: llm.RoleUser, Content: prompt}}, nil)
+		if err == nil && strings.TrimSpace(response.Content) != "" {
+			return strings.TrimSpace(response.Content)
+		}
+	}
+
+	runes := []rune(strings.TrimSpace(output.Raw))
+	tokenizer := llm.HeuristicTokenizer{}
+	for len(runes) > 0 && tokenizer.CountTokens(string(runes)) > fallbackDistillTokens {
+		runes = runes[:len(runes)*3/4]
+	}
+	return string(runes)
+}
+
+// crewMemoryQuery 检索记忆使用的查询：按键排序的输入值，没有输入时使用第一个任务的描述
+func crewMemoryQuery(inputs map[string]interface{}, firstTask agent.Task) string {
+	keys := make([]string, 0, len(inputs))
+	for key := range inputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if value := strings.TrimSpace(fmt.Sprint(inputs[key])); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 && firstTask != nil {
+		return firstTask.GetDescription()
+	}
+	return strings.Join(values, " ")
+}
+
+// renderCrewMemories 按相关度排序并过滤候选记忆，在token上限内逐条列出
+func renderCrewMemories(items []memory.MemoryItem, query string, settings crewMemorySettings) string {
+	terms := memoryTerms(query)
+	if len(terms) == 0 {
+		return ""
+	}
+
+	type scoredMemory struct {
+		line      string
+		relevance float64
+	}
+	var scored []scoredMemory
+	for _, item := range items {
+		text := fmt.Sprint(item.Value)
+		description, _ := item.Metadata["task_description"].(string)
+		relevance := memoryRelevance(terms, text+" "+description)
+		if relevance == 0 || relevance < settings.relevanceThreshold {
+			continue
+		}
+		line := "- " + text
+		if item.Agent != "" {
+			line = fmt.Sprintf("- [%s] %s", item.Agent, text)
+		}
+		scored = append(scored, scoredMemory{line: line, relevance: relevance})
+	}
+	// 相关度相同时保持存储返回的顺序（较新的在前）
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].relevance > scored[j].relevance })
+
+	maxTokens := settings.maxContextTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxMemoryContextTokens
+	}
+	tokenizer := llm.HeuristicTokenizer{}
+	var lines []string
+	used := 0
+	for _, recalled := range scored {
+		tokens := tokenizer.CountTokens(recalled.line)
+		if used+tokens > maxTokens {
+			continue
+		}
+		used += tokens
+		lines = append(lines, recalled.line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n")
+}
+
+// memoryTerms 把文本拆分为去重后的小写关键词，忽略单个字符
+func memoryTerms(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r > 127)
+	})
+	seen := make(map[string]bool, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) < 2 || seen[field] {
+			continue
+		}
+		seen[field] = true
+		terms = append(terms, field)
+	}
+	return terms
+}
+
+// memoryRelevance 查询词在记忆文本中出现的比例
+func memoryRelevance(terms []string, text string) float64 {
+	text = strings.ToLower(text)
+	hits := 0
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(terms))
+}
+
+// crewMemoryFilter 检索和删除某个Crew的记忆时使用的过滤条件
+func crewMemoryFilter(scope string) map[string]interface{} {
+	return map[string]interface{}{"crew": scope, "memory_type": CrewMemoryType}
+}
+
+// saveCrewMemory 把提炼后的任务输出保存到长期记忆，按Crew名称隔离
+func (mm *MemoryManager) saveCrewMemory(ctx context.Context, scope string, output *agent.TaskOutput, distilled string) error {
+	if mm.longTermMemory == nil {
+		return fmt.Errorf("long-term memory not enabled")
+	}
+	metadata := crewMemoryFilter(scope)
+	metadata["task_description"] = output.Description
+	metadata["agent_role"] = output.Agent
+	return mm.longTermMemory.SaveCompatible(ctx, distilled, metadata, output.Agent)
+}
+
+// searchCrewMemories 按关键词检索某个Crew保存的记忆
+func (mm *MemoryManager) searchCrewMemories(ctx context.Context, scope, query string) ([]memory.MemoryItem, error) {
+	if mm.longTermMemory == nil {
+		return nil, fmt.Errorf("long-term memory not enabled")
+	}
+	return mm.longTermMemory.SearchWithFilter(ctx, query, crewMemoryFilter(scope), crewMemorySearchLimit)
+}
+
+// ClearCrewMemories 删除某个Crew保存的记忆，返回删除的数量
+func (mm *MemoryManager) ClearCrewMemories(ctx context.Context, crewName string) (int, error) {
+	if mm.longTermMemory == nil {
+		return 0, fmt.Errorf("long-term memory not enabled")
+	}
+	return mm.longTermMemory.DeleteWithFilter(ctx, crewMemoryFilter(crewName))
+}
@@ -0,0 +1,138 @@
+package crew
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newCrewMemoryTestCrew(t *testing.T, model *llmtest.ScriptedLLM, config *CrewConfig) *BaseCrew {
+	t.Helper()
+	t.Setenv(memory.StorageDirEnv, t.TempDir())
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	analyst, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: "Analyst", Goal: "Analyse markets", Backstory: "Experienced",
+		LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	config.Name = "market-crew"
+	config.MemoryEnabled = true
+	c := NewBaseCrew(config, eventBus, log)
+	t.Cleanup(func() { _ = c.Close() })
+	c.AddAgent(analyst)
+	c.AddTask(agent.NewTaskWithOptions("Research the EV market", "Findings", agent.WithAssignedAgent(analyst)))
+	c.AddTask(agent.NewTaskWithOptions("Summarise the EV findings", "A summary", agent.WithAssignedAgent(analyst)))
+	return c
+}
+
+func TestCrewMemoryIsRecalledInLaterKickoffs(t *testing.T) {
+	model := llmtest.NewScriptedLLM(llmtest.Replies(
+		"EV sales grew 30% in Europe", "Europe leads EV growth",
+		"EV market memory: European EV sales grew 30% last year.", "EV summary memory: Europe leads EV growth.",
+		"Findings", "Summary",
+	)...)
+	c := newCrewMemoryTestCrew(t, model, &CrewConfig{})
+	ctx := context.Background()
+
+	if _, err := c.Kickoff(ctx, map[string]interface{}{"topic": "EV"}); err != nil {
+		t.Fatalf("first kickoff failed: %v", err)
+	}
+	if strings.Contains(model.Prompts()[0], "Memories From Previous Runs:") {
+		t.Errorf("the first kickoff must not inject memories:\n%s", model.Prompts()[0])
+	}
+	if distill := model.Calls()[2].UserPrompt(); !strings.Contains(distill, "Research the EV market") || !strings.Contains(distill, "Agent: Analyst") {
+		t.Errorf("expected the task description and agent role in the distillation prompt:\n%s", distill)
+	}
+
+	// KickoffForEach的副本共享原Crew的记忆
+	results, err := c.KickoffForEach(ctx, []map[string]interface{}{{"topic": "EV"}})
+	if err != nil {
+		t.Fatalf("second kickoff failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %d", len(results))
+	}
+
+	first, second := model.Prompts()[4], model.Prompts()[5]
+	for _, expected := range []string{
+		"Memories From Previous Runs:",
+		"- [Analyst] EV market memory: European EV sales grew 30% last year.",
+		"- [Analyst] EV summary memory: Europe leads EV growth.",
+	} {
+		if !strings.Contains(first, expected) {
+			t.Errorf("expected %q in the first task's prompt:\n%s", expected, first)
+		}
+	}
+	if strings.Contains(second, "Memories From Previous Runs:") {
+		t.Errorf("memories must only be injected into the first task:\n%s", second)
+	}
+
+	// 第二次Kickoff的两条记忆同样保存在原Crew的名称下
+	count, err := c.getMemoryManager().longTermMemory.CountWithFilter(ctx, crewMemoryFilter("market-crew"))
+	if err != nil {
+		t.Fatalf("failed to count crew memories: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("expected 4 crew memories, got %d", count)
+	}
+
+	removed, err := c.getMemoryManager().ClearCrewMemories(ctx, "market-crew")
+	if err != nil || removed != 4 {
+		t.Errorf("expected 4 crew memories removed, got %d (%v)", removed, err)
+	}
+}
+
+func TestCrewMemoryStoreFinalOutputOnly(t *testing.T) {
+	model := llmtest.NewScriptedLLM(llmtest.Replies("Findings", "Summary", "Distilled summary")...)
+	c := newCrewMemoryTestCrew(t, model, &CrewConfig{MemoryStore: CrewMemoryStoreFinalOutput})
+
+	if _, err := c.Kickoff(context.Background(), map[string]interface{}{"topic": "EV"}); err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	model.Verify(t)
+
+	items, err := c.getMemoryManager().searchCrewMemories(context.Background(), "market-crew", "")
+	if err != nil {
+		t.Fatalf("failed to search crew memories: %v", err)
+	}
+	if len(items) != 1 || items[0].Value != "Distilled summary" {
+		t.Fatalf("expected only the final output to be stored, got %+v", items)
+	}
+	if items[0].Metadata["task_description"] != "Summarise the EV findings" || items[0].Metadata["agent_role"] != "Analyst" {
+		t.Errorf("unexpected crew memory metadata: %v", items[0].Metadata)
+	}
+}
+
+func TestRenderCrewMemories(t *testing.T) {
+	items := []memory.MemoryItem{
+		{Value: "Solar panel prices fell", Agent: "Analyst"},
+		{Value: "EV battery costs fell 10% in 2024", Agent: "Analyst"},
+		{Value: "EV charging networks expanded", Agent: "Writer"},
+	}
+
+	rendered := renderCrewMemories(items, "EV battery costs", crewMemorySettings{relevanceThreshold: 0.5})
+	if rendered != "- [Analyst] EV battery costs fell 10% in 2024" {
+		t.Errorf("expected only the memory above the threshold, got %q", rendered)
+	}
+
+	rendered = renderCrewMemories(items, "EV battery costs", crewMemorySettings{})
+	lines := strings.Split(rendered, "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "battery") {
+		t.Errorf("expected the more relevant memory first, got %q", rendered)
+	}
+
+	rendered = renderCrewMemories(items, "EV battery costs", crewMemorySettings{maxContextTokens: 12})
+	if rendered == "" || strings.Contains(rendered, "\n") {
+		t.Errorf("expected the token budget to keep a single memory, got %q", rendered)
+	}
+}
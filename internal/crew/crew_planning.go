@@ -0,0 +1,182 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew/planning"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 规划者的角色设定，与Python版本CrewPlanner的规划Agent保持一致
+const (
+	plannerRole = "Task Execution Planner"
+	plannerGoal = "Your goal is to create an extremely detailed, step-by-step plan based on the tasks and tools " +
+		"available to each agent so that they can perform the tasks in an exemplary manner"
+	plannerBackstory = "Planner agent for crew planning"
+)
+
+// handleCrewPlanning 执行前让规划LLM为每个任务生成分步计划，并把计划加到对应任务描述前面
+// 对应Python版本的_handle_crew_planning
+func (c *BaseCrew) handleCrewPlanning(ctx context.Context, inputs map[string]interface{}) error {
+	c.mu.RLock()
+	tasks := append([]agent.Task(nil), c.tasks...)
+	c.mu.RUnlock()
+
+	c.logger.Info("handling crew planning",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "tasks_count", Value: len(tasks)},
+	)
+
+	if err := c.planTasks(ctx, tasks); err != nil {
+		c.eventBus.Emit(ctx, c, NewCrewPlanningFailedEvent(c.name, err.Error(), c.planningStrict))
+		return err
+	}
+	return nil
+}
+
+// planTasks 调用规划LLM生成计划并注入任务描述
+func (c *BaseCrew) planTasks(ctx context.Context, tasks []agent.Task) error {
+	planningLLM := c.resolvePlanningLLM()
+	if planningLLM == nil {
+		return fmt.Errorf("planning requires a planning LLM or manager LLM")
+	}
+
+	// 计划基于任务的原始描述生成，重复执行时不会叠加之前的计划
+	taskInfos := make([]planning.TaskInfo, len(tasks))
+	for i, task := range tasks {
+		taskInfos[i] = c.planningTaskInfo(task)
+	}
+
+	tasksSummary, err := planning.NewTaskSummaryGenerator(c.logger).GenerateTasksSummary(ctx, taskInfos)
+	if err != nil {
+		return fmt.Errorf("failed to create tasks summary: %w", err)
+	}
+
+	model := planningLLM.GetModel()
+	c.eventBus.Emit(ctx, c, NewCrewPlanningStartedEvent(c.name, len(tasks), model))
+
+	start := time.Now()
+	response, err := planningLLM.Call(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: fmt.Sprintf("You are %s. %s\n%s", plannerRole, plannerBackstory, plannerGoal)},
+		{Role: llm.RoleUser, Content: plannerPrompt(tasksSummary)},
+	}, &llm.CallOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to call planning LLM: %w", err)
+	}
+	duration := time.Since(start)
+
+	var result planning.PlannerTaskPydanticOutput
+	if err := result.FromJSON(extractJSONObject(response.Content)); err != nil {
+		return fmt.Errorf("invalid planning response: %w", err)
+	}
+	if err := result.Validate(); err != nil {
+		return fmt.Errorf("invalid planning response: %w", err)
+	}
+	if len(result.ListOfPlansPerTask) != len(tasks) {
+		c.logger.Warn("planning returned a different number of plans than tasks",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "tasks_count", Value: len(tasks)},
+			logger.Field{Key: "plans_count", Value: len(result.ListOfPlansPerTask)},
+		)
+	}
+
+	// 与Python版本一致按顺序把计划对应到任务，计划放在任务描述前面
+	for i, plan := range result.ListOfPlansPerTask {
+		if i >= len(tasks) {
+			break
+		}
+		tasks[i].SetDescription(fmt.Sprintf("Step-by-step plan for this task:\n%s\n\n%s",
+			strings.TrimSpace(plan.Plan), c.originalDescription(tasks[i])))
+	}
+
+	c.eventBus.Emit(ctx, c, NewCrewPlanningCompletedEvent(c.name, len(result.ListOfPlansPerTask), model, response.Usage, duration))
+	c.logger.Info("crew planning completed",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "plans_count", Value: len(result.ListOfPlansPerTask)},
+		logger.Field{Key: "total_tokens", Value: response.Usage.TotalTokens},
+		logger.Field{Key: "duration", Value: duration},
+	)
+	return nil
+}
+
+// resolvePlanningLLM 返回规划使用的LLM，未配置PlanningLLM时使用ManagerLLM
+func (c *BaseCrew) resolvePlanningLLM() llm.LLM {
+	if c.planningLLM != nil {
+		return c.planningLLM
+	}
+	if managerLLM, ok := c.managerLLM.(llm.LLM); ok {
+		return managerLLM
+	}
+	return nil
+}
+
+// originalDescription 返回任务规划前的描述，首次规划时记录下来
+func (c *BaseCrew) originalDescription(task agent.Task) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if description, ok := c.originalDescriptions[task.GetID()]; ok {
+		return description
+	}
+	description := task.GetDescription()
+	c.originalDescriptions[task.GetID()] = description
+	return description
+}
+
+// planningTaskInfo 把任务转换为生成任务摘要所需的信息
+func (c *BaseCrew) planningTaskInfo(task agent.Task) planning.TaskInfo {
+	info := planning.TaskInfo{
+		ID:             task.GetID(),
+		Description:    c.originalDescription(task),
+		ExpectedOutput: task.GetExpectedOutput(),
+	}
+
+	tools := task.GetTools()
+	if assigned := task.GetAssignedAgent(); assigned != nil {
+		info.AgentRole = assigned.GetRole()
+		info.AgentGoal = assigned.GetGoal()
+		tools = append(append([]agent.Tool(nil), tools...), assigned.GetTools()...)
+	}
+
+	seen := make(map[string]bool)
+	for _, tool := range tools {
+		if name := tool.GetName(); !seen[name] {
+			seen[name] = true
+			info.Tools = append(info.Tools, name)
+		}
+	}
+	return info
+}
+
+// plannerPrompt 构建规划请求，要求返回与PlannerTaskPydanticOutput一致的JSON
+func plannerPrompt(tasksSummary string) string {
+	return fmt.Sprintf(`Based on these tasks summary:
+%s
+
+Create the most descriptive plan based on the tasks descriptions, tools available, and agents' goals for them to execute their goals with perfection.
+Provide one plan per task, in the same order as the tasks above.
+
+Return only a JSON object with the following structure:
+{
+  "list_of_plans_per_task": [
+    {
+      "task": "task description",
+      "plan": "detailed step-by-step plan"
+    }
+  ]
+}`, tasksSummary)
+}
+
+// extractJSONObject 取出回复中的JSON对象，回复可能包含markdown代码块或说明文字
+func extractJSONObject(content string) string {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return content
+	}
+	return content[start : end+1]
+}
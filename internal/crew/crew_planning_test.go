@@ -0,0 +1,162 @@
+package crew
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newPlanningTestCrew(t *testing.T, config *CrewConfig, workerLLM *llmtest.ScriptedLLM) (*BaseCrew, *[]events.Event) {
+	t.Helper()
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	var mu sync.Mutex
+	var planningEvents []events.Event
+	for _, eventType := range []string{"crew_planning_started", "crew_planning_completed", "crew_planning_failed"} {
+		_, err := eventBus.SubscribeWithOptions(eventType, func(ctx context.Context, event events.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			planningEvents = append(planningEvents, event)
+			return nil
+		}, events.WithSyncDelivery())
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+	}
+
+	config.PlanningEnabled = true
+	crew := NewBaseCrew(config, eventBus, logger)
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Researcher",
+		Goal:      "Find facts",
+		Backstory: "Careful analyst",
+		LLM:       workerLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(worker)
+	crew.AddTask(agent.NewTaskWithOptions("Research Go generics", "Notes", agent.WithAssignedAgent(worker)))
+	crew.AddTask(agent.NewTaskWithOptions("Summarize the notes", "Summary", agent.WithAssignedAgent(worker)))
+	return crew, &planningEvents
+}
+
+func TestCrewPlanningInjectsPlans(t *testing.T) {
+	planningLLM := NewMockLLM("```json\n" + `{"list_of_plans_per_task": [
+		{"task": "Research Go generics", "plan": "1. Read the spec"},
+		{"task": "Summarize the notes", "plan": "1. List key points"}
+	]}` + "\n```")
+	workerLLM := NewMockLLM("notes", "summary", "notes again", "summary again")
+
+	config := DefaultCrewConfig()
+	config.PlanningLLM = planningLLM
+	crew, planningEvents := newPlanningTestCrew(t, config, workerLLM)
+
+	for i := 0; i < 2; i++ {
+		if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+			t.Fatalf("crew execution failed: %v", err)
+		}
+	}
+
+	if len(workerLLM.Prompts()) != 4 {
+		t.Fatalf("expected 4 worker prompts, got %d", len(workerLLM.Prompts()))
+	}
+	if !strings.Contains(workerLLM.Prompts()[0], "Step-by-step plan for this task:\n1. Read the spec\n\nResearch Go generics") {
+		t.Errorf("expected plan before the first task description, got:\n%s", workerLLM.Prompts()[0])
+	}
+	if !strings.Contains(workerLLM.Prompts()[1], "1. List key points") {
+		t.Errorf("expected plan in the second task prompt, got:\n%s", workerLLM.Prompts()[1])
+	}
+	// 重复执行时基于原始描述重新规划，计划不会叠加
+	if strings.Count(workerLLM.Prompts()[2], "Step-by-step plan for this task") != 1 {
+		t.Errorf("expected a single plan on repeated kickoff, got:\n%s", workerLLM.Prompts()[2])
+	}
+
+	if len(*planningEvents) != 4 {
+		t.Fatalf("expected started and completed events per kickoff, got %d", len(*planningEvents))
+	}
+	completed, ok := (*planningEvents)[1].(*CrewPlanningCompletedEvent)
+	if !ok {
+		t.Fatalf("expected completed event, got %T", (*planningEvents)[1])
+	}
+	if completed.PlansCount != 2 || completed.TotalTokens == 0 || completed.Cost == 0 {
+		t.Errorf("expected plan count and token usage in completed event, got %+v", completed)
+	}
+}
+
+func TestCrewClonePlansItsOwnTasks(t *testing.T) {
+	planningLLM := NewMockLLM(
+		`{"list_of_plans_per_task": [{"task": "Research Go generics", "plan": "1. Read the spec"}, {"task": "Summarize the notes", "plan": "1. List key points"}]}`,
+		`{"list_of_plans_per_task": [{"task": "Research Go generics", "plan": "1. Read the proposal"}, {"task": "Summarize the notes", "plan": "1. Draft an outline"}]}`,
+	)
+	config := DefaultCrewConfig()
+	config.PlanningLLM = planningLLM
+	crew, _ := newPlanningTestCrew(t, config, NewMockLLM())
+
+	if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	planned := crew.GetTasks()[0].GetDescription()
+
+	clone, err := crew.Clone()
+	if err != nil {
+		t.Fatalf("failed to clone crew: %v", err)
+	}
+	// 副本从规划前的描述开始
+	if description := clone.GetTasks()[0].GetDescription(); description != "Research Go generics" {
+		t.Errorf("expected clone to start from the original description, got %q", description)
+	}
+
+	if _, err := clone.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("clone execution failed: %v", err)
+	}
+	if description := clone.GetTasks()[0].GetDescription(); !strings.Contains(description, "1. Read the proposal") {
+		t.Errorf("expected clone task to carry its own plan, got %q", description)
+	}
+	if description := crew.GetTasks()[0].GetDescription(); description != planned {
+		t.Errorf("planning the clone should not change the original task, got %q", description)
+	}
+}
+
+func TestCrewPlanningFailure(t *testing.T) {
+	t.Run("non-strict continues without plan", func(t *testing.T) {
+		workerLLM := NewMockLLM("notes", "summary")
+		config := DefaultCrewConfig()
+		config.PlanningLLM = NewMockLLM("I cannot plan this")
+		crew, planningEvents := newPlanningTestCrew(t, config, workerLLM)
+
+		if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+			t.Fatalf("expected execution to continue, got %v", err)
+		}
+		if strings.Contains(workerLLM.Prompts()[0], "Step-by-step plan") {
+			t.Errorf("expected no plan in prompt, got:\n%s", workerLLM.Prompts()[0])
+		}
+		last := (*planningEvents)[len(*planningEvents)-1]
+		if failed, ok := last.(*CrewPlanningFailedEvent); !ok || failed.Strict {
+			t.Errorf("expected non-strict failed event, got %+v", last)
+		}
+	})
+
+	t.Run("strict aborts", func(t *testing.T) {
+		workerLLM := NewMockLLM("notes", "summary")
+		config := DefaultCrewConfig()
+		config.PlanningStrict = true
+		crew, _ := newPlanningTestCrew(t, config, workerLLM)
+
+		_, err := crew.Kickoff(context.Background(), nil)
+		if err == nil || !strings.Contains(err.Error(), "planning requires a planning LLM or manager LLM") {
+			t.Fatalf("expected planning error, got %v", err)
+		}
+		if len(workerLLM.Prompts()) != 0 {
+			t.Errorf("expected no task execution, got %d prompts", len(workerLLM.Prompts()))
+		}
+	})
+}
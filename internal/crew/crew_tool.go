@@ -0,0 +1,222 @@
+package crew
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// MaxCrewNestingDepth 作为工具执行的Crew允许嵌套的最大层数（包括最外层的Crew），
+// 防止Crew通过工具直接或间接调用自身时无限递归
+const MaxCrewNestingDepth = 3
+
+// 嵌套Crew的事件转发给调用方时在payload中记录的调用方执行
+const (
+	ParentCrewIDPayloadKey      = "parent_crew_id"
+	ParentCrewNamePayloadKey    = "parent_crew_name"
+	ParentExecutionIDPayloadKey = "parent_execution_id"
+)
+
+// crewExecution 正在执行的Kickoff，作为工具执行的Crew据此确定嵌套深度和转发事件的总线
+type crewExecution struct {
+	crewID      string
+	crewName    string
+	executionID int
+	eventBus    events.EventBus
+	depth       int // 最外层的Kickoff为1
+}
+
+type crewExecutionKey struct{}
+
+// crewExecutionFrom 返回ctx中正在执行的Kickoff，不在Kickoff中时返回nil
+func crewExecutionFrom(ctx context.Context) *crewExecution {
+	execution, _ := ctx.Value(crewExecutionKey{}).(*crewExecution)
+	return execution
+}
+
+// startCrewExecution 记录本次Kickoff，ctx中已有的Kickoff是调用本Crew的上层Crew
+func (c *BaseCrew) startCrewExecution(ctx context.Context, executionID int) context.Context {
+	c.mu.RLock()
+	execution := &crewExecution{crewID: c.id, crewName: c.name, executionID: executionID, eventBus: c.eventBus, depth: 1}
+	c.mu.RUnlock()
+	if parent := crewExecutionFrom(ctx); parent != nil {
+		execution.depth = parent.depth + 1
+	}
+	return context.WithValue(ctx, crewExecutionKey{}, execution)
+}
+
+// AsTool 把整个Crew包装为Agent可以调用的工具
+// 工具参数作为Kickoff的输入，每次调用在Crew的副本上执行，同一个Crew可以被并发或重复调用；
+// 返回最后一个任务的Raw输出，设置了FinalOutputSchema且综合成功时返回Parsed的JSON。
+// inputSchema为参数的JSON Schema，为nil时接受一个字符串参数input
+func (c *BaseCrew) AsTool(name, description string, inputSchema map[string]interface{}) agent.Tool {
+	if inputSchema == nil {
+		inputSchema = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"input": map[string]interface{}{
+					"type":        "string",
+					"description": "The request for the crew, including all necessary context",
+				},
+			},
+			"required": []string{"input"},
+		}
+	}
+
+	schema := agent.ToolSchema{Name: name, Description: description, Parameters: inputSchema}
+	return agent.NewBaseToolWithSchema(name, description, schema, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return c.kickoffAsTool(ctx, name, args)
+	})
+}
+
+// kickoffAsTool 在副本上执行Kickoff，开销计入调用工具的Agent当前的任务
+func (c *BaseCrew) kickoffAsTool(ctx context.Context, toolName string, args map[string]interface{}) (interface{}, error) {
+	parent := crewExecutionFrom(ctx)
+	if parent != nil && parent.depth >= MaxCrewNestingDepth {
+		return nil, fmt.Errorf("maximum crew nesting depth %d reached: %s cannot run crew %s. Complete the task yourself",
+			MaxCrewNestingDepth, parent.crewName, c.name)
+	}
+
+	eventBus := c.eventBus
+	if parent != nil {
+		eventBus = newNestedEventBus(c.eventBus, parent)
+	}
+	nested := c.cloneWithEventBus(eventBus)
+	if parent != nil {
+		nested.forwardAgentEvents(parent)
+	}
+
+	inputs := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		inputs[key] = value
+	}
+
+	c.logger.Info("running crew as tool",
+		logger.Field{Key: "tool", Value: toolName},
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "depth", Value: nestingDepth(parent) + 1},
+	)
+
+	result, err := nested.Kickoff(withoutKickoffState(ctx), inputs)
+	if result != nil && result.TokenUsage != nil {
+		usage := result.TokenUsage
+		agent.RecordNestedUsage(ctx, llm.Usage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+			Cost:             usage.TotalCost,
+		}, usage.LLMCalls)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("crew %s failed: %w", c.name, err)
+	}
+	return crewToolResult(result)
+}
+
+// crewToolResult 返回作为工具结果的Crew输出
+func crewToolResult(result *CrewOutput) (interface{}, error) {
+	if result == nil {
+		return "", nil
+	}
+	if result.Parsed != nil && result.SynthesisError == nil {
+		data, err := json.Marshal(result.Parsed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode crew output: %w", err)
+		}
+		return string(data), nil
+	}
+	return result.Raw, nil
+}
+
+// withoutKickoffState 屏蔽调用方Kickoff放入ctx的会话、记忆、重放和进度状态，
+// 嵌套的Crew按自己的配置开始一次新的Kickoff；预算和注入的上下文仍然沿用
+func withoutKickoffState(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, sessionIDKey{}, "")
+	ctx = context.WithValue(ctx, kickoffConversationKey{}, (*kickoffConversation)(nil))
+	ctx = context.WithValue(ctx, kickoffMemoryKey{}, (*kickoffMemory)(nil))
+	ctx = context.WithValue(ctx, replaySessionKey{}, (*replaySession)(nil))
+	return context.WithValue(ctx, progressKey{}, (*progressStream)(nil))
+}
+
+// nestingDepth 返回调用方Kickoff的嵌套深度，不在Kickoff中调用时为0
+func nestingDepth(parent *crewExecution) int {
+	if parent == nil {
+		return 0
+	}
+	return parent.depth
+}
+
+// forwardAgentEvents 副本的Agent（包括管理器和综合Agent）发射的事件同样转发给调用方
+func (c *BaseCrew) forwardAgentEvents(parent *crewExecution) {
+	c.mu.RLock()
+	agents := append([]agent.Agent(nil), c.agents...)
+	if c.managerAgent != nil {
+		agents = append(agents, c.managerAgent)
+	}
+	if c.synthesisAgent != nil {
+		agents = append(agents, c.synthesisAgent)
+	}
+	c.mu.RUnlock()
+
+	for _, a := range agents {
+		if bus := a.GetEventBus(); bus != nil {
+			_ = a.SetEventBus(newNestedEventBus(bus, parent))
+		}
+	}
+}
+
+// nestedEventBus 嵌套Crew的事件总线：事件照常发射到原来的总线，并带上调用方的执行信息转发给调用方的总线
+type nestedEventBus struct {
+	events.EventBus
+	parent  events.EventBus
+	payload map[string]interface{}
+}
+
+func newNestedEventBus(bus events.EventBus, parent *crewExecution) *nestedEventBus {
+	return &nestedEventBus{
+		EventBus: bus,
+		parent:   parent.eventBus,
+		payload: map[string]interface{}{
+			ParentCrewIDPayloadKey:      parent.crewID,
+			ParentCrewNamePayloadKey:    parent.crewName,
+			ParentExecutionIDPayloadKey: parent.executionID,
+		},
+	}
+}
+
+// Emit 记录调用方的执行后发射事件；多层嵌套时保留最近一层调用方的信息
+// 原来的总线与调用方的总线相同时只发射一次
+func (b *nestedEventBus) Emit(ctx context.Context, source interface{}, event events.Event) error {
+	if payload := event.GetPayload(); payload != nil {
+		for key, value := range b.payload {
+			if _, exists := payload[key]; !exists {
+				payload[key] = value
+			}
+		}
+	}
+
+	if b.parent == nil {
+		return b.EventBus.Emit(ctx, source, event)
+	}
+	if rootEventBus(b.EventBus) == rootEventBus(b.parent) {
+		return b.parent.Emit(ctx, source, event)
+	}
+	return errors.Join(b.EventBus.Emit(ctx, source, event), b.parent.Emit(ctx, source, event))
+}
+
+// rootEventBus 返回嵌套事件总线最终发射到的原始总线
+func rootEventBus(bus events.EventBus) events.EventBus {
+	for {
+		nested, ok := bus.(*nestedEventBus)
+		if !ok {
+			return bus
+		}
+		bus = nested.EventBus
+	}
+}
@@ -0,0 +1,133 @@
+package crew
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newCrewToolTestCrew(t *testing.T, name, role string, model *llmtest.ScriptedLLM, tools ...agent.Tool) *BaseCrew {
+	t.Helper()
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	member, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: role, Goal: "Do the work", Backstory: "Experienced",
+		LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	for _, tool := range tools {
+		if err := member.AddTool(tool); err != nil {
+			t.Fatalf("failed to add tool: %v", err)
+		}
+	}
+
+	c := NewBaseCrew(&CrewConfig{Name: name}, eventBus, log)
+	c.AddAgent(member)
+	c.AddTask(agent.NewTaskWithOptions("Handle the request", "An answer", agent.WithAssignedAgent(member)))
+	return c
+}
+
+func TestCrewAsToolRunsNestedCrew(t *testing.T) {
+	innerModel := llmtest.NewScriptedLLM(llmtest.Reply{
+		Content: "EV sales grew 30% in Europe",
+		Usage:   llm.Usage{PromptTokens: 40, CompletionTokens: 10, TotalTokens: 50, Cost: 0.5},
+	})
+	research := newCrewToolTestCrew(t, "research", "Researcher", innerModel)
+	tool := research.AsTool("research_department", "Ask the research department", nil)
+
+	outerModel := llmtest.NewScriptedLLM(
+		llmtest.Reply{ToolCalls: []llm.ToolCall{{ID: "call_1", Type: "function",
+			Function: llm.ToolCallFunction{Name: "research_department", Arguments: `{"input":"EV market in Europe"}`}}},
+			Usage: llm.Usage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25}},
+		llmtest.Reply{Content: "Report: EV sales grew 30% in Europe",
+			Usage: llm.Usage{PromptTokens: 30, CompletionTokens: 5, TotalTokens: 35}},
+	)
+	orchestrator := newCrewToolTestCrew(t, "orchestrator", "Orchestrator", outerModel, tool)
+
+	var mu sync.Mutex
+	var forwarded []events.Event
+	orchestrator.eventBus.SubscribeWithOptions("*", func(ctx context.Context, event events.Event) error {
+		if _, ok := event.GetPayload()[ParentExecutionIDPayloadKey]; ok {
+			mu.Lock()
+			forwarded = append(forwarded, event)
+			mu.Unlock()
+		}
+		return nil
+	}, events.WithSyncDelivery())
+
+	output, err := orchestrator.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	innerModel.Verify(t)
+	outerModel.Verify(t)
+
+	if output.Raw != "Report: EV sales grew 30% in Europe" {
+		t.Errorf("unexpected output: %q", output.Raw)
+	}
+	if prompt := innerModel.Prompts()[0]; !strings.Contains(prompt, "EV market in Europe") {
+		t.Errorf("expected the tool arguments as kickoff inputs of the nested crew:\n%s", prompt)
+	}
+	if !strings.Contains(outerModel.Calls()[1].LastMessage(), "EV sales grew 30% in Europe") {
+		t.Errorf("expected the nested crew output as the tool result:\n%s", outerModel.Calls()[1].LastMessage())
+	}
+
+	// 嵌套Crew的开销计入调用工具的Agent
+	task := output.TasksOutput[0]
+	if task.TokensUsed != 110 || task.LLMStats.Calls != 3 {
+		t.Errorf("expected nested usage in the task output, got %d tokens and %d calls", task.TokensUsed, task.LLMStats.Calls)
+	}
+	if usage := orchestrator.GetUsageMetrics().AgentUsage["Orchestrator"]; usage.TotalTokens != 110 || usage.TotalCost != 0.5 {
+		t.Errorf("expected nested usage in the orchestrator's stats, got %+v", usage)
+	}
+	if research.GetUsageMetrics().TotalTokens != 0 {
+		t.Error("the nested crew must run on a clone")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(forwarded) == 0 {
+		t.Fatal("expected nested crew events on the orchestrator's event bus")
+	}
+	var started bool
+	for _, event := range forwarded {
+		payload := event.GetPayload()
+		if payload[ParentCrewIDPayloadKey] != orchestrator.id || payload[ParentExecutionIDPayloadKey] != 1 {
+			t.Errorf("unexpected parent execution in %s: %v", event.GetType(), payload)
+		}
+		if _, ok := event.(*CrewKickoffStartedEvent); ok {
+			started = true
+		}
+	}
+	if !started {
+		t.Error("expected the nested crew's kickoff event to be forwarded")
+	}
+}
+
+func TestCrewAsToolNestingDepthGuard(t *testing.T) {
+	model := llmtest.NewScriptedLLM()
+	c := newCrewToolTestCrew(t, "recursive", "Worker", model)
+	tool := c.AsTool("recursive_crew", "Calls itself", nil)
+
+	ctx := context.WithValue(context.Background(), crewExecutionKey{}, &crewExecution{crewName: "recursive", depth: MaxCrewNestingDepth})
+	_, err := tool.Execute(ctx, map[string]interface{}{"input": "again"})
+	if err == nil || !strings.Contains(err.Error(), "maximum crew nesting depth") {
+		t.Fatalf("expected the nesting depth guard, got %v", err)
+	}
+	if model.CallCount() != 0 {
+		t.Error("the nested crew must not run past the maximum depth")
+	}
+
+	if schema := tool.GetSchema(); schema.Parameters["required"].([]string)[0] != "input" {
+		t.Errorf("expected the default input argument, got %v", schema.Parameters)
+	}
+}
@@ -3,6 +3,7 @@ package crew
 import (
 	"time"
 
+	"github.com/ynl/greensoulai/internal/llm"
 	"github.com/ynl/greensoulai/pkg/events"
 )
 
@@ -67,6 +68,35 @@ func NewCrewKickoffCompletedEvent(crewID, crewName string, executionID int, dura
 	}
 }
 
+// CrewRateLimitedEvent LLM调用因MaxRPM限制被限流事件
+type CrewRateLimitedEvent struct {
+	events.BaseEvent
+	CrewID   string        `json:"crew_id"`
+	CrewName string        `json:"crew_name"`
+	MaxRPM   int           `json:"max_rpm"`
+	Wait     time.Duration `json:"wait"`
+}
+
+// NewCrewRateLimitedEvent 创建限流事件
+func NewCrewRateLimitedEvent(crewID, crewName string, maxRPM int, wait time.Duration) *CrewRateLimitedEvent {
+	return &CrewRateLimitedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "crew_rate_limited",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_id":   crewID,
+				"crew_name": crewName,
+				"max_rpm":   maxRPM,
+				"wait_ms":   wait.Milliseconds(),
+			},
+		},
+		CrewID:   crewID,
+		CrewName: crewName,
+		MaxRPM:   maxRPM,
+		Wait:     wait,
+	}
+}
+
 // TaskExecutionStartedEvent 任务开始执行事件
 type TaskExecutionStartedEvent struct {
 	events.BaseEvent
@@ -157,6 +187,32 @@ func NewTaskExecutionFailedEvent(taskIndex int, taskDescription, agentRole, erro
 	}
 }
 
+// TaskExecutionSkippedEvent 任务被跳过事件，条件任务条件不满足或重放时复用已保存的输出
+type TaskExecutionSkippedEvent struct {
+	events.BaseEvent
+	TaskIndex       int    `json:"task_index"`
+	TaskDescription string `json:"task_description"`
+	Reason          string `json:"reason"`
+}
+
+// NewTaskExecutionSkippedEvent 创建任务跳过事件
+func NewTaskExecutionSkippedEvent(taskIndex int, taskDescription, reason string) *TaskExecutionSkippedEvent {
+	return &TaskExecutionSkippedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "task_execution_skipped",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"task_index":       taskIndex,
+				"task_description": taskDescription,
+				"reason":           reason,
+			},
+		},
+		TaskIndex:       taskIndex,
+		TaskDescription: taskDescription,
+		Reason:          reason,
+	}
+}
+
 // Sequential Process Events
 
 // SequentialProcessStartedEvent Sequential流程开始事件
@@ -231,6 +287,89 @@ func NewSequentialProcessFailedEvent(crewName, errorMsg string) *SequentialProce
 	}
 }
 
+// Parallel Process Events
+
+// ParallelProcessStartedEvent Parallel流程开始事件
+type ParallelProcessStartedEvent struct {
+	events.BaseEvent
+	CrewName       string `json:"crew_name"`
+	TasksCount     int    `json:"tasks_count"`
+	AgentsCount    int    `json:"agents_count"`
+	MaxConcurrency int    `json:"max_concurrency"`
+}
+
+// NewParallelProcessStartedEvent 创建Parallel流程开始事件
+func NewParallelProcessStartedEvent(crewName string, tasksCount, agentsCount, maxConcurrency int) *ParallelProcessStartedEvent {
+	return &ParallelProcessStartedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "parallel_process_started",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_name":       crewName,
+				"tasks_count":     tasksCount,
+				"agents_count":    agentsCount,
+				"max_concurrency": maxConcurrency,
+			},
+		},
+		CrewName:       crewName,
+		TasksCount:     tasksCount,
+		AgentsCount:    agentsCount,
+		MaxConcurrency: maxConcurrency,
+	}
+}
+
+// ParallelProcessCompletedEvent Parallel流程完成事件
+type ParallelProcessCompletedEvent struct {
+	events.BaseEvent
+	CrewName            string `json:"crew_name"`
+	CompletedTasksCount int    `json:"completed_tasks_count"`
+}
+
+// NewParallelProcessCompletedEvent 创建Parallel流程完成事件
+func NewParallelProcessCompletedEvent(crewName string, completedTasksCount int) *ParallelProcessCompletedEvent {
+	return &ParallelProcessCompletedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "parallel_process_completed",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_name":             crewName,
+				"completed_tasks_count": completedTasksCount,
+			},
+		},
+		CrewName:            crewName,
+		CompletedTasksCount: completedTasksCount,
+	}
+}
+
+// ParallelProcessFailedEvent Parallel流程失败事件
+type ParallelProcessFailedEvent struct {
+	events.BaseEvent
+	CrewName            string `json:"crew_name"`
+	CompletedTasksCount int    `json:"completed_tasks_count"`
+	FailedTasksCount    int    `json:"failed_tasks_count"`
+	Error               string `json:"error"`
+}
+
+// NewParallelProcessFailedEvent 创建Parallel流程失败事件
+func NewParallelProcessFailedEvent(crewName string, completedTasksCount, failedTasksCount int, errorMsg string) *ParallelProcessFailedEvent {
+	return &ParallelProcessFailedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "parallel_process_failed",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_name":             crewName,
+				"completed_tasks_count": completedTasksCount,
+				"failed_tasks_count":    failedTasksCount,
+				"error":                 errorMsg,
+			},
+		},
+		CrewName:            crewName,
+		CompletedTasksCount: completedTasksCount,
+		FailedTasksCount:    failedTasksCount,
+		Error:               errorMsg,
+	}
+}
+
 // Hierarchical Process Events
 
 // HierarchicalProcessStartedEvent Hierarchical流程开始事件
@@ -304,3 +443,180 @@ func NewHierarchicalProcessFailedEvent(crewName, errorMsg string) *HierarchicalP
 		Error:    errorMsg,
 	}
 }
+
+// Planning Events
+
+// CrewPlanningStartedEvent Crew规划开始事件
+type CrewPlanningStartedEvent struct {
+	events.BaseEvent
+	CrewName   string `json:"crew_name"`
+	TasksCount int    `json:"tasks_count"`
+	Model      string `json:"model"`
+}
+
+// NewCrewPlanningStartedEvent 创建Crew规划开始事件
+func NewCrewPlanningStartedEvent(crewName string, tasksCount int, model string) *CrewPlanningStartedEvent {
+	return &CrewPlanningStartedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "crew_planning_started",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_name":   crewName,
+				"tasks_count": tasksCount,
+				"model":       model,
+			},
+		},
+		CrewName:   crewName,
+		TasksCount: tasksCount,
+		Model:      model,
+	}
+}
+
+// CrewPlanningCompletedEvent Crew规划完成事件，携带规划调用的token使用量和成本
+type CrewPlanningCompletedEvent struct {
+	events.BaseEvent
+	CrewName         string        `json:"crew_name"`
+	PlansCount       int           `json:"plans_count"`
+	Model            string        `json:"model"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	TotalTokens      int           `json:"total_tokens"`
+	Cost             float64       `json:"cost"`
+	Duration         time.Duration `json:"duration"`
+}
+
+// NewCrewPlanningCompletedEvent 创建Crew规划完成事件
+func NewCrewPlanningCompletedEvent(crewName string, plansCount int, model string, usage llm.Usage, duration time.Duration) *CrewPlanningCompletedEvent {
+	return &CrewPlanningCompletedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "crew_planning_completed",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_name":         crewName,
+				"plans_count":       plansCount,
+				"model":             model,
+				"prompt_tokens":     usage.PromptTokens,
+				"completion_tokens": usage.CompletionTokens,
+				"total_tokens":      usage.TotalTokens,
+				"cost":              usage.Cost,
+				"duration_ms":       duration.Milliseconds(),
+			},
+		},
+		CrewName:         crewName,
+		PlansCount:       plansCount,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		Cost:             usage.Cost,
+		Duration:         duration,
+	}
+}
+
+// CrewPlanningFailedEvent Crew规划失败事件
+type CrewPlanningFailedEvent struct {
+	events.BaseEvent
+	CrewName string `json:"crew_name"`
+	Error    string `json:"error"`
+	Strict   bool   `json:"strict"` // 为true时规划失败终止了本次执行
+}
+
+// NewCrewPlanningFailedEvent 创建Crew规划失败事件
+func NewCrewPlanningFailedEvent(crewName, errorMsg string, strict bool) *CrewPlanningFailedEvent {
+	return &CrewPlanningFailedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "crew_planning_failed",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_name": crewName,
+				"error":     errorMsg,
+				"strict":    strict,
+			},
+		},
+		CrewName: crewName,
+		Error:    errorMsg,
+		Strict:   strict,
+	}
+}
+
+// Consensus Process Events
+
+// ConsensusRoundCompletedEvent Consensus流程中一个任务的候选执行和评审完成事件
+type ConsensusRoundCompletedEvent struct {
+	events.BaseEvent
+	CrewName   string `json:"crew_name"`
+	TaskIndex  int    `json:"task_index"`
+	TaskID     string `json:"task_id"`
+	Candidates int    `json:"candidates"`
+	Succeeded  int    `json:"succeeded"`
+	Winner     string `json:"winner"` // 被选中候选的Agent角色
+	Merged     bool   `json:"merged"` // 正式输出是否为评审合并的答案
+}
+
+// NewConsensusRoundCompletedEvent 创建Consensus评审完成事件
+func NewConsensusRoundCompletedEvent(crewName string, taskIndex int, taskID string, candidates, succeeded int, winner string, merged bool) *ConsensusRoundCompletedEvent {
+	return &ConsensusRoundCompletedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "consensus_round_completed",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_name":  crewName,
+				"task_index": taskIndex,
+				"task_id":    taskID,
+				"candidates": candidates,
+				"succeeded":  succeeded,
+				"winner":     winner,
+				"merged":     merged,
+			},
+		},
+		CrewName:   crewName,
+		TaskIndex:  taskIndex,
+		TaskID:     taskID,
+		Candidates: candidates,
+		Succeeded:  succeeded,
+		Winner:     winner,
+		Merged:     merged,
+	}
+}
+
+// BatchItemCompletedEvent KickoffForEachConcurrent中一组输入执行结束（包括失败）事件
+type BatchItemCompletedEvent struct {
+	events.BaseEvent
+	CrewName  string        `json:"crew_name"`
+	Index     int           `json:"index"`     // 输入在列表中的序号
+	Remaining int           `json:"remaining"` // 尚未结束的输入数
+	Total     int           `json:"total"`
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// NewBatchItemCompletedEvent 创建批量执行单项完成事件
+func NewBatchItemCompletedEvent(crewName string, index, remaining, total int, duration time.Duration, err error) *BatchItemCompletedEvent {
+	var errorMsg string
+	if err != nil {
+		errorMsg = err.Error()
+	}
+	return &BatchItemCompletedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      "batch_item_completed",
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"crew_name":   crewName,
+				"index":       index,
+				"remaining":   remaining,
+				"total":       total,
+				"success":     err == nil,
+				"error":       errorMsg,
+				"duration_ms": duration.Milliseconds(),
+			},
+		},
+		CrewName:  crewName,
+		Index:     index,
+		Remaining: remaining,
+		Total:     total,
+		Success:   err == nil,
+		Error:     errorMsg,
+		Duration:  duration,
+	}
+}
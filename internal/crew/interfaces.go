@@ -5,8 +5,11 @@ import (
 	"time"
 
 	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/memory"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/security"
 )
 
 // Process 定义Crew的执行模式
@@ -15,7 +18,8 @@ type Process int
 const (
 	ProcessSequential Process = iota
 	ProcessHierarchical
-	// TODO: ProcessConsensual
+	ProcessParallel  // 无依赖的任务并发执行
+	ProcessConsensus // 每个任务由多个Agent并发执行，评审从候选输出中选出或合并出正式输出
 )
 
 func (p Process) String() string {
@@ -24,6 +28,10 @@ func (p Process) String() string {
 		return "sequential"
 	case ProcessHierarchical:
 		return "hierarchical"
+	case ProcessParallel:
+		return "parallel"
+	case ProcessConsensus:
+		return "consensus"
 	default:
 		return "unknown"
 	}
@@ -36,7 +44,21 @@ type Crew interface {
 	KickoffAsync(ctx context.Context, inputs map[string]interface{}) (<-chan CrewResult, error)
 	KickoffForEach(ctx context.Context, inputsList []map[string]interface{}) ([]*CrewOutput, error)
 	KickoffForEachAsync(ctx context.Context, inputsList []map[string]interface{}) (<-chan []*CrewOutput, error)
+	// 用工作池并发执行多组输入，结果按输入顺序返回，单组输入的失败不丢失其他输入的结果
+	KickoffForEachConcurrent(ctx context.Context, inputsList []map[string]interface{}, opts KickoffForEachOptions) ([]CrewRunResult, error)
 	KickoffWithTimeout(ctx context.Context, inputs map[string]interface{}, timeout time.Duration) (*CrewOutput, error)
+	// 异步执行并返回类型化的进度通道，最后一条kickoff_finished带有执行结果，之后通道关闭
+	KickoffWithProgress(ctx context.Context, inputs map[string]interface{}) (<-chan CrewProgress, error)
+
+	// 从记录过的Kickoff的某个任务开始重新执行，之前的任务复用已保存的输出
+	ReplayFrom(ctx context.Context, kickoffID, taskID string, overrides map[string]interface{}) (*CrewOutput, error)
+	ReplayFromWithProgress(ctx context.Context, kickoffID, taskID string, overrides map[string]interface{}) (<-chan CrewProgress, error)
+
+	// 不调用LLM检查配置并估算成本，可以在Kickoff之前发现问题
+	Validate(ctx context.Context, inputs map[string]interface{}) (*ValidationReport, error)
+
+	// 把整个Crew包装为工具，供其他Crew或单独的Agent调用
+	AsTool(name, description string, inputSchema map[string]interface{}) agent.Tool
 
 	// 训练方法
 	Train(ctx context.Context, nIterations int, filename string, inputs map[string]interface{}) error
@@ -54,6 +76,8 @@ type Crew interface {
 	AddAfterKickoffCallback(callback KickoffCallback) error
 	AddTaskCallback(callback TaskCallback) error
 	AddStepCallback(callback StepCallback) error
+	AddBeforeTaskHook(hook BeforeTaskHook) error
+	AddAfterTaskHook(hook AfterTaskHook) error
 
 	// 状态查询
 	GetAgents() []agent.Agent
@@ -62,6 +86,9 @@ type Crew interface {
 	IsMemoryEnabled() bool
 	IsCacheEnabled() bool
 	GetUsageMetrics() *UsageMetrics
+	GetFingerprint() *security.Fingerprint
+	// 返回当前执行状态的快照：正在执行的任务、Agent及其LLM或工具调用，用于诊断卡住的执行
+	Status() CrewStatus
 
 	// 生命周期管理
 	Clone() (Crew, error)
@@ -70,17 +97,25 @@ type Crew interface {
 }
 
 // CrewOutput 定义Crew执行的输出结果
+// Raw、JSON、Pydantic和Parsed都取自最后一个任务的输出（与Python版本一致），完整过程见TasksOutput和AggregateRaw
 type CrewOutput struct {
 	Raw         string                 `json:"raw"`
 	JSON        map[string]interface{} `json:"json,omitempty"`
 	Pydantic    interface{}            `json:"pydantic,omitempty"`
+	Parsed      interface{}            `json:"parsed,omitempty"` // 最后一个任务声明了OutputSchema时解析后的值
 	TasksOutput []*agent.TaskOutput    `json:"tasks_output"`
 	TokenUsage  *UsageMetrics          `json:"token_usage"`
 	CreatedAt   time.Time              `json:"created_at"`
 	Duration    time.Duration          `json:"duration"`
 	Success     bool                   `json:"success"`
 	Error       error                  `json:"error,omitempty"`
+	Fingerprint string                 `json:"fingerprint,omitempty"` // 产生该输出的crew的指纹
 	Metadata    map[string]interface{} `json:"metadata"`
+
+	// 配置了FinalOutputSchema时的综合结果：成功时JSON和Parsed取自综合任务，
+	// 失败时SynthesisError记录原因，Success不受影响
+	SynthesisOutput *agent.TaskOutput `json:"synthesis_output,omitempty"`
+	SynthesisError  error             `json:"synthesis_error,omitempty"`
 }
 
 // CrewResult 定义异步执行的结果
@@ -89,38 +124,56 @@ type CrewResult struct {
 	Error  error
 }
 
-// UsageMetrics 定义使用统计
-type UsageMetrics struct {
-	TotalTokens      int           `json:"total_tokens"`
-	PromptTokens     int           `json:"prompt_tokens"`
-	CompletionTokens int           `json:"completion_tokens"`
-	TotalCost        float64       `json:"total_cost"`
-	SuccessfulTasks  int           `json:"successful_tasks"`
-	FailedTasks      int           `json:"failed_tasks"`
-	TotalTasks       int           `json:"total_tasks"`
-	ExecutionTime    time.Duration `json:"execution_time"`
-}
+// UsageMetrics 使用统计，与Agent、训练和评估共用同一个累加器
+type UsageMetrics = agent.UsageMetrics
 
-// AddUsageMetrics 累加使用统计
-func (u *UsageMetrics) AddUsageMetrics(other *UsageMetrics) {
-	if other == nil {
+// AgentUsage 单个Agent的使用统计
+type AgentUsage = agent.AgentUsage
+
+// recordTaskUsage 记录任务结果，输出中记录的委托（delegate_work/ask_question）开销计入执行委托的同事
+// output为nil表示任务失败且没有输出
+func recordTaskUsage(usage *UsageMetrics, output *agent.TaskOutput) {
+	usage.RecordTask(output)
+	if output == nil {
+		return
+	}
+	if _, replayed := output.Metadata["replayed_from"]; replayed {
 		return
 	}
-	u.TotalTokens += other.TotalTokens
-	u.PromptTokens += other.PromptTokens
-	u.CompletionTokens += other.CompletionTokens
-	u.TotalCost += other.TotalCost
-	u.SuccessfulTasks += other.SuccessfulTasks
-	u.FailedTasks += other.FailedTasks
-	u.TotalTasks += other.TotalTasks
-	u.ExecutionTime += other.ExecutionTime
+	if records, ok := output.Metadata["delegations"].([]DelegationRecord); ok {
+		for _, record := range records {
+			usage.AddDelegation(record.Coworker, AgentUsage{
+				DelegatedTasks:   1,
+				LLMCalls:         record.LLMCalls,
+				TotalTokens:      record.TokensUsed,
+				PromptTokens:     record.PromptTokens,
+				CompletionTokens: record.CompletionTokens,
+				TotalCost:        record.Cost,
+			})
+		}
+	}
 }
 
 // 回调函数类型定义
 type KickoffCallback func(ctx context.Context, crew Crew, output *CrewOutput) (*CrewOutput, error)
+
+// KickoffStartHook Kickoff开始时调用，返回的ctx用于本次执行的所有任务、Agent和工具，
+// 工具可以通过agent.ContextKey取出钩子放入的资源
+type KickoffStartHook func(ctx context.Context) (context.Context, error)
+
+// KickoffEndHook Kickoff结束时调用，用于释放OnKickoffStart创建的资源，执行失败时output可能为nil；
+// 返回的错误只记录日志并写入输出的Metadata，不影响执行结果
+type KickoffEndHook func(ctx context.Context, output *CrewOutput) error
 type TaskCallback func(ctx context.Context, task agent.Task, output *agent.TaskOutput) error
 type StepCallback func(ctx context.Context, agent agent.Agent, step *StepInfo) error
 
+// BeforeTaskHook 任务执行前的中间件钩子，返回任务实际看到的上下文，返回nil表示不修改
+type BeforeTaskHook func(ctx context.Context, task agent.Task, taskContext map[string]interface{}) (map[string]interface{}, error)
+
+// AfterTaskHook 任务执行后的中间件钩子，可以改写或脱敏输出，返回nil表示不修改
+// 改写后的输出会作为任务结果传入后续任务的上下文
+type AfterTaskHook func(ctx context.Context, task agent.Task, output *agent.TaskOutput) (*agent.TaskOutput, error)
+
 // StepInfo 定义步骤信息
 type StepInfo struct {
 	Agent       string                 `json:"agent"`
@@ -141,21 +194,63 @@ type CrewConfig struct {
 	MemoryEnabled          bool                   `json:"memory_enabled"`
 	CacheEnabled           bool                   `json:"cache_enabled"`
 	MaxRPM                 int                    `json:"max_rpm"`
+	MaxConcurrency         int                    `json:"max_concurrency"`       // Parallel模式下的最大并发任务数，<=0表示不限制
+	MaxTotalTokens         int                    `json:"max_total_tokens"`      // 一次Kickoff所有LLM调用的token预算，达到后Agent不再调用工具并给出最终答案，<=0表示不限制
+	MaxTotalCostUSD        float64                `json:"max_total_cost_usd"`    // 一次Kickoff的成本预算（美元），行为同MaxTotalTokens
+	HardCostCeilingUSD     float64                `json:"hard_cost_ceiling_usd"` // 成本硬上限，超过后Kickoff以agent.BudgetExceededError中止，<=0表示不限制
 	ShareCrew              bool                   `json:"share_crew"`
 	PlanningEnabled        bool                   `json:"planning_enabled"`
+	PlanningLLM            llm.LLM                `json:"-"`                    // 生成执行计划的LLM，为nil时使用ManagerLLM
+	PlanningStrict         bool                   `json:"planning_strict"`      // 为true时规划失败会终止执行，否则记录警告后继续
+	MaxDelegationDepth     int                    `json:"max_delegation_depth"` // 同事之间委托链的最大深度，<=0时使用DefaultMaxDelegationDepth
 	MaxExecutionTime       time.Duration          `json:"max_execution_time"`
 	FullOutput             bool                   `json:"full_output"`
 	StepCallback           StepCallback           `json:"-"`
 	TaskCallback           TaskCallback           `json:"-"`
 	BeforeKickoffCallbacks []KickoffCallback      `json:"-"`
 	AfterKickoffCallbacks  []KickoffCallback      `json:"-"`
+	OnKickoffStart         KickoffStartHook       `json:"-"` // Kickoff开始时调用，可以向ctx放入本次执行共享的资源，返回错误时终止Kickoff
+	OnKickoffEnd           KickoffEndHook         `json:"-"` // OnKickoffStart成功后总会在Kickoff结束时调用，包括执行失败时
 	ManagerAgent           agent.Agent            `json:"-"`
 	ManagerLLM             interface{}            `json:"-"`
 	FunctionCallingLLM     interface{}            `json:"-"`
 	ChatLLM                interface{}            `json:"-"`
+	Cache                  Cache                  `json:"-"`                  // 为nil时使用默认的内存LRU缓存（条目1小时后过期）
+	ToolCache              agent.ToolCache        `json:"-"`                  // 开启缓存的工具共享的结果缓存，为nil时使用内存缓存
+	PersistToolCache       bool                   `json:"persist_tool_cache"` // 为true时工具缓存在多次Kickoff之间保留，否则每次Kickoff前清空
 	PromptFile             string                 `json:"prompt_file"`
 	OutputLogFile          string                 `json:"output_log_file"`
+	FingerprintSeed        string                 `json:"fingerprint_seed"`          // 按种子生成确定性指纹，为空时随机生成
+	OutputDir              string                 `json:"output_dir"`                // 任务输出文件相对路径的基础目录，为空时使用当前工作目录
+	ReplayEnabled          bool                   `json:"replay_enabled"`            // 为true时每个任务完成后写入执行快照，可用ReplayFrom从某个任务重新执行
+	ReplayDir              string                 `json:"replay_dir"`                // 执行快照的存储目录，为空时使用DefaultReplayDir
+	FinalOutputSchema      *agent.OutputSchema    `json:"-"`                         // 设置后所有任务完成时再执行一次综合，把任务输出整理为符合该模式的JSON
+	SynthesisAgent         agent.Agent            `json:"-"`                         // 执行综合的Agent，为nil时依次使用ManagerAgent和最后一个任务的Agent
+	SessionMemory          *memory.SessionMemory  `json:"-"`                         // WithSession的Kickoff在多次执行之间保留对话，为nil时使用内存存储
+	ConsensusAgentsPerTask int                    `json:"consensus_agents_per_task"` // Consensus模式下每个任务的候选Agent数，<=0表示所有Agent
+	JudgeLLM               llm.LLM                `json:"-"`                         // Consensus模式下评审候选输出的LLM，为nil时由ManagerAgent评审
+	ConsensusRubric        string                 `json:"consensus_rubric"`          // 评审提示的text/template模板，为空时使用DefaultConsensusRubric
+	AssignmentStrategy     AssignmentStrategy     `json:"-"`                         // 为没有指定Agent的任务选择执行者，为nil时使用RoundRobinAssignment
+	StreamOutput           bool                   `json:"stream_output"`             // 为true时KickoffWithProgress流式执行任务，llm_tokens进度带有输出增量
+	ContextInjection       agent.ContextInjection `json:"context_injection"`         // 每次Kickoff开始时解析一次，注入到所有Agent的系统提示，优先于Agent自身的设置
 	Metadata               map[string]interface{} `json:"metadata"`
+
+	// Crew记忆：MemoryEnabled时成功的Kickoff之后把任务输出提炼保存到长期记忆，之后的Kickoff检索相关的记忆注入第一个任务
+	MemoryStore              CrewMemoryStore `json:"memory_store"`               // 保存哪些任务的输出，为空时保存所有任务
+	MemoryRelevanceThreshold float64         `json:"memory_relevance_threshold"` // 查询词在记忆中的命中比例低于该值时不注入，0表示命中任一词即可
+	MaxMemoryContextTokens   int             `json:"max_memory_context_tokens"`  // 注入的记忆的token上限，<=0时使用DefaultMaxMemoryContextTokens
+
+	// 内容审核所有Agent发送给LLM的提示和LLM的答案，Agent自身设置了审核时使用Agent的设置
+	Moderation *agent.ModerationConfig `json:"-"`
+
+	// 提示注入防护：没有设置防护的Agent把工具结果、知识条目和记忆包在分隔标记中并按策略检测
+	PromptInjection *agent.PromptInjectionConfig `json:"-"`
+
+	// 可复现执行：每个任务使用由RandomSeed和任务序号派生的种子（agent.DeriveSeed），相同种子的Kickoff可以复现，
+	// 种子记录在输出的Metadata["random_seed"]中；严格模式下所有LLM调用的温度固定为0，
+	// 破坏确定性的组件记录在任务输出的Metadata["reproducibility_warnings"]中，没有设置种子时使用0
+	RandomSeed            *int `json:"random_seed,omitempty"`
+	StrictReproducibility bool `json:"strict_reproducibility"`
 }
 
 // DefaultCrewConfig 返回默认配置
@@ -165,8 +260,9 @@ func DefaultCrewConfig() *CrewConfig {
 		Process:                ProcessSequential,
 		Verbose:                false,
 		MemoryEnabled:          false,
-		CacheEnabled:           true,
+		CacheEnabled:           false, // 缓存需显式开启，避免重复Kickoff时重放旧的回答
 		MaxRPM:                 60,
+		MaxConcurrency:         4,
 		ShareCrew:              false,
 		PlanningEnabled:        false,
 		MaxExecutionTime:       30 * time.Minute,
@@ -192,13 +288,9 @@ type Memory interface {
 	Clear(ctx context.Context) error
 }
 
-// Cache 接口定义（占位符，后续实现）
-type Cache interface {
-	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
-	Get(ctx context.Context, key string) (interface{}, error)
-	Delete(ctx context.Context, key string) error
-	Clear(ctx context.Context) error
-}
+// Cache LLM响应缓存接口，CacheEnabled时由Crew注入所有Agent
+// llm包提供内存LRU实现（llm.NewLRUCache）和文件持久化实现（llm.NewFileCache）
+type Cache = llm.ResponseCache
 
 // CrewBuilder 定义Crew构建器模式
 type CrewBuilder interface {
@@ -0,0 +1,49 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// KickoffEndHookErrorMetadataKey OnKickoffEnd返回错误时记录在CrewOutput.Metadata中的键
+const KickoffEndHookErrorMetadataKey = "kickoff_end_hook_error"
+
+// runKickoffStartHook 调用OnKickoffStart，未设置时原样返回ctx
+func (c *BaseCrew) runKickoffStartHook(ctx context.Context) (context.Context, error) {
+	if c.onKickoffStart == nil {
+		return ctx, nil
+	}
+	hookCtx, err := c.onKickoffStart(ctx)
+	if err != nil {
+		c.logger.Error("kickoff start hook failed",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "error", Value: err},
+		)
+		return ctx, fmt.Errorf("kickoff start hook failed: %w", err)
+	}
+	if hookCtx == nil {
+		hookCtx = ctx
+	}
+	return hookCtx, nil
+}
+
+// runKickoffEndHook 调用OnKickoffEnd，错误记录日志并写入输出的Metadata，不覆盖执行结果
+func (c *BaseCrew) runKickoffEndHook(ctx context.Context, output *CrewOutput) {
+	if c.onKickoffEnd == nil {
+		return
+	}
+	if err := c.onKickoffEnd(ctx, output); err != nil {
+		c.logger.Error("kickoff end hook failed",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "error", Value: err},
+		)
+		if output != nil {
+			if output.Metadata == nil {
+				output.Metadata = make(map[string]interface{})
+			}
+			output.Metadata[KickoffEndHookErrorMetadataKey] = err.Error()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// dbPool 测试用的共享资源
+type dbPool struct {
+	queries int
+	closed  bool
+}
+
+var dbPoolKey = agent.NewContextKey[*dbPool]("db_pool")
+
+func newLifecycleTestCrew(t *testing.T, model *llmtest.ScriptedLLM, config *CrewConfig) *BaseCrew {
+	t.Helper()
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	analyst, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: "Analyst", Goal: "Query the database", Backstory: "Knows SQL",
+		LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	query := agent.NewBaseTool("query", "Run a query on the shared pool", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		pool, ok := dbPoolKey.Value(ctx)
+		if !ok {
+			return nil, errors.New("no database pool in context")
+		}
+		pool.queries++
+		return "42 rows", nil
+	})
+	if err := analyst.AddTool(query); err != nil {
+		t.Fatalf("failed to add tool: %v", err)
+	}
+
+	c := NewBaseCrew(config, eventBus, log)
+	c.AddAgent(analyst)
+	c.AddTask(agent.NewTaskWithOptions("Count the orders", "A number",
+		agent.WithName("count"), agent.WithAssignedAgent(analyst)))
+	c.AddTask(agent.NewTaskWithOptions("Count the customers", "A number",
+		agent.WithName("customers"), agent.WithAssignedAgent(analyst)))
+	return c
+}
+
+func queryCall() llm.ToolCall {
+	return llm.ToolCall{ID: "call_1", Type: "function", Function: llm.ToolCallFunction{Name: "query", Arguments: "{}"}}
+}
+
+func TestKickoffLifecycleHooksShareResources(t *testing.T) {
+	model := llmtest.NewScriptedLLM(
+		llmtest.Reply{ToolCalls: []llm.ToolCall{queryCall()}}, llmtest.Reply{Content: "42 orders"},
+		llmtest.Reply{ToolCalls: []llm.ToolCall{queryCall()}}, llmtest.Reply{Content: "42 customers"},
+	)
+
+	var pools []*dbPool
+	var endOutput *CrewOutput
+	c := newLifecycleTestCrew(t, model, &CrewConfig{
+		Name: "lifecycle-crew",
+		OnKickoffStart: func(ctx context.Context) (context.Context, error) {
+			pool := &dbPool{}
+			pools = append(pools, pool)
+			return dbPoolKey.WithValue(ctx, pool), nil
+		},
+		OnKickoffEnd: func(ctx context.Context, output *CrewOutput) error {
+			dbPoolKey.MustValue(ctx).closed = true
+			endOutput = output
+			return errors.New("pool close timed out")
+		},
+	})
+
+	output, err := c.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	model.Verify(t)
+
+	if len(pools) != 1 {
+		t.Fatalf("expected one pool per kickoff, got %d", len(pools))
+	}
+	if pools[0].queries != 2 || !pools[0].closed {
+		t.Errorf("expected both tasks to use the pool and the end hook to close it, got %+v", pools[0])
+	}
+	if endOutput != output {
+		t.Error("end hook should receive the kickoff output")
+	}
+	if output.Raw != "42 customers" || !output.Success {
+		t.Errorf("end hook error must not override the result: %+v", output)
+	}
+	if output.Metadata[KickoffEndHookErrorMetadataKey] != "pool close timed out" {
+		t.Errorf("expected end hook error in metadata, got %v", output.Metadata)
+	}
+}
+
+func TestKickoffStartHookErrorAborts(t *testing.T) {
+	model := llmtest.NewScriptedLLM()
+	endCalled := false
+	c := newLifecycleTestCrew(t, model, &CrewConfig{
+		Name: "lifecycle-crew",
+		OnKickoffStart: func(ctx context.Context) (context.Context, error) {
+			return nil, errors.New("database unavailable")
+		},
+		OnKickoffEnd: func(ctx context.Context, output *CrewOutput) error {
+			endCalled = true
+			return nil
+		},
+	})
+
+	_, err := c.Kickoff(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "kickoff start hook failed: database unavailable") {
+		t.Fatalf("expected start hook error, got %v", err)
+	}
+	if model.CallCount() != 0 {
+		t.Errorf("no task should run after the start hook fails, got %d LLM calls", model.CallCount())
+	}
+	if endCalled {
+		t.Error("end hook should not run when the start hook fails")
+	}
+
+	// 失败的开始钩子不会让Crew停留在执行中状态
+	if c.executing {
+		t.Error("crew should not be executing after an aborted kickoff")
+	}
+}
+
+func TestKickoffEndHookRunsOnFailure(t *testing.T) {
+	model := llmtest.NewScriptedLLM(llmtest.Reply{Err: errors.New("model unavailable")})
+	var pool *dbPool
+	c := newLifecycleTestCrew(t, model, &CrewConfig{
+		Name: "lifecycle-crew",
+		OnKickoffStart: func(ctx context.Context) (context.Context, error) {
+			pool = &dbPool{}
+			return dbPoolKey.WithValue(ctx, pool), nil
+		},
+		OnKickoffEnd: func(ctx context.Context, output *CrewOutput) error {
+			dbPoolKey.MustValue(ctx).closed = true
+			return nil
+		},
+	})
+
+	if _, err := c.Kickoff(context.Background(), nil); err == nil {
+		t.Fatal("expected kickoff to fail")
+	}
+	if pool == nil || !pool.closed {
+		t.Error("end hook should release resources when the kickoff fails")
+	}
+}
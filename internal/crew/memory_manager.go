@@ -3,6 +3,7 @@ package crew
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/ynl/greensoulai/internal/agent"
@@ -12,6 +13,7 @@ import (
 	"github.com/ynl/greensoulai/internal/memory/external"
 	"github.com/ynl/greensoulai/internal/memory/long_term"
 	"github.com/ynl/greensoulai/internal/memory/short_term"
+	"github.com/ynl/greensoulai/internal/memory/storage"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
@@ -61,7 +63,7 @@ type MemoryManagerConfig struct {
 func DefaultMemoryManagerConfig() MemoryManagerConfig {
 	return MemoryManagerConfig{
 		Enabled:          true,
-		StoragePath:      "data",
+		StoragePath:      memory.StorageDir(),
 		EmbedderProvider: "default",
 		EnableShortTerm:  true,
 		EnableLongTerm:   true,
@@ -96,6 +98,14 @@ func NewMemoryManager(
 	return mm
 }
 
+// longTermDBPath 长期记忆数据库文件路径，StoragePath为存储目录
+func (mm *MemoryManager) longTermDBPath() string {
+	if mm.config.StoragePath == "" {
+		return storage.DefaultLTMDBPath()
+	}
+	return filepath.Join(mm.config.StoragePath, storage.LTMDBFileName)
+}
+
 // initializeMemorySystems 初始化各种记忆系统
 func (mm *MemoryManager) initializeMemorySystems() {
 	// 初始化短期记忆
@@ -115,7 +125,7 @@ func (mm *MemoryManager) initializeMemorySystems() {
 	if mm.config.EnableLongTerm {
 		mm.longTermMemory = long_term.NewLongTermMemory(
 			nil, // 使用默认存储
-			mm.config.StoragePath,
+			mm.longTermDBPath(),
 			mm.eventBus,
 			mm.logger,
 		)
@@ -289,6 +299,14 @@ func (mm *MemoryManager) ClearMemory(ctx context.Context, memoryType string) err
 	return fmt.Errorf("memory system not available for type: %s", memoryType)
 }
 
+// ConfigureAgent 为尚未配置记忆的Agent注入按角色隔离的短期记忆
+func (mm *MemoryManager) ConfigureAgent(a agent.Agent) error {
+	if !mm.config.Enabled || mm.shortTermMemory == nil || a.GetMemory() != nil {
+		return nil
+	}
+	return a.SetMemory(short_term.NewAgentMemory(mm.shortTermMemory, a.GetRole()))
+}
+
 // GetContextualMemory 获取上下文记忆实例（用于高级使用）
 func (mm *MemoryManager) GetContextualMemory() *contextual.ContextualMemory {
 	return mm.contextualMemory
@@ -0,0 +1,161 @@
+package crew
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+)
+
+// TaskOutputSeparator AggregateRaw的默认分隔符，与Python版本聚合任务输出时使用的分隔符一致
+const TaskOutputSeparator = "\n\n----------\n\n"
+
+// AggregateRaw 按顺序用separator拼接任务输出中非空的Raw，nil输出被忽略
+func AggregateRaw(outputs []*agent.TaskOutput, separator string) string {
+	raws := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		if output != nil && output.Raw != "" {
+			raws = append(raws, output.Raw)
+		}
+	}
+	return strings.Join(raws, separator)
+}
+
+// AggregateRaw 用separator拼接所有任务输出的Raw
+// CrewOutput.Raw只是最后一个任务的输出，需要完整过程时使用该方法
+func (o *CrewOutput) AggregateRaw(separator string) string {
+	return AggregateRaw(o.TasksOutput, separator)
+}
+
+// GetTaskOutputByIndex 返回TasksOutput中第index个（从0开始）任务输出，越界时返回nil
+// TasksOutput只包含产生了输出的任务，Parallel流程中失败的任务不占位
+func (o *CrewOutput) GetTaskOutputByIndex(index int) *agent.TaskOutput {
+	if index < 0 || index >= len(o.TasksOutput) {
+		return nil
+	}
+	return o.TasksOutput[index]
+}
+
+// GetTaskOutputByName 按任务名称查找任务输出，没有名称的任务按任务ID匹配，找不到时返回nil
+func (o *CrewOutput) GetTaskOutputByName(name string) *agent.TaskOutput {
+	for _, output := range o.TasksOutput {
+		if output != nil && output.Name == name {
+			return output
+		}
+	}
+	for _, output := range o.TasksOutput {
+		if output != nil && output.Name == "" && output.Task == name {
+			return output
+		}
+	}
+	return nil
+}
+
+// crewOutputDocument ToJSON导出的文档
+type crewOutputDocument struct {
+	Raw            string                 `json:"raw"`
+	JSON           map[string]interface{} `json:"json,omitempty"`
+	Parsed         interface{}            `json:"parsed,omitempty"`
+	TasksOutput    []taskOutputDocument   `json:"tasks_output"`
+	TokenUsage     *UsageMetrics          `json:"token_usage,omitempty"`
+	Duration       float64                `json:"duration"` // 秒
+	Success        bool                   `json:"success"`
+	Error          string                 `json:"error,omitempty"`
+	Synthesis      *taskOutputDocument    `json:"synthesis,omitempty"`
+	SynthesisError string                 `json:"synthesis_error,omitempty"`
+	Fingerprint    string                 `json:"fingerprint,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// taskOutputDocument ToJSON导出的单个任务输出
+type taskOutputDocument struct {
+	Name             string                 `json:"name,omitempty"`
+	TaskID           string                 `json:"task_id"`
+	Agent            string                 `json:"agent"`
+	Description      string                 `json:"description"`
+	Raw              string                 `json:"raw"`
+	JSON             map[string]interface{} `json:"json,omitempty"`
+	Parsed           interface{}            `json:"parsed,omitempty"`
+	Model            string                 `json:"model,omitempty"`
+	TokensUsed       int                    `json:"tokens"`
+	PromptTokens     int                    `json:"prompt_tokens"`
+	CompletionTokens int                    `json:"completion_tokens"`
+	Cost             float64                `json:"cost"`
+	IsValid          bool                   `json:"is_valid"`
+	Skipped          bool                   `json:"skipped,omitempty"`
+}
+
+// ToJSON 把Crew的结果导出为JSON文档，包括各任务输出、使用统计、耗时和执行结果
+// 元数据、JSON和Parsed中无法序列化的值（如函数、通道）被丢弃，不会导致导出失败
+func (o *CrewOutput) ToJSON() ([]byte, error) {
+	document := crewOutputDocument{
+		Raw:         o.Raw,
+		JSON:        jsonSafeMap(o.JSON),
+		Parsed:      jsonSafeValue(o.Parsed),
+		TasksOutput: newTaskOutputDocuments(o.TasksOutput),
+		Duration:    o.Duration.Seconds(),
+		Success:     o.Success,
+		Fingerprint: o.Fingerprint,
+		CreatedAt:   o.CreatedAt,
+		Metadata:    jsonSafeMap(o.Metadata),
+	}
+	if o.TokenUsage != nil {
+		document.TokenUsage = o.TokenUsage.Snapshot()
+	}
+	if o.Error != nil {
+		document.Error = o.Error.Error()
+	}
+	if o.SynthesisOutput != nil {
+		document.Synthesis = &newTaskOutputDocuments([]*agent.TaskOutput{o.SynthesisOutput})[0]
+	}
+	if o.SynthesisError != nil {
+		document.SynthesisError = o.SynthesisError.Error()
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// TaskOutputsToJSON 按ToJSON中tasks_output的格式导出任务输出，用于导出尚未完成的执行的部分结果
+func TaskOutputsToJSON(outputs []*agent.TaskOutput) ([]byte, error) {
+	return json.Marshal(newTaskOutputDocuments(outputs))
+}
+
+// newTaskOutputDocuments 转换任务输出，nil输出被忽略
+func newTaskOutputDocuments(outputs []*agent.TaskOutput) []taskOutputDocument {
+	documents := make([]taskOutputDocument, 0, len(outputs))
+	for _, output := range outputs {
+		if output == nil {
+			continue
+		}
+		documents = append(documents, taskOutputDocument{
+			Name:             output.Name,
+			TaskID:           output.Task,
+			Agent:            output.Agent,
+			Description:      output.Description,
+			Raw:              output.Raw,
+			JSON:             jsonSafeMap(output.JSON),
+			Parsed:           jsonSafeValue(output.Parsed),
+			Model:            output.Model,
+			TokensUsed:       output.TokensUsed,
+			PromptTokens:     output.PromptTokens,
+			CompletionTokens: output.CompletionTokens,
+			Cost:             output.Cost,
+			IsValid:          output.IsValid,
+			Skipped:          agent.IsSkippedOutput(output),
+		})
+	}
+	return documents
+}
+
+// jsonSafeValue 值无法序列化为JSON时返回nil
+func jsonSafeValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return nil
+	}
+	return value
+}
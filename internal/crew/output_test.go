@@ -0,0 +1,133 @@
+package crew
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func TestCrewOutputFinalTaskAndAccessors(t *testing.T) {
+	log := logger.NewTestLogger()
+	crew := NewBaseCrew(DefaultCrewConfig(), events.NewEventBus(log), log)
+	crew.AddAgent(&MockAgent{id: "agent1", role: "Writer", goal: "Write", backstory: "Writer"})
+
+	research := agent.NewTaskWithOptions("Research the topic", "Notes")
+	research.SetName("research")
+	write := agent.NewTaskWithOptions("Write the article", "Article")
+	write.SetName("write")
+	crew.AddTask(research)
+	crew.AddTask(write)
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Raw != "Mock agent output for: Write the article" {
+		t.Errorf("expected raw output of the final task, got %q", result.Raw)
+	}
+	if aggregated := result.AggregateRaw(" | "); aggregated != "Mock agent output for: Research the topic | Mock agent output for: Write the article" {
+		t.Errorf("unexpected aggregated output %q", aggregated)
+	}
+
+	if output := result.GetTaskOutputByName("research"); output == nil || output.Description != "Research the topic" {
+		t.Errorf("expected research output by name, got %+v", output)
+	}
+	if output := result.GetTaskOutputByName(write.GetID()); output != nil {
+		t.Errorf("expected named tasks not to match by id, got %+v", output)
+	}
+	if output := result.GetTaskOutputByIndex(1); output == nil || output.Name != "write" {
+		t.Errorf("expected write output at index 1, got %+v", output)
+	}
+	if result.GetTaskOutputByIndex(2) != nil || result.GetTaskOutputByIndex(-1) != nil || result.GetTaskOutputByName("missing") != nil {
+		t.Error("expected nil for unknown task outputs")
+	}
+}
+
+func TestBuildCrewOutputUsesFinalTaskStructuredOutput(t *testing.T) {
+	crew := NewBaseCrew(DefaultCrewConfig(), events.NewEventBus(logger.NewTestLogger()), logger.NewTestLogger())
+
+	type article struct {
+		Title string `json:"title"`
+	}
+	first := &agent.TaskOutput{Raw: "notes", JSON: map[string]interface{}{"notes": 1}}
+	last := &agent.TaskOutput{Raw: `{"title":"Go"}`, JSON: map[string]interface{}{"title": "Go"}, Parsed: article{Title: "Go"}}
+
+	output := crew.buildCrewOutput(nil, []*agent.TaskOutput{first, nil, last}, last)
+	if output.Raw != `{"title":"Go"}` || output.JSON["title"] != "Go" {
+		t.Errorf("expected final task output, got raw %q json %v", output.Raw, output.JSON)
+	}
+	if parsed, ok := output.Parsed.(article); !ok || parsed.Title != "Go" {
+		t.Errorf("expected parsed final task output, got %#v", output.Parsed)
+	}
+	if len(output.TasksOutput) != 2 {
+		t.Errorf("expected incomplete tasks to be left out, got %d outputs", len(output.TasksOutput))
+	}
+}
+
+func TestCrewOutputToJSON(t *testing.T) {
+	usage := &UsageMetrics{}
+	task := &agent.TaskOutput{
+		Name:        "write",
+		Task:        "t1",
+		Agent:       "Writer",
+		Description: "Write the article",
+		Raw:         "article",
+		JSON:        map[string]interface{}{"title": "Go", "callback": func() {}},
+		TokensUsed:  42,
+		IsValid:     true,
+	}
+	usage.RecordTask(task)
+
+	output := &CrewOutput{
+		Raw:         "article",
+		Parsed:      make(chan int),
+		TasksOutput: []*agent.TaskOutput{task},
+		TokenUsage:  usage,
+		Duration:    1500 * time.Millisecond,
+		Success:     false,
+		Error:       errors.New("after kickoff callback failed"),
+		Metadata:    map[string]interface{}{"process": "sequential", "client": make(chan struct{})},
+	}
+
+	data, err := output.ToJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if document["duration"] != 1.5 || document["success"] != false || document["error"] != "after kickoff callback failed" {
+		t.Errorf("unexpected document: %s", data)
+	}
+	if _, ok := document["parsed"]; ok {
+		t.Errorf("expected unserializable parsed value to be dropped, got %s", data)
+	}
+	metadata := document["metadata"].(map[string]interface{})
+	if metadata["process"] != "sequential" || metadata["client"] != nil {
+		t.Errorf("unexpected metadata %v", metadata)
+	}
+	if tokenUsage := document["token_usage"].(map[string]interface{}); tokenUsage["total_tokens"] != float64(42) {
+		t.Errorf("unexpected token usage %v", tokenUsage)
+	}
+
+	tasks := document["tasks_output"].([]interface{})
+	if len(tasks) != 1 {
+		t.Fatalf("expected one task output, got %s", data)
+	}
+	first := tasks[0].(map[string]interface{})
+	if first["name"] != "write" || first["agent"] != "Writer" || first["raw"] != "article" || first["tokens"] != float64(42) {
+		t.Errorf("unexpected task output %v", first)
+	}
+	if taskJSON := first["json"].(map[string]interface{}); taskJSON["title"] != "Go" || taskJSON["callback"] != nil {
+		t.Errorf("unexpected task json %v", taskJSON)
+	}
+}
@@ -2,13 +2,17 @@ package crew
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/internal/llm"
 	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/security"
+	"github.com/ynl/greensoulai/pkg/tracing"
 )
 
 // runSequentialProcess 执行顺序流程
@@ -60,6 +64,7 @@ func (c *BaseCrew) runHierarchicalProcess(ctx context.Context, inputs map[string
 		c.eventBus.Emit(ctx, c, hierarchicalFailedEvent)
 		return nil, fmt.Errorf("failed to create manager agent: %w", err)
 	}
+	c.configureAgents()
 
 	c.logger.Info("manager agent configured successfully",
 		logger.Field{Key: "manager_role", Value: c.managerAgent.GetRole()},
@@ -81,113 +86,160 @@ func (c *BaseCrew) runHierarchicalProcess(ctx context.Context, inputs map[string
 	return result, err
 }
 
+// runParallelProcess 执行并行流程
+// 任务在其依赖（ContextTasks/DependsOn）全部完成后并发执行，并发数受MaxConcurrency限制。
+// 单个任务失败不会丢弃其他任务的输出，所有失败会汇总为一个错误与部分结果一起返回
+func (c *BaseCrew) runParallelProcess(ctx context.Context, inputs map[string]interface{}) (*CrewOutput, error) {
+	maxConcurrency := c.maxConcurrency
+	if maxConcurrency <= 0 || maxConcurrency > len(c.tasks) {
+		maxConcurrency = len(c.tasks)
+	}
+
+	c.logger.Info("starting parallel process execution",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "tasks_count", Value: len(c.tasks)},
+		logger.Field{Key: "agents_count", Value: len(c.agents)},
+		logger.Field{Key: "max_concurrency", Value: maxConcurrency},
+	)
+
+	// 发射Parallel流程开始事件
+	parallelStartEvent := NewParallelProcessStartedEvent(c.name, len(c.tasks), len(c.agents), maxConcurrency)
+	c.eventBus.Emit(ctx, c, parallelStartEvent)
+
+	// 执行任务
+	result, err := c.executeTasksParallel(ctx, c.tasks, inputs, maxConcurrency)
+
+	// 发射Parallel流程完成事件
+	if err == nil {
+		parallelCompletedEvent := NewParallelProcessCompletedEvent(c.name, len(result.TasksOutput))
+		c.eventBus.Emit(ctx, c, parallelCompletedEvent)
+	} else {
+		completed := 0
+		if result != nil {
+			completed = len(result.TasksOutput)
+		}
+		parallelFailedEvent := NewParallelProcessFailedEvent(c.name, completed, len(c.tasks)-completed, err.Error())
+		c.eventBus.Emit(ctx, c, parallelFailedEvent)
+	}
+
+	return result, err
+}
+
 // executeTasks 执行任务列表
-// 支持任务上下文传递，前一个任务的输出会作为后续任务的上下文
+// 任务按依赖关系的拓扑顺序执行，同一依赖层级中互不依赖的任务并发执行：
+// 未声明依赖的任务以之前各层所有任务的输出作为上下文，声明了依赖的任务只接收其上游任务的输出。
+// 标记为异步执行的任务在后台启动后立即继续，只有依赖它的任务或最终汇总需要其结果时才等待（对标Python的async_execution）
 func (c *BaseCrew) executeTasks(ctx context.Context, tasks []agent.Task, inputs map[string]interface{}) (*CrewOutput, error) {
-	tasksOutput := make([]*agent.TaskOutput, 0, len(tasks))
+	graph, err := newTaskGraph(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	// 返回时取消仍在执行的异步任务
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outputs := make([]*agent.TaskOutput, len(tasks))
+	completedOutputs := make([]*agent.TaskOutput, 0, len(tasks))
 	var lastOutput *agent.TaskOutput
-	var combinedRaw string
 
-	for i, task := range tasks {
-		c.logger.Info("executing task",
-			logger.Field{Key: "crew_name", Value: c.name},
-			logger.Field{Key: "task_index", Value: i},
-			logger.Field{Key: "task_id", Value: task.GetID()},
-			logger.Field{Key: "task_description", Value: task.GetDescription()},
-		)
+	// 等待异步任务完成并记录其输出，失败时返回带任务ID的错误
+	pending := make(map[int]*asyncTaskExecution)
+	join := func(i int) error {
+		execution, ok := pending[i]
+		if !ok {
+			return nil
+		}
+		delete(pending, i)
 
-		// 选择执行该任务的agent
-		selectedAgent, err := c.selectAgentForTask(task, i)
+		output, err := execution.wait(ctx)
 		if err != nil {
-			c.logger.Error("failed to select agent for task",
-				logger.Field{Key: "task_index", Value: i},
-				logger.Field{Key: "task_id", Value: task.GetID()},
-				logger.Field{Key: "error", Value: err},
-			)
-			return nil, fmt.Errorf("failed to select agent for task %d (%s): %w", i, task.GetID(), err)
+			return fmt.Errorf("async task %s failed: %w", taskLabel(tasks[i]), err)
 		}
+		outputs[i] = output
+		completedOutputs = append(completedOutputs, output)
+		return nil
+	}
 
-		c.logger.Debug("agent selected for task",
-			logger.Field{Key: "task_index", Value: i},
-			logger.Field{Key: "task_id", Value: task.GetID()},
-			logger.Field{Key: "selected_agent", Value: selectedAgent.GetRole()},
-		)
+	// 声明了依赖时按依赖层级执行，同一层中互不依赖的任务并发执行；
+	// 否则严格按顺序逐个执行，每个任务都能看到之前所有任务的输出
+	stages := graph.levels()
+	if !graph.hasDependencies() {
+		stages = make([][]int, len(graph.order))
+		for k, i := range graph.order {
+			stages[k] = []int{i}
+		}
+	}
 
-		// 准备任务上下文
-		taskContext := c.prepareTaskContext(inputs, tasksOutput, lastOutput)
-
-		// 将上下文应用到任务中
-		if len(taskContext) > 0 {
-			// 设置任务上下文 - 检查task是否支持SetContext方法
-			if contextSetter, ok := task.(interface{ SetContext(map[string]interface{}) }); ok {
-				contextSetter.SetContext(taskContext)
-				c.logger.Debug("task context applied",
-					logger.Field{Key: "task_id", Value: task.GetID()},
-					logger.Field{Key: "context_keys", Value: len(taskContext)},
-				)
-			} else {
-				// 如果task不支持SetContext，我们记录一个debug消息但不报错
-				c.logger.Debug("task does not support context setting",
-					logger.Field{Key: "task_id", Value: task.GetID()},
-					logger.Field{Key: "task_type", Value: fmt.Sprintf("%T", task)},
-				)
+	for _, stage := range stages {
+		// 等待本层任务所依赖的异步任务
+		for _, i := range stage {
+			for _, dep := range graph.dependencies[i] {
+				if err := join(dep); err != nil {
+					return nil, err
+				}
 			}
 		}
 
-		// 发射任务开始事件
-		taskStartEvent := NewTaskExecutionStartedEvent(i, task.GetDescription(), selectedAgent.GetRole())
-		c.eventBus.Emit(ctx, c, taskStartEvent)
-
-		// 执行任务
-		start := time.Now()
-		output, err := selectedAgent.Execute(ctx, task)
-		duration := time.Since(start)
-
-		if err != nil {
-			c.logger.Error("task execution failed",
-				logger.Field{Key: "task_index", Value: i},
-				logger.Field{Key: "agent_role", Value: selectedAgent.GetRole()},
-				logger.Field{Key: "error", Value: err},
-				logger.Field{Key: "duration", Value: duration},
-			)
+		// 在启动任务前准备好本层所有任务的上下文，并评估条件任务是否需要跳过
+		stageOutputs := make([]*agent.TaskOutput, len(stage))
+		runStage := make([]int, 0, len(stage))
+		runPositions := make([]int, 0, len(stage))
+		taskContexts := make([]map[string]interface{}, 0, len(stage))
+		for k, i := range stage {
+			if reused := c.reusedTaskOutput(ctx, tasks[i], i); reused != nil {
+				stageOutputs[k] = reused
+				continue
+			}
 
-			// 发射任务失败事件
-			taskFailedEvent := NewTaskExecutionFailedEvent(i, task.GetDescription(), selectedAgent.GetRole(), err.Error(), duration)
-			c.eventBus.Emit(ctx, c, taskFailedEvent)
+			var taskContext map[string]interface{}
+			previous := lastOutput
+			if len(graph.dependencies[i]) > 0 {
+				dependencyOutputs, _ := graph.dependencyOutputs(i, outputs)
+				taskContext = c.prepareDependencyContext(inputs, dependencyOutputs, len(completedOutputs))
+				previous = nil
+				if len(dependencyOutputs) > 0 {
+					previous = dependencyOutputs[len(dependencyOutputs)-1]
+				}
+			} else {
+				taskContext = c.prepareTaskContext(inputs, completedOutputs, lastOutput)
+			}
 
-			return nil, fmt.Errorf("task %d execution failed: %w", i, err)
+			skipped, err := c.skipConditionalTask(ctx, tasks[i], i, previous)
+			if err != nil {
+				return nil, err
+			}
+			if skipped != nil {
+				stageOutputs[k] = skipped
+				continue
+			}
+			if tasks[i].IsAsyncExecution() {
+				pending[i] = c.startAsyncTask(ctx, tasks[i], i, taskContext)
+				continue
+			}
+			runStage = append(runStage, i)
+			runPositions = append(runPositions, k)
+			taskContexts = append(taskContexts, taskContext)
 		}
 
-		// 执行任务回调
-		if c.taskCallback != nil {
-			if callbackErr := c.taskCallback(ctx, task, output); callbackErr != nil {
-				c.logger.Error("task callback failed",
-					logger.Field{Key: "task_index", Value: i},
-					logger.Field{Key: "error", Value: callbackErr},
-				)
+		if len(runStage) > 0 {
+			executed, err := c.executeStage(ctx, tasks, runStage, taskContexts)
+			if err != nil {
+				return nil, err
+			}
+			for j, k := range runPositions {
+				stageOutputs[k] = executed[j]
 			}
 		}
 
-		// 发射任务完成事件
-		taskCompletedEvent := NewTaskExecutionCompletedEvent(i, task.GetDescription(), selectedAgent.GetRole(), duration, true)
-		c.eventBus.Emit(ctx, c, taskCompletedEvent)
-
-		c.logger.Info("task execution completed",
-			logger.Field{Key: "task_index", Value: i},
-			logger.Field{Key: "agent_role", Value: selectedAgent.GetRole()},
-			logger.Field{Key: "duration", Value: duration},
-		)
-
-		// 存储输出
-		tasksOutput = append(tasksOutput, output)
-		lastOutput = output
-
-		// 累积原始输出
-		if output != nil && output.Raw != "" {
-			if combinedRaw != "" {
-				combinedRaw += "\n\n"
+		// 按任务原始顺序存储输出，异步任务的输出在等待时记录
+		for k, i := range stage {
+			if stageOutputs[k] == nil {
+				continue
 			}
-			combinedRaw += output.Raw
+			outputs[i] = stageOutputs[k]
+			completedOutputs = append(completedOutputs, stageOutputs[k])
+			lastOutput = stageOutputs[k]
 		}
 
 		// 检查上下文取消
@@ -199,9 +251,233 @@ func (c *BaseCrew) executeTasks(ctx context.Context, tasks []agent.Task, inputs
 		}
 	}
 
-	// 构建最终输出
+	// 最终汇总前按声明顺序等待剩余的异步任务
+	if len(pending) > 0 {
+		for i := range tasks {
+			if err := join(i); err != nil {
+				return nil, err
+			}
+		}
+		if lastStage := stages[len(stages)-1]; len(lastStage) > 0 {
+			lastOutput = outputs[lastStage[len(lastStage)-1]]
+		}
+	}
+
+	return c.buildCrewOutput(tasks, outputs, lastOutput), nil
+}
+
+// asyncTaskExecution 在后台执行的异步任务
+type asyncTaskExecution struct {
+	done   chan struct{}
+	output *agent.TaskOutput
+	err    error
+}
+
+// startAsyncTask 在goroutine中启动异步任务，ctx取消时任务随之停止
+func (c *BaseCrew) startAsyncTask(ctx context.Context, task agent.Task, index int, taskContext map[string]interface{}) *asyncTaskExecution {
+	c.logger.Debug("starting async task",
+		logger.Field{Key: "task_index", Value: index},
+		logger.Field{Key: "task_id", Value: task.GetID()},
+	)
+
+	execution := &asyncTaskExecution{done: make(chan struct{})}
+	go func() {
+		defer close(execution.done)
+		execution.output, execution.err = c.executeTask(ctx, task, index, taskContext)
+	}()
+	return execution
+}
+
+// wait 等待异步任务完成，ctx取消时立即返回
+func (e *asyncTaskExecution) wait(ctx context.Context) (*agent.TaskOutput, error) {
+	select {
+	case <-e.done:
+		return e.output, e.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("execution cancelled: %w", ctx.Err())
+	}
+}
+
+// executeStage 执行同一依赖层级的任务，多个任务时并发执行，并发数受maxConcurrency限制
+// 返回的输出与stage按索引对应；有任务失败时返回按stage顺序的第一个错误
+func (c *BaseCrew) executeStage(ctx context.Context, tasks []agent.Task, stage []int, taskContexts []map[string]interface{}) ([]*agent.TaskOutput, error) {
+	stageOutputs := make([]*agent.TaskOutput, len(stage))
+	if len(stage) == 1 {
+		output, err := c.executeTask(ctx, tasks[stage[0]], stage[0], taskContexts[0])
+		if err != nil {
+			return nil, err
+		}
+		stageOutputs[0] = output
+		return stageOutputs, nil
+	}
+
+	maxConcurrency := c.maxConcurrency
+	if maxConcurrency <= 0 || maxConcurrency > len(stage) {
+		maxConcurrency = len(stage)
+	}
+
+	stageErrors := make([]error, len(stage))
+	semaphore := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for k := range stage {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				stageErrors[k] = fmt.Errorf("execution cancelled: %w", ctx.Err())
+				return
+			}
+
+			stageOutputs[k], stageErrors[k] = c.executeTask(ctx, tasks[stage[k]], stage[k], taskContexts[k])
+		}(k)
+	}
+	wg.Wait()
+
+	for _, err := range stageErrors {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stageOutputs, nil
+}
+
+// executeTasksParallel 并发执行任务列表
+// 每个任务在其依赖全部完成后立即开始，并发数受maxConcurrency限制；依赖失败的任务会被跳过。
+// 返回的CrewOutput始终非nil：TasksOutput按任务原始顺序保存成功的输出，失败的任务索引记录在Metadata中
+func (c *BaseCrew) executeTasksParallel(ctx context.Context, tasks []agent.Task, inputs map[string]interface{}, maxConcurrency int) (*CrewOutput, error) {
+	graph, err := newTaskGraph(tasks)
+	if err != nil {
+		return c.buildCrewOutput(tasks, nil, nil), err
+	}
+
+	outputs := make([]*agent.TaskOutput, len(tasks))
+	taskErrors := make([]error, len(tasks))
+
+	// 跨goroutine聚合使用统计，UsageMetrics并发安全
+	usage := &UsageMetrics{}
+
+	recordResult := func(index int, output *agent.TaskOutput, err error) {
+		// 每个goroutine只写入自己的索引，无需加锁
+		outputs[index] = output
+		taskErrors[index] = err
+
+		if err != nil {
+			// 失败任务产生的输出仍计入开销
+			usage.RecordTask(nil)
+			usage.AddTaskOutput(output)
+			return
+		}
+		recordTaskUsage(usage, output)
+	}
+
+	done := make([]chan struct{}, len(tasks))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	semaphore := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i := range tasks {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer close(done[index])
+
+			if reused := c.reusedTaskOutput(ctx, tasks[index], index); reused != nil {
+				recordResult(index, reused, nil)
+				return
+			}
+
+			// 等待所有依赖任务结束
+			for _, dep := range graph.dependencies[index] {
+				select {
+				case <-done[dep]:
+				case <-ctx.Done():
+					recordResult(index, nil, fmt.Errorf("task %d execution cancelled: %w", index, ctx.Err()))
+					return
+				}
+			}
+
+			dependencyOutputs, failedDep := graph.dependencyOutputs(index, outputs)
+			if failedDep >= 0 {
+				recordResult(index, nil, fmt.Errorf("task %d skipped: dependency task %s did not complete", index, taskLabel(tasks[failedDep])))
+				return
+			}
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				recordResult(index, nil, fmt.Errorf("task %d execution cancelled: %w", index, ctx.Err()))
+				return
+			}
+
+			var previous *agent.TaskOutput
+			if len(dependencyOutputs) > 0 {
+				previous = dependencyOutputs[len(dependencyOutputs)-1]
+			}
+			skipped, err := c.skipConditionalTask(ctx, tasks[index], index, previous)
+			if err != nil || skipped != nil {
+				recordResult(index, skipped, err)
+				return
+			}
+
+			var taskContext map[string]interface{}
+			if len(graph.dependencies[index]) > 0 {
+				taskContext = c.prepareDependencyContext(inputs, dependencyOutputs, len(dependencyOutputs))
+			} else {
+				taskContext = c.prepareTaskContext(inputs, nil, nil)
+			}
+
+			output, err := c.executeTask(ctx, tasks[index], index, taskContext)
+			recordResult(index, output, err)
+		}(i)
+	}
+	wg.Wait()
+
+	// 按原始顺序汇总结果
+	var lastOutput *agent.TaskOutput
+	var errs []error
+	failedIndices := make([]int, 0)
+	for i, output := range outputs {
+		if taskErrors[i] != nil || output == nil {
+			failedIndices = append(failedIndices, i)
+			if taskErrors[i] != nil {
+				errs = append(errs, taskErrors[i])
+			}
+			continue
+		}
+		lastOutput = output
+	}
+
+	crewOutput := c.buildCrewOutput(tasks, outputs, lastOutput)
+	crewOutput.TokenUsage = usage
+	crewOutput.Success = len(failedIndices) == 0
+	crewOutput.Metadata["max_concurrency"] = maxConcurrency
+	crewOutput.Metadata["failed_tasks"] = failedIndices
+
+	if len(failedIndices) > 0 {
+		return crewOutput, fmt.Errorf("%d of %d tasks failed in parallel process: %w", len(failedIndices), len(tasks), errors.Join(errs...))
+	}
+
+	return crewOutput, nil
+}
+
+// buildCrewOutput 按任务原始顺序汇总输出
+// outputs与tasks按索引对应，nil表示任务未完成；lastOutput为最后完成的任务输出，Crew的Raw、JSON、Pydantic和Parsed取自它
+func (c *BaseCrew) buildCrewOutput(tasks []agent.Task, outputs []*agent.TaskOutput, lastOutput *agent.TaskOutput) *CrewOutput {
+	tasksOutput := make([]*agent.TaskOutput, 0, len(outputs))
+	for _, output := range outputs {
+		if output != nil {
+			tasksOutput = append(tasksOutput, output)
+		}
+	}
+
 	crewOutput := &CrewOutput{
-		Raw:         combinedRaw,
 		TasksOutput: tasksOutput,
 		CreatedAt:   time.Now(),
 		Success:     true,
@@ -212,27 +488,219 @@ func (c *BaseCrew) executeTasks(ctx context.Context, tasks []agent.Task, inputs
 		},
 	}
 
-	// 如果最后一个任务有JSON输出，使用它作为crew的JSON输出
-	if lastOutput != nil && lastOutput.JSON != nil {
+	if lastOutput != nil {
+		crewOutput.Raw = lastOutput.Raw
 		crewOutput.JSON = lastOutput.JSON
+		crewOutput.Pydantic = lastOutput.Pydantic
+		crewOutput.Parsed = lastOutput.Parsed
 	}
 
-	// 如果最后一个任务有Pydantic输出，使用它作为crew的Pydantic输出
-	if lastOutput != nil && lastOutput.Pydantic != nil {
-		crewOutput.Pydantic = lastOutput.Pydantic
+	return crewOutput
+}
+
+// skipConditionalTask 评估条件任务，条件不满足时返回跳过输出，需要执行时返回nil
+// previous为上一个任务（声明了依赖时为最后一个依赖任务）的输出
+func (c *BaseCrew) skipConditionalTask(ctx context.Context, task agent.Task, index int, previous *agent.TaskOutput) (*agent.TaskOutput, error) {
+	conditional, ok := task.(agent.ConditionalTask)
+	if !ok {
+		return nil, nil
 	}
 
-	return crewOutput, nil
+	shouldExecute, err := conditional.ShouldExecute(ctx, previous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate condition for task %d (%s): %w", index, task.GetID(), err)
+	}
+	if shouldExecute {
+		return nil, nil
+	}
+
+	c.logger.Info("conditional task skipped",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "task_index", Value: index},
+		logger.Field{Key: "task_id", Value: task.GetID()},
+	)
+	c.eventBus.Emit(ctx, c, NewTaskExecutionSkippedEvent(index, task.GetDescription(), "condition_not_met"))
+	statusTrackerFrom(ctx).taskSkipped()
+
+	output := conditional.GetSkippedTaskOutput()
+	c.recordTaskSnapshot(ctx, task, index, "", nil, output)
+	return output, nil
 }
 
-// selectAgentForTask 为任务选择合适的agent
-// 完全对齐Python版本的_get_agent_to_use逻辑
-func (c *BaseCrew) selectAgentForTask(task agent.Task, taskIndex int) (agent.Agent, error) {
-	// Python版本逻辑：
-	// if self.process == Process.hierarchical:
-	//     return self.manager_agent
-	// return task.agent
+// executeTask 在task.execute span中选择agent并执行单个任务，供各流程复用
+func (c *BaseCrew) executeTask(ctx context.Context, task agent.Task, index int, taskContext map[string]interface{}) (*agent.TaskOutput, error) {
+	ctx, span := startTaskSpan(ctx, task, index)
+	output, err := c.runTask(ctx, task, index, taskContext)
+	endTaskSpan(span, output, err)
+	return output, err
+}
 
+// runTask 选择agent并执行单个任务
+// 负责上下文注入、任务事件发射、委托记录和任务回调
+func (c *BaseCrew) runTask(ctx context.Context, task agent.Task, index int, taskContext map[string]interface{}) (*agent.TaskOutput, error) {
+	c.logger.Info("executing task",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "task_index", Value: index},
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "task_description", Value: task.GetDescription()},
+	)
+
+	// 选择执行该任务的agent
+	selectedAgent, assignmentReason, err := c.assignAgentForTask(ctx, task, index)
+	if err != nil {
+		c.logger.Error("failed to select agent for task",
+			logger.Field{Key: "task_index", Value: index},
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "error", Value: err},
+		)
+		return nil, fmt.Errorf("failed to select agent for task %d (%s): %w", index, task.GetID(), err)
+	}
+
+	tracing.SpanFromContext(ctx).SetAttributes(tracing.String("agent.role", selectedAgent.GetRole()))
+	finishStatus := statusTrackerFrom(ctx).taskStarted(index, task, selectedAgent)
+	succeeded := false
+	defer func() { finishStatus(!succeeded) }()
+	ctx = withProgressTask(ctx, index, selectedAgent.GetRole())
+	ctx = c.withTaskSeed(ctx, index)
+
+	c.logger.Debug("agent selected for task",
+		logger.Field{Key: "task_index", Value: index},
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "selected_agent", Value: selectedAgent.GetRole()},
+	)
+
+	// 会话历史只注入第一个任务，后续任务通过前序任务输出获得
+	if conversation := conversationFrom(ctx); conversation != nil && conversation.history != "" && index == 0 {
+		if taskContext == nil {
+			taskContext = make(map[string]interface{})
+		}
+		taskContext[ConversationContextKey] = conversation.history
+	}
+
+	// 之前执行保存的Crew记忆同样只注入第一个任务
+	if recalled := kickoffMemoryFrom(ctx); recalled != nil && index == 0 {
+		if taskContext == nil {
+			taskContext = make(map[string]interface{})
+		}
+		taskContext[CrewMemoryContextKey] = recalled.context
+	}
+
+	// 执行前钩子可以修改任务看到的上下文
+	taskContext, err = c.runBeforeTaskHooks(ctx, task, taskContext)
+	if err != nil {
+		c.logger.Error("before task hook failed",
+			logger.Field{Key: "task_index", Value: index},
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "error", Value: err},
+		)
+		return nil, fmt.Errorf("task %d (%s): %w", index, task.GetID(), err)
+	}
+
+	// 将上下文应用到任务中，Agent会在构建提示时渲染该上下文
+	if len(taskContext) > 0 {
+		task.SetContext(taskContext)
+		c.logger.Debug("task context applied",
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "context_keys", Value: len(taskContext)},
+		)
+	}
+
+	// 发射任务开始事件
+	taskStartEvent := NewTaskExecutionStartedEvent(index, task.GetDescription(), selectedAgent.GetRole())
+	c.stampFingerprints(&taskStartEvent.BaseEvent, selectedAgent)
+	c.eventBus.Emit(ctx, c, taskStartEvent)
+
+	// 执行任务，收集执行期间（包括同事之间嵌套委托）产生的委托记录
+	execCtx, delegations := withDelegationCollector(ctx)
+	start := time.Now()
+	var output *agent.TaskOutput
+	switch {
+	case c.process == ProcessConsensus:
+		output, err = c.executeConsensusRound(execCtx, task, index, selectedAgent)
+	case c.shouldStreamTask(execCtx, task):
+		output, err = c.executeTaskStream(execCtx, task, selectedAgent)
+	default:
+		output, err = selectedAgent.Execute(execCtx, task)
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		c.logger.Error("task execution failed",
+			logger.Field{Key: "task_index", Value: index},
+			logger.Field{Key: "agent_role", Value: selectedAgent.GetRole()},
+			logger.Field{Key: "error", Value: err},
+			logger.Field{Key: "duration", Value: duration},
+		)
+
+		// 发射任务失败事件
+		taskFailedEvent := NewTaskExecutionFailedEvent(index, task.GetDescription(), selectedAgent.GetRole(), err.Error(), duration)
+		c.stampFingerprints(&taskFailedEvent.BaseEvent, selectedAgent)
+		c.eventBus.Emit(ctx, c, taskFailedEvent)
+
+		return nil, fmt.Errorf("task %d execution failed: %w", index, err)
+	}
+
+	// 记录委托给同事的工作
+	recordDelegations(output, delegations.take())
+
+	// 记录任务名称，用于按名称查找任务输出
+	if output != nil && output.Name == "" {
+		output.Name = task.GetName()
+	}
+	if output != nil {
+		if output.Metadata == nil {
+			output.Metadata = make(map[string]interface{})
+		}
+		output.Metadata[AssignmentReasonMetadataKey] = assignmentReason
+	}
+
+	// 执行后钩子可以改写输出，改写后的输出会传入后续任务
+	output, err = c.runAfterTaskHooks(ctx, task, output)
+	if err != nil {
+		c.logger.Error("after task hook failed",
+			logger.Field{Key: "task_index", Value: index},
+			logger.Field{Key: "task_id", Value: task.GetID()},
+			logger.Field{Key: "error", Value: err},
+		)
+
+		taskFailedEvent := NewTaskExecutionFailedEvent(index, task.GetDescription(), selectedAgent.GetRole(), err.Error(), duration)
+		c.stampFingerprints(&taskFailedEvent.BaseEvent, selectedAgent)
+		c.eventBus.Emit(ctx, c, taskFailedEvent)
+
+		return nil, fmt.Errorf("task %d (%s): %w", index, task.GetID(), err)
+	}
+
+	c.recordTaskSnapshot(ctx, task, index, selectedAgent.GetRole(), taskContext, output)
+
+	// 执行任务回调，回调在钩子之后执行，只用于通知
+	if c.taskCallback != nil {
+		if callbackErr := c.taskCallback(ctx, task, output); callbackErr != nil {
+			c.logger.Error("task callback failed",
+				logger.Field{Key: "task_index", Value: index},
+				logger.Field{Key: "error", Value: callbackErr},
+			)
+		}
+	}
+
+	// 发射任务完成事件
+	taskCompletedEvent := NewTaskExecutionCompletedEvent(index, task.GetDescription(), selectedAgent.GetRole(), duration, true)
+	c.stampFingerprints(&taskCompletedEvent.BaseEvent, selectedAgent)
+	c.eventBus.Emit(ctx, c, taskCompletedEvent)
+
+	c.logger.Info("task execution completed",
+		logger.Field{Key: "task_index", Value: index},
+		logger.Field{Key: "agent_role", Value: selectedAgent.GetRole()},
+		logger.Field{Key: "duration", Value: duration},
+	)
+
+	succeeded = true
+	return output, nil
+}
+
+// selectAgentForTask 为任务选择合适的agent，返回选中的Agent和分配理由
+// 依次考虑：层级模式的管理器、任务预分配的Agent、任务按角色或ID指定的Agent，最后由分配策略选择。
+// dryRun为true时（Validate演练）分配策略不调用LLM
+func (c *BaseCrew) selectAgentForTask(ctx context.Context, task agent.Task, taskIndex int, dryRun bool) (agent.Agent, string, error) {
 	// 1. 如果是Hierarchical模式且有管理器agent，直接返回管理器
 	if c.process == ProcessHierarchical {
 		if c.managerAgent != nil {
@@ -240,9 +708,9 @@ func (c *BaseCrew) selectAgentForTask(task agent.Task, taskIndex int) (agent.Age
 				logger.Field{Key: "task_id", Value: task.GetID()},
 				logger.Field{Key: "manager_role", Value: c.managerAgent.GetRole()},
 			)
-			return c.managerAgent, nil
+			return c.managerAgent, "hierarchical process: executed by the manager agent", nil
 		}
-		return nil, fmt.Errorf("hierarchical process requires manager agent")
+		return nil, "", fmt.Errorf("hierarchical process requires manager agent")
 	}
 
 	// 2. 优先检查任务是否已经指定了Agent（Python版本中的task.agent）
@@ -251,49 +719,79 @@ func (c *BaseCrew) selectAgentForTask(task agent.Task, taskIndex int) (agent.Age
 			logger.Field{Key: "task_id", Value: task.GetID()},
 			logger.Field{Key: "agent_role", Value: taskAgent.GetRole()},
 		)
-		return taskAgent, nil
+		return taskAgent, "assigned by the task", nil
 	}
 
-	// 3. Sequential模式的默认分配逻辑（当任务没有预分配Agent时）
-	if c.process == ProcessSequential {
-		if len(c.agents) == 0 {
-			return nil, fmt.Errorf("no agents available for sequential execution")
-		}
+	if len(c.agents) == 0 {
+		return nil, "", fmt.Errorf("no agents available for %s execution", c.process)
+	}
 
-		// 按索引分配agent（1:1映射优先）
-		if taskIndex < len(c.agents) {
-			selectedAgent := c.agents[taskIndex]
-			c.logger.Debug("sequential agent assignment (1:1)",
-				logger.Field{Key: "task_index", Value: taskIndex},
-				logger.Field{Key: "agent_index", Value: taskIndex},
-				logger.Field{Key: "agent_role", Value: selectedAgent.GetRole()},
-			)
-			return selectedAgent, nil
+	// 3. 任务按角色或ID指定了Agent时在成员中查找，找不到时报错而不是退回其他Agent
+	if role := task.GetAgentRole(); role != "" {
+		member := findAgentByRole(c.agents, role)
+		if member == nil {
+			return nil, "", fmt.Errorf("task names agent %q, which is not a member of the crew (agents: %s)",
+				role, strings.Join(agentRoles(c.agents), ", "))
 		}
+		return member, fmt.Sprintf("agent role %q named by the task", role), nil
+	}
 
-		// 如果任务数量超过agent数量，循环使用agent
-		agentIndex := taskIndex % len(c.agents)
-		selectedAgent := c.agents[agentIndex]
-		c.logger.Debug("sequential agent assignment (cyclic)",
-			logger.Field{Key: "task_index", Value: taskIndex},
-			logger.Field{Key: "agent_index", Value: agentIndex},
-			logger.Field{Key: "agent_role", Value: selectedAgent.GetRole()},
-			logger.Field{Key: "total_agents", Value: len(c.agents)},
-		)
-		return selectedAgent, nil
+	// 4. 由分配策略选择
+	strategy := c.assignmentStrategy
+	if strategy == nil {
+		strategy = RoundRobinAssignment{}
+	}
+	decision, err := strategy.Assign(ctx, AssignmentRequest{
+		Task:      task,
+		TaskIndex: taskIndex,
+		Agents:    c.agents,
+		Load:      c.assignmentLoadSnapshot(),
+		DryRun:    dryRun,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("%s assignment failed: %w", strategy.Name(), err)
+	}
+	if decision == nil || decision.Agent == nil {
+		return nil, "", fmt.Errorf("%s assignment selected no agent", strategy.Name())
 	}
 
-	// 4. 其他情况的默认处理
-	if len(c.agents) > 0 {
-		defaultAgent := c.agents[0]
-		c.logger.Debug("using default (first) agent",
-			logger.Field{Key: "task_id", Value: task.GetID()},
-			logger.Field{Key: "agent_role", Value: defaultAgent.GetRole()},
-		)
-		return defaultAgent, nil
+	c.logger.Debug("agent assigned by strategy",
+		logger.Field{Key: "task_index", Value: taskIndex},
+		logger.Field{Key: "strategy", Value: strategy.Name()},
+		logger.Field{Key: "agent_role", Value: decision.Agent.GetRole()},
+		logger.Field{Key: "reason", Value: decision.Reason},
+	)
+	return decision.Agent, decision.Reason, nil
+}
+
+// assignAgentForTask 为执行的任务选择Agent并计入该Agent本次Kickoff的任务数
+// 选择和计数在同一把锁内完成，Parallel流程中同时开始的任务也能看到彼此的分配
+func (c *BaseCrew) assignAgentForTask(ctx context.Context, task agent.Task, taskIndex int) (agent.Agent, string, error) {
+	c.assignmentMu.Lock()
+	defer c.assignmentMu.Unlock()
+
+	selected, reason, err := c.selectAgentForTask(ctx, task, taskIndex, false)
+	if err != nil {
+		return nil, "", err
 	}
+	c.assignmentLoad[selected.GetID()]++
+	return selected, reason, nil
+}
+
+// assignmentLoadSnapshot 返回每个Agent已分配任务数的副本
+func (c *BaseCrew) assignmentLoadSnapshot() map[string]int {
+	load := make(map[string]int, len(c.assignmentLoad))
+	for id, count := range c.assignmentLoad {
+		load[id] = count
+	}
+	return load
+}
 
-	return nil, fmt.Errorf("no available agents for task %s", task.GetID())
+// resetAssignmentLoad 在每次Kickoff开始时清空任务分配计数
+func (c *BaseCrew) resetAssignmentLoad() {
+	c.assignmentMu.Lock()
+	defer c.assignmentMu.Unlock()
+	c.assignmentLoad = make(map[string]int)
 }
 
 // getTaskAssignedAgent 检查任务是否已指定Agent
@@ -339,34 +837,33 @@ func (c *BaseCrew) prepareTaskContext(inputs map[string]interface{}, tasksOutput
 	context["crew_process"] = c.process.String()
 	context["total_tasks"] = len(c.tasks)
 	context["completed_tasks"] = len(tasksOutput)
+	if c.outputDir != "" {
+		context["output_directory"] = c.outputDir
+	}
+	if fingerprint := c.GetFingerprint(); fingerprint != nil {
+		context["crew_fingerprint"] = fingerprint.GetUUID()
+	}
 
 	return context
 }
 
-// aggregateRawOutputsFromTaskOutputs 聚合任务输出为上下文字符串
-// 完全对齐Python版本的aggregate_raw_outputs_from_task_outputs函数
-func (c *BaseCrew) aggregateRawOutputsFromTaskOutputs(taskOutputs []*agent.TaskOutput) string {
-	if len(taskOutputs) == 0 {
-		return ""
-	}
-
-	// Python版本使用的分隔符："\n\n----------\n\n"
-	dividers := "\n\n----------\n\n"
-
-	// 收集所有任务的raw输出
-	var rawOutputs []string
-	for _, output := range taskOutputs {
-		if output != nil && output.Raw != "" {
-			rawOutputs = append(rawOutputs, output.Raw)
-		}
+// prepareDependencyContext 为声明了依赖的任务准备上下文
+// 只包含其上游任务的输出，而不是之前所有任务的输出
+func (c *BaseCrew) prepareDependencyContext(inputs map[string]interface{}, dependencyOutputs []*agent.TaskOutput, completedTasks int) map[string]interface{} {
+	var lastOutput *agent.TaskOutput
+	if len(dependencyOutputs) > 0 {
+		lastOutput = dependencyOutputs[len(dependencyOutputs)-1]
 	}
 
-	if len(rawOutputs) == 0 {
-		return ""
-	}
+	context := c.prepareTaskContext(inputs, dependencyOutputs, lastOutput)
+	context["completed_tasks"] = completedTasks
+	return context
+}
 
-	// 使用分隔符连接，完全对标Python版本
-	return strings.Join(rawOutputs, dividers)
+// aggregateRawOutputsFromTaskOutputs 聚合任务输出为上下文字符串
+// 完全对齐Python版本的aggregate_raw_outputs_from_task_outputs函数
+func (c *BaseCrew) aggregateRawOutputsFromTaskOutputs(taskOutputs []*agent.TaskOutput) string {
+	return AggregateRaw(taskOutputs, TaskOutputSeparator)
 }
 
 // createManagerAgent 创建或配置管理器agent
@@ -415,11 +912,17 @@ func (c *BaseCrew) createManagerAgent() error {
 
 // validateManagerAgent 验证管理器Agent配置
 func (c *BaseCrew) validateManagerAgent(manager agent.Agent) error {
-	// 检查管理器agent是否有工具（在Python版本中，管理器不应该有工具）
-	if tools := manager.GetTools(); len(tools) > 0 {
+	// 检查管理器agent是否有工具（在Python版本中，管理器除委托工具外不应该有工具）
+	toolsCount := 0
+	for _, tool := range manager.GetTools() {
+		if !isDelegationTool(tool) {
+			toolsCount++
+		}
+	}
+	if toolsCount > 0 {
 		c.logger.Warn("manager agent has tools, this may cause issues in hierarchical mode",
 			logger.Field{Key: "manager_role", Value: manager.GetRole()},
-			logger.Field{Key: "tools_count", Value: len(tools)},
+			logger.Field{Key: "tools_count", Value: toolsCount},
 		)
 		// 注意：在Go版本中我们先警告但不强制移除工具，可以根据需要调整
 	}
@@ -442,10 +945,10 @@ func (c *BaseCrew) configureManagerAgentForDelegation(manager agent.Agent) error
 		return fmt.Errorf("failed to set delegation config: %w", err)
 	}
 
-	// TODO: 创建AgentTools来管理其他agents
-	// 这里需要实现类似Python版本中AgentTools的功能
-	// agentTools := NewAgentTools(c.agents)
-	// manager.SetTools(agentTools.GetTools())
+	// 为管理器添加委托工具，对标Python版本的AgentTools
+	if err := c.attachDelegationTools(manager); err != nil {
+		return err
+	}
 
 	c.logger.Debug("manager agent configured for delegation",
 		logger.Field{Key: "manager_role", Value: manager.GetRole()},
@@ -474,6 +977,8 @@ func (c *BaseCrew) createDefaultManagerAgent() (agent.Agent, error) {
 		Logger:         c.logger,
 		SecurityConfig: c.securityConfig,
 	}
+	// 管理器沿用crew的安全设置，但使用自己的指纹
+	config.SecurityConfig.Fingerprint = security.NewFingerprint()
 
 	// 创建管理器Agent
 	managerAgent, err := agent.NewBaseAgent(config)
@@ -486,11 +991,156 @@ func (c *BaseCrew) createDefaultManagerAgent() (agent.Agent, error) {
 		return nil, fmt.Errorf("failed to initialize manager agent: %w", err)
 	}
 
-	// TODO: 添加AgentTools以管理其他agents
-	// agentTools := NewAgentTools(c.agents)
-	// for _, tool := range agentTools.GetTools() {
-	//     managerAgent.AddTool(tool)
-	// }
+	// 添加AgentTools以管理其他agents
+	if err := c.attachDelegationTools(managerAgent); err != nil {
+		return nil, err
+	}
 
 	return managerAgent, nil
 }
+
+// attachDelegationTools 为管理器Agent添加委托工具（delegate_work/ask_question）
+// 重复调用时复用已有工具，只更新可委托的同事列表
+func (c *BaseCrew) attachDelegationTools(manager agent.Agent) error {
+	if c.agentTools == nil || c.agentTools.delegator != manager {
+		c.agentTools = NewAgentTools(manager, c.agents, c.logger)
+		c.agentTools.SetMaxDepth(c.maxDelegationDepth)
+	} else {
+		c.agentTools.SetAgents(c.agents)
+	}
+
+	existing := make(map[string]bool)
+	for _, tool := range manager.GetTools() {
+		if tool != nil {
+			existing[tool.GetName()] = true
+		}
+	}
+
+	for _, tool := range c.agentTools.GetTools() {
+		if existing[tool.GetName()] {
+			continue
+		}
+		if err := manager.AddTool(tool); err != nil {
+			return fmt.Errorf("failed to add delegation tool %s: %w", tool.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// runBeforeTaskHooks 按注册顺序执行任务前钩子，返回任务最终看到的上下文
+func (c *BaseCrew) runBeforeTaskHooks(ctx context.Context, task agent.Task, taskContext map[string]interface{}) (map[string]interface{}, error) {
+	c.mu.RLock()
+	hooks := append([]BeforeTaskHook(nil), c.beforeTaskHooks...)
+	c.mu.RUnlock()
+
+	for i, hook := range hooks {
+		updated, err := hook(ctx, task, taskContext)
+		if err != nil {
+			return nil, fmt.Errorf("before task hook %d failed: %w", i+1, err)
+		}
+		if updated != nil {
+			taskContext = updated
+		}
+	}
+	return taskContext, nil
+}
+
+// runAfterTaskHooks 按注册顺序执行任务后钩子，返回改写后的输出
+func (c *BaseCrew) runAfterTaskHooks(ctx context.Context, task agent.Task, output *agent.TaskOutput) (*agent.TaskOutput, error) {
+	c.mu.RLock()
+	hooks := append([]AfterTaskHook(nil), c.afterTaskHooks...)
+	c.mu.RUnlock()
+
+	for i, hook := range hooks {
+		updated, err := hook(ctx, task, output)
+		if err != nil {
+			return nil, fmt.Errorf("after task hook %d failed: %w", i+1, err)
+		}
+		if updated != nil {
+			output = updated
+		}
+	}
+	return output, nil
+}
+
+// attachCoworkerTools 为允许委托的Agent注入向同事提问和委托工作的工具，返回移除这些工具的函数
+// 管理器Agent由attachDelegationTools单独配置；Agent已有同名工具时不重复注入
+func (c *BaseCrew) attachCoworkerTools() func() {
+	c.mu.RLock()
+	agents := append([]agent.Agent(nil), c.agents...)
+	manager := c.managerAgent
+	maxDepth := c.maxDelegationDepth
+	c.mu.RUnlock()
+
+	if len(agents) < 2 {
+		return func() {}
+	}
+
+	attached := make(map[agent.Agent][]string)
+	for _, a := range agents {
+		if a == nil || a == manager || !a.GetExecutionConfig().AllowDelegation {
+			continue
+		}
+
+		existing := make(map[string]bool)
+		for _, tool := range a.GetTools() {
+			if tool != nil {
+				existing[tool.GetName()] = true
+			}
+		}
+
+		coworkerTools := NewAgentTools(a, agents, c.logger)
+		coworkerTools.SetMaxDepth(maxDepth)
+		for _, tool := range coworkerTools.GetTools() {
+			if existing[tool.GetName()] {
+				continue
+			}
+			if err := a.AddTool(tool); err != nil {
+				c.logger.Warn("failed to add coworker tool",
+					logger.Field{Key: "agent_role", Value: a.GetRole()},
+					logger.Field{Key: "tool", Value: tool.GetName()},
+					logger.Field{Key: "error", Value: err},
+				)
+				continue
+			}
+			attached[a] = append(attached[a], tool.GetName())
+		}
+	}
+
+	return func() {
+		for a, names := range attached {
+			for _, name := range names {
+				if err := a.RemoveTool(name); err != nil {
+					c.logger.Warn("failed to remove coworker tool",
+						logger.Field{Key: "agent_role", Value: a.GetRole()},
+						logger.Field{Key: "tool", Value: name},
+						logger.Field{Key: "error", Value: err},
+					)
+				}
+			}
+		}
+	}
+}
+
+// recordDelegations 将本次任务中的委托记录写入任务输出的元数据
+func recordDelegations(output *agent.TaskOutput, records []DelegationRecord) {
+	if output == nil || len(records) == 0 {
+		return
+	}
+
+	workers := make([]string, 0, len(records))
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if record.Success && !seen[record.Coworker] {
+			seen[record.Coworker] = true
+			workers = append(workers, record.Coworker)
+		}
+	}
+
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+	output.Metadata["delegated_to"] = workers
+	output.Metadata["delegations"] = records
+}
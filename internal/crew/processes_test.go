@@ -3,100 +3,40 @@ package crew
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
-// MockLLM 用于测试的Mock LLM
-type MockLLM struct {
-	responses []string
-	callCount int
-}
-
-func NewMockLLM(responses ...string) *MockLLM {
-	return &MockLLM{
-		responses: responses,
-		callCount: 0,
-	}
-}
-
-func (m *MockLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
-	if m.callCount >= len(m.responses) {
-		return &llm.Response{
-			Content:      "Default mock response",
-			Model:        "mock-model",
-			FinishReason: "stop",
+// NewMockLLM 创建crew测试共用的脚本化LLM：按顺序返回responses，用完后返回默认响应
+// 每次调用都被记录，通过Prompts()、CallCount()检查发给LLM的内容
+func NewMockLLM(responses ...string) *llmtest.ScriptedLLM {
+	replies := make([]llmtest.Reply, len(responses))
+	for i, response := range responses {
+		replies[i] = llmtest.Reply{
+			Content: response,
 			Usage: llm.Usage{
 				PromptTokens:     5,
-				CompletionTokens: 5,
-				TotalTokens:      10,
+				CompletionTokens: len(response),
+				TotalTokens:      5 + len(response),
 				Cost:             0.001,
 			},
-		}, nil
-	}
-
-	response := m.responses[m.callCount]
-	m.callCount++
-
-	return &llm.Response{
-		Content:      response,
-		Model:        "mock-model",
-		FinishReason: "stop",
-		Usage: llm.Usage{
-			PromptTokens:     5,
-			CompletionTokens: len(response),
-			TotalTokens:      5 + len(response),
-			Cost:             0.001,
-		},
-	}, nil
-}
-
-func (m *MockLLM) GetModel() string {
-	return "mock-model"
-}
-
-func (m *MockLLM) SupportsFunctionCalling() bool {
-	return false
-}
-
-func (m *MockLLM) GetContextWindowSize() int {
-	return 4096
-}
-
-func (m *MockLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
-	responseChan := make(chan llm.StreamResponse, 1)
-
-	go func() {
-		defer close(responseChan)
-
-		response, err := m.Call(ctx, messages, options)
-		if err != nil {
-			responseChan <- llm.StreamResponse{Error: err}
-			return
-		}
-
-		responseChan <- llm.StreamResponse{
-			Delta:        response.Content,
-			Usage:        &response.Usage,
-			FinishReason: response.FinishReason,
 		}
-	}()
-
-	return responseChan, nil
-}
-
-func (m *MockLLM) SetEventBus(eventBus events.EventBus) {
-	// Mock implementation - no-op
-}
-
-func (m *MockLLM) Close() error {
-	return nil
+	}
+	return llmtest.NewScriptedLLM(replies...).
+		WithModel("mock-model").
+		WithContextWindow(4096).
+		WithDefault(llmtest.Reply{
+			Content: "Default mock response",
+			Usage:   llm.Usage{PromptTokens: 5, CompletionTokens: 5, TotalTokens: 10, Cost: 0.001},
+		})
 }
 
 // ContextAwareTask 可以记录和返回上下文的Task
@@ -311,6 +251,283 @@ func TestSequentialContextPassing(t *testing.T) {
 	}
 }
 
+func TestSequentialProcessSkipsConditionalTask(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	var skippedEvents int
+	var mutex sync.Mutex
+	eventBus.Subscribe("task_execution_skipped", func(ctx context.Context, event events.Event) error {
+		mutex.Lock()
+		skippedEvents++
+		mutex.Unlock()
+		return nil
+	})
+
+	// 第一个任务的输出不包含"escalate"，升级任务应被跳过
+	condition, err := agent.RegexCondition("escalate")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	escalate := agent.WrapConditionalTask(agent.NewBaseTask("Escalate ticket", "Escalation report"), condition)
+	report := NewContextAwareTask("report", "Write report", "Report")
+
+	crew.AddAgent(&MockAgent{id: "a1", role: "Triage"})
+	crew.AddAgent(&MockAgent{id: "a2", role: "Escalation"})
+	crew.AddAgent(&MockAgent{id: "a3", role: "Reporter"})
+	crew.AddTask(&MockTask{id: "triage", description: "Triage ticket", expectedOutput: "Decision"})
+	crew.AddTask(escalate)
+	crew.AddTask(report)
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	if len(result.TasksOutput) != 3 || !agent.IsSkippedOutput(result.TasksOutput[1]) {
+		t.Fatalf("expected the second task output to be skipped, got %+v", result.TasksOutput)
+	}
+
+	// 下游任务的上下文明确显示任务被跳过
+	lastOutput, _ := report.GetContext()["last_task_output"].(string)
+	if !strings.HasPrefix(lastOutput, "[SKIPPED]") {
+		t.Errorf("expected downstream context to show the skip, got %q", lastOutput)
+	}
+	if !strings.Contains(result.AggregateRaw(TaskOutputSeparator), "[SKIPPED]") {
+		t.Error("expected aggregated output to include the skipped task")
+	}
+
+	metrics := result.TokenUsage
+	if metrics.SkippedTasks != 1 || metrics.FailedTasks != 0 || metrics.SuccessfulTasks != 2 {
+		t.Errorf("unexpected metrics: skipped=%d failed=%d successful=%d",
+			metrics.SkippedTasks, metrics.FailedTasks, metrics.SuccessfulTasks)
+	}
+
+	// 事件异步分发
+	deadline := time.Now().Add(time.Second)
+	for {
+		mutex.Lock()
+		count := skippedEvents
+		mutex.Unlock()
+		if count > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if skippedEvents != 1 {
+		t.Errorf("expected 1 skipped event, got %d", skippedEvents)
+	}
+}
+
+func TestParallelProcessSkipsConditionalTask(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+	crew.SetProcess(ProcessParallel)
+
+	followUp := agent.WrapConditionalTask(
+		agent.NewTaskWithOptions("Follow up", "Follow-up", agent.WithDependsOn("check")),
+		func(output *agent.TaskOutput) bool { return strings.Contains(output.Raw, "needs follow-up") },
+	)
+
+	crew.AddAgent(&MockAgent{id: "a1", role: "Checker"})
+	crew.AddAgent(&MockAgent{id: "a2", role: "FollowUp"})
+	crew.AddTask(&MockTask{id: "check", description: "Check status", expectedOutput: "Status"})
+	crew.AddTask(followUp)
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("skipped tasks should not fail the crew: %v", err)
+	}
+	if len(result.TasksOutput) != 2 || !agent.IsSkippedOutput(result.TasksOutput[1]) {
+		t.Fatalf("expected the follow-up task to be skipped, got %+v", result.TasksOutput)
+	}
+	if result.TokenUsage.SkippedTasks != 1 || result.TokenUsage.FailedTasks != 0 {
+		t.Errorf("unexpected metrics: %+v", result.TokenUsage)
+	}
+}
+
+func TestSequentialContextInjectedIntoPrompt(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+	crew.SetProcess(ProcessSequential)
+
+	recordingLLM := NewMockLLM(
+		"Research notes: Go has goroutines",
+		"Draft article about goroutines",
+		"Final edited article",
+	)
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write articles",
+		Backstory: "Experienced technical writer",
+		LLM:       recordingLLM,
+		EventBus:  eventBus,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(writer)
+
+	crew.AddTask(agent.NewBaseTask("Research the topic", "Research notes"))
+	crew.AddTask(agent.NewBaseTask("Write a draft", "A draft article"))
+	crew.AddTask(agent.NewBaseTask("Edit the draft", "A final article"))
+
+	result, err := crew.Kickoff(context.Background(), map[string]interface{}{"topic": "concurrency"})
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	if len(result.TasksOutput) != 3 {
+		t.Fatalf("expected 3 task outputs, got %d", len(result.TasksOutput))
+	}
+
+	if len(recordingLLM.Prompts()) != 3 {
+		t.Fatalf("expected 3 prompts, got %d", len(recordingLLM.Prompts()))
+	}
+
+	firstPrompt := recordingLLM.Prompts()[0]
+	if !strings.Contains(firstPrompt, "topic: concurrency") {
+		t.Error("first task prompt should contain initial inputs")
+	}
+	if strings.Contains(firstPrompt, "Previous Task Outputs") {
+		t.Error("first task prompt should not contain previous task outputs")
+	}
+
+	secondPrompt := recordingLLM.Prompts()[1]
+	if !strings.Contains(secondPrompt, "=== BEGIN CONTEXT ===") {
+		t.Error("second task prompt should contain a delimited context section")
+	}
+	if !strings.Contains(secondPrompt, "Research notes: Go has goroutines") {
+		t.Error("second task prompt should contain first task output")
+	}
+	if !strings.Contains(secondPrompt, "Completed Tasks: 1 of 3") {
+		t.Error("second task prompt should contain completed task count")
+	}
+
+	thirdPrompt := recordingLLM.Prompts()[2]
+	if !strings.Contains(thirdPrompt, "Research notes: Go has goroutines") ||
+		!strings.Contains(thirdPrompt, "Draft article about goroutines") {
+		t.Error("third task prompt should contain all previous task outputs")
+	}
+}
+
+func TestTaskHooksMutateContextAndOutput(t *testing.T) {
+	logger := logger.NewTestLogger()
+	crew := NewBaseCrew(nil, events.NewEventBus(logger), logger)
+
+	recordingLLM := NewMockLLM("Customer SSN is 123-45-6789", "Summary done")
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write summaries",
+		Backstory: "Careful writer",
+		LLM:       recordingLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(writer)
+	crew.AddTask(agent.NewBaseTask("Look up the customer", "Customer record"))
+	crew.AddTask(agent.NewBaseTask("Summarize the record", "Summary"))
+
+	var order []string
+	crew.AddBeforeTaskHook(func(ctx context.Context, task agent.Task, taskContext map[string]interface{}) (map[string]interface{}, error) {
+		order = append(order, "before1")
+		taskContext["audience"] = "support team"
+		return taskContext, nil
+	})
+	crew.AddBeforeTaskHook(func(ctx context.Context, task agent.Task, taskContext map[string]interface{}) (map[string]interface{}, error) {
+		order = append(order, "before2")
+		return nil, nil
+	})
+	crew.AddAfterTaskHook(func(ctx context.Context, task agent.Task, output *agent.TaskOutput) (*agent.TaskOutput, error) {
+		order = append(order, "after")
+		redacted := *output
+		redacted.Raw = strings.ReplaceAll(output.Raw, "123-45-6789", "[REDACTED]")
+		return &redacted, nil
+	})
+	var notified []string
+	crew.AddTaskCallback(func(ctx context.Context, task agent.Task, output *agent.TaskOutput) error {
+		order = append(order, "callback")
+		notified = append(notified, output.Raw)
+		return nil
+	})
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	if got := strings.Join(order[:4], ","); got != "before1,before2,after,callback" {
+		t.Errorf("expected hooks in registration order before the callback, got %s", got)
+	}
+	if !strings.Contains(recordingLLM.Prompts()[0], "audience: support team") {
+		t.Errorf("expected hook context in prompt, got:\n%s", recordingLLM.Prompts()[0])
+	}
+	// 脱敏后的输出传入后续任务和回调
+	if strings.Contains(recordingLLM.Prompts()[1], "123-45-6789") || !strings.Contains(recordingLLM.Prompts()[1], "[REDACTED]") {
+		t.Errorf("expected redacted output in the next task context, got:\n%s", recordingLLM.Prompts()[1])
+	}
+	if result.TasksOutput[0].Raw != "Customer SSN is [REDACTED]" || notified[0] != "Customer SSN is [REDACTED]" {
+		t.Errorf("expected redacted task output, got %q and %q", result.TasksOutput[0].Raw, notified[0])
+	}
+}
+
+func TestTaskHookErrorAbortsTask(t *testing.T) {
+	logger := logger.NewTestLogger()
+
+	t.Run("before hook", func(t *testing.T) {
+		crew := NewBaseCrew(nil, events.NewEventBus(logger), logger)
+		recordingLLM := NewMockLLM("result")
+		writer, _ := createTestAgent("Writer", "Write", recordingLLM, nil, logger)
+		crew.AddAgent(writer)
+		crew.AddTask(agent.NewBaseTask("Write something", "Text"))
+		crew.AddBeforeTaskHook(func(ctx context.Context, task agent.Task, taskContext map[string]interface{}) (map[string]interface{}, error) {
+			return taskContext, nil
+		})
+		crew.AddBeforeTaskHook(func(ctx context.Context, task agent.Task, taskContext map[string]interface{}) (map[string]interface{}, error) {
+			return nil, errors.New("missing tenant")
+		})
+
+		_, err := crew.Kickoff(context.Background(), nil)
+		if err == nil || !strings.Contains(err.Error(), "before task hook 2 failed: missing tenant") {
+			t.Fatalf("expected before hook error, got %v", err)
+		}
+		if len(recordingLLM.Prompts()) != 0 {
+			t.Errorf("expected task not to run, got %d prompts", len(recordingLLM.Prompts()))
+		}
+	})
+
+	t.Run("after hook", func(t *testing.T) {
+		crew := NewBaseCrew(nil, events.NewEventBus(logger), logger)
+		writer, _ := createTestAgent("Writer", "Write", NewMockLLM("result"), nil, logger)
+		crew.AddAgent(writer)
+		crew.AddTask(agent.NewBaseTask("Write something", "Text"))
+		crew.AddAfterTaskHook(func(ctx context.Context, task agent.Task, output *agent.TaskOutput) (*agent.TaskOutput, error) {
+			return nil, errors.New("redaction failed")
+		})
+		callbackCalled := false
+		crew.AddTaskCallback(func(ctx context.Context, task agent.Task, output *agent.TaskOutput) error {
+			callbackCalled = true
+			return nil
+		})
+
+		_, err := crew.Kickoff(context.Background(), nil)
+		if err == nil || !strings.Contains(err.Error(), "after task hook 1 failed: redaction failed") {
+			t.Fatalf("expected after hook error, got %v", err)
+		}
+		if callbackCalled {
+			t.Error("task callback should not run when a hook fails")
+		}
+	})
+}
+
 func TestHierarchicalProcess(t *testing.T) {
 	logger := logger.NewTestLogger()
 	eventBus := events.NewEventBus(logger)
@@ -553,7 +770,7 @@ func TestAgentSelection(t *testing.T) {
 				expectedOutput: "Test output",
 			}
 
-			selectedAgent, err := crew.selectAgentForTask(task, tc.taskIndex)
+			selectedAgent, _, err := crew.selectAgentForTask(context.Background(), task, tc.taskIndex, false)
 			if err != nil {
 				t.Errorf("task index %d: unexpected error: %v", tc.taskIndex, err)
 				continue
@@ -581,7 +798,7 @@ func TestAgentSelection(t *testing.T) {
 		// 测试任务分配给管理器
 		task := &MockTask{id: "t1", description: "Test task", expectedOutput: "Test output"}
 
-		selectedAgent, err := crew.selectAgentForTask(task, 0)
+		selectedAgent, _, err := crew.selectAgentForTask(context.Background(), task, 0, false)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -782,3 +999,398 @@ func (f *FailingMockAgent) Execute(ctx context.Context, task agent.Task) (*agent
 	}
 	return f.MockAgent.Execute(ctx, task)
 }
+
+// SlowMockAgent 按指定延迟执行并记录最大并发数的Agent
+type SlowMockAgent struct {
+	MockAgent
+	delay      time.Duration
+	tokens     int
+	shouldFail bool
+
+	mu        *sync.Mutex
+	active    *int
+	maxActive *int
+}
+
+func (s *SlowMockAgent) Execute(ctx context.Context, task agent.Task) (*agent.TaskOutput, error) {
+	s.mu.Lock()
+	*s.active++
+	if *s.active > *s.maxActive {
+		*s.maxActive = *s.active
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		*s.active--
+		s.mu.Unlock()
+	}()
+
+	time.Sleep(s.delay)
+	if s.shouldFail {
+		return nil, errors.New("simulated failure for " + task.GetDescription())
+	}
+
+	output, err := s.MockAgent.Execute(ctx, task)
+	if output != nil {
+		output.TokensUsed = s.tokens
+	}
+	return output, err
+}
+
+func newSlowAgents(delays []time.Duration, failing map[int]bool) ([]*SlowMockAgent, *int) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+
+	agents := make([]*SlowMockAgent, len(delays))
+	for i, delay := range delays {
+		agents[i] = &SlowMockAgent{
+			MockAgent:  MockAgent{id: "agent" + string(rune('A'+i)), role: "Agent" + string(rune('A'+i))},
+			delay:      delay,
+			tokens:     10 * (i + 1),
+			shouldFail: failing[i],
+			mu:         &mu,
+			active:     &active,
+			maxActive:  &maxActive,
+		}
+	}
+	return agents, &maxActive
+}
+
+func TestParallelProcessPreservesTaskOrder(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.Process = ProcessParallel
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	// 越靠前的任务越慢，完成顺序与任务顺序相反
+	agents, maxActive := newSlowAgents([]time.Duration{60 * time.Millisecond, 30 * time.Millisecond, time.Millisecond}, nil)
+	for i, a := range agents {
+		crew.AddAgent(a)
+		crew.AddTask(&MockTask{id: "t" + string(rune('1'+i)), description: "Task " + string(rune('1'+i)), expectedOutput: "Output"})
+	}
+
+	result, err := crew.Kickoff(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	if len(result.TasksOutput) != 3 {
+		t.Fatalf("expected 3 task outputs, got %d", len(result.TasksOutput))
+	}
+	for i, output := range result.TasksOutput {
+		expected := "Mock agent output for: Task " + string(rune('1'+i))
+		if output.Raw != expected {
+			t.Errorf("output %d: expected %q, got %q", i, expected, output.Raw)
+		}
+	}
+
+	if *maxActive < 2 {
+		t.Errorf("expected tasks to run concurrently, max active was %d", *maxActive)
+	}
+	if result.TokenUsage == nil || result.TokenUsage.TotalTokens != 60 {
+		t.Errorf("expected 60 aggregated tokens, got %+v", result.TokenUsage)
+	}
+	if result.TokenUsage.SuccessfulTasks != 3 {
+		t.Errorf("expected 3 successful tasks, got %d", result.TokenUsage.SuccessfulTasks)
+	}
+}
+
+func TestParallelProcessRespectsMaxConcurrency(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.Process = ProcessParallel
+	config.MaxConcurrency = 2
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	agents, maxActive := newSlowAgents([]time.Duration{
+		20 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond,
+	}, nil)
+	for i, a := range agents {
+		crew.AddAgent(a)
+		crew.AddTask(&MockTask{id: "t" + string(rune('1'+i)), description: "Task " + string(rune('1'+i)), expectedOutput: "Output"})
+	}
+
+	result, err := crew.Kickoff(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	if len(result.TasksOutput) != 5 {
+		t.Errorf("expected 5 task outputs, got %d", len(result.TasksOutput))
+	}
+	if *maxActive > 2 {
+		t.Errorf("expected at most 2 concurrent tasks, got %d", *maxActive)
+	}
+}
+
+func TestParallelProcessCollectsErrors(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.Process = ProcessParallel
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	agents, _ := newSlowAgents([]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond}, map[int]bool{1: true, 3: true})
+	for i, a := range agents {
+		crew.AddAgent(a)
+		crew.AddTask(&MockTask{id: "t" + string(rune('1'+i)), description: "Task " + string(rune('1'+i)), expectedOutput: "Output"})
+	}
+
+	result, err := crew.Kickoff(context.Background(), map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected error when tasks fail")
+	}
+	for _, fragment := range []string{"2 of 4 tasks failed", "Task 2", "Task 4"} {
+		if !strings.Contains(err.Error(), fragment) {
+			t.Errorf("expected error to contain %q, got %q", fragment, err.Error())
+		}
+	}
+
+	if result == nil {
+		t.Fatal("expected partial result with successful outputs")
+	}
+	if result.Success {
+		t.Error("partial result should not be marked successful")
+	}
+	if len(result.TasksOutput) != 2 ||
+		result.TasksOutput[0].Raw != "Mock agent output for: Task 1" ||
+		result.TasksOutput[1].Raw != "Mock agent output for: Task 3" {
+		t.Errorf("unexpected successful outputs: %+v", result.TasksOutput)
+	}
+	if failed, ok := result.Metadata["failed_tasks"].([]int); !ok || len(failed) != 2 || failed[0] != 1 || failed[1] != 3 {
+		t.Errorf("expected failed_tasks [1 3], got %v", result.Metadata["failed_tasks"])
+	}
+	if result.TokenUsage.TotalTokens != 40 || result.TokenUsage.FailedTasks != 2 {
+		t.Errorf("unexpected usage metrics: %+v", result.TokenUsage)
+	}
+}
+
+func TestParallelProcessRunsDependentTasksAfterDependencies(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.Process = ProcessParallel
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	research := agent.NewBaseTask("Research topic", "Findings")
+	outline := agent.NewBaseTask("Outline topic", "Outline")
+	write := agent.NewBaseTask("Write article", "Article")
+	write.SetContextTasks([]agent.Task{research, outline})
+
+	recordingLLM := NewMockLLM("article done")
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write",
+		Backstory: "Writes",
+		LLM:       recordingLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	crew.AddAgent(&MockAgent{id: "r", role: "Researcher"})
+	crew.AddAgent(&MockAgent{id: "o", role: "Outliner"})
+	crew.AddAgent(writer)
+	crew.AddTask(research)
+	crew.AddTask(outline)
+	crew.AddTask(write)
+
+	result, err := crew.Kickoff(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	if len(result.TasksOutput) != 3 {
+		t.Fatalf("expected 3 task outputs, got %d", len(result.TasksOutput))
+	}
+
+	if len(recordingLLM.Prompts()) != 1 {
+		t.Fatalf("expected writer to be called once, got %d", len(recordingLLM.Prompts()))
+	}
+	prompt := recordingLLM.Prompts()[0]
+	for _, fragment := range []string{"Mock agent output for: Research topic", "Mock agent output for: Outline topic"} {
+		if !strings.Contains(prompt, fragment) {
+			t.Errorf("dependent task prompt should contain %q", fragment)
+		}
+	}
+}
+
+func TestParallelProcessEventEmission(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	var capturedEvents []string
+	var mutex sync.Mutex
+	eventCapture := func(ctx context.Context, event events.Event) error {
+		mutex.Lock()
+		capturedEvents = append(capturedEvents, event.GetType())
+		mutex.Unlock()
+		return nil
+	}
+	eventBus.Subscribe("parallel_process_started", eventCapture)
+	eventBus.Subscribe("parallel_process_completed", eventCapture)
+	eventBus.Subscribe("task_execution_started", eventCapture)
+	eventBus.Subscribe("task_execution_completed", eventCapture)
+
+	crew := NewBaseCrew(nil, eventBus, logger)
+	crew.SetProcess(ProcessParallel)
+	crew.AddAgent(&MockAgent{id: "a1", role: "TestAgent"})
+	crew.AddTask(&MockTask{id: "t1", description: "Test task", expectedOutput: "Test output"})
+
+	if _, err := crew.Kickoff(context.Background(), map[string]interface{}{"test": "data"}); err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	expected := map[string]bool{
+		"parallel_process_started":   true,
+		"task_execution_started":     true,
+		"task_execution_completed":   true,
+		"parallel_process_completed": true,
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		mutex.Lock()
+		count := len(capturedEvents)
+		mutex.Unlock()
+		if count >= len(expected) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(capturedEvents) != len(expected) {
+		t.Fatalf("expected %d events, got %v", len(expected), capturedEvents)
+	}
+	for _, eventType := range capturedEvents {
+		if !expected[eventType] {
+			t.Errorf("unexpected event %s", eventType)
+		}
+	}
+}
+
+// BlockingMockAgent 阻塞直到上下文取消的Agent
+type BlockingMockAgent struct {
+	MockAgent
+	cancelled chan struct{}
+}
+
+func (b *BlockingMockAgent) Execute(ctx context.Context, task agent.Task) (*agent.TaskOutput, error) {
+	<-ctx.Done()
+	close(b.cancelled)
+	return nil, ctx.Err()
+}
+
+func TestSequentialProcessRunsAsyncTasksInBackground(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	// 异步的t1最慢，后续同步任务不等待它
+	agents, maxActive := newSlowAgents([]time.Duration{60 * time.Millisecond, 20 * time.Millisecond, time.Millisecond}, nil)
+	for _, a := range agents {
+		crew.AddAgent(a)
+	}
+	crew.AddTask(agent.NewTaskWithOptions("Task 1", "Output", agent.WithID("t1"), agent.WithAsyncExecution(true)))
+	crew.AddTask(&MockTask{id: "t2", description: "Task 2", expectedOutput: "Output"})
+	crew.AddTask(&MockTask{id: "t3", description: "Task 3", expectedOutput: "Output"})
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	if *maxActive != 2 {
+		t.Errorf("expected the async task to overlap with sync tasks, max active was %d", *maxActive)
+	}
+	if len(result.TasksOutput) != 3 {
+		t.Fatalf("expected 3 task outputs, got %d", len(result.TasksOutput))
+	}
+	for i, output := range result.TasksOutput {
+		expected := "Mock agent output for: Task " + string(rune('1'+i))
+		if output.Raw != expected {
+			t.Errorf("output %d: expected %q, got %q", i, expected, output.Raw)
+		}
+	}
+}
+
+func TestSequentialProcessJoinsAsyncTaskForDependents(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	agents, _ := newSlowAgents([]time.Duration{30 * time.Millisecond, time.Millisecond}, nil)
+	for _, a := range agents {
+		crew.AddAgent(a)
+	}
+	research := agent.NewTaskWithOptions("Research", "Findings", agent.WithID("research"), agent.WithAsyncExecution(true))
+	report := &ContextAwareTask{MockTask: &MockTask{id: "report", description: "Report", expectedOutput: "Report", dependsOn: []string{"research"}}}
+	crew.AddTask(research)
+	crew.AddTask(report)
+
+	if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	if report.GetContext()["last_task_output"] != "Mock agent output for: Research" {
+		t.Errorf("dependent task should receive the async task output, got %v", report.GetContext()["last_task_output"])
+	}
+}
+
+func TestSequentialProcessAsyncTaskErrorSurfacesAtJoin(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	agents, _ := newSlowAgents([]time.Duration{time.Millisecond, time.Millisecond}, map[int]bool{0: true})
+	for _, a := range agents {
+		crew.AddAgent(a)
+	}
+	crew.AddTask(agent.NewTaskWithOptions("Task 1", "Output", agent.WithID("t1"), agent.WithAsyncExecution(true)))
+	crew.AddTask(&MockTask{id: "t2", description: "Task 2", expectedOutput: "Output"})
+
+	_, err := crew.Kickoff(context.Background(), nil)
+	if err == nil {
+		t.Fatal("expected error from the async task")
+	}
+	if !strings.Contains(err.Error(), "async task 't1' failed") || !strings.Contains(err.Error(), "simulated failure for Task 1") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSequentialProcessCancelsAsyncTasks(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	blocking := &BlockingMockAgent{MockAgent: MockAgent{id: "a1", role: "Blocking"}, cancelled: make(chan struct{})}
+	crew.AddAgent(blocking)
+	crew.AddAgent(&MockAgent{id: "a2", role: "Fast"})
+	crew.AddTask(agent.NewTaskWithOptions("Task 1", "Output", agent.WithID("t1"), agent.WithAsyncExecution(true)))
+	crew.AddTask(&MockTask{id: "t2", description: "Task 2", expectedOutput: "Output"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := crew.Kickoff(ctx, nil)
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("crew did not stop promptly, took %v", elapsed)
+	}
+
+	select {
+	case <-blocking.cancelled:
+	case <-time.After(time.Second):
+		t.Error("in-flight async task was not cancelled")
+	}
+}
@@ -0,0 +1,245 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+)
+
+// ProgressType 执行进度条目的类型
+type ProgressType string
+
+const (
+	ProgressTaskStarted     ProgressType = "task_started"
+	ProgressTaskCompleted   ProgressType = "task_completed"
+	ProgressTaskFailed      ProgressType = "task_failed"
+	ProgressTaskSkipped     ProgressType = "task_skipped" // 条件任务未满足条件，或重放时复用已保存的输出
+	ProgressAgentThinking   ProgressType = "agent_thinking"
+	ProgressToolCalled      ProgressType = "tool_called"
+	ProgressLLMTokens       ProgressType = "llm_tokens"
+	ProgressKickoffFinished ProgressType = "kickoff_finished" // 最后一条进度，之后通道关闭
+)
+
+// DefaultProgressBufferSize 进度通道的默认缓冲大小
+const DefaultProgressBufferSize = 256
+
+// CrewProgress KickoffWithProgress推送的一条执行进度
+// 任务相关的条目带有任务序号和执行的Agent；Percent为已完成（含失败和跳过）任务占全部任务的百分比
+type CrewProgress struct {
+	Type      ProgressType  `json:"type"`
+	Timestamp time.Time     `json:"timestamp"`
+	TaskIndex int           `json:"task_index"` // 与任务无关的条目为-1
+	AgentRole string        `json:"agent_role,omitempty"`
+	Completed int           `json:"completed"`
+	Total     int           `json:"total"`
+	Percent   float64       `json:"percent"`
+	Message   string        `json:"message,omitempty"`  // 任务描述、错误信息或跳过原因
+	Tool      string        `json:"tool,omitempty"`     // tool_called的工具名称
+	Tokens    int           `json:"tokens,omitempty"`   // llm_tokens中一次LLM调用使用的token数
+	Delta     string        `json:"delta,omitempty"`    // llm_tokens中流式输出的增量文本，需开启CrewConfig.StreamOutput
+	Duration  time.Duration `json:"duration,omitempty"` // 任务完成或失败时的执行时长
+	Dropped   int           `json:"dropped,omitempty"`  // kickoff_finished中因消费过慢被丢弃的条目数
+
+	Output *CrewOutput `json:"-"` // kickoff_finished中的执行结果
+	Err    error       `json:"-"` // kickoff_finished中的执行错误
+}
+
+// progressStream 一次Kickoff的进度通道
+// 发送从不阻塞执行：缓冲区满时丢弃条目并计数，最后一条kickoff_finished总有预留的位置
+type progressStream struct {
+	mu        sync.Mutex
+	ch        chan CrewProgress
+	total     int
+	completed int
+	dropped   int
+	closed    bool
+}
+
+func newProgressStream(total, bufferSize int) *progressStream {
+	if bufferSize < 2 {
+		bufferSize = 2
+	}
+	return &progressStream{ch: make(chan CrewProgress, bufferSize), total: total}
+}
+
+// send 填充完成度后非阻塞地发送，缓冲区只剩最后一条的位置时丢弃
+func (s *progressStream) send(progress CrewProgress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	switch progress.Type {
+	case ProgressTaskCompleted, ProgressTaskFailed, ProgressTaskSkipped:
+		s.completed++
+	}
+	s.fillLocked(&progress)
+
+	if len(s.ch) >= cap(s.ch)-1 {
+		s.dropped++
+		return
+	}
+	s.ch <- progress
+}
+
+// finish 发送带有执行结果和丢弃计数的最后一条进度并关闭通道
+func (s *progressStream) finish(output *CrewOutput, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	progress := CrewProgress{Type: ProgressKickoffFinished, TaskIndex: -1, Dropped: s.dropped, Output: output, Err: err}
+	if err != nil {
+		progress.Message = err.Error()
+	}
+	s.fillLocked(&progress)
+	s.ch <- progress
+	close(s.ch)
+}
+
+func (s *progressStream) fillLocked(progress *CrewProgress) {
+	if progress.Timestamp.IsZero() {
+		progress.Timestamp = time.Now()
+	}
+	progress.Completed = s.completed
+	progress.Total = s.total
+	if s.total > 0 {
+		progress.Percent = float64(s.completed) * 100 / float64(s.total)
+	}
+}
+
+// handle 把本次Kickoff（按ctx中的进度通道区分）的事件转换为进度条目
+// 同一事件总线上其他Crew或其他Kickoff的事件被忽略
+func (s *progressStream) handle(ctx context.Context, event events.Event) error {
+	if progressFrom(ctx) != s {
+		return nil
+	}
+	scope := progressTaskFrom(ctx)
+
+	switch e := event.(type) {
+	case *TaskExecutionStartedEvent:
+		s.send(CrewProgress{Type: ProgressTaskStarted, TaskIndex: e.TaskIndex, AgentRole: e.AgentRole, Message: e.TaskDescription})
+	case *TaskExecutionCompletedEvent:
+		s.send(CrewProgress{Type: ProgressTaskCompleted, TaskIndex: e.TaskIndex, AgentRole: e.AgentRole, Duration: e.Duration})
+	case *TaskExecutionFailedEvent:
+		s.send(CrewProgress{Type: ProgressTaskFailed, TaskIndex: e.TaskIndex, AgentRole: e.AgentRole, Message: e.Error, Duration: e.Duration})
+	case *TaskExecutionSkippedEvent:
+		s.send(CrewProgress{Type: ProgressTaskSkipped, TaskIndex: e.TaskIndex, Message: e.Reason})
+	case *agent.AgentExecutionStartedEvent:
+		s.send(CrewProgress{Type: ProgressAgentThinking, TaskIndex: scope.index, AgentRole: e.Agent, Message: "working on the task"})
+	case *agent.AgentReasoningStartedEvent:
+		s.send(CrewProgress{Type: ProgressAgentThinking, TaskIndex: scope.index, AgentRole: e.Agent, Message: fmt.Sprintf("planning (attempt %d)", e.Attempt)})
+	case *agent.AgentToolUsageStartedEvent:
+		s.send(CrewProgress{Type: ProgressToolCalled, TaskIndex: scope.index, AgentRole: e.Agent, Tool: e.ToolName})
+	case *llm.LLMCallCompletedEvent:
+		s.send(CrewProgress{Type: ProgressLLMTokens, TaskIndex: scope.index, AgentRole: scope.agentRole, Tokens: e.TokensUsed})
+	}
+	return nil
+}
+
+// progressKey ctx中当前Kickoff的进度通道
+type progressKey struct{}
+
+func withProgress(ctx context.Context, stream *progressStream) context.Context {
+	return context.WithValue(ctx, progressKey{}, stream)
+}
+
+func progressFrom(ctx context.Context) *progressStream {
+	stream, _ := ctx.Value(progressKey{}).(*progressStream)
+	return stream
+}
+
+// progressTask 正在执行的任务，用于给Agent、工具和LLM事件标注任务序号
+type progressTask struct {
+	index     int
+	agentRole string
+}
+
+type progressTaskKey struct{}
+
+// withProgressTask 记录ctx中正在执行的任务，只在有进度通道时生效
+func withProgressTask(ctx context.Context, index int, agentRole string) context.Context {
+	if progressFrom(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressTaskKey{}, progressTask{index: index, agentRole: agentRole})
+}
+
+func progressTaskFrom(ctx context.Context) progressTask {
+	if task, ok := ctx.Value(progressTaskKey{}).(progressTask); ok {
+		return task
+	}
+	return progressTask{index: -1}
+}
+
+// KickoffWithProgress 异步启动Crew执行并返回类型化的进度通道
+// 进度通道有缓冲，消费过慢时丢弃条目而不阻塞执行；最后一条为kickoff_finished，
+// 带有执行结果、错误和丢弃的条目数，随后通道关闭
+func (c *BaseCrew) KickoffWithProgress(ctx context.Context, inputs map[string]interface{}) (<-chan CrewProgress, error) {
+	return c.runWithProgress(ctx, func(ctx context.Context) (*CrewOutput, error) {
+		return c.Kickoff(ctx, inputs)
+	})
+}
+
+// ReplayFromWithProgress 与ReplayFrom相同，但异步执行并返回进度通道，复用保存输出的任务以task_skipped报告
+func (c *BaseCrew) ReplayFromWithProgress(ctx context.Context, kickoffID, taskID string, overrides map[string]interface{}) (<-chan CrewProgress, error) {
+	return c.runWithProgress(ctx, func(ctx context.Context) (*CrewOutput, error) {
+		return c.ReplayFrom(ctx, kickoffID, taskID, overrides)
+	})
+}
+
+// runWithProgress 在订阅本Crew事件的情况下异步执行run，执行结束后关闭进度通道
+func (c *BaseCrew) runWithProgress(ctx context.Context, run func(ctx context.Context) (*CrewOutput, error)) (<-chan CrewProgress, error) {
+	stream := newProgressStream(len(c.GetTasks()), DefaultProgressBufferSize)
+	subscription, err := c.eventBus.SubscribeWithOptions("*", stream.handle, events.WithSyncDelivery())
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to crew events: %w", err)
+	}
+
+	go func() {
+		defer subscription.Unsubscribe()
+		output, err := run(withProgress(ctx, stream))
+		stream.finish(output, err)
+	}()
+	return stream.ch, nil
+}
+
+// shouldStreamTask 开启StreamOutput且有进度通道时流式执行任务
+// 带有护栏或需要审批的任务要求完整输出，仍然按普通方式执行
+func (c *BaseCrew) shouldStreamTask(ctx context.Context, task agent.Task) bool {
+	return c.streamOutput && progressFrom(ctx) != nil && !task.HasGuardrail() && !task.IsApprovalRequired()
+}
+
+// executeTaskStream 通过Agent的ExecuteStream执行任务，把输出增量作为llm_tokens进度推送
+func (c *BaseCrew) executeTaskStream(ctx context.Context, task agent.Task, executor agent.Agent) (*agent.TaskOutput, error) {
+	chunks, err := executor.ExecuteStream(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := progressFrom(ctx)
+	scope := progressTaskFrom(ctx)
+	var output *agent.TaskOutput
+	for chunk := range chunks {
+		if chunk.Done {
+			output, err = chunk.Output, chunk.Error
+			continue
+		}
+		if chunk.Delta != "" {
+			stream.send(CrewProgress{Type: ProgressLLMTokens, TaskIndex: scope.index, AgentRole: scope.agentRole, Delta: chunk.Delta})
+		}
+	}
+	if err == nil && output == nil {
+		err = fmt.Errorf("agent stream ended without output")
+	}
+	return output, err
+}
@@ -0,0 +1,172 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// collectProgress 读取进度直到通道关闭
+func collectProgress(t *testing.T, progress <-chan CrewProgress) []CrewProgress {
+	t.Helper()
+	var entries []CrewProgress
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case entry, ok := <-progress:
+			if !ok {
+				return entries
+			}
+			entries = append(entries, entry)
+		case <-timeout:
+			t.Fatalf("progress channel was not closed, got %d entries", len(entries))
+		}
+	}
+}
+
+func TestKickoffWithProgress(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	c := NewBaseCrew(&CrewConfig{Name: "progress-crew"}, eventBus, log)
+	c.AddAgent(newAssignmentTestAgent(t, "Researcher", "Research", llmtest.NewScriptedLLM(llmtest.Replies("notes")...), eventBus, log))
+	c.AddAgent(newAssignmentTestAgent(t, "Writer", "Write", llmtest.NewScriptedLLM(llmtest.Replies("article")...), eventBus, log))
+	c.AddTask(agent.NewTaskWithOptions("Research Go", "Notes"))
+	c.AddTask(agent.NewTaskWithOptions("Write about Go", "An article"))
+
+	// 同一事件总线上另一个Crew的执行不出现在进度中
+	other := NewBaseCrew(&CrewConfig{Name: "other-crew"}, eventBus, log)
+	other.AddAgent(newAssignmentTestAgent(t, "Other", "Other", llmtest.NewScriptedLLM(llmtest.Replies("other")...), eventBus, log))
+	other.AddTask(agent.NewTaskWithOptions("Other work", "Other"))
+
+	progress, err := c.KickoffWithProgress(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to start kickoff: %v", err)
+	}
+	if _, err := other.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("other kickoff failed: %v", err)
+	}
+	entries := collectProgress(t, progress)
+
+	var types []string
+	for _, entry := range entries {
+		types = append(types, string(entry.Type))
+		if entry.AgentRole == "Other" {
+			t.Errorf("progress must not include other crews: %+v", entry)
+		}
+	}
+	expected := "task_started,agent_thinking,task_completed,task_started,agent_thinking,task_completed,kickoff_finished"
+	if strings.Join(types, ",") != expected {
+		t.Fatalf("unexpected progress sequence:\n got %s\nwant %s", strings.Join(types, ","), expected)
+	}
+
+	started, thinking, completed := entries[3], entries[4], entries[5]
+	if started.TaskIndex != 1 || started.AgentRole != "Writer" || started.Message != "Write about Go" || started.Percent != 50 {
+		t.Errorf("unexpected task_started entry: %+v", started)
+	}
+	if thinking.TaskIndex != 1 || thinking.AgentRole != "Writer" {
+		t.Errorf("expected agent_thinking tagged with the task, got %+v", thinking)
+	}
+	if completed.Completed != 2 || completed.Total != 2 || completed.Percent != 100 {
+		t.Errorf("unexpected task_completed entry: %+v", completed)
+	}
+
+	final := entries[len(entries)-1]
+	if final.Err != nil || final.Output == nil || final.Output.Raw != "article" || final.Dropped != 0 {
+		t.Errorf("unexpected final progress: %+v", final)
+	}
+}
+
+func TestKickoffWithProgressReportsFailure(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	c := NewBaseCrew(&CrewConfig{Name: "failing-crew"}, eventBus, log)
+	c.AddAgent(newAssignmentTestAgent(t, "Writer", "Write", llmtest.NewScriptedLLM(llmtest.Reply{Err: errors.New("model unavailable")}), eventBus, log))
+	c.AddTask(agent.NewTaskWithOptions("Write", "Text"))
+
+	progress, err := c.KickoffWithProgress(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to start kickoff: %v", err)
+	}
+	entries := collectProgress(t, progress)
+
+	failed := entries[len(entries)-2]
+	if failed.Type != ProgressTaskFailed || failed.Message == "" || failed.Percent != 100 {
+		t.Errorf("expected a task_failed entry, got %+v", failed)
+	}
+	final := entries[len(entries)-1]
+	if final.Type != ProgressKickoffFinished || final.Err == nil || final.Message == "" {
+		t.Errorf("expected the error in the final progress, got %+v", final)
+	}
+}
+
+func TestKickoffWithProgressStreamsTokens(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	model := llmtest.NewScriptedLLM(llmtest.Reply{Chunks: []llm.StreamResponse{
+		{Delta: "Go is "}, {Delta: "fast."}, {FinishReason: "stop"},
+	}})
+	c := NewBaseCrew(&CrewConfig{Name: "stream-crew", StreamOutput: true}, eventBus, log)
+	c.AddAgent(newAssignmentTestAgent(t, "Writer", "Write", model, eventBus, log))
+	c.AddTask(agent.NewTaskWithOptions("Describe Go", "One sentence"))
+
+	progress, err := c.KickoffWithProgress(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to start kickoff: %v", err)
+	}
+
+	var deltas []string
+	var final CrewProgress
+	for _, entry := range collectProgress(t, progress) {
+		switch entry.Type {
+		case ProgressLLMTokens:
+			if entry.TaskIndex != 0 || entry.AgentRole != "Writer" {
+				t.Errorf("expected token deltas tagged with the task, got %+v", entry)
+			}
+			deltas = append(deltas, entry.Delta)
+		case ProgressKickoffFinished:
+			final = entry
+		}
+	}
+	if strings.Join(deltas, "|") != "Go is |fast." {
+		t.Errorf("unexpected deltas %q", deltas)
+	}
+	if final.Output == nil || final.Output.Raw != "Go is fast." {
+		t.Errorf("expected the streamed output as the result, got %+v", final.Output)
+	}
+}
+
+func TestProgressStreamDropsWhenConsumerIsSlow(t *testing.T) {
+	stream := newProgressStream(4, 3)
+	for i := 0; i < 4; i++ {
+		stream.send(CrewProgress{Type: ProgressTaskCompleted, TaskIndex: i})
+	}
+	stream.finish(&CrewOutput{Raw: "done"}, nil)
+
+	var entries []CrewProgress
+	for entry := range stream.ch {
+		entries = append(entries, entry)
+	}
+	// 缓冲区为3时保留一个位置给最后一条进度，其余2条以外的都被丢弃
+	if len(entries) != 3 || entries[0].TaskIndex != 0 || entries[1].TaskIndex != 1 {
+		t.Fatalf("expected the first two entries and the final one, got %+v", entries)
+	}
+	final := entries[2]
+	if final.Type != ProgressKickoffFinished || final.Dropped != 2 || final.Output.Raw != "done" {
+		t.Errorf("unexpected final progress: %+v", final)
+	}
+	// 被丢弃的条目仍计入完成度
+	if final.Completed != 4 || final.Percent != 100 {
+		t.Errorf("expected dropped entries to count towards completion, got %+v", final)
+	}
+
+	// 结束后的发送被忽略
+	stream.send(CrewProgress{Type: ProgressTaskStarted})
+}
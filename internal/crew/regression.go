@@ -0,0 +1,596 @@
+package crew
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/memory"
+)
+
+// ============================================================================
+// 回归测试 - 把Crew的输出与保存的基线比较
+// ============================================================================
+
+// Baseline Crew输出的规范化快照，作为回归测试的基准
+type Baseline struct {
+	Crew       string                 `json:"crew"`
+	Inputs     map[string]interface{} `json:"inputs,omitempty"`
+	TasksHash  string                 `json:"tasks_hash"` // 所有任务描述的哈希
+	CapturedAt time.Time              `json:"captured_at"`
+	Tasks      []BaselineTask         `json:"tasks"`
+}
+
+// BaselineTask 单个任务的基线输出
+type BaselineTask struct {
+	Name            string      `json:"name"` // 任务名称，未命名的任务为task_<序号>
+	DescriptionHash string      `json:"description_hash"`
+	Output          string      `json:"output"`         // 去掉首尾空白、统一换行符后的输出
+	JSON            interface{} `json:"json,omitempty"` // 任务声明了OutputSchema或输出为JSON时解析后的值
+}
+
+// NewBaseline 从Crew输出创建基线
+func NewBaseline(crewName string, inputs map[string]interface{}, output *CrewOutput) (*Baseline, error) {
+	baseline := &Baseline{
+		Crew:       crewName,
+		Inputs:     inputs,
+		CapturedAt: time.Now(),
+		Tasks:      make([]BaselineTask, 0, len(output.TasksOutput)),
+	}
+
+	hash := sha256.New()
+	for i, taskOutput := range output.TasksOutput {
+		task, err := baselineTask(i, taskOutput)
+		if err != nil {
+			return nil, err
+		}
+		baseline.Tasks = append(baseline.Tasks, task)
+		hash.Write([]byte(taskOutput.Description))
+		hash.Write([]byte{0})
+	}
+	baseline.TasksHash = hex.EncodeToString(hash.Sum(nil))
+	return baseline, nil
+}
+
+// baselineTask 规范化任务输出，JSON值经过一次序列化，与从文件读取的基线类型一致
+func baselineTask(index int, output *agent.TaskOutput) (BaselineTask, error) {
+	name := output.Name
+	if name == "" {
+		name = fmt.Sprintf("task_%d", index+1)
+	}
+	descriptionHash := sha256.Sum256([]byte(output.Description))
+	task := BaselineTask{
+		Name:            name,
+		DescriptionHash: hex.EncodeToString(descriptionHash[:]),
+		Output:          normalizeOutputText(output.Raw),
+	}
+
+	var value interface{}
+	switch {
+	case output.Parsed != nil:
+		value = output.Parsed
+	case len(output.JSON) > 0:
+		value = output.JSON
+	default:
+		return task, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return task, fmt.Errorf("failed to normalize JSON output of task %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, &task.JSON); err != nil {
+		return task, fmt.Errorf("failed to normalize JSON output of task %s: %w", name, err)
+	}
+	return task, nil
+}
+
+func normalizeOutputText(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// Save 以JSON格式保存基线，目录不存在时自动创建
+func (b *Baseline) Save(path string) error {
+	if err := writeJSONFile(path, b); err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+	return nil
+}
+
+// LoadBaseline 读取保存的基线
+func LoadBaseline(path string) (*Baseline, error) {
+	var baseline Baseline
+	if err := readJSONFile(path, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to load baseline: %w", err)
+	}
+	return &baseline, nil
+}
+
+// BaselineCompareOptions 基线比较选项
+type BaselineCompareOptions struct {
+	// TextThreshold 自由文本输出与基线的最低相似度（0-1），<=0时使用默认的0.8
+	TextThreshold float64
+	// TaskThresholds 按任务名称覆盖TextThreshold
+	TaskThresholds map[string]float64
+	// Embedder 设置后自由文本按嵌入向量的余弦相似度比较，否则使用Levenshtein比率
+	Embedder memory.Embedder
+	// IgnorePaths 比较JSON输出时忽略的路径，用于时间戳、ID等每次执行都不同的字段。
+	// 支持$.a.b、$.items[0]、$.items[*].id、$..id（任意深度）；不以$开头的名称等同于$..name。
+	// 忽略一个路径同时忽略它下面的所有字段
+	IgnorePaths []string
+}
+
+// DefaultBaselineCompareOptions 返回默认的比较选项
+func DefaultBaselineCompareOptions() BaselineCompareOptions {
+	return BaselineCompareOptions{TextThreshold: 0.8}
+}
+
+// 相似度的计算方式
+const (
+	SimilarityLevenshtein = "levenshtein"
+	SimilarityEmbedding   = "embedding"
+)
+
+// RegressionStatus 任务与基线比较的结果
+type RegressionStatus string
+
+const (
+	RegressionPassed  RegressionStatus = "passed"
+	RegressionFailed  RegressionStatus = "failed"
+	RegressionMissing RegressionStatus = "missing" // 基线中的任务本次没有输出
+	RegressionNew     RegressionStatus = "new"     // 基线中没有的任务，不算回归
+)
+
+// RegressionReport 与基线比较的结构化报告
+type RegressionReport struct {
+	Crew               string           `json:"crew"`
+	BaselineCapturedAt time.Time        `json:"baseline_captured_at"`
+	ComparedAt         time.Time        `json:"compared_at"`
+	Similarity         string           `json:"similarity"`    // 自由文本的相似度计算方式
+	TasksChanged       bool             `json:"tasks_changed"` // 任务描述与基线不同
+	KickoffError       string           `json:"kickoff_error,omitempty"`
+	Passed             bool             `json:"passed"`
+	Tasks              []TaskRegression `json:"tasks"`
+}
+
+// TaskRegression 单个任务的比较结果
+type TaskRegression struct {
+	Task               string           `json:"task"`
+	Status             RegressionStatus `json:"status"`
+	Kind               string           `json:"kind,omitempty"`       // json或text
+	Similarity         float64          `json:"similarity,omitempty"` // 自由文本与基线的相似度
+	Threshold          float64          `json:"threshold,omitempty"`
+	FieldDiffs         []FieldDiff      `json:"field_diffs,omitempty"` // JSON输出中与基线不同的字段
+	DescriptionChanged bool             `json:"description_changed,omitempty"`
+	Error              string           `json:"error,omitempty"`
+}
+
+// FieldDiff JSON输出中与基线不同的字段
+type FieldDiff struct {
+	Path     string      `json:"path"`
+	Change   string      `json:"change"` // changed、added或removed
+	Baseline interface{} `json:"baseline,omitempty"`
+	Current  interface{} `json:"current,omitempty"`
+}
+
+// Regressions 返回失败和缺失的任务
+func (r *RegressionReport) Regressions() []TaskRegression {
+	var regressions []TaskRegression
+	for _, task := range r.Tasks {
+		if task.Status == RegressionFailed || task.Status == RegressionMissing {
+			regressions = append(regressions, task)
+		}
+	}
+	return regressions
+}
+
+// CompareBaseline 把Crew输出与基线逐个任务比较：JSON输出按字段精确比较，自由文本按相似度与阈值比较
+func CompareBaseline(ctx context.Context, baseline *Baseline, output *CrewOutput, opts BaselineCompareOptions) (*RegressionReport, error) {
+	if opts.TextThreshold <= 0 {
+		opts.TextThreshold = DefaultBaselineCompareOptions().TextThreshold
+	}
+	ignore, err := parseJSONPathPatterns(opts.IgnorePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := NewBaseline(baseline.Crew, nil, output)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RegressionReport{
+		Crew:               baseline.Crew,
+		BaselineCapturedAt: baseline.CapturedAt,
+		ComparedAt:         time.Now(),
+		Similarity:         SimilarityLevenshtein,
+		TasksChanged:       current.TasksHash != baseline.TasksHash,
+		Passed:             true,
+	}
+	if opts.Embedder != nil {
+		report.Similarity = SimilarityEmbedding
+	}
+	if output.Error != nil {
+		report.KickoffError = output.Error.Error()
+	}
+
+	currentTasks := make(map[string]BaselineTask, len(current.Tasks))
+	for _, task := range current.Tasks {
+		currentTasks[task.Name] = task
+	}
+	compared := make(map[string]bool, len(baseline.Tasks))
+	for _, expected := range baseline.Tasks {
+		compared[expected.Name] = true
+		actual, ok := currentTasks[expected.Name]
+		if !ok {
+			report.Tasks = append(report.Tasks, TaskRegression{Task: expected.Name, Status: RegressionMissing, Error: "task produced no output"})
+			continue
+		}
+		result, err := compareBaselineTask(ctx, expected, actual, opts, ignore)
+		if err != nil {
+			return nil, err
+		}
+		report.Tasks = append(report.Tasks, result)
+	}
+	for _, task := range current.Tasks {
+		if !compared[task.Name] {
+			report.Tasks = append(report.Tasks, TaskRegression{Task: task.Name, Status: RegressionNew})
+		}
+	}
+
+	report.Passed = len(report.Regressions()) == 0
+	return report, nil
+}
+
+// compareBaselineTask 比较单个任务，基线有JSON输出时按字段比较，否则按文本相似度比较
+func compareBaselineTask(ctx context.Context, expected, actual BaselineTask, opts BaselineCompareOptions,
+	ignore [][]jsonPathToken) (TaskRegression, error) {
+
+	result := TaskRegression{
+		Task:               expected.Name,
+		Status:             RegressionPassed,
+		DescriptionChanged: expected.DescriptionHash != actual.DescriptionHash,
+	}
+
+	if expected.JSON != nil {
+		result.Kind = "json"
+		if actual.JSON == nil {
+			result.Status = RegressionFailed
+			result.Error = "output is no longer parsed JSON"
+			return result, nil
+		}
+		diffJSON(nil, expected.JSON, actual.JSON, ignore, &result.FieldDiffs)
+		if len(result.FieldDiffs) > 0 {
+			result.Status = RegressionFailed
+		}
+		return result, nil
+	}
+
+	result.Kind = "text"
+	result.Threshold = opts.TextThreshold
+	if threshold, ok := opts.TaskThresholds[expected.Name]; ok {
+		result.Threshold = threshold
+	}
+	similarity, err := textSimilarity(ctx, opts.Embedder, expected.Output, actual.Output)
+	if err != nil {
+		return result, fmt.Errorf("failed to compare task %s: %w", expected.Name, err)
+	}
+	result.Similarity = similarity
+	if similarity < result.Threshold {
+		result.Status = RegressionFailed
+	}
+	return result, nil
+}
+
+// textSimilarity 计算两段文本的相似度：有嵌入器时为余弦相似度，否则为Levenshtein比率
+func textSimilarity(ctx context.Context, embedder memory.Embedder, a, b string) (float64, error) {
+	if a == b {
+		return 1, nil
+	}
+	if embedder == nil {
+		return LevenshteinRatio(a, b), nil
+	}
+	vectors, err := embedder.Embed(ctx, []string{a, b})
+	if err != nil {
+		return 0, err
+	}
+	if len(vectors) != 2 {
+		return 0, fmt.Errorf("embedder returned %d vectors for 2 texts", len(vectors))
+	}
+	return memory.CosineSimilarity(vectors[0], vectors[1]), nil
+}
+
+// LevenshteinRatio 返回1-编辑距离/较长文本的字符数，相同的文本为1
+func LevenshteinRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
+
+// ============================================================================
+// JSON字段比较
+// ============================================================================
+
+// jsonPathElem JSON值中的一级路径：对象的键或数组的下标
+type jsonPathElem struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// jsonPathToken 忽略模式中的一级：键、下标或通配符，recursive表示可以跳过任意层级（..）
+type jsonPathToken struct {
+	key       string
+	index     int
+	isIndex   bool
+	any       bool
+	recursive bool
+}
+
+func (t jsonPathToken) matches(elem jsonPathElem) bool {
+	switch {
+	case t.any:
+		return true
+	case t.isIndex:
+		return elem.isIndex && elem.index == t.index
+	default:
+		return !elem.isIndex && elem.key == t.key
+	}
+}
+
+// parseJSONPathPatterns 解析忽略模式
+func parseJSONPathPatterns(patterns []string) ([][]jsonPathToken, error) {
+	parsed := make([][]jsonPathToken, 0, len(patterns))
+	for _, pattern := range patterns {
+		tokens, err := parseJSONPathPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, tokens)
+	}
+	return parsed, nil
+}
+
+func parseJSONPathPattern(pattern string) ([]jsonPathToken, error) {
+	p := strings.TrimSpace(pattern)
+	if p == "" {
+		return nil, fmt.Errorf("empty ignore path")
+	}
+	if !strings.HasPrefix(p, "$") {
+		p = "$.." + p
+	}
+	p = p[1:]
+
+	var tokens []jsonPathToken
+	for p != "" {
+		recursive := false
+		switch {
+		case strings.HasPrefix(p, ".."):
+			recursive = true
+			p = p[2:]
+		case p[0] == '.':
+			p = p[1:]
+		case p[0] == '[':
+		default:
+			return nil, fmt.Errorf("invalid ignore path %q", pattern)
+		}
+
+		var token jsonPathToken
+		if strings.HasPrefix(p, "[") {
+			end := strings.Index(p, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid ignore path %q: missing ]", pattern)
+			}
+			inner := strings.Trim(p[1:end], `'"`)
+			p = p[end+1:]
+			switch n, err := strconv.Atoi(inner); {
+			case inner == "*":
+				token.any = true
+			case err == nil:
+				token.index, token.isIndex = n, true
+			case inner != "":
+				token.key = inner
+			default:
+				return nil, fmt.Errorf("invalid ignore path %q: empty brackets", pattern)
+			}
+		} else {
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			name := p[:end]
+			p = p[end:]
+			if name == "" {
+				return nil, fmt.Errorf("invalid ignore path %q: empty field name", pattern)
+			}
+			if name == "*" {
+				token.any = true
+			} else {
+				token.key = name
+			}
+		}
+		token.recursive = recursive
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// jsonPathIgnored 路径或它的上级路径与任一忽略模式匹配时返回true
+func jsonPathIgnored(path []jsonPathElem, patterns [][]jsonPathToken) bool {
+	for _, tokens := range patterns {
+		if matchJSONPath(tokens, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchJSONPath(tokens []jsonPathToken, path []jsonPathElem) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	token := tokens[0]
+	if token.recursive {
+		for i := range path {
+			if token.matches(path[i]) && matchJSONPath(tokens[1:], path[i+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(path) > 0 && token.matches(path[0]) && matchJSONPath(tokens[1:], path[1:])
+}
+
+// formatJSONPath 把路径格式化为$.a.items[0]形式
+func formatJSONPath(path []jsonPathElem) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, elem := range path {
+		if elem.isIndex {
+			fmt.Fprintf(&b, "[%d]", elem.index)
+		} else {
+			b.WriteString(".")
+			b.WriteString(elem.key)
+		}
+	}
+	return b.String()
+}
+
+// diffJSON 递归比较两个JSON值，把不同的字段追加到diffs，忽略的路径不比较
+func diffJSON(path []jsonPathElem, expected, actual interface{}, ignore [][]jsonPathToken, diffs *[]FieldDiff) {
+	if jsonPathIgnored(path, ignore) {
+		return
+	}
+
+	child := func(elem jsonPathElem) []jsonPathElem {
+		return append(append(make([]jsonPathElem, 0, len(path)+1), path...), elem)
+	}
+	record := func(path []jsonPathElem, change string, expected, actual interface{}) {
+		if !jsonPathIgnored(path, ignore) {
+			*diffs = append(*diffs, FieldDiff{Path: formatJSONPath(path), Change: change, Baseline: expected, Current: actual})
+		}
+	}
+
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(e)+len(a))
+		for key := range e {
+			keys = append(keys, key)
+		}
+		for key := range a {
+			if _, ok := e[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			ev, inExpected := e[key]
+			av, inActual := a[key]
+			elemPath := child(jsonPathElem{key: key})
+			switch {
+			case !inActual:
+				record(elemPath, "removed", ev, nil)
+			case !inExpected:
+				record(elemPath, "added", nil, av)
+			default:
+				diffJSON(elemPath, ev, av, ignore, diffs)
+			}
+		}
+		return
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(e) || i < len(a); i++ {
+			elemPath := child(jsonPathElem{index: i, isIndex: true})
+			switch {
+			case i >= len(a):
+				record(elemPath, "removed", e[i], nil)
+			case i >= len(e):
+				record(elemPath, "added", nil, a[i])
+			default:
+				diffJSON(elemPath, e[i], a[i], ignore, diffs)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		record(path, "changed", expected, actual)
+	}
+}
+
+// ============================================================================
+// BaseCrew的回归测试入口
+// ============================================================================
+
+// CaptureBaseline 运行Crew并把规范化的输出保存为path处的基线
+func (c *BaseCrew) CaptureBaseline(ctx context.Context, inputs map[string]interface{}, path string) (*Baseline, error) {
+	output, err := c.Kickoff(ctx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run crew for baseline: %w", err)
+	}
+	baseline, err := NewBaseline(c.name, inputs, output)
+	if err != nil {
+		return nil, err
+	}
+	if err := baseline.Save(path); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// CompareAgainstBaseline 重新运行Crew并与path处的基线比较
+// Kickoff失败时仍比较已完成的任务，未完成的任务记为缺失
+func (c *BaseCrew) CompareAgainstBaseline(ctx context.Context, inputs map[string]interface{}, path string, opts BaselineCompareOptions) (*RegressionReport, error) {
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Kickoff(ctx, inputs)
+	if output == nil {
+		if err == nil {
+			err = fmt.Errorf("crew produced no output")
+		}
+		return nil, fmt.Errorf("failed to run crew for baseline comparison: %w", err)
+	}
+	if err != nil && output.Error == nil {
+		output.Error = err
+	}
+	return CompareBaseline(ctx, baseline, output, opts)
+}
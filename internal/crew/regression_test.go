@@ -0,0 +1,240 @@
+package crew
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func TestLevenshteinRatio(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"kitten", "kitten", 1},
+		{"kitten", "sitting", 1 - 3.0/7},
+		{"abc", "", 0},
+		{"你好世界", "你好地球", 0.5},
+	}
+	for _, tc := range cases {
+		if got := LevenshteinRatio(tc.a, tc.b); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("LevenshteinRatio(%q, %q) = %f, want %f", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestParseJSONPathPattern(t *testing.T) {
+	path := []jsonPathElem{{key: "items"}, {index: 2, isIndex: true}, {key: "id"}}
+	cases := map[string]bool{
+		"$.items[2].id":     true,
+		"$.items[*].id":     true,
+		"$.items[1].id":     false,
+		"$.items":           true, // 上级路径忽略整个子树
+		"$..id":             true,
+		"id":                true,
+		"$.*[*].id":         true,
+		"$['items'][2].id":  true,
+		"$.items[2].name":   false,
+		"created_at":        false,
+		"$..items..id":      true,
+		"$.items[2].id.sub": false,
+	}
+	for pattern, want := range cases {
+		tokens, err := parseJSONPathPattern(pattern)
+		if err != nil {
+			t.Errorf("parse %q failed: %v", pattern, err)
+			continue
+		}
+		if got := matchJSONPath(tokens, path); got != want {
+			t.Errorf("pattern %q match = %v, want %v", pattern, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "$.items[", "$.items[]", "$.", "$x"} {
+		if _, err := parseJSONPathPattern(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func regressionOutput(tasks ...*agent.TaskOutput) *CrewOutput {
+	return &CrewOutput{TasksOutput: tasks, Success: true}
+}
+
+func TestCompareBaselineJSONFields(t *testing.T) {
+	baseline, err := NewBaseline("report-crew", nil, regressionOutput(
+		&agent.TaskOutput{Name: "extract", Description: "Extract orders", Raw: `{"total": 3}`, JSON: map[string]interface{}{
+			"total":      3,
+			"created_at": "2026-03-14T10:00:00Z",
+			"orders": []interface{}{
+				map[string]interface{}{"id": "a1", "amount": 10.5},
+				map[string]interface{}{"id": "a2", "amount": 7},
+			},
+		}},
+	))
+	if err != nil {
+		t.Fatalf("NewBaseline failed: %v", err)
+	}
+
+	current := regressionOutput(&agent.TaskOutput{Name: "extract", Description: "Extract orders", JSON: map[string]interface{}{
+		"total":      4,
+		"created_at": "2026-03-15T08:00:00Z",
+		"currency":   "EUR",
+		"orders": []interface{}{
+			map[string]interface{}{"id": "b1", "amount": 10.5},
+		},
+	}})
+
+	opts := DefaultBaselineCompareOptions()
+	opts.IgnorePaths = []string{"created_at", "$.orders[*].id"}
+	report, err := CompareBaseline(context.Background(), baseline, current, opts)
+	if err != nil {
+		t.Fatalf("CompareBaseline failed: %v", err)
+	}
+	if report.Passed || len(report.Tasks) != 1 {
+		t.Fatalf("expected a failed report with one task, got %+v", report)
+	}
+
+	task := report.Tasks[0]
+	if task.Status != RegressionFailed || task.Kind != "json" {
+		t.Errorf("unexpected task result: %+v", task)
+	}
+	var got []string
+	for _, diff := range task.FieldDiffs {
+		got = append(got, diff.Path+":"+diff.Change)
+	}
+	want := "$.currency:added,$.orders[1]:removed,$.total:changed"
+	if strings.Join(got, ",") != want {
+		t.Errorf("expected diffs %s, got %s", want, strings.Join(got, ","))
+	}
+
+	// 忽略所有不同的字段后通过
+	opts.IgnorePaths = append(opts.IgnorePaths, "total", "currency", "$.orders[1]")
+	if report, err = CompareBaseline(context.Background(), baseline, current, opts); err != nil || !report.Passed {
+		t.Errorf("expected comparison to pass with ignores, got %+v, %v", report, err)
+	}
+}
+
+// fixedEmbedder 按文本中是否包含"sun"返回两种向量
+type fixedEmbedder struct{}
+
+func (fixedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if strings.Contains(text, "sun") {
+			vectors[i] = []float32{1, 0}
+		} else {
+			vectors[i] = []float32{0, 1}
+		}
+	}
+	return vectors, nil
+}
+
+func TestCompareBaselineTextSimilarity(t *testing.T) {
+	baseline, err := NewBaseline("weather", nil, regressionOutput(
+		&agent.TaskOutput{Name: "forecast", Description: "Forecast", Raw: "Tomorrow will be sunny and warm.\r\n"},
+		&agent.TaskOutput{Name: "advice", Description: "Advice", Raw: "Bring sunglasses."},
+		&agent.TaskOutput{Name: "summary", Description: "Summary", Raw: "Nice day."},
+	))
+	if err != nil {
+		t.Fatalf("NewBaseline failed: %v", err)
+	}
+
+	current := regressionOutput(
+		&agent.TaskOutput{Name: "forecast", Description: "Forecast tomorrow", Raw: "Tomorrow will be sunny and hot."},
+		&agent.TaskOutput{Name: "advice", Description: "Advice", Raw: "Take an umbrella, rain is likely."},
+		&agent.TaskOutput{Name: "outfit", Description: "Outfit", Raw: "Shorts."},
+	)
+
+	opts := DefaultBaselineCompareOptions()
+	opts.TaskThresholds = map[string]float64{"forecast": 0.85}
+	report, err := CompareBaseline(context.Background(), baseline, current, opts)
+	if err != nil {
+		t.Fatalf("CompareBaseline failed: %v", err)
+	}
+	if report.Passed || !report.TasksChanged || report.Similarity != SimilarityLevenshtein {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	byTask := make(map[string]TaskRegression)
+	for _, task := range report.Tasks {
+		byTask[task.Task] = task
+	}
+	forecast := byTask["forecast"]
+	if forecast.Status != RegressionPassed || forecast.Threshold != 0.85 || !forecast.DescriptionChanged || forecast.Similarity < 0.85 {
+		t.Errorf("unexpected forecast result: %+v", forecast)
+	}
+	if advice := byTask["advice"]; advice.Status != RegressionFailed || advice.Threshold != 0.8 {
+		t.Errorf("unexpected advice result: %+v", advice)
+	}
+	if byTask["summary"].Status != RegressionMissing || byTask["outfit"].Status != RegressionNew {
+		t.Errorf("expected missing summary and new outfit, got %+v", report.Tasks)
+	}
+	if len(report.Regressions()) != 2 {
+		t.Errorf("expected 2 regressions, got %+v", report.Regressions())
+	}
+
+	// 有嵌入器时按余弦相似度比较
+	opts.Embedder = fixedEmbedder{}
+	report, err = CompareBaseline(context.Background(), baseline, current, opts)
+	if err != nil {
+		t.Fatalf("CompareBaseline failed: %v", err)
+	}
+	if report.Similarity != SimilarityEmbedding || report.Tasks[0].Similarity != 1 || report.Tasks[1].Similarity != 0 {
+		t.Errorf("unexpected embedding similarities: %+v", report.Tasks)
+	}
+}
+
+func TestCaptureAndCompareBaseline(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	model := llmtest.NewScriptedLLM(llmtest.Replies(
+		"Go is a compiled language with fast builds.", "Go: fast builds, simple syntax.",
+		"Go is a compiled language with very fast builds.", "Rust: memory safety without GC.",
+	)...)
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: "Writer", Goal: "Write", Backstory: "Writes", LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	c := NewBaseCrew(&CrewConfig{Name: "writer-crew"}, eventBus, log)
+	c.AddAgent(writer)
+	c.AddTask(agent.NewTaskWithOptions("Describe {topic}", "A sentence", agent.WithName("describe"), agent.WithAssignedAgent(writer)))
+	c.AddTask(agent.NewTaskWithOptions("Summarize it", "A phrase", agent.WithName("summarize"), agent.WithAssignedAgent(writer)))
+
+	path := filepath.Join(t.TempDir(), "baselines", "writer.json")
+	inputs := map[string]interface{}{"topic": "Go"}
+	baseline, err := c.CaptureBaseline(context.Background(), inputs, path)
+	if err != nil {
+		t.Fatalf("CaptureBaseline failed: %v", err)
+	}
+	if len(baseline.Tasks) != 2 || baseline.Tasks[1].Output != "Go: fast builds, simple syntax." {
+		t.Fatalf("unexpected baseline: %+v", baseline)
+	}
+
+	loaded, err := LoadBaseline(path)
+	if err != nil || loaded.TasksHash != baseline.TasksHash || loaded.Inputs["topic"] != "Go" {
+		t.Fatalf("unexpected loaded baseline: %+v, %v", loaded, err)
+	}
+
+	report, err := c.CompareAgainstBaseline(context.Background(), inputs, path, DefaultBaselineCompareOptions())
+	if err != nil {
+		t.Fatalf("CompareAgainstBaseline failed: %v", err)
+	}
+	model.Verify(t)
+	if report.Passed || report.TasksChanged {
+		t.Errorf("expected a regression with unchanged tasks, got %+v", report)
+	}
+	if report.Tasks[0].Status != RegressionPassed || report.Tasks[1].Status != RegressionFailed {
+		t.Errorf("expected describe to pass and summarize to regress, got %+v", report.Tasks)
+	}
+}
@@ -0,0 +1,462 @@
+package crew
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// DefaultReplayDir 执行快照的默认存储目录，相对当前工作目录
+const DefaultReplayDir = ".greensoulai/replay"
+
+const (
+	replayKickoffFile    = "kickoff.json"
+	replayTaskFilePrefix = "task_"
+)
+
+// ErrKickoffNotFound 快照存储中没有指定的Kickoff
+var ErrKickoffNotFound = errors.New("kickoff not found")
+
+// ReplayTask Kickoff开始时任务列表中的一项
+type ReplayTask struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description"` // 规划前的任务描述
+}
+
+// KickoffRecord 一次Kickoff的记录，保存在<kickoff-id>/kickoff.json
+type KickoffRecord struct {
+	KickoffID      string                 `json:"kickoff_id"`
+	CrewName       string                 `json:"crew_name"`
+	Process        string                 `json:"process"`
+	Inputs         map[string]interface{} `json:"inputs"`
+	Tasks          []ReplayTask           `json:"tasks"`
+	TasksHash      string                 `json:"tasks_hash"`                 // 任务描述的哈希，用于发现任务列表的变化
+	ReplayOf       string                 `json:"replay_of,omitempty"`        // 由ReplayFrom产生时为来源Kickoff的ID
+	ReplayFromTask string                 `json:"replay_from_task,omitempty"` // 由ReplayFrom产生时为重放起点的任务ID
+	StartedAt      time.Time              `json:"started_at"`
+}
+
+// TaskSnapshot 单个任务完成后的执行快照，保存在<kickoff-id>/task_<index>.json
+type TaskSnapshot struct {
+	KickoffID   string                 `json:"kickoff_id"`
+	TaskID      string                 `json:"task_id"`
+	TaskIndex   int                    `json:"task_index"`
+	Description string                 `json:"description"`
+	Agent       string                 `json:"agent,omitempty"`
+	Inputs      map[string]interface{} `json:"inputs"`
+	Context     map[string]interface{} `json:"context,omitempty"` // 任务执行时的上下文，之前任务的完整输出不重复保存
+	Output      *agent.TaskOutput      `json:"output"`
+	Replayed    bool                   `json:"replayed,omitempty"` // 输出由ReplayFrom从来源Kickoff复用，没有重新执行
+	CompletedAt time.Time              `json:"completed_at"`
+}
+
+// ReplayStore 以JSON文件保存Kickoff记录和任务快照，每次Kickoff一个子目录
+type ReplayStore struct {
+	dir string
+}
+
+// NewReplayStore 创建快照存储，dir为空时使用DefaultReplayDir
+func NewReplayStore(dir string) *ReplayStore {
+	if dir == "" {
+		dir = DefaultReplayDir
+	}
+	return &ReplayStore{dir: dir}
+}
+
+// Dir 返回存储目录
+func (s *ReplayStore) Dir() string {
+	return s.dir
+}
+
+// kickoffDir 返回Kickoff的子目录，拒绝可能跳出存储目录的ID
+func (s *ReplayStore) kickoffDir(kickoffID string) (string, error) {
+	if kickoffID == "" || kickoffID == "." || kickoffID == ".." || strings.ContainsAny(kickoffID, `/\`) {
+		return "", fmt.Errorf("invalid kickoff id %q", kickoffID)
+	}
+	return filepath.Join(s.dir, kickoffID), nil
+}
+
+// SaveKickoff 写入Kickoff记录
+func (s *ReplayStore) SaveKickoff(record *KickoffRecord) error {
+	dir, err := s.kickoffDir(record.KickoffID)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filepath.Join(dir, replayKickoffFile), record)
+}
+
+// SaveTask 写入任务快照，同一任务的快照会被覆盖
+func (s *ReplayStore) SaveTask(snapshot *TaskSnapshot) error {
+	dir, err := s.kickoffDir(snapshot.KickoffID)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s%03d.json", replayTaskFilePrefix, snapshot.TaskIndex)
+	return writeJSONFile(filepath.Join(dir, name), snapshot)
+}
+
+// LoadKickoff 读取Kickoff记录及其任务快照，快照按任务索引排序
+func (s *ReplayStore) LoadKickoff(kickoffID string) (*KickoffRecord, []*TaskSnapshot, error) {
+	dir, err := s.kickoffDir(kickoffID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var record KickoffRecord
+	if err := readJSONFile(filepath.Join(dir, replayKickoffFile), &record); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrKickoffNotFound, kickoffID)
+		}
+		return nil, nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, replayTaskFilePrefix+"*.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshots := make([]*TaskSnapshot, 0, len(paths))
+	for _, path := range paths {
+		var snapshot TaskSnapshot
+		if err := readJSONFile(path, &snapshot); err != nil {
+			return nil, nil, err
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].TaskIndex < snapshots[j].TaskIndex
+	})
+
+	return &record, snapshots, nil
+}
+
+// ListKickoffs 返回存储中的所有Kickoff记录，最近开始的排在前面
+// 存储目录不存在时返回空列表
+func (s *ReplayStore) ListKickoffs() ([]*KickoffRecord, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	records := make([]*KickoffRecord, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		var record KickoffRecord
+		if err := readJSONFile(filepath.Join(s.dir, entry.Name(), replayKickoffFile), &record); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+	return records, nil
+}
+
+// writeJSONFile 先写临时文件再重命名，避免中断时留下不完整的快照
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readJSONFile 读取JSON文件到v
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// TasksHash 计算任务描述的哈希，两次Kickoff的哈希不同说明任务列表发生了变化
+func TasksHash(tasks []ReplayTask) string {
+	hash := sha256.New()
+	for _, task := range tasks {
+		hash.Write([]byte(task.Description))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replaySession Kickoff期间的快照记录与重放状态，通过ctx传给各流程
+type replaySession struct {
+	store     *ReplayStore // 为nil时不写入快照
+	kickoffID string
+	inputs    map[string]interface{}
+
+	// 重放时的来源Kickoff、起点任务和复用的来源快照（按任务索引）
+	replayOf   string
+	fromTaskID string
+	reused     map[int]*TaskSnapshot
+}
+
+type replaySessionKey struct{}
+
+// replaySessionFrom 返回ctx中的重放会话，没有时返回nil
+func replaySessionFrom(ctx context.Context) *replaySession {
+	session, _ := ctx.Value(replaySessionKey{}).(*replaySession)
+	return session
+}
+
+// replayTasks 返回当前任务列表的快照记录，描述使用规划前的原始描述
+func (c *BaseCrew) replayTasks() []ReplayTask {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tasks := make([]ReplayTask, len(c.tasks))
+	for i, task := range c.tasks {
+		description, ok := c.originalDescriptions[task.GetID()]
+		if !ok {
+			description = task.GetDescription()
+		}
+		tasks[i] = ReplayTask{ID: task.GetID(), Name: task.GetName(), Description: description}
+	}
+	return tasks
+}
+
+// startReplaySession 开启快照记录或重放时创建会话，开启快照记录时写入Kickoff记录
+// replay为ReplayFrom准备的重放状态，普通Kickoff时为nil；写入失败只记录警告，不影响执行
+func (c *BaseCrew) startReplaySession(ctx context.Context, inputs map[string]interface{}, replay *replaySession) (context.Context, *replaySession) {
+	if !c.replayEnabled && replay == nil {
+		return ctx, nil
+	}
+
+	session := replay
+	if session == nil {
+		session = &replaySession{}
+	}
+	session.inputs = inputs
+
+	if c.replayEnabled {
+		session.store = NewReplayStore(c.replayDir)
+		session.kickoffID = uuid.New().String()
+
+		tasks := c.replayTasks()
+		record := &KickoffRecord{
+			KickoffID:      session.kickoffID,
+			CrewName:       c.name,
+			Process:        c.process.String(),
+			Inputs:         jsonSafeMap(inputs),
+			Tasks:          tasks,
+			TasksHash:      TasksHash(tasks),
+			ReplayOf:       session.replayOf,
+			ReplayFromTask: session.fromTaskID,
+			StartedAt:      time.Now(),
+		}
+		if err := session.store.SaveKickoff(record); err != nil {
+			c.logger.Warn("failed to save kickoff record",
+				logger.Field{Key: "kickoff_id", Value: session.kickoffID},
+				logger.Field{Key: "error", Value: err},
+			)
+		}
+	}
+
+	return context.WithValue(ctx, replaySessionKey{}, session), session
+}
+
+// reusedTaskOutput 重放时返回来源Kickoff中该任务的输出，任务需要执行时返回nil
+// 复用的输出同样写入本次Kickoff的快照，使本次Kickoff也可以再次重放
+func (c *BaseCrew) reusedTaskOutput(ctx context.Context, task agent.Task, index int) *agent.TaskOutput {
+	session := replaySessionFrom(ctx)
+	if session == nil {
+		return nil
+	}
+	snapshot, ok := session.reused[index]
+	if !ok {
+		return nil
+	}
+
+	c.logger.Info("task output reused from replayed kickoff",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "task_index", Value: index},
+		logger.Field{Key: "task_id", Value: task.GetID()},
+		logger.Field{Key: "replay_of", Value: session.replayOf},
+	)
+	c.eventBus.Emit(ctx, c, NewTaskExecutionSkippedEvent(index, task.GetDescription(), "replayed"))
+	statusTrackerFrom(ctx).taskSkipped()
+
+	// 标记复用的输出，使用统计不再重复计入来源Kickoff的开销
+	output := snapshot.Output
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]interface{})
+	}
+	output.Metadata["replayed_from"] = session.replayOf
+
+	if session.store != nil {
+		c.saveTaskSnapshot(session, &TaskSnapshot{
+			TaskID:      task.GetID(),
+			TaskIndex:   index,
+			Description: task.GetDescription(),
+			Agent:       snapshot.Agent,
+			Context:     snapshot.Context,
+			Output:      output,
+			Replayed:    true,
+		})
+	}
+	return output
+}
+
+// recordTaskSnapshot 开启快照记录时写入任务完成后的快照
+func (c *BaseCrew) recordTaskSnapshot(ctx context.Context, task agent.Task, index int, agentRole string, taskContext map[string]interface{}, output *agent.TaskOutput) {
+	session := replaySessionFrom(ctx)
+	if session == nil || session.store == nil {
+		return
+	}
+
+	// 之前任务的完整输出已有各自的快照，不在上下文中重复保存
+	snapshotContext := make(map[string]interface{}, len(taskContext))
+	for key, value := range taskContext {
+		if key != "previous_tasks_output" {
+			snapshotContext[key] = value
+		}
+	}
+
+	c.saveTaskSnapshot(session, &TaskSnapshot{
+		TaskID:      task.GetID(),
+		TaskIndex:   index,
+		Description: task.GetDescription(),
+		Agent:       agentRole,
+		Context:     jsonSafeMap(snapshotContext),
+		Output:      output,
+	})
+}
+
+// saveTaskSnapshot 补全快照的Kickoff信息后写入存储，失败只记录警告
+func (c *BaseCrew) saveTaskSnapshot(session *replaySession, snapshot *TaskSnapshot) {
+	snapshot.KickoffID = session.kickoffID
+	snapshot.Inputs = jsonSafeMap(session.inputs)
+	snapshot.CompletedAt = time.Now()
+
+	if err := session.store.SaveTask(snapshot); err != nil {
+		c.logger.Warn("failed to save task snapshot",
+			logger.Field{Key: "kickoff_id", Value: session.kickoffID},
+			logger.Field{Key: "task_index", Value: snapshot.TaskIndex},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+}
+
+// jsonSafeMap 返回只包含可以序列化为JSON的值的副本，无法序列化的值（如函数、通道）被丢弃
+func jsonSafeMap(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	safe := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if _, err := json.Marshal(value); err == nil {
+			safe[key] = value
+		}
+	}
+	return safe
+}
+
+// ReplayFrom 从一次记录过的Kickoff的指定任务开始重新执行
+// taskID之前的任务不再执行，直接复用快照中的输出作为后续任务的上下文；输入为来源Kickoff的输入加上overrides。
+// taskID可以是记录中的任务ID或任务名称，也可以是当前Crew的任务ID；当前任务描述与记录不一致时记录警告，结果可能与预期不符
+func (c *BaseCrew) ReplayFrom(ctx context.Context, kickoffID, taskID string, overrides map[string]interface{}) (*CrewOutput, error) {
+	store := NewReplayStore(c.replayDir)
+	record, snapshots, err := store.LoadKickoff(kickoffID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kickoff %s: %w", kickoffID, err)
+	}
+
+	currentTasks := c.replayTasks()
+	fromIndex := replayTaskIndex(record.Tasks, taskID)
+	if fromIndex < 0 {
+		fromIndex = replayTaskIndex(currentTasks, taskID)
+	}
+	if fromIndex < 0 {
+		return nil, fmt.Errorf("task %s not found in kickoff %s", taskID, kickoffID)
+	}
+	if fromIndex >= len(currentTasks) {
+		return nil, fmt.Errorf("task %s is at index %d but the crew has only %d tasks", taskID, fromIndex, len(currentTasks))
+	}
+
+	if TasksHash(currentTasks) != record.TasksHash {
+		c.logger.Warn("task list changed since the replayed kickoff, stored outputs may not match the current tasks",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "kickoff_id", Value: kickoffID},
+			logger.Field{Key: "recorded_tasks", Value: len(record.Tasks)},
+			logger.Field{Key: "current_tasks", Value: len(currentTasks)},
+		)
+	}
+
+	// 起点之前的每个任务都需要有快照
+	reused := make(map[int]*TaskSnapshot, fromIndex)
+	for _, snapshot := range snapshots {
+		if snapshot.TaskIndex < fromIndex && snapshot.Output != nil {
+			reused[snapshot.TaskIndex] = snapshot
+		}
+	}
+	for i := 0; i < fromIndex; i++ {
+		if _, ok := reused[i]; !ok {
+			return nil, fmt.Errorf("kickoff %s has no stored output for task %d", kickoffID, i)
+		}
+	}
+
+	inputs := make(map[string]interface{}, len(record.Inputs)+len(overrides))
+	for key, value := range record.Inputs {
+		inputs[key] = value
+	}
+	for key, value := range overrides {
+		inputs[key] = value
+	}
+
+	c.logger.Info("replaying crew kickoff",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "kickoff_id", Value: kickoffID},
+		logger.Field{Key: "from_task_index", Value: fromIndex},
+		logger.Field{Key: "reused_tasks", Value: len(reused)},
+	)
+
+	return c.kickoff(ctx, inputs, &replaySession{
+		replayOf:   kickoffID,
+		fromTaskID: taskID,
+		reused:     reused,
+	})
+}
+
+// replayTaskIndex 返回ID或名称为taskID的任务在列表中的索引，不存在时返回-1
+// 任务ID在每次构建Crew时重新生成，跨进程重放时按任务名称查找
+func replayTaskIndex(tasks []ReplayTask, taskID string) int {
+	for i, task := range tasks {
+		if task.ID == taskID || (task.Name != "" && task.Name == taskID) {
+			return i
+		}
+	}
+	return -1
+}
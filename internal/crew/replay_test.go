@@ -0,0 +1,135 @@
+package crew
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// warnRecordingLogger 记录警告消息的测试日志
+type warnRecordingLogger struct {
+	logger.Logger
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (l *warnRecordingLogger) Warn(msg string, fields ...logger.Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, msg)
+}
+
+func newReplayTestCrew(t *testing.T, log logger.Logger) (*BaseCrew, *[]string) {
+	t.Helper()
+	config := DefaultCrewConfig()
+	config.ReplayEnabled = true
+	config.ReplayDir = t.TempDir()
+	crew := NewBaseCrew(config, events.NewEventBus(log), log)
+
+	crew.AddAgent(&MockAgent{id: "agent1", role: "Writer", goal: "Write", backstory: "Writer"})
+	crew.AddTask(&MockTask{id: "t1", description: "Research the topic", expectedOutput: "Notes"})
+	crew.AddTask(&MockTask{id: "t2", description: "Draft the article", expectedOutput: "Draft"})
+	crew.AddTask(&MockTask{id: "t3", description: "Edit the article", expectedOutput: "Article"})
+
+	executed := make([]string, 0)
+	crew.AddTaskCallback(func(ctx context.Context, task agent.Task, output *agent.TaskOutput) error {
+		executed = append(executed, task.GetID())
+		return nil
+	})
+	return crew, &executed
+}
+
+func TestReplayFromSkipsEarlierTasks(t *testing.T) {
+	crew, executed := newReplayTestCrew(t, logger.NewTestLogger())
+
+	result, err := crew.Kickoff(context.Background(), map[string]interface{}{"topic": "Go", "audience": "developers"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kickoffID, _ := result.Metadata["kickoff_id"].(string)
+	if kickoffID == "" {
+		t.Fatalf("expected kickoff id in metadata, got %v", result.Metadata)
+	}
+
+	store := NewReplayStore(crew.replayDir)
+	record, snapshots, err := store.LoadKickoff(kickoffID)
+	if err != nil {
+		t.Fatalf("failed to load kickoff: %v", err)
+	}
+	if len(record.Tasks) != 3 || record.Inputs["topic"] != "Go" || len(snapshots) != 3 {
+		t.Fatalf("unexpected kickoff record %+v with %d snapshots", record, len(snapshots))
+	}
+	if snapshots[1].TaskID != "t2" || snapshots[1].Agent != "Writer" || snapshots[1].Output.Raw != "Mock agent output for: Draft the article" {
+		t.Errorf("unexpected snapshot: %+v", snapshots[1])
+	}
+	if _, ok := snapshots[1].Context["previous_tasks_output"]; ok || snapshots[1].Context["aggregated_context"] == nil {
+		t.Errorf("expected resolved context without full previous outputs, got %v", snapshots[1].Context)
+	}
+
+	*executed = (*executed)[:0]
+	replayed, err := crew.ReplayFrom(context.Background(), kickoffID, "t2", map[string]interface{}{"topic": "Rust"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(*executed, ",") != "t2,t3" {
+		t.Errorf("expected only t2 and t3 to be executed, got %v", *executed)
+	}
+	if len(replayed.TasksOutput) != 3 || replayed.TasksOutput[0].Raw != snapshots[0].Output.Raw {
+		t.Errorf("expected the stored output of t1 to be reused, got %+v", replayed.TasksOutput)
+	}
+	if replayed.Metadata["replay_of"] != kickoffID || replayed.TokenUsage.SuccessfulTasks != 3 {
+		t.Errorf("unexpected replay result metadata %v, usage %+v", replayed.Metadata, replayed.TokenUsage)
+	}
+
+	// 重放本身也会记录快照，输入为原输入加上覆盖的输入
+	replayRecord, replaySnapshots, err := store.LoadKickoff(replayed.Metadata["kickoff_id"].(string))
+	if err != nil {
+		t.Fatalf("failed to load replay kickoff: %v", err)
+	}
+	if replayRecord.ReplayOf != kickoffID || replayRecord.Inputs["topic"] != "Rust" || replayRecord.Inputs["audience"] != "developers" {
+		t.Errorf("unexpected replay record: %+v", replayRecord)
+	}
+	if len(replaySnapshots) != 3 || !replaySnapshots[0].Replayed || replaySnapshots[1].Replayed {
+		t.Errorf("expected only t1 to be marked as replayed, got %+v", replaySnapshots)
+	}
+
+	if _, err := crew.ReplayFrom(context.Background(), kickoffID, "missing", nil); err == nil {
+		t.Error("expected error for unknown task")
+	}
+	if _, err := crew.ReplayFrom(context.Background(), "missing", "t2", nil); err == nil {
+		t.Error("expected error for unknown kickoff")
+	}
+}
+
+func TestReplayFromWarnsWhenTasksChanged(t *testing.T) {
+	log := &warnRecordingLogger{Logger: logger.NewTestLogger()}
+	crew, _ := newReplayTestCrew(t, log)
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kickoffID := result.Metadata["kickoff_id"].(string)
+
+	crew.tasks[2].(*MockTask).description = "Edit and publish the article"
+	if _, err := crew.ReplayFrom(context.Background(), kickoffID, "t3", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	found := false
+	for _, warning := range log.warnings {
+		if strings.Contains(warning, "task list changed") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a task list mismatch warning, got %v", log.warnings)
+	}
+}
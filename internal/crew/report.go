@@ -0,0 +1,701 @@
+package crew
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+)
+
+// ReportFormat 运行报告的格式
+type ReportFormat string
+
+const (
+	ReportFormatMarkdown ReportFormat = "markdown"
+	ReportFormatHTML     ReportFormat = "html"
+)
+
+// defaultReportPreviewLength HTML报告中输出默认显示的字符数，超出部分折叠在"显示更多"中
+const defaultReportPreviewLength = 2000
+
+// reportEventTypes 运行报告使用的事件：工具调用的次数、失败和耗时，以及没有输出的失败任务
+var reportEventTypes = []string{events.EventTypeToolUsageFinished, "task_execution_failed"}
+
+// ReportFormatFromPath 按文件扩展名推断报告格式，.html和.htm为HTML，其余为Markdown
+func ReportFormatFromPath(path string) ReportFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return ReportFormatHTML
+	default:
+		return ReportFormatMarkdown
+	}
+}
+
+// ReportOption 运行报告选项
+type ReportOption func(*reportOptions)
+
+type reportOptions struct {
+	title         string
+	events        []events.Event
+	previewLength int
+}
+
+// WithReportTitle 设置报告标题，默认为"Crew 运行报告"
+func WithReportTitle(title string) ReportOption {
+	return func(o *reportOptions) {
+		o.title = title
+	}
+}
+
+// WithReportEvents 使用执行期间记录的事件补充工具调用明细和失败的任务，通常来自ReportRecorder
+func WithReportEvents(recorded []events.Event) ReportOption {
+	return func(o *reportOptions) {
+		o.events = recorded
+	}
+}
+
+// WithReportPreviewLength 设置HTML报告中输出直接显示的字符数，Markdown报告始终保留完整输出
+func WithReportPreviewLength(length int) ReportOption {
+	return func(o *reportOptions) {
+		if length > 0 {
+			o.previewLength = length
+		}
+	}
+}
+
+// WriteReport 把Crew的运行结果渲染为报告写入path，format为空时按扩展名推断
+func WriteReport(output *CrewOutput, path string, format ReportFormat, opts ...ReportOption) error {
+	if format == "" {
+		format = ReportFormatFromPath(path)
+	}
+	var buf bytes.Buffer
+	if err := RenderReport(&buf, output, format, opts...); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// RenderReport 渲染运行报告：摘要（状态、耗时、token、成本），每个任务的智能体、耗时、token、成本、使用的工具、输出和元数据，
+// 以及综合结果和Crew的元数据。二进制或不可打印的元数据值被跳过
+func RenderReport(w io.Writer, output *CrewOutput, format ReportFormat, opts ...ReportOption) error {
+	if output == nil {
+		return fmt.Errorf("crew output is nil")
+	}
+	options := reportOptions{previewLength: defaultReportPreviewLength}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	view := newReportView(output, options)
+	switch format {
+	case ReportFormatMarkdown:
+		_, err := io.WriteString(w, view.markdown())
+		return err
+	case ReportFormatHTML:
+		return view.html(w, options.previewLength)
+	default:
+		return fmt.Errorf("unsupported report format %q, expected %s or %s", format, ReportFormatMarkdown, ReportFormatHTML)
+	}
+}
+
+// ReportRecorder 记录运行报告使用的事件，在Kickoff之前创建，结束后把Events传给WithReportEvents
+type ReportRecorder struct {
+	mu            sync.Mutex
+	recorded      []events.Event
+	subscriptions []*events.Subscription
+}
+
+// NewReportRecorder 在事件总线上同步记录工具调用和任务失败事件，eventBus为nil时不记录
+func NewReportRecorder(eventBus events.EventBus) (*ReportRecorder, error) {
+	recorder := &ReportRecorder{}
+	if eventBus == nil {
+		return recorder, nil
+	}
+	for _, eventType := range reportEventTypes {
+		subscription, err := eventBus.SubscribeWithOptions(eventType, recorder.record, events.WithSyncDelivery())
+		if err != nil {
+			recorder.Close()
+			return nil, fmt.Errorf("failed to subscribe to %s events: %w", eventType, err)
+		}
+		recorder.subscriptions = append(recorder.subscriptions, subscription)
+	}
+	return recorder, nil
+}
+
+func (r *ReportRecorder) record(ctx context.Context, event events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recorded = append(r.recorded, event)
+	return nil
+}
+
+// Events 返回已记录的事件
+func (r *ReportRecorder) Events() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.recorded...)
+}
+
+// Close 取消订阅，已记录的事件仍然可以读取
+func (r *ReportRecorder) Close() {
+	for _, subscription := range r.subscriptions {
+		subscription.Unsubscribe()
+	}
+	r.subscriptions = nil
+}
+
+// reportView 渲染Markdown和HTML共用的报告内容
+type reportView struct {
+	Title     string
+	Summary   []reportField
+	Tasks     []*reportTask
+	Failures  []reportFailure
+	Synthesis *reportTask
+	Metadata  []reportField
+}
+
+type reportField struct {
+	Label string
+	Value string
+}
+
+type reportTask struct {
+	Index    int
+	Anchor   string
+	Heading  string
+	Name     string
+	Agent    string
+	Duration string
+	Tokens   int
+	Cost     string
+	Fields   []reportField
+	Tools    []*reportToolCalls
+	Output   string
+	Language string
+	Metadata []reportField
+}
+
+// reportToolCalls 事件中记录的一个任务对一个工具的调用
+type reportToolCalls struct {
+	Name     string
+	Calls    int
+	Failures int
+	duration time.Duration
+}
+
+func (c *reportToolCalls) Duration() string { return formatReportDuration(c.duration) }
+
+type reportFailure struct {
+	Index    int
+	Task     string
+	Agent    string
+	Duration string
+	Error    string
+}
+
+// newReportView 整理Crew输出和事件中的报告内容
+func newReportView(output *CrewOutput, options reportOptions) *reportView {
+	view := &reportView{Title: options.title}
+	if view.Title == "" {
+		view.Title = "Crew 运行报告"
+	}
+
+	toolCalls := make(map[string][]*reportToolCalls)
+	for _, event := range options.events {
+		switch e := event.(type) {
+		case *agent.AgentToolUsageCompletedEvent:
+			toolCalls[e.TaskID] = addReportToolCall(toolCalls[e.TaskID], e)
+		case *TaskExecutionFailedEvent:
+			view.Failures = append(view.Failures, reportFailure{
+				Index:    e.TaskIndex + 1,
+				Task:     reportTaskTitle(e.TaskDescription),
+				Agent:    e.AgentRole,
+				Duration: formatReportDuration(e.Duration),
+				Error:    e.Error,
+			})
+		}
+	}
+
+	completed, skipped := 0, 0
+	for _, taskOutput := range output.TasksOutput {
+		if taskOutput == nil {
+			continue
+		}
+		if agent.IsSkippedOutput(taskOutput) {
+			skipped++
+		} else {
+			completed++
+		}
+		index := len(view.Tasks) + 1
+		task := newReportTask(taskOutput, fmt.Sprintf("task-%d", index), toolCalls[taskOutput.Task])
+		task.Index = index
+		task.Heading = fmt.Sprintf("%d. %s", index, task.Name)
+		view.Tasks = append(view.Tasks, task)
+	}
+	if output.SynthesisOutput != nil {
+		view.Synthesis = newReportTask(output.SynthesisOutput, "synthesis", toolCalls[output.SynthesisOutput.Task])
+		view.Synthesis.Heading = view.Synthesis.Name
+	}
+
+	status := "✅ 成功"
+	if !output.Success {
+		status = "❌ 失败"
+	}
+	view.Summary = append(view.Summary, reportField{"状态", status})
+	if !output.CreatedAt.IsZero() {
+		view.Summary = append(view.Summary, reportField{"完成时间", output.CreatedAt.Format("2006-01-02 15:04:05 MST")})
+	}
+	view.Summary = append(view.Summary, reportField{"总耗时", formatReportDuration(output.Duration)})
+
+	var usage *UsageMetrics
+	if output.TokenUsage != nil {
+		usage = output.TokenUsage.Snapshot()
+	}
+	failed := len(view.Failures)
+	if usage != nil && usage.FailedTasks > failed {
+		failed = usage.FailedTasks
+	}
+	view.Summary = append(view.Summary, reportField{"任务", fmt.Sprintf("%d 个完成，%d 个跳过，%d 个失败", completed, skipped, failed)})
+	if usage != nil {
+		view.Summary = append(view.Summary,
+			reportField{"Token", formatReportTokens(usage.TotalTokens, usage.PromptTokens, usage.CompletionTokens)},
+			reportField{"成本", formatReportCost(usage.TotalCost)},
+			reportField{"LLM 调用", fmt.Sprintf("%d", usage.LLMCalls)},
+		)
+	}
+	if output.Error != nil {
+		view.Summary = append(view.Summary, reportField{"错误", output.Error.Error()})
+	}
+	if output.SynthesisError != nil {
+		view.Summary = append(view.Summary, reportField{"综合失败", output.SynthesisError.Error()})
+	}
+	view.Metadata = reportMetadata(output.Metadata)
+	return view
+}
+
+// newReportTask 整理一个任务输出
+func newReportTask(output *agent.TaskOutput, anchor string, tools []*reportToolCalls) *reportTask {
+	task := &reportTask{
+		Anchor:   anchor,
+		Name:     output.Name,
+		Agent:    output.Agent,
+		Duration: formatReportDuration(output.ExecutionTime),
+		Tokens:   output.TokensUsed,
+		Cost:     formatReportCost(output.Cost),
+		Tools:    tools,
+		Output:   output.Raw,
+		Language: "text",
+		Metadata: reportMetadata(output.Metadata),
+	}
+	if task.Name == "" {
+		task.Name = reportTaskTitle(output.Description)
+	}
+	if task.Name == "" {
+		task.Name = output.Task
+	}
+	if output.OutputFormat != agent.OutputFormatRAW && output.JSON != nil {
+		task.Language = "json"
+	}
+
+	task.Fields = append(task.Fields, reportField{"智能体", output.Agent})
+	if output.Model != "" {
+		task.Fields = append(task.Fields, reportField{"模型", output.Model})
+	}
+	task.Fields = append(task.Fields,
+		reportField{"耗时", task.Duration},
+		reportField{"Token", formatReportTokens(output.TokensUsed, output.PromptTokens, output.CompletionTokens)},
+		reportField{"成本", task.Cost},
+	)
+	if output.LLMStats.Calls > 0 {
+		task.Fields = append(task.Fields, reportField{"LLM 调用", fmt.Sprintf("%d", output.LLMStats.Calls)})
+	}
+	if used := reportToolsUsed(output); used != "" {
+		task.Fields = append(task.Fields, reportField{"工具", used})
+	}
+	if agent.IsSkippedOutput(output) {
+		task.Fields = append(task.Fields, reportField{"状态", "已跳过"})
+	} else if output.ValidationError != "" {
+		task.Fields = append(task.Fields, reportField{"校验错误", output.ValidationError})
+	}
+	return task
+}
+
+// addReportToolCall 按工具累加一次调用，工具按第一次调用的顺序排列
+func addReportToolCall(calls []*reportToolCalls, event *agent.AgentToolUsageCompletedEvent) []*reportToolCalls {
+	var current *reportToolCalls
+	for _, call := range calls {
+		if call.Name == event.ToolName {
+			current = call
+			break
+		}
+	}
+	if current == nil {
+		current = &reportToolCalls{Name: event.ToolName}
+		calls = append(calls, current)
+	}
+	current.Calls++
+	current.duration += event.Duration
+	if !event.Success {
+		current.Failures++
+	}
+	return calls
+}
+
+// reportToolsUsed 列出任务使用的工具和调用次数，如"search ×2, calculator"
+func reportToolsUsed(output *agent.TaskOutput) string {
+	counts, _ := output.Metadata["tool_usage"].(map[string]int)
+	var parts []string
+	seen := make(map[string]bool)
+	for _, tool := range output.ToolsUsed {
+		if seen[tool] {
+			continue
+		}
+		seen[tool] = true
+		if count := counts[tool]; count > 1 {
+			parts = append(parts, fmt.Sprintf("%s ×%d", tool, count))
+		} else {
+			parts = append(parts, tool)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reportTaskTitle 取任务描述的第一行作为标题，过长时截断
+func reportTaskTitle(description string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	return truncateRunes(strings.TrimSpace(title), 80)
+}
+
+// reportMetadata 按键排序整理元数据，跳过无法安全显示的值
+func reportMetadata(metadata map[string]interface{}) []reportField {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var fields []reportField
+	for _, key := range keys {
+		if !isPrintableText(key) {
+			continue
+		}
+		if value, ok := reportValue(metadata[key]); ok {
+			fields = append(fields, reportField{key, value})
+		}
+	}
+	return fields
+}
+
+// reportValue 把元数据值转换为单行文本
+// 字节切片、包含控制字符或非法UTF-8的字符串以及无法序列化为JSON的值（函数、通道等）返回false
+func reportValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil, []byte, json.RawMessage:
+		return "", false
+	case string:
+		if !isPrintableText(v) {
+			return "", false
+		}
+		return strings.Join(strings.Fields(v), " "), true
+	case error:
+		return reportValue(v.Error())
+	case time.Duration:
+		return v.String(), true
+	case time.Time:
+		return v.Format(time.RFC3339), true
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", false
+	}
+	text := strings.TrimSpace(buf.String())
+	if text == "null" {
+		return "", false
+	}
+	return text, true
+}
+
+// isPrintableText 文本是合法的UTF-8并且除换行、回车和制表符外不含控制字符
+func isPrintableText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+func formatReportDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+func formatReportCost(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}
+
+func formatReportTokens(total, prompt, completion int) string {
+	if prompt == 0 && completion == 0 {
+		return fmt.Sprintf("%d", total)
+	}
+	return fmt.Sprintf("%d（提示 %d，补全 %d）", total, prompt, completion)
+}
+
+// truncateRunes 截断到最多limit个字符
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit]) + "…"
+}
+
+// markdown 渲染Markdown报告，输出保持完整
+func (v *reportView) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownText(v.Title))
+	writeMarkdownFields(&b, v.Summary)
+
+	if len(v.Tasks) > 0 {
+		b.WriteString("\n## 任务概览\n\n")
+		b.WriteString("| # | 任务 | 智能体 | 耗时 | Token | 成本 |\n")
+		b.WriteString("| --- | --- | --- | --- | ---: | ---: |\n")
+		for _, task := range v.Tasks {
+			fmt.Fprintf(&b, "| %d | [%s](#%s) | %s | %s | %d | %s |\n",
+				task.Index, markdownLinkText(task.Name), task.Anchor, markdownCell(task.Agent), task.Duration, task.Tokens, task.Cost)
+		}
+
+		b.WriteString("\n## 任务详情\n")
+		for _, task := range v.Tasks {
+			writeMarkdownTask(&b, task, "###")
+		}
+	}
+
+	if len(v.Failures) > 0 {
+		b.WriteString("\n## 失败的任务\n\n")
+		b.WriteString("| # | 任务 | 智能体 | 耗时 | 错误 |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, failure := range v.Failures {
+			fmt.Fprintf(&b, "| %d | %s | %s | %s | %s |\n", failure.Index, markdownCell(failure.Task),
+				markdownCell(failure.Agent), failure.Duration, markdownCell(failure.Error))
+		}
+	}
+
+	if v.Synthesis != nil {
+		b.WriteString("\n## 综合结果\n")
+		writeMarkdownTask(&b, v.Synthesis, "")
+	}
+
+	if len(v.Metadata) > 0 {
+		b.WriteString("\n## 元数据\n\n")
+		writeMarkdownMetadata(&b, v.Metadata)
+	}
+	return b.String()
+}
+
+// writeMarkdownTask 输出一个任务的详情，heading为空时不输出标题
+func writeMarkdownTask(b *strings.Builder, task *reportTask, heading string) {
+	if heading != "" {
+		fmt.Fprintf(b, "\n<a id=\"%s\"></a>\n\n%s %s\n", task.Anchor, heading, markdownText(task.Heading))
+	}
+	b.WriteString("\n")
+	writeMarkdownFields(b, task.Fields)
+
+	if len(task.Tools) > 0 {
+		b.WriteString("\n**工具调用**\n\n")
+		b.WriteString("| 工具 | 调用 | 失败 | 耗时 |\n")
+		b.WriteString("| --- | ---: | ---: | --- |\n")
+		for _, tool := range task.Tools {
+			fmt.Fprintf(b, "| %s | %d | %d | %s |\n", markdownCell(tool.Name), tool.Calls, tool.Failures, tool.Duration())
+		}
+	}
+
+	b.WriteString("\n**输出**\n\n")
+	if task.Output == "" {
+		b.WriteString("_（无输出）_\n")
+	} else {
+		fence := markdownFence(task.Output)
+		fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, task.Language, task.Output, fence)
+	}
+
+	if len(task.Metadata) > 0 {
+		b.WriteString("\n**元数据**\n\n")
+		writeMarkdownMetadata(b, task.Metadata)
+	}
+}
+
+func writeMarkdownFields(b *strings.Builder, fields []reportField) {
+	b.WriteString("| 项目 | 值 |\n| --- | --- |\n")
+	for _, field := range fields {
+		fmt.Fprintf(b, "| %s | %s |\n", field.Label, markdownCell(field.Value))
+	}
+}
+
+func writeMarkdownMetadata(b *strings.Builder, fields []reportField) {
+	for _, field := range fields {
+		fmt.Fprintf(b, "- `%s`: %s\n", strings.ReplaceAll(field.Label, "`", "'"), markdownText(field.Value))
+	}
+}
+
+// markdownFence 返回比输出中最长的连续反引号更长的代码块围栏
+func markdownFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// markdownText 把文本压成一行
+func markdownText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// markdownCell 转义表格单元格中的竖线并压成一行
+func markdownCell(s string) string {
+	return strings.ReplaceAll(markdownText(s), "|", `\|`)
+}
+
+func markdownLinkText(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(markdownCell(s))
+}
+
+// html 渲染HTML报告，每个任务是可折叠的区块，超过previewLength的输出只显示开头，完整内容折叠在"显示更多"中
+func (v *reportView) html(w io.Writer, previewLength int) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"truncated": func(s string) bool { return utf8.RuneCountInString(s) > previewLength },
+		"preview":   func(s string) string { return truncateRunes(s, previewLength) },
+		"length":    utf8.RuneCountInString,
+	}).Parse(reportHTMLTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse report template: %w", err)
+	}
+	return tmpl.Execute(w, v)
+}
+
+const reportHTMLTemplate = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #222; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #ddd; padding: 4px 10px; text-align: left; vertical-align: top; }
+th { background: #f5f5f5; }
+details.task { border: 1px solid #ddd; border-radius: 6px; margin: 1em 0; padding: 0.5em 1em; }
+details.task > summary { font-weight: bold; cursor: pointer; }
+pre { background: #f7f7f7; padding: 0.75em; overflow-x: auto; white-space: pre-wrap; word-break: break-word; }
+.failure { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+{{- range .Summary}}
+<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- if .Tasks}}
+<h2>任务概览</h2>
+<table>
+<tr><th>#</th><th>任务</th><th>智能体</th><th>耗时</th><th>Token</th><th>成本</th></tr>
+{{- range .Tasks}}
+<tr><td>{{.Index}}</td><td><a href="#{{.Anchor}}">{{.Name}}</a></td><td>{{.Agent}}</td><td>{{.Duration}}</td><td>{{.Tokens}}</td><td>{{.Cost}}</td></tr>
+{{- end}}
+</table>
+<h2>任务详情</h2>
+{{- range .Tasks}}
+{{template "task" .}}
+{{- end}}
+{{- end}}
+{{- if .Failures}}
+<h2>失败的任务</h2>
+<table>
+<tr><th>#</th><th>任务</th><th>智能体</th><th>耗时</th><th>错误</th></tr>
+{{- range .Failures}}
+<tr><td>{{.Index}}</td><td>{{.Task}}</td><td>{{.Agent}}</td><td>{{.Duration}}</td><td class="failure">{{.Error}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- with .Synthesis}}
+<h2>综合结果</h2>
+{{template "task" .}}
+{{- end}}
+{{- if .Metadata}}
+<h2>元数据</h2>
+{{template "metadata" .Metadata}}
+{{- end}}
+</body>
+</html>
+{{define "task"}}
+<details class="task" id="{{.Anchor}}" open>
+<summary>{{.Heading}}</summary>
+<table>
+{{- range .Fields}}
+<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- if .Tools}}
+<h4>工具调用</h4>
+<table>
+<tr><th>工具</th><th>调用</th><th>失败</th><th>耗时</th></tr>
+{{- range .Tools}}
+<tr><td>{{.Name}}</td><td>{{.Calls}}</td><td>{{.Failures}}</td><td>{{.Duration}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<h4>输出</h4>
+{{- if not .Output}}
+<p><em>（无输出）</em></p>
+{{- else if truncated .Output}}
+<pre>{{preview .Output}}</pre>
+<details id="{{.Anchor}}-output">
+<summary><a href="#{{.Anchor}}-output">显示更多（共 {{length .Output}} 个字符）</a></summary>
+<pre>{{.Output}}</pre>
+</details>
+{{- else}}
+<pre>{{.Output}}</pre>
+{{- end}}
+{{- if .Metadata}}
+<details>
+<summary>元数据</summary>
+{{template "metadata" .Metadata}}
+</details>
+{{- end}}
+</details>
+{{- end}}
+{{define "metadata"}}
+<ul>
+{{- range .}}
+<li><code>{{.Label}}</code>: {{.Value}}</li>
+{{- end}}
+</ul>
+{{- end}}
+`
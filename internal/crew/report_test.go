@@ -0,0 +1,216 @@
+package crew
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+var update = flag.Bool("update", false, "更新golden文件")
+
+// assertReportGolden 比较报告与testdata中的golden文件，-update时重写golden文件
+func assertReportGolden(t *testing.T, name, got string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatalf("failed to create testdata directory: %v", err)
+		}
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("report does not match %s:\n%s", golden, got)
+	}
+}
+
+// reportTestOutput 覆盖各类内容的Crew输出：工具调用、JSON输出、包含反引号的输出、跳过的任务和不可打印的元数据
+func reportTestOutput() *CrewOutput {
+	finishedAt := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	research := &agent.TaskOutput{
+		Raw:              "Go 1.22 added range over integers.\nUse `for i := range 10`.",
+		Agent:            "Researcher",
+		Task:             "task-research",
+		Name:             "research",
+		Description:      "Research the latest Go release",
+		ExecutionTime:    1500 * time.Millisecond,
+		TokensUsed:       420,
+		PromptTokens:     300,
+		CompletionTokens: 120,
+		Cost:             0.0021,
+		Model:            "gpt-4o-mini",
+		LLMStats:         agent.LLMCallStats{Calls: 3},
+		ToolsUsed:        []string{"web_search", "web_search", "calculator"},
+		Metadata: map[string]interface{}{
+			"tool_usage":  map[string]int{"web_search": 2, "calculator": 1},
+			"sources":     []string{"go.dev", "github.com"},
+			"raw_page":    []byte{0x89, 'P', 'N', 'G', 0x00},
+			"control":     "bell\x07",
+			"callback":    func() {},
+			"retry_after": 2 * time.Second,
+		},
+	}
+	write := &agent.TaskOutput{
+		Raw:           "```go\nfmt.Println(\"hi\")\n```\n| done |",
+		JSON:          map[string]interface{}{"title": "Go"},
+		OutputFormat:  agent.OutputFormatJSON,
+		Agent:         "Writer | Editor",
+		Task:          "task-write",
+		Description:   "Write the article\nwith a catchy title",
+		ExecutionTime: 2250 * time.Millisecond,
+		TokensUsed:    800,
+		Cost:          0.004,
+		Model:         "gpt-4o",
+		Metadata:      map[string]interface{}{"note": "multi\nline  value"},
+	}
+	review := &agent.TaskOutput{
+		Agent:       "Reviewer",
+		Task:        "task-review",
+		Name:        "review",
+		Description: "Review the article",
+		Metadata:    map[string]interface{}{"skipped": true},
+	}
+
+	return &CrewOutput{
+		Raw:         write.Raw,
+		TasksOutput: []*agent.TaskOutput{research, write, review},
+		TokenUsage: &UsageMetrics{
+			TotalTokens:      1220,
+			PromptTokens:     300,
+			CompletionTokens: 120,
+			TotalCost:        0.0061,
+			LLMCalls:         4,
+			FailedTasks:      1,
+		},
+		CreatedAt: finishedAt,
+		Duration:  4 * time.Second,
+		Success:   false,
+		Error:     errors.New("1 task(s) failed"),
+		Metadata: map[string]interface{}{
+			"kickoff_id": "kickoff-1",
+			"process":    "sequential",
+			"payload":    []byte("binary"),
+		},
+	}
+}
+
+// reportTestEvents 执行期间记录的工具调用和任务失败事件
+func reportTestEvents() []events.Event {
+	return []events.Event{
+		agent.NewAgentToolUsageCompletedEvent("a1", "Researcher", "task-research", "web_search", 300*time.Millisecond, true, "results", nil),
+		agent.NewAgentToolUsageCompletedEvent("a1", "Researcher", "task-research", "calculator", 5*time.Millisecond, true, 42, nil),
+		agent.NewAgentToolUsageCompletedEvent("a1", "Researcher", "task-research", "web_search", 200*time.Millisecond, false, nil, errors.New("timeout")),
+		NewTaskExecutionFailedEvent(3, "Publish the article\nto the blog", "Publisher", "blog api unavailable", 750*time.Millisecond),
+	}
+}
+
+func TestRenderReportMarkdownGolden(t *testing.T) {
+	var buf bytes.Buffer
+	err := RenderReport(&buf, reportTestOutput(), ReportFormatMarkdown,
+		WithReportTitle("Go 周报"), WithReportEvents(reportTestEvents()))
+	if err != nil {
+		t.Fatalf("RenderReport failed: %v", err)
+	}
+	assertReportGolden(t, "report.md.golden", buf.String())
+}
+
+func TestRenderReportHTML(t *testing.T) {
+	output := reportTestOutput()
+	output.TasksOutput[0].Raw = strings.Repeat("长", 30) + "<script>alert(1)</script>"
+
+	var buf bytes.Buffer
+	if err := RenderReport(&buf, output, ReportFormatHTML, WithReportPreviewLength(20)); err != nil {
+		t.Fatalf("RenderReport failed: %v", err)
+	}
+	html := buf.String()
+
+	if strings.Contains(html, "<script>") {
+		t.Error("expected output to be escaped")
+	}
+	if !strings.Contains(html, "<pre>"+strings.Repeat("长", 20)+"…</pre>") {
+		t.Error("expected long output to be truncated to the preview length")
+	}
+	if !strings.Contains(html, `<a href="#task-1-output">显示更多（共 55 个字符）</a>`) {
+		t.Error("expected a show more anchor for the truncated output")
+	}
+	if !strings.Contains(html, "&lt;script&gt;alert(1)&lt;/script&gt;</pre>") {
+		t.Error("expected the full output in the collapsed section")
+	}
+	if strings.Contains(html, `id="task-3-output"`) || !strings.Contains(html, "<p><em>（无输出）</em></p>") {
+		t.Error("expected empty outputs to be marked instead of collapsed")
+	}
+	if !strings.Contains(html, `<details class="task" id="task-3" open>`) {
+		t.Error("expected a collapsible section per task")
+	}
+	if strings.Contains(html, "raw_page") || strings.Contains(html, "callback") || strings.Contains(html, "control") {
+		t.Error("expected unprintable metadata to be skipped")
+	}
+}
+
+func TestWriteReportInfersFormatFromPath(t *testing.T) {
+	dir := t.TempDir()
+	output := reportTestOutput()
+
+	htmlPath := filepath.Join(dir, "report.HTML")
+	if err := WriteReport(output, htmlPath, ""); err != nil {
+		t.Fatalf("WriteReport failed: %v", err)
+	}
+	if data, _ := os.ReadFile(htmlPath); !bytes.HasPrefix(data, []byte("<!DOCTYPE html>")) {
+		t.Errorf("expected an HTML report, got %q", data[:min(len(data), 40)])
+	}
+
+	mdPath := filepath.Join(dir, "report.txt")
+	if err := WriteReport(output, mdPath, ""); err != nil {
+		t.Fatalf("WriteReport failed: %v", err)
+	}
+	if data, _ := os.ReadFile(mdPath); !bytes.HasPrefix(data, []byte("# Crew 运行报告\n")) {
+		t.Errorf("expected a Markdown report, got %q", data[:min(len(data), 40)])
+	}
+
+	if err := WriteReport(output, mdPath, "pdf"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+	if err := WriteReport(nil, mdPath, ReportFormatMarkdown); err == nil {
+		t.Error("expected an error for a nil output")
+	}
+}
+
+func TestReportRecorder(t *testing.T) {
+	log := logger.NewTestLogger()
+	bus := events.NewEventBus(log)
+	recorder, err := NewReportRecorder(bus)
+	if err != nil {
+		t.Fatalf("NewReportRecorder failed: %v", err)
+	}
+
+	for _, event := range reportTestEvents() {
+		bus.Emit(context.Background(), nil, event)
+	}
+	bus.Emit(context.Background(), nil, NewTaskExecutionStartedEvent(0, "Research", "Researcher"))
+	recorder.Close()
+	bus.Emit(context.Background(), nil, NewTaskExecutionFailedEvent(4, "Late", "Publisher", "ignored", 0))
+
+	if got := len(recorder.Events()); got != 4 {
+		t.Errorf("expected 4 recorded events, got %d", got)
+	}
+	for _, eventType := range reportEventTypes {
+		if count := bus.GetHandlerCount(eventType); count != 0 {
+			t.Errorf("expected recorder to unsubscribe from %s, %d handlers left", eventType, count)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// ConversationContextKey 会话历史注入第一个任务上下文时使用的键
+const ConversationContextKey = "conversation_history"
+
+// sessionInputKeys 作为本轮用户输入记录的输入键，按顺序取第一个非空字符串
+var sessionInputKeys = []string{"message", "input", "query", "question"}
+
+type sessionIDKey struct{}
+
+// WithSession 返回携带会话ID的ctx，用该ctx执行的Kickoff会读取并追加该会话的对话
+// HTTP服务和chat命令使用同一个会话ID在多次Kickoff之间保持对话
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	if sessionID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionIDKey{}, sessionID)
+}
+
+// SessionIDFrom 返回ctx中的会话ID
+func SessionIDFrom(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(sessionIDKey{}).(string)
+	return sessionID, ok && sessionID != ""
+}
+
+// kickoffConversation 本次Kickoff所属的会话及读取到的历史
+type kickoffConversation struct {
+	sessionID string
+	history   string
+}
+
+type kickoffConversationKey struct{}
+
+// conversationFrom 返回ctx中本次Kickoff的会话，没有会话时返回nil
+func conversationFrom(ctx context.Context) *kickoffConversation {
+	conversation, _ := ctx.Value(kickoffConversationKey{}).(*kickoffConversation)
+	return conversation
+}
+
+// SetSessionMemory 设置会话记忆，副本共享同一个会话记忆
+func (c *BaseCrew) SetSessionMemory(sessionMemory *memory.SessionMemory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessionMemory = sessionMemory
+}
+
+// GetSessionMemory 返回会话记忆
+func (c *BaseCrew) GetSessionMemory() *memory.SessionMemory {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionMemory
+}
+
+// startConversation ctx携带会话ID时读取会话历史，供第一个任务使用
+// 没有配置摘要LLM时使用第一个Agent的LLM；读取失败只记录警告，本次Kickoff不带历史执行
+func (c *BaseCrew) startConversation(ctx context.Context) context.Context {
+	sessionID, ok := SessionIDFrom(ctx)
+	sessionMemory := c.GetSessionMemory()
+	if !ok || sessionMemory == nil {
+		return ctx
+	}
+
+	c.mu.RLock()
+	for _, a := range c.agents {
+		if agentLLM := a.GetLLM(); agentLLM != nil {
+			sessionMemory.SetSummaryLLM(agentLLM)
+			break
+		}
+	}
+	c.mu.RUnlock()
+
+	conversation := &kickoffConversation{sessionID: sessionID}
+	state, err := sessionMemory.Load(ctx, sessionID)
+	if err != nil {
+		c.logger.Warn("failed to load session, continuing without history",
+			logger.Field{Key: "session_id", Value: sessionID},
+			logger.Field{Key: "error", Value: err},
+		)
+	} else {
+		conversation.history = state.Render()
+	}
+	return context.WithValue(ctx, kickoffConversationKey{}, conversation)
+}
+
+// finishConversation Kickoff成功后把本轮输入和最终回答追加到会话
+func (c *BaseCrew) finishConversation(ctx context.Context, inputs map[string]interface{}, result *CrewOutput) {
+	conversation := conversationFrom(ctx)
+	sessionMemory := c.GetSessionMemory()
+	if conversation == nil || sessionMemory == nil || result == nil {
+		return
+	}
+
+	// 执行超时或被取消时仍然保存本轮
+	if _, err := sessionMemory.Append(context.WithoutCancel(ctx), conversation.sessionID, sessionInput(inputs), result.Raw); err != nil {
+		c.logger.Warn("failed to update session",
+			logger.Field{Key: "session_id", Value: conversation.sessionID},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+}
+
+// sessionInput 返回本轮记录的用户输入，没有约定的输入键时按键排序列出所有输入
+func sessionInput(inputs map[string]interface{}) string {
+	for _, key := range sessionInputKeys {
+		if value, ok := inputs[key].(string); ok && strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+
+	keys := make([]string, 0, len(inputs))
+	for key := range inputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = fmt.Sprintf("%s: %v", key, inputs[key])
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,100 @@
+package crew
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newSessionTestCrew(t *testing.T, model *llmtest.ScriptedLLM, sessions *memory.SessionMemory) *BaseCrew {
+	t.Helper()
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	assistant, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: "Assistant", Goal: "Answer questions", Backstory: "Helpful",
+		LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	c := NewBaseCrew(&CrewConfig{Name: "chat-crew", SessionMemory: sessions}, eventBus, log)
+	c.AddAgent(assistant)
+	c.AddTask(agent.NewTaskWithOptions("Answer the user's message", "A short answer",
+		agent.WithName("answer"), agent.WithAssignedAgent(assistant)))
+	c.AddTask(agent.NewTaskWithOptions("Polish the answer", "The final answer",
+		agent.WithName("polish"), agent.WithAssignedAgent(assistant)))
+	return c
+}
+
+func TestKickoffWithSessionRemembersEarlierAnswers(t *testing.T) {
+	model := llmtest.NewScriptedLLM(llmtest.Replies(
+		"Draft: nice to meet you", "Nice to meet you, Alice!",
+		"Draft: your name is Alice", "Your name is Alice.",
+		"Draft: hello", "Hello!",
+	)...)
+	c := newSessionTestCrew(t, model, nil)
+	ctx := WithSession(context.Background(), "alice")
+
+	if _, err := c.Kickoff(ctx, map[string]interface{}{"message": "My name is Alice"}); err != nil {
+		t.Fatalf("first kickoff failed: %v", err)
+	}
+	if strings.Contains(model.Prompts()[0], "Conversation So Far:") {
+		t.Errorf("a new session must not inject history:\n%s", model.Prompts()[0])
+	}
+
+	// 副本（HTTP服务的执行方式）共享会话记忆
+	clone, err := c.Clone()
+	if err != nil {
+		t.Fatalf("failed to clone crew: %v", err)
+	}
+	if _, err := clone.Kickoff(ctx, map[string]interface{}{"message": "What is my name?"}); err != nil {
+		t.Fatalf("second kickoff failed: %v", err)
+	}
+
+	prompts := model.Prompts()
+	first, second := prompts[2], prompts[3]
+	for _, expected := range []string{"Conversation So Far:", "User: My name is Alice", "Assistant: Nice to meet you, Alice!"} {
+		if !strings.Contains(first, expected) {
+			t.Errorf("expected %q in the first task's prompt:\n%s", expected, first)
+		}
+	}
+	if strings.Contains(second, "Conversation So Far:") {
+		t.Errorf("history must only be injected into the first task:\n%s", second)
+	}
+
+	state, err := c.GetSessionMemory().Load(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("failed to load session: %v", err)
+	}
+	if len(state.Turns) != 2 || state.Turns[1].Input != "What is my name?" || state.Turns[1].Output != "Your name is Alice." {
+		t.Errorf("unexpected session turns: %+v", state.Turns)
+	}
+
+	// 不带会话的Kickoff既不读取也不记录
+	if _, err := c.Kickoff(context.Background(), map[string]interface{}{"message": "Hi"}); err != nil {
+		t.Fatalf("third kickoff failed: %v", err)
+	}
+	if strings.Contains(model.Prompts()[4], "Conversation So Far:") {
+		t.Errorf("kickoff without a session must not inject history:\n%s", model.Prompts()[4])
+	}
+	ids, _ := c.GetSessionMemory().Sessions(context.Background())
+	if len(ids) != 1 {
+		t.Errorf("expected only the alice session, got %v", ids)
+	}
+}
+
+func TestSessionInput(t *testing.T) {
+	if got := sessionInput(map[string]interface{}{"query": "weather?", "city": "Paris"}); got != "weather?" {
+		t.Errorf("expected the query input, got %q", got)
+	}
+	if got := sessionInput(map[string]interface{}{"topic": "Go", "audience": "beginners"}); got != "audience: beginners\ntopic: Go" {
+		t.Errorf("expected sorted inputs, got %q", got)
+	}
+}
@@ -0,0 +1,260 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// CrewStatus Crew执行状态的快照，用于诊断卡住的执行
+// 没有执行时保留最近一次Kickoff结束时的计数
+type CrewStatus struct {
+	CrewID         string              `json:"crew_id"`
+	CrewName       string              `json:"crew_name"`
+	Executing      bool                `json:"executing"`
+	ExecutionID    int                 `json:"execution_id,omitempty"` // 第几次Kickoff，从1开始
+	StartedAt      time.Time           `json:"started_at,omitempty"`
+	Elapsed        time.Duration       `json:"elapsed,omitempty"` // Kickoff已经执行（或最近一次Kickoff执行）的时长
+	TotalTasks     int                 `json:"total_tasks"`
+	CompletedTasks int                 `json:"completed_tasks"` // 已结束的任务，包括失败和跳过的任务
+	FailedTasks    int                 `json:"failed_tasks"`
+	PendingTasks   int                 `json:"pending_tasks"`
+	RunningTasks   []RunningTaskStatus `json:"running_tasks,omitempty"` // 按任务序号排列，并行流程中可能有多个
+	LastEventAt    time.Time           `json:"last_event_at,omitempty"`
+	LastEventType  string              `json:"last_event_type,omitempty"`
+}
+
+// RunningTaskStatus 正在执行的任务
+type RunningTaskStatus struct {
+	Index       int               `json:"index"`
+	TaskID      string            `json:"task_id"`
+	Description string            `json:"description"`
+	Agent       string            `json:"agent"`
+	StartedAt   time.Time         `json:"started_at"`
+	Elapsed     time.Duration     `json:"elapsed"`
+	Step        *agent.StepStatus `json:"step,omitempty"` // Agent实现了agent.StepReporter且正在调用LLM或工具时的当前步骤
+}
+
+// CurrentStepElapsed 当前步骤已经执行的时长，Agent不报告步骤时为任务已经执行的时长
+func (t RunningTaskStatus) CurrentStepElapsed() time.Duration {
+	if t.Step != nil {
+		return t.Step.Elapsed
+	}
+	return t.Elapsed
+}
+
+// String 返回多行的可读描述，CLI收到SIGUSR1时输出
+func (s CrewStatus) String() string {
+	var b strings.Builder
+	state := "空闲"
+	if s.Executing {
+		state = "执行中"
+	}
+	fmt.Fprintf(&b, "📊 Crew %s 状态: %s", s.CrewName, state)
+	if s.ExecutionID > 0 {
+		fmt.Fprintf(&b, "（第 %d 次执行，已用时 %s）", s.ExecutionID, s.Elapsed.Round(time.Millisecond))
+	}
+	fmt.Fprintf(&b, "\n   任务: 共 %d，已完成 %d（失败 %d），等待 %d\n",
+		s.TotalTasks, s.CompletedTasks, s.FailedTasks, s.PendingTasks)
+	for _, task := range s.RunningTasks {
+		fmt.Fprintf(&b, "   ▶ 任务 %d [%s] %s: %s（已用时 %s）\n", task.Index+1, task.Agent, task.TaskID,
+			firstLine(task.Description), task.Elapsed.Round(time.Millisecond))
+		if step := task.Step; step != nil {
+			switch step.Kind {
+			case agent.StepKindLLMCall:
+				fmt.Fprintf(&b, "     LLM调用中（第 %d 次尝试，已用时 %s）\n", step.Attempt, step.Elapsed.Round(time.Millisecond))
+			case agent.StepKindToolCall:
+				fmt.Fprintf(&b, "     工具 %s 执行中（已用时 %s）\n", step.Tool, step.Elapsed.Round(time.Millisecond))
+			}
+		}
+	}
+	if !s.LastEventAt.IsZero() {
+		fmt.Fprintf(&b, "   最近事件: %s（%s前）\n", s.LastEventType, time.Since(s.LastEventAt).Round(time.Millisecond))
+	}
+	return b.String()
+}
+
+// firstLine 返回文本的第一行
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
+
+// runningTask 执行中任务的记录
+type runningTask struct {
+	index       int
+	task        agent.Task
+	executor    agent.Agent
+	description string
+	startedAt   time.Time
+}
+
+// statusTracker 一次Kickoff的执行状态，通过ctx区分本次Kickoff的事件
+type statusTracker struct {
+	mu            sync.Mutex
+	executionID   int
+	startedAt     time.Time
+	finishedAt    time.Time
+	totalTasks    int
+	completed     int
+	failed        int
+	running       map[int]*runningTask
+	lastEventAt   time.Time
+	lastEventType string
+}
+
+type statusTrackerKey struct{}
+
+func statusTrackerFrom(ctx context.Context) *statusTracker {
+	tracker, _ := ctx.Value(statusTrackerKey{}).(*statusTracker)
+	return tracker
+}
+
+// startStatus 为本次Kickoff创建状态记录并订阅事件总线以记录最近的事件，返回的函数在Kickoff结束时调用
+func (c *BaseCrew) startStatus(ctx context.Context, executionID int) (context.Context, func()) {
+	tracker := &statusTracker{
+		executionID: executionID,
+		startedAt:   time.Now(),
+		totalTasks:  len(c.GetTasks()),
+		running:     make(map[int]*runningTask),
+	}
+	c.mu.Lock()
+	c.status = tracker
+	c.mu.Unlock()
+
+	ctx = context.WithValue(ctx, statusTrackerKey{}, tracker)
+	subscription, err := c.eventBus.SubscribeWithOptions("*", tracker.handle, events.WithSyncDelivery())
+	if err != nil {
+		c.logger.Warn("failed to subscribe to crew events, last event time is not tracked",
+			logger.Field{Key: "crew_name", Value: c.name},
+			logger.Field{Key: "error", Value: err},
+		)
+		subscription = nil
+	}
+	return ctx, func() {
+		if subscription != nil {
+			subscription.Unsubscribe()
+		}
+		tracker.mu.Lock()
+		tracker.finishedAt = time.Now()
+		tracker.running = make(map[int]*runningTask)
+		tracker.mu.Unlock()
+	}
+}
+
+// handle 记录本次Kickoff（包括其中Agent、工具和LLM）最近的事件
+func (t *statusTracker) handle(ctx context.Context, event events.Event) error {
+	if statusTrackerFrom(ctx) != t {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastEventAt = event.GetTimestamp()
+	if t.lastEventAt.IsZero() {
+		t.lastEventAt = time.Now()
+	}
+	t.lastEventType = event.GetType()
+	return nil
+}
+
+// taskStarted 记录开始执行的任务，返回的函数在任务结束时调用，failed表示任务失败
+func (t *statusTracker) taskStarted(index int, task agent.Task, executor agent.Agent) func(failed bool) {
+	if t == nil {
+		return func(bool) {}
+	}
+	t.mu.Lock()
+	t.running[index] = &runningTask{
+		index:       index,
+		task:        task,
+		executor:    executor,
+		description: task.GetDescription(),
+		startedAt:   time.Now(),
+	}
+	t.mu.Unlock()
+
+	return func(failed bool) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.running, index)
+		t.completed++
+		if failed {
+			t.failed++
+		}
+	}
+}
+
+// taskSkipped 记录跳过的任务（条件不满足或重放时复用保存的输出）
+func (t *statusTracker) taskSkipped() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.completed++
+}
+
+// Status 返回Crew当前执行状态的快照，可以在执行期间从其他goroutine调用
+func (c *BaseCrew) Status() CrewStatus {
+	c.mu.RLock()
+	status := CrewStatus{
+		CrewID:     c.id,
+		CrewName:   c.name,
+		Executing:  c.executing,
+		TotalTasks: len(c.tasks),
+	}
+	tracker := c.status
+	c.mu.RUnlock()
+
+	if tracker == nil {
+		status.PendingTasks = status.TotalTasks
+		return status
+	}
+
+	now := time.Now()
+	tracker.mu.Lock()
+	status.ExecutionID = tracker.executionID
+	status.StartedAt = tracker.startedAt
+	status.TotalTasks = tracker.totalTasks
+	status.CompletedTasks = tracker.completed
+	status.FailedTasks = tracker.failed
+	status.LastEventAt = tracker.lastEventAt
+	status.LastEventType = tracker.lastEventType
+	if tracker.finishedAt.IsZero() {
+		status.Elapsed = now.Sub(tracker.startedAt)
+	} else {
+		status.Elapsed = tracker.finishedAt.Sub(tracker.startedAt)
+	}
+	running := make([]*runningTask, 0, len(tracker.running))
+	for _, task := range tracker.running {
+		running = append(running, task)
+	}
+	tracker.mu.Unlock()
+
+	sort.Slice(running, func(i, j int) bool { return running[i].index < running[j].index })
+	for _, task := range running {
+		taskStatus := RunningTaskStatus{
+			Index:       task.index,
+			TaskID:      task.task.GetID(),
+			Description: task.description,
+			Agent:       task.executor.GetRole(),
+			StartedAt:   task.startedAt,
+			Elapsed:     now.Sub(task.startedAt),
+		}
+		// Agent的锁在Crew的锁之外获取
+		if reporter, ok := task.executor.(agent.StepReporter); ok {
+			if step, ok := reporter.CurrentStep(taskStatus.TaskID); ok {
+				taskStatus.Step = &step
+			}
+		}
+		status.RunningTasks = append(status.RunningTasks, taskStatus)
+	}
+	status.PendingTasks = max(status.TotalTasks-status.CompletedTasks-len(status.RunningTasks), 0)
+	return status
+}
@@ -0,0 +1,105 @@
+package crew
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// blockingLLM 每次调用前通知started并等待release，用于在LLM调用期间检查状态
+type blockingLLM struct {
+	*llmtest.ScriptedLLM
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return b.ScriptedLLM.Call(ctx, messages, options)
+}
+
+func TestCrewStatus(t *testing.T) {
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	model := &blockingLLM{
+		ScriptedLLM: llmtest.NewScriptedLLM(llmtest.Replies("Sources", "Report")...),
+		started:     make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: "Writer", Goal: "Write", Backstory: "Experienced",
+		LLM: model, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	c := NewBaseCrew(&CrewConfig{Name: "report"}, eventBus, log)
+	c.AddAgent(writer)
+	c.AddTask(agent.NewTaskWithOptions("Collect sources\nfrom the web", "Sources", agent.WithAssignedAgent(writer)))
+	c.AddTask(agent.NewTaskWithOptions("Write the report", "A report", agent.WithAssignedAgent(writer)))
+
+	if status := c.Status(); status.Executing || status.PendingTasks != 2 || status.ExecutionID != 0 {
+		t.Errorf("expected an idle crew with 2 pending tasks, got %+v", status)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Kickoff(context.Background(), nil)
+		done <- err
+	}()
+
+	// 第一个任务的LLM调用进行中
+	<-model.started
+	status := c.Status()
+	if !status.Executing || status.ExecutionID != 1 || status.CompletedTasks != 0 || status.PendingTasks != 1 {
+		t.Errorf("unexpected counts while the first task runs: %+v", status)
+	}
+	if len(status.RunningTasks) != 1 {
+		t.Fatalf("expected 1 running task, got %+v", status.RunningTasks)
+	}
+	running := status.RunningTasks[0]
+	if running.Index != 0 || running.Agent != "Writer" || running.Description != "Collect sources\nfrom the web" {
+		t.Errorf("unexpected running task: %+v", running)
+	}
+	if running.Step == nil || running.Step.Kind != agent.StepKindLLMCall || running.Step.Attempt != 1 {
+		t.Errorf("expected the agent to report the LLM call, got %+v", running.Step)
+	}
+	if status.LastEventAt.IsZero() || status.LastEventType == "" {
+		t.Error("expected the last event to be recorded")
+	}
+	text := status.String()
+	if !strings.Contains(text, "▶ 任务 1 [Writer]") || !strings.Contains(text, "LLM调用中（第 1 次尝试") {
+		t.Errorf("unexpected status text:\n%s", text)
+	}
+	if _, err := json.Marshal(status); err != nil {
+		t.Errorf("status should be serializable: %v", err)
+	}
+	model.release <- struct{}{}
+
+	// 第二个任务
+	<-model.started
+	status = c.Status()
+	if status.CompletedTasks != 1 || status.PendingTasks != 0 || len(status.RunningTasks) != 1 || status.RunningTasks[0].Index != 1 {
+		t.Errorf("unexpected status while the second task runs: %+v", status)
+	}
+	model.release <- struct{}{}
+
+	if err := <-done; err != nil {
+		t.Fatalf("kickoff failed: %v", err)
+	}
+	status = c.Status()
+	if status.Executing || status.CompletedTasks != 2 || status.FailedTasks != 0 || len(status.RunningTasks) != 0 {
+		t.Errorf("expected the finished kickoff to be reported, got %+v", status)
+	}
+	if step, ok := writer.CurrentStep(c.GetTasks()[1].GetID()); ok {
+		t.Errorf("expected no current step after the kickoff, got %+v", step)
+	}
+}
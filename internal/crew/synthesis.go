@@ -0,0 +1,121 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// SynthesisTaskName 综合任务的名称，记录在CrewOutput.SynthesisOutput.Name中
+const SynthesisTaskName = "final_output_synthesis"
+
+// synthesizeFinalOutput 所有任务完成后让一个Agent把任务输出综合为符合FinalOutputSchema的JSON
+// 成功时写入CrewOutput的JSON、Parsed（以及结构体模式下的Pydantic），Raw仍是最后一个任务的输出；
+// 失败时只记录SynthesisError，任务输出和Success保持不变，不因格式问题丢失已完成的工作
+func (c *BaseCrew) synthesizeFinalOutput(ctx context.Context, result *CrewOutput) {
+	c.mu.RLock()
+	schema := c.finalOutputSchema
+	c.mu.RUnlock()
+	if schema == nil || result == nil {
+		return
+	}
+
+	synthesizer := c.resolveSynthesisAgent()
+	if synthesizer == nil {
+		result.SynthesisError = fmt.Errorf("final output synthesis requires a synthesis agent, a manager agent or a crew agent")
+		c.logSynthesisFailure(result.SynthesisError)
+		return
+	}
+
+	task := agent.NewTaskWithOptions(
+		synthesisDescription(result.TasksOutput),
+		fmt.Sprintf("A single JSON value matching the %s schema that combines the task outputs above", schema.Name),
+		agent.WithName(SynthesisTaskName),
+		agent.WithOutputSchema(schema),
+		agent.WithOutputFormat(agent.OutputFormatJSON),
+		agent.WithAssignedAgent(synthesizer),
+	)
+
+	output, err := synthesizer.Execute(ctx, task)
+	if output != nil {
+		output.Name = SynthesisTaskName
+		result.SynthesisOutput = output
+	}
+	switch {
+	case err != nil:
+		result.SynthesisError = fmt.Errorf("final output synthesis failed: %w", err)
+	case output == nil:
+		result.SynthesisError = fmt.Errorf("final output synthesis returned no output")
+	case !output.IsValid:
+		result.SynthesisError = fmt.Errorf("final output does not match schema %s: %s", schema.Name, output.ValidationError)
+	}
+	if result.SynthesisError != nil {
+		c.logSynthesisFailure(result.SynthesisError)
+		return
+	}
+
+	result.JSON = output.JSON
+	result.Parsed = output.Parsed
+	if output.Pydantic != nil {
+		result.Pydantic = output.Pydantic
+	}
+	result.Metadata["synthesis_agent"] = synthesizer.GetRole()
+
+	c.logger.Info("final output synthesized",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "schema", Value: schema.Name},
+		logger.Field{Key: "agent", Value: synthesizer.GetRole()},
+	)
+}
+
+// resolveSynthesisAgent 返回执行综合任务的Agent：SynthesisAgent、ManagerAgent、
+// 最后一个任务分配的Agent，都没有时使用第一个Agent
+func (c *BaseCrew) resolveSynthesisAgent() agent.Agent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.synthesisAgent != nil {
+		return c.synthesisAgent
+	}
+	if c.managerAgent != nil {
+		return c.managerAgent
+	}
+	for i := len(c.tasks) - 1; i >= 0; i-- {
+		if assigned := c.tasks[i].GetAssignedAgent(); assigned != nil {
+			return assigned
+		}
+	}
+	if len(c.agents) > 0 {
+		return c.agents[0]
+	}
+	return nil
+}
+
+// synthesisDescription 构建综合任务的描述，按顺序列出各任务的输出
+func synthesisDescription(outputs []*agent.TaskOutput) string {
+	var b strings.Builder
+	b.WriteString("Combine the outputs of the crew's tasks below into the crew's final answer. ")
+	b.WriteString("Use only information from these outputs.\n")
+	for i, output := range outputs {
+		if output == nil || agent.IsSkippedOutput(output) {
+			continue
+		}
+		title := output.Name
+		if title == "" {
+			title = output.Description
+		}
+		fmt.Fprintf(&b, "\n## Task %d: %s\n%s\n", i+1, title, output.Raw)
+	}
+	return b.String()
+}
+
+// logSynthesisFailure 记录综合失败，执行结果仍然成功
+func (c *BaseCrew) logSynthesisFailure(err error) {
+	c.logger.Warn("final output synthesis failed, returning raw task outputs",
+		logger.Field{Key: "crew_name", Value: c.name},
+		logger.Field{Key: "error", Value: err},
+	)
+}
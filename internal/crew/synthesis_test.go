@@ -0,0 +1,133 @@
+package crew
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+type articleReport struct {
+	Title    string   `json:"title"`
+	Findings []string `json:"findings"`
+}
+
+func newSynthesisTestCrew(t *testing.T, workerLLM *llmtest.ScriptedLLM) *BaseCrew {
+	t.Helper()
+
+	schema, err := agent.NewOutputSchemaFromStruct(articleReport{})
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	logger := logger.NewTestLogger()
+	config := DefaultCrewConfig()
+	config.FinalOutputSchema = schema
+	crew := NewBaseCrew(config, events.NewEventBus(logger), logger)
+
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write",
+		Backstory: "Writes",
+		LLM:       workerLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(writer)
+	crew.AddTask(agent.NewTaskWithOptions("Research goroutines", "Notes", agent.WithName("research"), agent.WithAssignedAgent(writer)))
+	crew.AddTask(agent.NewTaskWithOptions("Write the article", "An article", agent.WithName("write"), agent.WithAssignedAgent(writer)))
+	return crew
+}
+
+func TestFinalOutputSynthesis(t *testing.T) {
+	workerLLM := NewMockLLM(
+		"Goroutines are cheap",
+		"Goroutines make concurrency easy",
+		`{"title": "Goroutines", "findings": ["cheap", "easy"]}`,
+	)
+	crew := newSynthesisTestCrew(t, workerLLM)
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	if result.SynthesisError != nil {
+		t.Fatalf("unexpected synthesis error: %v", result.SynthesisError)
+	}
+
+	report, ok := result.Parsed.(*articleReport)
+	if !ok {
+		t.Fatalf("expected *articleReport in Parsed, got %T", result.Parsed)
+	}
+	if report.Title != "Goroutines" || len(report.Findings) != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if result.JSON["title"] != "Goroutines" {
+		t.Errorf("expected synthesized JSON, got %v", result.JSON)
+	}
+	if result.Raw != "Goroutines make concurrency easy" || len(result.TasksOutput) != 2 {
+		t.Errorf("expected Raw and TasksOutput to keep the task outputs, got %q and %d outputs", result.Raw, len(result.TasksOutput))
+	}
+
+	// 综合任务的提示包含所有任务的输出
+	synthesisPrompt := workerLLM.Prompts()[len(workerLLM.Prompts())-1]
+	for _, expected := range []string{"Goroutines are cheap", "Goroutines make concurrency easy", "research", "write"} {
+		if !strings.Contains(synthesisPrompt, expected) {
+			t.Errorf("expected synthesis prompt to contain %q", expected)
+		}
+	}
+
+	// 综合的开销计入使用统计
+	if result.SynthesisOutput == nil || result.TokenUsage.TotalTokens <= sumTaskTokens(result.TasksOutput) {
+		t.Errorf("expected synthesis usage in token usage, got %d", result.TokenUsage.TotalTokens)
+	}
+	if result.TokenUsage.TotalTasks != 2 {
+		t.Errorf("synthesis should not count as a task, got %d tasks", result.TokenUsage.TotalTasks)
+	}
+}
+
+func TestFinalOutputSynthesisFailureKeepsTaskOutputs(t *testing.T) {
+	// 综合及所有修正都返回非JSON内容
+	crew := newSynthesisTestCrew(t, NewMockLLM("notes", "article"))
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("synthesis failure must not fail the crew: %v", err)
+	}
+	if !result.Success {
+		t.Error("expected Success=true when only synthesis failed")
+	}
+	if result.SynthesisError == nil || !strings.Contains(result.SynthesisError.Error(), "articleReport") {
+		t.Errorf("expected a synthesis error naming the schema, got %v", result.SynthesisError)
+	}
+	if result.Parsed != nil || len(result.TasksOutput) != 2 || result.Raw != "article" {
+		t.Errorf("expected the raw task outputs, got parsed %v and %d outputs", result.Parsed, len(result.TasksOutput))
+	}
+
+	data, err := result.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if document["synthesis_error"] == nil {
+		t.Error("expected synthesis_error in the exported document")
+	}
+}
+
+func sumTaskTokens(outputs []*agent.TaskOutput) int {
+	total := 0
+	for _, output := range outputs {
+		total += output.TokensUsed
+	}
+	return total
+}
@@ -0,0 +1,211 @@
+package crew
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/agent"
+)
+
+// taskGraph 任务依赖图
+// 依赖来自任务的ContextTasks（对标Python的task.context）和DependsOn声明的任务ID
+type taskGraph struct {
+	tasks        []agent.Task
+	dependencies [][]int // dependencies[i] 为任务i依赖的任务索引，按声明顺序
+	order        []int   // 稳定的拓扑顺序：无依赖约束时保持任务的原始顺序
+}
+
+// newTaskGraph 构建任务依赖图并校验
+// 引用未知任务、依赖自身或存在环时返回错误，错误信息中包含相关任务
+func newTaskGraph(tasks []agent.Task) (*taskGraph, error) {
+	indexByID := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		indexByID[task.GetID()] = i
+	}
+
+	graph := &taskGraph{
+		tasks:        tasks,
+		dependencies: make([][]int, len(tasks)),
+	}
+
+	for i, task := range tasks {
+		seen := make(map[int]bool)
+		addDependency := func(depID string) error {
+			depIndex, ok := indexByID[depID]
+			if !ok {
+				return fmt.Errorf("task %s depends on unknown task %q", taskLabel(task), depID)
+			}
+			if depIndex == i {
+				return fmt.Errorf("task %s cannot depend on itself", taskLabel(task))
+			}
+			if !seen[depIndex] {
+				seen[depIndex] = true
+				graph.dependencies[i] = append(graph.dependencies[i], depIndex)
+			}
+			return nil
+		}
+
+		for _, contextTask := range task.GetContextTasks() {
+			if contextTask == nil {
+				continue
+			}
+			if err := addDependency(contextTask.GetID()); err != nil {
+				return nil, err
+			}
+		}
+		for _, depID := range task.GetDependsOn() {
+			if err := addDependency(depID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	order, err := graph.topologicalOrder()
+	if err != nil {
+		return nil, err
+	}
+	graph.order = order
+
+	return graph, nil
+}
+
+// dependencyOutputs 按声明顺序返回任务index的依赖输出
+// 若有依赖未完成（输出为nil），返回该依赖的索引，否则返回-1
+func (g *taskGraph) dependencyOutputs(index int, outputs []*agent.TaskOutput) ([]*agent.TaskOutput, int) {
+	deps := g.dependencies[index]
+	result := make([]*agent.TaskOutput, 0, len(deps))
+	for _, dep := range deps {
+		if outputs[dep] == nil {
+			return nil, dep
+		}
+		result = append(result, outputs[dep])
+	}
+	return result, -1
+}
+
+// hasDependencies 是否有任务声明了依赖
+func (g *taskGraph) hasDependencies() bool {
+	for _, deps := range g.dependencies {
+		if len(deps) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// levels 按依赖深度对任务分组
+// 同一层的任务互不依赖，只依赖之前各层的任务，可以并发执行；层内保持任务的原始顺序
+func (g *taskGraph) levels() [][]int {
+	depth := make([]int, len(g.tasks))
+	var levels [][]int
+	for _, i := range g.order {
+		for _, dep := range g.dependencies[i] {
+			if depth[dep]+1 > depth[i] {
+				depth[i] = depth[dep] + 1
+			}
+		}
+		for len(levels) <= depth[i] {
+			levels = append(levels, nil)
+		}
+		levels[depth[i]] = append(levels[depth[i]], i)
+	}
+
+	for _, level := range levels {
+		sort.Ints(level)
+	}
+	return levels
+}
+
+// topologicalOrder 计算拓扑顺序（Kahn算法），每次选择索引最小的就绪任务以保持稳定
+func (g *taskGraph) topologicalOrder() ([]int, error) {
+	inDegree := make([]int, len(g.tasks))
+	dependents := make([][]int, len(g.tasks))
+	for i, deps := range g.dependencies {
+		inDegree[i] = len(deps)
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+	}
+
+	order := make([]int, 0, len(g.tasks))
+	scheduled := make([]bool, len(g.tasks))
+	for len(order) < len(g.tasks) {
+		next := -1
+		for i := range g.tasks {
+			if !scheduled[i] && inDegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			return nil, g.cycleError(scheduled)
+		}
+
+		scheduled[next] = true
+		order = append(order, next)
+		for _, dependent := range dependents[next] {
+			inDegree[dependent]--
+		}
+	}
+
+	return order, nil
+}
+
+// cycleError 在未能调度的任务中找出一个环并生成错误
+func (g *taskGraph) cycleError(scheduled []bool) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.tasks))
+	var stack []int
+	var cycle []int
+
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, dep := range g.dependencies[i] {
+			if scheduled[dep] || state[dep] == visited {
+				continue
+			}
+			if state[dep] == visiting {
+				for j, idx := range stack {
+					if idx == dep {
+						cycle = append(append([]int{}, stack[j:]...), dep)
+						break
+					}
+				}
+				return true
+			}
+			if visit(dep) {
+				return true
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return false
+	}
+
+	for i := range g.tasks {
+		if !scheduled[i] && state[i] == unvisited && visit(i) {
+			break
+		}
+	}
+
+	labels := make([]string, 0, len(cycle))
+	for _, idx := range cycle {
+		labels = append(labels, taskLabel(g.tasks[idx]))
+	}
+	return fmt.Errorf("task dependency cycle detected: %s", strings.Join(labels, " -> "))
+}
+
+// taskLabel 返回用于错误信息的任务标识
+func taskLabel(task agent.Task) string {
+	if name := task.GetName(); name != "" {
+		return fmt.Sprintf("'%s' (%s)", name, task.GetID())
+	}
+	return fmt.Sprintf("'%s'", task.GetID())
+}
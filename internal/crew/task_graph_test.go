@@ -0,0 +1,261 @@
+package crew
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func TestTaskGraphTopologicalOrder(t *testing.T) {
+	// t1依赖t3，t2依赖t1：拓扑顺序应为 t3, t1, t2
+	t1 := &MockTask{id: "t1", dependsOn: []string{"t3"}}
+	t2 := &MockTask{id: "t2", dependsOn: []string{"t1"}}
+	t3 := &MockTask{id: "t3"}
+	t4 := &MockTask{id: "t4"}
+
+	graph, err := newTaskGraph([]agent.Task{t1, t2, t3, t4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []int{2, 0, 1, 3}
+	if len(graph.order) != len(expected) {
+		t.Fatalf("expected order %v, got %v", expected, graph.order)
+	}
+	for i := range expected {
+		if graph.order[i] != expected[i] {
+			t.Fatalf("expected order %v, got %v", expected, graph.order)
+		}
+	}
+}
+
+func TestTaskGraphMergesContextTasksAndDependsOn(t *testing.T) {
+	research := agent.NewTaskWithOptions("Research", "Notes", agent.WithID("research"))
+	outline := agent.NewTaskWithOptions("Outline", "Outline", agent.WithID("outline"))
+	write := agent.NewTaskWithOptions("Write", "Article", agent.WithID("write"), agent.WithDependsOn("outline", "research"))
+	write.SetContextTasks([]agent.Task{research})
+
+	graph, err := newTaskGraph([]agent.Task{research, outline, write})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deps := graph.dependencies[2]
+	if len(deps) != 2 || deps[0] != 0 || deps[1] != 1 {
+		t.Errorf("expected deduplicated dependencies [0 1], got %v", deps)
+	}
+}
+
+func TestTaskGraphValidationErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		tasks       []agent.Task
+		errContains []string
+	}{
+		{
+			name:        "unknown dependency",
+			tasks:       []agent.Task{&MockTask{id: "t1", dependsOn: []string{"missing"}}},
+			errContains: []string{"depends on unknown task", "missing"},
+		},
+		{
+			name:        "self dependency",
+			tasks:       []agent.Task{&MockTask{id: "t1", dependsOn: []string{"t1"}}},
+			errContains: []string{"cannot depend on itself"},
+		},
+		{
+			name: "cycle",
+			tasks: []agent.Task{
+				&MockTask{id: "a", dependsOn: []string{"c"}},
+				&MockTask{id: "b", dependsOn: []string{"a"}},
+				&MockTask{id: "c", dependsOn: []string{"b"}},
+				&MockTask{id: "d"},
+			},
+			errContains: []string{"cycle detected", "(a) -> 'mock-task' (c) -> 'mock-task' (b) -> 'mock-task' (a)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTaskGraph(tt.tasks)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, fragment := range tt.errContains {
+				if !strings.Contains(err.Error(), fragment) {
+					t.Errorf("expected error containing %q, got %q", fragment, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestKickoffRejectsDependencyCycleBeforeExecution(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	mockLLM := NewMockLLM("should not be called")
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Worker",
+		Goal:      "Work",
+		Backstory: "Works",
+		LLM:       mockLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(worker)
+
+	first := agent.NewTaskWithOptions("First", "Output", agent.WithID("first"), agent.WithDependsOn("second"))
+	second := agent.NewTaskWithOptions("Second", "Output", agent.WithID("second"), agent.WithDependsOn("first"))
+	crew.AddTask(first)
+	crew.AddTask(second)
+
+	_, err = crew.Kickoff(context.Background(), nil)
+	if err == nil {
+		t.Fatal("expected cycle validation error")
+	}
+	if !strings.Contains(err.Error(), "'first' -> 'second' -> 'first'") {
+		t.Errorf("error should name the tasks in the cycle, got %q", err.Error())
+	}
+	if mockLLM.CallCount() != 0 {
+		t.Errorf("expected no LLM calls, got %d", mockLLM.CallCount())
+	}
+}
+
+func TestSequentialProcessSchedulesByDependencies(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	recordingLLM := NewMockLLM("summary done")
+	summarizer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Summarizer",
+		Goal:      "Summarize",
+		Backstory: "Summarizes",
+		LLM:       recordingLLM,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	// 汇总任务排在最前，但依赖后两个任务中的"Collect data"
+	summarize := agent.NewTaskWithOptions("Summarize data", "Summary", agent.WithID("summarize"), agent.WithDependsOn("collect"))
+	summarize.SetAssignedAgent(summarizer)
+	collect := agent.NewTaskWithOptions("Collect data", "Data", agent.WithID("collect"))
+	unrelated := agent.NewTaskWithOptions("Unrelated work", "Other", agent.WithID("unrelated"))
+
+	crew.AddAgent(&MockAgent{id: "a1", role: "Collector"})
+	crew.AddAgent(&MockAgent{id: "a2", role: "Helper"})
+	crew.AddAgent(&MockAgent{id: "a3", role: "Other"})
+	crew.AddTask(summarize)
+	crew.AddTask(unrelated)
+	crew.AddTask(collect)
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	// 输出按任务原始顺序排列
+	if len(result.TasksOutput) != 3 || result.TasksOutput[0].Raw != "summary done" {
+		t.Fatalf("expected summary as first task output, got %+v", result.TasksOutput)
+	}
+
+	if len(recordingLLM.Prompts()) != 1 {
+		t.Fatalf("expected one summarizer call, got %d", len(recordingLLM.Prompts()))
+	}
+	prompt := recordingLLM.Prompts()[0]
+	if !strings.Contains(prompt, "Mock agent output for: Collect data") {
+		t.Error("prompt should contain the upstream task output")
+	}
+	if strings.Contains(prompt, "Mock agent output for: Unrelated work") {
+		t.Error("prompt should not contain outputs of unrelated tasks")
+	}
+}
+
+func TestParallelProcessSkipsTasksWithFailedDependencies(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+
+	config := DefaultCrewConfig()
+	config.Process = ProcessParallel
+	crew := NewBaseCrew(config, eventBus, logger)
+
+	crew.AddAgent(&FailingMockAgent{MockAgent: MockAgent{id: "a1", role: "Failing"}, shouldFail: true})
+	crew.AddAgent(&MockAgent{id: "a2", role: "Dependent"})
+	crew.AddAgent(&MockAgent{id: "a3", role: "Independent"})
+	crew.AddTask(&MockTask{id: "t1", description: "Upstream"})
+	crew.AddTask(&MockTask{id: "t2", description: "Downstream", dependsOn: []string{"t1"}})
+	crew.AddTask(&MockTask{id: "t3", description: "Independent"})
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "2 of 3 tasks failed") || !strings.Contains(err.Error(), "task 1 skipped") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(result.TasksOutput) != 1 || result.TasksOutput[0].Raw != "Mock agent output for: Independent" {
+		t.Errorf("expected only the independent output, got %+v", result.TasksOutput)
+	}
+}
+
+func TestSequentialProcessRunsIndependentDependenciesConcurrently(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	// t1和t2互不依赖，t3依赖两者：t1和t2应并发执行，t3在两者完成后执行
+	agents, maxActive := newSlowAgents([]time.Duration{30 * time.Millisecond, 30 * time.Millisecond, time.Millisecond}, nil)
+	for _, a := range agents {
+		crew.AddAgent(a)
+	}
+	crew.AddTask(&MockTask{id: "t1", description: "Task 1", expectedOutput: "Output"})
+	crew.AddTask(&MockTask{id: "t2", description: "Task 2", expectedOutput: "Output"})
+	crew.AddTask(&MockTask{id: "t3", description: "Task 3", expectedOutput: "Output", dependsOn: []string{"t1", "t2"}})
+
+	result, err := crew.Kickoff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+
+	if len(result.TasksOutput) != 3 {
+		t.Fatalf("expected 3 task outputs, got %d", len(result.TasksOutput))
+	}
+	for i, output := range result.TasksOutput {
+		expected := "Mock agent output for: Task " + string(rune('1'+i))
+		if output.Raw != expected {
+			t.Errorf("output %d: expected %q, got %q", i, expected, output.Raw)
+		}
+	}
+	if *maxActive != 2 {
+		t.Errorf("expected independent tasks to run concurrently, max active was %d", *maxActive)
+	}
+}
+
+func TestSequentialProcessWithoutDependenciesRunsOneTaskAtATime(t *testing.T) {
+	logger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(logger)
+	crew := NewBaseCrew(nil, eventBus, logger)
+
+	agents, maxActive := newSlowAgents([]time.Duration{10 * time.Millisecond, 10 * time.Millisecond}, nil)
+	for i, a := range agents {
+		crew.AddAgent(a)
+		crew.AddTask(&MockTask{id: "t" + string(rune('1'+i)), description: "Task " + string(rune('1'+i)), expectedOutput: "Output"})
+	}
+
+	if _, err := crew.Kickoff(context.Background(), nil); err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	if *maxActive != 1 {
+		t.Errorf("expected tasks to run one at a time, max active was %d", *maxActive)
+	}
+}
@@ -0,0 +1,85 @@
+package crew
+
+import (
+	"context"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+// startKickoffSpan 开始crew.kickoff span，ctx中已有的span成为其父span
+func (c *BaseCrew) startKickoffSpan(ctx context.Context, replay bool) (context.Context, tracing.Span) {
+	if !tracing.Enabled() {
+		return tracing.Start(ctx, tracing.SpanCrewKickoff)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return tracing.Start(ctx, tracing.SpanCrewKickoff,
+		tracing.String("crew.id", c.id),
+		tracing.String("crew.name", c.name),
+		tracing.String("crew.process", c.process.String()),
+		tracing.Int("crew.agent_count", len(c.agents)),
+		tracing.Int("crew.task_count", len(c.tasks)),
+		tracing.Bool("crew.replay", replay),
+	)
+}
+
+// endKickoffSpan 记录Kickoff结果和用量并结束span
+func endKickoffSpan(span tracing.Span, result *CrewOutput, err error) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(tracing.Bool("crew.success", err == nil))
+	if err != nil {
+		span.RecordError(err)
+	}
+	if result != nil && result.TokenUsage != nil {
+		usage := result.TokenUsage
+		span.SetAttributes(
+			tracing.Int("crew.tasks_completed", len(result.TasksOutput)),
+			tracing.Int("llm.total_tokens", usage.TotalTokens),
+			tracing.Int("llm.prompt_tokens", usage.PromptTokens),
+			tracing.Int("llm.completion_tokens", usage.CompletionTokens),
+			tracing.Float64("llm.cost", usage.TotalCost),
+			tracing.Int("llm.calls", usage.LLMCalls),
+			tracing.Int("llm.retries", usage.Retries),
+		)
+	}
+}
+
+// startTaskSpan 开始task.execute span
+func startTaskSpan(ctx context.Context, task agent.Task, index int) (context.Context, tracing.Span) {
+	if !tracing.Enabled() {
+		return tracing.Start(ctx, tracing.SpanTaskExecute)
+	}
+	return tracing.Start(ctx, tracing.SpanTaskExecute,
+		tracing.String("task.id", task.GetID()),
+		tracing.String("task.name", task.GetName()),
+		tracing.Int("task.index", index),
+		tracing.String("task.description", task.GetDescription()),
+	)
+}
+
+// endTaskSpan 记录任务结果并结束span
+func endTaskSpan(span tracing.Span, output *agent.TaskOutput, err error) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(tracing.Bool("task.success", err == nil))
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if output != nil {
+		span.SetAttributes(
+			tracing.Int("llm.total_tokens", output.TokensUsed),
+			tracing.Float64("llm.cost", output.Cost),
+			tracing.Strings("task.tools_used", output.ToolsUsed),
+		)
+	}
+}
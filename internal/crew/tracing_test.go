@@ -0,0 +1,72 @@
+package crew
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+func TestCrewKickoffSpans(t *testing.T) {
+	recorder := tracing.NewRecorder()
+	tracing.SetTracer(recorder)
+	defer tracing.SetTracer(nil)
+
+	logger := logger.NewTestLogger()
+	config := DefaultCrewConfig()
+	config.Name = "traced-crew"
+	crew := NewBaseCrew(config, events.NewEventBus(logger), logger)
+
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write",
+		Backstory: "Writes",
+		LLM:       NewMockLLM("draft", "final"),
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	crew.AddAgent(writer)
+	crew.AddTask(agent.NewTaskWithOptions("Draft", "A draft", agent.WithName("draft"), agent.WithAssignedAgent(writer)))
+	crew.AddTask(agent.NewTaskWithOptions("Polish", "An article", agent.WithName("polish"), agent.WithAssignedAgent(writer)))
+
+	// 调用方ctx中的span成为crew.kickoff的父span
+	ctx, request := tracing.Start(context.Background(), "http.request")
+	if _, err := crew.Kickoff(ctx, nil); err != nil {
+		t.Fatalf("crew execution failed: %v", err)
+	}
+	request.End()
+
+	kickoffs := recorder.SpansNamed(tracing.SpanCrewKickoff)
+	if len(kickoffs) != 1 {
+		t.Fatalf("expected one crew.kickoff span, got %d", len(kickoffs))
+	}
+	kickoff := kickoffs[0]
+	if kickoff.Parent == nil || kickoff.Parent.Name != "http.request" {
+		t.Error("expected crew.kickoff to continue the caller's trace")
+	}
+	if kickoff.Attributes["crew.name"] != "traced-crew" || kickoff.Attributes["crew.success"] != true || !kickoff.Ended {
+		t.Errorf("unexpected crew.kickoff attributes: %v", kickoff.Attributes)
+	}
+	if tokens, _ := kickoff.Attributes["llm.total_tokens"].(int); tokens <= 0 {
+		t.Errorf("expected token usage on crew.kickoff, got %v", kickoff.Attributes["llm.total_tokens"])
+	}
+
+	tasks := recorder.SpansNamed(tracing.SpanTaskExecute)
+	agents := recorder.SpansNamed(tracing.SpanAgentExecute)
+	if len(tasks) != 2 || len(agents) != 2 {
+		t.Fatalf("expected 2 task and 2 agent spans, got %d and %d", len(tasks), len(agents))
+	}
+	for i, name := range []string{"draft", "polish"} {
+		if tasks[i].Parent != kickoff || tasks[i].Attributes["task.name"] != name || tasks[i].Attributes["agent.role"] != "Writer" {
+			t.Errorf("unexpected task span %d: %v", i, tasks[i].Attributes)
+		}
+		if agents[i].Parent != tasks[i] {
+			t.Errorf("expected agent span %d to be a child of its task span", i)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package crew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// ValidationSeverity 校验问题的严重程度
+type ValidationSeverity string
+
+const (
+	SeverityError   ValidationSeverity = "error"   // 执行一定会失败或结果不可用
+	SeverityWarning ValidationSeverity = "warning" // 可以执行，但很可能不是预期的配置
+)
+
+// ValidationIssue 校验发现的一个问题
+type ValidationIssue struct {
+	Severity ValidationSeverity `json:"severity"`
+	Code     string             `json:"code"`           // 稳定的问题代码，如 unknown_agent、missing_input
+	Task     string             `json:"task,omitempty"` // 相关任务的名称（没有名称时为ID），与具体任务无关时为空
+	Message  string             `json:"message"`
+}
+
+// 校验问题代码
+const (
+	IssueNoAgents           = "no_agents"
+	IssueNoTasks            = "no_tasks"
+	IssueUnknownAgent       = "unknown_agent"
+	IssueEmptyDescription   = "empty_description"
+	IssueEmptyExpected      = "empty_expected_output"
+	IssueMissingManager     = "missing_manager"
+	IssueMissingJudge       = "missing_judge"
+	IssueAssignmentFailed   = "assignment_failed" // 任务指定的Agent角色不存在，或分配策略无法为任务选择Agent
+	IssueInvalidToolSchema  = "invalid_tool_schema"
+	IssueMissingInput       = "missing_input"
+	IssueInvalidDependency  = "invalid_dependency" // 依赖未知任务、依赖自身或存在依赖环
+	IssueMissingLLM         = "missing_llm"
+	IssueUnpricedModel      = "unpriced_model"
+	IssuePromptBuildFailure = "prompt_build_failed"
+)
+
+// TaskCostEstimate 单个任务的token和成本估算
+// 下限为一次LLM调用且回复长度与期望输出相当；上限按工具调用轮次和Agent的MaxTokens计算
+type TaskCostEstimate struct {
+	Task                string  `json:"task"`
+	Agent               string  `json:"agent"`
+	Model               string  `json:"model"`
+	PromptTokens        int     `json:"prompt_tokens"` // 单次调用的提示token数：系统提示、任务提示和工具模式
+	MinCalls            int     `json:"min_calls"`
+	MaxCalls            int     `json:"max_calls"`
+	MinCompletionTokens int     `json:"min_completion_tokens"`
+	MaxCompletionTokens int     `json:"max_completion_tokens"`
+	MinCost             float64 `json:"min_cost"`
+	MaxCost             float64 `json:"max_cost"`
+	Priced              bool    `json:"priced"` // 模型没有登记价格时为false，成本记为0
+}
+
+// ValidationReport dry run的结果：配置问题和每个任务的成本估算
+type ValidationReport struct {
+	Crew         string             `json:"crew"`
+	Process      string             `json:"process"`
+	Issues       []ValidationIssue  `json:"issues"`
+	Tasks        []TaskCostEstimate `json:"tasks"`
+	PromptTokens int                `json:"prompt_tokens"`
+	MinCost      float64            `json:"min_cost"`
+	MaxCost      float64            `json:"max_cost"`
+}
+
+// 成本估算参数
+const (
+	dryRunToolRounds          = 3   // 有工具的任务在上限中按最多3轮工具调用估算
+	dryRunMinCompletionTokens = 100 // 回复长度下限
+	dryRunMaxCompletionTokens = 4096
+)
+
+// placeholderPattern 任务描述中的输入占位符，如 {topic}
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Valid 是否没有error级别的问题
+func (r *ValidationReport) Valid() bool {
+	return len(r.Errors()) == 0
+}
+
+// Errors 返回error级别的问题
+func (r *ValidationReport) Errors() []ValidationIssue {
+	return r.filter(SeverityError)
+}
+
+// Warnings 返回warning级别的问题
+func (r *ValidationReport) Warnings() []ValidationIssue {
+	return r.filter(SeverityWarning)
+}
+
+func (r *ValidationReport) filter(severity ValidationSeverity) []ValidationIssue {
+	var issues []ValidationIssue
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Err 有error级别的问题时返回汇总所有错误的error，CI可以据此让构建失败
+func (r *ValidationReport) Err() error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(errs))
+	for _, issue := range errs {
+		lines = append(lines, "  - "+issue.String())
+	}
+	return fmt.Errorf("crew %s has %d validation error(s):\n%s", r.Crew, len(errs), strings.Join(lines, "\n"))
+}
+
+func (i ValidationIssue) String() string {
+	if i.Task == "" {
+		return fmt.Sprintf("[%s] %s", i.Code, i.Message)
+	}
+	return fmt.Sprintf("[%s] task %s: %s", i.Code, i.Task, i.Message)
+}
+
+func (r *ValidationReport) addIssue(severity ValidationSeverity, code, task, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Severity: severity,
+		Code:     code,
+		Task:     task,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// PrintTable 输出问题列表和每个任务的成本估算表
+func (r *ValidationReport) PrintTable(w io.Writer) {
+	fmt.Fprintf(w, "Crew %s (%s)\n", r.Crew, r.Process)
+
+	if len(r.Issues) == 0 {
+		fmt.Fprintln(w, "\nNo issues found")
+	} else {
+		fmt.Fprintf(w, "\n%d error(s), %d warning(s)\n", len(r.Errors()), len(r.Warnings()))
+		for _, issue := range r.Issues {
+			fmt.Fprintf(w, "  %-7s %s\n", issue.Severity, issue)
+		}
+	}
+
+	if len(r.Tasks) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tAGENT\tMODEL\tPROMPT TOKENS\tCALLS\tEST. COST (USD)")
+	for _, task := range r.Tasks {
+		cost := formatCostRange(task.MinCost, task.MaxCost)
+		if !task.Priced {
+			cost = "unpriced"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d-%d\t%s\n",
+			task.Task, task.Agent, task.Model, task.PromptTokens, task.MinCalls, task.MaxCalls, cost)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t\t%d\t\t%s\n", r.PromptTokens, formatCostRange(r.MinCost, r.MaxCost))
+	tw.Flush()
+}
+
+// String 返回PrintTable的输出
+func (r *ValidationReport) String() string {
+	var b strings.Builder
+	r.PrintTable(&b)
+	return b.String()
+}
+
+func formatCostRange(minCost, maxCost float64) string {
+	return fmt.Sprintf("$%.4f - $%.4f", minCost, maxCost)
+}
+
+// Validate 在不调用LLM的情况下检查配置并估算成本（dry run）
+// 检查引用不属于crew的Agent、空的任务描述、缺少管理者的层级模式、无法构建的工具模式、
+// inputs中没有对应值的占位符和依赖环；成本按启发式token估算和模型价格计算，不发出任何网络请求
+func (c *BaseCrew) Validate(ctx context.Context, inputs map[string]interface{}) (*ValidationReport, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	report := &ValidationReport{
+		Crew:    c.name,
+		Process: c.process.String(),
+	}
+
+	if len(c.agents) == 0 {
+		report.addIssue(SeverityError, IssueNoAgents, "", "crew must have at least one agent")
+	}
+	if len(c.tasks) == 0 {
+		report.addIssue(SeverityError, IssueNoTasks, "", "crew must have at least one task")
+	}
+	if c.process == ProcessHierarchical && c.managerAgent == nil && c.managerLLM == nil {
+		report.addIssue(SeverityError, IssueMissingManager, "", "hierarchical process requires either a manager agent or a manager LLM")
+	}
+	if c.process == ProcessConsensus && c.judgeLLM == nil && c.managerAgent == nil && c.managerLLM == nil {
+		report.addIssue(SeverityError, IssueMissingJudge, "", "consensus process requires a judge LLM, a manager agent or a manager LLM")
+	}
+	if _, err := newTaskGraph(c.tasks); err != nil {
+		report.addIssue(SeverityError, IssueInvalidDependency, "", "%v", err)
+	}
+
+	members := make(map[agent.Agent]bool, len(c.agents))
+	for _, member := range c.agents {
+		members[member] = true
+	}
+
+	checkedTools := make(map[agent.Tool]bool)
+	checkTools := func(taskName string, tools []agent.Tool) {
+		for _, tool := range tools {
+			if checkedTools[tool] {
+				continue
+			}
+			checkedTools[tool] = true
+			if err := agent.ValidateToolSchema(tool); err != nil {
+				report.addIssue(SeverityError, IssueInvalidToolSchema, taskName, "%v", err)
+			}
+		}
+	}
+
+	unpriced := make(map[string]bool)
+	for i, task := range c.tasks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		name := task.GetName()
+		if name == "" {
+			name = task.GetID()
+		}
+
+		if strings.TrimSpace(task.GetDescription()) == "" {
+			report.addIssue(SeverityError, IssueEmptyDescription, name, "description is empty")
+		}
+		if strings.TrimSpace(task.GetExpectedOutput()) == "" {
+			report.addIssue(SeverityWarning, IssueEmptyExpected, name, "expected output is empty")
+		}
+		for _, key := range missingInputs(task, inputs) {
+			report.addIssue(SeverityError, IssueMissingInput, name, "placeholder {%s} has no matching input", key)
+		}
+
+		assigned := task.GetAssignedAgent()
+		if assigned != nil && !members[assigned] {
+			report.addIssue(SeverityError, IssueUnknownAgent, name, "assigned agent %q is not a member of the crew", assigned.GetRole())
+		}
+		if assigned == nil && c.process != ProcessHierarchical && len(c.agents) > 0 {
+			if _, _, err := c.selectAgentForTask(ctx, task, i, true); err != nil {
+				report.addIssue(SeverityError, IssueAssignmentFailed, name, "%v", err)
+			}
+		}
+
+		executor := c.dryRunAgent(ctx, task, i)
+		if executor == nil {
+			checkTools(name, task.GetTools())
+			continue
+		}
+		checkTools(name, task.GetTools())
+		checkTools(name, executor.GetTools())
+
+		model := c.dryRunLLM(executor)
+		if model == nil {
+			report.addIssue(SeverityError, IssueMissingLLM, name, "agent %q has no LLM", executor.GetRole())
+		}
+		estimate, err := estimateTaskCost(task, executor, model)
+		if err != nil {
+			report.addIssue(SeverityError, IssuePromptBuildFailure, name, "%v", err)
+			continue
+		}
+		if model != nil && !estimate.Priced && !unpriced[estimate.Model] {
+			unpriced[estimate.Model] = true
+			report.addIssue(SeverityWarning, IssueUnpricedModel, "", "no pricing registered for model %s, its cost is counted as 0", estimate.Model)
+		}
+		estimate.Task = name
+		report.Tasks = append(report.Tasks, estimate)
+		report.PromptTokens += estimate.PromptTokens
+		report.MinCost += estimate.MinCost
+		report.MaxCost += estimate.MaxCost
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Severity == SeverityError && report.Issues[j].Severity != SeverityError
+	})
+	return report, nil
+}
+
+// dryRunAgent 返回执行任务的Agent：层级模式下为管理者（尚未创建时退回任务的Agent），否则与执行时的选择一致
+func (c *BaseCrew) dryRunAgent(ctx context.Context, task agent.Task, index int) agent.Agent {
+	if c.process == ProcessHierarchical {
+		if c.managerAgent != nil {
+			return c.managerAgent
+		}
+		if assigned := task.GetAssignedAgent(); assigned != nil {
+			return assigned
+		}
+		if len(c.agents) > 0 {
+			return c.agents[0]
+		}
+		return nil
+	}
+	executor, _, err := c.selectAgentForTask(ctx, task, index, true)
+	if err != nil {
+		return nil
+	}
+	return executor
+}
+
+// dryRunLLM 返回执行任务的LLM；层级模式下尚未创建管理者时使用ManagerLLM
+func (c *BaseCrew) dryRunLLM(executor agent.Agent) llm.LLM {
+	if c.process == ProcessHierarchical && c.managerAgent == nil {
+		if managerLLM, ok := c.managerLLM.(llm.LLM); ok {
+			return managerLLM
+		}
+	}
+	return executor.GetLLM()
+}
+
+// missingInputs 返回任务描述和期望输出中在inputs里没有值的占位符，按出现顺序去重
+func missingInputs(task agent.Task, inputs map[string]interface{}) []string {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, text := range []string{task.GetDescription(), task.GetExpectedOutput()} {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			key := match[1]
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, ok := inputs[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+	}
+	return missing
+}
+
+// requestPreviewer 可以在不调用LLM的情况下构建请求的Agent，如agent.BaseAgent
+type requestPreviewer interface {
+	PreviewRequest(task agent.Task) ([]llm.Message, *llm.CallOptions, error)
+}
+
+// estimateTaskCost 用模型的tokenizer估算任务提示的token数，并按模型价格计算成本范围
+func estimateTaskCost(task agent.Task, executor agent.Agent, model llm.LLM) (TaskCostEstimate, error) {
+	estimate := TaskCostEstimate{Agent: executor.GetRole()}
+	var tokenizer llm.Tokenizer = llm.HeuristicTokenizer{}
+	if model != nil {
+		tokenizer = model.GetTokenizer()
+	}
+
+	var messages []llm.Message
+	var options *llm.CallOptions
+	if previewer, ok := executor.(requestPreviewer); ok {
+		var err error
+		if messages, options, err = previewer.PreviewRequest(task); err != nil {
+			return estimate, err
+		}
+	} else {
+		system := fmt.Sprintf("You are %s. %s\n%s", executor.GetRole(), executor.GetBackstory(), executor.GetGoal())
+		messages = []llm.Message{
+			{Role: llm.RoleSystem, Content: system},
+			{Role: llm.RoleUser, Content: task.GetDescription() + "\n\n" + task.GetExpectedOutput()},
+		}
+	}
+
+	estimate.PromptTokens = llm.EstimateTokens(tokenizer, messages)
+	if options != nil && len(options.Tools) > 0 {
+		if schemas, err := json.Marshal(options.Tools); err == nil {
+			estimate.PromptTokens += tokenizer.CountTokens(string(schemas))
+		}
+	}
+
+	estimate.MinCalls, estimate.MaxCalls = 1, 1
+	if len(task.GetTools()) > 0 || len(executor.GetTools()) > 0 {
+		estimate.MaxCalls += dryRunToolRounds
+	}
+	estimate.MinCompletionTokens = max(tokenizer.CountTokens(task.GetExpectedOutput()), dryRunMinCompletionTokens)
+	estimate.MaxCompletionTokens = dryRunMaxCompletionTokens
+	if maxTokens := executor.GetExecutionConfig().MaxTokens; maxTokens > 0 {
+		estimate.MaxCompletionTokens = maxTokens
+	}
+	estimate.MaxCompletionTokens = max(estimate.MaxCompletionTokens, estimate.MinCompletionTokens)
+
+	if model == nil {
+		return estimate, nil
+	}
+	estimate.Model = model.GetModel()
+	pricing, ok := llm.LookupModelPricing(estimate.Model)
+	if !ok {
+		return estimate, nil
+	}
+	estimate.Priced = true
+	estimate.MinCost = pricing.Cost(llm.Usage{
+		PromptTokens:     estimate.PromptTokens * estimate.MinCalls,
+		CompletionTokens: estimate.MinCompletionTokens * estimate.MinCalls,
+	})
+	estimate.MaxCost = pricing.Cost(llm.Usage{
+		PromptTokens:     estimate.PromptTokens * estimate.MaxCalls,
+		CompletionTokens: estimate.MaxCompletionTokens * estimate.MaxCalls,
+	})
+	return estimate, nil
+}
@@ -0,0 +1,127 @@
+package crew
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newValidationTestCrew(t *testing.T, model *llmtest.ScriptedLLM) (*BaseCrew, agent.Agent) {
+	t.Helper()
+	log := logger.NewTestLogger()
+	eventBus := events.NewEventBus(log)
+	researcher, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role: "Researcher", Goal: "Find facts", Backstory: "Careful analyst",
+		LLM: model, Tools: []agent.Tool{agent.NewCalculatorTool()}, EventBus: eventBus, Logger: log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	c := NewBaseCrew(&CrewConfig{Name: "research-crew"}, eventBus, log)
+	c.AddAgent(researcher)
+	return c, researcher
+}
+
+func issueCodes(report *ValidationReport) map[string]bool {
+	codes := make(map[string]bool)
+	for _, issue := range report.Issues {
+		codes[issue.Code] = true
+	}
+	return codes
+}
+
+func TestValidateEstimatesCostWithoutCallingLLM(t *testing.T) {
+	model := llmtest.NewScriptedLLM().WithModel("gpt-4o-mini")
+	c, researcher := newValidationTestCrew(t, model)
+	c.AddTask(agent.NewTaskWithOptions("Research {topic} in depth", "A list of ten facts",
+		agent.WithName("research"), agent.WithAssignedAgent(researcher)))
+
+	report, err := c.Validate(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Valid() || report.Err() != nil {
+		t.Fatalf("expected a valid crew, got issues: %v", report.Issues)
+	}
+	if model.CallCount() != 0 {
+		t.Errorf("dry run must not call the LLM, got %d calls", model.CallCount())
+	}
+
+	if len(report.Tasks) != 1 {
+		t.Fatalf("expected one task estimate, got %+v", report.Tasks)
+	}
+	estimate := report.Tasks[0]
+	if estimate.Task != "research" || estimate.Agent != "Researcher" || estimate.Model != "gpt-4o-mini" || !estimate.Priced {
+		t.Errorf("unexpected estimate: %+v", estimate)
+	}
+	// 有工具的任务按最多3轮工具调用估算上限
+	if estimate.PromptTokens == 0 || estimate.MaxCalls != 4 || !(0 < estimate.MinCost && estimate.MinCost < estimate.MaxCost) {
+		t.Errorf("unexpected estimate: %+v", estimate)
+	}
+	if report.MinCost != estimate.MinCost || report.MaxCost != estimate.MaxCost {
+		t.Errorf("expected totals to match the only task, got %+v", report)
+	}
+
+	table := report.String()
+	for _, expected := range []string{"No issues found", "research", "gpt-4o-mini", "TOTAL"} {
+		if !strings.Contains(table, expected) {
+			t.Errorf("expected %q in table:\n%s", expected, table)
+		}
+	}
+}
+
+func TestValidateReportsConfigurationProblems(t *testing.T) {
+	model := llmtest.NewScriptedLLM().WithModel("self-hosted-model")
+	c, researcher := newValidationTestCrew(t, model)
+
+	outsider, _ := agent.NewBaseAgent(agent.AgentConfig{Role: "Outsider", Goal: "g", Backstory: "b", LLM: model})
+	broken := agent.NewBaseToolWithSchema("lookup", "Look things up",
+		agent.ToolSchema{Name: "lookup", Required: []string{"query"}}, nil)
+
+	research := agent.NewTaskWithOptions("Research {topic} for {audience}", "Notes",
+		agent.WithName("research"), agent.WithAssignedAgent(researcher), agent.WithDependsOn("review"))
+	research.SetTools([]agent.Tool{broken})
+	review := agent.NewTaskWithOptions("  ", "", agent.WithName("review"), agent.WithID("review"),
+		agent.WithAssignedAgent(outsider), agent.WithDependsOn(research.GetID()))
+	c.AddTask(research)
+	c.AddTask(review)
+	c.SetProcess(ProcessHierarchical)
+
+	report, err := c.Validate(context.Background(), map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	codes := issueCodes(report)
+	for _, code := range []string{
+		IssueMissingManager, IssueInvalidDependency, IssueEmptyDescription, IssueEmptyExpected,
+		IssueUnknownAgent, IssueInvalidToolSchema, IssueMissingInput, IssueUnpricedModel,
+	} {
+		if !codes[code] {
+			t.Errorf("expected issue %s, got %v", code, report.Issues)
+		}
+	}
+	for _, issue := range report.Issues {
+		if issue.Code == IssueMissingInput && !strings.Contains(issue.Message, "{audience}") {
+			t.Errorf("expected only {audience} to be missing, got %q", issue.Message)
+		}
+	}
+	if report.Valid() {
+		t.Error("expected the report to be invalid")
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "validation error(s)") {
+		t.Errorf("expected a summary error, got %v", err)
+	}
+	if report.Issues[len(report.Issues)-1].Severity != SeverityWarning {
+		t.Errorf("expected errors to be listed before warnings: %v", report.Issues)
+	}
+	if model.CallCount() != 0 {
+		t.Errorf("dry run must not call the LLM, got %d calls", model.CallCount())
+	}
+}
@@ -0,0 +1,221 @@
+package crew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/queue"
+)
+
+// CrewFactory 按kickoff请求中的Crew名称返回要执行的Crew
+// 每个请求调用一次，返回的Crew只用于这一次kickoff，通常返回已配置Crew的Clone
+type CrewFactory func(ctx context.Context, name string) (Crew, error)
+
+// WorkerConfig 队列worker配置
+type WorkerConfig struct {
+	Concurrency     int               // 同时执行的kickoff数
+	JobTimeout      time.Duration     // 单次kickoff的执行超时，0表示不限；应小于队列的VisibilityTimeout，否则请求会被重复投递
+	ShutdownTimeout time.Duration     // 停止后等待执行中的kickoff完成的时间，超时后取消它们，0表示一直等待
+	ErrorBackoff    time.Duration     // Dequeue失败后等待多久重试
+	Results         queue.ResultStore // 保存kickoff的结果，为nil时使用实现了ResultStore的队列
+}
+
+// DefaultWorkerConfig 返回默认配置
+func DefaultWorkerConfig() *WorkerConfig {
+	return &WorkerConfig{
+		Concurrency:     1,
+		JobTimeout:      30 * time.Minute,
+		ShutdownTimeout: time.Minute,
+		ErrorBackoff:    time.Second,
+	}
+}
+
+// Worker 从队列取出kickoff请求并执行Crew，结果以CrewOutput.ToJSON的形式写入结果存储
+// 执行成功并保存结果后才确认请求，失败（包括panic和超时）时Nack，由队列决定重试或转入死信，
+// 同一个请求可能被执行不止一次
+type Worker struct {
+	queue   queue.Queue
+	factory CrewFactory
+	config  WorkerConfig
+	results queue.ResultStore
+	logger  logger.Logger
+}
+
+// NewWorker 创建worker，config为nil时使用DefaultWorkerConfig
+func NewWorker(q queue.Queue, factory CrewFactory, config *WorkerConfig, log logger.Logger) (*Worker, error) {
+	if q == nil {
+		return nil, fmt.Errorf("queue cannot be nil")
+	}
+	if factory == nil {
+		return nil, fmt.Errorf("crew factory cannot be nil")
+	}
+	if config == nil {
+		config = DefaultWorkerConfig()
+	}
+	if config.Concurrency < 0 {
+		return nil, fmt.Errorf("worker concurrency cannot be negative")
+	}
+	if log == nil {
+		log = logger.NewConsoleLogger()
+	}
+
+	w := &Worker{queue: q, factory: factory, config: *config, results: config.Results, logger: log}
+	if w.config.Concurrency == 0 {
+		w.config.Concurrency = 1
+	}
+	if w.results == nil {
+		store, ok := q.(queue.ResultStore)
+		if !ok {
+			return nil, fmt.Errorf("a result store is required when the queue does not store results")
+		}
+		w.results = store
+	}
+	return w, nil
+}
+
+// Run 执行队列中的kickoff请求，直到ctx结束或队列关闭
+// ctx结束后不再取出新的请求，等待执行中的kickoff完成后返回；
+// 超过ShutdownTimeout时取消仍在执行的kickoff，它们会被Nack并重新投递
+func (w *Worker) Run(ctx context.Context) error {
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, jobCtx)
+		}()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+
+	w.logger.Info("crew worker started", logger.Field{Key: "concurrency", Value: w.config.Concurrency})
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+	}
+
+	w.logger.Info("crew worker stopping, draining in-flight kickoffs")
+	if w.config.ShutdownTimeout <= 0 {
+		<-stopped
+		return nil
+	}
+
+	timer := time.NewTimer(w.config.ShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+		return nil
+	case <-timer.C:
+		w.logger.Warn("shutdown timeout reached, cancelling in-flight kickoffs")
+		cancelJobs()
+		<-stopped
+		return fmt.Errorf("worker shutdown timed out after %s, in-flight kickoffs were cancelled", w.config.ShutdownTimeout)
+	}
+}
+
+// loop 依次取出并执行请求，ctx结束或队列关闭时返回
+func (w *Worker) loop(ctx, jobCtx context.Context) {
+	for {
+		delivery, err := w.queue.Dequeue(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, queue.ErrQueueClosed) {
+				return
+			}
+			w.logger.Error("failed to dequeue kickoff request", logger.Field{Key: "error", Value: err})
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.config.ErrorBackoff):
+			}
+			continue
+		}
+		w.process(jobCtx, delivery)
+	}
+}
+
+// process 执行一次投递：记录执行状态，执行Crew，保存结果后Ack，失败时Nack
+func (w *Worker) process(ctx context.Context, delivery *queue.Delivery) {
+	request := delivery.Request
+	fields := []logger.Field{
+		{Key: "kickoff_id", Value: request.ID},
+		{Key: "crew_name", Value: request.CrewName},
+		{Key: "attempt", Value: delivery.Attempts},
+	}
+	// 确认和保存结果不受关闭时取消执行的影响
+	settleCtx := context.WithoutCancel(ctx)
+
+	running := &queue.Result{ID: request.ID, Status: queue.ResultRunning, Attempts: delivery.Attempts, EnqueuedAt: request.EnqueuedAt}
+	if err := w.results.SaveResult(settleCtx, running); err != nil {
+		w.logger.Warn("failed to save kickoff status", append(fields, logger.Field{Key: "error", Value: err})...)
+	}
+
+	w.logger.Info("kickoff dequeued", fields...)
+	start := time.Now()
+	output, err := w.execute(ctx, request)
+	var data []byte
+	if err == nil {
+		if data, err = output.ToJSON(); err != nil {
+			err = fmt.Errorf("failed to encode crew output: %w", err)
+		}
+	}
+	fields = append(fields, logger.Field{Key: "duration", Value: time.Since(start)})
+
+	// 结果先于Ack保存，保存失败时请求重新投递
+	result := &queue.Result{ID: request.ID, Status: queue.ResultCompleted, Output: data, Attempts: delivery.Attempts, EnqueuedAt: request.EnqueuedAt}
+	if err != nil {
+		result.Status, result.Output, result.Error = queue.ResultFailed, nil, err.Error()
+	}
+	if saveErr := w.results.SaveResult(settleCtx, result); saveErr != nil {
+		if err == nil {
+			err = fmt.Errorf("failed to save kickoff result: %w", saveErr)
+		} else {
+			w.logger.Warn("failed to save kickoff failure", append(fields, logger.Field{Key: "error", Value: saveErr})...)
+		}
+	}
+
+	if err != nil {
+		w.logger.Error("kickoff failed", append(fields, logger.Field{Key: "error", Value: err})...)
+		if nackErr := w.queue.Nack(settleCtx, delivery, err); nackErr != nil {
+			w.logger.Error("failed to nack kickoff request", append(fields, logger.Field{Key: "error", Value: nackErr})...)
+		}
+		return
+	}
+
+	if err := w.queue.Ack(settleCtx, delivery); err != nil {
+		w.logger.Error("failed to ack kickoff request", append(fields, logger.Field{Key: "error", Value: err})...)
+		return
+	}
+	w.logger.Info("kickoff completed", fields...)
+}
+
+// execute 创建Crew并执行kickoff，Crew的panic转为错误
+func (w *Worker) execute(ctx context.Context, request queue.KickoffRequest) (output *CrewOutput, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			output, err = nil, fmt.Errorf("crew %s panicked: %v", request.CrewName, r)
+		}
+	}()
+
+	if w.config.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.config.JobTimeout)
+		defer cancel()
+	}
+
+	c, err := w.factory(ctx, request.CrewName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create crew %s: %w", request.CrewName, err)
+	}
+	return c.Kickoff(WithSession(ctx, request.SessionID), request.Inputs)
+}
@@ -0,0 +1,169 @@
+package crew
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/queue"
+)
+
+func newWorkerTestQueue(t *testing.T, maxRetries int) *queue.SQLiteQueue {
+	t.Helper()
+	q, err := queue.NewSQLiteQueue(filepath.Join(t.TempDir(), "queue.db"), &queue.Options{
+		MaxRetries:   maxRetries,
+		PollInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+// startWorker 在后台运行worker，返回停止函数，停止函数返回Run的结果
+func startWorker(t *testing.T, q queue.Queue, factory CrewFactory) (stop func() error) {
+	t.Helper()
+	worker, err := NewWorker(q, factory, &WorkerConfig{Concurrency: 1, JobTimeout: 5 * time.Second, ShutdownTimeout: 5 * time.Second}, logger.NewTestLogger())
+	if err != nil {
+		t.Fatalf("failed to create worker: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx) }()
+	return func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("worker did not stop")
+			return nil
+		}
+	}
+}
+
+// waitForResult 等待请求的结果进入指定状态
+func waitForResult(t *testing.T, store queue.ResultStore, id string, status queue.ResultStatus) *queue.Result {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if result, ok, err := store.GetResult(context.Background(), id); err == nil && ok && result.Status == status {
+			return result
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("kickoff %s did not reach status %s", id, status)
+	return nil
+}
+
+func TestWorkerExecutesQueuedKickoffs(t *testing.T) {
+	q := newWorkerTestQueue(t, 3)
+	model := llmtest.NewScriptedLLM(llmtest.Reply{Content: "EV sales grew 30%"})
+	research := newCrewToolTestCrew(t, "research", "Researcher", model)
+
+	var requested []string
+	stop := startWorker(t, q, func(ctx context.Context, name string) (Crew, error) {
+		requested = append(requested, name)
+		return research.Clone()
+	})
+
+	id, err := q.Enqueue(context.Background(), queue.KickoffRequest{CrewName: "research", Inputs: map[string]interface{}{"topic": "EV market"}})
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	result := waitForResult(t, q, id, queue.ResultCompleted)
+	if err := stop(); err != nil {
+		t.Errorf("expected a graceful stop, got %v", err)
+	}
+	model.Verify(t)
+
+	if !strings.Contains(string(result.Output), `"raw":"EV sales grew 30%"`) || result.Attempts != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if !strings.Contains(model.Prompts()[0], "EV market") {
+		t.Errorf("expected the queued inputs in the prompt:\n%s", model.Prompts()[0])
+	}
+	if len(requested) != 1 || requested[0] != "research" {
+		t.Errorf("expected the factory to be asked for the research crew, got %v", requested)
+	}
+	if letters, _ := q.DeadLetters(context.Background()); len(letters) != 0 {
+		t.Errorf("expected no dead letters, got %+v", letters)
+	}
+}
+
+func TestWorkerRecoversPanicsAndDeadLetters(t *testing.T) {
+	q := newWorkerTestQueue(t, 1)
+	stop := startWorker(t, q, func(ctx context.Context, name string) (Crew, error) {
+		panic("factory exploded")
+	})
+	defer stop()
+
+	id, err := q.Enqueue(context.Background(), queue.KickoffRequest{CrewName: "broken"})
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var letters []*queue.DeadLetter
+	for time.Now().Before(deadline) && len(letters) == 0 {
+		letters, _ = q.DeadLetters(context.Background())
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(letters) != 1 || letters[0].Attempts != 2 || !strings.Contains(letters[0].LastError, "panicked: factory exploded") {
+		t.Fatalf("expected the request to be dead-lettered after one retry, got %+v", letters)
+	}
+
+	result := waitForResult(t, q, id, queue.ResultFailed)
+	if result.Attempts != 2 || !strings.Contains(result.Error, "factory exploded") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestWorkerFinishesInFlightKickoffOnShutdown(t *testing.T) {
+	q := newWorkerTestQueue(t, 3)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	model := llmtest.NewScriptedLLM(llmtest.Reply{Content: "slow answer", Expect: func(call llmtest.Call) error {
+		close(started)
+		<-release
+		return nil
+	}})
+	research := newCrewToolTestCrew(t, "research", "Researcher", model)
+	stop := startWorker(t, q, func(ctx context.Context, name string) (Crew, error) {
+		return research.Clone()
+	})
+
+	id, _ := q.Enqueue(context.Background(), queue.KickoffRequest{CrewName: "research"})
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- stop() }()
+	time.Sleep(50 * time.Millisecond)
+
+	// 停止后不再取出新的请求
+	waiting, _ := q.Enqueue(context.Background(), queue.KickoffRequest{CrewName: "research"})
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-stopped:
+		t.Fatalf("worker stopped before the in-flight kickoff finished: %v", err)
+	default:
+	}
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Errorf("expected a graceful stop, got %v", err)
+	}
+	waitForResult(t, q, id, queue.ResultCompleted)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	delivery, err := q.Dequeue(ctx)
+	if err != nil || delivery.Request.ID != waiting {
+		t.Errorf("expected the request enqueued after shutdown to stay queued, got %+v %v", delivery, err)
+	}
+}
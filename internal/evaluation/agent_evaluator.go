@@ -0,0 +1,387 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// AgentEvaluatorImpl AgentEvaluator接口的实现，对应Python版本的AgentEvaluator类
+// 对每次Agent执行并发运行所有指标评估器，结果按Agent角色和迭代次数保存
+type AgentEvaluatorImpl struct {
+	evaluators []BaseEvaluator                             // 指标评估器
+	config     *EvaluationConfig                           // 评估配置
+	eventBus   events.EventBus                             // 事件总线
+	logger     logger.Logger                               // 日志器
+	results    map[int]map[string][]*AgentEvaluationResult // 评估结果，按迭代和Agent角色分组
+	ordered    []*AgentEvaluationResult                    // 按评估顺序保存的全部结果
+	iteration  int                                         // 当前评估迭代次数
+	mu         sync.RWMutex                                // 并发安全锁
+}
+
+// NewAgentEvaluator 创建新的AgentEvaluator实例
+func NewAgentEvaluator(
+	evaluators []BaseEvaluator,
+	config *EvaluationConfig,
+	eventBus events.EventBus,
+	logger logger.Logger,
+) *AgentEvaluatorImpl {
+	// 如果配置为空，使用默认配置
+	if config == nil {
+		config = DefaultEvaluationConfig()
+	}
+
+	return &AgentEvaluatorImpl{
+		evaluators: append([]BaseEvaluator{}, evaluators...),
+		config:     config,
+		eventBus:   eventBus,
+		logger:     logger,
+		results:    make(map[int]map[string][]*AgentEvaluationResult),
+		iteration:  1,
+	}
+}
+
+// AddEvaluator 添加评估器，同一指标类别只能有一个评估器
+func (ae *AgentEvaluatorImpl) AddEvaluator(evaluator BaseEvaluator) error {
+	if evaluator == nil {
+		return NewEvaluationConfigError("evaluator", "", "evaluator cannot be nil")
+	}
+
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	category := evaluator.GetMetricCategory()
+	for _, existing := range ae.evaluators {
+		if existing.GetMetricCategory() == category {
+			return NewEvaluatorCreationError("metric_evaluator", string(category), "evaluator already registered", ErrEvaluatorAlreadyExists)
+		}
+	}
+	ae.evaluators = append(ae.evaluators, evaluator)
+	return nil
+}
+
+// RemoveEvaluator 移除评估器
+func (ae *AgentEvaluatorImpl) RemoveEvaluator(category MetricCategory) error {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	for i, existing := range ae.evaluators {
+		if existing.GetMetricCategory() == category {
+			ae.evaluators = append(ae.evaluators[:i], ae.evaluators[i+1:]...)
+			return nil
+		}
+	}
+	return NewMetricCategoryError(category, ErrEvaluatorNotFound.Error())
+}
+
+// GetEvaluators 获取所有评估器
+func (ae *AgentEvaluatorImpl) GetEvaluators() []BaseEvaluator {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+	return append([]BaseEvaluator{}, ae.evaluators...)
+}
+
+// metricOutcome 单个指标的评估结果
+type metricOutcome struct {
+	category MetricCategory
+	score    *EvaluationScore
+	err      error
+	duration time.Duration
+}
+
+// Evaluate 评估agent，所有指标并发执行并共享同一个超时时间
+// 单个指标失败只记录在结果的Errors中；所有指标都失败时返回错误
+func (ae *AgentEvaluatorImpl) Evaluate(ctx context.Context, evalAgent agent.Agent, executionTrace map[string]interface{}, finalOutput interface{}, task Task) (*AgentEvaluationResult, error) {
+	startTime := time.Now()
+
+	if evalAgent == nil {
+		return nil, NewAgentEvaluationError("", "", "", "validation", "agent cannot be nil", ErrAgentNotFound)
+	}
+	if task == nil {
+		return nil, NewAgentEvaluationError(evalAgent.GetID(), evalAgent.GetRole(), "", "validation", "task cannot be nil", ErrTaskNotFound)
+	}
+
+	ae.mu.RLock()
+	evaluators := append([]BaseEvaluator{}, ae.evaluators...)
+	iteration := ae.iteration
+	timeoutSeconds := ae.config.TimeoutSeconds
+	ae.mu.RUnlock()
+
+	agentID, agentRole, taskID := evalAgent.GetID(), evalAgent.GetRole(), task.GetID()
+	iterationID := fmt.Sprintf("iteration_%d", iteration)
+
+	if len(evaluators) == 0 {
+		return nil, NewAgentEvaluationError(agentID, agentRole, taskID, "validation", "no metric evaluators configured", ErrEvaluatorNotFound)
+	}
+
+	ae.emit(ctx, NewAgentEvaluationStartedEvent(ae, agentID, agentRole, taskID, iteration, iterationID))
+	ae.logger.Debug("Starting agent evaluation",
+		logger.Field{Key: "agent_role", Value: agentRole},
+		logger.Field{Key: "task_id", Value: taskID},
+		logger.Field{Key: "metrics", Value: len(evaluators)},
+		logger.Field{Key: "iteration", Value: iteration},
+	)
+
+	// 所有指标共享同一个超时时间
+	metricCtx := ctx
+	if timeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		metricCtx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	outcomes := make([]metricOutcome, len(evaluators))
+	var wg sync.WaitGroup
+	for i, evaluator := range evaluators {
+		wg.Add(1)
+		go func(i int, evaluator BaseEvaluator) {
+			defer wg.Done()
+			metricStart := time.Now()
+			score, err := evaluator.Evaluate(metricCtx, evalAgent, executionTrace, finalOutput, task)
+			if err == nil && score == nil {
+				err = fmt.Errorf("%w: evaluator returned no score", ErrLLMResponseInvalid)
+			}
+			outcomes[i] = metricOutcome{
+				category: evaluator.GetMetricCategory(),
+				score:    score,
+				err:      err,
+				duration: time.Since(metricStart),
+			}
+		}(i, evaluator)
+	}
+	wg.Wait()
+
+	result := &AgentEvaluationResult{
+		AgentID:   agentID,
+		AgentRole: agentRole,
+		TaskID:    taskID,
+		Iteration: iteration,
+		Metrics:   make(map[string]*EvaluationScore),
+		Timestamp: time.Now(),
+	}
+
+	for _, outcome := range outcomes {
+		durationMs := float64(outcome.duration.Milliseconds())
+		switch {
+		case errors.Is(outcome.err, ErrMetricNotApplicable):
+			ae.logger.Debug("Metric not applicable",
+				logger.Field{Key: "agent_role", Value: agentRole},
+				logger.Field{Key: "metric", Value: outcome.category},
+			)
+		case outcome.err != nil:
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			result.Errors[string(outcome.category)] = outcome.err.Error()
+			ae.logger.Warn("Metric evaluation failed",
+				logger.Field{Key: "agent_role", Value: agentRole},
+				logger.Field{Key: "metric", Value: outcome.category},
+				logger.Field{Key: "error", Value: outcome.err.Error()},
+			)
+			ae.emit(ctx, NewAgentEvaluationFailedEvent(ae, agentID, agentRole, taskID, iteration, iterationID,
+				fmt.Sprintf("%s: %v", outcome.category, outcome.err), durationMs))
+		default:
+			result.Metrics[string(outcome.category)] = outcome.score
+			ae.emit(ctx, NewAgentEvaluationCompletedEvent(ae, agentID, agentRole, taskID, iteration, iterationID,
+				outcome.category, outcome.score, durationMs))
+		}
+	}
+
+	executionTime := float64(time.Since(startTime).Milliseconds())
+
+	if len(result.Metrics) == 0 && len(result.Errors) > 0 {
+		err := NewAgentEvaluationError(agentID, agentRole, taskID, "metric_evaluation", "all metric evaluations failed", ErrAgentEvaluationFailed)
+		ae.emit(ctx, NewAgentEvaluationFailedEvent(ae, agentID, agentRole, taskID, iteration, iterationID, err.Error(), executionTime))
+		return nil, err
+	}
+
+	ae.mu.Lock()
+	if ae.results[iteration] == nil {
+		ae.results[iteration] = make(map[string][]*AgentEvaluationResult)
+	}
+	ae.results[iteration][agentRole] = append(ae.results[iteration][agentRole], result)
+	ae.ordered = append(ae.ordered, result)
+	ae.mu.Unlock()
+
+	ae.emit(ctx, NewAgentEvaluatedEvent(ae, agentID, agentRole, taskID, result, executionTime, iterationID))
+	ae.logger.Info("Agent evaluation completed",
+		logger.Field{Key: "agent_role", Value: agentRole},
+		logger.Field{Key: "task_id", Value: taskID},
+		logger.Field{Key: "average_score", Value: result.GetAverageScore()},
+		logger.Field{Key: "failed_metrics", Value: len(result.Errors)},
+		logger.Field{Key: "execution_time_ms", Value: executionTime},
+	)
+
+	return result, nil
+}
+
+// EvaluateAsync 异步评估agent，评估失败时通道中不会有结果
+func (ae *AgentEvaluatorImpl) EvaluateAsync(ctx context.Context, evalAgent agent.Agent, executionTrace map[string]interface{}, finalOutput interface{}, task Task) (<-chan *AgentEvaluationResult, error) {
+	resultChan := make(chan *AgentEvaluationResult, 1)
+	go func() {
+		defer close(resultChan)
+		result, err := ae.Evaluate(ctx, evalAgent, executionTrace, finalOutput, task)
+		if err != nil {
+			ae.logger.Error("Async agent evaluation failed", logger.Field{Key: "error", Value: err.Error()})
+			return
+		}
+		resultChan <- result
+	}()
+	return resultChan, nil
+}
+
+// DisplayEvaluationWithFeedback 显示评估结果和反馈
+func (ae *AgentEvaluatorImpl) DisplayEvaluationWithFeedback(ctx context.Context) error {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+
+	if len(ae.ordered) == 0 {
+		ae.logger.Info("No agent evaluation results to display")
+		return nil
+	}
+
+	ae.logger.Info("=== Agent Evaluation Results ===")
+	for _, result := range ae.ordered {
+		ae.logger.Info("Agent Evaluation",
+			logger.Field{Key: "agent_role", Value: result.AgentRole},
+			logger.Field{Key: "task_id", Value: result.TaskID},
+			logger.Field{Key: "iteration", Value: result.Iteration},
+			logger.Field{Key: "average_score", Value: fmt.Sprintf("%.2f", result.GetAverageScore())},
+		)
+		for _, category := range sortedMetricNames(result.Metrics) {
+			score := result.Metrics[category]
+			ae.logger.Info("Metric",
+				logger.Field{Key: "metric", Value: category},
+				logger.Field{Key: "score", Value: score.Score},
+				logger.Field{Key: "feedback", Value: score.Feedback},
+			)
+		}
+		for category, errMsg := range result.Errors {
+			ae.logger.Warn("Metric failed",
+				logger.Field{Key: "metric", Value: category},
+				logger.Field{Key: "error", Value: errMsg},
+			)
+		}
+	}
+	return nil
+}
+
+// GetIterationsResults 获取迭代结果，按评估顺序返回
+func (ae *AgentEvaluatorImpl) GetIterationsResults() []*AgentEvaluationResult {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+	return append([]*AgentEvaluationResult{}, ae.ordered...)
+}
+
+// GetAgentResult 获取指定Agent角色在某次迭代中的汇总结果
+// 同一迭代中执行了多个任务时，各指标取所有任务的平均分，反馈按任务顺序合并
+func (ae *AgentEvaluatorImpl) GetAgentResult(agentRole string, iteration int) *AgentEvaluationResult {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+
+	results := ae.results[iteration][agentRole]
+	if len(results) == 0 {
+		return nil
+	}
+	if len(results) == 1 {
+		return results[0]
+	}
+
+	aggregated := &AgentEvaluationResult{
+		AgentID:   results[0].AgentID,
+		AgentRole: agentRole,
+		Iteration: iteration,
+		Metrics:   make(map[string]*EvaluationScore),
+		Timestamp: results[len(results)-1].Timestamp,
+	}
+
+	feedback := make(map[string][]string)
+	counts := make(map[string]int)
+	for _, result := range results {
+		for category, score := range result.Metrics {
+			if aggregated.Metrics[category] == nil {
+				aggregated.Metrics[category] = &EvaluationScore{Category: score.Category, Criteria: score.Criteria}
+			}
+			aggregated.Metrics[category].Score += score.Score
+			counts[category]++
+			feedback[category] = append(feedback[category], score.Feedback)
+		}
+		for category, errMsg := range result.Errors {
+			if aggregated.Errors == nil {
+				aggregated.Errors = make(map[string]string)
+			}
+			aggregated.Errors[category] = errMsg
+		}
+	}
+	for category, score := range aggregated.Metrics {
+		score.Score /= float64(counts[category])
+		score.Feedback = strings.Join(feedback[category], "\n")
+	}
+	return aggregated
+}
+
+// SetIteration 设置评估迭代次数
+func (ae *AgentEvaluatorImpl) SetIteration(iteration int) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	ae.iteration = iteration
+}
+
+// GetIteration 获取当前评估迭代次数
+func (ae *AgentEvaluatorImpl) GetIteration() int {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+	return ae.iteration
+}
+
+// Reset 重置评估状态
+func (ae *AgentEvaluatorImpl) Reset() error {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	ae.results = make(map[int]map[string][]*AgentEvaluationResult)
+	ae.ordered = nil
+	ae.iteration = 1
+
+	ae.logger.Info("Agent evaluator state reset")
+	return nil
+}
+
+// SetConfig 设置配置
+func (ae *AgentEvaluatorImpl) SetConfig(config *EvaluationConfig) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	ae.config = config
+}
+
+// GetConfig 获取配置
+func (ae *AgentEvaluatorImpl) GetConfig() *EvaluationConfig {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+	return ae.config
+}
+
+// emit 发射事件
+func (ae *AgentEvaluatorImpl) emit(ctx context.Context, event events.Event) {
+	if ae.eventBus != nil {
+		ae.eventBus.Emit(ctx, ae, event)
+	}
+}
+
+// sortedMetricNames 按名称排序的指标列表
+func sortedMetricNames(metrics map[string]*EvaluationScore) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,182 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// stubMetricEvaluator 返回固定评分或错误，block为true时等待上下文取消
+type stubMetricEvaluator struct {
+	category MetricCategory
+	score    float64
+	err      error
+	block    bool
+}
+
+func (s *stubMetricEvaluator) GetMetricCategory() MetricCategory { return s.category }
+func (s *stubMetricEvaluator) SetLLM(llm llm.LLM) error          { return nil }
+func (s *stubMetricEvaluator) GetLLM() llm.LLM                   { return nil }
+
+func (s *stubMetricEvaluator) Evaluate(ctx context.Context, evalAgent agent.Agent, executionTrace map[string]interface{}, finalOutput interface{}, task Task) (*EvaluationScore, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &EvaluationScore{Score: s.score, Feedback: string(s.category) + " feedback", Category: string(s.category)}, nil
+}
+
+func newTestEvalAgent(t *testing.T, evalLLM llm.LLM, tools ...agent.Tool) agent.Agent {
+	t.Helper()
+	a, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Researcher",
+		Goal:      "Find accurate facts",
+		Backstory: "Careful analyst",
+		LLM:       evalLLM,
+		Tools:     tools,
+		Logger:    logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+	return a
+}
+
+func TestAgentEvaluatorPartialResult(t *testing.T) {
+	log := logger.NewTestLogger()
+	bus := events.NewEventBus(log)
+
+	var mu sync.Mutex
+	var eventTypes []string
+	_, err := bus.SubscribeWithOptions("evaluation.agent.*", func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		eventTypes = append(eventTypes, event.GetType())
+		return nil
+	}, events.WithSyncDelivery())
+	require.NoError(t, err)
+
+	evaluator := NewAgentEvaluator([]BaseEvaluator{
+		&stubMetricEvaluator{category: MetricCategoryGoalAlignment, score: 8},
+		&stubMetricEvaluator{category: MetricCategorySemanticQuality, err: errors.New("judge unavailable")},
+		&stubMetricEvaluator{category: MetricCategoryToolUsage, err: ErrMetricNotApplicable},
+	}, nil, bus, log)
+
+	evalAgent := newTestEvalAgent(t, &evaluatorTestLLM{})
+	task := &testEvalTask{id: "task-1", description: "Research Go", expected: "Notes"}
+
+	result, err := evaluator.Evaluate(context.Background(), evalAgent, nil, "Go is compiled", task)
+	require.NoError(t, err)
+	assert.True(t, result.IsPartial())
+	assert.Equal(t, "Researcher", result.AgentRole)
+	assert.Equal(t, 1, result.Iteration)
+	assert.Equal(t, 8.0, result.Metrics[string(MetricCategoryGoalAlignment)].Score)
+	assert.NotContains(t, result.Metrics, string(MetricCategoryToolUsage))
+	assert.Contains(t, result.Errors[string(MetricCategorySemanticQuality)], "judge unavailable")
+
+	assert.Same(t, result, evaluator.GetAgentResult("Researcher", 1))
+	assert.Nil(t, evaluator.GetAgentResult("Researcher", 2))
+	assert.Len(t, evaluator.GetIterationsResults(), 1)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		EventTypeAgentEvaluationStarted,
+		EventTypeAgentEvaluationCompleted, // goal_alignment
+		EventTypeAgentEvaluationFailed,    // semantic_quality
+		EventTypeAgentEvaluationCompleted, // 汇总结果
+	}, eventTypes)
+}
+
+func TestAgentEvaluatorSharedTimeout(t *testing.T) {
+	config := DefaultEvaluationConfig()
+	config.TimeoutSeconds = 1
+	evaluator := NewAgentEvaluator([]BaseEvaluator{
+		&stubMetricEvaluator{category: MetricCategoryGoalAlignment, score: 7},
+		&stubMetricEvaluator{category: MetricCategorySemanticQuality, block: true},
+	}, config, nil, logger.NewTestLogger())
+
+	result, err := evaluator.Evaluate(context.Background(), newTestEvalAgent(t, &evaluatorTestLLM{}), nil, "output",
+		&testEvalTask{id: "task-1"})
+	require.NoError(t, err)
+	assert.Len(t, result.Metrics, 1)
+	assert.Contains(t, result.Errors[string(MetricCategorySemanticQuality)], "deadline exceeded")
+}
+
+func TestAgentEvaluatorAllMetricsFail(t *testing.T) {
+	evaluator := NewAgentEvaluator([]BaseEvaluator{
+		&stubMetricEvaluator{category: MetricCategoryGoalAlignment, err: errors.New("boom")},
+	}, nil, nil, logger.NewTestLogger())
+
+	_, err := evaluator.Evaluate(context.Background(), newTestEvalAgent(t, &evaluatorTestLLM{}), nil, "output", &testEvalTask{id: "task-1"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrAgentEvaluationFailed)
+	assert.Empty(t, evaluator.GetIterationsResults())
+}
+
+func TestAgentEvaluatorAggregatesByRoleAndIteration(t *testing.T) {
+	goal := &stubMetricEvaluator{category: MetricCategoryGoalAlignment, score: 6}
+	evaluator := NewAgentEvaluator([]BaseEvaluator{goal}, nil, nil, logger.NewTestLogger())
+	evalAgent := newTestEvalAgent(t, &evaluatorTestLLM{})
+
+	evaluator.SetIteration(2)
+	_, err := evaluator.Evaluate(context.Background(), evalAgent, nil, "first", &testEvalTask{id: "task-1"})
+	require.NoError(t, err)
+	goal.score = 9
+	_, err = evaluator.Evaluate(context.Background(), evalAgent, nil, "second", &testEvalTask{id: "task-2"})
+	require.NoError(t, err)
+
+	aggregated := evaluator.GetAgentResult("Researcher", 2)
+	require.NotNil(t, aggregated)
+	assert.Equal(t, 7.5, aggregated.Metrics[string(MetricCategoryGoalAlignment)].Score)
+	assert.Equal(t, "goal_alignment feedback\ngoal_alignment feedback", aggregated.Metrics[string(MetricCategoryGoalAlignment)].Feedback)
+
+	assert.Error(t, evaluator.AddEvaluator(&stubMetricEvaluator{category: MetricCategoryGoalAlignment}))
+	require.NoError(t, evaluator.RemoveEvaluator(MetricCategoryGoalAlignment))
+	assert.Empty(t, evaluator.GetEvaluators())
+}
+
+func TestMetricEvaluators(t *testing.T) {
+	evalLLM := &evaluatorTestLLM{reply: "```json\n{\"score\": 7, \"feedback\": \"Clear and on topic\"}\n```"}
+	evaluators, err := NewMetricEvaluators(evalLLM, MetricCategoryGoalAlignment, MetricCategorySemanticQuality, MetricCategoryToolUsage)
+	require.NoError(t, err)
+	require.Len(t, evaluators, 3)
+
+	evalAgent := newTestEvalAgent(t, evalLLM)
+	task := &testEvalTask{id: "task-1", description: "Research Go", expected: "Notes"}
+
+	score, err := evaluators[0].Evaluate(context.Background(), evalAgent, nil, &agent.TaskOutput{Raw: "Go is compiled"}, task)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, score.Score)
+	assert.Equal(t, "Clear and on topic", score.Feedback)
+	assert.Equal(t, string(MetricCategoryGoalAlignment), score.Category)
+	assert.Contains(t, evalLLM.messages[1].Content, "Find accurate facts")
+	assert.Contains(t, evalLLM.messages[1].Content, "Go is compiled")
+
+	// 没有工具的Agent不适用工具使用指标
+	_, err = evaluators[2].Evaluate(context.Background(), evalAgent, nil, "output", task)
+	assert.ErrorIs(t, err, ErrMetricNotApplicable)
+
+	toolAgent := newTestEvalAgent(t, evalLLM, agent.NewBaseTool("search", "Search the web", nil))
+	_, err = evaluators[2].Evaluate(context.Background(), toolAgent, map[string]interface{}{"tools_used": []string{"search"}}, "output", task)
+	require.NoError(t, err)
+	assert.Contains(t, evalLLM.messages[1].Content, "- search: Search the web")
+	assert.Contains(t, evalLLM.messages[1].Content, "Tools Called (in order): search")
+
+	evalLLM.reply = `{"score": 12, "feedback": "too generous"}`
+	_, err = evaluators[1].Evaluate(context.Background(), evalAgent, nil, "output", task)
+	assert.Error(t, err)
+
+	_, err = NewMetricEvaluators(evalLLM, MetricCategoryCreativity)
+	assert.Error(t, err)
+}
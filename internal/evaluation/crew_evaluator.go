@@ -414,57 +414,69 @@ func (ce *CrewEvaluatorImpl) findTaskByOutput(taskOutput *TaskOutput) (Task, err
 
 // createEvaluatorAgent 创建评估代理，对应Python版本的_evaluator_agent()
 func (ce *CrewEvaluatorImpl) createEvaluatorAgent(ctx context.Context) (agent.Agent, error) {
-	// 这里需要依赖agent.Agent的实现，暂时返回一个简化的Mock Agent
-	// 在实际实现中，需要创建一个真正的Agent实例
-
-	/* 对应Python版本的评估代理配置 - 待Agent系统实现后启用
-	agentConfig := map[string]interface{}{
-		"role": "Task Execution Evaluator",
-		"goal": "Your goal is to evaluate the performance of the agents in the crew based on the tasks they have performed using score from 1 to 10 evaluating on completion, quality, and overall performance.",
-		"backstory": "Evaluator agent for crew evaluation with precise capabilities to evaluate the performance of the agents in the crew based on the tasks they have performed",
-		"llm": ce.llm,
-		"verbose": ce.config.EnableVerbose,
-		"max_iter": 10,
-		"temperature": 0.1, // 保持评估一致性
-	}
-	*/
-
-	// TODO: 这里需要调用真正的Agent工厂方法创建Agent
-	// 目前返回一个错误，提示需要实现Agent系统
-	return nil, fmt.Errorf("agent system not fully implemented - cannot create evaluator agent")
+	if ce.llm == nil {
+		return nil, NewEvaluationConfigError("llm", "", "evaluator llm cannot be nil")
+	}
+
+	executionConfig := agent.DefaultExecutionConfig()
+	executionConfig.MaxIterations = 10
+	executionConfig.Temperature = 0.1 // 保持评估一致性
+	executionConfig.Verbose = ce.config.EnableVerbose
+
+	return agent.NewBaseAgent(agent.AgentConfig{
+		Role:            "Task Execution Evaluator",
+		Goal:            "Your goal is to evaluate the performance of the agents in the crew based on the tasks they have performed using score from 1 to 10 evaluating on completion, quality, and overall performance.",
+		Backstory:       "Evaluator agent for crew evaluation with precise capabilities to evaluate the performance of the agents in the crew based on the tasks they have performed",
+		LLM:             ce.llm,
+		ExecutionConfig: executionConfig,
+		EventBus:        ce.eventBus,
+		Logger:          ce.logger,
+	})
 }
 
 // createEvaluationTask 创建评估任务，对应Python版本的_evaluation_task()
 func (ce *CrewEvaluatorImpl) createEvaluationTask(ctx context.Context, evaluatorAgent agent.Agent, taskToEvaluate Task, taskOutput string) (Task, error) {
-	/* 构建评估任务描述，与Python版本保持一致 - 待Task系统实现后启用
-		description := fmt.Sprintf(`
-	Analyze the task execution and provide an evaluation focusing on completion, quality, and overall performance.
-
-	Task Details:
-	- Description: %s
-	- Expected Output: %s
-	- Agent: %s
-	- Actual Output: %s
-
-	Please provide an evaluation with a score from 1 to 10, where:
-	- 1-3: Poor performance, significant issues
-	- 4-6: Average performance, some issues
-	- 7-8: Good performance, minor issues
-	- 9-10: Excellent performance, meets or exceeds expectations
-
-	Return the result in JSON format: {"quality": <score>}`,
-			taskToEvaluate.GetDescription(),
-			taskToEvaluate.GetExpectedOutput(),
-			taskToEvaluate.GetAgent().GetRole(),
-			taskOutput,
-		)
+	if evaluatorAgent == nil {
+		return nil, NewEvaluationConfigError("evaluator_agent", "", "evaluator agent cannot be nil")
+	}
+
+	agentRole := "unknown"
+	if taskAgent := taskToEvaluate.GetAgent(); taskAgent != nil {
+		agentRole = taskAgent.GetRole()
+	}
+
+	// 构建评估任务描述，与Python版本保持一致
+	description := fmt.Sprintf(`Analyze the task execution and provide an evaluation focusing on completion, quality, and overall performance.
+
+Task Details:
+- Description: %s
+- Expected Output: %s
+- Agent: %s
+- Actual Output: %s
 
-		expectedOutput := `JSON object with quality score: {"quality": <numeric_score>}`
-	*/
+Please provide an evaluation with a score from 1 to 10, where:
+- 1-3: Poor performance, significant issues
+- 4-6: Average performance, some issues
+- 7-8: Good performance, minor issues
+- 9-10: Excellent performance, meets or exceeds expectations
 
-	// TODO: 这里需要调用真正的Task工厂方法创建Task
-	// 目前返回一个错误，提示需要实现Task系统
-	return nil, fmt.Errorf("task system not fully implemented - cannot create evaluation task")
+Return the result in JSON format: {"quality": <score>}`,
+		taskToEvaluate.GetDescription(),
+		taskToEvaluate.GetExpectedOutput(),
+		agentRole,
+		taskOutput,
+	)
+
+	expectedOutput := `JSON object with quality score: {"quality": <numeric_score>}`
+
+	task := agent.NewBaseTask(description, expectedOutput)
+	task.SetName("evaluation_task")
+	task.SetOutputFormat(agent.OutputFormatJSON)
+	if err := task.SetAssignedAgent(evaluatorAgent); err != nil {
+		return nil, err
+	}
+
+	return newEvaluationTask(task, evaluatorAgent), nil
 }
 
 // parseEvaluationResult 解析评估结果
@@ -492,9 +504,9 @@ func (ce *CrewEvaluatorImpl) parseEvaluationResult(taskOutput *TaskOutput) (*Tas
 		}
 	}
 
-	// 从Raw文本解析JSON
+	// 从Raw文本解析JSON，LLM可能在JSON前后附加说明或代码块标记
 	if taskOutput.Raw != "" {
-		err := result.FromJSON(taskOutput.Raw)
+		err := result.FromJSON(extractJSONObject(taskOutput.Raw))
 		if err != nil {
 			return nil, NewTaskOutputError("", "result_parsing",
 				fmt.Sprintf("failed to parse quality score from raw output: %s", taskOutput.Raw), err)
@@ -568,6 +580,11 @@ func (ce *CrewEvaluatorImpl) copyExecutionTimes() map[int][]float64 {
 
 // getGradeFromScore 根据分数获取等级
 func (ce *CrewEvaluatorImpl) getGradeFromScore(score float64) string {
+	return GradeFromScore(score)
+}
+
+// GradeFromScore 根据1-10分的评分获取等级
+func GradeFromScore(score float64) string {
 	switch {
 	case score >= 9.0:
 		return "A+"
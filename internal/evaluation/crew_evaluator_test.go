@@ -0,0 +1,114 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// evaluatorTestLLM 返回固定回复并记录最后一次收到的消息
+type evaluatorTestLLM struct {
+	reply    string
+	err      error
+	messages []llm.Message
+}
+
+func (l *evaluatorTestLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	l.messages = messages
+	if l.err != nil {
+		return nil, l.err
+	}
+	return &llm.Response{Content: l.reply, Model: "judge"}, nil
+}
+
+func (l *evaluatorTestLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	return nil, errors.New("streaming not supported")
+}
+
+func (l *evaluatorTestLLM) GetModel() string                     { return "judge" }
+func (l *evaluatorTestLLM) SupportsFunctionCalling() bool        { return false }
+func (l *evaluatorTestLLM) GetContextWindowSize() int            { return 8192 }
+func (l *evaluatorTestLLM) GetTokenizer() llm.Tokenizer          { return llm.HeuristicTokenizer{} }
+func (l *evaluatorTestLLM) SetEventBus(eventBus events.EventBus) {}
+func (l *evaluatorTestLLM) Close() error                         { return nil }
+
+type testEvalTask struct {
+	id, description, expected string
+}
+
+func (t *testEvalTask) GetID() string                       { return t.id }
+func (t *testEvalTask) GetDescription() string              { return t.description }
+func (t *testEvalTask) GetExpectedOutput() string           { return t.expected }
+func (t *testEvalTask) GetAgent() agent.Agent               { return nil }
+func (t *testEvalTask) GetExecutionDuration() time.Duration { return 2 * time.Second }
+func (t *testEvalTask) Execute(ctx context.Context) (*TaskOutput, error) {
+	return nil, errors.New("not used")
+}
+func (t *testEvalTask) ExecuteSync(ctx context.Context) (*TaskOutput, error) {
+	return nil, errors.New("not used")
+}
+
+type testEvalCrew struct {
+	tasks []Task
+}
+
+func (c *testEvalCrew) GetName() string          { return "test-crew" }
+func (c *testEvalCrew) GetTasks() []Task         { return c.tasks }
+func (c *testEvalCrew) GetAgents() []agent.Agent { return nil }
+func (c *testEvalCrew) Execute(ctx context.Context, inputs map[string]interface{}) (*CrewOutput, error) {
+	return nil, errors.New("not used")
+}
+func (c *testEvalCrew) SetTaskCallback(callback func(*TaskOutput)) error { return nil }
+
+func newTestCrewEvaluator(evalLLM llm.LLM) *CrewEvaluatorImpl {
+	log := logger.NewTestLogger()
+	crew := &testEvalCrew{tasks: []Task{&testEvalTask{id: "task-1", description: "Research Go", expected: "Notes"}}}
+	return NewCrewEvaluator(crew, evalLLM, nil, events.NewEventBus(log), log)
+}
+
+func TestCrewEvaluatorEvaluate(t *testing.T) {
+	evalLLM := &evaluatorTestLLM{reply: "Here is my evaluation:\n```json\n{\"quality\": 8.5}\n```"}
+	evaluator := newTestCrewEvaluator(evalLLM)
+	evaluator.SetIteration(1)
+
+	result, err := evaluator.Evaluate(context.Background(), &TaskOutput{TaskID: "task-1", Raw: "Go is a compiled language"})
+	require.NoError(t, err)
+	assert.Equal(t, 8.5, result.Quality)
+	assert.Equal(t, map[int][]float64{1: {8.5}}, evaluator.GetTasksScores())
+	assert.Equal(t, map[int][]float64{1: {2000}}, evaluator.GetExecutionTimes())
+
+	// 评估提示包含被评估任务的描述、期望输出和实际输出
+	var prompt strings.Builder
+	for _, message := range evalLLM.messages {
+		prompt.WriteString(message.Content.(string))
+	}
+	for _, want := range []string{"Research Go", "Notes", "Go is a compiled language", `{"quality": <score>}`} {
+		assert.Contains(t, prompt.String(), want)
+	}
+}
+
+func TestCrewEvaluatorEvaluateLLMFailure(t *testing.T) {
+	evaluator := newTestCrewEvaluator(&evaluatorTestLLM{err: errors.New("evaluator unavailable")})
+
+	_, err := evaluator.Evaluate(context.Background(), &TaskOutput{TaskID: "task-1", Raw: "output"})
+	require.Error(t, err)
+	var execErr *EvaluationExecutionError
+	assert.ErrorAs(t, err, &execErr)
+	assert.Empty(t, evaluator.GetTasksScores())
+}
+
+func TestGradeFromScore(t *testing.T) {
+	assert.Equal(t, "A+", GradeFromScore(9.2))
+	assert.Equal(t, "B", GradeFromScore(7.67))
+	assert.Equal(t, "F", GradeFromScore(3))
+}
@@ -250,6 +250,7 @@ var (
 	ErrEvaluatorNotFound      = fmt.Errorf("evaluator not found")
 	ErrEvaluatorAlreadyExists = fmt.Errorf("evaluator already exists")
 	ErrInvalidEvaluatorType   = fmt.Errorf("invalid evaluator type")
+	ErrMetricNotApplicable    = fmt.Errorf("metric not applicable")
 
 	// 任务相关错误
 	ErrTaskNotFound         = fmt.Errorf("task not found")
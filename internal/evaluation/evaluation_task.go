@@ -0,0 +1,81 @@
+package evaluation
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+)
+
+// evaluationTask 评估任务，把agent.BaseTask适配为评估用的Task接口，由评估代理同步执行
+type evaluationTask struct {
+	task     *agent.BaseTask
+	agent    agent.Agent
+	duration time.Duration
+	mu       sync.RWMutex
+}
+
+func newEvaluationTask(task *agent.BaseTask, evaluatorAgent agent.Agent) *evaluationTask {
+	return &evaluationTask{task: task, agent: evaluatorAgent}
+}
+
+func (t *evaluationTask) GetID() string             { return t.task.GetID() }
+func (t *evaluationTask) GetDescription() string    { return t.task.GetDescription() }
+func (t *evaluationTask) GetExpectedOutput() string { return t.task.GetExpectedOutput() }
+func (t *evaluationTask) GetAgent() agent.Agent     { return t.agent }
+
+// GetExecutionDuration 获取最近一次执行耗时
+func (t *evaluationTask) GetExecutionDuration() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.duration
+}
+
+// Execute 执行评估任务，评估任务总是同步执行
+func (t *evaluationTask) Execute(ctx context.Context) (*TaskOutput, error) {
+	return t.ExecuteSync(ctx)
+}
+
+// ExecuteSync 由评估代理执行任务，并转换为评估用的TaskOutput
+func (t *evaluationTask) ExecuteSync(ctx context.Context) (*TaskOutput, error) {
+	startTime := time.Now()
+	output, err := t.agent.Execute(ctx, t.task)
+
+	t.mu.Lock()
+	t.duration = time.Since(startTime)
+	t.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return FromAgentTaskOutput(output), nil
+}
+
+// FromAgentTaskOutput 把agent.TaskOutput转换为评估用的TaskOutput
+func FromAgentTaskOutput(output *agent.TaskOutput) *TaskOutput {
+	if output == nil {
+		return nil
+	}
+	return &TaskOutput{
+		TaskID:      output.Task,
+		Description: output.Description,
+		Raw:         output.Raw,
+		Agent:       output.Agent,
+		Summary:     output.Summary,
+		JSONDict:    output.JSON,
+		Pydantic:    output.Pydantic,
+		Metadata:    output.Metadata,
+	}
+}
+
+// extractJSONObject 提取文本中第一个'{'到最后一个'}'之间的内容，找不到时原样返回
+func extractJSONObject(raw string) string {
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end < start {
+		return raw
+	}
+	return raw[start : end+1]
+}
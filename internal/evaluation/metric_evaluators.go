@@ -0,0 +1,236 @@
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// metricEvaluator 基于LLM打分的指标评估器，对应Python版本experimental.evaluation.metrics中的各个评估器
+// 不同指标只在评估标准和提示内容上不同
+type metricEvaluator struct {
+	category MetricCategory
+	criteria string
+	prompt   func(evalAgent agent.Agent, executionTrace map[string]interface{}, finalOutput interface{}, task Task) (string, error)
+	llm      llm.LLM
+	mu       sync.RWMutex
+}
+
+// NewGoalAlignmentEvaluator 创建目标对齐度评估器：输出是否达成任务要求和Agent目标
+func NewGoalAlignmentEvaluator(evalLLM llm.LLM) BaseEvaluator {
+	return &metricEvaluator{
+		category: MetricCategoryGoalAlignment,
+		criteria: "How well the agent's output aligns with the task requirements and the agent's goal",
+		prompt:   outputPrompt,
+		llm:      evalLLM,
+	}
+}
+
+// NewSemanticQualityEvaluator 创建语义质量评估器：输出的清晰度、准确性、连贯性和相关性
+func NewSemanticQualityEvaluator(evalLLM llm.LLM) BaseEvaluator {
+	return &metricEvaluator{
+		category: MetricCategorySemanticQuality,
+		criteria: "The clarity, accuracy, coherence and relevance of the output, regardless of task-specific requirements",
+		prompt:   outputPrompt,
+		llm:      evalLLM,
+	}
+}
+
+// NewToolUsageEvaluator 创建工具使用正确性评估器：是否选择了合适的工具并正确使用
+// Agent没有可用工具且没有调用记录时返回ErrMetricNotApplicable
+func NewToolUsageEvaluator(evalLLM llm.LLM) BaseEvaluator {
+	return &metricEvaluator{
+		category: MetricCategoryToolUsage,
+		criteria: "Whether the agent selected appropriate tools from its toolset and used them correctly, without unnecessary or missing calls",
+		prompt:   toolUsagePrompt,
+		llm:      evalLLM,
+	}
+}
+
+// NewMetricEvaluators 按指标类别创建评估器
+func NewMetricEvaluators(evalLLM llm.LLM, categories ...MetricCategory) ([]BaseEvaluator, error) {
+	evaluators := make([]BaseEvaluator, 0, len(categories))
+	for _, category := range categories {
+		switch category {
+		case MetricCategoryGoalAlignment:
+			evaluators = append(evaluators, NewGoalAlignmentEvaluator(evalLLM))
+		case MetricCategorySemanticQuality:
+			evaluators = append(evaluators, NewSemanticQualityEvaluator(evalLLM))
+		case MetricCategoryToolUsage:
+			evaluators = append(evaluators, NewToolUsageEvaluator(evalLLM))
+		default:
+			return nil, NewMetricCategoryError(category, "no evaluator available for metric category")
+		}
+	}
+	return evaluators, nil
+}
+
+// GetMetricCategory 获取评估指标类别
+func (m *metricEvaluator) GetMetricCategory() MetricCategory {
+	return m.category
+}
+
+// SetLLM 设置LLM
+func (m *metricEvaluator) SetLLM(evalLLM llm.LLM) error {
+	if evalLLM == nil {
+		return NewEvaluationConfigError("llm", "", "LLM cannot be nil")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.llm = evalLLM
+	return nil
+}
+
+// GetLLM 获取LLM
+func (m *metricEvaluator) GetLLM() llm.LLM {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.llm
+}
+
+// Evaluate 让评估LLM按指标标准打出0-10分并给出理由
+func (m *metricEvaluator) Evaluate(ctx context.Context, evalAgent agent.Agent, executionTrace map[string]interface{}, finalOutput interface{}, task Task) (*EvaluationScore, error) {
+	evalLLM := m.GetLLM()
+	if evalLLM == nil {
+		return nil, NewEvaluationConfigError("llm", "", fmt.Sprintf("LLM not configured for %s evaluator", m.category))
+	}
+	if evalAgent == nil {
+		return nil, NewAgentEvaluationError("", "", "", "validation", "agent cannot be nil", ErrAgentNotFound)
+	}
+	if task == nil {
+		return nil, NewAgentEvaluationError(evalAgent.GetID(), evalAgent.GetRole(), "", "validation", "task cannot be nil", ErrTaskNotFound)
+	}
+
+	details, err := m.prompt(evalAgent, executionTrace, finalOutput, task)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`Evaluate the agent's execution on the metric "%s".
+
+Criteria: %s
+
+%s
+
+Score the execution from 0 to 10, where 0 is completely failing the criteria and 10 is perfect.
+Return only a JSON object: {"score": <number>, "feedback": "<reasoning for the score>"}`, m.category, m.criteria, details)
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are an expert evaluator of AI agent executions. Be objective and explain your reasoning."},
+		{Role: llm.RoleUser, Content: query},
+	}
+
+	response, err := evalLLM.Call(ctx, messages, &llm.CallOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call LLM for %s evaluation: %w", m.category, err)
+	}
+	if strings.TrimSpace(response.Content) == "" {
+		return nil, NewLLMResponseError(evalLLM.GetModel(), query, "", "empty_response", ErrLLMResponseEmpty)
+	}
+
+	score, err := parseMetricScore(response.Content)
+	if err != nil {
+		return nil, NewLLMResponseError(evalLLM.GetModel(), query, response.Content, "response_parsing", err)
+	}
+	score.Category = string(m.category)
+	score.Criteria = m.criteria
+	return score, nil
+}
+
+// parseMetricScore 解析{"score": N, "feedback": "..."}格式的回复
+func parseMetricScore(content string) (*EvaluationScore, error) {
+	var parsed struct {
+		Score    *float64 `json:"score"`
+		Feedback string   `json:"feedback"`
+	}
+	if err := json.Unmarshal([]byte(extractJSONObject(content)), &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLLMResponseInvalid, err)
+	}
+	if parsed.Score == nil {
+		return nil, fmt.Errorf("%w: missing score", ErrLLMResponseInvalid)
+	}
+	if *parsed.Score < 0 || *parsed.Score > 10 {
+		return nil, NewScoreValidationError(*parsed.Score, 0, 10, "metric score")
+	}
+	return &EvaluationScore{Score: *parsed.Score, Feedback: parsed.Feedback}, nil
+}
+
+// outputPrompt 评估提示中的Agent、任务和最终输出
+func outputPrompt(evalAgent agent.Agent, executionTrace map[string]interface{}, finalOutput interface{}, task Task) (string, error) {
+	return fmt.Sprintf(`Agent:
+- Role: %s
+- Goal: %s
+- Backstory: %s
+
+Task:
+- Description: %s
+- Expected Output: %s
+
+Agent's Final Output:
+%s`, evalAgent.GetRole(), evalAgent.GetGoal(), evalAgent.GetBackstory(),
+		task.GetDescription(), task.GetExpectedOutput(), outputText(finalOutput)), nil
+}
+
+// toolUsagePrompt 在输出之外加入Agent的可用工具和实际调用的工具
+func toolUsagePrompt(evalAgent agent.Agent, executionTrace map[string]interface{}, finalOutput interface{}, task Task) (string, error) {
+	tools := evalAgent.GetTools()
+	used := toolsUsed(executionTrace, finalOutput)
+	if len(tools) == 0 && len(used) == 0 {
+		return "", fmt.Errorf("%w: agent %s has no tools", ErrMetricNotApplicable, evalAgent.GetRole())
+	}
+
+	var available strings.Builder
+	for _, tool := range tools {
+		fmt.Fprintf(&available, "- %s: %s\n", tool.GetName(), tool.GetDescription())
+	}
+	if len(tools) == 0 {
+		available.WriteString("(none)\n")
+	}
+
+	calls := "(none)"
+	if len(used) > 0 {
+		calls = strings.Join(used, ", ")
+	}
+
+	details, _ := outputPrompt(evalAgent, executionTrace, finalOutput, task)
+	return fmt.Sprintf("%s\n\nAvailable Tools:\n%s\nTools Called (in order): %s", details, available.String(), calls), nil
+}
+
+// outputText 获取最终输出的文本，支持agent.TaskOutput、评估TaskOutput和字符串
+func outputText(finalOutput interface{}) string {
+	switch output := finalOutput.(type) {
+	case nil:
+		return ""
+	case string:
+		return output
+	case *agent.TaskOutput:
+		return output.Raw
+	case *TaskOutput:
+		return output.Raw
+	default:
+		return fmt.Sprintf("%v", output)
+	}
+}
+
+// toolsUsed 获取执行过程中调用的工具，优先使用执行轨迹中的tools_used
+func toolsUsed(executionTrace map[string]interface{}, finalOutput interface{}) []string {
+	switch used := executionTrace["tools_used"].(type) {
+	case []string:
+		return used
+	case []interface{}:
+		names := make([]string, 0, len(used))
+		for _, name := range used {
+			names = append(names, fmt.Sprintf("%v", name))
+		}
+		return names
+	}
+	if output, ok := finalOutput.(*agent.TaskOutput); ok {
+		return output.ToolsUsed
+	}
+	return nil
+}
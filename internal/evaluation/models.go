@@ -126,12 +126,20 @@ type TrainingTaskEvaluation struct {
 // AgentEvaluationResult Agent评估结果
 type AgentEvaluationResult struct {
 	AgentID   string                      `json:"agent_id" validate:"required"` // Agent ID
+	AgentRole string                      `json:"agent_role,omitempty"`         // Agent角色
 	TaskID    string                      `json:"task_id,omitempty"`            // 任务ID
+	Iteration int                         `json:"iteration"`                    // 评估迭代次数
 	Metrics   map[string]*EvaluationScore `json:"metrics"`                      // 评估指标
+	Errors    map[string]string           `json:"errors,omitempty"`             // 评估失败的指标及错误信息
 	Timestamp time.Time                   `json:"timestamp"`                    // 评估时间戳
 	Metadata  map[string]interface{}      `json:"metadata,omitempty"`           // 元数据
 }
 
+// IsPartial 是否有指标评估失败
+func (a *AgentEvaluationResult) IsPartial() bool {
+	return len(a.Errors) > 0
+}
+
 // GetAverageScore 获取平均评分
 func (a *AgentEvaluationResult) GetAverageScore() float64 {
 	if len(a.Metrics) == 0 {
@@ -227,6 +235,7 @@ const (
 	MetricCategoryCreativity      MetricCategory = "creativity"       // 创造性
 	MetricCategoryCoherence       MetricCategory = "coherence"        // 连贯性
 	MetricCategoryRelevance       MetricCategory = "relevance"        // 相关性
+	MetricCategoryToolUsage       MetricCategory = "tool_usage"       // 工具使用正确性
 )
 
 // String 返回指标类别的字符串表示
@@ -239,7 +248,7 @@ func (m MetricCategory) IsValid() bool {
 	switch m {
 	case MetricCategoryGoalAlignment, MetricCategorySemanticQuality, MetricCategoryTaskCompletion,
 		MetricCategoryEfficiency, MetricCategoryAccuracy, MetricCategoryCreativity,
-		MetricCategoryCoherence, MetricCategoryRelevance:
+		MetricCategoryCoherence, MetricCategoryRelevance, MetricCategoryToolUsage:
 		return true
 	default:
 		return false
@@ -252,6 +252,7 @@ func TestMetricCategory(t *testing.T) {
 			MetricCategoryCreativity,
 			MetricCategoryCoherence,
 			MetricCategoryRelevance,
+			MetricCategoryToolUsage,
 		}
 
 		for _, category := range validCategories {
@@ -161,3 +161,86 @@ func NewKnowledgeStorageResetEvent(collection string) *KnowledgeStorageResetEven
 		Collection: collection,
 	}
 }
+
+// 知识源刷新相关的事件类型
+const (
+	// KnowledgeRefreshRequestedEventType 请求刷新知识源，KnowledgeManager运行时订阅该事件
+	KnowledgeRefreshRequestedEventType = "knowledge_refresh_requested"
+	KnowledgeRefreshedEventType        = "knowledge_refreshed"
+	KnowledgeRefreshFailedEventType    = "knowledge_refresh_failed"
+)
+
+// KnowledgeRefreshRequestedEvent 知识源刷新请求事件，SourceName为空表示刷新所有知识源
+type KnowledgeRefreshRequestedEvent struct {
+	events.BaseEvent
+	SourceName string `json:"source_name"`
+}
+
+// NewKnowledgeRefreshRequestedEvent 创建知识源刷新请求事件
+func NewKnowledgeRefreshRequestedEvent(sourceName string) *KnowledgeRefreshRequestedEvent {
+	return &KnowledgeRefreshRequestedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      KnowledgeRefreshRequestedEventType,
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"source_name": sourceName,
+			},
+		},
+		SourceName: sourceName,
+	}
+}
+
+// KnowledgeRefreshedEvent 知识源刷新完成事件，记录新增、更新和删除的块数
+type KnowledgeRefreshedEvent struct {
+	events.BaseEvent
+	SourceName string        `json:"source_name"`
+	Added      int           `json:"added"`
+	Updated    int           `json:"updated"`
+	Removed    int           `json:"removed"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// NewKnowledgeRefreshedEvent 创建知识源刷新完成事件
+func NewKnowledgeRefreshedEvent(sourceName string, added, updated, removed int, duration time.Duration) *KnowledgeRefreshedEvent {
+	return &KnowledgeRefreshedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      KnowledgeRefreshedEventType,
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"source_name": sourceName,
+				"added":       added,
+				"updated":     updated,
+				"removed":     removed,
+				"duration":    duration,
+			},
+		},
+		SourceName: sourceName,
+		Added:      added,
+		Updated:    updated,
+		Removed:    removed,
+		Duration:   duration,
+	}
+}
+
+// KnowledgeRefreshFailedEvent 知识源刷新失败事件，失败时知识源继续使用原有索引
+type KnowledgeRefreshFailedEvent struct {
+	events.BaseEvent
+	SourceName string `json:"source_name"`
+	Error      string `json:"error"`
+}
+
+// NewKnowledgeRefreshFailedEvent 创建知识源刷新失败事件
+func NewKnowledgeRefreshFailedEvent(sourceName, errorMsg string) *KnowledgeRefreshFailedEvent {
+	return &KnowledgeRefreshFailedEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      KnowledgeRefreshFailedEventType,
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"source_name": sourceName,
+				"error":       errorMsg,
+			},
+		},
+		SourceName: sourceName,
+		Error:      errorMsg,
+	}
+}
@@ -0,0 +1,179 @@
+package source
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 确保CSV知识源实现了agent.KnowledgeSource接口
+var _ agent.KnowledgeSource = (*CSVKnowledgeSource)(nil)
+
+// CSVSourceOptions CSV知识源选项
+type CSVSourceOptions struct {
+	FileSourceOptions
+	Columns      []string // 只包含这些列，为空时包含所有列
+	RowsPerChunk int      // 每个块包含的行数
+	MaxRowLength int      // 每行文本的最大字符数，超出部分截断，0表示不限制
+}
+
+// DefaultCSVSourceOptions 返回默认的CSV知识源选项
+func DefaultCSVSourceOptions() CSVSourceOptions {
+	return CSVSourceOptions{
+		FileSourceOptions: DefaultFileSourceOptions(),
+		RowsPerChunk:      20,
+		MaxRowLength:      500,
+	}
+}
+
+// CSVKnowledgeSource CSV文件知识源
+// 第一行作为表头，每行转换为"列名: 值"形式的文本，查询结果的元数据包含行范围（"row_start"、"row_end"，从1开始，不含表头）
+type CSVKnowledgeSource struct {
+	*indexedSource
+	csvOptions CSVSourceOptions
+}
+
+// NewCSVKnowledgeSource 创建CSV知识源，内容在Initialize时加载
+func NewCSVKnowledgeSource(path string, options CSVSourceOptions, log logger.Logger) *CSVKnowledgeSource {
+	source := &CSVKnowledgeSource{
+		indexedSource: newIndexedSource(path, options.FileSourceOptions, log),
+		csvOptions:    options,
+	}
+	source.load = source.loadCSV
+	return source
+}
+
+// GetDescription 获取源描述
+func (cs *CSVKnowledgeSource) GetDescription() string {
+	return fmt.Sprintf("Knowledge loaded from CSV %s", cs.path)
+}
+
+// Initialize 读取CSV、按行分块并建立索引
+func (cs *CSVKnowledgeSource) Initialize() error {
+	return cs.initialize()
+}
+
+// loadCSV 将每RowsPerChunk行合并为一个块
+func (cs *CSVKnowledgeSource) loadCSV(index *chunkIndex, file string, data []byte, modTime time.Time) error {
+	if isBinary(data) {
+		return fmt.Errorf("CSV file %s contains binary data", file)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		cs.logger.Warn("empty CSV file", logger.Field{Key: "file_path", Value: file})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header from %s: %w", file, err)
+	}
+
+	columns, err := cs.selectColumns(header)
+	if err != nil {
+		return fmt.Errorf("invalid CSV columns for %s: %w", file, err)
+	}
+
+	rowsPerChunk := cs.csvOptions.RowsPerChunk
+	if rowsPerChunk <= 0 {
+		rowsPerChunk = 1
+	}
+
+	var lines []string
+	rowStart, rowEnd, row := 0, 0, 0
+	flush := func() {
+		if len(lines) == 0 {
+			return
+		}
+		index.addChunk(file, strings.Join(lines, "\n"), modTime, map[string]interface{}{
+			"row_start": rowStart,
+			"row_end":   rowEnd,
+		})
+		lines = lines[:0]
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV row %d from %s: %w", row+1, file, err)
+		}
+		row++
+
+		text := cs.formatRow(header, columns, record)
+		if text == "" {
+			continue
+		}
+		if len(lines) == 0 {
+			rowStart = row
+		}
+		lines = append(lines, text)
+		rowEnd = row
+		if len(lines) == rowsPerChunk {
+			flush()
+		}
+	}
+	flush()
+	return nil
+}
+
+// selectColumns 返回需要包含的列索引，指定的列不存在时返回错误
+func (cs *CSVKnowledgeSource) selectColumns(header []string) ([]int, error) {
+	if len(cs.csvOptions.Columns) == 0 {
+		columns := make([]int, len(header))
+		for i := range header {
+			columns[i] = i
+		}
+		return columns, nil
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.TrimSpace(name)] = i
+	}
+
+	columns := make([]int, 0, len(cs.csvOptions.Columns))
+	for _, name := range cs.csvOptions.Columns {
+		i, ok := positions[name]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found", name)
+		}
+		columns = append(columns, i)
+	}
+	return columns, nil
+}
+
+// formatRow 将一行转换为"列名: 值"文本，跳过空值并按MaxRowLength截断
+func (cs *CSVKnowledgeSource) formatRow(header []string, columns []int, record []string) string {
+	parts := make([]string, 0, len(columns))
+	for _, i := range columns {
+		if i >= len(record) {
+			continue
+		}
+		value := strings.TrimSpace(record[i])
+		if value == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", strings.TrimSpace(header[i]), value))
+	}
+
+	text := strings.Join(parts, " | ")
+	if limit := cs.csvOptions.MaxRowLength; limit > 0 {
+		if runes := []rune(text); len(runes) > limit {
+			text = string(runes[:limit]) + "..."
+		}
+	}
+	return text
+}
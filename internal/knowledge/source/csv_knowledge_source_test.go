@@ -0,0 +1,96 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const testCSV = `country,capital,population,notes
+France,Paris,68000000,
+Germany,Berlin,84000000,largest economy in Europe
+Japan,Tokyo,125000000,
+Kenya,Nairobi,54000000,
+Brazil,Brasilia,203000000,capital moved from Rio de Janeiro in 1960
+`
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "countries.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+	return path
+}
+
+func TestCSVKnowledgeSource(t *testing.T) {
+	path := writeCSV(t, testCSV)
+
+	options := DefaultCSVSourceOptions()
+	options.RowsPerChunk = 2
+	source := NewCSVKnowledgeSource(path, options, logger.NewTestLogger())
+	if err := source.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer source.Close()
+
+	if stats := source.GetStats(); stats.TotalItems != 3 {
+		t.Errorf("Expected 3 chunks, got %d", stats.TotalItems)
+	}
+
+	items, err := source.Query(context.Background(), "capital of Kenya", agent.DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 item, got %+v", items)
+	}
+	expected := "country: Japan | capital: Tokyo | population: 125000000\ncountry: Kenya | capital: Nairobi | population: 54000000"
+	if items[0].Content != expected {
+		t.Errorf("Unexpected content:\n%s", items[0].Content)
+	}
+	if items[0].Metadata["row_start"] != 3 || items[0].Metadata["row_end"] != 4 {
+		t.Errorf("Expected rows 3-4, got %v", items[0].Metadata)
+	}
+}
+
+func TestCSVKnowledgeSource_ColumnsAndTruncation(t *testing.T) {
+	path := writeCSV(t, testCSV)
+
+	options := DefaultCSVSourceOptions()
+	options.Columns = []string{"capital", "notes"}
+	options.RowsPerChunk = 1
+	options.MaxRowLength = 30
+	source := NewCSVKnowledgeSource(path, options, logger.NewTestLogger())
+	if err := source.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	items, err := source.Query(context.Background(), "Brasilia", agent.QueryOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 item, got %+v", items)
+	}
+	if items[0].Content != "capital: Brasilia | notes: cap..." {
+		t.Errorf("Expected a filtered and truncated row, got %q", items[0].Content)
+	}
+	if strings.Contains(items[0].Content, "population") {
+		t.Error("Filtered columns should not be included")
+	}
+	if items[0].Metadata["row_start"] != 5 || items[0].Metadata["row_end"] != 5 {
+		t.Errorf("Expected row 5, got %v", items[0].Metadata)
+	}
+
+	options.Columns = []string{"gdp"}
+	err = NewCSVKnowledgeSource(path, options, logger.NewTestLogger()).Initialize()
+	if err == nil || !strings.Contains(err.Error(), `column "gdp" not found`) {
+		t.Errorf("Expected unknown column error, got %v", err)
+	}
+}
@@ -0,0 +1,436 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 确保文件和目录知识源实现了agent.KnowledgeSource接口
+var (
+	_ agent.KnowledgeSource = (*FileKnowledgeSource)(nil)
+	_ agent.KnowledgeSource = (*DirectoryKnowledgeSource)(nil)
+)
+
+// FileSourceOptions 文件和目录知识源的加载与索引选项
+type FileSourceOptions struct {
+	ChunkSize    int             // 每个块的字符数
+	ChunkOverlap int             // 相邻块重叠的字符数
+	MaxFileSize  int64           // 超过该字节数的文件会被跳过，0表示不限制
+	Extensions   []string        // 目录知识源加载的文件扩展名
+	Embedder     memory.Embedder // 设置后使用语义排序，否则使用关键词评分
+}
+
+// DefaultFileSourceOptions 返回默认的文件知识源选项
+func DefaultFileSourceOptions() FileSourceOptions {
+	return FileSourceOptions{
+		ChunkSize:    1000,
+		ChunkOverlap: 200,
+		MaxFileSize:  10 * 1024 * 1024,
+		Extensions:   []string{".txt", ".md", ".markdown"},
+	}
+}
+
+// indexedSource 文件和目录知识源共用的索引、查询、刷新和统计实现
+type indexedSource struct {
+	path    string
+	options FileSourceOptions
+	logger  logger.Logger
+	load    documentLoader
+	list    func() ([]string, error)
+
+	refreshMu    sync.Mutex // 保证同一时间只有一次索引构建
+	mu           sync.RWMutex
+	index        *chunkIndex
+	totalQueries int
+	totalScore   float64
+	totalResults int
+	lastQueried  time.Time
+}
+
+// documentLoader 将一个文件的内容分块并加入索引
+type documentLoader func(index *chunkIndex, file string, data []byte, modTime time.Time) error
+
+// newIndexedSource 创建默认按文本文件加载的索引源
+func newIndexedSource(path string, options FileSourceOptions, log logger.Logger) *indexedSource {
+	s := &indexedSource{
+		path:    filepath.Clean(path),
+		options: options,
+		logger:  log,
+	}
+	s.load = s.loadText
+	s.list = s.listFile
+	return s
+}
+
+// GetName 获取源名称（即文件或目录路径）
+func (s *indexedSource) GetName() string {
+	return s.path
+}
+
+// Query 查询与query最相关的内容块
+func (s *indexedSource) Query(ctx context.Context, query string, options agent.QueryOptions) ([]agent.KnowledgeItem, error) {
+	s.mu.RLock()
+	index := s.index
+	s.mu.RUnlock()
+
+	if index == nil {
+		return nil, fmt.Errorf("knowledge source %s is not initialized", s.path)
+	}
+
+	items, err := index.query(ctx, s.path, query, options)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.totalQueries++
+	s.lastQueried = time.Now()
+	for _, item := range items {
+		s.totalScore += item.Score
+		s.totalResults++
+	}
+	s.mu.Unlock()
+
+	return items, nil
+}
+
+// Close 释放索引
+func (s *indexedSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index = nil
+	return nil
+}
+
+// GetStats 获取知识源统计信息
+func (s *indexedSource) GetStats() agent.KnowledgeStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := agent.KnowledgeStats{
+		TotalQueries: s.totalQueries,
+		LastQueried:  s.lastQueried,
+	}
+	if s.index != nil {
+		stats.TotalItems = len(s.index.chunks)
+		stats.IndexSize = s.index.size
+	}
+	if s.totalResults > 0 {
+		stats.AverageScore = s.totalScore / float64(s.totalResults)
+	}
+	return stats
+}
+
+// initialize 列出文件并建立索引
+func (s *indexedSource) initialize() error {
+	files, err := s.list()
+	if err != nil {
+		return err
+	}
+	return s.buildIndex(files)
+}
+
+// listFile 检查路径是否为文件，单文件知识源的文件列表
+func (s *indexedSource) listFile() ([]string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat knowledge file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("knowledge file %s is a directory", s.path)
+	}
+	return []string{s.path}, nil
+}
+
+// buildIndex 加载文件并建立索引
+func (s *indexedSource) buildIndex(files []string) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	index, _, err := s.indexFiles(context.Background(), files, nil)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.index = index
+	s.mu.Unlock()
+
+	s.logger.Info("knowledge source indexed",
+		logger.Field{Key: "source_name", Value: s.path},
+		logger.Field{Key: "files_count", Value: len(files)},
+		logger.Field{Key: "chunks_count", Value: len(index.chunks)},
+		logger.Field{Key: "semantic", Value: index.embedder != nil},
+	)
+	return nil
+}
+
+// Refresh 重新列出文件，只重新分块和嵌入修改时间和内容哈希都变化的文件
+// 新索引构建完成后才替换原有索引，构建期间的查询使用原有索引，失败时原有索引保持不变
+func (s *indexedSource) Refresh(ctx context.Context) (agent.KnowledgeRefresh, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.RLock()
+	previous := s.index
+	s.mu.RUnlock()
+	if previous == nil {
+		return agent.KnowledgeRefresh{}, fmt.Errorf("knowledge source %s is not initialized", s.path)
+	}
+
+	files, err := s.list()
+	if err != nil {
+		return agent.KnowledgeRefresh{}, err
+	}
+	index, result, err := s.indexFiles(ctx, files, previous)
+	if err != nil {
+		return agent.KnowledgeRefresh{}, err
+	}
+
+	s.mu.Lock()
+	closed := s.index != previous
+	if !closed {
+		s.index = index
+	}
+	s.mu.Unlock()
+	if closed {
+		return agent.KnowledgeRefresh{}, fmt.Errorf("knowledge source %s was closed during refresh", s.path)
+	}
+
+	if result.Changed() {
+		s.logger.Info("knowledge source refreshed",
+			logger.Field{Key: "source_name", Value: s.path},
+			logger.Field{Key: "added", Value: result.Added},
+			logger.Field{Key: "updated", Value: result.Updated},
+			logger.Field{Key: "removed", Value: result.Removed},
+		)
+	}
+	return result, nil
+}
+
+// indexFiles 为files构建新索引，并返回相对previous新增、更新和删除的块数
+// previous中修改时间和大小未变、或内容哈希未变的文件直接复用原有的块和向量，
+// 变化的文件重新分块，内容未变的块复用原有向量，其余的块重新嵌入
+func (s *indexedSource) indexFiles(ctx context.Context, files []string, previous *chunkIndex) (*chunkIndex, agent.KnowledgeRefresh, error) {
+	var result agent.KnowledgeRefresh
+	previousFiles := map[string]fileState{}
+	previousChunks := map[string][]indexedChunk{}
+	if previous != nil {
+		previousFiles, previousChunks = previous.files, previous.chunksByFile()
+	}
+
+	index := newChunkIndex(s.options.Embedder)
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, result, err
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, result, fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		old, indexed := previousFiles[file]
+		if indexed && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			index.reuseFile(file, old, previousChunks[file])
+			continue
+		}
+
+		state := fileState{modTime: info.ModTime(), size: info.Size()}
+		data, ok, err := s.readFile(file, info)
+		if err != nil {
+			return nil, result, err
+		}
+		if !ok {
+			// 记录跳过的文件，文件不变时下次刷新不再读取
+			index.files[file] = state
+			compareChunks(&result, previousChunks[file], nil)
+			continue
+		}
+
+		state.hash = sha256.Sum256(data)
+		if indexed && old.hash == state.hash {
+			index.reuseFile(file, state, previousChunks[file])
+			continue
+		}
+
+		start := len(index.chunks)
+		if err := s.load(index, file, data, state.modTime); err != nil {
+			return nil, result, err
+		}
+		index.files[file] = state
+		compareChunks(&result, previousChunks[file], index.chunks[start:])
+	}
+
+	for file, chunks := range previousChunks {
+		if _, ok := index.files[file]; !ok {
+			result.Removed += len(chunks)
+		}
+	}
+
+	if err := index.embed(ctx); err != nil {
+		return nil, result, err
+	}
+	return index, result, nil
+}
+
+// compareChunks 比较文件重新分块前后的块，按位置统计新增、更新和删除的块数，
+// 并让内容未变的块复用原有向量
+func compareChunks(result *agent.KnowledgeRefresh, before, after []indexedChunk) {
+	vectors := make(map[string][]float32, len(before))
+	for _, chunk := range before {
+		if chunk.vector != nil {
+			vectors[chunk.content] = chunk.vector
+		}
+	}
+
+	for i := range after {
+		after[i].vector = vectors[after[i].content]
+		if i >= len(before) {
+			result.Added++
+		} else if before[i].content != after[i].content {
+			result.Updated++
+		}
+	}
+	if len(before) > len(after) {
+		result.Removed += len(before) - len(after)
+	}
+}
+
+// readFile 读取文件内容，超过大小限制的文件会被跳过并记录警告
+func (s *indexedSource) readFile(file string, info os.FileInfo) ([]byte, bool, error) {
+	if s.options.MaxFileSize > 0 && info.Size() > s.options.MaxFileSize {
+		s.logger.Warn("file exceeds size limit, skipping",
+			logger.Field{Key: "file_path", Value: file},
+			logger.Field{Key: "size", Value: info.Size()},
+			logger.Field{Key: "max_size", Value: s.options.MaxFileSize},
+		)
+		return nil, false, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return data, true, nil
+}
+
+// loadText 按文本文件加载，二进制文件会被跳过并记录警告
+func (s *indexedSource) loadText(index *chunkIndex, file string, data []byte, modTime time.Time) error {
+	if isBinary(data) {
+		s.logger.Warn("binary file, skipping",
+			logger.Field{Key: "file_path", Value: file},
+		)
+		return nil
+	}
+	index.addDocument(file, string(data), modTime, s.options.ChunkSize, s.options.ChunkOverlap, nil)
+	return nil
+}
+
+// isBinary 根据NUL字节和UTF-8有效性判断内容是否为二进制
+func isBinary(data []byte) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+		// 避免在多字节字符中间截断导致误判
+		for i := 0; i < utf8.UTFMax && !utf8.Valid(sample); i++ {
+			sample = sample[:len(sample)-1]
+		}
+	}
+	return bytes.IndexByte(sample, 0) >= 0 || !utf8.Valid(sample)
+}
+
+// FileKnowledgeSource 单个文本或Markdown文件的知识源
+type FileKnowledgeSource struct {
+	*indexedSource
+}
+
+// NewFileKnowledgeSource 创建文件知识源，内容在Initialize时加载
+func NewFileKnowledgeSource(path string, options FileSourceOptions, log logger.Logger) *FileKnowledgeSource {
+	return &FileKnowledgeSource{indexedSource: newIndexedSource(path, options, log)}
+}
+
+// GetDescription 获取源描述
+func (fks *FileKnowledgeSource) GetDescription() string {
+	return fmt.Sprintf("Knowledge loaded from file %s", fks.path)
+}
+
+// Initialize 加载文件、分块并建立索引
+func (fks *FileKnowledgeSource) Initialize() error {
+	return fks.initialize()
+}
+
+// DirectoryKnowledgeSource 递归加载目录中匹配扩展名的文件的知识源
+type DirectoryKnowledgeSource struct {
+	*indexedSource
+}
+
+// NewDirectoryKnowledgeSource 创建目录知识源，内容在Initialize时加载
+func NewDirectoryKnowledgeSource(path string, options FileSourceOptions, log logger.Logger) *DirectoryKnowledgeSource {
+	source := &DirectoryKnowledgeSource{indexedSource: newIndexedSource(path, options, log)}
+	source.list = source.listFiles
+	return source
+}
+
+// GetDescription 获取源描述
+func (ds *DirectoryKnowledgeSource) GetDescription() string {
+	return fmt.Sprintf("Knowledge loaded from files in directory %s", ds.path)
+}
+
+// Initialize 递归查找匹配的文件，加载、分块并建立索引
+func (ds *DirectoryKnowledgeSource) Initialize() error {
+	return ds.initialize()
+}
+
+// listFiles 递归查找目录中匹配扩展名的文件，刷新时新增和删除的文件由此发现
+func (ds *DirectoryKnowledgeSource) listFiles() ([]string, error) {
+	info, err := os.Stat(ds.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat knowledge directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("knowledge directory %s is not a directory", ds.path)
+	}
+
+	// WalkDir按字典序遍历，保证索引顺序稳定
+	var files []string
+	err = filepath.WalkDir(ds.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() && ds.matchesExtension(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk knowledge directory: %w", err)
+	}
+	return files, nil
+}
+
+// matchesExtension 检查文件扩展名是否在加载范围内，未配置扩展名时加载所有文件
+func (ds *DirectoryKnowledgeSource) matchesExtension(path string) bool {
+	if len(ds.options.Extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, allowed := range ds.options.Extensions {
+		if ext == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,375 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/agent/agenttest"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// writeKnowledgeFiles 在临时目录中创建测试文件，返回目录路径
+func writeKnowledgeFiles(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func newTestDocsDir(t *testing.T) string {
+	return writeKnowledgeFiles(t, map[string][]byte{
+		"overview.md":         []byte("# Overview\nGreenSoul agents collaborate in crews to finish tasks."),
+		"guides/memory.txt":   []byte("Short term memory keeps recent insights. Long term memory persists task evaluations."),
+		"guides/tools.md":     []byte("Tools extend agents with web search and calculators."),
+		"guides/notes.csv":    []byte("memory,memory,memory"),
+		"assets/logo.txt":     {0x89, 'P', 'N', 'G', 0x00, 0x01},
+		"archive/huge.md":     []byte(strings.Repeat("memory ", 200)),
+		"guides/empty.md":     []byte("   "),
+		"guides/deep/faq.txt": []byte("Knowledge sources answer questions about crews."),
+		"guides/llm.md":       []byte("混合专家架构在保持推理成本的同时扩大了参数规模。"),
+	})
+}
+
+func TestChunkText(t *testing.T) {
+	chunks := chunkText("abcdefghij", 4, 1)
+	expected := []string{"abcd", "defg", "ghij"}
+	if strings.Join(chunks, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected chunks %v, got %v", expected, chunks)
+	}
+
+	// 按字符而不是字节分块，不会截断多字节字符
+	chunks = chunkText("知识源分块测试", 3, 0)
+	if len(chunks) != 3 || chunks[0] != "知识源" || chunks[2] != "试" {
+		t.Errorf("Unexpected multi-byte chunks: %v", chunks)
+	}
+
+	// 重叠不小于块大小时忽略重叠，避免死循环
+	if chunks := chunkText("abcdef", 2, 5); len(chunks) != 3 {
+		t.Errorf("Expected 3 chunks when overlap is invalid, got %v", chunks)
+	}
+}
+
+func TestTermFrequencies_CJK(t *testing.T) {
+	terms := termFrequencies("研究2024年大语言模型 LLMs")
+	for _, term := range []string{"研究", "2024", "年大", "语言", "模型", "llms"} {
+		if terms[term] == 0 {
+			t.Errorf("Expected term %q in %v", term, terms)
+		}
+	}
+	if terms["研究2024年大语言模型"] != 0 {
+		t.Error("CJK text should not be kept as a single term")
+	}
+}
+
+func TestFileKnowledgeSource(t *testing.T) {
+	dir := newTestDocsDir(t)
+	path := filepath.Join(dir, "guides", "memory.txt")
+
+	options := DefaultFileSourceOptions()
+	options.ChunkSize = 40
+	options.ChunkOverlap = 0
+	source := NewFileKnowledgeSource(path, options, logger.NewTestLogger())
+
+	if source.GetName() != path {
+		t.Errorf("Expected name %s, got %s", path, source.GetName())
+	}
+	if _, err := source.Query(context.Background(), "memory", agent.DefaultQueryOptions()); err == nil {
+		t.Error("Expected error when querying before Initialize")
+	}
+
+	if err := source.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if stats := source.GetStats(); stats.TotalItems != 3 {
+		t.Errorf("Expected 3 chunks, got %d", stats.TotalItems)
+	}
+
+	items, err := source.Query(context.Background(), "long term evaluations", agent.QueryOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(items) != 1 || !strings.Contains(items[0].Content, "Long term") {
+		t.Fatalf("Expected the long term chunk first, got %+v", items)
+	}
+	if items[0].Score != 1 || items[0].Source != path || items[0].Metadata["chunk"] != 1 {
+		t.Errorf("Unexpected item: %+v", items[0])
+	}
+
+	if err := source.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if _, err := source.Query(context.Background(), "memory", agent.DefaultQueryOptions()); err == nil {
+		t.Error("Expected error when querying a closed source")
+	}
+
+	if err := NewFileKnowledgeSource(dir, options, logger.NewTestLogger()).Initialize(); err == nil {
+		t.Error("Expected error for a directory path")
+	}
+	if err := NewFileKnowledgeSource(filepath.Join(dir, "missing.md"), options, logger.NewTestLogger()).Initialize(); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func TestDirectoryKnowledgeSource(t *testing.T) {
+	dir := newTestDocsDir(t)
+
+	options := DefaultFileSourceOptions()
+	options.MaxFileSize = 500
+	source := NewDirectoryKnowledgeSource(dir, options, logger.NewTestLogger())
+	if err := source.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer source.Close()
+
+	// 二进制、超大、空文件以及不匹配扩展名的文件都被跳过
+	if stats := source.GetStats(); stats.TotalItems != 5 {
+		t.Errorf("Expected 5 indexed chunks, got %d", stats.TotalItems)
+	}
+
+	items, err := source.Query(context.Background(), "What does memory keep?", agent.DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Source != filepath.Join(dir, "guides", "memory.txt") {
+		t.Fatalf("Expected only the memory guide, got %+v", items)
+	}
+
+	items, err = source.Query(context.Background(), "研究大语言模型的最新架构", agent.DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(items) != 1 || !strings.HasSuffix(items[0].Source, "llm.md") {
+		t.Fatalf("Expected the Chinese notes to match, got %+v", items)
+	}
+
+	// 递归加载子目录，过滤器按元数据筛选结果
+	items, err = source.Query(context.Background(), "crews", agent.QueryOptions{
+		Filters: []agent.QueryFilter{{Field: "file", Operator: "contains", Value: "deep"}},
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(items) != 1 || !strings.HasSuffix(items[0].Source, "faq.txt") {
+		t.Errorf("Expected the nested faq, got %+v", items)
+	}
+
+	if _, err := source.Query(context.Background(), "crews", agent.QueryOptions{
+		Filters: []agent.QueryFilter{{Field: "file", Operator: "regex", Value: ".*"}},
+	}); err == nil {
+		t.Error("Expected error for an unsupported filter operator")
+	}
+
+	stats := source.GetStats()
+	if stats.TotalQueries != 3 || stats.AverageScore != 1 || stats.LastQueried.IsZero() {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestDirectoryKnowledgeSource_SemanticRanking(t *testing.T) {
+	dir := newTestDocsDir(t)
+
+	options := DefaultFileSourceOptions()
+	options.Embedder = memory.NewHashEmbedder(256)
+	source := NewDirectoryKnowledgeSource(dir, options, logger.NewTestLogger())
+	if err := source.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	items, err := source.Query(context.Background(), "web search tools", agent.QueryOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(items) == 0 || !strings.HasSuffix(items[0].Source, "tools.md") {
+		t.Fatalf("Expected the tools guide first, got %+v", items)
+	}
+	if len(items) > 2 {
+		t.Errorf("Expected at most 2 items, got %d", len(items))
+	}
+}
+
+func TestDirectoryKnowledgeSourceCitedByAgent(t *testing.T) {
+	dir := newTestDocsDir(t)
+	source := NewDirectoryKnowledgeSource(dir, DefaultFileSourceOptions(), logger.NewTestLogger())
+
+	prompt := agenttest.FirstPrompt(t, agent.AgentConfig{KnowledgeSources: []agent.KnowledgeSource{source}},
+		agent.NewBaseTask("Explain how tools extend agents", "a short answer"))
+
+	expected := "[" + filepath.Join(dir, "guides", "tools.md") + "] Tools extend agents with web search and calculators."
+	if !strings.Contains(prompt, "Relevant Knowledge:") || !strings.Contains(prompt, expected) {
+		t.Errorf("Expected prompt to cite %q, got:\n%s", expected, prompt)
+	}
+}
+
+// countingEmbedder 记录嵌入的文本，嵌入包含blockOn的文本时先通知embedStarted并等待release
+type countingEmbedder struct {
+	memory.Embedder
+	mu           sync.Mutex
+	texts        []string
+	err          error
+	blockOn      string
+	embedStarted chan struct{}
+	release      chan struct{}
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.blockOn != "" && strings.Contains(strings.Join(texts, "\n"), e.blockOn) {
+		e.embedStarted <- struct{}{}
+		<-e.release
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.texts = append(e.texts, texts...)
+	return e.Embedder.Embed(ctx, texts)
+}
+
+func (e *countingEmbedder) embedded() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	texts := e.texts
+	e.texts = nil
+	return texts
+}
+
+// rewriteFile 写入新内容并把修改时间推后，避免文件系统时间精度导致修改不被发现
+func rewriteFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+}
+
+func TestDirectoryKnowledgeSource_Refresh(t *testing.T) {
+	dir := writeKnowledgeFiles(t, map[string][]byte{
+		"a.md": []byte("aaaaaaaaaabbbbbbbbbb"),
+		"b.md": []byte("bbbb"),
+		"c.md": []byte("cccc"),
+	})
+	embedder := &countingEmbedder{Embedder: memory.NewHashEmbedder(64)}
+	options := DefaultFileSourceOptions()
+	options.ChunkSize = 10
+	options.ChunkOverlap = 0
+	options.Embedder = embedder
+	source := NewDirectoryKnowledgeSource(dir, options, logger.NewTestLogger())
+
+	if _, err := source.Refresh(context.Background()); err == nil {
+		t.Error("Expected error when refreshing before Initialize")
+	}
+	if err := source.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if texts := embedder.embedded(); len(texts) != 4 {
+		t.Fatalf("Expected 4 chunks embedded at Initialize, got %v", texts)
+	}
+
+	result, err := source.Refresh(context.Background())
+	if err != nil || result.Changed() {
+		t.Fatalf("Expected an unchanged refresh, got %+v, %v", result, err)
+	}
+
+	// 修改a.md（第一块不变、第二块更新、新增第三块），新增d.md，删除c.md，只改b.md的修改时间
+	later := time.Now().Add(time.Hour)
+	rewriteFile(t, filepath.Join(dir, "a.md"), "aaaaaaaaaaccccccccccdddddddddd", later)
+	rewriteFile(t, filepath.Join(dir, "b.md"), "bbbb", later)
+	rewriteFile(t, filepath.Join(dir, "d.md"), "dddd", later)
+	if err := os.Remove(filepath.Join(dir, "c.md")); err != nil {
+		t.Fatalf("failed to remove c.md: %v", err)
+	}
+
+	result, err = source.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if result != (agent.KnowledgeRefresh{Added: 2, Updated: 1, Removed: 1}) {
+		t.Errorf("Unexpected refresh result: %+v", result)
+	}
+	if texts := embedder.embedded(); strings.Join(texts, ",") != "cccccccccc,dddddddddd,dddd" {
+		t.Errorf("Expected only the changed chunks to be embedded, got %v", texts)
+	}
+	if stats := source.GetStats(); stats.TotalItems != 5 {
+		t.Errorf("Expected 5 chunks after refresh, got %d", stats.TotalItems)
+	}
+
+	items, err := source.Query(context.Background(), "cccc", agent.DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for _, item := range items {
+		if strings.HasSuffix(item.Source, "c.md") {
+			t.Errorf("Expected the removed file to be gone, got %+v", item)
+		}
+	}
+}
+
+func TestDirectoryKnowledgeSource_RefreshKeepsServingPreviousIndex(t *testing.T) {
+	dir := writeKnowledgeFiles(t, map[string][]byte{"notes.md": []byte("original release notes")})
+	embedder := &countingEmbedder{Embedder: memory.NewHashEmbedder(64)}
+	options := DefaultFileSourceOptions()
+	options.Embedder = embedder
+	source := NewDirectoryKnowledgeSource(dir, options, logger.NewTestLogger())
+	if err := source.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	queryContent := func() string {
+		t.Helper()
+		items, err := source.Query(context.Background(), "release notes", agent.DefaultQueryOptions())
+		if err != nil || len(items) != 1 {
+			t.Fatalf("Expected one item, got %+v, %v", items, err)
+		}
+		return items[0].Content
+	}
+
+	// 刷新进行中时查询使用原有索引
+	rewriteFile(t, filepath.Join(dir, "notes.md"), "updated release notes", time.Now().Add(time.Hour))
+	embedder.blockOn = "updated"
+	embedder.embedStarted, embedder.release = make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := source.Refresh(context.Background())
+		done <- err
+	}()
+	<-embedder.embedStarted
+	if content := queryContent(); content != "original release notes" {
+		t.Errorf("Expected the previous index during refresh, got %q", content)
+	}
+	close(embedder.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if content := queryContent(); content != "updated release notes" {
+		t.Errorf("Expected the refreshed content, got %q", content)
+	}
+
+	// 刷新失败时保留原有索引
+	embedder.err = errors.New("embedding service unavailable")
+	rewriteFile(t, filepath.Join(dir, "notes.md"), "broken release notes", time.Now().Add(2*time.Hour))
+	if _, err := source.Refresh(context.Background()); err == nil {
+		t.Fatal("Expected the refresh to fail")
+	}
+	embedder.err = nil
+	if content := queryContent(); content != "updated release notes" {
+		t.Errorf("Expected the previous index after a failed refresh, got %q", content)
+	}
+}
@@ -0,0 +1,366 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/memory"
+)
+
+// embedBatchSize 每次调用嵌入器的最大文本数量
+const embedBatchSize = 64
+
+// indexedChunk 索引中的一个内容块
+type indexedChunk struct {
+	id       string
+	file     string
+	position int
+	content  string
+	modTime  time.Time
+	metadata map[string]interface{}
+	terms    map[string]int
+	vector   []float32
+}
+
+// fileState 建立索引时文件的状态，刷新时用于判断文件是否变化
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// chunkIndex 内容块索引，支持关键词评分和基于嵌入器的语义排序
+// 建立后不再修改，刷新时构建新索引后整体替换
+type chunkIndex struct {
+	chunks    []indexedChunk
+	docFreq   map[string]int
+	positions map[string]int
+	files     map[string]fileState
+	embedder  memory.Embedder
+	size      int64
+}
+
+// newChunkIndex 创建内容块索引，embedder为nil时使用关键词评分
+func newChunkIndex(embedder memory.Embedder) *chunkIndex {
+	return &chunkIndex{
+		docFreq:   make(map[string]int),
+		positions: make(map[string]int),
+		files:     make(map[string]fileState),
+		embedder:  embedder,
+	}
+}
+
+// addDocument 将文档分块后加入索引，metadata会附加到每个块上
+func (idx *chunkIndex) addDocument(file, content string, modTime time.Time, chunkSize, chunkOverlap int, metadata map[string]interface{}) {
+	for _, chunk := range chunkText(content, chunkSize, chunkOverlap) {
+		idx.addChunk(file, chunk, modTime, metadata)
+	}
+}
+
+// addChunk 将一个内容块加入索引，块在文件内按加入顺序编号
+func (idx *chunkIndex) addChunk(file, content string, modTime time.Time, metadata map[string]interface{}) {
+	idx.addIndexedChunk(indexedChunk{
+		file:     file,
+		content:  content,
+		modTime:  modTime,
+		metadata: metadata,
+		terms:    termFrequencies(content),
+	})
+}
+
+// addIndexedChunk 将已计算词频的内容块加入索引，刷新时用于复用未变化文件的块和向量
+func (idx *chunkIndex) addIndexedChunk(chunk indexedChunk) {
+	for term := range chunk.terms {
+		idx.docFreq[term]++
+	}
+
+	chunk.position = idx.positions[chunk.file]
+	chunk.id = fmt.Sprintf("%s#%d", chunk.file, chunk.position)
+	idx.positions[chunk.file]++
+	idx.chunks = append(idx.chunks, chunk)
+	idx.size += int64(len(chunk.content))
+}
+
+// reuseFile 把未变化文件原有的内容块（包括向量）加入索引
+func (idx *chunkIndex) reuseFile(file string, state fileState, chunks []indexedChunk) {
+	for _, chunk := range chunks {
+		idx.addIndexedChunk(chunk)
+	}
+	idx.files[file] = state
+}
+
+// chunksByFile 按文件分组返回内容块，组内保持块的顺序
+func (idx *chunkIndex) chunksByFile() map[string][]indexedChunk {
+	files := make(map[string][]indexedChunk)
+	for _, chunk := range idx.chunks {
+		files[chunk.file] = append(files[chunk.file], chunk)
+	}
+	return files
+}
+
+// embed 为还没有向量的内容块生成向量
+func (idx *chunkIndex) embed(ctx context.Context) error {
+	if idx.embedder == nil {
+		return nil
+	}
+
+	var pending []int
+	for i, chunk := range idx.chunks {
+		if chunk.vector == nil {
+			pending = append(pending, i)
+		}
+	}
+
+	for start := 0; start < len(pending); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		texts := make([]string, 0, end-start)
+		for _, i := range pending[start:end] {
+			texts = append(texts, idx.chunks[i].content)
+		}
+
+		vectors, err := idx.embedder.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(texts))
+		}
+		for i, vector := range vectors {
+			idx.chunks[pending[start+i]].vector = vector
+		}
+	}
+	return nil
+}
+
+// query 按相关度返回内容块，分数按最佳匹配归一化到[0,1]
+func (idx *chunkIndex) query(ctx context.Context, source, query string, options agent.QueryOptions) ([]agent.KnowledgeItem, error) {
+	if len(idx.chunks) == 0 || strings.TrimSpace(query) == "" {
+		return []agent.KnowledgeItem{}, nil
+	}
+
+	var scores []float64
+	var err error
+	if idx.embedder != nil {
+		scores, err = idx.semanticScores(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		scores = idx.keywordScores(query)
+	}
+
+	best := 0.0
+	for _, score := range scores {
+		best = math.Max(best, score)
+	}
+	if best <= 0 {
+		return []agent.KnowledgeItem{}, nil
+	}
+
+	items := make([]agent.KnowledgeItem, 0)
+	for i, chunk := range idx.chunks {
+		score := scores[i] / best
+		if score <= 0 || score < options.Threshold {
+			continue
+		}
+
+		metadata := map[string]interface{}{
+			"source": source,
+			"file":   chunk.file,
+			"chunk":  chunk.position,
+		}
+		for key, value := range chunk.metadata {
+			metadata[key] = value
+		}
+		matched, err := matchesFilters(metadata, options.Filters)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+
+		items = append(items, agent.KnowledgeItem{
+			ID:        chunk.id,
+			Content:   chunk.content,
+			Source:    chunk.file,
+			Score:     score,
+			Metadata:  metadata,
+			CreatedAt: chunk.modTime,
+		})
+	}
+
+	// 分数相同时保持文件和块的原始顺序
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+	if options.Limit > 0 && len(items) > options.Limit {
+		items = items[:options.Limit]
+	}
+	return items, nil
+}
+
+// keywordScores 使用TF-IDF计算每个内容块与查询的相关度
+func (idx *chunkIndex) keywordScores(query string) []float64 {
+	queryTerms := termFrequencies(query)
+	total := float64(len(idx.chunks))
+
+	scores := make([]float64, len(idx.chunks))
+	for i, chunk := range idx.chunks {
+		for term := range queryTerms {
+			tf := chunk.terms[term]
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + total/float64(idx.docFreq[term]))
+			scores[i] += (1 + math.Log(float64(tf))) * idf
+		}
+	}
+	return scores
+}
+
+// semanticScores 计算查询向量与每个内容块向量的余弦相似度
+func (idx *chunkIndex) semanticScores(ctx context.Context, query string) ([]float64, error) {
+	vectors, err := idx.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for the query", len(vectors))
+	}
+
+	scores := make([]float64, len(idx.chunks))
+	for i, chunk := range idx.chunks {
+		scores[i] = memory.CosineSimilarity(vectors[0], chunk.vector)
+	}
+	return scores, nil
+}
+
+// matchesFilters 检查元数据是否满足所有过滤条件
+func matchesFilters(metadata map[string]interface{}, filters []agent.QueryFilter) (bool, error) {
+	for _, filter := range filters {
+		value := fmt.Sprint(metadata[filter.Field])
+		expected := fmt.Sprint(filter.Value)
+
+		switch filter.Operator {
+		case "", "eq", "==":
+			if value != expected {
+				return false, nil
+			}
+		case "ne", "!=":
+			if value == expected {
+				return false, nil
+			}
+		case "contains":
+			if !strings.Contains(value, expected) {
+				return false, nil
+			}
+		default:
+			return false, fmt.Errorf("unsupported filter operator: %s", filter.Operator)
+		}
+	}
+	return true, nil
+}
+
+// stopWords 关键词评分时忽略的常见英文词
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"with": true,
+}
+
+// termFrequencies 将文本切分为小写词项并统计词频
+// 中日韩文字没有空格分词，按相邻两个字符的二元组切分
+func termFrequencies(text string) map[string]int {
+	terms := make(map[string]int)
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, field := range fields {
+		for _, term := range splitCJK(field) {
+			if !stopWords[term] {
+				terms[term]++
+			}
+		}
+	}
+	return terms
+}
+
+// splitCJK 将词中的中日韩文字片段切分为二元组，其余片段保持不变
+func splitCJK(field string) []string {
+	var terms []string
+	var word, cjk []rune
+
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			terms = append(terms, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			terms = append(terms, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+
+	for _, r := range field {
+		if isCJK(r) {
+			flushWord()
+			cjk = append(cjk, r)
+		} else {
+			flushCJK()
+			word = append(word, r)
+		}
+	}
+	flushCJK()
+	flushWord()
+	return terms
+}
+
+// isCJK 判断字符是否属于中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// chunkText 按字符数分块，相邻块之间保留chunkOverlap个字符的重叠
+func chunkText(text string, chunkSize, chunkOverlap int) []string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) == 0 {
+		return nil
+	}
+	if chunkSize <= 0 || len(runes) <= chunkSize {
+		return []string{string(runes)}
+	}
+	if chunkOverlap < 0 || chunkOverlap >= chunkSize {
+		chunkOverlap = 0
+	}
+
+	var chunks []string
+	for start := 0; start < len(runes); start += chunkSize - chunkOverlap {
+		end := start + chunkSize
+		if end > len(runes) {
+			end = len(runes)
+		}
+		chunks = append(chunks, string(runes[start:end]))
+		if end == len(runes) {
+			break
+		}
+	}
+	return chunks
+}
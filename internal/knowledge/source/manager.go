@@ -0,0 +1,229 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/knowledge"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// refreshRequestBuffer 等待处理的刷新请求数，超出的请求被丢弃（已有刷新在排队）
+const refreshRequestBuffer = 16
+
+// ManagerConfig 知识源管理器配置
+type ManagerConfig struct {
+	RefreshInterval time.Duration // 定期刷新的间隔，<=0表示只按需刷新
+	RefreshTimeout  time.Duration // 单个知识源一次刷新的超时，0表示不限
+}
+
+// DefaultManagerConfig 返回默认配置
+func DefaultManagerConfig() *ManagerConfig {
+	return &ManagerConfig{
+		RefreshInterval: 5 * time.Minute,
+		RefreshTimeout:  2 * time.Minute,
+	}
+}
+
+// KnowledgeManager 管理多个知识源的初始化和刷新
+// Run运行期间按RefreshInterval定期刷新，并在收到knowledge_refresh_requested事件时按需刷新；
+// 每次刷新发出knowledge_refreshed或knowledge_refresh_failed事件，失败的知识源继续使用原有索引
+type KnowledgeManager struct {
+	config   ManagerConfig
+	eventBus events.EventBus
+	logger   logger.Logger
+	requests chan string
+
+	mu      sync.RWMutex
+	sources []agent.KnowledgeSource
+
+	refreshMu sync.Mutex // 同一时间只执行一轮刷新
+}
+
+// NewKnowledgeManager 创建知识源管理器，config为nil时使用DefaultManagerConfig，eventBus为nil时不发出事件
+func NewKnowledgeManager(sources []agent.KnowledgeSource, config *ManagerConfig, eventBus events.EventBus, log logger.Logger) *KnowledgeManager {
+	if config == nil {
+		config = DefaultManagerConfig()
+	}
+	if log == nil {
+		log = logger.NewConsoleLogger()
+	}
+	return &KnowledgeManager{
+		config:   *config,
+		eventBus: eventBus,
+		logger:   log,
+		requests: make(chan string, refreshRequestBuffer),
+		sources:  append([]agent.KnowledgeSource(nil), sources...),
+	}
+}
+
+// AddSource 添加知识源，名称不能与已有知识源重复
+func (m *KnowledgeManager) AddSource(source agent.KnowledgeSource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.sources {
+		if existing.GetName() == source.GetName() {
+			return fmt.Errorf("knowledge source already exists: %s", source.GetName())
+		}
+	}
+	m.sources = append(m.sources, source)
+	return nil
+}
+
+// Sources 返回管理的知识源，可以直接设置到Agent的KnowledgeSources，刷新后Agent查询到的是新内容
+func (m *KnowledgeManager) Sources() []agent.KnowledgeSource {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]agent.KnowledgeSource(nil), m.sources...)
+}
+
+// Initialize 初始化所有知识源，返回所有失败的汇总
+func (m *KnowledgeManager) Initialize() error {
+	var errs []error
+	for _, source := range m.Sources() {
+		if err := source.Initialize(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to initialize knowledge source %s: %w", source.GetName(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RefreshAll 依次刷新所有知识源，返回每个成功刷新的知识源的变化和所有失败的汇总
+func (m *KnowledgeManager) RefreshAll(ctx context.Context) (map[string]agent.KnowledgeRefresh, error) {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	results := make(map[string]agent.KnowledgeRefresh)
+	var errs []error
+	for _, source := range m.Sources() {
+		result, err := m.refreshSource(ctx, source)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		results[source.GetName()] = result
+	}
+	return results, errors.Join(errs...)
+}
+
+// Refresh 刷新指定名称的知识源
+func (m *KnowledgeManager) Refresh(ctx context.Context, name string) (agent.KnowledgeRefresh, error) {
+	source := m.source(name)
+	if source == nil {
+		return agent.KnowledgeRefresh{}, fmt.Errorf("knowledge source not found: %s", name)
+	}
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+	return m.refreshSource(ctx, source)
+}
+
+// source 按名称查找知识源
+func (m *KnowledgeManager) source(name string) agent.KnowledgeSource {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, source := range m.sources {
+		if source.GetName() == name {
+			return source
+		}
+	}
+	return nil
+}
+
+// refreshSource 刷新一个知识源，记录结果并发出事件
+func (m *KnowledgeManager) refreshSource(ctx context.Context, source agent.KnowledgeSource) (agent.KnowledgeRefresh, error) {
+	if m.config.RefreshTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.config.RefreshTimeout)
+		defer cancel()
+	}
+
+	name := source.GetName()
+	started := time.Now()
+	result, err := source.Refresh(ctx)
+	if err != nil {
+		m.logger.Error("knowledge source refresh failed, keeping the previous index",
+			logger.Field{Key: "source_name", Value: name},
+			logger.Field{Key: "error", Value: err},
+		)
+		m.emit(ctx, knowledge.NewKnowledgeRefreshFailedEvent(name, err.Error()))
+		return agent.KnowledgeRefresh{}, fmt.Errorf("failed to refresh knowledge source %s: %w", name, err)
+	}
+
+	m.emit(ctx, knowledge.NewKnowledgeRefreshedEvent(name, result.Added, result.Updated, result.Removed, time.Since(started)))
+	return result, nil
+}
+
+// emit 发出事件，没有事件总线时忽略
+func (m *KnowledgeManager) emit(ctx context.Context, event events.Event) {
+	if m.eventBus != nil {
+		m.eventBus.Emit(context.WithoutCancel(ctx), m, event)
+	}
+}
+
+// RequestRefresh 请求Run刷新指定知识源，name为空时刷新所有知识源，已有足够请求在排队时忽略
+func (m *KnowledgeManager) RequestRefresh(name string) {
+	select {
+	case m.requests <- name:
+	default:
+		m.logger.Debug("knowledge refresh already pending, dropping request",
+			logger.Field{Key: "source_name", Value: name},
+		)
+	}
+}
+
+// Run 定期和按需刷新知识源，直到ctx结束
+// 有事件总线时订阅knowledge_refresh_requested事件，事件的SourceName为空时刷新所有知识源
+func (m *KnowledgeManager) Run(ctx context.Context) error {
+	if m.eventBus != nil {
+		subscription, err := m.eventBus.SubscribeWithOptions(knowledge.KnowledgeRefreshRequestedEventType,
+			func(ctx context.Context, event events.Event) error {
+				name := ""
+				if request, ok := event.(*knowledge.KnowledgeRefreshRequestedEvent); ok {
+					name = request.SourceName
+				}
+				m.RequestRefresh(name)
+				return nil
+			}, events.WithSyncDelivery())
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to knowledge refresh requests: %w", err)
+		}
+		defer subscription.Unsubscribe()
+	}
+
+	var ticks <-chan time.Time
+	if m.config.RefreshInterval > 0 {
+		ticker := time.NewTicker(m.config.RefreshInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	m.logger.Info("knowledge manager started",
+		logger.Field{Key: "sources_count", Value: len(m.Sources())},
+		logger.Field{Key: "refresh_interval", Value: m.config.RefreshInterval},
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+			m.RefreshAll(ctx)
+		case name := <-m.requests:
+			// 刷新失败已经记录日志并发出事件
+			switch {
+			case name == "":
+				m.RefreshAll(ctx)
+			case m.source(name) == nil:
+				m.logger.Warn("refresh requested for unknown knowledge source",
+					logger.Field{Key: "source_name", Value: name},
+				)
+			default:
+				m.Refresh(ctx, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/knowledge"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// refreshStubSource 返回预设刷新结果的知识源，每次刷新时向refreshed发送名称
+type refreshStubSource struct {
+	name      string
+	result    agent.KnowledgeRefresh
+	err       error
+	refreshed chan string
+}
+
+func (s *refreshStubSource) GetName() string        { return s.name }
+func (s *refreshStubSource) GetDescription() string { return "refresh stub" }
+func (s *refreshStubSource) Initialize() error      { return nil }
+func (s *refreshStubSource) Close() error           { return nil }
+func (s *refreshStubSource) GetStats() agent.KnowledgeStats {
+	return agent.KnowledgeStats{}
+}
+
+func (s *refreshStubSource) Query(ctx context.Context, query string, options agent.QueryOptions) ([]agent.KnowledgeItem, error) {
+	return nil, nil
+}
+
+func (s *refreshStubSource) Refresh(ctx context.Context) (agent.KnowledgeRefresh, error) {
+	if s.refreshed != nil {
+		s.refreshed <- s.name
+	}
+	return s.result, s.err
+}
+
+// recordRefreshEvents 同步记录刷新完成和失败事件
+func recordRefreshEvents(t *testing.T, bus events.EventBus) func() []events.Event {
+	t.Helper()
+	var mu sync.Mutex
+	var recorded []events.Event
+	for _, eventType := range []string{knowledge.KnowledgeRefreshedEventType, knowledge.KnowledgeRefreshFailedEventType} {
+		if _, err := bus.SubscribeWithOptions(eventType, func(ctx context.Context, event events.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			recorded = append(recorded, event)
+			return nil
+		}, events.WithSyncDelivery()); err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+	}
+	return func() []events.Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]events.Event(nil), recorded...)
+	}
+}
+
+func TestKnowledgeManagerRefreshAll(t *testing.T) {
+	bus := events.NewEventBus(logger.NewTestLogger())
+	recorded := recordRefreshEvents(t, bus)
+
+	healthy := &refreshStubSource{name: "docs", result: agent.KnowledgeRefresh{Added: 2, Updated: 1, Removed: 3}}
+	broken := &refreshStubSource{name: "wiki", err: errors.New("wiki unavailable")}
+	manager := NewKnowledgeManager([]agent.KnowledgeSource{healthy, broken}, nil, bus, logger.NewTestLogger())
+
+	if err := manager.AddSource(&refreshStubSource{name: "docs"}); err == nil {
+		t.Error("Expected error when adding a duplicate source")
+	}
+
+	results, err := manager.RefreshAll(context.Background())
+	if err == nil {
+		t.Error("Expected the failed source to be reported")
+	}
+	if len(results) != 1 || results["docs"] != healthy.result {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	got := recorded()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(got))
+	}
+	refreshed, ok := got[0].(*knowledge.KnowledgeRefreshedEvent)
+	if !ok || refreshed.SourceName != "docs" || refreshed.Added != 2 || refreshed.Updated != 1 || refreshed.Removed != 3 {
+		t.Errorf("Unexpected refreshed event: %+v", got[0])
+	}
+	failed, ok := got[1].(*knowledge.KnowledgeRefreshFailedEvent)
+	if !ok || failed.SourceName != "wiki" || failed.Error != "wiki unavailable" {
+		t.Errorf("Unexpected failed event: %+v", got[1])
+	}
+
+	if _, err := manager.Refresh(context.Background(), "missing"); err == nil {
+		t.Error("Expected error for an unknown source")
+	}
+}
+
+func TestKnowledgeManagerRunRefreshesOnRequestAndInterval(t *testing.T) {
+	bus := events.NewEventBus(logger.NewTestLogger())
+	refreshed := make(chan string, 16)
+	docs := &refreshStubSource{name: "docs", refreshed: refreshed}
+	wiki := &refreshStubSource{name: "wiki", refreshed: refreshed}
+	manager := NewKnowledgeManager([]agent.KnowledgeSource{docs, wiki}, &ManagerConfig{}, bus, logger.NewTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- manager.Run(ctx) }()
+
+	waitRefreshed := func(want ...string) {
+		t.Helper()
+		for _, name := range want {
+			select {
+			case got := <-refreshed:
+				if got != name {
+					t.Errorf("Expected %s to be refreshed, got %s", name, got)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timed out waiting for %s to be refreshed", name)
+			}
+		}
+	}
+
+	// Run订阅后才能收到事件，先等订阅完成
+	deadline := time.Now().Add(2 * time.Second)
+	for bus.GetHandlerCount(knowledge.KnowledgeRefreshRequestedEventType) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	bus.Emit(context.Background(), nil, knowledge.NewKnowledgeRefreshRequestedEvent("wiki"))
+	waitRefreshed("wiki")
+	bus.Emit(context.Background(), nil, knowledge.NewKnowledgeRefreshRequestedEvent(""))
+	waitRefreshed("docs", "wiki")
+
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Errorf("Run returned an error: %v", err)
+	}
+	if count := bus.GetHandlerCount(knowledge.KnowledgeRefreshRequestedEventType); count != 0 {
+		t.Errorf("Expected Run to unsubscribe, %d handlers left", count)
+	}
+
+	// 定期刷新
+	manager = NewKnowledgeManager([]agent.KnowledgeSource{docs}, &ManagerConfig{RefreshInterval: 10 * time.Millisecond}, nil, logger.NewTestLogger())
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Run(ctx)
+	waitRefreshed("docs", "docs")
+}
@@ -0,0 +1,60 @@
+package source
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 确保PDF知识源实现了agent.KnowledgeSource接口
+var _ agent.KnowledgeSource = (*PDFKnowledgeSource)(nil)
+
+// PDFKnowledgeSource PDF文件知识源，按页提取文本，查询结果的元数据包含页码（"page"）
+type PDFKnowledgeSource struct {
+	*indexedSource
+}
+
+// NewPDFKnowledgeSource 创建PDF知识源，内容在Initialize时加载
+func NewPDFKnowledgeSource(path string, options FileSourceOptions, log logger.Logger) *PDFKnowledgeSource {
+	source := &PDFKnowledgeSource{indexedSource: newIndexedSource(path, options, log)}
+	source.load = source.loadPDF
+	return source
+}
+
+// GetDescription 获取源描述
+func (ps *PDFKnowledgeSource) GetDescription() string {
+	return fmt.Sprintf("Knowledge extracted from PDF %s", ps.path)
+}
+
+// Initialize 提取PDF文本、按页分块并建立索引，损坏的PDF返回错误
+func (ps *PDFKnowledgeSource) Initialize() error {
+	return ps.initialize()
+}
+
+// loadPDF 按页提取文本并分块，每个块记录所在页码
+func (ps *PDFKnowledgeSource) loadPDF(index *chunkIndex, file string, data []byte, modTime time.Time) error {
+	pages, err := extractPDFPages(data)
+	if err != nil {
+		return fmt.Errorf("failed to extract text from PDF %s: %w", file, err)
+	}
+
+	textPages := 0
+	for i, text := range pages {
+		if text == "" {
+			continue
+		}
+		textPages++
+		index.addDocument(file, text, modTime, ps.options.ChunkSize, ps.options.ChunkOverlap,
+			map[string]interface{}{"page": i + 1})
+	}
+
+	if textPages == 0 {
+		ps.logger.Warn("no extractable text in PDF, it may contain only scanned images",
+			logger.Field{Key: "file_path", Value: file},
+			logger.Field{Key: "pages", Value: len(pages)},
+		)
+	}
+	return nil
+}
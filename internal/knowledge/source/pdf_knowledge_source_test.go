@@ -0,0 +1,172 @@
+package source
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// toUnicodeCMap 将编码0x0001-0x001A映射为a-z，0x0020映射为空格
+const toUnicodeCMap = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+1 beginbfchar
+<0020> <0020>
+endbfchar
+1 beginbfrange
+<0001> <001A> <0061>
+endbfrange
+endcmap
+end end`
+
+// encodeCIDText 按toUnicodeCMap将小写文本编码为双字节十六进制字符串
+func encodeCIDText(text string) string {
+	var out strings.Builder
+	for _, r := range text {
+		code := 0x20
+		if r >= 'a' && r <= 'z' {
+			code = int(r-'a') + 1
+		}
+		fmt.Fprintf(&out, "%04X", code)
+	}
+	return "<" + out.String() + ">"
+}
+
+func compress(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	w.Close()
+	return buf.String()
+}
+
+// buildTestPDF 生成两页的PDF：第一页为未压缩的简单字体文本，第二页为压缩的Type0字体文本
+func buildTestPDF(t *testing.T) []byte {
+	t.Helper()
+
+	page1 := "BT /F1 12 Tf 72 720 Td (Quarterly revenue grew 12\\%) Tj 0 -14 Td [(in the ) -300 (third) 250 ( quarter)] TJ ET"
+	page2 := compress(t, "BT /F2 12 Tf 72 720 Td "+encodeCIDText("risk factors")+" Tj ET")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 8 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(page1), page1),
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(page2), page2),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(toUnicodeCMap), toUnicodeCMap),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestExtractPDFPages(t *testing.T) {
+	pages, err := extractPDFPages(buildTestPDF(t))
+	if err != nil {
+		t.Fatalf("extractPDFPages failed: %v", err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("Expected 2 pages, got %d", len(pages))
+	}
+	if pages[0] != "Quarterly revenue grew 12%\nin the third quarter" {
+		t.Errorf("Unexpected page 1 text: %q", pages[0])
+	}
+	if pages[1] != "risk factors" {
+		t.Errorf("Unexpected page 2 text: %q", pages[1])
+	}
+}
+
+func TestExtractPDFPages_Corrupt(t *testing.T) {
+	cases := map[string][]byte{
+		"not a pdf":  []byte("name,value\nfoo,1\n"),
+		"no objects": []byte("%PDF-1.4\n%%EOF"),
+		"no catalog": []byte("%PDF-1.4\n1 0 obj\n<< /Type /Font >>\nendobj\n%%EOF"),
+		"bad filter": []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+			"2 0 obj\n<< /Type /Pages /Kids [3 0 R] >>\nendobj\n" +
+			"3 0 obj\n<< /Type /Page /Contents 4 0 R >>\nendobj\n" +
+			"4 0 obj\n<< /Length 4 /Filter /JBIG2Decode >>\nstream\nabcd\nendstream\nendobj\n"),
+	}
+	for name, data := range cases {
+		if _, err := extractPDFPages(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// 任意位置截断或损坏都不会panic
+	data := buildTestPDF(t)
+	for i := 0; i < len(data); i += 7 {
+		extractPDFPages(data[:i])
+
+		damaged := append([]byte(nil), data...)
+		damaged[i] = '['
+		extractPDFPages(damaged)
+	}
+}
+
+func TestPDFKnowledgeSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(path, buildTestPDF(t), 0o644); err != nil {
+		t.Fatalf("failed to write PDF: %v", err)
+	}
+
+	source := NewPDFKnowledgeSource(path, DefaultFileSourceOptions(), logger.NewTestLogger())
+	if err := source.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer source.Close()
+
+	if source.GetName() != path {
+		t.Errorf("Expected name %s, got %s", path, source.GetName())
+	}
+	if stats := source.GetStats(); stats.TotalItems != 2 {
+		t.Errorf("Expected one chunk per page, got %d", stats.TotalItems)
+	}
+
+	items, err := source.Query(context.Background(), "What are the risk factors?", agent.DefaultQueryOptions())
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Content != "risk factors" || items[0].Metadata["page"] != 2 {
+		t.Errorf("Expected the page 2 chunk, got %+v", items)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.pdf")
+	if err := os.WriteFile(corrupt, []byte("%PDF-1.7\ngarbage"), 0o644); err != nil {
+		t.Fatalf("failed to write PDF: %v", err)
+	}
+	err = NewPDFKnowledgeSource(corrupt, DefaultFileSourceOptions(), logger.NewTestLogger()).Initialize()
+	if err == nil || !strings.Contains(err.Error(), "failed to extract text from PDF") {
+		t.Errorf("Expected a descriptive extraction error, got %v", err)
+	}
+}
@@ -0,0 +1,1032 @@
+package source
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// 本文件实现一个精简的纯Go PDF文本提取器：
+// 顺序扫描文件中的间接对象（包括对象流中的压缩对象），沿页面树读取每页的内容流，
+// 解析文本操作符并通过字体的ToUnicode映射还原文本。不依赖交叉引用表，因此能容忍轻微损坏的文件。
+
+// PDF对象类型
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfArray   []interface{}
+	pdfDict    map[pdfName]interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		data []byte
+	}
+)
+
+const (
+	// maxPDFResolveDepth 解析间接引用和页面树的最大深度，防止循环引用
+	maxPDFResolveDepth = 32
+	// maxPDFNesting 数组和字典的最大嵌套层数，防止恶意文件耗尽栈空间
+	maxPDFNesting = 128
+)
+
+var (
+	errPDFEOF        = errors.New("unexpected end of PDF data")
+	pdfObjectPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+)
+
+// extractPDFPages 提取PDF每一页的文本，返回顺序与页码一致
+// 损坏的文件返回描述性错误而不是panic
+func extractPDFPages(data []byte) (pages []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file: missing %PDF header")
+	}
+
+	doc := parsePDFDocument(data)
+	if len(doc.objects) == 0 {
+		return nil, errors.New("malformed PDF: no objects found")
+	}
+
+	catalog := doc.catalog()
+	if catalog == nil {
+		return nil, errors.New("malformed PDF: document catalog not found")
+	}
+
+	var pageDicts []pdfPage
+	doc.collectPages(catalog["Pages"], nil, 0, map[pdfRef]bool{}, &pageDicts)
+	if len(pageDicts) == 0 {
+		return nil, errors.New("malformed PDF: no pages found")
+	}
+
+	pages = make([]string, len(pageDicts))
+	for i, page := range pageDicts {
+		text, err := doc.pageText(page)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i+1, err)
+		}
+		pages[i] = text
+	}
+	return pages, nil
+}
+
+// pdfDocument 扫描得到的PDF对象集合
+type pdfDocument struct {
+	data    []byte
+	objects map[int]interface{}
+	fonts   map[interface{}]*pdfFont
+}
+
+// pdfPage 页面字典及其（可能继承的）资源
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// parsePDFDocument 顺序扫描所有间接对象，后出现的对象覆盖先出现的（增量更新）
+func parsePDFDocument(data []byte) *pdfDocument {
+	doc := &pdfDocument{
+		data:    data,
+		objects: make(map[int]interface{}),
+		fonts:   make(map[interface{}]*pdfFont),
+	}
+
+	pos := 0
+	for pos < len(data) {
+		loc := pdfObjectPattern.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		if start > 0 && !isPDFWhitespace(data[start-1]) && !isPDFDelimiter(data[start-1]) {
+			pos = end
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+
+		obj, next, err := parseIndirectObject(data, end)
+		if err != nil {
+			pos = end
+			continue
+		}
+		doc.objects[num] = obj
+		pos = next
+	}
+
+	doc.expandObjectStreams()
+	return doc
+}
+
+// parseIndirectObject 解析"obj"关键字之后的对象，返回对象及其结束位置
+func parseIndirectObject(data []byte, pos int) (interface{}, int, error) {
+	lexer := &pdfLexer{data: data, pos: pos, refs: true}
+	obj, err := lexer.readObject()
+	if err != nil {
+		return nil, pos, err
+	}
+
+	dict, ok := obj.(pdfDict)
+	lexer.skipSpace()
+	if !ok || !bytes.HasPrefix(data[lexer.pos:], []byte("stream")) {
+		return obj, lexer.pos, nil
+	}
+
+	// stream关键字后紧跟一个换行符（CRLF或LF）
+	start := lexer.pos + len("stream")
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	if length, ok := dict["Length"].(float64); ok && length >= 0 {
+		end := start + int(length)
+		if end <= len(data) {
+			rest := bytes.TrimLeft(data[end:], " \t\r\n")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return &pdfStream{dict: dict, data: data[start:end]}, end, nil
+			}
+		}
+	}
+
+	// 长度缺失、为间接引用或不正确时，查找endstream关键字
+	idx := bytes.Index(data[start:], []byte("endstream"))
+	if idx < 0 {
+		return nil, pos, errors.New("stream without endstream")
+	}
+	streamData := data[start : start+idx]
+	streamData = bytes.TrimSuffix(streamData, []byte("\n"))
+	streamData = bytes.TrimSuffix(streamData, []byte("\r"))
+	return &pdfStream{dict: dict, data: streamData}, start + idx, nil
+}
+
+// expandObjectStreams 解压对象流（/Type /ObjStm）中的压缩对象
+func (d *pdfDocument) expandObjectStreams() {
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	for _, num := range nums {
+		stream, ok := d.objects[num].(*pdfStream)
+		if !ok || !isPDFName(stream.dict["Type"], "ObjStm") {
+			continue
+		}
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		count, _ := d.resolve(stream.dict["N"]).(float64)
+		first, _ := d.resolve(stream.dict["First"]).(float64)
+
+		header := &pdfLexer{data: data}
+		for i := 0; i < int(count); i++ {
+			objNum, err1 := header.readObject()
+			offset, err2 := header.readObject()
+			n, ok1 := objNum.(float64)
+			o, ok2 := offset.(float64)
+			if err1 != nil || err2 != nil || !ok1 || !ok2 {
+				break
+			}
+			if _, exists := d.objects[int(n)]; exists {
+				continue
+			}
+			pos := int(first) + int(o)
+			if pos < 0 || pos >= len(data) {
+				continue
+			}
+			lexer := &pdfLexer{data: data, pos: pos, refs: true}
+			if obj, err := lexer.readObject(); err == nil {
+				d.objects[int(n)] = obj
+			}
+		}
+	}
+}
+
+// isPDFName 判断对象是否为指定的名称（对象可能是切片等不可比较的类型）
+func isPDFName(obj interface{}, name pdfName) bool {
+	value, ok := obj.(pdfName)
+	return ok && value == name
+}
+
+// resolve 解析间接引用
+func (d *pdfDocument) resolve(obj interface{}) interface{} {
+	for i := 0; i < maxPDFResolveDepth; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+// resolveDict 解析为字典，流对象返回其字典
+func (d *pdfDocument) resolveDict(obj interface{}) pdfDict {
+	switch v := d.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// catalog 查找文档目录：优先使用trailer或交叉引用流中的/Root，否则查找/Type /Catalog对象
+func (d *pdfDocument) catalog() pdfDict {
+	if idx := bytes.LastIndex(d.data, []byte("trailer")); idx >= 0 {
+		lexer := &pdfLexer{data: d.data, pos: idx + len("trailer"), refs: true}
+		if trailer, err := lexer.readObject(); err == nil {
+			if dict, ok := trailer.(pdfDict); ok {
+				if root := d.resolveDict(dict["Root"]); root != nil {
+					return root
+				}
+			}
+		}
+	}
+
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	for _, num := range nums {
+		dict := d.resolveDict(d.objects[num])
+		if dict == nil {
+			continue
+		}
+		if isPDFName(dict["Type"], "XRef") {
+			if root := d.resolveDict(dict["Root"]); root != nil {
+				return root
+			}
+		}
+	}
+	for _, num := range nums {
+		if dict, ok := d.objects[num].(pdfDict); ok && isPDFName(dict["Type"], "Catalog") {
+			return dict
+		}
+	}
+	return nil
+}
+
+// collectPages 按顺序遍历页面树，资源字典沿树继承
+func (d *pdfDocument) collectPages(node interface{}, resources pdfDict, depth int, visited map[pdfRef]bool, pages *[]pdfPage) {
+	if depth > maxPDFResolveDepth {
+		return
+	}
+	if ref, ok := node.(pdfRef); ok {
+		if visited[ref] {
+			return
+		}
+		visited[ref] = true
+	}
+
+	dict := d.resolveDict(node)
+	if dict == nil {
+		return
+	}
+	if own := d.resolveDict(dict["Resources"]); own != nil {
+		resources = own
+	}
+
+	if kids, ok := d.resolve(dict["Kids"]).(pdfArray); ok && !isPDFName(dict["Type"], "Page") {
+		for _, kid := range kids {
+			d.collectPages(kid, resources, depth+1, visited, pages)
+		}
+		return
+	}
+	*pages = append(*pages, pdfPage{dict: dict, resources: resources})
+}
+
+// pageText 解码页面内容流并提取文本
+func (d *pdfDocument) pageText(page pdfPage) (string, error) {
+	var streams []*pdfStream
+	switch contents := d.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		streams = append(streams, contents)
+	case pdfArray:
+		for _, item := range contents {
+			if stream, ok := d.resolve(item).(*pdfStream); ok {
+				streams = append(streams, stream)
+			}
+		}
+	}
+
+	var content bytes.Buffer
+	for _, stream := range streams {
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			return "", err
+		}
+		content.Write(data)
+		content.WriteByte('\n')
+	}
+
+	fonts := d.resolveDict(page.resources["Font"])
+	return extractContentText(content.Bytes(), func(name pdfName) *pdfFont {
+		if fonts == nil {
+			return nil
+		}
+		return d.font(fonts[name])
+	}), nil
+}
+
+// decodeStream 按/Filter解码流数据
+func (d *pdfDocument) decodeStream(stream *pdfStream) ([]byte, error) {
+	var filters []pdfName
+	switch filter := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = append(filters, filter)
+	case pdfArray:
+		for _, item := range filter {
+			if name, ok := d.resolve(item).(pdfName); ok {
+				filters = append(filters, name)
+			}
+		}
+	}
+
+	data := stream.data
+	for _, filter := range filters {
+		var err error
+		switch filter {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+		case "ASCIIHexDecode", "AHx":
+			data = decodeASCIIHex(data)
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %s", filter)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s stream: %w", filter, err)
+		}
+	}
+	return data, nil
+}
+
+// inflate 解压zlib数据，兼容缺少zlib头的原始deflate数据和截断的数据
+func inflate(data []byte) ([]byte, error) {
+	var reader io.Reader
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		defer zr.Close()
+		reader = zr
+	} else {
+		fr := flate.NewReader(bytes.NewReader(data))
+		defer fr.Close()
+		reader = fr
+	}
+
+	out, err := io.ReadAll(reader)
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeASCIIHex 解码ASCIIHexDecode数据
+func decodeASCIIHex(data []byte) []byte {
+	if idx := bytes.IndexByte(data, '>'); idx >= 0 {
+		data = data[:idx]
+	}
+	return hexBytes(data)
+}
+
+// hexBytes 将十六进制字符转换为字节，忽略非十六进制字符，奇数长度时补0
+func hexBytes(data []byte) []byte {
+	var digits []byte
+	for _, c := range data {
+		if _, ok := hexValue(c); ok {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		hi, _ := hexValue(digits[2*i])
+		lo, _ := hexValue(digits[2*i+1])
+		out[i] = hi<<4 | lo
+	}
+	return out
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// pdfFont 将字符串中的字符编码还原为文本
+type pdfFont struct {
+	codeLen int
+	cmap    *pdfCMap
+}
+
+// font 根据字体字典创建（并缓存）字体解码器
+func (d *pdfDocument) font(obj interface{}) *pdfFont {
+	key := obj
+	if _, ok := obj.(pdfRef); !ok {
+		key = fmt.Sprintf("%p", d.resolveDict(obj))
+	}
+	if font, ok := d.fonts[key]; ok {
+		return font
+	}
+
+	dict := d.resolveDict(obj)
+	font := &pdfFont{codeLen: 1}
+	if dict != nil {
+		if isPDFName(dict["Subtype"], "Type0") {
+			font.codeLen = 2
+		}
+		if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+			if data, err := d.decodeStream(stream); err == nil {
+				font.cmap = parseCMap(data)
+				if font.cmap.codeLen > 0 {
+					font.codeLen = font.cmap.codeLen
+				}
+			}
+		}
+	}
+	d.fonts[key] = font
+	return font
+}
+
+// decode 将字符串按字体编码还原为文本，没有ToUnicode映射的简单字体按Latin-1处理
+func (f *pdfFont) decode(s []byte) string {
+	codeLen := 1
+	if f != nil && f.codeLen > 0 {
+		codeLen = f.codeLen
+	}
+
+	var out strings.Builder
+	for i := 0; i+codeLen <= len(s); i += codeLen {
+		var code uint32
+		for _, b := range s[i : i+codeLen] {
+			code = code<<8 | uint32(b)
+		}
+		if f != nil && f.cmap != nil {
+			if text, ok := f.cmap.lookup(code); ok {
+				out.WriteString(text)
+				continue
+			}
+		}
+		if code >= 0x20 && code != 0x7f {
+			out.WriteRune(rune(code))
+		}
+	}
+	return out.String()
+}
+
+// pdfCMap ToUnicode映射
+type pdfCMap struct {
+	codeLen int
+	chars   map[uint32]string
+	ranges  []cmapRange
+}
+
+// cmapRange bfrange映射：目标为起始文本（末字符递增）或逐个列出的文本
+type cmapRange struct {
+	lo, hi uint32
+	base   []rune
+	list   []string
+}
+
+// lookup 查找编码对应的文本
+func (c *pdfCMap) lookup(code uint32) (string, bool) {
+	if text, ok := c.chars[code]; ok {
+		return text, true
+	}
+	for _, r := range c.ranges {
+		if code < r.lo || code > r.hi {
+			continue
+		}
+		offset := code - r.lo
+		if r.list != nil {
+			if int(offset) < len(r.list) {
+				return r.list[offset], true
+			}
+			return "", false
+		}
+		if len(r.base) == 0 {
+			return "", false
+		}
+		text := append([]rune(nil), r.base...)
+		text[len(text)-1] += rune(offset)
+		return string(text), true
+	}
+	return "", false
+}
+
+// parseCMap 解析ToUnicode CMap中的codespacerange、bfchar和bfrange
+func parseCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{chars: make(map[uint32]string)}
+	lexer := &pdfLexer{data: data}
+
+	var operands []interface{}
+	section := ""
+	for {
+		obj, err := lexer.readObject()
+		if err != nil {
+			break
+		}
+		keyword, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch keyword {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = string(keyword)
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					cmap.codeLen = len(lo)
+				}
+			}
+			section = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					cmap.chars[bytesToCode(src)] = utf16BEString(dst)
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				r := cmapRange{lo: bytesToCode(lo), hi: bytesToCode(hi)}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					r.base = []rune(utf16BEString(dst))
+				case pdfArray:
+					r.list = make([]string, 0, len(dst))
+					for _, item := range dst {
+						s, _ := item.(pdfString)
+						r.list = append(r.list, utf16BEString(s))
+					}
+				}
+				cmap.ranges = append(cmap.ranges, r)
+			}
+			section = ""
+		}
+
+		// 映射段内的操作数需要保留到段结束
+		if section == "" || keyword == pdfKeyword(section) {
+			operands = operands[:0]
+		}
+	}
+	return cmap
+}
+
+func bytesToCode(b []byte) uint32 {
+	var code uint32
+	for _, c := range b {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+// utf16BEString 将UTF-16BE字节解码为字符串
+func utf16BEString(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// textWriter 收集提取的文本，避免重复的空格和换行
+type textWriter struct {
+	strings.Builder
+	pending string
+}
+
+func (w *textWriter) text(s string) {
+	if s == "" {
+		return
+	}
+	if w.Len() > 0 {
+		w.WriteString(w.pending)
+	}
+	w.pending = ""
+	w.WriteString(s)
+}
+
+func (w *textWriter) separate(sep string) {
+	if sep == "\n" || w.pending == "" {
+		w.pending = sep
+	}
+}
+
+// extractContentText 解析内容流中的文本操作符
+func extractContentText(content []byte, fonts func(pdfName) *pdfFont) string {
+	lexer := &pdfLexer{data: content}
+	var out textWriter
+	var operands []interface{}
+	var font *pdfFont
+	lastY := math.NaN()
+
+	for {
+		obj, err := lexer.readObject()
+		if err != nil {
+			break
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = fonts(name)
+				}
+			}
+		case "Tj", "'", "\"":
+			if op != "Tj" {
+				out.separate("\n")
+			}
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					out.text(font.decode(s))
+				}
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				if items, ok := operands[len(operands)-1].(pdfArray); ok {
+					for _, item := range items {
+						switch v := item.(type) {
+						case pdfString:
+							out.text(font.decode(v))
+						case float64:
+							// 较大的负间距表示单词间的空格
+							if v < -200 {
+								out.separate(" ")
+							}
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, ok := operands[len(operands)-1].(float64); ok && ty != 0 {
+					out.separate("\n")
+				} else {
+					out.separate(" ")
+				}
+			}
+		case "T*":
+			out.separate("\n")
+		case "Tm":
+			if len(operands) >= 6 {
+				if y, ok := operands[len(operands)-1].(float64); ok {
+					if y != lastY {
+						out.separate("\n")
+					} else {
+						out.separate(" ")
+					}
+					lastY = y
+				}
+			}
+		case "ET":
+			out.separate(" ")
+		case "ID":
+			lexer.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// pdfLexer PDF对象和内容流的词法分析器
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	refs  bool // 是否识别"num gen R"间接引用（内容流中关闭）
+	depth int
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace 跳过空白和注释
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFWhitespace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// readObject 读取下一个对象，关键字和操作符以pdfKeyword返回
+func (l *pdfLexer) readObject() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errPDFEOF
+	}
+
+	switch c := l.data[l.pos]; c {
+	case '/':
+		l.pos++
+		return l.readName(), nil
+	case '(':
+		l.pos++
+		return l.readLiteralString()
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return l.readDict()
+		}
+		l.pos++
+		return l.readHexString()
+	case '[':
+		l.pos++
+		return l.readArray()
+	case '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		l.pos++
+		return pdfKeyword(">"), nil
+	case ']', ')', '{', '}':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+
+	token := l.readToken()
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if strings.IndexByte("+-.0123456789", token[0]) < 0 {
+		return pdfKeyword(token), nil
+	}
+	number, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return pdfKeyword(token), nil
+	}
+	if l.refs {
+		if ref, ok := l.tryReadRef(token); ok {
+			return ref, nil
+		}
+	}
+	return number, nil
+}
+
+// tryReadRef 尝试把"num gen R"识别为间接引用，失败时恢复读取位置
+func (l *pdfLexer) tryReadRef(numToken string) (pdfRef, bool) {
+	num, err := strconv.Atoi(numToken)
+	if err != nil {
+		return pdfRef{}, false
+	}
+
+	saved := l.pos
+	l.skipSpace()
+	if l.pos < len(l.data) && !isPDFDelimiter(l.data[l.pos]) {
+		gen, err := strconv.Atoi(l.readToken())
+		l.skipSpace()
+		if err == nil && l.pos < len(l.data) && l.data[l.pos] == 'R' &&
+			(l.pos+1 == len(l.data) || isPDFWhitespace(l.data[l.pos+1]) || isPDFDelimiter(l.data[l.pos+1])) {
+			l.pos++
+			return pdfRef{num: num, gen: gen}, true
+		}
+	}
+	l.pos = saved
+	return pdfRef{}, false
+}
+
+// readToken 读取到下一个空白或分隔符为止的普通词
+func (l *pdfLexer) readToken() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// readName 读取名称对象，处理#xx转义
+func (l *pdfLexer) readName() pdfName {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	raw := l.data[start:l.pos]
+	if bytes.IndexByte(raw, '#') < 0 {
+		return pdfName(raw)
+	}
+
+	var name []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			hi, ok1 := hexValue(raw[i+1])
+			lo, ok2 := hexValue(raw[i+2])
+			if ok1 && ok2 {
+				name = append(name, hi<<4|lo)
+				i += 2
+				continue
+			}
+		}
+		name = append(name, raw[i])
+	}
+	return pdfName(name)
+}
+
+// readLiteralString 读取括号字符串，处理嵌套括号和转义
+func (l *pdfLexer) readLiteralString() (pdfString, error) {
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return nil, errPDFEOF
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// 行尾续行
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(value))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return nil, errPDFEOF
+}
+
+// readHexString 读取十六进制字符串
+func (l *pdfLexer) readHexString() (pdfString, error) {
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		return nil, errPDFEOF
+	}
+	s := hexBytes(l.data[l.pos : l.pos+end])
+	l.pos += end + 1
+	return s, nil
+}
+
+// readArray 读取数组直到']'
+func (l *pdfLexer) readArray() (pdfArray, error) {
+	if l.depth++; l.depth > maxPDFNesting {
+		return nil, errors.New("PDF objects nested too deeply")
+	}
+	defer func() { l.depth-- }()
+
+	array := pdfArray{}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return nil, errPDFEOF
+		}
+		if l.data[l.pos] == ']' {
+			l.pos++
+			return array, nil
+		}
+		obj, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, obj)
+	}
+}
+
+// readDict 读取字典直到'>>'
+func (l *pdfLexer) readDict() (pdfDict, error) {
+	if l.depth++; l.depth > maxPDFNesting {
+		return nil, errors.New("PDF objects nested too deeply")
+	}
+	defer func() { l.depth-- }()
+
+	dict := pdfDict{}
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return dict, nil
+		}
+		key, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, fmt.Errorf("invalid dictionary key %v", key)
+		}
+		value, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		dict[name] = value
+	}
+}
+
+// skipInlineImage 跳过内联图像（ID与EI之间的二进制数据）
+func (l *pdfLexer) skipInlineImage() {
+	for l.pos+2 < len(l.data) {
+		if isPDFWhitespace(l.data[l.pos]) && l.data[l.pos+1] == 'E' && l.data[l.pos+2] == 'I' &&
+			(l.pos+3 == len(l.data) || isPDFWhitespace(l.data[l.pos+3])) {
+			l.pos += 3
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
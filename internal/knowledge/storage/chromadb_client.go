@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ynl/greensoulai/internal/llm"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
@@ -124,10 +125,8 @@ func NewChromaDBClient(config *ChromaDBConfig, log logger.Logger) *ChromaDBClien
 	baseURL := fmt.Sprintf("http://%s:%d/api/%s", config.Host, config.Port, config.APIVersion)
 
 	client := &ChromaDBClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		baseURL:    baseURL,
+		httpClient: llm.NewSharedHTTPClient(config.Timeout),
 		logger:     log,
 		apiVersion: config.APIVersion,
 	}
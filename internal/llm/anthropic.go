@@ -0,0 +1,759 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const (
+	// Anthropic API constants
+	defaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	anthropicMessagesEndpoint = "/messages"
+	anthropicAPIVersion       = "2023-06-01"
+
+	// max_tokens is required by the Messages API
+	defaultAnthropicMaxTokens = 4096
+)
+
+// Anthropic model context windows, matched by model name prefix
+var anthropicContextWindows = map[string]int{
+	"claude-opus-4":     200000,
+	"claude-sonnet-4":   200000,
+	"claude-3-7-sonnet": 200000,
+	"claude-3-5-sonnet": 200000,
+	"claude-3-5-haiku":  200000,
+	"claude-3-opus":     200000,
+	"claude-3-sonnet":   200000,
+	"claude-3-haiku":    200000,
+	"claude-2.1":        200000,
+	"claude-2.0":        100000,
+	"claude-instant":    100000,
+}
+
+// AnthropicLLM represents an Anthropic Claude LLM instance
+type AnthropicLLM struct {
+	*BaseLLM
+}
+
+// AnthropicRequest represents the request structure for the Anthropic Messages API
+type AnthropicRequest struct {
+	Model         string             `json:"model"`
+	Messages      []AnthropicMessage `json:"messages"`
+	System        string             `json:"system,omitempty"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Tools         []AnthropicTool    `json:"tools,omitempty"`
+	ToolChoice    interface{}        `json:"tool_choice,omitempty"`
+	Metadata      map[string]string  `json:"metadata,omitempty"`
+}
+
+// AnthropicMessage represents a message in Anthropic format
+type AnthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []AnthropicContentBlock `json:"content"`
+}
+
+// AnthropicContentBlock represents a text, tool_use or tool_result content block
+type AnthropicContentBlock struct {
+	Type      string                 `json:"type"`
+	Text      string                 `json:"text,omitempty"`
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Input     map[string]interface{} `json:"input,omitempty"`
+	ToolUseID string                 `json:"tool_use_id,omitempty"`
+	Content   string                 `json:"content,omitempty"`
+}
+
+// MarshalJSON encodes only the fields that belong to the block type.
+// tool_use blocks always carry an input object, even when it is empty.
+func (b AnthropicContentBlock) MarshalJSON() ([]byte, error) {
+	switch b.Type {
+	case "text":
+		return json.Marshal(map[string]interface{}{"type": b.Type, "text": b.Text})
+	case "tool_use":
+		input := b.Input
+		if input == nil {
+			input = map[string]interface{}{}
+		}
+		return json.Marshal(map[string]interface{}{"type": b.Type, "id": b.ID, "name": b.Name, "input": input})
+	case "tool_result":
+		return json.Marshal(map[string]interface{}{"type": b.Type, "tool_use_id": b.ToolUseID, "content": b.Content})
+	default:
+		type block AnthropicContentBlock
+		return json.Marshal(block(b))
+	}
+}
+
+// AnthropicTool represents a tool in Anthropic format
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// AnthropicResponse represents the response structure for the Anthropic Messages API
+type AnthropicResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []AnthropicContentBlock `json:"content"`
+	StopReason   string                  `json:"stop_reason"`
+	StopSequence string                  `json:"stop_sequence,omitempty"`
+	Usage        AnthropicUsage          `json:"usage"`
+	Error        *AnthropicError         `json:"error,omitempty"`
+}
+
+// AnthropicUsage represents usage information in Anthropic response
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// AnthropicError represents an error from Anthropic API
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// anthropicStreamEvent represents a server-sent event from the streaming API
+type anthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	Message      *AnthropicResponse     `json:"message,omitempty"`
+	ContentBlock *AnthropicContentBlock `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text,omitempty"`
+		PartialJSON string `json:"partial_json,omitempty"`
+		StopReason  string `json:"stop_reason,omitempty"`
+	} `json:"delta,omitempty"`
+	Usage *AnthropicUsage `json:"usage,omitempty"`
+	Error *AnthropicError `json:"error,omitempty"`
+}
+
+// anthropicContextWindow returns the context window for a model, defaulting to 200K
+func anthropicContextWindow(model string) int {
+	if window, exists := anthropicContextWindows[model]; exists {
+		return window
+	}
+
+	// Longest prefix wins, so dated model IDs resolve to their family
+	prefixes := make([]string, 0, len(anthropicContextWindows))
+	for prefix := range anthropicContextWindows {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return anthropicContextWindows[prefix]
+		}
+	}
+
+	return 200000
+}
+
+// NewAnthropicLLM creates a new Anthropic LLM instance
+func NewAnthropicLLM(model string, options ...BaseLLMOption) *AnthropicLLM {
+	defaultOptions := []BaseLLMOption{
+		WithContextWindow(anthropicContextWindow(model)),
+		WithFunctionCalling(true),
+	}
+
+	// Apply user options first, then defaults
+	allOptions := append(options, defaultOptions...)
+
+	// Add default base URL only if not already set
+	allOptions = append(allOptions, func(b *BaseLLM) {
+		if b.baseURL == "" {
+			WithBaseURL(defaultAnthropicBaseURL)(b)
+		}
+	})
+
+	return &AnthropicLLM{
+		BaseLLM: NewBaseLLM("anthropic", model, allOptions...),
+	}
+}
+
+// Call sends a synchronous request to the Anthropic API
+func (a *AnthropicLLM) Call(ctx context.Context, messages []Message, options *CallOptions) (result *Response, err error) {
+	ctx, telemetry := a.startCall(ctx, messages, options, false)
+	defer func() { telemetry.finish(result, err) }()
+
+	request, err := a.prepareRequest(messages, options)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := a.makeAPICall(ctx, request)
+	if err != nil {
+		a.LogError("Anthropic API call failed",
+			logger.Field{Key: "model", Value: a.GetModel()},
+			logger.Field{Key: "error", Value: err},
+		)
+		return nil, err
+	}
+
+	result = a.convertResponse(response)
+
+	a.LogDebug("Anthropic API call completed",
+		logger.Field{Key: "model", Value: a.GetModel()},
+		logger.Field{Key: "usage", Value: result.Usage},
+	)
+
+	return result, nil
+}
+
+// CallStream sends a streaming request to the Anthropic API
+func (a *AnthropicLLM) CallStream(ctx context.Context, messages []Message, options *CallOptions) (stream <-chan StreamResponse, err error) {
+	ctx, telemetry := a.startCall(ctx, messages, options, true)
+	defer func() {
+		if err != nil {
+			telemetry.finish(nil, err)
+		}
+	}()
+
+	request, err := a.prepareRequest(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = true
+
+	responseChannel := make(chan StreamResponse, 100)
+	go a.streamAPICall(ctx, request, responseChannel)
+
+	return telemetry.observe(responseChannel), nil
+}
+
+// prepareRequest validates inputs and builds an Anthropic request
+func (a *AnthropicLLM) prepareRequest(messages []Message, options *CallOptions) (*AnthropicRequest, error) {
+	if err := a.ValidateMessages(messages); err != nil {
+		return nil, fmt.Errorf("invalid messages: %w", err)
+	}
+
+	if err := a.ValidateCallOptions(options); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	// Image content parts are only serialized by the OpenAI client
+	if err := checkVision(a.GetModel(), false, messages); err != nil {
+		return nil, err
+	}
+
+	system, anthropicMessages := convertAnthropicMessages(messages)
+	if len(anthropicMessages) == 0 {
+		return nil, fmt.Errorf("invalid messages: at least one non-system message is required")
+	}
+
+	return a.buildRequest(system, anthropicMessages, options), nil
+}
+
+// convertAnthropicMessages converts internal messages to Anthropic format.
+// System messages become the system parameter, tool messages become tool_result blocks,
+// and consecutive messages with the same role are merged as the API requires alternation.
+func convertAnthropicMessages(messages []Message) (string, []AnthropicMessage) {
+	var systemParts []string
+	var result []AnthropicMessage
+
+	// Tool use IDs requested by the latest assistant message and not yet answered
+	var pendingToolUses []AnthropicContentBlock
+
+	appendBlocks := func(role string, blocks []AnthropicContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, blocks...)
+			return
+		}
+		result = append(result, AnthropicMessage{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages {
+		text := messageText(msg.Content)
+
+		switch msg.Role {
+		case RoleSystem:
+			if text != "" {
+				systemParts = append(systemParts, text)
+			}
+
+		case RoleAssistant:
+			var blocks []AnthropicContentBlock
+			if text != "" {
+				blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: text})
+			}
+			pendingToolUses = nil
+			for _, tc := range msg.ToolCalls {
+				block := AnthropicContentBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: toolCallInput(tc),
+				}
+				blocks = append(blocks, block)
+				pendingToolUses = append(pendingToolUses, block)
+			}
+			appendBlocks("assistant", blocks)
+
+		case RoleTool:
+			toolUseID := msg.ToolCallID
+			if toolUseID == "" {
+				// Fall back to the oldest pending tool_use with the same name
+				for _, pending := range pendingToolUses {
+					if pending.Name == msg.Name {
+						toolUseID = pending.ID
+						break
+					}
+				}
+			}
+			for i, pending := range pendingToolUses {
+				if pending.ID == toolUseID {
+					pendingToolUses = append(pendingToolUses[:i], pendingToolUses[i+1:]...)
+					break
+				}
+			}
+
+			if toolUseID == "" {
+				appendBlocks("user", []AnthropicContentBlock{{
+					Type: "text",
+					Text: fmt.Sprintf("Tool result (%s): %s", msg.Name, text),
+				}})
+				continue
+			}
+			appendBlocks("user", []AnthropicContentBlock{{
+				Type:      "tool_result",
+				ToolUseID: toolUseID,
+				Content:   text,
+			}})
+
+		default:
+			if text != "" {
+				appendBlocks("user", []AnthropicContentBlock{{Type: "text", Text: text}})
+			}
+		}
+	}
+
+	return strings.Join(systemParts, "\n\n"), result
+}
+
+// messageText extracts text from message content; multi-part content yields its text parts only
+func messageText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case []ContentPart:
+		texts := make([]string, 0, len(v))
+		for _, part := range v {
+			if part.Type == ContentPartText {
+				texts = append(texts, part.Text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
+
+// toolCallInput returns the tool call arguments as an object
+func toolCallInput(tc ToolCall) map[string]interface{} {
+	if tc.Args != nil {
+		return tc.Args
+	}
+	input := make(map[string]interface{})
+	if tc.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(tc.Function.Arguments), &input); err != nil {
+			input = map[string]interface{}{"input": tc.Function.Arguments}
+		}
+	}
+	return input
+}
+
+// buildRequest builds an Anthropic request
+func (a *AnthropicLLM) buildRequest(system string, messages []AnthropicMessage, options *CallOptions) *AnthropicRequest {
+	request := &AnthropicRequest{
+		Model:     a.GetModel(),
+		Messages:  messages,
+		System:    system,
+		MaxTokens: defaultAnthropicMaxTokens,
+	}
+
+	if options == nil {
+		return request
+	}
+
+	if options.MaxTokens != nil {
+		request.MaxTokens = *options.MaxTokens
+	} else if options.MaxCompletionTokens != nil {
+		request.MaxTokens = *options.MaxCompletionTokens
+	}
+	request.Temperature = options.Temperature
+	request.TopP = options.TopP
+	request.StopSequences = options.StopSequences
+	request.Stream = options.Stream
+	if options.User != "" {
+		request.Metadata = map[string]string{"user_id": options.User}
+	}
+
+	if len(options.Tools) > 0 {
+		request.Tools = make([]AnthropicTool, len(options.Tools))
+		for i, tool := range options.Tools {
+			schema := tool.Function.Parameters
+			if schema == nil {
+				schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+			}
+			request.Tools[i] = AnthropicTool{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				InputSchema: schema,
+			}
+		}
+		request.ToolChoice = convertAnthropicToolChoice(options.ToolChoice)
+	}
+
+	return request
+}
+
+// convertAnthropicToolChoice maps OpenAI-style tool_choice values to Anthropic format
+func convertAnthropicToolChoice(choice interface{}) interface{} {
+	switch v := choice.(type) {
+	case nil:
+		return nil
+	case string:
+		switch v {
+		case "auto":
+			return map[string]interface{}{"type": "auto"}
+		case "none":
+			return map[string]interface{}{"type": "none"}
+		case "required", "any":
+			return map[string]interface{}{"type": "any"}
+		default:
+			return map[string]interface{}{"type": "tool", "name": v}
+		}
+	case map[string]interface{}:
+		// {"type": "function", "function": {"name": "..."}}
+		if function, ok := v["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok {
+				return map[string]interface{}{"type": "tool", "name": name}
+			}
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// newHTTPRequest creates an HTTP request with Anthropic headers
+func (a *AnthropicLLM) newHTTPRequest(ctx context.Context, body []byte, stream bool) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.GetBaseURL()+anthropicMessagesEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", a.GetAPIKey())
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	for key, value := range a.GetCustomHeaders() {
+		httpReq.Header.Set(key, value)
+	}
+
+	return httpReq, nil
+}
+
+// makeAPICall makes a synchronous API call to Anthropic
+func (a *AnthropicLLM) makeAPICall(ctx context.Context, request *AnthropicRequest) (*AnthropicResponse, error) {
+	bodyBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make request with retries on transport errors, 429, 529 (overloaded) and 5xx
+	var response *http.Response
+	var lastErr error
+
+	maxRetries := a.transportRetries(ctx)
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		httpReq, err := a.newHTTPRequest(ctx, bodyBytes, false)
+		if err != nil {
+			return nil, err
+		}
+
+		response, lastErr = a.doHTTP(httpReq)
+		observeHTTPAttempt(ctx, attempt, response)
+		if lastErr == nil && response.StatusCode != http.StatusTooManyRequests && response.StatusCode < 500 {
+			break
+		}
+
+		if attempt < maxRetries {
+			if lastErr == nil {
+				response.Body.Close()
+			}
+			waitTime := time.Duration(attempt+1) * time.Second
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(waitTime):
+			}
+		}
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("HTTP request failed after %d retries: %w", maxRetries, lastErr)
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			a.logger.Error("Failed to close response body",
+				logger.Field{Key: "error", Value: err})
+		}
+	}()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var anthropicResponse AnthropicResponse
+	if err := json.Unmarshal(responseBody, &anthropicResponse); err != nil {
+		if response.StatusCode >= 400 {
+			return nil, fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(responseBody))
+		}
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if anthropicResponse.Error != nil {
+		return nil, fmt.Errorf("Anthropic API error: %s (type: %s, status: %d)",
+			anthropicResponse.Error.Message,
+			anthropicResponse.Error.Type,
+			response.StatusCode)
+	}
+
+	if response.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	return &anthropicResponse, nil
+}
+
+// streamAPICall handles streaming API calls using server-sent events
+func (a *AnthropicLLM) streamAPICall(ctx context.Context, request *AnthropicRequest, responseChannel chan<- StreamResponse) {
+	defer close(responseChannel)
+
+	bodyBytes, err := json.Marshal(request)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("failed to marshal request: %w", err)}
+		return
+	}
+
+	httpReq, err := a.newHTTPRequest(ctx, bodyBytes, true)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: err}
+		return
+	}
+
+	response, err := a.doHTTP(httpReq)
+	observeHTTPAttempt(ctx, 0, response)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("HTTP request failed: %w", err)}
+		return
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			a.logger.Error("Failed to close response body",
+				logger.Field{Key: "error", Value: err})
+		}
+	}()
+
+	if response.StatusCode >= 400 {
+		body, _ := io.ReadAll(response.Body)
+		responseChannel <- StreamResponse{Error: fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(body))}
+		return
+	}
+
+	var usage Usage
+	var finishReason string
+	var toolCalls []ToolCall
+	var toolInputs []*strings.Builder
+	blockToolIndex := make(map[int]int) // content block index -> toolCalls index
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			a.LogError("Failed to parse streaming event",
+				logger.Field{Key: "data", Value: data},
+				logger.Field{Key: "error", Value: err},
+			)
+			continue
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage.PromptTokens = event.Message.Usage.InputTokens
+				usage.CompletionTokens = event.Message.Usage.OutputTokens
+			}
+
+		case "content_block_start":
+			if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+				blockToolIndex[event.Index] = len(toolCalls)
+				toolCalls = append(toolCalls, ToolCall{
+					ID:       event.ContentBlock.ID,
+					Type:     "function",
+					Function: ToolCallFunction{Name: event.ContentBlock.Name},
+				})
+				toolInputs = append(toolInputs, &strings.Builder{})
+			} else if event.ContentBlock != nil && event.ContentBlock.Text != "" {
+				responseChannel <- StreamResponse{Delta: event.ContentBlock.Text}
+			}
+
+		case "content_block_delta":
+			if event.Delta == nil {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				responseChannel <- StreamResponse{Delta: event.Delta.Text}
+			case "input_json_delta":
+				if i, ok := blockToolIndex[event.Index]; ok {
+					toolInputs[i].WriteString(event.Delta.PartialJSON)
+				}
+			}
+
+		case "message_delta":
+			if event.Delta != nil && event.Delta.StopReason != "" {
+				finishReason = convertAnthropicStopReason(event.Delta.StopReason)
+			}
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
+			}
+
+		case "message_stop":
+			for i := range toolCalls {
+				toolCalls[i].Function.Arguments = toolInputs[i].String()
+				if toolCalls[i].Function.Arguments == "" {
+					toolCalls[i].Function.Arguments = "{}"
+				}
+				toolCalls[i].Args = toolCallInput(toolCalls[i])
+			}
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+			usage.Cost = a.calculateCost(a.GetModel(), usage)
+			responseChannel <- StreamResponse{
+				FinishReason: finishReason,
+				Usage:        &usage,
+				ToolCalls:    toolCalls,
+			}
+			return
+
+		case "error":
+			message := "unknown error"
+			if event.Error != nil {
+				message = fmt.Sprintf("%s (type: %s)", event.Error.Message, event.Error.Type)
+			}
+			responseChannel <- StreamResponse{Error: fmt.Errorf("Anthropic API error: %s", message)}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			responseChannel <- StreamResponse{Error: ctx.Err()}
+			return
+		default:
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("scanner error: %w", err)}
+	}
+}
+
+// convertAnthropicStopReason maps Anthropic stop reasons to OpenAI-style finish reasons
+func convertAnthropicStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return reason
+	}
+}
+
+// convertResponse converts Anthropic response to internal format
+func (a *AnthropicLLM) convertResponse(response *AnthropicResponse) *Response {
+	usage := Usage{
+		PromptTokens:     response.Usage.InputTokens,
+		CompletionTokens: response.Usage.OutputTokens,
+		TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
+	}
+	usage.Cost = a.calculateCost(response.Model, usage)
+
+	result := &Response{
+		Usage:        usage,
+		Model:        response.Model,
+		FinishReason: convertAnthropicStopReason(response.StopReason),
+		Metadata: map[string]interface{}{
+			"id":          response.ID,
+			"stop_reason": response.StopReason,
+		},
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			input := block.Input
+			if input == nil {
+				input = make(map[string]interface{})
+			}
+			arguments, _ := json.Marshal(input)
+			result.ToolCalls = append(result.ToolCalls, ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: ToolCallFunction{
+					Name:      block.Name,
+					Arguments: string(arguments),
+				},
+				Args: input,
+			})
+		}
+	}
+	result.Content = text.String()
+
+	return result
+}
@@ -0,0 +1,333 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewAnthropicLLM(t *testing.T) {
+	llm := NewAnthropicLLM("claude-3-5-sonnet-20241022", WithAPIKey("test-key"))
+
+	if llm.GetProvider() != "anthropic" {
+		t.Errorf("Expected provider 'anthropic', got %s", llm.GetProvider())
+	}
+	if llm.GetBaseURL() != defaultAnthropicBaseURL {
+		t.Errorf("Expected base URL %s, got %s", defaultAnthropicBaseURL, llm.GetBaseURL())
+	}
+	if !llm.SupportsFunctionCalling() {
+		t.Error("Expected function calling support to be true")
+	}
+	if llm.GetContextWindowSize() != 200000 {
+		t.Errorf("Expected context window 200000, got %d", llm.GetContextWindowSize())
+	}
+
+	if window := NewAnthropicLLM("claude-instant-1.2").GetContextWindowSize(); window != 100000 {
+		t.Errorf("Expected context window 100000 for claude-instant, got %d", window)
+	}
+}
+
+func TestConvertAnthropicMessages(t *testing.T) {
+	messages := []Message{
+		{Role: RoleSystem, Content: "You are helpful."},
+		{Role: RoleSystem, Content: "Be brief."},
+		{Role: RoleUser, Content: "What's the weather in Paris and Rome?"},
+		{Role: RoleAssistant, Content: "Let me check.", ToolCalls: []ToolCall{
+			{ID: "toolu_1", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: `{"city":"Paris"}`}},
+			{ID: "toolu_2", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: `{"city":"Rome"}`}},
+		}},
+		{Role: RoleTool, Content: "Sunny", Name: "weather", ToolCallID: "toolu_1"},
+		{Role: RoleTool, Content: "Rainy", Name: "weather"},
+		{Role: RoleUser, Content: "Summarize."},
+	}
+
+	system, converted := convertAnthropicMessages(messages)
+	if system != "You are helpful.\n\nBe brief." {
+		t.Errorf("unexpected system prompt: %q", system)
+	}
+	if len(converted) != 3 {
+		t.Fatalf("expected 3 messages, got %d: %+v", len(converted), converted)
+	}
+
+	assistant := converted[1]
+	if assistant.Role != "assistant" || len(assistant.Content) != 3 {
+		t.Fatalf("unexpected assistant message: %+v", assistant)
+	}
+	if assistant.Content[1].Type != "tool_use" || assistant.Content[1].Input["city"] != "Paris" {
+		t.Errorf("unexpected tool_use block: %+v", assistant.Content[1])
+	}
+
+	// 工具结果与随后的用户消息合并为一条user消息
+	results := converted[2]
+	if results.Role != "user" || len(results.Content) != 3 {
+		t.Fatalf("unexpected tool result message: %+v", results)
+	}
+	if results.Content[0].Type != "tool_result" || results.Content[0].ToolUseID != "toolu_1" || results.Content[0].Content != "Sunny" {
+		t.Errorf("unexpected first tool result: %+v", results.Content[0])
+	}
+	if results.Content[1].ToolUseID != "toolu_2" {
+		t.Errorf("tool result without ID should match the pending tool_use, got %+v", results.Content[1])
+	}
+	if results.Content[2].Type != "text" || results.Content[2].Text != "Summarize." {
+		t.Errorf("unexpected trailing text block: %+v", results.Content[2])
+	}
+}
+
+func TestAnthropicLLM_BuildRequest(t *testing.T) {
+	llm := NewAnthropicLLM("claude-3-5-haiku-latest")
+	temperature := 0.3
+	options := &CallOptions{
+		Temperature:   &temperature,
+		StopSequences: []string{"END"},
+		Tools: []Tool{{Type: "function", Function: ToolSchema{
+			Name:        "search",
+			Description: "Search the web",
+			Parameters:  map[string]interface{}{"type": "object"},
+		}}},
+		ToolChoice: "required",
+	}
+
+	request := llm.buildRequest("system", nil, options)
+	if request.MaxTokens != defaultAnthropicMaxTokens {
+		t.Errorf("expected default max tokens, got %d", request.MaxTokens)
+	}
+	if len(request.Tools) != 1 || request.Tools[0].Name != "search" || request.Tools[0].InputSchema["type"] != "object" {
+		t.Errorf("unexpected tools: %+v", request.Tools)
+	}
+	if choice, ok := request.ToolChoice.(map[string]interface{}); !ok || choice["type"] != "any" {
+		t.Errorf("expected tool_choice any, got %v", request.ToolChoice)
+	}
+
+	data, err := json.Marshal(AnthropicContentBlock{Type: "tool_use", ID: "toolu_1", Name: "noop"})
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	if !strings.Contains(string(data), `"input":{}`) {
+		t.Errorf("tool_use block must include an input object, got %s", data)
+	}
+}
+
+func TestAnthropicLLM_Call_Success(t *testing.T) {
+	var captured AnthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != anthropicAPIVersion {
+			t.Errorf("missing Anthropic headers: %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &captured)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-3-5-sonnet-20241022",
+			"content": [
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 1000, "output_tokens": 200}
+		}`)
+	}))
+	defer server.Close()
+
+	llm := NewAnthropicLLM("claude-3-5-sonnet-20241022", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	response, err := llm.Call(context.Background(), []Message{
+		{Role: RoleSystem, Content: "Be helpful."},
+		{Role: RoleUser, Content: "Weather in Paris?"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if captured.System != "Be helpful." || len(captured.Messages) != 1 || captured.Messages[0].Role != "user" {
+		t.Errorf("unexpected request: %+v", captured)
+	}
+	if response.Content != "Checking." || response.FinishReason != "tool_calls" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].Function.Name != "weather" || response.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("unexpected tool calls: %+v", response.ToolCalls)
+	}
+	if response.Usage.PromptTokens != 1000 || response.Usage.CompletionTokens != 200 || response.Usage.TotalTokens != 1200 {
+		t.Errorf("unexpected usage: %+v", response.Usage)
+	}
+	if response.Usage.Cost <= 0 {
+		t.Errorf("expected cost to be calculated, got %f", response.Usage.Cost)
+	}
+}
+
+func TestAnthropicLLM_Call_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"type": "error", "error": {"type": "invalid_request_error", "message": "max_tokens too large"}}`)
+	}))
+	defer server.Close()
+
+	llm := NewAnthropicLLM("claude-3-haiku-20240307", WithBaseURL(server.URL), WithMaxRetries(0))
+	_, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "max_tokens too large") {
+		t.Errorf("expected API error, got %v", err)
+	}
+
+	if _, err := llm.Call(context.Background(), []Message{{Role: RoleSystem, Content: "Only system"}}, nil); err == nil {
+		t.Error("expected error when no user messages are present")
+	}
+}
+
+func TestAnthropicLLM_Call_WithoutTransportRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"type": "error", "error": {"type": "overloaded_error", "message": "overloaded"}}`)
+	}))
+	defer server.Close()
+
+	llm := NewAnthropicLLM("claude-3-haiku-20240307", WithBaseURL(server.URL), WithMaxRetries(3))
+	ctx := WithoutTransportRetries(context.Background())
+	if _, err := llm.Call(ctx, []Message{{Role: RoleUser, Content: "Hi"}}, nil); err == nil {
+		t.Fatal("expected error from unavailable server")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected a single HTTP request when transport retries are disabled, got %d", got)
+	}
+}
+
+func TestAnthropicLLM_CallStream_Success(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude-3-5-sonnet-20241022","usage":{"input_tokens":50,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"search","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"query\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"go\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request AnthropicRequest
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &request)
+		if !request.Stream {
+			t.Error("expected stream to be enabled")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var typed struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+	defer server.Close()
+
+	llm := NewAnthropicLLM("claude-3-5-sonnet-20241022", WithBaseURL(server.URL))
+	stream, err := llm.CallStream(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var content strings.Builder
+	var final StreamResponse
+	for chunk := range stream {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected stream error: %v", chunk.Error)
+		}
+		content.WriteString(chunk.Delta)
+		if chunk.FinishReason != "" {
+			final = chunk
+		}
+	}
+
+	if content.String() != "Hello world" {
+		t.Errorf("unexpected content: %q", content.String())
+	}
+	if final.FinishReason != "tool_calls" || final.Usage == nil || final.Usage.TotalTokens != 80 {
+		t.Errorf("unexpected final chunk: %+v", final)
+	}
+	if len(final.ToolCalls) != 1 || final.ToolCalls[0].Args["query"] != "go" {
+		t.Errorf("unexpected tool calls: %+v", final.ToolCalls)
+	}
+}
+
+func TestAnthropicLLM_CallStream_MultipleToolCalls(t *testing.T) {
+	// 第一个工具的输入在第二个工具开始后继续到达
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"search","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"query\":"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"fetch","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"go\"}"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"url\":\"https://go.dev\"}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	llm := NewAnthropicLLM("claude-3-5-sonnet-20241022", WithBaseURL(server.URL))
+	stream, err := llm.CallStream(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var final StreamResponse
+	for chunk := range stream {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected stream error: %v", chunk.Error)
+		}
+		if chunk.FinishReason != "" {
+			final = chunk
+		}
+	}
+
+	if len(final.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %+v", final.ToolCalls)
+	}
+	if final.ToolCalls[0].Args["query"] != "go" {
+		t.Errorf("unexpected first tool call: %+v", final.ToolCalls[0])
+	}
+	if final.ToolCalls[1].Args["url"] != "https://go.dev" {
+		t.Errorf("unexpected second tool call: %+v", final.ToolCalls[1])
+	}
+}
+
+func TestCreateLLMAnthropic(t *testing.T) {
+	llm, err := CreateLLM(&Config{Provider: "anthropic", Model: "claude-sonnet-4-20250514", APIKey: "key", MaxRetries: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	anthropicLLM, ok := llm.(*AnthropicLLM)
+	if !ok {
+		t.Fatalf("expected *AnthropicLLM, got %T", llm)
+	}
+	if anthropicLLM.GetAPIKey() != "key" || anthropicLLM.GetMaxRetries() != 1 {
+		t.Errorf("config not applied: key=%q retries=%d", anthropicLLM.GetAPIKey(), anthropicLLM.GetMaxRetries())
+	}
+
+	if _, err := CreateLLM(&Config{Provider: "anthropic"}); err == nil {
+		t.Error("expected error for missing model")
+	}
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -19,6 +20,7 @@ type BaseLLM struct {
 	timeout          time.Duration
 	maxRetries       int
 	client           *http.Client
+	transportConfig  *HTTPClientConfig
 	logger           logger.Logger
 	eventBus         events.EventBus
 	contextWindow    int
@@ -38,16 +40,8 @@ func NewBaseLLM(provider, model string, options ...BaseLLMOption) *BaseLLM {
 		maxRetries:       3,
 		contextWindow:    4096,
 		supportsFuncCall: false,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
-		logger:        logger.NewConsoleLogger(),
-		customHeaders: make(map[string]string),
+		logger:           logger.NewConsoleLogger(),
+		customHeaders:    make(map[string]string),
 	}
 
 	// Apply options
@@ -55,6 +49,16 @@ func NewBaseLLM(provider, model string, options ...BaseLLMOption) *BaseLLM {
 		option(b)
 	}
 
+	// An explicit client wins; transport options get a dedicated transport;
+	// everything else shares the package-level connection pool
+	if b.client == nil {
+		if b.transportConfig != nil {
+			b.client = NewHTTPClient(*b.transportConfig)
+		} else {
+			b.client = NewSharedHTTPClient(0)
+		}
+	}
+
 	return b
 }
 
@@ -72,13 +76,11 @@ func WithBaseURL(baseURL string) BaseLLMOption {
 	}
 }
 
-// WithTimeout sets the request timeout
+// WithTimeout sets the timeout of each HTTP attempt. It is applied as a request context
+// deadline, so the HTTP client (possibly shared with other LLMs) is never modified.
 func WithTimeout(timeout time.Duration) BaseLLMOption {
 	return func(b *BaseLLM) {
 		b.timeout = timeout
-		if b.client != nil {
-			b.client.Timeout = timeout
-		}
 	}
 }
 
@@ -89,13 +91,46 @@ func WithMaxRetries(maxRetries int) BaseLLMOption {
 	}
 }
 
-// WithHTTPClient sets the HTTP client
+// WithHTTPClient sets the HTTP client. It takes precedence over WithProxy, WithTLSConfig
+// and WithMaxIdleConns; a client Timeout still applies in addition to WithTimeout.
 func WithHTTPClient(client *http.Client) BaseLLMOption {
 	return func(b *BaseLLM) {
 		b.client = client
 	}
 }
 
+// WithProxy sends requests through the given proxy URL instead of the shared transport
+func WithProxy(proxyURL string) BaseLLMOption {
+	return func(b *BaseLLM) {
+		b.ensureTransportConfig().Proxy = proxyURL
+	}
+}
+
+// WithTLSConfig sets the TLS configuration, e.g. to trust a custom CA, instead of using the shared transport
+func WithTLSConfig(config *tls.Config) BaseLLMOption {
+	return func(b *BaseLLM) {
+		b.ensureTransportConfig().TLSConfig = config
+	}
+}
+
+// WithMaxIdleConns sets the idle connection pool size (overall and per host) instead of using the shared transport
+func WithMaxIdleConns(n int) BaseLLMOption {
+	return func(b *BaseLLM) {
+		config := b.ensureTransportConfig()
+		config.MaxIdleConns = n
+		config.MaxIdleConnsPerHost = n
+	}
+}
+
+// ensureTransportConfig starts a dedicated transport configuration from the shared settings
+func (b *BaseLLM) ensureTransportConfig() *HTTPClientConfig {
+	if b.transportConfig == nil {
+		config := SharedHTTPClientConfig()
+		b.transportConfig = &config
+	}
+	return b.transportConfig
+}
+
 // WithLogger sets the logger
 func WithLogger(logger logger.Logger) BaseLLMOption {
 	return func(b *BaseLLM) {
@@ -139,6 +174,13 @@ func WithCustomHeader(key, value string) BaseLLMOption {
 	}
 }
 
+// WithEventBus sets the event bus that receives the call started, completed and failed events
+func WithEventBus(eventBus events.EventBus) BaseLLMOption {
+	return func(b *BaseLLM) {
+		b.eventBus = eventBus
+	}
+}
+
 // GetModel returns the model identifier
 func (b *BaseLLM) GetModel() string {
 	return b.model
@@ -154,6 +196,12 @@ func (b *BaseLLM) GetContextWindowSize() int {
 	return b.contextWindow
 }
 
+// GetTokenizer returns the model's tokenizer, see TokenizerForModel.
+// OpenAI models count with ScriptHeuristicTokenizer until their BPE vocabulary is cached locally
+func (b *BaseLLM) GetTokenizer() Tokenizer {
+	return TokenizerForModel(b.model)
+}
+
 // SetEventBus sets the event bus for emitting events
 func (b *BaseLLM) SetEventBus(eventBus events.EventBus) {
 	b.eventBus = eventBus
@@ -190,6 +238,23 @@ func (b *BaseLLM) GetMaxRetries() int {
 	return b.maxRetries
 }
 
+// transportRetriesDisabledKey marks contexts whose HTTP requests must not be retried by providers
+type transportRetriesDisabledKey struct{}
+
+// WithoutTransportRetries returns a context that makes providers send each HTTP request once.
+// Callers that apply their own retry policy use it so that the two retry layers do not multiply.
+func WithoutTransportRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, transportRetriesDisabledKey{}, true)
+}
+
+// transportRetries returns the number of HTTP retries for a request made with ctx
+func (b *BaseLLM) transportRetries(ctx context.Context) int {
+	if disabled, _ := ctx.Value(transportRetriesDisabledKey{}).(bool); disabled {
+		return 0
+	}
+	return b.maxRetries
+}
+
 // GetHTTPClient returns the HTTP client
 func (b *BaseLLM) GetHTTPClient() *http.Client {
 	return b.client
@@ -255,7 +320,8 @@ func (b *BaseLLM) ValidateMessages(messages []Message) error {
 			return fmt.Errorf("message %d: role cannot be empty", i)
 		}
 
-		if msg.Content == nil {
+		// Assistant messages that only request tool calls have no content
+		if msg.Content == nil && !(msg.Role == RoleAssistant && len(msg.ToolCalls) > 0) {
 			return fmt.Errorf("message %d: content cannot be nil", i)
 		}
 
@@ -298,5 +364,15 @@ func (b *BaseLLM) ValidateCallOptions(options *CallOptions) error {
 		}
 	}
 
+	if options.ResponseFormat != nil {
+		format, err := ParseResponseFormat(options.ResponseFormat)
+		if err != nil {
+			return err
+		}
+		if err := format.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -188,6 +188,15 @@ func TestBaseLLM_ValidateMessages(t *testing.T) {
 			wantErr: true,
 			errMsg:  "content cannot be nil",
 		},
+		{
+			name: "assistant tool call message without content",
+			messages: []Message{
+				{Role: RoleUser, Content: "Weather?"},
+				{Role: RoleAssistant, Content: nil, ToolCalls: []ToolCall{{ID: "call_1", Function: ToolCallFunction{Name: "get_weather"}}}},
+				{Role: RoleTool, Content: "Sunny", ToolCallID: "call_1"},
+			},
+			wantErr: false,
+		},
 		{
 			name: "message with invalid role",
 			messages: []Message{
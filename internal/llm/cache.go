@@ -0,0 +1,337 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCache caches LLM responses keyed by CacheKey.
+// Implementations must be safe for concurrent use.
+type ResponseCache interface {
+	// Get returns the cached response and true on a hit
+	Get(ctx context.Context, key string) (*Response, bool, error)
+
+	// Set stores a response; ttl <= 0 uses the cache's default TTL
+	Set(ctx context.Context, key string, response *Response, ttl time.Duration) error
+
+	// Delete removes a cached response
+	Delete(ctx context.Context, key string) error
+
+	// Clear removes all cached responses
+	Clear(ctx context.Context) error
+}
+
+// cacheKeyPayload contains the request fields that determine a response
+type cacheKeyPayload struct {
+	Model               string      `json:"model"`
+	Messages            []Message   `json:"messages"`
+	Temperature         *float64    `json:"temperature,omitempty"`
+	MaxTokens           *int        `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int        `json:"max_completion_tokens,omitempty"`
+	TopP                *float64    `json:"top_p,omitempty"`
+	StopSequences       []string    `json:"stop,omitempty"`
+	Tools               []Tool      `json:"tools,omitempty"`
+	ToolChoice          interface{} `json:"tool_choice,omitempty"`
+	ResponseFormat      interface{} `json:"response_format,omitempty"`
+	Seed                *int        `json:"seed,omitempty"`
+}
+
+// CacheKey hashes the model, messages and response-affecting options into a cache key
+func CacheKey(model string, messages []Message, options *CallOptions) (string, error) {
+	payload := cacheKeyPayload{Model: model, Messages: messages}
+	if options != nil {
+		payload.Temperature = options.Temperature
+		payload.MaxTokens = options.MaxTokens
+		payload.MaxCompletionTokens = options.MaxCompletionTokens
+		payload.TopP = options.TopP
+		payload.StopSequences = options.StopSequences
+		payload.Tools = options.Tools
+		payload.ToolChoice = options.ToolChoice
+		payload.ResponseFormat = options.ResponseFormat
+		payload.Seed = options.Seed
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to build cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cloneResponse returns a copy so callers cannot mutate cached entries
+func cloneResponse(response *Response) *Response {
+	if response == nil {
+		return nil
+	}
+	clone := *response
+	if response.ToolCalls != nil {
+		clone.ToolCalls = make([]ToolCall, len(response.ToolCalls))
+		copy(clone.ToolCalls, response.ToolCalls)
+	}
+	if response.Metadata != nil {
+		clone.Metadata = make(map[string]interface{}, len(response.Metadata))
+		for k, v := range response.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+	return &clone
+}
+
+// expiryFor returns the expiry time for ttl, falling back to defaultTTL; zero means no expiry
+func expiryFor(ttl, defaultTTL time.Duration) time.Time {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// lruEntry is an element of LRUCache
+type lruEntry struct {
+	key       string
+	response  *Response
+	expiresAt time.Time
+}
+
+// LRUCache is an in-memory ResponseCache with least-recently-used eviction
+type LRUCache struct {
+	capacity   int
+	defaultTTL time.Duration
+	entries    map[string]*list.Element
+	order      *list.List // front is most recently used
+	mu         sync.Mutex
+}
+
+// NewLRUCache creates an in-memory cache holding at most capacity responses.
+// capacity <= 0 means unbounded; defaultTTL <= 0 means entries never expire.
+func NewLRUCache(capacity int, defaultTTL time.Duration) *LRUCache {
+	return &LRUCache{
+		capacity:   capacity,
+		defaultTTL: defaultTTL,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Get implements ResponseCache
+func (c *LRUCache) Get(ctx context.Context, key string) (*Response, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return cloneResponse(entry.response), true, nil
+}
+
+// Set implements ResponseCache
+func (c *LRUCache) Set(ctx context.Context, key string, response *Response, ttl time.Duration) error {
+	if response == nil {
+		return fmt.Errorf("cannot cache nil response")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{key: key, response: cloneResponse(response), expiresAt: expiryFor(ttl, c.defaultTTL)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Delete implements ResponseCache
+func (c *LRUCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// Clear implements ResponseCache
+func (c *LRUCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	return nil
+}
+
+// Len returns the number of cached responses, including expired entries not yet evicted
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// fileCacheEntry is the on-disk format of FileCache
+type fileCacheEntry struct {
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	Response  *Response `json:"response"`
+}
+
+// FileCache is a persistent ResponseCache storing one JSON file per key in a directory
+type FileCache struct {
+	dir        string
+	defaultTTL time.Duration
+	mu         sync.RWMutex
+}
+
+// NewFileCache creates a file-backed cache in dir, creating the directory if needed.
+// defaultTTL <= 0 means entries never expire.
+func NewFileCache(dir string, defaultTTL time.Duration) (*FileCache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileCache{dir: dir, defaultTTL: defaultTTL}, nil
+}
+
+// path returns the file path for key
+func (c *FileCache) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid cache key %q", key)
+	}
+	return filepath.Join(c.dir, key+".json"), nil
+}
+
+// Get implements ResponseCache
+func (c *FileCache) Get(ctx context.Context, key string) (*Response, bool, error) {
+	path, err := c.path(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.mu.RLock()
+	data, err := os.ReadFile(path)
+	c.mu.RUnlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	var entry fileCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		// Treat corrupt entries as misses and drop them
+		_ = c.Delete(ctx, key)
+		return nil, false, nil
+	}
+	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		_ = c.Delete(ctx, key)
+		return nil, false, nil
+	}
+
+	return entry.Response, true, nil
+}
+
+// Set implements ResponseCache
+func (c *FileCache) Set(ctx context.Context, key string, response *Response, ttl time.Duration) error {
+	if response == nil {
+		return fmt.Errorf("cannot cache nil response")
+	}
+	path, err := c.path(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fileCacheEntry{ExpiresAt: expiryFor(ttl, c.defaultTTL), Response: response})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Write to a temp file and rename so readers never see partial entries
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Delete implements ResponseCache
+func (c *FileCache) Delete(ctx context.Context, key string) error {
+	path, err := c.path(key)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clear implements ResponseCache
+func (c *FileCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list cache entries: %w", err)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete cache entry: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	messages := []Message{{Role: RoleUser, Content: "hello"}}
+	temperature := 0.2
+	maxTokens := 100
+
+	base, err := CacheKey("gpt-4o", messages, &CallOptions{Temperature: &temperature, MaxTokens: &maxTokens})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	same, _ := CacheKey("gpt-4o", []Message{{Role: RoleUser, Content: "hello"}}, &CallOptions{Temperature: &temperature, MaxTokens: &maxTokens, User: "ignored"})
+	if base != same {
+		t.Error("identical requests should produce the same key")
+	}
+
+	otherTemperature := 0.9
+	variants := map[string]struct {
+		model    string
+		messages []Message
+		options  *CallOptions
+	}{
+		"model":       {"gpt-4o-mini", messages, &CallOptions{Temperature: &temperature, MaxTokens: &maxTokens}},
+		"messages":    {"gpt-4o", []Message{{Role: RoleUser, Content: "bye"}}, &CallOptions{Temperature: &temperature, MaxTokens: &maxTokens}},
+		"temperature": {"gpt-4o", messages, &CallOptions{Temperature: &otherTemperature, MaxTokens: &maxTokens}},
+		"max tokens":  {"gpt-4o", messages, &CallOptions{Temperature: &temperature}},
+		"tools": {"gpt-4o", messages, &CallOptions{Temperature: &temperature, MaxTokens: &maxTokens,
+			Tools: []Tool{{Type: "function", Function: ToolSchema{Name: "search"}}}}},
+	}
+	for name, v := range variants {
+		key, err := CacheKey(v.model, v.messages, v.options)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if key == base {
+			t.Errorf("changing %s should change the key", name)
+		}
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2, 0)
+
+	cache.Set(ctx, "a", &Response{Content: "A"}, 0)
+	cache.Set(ctx, "b", &Response{Content: "B"}, 0)
+
+	// 访问a使b成为最久未使用的条目
+	if resp, ok, _ := cache.Get(ctx, "a"); !ok || resp.Content != "A" {
+		t.Fatalf("expected hit for a, got %v %v", resp, ok)
+	}
+	cache.Set(ctx, "c", &Response{Content: "C"}, 0)
+
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", cache.Len())
+	}
+
+	// 返回副本，修改不影响缓存内容
+	resp, _, _ := cache.Get(ctx, "c")
+	resp.Content = "mutated"
+	if resp, _, _ := cache.Get(ctx, "c"); resp.Content != "C" {
+		t.Errorf("cached entry was mutated: %q", resp.Content)
+	}
+
+	cache.Delete(ctx, "c")
+	if _, ok, _ := cache.Get(ctx, "c"); ok {
+		t.Error("expected c to be deleted")
+	}
+	cache.Clear(ctx)
+	if cache.Len() != 0 {
+		t.Errorf("expected empty cache, got %d", cache.Len())
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(0, time.Hour)
+
+	cache.Set(ctx, "short", &Response{Content: "short"}, 10*time.Millisecond)
+	cache.Set(ctx, "default", &Response{Content: "default"}, 0)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok, _ := cache.Get(ctx, "short"); ok {
+		t.Error("expected short-lived entry to expire")
+	}
+	if _, ok, _ := cache.Get(ctx, "default"); !ok {
+		t.Error("expected entry with default TTL to be cached")
+	}
+}
+
+func TestLRUCacheConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(50, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d", (i+j)%80)
+				cache.Set(ctx, key, &Response{Content: key}, 0)
+				if resp, ok, _ := cache.Get(ctx, key); ok && resp.Content != key {
+					t.Errorf("expected %q, got %q", key, resp.Content)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if cache.Len() > 50 {
+		t.Errorf("cache exceeded capacity: %d", cache.Len())
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := NewFileCache(dir, 0)
+	if err != nil {
+		t.Fatalf("failed to create file cache: %v", err)
+	}
+
+	key, _ := CacheKey("gpt-4o", []Message{{Role: RoleUser, Content: "hello"}}, nil)
+	response := &Response{Content: "hi", Model: "gpt-4o", Usage: Usage{TotalTokens: 7}}
+	if err := cache.Set(ctx, key, response, 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	// 新实例读取同一目录，验证持久化
+	reopened, err := NewFileCache(dir, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen file cache: %v", err)
+	}
+	cached, ok, err := reopened.Get(ctx, key)
+	if err != nil || !ok {
+		t.Fatalf("expected persisted hit, got ok=%v err=%v", ok, err)
+	}
+	if cached.Content != "hi" || cached.Usage.TotalTokens != 7 {
+		t.Errorf("unexpected cached response: %+v", cached)
+	}
+
+	if err := cache.Set(ctx, "expiring", response, 10*time.Millisecond); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok, _ := cache.Get(ctx, "expiring"); ok {
+		t.Error("expected expired entry to miss")
+	}
+
+	if _, _, err := cache.Get(ctx, "../escape"); err == nil {
+		t.Error("expected invalid key error")
+	}
+
+	if err := cache.Clear(ctx); err != nil {
+		t.Fatalf("failed to clear: %v", err)
+	}
+	if _, ok, _ := reopened.Get(ctx, key); ok {
+		t.Error("expected cleared cache to miss")
+	}
+}
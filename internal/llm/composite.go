@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ynl/greensoulai/pkg/events"
+)
+
+// providerOf returns the provider name of an LLM, or an empty string when it does not report one
+func providerOf(l LLM) string {
+	if p, ok := l.(interface{ GetProvider() string }); ok {
+		return p.GetProvider()
+	}
+	return ""
+}
+
+// compositeUsage accumulates the usage of the LLMs behind a composite LLM, keyed by the model that served each call
+type compositeUsage struct {
+	mu      sync.Mutex
+	byModel map[string]Usage
+}
+
+// record adds usage to model's total. A zero cost is priced with model's pricing,
+// so calls served by a substitute are charged at the substitute's prices
+func (u *compositeUsage) record(model string, usage Usage) Usage {
+	if usage.Cost == 0 {
+		usage.Cost = CalculateCost(model, usage)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.byModel == nil {
+		u.byModel = make(map[string]Usage)
+	}
+	total := u.byModel[model]
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+	total.Cost += usage.Cost
+	u.byModel[model] = total
+	return usage
+}
+
+// Usage returns the usage of all calls made through the composite
+func (u *compositeUsage) Usage() Usage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var total Usage
+	for _, usage := range u.byModel {
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+		total.TotalTokens += usage.TotalTokens
+		total.Cost += usage.Cost
+	}
+	return total
+}
+
+// UsageByModel returns the usage of the calls made through the composite per model that served them
+func (u *compositeUsage) UsageByModel() map[string]Usage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	byModel := make(map[string]Usage, len(u.byModel))
+	for model, usage := range u.byModel {
+		byModel[model] = usage
+	}
+	return byModel
+}
+
+// recordResponse sets the model that served the call on response when the provider left it
+// empty, prices its usage and adds it to the totals
+func (u *compositeUsage) recordResponse(l LLM, response *Response) *Response {
+	if response == nil {
+		return nil
+	}
+	if response.Model == "" {
+		response.Model = l.GetModel()
+	}
+	response.Usage = u.record(response.Model, response.Usage)
+	return response
+}
+
+// recordStream relays a stream served by l, recording its final usage once the stream ends
+func (u *compositeUsage) recordStream(l LLM, first *StreamResponse, stream <-chan StreamResponse) <-chan StreamResponse {
+	relay := make(chan StreamResponse, cap(stream)+1)
+	go func() {
+		defer close(relay)
+
+		var usage *Usage
+		forward := func(chunk StreamResponse) {
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			relay <- chunk
+		}
+		if first != nil {
+			forward(*first)
+		}
+		for chunk := range stream {
+			forward(chunk)
+		}
+		if usage != nil {
+			u.record(l.GetModel(), *usage)
+		}
+	}()
+	return relay
+}
+
+// setEventBus sets the event bus on every LLM
+func setEventBus(llms []LLM, eventBus events.EventBus) {
+	for _, l := range llms {
+		l.SetEventBus(eventBus)
+	}
+}
+
+// closeAll closes every LLM and joins their errors
+func closeAll(llms []LLM) error {
+	var errs []error
+	for _, l := range llms {
+		if err := l.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// emitEvent emits event on eventBus when one is set
+func emitEvent(ctx context.Context, eventBus events.EventBus, event events.Event) {
+	if eventBus != nil {
+		_ = eventBus.Emit(ctx, nil, event)
+	}
+}
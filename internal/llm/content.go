@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrVisionNotSupported is returned when a message contains images and the model cannot accept image inputs
+var ErrVisionNotSupported = errors.New("image inputs not supported")
+
+// ContentPartType is the type of a multi-part message content part
+type ContentPartType string
+
+const (
+	ContentPartText  ContentPartType = "text"
+	ContentPartImage ContentPartType = "image_url"
+)
+
+// ImageDetail controls how much detail a vision model uses for an image, trading accuracy for image tokens
+type ImageDetail string
+
+const (
+	ImageDetailAuto ImageDetail = "auto"
+	ImageDetailLow  ImageDetail = "low"
+	ImageDetailHigh ImageDetail = "high"
+)
+
+// ContentPart is one part of a multi-part message.
+// Message.Content holds a []ContentPart to send text together with images;
+// the JSON form matches the OpenAI chat completions content parts
+type ContentPart struct {
+	Type     ContentPartType `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *ImageURL       `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by http(s) URL or by a base64 data URL
+type ImageURL struct {
+	URL    string      `json:"url"`
+	Detail ImageDetail `json:"detail,omitempty"`
+}
+
+// TextPart returns a text content part
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: ContentPartText, Text: text}
+}
+
+// ImageURLPart returns an image content part referencing an http(s) or data URL
+func ImageURLPart(url string, detail ImageDetail) ContentPart {
+	return ContentPart{Type: ContentPartImage, ImageURL: &ImageURL{URL: url, Detail: detail}}
+}
+
+// ImageDataPart returns an image content part embedding the image as a base64 data URL
+func ImageDataPart(mimeType string, data []byte, detail ImageDetail) ContentPart {
+	return ImageURLPart("data:"+mimeType+";base64,"+base64.StdEncoding.EncodeToString(data), detail)
+}
+
+// ImageFilePart reads an image file and returns it as an embedded image content part.
+// The MIME type comes from the file extension, or from the content when the extension is unknown
+func ImageFilePart(path string, detail ImageDetail) (ContentPart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContentPart{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	if !strings.HasPrefix(mimeType, "image/") {
+		return ContentPart{}, fmt.Errorf("%s is not an image (%s)", path, mimeType)
+	}
+	return ImageDataPart(mimeType, data, detail), nil
+}
+
+// IsImageURL reports whether source is an http(s) or data URL rather than a file path
+func IsImageURL(source string) bool {
+	lower := strings.ToLower(source)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "data:")
+}
+
+// ContentText returns the text of a message content: the string itself, or the text parts of a multi-part content
+func ContentText(content interface{}) string {
+	return messageText(content)
+}
+
+// HasImages reports whether any message contains an image part
+func HasImages(messages []Message) bool {
+	for _, msg := range messages {
+		if len(imageParts(msg.Content)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// imageParts returns the image parts of a message content
+func imageParts(content interface{}) []ContentPart {
+	parts, ok := content.([]ContentPart)
+	if !ok {
+		return nil
+	}
+	var images []ContentPart
+	for _, part := range parts {
+		if part.Type == ContentPartImage && part.ImageURL != nil {
+			images = append(images, part)
+		}
+	}
+	return images
+}
+
+// checkVision rejects messages with images for models that cannot accept them
+func checkVision(model string, supportsVision bool, messages []Message) error {
+	if !supportsVision && HasImages(messages) {
+		return fmt.Errorf("%w: model %s does not accept images", ErrVisionNotSupported, model)
+	}
+	return nil
+}
+
+// estimatedImageTokens approximates the prompt tokens of an image: OpenAI charges 85 tokens for
+// low detail and 85 plus 170 per 512px tile otherwise, 765 for a typical 1024x1024 image
+func estimatedImageTokens(part ContentPart) int {
+	if part.ImageURL.Detail == ImageDetailLow {
+		return 85
+	}
+	return 765
+}
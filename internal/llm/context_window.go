@@ -0,0 +1,393 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens of a text for a model
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface
+type TokenizerFunc func(text string) int
+
+// CountTokens implements Tokenizer
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// HeuristicTokenizer estimates one token per four characters, used when no model tokenizer is available
+type HeuristicTokenizer struct{}
+
+// CountTokens implements Tokenizer
+func (HeuristicTokenizer) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// messageTokenOverhead approximates the role and formatting tokens every message adds
+const messageTokenOverhead = 4
+
+// minTruncatedTokens is the smallest size middle-out truncation shrinks a message to
+const minTruncatedTokens = 64
+
+// EstimateMessageTokens estimates the prompt tokens of a message, including its tool calls and images.
+// A MessageCounter counts the message without the conversation-level overhead
+func EstimateMessageTokens(tokenizer Tokenizer, msg Message) int {
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+	if counter, ok := tokenizer.(MessageCounter); ok {
+		return counter.CountMessages([]Message{msg}) - counter.CountMessages(nil)
+	}
+	tokens := messageTokenOverhead + tokenizer.CountTokens(messageText(msg.Content)) + EstimateImageTokens(msg.Content)
+	for _, tc := range msg.ToolCalls {
+		tokens += tokenizer.CountTokens(tc.Function.Name) + tokenizer.CountTokens(tc.Function.Arguments)
+	}
+	return tokens
+}
+
+// EstimateTokens estimates the prompt tokens of a conversation, delegating to the tokenizer when it is a MessageCounter
+func EstimateTokens(tokenizer Tokenizer, messages []Message) int {
+	if counter, ok := tokenizer.(MessageCounter); ok {
+		return counter.CountMessages(messages)
+	}
+	total := 0
+	for _, msg := range messages {
+		total += EstimateMessageTokens(tokenizer, msg)
+	}
+	return total
+}
+
+// ContextBudget returns the prompt token budget of a call: the context window minus the completion reserve.
+// A reserved value <= 0 uses the call's max tokens, capped at half of the window
+func ContextBudget(contextWindow int, options *CallOptions, reserved int) int {
+	if reserved <= 0 && options != nil {
+		if options.MaxCompletionTokens != nil {
+			reserved = *options.MaxCompletionTokens
+		} else if options.MaxTokens != nil {
+			reserved = *options.MaxTokens
+		}
+		if reserved > contextWindow/2 {
+			reserved = contextWindow / 2
+		}
+	}
+	if reserved < 0 {
+		reserved = 0
+	}
+	return contextWindow - reserved
+}
+
+// ErrContextWindowExceeded matches every ContextWindowExceededError
+var ErrContextWindowExceeded = errors.New("context window exceeded")
+
+// ContextWindowExceededError reports a prompt that does not fit into the model's context window
+type ContextWindowExceededError struct {
+	Needed    int // estimated prompt tokens
+	Available int // prompt tokens the window leaves after the completion reserve
+}
+
+func (e *ContextWindowExceededError) Error() string {
+	return fmt.Sprintf("context window exceeded: needed %d tokens, have %d", e.Needed, e.Available)
+}
+
+// Is makes errors.Is(err, ErrContextWindowExceeded) match
+func (e *ContextWindowExceededError) Is(target error) bool {
+	return target == ErrContextWindowExceeded
+}
+
+// TrimStrategy selects how a conversation is shortened to fit the context window
+type TrimStrategy string
+
+const (
+	// TrimDropOldest removes the oldest turns, keeping system messages, the first request and the latest turn
+	TrimDropOldest TrimStrategy = "drop_oldest"
+	// TrimMiddleOut cuts the middle out of the longest messages, tool results first
+	TrimMiddleOut TrimStrategy = "middle_out"
+	// TrimSummarize replaces earlier turns with an LLM-written summary in a single system note
+	TrimSummarize TrimStrategy = "summarize"
+)
+
+// ContextManagerOption configures a ContextManager
+type ContextManagerOption func(*ContextManager)
+
+// WithTokenizer sets the tokenizer used for estimates
+func WithTokenizer(tokenizer Tokenizer) ContextManagerOption {
+	return func(m *ContextManager) {
+		if tokenizer != nil {
+			m.tokenizer = tokenizer
+		}
+	}
+}
+
+// WithTrimStrategy sets the trimming strategy
+func WithTrimStrategy(strategy TrimStrategy) ContextManagerOption {
+	return func(m *ContextManager) {
+		if strategy != "" {
+			m.strategy = strategy
+		}
+	}
+}
+
+// WithSummarizer sets the LLM that writes summaries for TrimSummarize
+func WithSummarizer(summarizer LLM) ContextManagerOption {
+	return func(m *ContextManager) {
+		m.summarizer = summarizer
+	}
+}
+
+// ContextManager keeps conversations within a token budget
+type ContextManager struct {
+	tokenizer  Tokenizer
+	strategy   TrimStrategy
+	summarizer LLM
+}
+
+// NewContextManager creates a context manager, defaulting to heuristic estimates and middle-out truncation
+func NewContextManager(options ...ContextManagerOption) *ContextManager {
+	m := &ContextManager{
+		tokenizer: HeuristicTokenizer{},
+		strategy:  TrimMiddleOut,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// Strategy returns the configured trimming strategy
+func (m *ContextManager) Strategy() TrimStrategy {
+	return m.strategy
+}
+
+// TrimResult describes the outcome of fitting a conversation into a budget
+type TrimResult struct {
+	Messages        []Message
+	Strategy        TrimStrategy // empty when the conversation already fit
+	TokensBefore    int
+	TokensAfter     int
+	MessagesRemoved int
+}
+
+// Trimmed reports whether the conversation was changed
+func (r *TrimResult) Trimmed() bool {
+	return r.Strategy != ""
+}
+
+// TokensTrimmed returns how many estimated tokens were removed
+func (r *TrimResult) TokensTrimmed() int {
+	return r.TokensBefore - r.TokensAfter
+}
+
+// Check returns a ContextWindowExceededError when the conversation does not fit into budget
+func (m *ContextManager) Check(messages []Message, budget int) error {
+	if tokens := EstimateTokens(m.tokenizer, messages); tokens > budget {
+		return &ContextWindowExceededError{Needed: tokens, Available: budget}
+	}
+	return nil
+}
+
+// Fit shortens the conversation with the configured strategy until it fits into budget tokens.
+// The input slice is never modified; a ContextWindowExceededError is returned when the strategy cannot make it fit
+func (m *ContextManager) Fit(ctx context.Context, messages []Message, budget int) (*TrimResult, error) {
+	result := &TrimResult{Messages: messages, TokensBefore: EstimateTokens(m.tokenizer, messages)}
+	result.TokensAfter = result.TokensBefore
+	if result.TokensBefore <= budget {
+		return result, nil
+	}
+
+	var trimmed []Message
+	var err error
+	switch m.strategy {
+	case TrimDropOldest:
+		trimmed = m.dropOldest(messages, budget)
+	case TrimMiddleOut:
+		trimmed = m.middleOut(messages, budget)
+	case TrimSummarize:
+		trimmed, err = m.summarize(ctx, messages)
+	default:
+		return nil, fmt.Errorf("unknown context trim strategy %q", m.strategy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Messages = trimmed
+	result.Strategy = m.strategy
+	result.TokensAfter = EstimateTokens(m.tokenizer, trimmed)
+	result.MessagesRemoved = len(messages) - len(trimmed)
+	if result.TokensAfter > budget {
+		return result, &ContextWindowExceededError{Needed: result.TokensAfter, Available: budget}
+	}
+	return result, nil
+}
+
+// conversationBounds splits a conversation into a protected head (leading system messages and the first request),
+// a trimmable middle and a protected tail (the latest turn, including the tool call its tool results answer)
+func conversationBounds(messages []Message) (int, int) {
+	head := 0
+	for head < len(messages) && messages[head].Role == RoleSystem {
+		head++
+	}
+	if head < len(messages) {
+		head++
+	}
+
+	tail := len(messages) - 1
+	for tail > head && messages[tail].Role == RoleTool {
+		tail--
+	}
+	if tail < head {
+		tail = head
+	}
+	return head, tail
+}
+
+// dropOldest removes whole turns from the start of the middle until the conversation fits
+func (m *ContextManager) dropOldest(messages []Message, budget int) []Message {
+	head, tail := conversationBounds(messages)
+	total := EstimateTokens(m.tokenizer, messages)
+
+	start := head
+	for start < tail && total > budget {
+		// An assistant message with tool calls is dropped together with its tool results so no orphaned results remain
+		end := start + 1
+		for end < tail && messages[end].Role == RoleTool {
+			end++
+		}
+		for i := start; i < end; i++ {
+			total -= EstimateMessageTokens(m.tokenizer, messages[i])
+		}
+		start = end
+	}
+
+	trimmed := make([]Message, 0, len(messages)-(start-head))
+	trimmed = append(trimmed, messages[:head]...)
+	return append(trimmed, messages[start:]...)
+}
+
+// middleOut truncates the longest messages, tool results first, keeping their beginning and end
+func (m *ContextManager) middleOut(messages []Message, budget int) []Message {
+	trimmed := make([]Message, len(messages))
+	copy(trimmed, messages)
+
+	candidates := make([]int, 0, len(trimmed))
+	for i, msg := range trimmed {
+		if msg.Role != RoleSystem {
+			candidates = append(candidates, i)
+		}
+	}
+	sizes := make(map[int]int, len(candidates))
+	for _, i := range candidates {
+		sizes[i] = m.tokenizer.CountTokens(messageText(trimmed[i].Content))
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		toolA, toolB := trimmed[candidates[a]].Role == RoleTool, trimmed[candidates[b]].Role == RoleTool
+		if toolA != toolB {
+			return toolA
+		}
+		return sizes[candidates[a]] > sizes[candidates[b]]
+	})
+
+	total := EstimateTokens(m.tokenizer, trimmed)
+	for _, i := range candidates {
+		if total <= budget {
+			break
+		}
+		keep := sizes[i] - (total - budget)
+		if keep < minTruncatedTokens {
+			keep = minTruncatedTokens
+		}
+		if keep >= sizes[i] {
+			continue
+		}
+
+		before := EstimateMessageTokens(m.tokenizer, trimmed[i])
+		truncated := m.truncateMiddle(messageText(trimmed[i].Content), keep)
+		if images := imageParts(trimmed[i].Content); len(images) > 0 {
+			// Images cannot be truncated, so only the text is shortened
+			trimmed[i].Content = append([]ContentPart{TextPart(truncated)}, images...)
+		} else {
+			trimmed[i].Content = truncated
+		}
+		total += EstimateMessageTokens(m.tokenizer, trimmed[i]) - before
+	}
+	return trimmed
+}
+
+// truncateMiddle shortens text to about keep tokens, replacing the middle with a marker
+func (m *ContextManager) truncateMiddle(text string, keep int) string {
+	runes := []rune(text)
+	tokens := m.tokenizer.CountTokens(text)
+	if tokens <= keep || tokens == 0 {
+		return text
+	}
+
+	// Convert the kept tokens to runes by the text's rune/token ratio, leaving room for the marker
+	marker := fmt.Sprintf("\n\n[... %d tokens truncated ...]\n\n", tokens-keep)
+	keepRunes := len(runes)*keep/tokens - utf8.RuneCountInString(marker)
+	if keepRunes < 2 {
+		keepRunes = 2
+	}
+	headRunes := keepRunes / 2
+	tailRunes := keepRunes - headRunes
+	return string(runes[:headRunes]) + marker + string(runes[len(runes)-tailRunes:])
+}
+
+// summarize replaces the middle of the conversation with a summary written by the summarizer
+func (m *ContextManager) summarize(ctx context.Context, messages []Message) ([]Message, error) {
+	if m.summarizer == nil {
+		return nil, fmt.Errorf("context trim strategy %q requires a summarizer", TrimSummarize)
+	}
+
+	head, tail := conversationBounds(messages)
+	if tail <= head {
+		return messages, nil
+	}
+
+	transcript := renderTranscript(messages[head:tail])
+	// The transcript itself must fit the summarizer's context window
+	if window := m.summarizer.GetContextWindowSize(); window > 0 {
+		transcript = m.truncateMiddle(transcript, window/2)
+	}
+
+	response, err := m.summarizer.Call(ctx, []Message{
+		{Role: RoleSystem, Content: "You condense conversations. Summarize the conversation below so it can replace the original. " +
+			"Keep every fact, tool result, decision and open question that later steps may need. Reply with the summary only."},
+		{Role: RoleUser, Content: transcript},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize earlier conversation: %w", err)
+	}
+
+	trimmed := make([]Message, 0, head+1+len(messages)-tail)
+	trimmed = append(trimmed, messages[:head]...)
+	trimmed = append(trimmed, Message{
+		Role:    RoleSystem,
+		Content: "Summary of the earlier conversation:\n" + strings.TrimSpace(response.Content),
+	})
+	return append(trimmed, messages[tail:]...), nil
+}
+
+// renderTranscript renders messages as plain text for summarization
+func renderTranscript(messages []Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		b.WriteString(string(msg.Role))
+		if msg.Name != "" {
+			b.WriteString(" (" + msg.Name + ")")
+		}
+		b.WriteString(": ")
+		b.WriteString(messageText(msg.Content))
+		for _, tc := range msg.ToolCalls {
+			b.WriteString(fmt.Sprintf("\n[called %s with %s]", tc.Function.Name, tc.Function.Arguments))
+		}
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// tokenPerChar counts one token per character so budgets in tests are easy to reason about
+var tokenPerChar = TokenizerFunc(func(text string) int { return len(text) })
+
+func TestEstimateTokens(t *testing.T) {
+	if got := (HeuristicTokenizer{}).CountTokens("abcdefgh"); got != 2 {
+		t.Errorf("Expected 2 tokens for 8 characters, got %d", got)
+	}
+	if got := (HeuristicTokenizer{}).CountTokens("你好世界！"); got != 2 {
+		t.Errorf("Expected characters rather than bytes to be counted, got %d", got)
+	}
+
+	messages := []Message{
+		{Role: RoleUser, Content: "hello"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{Function: ToolCallFunction{Name: "search", Arguments: `{"q":1}`}}}},
+	}
+	want := (messageTokenOverhead + 5) + (messageTokenOverhead + 6 + 7)
+	if got := EstimateTokens(tokenPerChar, messages); got != want {
+		t.Errorf("Expected %d tokens, got %d", want, got)
+	}
+
+	// 图片按细节级别估算，不计入data URL的长度
+	image := Message{Role: RoleUser, Content: []ContentPart{
+		TextPart("hello"),
+		ImageDataPart("image/png", []byte(strings.Repeat("x", 1000)), ImageDetailLow),
+		ImageURLPart("https://example.com/a.png", ImageDetailHigh),
+	}}
+	if got := EstimateMessageTokens(tokenPerChar, image); got != messageTokenOverhead+5+85+765 {
+		t.Errorf("Expected text and image estimates, got %d", got)
+	}
+}
+
+func TestContextBudget(t *testing.T) {
+	maxTokens := 1000
+	options := &CallOptions{MaxTokens: &maxTokens}
+
+	if got := ContextBudget(8000, options, 0); got != 7000 {
+		t.Errorf("Expected max tokens to be reserved, got budget %d", got)
+	}
+	if got := ContextBudget(1500, options, 0); got != 750 {
+		t.Errorf("Expected the reserve to be capped at half the window, got budget %d", got)
+	}
+	if got := ContextBudget(8000, options, 3000); got != 5000 {
+		t.Errorf("Expected explicit reserve to win, got budget %d", got)
+	}
+	if got := ContextBudget(8000, nil, 0); got != 8000 {
+		t.Errorf("Expected the whole window without a reserve, got budget %d", got)
+	}
+}
+
+func TestContextManager_Check(t *testing.T) {
+	manager := NewContextManager(WithTokenizer(tokenPerChar))
+	messages := []Message{{Role: RoleUser, Content: strings.Repeat("x", 96)}}
+
+	if err := manager.Check(messages, 100); err != nil {
+		t.Errorf("Expected messages to fit, got %v", err)
+	}
+
+	err := manager.Check(messages, 50)
+	if !errors.Is(err, ErrContextWindowExceeded) {
+		t.Fatalf("Expected ErrContextWindowExceeded, got %v", err)
+	}
+	if err.Error() != "context window exceeded: needed 100 tokens, have 50" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
+
+// toolConversation builds a conversation with two tool-calling turns before the latest tool result
+func toolConversation() []Message {
+	call := func(id string) []ToolCall {
+		return []ToolCall{{ID: id, Function: ToolCallFunction{Name: "search", Arguments: "{}"}}}
+	}
+	return []Message{
+		{Role: RoleSystem, Content: "You are a researcher"},
+		{Role: RoleUser, Content: "Research the topic"},
+		{Role: RoleAssistant, Content: "", ToolCalls: call("call_1")},
+		{Role: RoleTool, Content: strings.Repeat("a", 300), ToolCallID: "call_1"},
+		{Role: RoleAssistant, Content: "", ToolCalls: call("call_2")},
+		{Role: RoleTool, Content: strings.Repeat("b", 300), ToolCallID: "call_2"},
+		{Role: RoleAssistant, Content: "", ToolCalls: call("call_3")},
+		{Role: RoleTool, Content: strings.Repeat("c", 300), ToolCallID: "call_3"},
+	}
+}
+
+func TestContextManager_FitWithinBudget(t *testing.T) {
+	manager := NewContextManager(WithTokenizer(tokenPerChar))
+	messages := toolConversation()
+
+	result, err := manager.Fit(context.Background(), messages, 10000)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Trimmed() || len(result.Messages) != len(messages) {
+		t.Errorf("Expected messages to be left unchanged, got %+v", result)
+	}
+}
+
+func TestContextManager_DropOldest(t *testing.T) {
+	manager := NewContextManager(WithTokenizer(tokenPerChar), WithTrimStrategy(TrimDropOldest))
+	messages := toolConversation()
+	total := EstimateTokens(tokenPerChar, messages)
+
+	result, err := manager.Fit(context.Background(), messages, total-100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 最早的工具调用与其结果一起删除
+	if len(result.Messages) != 6 || result.MessagesRemoved != 2 {
+		t.Fatalf("Expected the oldest turn to be removed, got %d messages", len(result.Messages))
+	}
+	if result.Messages[1].Content != "Research the topic" || result.Messages[3].ToolCallID != "call_2" {
+		t.Errorf("Unexpected messages after trimming: %+v", result.Messages)
+	}
+	if result.Strategy != TrimDropOldest || result.TokensTrimmed() != total-result.TokensAfter {
+		t.Errorf("Unexpected trim result: %+v", result)
+	}
+	if len(messages) != 8 || messages[3].ToolCallID != "call_1" {
+		t.Error("Expected the input messages to be left unchanged")
+	}
+
+	// 只剩系统消息、首个请求和最近一轮时无法继续裁剪
+	_, err = manager.Fit(context.Background(), messages, 300)
+	var exceeded *ContextWindowExceededError
+	if !errors.As(err, &exceeded) || exceeded.Available != 300 {
+		t.Errorf("Expected ContextWindowExceededError, got %v", err)
+	}
+}
+
+func TestContextManager_MiddleOut(t *testing.T) {
+	manager := NewContextManager(WithTokenizer(tokenPerChar))
+	messages := toolConversation()
+	messages[1].Content = strings.Repeat("q", 400)
+	total := EstimateTokens(tokenPerChar, messages)
+	budget := total - 150
+
+	result, err := manager.Fit(context.Background(), messages, budget)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.TokensAfter > budget || result.MessagesRemoved != 0 {
+		t.Errorf("Expected %d tokens or less without removing messages, got %+v", budget, result)
+	}
+
+	// 先截断工具结果，而不是更长的任务请求
+	if result.Messages[1].Content != messages[1].Content {
+		t.Error("Expected the request to be kept while tool results can be truncated")
+	}
+	truncated := result.Messages[3].Content.(string)
+	if !strings.HasPrefix(truncated, "aaa") || !strings.HasSuffix(truncated, "aaa") || !strings.Contains(truncated, "tokens truncated") {
+		t.Errorf("Expected the middle of the tool result to be cut out, got %q", truncated)
+	}
+
+	// 多部分内容只截断文本，保留图片
+	messages = []Message{{Role: RoleUser, Content: []ContentPart{
+		TextPart(strings.Repeat("q", 400)),
+		ImageURLPart("https://example.com/a.png", ImageDetailLow),
+	}}}
+	result, err = manager.Fit(context.Background(), messages, EstimateTokens(tokenPerChar, messages)-150)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	parts, ok := result.Messages[0].Content.([]ContentPart)
+	if !ok || len(parts) != 2 || parts[1].ImageURL == nil || !strings.Contains(parts[0].Text, "tokens truncated") {
+		t.Errorf("Expected the text to be truncated and the image kept, got %+v", result.Messages[0].Content)
+	}
+}
+
+func TestContextManager_Summarize(t *testing.T) {
+	manager := NewContextManager(WithTokenizer(tokenPerChar), WithTrimStrategy(TrimSummarize), WithSummarizer(&MockLLM{model: "summarizer"}))
+	messages := toolConversation()
+
+	result, err := manager.Fit(context.Background(), messages, 600)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(result.Messages) != 5 || result.MessagesRemoved != 3 {
+		t.Fatalf("Expected earlier turns to be replaced by one note, got %d messages", len(result.Messages))
+	}
+	note := result.Messages[2]
+	if note.Role != RoleSystem || note.Content != "Summary of the earlier conversation:\nMock response" {
+		t.Errorf("Unexpected summary note: %+v", note)
+	}
+	if result.Messages[3].ToolCalls[0].ID != "call_3" || result.Messages[4].ToolCallID != "call_3" {
+		t.Error("Expected the latest tool call and its result to be kept")
+	}
+
+	_, err = NewContextManager(WithTrimStrategy(TrimSummarize)).Fit(context.Background(), messages, 10)
+	if err == nil || !strings.Contains(err.Error(), "requires a summarizer") {
+		t.Errorf("Expected missing summarizer error, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const (
+	openAIEmbeddingsEndpoint  = "/embeddings"
+	defaultOpenAIEmbedModel   = "text-embedding-3-small"
+	maxOpenAIEmbeddingsInputs = 2048
+)
+
+// OpenAIEmbedder computes text embeddings with the OpenAI embeddings API.
+// It shares the HTTP client configuration (API key, base URL, timeout, retries,
+// custom headers) with the chat client through BaseLLM options.
+type OpenAIEmbedder struct {
+	*BaseLLM
+}
+
+// OpenAIEmbeddingRequest represents the request structure for the OpenAI embeddings API
+type OpenAIEmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format,omitempty"`
+}
+
+// OpenAIEmbeddingResponse represents the response structure for the OpenAI embeddings API
+type OpenAIEmbeddingResponse struct {
+	Object string                `json:"object"`
+	Model  string                `json:"model"`
+	Data   []OpenAIEmbeddingData `json:"data"`
+	Usage  OpenAIUsage           `json:"usage"`
+	Error  *OpenAIError          `json:"error,omitempty"`
+}
+
+// OpenAIEmbeddingData holds a single embedding in an OpenAI response
+type OpenAIEmbeddingData struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// NewOpenAIEmbedder creates an OpenAI embedder; an empty model defaults to text-embedding-3-small
+func NewOpenAIEmbedder(model string, options ...BaseLLMOption) *OpenAIEmbedder {
+	if model == "" {
+		model = defaultOpenAIEmbedModel
+	}
+
+	allOptions := append(options, func(b *BaseLLM) {
+		if b.baseURL == "" {
+			WithBaseURL(defaultOpenAIBaseURL)(b)
+		}
+	})
+
+	return &OpenAIEmbedder{
+		BaseLLM: NewBaseLLM("openai", model, allOptions...),
+	}
+}
+
+// Embed returns one embedding per input text, in input order
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxOpenAIEmbeddingsInputs {
+		end := start + maxOpenAIEmbeddingsInputs
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
+
+// embedBatch embeds up to maxOpenAIEmbeddingsInputs texts in a single request
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	bodyBytes, err := json.Marshal(&OpenAIEmbeddingRequest{
+		Model:          e.GetModel(),
+		Input:          texts,
+		EncodingFormat: "float",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+	}
+
+	responseBody, statusCode, err := e.doRequest(ctx, bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var response OpenAIEmbeddingResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal embeddings response: %w", err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("OpenAI API error: %s (type: %s, code: %s)",
+			response.Error.Message, response.Error.Type, response.Error.Code)
+	}
+	if statusCode >= 400 {
+		return nil, fmt.Errorf("HTTP error %d: %s", statusCode, string(responseBody))
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Data))
+	}
+
+	// The API documents data as ordered by index, but place by index to be safe
+	embeddings := make([][]float32, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+
+	e.LogDebug("OpenAI embeddings computed",
+		logger.Field{Key: "model", Value: e.GetModel()},
+		logger.Field{Key: "inputs", Value: len(texts)},
+		logger.Field{Key: "tokens", Value: response.Usage.TotalTokens},
+	)
+
+	return embeddings, nil
+}
+
+// doRequest posts body to the embeddings endpoint, retrying transport errors and 5xx responses
+func (e *OpenAIEmbedder) doRequest(ctx context.Context, body []byte) ([]byte, int, error) {
+	var lastErr error
+	for attempt := 0; attempt <= e.GetMaxRetries(); attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", e.GetBaseURL()+openAIEmbeddingsEndpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+e.GetAPIKey())
+		for key, value := range e.GetCustomHeaders() {
+			httpReq.Header.Set(key, value)
+		}
+
+		response, err := e.doHTTP(httpReq)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		responseBody, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if response.StatusCode >= 500 {
+			lastErr = fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(responseBody))
+			continue
+		}
+		return responseBody, response.StatusCode, nil
+	}
+
+	return nil, 0, fmt.Errorf("embeddings request failed after %d retries: %w", e.GetMaxRetries(), lastErr)
+}
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOpenAIEmbedder_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != openAIEmbeddingsEndpoint {
+			t.Errorf("Expected path %s, got %s", openAIEmbeddingsEndpoint, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected Authorization header: %s", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("X-Custom") != "yes" {
+			t.Error("Expected custom headers to be sent")
+		}
+
+		var request OpenAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Model != defaultOpenAIEmbedModel {
+			t.Errorf("Expected default model %s, got %s", defaultOpenAIEmbedModel, request.Model)
+		}
+		if len(request.Input) != 2 {
+			t.Fatalf("Expected 2 inputs, got %d", len(request.Input))
+		}
+
+		// Return data out of order to check results are placed by index
+		fmt.Fprint(w, `{"object":"list","model":"text-embedding-3-small",`+
+			`"data":[{"object":"embedding","index":1,"embedding":[0,1]},{"object":"embedding","index":0,"embedding":[1,0]}],`+
+			`"usage":{"prompt_tokens":4,"total_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("", WithAPIKey("test-key"), WithBaseURL(server.URL), WithCustomHeader("X-Custom", "yes"))
+	embeddings, err := embedder.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(embeddings) != 2 || embeddings[0][0] != 1 || embeddings[1][1] != 1 {
+		t.Errorf("unexpected embeddings: %v", embeddings)
+	}
+}
+
+func TestOpenAIEmbedder_DefaultBaseURL(t *testing.T) {
+	embedder := NewOpenAIEmbedder("text-embedding-3-large")
+	if embedder.GetBaseURL() != defaultOpenAIBaseURL {
+		t.Errorf("Expected base URL %s, got %s", defaultOpenAIBaseURL, embedder.GetBaseURL())
+	}
+	if embedder.GetModel() != "text-embedding-3-large" {
+		t.Errorf("Expected model text-embedding-3-large, got %s", embedder.GetModel())
+	}
+}
+
+func TestOpenAIEmbedder_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`)
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("", WithAPIKey("bad"), WithBaseURL(server.URL))
+	_, err := embedder.Embed(context.Background(), []string{"text"})
+	if err == nil || !strings.Contains(err.Error(), "Incorrect API key") {
+		t.Errorf("Expected API error, got %v", err)
+	}
+}
+
+func TestOpenAIEmbedder_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request OpenAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Expected the request body to be resent on retry: %v", err)
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"index":0,"embedding":[0.5,0.5]}]}`)
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("", WithAPIKey("key"), WithBaseURL(server.URL), WithMaxRetries(1))
+	embeddings, err := embedder.Embed(context.Background(), []string{"text"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 2 || len(embeddings) != 1 {
+		t.Errorf("Expected a successful retry, got %d calls and %v", calls, embeddings)
+	}
+}
@@ -14,72 +14,106 @@ const (
 	EventTypeLLMStreamStarted = "llm_stream_started"
 	EventTypeLLMStreamChunk   = "llm_stream_chunk"
 	EventTypeLLMStreamEnded   = "llm_stream_ended"
+
+	EventTypeLLMFallbackTriggered = "llm_fallback_triggered"
 )
 
 // LLMCallStartedEvent represents the start of an LLM call
 type LLMCallStartedEvent struct {
 	events.BaseEvent
-	Provider string                 `json:"provider"`
-	Model    string                 `json:"model"`
-	Messages []Message              `json:"messages"`
-	Options  *CallOptions           `json:"options"`
-	Metadata map[string]interface{} `json:"metadata"`
+	Provider              string                 `json:"provider"`
+	Model                 string                 `json:"model"`
+	Messages              []Message              `json:"messages"`
+	Options               *CallOptions           `json:"options"`
+	MessageCount          int                    `json:"message_count"`
+	EstimatedPromptTokens int                    `json:"estimated_prompt_tokens"`
+	Metadata              map[string]interface{} `json:"metadata"`
 }
 
 // NewLLMCallStartedEvent creates a new LLM call started event
 func NewLLMCallStartedEvent(provider, model string, messages []Message, options *CallOptions) *LLMCallStartedEvent {
+	promptTokens := EstimateTokens(nil, messages)
+
 	return &LLMCallStartedEvent{
 		BaseEvent: events.BaseEvent{
 			Type:      EventTypeLLMCallStarted,
 			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"provider":                provider,
+				"model":                   model,
+				"message_count":           len(messages),
+				"estimated_prompt_tokens": promptTokens,
+			},
 		},
-		Provider: provider,
-		Model:    model,
-		Messages: messages,
-		Options:  options,
-		Metadata: make(map[string]interface{}),
+		Provider:              provider,
+		Model:                 model,
+		Messages:              messages,
+		Options:               options,
+		MessageCount:          len(messages),
+		EstimatedPromptTokens: promptTokens,
+		Metadata:              make(map[string]interface{}),
 	}
 }
 
 // LLMCallCompletedEvent represents the completion of an LLM call
 type LLMCallCompletedEvent struct {
 	events.BaseEvent
-	Provider   string                 `json:"provider"`
-	Model      string                 `json:"model"`
-	Response   *Response              `json:"response"`
-	Duration   time.Duration          `json:"duration"`
-	TokensUsed int                    `json:"tokens_used"`
-	Cost       float64                `json:"cost"`
-	Metadata   map[string]interface{} `json:"metadata"`
+	Provider     string                 `json:"provider"`
+	Model        string                 `json:"model"`
+	Response     *Response              `json:"response"`
+	Duration     time.Duration          `json:"duration"`
+	Usage        Usage                  `json:"usage"`
+	TokensUsed   int                    `json:"tokens_used"`
+	Cost         float64                `json:"cost"`
+	FinishReason string                 `json:"finish_reason,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata"`
 }
 
 // NewLLMCallCompletedEvent creates a new LLM call completed event
 func NewLLMCallCompletedEvent(provider, model string, response *Response, duration time.Duration) *LLMCallCompletedEvent {
-	cost := calculateCost(provider, model, response.Usage)
+	cost := response.Usage.Cost
+	if cost == 0 {
+		cost = CalculateCost(model, response.Usage)
+	}
 
 	return &LLMCallCompletedEvent{
 		BaseEvent: events.BaseEvent{
 			Type:      EventTypeLLMCallCompleted,
 			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"provider":          provider,
+				"model":             model,
+				"duration_ms":       duration.Milliseconds(),
+				"prompt_tokens":     response.Usage.PromptTokens,
+				"completion_tokens": response.Usage.CompletionTokens,
+				"tokens_used":       response.Usage.TotalTokens,
+				"cost":              cost,
+				"finish_reason":     response.FinishReason,
+			},
 		},
-		Provider:   provider,
-		Model:      model,
-		Response:   response,
-		Duration:   duration,
-		TokensUsed: response.Usage.TotalTokens,
-		Cost:       cost,
-		Metadata:   make(map[string]interface{}),
+		Provider:     provider,
+		Model:        model,
+		Response:     response,
+		Duration:     duration,
+		Usage:        response.Usage,
+		TokensUsed:   response.Usage.TotalTokens,
+		Cost:         cost,
+		FinishReason: response.FinishReason,
+		Metadata:     make(map[string]interface{}),
 	}
 }
 
 // LLMCallFailedEvent represents a failed LLM call
 type LLMCallFailedEvent struct {
 	events.BaseEvent
-	Provider string                 `json:"provider"`
-	Model    string                 `json:"model"`
-	Error    error                  `json:"error"`
-	Duration time.Duration          `json:"duration"`
-	Metadata map[string]interface{} `json:"metadata"`
+	Provider     string                 `json:"provider"`
+	Model        string                 `json:"model"`
+	Error        error                  `json:"error"`
+	ErrorClass   string                 `json:"error_class"`
+	HTTPStatus   int                    `json:"http_status,omitempty"`
+	RetryAttempt int                    `json:"retry_attempt"`
+	Duration     time.Duration          `json:"duration"`
+	Metadata     map[string]interface{} `json:"metadata"`
 }
 
 // NewLLMCallFailedEvent creates a new LLM call failed event
@@ -88,15 +122,34 @@ func NewLLMCallFailedEvent(provider, model string, err error, duration time.Dura
 		BaseEvent: events.BaseEvent{
 			Type:      EventTypeLLMCallFailed,
 			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"provider":    provider,
+				"model":       model,
+				"error":       errorMessage(err),
+				"error_class": ErrorClassAPIError,
+				"duration_ms": duration.Milliseconds(),
+			},
 		},
-		Provider: provider,
-		Model:    model,
-		Error:    err,
-		Duration: duration,
-		Metadata: make(map[string]interface{}),
+		Provider:   provider,
+		Model:      model,
+		Error:      err,
+		ErrorClass: ErrorClassAPIError,
+		Duration:   duration,
+		Metadata:   make(map[string]interface{}),
 	}
 }
 
+// withHTTPAttempt records the HTTP status and retry attempt of the failed request and classifies the error
+func (e *LLMCallFailedEvent) withHTTPAttempt(statusCode, retryAttempt int, requested bool) *LLMCallFailedEvent {
+	e.ErrorClass = classifyError(e.Error, statusCode, requested)
+	e.HTTPStatus = statusCode
+	e.RetryAttempt = retryAttempt
+	e.Payload["error_class"] = e.ErrorClass
+	e.Payload["http_status"] = statusCode
+	e.Payload["retry_attempt"] = retryAttempt
+	return e
+}
+
 // LLMStreamStartedEvent represents the start of streaming
 type LLMStreamStartedEvent struct {
 	events.BaseEvent
@@ -167,7 +220,7 @@ func NewLLMStreamEndedEvent(provider, model string, duration time.Duration, toke
 		CompletionTokens: completionTokens,
 		TotalTokens:      tokensUsed,
 	}
-	cost := calculateCost(provider, model, usage)
+	cost := CalculateCost(model, usage)
 
 	return &LLMStreamEndedEvent{
 		BaseEvent: events.BaseEvent{
@@ -184,50 +237,41 @@ func NewLLMStreamEndedEvent(provider, model string, duration time.Duration, toke
 	}
 }
 
-// calculateCost estimates the cost based on provider, model, and usage
-func calculateCost(provider, model string, usage Usage) float64 {
-	// This is a simplified cost calculation
-	// In a real implementation, you would have a more comprehensive pricing table
-
-	switch provider {
-	case "openai":
-		return calculateOpenAICost(model, usage)
-	default:
-		return 0.0
-	}
+// LLMFallbackTriggeredEvent is emitted when a FallbackLLM gives up on a provider and tries the next one
+type LLMFallbackTriggeredEvent struct {
+	events.BaseEvent
+	FailedProvider     string `json:"failed_provider"`
+	FailedModel        string `json:"failed_model"`
+	SubstituteProvider string `json:"substitute_provider"`
+	SubstituteModel    string `json:"substitute_model"`
+	ErrorClass         string `json:"error_class"`
+	Error              error  `json:"error"`
+	Stream             bool   `json:"stream"`
 }
 
-// calculateOpenAICost calculates cost for OpenAI models
-func calculateOpenAICost(model string, usage Usage) float64 {
-	// OpenAI pricing (as of 2024, in USD per 1K tokens)
-	// These are example prices and should be updated with current pricing
-
-	var inputCostPer1K, outputCostPer1K float64
-
-	switch model {
-	case "gpt-4":
-		inputCostPer1K = 0.03
-		outputCostPer1K = 0.06
-	case "gpt-4-turbo":
-		inputCostPer1K = 0.01
-		outputCostPer1K = 0.03
-	case "gpt-4o":
-		inputCostPer1K = 0.005
-		outputCostPer1K = 0.015
-	case "gpt-4o-mini":
-		inputCostPer1K = 0.00015
-		outputCostPer1K = 0.0006
-	case "gpt-3.5-turbo":
-		inputCostPer1K = 0.0015
-		outputCostPer1K = 0.002
-	default:
-		// Default pricing
-		inputCostPer1K = 0.002
-		outputCostPer1K = 0.002
+// NewLLMFallbackTriggeredEvent creates a new LLM fallback triggered event
+func NewLLMFallbackTriggeredEvent(failed, substitute LLM, errorClass string, err error, stream bool) *LLMFallbackTriggeredEvent {
+	failedProvider, substituteProvider := providerOf(failed), providerOf(substitute)
+	return &LLMFallbackTriggeredEvent{
+		BaseEvent: events.BaseEvent{
+			Type:      EventTypeLLMFallbackTriggered,
+			Timestamp: time.Now(),
+			Payload: map[string]interface{}{
+				"failed_provider":     failedProvider,
+				"failed_model":        failed.GetModel(),
+				"substitute_provider": substituteProvider,
+				"substitute_model":    substitute.GetModel(),
+				"error_class":         errorClass,
+				"error":               errorMessage(err),
+				"stream":              stream,
+			},
+		},
+		FailedProvider:     failedProvider,
+		FailedModel:        failed.GetModel(),
+		SubstituteProvider: substituteProvider,
+		SubstituteModel:    substitute.GetModel(),
+		ErrorClass:         errorClass,
+		Error:              err,
+		Stream:             stream,
 	}
-
-	inputCost := float64(usage.PromptTokens) / 1000.0 * inputCostPer1K
-	outputCost := float64(usage.CompletionTokens) / 1000.0 * outputCostPer1K
-
-	return inputCost + outputCost
 }
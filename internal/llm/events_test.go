@@ -239,7 +239,7 @@ func TestNewLLMStreamEndedEvent(t *testing.T) {
 	}
 }
 
-func TestCalculateOpenAICost(t *testing.T) {
+func TestCalculateCostPricingTable(t *testing.T) {
 	tests := []struct {
 		name            string
 		model           string
@@ -255,7 +255,7 @@ func TestCalculateOpenAICost(t *testing.T) {
 				CompletionTokens: 500,
 				TotalTokens:      1500,
 			},
-			expectedCost:    0.06, // (1000/1000 * 0.03) + (500/1000 * 0.06) = 0.03 + 0.03 = 0.06
+			expectedCost:    0.06, // (1000/1M * $30) + (500/1M * $60) = 0.03 + 0.03 = 0.06
 			acceptableDelta: 0.0001,
 		},
 		{
@@ -266,7 +266,7 @@ func TestCalculateOpenAICost(t *testing.T) {
 				CompletionTokens: 1000,
 				TotalTokens:      3000,
 			},
-			expectedCost:    0.005, // (2000/1000 * 0.0015) + (1000/1000 * 0.002) = 0.003 + 0.002 = 0.005
+			expectedCost:    0.0025, // (2000/1M * $0.50) + (1000/1M * $1.50) = 0.001 + 0.0015 = 0.0025
 			acceptableDelta: 0.0001,
 		},
 		{
@@ -277,25 +277,25 @@ func TestCalculateOpenAICost(t *testing.T) {
 				CompletionTokens: 5000,
 				TotalTokens:      15000,
 			},
-			expectedCost:    0.0045, // (10000/1000 * 0.00015) + (5000/1000 * 0.0006) = 0.0015 + 0.003 = 0.0045
+			expectedCost:    0.0045, // (10000/1M * $0.15) + (5000/1M * $0.60) = 0.0015 + 0.003 = 0.0045
 			acceptableDelta: 0.0001,
 		},
 		{
-			name:  "unknown model default pricing",
+			name:  "unknown model costs nothing",
 			model: "unknown-model",
 			usage: Usage{
 				PromptTokens:     1000,
 				CompletionTokens: 1000,
 				TotalTokens:      2000,
 			},
-			expectedCost:    0.004, // (1000/1000 * 0.002) + (1000/1000 * 0.002) = 0.002 + 0.002 = 0.004
+			expectedCost:    0,
 			acceptableDelta: 0.0001,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cost := calculateOpenAICost(tt.model, tt.usage)
+			cost := CalculateCost(tt.model, tt.usage)
 
 			if cost < tt.expectedCost-tt.acceptableDelta || cost > tt.expectedCost+tt.acceptableDelta {
 				t.Errorf("Expected cost around %f, got %f (delta: %f)", tt.expectedCost, cost, tt.acceptableDelta)
@@ -324,7 +324,7 @@ func TestCalculateCost(t *testing.T) {
 			wantCost: true,
 		},
 		{
-			name:     "Unknown provider no cost",
+			name:     "Unknown model no cost",
 			provider: "unknown",
 			model:    "some-model",
 			usage: Usage{
@@ -338,7 +338,7 @@ func TestCalculateCost(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cost := calculateCost(tt.provider, tt.model, tt.usage)
+			cost := CalculateCost(tt.model, tt.usage)
 
 			if tt.wantCost && cost <= 0 {
 				t.Errorf("Expected cost > 0, got %f", cost)
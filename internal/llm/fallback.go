@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ynl/greensoulai/pkg/events"
+)
+
+// DefaultFallbackErrorClasses are the error classes on which a FallbackLLM moves on to the next LLM:
+// provider outages and overload, but not requests the next provider would reject as well
+var DefaultFallbackErrorClasses = []string{
+	ErrorClassTimeout,
+	ErrorClassRateLimit,
+	ErrorClassServerError,
+	ErrorClassNetwork,
+}
+
+// FallbackLLM tries a chain of LLMs in order, moving on to the next one when a call fails
+// with a fallback error class. Responses report the model that actually served the call
+type FallbackLLM struct {
+	compositeUsage
+
+	llms       []LLM
+	fallbackOn map[string]bool
+
+	mu       sync.RWMutex
+	eventBus events.EventBus
+}
+
+// NewFallbackLLM creates an LLM that calls primary and falls back to secondaries in order
+func NewFallbackLLM(primary LLM, secondaries ...LLM) *FallbackLLM {
+	f := &FallbackLLM{llms: append([]LLM{primary}, secondaries...)}
+	f.SetFallbackOn(DefaultFallbackErrorClasses...)
+	return f
+}
+
+// SetFallbackOn replaces the error classes that trigger a fallback
+func (f *FallbackLLM) SetFallbackOn(classes ...string) {
+	fallbackOn := make(map[string]bool, len(classes))
+	for _, class := range classes {
+		fallbackOn[class] = true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fallbackOn = fallbackOn
+}
+
+// LLMs returns the chain, primary first
+func (f *FallbackLLM) LLMs() []LLM {
+	return append([]LLM(nil), f.llms...)
+}
+
+// Call implements LLM
+func (f *FallbackLLM) Call(ctx context.Context, messages []Message, options *CallOptions) (*Response, error) {
+	var err error
+	for i, l := range f.llms {
+		var response *Response
+		response, err = l.Call(ctx, messages, options)
+		if err == nil {
+			return f.recordResponse(l, response), nil
+		}
+		if !f.fallback(ctx, i, err, false) {
+			break
+		}
+	}
+	return nil, err
+}
+
+// CallStream implements LLM. A stream falls back only when it fails before its first chunk;
+// once content has been relayed to the caller, later errors are passed through
+func (f *FallbackLLM) CallStream(ctx context.Context, messages []Message, options *CallOptions) (<-chan StreamResponse, error) {
+	var err error
+	for i, l := range f.llms {
+		var stream <-chan StreamResponse
+		stream, err = l.CallStream(ctx, messages, options)
+		if err == nil {
+			var first StreamResponse
+			var ok bool
+			first, ok, err = firstChunk(ctx, stream)
+			if err == nil {
+				if !ok {
+					return f.recordStream(l, nil, stream), nil
+				}
+				return f.recordStream(l, &first, stream), nil
+			}
+		}
+		if !f.fallback(ctx, i, err, true) {
+			break
+		}
+	}
+	return nil, err
+}
+
+// firstChunk waits for the first chunk of stream. It returns the chunk's error, draining the
+// rest of the stream, when the stream fails before producing any content
+func firstChunk(ctx context.Context, stream <-chan StreamResponse) (StreamResponse, bool, error) {
+	select {
+	case <-ctx.Done():
+		go drain(stream)
+		return StreamResponse{}, false, ctx.Err()
+	case chunk, ok := <-stream:
+		if !ok {
+			return chunk, false, nil
+		}
+		if chunk.Error != nil && chunk.Delta == "" && chunk.Refusal == "" && len(chunk.ToolCalls) == 0 {
+			go drain(stream)
+			return chunk, false, chunk.Error
+		}
+		return chunk, true, nil
+	}
+}
+
+// drain discards the remaining chunks so the provider's goroutine can finish
+func drain(stream <-chan StreamResponse) {
+	for range stream {
+	}
+}
+
+// fallback reports whether the call that failed on the i-th LLM should move on to the next one,
+// and emits the fallback event when it does
+func (f *FallbackLLM) fallback(ctx context.Context, i int, err error, stream bool) bool {
+	if i+1 >= len(f.llms) || ctx.Err() != nil {
+		return false
+	}
+
+	class := ClassifyError(err)
+	f.mu.RLock()
+	fallbackOn, eventBus := f.fallbackOn[class], f.eventBus
+	f.mu.RUnlock()
+	if !fallbackOn {
+		return false
+	}
+
+	emitEvent(ctx, eventBus, NewLLMFallbackTriggeredEvent(f.llms[i], f.llms[i+1], class, err, stream))
+	return true
+}
+
+// GetModel returns the primary model
+func (f *FallbackLLM) GetModel() string {
+	return f.llms[0].GetModel()
+}
+
+// GetProvider returns the primary provider
+func (f *FallbackLLM) GetProvider() string {
+	return providerOf(f.llms[0])
+}
+
+// SupportsFunctionCalling reports whether every LLM in the chain supports function calling,
+// so a fallback never lands on a model that cannot handle the request's tools
+func (f *FallbackLLM) SupportsFunctionCalling() bool {
+	for _, l := range f.llms {
+		if !l.SupportsFunctionCalling() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetContextWindowSize returns the smallest context window in the chain
+func (f *FallbackLLM) GetContextWindowSize() int {
+	size := f.llms[0].GetContextWindowSize()
+	for _, l := range f.llms[1:] {
+		if s := l.GetContextWindowSize(); s < size {
+			size = s
+		}
+	}
+	return size
+}
+
+// GetTokenizer returns the tokenizer of the primary LLM
+func (f *FallbackLLM) GetTokenizer() Tokenizer {
+	return f.llms[0].GetTokenizer()
+}
+
+// SetEventBus sets the event bus of the composite and of every LLM in the chain
+func (f *FallbackLLM) SetEventBus(eventBus events.EventBus) {
+	f.mu.Lock()
+	f.eventBus = eventBus
+	f.mu.Unlock()
+	setEventBus(f.llms, eventBus)
+}
+
+// Close closes every LLM in the chain
+func (f *FallbackLLM) Close() error {
+	if err := closeAll(f.llms); err != nil {
+		return fmt.Errorf("failed to close fallback LLMs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// stubLLM answers every call with a fixed response or error and counts its calls
+type stubLLM struct {
+	MockLLM
+	provider string
+	usage    Usage
+	err      error
+	chunks   []StreamResponse
+	calls    int
+}
+
+func newStubLLM(provider, model string) *stubLLM {
+	return &stubLLM{MockLLM: MockLLM{model: model}, provider: provider}
+}
+
+func (s *stubLLM) GetProvider() string { return s.provider }
+
+func (s *stubLLM) Call(ctx context.Context, messages []Message, options *CallOptions) (*Response, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &Response{Content: "from " + s.model, Usage: s.usage}, nil
+}
+
+func (s *stubLLM) CallStream(ctx context.Context, messages []Message, options *CallOptions) (<-chan StreamResponse, error) {
+	s.calls++
+	if s.err != nil && s.chunks == nil {
+		return nil, s.err
+	}
+	stream := make(chan StreamResponse, len(s.chunks))
+	for _, chunk := range s.chunks {
+		stream <- chunk
+	}
+	close(stream)
+	return stream, nil
+}
+
+func subscribeFallbacks(t *testing.T) (events.EventBus, *[]*LLMFallbackTriggeredEvent) {
+	bus := events.NewEventBus(logger.NewTestLogger())
+	var triggered []*LLMFallbackTriggeredEvent
+	_, err := bus.SubscribeWithOptions(EventTypeLLMFallbackTriggered, func(ctx context.Context, event events.Event) error {
+		triggered = append(triggered, event.(*LLMFallbackTriggeredEvent))
+		return nil
+	}, events.WithSyncDelivery())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	return bus, &triggered
+}
+
+func TestFallbackLLM_FallsBackOnRetryableErrors(t *testing.T) {
+	primary := newStubLLM("openai", "gpt-4o")
+	primary.err = errors.New("HTTP error 503: service unavailable")
+	secondary := newStubLLM("anthropic", "claude-3-5-haiku")
+	secondary.err = errors.New("HTTP error 429: too many requests")
+	last := newStubLLM("ollama", "llama3")
+	last.usage = Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+
+	bus, triggered := subscribeFallbacks(t)
+	fallback := NewFallbackLLM(primary, secondary, last)
+	fallback.SetEventBus(bus)
+
+	response, err := fallback.Call(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if response.Model != "llama3" || response.Content != "from llama3" {
+		t.Errorf("Expected the response of llama3, got model %q content %q", response.Model, response.Content)
+	}
+	if primary.calls != 1 || secondary.calls != 1 || last.calls != 1 {
+		t.Errorf("Expected each LLM to be called once, got %d %d %d", primary.calls, secondary.calls, last.calls)
+	}
+
+	if len(*triggered) != 2 {
+		t.Fatalf("Expected 2 fallback events, got %d", len(*triggered))
+	}
+	first := (*triggered)[0]
+	if first.FailedProvider != "openai" || first.FailedModel != "gpt-4o" ||
+		first.SubstituteProvider != "anthropic" || first.ErrorClass != ErrorClassServerError {
+		t.Errorf("Unexpected first fallback event: %+v", first.Payload)
+	}
+	if second := (*triggered)[1]; second.SubstituteModel != "llama3" || second.ErrorClass != ErrorClassRateLimit {
+		t.Errorf("Unexpected second fallback event: %+v", second.Payload)
+	}
+
+	if usage := fallback.UsageByModel()["llama3"]; usage.TotalTokens != 15 {
+		t.Errorf("Expected usage to be recorded for llama3, got %+v", usage)
+	}
+	if total := fallback.Usage(); total.TotalTokens != 15 {
+		t.Errorf("Expected total usage of 15 tokens, got %d", total.TotalTokens)
+	}
+}
+
+func TestFallbackLLM_DoesNotFallBackOnClientErrors(t *testing.T) {
+	primary := newStubLLM("openai", "gpt-4o")
+	primary.err = errors.New("HTTP error 400: bad request")
+	secondary := newStubLLM("anthropic", "claude-3-5-haiku")
+
+	_, err := NewFallbackLLM(primary, secondary).Call(context.Background(), nil, nil)
+	if err != primary.err {
+		t.Errorf("Expected the primary's error, got %v", err)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("Expected no fallback for a client error, got %d calls", secondary.calls)
+	}
+}
+
+func TestFallbackLLM_PricesUsageWithTheServingModel(t *testing.T) {
+	primary := newStubLLM("openai", "gpt-4")
+	primary.err = fmt.Errorf("HTTP request failed after 3 retries: %w", context.DeadlineExceeded)
+	secondary := newStubLLM("openai", "gpt-4o-mini")
+	secondary.usage = Usage{PromptTokens: 1000, CompletionTokens: 1000, TotalTokens: 2000}
+
+	response, err := NewFallbackLLM(primary, secondary).Call(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	expected := CalculateCost("gpt-4o-mini", secondary.usage)
+	if response.Usage.Cost != expected {
+		t.Errorf("Expected cost %v priced for gpt-4o-mini, got %v", expected, response.Usage.Cost)
+	}
+}
+
+func TestFallbackLLM_StreamFallsBackOnlyBeforeFirstChunk(t *testing.T) {
+	primary := newStubLLM("openai", "gpt-4o")
+	primary.chunks = []StreamResponse{{Error: errors.New("HTTP error 502: bad gateway")}}
+	secondary := newStubLLM("anthropic", "claude-3-5-haiku")
+	secondary.chunks = []StreamResponse{
+		{Delta: "Hel"},
+		{Delta: "lo", Usage: &Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}, FinishReason: "stop"},
+	}
+
+	bus, triggered := subscribeFallbacks(t)
+	fallback := NewFallbackLLM(primary, secondary)
+	fallback.SetEventBus(bus)
+
+	stream, err := fallback.CallStream(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	content := ""
+	for chunk := range stream {
+		content += chunk.Delta
+	}
+	if content != "Hello" {
+		t.Errorf("Expected the substitute's stream, got %q", content)
+	}
+	if len(*triggered) != 1 || !(*triggered)[0].Stream {
+		t.Errorf("Expected one stream fallback event, got %d", len(*triggered))
+	}
+	if usage := fallback.UsageByModel()["claude-3-5-haiku"]; usage.TotalTokens != 5 {
+		t.Errorf("Expected stream usage to be recorded, got %+v", usage)
+	}
+
+	// 已经输出内容后的错误直接传给调用方
+	midStream := newStubLLM("openai", "gpt-4o")
+	midStream.chunks = []StreamResponse{{Delta: "partial"}, {Error: errors.New("HTTP error 500: boom")}}
+	untouched := newStubLLM("anthropic", "claude-3-5-haiku")
+
+	stream, err = NewFallbackLLM(midStream, untouched).CallStream(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	var streamErr error
+	for chunk := range stream {
+		if chunk.Error != nil {
+			streamErr = chunk.Error
+		}
+	}
+	if streamErr == nil || untouched.calls != 0 {
+		t.Errorf("Expected a mid-stream error without fallback, got err %v and %d substitute calls", streamErr, untouched.calls)
+	}
+}
+
+func TestClassifyError_Message(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{errors.New("HTTP error 429: slow down"), ErrorClassRateLimit},
+		{errors.New("HTTP error 503: unavailable"), ErrorClassServerError},
+		{errors.New("HTTP error 401: unauthorized"), ErrorClassAuthentication},
+		{errors.New("HTTP error 400: bad request"), ErrorClassInvalidRequest},
+		{errors.New("Anthropic API error: Overloaded (type: overloaded_error, status: 529)"), ErrorClassServerError},
+		{errors.New("OpenAI API error: Rate limit reached (type: requests, code: rate_limit_exceeded)"), ErrorClassRateLimit},
+		{fmt.Errorf("HTTP request failed after 3 retries: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{errors.New("HTTP request failed after 3 retries: dial tcp: connection refused"), ErrorClassNetwork},
+		{context.Canceled, ErrorClassCanceled},
+		{errors.New("something odd"), ErrorClassAPIError},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.expected {
+			t.Errorf("ClassifyError(%q) = %q, expected %q", tt.err, got, tt.expected)
+		}
+	}
+}
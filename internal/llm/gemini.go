@@ -0,0 +1,916 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const (
+	// Gemini API constants
+	defaultGeminiBaseURL  = "https://generativelanguage.googleapis.com/v1beta"
+	geminiGenerateMethod  = ":generateContent"
+	geminiStreamMethod    = ":streamGenerateContent?alt=sse"
+	geminiModelNamePrefix = "models/"
+
+	// Context window used when a Gemini model family is unknown
+	defaultGeminiContextWindow = 1048576
+)
+
+// Gemini model context windows, matched by model name prefix
+var geminiContextWindows = map[string]int{
+	"gemini-pro":            32760,
+	"gemini-1.0-pro":        32760,
+	"gemini-1.5-pro":        2097152,
+	"gemini-1.5-flash":      1048576,
+	"gemini-1.5-flash-8b":   1048576,
+	"gemini-2.0-pro":        2097152,
+	"gemini-2.0-flash":      1048576,
+	"gemini-2.0-flash-lite": 1048576,
+	"gemini-2.5-pro":        1048576,
+	"gemini-2.5-flash":      1048576,
+}
+
+// geminiBlockedFinishReasons are finish reasons for responses Gemini withheld
+var geminiBlockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
+// geminiSchemaKeys are the OpenAPI schema fields accepted in function declarations;
+// other JSON Schema keywords such as additionalProperties or $schema are rejected by the API
+var geminiSchemaKeys = map[string]bool{
+	"type":             true,
+	"format":           true,
+	"title":            true,
+	"description":      true,
+	"nullable":         true,
+	"enum":             true,
+	"items":            true,
+	"properties":       true,
+	"required":         true,
+	"minItems":         true,
+	"maxItems":         true,
+	"minProperties":    true,
+	"maxProperties":    true,
+	"minimum":          true,
+	"maximum":          true,
+	"minLength":        true,
+	"maxLength":        true,
+	"pattern":          true,
+	"anyOf":            true,
+	"propertyOrdering": true,
+	"default":          true,
+	"example":          true,
+}
+
+// GeminiLLM represents a Google Gemini LLM instance using the generateContent REST API
+type GeminiLLM struct {
+	*BaseLLM
+}
+
+// GeminiRequest represents the request structure for generateContent and streamGenerateContent
+type GeminiRequest struct {
+	Contents          []GeminiContent         `json:"contents"`
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []GeminiTool            `json:"tools,omitempty"`
+	ToolConfig        *GeminiToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// GeminiContent represents a conversation turn; the role is "user" or "model"
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart represents a text, functionCall or functionResponse part
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiFunctionCall represents a function call requested by the model
+type GeminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse carries a tool result back to the model
+type GeminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// GeminiTool represents a set of function declarations
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionDeclaration represents a function the model may call
+type GeminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// GeminiToolConfig controls whether and which functions the model calls
+type GeminiToolConfig struct {
+	FunctionCallingConfig GeminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+// GeminiFunctionCallingConfig represents the function calling mode: AUTO, ANY or NONE
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+// GeminiGenerationConfig represents sampling and output options
+type GeminiGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	CandidateCount   *int     `json:"candidateCount,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+}
+
+// GeminiResponse represents a response, or a single server-sent event when streaming
+type GeminiResponse struct {
+	Candidates     []GeminiCandidate     `json:"candidates"`
+	PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *GeminiUsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string                `json:"modelVersion,omitempty"`
+	ResponseID     string                `json:"responseId,omitempty"`
+	Error          *GeminiError          `json:"error,omitempty"`
+}
+
+// GeminiCandidate represents a generated candidate
+type GeminiCandidate struct {
+	Content       GeminiContent        `json:"content"`
+	FinishReason  string               `json:"finishReason,omitempty"`
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings,omitempty"`
+	Index         int                  `json:"index"`
+}
+
+// GeminiSafetyRating represents the harm probability for one category
+type GeminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// GeminiPromptFeedback reports why a prompt was blocked before generation
+type GeminiPromptFeedback struct {
+	BlockReason   string               `json:"blockReason,omitempty"`
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings,omitempty"`
+}
+
+// GeminiUsageMetadata represents token usage in Gemini responses
+type GeminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+}
+
+// GeminiError represents an error from the Gemini API
+type GeminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// geminiContextWindow returns the context window for a model, defaulting to 1M
+func geminiContextWindow(model string) int {
+	model = strings.TrimPrefix(model, geminiModelNamePrefix)
+	if window, exists := geminiContextWindows[model]; exists {
+		return window
+	}
+
+	// Longest prefix wins, so versioned model IDs resolve to their family
+	prefixes := make([]string, 0, len(geminiContextWindows))
+	for prefix := range geminiContextWindows {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return geminiContextWindows[prefix]
+		}
+	}
+
+	return defaultGeminiContextWindow
+}
+
+// NewGeminiLLM creates a new Gemini LLM instance
+func NewGeminiLLM(model string, options ...BaseLLMOption) *GeminiLLM {
+	defaultOptions := []BaseLLMOption{
+		WithContextWindow(geminiContextWindow(model)),
+		WithFunctionCalling(true),
+	}
+
+	// Apply user options first, then defaults
+	allOptions := append(options, defaultOptions...)
+
+	// Add default base URL only if not already set
+	allOptions = append(allOptions, func(b *BaseLLM) {
+		if b.baseURL == "" {
+			WithBaseURL(defaultGeminiBaseURL)(b)
+		}
+		b.baseURL = strings.TrimSuffix(b.baseURL, "/")
+	})
+
+	return &GeminiLLM{
+		BaseLLM: NewBaseLLM("gemini", strings.TrimPrefix(model, geminiModelNamePrefix), allOptions...),
+	}
+}
+
+// Call sends a synchronous request to the Gemini API
+func (g *GeminiLLM) Call(ctx context.Context, messages []Message, options *CallOptions) (result *Response, err error) {
+	ctx, telemetry := g.startCall(ctx, messages, options, false)
+	defer func() { telemetry.finish(result, err) }()
+
+	request, err := g.prepareRequest(messages, options)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := g.makeAPICall(ctx, request)
+	if err != nil {
+		g.LogError("Gemini API call failed",
+			logger.Field{Key: "model", Value: g.GetModel()},
+			logger.Field{Key: "error", Value: err},
+		)
+		return nil, err
+	}
+
+	result, err = g.convertResponse(response)
+	if err != nil {
+		return nil, err
+	}
+
+	g.LogDebug("Gemini API call completed",
+		logger.Field{Key: "model", Value: g.GetModel()},
+		logger.Field{Key: "usage", Value: result.Usage},
+	)
+
+	return result, nil
+}
+
+// CallStream sends a streaming request to the Gemini API
+func (g *GeminiLLM) CallStream(ctx context.Context, messages []Message, options *CallOptions) (stream <-chan StreamResponse, err error) {
+	ctx, telemetry := g.startCall(ctx, messages, options, true)
+	defer func() {
+		if err != nil {
+			telemetry.finish(nil, err)
+		}
+	}()
+
+	request, err := g.prepareRequest(messages, options)
+	if err != nil {
+		return nil, err
+	}
+
+	responseChannel := make(chan StreamResponse, 100)
+	go g.streamAPICall(ctx, request, responseChannel)
+
+	return telemetry.observe(responseChannel), nil
+}
+
+// prepareRequest validates inputs and builds a Gemini request
+func (g *GeminiLLM) prepareRequest(messages []Message, options *CallOptions) (*GeminiRequest, error) {
+	if err := g.ValidateMessages(messages); err != nil {
+		return nil, fmt.Errorf("invalid messages: %w", err)
+	}
+
+	if err := g.ValidateCallOptions(options); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	// Image content parts are only serialized by the OpenAI client
+	if err := checkVision(g.GetModel(), false, messages); err != nil {
+		return nil, err
+	}
+
+	system, contents := convertGeminiMessages(messages)
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("invalid messages: at least one non-system message is required")
+	}
+
+	return g.buildRequest(system, contents, options), nil
+}
+
+// convertGeminiMessages converts internal messages to Gemini contents.
+// System messages become the systemInstruction, assistant messages use the "model" role,
+// tool results become functionResponse parts in a user turn, and consecutive turns with the
+// same role are merged so parallel function calls are answered in a single turn.
+func convertGeminiMessages(messages []Message) (*GeminiContent, []GeminiContent) {
+	var systemParts []GeminiPart
+	var result []GeminiContent
+
+	// Function names requested by the latest assistant message, by tool call ID
+	pendingCalls := make(map[string]string)
+
+	appendParts := func(role string, parts []GeminiPart) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Parts = append(result[n-1].Parts, parts...)
+			return
+		}
+		result = append(result, GeminiContent{Role: role, Parts: parts})
+	}
+
+	for _, msg := range messages {
+		text := messageText(msg.Content)
+
+		switch msg.Role {
+		case RoleSystem:
+			if text != "" {
+				systemParts = append(systemParts, GeminiPart{Text: text})
+			}
+
+		case RoleAssistant:
+			var parts []GeminiPart
+			if text != "" {
+				parts = append(parts, GeminiPart{Text: text})
+			}
+			pendingCalls = make(map[string]string)
+			for _, tc := range msg.ToolCalls {
+				// Call IDs are not sent back: Gemini matches responses to calls by order and name
+				parts = append(parts, GeminiPart{FunctionCall: &GeminiFunctionCall{
+					Name: tc.Function.Name,
+					Args: toolCallInput(tc),
+				}})
+				pendingCalls[tc.ID] = tc.Function.Name
+			}
+			appendParts("model", parts)
+
+		case RoleTool:
+			name := msg.Name
+			if name == "" {
+				name = pendingCalls[msg.ToolCallID]
+			}
+			if name == "" {
+				appendParts("user", []GeminiPart{{Text: fmt.Sprintf("Tool result: %s", text)}})
+				continue
+			}
+			appendParts("user", []GeminiPart{{FunctionResponse: &GeminiFunctionResponse{
+				Name:     name,
+				Response: geminiFunctionResponse(text),
+			}}})
+
+		default:
+			if text != "" {
+				appendParts("user", []GeminiPart{{Text: text}})
+			}
+		}
+	}
+
+	if len(systemParts) == 0 {
+		return nil, result
+	}
+	return &GeminiContent{Parts: systemParts}, result
+}
+
+// geminiFunctionResponse wraps a tool result in the object Gemini expects;
+// results that are JSON objects are passed through as they are
+func geminiFunctionResponse(text string) map[string]interface{} {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &object); err == nil && object != nil {
+		return object
+	}
+	return map[string]interface{}{"result": text}
+}
+
+// buildRequest builds a Gemini request
+func (g *GeminiLLM) buildRequest(system *GeminiContent, contents []GeminiContent, options *CallOptions) *GeminiRequest {
+	request := &GeminiRequest{
+		Contents:          contents,
+		SystemInstruction: system,
+	}
+
+	if options == nil {
+		return request
+	}
+
+	config := &GeminiGenerationConfig{
+		Temperature:      options.Temperature,
+		TopP:             options.TopP,
+		MaxOutputTokens:  options.MaxTokens,
+		StopSequences:    options.StopSequences,
+		CandidateCount:   options.N,
+		Seed:             options.Seed,
+		PresencePenalty:  options.PresencePenalty,
+		FrequencyPenalty: options.FrequencyPenalty,
+	}
+	if config.MaxOutputTokens == nil {
+		config.MaxOutputTokens = options.MaxCompletionTokens
+	}
+	if config.Temperature != nil || config.TopP != nil || config.MaxOutputTokens != nil || len(config.StopSequences) > 0 ||
+		config.CandidateCount != nil || config.Seed != nil || config.PresencePenalty != nil || config.FrequencyPenalty != nil {
+		request.GenerationConfig = config
+	}
+
+	if len(options.Tools) > 0 {
+		declarations := make([]GeminiFunctionDeclaration, len(options.Tools))
+		for i, tool := range options.Tools {
+			declarations[i] = convertGeminiTool(tool)
+		}
+		request.Tools = []GeminiTool{{FunctionDeclarations: declarations}}
+		request.ToolConfig = convertGeminiToolChoice(options.ToolChoice)
+	}
+
+	return request
+}
+
+// convertGeminiTool converts a tool schema to a function declaration.
+// Tools without parameters omit them, since Gemini rejects object schemas with no properties
+func convertGeminiTool(tool Tool) GeminiFunctionDeclaration {
+	declaration := GeminiFunctionDeclaration{
+		Name:        tool.Function.Name,
+		Description: tool.Function.Description,
+	}
+	if properties, ok := tool.Function.Parameters["properties"].(map[string]interface{}); ok && len(properties) > 0 {
+		declaration.Parameters = geminiSchema(tool.Function.Parameters)
+	}
+	return declaration
+}
+
+// geminiSchema converts a JSON Schema to the OpenAPI subset Gemini accepts:
+// unsupported keywords are dropped, ["string", "null"] types become nullable,
+// const becomes a single-value enum and oneOf is treated as anyOf
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		switch key {
+		case "type":
+			typ, nullable := geminiSchemaType(value)
+			if typ != "" {
+				result["type"] = typ
+			}
+			if nullable {
+				result["nullable"] = true
+			}
+		case "const":
+			result["enum"] = []interface{}{value}
+		case "properties":
+			if properties, ok := value.(map[string]interface{}); ok {
+				converted := make(map[string]interface{}, len(properties))
+				for name, property := range properties {
+					if propertySchema, ok := property.(map[string]interface{}); ok {
+						converted[name] = geminiSchema(propertySchema)
+					}
+				}
+				result["properties"] = converted
+			}
+		case "items":
+			if items, ok := value.(map[string]interface{}); ok {
+				result["items"] = geminiSchema(items)
+			}
+		case "anyOf", "oneOf":
+			if variants, ok := value.([]interface{}); ok {
+				converted := make([]interface{}, 0, len(variants))
+				for _, variant := range variants {
+					if variantSchema, ok := variant.(map[string]interface{}); ok {
+						converted = append(converted, geminiSchema(variantSchema))
+					}
+				}
+				result["anyOf"] = converted
+			}
+		default:
+			if geminiSchemaKeys[key] {
+				result[key] = value
+			}
+		}
+	}
+	return result
+}
+
+// geminiSchemaType returns the single schema type and whether "null" was one of the allowed types
+func geminiSchemaType(value interface{}) (string, bool) {
+	var types []string
+	switch v := value.(type) {
+	case string:
+		types = []string{v}
+	case []string:
+		types = v
+	case []interface{}:
+		for _, t := range v {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+
+	var typ string
+	nullable := false
+	for _, t := range types {
+		if t == "null" {
+			nullable = true
+		} else if typ == "" {
+			typ = t
+		}
+	}
+	return typ, nullable
+}
+
+// convertGeminiToolChoice maps OpenAI-style tool_choice values to a function calling config
+func convertGeminiToolChoice(choice interface{}) *GeminiToolConfig {
+	config := func(mode string, names ...string) *GeminiToolConfig {
+		return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{Mode: mode, AllowedFunctionNames: names}}
+	}
+
+	switch v := choice.(type) {
+	case string:
+		switch v {
+		case "", "auto":
+			return nil
+		case "none":
+			return config("NONE")
+		case "required", "any":
+			return config("ANY")
+		default:
+			return config("ANY", v)
+		}
+	case map[string]interface{}:
+		// {"type": "function", "function": {"name": "..."}}
+		if function, ok := v["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok {
+				return config("ANY", name)
+			}
+		}
+	}
+	return nil
+}
+
+// endpoint returns the generateContent or streamGenerateContent URL for the model
+func (g *GeminiLLM) endpoint(stream bool) string {
+	method := geminiGenerateMethod
+	if stream {
+		method = geminiStreamMethod
+	}
+	return g.GetBaseURL() + "/" + geminiModelNamePrefix + g.GetModel() + method
+}
+
+// newHTTPRequest creates an HTTP request with Gemini headers
+func (g *GeminiLLM) newHTTPRequest(ctx context.Context, body []byte, stream bool) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.endpoint(stream), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", g.GetAPIKey())
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	for key, value := range g.GetCustomHeaders() {
+		httpReq.Header.Set(key, value)
+	}
+
+	return httpReq, nil
+}
+
+// makeAPICall makes a synchronous API call to Gemini
+func (g *GeminiLLM) makeAPICall(ctx context.Context, request *GeminiRequest) (*GeminiResponse, error) {
+	bodyBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make request with retries on transport errors, 429 and 5xx
+	var response *http.Response
+	var lastErr error
+
+	maxRetries := g.transportRetries(ctx)
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		httpReq, err := g.newHTTPRequest(ctx, bodyBytes, false)
+		if err != nil {
+			return nil, err
+		}
+
+		response, lastErr = g.doHTTP(httpReq)
+		observeHTTPAttempt(ctx, attempt, response)
+		if lastErr == nil && response.StatusCode != http.StatusTooManyRequests && response.StatusCode < 500 {
+			break
+		}
+
+		if attempt < maxRetries {
+			if lastErr == nil {
+				response.Body.Close()
+			}
+			waitTime := time.Duration(attempt+1) * time.Second
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(waitTime):
+			}
+		}
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("HTTP request failed after %d retries: %w", maxRetries, lastErr)
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			g.logger.Error("Failed to close response body",
+				logger.Field{Key: "error", Value: err})
+		}
+	}()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var geminiResponse GeminiResponse
+	if err := json.Unmarshal(responseBody, &geminiResponse); err != nil {
+		if response.StatusCode >= 400 {
+			return nil, fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(responseBody))
+		}
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if geminiResponse.Error != nil {
+		return nil, fmt.Errorf("Gemini API error: %s (status: %d %s)",
+			geminiResponse.Error.Message,
+			response.StatusCode,
+			geminiResponse.Error.Status)
+	}
+
+	if response.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	return &geminiResponse, nil
+}
+
+// streamAPICall handles streaming API calls; with alt=sse each event carries a partial GeminiResponse
+func (g *GeminiLLM) streamAPICall(ctx context.Context, request *GeminiRequest, responseChannel chan<- StreamResponse) {
+	defer close(responseChannel)
+
+	bodyBytes, err := json.Marshal(request)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("failed to marshal request: %w", err)}
+		return
+	}
+
+	httpReq, err := g.newHTTPRequest(ctx, bodyBytes, true)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: err}
+		return
+	}
+
+	response, err := g.doHTTP(httpReq)
+	observeHTTPAttempt(ctx, 0, response)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("HTTP request failed: %w", err)}
+		return
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			g.logger.Error("Failed to close response body",
+				logger.Field{Key: "error", Value: err})
+		}
+	}()
+
+	if response.StatusCode >= 400 {
+		body, _ := io.ReadAll(response.Body)
+		responseChannel <- StreamResponse{Error: fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(body))}
+		return
+	}
+
+	model := g.GetModel()
+	var usage Usage
+	var finishReason string
+	var toolCalls []ToolCall
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+		var chunk GeminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			g.LogError("Failed to parse streaming chunk",
+				logger.Field{Key: "data", Value: data},
+				logger.Field{Key: "error", Value: err},
+			)
+			continue
+		}
+
+		if chunk.Error != nil {
+			responseChannel <- StreamResponse{Error: fmt.Errorf("Gemini API error: %s (status: %s)", chunk.Error.Message, chunk.Error.Status)}
+			return
+		}
+		if err := geminiBlockedError(&chunk); err != nil {
+			responseChannel <- StreamResponse{Error: err}
+			return
+		}
+
+		if chunk.ModelVersion != "" {
+			model = chunk.ModelVersion
+		}
+		// Usage metadata is cumulative, the last chunk carries the totals
+		if chunk.UsageMetadata != nil {
+			usage = geminiUsage(chunk.UsageMetadata)
+		}
+		if len(chunk.Candidates) > 0 {
+			candidate := chunk.Candidates[0]
+			text, calls := geminiParts(candidate.Content.Parts, len(toolCalls))
+			if text != "" {
+				responseChannel <- StreamResponse{Delta: text}
+			}
+			toolCalls = append(toolCalls, calls...)
+			if candidate.FinishReason != "" {
+				finishReason = candidate.FinishReason
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			responseChannel <- StreamResponse{Error: ctx.Err()}
+			return
+		default:
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("scanner error: %w", err)}
+		return
+	}
+
+	usage.Cost = g.calculateCost(model, usage)
+	responseChannel <- StreamResponse{
+		FinishReason: convertGeminiFinishReason(finishReason, len(toolCalls) > 0),
+		Usage:        &usage,
+		ToolCalls:    toolCalls,
+	}
+}
+
+// geminiParts collects the text and function calls of response parts, skipping thought summaries.
+// Gemini may omit call IDs, in which case they are synthesized starting at offset
+func geminiParts(parts []GeminiPart, offset int) (string, []ToolCall) {
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, part := range parts {
+		switch {
+		case part.FunctionCall != nil:
+			args := part.FunctionCall.Args
+			if args == nil {
+				args = make(map[string]interface{})
+			}
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%d", offset+len(toolCalls))
+			}
+			arguments, _ := json.Marshal(args)
+			toolCalls = append(toolCalls, ToolCall{
+				ID:   id,
+				Type: "function",
+				Function: ToolCallFunction{
+					Name:      part.FunctionCall.Name,
+					Arguments: string(arguments),
+				},
+				Args: args,
+			})
+		case !part.Thought:
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String(), toolCalls
+}
+
+// geminiUsage maps usageMetadata to token usage; thinking tokens are billed as output
+func geminiUsage(metadata *GeminiUsageMetadata) Usage {
+	usage := Usage{
+		PromptTokens:     metadata.PromptTokenCount,
+		CompletionTokens: metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount,
+		TotalTokens:      metadata.TotalTokenCount,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage
+}
+
+// geminiBlockedError returns a ContentBlockedError when the prompt or the first candidate was blocked
+func geminiBlockedError(response *GeminiResponse) error {
+	if feedback := response.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		return &ContentBlockedError{
+			Provider: "gemini",
+			Reason:   feedback.BlockReason,
+			Category: geminiBlockedCategory(feedback.SafetyRatings),
+		}
+	}
+	if len(response.Candidates) > 0 && geminiBlockedFinishReasons[response.Candidates[0].FinishReason] {
+		candidate := response.Candidates[0]
+		return &ContentBlockedError{
+			Provider: "gemini",
+			Reason:   candidate.FinishReason,
+			Category: geminiBlockedCategory(candidate.SafetyRatings),
+		}
+	}
+	return nil
+}
+
+// geminiBlockedCategory returns the harm category that caused a block: the rating marked
+// as blocked, otherwise the one with the highest probability
+func geminiBlockedCategory(ratings []GeminiSafetyRating) string {
+	for _, rating := range ratings {
+		if rating.Blocked {
+			return rating.Category
+		}
+	}
+	for _, probability := range []string{"HIGH", "MEDIUM"} {
+		for _, rating := range ratings {
+			if rating.Probability == probability {
+				return rating.Category
+			}
+		}
+	}
+	return ""
+}
+
+// convertGeminiFinishReason maps Gemini finish reasons to OpenAI-style finish reasons
+func convertGeminiFinishReason(reason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	switch {
+	case reason == "" || reason == "STOP":
+		return "stop"
+	case reason == "MAX_TOKENS":
+		return "length"
+	case geminiBlockedFinishReasons[reason]:
+		return "content_filter"
+	default:
+		return strings.ToLower(reason)
+	}
+}
+
+// convertResponse converts a Gemini response to internal format; blocked prompts and
+// candidates are returned as a ContentBlockedError
+func (g *GeminiLLM) convertResponse(response *GeminiResponse) (*Response, error) {
+	if err := geminiBlockedError(response); err != nil {
+		return nil, err
+	}
+
+	model := response.ModelVersion
+	if model == "" {
+		model = g.GetModel()
+	}
+
+	var usage Usage
+	if response.UsageMetadata != nil {
+		usage = geminiUsage(response.UsageMetadata)
+	}
+	usage.Cost = g.calculateCost(model, usage)
+
+	result := &Response{
+		Usage: usage,
+		Model: model,
+		Metadata: map[string]interface{}{
+			"response_id": response.ResponseID,
+		},
+	}
+
+	if len(response.Candidates) == 0 {
+		result.FinishReason = "stop"
+		return result, nil
+	}
+
+	candidate := response.Candidates[0]
+	result.Content, result.ToolCalls = geminiParts(candidate.Content.Parts, 0)
+	result.FinishReason = convertGeminiFinishReason(candidate.FinishReason, len(result.ToolCalls) > 0)
+	result.Metadata["finish_reason"] = candidate.FinishReason
+
+	return result, nil
+}
@@ -0,0 +1,323 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewGeminiLLM(t *testing.T) {
+	llm := NewGeminiLLM("models/gemini-1.5-pro-002", WithAPIKey("test-key"))
+
+	if llm.GetProvider() != "gemini" {
+		t.Errorf("Expected provider 'gemini', got %s", llm.GetProvider())
+	}
+	if llm.GetModel() != "gemini-1.5-pro-002" {
+		t.Errorf("Expected the models/ prefix to be stripped, got %s", llm.GetModel())
+	}
+	if llm.GetBaseURL() != defaultGeminiBaseURL {
+		t.Errorf("Expected base URL %s, got %s", defaultGeminiBaseURL, llm.GetBaseURL())
+	}
+	if !llm.SupportsFunctionCalling() {
+		t.Error("Expected function calling support to be true")
+	}
+
+	windows := map[string]int{
+		"gemini-1.5-pro-002":        2097152,
+		"gemini-1.5-flash-8b-001":   1048576,
+		"gemini-2.0-flash-lite-001": 1048576,
+		"gemini-1.0-pro":            32760,
+		"gemini-exp-1206":           defaultGeminiContextWindow,
+	}
+	for model, expected := range windows {
+		if window := NewGeminiLLM(model).GetContextWindowSize(); window != expected {
+			t.Errorf("Expected context window %d for %s, got %d", expected, model, window)
+		}
+	}
+}
+
+func TestConvertGeminiMessages(t *testing.T) {
+	messages := []Message{
+		{Role: RoleSystem, Content: "You are helpful."},
+		{Role: RoleUser, Content: "What's the weather in Paris and Rome?"},
+		{Role: RoleAssistant, Content: "Let me check.", ToolCalls: []ToolCall{
+			{ID: "call_0", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: `{"city":"Paris"}`}},
+			{ID: "call_1", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: `{"city":"Rome"}`}},
+		}},
+		{Role: RoleTool, Content: `{"forecast":"Sunny"}`, ToolCallID: "call_0"},
+		{Role: RoleTool, Content: "Rainy", Name: "weather", ToolCallID: "call_1"},
+		{Role: RoleUser, Content: "Summarize."},
+	}
+
+	system, contents := convertGeminiMessages(messages)
+	if system == nil || len(system.Parts) != 1 || system.Parts[0].Text != "You are helpful." {
+		t.Errorf("unexpected system instruction: %+v", system)
+	}
+	if len(contents) != 3 {
+		t.Fatalf("expected 3 contents, got %d: %+v", len(contents), contents)
+	}
+
+	model := contents[1]
+	if model.Role != "model" || len(model.Parts) != 3 {
+		t.Fatalf("unexpected model content: %+v", model)
+	}
+	if call := model.Parts[1].FunctionCall; call == nil || call.Name != "weather" || call.Args["city"] != "Paris" || call.ID != "" {
+		t.Errorf("unexpected function call part: %+v", model.Parts[1].FunctionCall)
+	}
+
+	// 工具结果与随后的用户消息合并为一条user内容
+	results := contents[2]
+	if results.Role != "user" || len(results.Parts) != 3 {
+		t.Fatalf("unexpected function response content: %+v", results)
+	}
+	first := results.Parts[0].FunctionResponse
+	if first == nil || first.Name != "weather" || first.Response["forecast"] != "Sunny" {
+		t.Errorf("tool result without a name should match the pending call, got %+v", first)
+	}
+	if second := results.Parts[1].FunctionResponse; second == nil || second.Response["result"] != "Rainy" {
+		t.Errorf("non-JSON tool results should be wrapped, got %+v", second)
+	}
+	if results.Parts[2].Text != "Summarize." {
+		t.Errorf("unexpected trailing text part: %+v", results.Parts[2])
+	}
+}
+
+func TestGeminiLLM_BuildRequest(t *testing.T) {
+	llm := NewGeminiLLM("gemini-2.0-flash")
+	temperature := 0.3
+	maxTokens := 256
+	options := &CallOptions{
+		Temperature:   &temperature,
+		MaxTokens:     &maxTokens,
+		StopSequences: []string{"END"},
+		Tools: []Tool{
+			{Type: "function", Function: ToolSchema{Name: "search", Description: "Search the web",
+				Parameters: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}}},
+		},
+		ToolChoice: map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "search"}},
+	}
+
+	request := llm.buildRequest(nil, nil, options)
+	config := request.GenerationConfig
+	if config == nil || *config.Temperature != 0.3 || *config.MaxOutputTokens != 256 || config.StopSequences[0] != "END" {
+		t.Errorf("unexpected generation config: %+v", config)
+	}
+	if len(request.Tools) != 1 || request.Tools[0].FunctionDeclarations[0].Parameters != nil {
+		t.Errorf("tools without properties should omit parameters: %+v", request.Tools)
+	}
+	calling := request.ToolConfig.FunctionCallingConfig
+	if calling.Mode != "ANY" || len(calling.AllowedFunctionNames) != 1 || calling.AllowedFunctionNames[0] != "search" {
+		t.Errorf("unexpected function calling config: %+v", calling)
+	}
+
+	if request := llm.buildRequest(nil, nil, &CallOptions{}); request.GenerationConfig != nil || request.ToolConfig != nil {
+		t.Errorf("expected no generation or tool config, got %+v", request)
+	}
+	if config := convertGeminiToolChoice("none"); config == nil || config.FunctionCallingConfig.Mode != "NONE" {
+		t.Errorf("expected NONE mode, got %+v", config)
+	}
+}
+
+func TestGeminiLLM_Call_ToolCallsRoundTrip(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/gemini_tool_calls.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	var requests []GeminiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.0-flash:generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("expected API key header, got %q", r.Header.Get("x-goog-api-key"))
+		}
+		var request GeminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, request)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	llm := NewGeminiLLM("gemini-2.0-flash", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	messages := []Message{
+		{Role: RoleSystem, Content: "Use the weather tool."},
+		{Role: RoleUser, Content: "Weather in Shanghai and Beijing?"},
+	}
+
+	response, err := llm.Call(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got %s", response.FinishReason)
+	}
+	if len(response.ToolCalls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %d", len(response.ToolCalls))
+	}
+	if response.ToolCalls[0].ID != "call_0" || response.ToolCalls[1].ID != "call_1" {
+		t.Errorf("Expected synthesized tool call IDs, got %s, %s", response.ToolCalls[0].ID, response.ToolCalls[1].ID)
+	}
+	if second := response.ToolCalls[1]; second.Function.Name != "get_weather" || second.Args["city"] != "Beijing" ||
+		second.Function.Arguments != `{"city":"Beijing","unit":"celsius"}` {
+		t.Errorf("Unexpected second tool call: %+v", second)
+	}
+	if response.Usage.PromptTokens != 64 || response.Usage.CompletionTokens != 22 || response.Usage.TotalTokens != 86 {
+		t.Errorf("Unexpected usage: %+v", response.Usage)
+	}
+	if response.Usage.Cost <= 0 {
+		t.Errorf("Expected cost from gemini-2.0-flash pricing, got %f", response.Usage.Cost)
+	}
+
+	// 把工具调用和结果送回模型
+	messages = append(messages,
+		Message{Role: RoleAssistant, Content: nil, ToolCalls: response.ToolCalls},
+		Message{Role: RoleTool, Content: "22°C, cloudy", ToolCallID: response.ToolCalls[0].ID},
+		Message{Role: RoleTool, Content: "18°C, sunny", ToolCallID: response.ToolCalls[1].ID},
+	)
+	if _, err := llm.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Expected no error sending tool results, got %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	sent := requests[1]
+	if sent.SystemInstruction == nil || sent.SystemInstruction.Parts[0].Text != "Use the weather tool." {
+		t.Errorf("Unexpected system instruction: %+v", sent.SystemInstruction)
+	}
+	if len(sent.Contents) != 3 {
+		t.Fatalf("Expected 3 contents, got %d", len(sent.Contents))
+	}
+	if model := sent.Contents[1]; model.Role != "model" || len(model.Parts) != 2 || model.Parts[1].FunctionCall.Args["unit"] != "celsius" {
+		t.Errorf("Unexpected model content: %+v", model)
+	}
+	results := sent.Contents[2]
+	if results.Role != "user" || len(results.Parts) != 2 {
+		t.Fatalf("Expected both tool results in one user content, got %+v", results)
+	}
+	if response := results.Parts[1].FunctionResponse; response == nil || response.Name != "get_weather" || response.Response["result"] != "18°C, sunny" {
+		t.Errorf("Unexpected function response: %+v", results.Parts[1].FunctionResponse)
+	}
+}
+
+func TestGeminiLLM_CallStream_AggregatesChunks(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/gemini_stream.txt")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.0-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected stream URL %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	llm := NewGeminiLLM("gemini-2.0-flash", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	respChan, err := llm.CallStream(context.Background(), []Message{{Role: RoleUser, Content: "Weather in Shanghai?"}}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var content strings.Builder
+	var final StreamResponse
+	for response := range respChan {
+		if response.Error != nil {
+			t.Fatalf("Unexpected error in stream: %v", response.Error)
+		}
+		content.WriteString(response.Delta)
+		if response.FinishReason != "" {
+			final = response
+		}
+	}
+
+	if content.String() != "Shanghai is 22°C and cloudy today." {
+		t.Errorf("Unexpected aggregated content: %q", content.String())
+	}
+	if final.FinishReason != "stop" {
+		t.Errorf("Expected finish reason 'stop', got %s", final.FinishReason)
+	}
+	if final.Usage == nil || final.Usage.PromptTokens != 12 || final.Usage.CompletionTokens != 9 || final.Usage.TotalTokens != 21 {
+		t.Errorf("Expected usage from the last chunk, got %+v", final.Usage)
+	}
+}
+
+func TestGeminiLLM_Call_SafetyBlock(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/gemini_safety_block.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	llm := NewGeminiLLM("gemini-1.5-flash", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	_, err = llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "..."}}, nil)
+	if !errors.Is(err, ErrContentBlocked) {
+		t.Fatalf("Expected ErrContentBlocked, got %v", err)
+	}
+
+	var blocked *ContentBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Expected *ContentBlockedError, got %T", err)
+	}
+	if blocked.Provider != "gemini" || blocked.Reason != "SAFETY" || blocked.Category != "HARM_CATEGORY_DANGEROUS_CONTENT" {
+		t.Errorf("Unexpected blocked error: %+v", blocked)
+	}
+
+	// 提示词本身被拦截时没有候选结果
+	response := &GeminiResponse{PromptFeedback: &GeminiPromptFeedback{
+		BlockReason:   "SAFETY",
+		SafetyRatings: []GeminiSafetyRating{{Category: "HARM_CATEGORY_HARASSMENT", Probability: "HIGH"}},
+	}}
+	if _, err := llm.convertResponse(response); !errors.As(err, &blocked) || blocked.Category != "HARM_CATEGORY_HARASSMENT" {
+		t.Errorf("Expected a blocked prompt to return the category, got %v", err)
+	}
+}
+
+func TestGeminiLLM_Call_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":400,"message":"API key not valid.","status":"INVALID_ARGUMENT"}}`))
+	}))
+	defer server.Close()
+
+	llm := NewGeminiLLM("gemini-2.0-flash", WithAPIKey("bad-key"), WithBaseURL(server.URL))
+	_, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "API key not valid.") || !strings.Contains(err.Error(), "status: 400") {
+		t.Errorf("Expected Gemini API error with status, got %v", err)
+	}
+}
+
+func TestCreateLLMGemini(t *testing.T) {
+	llm, err := CreateLLM(&Config{Provider: "gemini", Model: "gemini-2.0-flash", APIKey: "key", MaxRetries: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	geminiLLM, ok := llm.(*GeminiLLM)
+	if !ok {
+		t.Fatalf("expected *GeminiLLM, got %T", llm)
+	}
+	if geminiLLM.GetAPIKey() != "key" || geminiLLM.GetMaxRetries() != 1 {
+		t.Errorf("config not applied: key=%q retries=%d", geminiLLM.GetAPIKey(), geminiLLM.GetMaxRetries())
+	}
+
+	if _, err := CreateLLM(&Config{Provider: "gemini"}); err == nil {
+		t.Error("expected error for missing model")
+	}
+}
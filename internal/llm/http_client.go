@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HTTPClientConfig configures the pooled HTTP transport used for outbound API calls
+type HTTPClientConfig struct {
+	// Proxy is the outbound proxy URL. When empty the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables are honoured.
+	Proxy string `json:"proxy,omitempty"`
+	// TLSConfig customises TLS, e.g. a RootCAs pool that trusts a corporate CA
+	TLSConfig           *tls.Config   `json:"-"`
+	MaxIdleConns        int           `json:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `json:"idle_conn_timeout"`
+	DialTimeout         time.Duration `json:"dial_timeout"`
+	TLSHandshakeTimeout time.Duration `json:"tls_handshake_timeout"`
+}
+
+// DefaultHTTPClientConfig returns the transport settings used by the shared client
+func DefaultHTTPClientConfig() HTTPClientConfig {
+	return HTTPClientConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+var errMissingProxyHost = errors.New("proxy URL has no host")
+
+// NewHTTPTransport builds a transport from config. An invalid proxy URL does not panic;
+// every request sent through the transport fails with the parse error instead.
+func NewHTTPTransport(config HTTPClientConfig) *http.Transport {
+	proxy := http.ProxyFromEnvironment
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err == nil && proxyURL.Host == "" {
+			err = &url.Error{Op: "parse", URL: config.Proxy, Err: errMissingProxyHost}
+		}
+		if err != nil {
+			proxy = func(*http.Request) (*url.URL, error) { return nil, err }
+		} else {
+			proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLSConfig != nil {
+		tlsConfig = config.TLSConfig.Clone()
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           (&net.Dialer{Timeout: config.DialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// NewHTTPClient builds a client with its own transport from config.
+// The client has no overall timeout; deadlines come from the request context.
+func NewHTTPClient(config HTTPClientConfig) *http.Client {
+	return &http.Client{Transport: NewHTTPTransport(config)}
+}
+
+// shared holds the package-level transport that providers, embedders, external memory
+// and web tools reuse so that connections are pooled across the whole process
+var shared = struct {
+	mu        sync.RWMutex
+	config    HTTPClientConfig
+	transport *http.Transport
+}{config: DefaultHTTPClientConfig()}
+
+// ConfigureHTTPClient replaces the shared transport. Clients created earlier by
+// NewSharedHTTPClient pick up the new transport on their next request; idle
+// connections of the previous transport are closed.
+func ConfigureHTTPClient(config HTTPClientConfig) {
+	transport := NewHTTPTransport(config)
+
+	shared.mu.Lock()
+	previous := shared.transport
+	shared.config = config
+	shared.transport = transport
+	shared.mu.Unlock()
+
+	if previous != nil {
+		previous.CloseIdleConnections()
+	}
+}
+
+// SharedHTTPClientConfig returns the configuration of the shared transport
+func SharedHTTPClientConfig() HTTPClientConfig {
+	shared.mu.RLock()
+	defer shared.mu.RUnlock()
+	return shared.config
+}
+
+// sharedTransport returns the shared transport, creating it on first use
+func sharedTransport() *http.Transport {
+	shared.mu.RLock()
+	transport := shared.transport
+	shared.mu.RUnlock()
+	if transport != nil {
+		return transport
+	}
+
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.transport == nil {
+		shared.transport = NewHTTPTransport(shared.config)
+	}
+	return shared.transport
+}
+
+// SharedHTTPTransport is a RoundTripper that always delegates to the current shared transport
+var SharedHTTPTransport http.RoundTripper = sharedRoundTripper{}
+
+type sharedRoundTripper struct{}
+
+func (sharedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return sharedTransport().RoundTrip(req)
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the shared transport
+func (sharedRoundTripper) CloseIdleConnections() {
+	sharedTransport().CloseIdleConnections()
+}
+
+// NewSharedHTTPClient is the default client factory. The returned client shares the pooled
+// transport configured by ConfigureHTTPClient; timeout is the client's overall timeout,
+// zero leaves deadlines to the request context. Creating a client per component is cheap,
+// only the transport holds connections.
+func NewSharedHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: SharedHTTPTransport, Timeout: timeout}
+}
+
+// cancelOnClose releases the request's timeout context once the response body is closed,
+// so that the deadline also covers reading (and streaming) the body
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// doHTTP sends req with the LLM's client. The LLM timeout is applied as a context deadline
+// per attempt rather than on the client, so that LLMs sharing a client keep their own
+// timeouts. It composes with the caller's context and with a client Timeout set through
+// WithHTTPClient: whichever deadline is earliest wins.
+func (b *BaseLLM) doHTTP(req *http.Request) (*http.Response, error) {
+	if b.timeout <= 0 {
+		return b.client.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), b.timeout)
+	response, err := b.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
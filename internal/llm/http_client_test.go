@@ -0,0 +1,220 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const httpClientTestResponse = `{
+	"id": "chatcmpl-1",
+	"object": "chat.completion",
+	"model": "gpt-4",
+	"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}],
+	"usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}
+}`
+
+// connCountingServer is an httptest server that counts the TCP connections it accepts
+type connCountingServer struct {
+	*httptest.Server
+	connections atomic.Int32
+}
+
+func newConnCountingServer(handler http.HandlerFunc) *connCountingServer {
+	server := &connCountingServer{}
+	server.Server = httptest.NewUnstartedServer(handler)
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			server.connections.Add(1)
+		}
+	}
+	server.Start()
+	return server
+}
+
+func TestWithProxy_RequestsTraverseProxy(t *testing.T) {
+	var mu sync.Mutex
+	var proxied []string
+	proxy := newConnCountingServer(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL of the upstream target
+		mu.Lock()
+		proxied = append(proxied, r.RequestURI)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(httpClientTestResponse))
+	})
+	defer proxy.Close()
+
+	llm := NewOpenAILLM("gpt-4",
+		WithAPIKey("test-key"),
+		WithBaseURL("http://api.upstream.test/v1"),
+		WithProxy(proxy.URL),
+		WithMaxIdleConns(4),
+		WithMaxRetries(0),
+	)
+	if llm.GetHTTPClient().Transport == SharedHTTPTransport {
+		t.Fatal("expected transport options to create a dedicated transport")
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(proxied) != 3 {
+		t.Fatalf("expected 3 requests through the proxy, got %d", len(proxied))
+	}
+	for _, uri := range proxied {
+		if uri != "http://api.upstream.test/v1/chat/completions" {
+			t.Errorf("expected the proxy to receive the upstream URL, got %q", uri)
+		}
+	}
+	if got := proxy.connections.Load(); got != 1 {
+		t.Errorf("expected sequential calls to reuse one connection, got %d connections", got)
+	}
+}
+
+func TestWithProxy_InvalidURL(t *testing.T) {
+	llm := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL("http://api.upstream.test/v1"),
+		WithProxy("proxy.internal:3128"), WithMaxRetries(0))
+
+	_, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err == nil || !errors.Is(err, errMissingProxyHost) {
+		t.Errorf("expected the invalid proxy URL to fail the request, got %v", err)
+	}
+}
+
+func TestSharedHTTPClient_ReusesConnections(t *testing.T) {
+	server := newConnCountingServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/embeddings") {
+			w.Write([]byte(`{"data": [{"embedding": [0.1, 0.2], "index": 0}], "model": "text-embedding-3-small", "usage": {"prompt_tokens": 1, "total_tokens": 1}}`))
+			return
+		}
+		w.Write([]byte(httpClientTestResponse))
+	})
+	defer server.Close()
+
+	chat := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	embedder := NewOpenAIEmbedder("text-embedding-3-small", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	if chat.GetHTTPClient().Transport != SharedHTTPTransport || embedder.GetHTTPClient().Transport != SharedHTTPTransport {
+		t.Fatal("expected LLMs without transport options to use the shared transport")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := chat.Call(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil); err != nil {
+			t.Fatalf("chat call failed: %v", err)
+		}
+		if _, err := embedder.Embed(context.Background(), []string{"hi"}); err != nil {
+			t.Fatalf("embed call failed: %v", err)
+		}
+	}
+
+	if got := server.connections.Load(); got != 1 {
+		t.Errorf("expected the chat model and embedder to share one pooled connection, got %d connections", got)
+	}
+}
+
+func TestConfigureHTTPClient(t *testing.T) {
+	previous := SharedHTTPClientConfig()
+	defer ConfigureHTTPClient(previous)
+
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(httpClientTestResponse))
+	}))
+	defer proxy.Close()
+
+	// LLMs created before the shared transport is configured pick up the change
+	llm := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL("http://api.upstream.test/v1"), WithMaxRetries(0))
+
+	config := DefaultHTTPClientConfig()
+	config.Proxy = proxy.URL
+	ConfigureHTTPClient(config)
+	if got := SharedHTTPClientConfig().Proxy; got != proxy.URL {
+		t.Errorf("expected the shared config to be updated, got proxy %q", got)
+	}
+
+	if _, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if _, err := NewSharedHTTPClient(time.Second).Get("http://other.upstream.test/health"); err != nil {
+		t.Fatalf("shared client request failed: %v", err)
+	}
+	if got := proxied.Load(); got != 2 {
+		t.Errorf("expected both requests through the configured proxy, got %d", got)
+	}
+}
+
+func TestWithTimeout_ComposesWithContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(httpClientTestResponse))
+	}))
+	defer server.Close()
+
+	messages := []Message{{Role: RoleUser, Content: "hi"}}
+	newLLM := func(timeout time.Duration) *OpenAILLM {
+		return NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL(server.URL),
+			WithTimeout(timeout), WithMaxRetries(0))
+	}
+
+	t.Run("llm timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := newLLM(50*time.Millisecond).Call(context.Background(), messages, nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the LLM timeout to cancel the request, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+			t.Errorf("expected the request to stop at the LLM timeout, took %v", elapsed)
+		}
+	})
+
+	t.Run("context deadline is shorter", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := newLLM(time.Minute).Call(ctx, messages, nil); err == nil {
+			t.Error("expected the context deadline to cancel the request")
+		}
+		if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+			t.Errorf("expected the request to stop at the context deadline, took %v", elapsed)
+		}
+	})
+
+	t.Run("timeouts are per LLM", func(t *testing.T) {
+		// A short timeout on one LLM must not leak into other LLMs sharing the client
+		newLLM(time.Millisecond)
+		if _, err := newLLM(5*time.Second).Call(context.Background(), messages, nil); err != nil {
+			t.Errorf("expected the slow call to succeed within its own timeout, got %v", err)
+		}
+		if timeout := NewSharedHTTPClient(0).Timeout; timeout != 0 {
+			t.Errorf("expected the shared client to have no overall timeout, got %v", timeout)
+		}
+	})
+
+	t.Run("client timeout", func(t *testing.T) {
+		llm := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL(server.URL), WithMaxRetries(0),
+			WithTimeout(time.Minute), WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}))
+		if _, err := llm.Call(context.Background(), messages, nil); err == nil {
+			t.Error("expected the client timeout to apply in addition to the LLM timeout")
+		}
+	})
+}
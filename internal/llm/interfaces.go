@@ -2,6 +2,8 @@ package llm
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ynl/greensoulai/pkg/events"
@@ -12,6 +14,11 @@ type Message struct {
 	Role    Role        `json:"role"`
 	Content interface{} `json:"content"`
 	Name    string      `json:"name,omitempty"`
+
+	// ToolCalls holds the tool calls requested by an assistant message
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID links a tool message to the tool call it answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 // Role represents the role of a message sender
@@ -29,17 +36,20 @@ type Usage struct {
 	PromptTokens     int     `json:"prompt_tokens"`
 	CompletionTokens int     `json:"completion_tokens"`
 	TotalTokens      int     `json:"total_tokens"`
+	ImageTokens      int     `json:"image_tokens,omitempty"` // provider-reported prompt tokens spent on images, already included in PromptTokens
 	Cost             float64 `json:"cost,omitempty"`
 }
 
 // Response represents an LLM response
 type Response struct {
-	Content      string                 `json:"content"`
-	Usage        Usage                  `json:"usage"`
-	Model        string                 `json:"model"`
-	FinishReason string                 `json:"finish_reason,omitempty"`
-	ToolCalls    []ToolCall             `json:"tool_calls,omitempty"`
-	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Content           string                 `json:"content"`
+	Usage             Usage                  `json:"usage"`
+	Model             string                 `json:"model"`
+	FinishReason      string                 `json:"finish_reason,omitempty"`
+	ToolCalls         []ToolCall             `json:"tool_calls,omitempty"`
+	Refusal           string                 `json:"refusal,omitempty"`            // structured output refusal, set instead of Content
+	SystemFingerprint string                 `json:"system_fingerprint,omitempty"` // provider backend configuration; seeded responses only reproduce while it stays the same
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // StreamResponse represents a streaming LLM response
@@ -49,6 +59,7 @@ type StreamResponse struct {
 	FinishReason string     `json:"finish_reason,omitempty"`
 	Error        error      `json:"error,omitempty"`
 	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	Refusal      string     `json:"refusal,omitempty"` // next refusal fragment, streamed separately from Delta
 }
 
 // ToolCall represents a function/tool call
@@ -96,7 +107,7 @@ type CallOptions struct {
 	Seed           *int            `json:"seed,omitempty"`            // 随机种子
 	Logprobs       *int            `json:"logprobs,omitempty"`        // 返回logprobs数量
 	TopLogprobs    *int            `json:"top_logprobs,omitempty"`    // 返回top logprobs
-	ResponseFormat interface{}     `json:"response_format,omitempty"` // 响应格式
+	ResponseFormat interface{}     `json:"response_format,omitempty"` // structured output format, see ResponseFormat
 	LogitBias      map[int]float64 `json:"logit_bias,omitempty"`      // logit偏置
 	User           string          `json:"user,omitempty"`            // 用户ID
 	Timeout        *time.Duration  `json:"timeout,omitempty"`         // 请求超时
@@ -113,6 +124,32 @@ type CallOptions struct {
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// ErrStreamingNotSupported is returned by CallStream implementations that cannot stream.
+// Callers may fall back to Call when they receive it.
+var ErrStreamingNotSupported = errors.New("streaming not supported")
+
+// ErrContentBlocked matches every ContentBlockedError
+var ErrContentBlocked = errors.New("content blocked")
+
+// ContentBlockedError reports a prompt or response the provider withheld for safety or policy reasons
+type ContentBlockedError struct {
+	Provider string // provider that blocked the content
+	Reason   string // provider finish or block reason, e.g. SAFETY
+	Category string // harm category that triggered the block, empty when unknown
+}
+
+func (e *ContentBlockedError) Error() string {
+	if e.Category != "" {
+		return fmt.Sprintf("%s: content blocked (%s, category: %s)", e.Provider, e.Reason, e.Category)
+	}
+	return fmt.Sprintf("%s: content blocked (%s)", e.Provider, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrContentBlocked) match
+func (e *ContentBlockedError) Is(target error) bool {
+	return target == ErrContentBlocked
+}
+
 // LLM defines the interface for language model implementations
 type LLM interface {
 	// Call sends a synchronous request to the LLM
@@ -130,6 +167,11 @@ type LLM interface {
 	// GetContextWindowSize returns the maximum context window size
 	GetContextWindowSize() int
 
+	// GetTokenizer returns the tokenizer that counts prompt tokens for the model.
+	// Implementations based on BaseLLM use TokenizerForModel, which is only tiktoken-exact once
+	// the model's vocabulary is cached locally and falls back to ScriptHeuristicTokenizer before that
+	GetTokenizer() Tokenizer
+
 	// SetEventBus sets the event bus for emitting events
 	SetEventBus(eventBus events.EventBus)
 
@@ -225,6 +267,7 @@ func WithSeed(seed int) CallOption {
 }
 
 // WithResponseFormat sets the response format, 对标Python版本
+// format may be a *ResponseFormat, an OpenAI-style map or a type string such as "json_object"
 func WithResponseFormat(format interface{}) CallOption {
 	return func(opts *CallOptions) {
 		opts.ResponseFormat = format
@@ -0,0 +1,357 @@
+package llmtest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+)
+
+// RecordEnvVar switches GoldenLLM from replaying golden files to recording them
+const RecordEnvVar = "GREENSOULAI_LLM_RECORD"
+
+// Normalizer rewrites request messages before they are hashed, so that values that change
+// between runs (IDs, timestamps) do not prevent a recording from matching
+type Normalizer func(messages []llm.Message) []llm.Message
+
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// NormalizeMessages is the default Normalizer: it unifies line endings, trims surrounding
+// whitespace and replaces UUIDs in string contents
+func NormalizeMessages(messages []llm.Message) []llm.Message {
+	normalized := make([]llm.Message, len(messages))
+	for i, msg := range messages {
+		normalized[i] = msg
+		if content, ok := msg.Content.(string); ok {
+			content = strings.ReplaceAll(content, "\r\n", "\n")
+			content = uuidPattern.ReplaceAllString(strings.TrimSpace(content), "<uuid>")
+			normalized[i].Content = content
+		}
+	}
+	return normalized
+}
+
+// RequestHash returns the key of a request in golden files. It covers the normalized messages,
+// the response-affecting options and whether the request is streamed, but not the model, so
+// recordings can be replayed by an LLM that was configured differently
+func RequestHash(messages []llm.Message, options *llm.CallOptions, stream bool, normalize Normalizer) (string, error) {
+	if normalize == nil {
+		normalize = NormalizeMessages
+	}
+	key, err := llm.CacheKey("", normalize(messages), options)
+	if err != nil {
+		return "", err
+	}
+	mode := "call"
+	if stream {
+		mode = "stream"
+	}
+	sum := sha256.Sum256([]byte(mode + ":" + key))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GoldenOption configures a RecordingLLM or ReplayLLM
+type GoldenOption func(*goldenConfig)
+
+type goldenConfig struct {
+	normalize       Normalizer
+	model           string
+	functionCalling bool
+}
+
+// WithNormalizer replaces NormalizeMessages; recording and replay must use the same normalizer
+func WithNormalizer(normalize Normalizer) GoldenOption {
+	return func(c *goldenConfig) { c.normalize = normalize }
+}
+
+// WithReplayModel sets the model a ReplayLLM reports from GetModel
+func WithReplayModel(model string) GoldenOption {
+	return func(c *goldenConfig) { c.model = model }
+}
+
+// WithReplayFunctionCalling sets what a ReplayLLM reports from SupportsFunctionCalling. It must
+// match the recorded provider, since agents build different requests with native tool calling
+func WithReplayFunctionCalling(supported bool) GoldenOption {
+	return func(c *goldenConfig) { c.functionCalling = supported }
+}
+
+func newGoldenConfig(opts []GoldenOption) goldenConfig {
+	config := goldenConfig{normalize: NormalizeMessages, model: "replay"}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// goldenFile is the content of one golden file: a request and the replies recorded for it in
+// order, so a test that sends the same request several times replays the same sequence
+type goldenFile struct {
+	Model    string        `json:"model"`
+	Stream   bool          `json:"stream"`
+	Messages []llm.Message `json:"messages"`
+	Replies  []goldenReply `json:"replies"`
+}
+
+type goldenReply struct {
+	Response *llm.Response `json:"response,omitempty"`
+	Chunks   []goldenChunk `json:"chunks,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// goldenChunk is an llm.StreamResponse whose error is stored as text
+type goldenChunk struct {
+	Delta        string         `json:"delta,omitempty"`
+	Usage        *llm.Usage     `json:"usage,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	ToolCalls    []llm.ToolCall `json:"tool_calls,omitempty"`
+	Refusal      string         `json:"refusal,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+func newGoldenChunk(chunk llm.StreamResponse) goldenChunk {
+	recorded := goldenChunk{
+		Delta:        chunk.Delta,
+		Usage:        chunk.Usage,
+		FinishReason: chunk.FinishReason,
+		ToolCalls:    chunk.ToolCalls,
+		Refusal:      chunk.Refusal,
+	}
+	if chunk.Error != nil {
+		recorded.Error = chunk.Error.Error()
+	}
+	return recorded
+}
+
+func (c goldenChunk) streamResponse() llm.StreamResponse {
+	chunk := llm.StreamResponse{
+		Delta:        c.Delta,
+		Usage:        c.Usage,
+		FinishReason: c.FinishReason,
+		ToolCalls:    c.ToolCalls,
+		Refusal:      c.Refusal,
+	}
+	if c.Error != "" {
+		chunk.Error = errors.New(c.Error)
+	}
+	return chunk
+}
+
+// goldenPath returns the golden file of a request hash
+func goldenPath(dir, hash string) string {
+	return filepath.Join(dir, hash[:16]+".json")
+}
+
+// RecordingLLM wraps a real provider and writes every request and its reply to a golden file
+// in dir. Files are rewritten on the first matching request of a RecordingLLM, so re-recording
+// replaces stale replies
+type RecordingLLM struct {
+	llm.LLM
+	dir    string
+	config goldenConfig
+
+	mu       sync.Mutex
+	recorded map[string]*goldenFile
+}
+
+// NewRecordingLLM creates a RecordingLLM recording the calls of inner into dir
+func NewRecordingLLM(inner llm.LLM, dir string, opts ...GoldenOption) *RecordingLLM {
+	return &RecordingLLM{
+		LLM:      inner,
+		dir:      dir,
+		config:   newGoldenConfig(opts),
+		recorded: make(map[string]*goldenFile),
+	}
+}
+
+// Call implements llm.LLM
+func (r *RecordingLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	response, err := r.LLM.Call(ctx, messages, options)
+
+	reply := goldenReply{Response: response}
+	if err != nil {
+		reply = goldenReply{Error: err.Error()}
+	}
+	if recordErr := r.record(messages, options, false, reply); recordErr != nil {
+		return nil, recordErr
+	}
+	return response, err
+}
+
+// CallStream implements llm.LLM. The stream is forwarded unchanged and recorded once it ends
+func (r *RecordingLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	stream, err := r.LLM.CallStream(ctx, messages, options)
+	if err != nil {
+		if recordErr := r.record(messages, options, true, goldenReply{Error: err.Error()}); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+
+	forwarded := make(chan llm.StreamResponse)
+	go func() {
+		defer close(forwarded)
+		var chunks []goldenChunk
+		for chunk := range stream {
+			chunks = append(chunks, newGoldenChunk(chunk))
+			forwarded <- chunk
+		}
+		if recordErr := r.record(messages, options, true, goldenReply{Chunks: chunks}); recordErr != nil {
+			forwarded <- llm.StreamResponse{Error: recordErr}
+		}
+	}()
+	return forwarded, nil
+}
+
+// record appends reply to the golden file of the request and rewrites the file
+func (r *RecordingLLM) record(messages []llm.Message, options *llm.CallOptions, stream bool, reply goldenReply) error {
+	hash, err := RequestHash(messages, options, stream, r.config.normalize)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, ok := r.recorded[hash]
+	if !ok {
+		file = &goldenFile{Model: r.LLM.GetModel(), Stream: stream, Messages: r.config.normalize(messages)}
+		r.recorded[hash] = file
+	}
+	file.Replies = append(file.Replies, reply)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("llmtest: failed to encode recording: %w", err)
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("llmtest: failed to create recording directory: %w", err)
+	}
+	if err := os.WriteFile(goldenPath(r.dir, hash), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("llmtest: failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// ReplayLLM answers calls from the golden files written by a RecordingLLM. Requests are matched
+// by RequestHash; repeated requests get the recorded replies in order and then the last one again
+type ReplayLLM struct {
+	dir    string
+	config goldenConfig
+
+	mu     sync.Mutex
+	served map[string]int
+}
+
+// NewReplayLLM creates a ReplayLLM serving the golden files in dir
+func NewReplayLLM(dir string, opts ...GoldenOption) *ReplayLLM {
+	return &ReplayLLM{dir: dir, config: newGoldenConfig(opts), served: make(map[string]int)}
+}
+
+// next returns the recorded reply of a request
+func (r *ReplayLLM) next(messages []llm.Message, options *llm.CallOptions, stream bool) (goldenReply, error) {
+	hash, err := RequestHash(messages, options, stream, r.config.normalize)
+	if err != nil {
+		return goldenReply{}, err
+	}
+
+	path := goldenPath(r.dir, hash)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return goldenReply{}, fmt.Errorf("llmtest: no recording for request %s in %s; run the test with %s=1 to record it",
+				hash[:16], r.dir, RecordEnvVar)
+		}
+		return goldenReply{}, fmt.Errorf("llmtest: failed to read recording: %w", err)
+	}
+	var file goldenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return goldenReply{}, fmt.Errorf("llmtest: invalid recording %s: %w", path, err)
+	}
+	if len(file.Replies) == 0 {
+		return goldenReply{}, fmt.Errorf("llmtest: recording %s has no replies", path)
+	}
+
+	r.mu.Lock()
+	index := r.served[hash]
+	r.served[hash]++
+	r.mu.Unlock()
+
+	if index >= len(file.Replies) {
+		index = len(file.Replies) - 1
+	}
+	return file.Replies[index], nil
+}
+
+// Call implements llm.LLM
+func (r *ReplayLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	reply, err := r.next(messages, options, false)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+	return reply.Response, nil
+}
+
+// CallStream implements llm.LLM by replaying the recorded chunk sequence
+func (r *ReplayLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	reply, err := r.next(messages, options, true)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+
+	chunks := make([]llm.StreamResponse, len(reply.Chunks))
+	for i, chunk := range reply.Chunks {
+		chunks[i] = chunk.streamResponse()
+	}
+	return streamChunks(chunks), nil
+}
+
+// GetModel implements llm.LLM
+func (r *ReplayLLM) GetModel() string { return r.config.model }
+
+// SupportsFunctionCalling implements llm.LLM
+func (r *ReplayLLM) SupportsFunctionCalling() bool { return r.config.functionCalling }
+
+// GetContextWindowSize implements llm.LLM
+func (r *ReplayLLM) GetContextWindowSize() int { return 8192 }
+
+// GetTokenizer implements llm.LLM with the heuristic tokenizer
+func (r *ReplayLLM) GetTokenizer() llm.Tokenizer { return llm.HeuristicTokenizer{} }
+
+// SetEventBus implements llm.LLM
+func (r *ReplayLLM) SetEventBus(eventBus events.EventBus) {}
+
+// Close implements llm.LLM
+func (r *ReplayLLM) Close() error { return nil }
+
+// GoldenLLM returns a RecordingLLM around provider() when RecordEnvVar is set and a ReplayLLM
+// over dir otherwise, so tests hit the network only when recordings are refreshed
+func GoldenLLM(t testing.TB, dir string, provider func() llm.LLM, opts ...GoldenOption) llm.LLM {
+	t.Helper()
+	if os.Getenv(RecordEnvVar) == "" {
+		return NewReplayLLM(dir, opts...)
+	}
+
+	inner := provider()
+	if inner == nil {
+		t.Fatalf("llmtest: %s is set but no provider is available", RecordEnvVar)
+	}
+	t.Cleanup(func() { inner.Close() })
+	return NewRecordingLLM(inner, dir, opts...)
+}
@@ -0,0 +1,41 @@
+// Package llmtest provides LLM test doubles that can be shared across packages:
+// ScriptedLLM answers calls from a declared script, RecordingLLM captures a real
+// provider's answers in golden files and ReplayLLM serves them back without network access.
+package llmtest
+
+import "github.com/ynl/greensoulai/internal/llm"
+
+// Call is a request received by a test double
+type Call struct {
+	Messages []llm.Message
+	Options  *llm.CallOptions
+	Stream   bool
+}
+
+// LastMessage returns the text of the call's last message
+func (c Call) LastMessage() string {
+	if len(c.Messages) == 0 {
+		return ""
+	}
+	return llm.ContentText(c.Messages[len(c.Messages)-1].Content)
+}
+
+// UserPrompt returns the text of the call's first user message
+func (c Call) UserPrompt() string {
+	return c.firstContent(llm.RoleUser)
+}
+
+// SystemPrompt returns the text of the call's first system message
+func (c Call) SystemPrompt() string {
+	return c.firstContent(llm.RoleSystem)
+}
+
+// firstContent returns the text of the call's first message with role
+func (c Call) firstContent(role llm.Role) string {
+	for _, msg := range c.Messages {
+		if msg.Role == role {
+			return llm.ContentText(msg.Content)
+		}
+	}
+	return ""
+}
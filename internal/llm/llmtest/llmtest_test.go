@@ -0,0 +1,211 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+func userMessages(content string) []llm.Message {
+	return []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are a tester"},
+		{Role: llm.RoleUser, Content: content},
+	}
+}
+
+func collect(t *testing.T, stream <-chan llm.StreamResponse) (string, error) {
+	t.Helper()
+	var content strings.Builder
+	var streamErr error
+	for chunk := range stream {
+		content.WriteString(chunk.Delta)
+		if chunk.Error != nil {
+			streamErr = chunk.Error
+		}
+	}
+	return content.String(), streamErr
+}
+
+func TestScriptedLLM_RepliesInOrderAndRecordsCalls(t *testing.T) {
+	scripted := NewScriptedLLM(
+		Reply{Content: "first", Usage: llm.Usage{TotalTokens: 3}},
+		Reply{
+			Content: "second",
+			Expect: func(call Call) error {
+				if call.Options == nil || call.Options.MaxTokens == nil {
+					return errors.New("expected max tokens")
+				}
+				return nil
+			},
+		},
+	).WithModel("test-model")
+
+	response, err := scripted.Call(context.Background(), userMessages("one"), nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if response.Content != "first" || response.Model != "test-model" || response.Usage.TotalTokens != 3 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	maxTokens := 10
+	if _, err := scripted.Call(context.Background(), userMessages("two"), &llm.CallOptions{MaxTokens: &maxTokens}); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	calls := scripted.Calls()
+	if len(calls) != 2 || calls[0].SystemPrompt() != "You are a tester" || calls[1].UserPrompt() != "two" {
+		t.Errorf("Unexpected calls: %+v", calls)
+	}
+	if prompts := scripted.Prompts(); len(prompts) != 2 || prompts[0] != "one" {
+		t.Errorf("Unexpected prompts: %v", prompts)
+	}
+	scripted.Verify(t)
+}
+
+func TestScriptedLLM_ReportsFailures(t *testing.T) {
+	scripted := NewScriptedLLM(
+		Reply{Content: "a", Expect: func(call Call) error { return fmt.Errorf("unexpected prompt %q", call.UserPrompt()) }},
+		Reply{Content: "unused"},
+	)
+
+	if _, err := scripted.Call(context.Background(), userMessages("hi"), nil); err == nil {
+		t.Error("Expected a failed expectation to fail the call")
+	}
+	if len(scripted.Failures()) != 1 {
+		t.Fatalf("Expected 1 failure, got %v", scripted.Failures())
+	}
+
+	_, _ = scripted.Call(context.Background(), userMessages("again"), nil)
+	if _, err := scripted.Call(context.Background(), userMessages("more"), nil); err == nil {
+		t.Error("Expected a call beyond the script to fail")
+	}
+	if len(scripted.Failures()) != 2 {
+		t.Errorf("Expected the unscripted call to be reported, got %v", scripted.Failures())
+	}
+
+	withDefault := NewScriptedLLM().WithDefault(Reply{Content: "default"})
+	response, err := withDefault.Call(context.Background(), nil, nil)
+	if err != nil || response.Content != "default" {
+		t.Errorf("Expected the default reply, got %v, %v", response, err)
+	}
+}
+
+func TestScriptedLLM_Stream(t *testing.T) {
+	scripted := NewScriptedLLM(
+		Reply{Content: "whole"},
+		Reply{Chunks: []llm.StreamResponse{{Delta: "Hel"}, {Delta: "lo", FinishReason: "stop"}}},
+	)
+
+	content, err := collect(t, mustStream(t, scripted, "a"))
+	if err != nil || content != "whole" {
+		t.Errorf("Expected the reply as one chunk, got %q, %v", content, err)
+	}
+	content, err = collect(t, mustStream(t, scripted, "b"))
+	if err != nil || content != "Hello" {
+		t.Errorf("Expected the scripted chunks, got %q, %v", content, err)
+	}
+	if calls := scripted.Calls(); !calls[0].Stream {
+		t.Error("Expected stream calls to be marked")
+	}
+}
+
+func mustStream(t *testing.T, l llm.LLM, prompt string) <-chan llm.StreamResponse {
+	t.Helper()
+	stream, err := l.CallStream(context.Background(), userMessages(prompt), nil)
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	return stream
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	provider := NewScriptedLLM(
+		Reply{Content: "run 1", Usage: llm.Usage{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6}},
+		Reply{Content: "run 2"},
+		Reply{Err: errors.New("HTTP error 500: boom")},
+		Reply{Chunks: []llm.StreamResponse{
+			{Delta: "stre"},
+			{Delta: "amed", Usage: &llm.Usage{TotalTokens: 7}},
+			{Error: errors.New("HTTP error 502: bad gateway")},
+		}},
+	).WithModel("gpt-4o")
+
+	recorder := NewRecordingLLM(provider, dir)
+	id := "Request 123e4567-e89b-12d3-a456-426614174000"
+	for _, expected := range []string{"run 1", "run 2"} {
+		response, err := recorder.Call(context.Background(), userMessages(id), nil)
+		if err != nil || response.Content != expected {
+			t.Fatalf("Expected %q while recording, got %v, %v", expected, response, err)
+		}
+	}
+	if _, err := recorder.Call(context.Background(), userMessages("failing"), nil); err == nil {
+		t.Fatal("Expected the provider error to pass through")
+	}
+	if content, err := collect(t, mustStream(t, recorder, "stream")); content != "streamed" || err == nil {
+		t.Fatalf("Expected the stream to pass through, got %q, %v", content, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 golden files, got %d", len(files))
+	}
+
+	// 回放时UUID不同、空白不同的请求仍然匹配
+	replay := NewReplayLLM(dir, WithReplayModel("gpt-4o"))
+	other := "  Request 00000000-0000-0000-0000-000000000000\r\n"
+	for _, expected := range []string{"run 1", "run 2", "run 2"} {
+		response, err := replay.Call(context.Background(), userMessages(other), nil)
+		if err != nil || response.Content != expected {
+			t.Fatalf("Expected replayed %q, got %v, %v", expected, response, err)
+		}
+		if expected == "run 1" && (response.Usage.TotalTokens != 6 || response.Model != "gpt-4o") {
+			t.Errorf("Expected the recorded response, got %+v", response)
+		}
+	}
+	if _, err := replay.Call(context.Background(), userMessages("failing"), nil); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected the recorded error, got %v", err)
+	}
+	content, err := collect(t, mustStream(t, replay, "stream"))
+	if content != "streamed" || err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Expected the recorded chunks, got %q, %v", content, err)
+	}
+
+	if _, err := replay.Call(context.Background(), userMessages("never recorded"), nil); err == nil || !strings.Contains(err.Error(), RecordEnvVar) {
+		t.Errorf("Expected a missing recording error naming %s, got %v", RecordEnvVar, err)
+	}
+	// 同样的消息以流式请求时不匹配普通调用的录制
+	if _, err := replay.CallStream(context.Background(), userMessages(id), nil); err == nil {
+		t.Error("Expected stream and call recordings to be kept apart")
+	}
+}
+
+func TestGoldenLLM_ChoosesModeFromEnvironment(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv(RecordEnvVar, "1")
+	recording := GoldenLLM(t, dir, func() llm.LLM { return NewScriptedLLM(Reply{Content: "recorded"}) })
+	if _, ok := recording.(*RecordingLLM); !ok {
+		t.Fatalf("Expected a RecordingLLM, got %T", recording)
+	}
+	if _, err := recording.Call(context.Background(), userMessages("hi"), nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	os.Unsetenv(RecordEnvVar)
+	replaying := GoldenLLM(t, dir, func() llm.LLM {
+		t.Fatal("provider must not be created when replaying")
+		return nil
+	})
+	response, err := replaying.Call(context.Background(), userMessages("hi"), nil)
+	if err != nil || response.Content != "recorded" {
+		t.Errorf("Expected the recorded reply, got %v, %v", response, err)
+	}
+}
@@ -0,0 +1,245 @@
+package llmtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+)
+
+// Reply is one scripted answer of a ScriptedLLM
+type Reply struct {
+	Content      string
+	ToolCalls    []llm.ToolCall
+	Refusal      string
+	FinishReason string // defaults to "stop"
+	Usage        llm.Usage
+	Err          error  // returned instead of a response
+	Fingerprint  string // reported as the response's SystemFingerprint
+
+	// Chunks are streamed by CallStream; when empty the reply is streamed as a single chunk
+	Chunks []llm.StreamResponse
+
+	// Expect optionally asserts on the call that consumes the reply. A non-nil error fails the
+	// call and is reported by Verify
+	Expect func(call Call) error
+}
+
+// Replies creates one plain reply per content
+func Replies(contents ...string) []Reply {
+	replies := make([]Reply, len(contents))
+	for i, content := range contents {
+		replies[i] = Reply{Content: content}
+	}
+	return replies
+}
+
+// ScriptedLLM is an llm.LLM that answers calls with its replies in order and records every call.
+// Calls beyond the script get the default reply when one is set and fail otherwise.
+// It is safe for concurrent use; concurrent calls consume replies in arrival order
+type ScriptedLLM struct {
+	model           string
+	functionCalling bool
+	contextWindow   int
+	replies         []Reply
+	defaultReply    *Reply
+	mu              sync.Mutex
+	calls           []Call
+	failures        []error
+	consumedReplies int
+}
+
+// NewScriptedLLM creates a ScriptedLLM answering with replies
+func NewScriptedLLM(replies ...Reply) *ScriptedLLM {
+	return &ScriptedLLM{
+		model:         "scripted",
+		contextWindow: 8192,
+		replies:       replies,
+	}
+}
+
+// WithModel sets the model reported by GetModel and in responses
+func (s *ScriptedLLM) WithModel(model string) *ScriptedLLM {
+	s.model = model
+	return s
+}
+
+// WithDefault sets the reply used once the script is exhausted
+func (s *ScriptedLLM) WithDefault(reply Reply) *ScriptedLLM {
+	s.defaultReply = &reply
+	return s
+}
+
+// WithFunctionCalling sets what SupportsFunctionCalling reports
+func (s *ScriptedLLM) WithFunctionCalling(supported bool) *ScriptedLLM {
+	s.functionCalling = supported
+	return s
+}
+
+// WithContextWindow sets what GetContextWindowSize reports
+func (s *ScriptedLLM) WithContextWindow(size int) *ScriptedLLM {
+	s.contextWindow = size
+	return s
+}
+
+// Calls returns the calls received so far, in call order
+func (s *ScriptedLLM) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallCount returns the number of calls received so far
+func (s *ScriptedLLM) CallCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+// Prompts returns the last message of every call, in call order
+func (s *ScriptedLLM) Prompts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prompts := make([]string, len(s.calls))
+	for i, call := range s.calls {
+		prompts[i] = call.LastMessage()
+	}
+	return prompts
+}
+
+// Failures returns the failed expectations and unscripted calls so far
+func (s *ScriptedLLM) Failures() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]error(nil), s.failures...)
+}
+
+// Verify fails t for every failed expectation, unscripted call and unused reply
+func (s *ScriptedLLM) Verify(t testing.TB) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, err := range s.failures {
+		t.Error(err)
+	}
+	if unused := len(s.replies) - s.consumedReplies; unused > 0 {
+		t.Errorf("llmtest: %d of %d scripted replies were not used", unused, len(s.replies))
+	}
+}
+
+// next records call and returns the reply that answers it
+func (s *ScriptedLLM) next(call Call) (Reply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, call)
+	index := len(s.calls)
+
+	var reply Reply
+	switch {
+	case s.consumedReplies < len(s.replies):
+		reply = s.replies[s.consumedReplies]
+		s.consumedReplies++
+	case s.defaultReply != nil:
+		reply = *s.defaultReply
+	default:
+		err := fmt.Errorf("llmtest: unexpected call %d, the script has %d replies", index, len(s.replies))
+		s.failures = append(s.failures, err)
+		return Reply{}, err
+	}
+
+	if reply.Expect != nil {
+		if err := reply.Expect(call); err != nil {
+			err = fmt.Errorf("llmtest: call %d: %w", index, err)
+			s.failures = append(s.failures, err)
+			return Reply{}, err
+		}
+	}
+	return reply, nil
+}
+
+// response converts a reply to a response
+func (s *ScriptedLLM) response(reply Reply) *llm.Response {
+	finishReason := reply.FinishReason
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+	return &llm.Response{
+		Content:      reply.Content,
+		ToolCalls:    reply.ToolCalls,
+		Refusal:      reply.Refusal,
+		FinishReason: finishReason,
+		Usage:        reply.Usage,
+		Model:        s.model,
+
+		SystemFingerprint: reply.Fingerprint,
+	}
+}
+
+// Call implements llm.LLM
+func (s *ScriptedLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	reply, err := s.next(Call{Messages: messages, Options: options})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+	return s.response(reply), nil
+}
+
+// CallStream implements llm.LLM
+func (s *ScriptedLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	reply, err := s.next(Call{Messages: messages, Options: options, Stream: true})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != nil && len(reply.Chunks) == 0 {
+		return nil, reply.Err
+	}
+
+	chunks := reply.Chunks
+	if len(chunks) == 0 {
+		response := s.response(reply)
+		chunks = []llm.StreamResponse{{
+			Delta:        response.Content,
+			ToolCalls:    response.ToolCalls,
+			Refusal:      response.Refusal,
+			FinishReason: response.FinishReason,
+			Usage:        &response.Usage,
+		}}
+	}
+	return streamChunks(chunks), nil
+}
+
+// streamChunks returns a closed channel holding chunks
+func streamChunks(chunks []llm.StreamResponse) <-chan llm.StreamResponse {
+	stream := make(chan llm.StreamResponse, len(chunks))
+	for _, chunk := range chunks {
+		stream <- chunk
+	}
+	close(stream)
+	return stream
+}
+
+// GetModel implements llm.LLM
+func (s *ScriptedLLM) GetModel() string { return s.model }
+
+// SupportsFunctionCalling implements llm.LLM
+func (s *ScriptedLLM) SupportsFunctionCalling() bool { return s.functionCalling }
+
+// GetContextWindowSize implements llm.LLM
+func (s *ScriptedLLM) GetContextWindowSize() int { return s.contextWindow }
+
+// GetTokenizer implements llm.LLM with the heuristic tokenizer, keeping token estimates deterministic
+func (s *ScriptedLLM) GetTokenizer() llm.Tokenizer { return llm.HeuristicTokenizer{} }
+
+// SetEventBus implements llm.LLM
+func (s *ScriptedLLM) SetEventBus(eventBus events.EventBus) {}
+
+// Close implements llm.LLM
+func (s *ScriptedLLM) Close() error { return nil }
@@ -0,0 +1,567 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const (
+	// Ollama API constants
+	defaultOllamaBaseURL = "http://localhost:11434"
+	ollamaChatEndpoint   = "/api/chat"
+
+	// Context window used when a local model family is unknown
+	defaultOllamaContextWindow = 4096
+)
+
+// Local model context windows, matched by model family (the name before the ":" tag)
+var ollamaContextWindows = map[string]int{
+	"llama2":         4096,
+	"llama3":         8192,
+	"llama3.1":       131072,
+	"llama3.2":       131072,
+	"llama3.3":       131072,
+	"mistral":        32768,
+	"mistral-nemo":   131072,
+	"mixtral":        32768,
+	"qwen":           32768,
+	"qwen2":          32768,
+	"qwen2.5":        32768,
+	"qwen2.5-coder":  32768,
+	"qwen3":          40960,
+	"gemma":          8192,
+	"gemma2":         8192,
+	"gemma3":         131072,
+	"phi3":           4096,
+	"phi3.5":         131072,
+	"phi4":           16384,
+	"codellama":      16384,
+	"deepseek-r1":    131072,
+	"deepseek-coder": 16384,
+	"command-r":      131072,
+}
+
+// OllamaLLM represents an LLM served by a local Ollama instance
+type OllamaLLM struct {
+	*BaseLLM
+}
+
+// OllamaRequest represents the request structure for the Ollama chat API
+type OllamaRequest struct {
+	Model    string                 `json:"model"`
+	Messages []OllamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+	Tools    []Tool                 `json:"tools,omitempty"`
+	Format   interface{}            `json:"format,omitempty"`
+}
+
+// OllamaMessage represents a message in Ollama format
+type OllamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+// OllamaToolCall represents a tool call in Ollama format; arguments are an object, not a string
+type OllamaToolCall struct {
+	Function struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	} `json:"function"`
+}
+
+// OllamaResponse represents a response, or a single ndjson line when streaming
+type OllamaResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         OllamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason,omitempty"`
+	PromptEvalCount int           `json:"prompt_eval_count,omitempty"`
+	EvalCount       int           `json:"eval_count,omitempty"`
+	TotalDuration   int64         `json:"total_duration,omitempty"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// ollamaContextWindow returns the context window for a local model
+func ollamaContextWindow(model string) int {
+	family := model
+	if i := strings.Index(family, ":"); i >= 0 {
+		family = family[:i]
+	}
+	// Drop a registry namespace such as "library/llama3"
+	if i := strings.LastIndex(family, "/"); i >= 0 {
+		family = family[i+1:]
+	}
+
+	if window, exists := ollamaContextWindows[family]; exists {
+		return window
+	}
+
+	// Longest prefix wins, so variants like "llama3.1-instruct" resolve to their family
+	prefixes := make([]string, 0, len(ollamaContextWindows))
+	for prefix := range ollamaContextWindows {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(family, prefix) {
+			return ollamaContextWindows[prefix]
+		}
+	}
+
+	return defaultOllamaContextWindow
+}
+
+// NewOllamaLLM creates a new Ollama LLM instance.
+// Function calling is off by default since many local models do not support it;
+// agents then fall back to JSON tool calls in the prompt. Pass WithFunctionCalling(true)
+// for models with native tool support.
+func NewOllamaLLM(model string, options ...BaseLLMOption) *OllamaLLM {
+	// Apply defaults first so user options can override them
+	allOptions := append([]BaseLLMOption{
+		WithContextWindow(ollamaContextWindow(model)),
+		WithFunctionCalling(false),
+	}, options...)
+
+	// Add default base URL only if not already set
+	allOptions = append(allOptions, func(b *BaseLLM) {
+		if b.baseURL == "" {
+			WithBaseURL(defaultOllamaBaseURL)(b)
+		}
+		b.baseURL = strings.TrimSuffix(b.baseURL, "/")
+	})
+
+	return &OllamaLLM{
+		BaseLLM: NewBaseLLM("ollama", model, allOptions...),
+	}
+}
+
+// Call sends a synchronous request to the Ollama chat API
+func (o *OllamaLLM) Call(ctx context.Context, messages []Message, options *CallOptions) (result *Response, err error) {
+	ctx, telemetry := o.startCall(ctx, messages, options, false)
+	defer func() { telemetry.finish(result, err) }()
+
+	request, err := o.prepareRequest(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = false
+
+	response, err := o.makeAPICall(ctx, request)
+	if err != nil {
+		o.LogError("Ollama API call failed",
+			logger.Field{Key: "model", Value: o.GetModel()},
+			logger.Field{Key: "error", Value: err},
+		)
+		return nil, err
+	}
+
+	result = o.convertResponse(response)
+
+	o.LogDebug("Ollama API call completed",
+		logger.Field{Key: "model", Value: o.GetModel()},
+		logger.Field{Key: "usage", Value: result.Usage},
+	)
+
+	return result, nil
+}
+
+// CallStream sends a streaming request to the Ollama chat API
+func (o *OllamaLLM) CallStream(ctx context.Context, messages []Message, options *CallOptions) (stream <-chan StreamResponse, err error) {
+	ctx, telemetry := o.startCall(ctx, messages, options, true)
+	defer func() {
+		if err != nil {
+			telemetry.finish(nil, err)
+		}
+	}()
+
+	request, err := o.prepareRequest(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = true
+
+	responseChannel := make(chan StreamResponse, 100)
+	go o.streamAPICall(ctx, request, responseChannel)
+
+	return telemetry.observe(responseChannel), nil
+}
+
+// prepareRequest validates inputs and builds an Ollama request
+func (o *OllamaLLM) prepareRequest(messages []Message, options *CallOptions) (*OllamaRequest, error) {
+	if err := o.ValidateMessages(messages); err != nil {
+		return nil, fmt.Errorf("invalid messages: %w", err)
+	}
+
+	if err := o.ValidateCallOptions(options); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	// Image content parts are only serialized by the OpenAI client
+	if err := checkVision(o.GetModel(), false, messages); err != nil {
+		return nil, err
+	}
+
+	return o.buildRequest(convertOllamaMessages(messages), options), nil
+}
+
+// convertOllamaMessages converts internal messages to Ollama format
+func convertOllamaMessages(messages []Message) []OllamaMessage {
+	result := make([]OllamaMessage, 0, len(messages))
+	for _, msg := range messages {
+		ollamaMsg := OllamaMessage{
+			Role:    string(msg.Role),
+			Content: messageText(msg.Content),
+		}
+		if msg.Role == RoleTool {
+			ollamaMsg.ToolName = msg.Name
+		}
+		for _, tc := range msg.ToolCalls {
+			var call OllamaToolCall
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = toolCallInput(tc)
+			ollamaMsg.ToolCalls = append(ollamaMsg.ToolCalls, call)
+		}
+		result = append(result, ollamaMsg)
+	}
+	return result
+}
+
+// buildRequest builds an Ollama request
+func (o *OllamaLLM) buildRequest(messages []OllamaMessage, options *CallOptions) *OllamaRequest {
+	request := &OllamaRequest{
+		Model:    o.GetModel(),
+		Messages: messages,
+	}
+
+	if options == nil {
+		return request
+	}
+
+	modelOptions := make(map[string]interface{})
+	if options.Temperature != nil {
+		modelOptions["temperature"] = *options.Temperature
+	}
+	if options.TopP != nil {
+		modelOptions["top_p"] = *options.TopP
+	}
+	if options.MaxTokens != nil {
+		modelOptions["num_predict"] = *options.MaxTokens
+	} else if options.MaxCompletionTokens != nil {
+		modelOptions["num_predict"] = *options.MaxCompletionTokens
+	}
+	if len(options.StopSequences) > 0 {
+		modelOptions["stop"] = options.StopSequences
+	}
+	if options.Seed != nil {
+		modelOptions["seed"] = *options.Seed
+	}
+	if options.FrequencyPenalty != nil {
+		modelOptions["frequency_penalty"] = *options.FrequencyPenalty
+	}
+	if options.PresencePenalty != nil {
+		modelOptions["presence_penalty"] = *options.PresencePenalty
+	}
+	if len(modelOptions) > 0 {
+		request.Options = modelOptions
+	}
+
+	request.Stream = options.Stream
+	request.Format = convertOllamaFormat(options.ResponseFormat)
+
+	// Only send tools to models that can use them; otherwise the prompt carries the tool protocol
+	if o.SupportsFunctionCalling() && len(options.Tools) > 0 {
+		request.Tools = options.Tools
+	}
+
+	return request
+}
+
+// convertOllamaFormat maps OpenAI-style response_format values to Ollama's format field
+func convertOllamaFormat(responseFormat interface{}) interface{} {
+	format, err := ParseResponseFormat(responseFormat)
+	if err != nil || format == nil {
+		return nil
+	}
+	switch format.Type {
+	case ResponseFormatJSONObject:
+		return "json"
+	case ResponseFormatJSONSchema:
+		// Ollama accepts the JSON Schema itself as the format
+		if format.JSONSchema != nil && format.JSONSchema.Schema != nil {
+			return format.JSONSchema.Schema
+		}
+		return "json"
+	default:
+		return nil
+	}
+}
+
+// newHTTPRequest creates an HTTP request for the Ollama chat API
+func (o *OllamaLLM) newHTTPRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.GetBaseURL()+ollamaChatEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	// Ollama needs no key locally, but proxies in front of it may
+	if apiKey := o.GetAPIKey(); apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	for key, value := range o.GetCustomHeaders() {
+		httpReq.Header.Set(key, value)
+	}
+
+	return httpReq, nil
+}
+
+// makeAPICall makes a synchronous API call to Ollama
+func (o *OllamaLLM) makeAPICall(ctx context.Context, request *OllamaRequest) (*OllamaResponse, error) {
+	bodyBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make request with retries on transport errors and 5xx (e.g. while a model is loading)
+	var response *http.Response
+	var lastErr error
+
+	maxRetries := o.transportRetries(ctx)
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		httpReq, err := o.newHTTPRequest(ctx, bodyBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		response, lastErr = o.doHTTP(httpReq)
+		observeHTTPAttempt(ctx, attempt, response)
+		if lastErr == nil && response.StatusCode < 500 {
+			break
+		}
+
+		if attempt < maxRetries {
+			if lastErr == nil {
+				response.Body.Close()
+			}
+			waitTime := time.Duration(attempt+1) * time.Second
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(waitTime):
+			}
+		}
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("HTTP request to Ollama at %s failed after %d retries (is `ollama serve` running?): %w",
+			o.GetBaseURL(), maxRetries, lastErr)
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			o.logger.Error("Failed to close response body",
+				logger.Field{Key: "error", Value: err})
+		}
+	}()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var ollamaResponse OllamaResponse
+	if err := json.Unmarshal(responseBody, &ollamaResponse); err != nil {
+		if response.StatusCode >= 400 {
+			return nil, fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(responseBody))
+		}
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if ollamaResponse.Error != "" {
+		return nil, fmt.Errorf("Ollama API error: %s (status: %d)", ollamaResponse.Error, response.StatusCode)
+	}
+
+	if response.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	return &ollamaResponse, nil
+}
+
+// streamAPICall handles streaming API calls; Ollama streams newline-delimited JSON, not SSE
+func (o *OllamaLLM) streamAPICall(ctx context.Context, request *OllamaRequest, responseChannel chan<- StreamResponse) {
+	defer close(responseChannel)
+
+	bodyBytes, err := json.Marshal(request)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("failed to marshal request: %w", err)}
+		return
+	}
+
+	httpReq, err := o.newHTTPRequest(ctx, bodyBytes)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: err}
+		return
+	}
+
+	response, err := o.doHTTP(httpReq)
+	observeHTTPAttempt(ctx, 0, response)
+	if err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("HTTP request failed: %w", err)}
+		return
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			o.logger.Error("Failed to close response body",
+				logger.Field{Key: "error", Value: err})
+		}
+	}()
+
+	if response.StatusCode >= 400 {
+		body, _ := io.ReadAll(response.Body)
+		responseChannel <- StreamResponse{Error: fmt.Errorf("HTTP error %d: %s", response.StatusCode, string(body))}
+		return
+	}
+
+	var toolCalls []ToolCall
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var chunk OllamaResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			o.LogError("Failed to parse streaming chunk",
+				logger.Field{Key: "data", Value: line},
+				logger.Field{Key: "error", Value: err},
+			)
+			continue
+		}
+
+		if chunk.Error != "" {
+			responseChannel <- StreamResponse{Error: fmt.Errorf("Ollama API error: %s", chunk.Error)}
+			return
+		}
+
+		if chunk.Message.Content != "" {
+			responseChannel <- StreamResponse{Delta: chunk.Message.Content}
+		}
+		toolCalls = append(toolCalls, convertOllamaToolCalls(chunk.Message.ToolCalls, len(toolCalls))...)
+
+		if chunk.Done {
+			usage := ollamaUsage(&chunk)
+			usage.Cost = o.calculateCost(chunk.Model, usage)
+			responseChannel <- StreamResponse{
+				FinishReason: convertOllamaDoneReason(chunk.DoneReason, len(toolCalls) > 0),
+				Usage:        &usage,
+				ToolCalls:    toolCalls,
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			responseChannel <- StreamResponse{Error: ctx.Err()}
+			return
+		default:
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		responseChannel <- StreamResponse{Error: fmt.Errorf("scanner error: %w", err)}
+	}
+}
+
+// convertOllamaToolCalls converts Ollama tool calls; Ollama has no call IDs so they are synthesized
+func convertOllamaToolCalls(calls []OllamaToolCall, offset int) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	result := make([]ToolCall, 0, len(calls))
+	for i, call := range calls {
+		args := call.Function.Arguments
+		if args == nil {
+			args = make(map[string]interface{})
+		}
+		arguments, _ := json.Marshal(args)
+		result = append(result, ToolCall{
+			ID:   fmt.Sprintf("call_%d", offset+i),
+			Type: "function",
+			Function: ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: string(arguments),
+			},
+			Args: args,
+		})
+	}
+	return result
+}
+
+// ollamaUsage maps Ollama's eval counters to token usage
+func ollamaUsage(response *OllamaResponse) Usage {
+	return Usage{
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+		TotalTokens:      response.PromptEvalCount + response.EvalCount,
+	}
+}
+
+// convertOllamaDoneReason maps Ollama done reasons to OpenAI-style finish reasons
+func convertOllamaDoneReason(reason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	switch reason {
+	case "", "stop":
+		return "stop"
+	case "length":
+		return "length"
+	default:
+		return reason
+	}
+}
+
+// convertResponse converts Ollama response to internal format
+func (o *OllamaLLM) convertResponse(response *OllamaResponse) *Response {
+	toolCalls := convertOllamaToolCalls(response.Message.ToolCalls, 0)
+
+	model := response.Model
+	if model == "" {
+		model = o.GetModel()
+	}
+
+	usage := ollamaUsage(response)
+	usage.Cost = o.calculateCost(model, usage)
+
+	return &Response{
+		Content:      response.Message.Content,
+		Usage:        usage,
+		Model:        model,
+		FinishReason: convertOllamaDoneReason(response.DoneReason, len(toolCalls) > 0),
+		ToolCalls:    toolCalls,
+		Metadata: map[string]interface{}{
+			"created_at":     response.CreatedAt,
+			"done_reason":    response.DoneReason,
+			"total_duration": response.TotalDuration,
+		},
+	}
+}
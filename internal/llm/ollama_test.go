@@ -0,0 +1,291 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewOllamaLLM(t *testing.T) {
+	llm := NewOllamaLLM("llama3.1:8b")
+
+	if llm.GetProvider() != "ollama" {
+		t.Errorf("Expected provider 'ollama', got %s", llm.GetProvider())
+	}
+	if llm.GetBaseURL() != defaultOllamaBaseURL {
+		t.Errorf("Expected base URL %s, got %s", defaultOllamaBaseURL, llm.GetBaseURL())
+	}
+	if llm.SupportsFunctionCalling() {
+		t.Error("Expected function calling to be disabled by default")
+	}
+	if llm.GetContextWindowSize() != 131072 {
+		t.Errorf("Expected context window 131072, got %d", llm.GetContextWindowSize())
+	}
+
+	enabled := NewOllamaLLM("qwen2.5", WithFunctionCalling(true), WithBaseURL("http://gpu-box:11434/"))
+	if !enabled.SupportsFunctionCalling() {
+		t.Error("Expected WithFunctionCalling(true) to override the default")
+	}
+	if enabled.GetBaseURL() != "http://gpu-box:11434" {
+		t.Errorf("Expected trailing slash to be trimmed, got %s", enabled.GetBaseURL())
+	}
+}
+
+func TestOllamaContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"llama3":               8192,
+		"llama3:latest":        8192,
+		"llama3.2:3b":          131072,
+		"mistral:7b-instruct":  32768,
+		"library/phi3:mini":    4096,
+		"deepseek-r1:14b":      131072,
+		"codellama:13b-python": 16384,
+		"some-custom-model":    defaultOllamaContextWindow,
+	}
+	for model, expected := range tests {
+		if window := ollamaContextWindow(model); window != expected {
+			t.Errorf("ollamaContextWindow(%q) = %d, expected %d", model, window, expected)
+		}
+	}
+}
+
+func TestOllamaLLM_BuildRequest(t *testing.T) {
+	temp, maxTokens, seed := 0.2, 256, 7
+	options := &CallOptions{
+		Temperature:    &temp,
+		MaxTokens:      &maxTokens,
+		Seed:           &seed,
+		StopSequences:  []string{"END"},
+		ResponseFormat: map[string]interface{}{"type": "json_object"},
+		Tools: []Tool{{Type: "function", Function: ToolSchema{
+			Name:       "search",
+			Parameters: map[string]interface{}{"type": "object"},
+		}}},
+	}
+
+	request := NewOllamaLLM("llama3.2").buildRequest(nil, options)
+	if request.Options["temperature"] != 0.2 || request.Options["num_predict"] != 256 || request.Options["seed"] != 7 {
+		t.Errorf("unexpected model options: %v", request.Options)
+	}
+	if request.Format != "json" {
+		t.Errorf("Expected format 'json', got %v", request.Format)
+	}
+	if len(request.Tools) != 0 {
+		t.Error("Expected tools to be omitted when function calling is disabled")
+	}
+
+	request = NewOllamaLLM("llama3.2", WithFunctionCalling(true)).buildRequest(nil, options)
+	if len(request.Tools) != 1 || request.Tools[0].Function.Name != "search" {
+		t.Errorf("Expected tools to be sent when function calling is enabled, got %v", request.Tools)
+	}
+}
+
+func TestConvertOllamaMessages(t *testing.T) {
+	messages := convertOllamaMessages([]Message{
+		{Role: RoleSystem, Content: "You are helpful."},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{
+			ID:       "call_0",
+			Function: ToolCallFunction{Name: "search", Arguments: `{"query":"go"}`},
+		}}},
+		{Role: RoleTool, Name: "search", Content: "results", ToolCallID: "call_0"},
+	})
+
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	if messages[0].Role != "system" || messages[0].Content != "You are helpful." {
+		t.Errorf("unexpected system message: %+v", messages[0])
+	}
+	if len(messages[1].ToolCalls) != 1 || messages[1].ToolCalls[0].Function.Arguments["query"] != "go" {
+		t.Errorf("Expected tool call arguments as an object, got %+v", messages[1].ToolCalls)
+	}
+	if messages[2].Role != "tool" || messages[2].ToolName != "search" {
+		t.Errorf("unexpected tool message: %+v", messages[2])
+	}
+}
+
+func TestOllamaLLM_Call_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ollamaChatEndpoint {
+			t.Errorf("Expected path %s, got %s", ollamaChatEndpoint, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("Expected no Authorization header without an API key")
+		}
+
+		var request OllamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Stream {
+			t.Error("Expected stream to be false for Call")
+		}
+
+		fmt.Fprint(w, `{"model":"llama3.2","created_at":"2024-01-01T00:00:00Z",`+
+			`"message":{"role":"assistant","content":"Hello from llama"},`+
+			`"done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":12}`)
+	}))
+	defer server.Close()
+
+	llm := NewOllamaLLM("llama3.2", WithBaseURL(server.URL))
+	response, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response.Content != "Hello from llama" {
+		t.Errorf("Expected content 'Hello from llama', got %q", response.Content)
+	}
+	if response.FinishReason != "stop" {
+		t.Errorf("Expected finish reason 'stop', got %q", response.FinishReason)
+	}
+	if response.Usage.PromptTokens != 26 || response.Usage.CompletionTokens != 12 || response.Usage.TotalTokens != 38 {
+		t.Errorf("unexpected usage: %+v", response.Usage)
+	}
+	if response.Usage.Cost != 0 {
+		t.Errorf("Expected local models to cost nothing, got %f", response.Usage.Cost)
+	}
+}
+
+func TestOllamaLLM_Call_ToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"model":"qwen2.5","message":{"role":"assistant","content":"",`+
+			`"tool_calls":[{"function":{"name":"search","arguments":{"query":"go"}}}]},`+
+			`"done":true,"done_reason":"stop"}`)
+	}))
+	defer server.Close()
+
+	llm := NewOllamaLLM("qwen2.5", WithBaseURL(server.URL), WithFunctionCalling(true))
+	response, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Search go"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got %q", response.FinishReason)
+	}
+	if len(response.ToolCalls) != 1 {
+		t.Fatalf("Expected 1 tool call, got %d", len(response.ToolCalls))
+	}
+	call := response.ToolCalls[0]
+	if call.ID == "" || call.Function.Name != "search" || call.Function.Arguments != `{"query":"go"}` || call.Args["query"] != "go" {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+}
+
+func TestOllamaLLM_Call_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"llama9\" not found, try pulling it first"}`)
+	}))
+	defer server.Close()
+
+	llm := NewOllamaLLM("llama9", WithBaseURL(server.URL))
+	_, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}}, nil)
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if !strings.Contains(err.Error(), "try pulling it first") {
+		t.Errorf("Expected Ollama error message, got %v", err)
+	}
+}
+
+func TestOllamaLLM_CallStream_Success(t *testing.T) {
+	lines := []string{
+		`{"model":"llama3.2","message":{"role":"assistant","content":"Hello"},"done":false}`,
+		`{"model":"llama3.2","message":{"role":"assistant","content":" world"},"done":false}`,
+		`{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":8,"eval_count":2}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request OllamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if !request.Stream {
+			t.Error("Expected stream to be true for CallStream")
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+	}))
+	defer server.Close()
+
+	llm := NewOllamaLLM("llama3.2", WithBaseURL(server.URL))
+	stream, err := llm.CallStream(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var content strings.Builder
+	var final StreamResponse
+	for chunk := range stream {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected stream error: %v", chunk.Error)
+		}
+		content.WriteString(chunk.Delta)
+		if chunk.Usage != nil {
+			final = chunk
+		}
+	}
+
+	if content.String() != "Hello world" {
+		t.Errorf("Expected 'Hello world', got %q", content.String())
+	}
+	if final.FinishReason != "length" {
+		t.Errorf("Expected finish reason 'length', got %q", final.FinishReason)
+	}
+	if final.Usage == nil || final.Usage.TotalTokens != 10 {
+		t.Errorf("unexpected final usage: %+v", final.Usage)
+	}
+}
+
+func TestCreateLLMOllama(t *testing.T) {
+	llm, err := CreateLLM(&Config{Provider: "ollama", Model: "llama3.2", BaseURL: "http://localhost:11434"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ollamaLLM, ok := llm.(*OllamaLLM)
+	if !ok {
+		t.Fatalf("expected *OllamaLLM, got %T", llm)
+	}
+	if ollamaLLM.SupportsFunctionCalling() {
+		t.Error("Expected function calling to be disabled by default")
+	}
+
+	llm, err = CreateLLM(&Config{
+		Provider: "ollama",
+		Model:    "llama3.2",
+		Metadata: map[string]interface{}{"function_calling": true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !llm.SupportsFunctionCalling() {
+		t.Error("Expected metadata function_calling to enable function calling")
+	}
+
+	// OpenAI-compatible local servers go through the OpenAI client
+	llm, err = CreateLLM(&Config{Provider: "ollama", Model: "mistral", BaseURL: "http://localhost:8080/v1/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compatible, ok := llm.(*OpenAILLM)
+	if !ok {
+		t.Fatalf("expected *OpenAILLM for a /v1 base URL, got %T", llm)
+	}
+	if compatible.GetBaseURL() != "http://localhost:8080/v1" || compatible.SupportsFunctionCalling() || compatible.GetContextWindowSize() != 32768 {
+		t.Errorf("unexpected compatible config: url=%s fc=%v window=%d",
+			compatible.GetBaseURL(), compatible.SupportsFunctionCalling(), compatible.GetContextWindowSize())
+	}
+
+	if _, err := CreateLLM(&Config{Provider: "ollama"}); err == nil {
+		t.Error("expected error for missing model")
+	}
+}
@@ -31,6 +31,18 @@ var openAIContextWindows = map[string]int{
 	"gpt-3.5-turbo-16k": 16385,
 }
 
+// Model families that support response_format; exceptions lists snapshots that predate the feature
+var (
+	openAIJSONObjectModels = []string{"gpt-3.5-turbo", "gpt-4-turbo", "gpt-4-1106", "gpt-4-0125", "gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"}
+	openAIJSONSchemaModels = []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"}
+
+	openAIJSONObjectExceptions = []string{"gpt-3.5-turbo-0301", "gpt-3.5-turbo-0613", "gpt-3.5-turbo-16k-0613", "o1-preview", "o1-mini"}
+	openAIJSONSchemaExceptions = []string{"gpt-4o-2024-05-13", "o1-preview", "o1-mini"}
+
+	openAIVisionModels     = []string{"gpt-4o", "chatgpt-4o", "gpt-4-turbo", "gpt-4-vision-preview", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"}
+	openAIVisionExceptions = []string{"gpt-4-turbo-preview", "o1-preview", "o1-mini", "o3-mini"}
+)
+
 // OpenAILLM represents an OpenAI LLM instance
 type OpenAILLM struct {
 	*BaseLLM
@@ -70,6 +82,7 @@ type OpenAIMessage struct {
 	Name       string           `json:"name,omitempty"`
 	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
 	ToolCallId string           `json:"tool_call_id,omitempty"`
+	Refusal    string           `json:"refusal,omitempty"`
 }
 
 // OpenAITool represents a tool in OpenAI format
@@ -86,7 +99,9 @@ type OpenAIToolSchema struct {
 }
 
 // OpenAIToolCall represents a tool call in OpenAI format
+// Index is only set on streamed deltas, where it identifies the call a fragment belongs to
 type OpenAIToolCall struct {
+	Index    *int               `json:"index,omitempty"`
 	ID       string             `json:"id"`
 	Type     string             `json:"type"`
 	Function OpenAIToolCallFunc `json:"function"`
@@ -107,6 +122,8 @@ type OpenAIChatResponse struct {
 	Usage   OpenAIUsage    `json:"usage"`
 	Choices []OpenAIChoice `json:"choices"`
 	Error   *OpenAIError   `json:"error,omitempty"`
+
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
 }
 
 // OpenAIChoice represents a choice in OpenAI response
@@ -122,6 +139,27 @@ type OpenAIUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	PromptTokensDetails *OpenAIPromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// OpenAIPromptTokensDetails breaks down the prompt tokens in OpenAI usage
+type OpenAIPromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens,omitempty"`
+	ImageTokens  int `json:"image_tokens,omitempty"`
+}
+
+// toUsage converts OpenAI usage to the internal format, without cost
+func (u OpenAIUsage) toUsage() Usage {
+	usage := Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.ImageTokens = u.PromptTokensDetails.ImageTokens
+	}
+	return usage
 }
 
 // OpenAIError represents an error from OpenAI API
@@ -175,7 +213,10 @@ func WithOrganization(organization string) BaseLLMOption {
 }
 
 // Call sends a synchronous request to the OpenAI API
-func (o *OpenAILLM) Call(ctx context.Context, messages []Message, options *CallOptions) (*Response, error) {
+func (o *OpenAILLM) Call(ctx context.Context, messages []Message, options *CallOptions) (result *Response, err error) {
+	ctx, telemetry := o.startCall(ctx, messages, options, false)
+	defer func() { telemetry.finish(result, err) }()
+
 	// Validate inputs
 	if err := o.ValidateMessages(messages); err != nil {
 		return nil, fmt.Errorf("invalid messages: %w", err)
@@ -185,6 +226,14 @@ func (o *OpenAILLM) Call(ctx context.Context, messages []Message, options *CallO
 		return nil, fmt.Errorf("invalid options: %w", err)
 	}
 
+	if err := o.checkResponseFormat(options); err != nil {
+		return nil, err
+	}
+
+	if err := checkVision(o.GetModel(), o.SupportsVision(), messages); err != nil {
+		return nil, err
+	}
+
 	// Convert to OpenAI format
 	openAIMessages := o.convertMessages(messages)
 	request := o.buildChatRequest(openAIMessages, options)
@@ -200,7 +249,7 @@ func (o *OpenAILLM) Call(ctx context.Context, messages []Message, options *CallO
 	}
 
 	// Convert response
-	result := o.convertResponse(response)
+	result = o.convertResponse(response)
 
 	o.LogDebug("OpenAI API call completed",
 		logger.Field{Key: "model", Value: o.GetModel()},
@@ -211,7 +260,14 @@ func (o *OpenAILLM) Call(ctx context.Context, messages []Message, options *CallO
 }
 
 // CallStream sends a streaming request to the OpenAI API
-func (o *OpenAILLM) CallStream(ctx context.Context, messages []Message, options *CallOptions) (<-chan StreamResponse, error) {
+func (o *OpenAILLM) CallStream(ctx context.Context, messages []Message, options *CallOptions) (stream <-chan StreamResponse, err error) {
+	ctx, telemetry := o.startCall(ctx, messages, options, true)
+	defer func() {
+		if err != nil {
+			telemetry.finish(nil, err)
+		}
+	}()
+
 	// Validate inputs
 	if err := o.ValidateMessages(messages); err != nil {
 		return nil, fmt.Errorf("invalid messages: %w", err)
@@ -221,6 +277,14 @@ func (o *OpenAILLM) CallStream(ctx context.Context, messages []Message, options
 		return nil, fmt.Errorf("invalid options: %w", err)
 	}
 
+	if err := o.checkResponseFormat(options); err != nil {
+		return nil, err
+	}
+
+	if err := checkVision(o.GetModel(), o.SupportsVision(), messages); err != nil {
+		return nil, err
+	}
+
 	// Convert to OpenAI format and enable streaming
 	openAIMessages := o.convertMessages(messages)
 	request := o.buildChatRequest(openAIMessages, options)
@@ -232,7 +296,7 @@ func (o *OpenAILLM) CallStream(ctx context.Context, messages []Message, options
 	// Start streaming in a goroutine
 	go o.streamAPICall(ctx, request, responseChannel)
 
-	return responseChannel, nil
+	return telemetry.observe(responseChannel), nil
 }
 
 // convertMessages converts internal messages to OpenAI format
@@ -241,9 +305,24 @@ func (o *OpenAILLM) convertMessages(messages []Message) []OpenAIMessage {
 
 	for i, msg := range messages {
 		openAIMsg := OpenAIMessage{
-			Role:    string(msg.Role),
-			Content: msg.Content,
-			Name:    msg.Name,
+			Role:       string(msg.Role),
+			Content:    msg.Content,
+			Name:       msg.Name,
+			ToolCallId: msg.ToolCallID,
+		}
+
+		if len(msg.ToolCalls) > 0 {
+			openAIMsg.ToolCalls = make([]OpenAIToolCall, len(msg.ToolCalls))
+			for j, tc := range msg.ToolCalls {
+				openAIMsg.ToolCalls[j] = OpenAIToolCall{
+					ID:   tc.ID,
+					Type: "function",
+					Function: OpenAIToolCallFunc{
+						Name:      tc.Function.Name,
+						Arguments: tc.Function.Arguments,
+					},
+				}
+			}
 		}
 
 		openAIMessages[i] = openAIMsg
@@ -269,7 +348,7 @@ func (o *OpenAILLM) buildChatRequest(messages []OpenAIMessage, options *CallOpti
 		request.Stop = options.StopSequences
 		request.Stream = options.Stream
 		request.User = options.User
-		request.ResponseFormat = options.ResponseFormat
+		request.ResponseFormat = openAIResponseFormat(options.ResponseFormat)
 
 		// 对标Python版本的新增参数
 		request.N = options.N
@@ -299,6 +378,78 @@ func (o *OpenAILLM) buildChatRequest(messages []OpenAIMessage, options *CallOpti
 	return request
 }
 
+// SupportsResponseFormat reports whether the model supports the given response_format type
+func (o *OpenAILLM) SupportsResponseFormat(formatType string) bool {
+	model := o.GetModel()
+	switch formatType {
+	case ResponseFormatText:
+		return true
+	case ResponseFormatJSONObject:
+		return hasModelPrefix(model, openAIJSONObjectModels) && !hasModelPrefix(model, openAIJSONObjectExceptions)
+	case ResponseFormatJSONSchema:
+		return hasModelPrefix(model, openAIJSONSchemaModels) && !hasModelPrefix(model, openAIJSONSchemaExceptions)
+	default:
+		return false
+	}
+}
+
+// SupportsVision reports whether the model accepts image content parts.
+// Models served through OpenAI-compatible endpoints are assumed to, the server rejects images it cannot handle
+func (o *OpenAILLM) SupportsVision() bool {
+	model := o.GetModel()
+	if !isOpenAIModel(model) {
+		return true
+	}
+	return hasModelPrefix(model, openAIVisionModels) && !hasModelPrefix(model, openAIVisionExceptions)
+}
+
+// checkResponseFormat rejects response formats that a known OpenAI model cannot enforce.
+// Models served through OpenAI-compatible endpoints are passed through unchecked
+func (o *OpenAILLM) checkResponseFormat(options *CallOptions) error {
+	if options == nil || options.ResponseFormat == nil || !isOpenAIModel(o.GetModel()) {
+		return nil
+	}
+	format, err := ParseResponseFormat(options.ResponseFormat)
+	if err != nil {
+		return err
+	}
+	if !o.SupportsResponseFormat(format.Type) {
+		return fmt.Errorf("%w: model %s does not support response_format %q", ErrResponseFormatNotSupported, o.GetModel(), format.Type)
+	}
+	return nil
+}
+
+// openAIResponseFormat converts CallOptions.ResponseFormat to the request's response_format object
+func openAIResponseFormat(responseFormat interface{}) interface{} {
+	format, err := ParseResponseFormat(responseFormat)
+	if err != nil || format == nil {
+		return responseFormat
+	}
+	if format.JSONSchema != nil && format.JSONSchema.Name == "" {
+		// OpenAI requires a schema name
+		schema := *format.JSONSchema
+		schema.Name = "response"
+		return &ResponseFormat{Type: format.Type, JSONSchema: &schema}
+	}
+	return format
+}
+
+// isOpenAIModel reports whether the model name belongs to an OpenAI model family
+func isOpenAIModel(model string) bool {
+	return strings.HasPrefix(model, "gpt-") || strings.HasPrefix(model, "chatgpt-") ||
+		hasModelPrefix(model, []string{"o1", "o3", "o4"})
+}
+
+// hasModelPrefix reports whether the model is one of the families, matching whole name segments
+func hasModelPrefix(model string, families []string) bool {
+	for _, family := range families {
+		if model == family || strings.HasPrefix(model, family+"-") {
+			return true
+		}
+	}
+	return false
+}
+
 // makeAPICall makes a synchronous API call to OpenAI
 func (o *OpenAILLM) makeAPICall(ctx context.Context, request *OpenAIChatRequest) (*OpenAIChatResponse, error) {
 	// Prepare request body
@@ -331,13 +482,15 @@ func (o *OpenAILLM) makeAPICall(ctx context.Context, request *OpenAIChatRequest)
 	var response *http.Response
 	var lastErr error
 
-	for attempt := 0; attempt <= o.GetMaxRetries(); attempt++ {
-		response, lastErr = o.GetHTTPClient().Do(httpReq)
+	maxRetries := o.transportRetries(ctx)
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		response, lastErr = o.doHTTP(httpReq)
+		observeHTTPAttempt(ctx, attempt, response)
 		if lastErr == nil && response.StatusCode < 500 {
 			break // Success or client error (4xx)
 		}
 
-		if attempt < o.GetMaxRetries() {
+		if attempt < maxRetries {
 			// Wait before retry (exponential backoff)
 			waitTime := time.Duration(attempt+1) * time.Second
 			select {
@@ -350,7 +503,7 @@ func (o *OpenAILLM) makeAPICall(ctx context.Context, request *OpenAIChatRequest)
 	}
 
 	if lastErr != nil {
-		return nil, fmt.Errorf("HTTP request failed after %d retries: %w", o.GetMaxRetries(), lastErr)
+		return nil, fmt.Errorf("HTTP request failed after %d retries: %w", maxRetries, lastErr)
 	}
 	defer func() {
 		if err := response.Body.Close(); err != nil {
@@ -422,7 +575,8 @@ func (o *OpenAILLM) streamAPICall(ctx context.Context, request *OpenAIChatReques
 	}
 
 	// Make request
-	response, err := o.GetHTTPClient().Do(httpReq)
+	response, err := o.doHTTP(httpReq)
+	observeHTTPAttempt(ctx, 0, response)
 	if err != nil {
 		responseChannel <- StreamResponse{Error: fmt.Errorf("HTTP request failed: %w", err)}
 		return
@@ -441,8 +595,14 @@ func (o *OpenAILLM) streamAPICall(ctx context.Context, request *OpenAIChatReques
 		return
 	}
 
+	// Tool calls arrive as fragments keyed by index and are emitted complete on the final chunk
+	var toolCalls []ToolCall
+	var toolArguments []*strings.Builder
+	toolCallIndex := make(map[int]int) // delta index -> toolCalls index
+
 	// Process streaming response
 	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
 		line := scanner.Text()
 
@@ -476,14 +636,44 @@ func (o *OpenAILLM) streamAPICall(ctx context.Context, request *OpenAIChatReques
 						streamResp.Delta = content
 					}
 				}
+				if choice.Delta != nil {
+					streamResp.Refusal = choice.Delta.Refusal
+				}
+
+				if choice.Delta != nil {
+					for j, tc := range choice.Delta.ToolCalls {
+						index := j
+						if tc.Index != nil {
+							index = *tc.Index
+						}
+						i, exists := toolCallIndex[index]
+						if !exists {
+							i = len(toolCalls)
+							toolCallIndex[index] = i
+							toolCalls = append(toolCalls, ToolCall{Type: "function"})
+							toolArguments = append(toolArguments, &strings.Builder{})
+						}
+						if tc.ID != "" {
+							toolCalls[i].ID = tc.ID
+						}
+						if tc.Function.Name != "" {
+							toolCalls[i].Function.Name += tc.Function.Name
+						}
+						toolArguments[i].WriteString(tc.Function.Arguments)
+					}
+				}
+
+				if choice.FinishReason != "" && len(toolCalls) > 0 {
+					streamResp.ToolCalls = completeToolCalls(toolCalls, toolArguments)
+					toolCalls, toolArguments = nil, nil
+					toolCallIndex = make(map[int]int)
+				}
 
 				// Include usage if available (usually in last chunk)
 				if chunk.Usage.TotalTokens > 0 {
-					streamResp.Usage = &Usage{
-						PromptTokens:     chunk.Usage.PromptTokens,
-						CompletionTokens: chunk.Usage.CompletionTokens,
-						TotalTokens:      chunk.Usage.TotalTokens,
-					}
+					usage := chunk.Usage.toUsage()
+					streamResp.Usage = &usage
+					streamResp.Usage.Cost = o.calculateCost(chunk.Model, *streamResp.Usage)
 				}
 
 				responseChannel <- streamResp
@@ -501,32 +691,47 @@ func (o *OpenAILLM) streamAPICall(ctx context.Context, request *OpenAIChatReques
 
 	if err := scanner.Err(); err != nil {
 		responseChannel <- StreamResponse{Error: fmt.Errorf("scanner error: %w", err)}
+		return
+	}
+
+	// Some compatible servers end the stream without a finish reason
+	if len(toolCalls) > 0 {
+		responseChannel <- StreamResponse{ToolCalls: completeToolCalls(toolCalls, toolArguments)}
 	}
 }
 
+// completeToolCalls fills in the accumulated arguments of streamed tool calls
+func completeToolCalls(toolCalls []ToolCall, arguments []*strings.Builder) []ToolCall {
+	for i := range toolCalls {
+		toolCalls[i].Function.Arguments = arguments[i].String()
+		if toolCalls[i].Function.Arguments == "" {
+			toolCalls[i].Function.Arguments = "{}"
+		}
+		toolCalls[i].Args = toolCallInput(toolCalls[i])
+	}
+	return toolCalls
+}
+
 // convertResponse converts OpenAI response to internal format
 func (o *OpenAILLM) convertResponse(response *OpenAIChatResponse) *Response {
+	usage := response.Usage.toUsage()
+	usage.Cost = o.calculateCost(response.Model, usage)
+
 	if len(response.Choices) == 0 {
 		return &Response{
-			Content: "",
-			Usage: Usage{
-				PromptTokens:     response.Usage.PromptTokens,
-				CompletionTokens: response.Usage.CompletionTokens,
-				TotalTokens:      response.Usage.TotalTokens,
-			},
-			Model: response.Model,
+			Content:           "",
+			Usage:             usage,
+			Model:             response.Model,
+			SystemFingerprint: response.SystemFingerprint,
 		}
 	}
 
 	choice := response.Choices[0]
 	result := &Response{
-		Usage: Usage{
-			PromptTokens:     response.Usage.PromptTokens,
-			CompletionTokens: response.Usage.CompletionTokens,
-			TotalTokens:      response.Usage.TotalTokens,
-		},
-		Model:        response.Model,
-		FinishReason: choice.FinishReason,
+		Usage:             usage,
+		Model:             response.Model,
+		FinishReason:      choice.FinishReason,
+		SystemFingerprint: response.SystemFingerprint,
 		Metadata: map[string]interface{}{
 			"id":      response.ID,
 			"object":  response.Object,
@@ -541,6 +746,9 @@ func (o *OpenAILLM) convertResponse(response *OpenAIChatResponse) *Response {
 		}
 	}
 
+	// Strict structured outputs report refusals separately from content
+	result.Refusal = choice.Message.Refusal
+
 	// Extract tool calls if present
 	if len(choice.Message.ToolCalls) > 0 {
 		result.ToolCalls = make([]ToolCall, len(choice.Message.ToolCalls))
@@ -553,6 +761,7 @@ func (o *OpenAILLM) convertResponse(response *OpenAIChatResponse) *Response {
 					Arguments: tc.Function.Arguments,
 				},
 			}
+			result.ToolCalls[i].Args = toolCallInput(result.ToolCalls[i])
 		}
 	}
 
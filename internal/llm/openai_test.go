@@ -3,9 +3,13 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -580,3 +584,368 @@ func TestOpenAILLM_CallStream_Success(t *testing.T) {
 		t.Errorf("Expected total tokens 30, got %d", finalUsage.TotalTokens)
 	}
 }
+
+func TestOpenAILLM_Call_ParallelToolCallsRoundTrip(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/openai_parallel_tool_calls.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	var requests []OpenAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request OpenAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, request)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	llm := NewOpenAILLM("gpt-4o", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	messages := []Message{{Role: RoleUser, Content: "Weather in Shanghai and Beijing?"}}
+
+	response, err := llm.Call(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got %s", response.FinishReason)
+	}
+	if len(response.ToolCalls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %d", len(response.ToolCalls))
+	}
+	if response.ToolCalls[0].ID != "call_Xb1mL9sQeTz2" || response.ToolCalls[1].ID != "call_Kd8pV3nRw0Ja" {
+		t.Errorf("Unexpected tool call IDs: %s, %s", response.ToolCalls[0].ID, response.ToolCalls[1].ID)
+	}
+	if response.ToolCalls[1].Function.Name != "get_weather" {
+		t.Errorf("Expected function name 'get_weather', got %s", response.ToolCalls[1].Function.Name)
+	}
+	if city := response.ToolCalls[1].Args["city"]; city != "Beijing" {
+		t.Errorf("Expected parsed city 'Beijing', got %v", city)
+	}
+
+	// 把工具调用和结果送回模型
+	messages = append(messages,
+		Message{Role: RoleAssistant, Content: nil, ToolCalls: response.ToolCalls},
+		Message{Role: RoleTool, Content: "22°C, cloudy", ToolCallID: response.ToolCalls[0].ID},
+		Message{Role: RoleTool, Content: "18°C, sunny", ToolCallID: response.ToolCalls[1].ID},
+	)
+	if _, err := llm.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Expected no error sending tool results, got %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	sent := requests[1].Messages
+	if len(sent) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(sent))
+	}
+	if sent[1].Role != "assistant" || len(sent[1].ToolCalls) != 2 || sent[1].ToolCalls[1].Function.Arguments != `{"city": "Beijing", "unit": "celsius"}` {
+		t.Errorf("Unexpected assistant message: %+v", sent[1])
+	}
+	if sent[1].ToolCalls[0].Index != nil {
+		t.Errorf("Expected no index in request tool calls")
+	}
+	if sent[3].Role != "tool" || sent[3].ToolCallId != "call_Kd8pV3nRw0Ja" || sent[3].Content != "18°C, sunny" {
+		t.Errorf("Unexpected tool message: %+v", sent[3])
+	}
+}
+
+func TestOpenAILLM_CallStream_ParallelToolCalls(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/openai_parallel_tool_calls_stream.txt")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	llm := NewOpenAILLM("gpt-4o", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	respChan, err := llm.CallStream(context.Background(), []Message{{Role: RoleUser, Content: "Weather?"}}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var final StreamResponse
+	chunksWithToolCalls := 0
+	for response := range respChan {
+		if response.Error != nil {
+			t.Fatalf("Unexpected error in stream: %v", response.Error)
+		}
+		if len(response.ToolCalls) > 0 {
+			chunksWithToolCalls++
+			final = response
+		}
+	}
+
+	if chunksWithToolCalls != 1 {
+		t.Fatalf("Expected tool calls on exactly one chunk, got %d", chunksWithToolCalls)
+	}
+	if final.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason 'tool_calls', got %s", final.FinishReason)
+	}
+	if len(final.ToolCalls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %d", len(final.ToolCalls))
+	}
+
+	first, second := final.ToolCalls[0], final.ToolCalls[1]
+	if first.ID != "call_Xb1mL9sQeTz2" || first.Function.Name != "get_weather" || first.Function.Arguments != `{"city": "Shanghai"}` {
+		t.Errorf("Unexpected first tool call: %+v", first)
+	}
+	if second.ID != "call_Kd8pV3nRw0Ja" || second.Args["city"] != "Beijing" {
+		t.Errorf("Unexpected second tool call: %+v", second)
+	}
+	if final.Usage == nil || final.Usage.TotalTokens != 120 {
+		t.Errorf("Expected usage with 120 total tokens, got %+v", final.Usage)
+	}
+}
+
+func TestOpenAILLM_ResponseFormat(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{"label": map[string]interface{}{"type": "string"}},
+		"required":             []string{"label"},
+		"additionalProperties": false,
+	}
+
+	t.Run("json_schema is sent as response_format", func(t *testing.T) {
+		var request map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&request)
+			w.Write([]byte(`{"model":"gpt-4o-mini","choices":[{"message":{"role":"assistant","content":"{\"label\":\"positive\"}"},"finish_reason":"stop"}]}`))
+		}))
+		defer server.Close()
+
+		llm := NewOpenAILLM("gpt-4o-mini", WithAPIKey("test-key"), WithBaseURL(server.URL))
+		options := &CallOptions{ResponseFormat: JSONSchemaResponseFormat("sentiment", schema, true)}
+		response, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Classify"}}, options)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.Content != `{"label":"positive"}` {
+			t.Errorf("Unexpected content %s", response.Content)
+		}
+
+		format, ok := request["response_format"].(map[string]interface{})
+		if !ok || format["type"] != "json_schema" {
+			t.Fatalf("Expected json_schema response_format, got %v", request["response_format"])
+		}
+		jsonSchema := format["json_schema"].(map[string]interface{})
+		if jsonSchema["name"] != "sentiment" || jsonSchema["strict"] != true || jsonSchema["schema"] == nil {
+			t.Errorf("Unexpected json_schema %v", jsonSchema)
+		}
+	})
+
+	t.Run("string formats are converted to objects", func(t *testing.T) {
+		llm := NewOpenAILLM("gpt-4o")
+		request := llm.buildChatRequest(nil, &CallOptions{ResponseFormat: "json_object"})
+		format, ok := request.ResponseFormat.(*ResponseFormat)
+		if !ok || format.Type != ResponseFormatJSONObject {
+			t.Errorf("Expected json_object format, got %#v", request.ResponseFormat)
+		}
+	})
+
+	t.Run("unsupported model returns a clear error", func(t *testing.T) {
+		llm := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL("http://127.0.0.1:0"))
+		_, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Classify"}},
+			&CallOptions{ResponseFormat: JSONObjectFormat()})
+		if !errors.Is(err, ErrResponseFormatNotSupported) {
+			t.Fatalf("Expected ErrResponseFormatNotSupported, got %v", err)
+		}
+		if !strings.Contains(err.Error(), `model gpt-4 does not support response_format "json_object"`) {
+			t.Errorf("Unexpected error message: %v", err)
+		}
+	})
+
+	t.Run("model support", func(t *testing.T) {
+		tests := []struct {
+			model      string
+			jsonObject bool
+			jsonSchema bool
+		}{
+			{"gpt-4o", true, true},
+			{"gpt-4o-mini", true, true},
+			{"gpt-4o-2024-05-13", true, false},
+			{"gpt-4-turbo", true, false},
+			{"gpt-3.5-turbo", true, false},
+			{"gpt-4", false, false},
+			{"o1-mini", false, false},
+			{"o3-mini", true, true},
+			{"llama3", false, false},
+		}
+		for _, tt := range tests {
+			llm := NewOpenAILLM(tt.model)
+			if got := llm.SupportsResponseFormat(ResponseFormatJSONObject); got != tt.jsonObject {
+				t.Errorf("%s: json_object support = %v, want %v", tt.model, got, tt.jsonObject)
+			}
+			if got := llm.SupportsResponseFormat(ResponseFormatJSONSchema); got != tt.jsonSchema {
+				t.Errorf("%s: json_schema support = %v, want %v", tt.model, got, tt.jsonSchema)
+			}
+		}
+	})
+
+	t.Run("invalid json_schema format", func(t *testing.T) {
+		llm := NewOpenAILLM("gpt-4o", WithAPIKey("test-key"))
+		_, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Classify"}},
+			&CallOptions{ResponseFormat: map[string]interface{}{"type": "json_schema"}})
+		if err == nil || !strings.Contains(err.Error(), "requires a schema") {
+			t.Errorf("Expected missing schema error, got %v", err)
+		}
+	})
+}
+
+func TestOpenAILLM_Refusal(t *testing.T) {
+	t.Run("call", func(t *testing.T) {
+		server := createMockOpenAIServer(t, `{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":null,"refusal":"I can't help with that."},"finish_reason":"stop"}]}`, 200)
+		defer server.Close()
+
+		llm := NewOpenAILLM("gpt-4o", WithAPIKey("test-key"), WithBaseURL(server.URL))
+		response, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Hello"}}, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.Refusal != "I can't help with that." || response.Content != "" {
+			t.Errorf("Unexpected refusal %q and content %q", response.Refusal, response.Content)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		server := createMockStreamingServer(t, []string{
+			`{"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":null,"refusal":""},"finish_reason":null}]}`,
+			`{"model":"gpt-4o","choices":[{"index":0,"delta":{"refusal":"I can't "},"finish_reason":null}]}`,
+			`{"model":"gpt-4o","choices":[{"index":0,"delta":{"refusal":"help with that."},"finish_reason":"stop"}]}`,
+		})
+		defer server.Close()
+
+		llm := NewOpenAILLM("gpt-4o", WithAPIKey("test-key"), WithBaseURL(server.URL))
+		respChan, err := llm.CallStream(context.Background(), []Message{{Role: RoleUser, Content: "Hello"}}, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var refusal, content string
+		for response := range respChan {
+			refusal += response.Refusal
+			content += response.Delta
+		}
+		if refusal != "I can't help with that." || content != "" {
+			t.Errorf("Unexpected refusal %q and content %q", refusal, content)
+		}
+	})
+}
+
+func TestOpenAILLM_Call_SeedAndSystemFingerprint(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Write([]byte(`{"model":"gpt-4o","system_fingerprint":"fp_44709d6fcb","choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	llm := NewOpenAILLM("gpt-4o", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	options := DefaultCallOptions()
+	options.ApplyOptions(WithSeed(42))
+	response, err := llm.Call(context.Background(), []Message{{Role: RoleUser, Content: "Hello"}}, options)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if request["seed"] != float64(42) {
+		t.Errorf("Expected seed 42 to be sent, got %v", request["seed"])
+	}
+	if response.SystemFingerprint != "fp_44709d6fcb" {
+		t.Errorf("Expected system fingerprint fp_44709d6fcb, got %q", response.SystemFingerprint)
+	}
+}
+
+func TestOpenAILLM_Call_MultiPartContent(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/openai_vision.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	expected, err := os.ReadFile("testdata/openai_vision_request.json")
+	if err != nil {
+		t.Fatalf("Failed to read expected request: %v", err)
+	}
+
+	var request struct {
+		Messages json.RawMessage `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	imagePath := filepath.Join(t.TempDir(), "q2.png")
+	if err := os.WriteFile(imagePath, []byte("\x89PNG\r\n\x1a\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	image, err := ImageFilePart(imagePath, ImageDetailLow)
+	if err != nil {
+		t.Fatalf("Failed to load image: %v", err)
+	}
+
+	llm := NewOpenAILLM("gpt-4o", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	response, err := llm.Call(context.Background(), []Message{
+		{Role: RoleSystem, Content: "You describe charts."},
+		{Role: RoleUser, Content: []ContentPart{
+			TextPart("Compare these two charts."),
+			ImageURLPart("https://example.com/q1.png", ImageDetailHigh),
+			image,
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var got, want interface{}
+	if err := json.Unmarshal(request.Messages, &got); err != nil {
+		t.Fatalf("Failed to parse sent messages: %v", err)
+	}
+	if err := json.Unmarshal(expected, &want); err != nil {
+		t.Fatalf("Failed to parse expected messages: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected request messages:\n got %s\nwant %s", request.Messages, expected)
+	}
+
+	if response.Usage.PromptTokens != 1122 || response.Usage.ImageTokens != 850 || response.Usage.TotalTokens != 1140 {
+		t.Errorf("Expected the provider-reported image tokens in usage, got %+v", response.Usage)
+	}
+}
+
+func TestOpenAILLM_VisionNotSupported(t *testing.T) {
+	messages := []Message{{Role: RoleUser, Content: []ContentPart{
+		TextPart("What is in this picture?"),
+		ImageURLPart("https://example.com/cat.jpg", ImageDetailAuto),
+	}}}
+
+	for _, model := range []string{"gpt-3.5-turbo", "gpt-4", "o3-mini"} {
+		llm := NewOpenAILLM(model, WithAPIKey("test-key"), WithBaseURL("http://127.0.0.1:0"))
+		if llm.SupportsVision() {
+			t.Errorf("Expected %s not to support vision", model)
+		}
+		_, err := llm.Call(context.Background(), messages, nil)
+		if !errors.Is(err, ErrVisionNotSupported) {
+			t.Fatalf("Expected ErrVisionNotSupported for %s, got %v", model, err)
+		}
+		if _, err := llm.CallStream(context.Background(), messages, nil); !errors.Is(err, ErrVisionNotSupported) {
+			t.Errorf("Expected ErrVisionNotSupported from CallStream for %s, got %v", model, err)
+		}
+	}
+
+	for _, model := range []string{"gpt-4o-mini", "gpt-4.1", "gpt-4-turbo", "o4-mini", "llava:13b"} {
+		if !NewOpenAILLM(model).SupportsVision() {
+			t.Errorf("Expected %s to support vision", model)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// ModelPricing holds token prices in USD per million tokens, as providers publish them
+type ModelPricing struct {
+	InputCostPerMillion  float64 `json:"input_cost_per_million"`
+	OutputCostPerMillion float64 `json:"output_cost_per_million"`
+}
+
+// Cost returns the USD cost of usage at these prices
+func (p ModelPricing) Cost(usage Usage) float64 {
+	inputCost := float64(usage.PromptTokens) / 1e6 * p.InputCostPerMillion
+	outputCost := float64(usage.CompletionTokens) / 1e6 * p.OutputCostPerMillion
+	return inputCost + outputCost
+}
+
+// Default prices in USD per million tokens. Keys are model name patterns matched by
+// longest prefix, so dated snapshots like "gpt-4o-mini-2024-07-18" resolve to their family.
+var defaultModelPricing = map[string]ModelPricing{
+	// OpenAI
+	"gpt-5":         {1.25, 10},
+	"gpt-5-mini":    {0.25, 2},
+	"gpt-5-nano":    {0.05, 0.40},
+	"gpt-4.1":       {2, 8},
+	"gpt-4.1-mini":  {0.40, 1.60},
+	"gpt-4.1-nano":  {0.10, 0.40},
+	"gpt-4o":        {2.50, 10},
+	"gpt-4o-mini":   {0.15, 0.60},
+	"gpt-4-turbo":   {10, 30},
+	"gpt-4":         {30, 60},
+	"gpt-4-32k":     {60, 120},
+	"gpt-3.5-turbo": {0.50, 1.50},
+	"o1":            {15, 60},
+	"o1-mini":       {1.10, 4.40},
+	"o3":            {2, 8},
+	"o3-mini":       {1.10, 4.40},
+	"o4-mini":       {1.10, 4.40},
+
+	// Anthropic
+	"claude-opus-4":     {15, 75},
+	"claude-opus-4-5":   {5, 25},
+	"claude-sonnet-4":   {3, 15},
+	"claude-haiku-4-5":  {1, 5},
+	"claude-3-7-sonnet": {3, 15},
+	"claude-3-5-sonnet": {3, 15},
+	"claude-3-5-haiku":  {0.80, 4},
+	"claude-3-opus":     {15, 75},
+	"claude-3-sonnet":   {3, 15},
+	"claude-3-haiku":    {0.25, 1.25},
+
+	// Google Gemini
+	"gemini-1.5-pro":        {1.25, 5},
+	"gemini-1.5-flash":      {0.075, 0.30},
+	"gemini-1.5-flash-8b":   {0.0375, 0.15},
+	"gemini-2.0-flash":      {0.10, 0.40},
+	"gemini-2.0-flash-lite": {0.075, 0.30},
+	"gemini-2.5-pro":        {1.25, 10},
+	"gemini-2.5-flash":      {0.30, 2.50},
+}
+
+// PricingRegistry maps model name patterns to token prices.
+// It is safe for concurrent use.
+type PricingRegistry struct {
+	prices   map[string]ModelPricing
+	prefixes []string // registered patterns, longest first
+	warned   map[string]bool
+	mu       sync.RWMutex
+}
+
+// NewPricingRegistry creates a registry preloaded with the default price table
+func NewPricingRegistry() *PricingRegistry {
+	registry := &PricingRegistry{
+		prices: make(map[string]ModelPricing, len(defaultModelPricing)),
+		warned: make(map[string]bool),
+	}
+	for model, pricing := range defaultModelPricing {
+		registry.prices[model] = pricing
+	}
+	registry.sortPrefixes()
+	return registry
+}
+
+// sortPrefixes rebuilds the longest-first pattern list; callers hold the write lock
+func (r *PricingRegistry) sortPrefixes() {
+	r.prefixes = make([]string, 0, len(r.prices))
+	for model := range r.prices {
+		r.prefixes = append(r.prefixes, model)
+	}
+	sort.Slice(r.prefixes, func(i, j int) bool { return len(r.prefixes[i]) > len(r.prefixes[j]) })
+}
+
+// Register sets the pricing for a model name or name prefix, replacing any existing entry
+func (r *PricingRegistry) Register(model string, pricing ModelPricing) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prices[model] = pricing
+	delete(r.warned, model)
+	r.sortPrefixes()
+}
+
+// Lookup returns the pricing for a model: an exact match first, then the longest
+// registered prefix. Routed names such as "openai/gpt-4o-mini" fall back to the
+// name after the last "/", and OpenRouter ":free" variants cost nothing.
+func (r *PricingRegistry) Lookup(model string) (ModelPricing, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if pricing, ok := r.lookupLocked(model); ok {
+		return pricing, true
+	}
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		if pricing, ok := r.lookupLocked(model[i+1:]); ok {
+			return pricing, true
+		}
+	}
+	if strings.HasSuffix(model, ":free") {
+		return ModelPricing{}, true
+	}
+	return ModelPricing{}, false
+}
+
+// lookupLocked matches model exactly or by longest prefix; callers hold the read lock
+func (r *PricingRegistry) lookupLocked(model string) (ModelPricing, bool) {
+	if model == "" {
+		return ModelPricing{}, false
+	}
+	if pricing, ok := r.prices[model]; ok {
+		return pricing, true
+	}
+	for _, prefix := range r.prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return r.prices[prefix], true
+		}
+	}
+	return ModelPricing{}, false
+}
+
+// Cost returns the USD cost of usage for model, or 0 if the model has no pricing
+func (r *PricingRegistry) Cost(model string, usage Usage) float64 {
+	pricing, ok := r.Lookup(model)
+	if !ok {
+		return 0
+	}
+	return pricing.Cost(usage)
+}
+
+// firstMiss reports whether this is the first lookup miss for model, so it is logged once
+func (r *PricingRegistry) firstMiss(model string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.warned[model] {
+		return false
+	}
+	r.warned[model] = true
+	return true
+}
+
+// Global pricing registry used by all providers
+var globalPricing = NewPricingRegistry()
+
+// RegisterModelPricing registers token prices (USD per million tokens) for a model name
+// or prefix, e.g. for self-hosted or OpenRouter models missing from the default table
+func RegisterModelPricing(model string, inputCostPerMillion, outputCostPerMillion float64) {
+	globalPricing.Register(model, ModelPricing{
+		InputCostPerMillion:  inputCostPerMillion,
+		OutputCostPerMillion: outputCostPerMillion,
+	})
+}
+
+// LookupModelPricing returns the registered pricing for a model
+func LookupModelPricing(model string) (ModelPricing, bool) {
+	return globalPricing.Lookup(model)
+}
+
+// CalculateCost returns the USD cost of usage for model, or 0 if the model has no pricing
+func CalculateCost(model string, usage Usage) float64 {
+	return globalPricing.Cost(model, usage)
+}
+
+// calculateCost prices usage for a response and logs once per model without pricing
+func (b *BaseLLM) calculateCost(model string, usage Usage) float64 {
+	if model == "" {
+		model = b.model
+	}
+
+	pricing, ok := globalPricing.Lookup(model)
+	if !ok {
+		if globalPricing.firstMiss(model) {
+			b.LogDebug("No pricing registered for model, recording cost as 0",
+				logger.Field{Key: "provider", Value: b.provider},
+				logger.Field{Key: "model", Value: model},
+				logger.Field{Key: "hint", Value: "use llm.RegisterModelPricing to set prices"},
+			)
+		}
+		return 0
+	}
+	return pricing.Cost(usage)
+}
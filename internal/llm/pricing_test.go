@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestPricingRegistryLookup(t *testing.T) {
+	registry := NewPricingRegistry()
+
+	tests := []struct {
+		model  string
+		input  float64
+		output float64
+		found  bool
+	}{
+		{"gpt-4o", 2.50, 10, true},
+		{"gpt-4o-mini-2024-07-18", 0.15, 0.60, true},
+		{"gpt-4-0613", 30, 60, true},
+		{"claude-3-5-haiku-20241022", 0.80, 4, true},
+		{"claude-opus-4-5-20251101", 5, 25, true},
+		{"openai/gpt-4o-mini", 0.15, 0.60, true},
+		{"moonshotai/kimi-k2:free", 0, 0, true},
+		{"llama3.2", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		pricing, found := registry.Lookup(tt.model)
+		if found != tt.found {
+			t.Errorf("Lookup(%q) found = %v, expected %v", tt.model, found, tt.found)
+			continue
+		}
+		if pricing.InputCostPerMillion != tt.input || pricing.OutputCostPerMillion != tt.output {
+			t.Errorf("Lookup(%q) = %+v, expected input %v output %v", tt.model, pricing, tt.input, tt.output)
+		}
+	}
+}
+
+func TestPricingRegistryRegister(t *testing.T) {
+	registry := NewPricingRegistry()
+	usage := Usage{PromptTokens: 2000, CompletionTokens: 1000, TotalTokens: 3000}
+
+	if cost := registry.Cost("my-finetune", usage); cost != 0 {
+		t.Errorf("Expected unknown model to cost 0, got %f", cost)
+	}
+
+	registry.Register("my-finetune", ModelPricing{InputCostPerMillion: 1, OutputCostPerMillion: 4})
+	if cost := registry.Cost("my-finetune", usage); math.Abs(cost-0.006) > 1e-12 {
+		t.Errorf("Expected cost 0.006, got %f", cost)
+	}
+	if cost := registry.Cost("my-finetune-v2", usage); math.Abs(cost-0.006) > 1e-12 {
+		t.Errorf("Expected registered names to match as prefixes, got %f", cost)
+	}
+
+	// Overriding a default entry takes effect immediately
+	registry.Register("gpt-4o", ModelPricing{InputCostPerMillion: 1, OutputCostPerMillion: 1})
+	if pricing, _ := registry.Lookup("gpt-4o-2024-08-06"); pricing.InputCostPerMillion != 1 {
+		t.Errorf("Expected overridden gpt-4o pricing, got %+v", pricing)
+	}
+	if pricing, _ := registry.Lookup("gpt-4o-mini"); pricing.InputCostPerMillion != 0.15 {
+		t.Errorf("Expected gpt-4o-mini pricing to be unaffected, got %+v", pricing)
+	}
+}
+
+func TestPricingRegistryFirstMiss(t *testing.T) {
+	registry := NewPricingRegistry()
+
+	if !registry.firstMiss("mystery-model") {
+		t.Error("Expected first miss to be reported")
+	}
+	if registry.firstMiss("mystery-model") {
+		t.Error("Expected repeated miss not to be reported again")
+	}
+
+	// Registering pricing resets the miss so a later removal would be logged again
+	registry.Register("mystery-model", ModelPricing{})
+	if !registry.firstMiss("mystery-model") {
+		t.Error("Expected miss to be reported again after re-registration")
+	}
+}
+
+func TestPricingRegistryConcurrent(t *testing.T) {
+	registry := NewPricingRegistry()
+	usage := Usage{PromptTokens: 100, CompletionTokens: 100}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			registry.Register("concurrent-model", ModelPricing{InputCostPerMillion: 1, OutputCostPerMillion: 1})
+		}()
+		go func() {
+			defer wg.Done()
+			registry.Cost("gpt-4o", usage)
+			registry.firstMiss("unknown")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRegisterModelPricing(t *testing.T) {
+	RegisterModelPricing("pricing-test/self-hosted", 0.5, 1.5)
+
+	usage := Usage{PromptTokens: 1000000, CompletionTokens: 1000000}
+	if cost := CalculateCost("pricing-test/self-hosted", usage); math.Abs(cost-2.0) > 1e-9 {
+		t.Errorf("Expected cost 2.0, got %f", cost)
+	}
+
+	llm := NewOpenAILLM("pricing-test/self-hosted")
+	response := llm.convertResponse(&OpenAIChatResponse{
+		Model: "pricing-test/self-hosted",
+		Usage: OpenAIUsage{PromptTokens: 1000000, CompletionTokens: 1000000, TotalTokens: 2000000},
+	})
+	if math.Abs(response.Usage.Cost-2.0) > 1e-9 {
+		t.Errorf("Expected OpenAI response cost 2.0, got %f", response.Usage.Cost)
+	}
+
+	ollama := NewOllamaLLM("pricing-test-unknown")
+	local := ollama.convertResponse(&OllamaResponse{PromptEvalCount: 10, EvalCount: 10})
+	if local.Usage.Cost != 0 {
+		t.Errorf("Expected unknown model cost 0, got %f", local.Usage.Cost)
+	}
+}
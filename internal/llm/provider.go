@@ -2,6 +2,7 @@ package llm
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -20,6 +21,9 @@ func NewProviderRegistry() *ProviderRegistry {
 
 	// Register built-in providers
 	registry.RegisterProvider(&OpenAIProvider{})
+	registry.RegisterProvider(&AnthropicProvider{})
+	registry.RegisterProvider(&OllamaProvider{})
+	registry.RegisterProvider(&GeminiProvider{})
 
 	return registry
 }
@@ -121,6 +125,124 @@ func (p *OpenAIProvider) CreateLLM(config map[string]interface{}) (LLM, error) {
 		return nil, fmt.Errorf("model is required for OpenAI provider")
 	}
 
+	return NewOpenAILLM(model, providerOptions(config)...), nil
+}
+
+// SupportedModels returns the list of supported OpenAI models
+func (p *OpenAIProvider) SupportedModels() []string {
+	models := make([]string, 0, len(openAIContextWindows))
+	for model := range openAIContextWindows {
+		models = append(models, model)
+	}
+	return models
+}
+
+// AnthropicProvider implements the Provider interface for Anthropic
+type AnthropicProvider struct{}
+
+// Name returns the provider name
+func (p *AnthropicProvider) Name() string {
+	return "anthropic"
+}
+
+// CreateLLM creates a new Anthropic LLM instance
+func (p *AnthropicProvider) CreateLLM(config map[string]interface{}) (LLM, error) {
+	model, ok := config["model"].(string)
+	if !ok || model == "" {
+		return nil, fmt.Errorf("model is required for Anthropic provider")
+	}
+
+	return NewAnthropicLLM(model, providerOptions(config)...), nil
+}
+
+// SupportedModels returns the list of supported Anthropic model families
+func (p *AnthropicProvider) SupportedModels() []string {
+	models := make([]string, 0, len(anthropicContextWindows))
+	for model := range anthropicContextWindows {
+		models = append(models, model)
+	}
+	return models
+}
+
+// OllamaProvider implements the Provider interface for local models served by Ollama.
+// A base_url ending in "/v1" targets an OpenAI-compatible server (Ollama, llama.cpp, vLLM)
+// through the OpenAI client instead of Ollama's native /api/chat endpoint.
+type OllamaProvider struct{}
+
+// Name returns the provider name
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
+
+// CreateLLM creates a new Ollama LLM instance.
+// Set metadata["function_calling"] = true for local models with native tool support.
+func (p *OllamaProvider) CreateLLM(config map[string]interface{}) (LLM, error) {
+	model, ok := config["model"].(string)
+	if !ok || model == "" {
+		return nil, fmt.Errorf("model is required for Ollama provider")
+	}
+
+	functionCalling := false
+	if metadata, ok := config["metadata"].(map[string]interface{}); ok {
+		if enabled, ok := metadata["function_calling"].(bool); ok {
+			functionCalling = enabled
+		}
+	}
+
+	options := providerOptions(config)
+
+	baseURL, _ := config["base_url"].(string)
+	if strings.HasSuffix(strings.TrimSuffix(baseURL, "/"), "/v1") {
+		compatible := NewOpenAILLM(model, options...)
+		// OpenAI defaults assume a hosted model; use local-model capabilities instead
+		compatible.baseURL = strings.TrimSuffix(baseURL, "/")
+		compatible.contextWindow = ollamaContextWindow(model)
+		compatible.supportsFuncCall = functionCalling
+		return compatible, nil
+	}
+
+	options = append(options, WithFunctionCalling(functionCalling))
+	return NewOllamaLLM(model, options...), nil
+}
+
+// SupportedModels returns the list of local model families with known context windows
+func (p *OllamaProvider) SupportedModels() []string {
+	models := make([]string, 0, len(ollamaContextWindows))
+	for model := range ollamaContextWindows {
+		models = append(models, model)
+	}
+	return models
+}
+
+// GeminiProvider implements the Provider interface for Google Gemini
+type GeminiProvider struct{}
+
+// Name returns the provider name
+func (p *GeminiProvider) Name() string {
+	return "gemini"
+}
+
+// CreateLLM creates a new Gemini LLM instance
+func (p *GeminiProvider) CreateLLM(config map[string]interface{}) (LLM, error) {
+	model, ok := config["model"].(string)
+	if !ok || model == "" {
+		return nil, fmt.Errorf("model is required for Gemini provider")
+	}
+
+	return NewGeminiLLM(model, providerOptions(config)...), nil
+}
+
+// SupportedModels returns the list of supported Gemini model families
+func (p *GeminiProvider) SupportedModels() []string {
+	models := make([]string, 0, len(geminiContextWindows))
+	for model := range geminiContextWindows {
+		models = append(models, model)
+	}
+	return models
+}
+
+// providerOptions converts the common provider config map into BaseLLM options
+func providerOptions(config map[string]interface{}) []BaseLLMOption {
 	var options []BaseLLMOption
 
 	if apiKey, ok := config["api_key"].(string); ok && apiKey != "" {
@@ -148,14 +270,5 @@ func (p *OpenAIProvider) CreateLLM(config map[string]interface{}) (LLM, error) {
 		options = append(options, WithMaxRetries(maxRetries))
 	}
 
-	return NewOpenAILLM(model, options...), nil
-}
-
-// SupportedModels returns the list of supported OpenAI models
-func (p *OpenAIProvider) SupportedModels() []string {
-	models := make([]string, 0, len(openAIContextWindows))
-	for model := range openAIContextWindows {
-		models = append(models, model)
-	}
-	return models
+	return options
 }
@@ -450,6 +450,10 @@ func (m *MockLLM) GetContextWindowSize() int {
 	return 4096
 }
 
+func (m *MockLLM) GetTokenizer() Tokenizer {
+	return HeuristicTokenizer{}
+}
+
 func (m *MockLLM) SetEventBus(eventBus events.EventBus) {
 	// Mock implementation
 }
@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Response format types, matching OpenAI's response_format.type
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ErrResponseFormatNotSupported is returned when the selected model cannot enforce the requested response format
+var ErrResponseFormatNotSupported = errors.New("response format not supported")
+
+// ResponseFormat requests a provider-native structured output mode.
+// CallOptions.ResponseFormat accepts a *ResponseFormat, a ResponseFormat, an OpenAI-style map
+// or a type string such as "json_object"
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat describes the schema the response must conform to in json_schema mode
+type JSONSchemaFormat struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+	// Strict asks the provider to guarantee schema adherence; the schema must then
+	// set additionalProperties to false and list every property as required
+	Strict bool `json:"strict,omitempty"`
+}
+
+// JSONObjectFormat returns a response format that only requires valid JSON
+func JSONObjectFormat() *ResponseFormat {
+	return &ResponseFormat{Type: ResponseFormatJSONObject}
+}
+
+// JSONSchemaResponseFormat returns a response format that enforces the given JSON Schema
+func JSONSchemaResponseFormat(name string, schema map[string]interface{}, strict bool) *ResponseFormat {
+	return &ResponseFormat{
+		Type:       ResponseFormatJSONSchema,
+		JSONSchema: &JSONSchemaFormat{Name: name, Schema: schema, Strict: strict},
+	}
+}
+
+// Validate checks that the response format is complete
+func (f *ResponseFormat) Validate() error {
+	switch f.Type {
+	case ResponseFormatText, ResponseFormatJSONObject:
+		return nil
+	case ResponseFormatJSONSchema:
+		if f.JSONSchema == nil || f.JSONSchema.Schema == nil {
+			return fmt.Errorf("response_format json_schema requires a schema")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown response_format type %q", f.Type)
+	}
+}
+
+// ParseResponseFormat converts any accepted CallOptions.ResponseFormat value to a ResponseFormat.
+// It returns nil when no format is set
+func ParseResponseFormat(format interface{}) (*ResponseFormat, error) {
+	switch v := format.(type) {
+	case nil:
+		return nil, nil
+	case *ResponseFormat:
+		return v, nil
+	case ResponseFormat:
+		return &v, nil
+	case string:
+		if v == "json" {
+			v = ResponseFormatJSONObject
+		}
+		return &ResponseFormat{Type: v}, nil
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response_format: %w", err)
+		}
+		var parsed ResponseFormat
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("invalid response_format: %w", err)
+		}
+		return &parsed, nil
+	default:
+		return nil, fmt.Errorf("unsupported response_format value of type %T", format)
+	}
+}
+
+// ResponseFormatSupporter is implemented by LLMs that can enforce a response format natively.
+// Callers check for it with a type assertion and fall back to prompt instructions otherwise
+type ResponseFormatSupporter interface {
+	// SupportsResponseFormat reports whether the model supports the given response format type
+	SupportsResponseFormat(formatType string) bool
+}
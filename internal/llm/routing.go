@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ynl/greensoulai/pkg/events"
+)
+
+// ErrNoRoute is returned by a RoutingLLM when no rule matches a call
+var ErrNoRoute = errors.New("no routing rule matches the call")
+
+// RoutePredicate decides whether a call should be served by a rule's LLM
+type RoutePredicate func(messages []Message, options *CallOptions) bool
+
+// RoutingRule routes the calls matching Match to LLM. A nil Match matches every call,
+// which makes the rule a default route when it comes last
+type RoutingRule struct {
+	Name  string
+	Match RoutePredicate
+	LLM   LLM
+}
+
+// PromptTokensAbove matches calls whose messages are estimated at more than n tokens
+func PromptTokensAbove(n int) RoutePredicate {
+	return func(messages []Message, options *CallOptions) bool {
+		return EstimateTokens(nil, messages) > n
+	}
+}
+
+// RequiresTools matches calls that offer tools to the model
+func RequiresTools() RoutePredicate {
+	return func(messages []Message, options *CallOptions) bool {
+		return options != nil && len(options.Tools) > 0
+	}
+}
+
+// RoutingLLM picks the LLM for each call by the first matching rule, for example sending
+// long prompts to a large-context model and everything else to a cheaper one.
+// Responses report the model that actually served the call
+type RoutingLLM struct {
+	compositeUsage
+
+	rules []RoutingRule
+	llms  []LLM // distinct LLMs of the rules
+}
+
+// NewRoutingLLM creates an LLM that routes calls by rules, tried in order
+func NewRoutingLLM(rules ...RoutingRule) *RoutingLLM {
+	r := &RoutingLLM{rules: rules}
+	for _, rule := range rules {
+		if !containsLLM(r.llms, rule.LLM) {
+			r.llms = append(r.llms, rule.LLM)
+		}
+	}
+	return r
+}
+
+// Route returns the rule that serves a call
+func (r *RoutingLLM) Route(messages []Message, options *CallOptions) (RoutingRule, error) {
+	for _, rule := range r.rules {
+		if rule.Match == nil || rule.Match(messages, options) {
+			return rule, nil
+		}
+	}
+	return RoutingRule{}, ErrNoRoute
+}
+
+// Call implements LLM
+func (r *RoutingLLM) Call(ctx context.Context, messages []Message, options *CallOptions) (*Response, error) {
+	rule, err := r.Route(messages, options)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := rule.LLM.Call(ctx, messages, options)
+	if err != nil {
+		return nil, err
+	}
+	response = r.recordResponse(rule.LLM, response)
+	if rule.Name != "" {
+		if response.Metadata == nil {
+			response.Metadata = make(map[string]interface{})
+		}
+		response.Metadata["route"] = rule.Name
+	}
+	return response, nil
+}
+
+// CallStream implements LLM
+func (r *RoutingLLM) CallStream(ctx context.Context, messages []Message, options *CallOptions) (<-chan StreamResponse, error) {
+	rule, err := r.Route(messages, options)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := rule.LLM.CallStream(ctx, messages, options)
+	if err != nil {
+		return nil, err
+	}
+	return r.recordStream(rule.LLM, nil, stream), nil
+}
+
+// GetModel returns the model of the last rule, the default route by convention
+func (r *RoutingLLM) GetModel() string {
+	if len(r.rules) == 0 {
+		return ""
+	}
+	return r.rules[len(r.rules)-1].LLM.GetModel()
+}
+
+// SupportsFunctionCalling reports whether every routed LLM supports function calling
+func (r *RoutingLLM) SupportsFunctionCalling() bool {
+	for _, l := range r.llms {
+		if !l.SupportsFunctionCalling() {
+			return false
+		}
+	}
+	return len(r.llms) > 0
+}
+
+// GetContextWindowSize returns the largest context window of the routed LLMs,
+// since prompts too long for the others are expected to be routed to it
+func (r *RoutingLLM) GetContextWindowSize() int {
+	size := 0
+	for _, l := range r.llms {
+		if s := l.GetContextWindowSize(); s > size {
+			size = s
+		}
+	}
+	return size
+}
+
+// GetTokenizer returns the tokenizer of the LLM with the largest context window, the one long prompts end up at
+func (r *RoutingLLM) GetTokenizer() Tokenizer {
+	var largest LLM
+	for _, l := range r.llms {
+		if largest == nil || l.GetContextWindowSize() > largest.GetContextWindowSize() {
+			largest = l
+		}
+	}
+	if largest == nil {
+		return HeuristicTokenizer{}
+	}
+	return largest.GetTokenizer()
+}
+
+// SetEventBus sets the event bus of every routed LLM
+func (r *RoutingLLM) SetEventBus(eventBus events.EventBus) {
+	setEventBus(r.llms, eventBus)
+}
+
+// Close closes every routed LLM
+func (r *RoutingLLM) Close() error {
+	if err := closeAll(r.llms); err != nil {
+		return fmt.Errorf("failed to close routed LLMs: %w", err)
+	}
+	return nil
+}
+
+// containsLLM reports whether llms already holds l
+func containsLLM(llms []LLM, l LLM) bool {
+	for _, existing := range llms {
+		if existing == l {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRoutingLLM_RoutesByPromptSize(t *testing.T) {
+	large := newStubLLM("anthropic", "claude-sonnet-4")
+	large.usage = Usage{PromptTokens: 5000, CompletionTokens: 100, TotalTokens: 5100}
+	cheap := newStubLLM("openai", "gpt-4o-mini")
+	cheap.usage = Usage{PromptTokens: 10, CompletionTokens: 10, TotalTokens: 20}
+
+	router := NewRoutingLLM(
+		RoutingRule{Name: "long-context", Match: PromptTokensAbove(1000), LLM: large},
+		RoutingRule{Name: "default", LLM: cheap},
+	)
+
+	short := []Message{{Role: RoleUser, Content: "Hi"}}
+	response, err := router.Call(context.Background(), short, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if response.Model != "gpt-4o-mini" || response.Metadata["route"] != "default" {
+		t.Errorf("Expected the default route, got model %q route %v", response.Model, response.Metadata["route"])
+	}
+
+	long := []Message{{Role: RoleUser, Content: strings.Repeat("lorem ipsum ", 2000)}}
+	response, err = router.Call(context.Background(), long, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if response.Model != "claude-sonnet-4" || response.Metadata["route"] != "long-context" {
+		t.Errorf("Expected the long-context route, got model %q route %v", response.Model, response.Metadata["route"])
+	}
+
+	byModel := router.UsageByModel()
+	if byModel["gpt-4o-mini"].TotalTokens != 20 || byModel["claude-sonnet-4"].TotalTokens != 5100 {
+		t.Errorf("Unexpected usage by model: %+v", byModel)
+	}
+	total := router.Usage()
+	if total.TotalTokens != 5120 {
+		t.Errorf("Expected 5120 total tokens, got %d", total.TotalTokens)
+	}
+	expectedCost := CalculateCost("gpt-4o-mini", cheap.usage) + CalculateCost("claude-sonnet-4", large.usage)
+	if diff := total.Cost - expectedCost; diff > 1e-12 || diff < -1e-12 {
+		t.Errorf("Expected total cost %v, got %v", expectedCost, total.Cost)
+	}
+
+	if router.GetModel() != "gpt-4o-mini" {
+		t.Errorf("Expected the default route's model, got %q", router.GetModel())
+	}
+}
+
+func TestRoutingLLM_NoMatchingRule(t *testing.T) {
+	router := NewRoutingLLM(RoutingRule{Match: RequiresTools(), LLM: newStubLLM("openai", "gpt-4o")})
+
+	if _, err := router.Call(context.Background(), nil, nil); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Expected ErrNoRoute, got %v", err)
+	}
+	if _, err := router.Call(context.Background(), nil, &CallOptions{Tools: []Tool{{Type: "function"}}}); err != nil {
+		t.Errorf("Expected the tools route to match, got %v", err)
+	}
+}
+
+func TestRoutingLLM_ComposesWithFallback(t *testing.T) {
+	primary := newStubLLM("openai", "gpt-4o-mini")
+	primary.err = errors.New("HTTP error 500: internal")
+	backup := newStubLLM("ollama", "llama3")
+
+	router := NewRoutingLLM(RoutingRule{LLM: NewFallbackLLM(primary, backup)})
+	response, err := router.Call(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if response.Model != "llama3" {
+		t.Errorf("Expected the fallback's model, got %q", response.Model)
+	}
+}
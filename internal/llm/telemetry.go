@@ -0,0 +1,257 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+// Error classes reported in llm_call_failed events
+const (
+	ErrorClassCanceled       = "canceled"
+	ErrorClassTimeout        = "timeout"
+	ErrorClassInvalidRequest = "invalid_request"
+	ErrorClassAuthentication = "authentication"
+	ErrorClassRateLimit      = "rate_limit"
+	ErrorClassServerError    = "server_error"
+	ErrorClassNetwork        = "network"
+	ErrorClassAPIError       = "api_error"
+)
+
+// retryAttemptKey carries the caller's retry attempt of an LLM call
+type retryAttemptKey struct{}
+
+// WithRetryAttempt returns a context that marks the calls made with it as the given retry attempt
+// of the caller's own retry loop. Failure events add the provider's transport retries on top
+func WithRetryAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, retryAttemptKey{}, attempt)
+}
+
+// callTelemetryKey carries the telemetry of the current call down to the provider's HTTP requests
+type callTelemetryKey struct{}
+
+// callTelemetry measures one Call or CallStream, emits its usage events and ends its llm.call span.
+// A nil *callTelemetry is valid and does nothing, so providers need no event bus checks
+type callTelemetry struct {
+	llm          *BaseLLM
+	ctx          context.Context
+	span         tracing.Span
+	stream       bool
+	start        time.Time
+	firstToken   time.Duration
+	retryAttempt int
+	httpAttempt  int
+	statusCode   int
+	requested    bool
+}
+
+// startCall starts the llm.call span, emits the started event of a call and returns a context
+// carrying its telemetry. Without an event bus or tracer it returns ctx unchanged and a nil telemetry
+func (b *BaseLLM) startCall(ctx context.Context, messages []Message, options *CallOptions, stream bool) (context.Context, *callTelemetry) {
+	if b.eventBus == nil && !tracing.Enabled() {
+		return ctx, nil
+	}
+
+	t := &callTelemetry{llm: b, stream: stream, start: time.Now()}
+	if attempt, ok := ctx.Value(retryAttemptKey{}).(int); ok {
+		t.retryAttempt = attempt
+	}
+	ctx, t.span = tracing.Start(ctx, tracing.SpanLLMCall,
+		tracing.String("llm.provider", b.provider),
+		tracing.String("llm.model", b.model),
+		tracing.Bool("llm.stream", stream),
+		tracing.Int("llm.message_count", len(messages)),
+		tracing.Int("llm.retry_attempt", t.retryAttempt),
+	)
+	t.ctx = ctx
+
+	if b.eventBus != nil {
+		event := NewLLMCallStartedEvent(b.provider, b.model, messages, options)
+		event.Payload["stream"] = stream
+		b.EmitEvent(ctx, event)
+	}
+
+	return context.WithValue(ctx, callTelemetryKey{}, t), t
+}
+
+// observeHTTPAttempt records the transport retry and status of an HTTP request made for the call in ctx.
+// response is nil when the request failed before a response arrived
+func observeHTTPAttempt(ctx context.Context, attempt int, response *http.Response) {
+	t, _ := ctx.Value(callTelemetryKey{}).(*callTelemetry)
+	if t == nil {
+		return
+	}
+	if attempt > 0 {
+		t.span.AddEvent(tracing.EventLLMRetry,
+			tracing.String("retry.source", "transport"),
+			tracing.Int("retry.attempt", attempt),
+			tracing.Int("retry.previous_status", t.statusCode),
+		)
+	}
+	t.requested = true
+	t.httpAttempt = attempt
+	t.statusCode = 0
+	if response != nil {
+		t.statusCode = response.StatusCode
+	}
+}
+
+// finish emits the completed event, or the failed event when err is set
+func (t *callTelemetry) finish(response *Response, err error) {
+	if t == nil {
+		return
+	}
+	duration := time.Since(t.start)
+	defer t.span.End()
+
+	if err != nil {
+		event := NewLLMCallFailedEvent(t.llm.provider, t.llm.model, err, duration).
+			withHTTPAttempt(t.statusCode, t.retryAttempt+t.httpAttempt, t.requested)
+		event.Payload["stream"] = t.stream
+		t.span.SetAttributes(
+			tracing.Bool("llm.success", false),
+			tracing.String("llm.error_class", event.ErrorClass),
+			tracing.Int("llm.http_attempts", t.httpAttempt+1),
+		)
+		t.span.RecordError(err)
+		t.llm.EmitEvent(t.ctx, event)
+		return
+	}
+
+	event := NewLLMCallCompletedEvent(t.llm.provider, t.llm.model, response, duration)
+	event.Payload["stream"] = t.stream
+	if t.stream {
+		event.Metadata["first_token_latency"] = t.firstToken
+		event.Payload["first_token_ms"] = t.firstToken.Milliseconds()
+	}
+	t.span.SetAttributes(
+		tracing.Bool("llm.success", true),
+		tracing.Int("llm.prompt_tokens", response.Usage.PromptTokens),
+		tracing.Int("llm.completion_tokens", response.Usage.CompletionTokens),
+		tracing.Int("llm.total_tokens", response.Usage.TotalTokens),
+		tracing.Float64("llm.cost", event.Cost),
+		tracing.String("llm.finish_reason", response.FinishReason),
+	)
+	t.llm.EmitEvent(t.ctx, event)
+}
+
+// observe relays a provider stream, measuring the first-token latency and collecting the usage and
+// finish reason of the chunks, and emits the completed or failed event once the stream ends
+func (t *callTelemetry) observe(stream <-chan StreamResponse) <-chan StreamResponse {
+	if t == nil {
+		return stream
+	}
+
+	relay := make(chan StreamResponse, cap(stream))
+	go func() {
+		defer close(relay)
+
+		response := &Response{Model: t.llm.model}
+		var streamErr error
+		for chunk := range stream {
+			if t.firstToken == 0 && (chunk.Delta != "" || chunk.Refusal != "" || len(chunk.ToolCalls) > 0) {
+				t.firstToken = time.Since(t.start)
+			}
+			if chunk.Usage != nil {
+				response.Usage = *chunk.Usage
+			}
+			if chunk.FinishReason != "" {
+				response.FinishReason = chunk.FinishReason
+			}
+			if chunk.Error != nil {
+				streamErr = chunk.Error
+			}
+			relay <- chunk
+		}
+		t.finish(response, streamErr)
+	}()
+	return relay
+}
+
+// classifyError maps a failed call to an error class.
+// requested reports whether an HTTP request was made; failures before that are invalid requests
+func classifyError(err error, statusCode int, requested bool) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, ErrResponseFormatNotSupported), !requested:
+		return ErrorClassInvalidRequest
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return ErrorClassAuthentication
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorClassRateLimit
+	case statusCode >= 500:
+		return ErrorClassServerError
+	case statusCode >= 400:
+		return ErrorClassInvalidRequest
+	case statusCode == 0:
+		return ErrorClassNetwork
+	default:
+		return ErrorClassAPIError
+	}
+}
+
+// errorStatusPattern finds the HTTP status in provider error messages such as "HTTP error 503: ..."
+// or "Anthropic API error: ... (type: overloaded_error, status: 529)"
+var errorStatusPattern = regexp.MustCompile(`(?i)(?:HTTP error|status(?: code)?)[:\s]+(\d{3})`)
+
+// ClassifyError maps an error returned by Call or CallStream to an error class.
+// Providers report the HTTP status only in the error message, so unlike the failed event's
+// classification this inspects the message; errors that match nothing are ErrorClassAPIError
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+	if errors.Is(err, ErrResponseFormatNotSupported) {
+		return ErrorClassInvalidRequest
+	}
+
+	if match := errorStatusPattern.FindStringSubmatch(err.Error()); len(match) == 2 {
+		if status, convErr := strconv.Atoi(match[1]); convErr == nil && status >= 400 {
+			if status == http.StatusRequestTimeout {
+				return ErrorClassTimeout
+			}
+			return classifyError(err, status, true)
+		}
+	}
+
+	var netErr net.Error
+	msg := strings.ToLower(err.Error())
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout(),
+		strings.Contains(msg, "timeout"), strings.Contains(msg, "timed out"):
+		return ErrorClassTimeout
+	case strings.Contains(msg, "rate_limit"), strings.Contains(msg, "rate limit"),
+		strings.Contains(msg, "too many requests"):
+		return ErrorClassRateLimit
+	case strings.Contains(msg, "server_error"), strings.Contains(msg, "overloaded"),
+		strings.Contains(msg, "service unavailable"), strings.Contains(msg, "bad gateway"):
+		return ErrorClassServerError
+	case errors.As(err, &netErr), strings.Contains(msg, "http request failed"),
+		strings.Contains(msg, "connection refused"), strings.Contains(msg, "no such host"):
+		return ErrorClassNetwork
+	default:
+		return ErrorClassAPIError
+	}
+}
+
+// errorMessage returns the message of err, or an empty string for nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/tracing"
+)
+
+// eventRecorder collects the LLM call events emitted on a bus
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func newEventRecorder(t *testing.T) (events.EventBus, *eventRecorder) {
+	bus := events.NewEventBus(logger.NewTestLogger())
+	recorder := &eventRecorder{}
+	for _, eventType := range []string{EventTypeLLMCallStarted, EventTypeLLMCallCompleted, EventTypeLLMCallFailed} {
+		_, err := bus.SubscribeWithOptions(eventType, func(ctx context.Context, event events.Event) error {
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			recorder.events = append(recorder.events, event)
+			return nil
+		}, events.WithSyncDelivery())
+		if err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+	}
+	return bus, recorder
+}
+
+func (r *eventRecorder) recorded() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.events...)
+}
+
+func TestTelemetry_CallEvents(t *testing.T) {
+	server := createMockOpenAIServer(t, `{
+		"model": "gpt-4",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 10, "completion_tokens": 20, "total_tokens": 30}
+	}`, http.StatusOK)
+	defer server.Close()
+
+	bus, recorder := newEventRecorder(t)
+	llm := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL(server.URL), WithEventBus(bus))
+
+	messages := []Message{
+		{Role: RoleSystem, Content: "You are helpful"},
+		{Role: RoleUser, Content: "Hello"},
+	}
+	if _, err := llm.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	recorded := recorder.recorded()
+	if len(recorded) != 2 {
+		t.Fatalf("Expected started and completed events, got %d events", len(recorded))
+	}
+
+	started := recorded[0].GetPayload()
+	if recorded[0].GetType() != EventTypeLLMCallStarted || started["model"] != "gpt-4" || started["message_count"] != 2 {
+		t.Errorf("Unexpected started event: %v", started)
+	}
+	if started["estimated_prompt_tokens"] != EstimateTokens(nil, messages) || started["stream"] != false {
+		t.Errorf("Unexpected started event: %v", started)
+	}
+
+	completed := recorded[1].GetPayload()
+	if recorded[1].GetType() != EventTypeLLMCallCompleted || completed["finish_reason"] != "stop" || completed["tokens_used"] != 30 {
+		t.Errorf("Unexpected completed event: %v", completed)
+	}
+	if _, ok := completed["duration_ms"].(int64); !ok {
+		t.Errorf("Expected duration_ms in completed event, got %v", completed["duration_ms"])
+	}
+	if cost, _ := completed["cost"].(float64); cost <= 0 {
+		t.Errorf("Expected cost in completed event, got %v", completed["cost"])
+	}
+}
+
+func TestTelemetry_FailedEvent(t *testing.T) {
+	server := createMockOpenAIServer(t, `{"error": {"message": "Rate limit reached", "type": "requests"}}`, http.StatusTooManyRequests)
+	defer server.Close()
+
+	bus, recorder := newEventRecorder(t)
+	llm := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	llm.SetEventBus(bus)
+
+	ctx := WithRetryAttempt(context.Background(), 2)
+	if _, err := llm.Call(ctx, []Message{{Role: RoleUser, Content: "Hello"}}, nil); err == nil {
+		t.Fatal("Expected rate limit error")
+	}
+
+	recorded := recorder.recorded()
+	if len(recorded) != 2 || recorded[1].GetType() != EventTypeLLMCallFailed {
+		t.Fatalf("Expected started and failed events, got %v", recorded)
+	}
+	failed := recorded[1].(*LLMCallFailedEvent)
+	if failed.ErrorClass != ErrorClassRateLimit || failed.HTTPStatus != http.StatusTooManyRequests || failed.RetryAttempt != 2 {
+		t.Errorf("Unexpected failed event: %+v", failed)
+	}
+	if failed.GetPayload()["error_class"] != ErrorClassRateLimit {
+		t.Errorf("Expected error class in payload, got %v", failed.GetPayload())
+	}
+
+	// Invalid input fails before any request is sent
+	if _, err := llm.Call(context.Background(), nil, nil); err == nil {
+		t.Fatal("Expected invalid input error")
+	}
+	recorded = recorder.recorded()
+	if failed := recorded[len(recorded)-1].(*LLMCallFailedEvent); failed.ErrorClass != ErrorClassInvalidRequest || failed.HTTPStatus != 0 {
+		t.Errorf("Expected an invalid request without HTTP status, got %+v", failed)
+	}
+}
+
+func TestTelemetry_StreamEvents(t *testing.T) {
+	server := createMockStreamingServer(t, []string{
+		`{"model":"gpt-4","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"},"finish_reason":null}]}`,
+		`{"model":"gpt-4","choices":[{"index":0,"delta":{"content":"!"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`,
+	})
+	defer server.Close()
+
+	bus, recorder := newEventRecorder(t)
+	llm := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL(server.URL), WithEventBus(bus))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := llm.CallStream(ctx, []Message{{Role: RoleUser, Content: "Hello"}}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var content string
+	for chunk := range stream {
+		content += chunk.Delta
+	}
+	if content != "Hello!" {
+		t.Errorf("Expected chunks to be relayed unchanged, got %q", content)
+	}
+
+	recorded := recorder.recorded()
+	if len(recorded) != 2 {
+		t.Fatalf("Expected started and completed events, got %d events", len(recorded))
+	}
+	completed := recorded[1].GetPayload()
+	if completed["stream"] != true || completed["finish_reason"] != "stop" || completed["tokens_used"] != 12 {
+		t.Errorf("Unexpected completed event: %v", completed)
+	}
+	if _, ok := completed["first_token_ms"].(int64); !ok {
+		t.Errorf("Expected first-token latency in completed event, got %v", completed)
+	}
+}
+
+func TestTelemetry_WithoutEventBus(t *testing.T) {
+	llm := NewOpenAILLM("gpt-4")
+	stream := make(chan StreamResponse)
+
+	ctx, telemetry := llm.startCall(context.Background(), nil, nil, true)
+	if telemetry != nil || ctx != context.Background() {
+		t.Error("Expected no telemetry without an event bus")
+	}
+	if telemetry.observe(stream) != (<-chan StreamResponse)(stream) {
+		t.Error("Expected the stream to be returned unchanged")
+	}
+	telemetry.finish(nil, errors.New("ignored"))
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err        error
+		statusCode int
+		requested  bool
+		want       string
+	}{
+		{context.Canceled, 0, true, ErrorClassCanceled},
+		{context.DeadlineExceeded, 0, true, ErrorClassTimeout},
+		{errors.New("invalid messages"), 0, false, ErrorClassInvalidRequest},
+		{errors.New("HTTP error 401"), http.StatusUnauthorized, true, ErrorClassAuthentication},
+		{errors.New("HTTP error 429"), http.StatusTooManyRequests, true, ErrorClassRateLimit},
+		{errors.New("HTTP error 503"), http.StatusServiceUnavailable, true, ErrorClassServerError},
+		{errors.New("HTTP error 400"), http.StatusBadRequest, true, ErrorClassInvalidRequest},
+		{errors.New("connection refused"), 0, true, ErrorClassNetwork},
+		{errors.New("failed to unmarshal response"), http.StatusOK, true, ErrorClassAPIError},
+	}
+
+	for _, tt := range tests {
+		if got := classifyError(tt.err, tt.statusCode, tt.requested); got != tt.want {
+			t.Errorf("classifyError(%v, %d) = %s, want %s", tt.err, tt.statusCode, got, tt.want)
+		}
+	}
+}
+
+func TestTelemetry_Span(t *testing.T) {
+	server := createMockOpenAIServer(t, `{
+		"model": "gpt-4",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 10, "completion_tokens": 20, "total_tokens": 30}
+	}`, http.StatusOK)
+	defer server.Close()
+
+	recorder := tracing.NewRecorder()
+	tracing.SetTracer(recorder)
+	defer tracing.SetTracer(nil)
+
+	// 没有事件总线时也会创建span
+	llm := NewOpenAILLM("gpt-4", WithAPIKey("test-key"), WithBaseURL(server.URL))
+	if _, err := llm.Call(WithRetryAttempt(context.Background(), 2), []Message{{Role: RoleUser, Content: "Hello"}}, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	spans := recorder.SpansNamed(tracing.SpanLLMCall)
+	if len(spans) != 1 {
+		t.Fatalf("Expected one llm.call span, got %d", len(spans))
+	}
+	attrs := spans[0].Attributes
+	if !spans[0].Ended || attrs["llm.model"] != "gpt-4" || attrs["llm.success"] != true || attrs["llm.retry_attempt"] != 2 {
+		t.Errorf("Unexpected span attributes: %v", attrs)
+	}
+	if attrs["llm.total_tokens"] != 30 || attrs["llm.finish_reason"] != "stop" {
+		t.Errorf("Unexpected span usage: %v", attrs)
+	}
+	if cost, _ := attrs["llm.cost"].(float64); cost <= 0 {
+		t.Errorf("Expected cost on the span, got %v", attrs["llm.cost"])
+	}
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MessageCounter is implemented by tokenizers that count a whole conversation, including
+// the per-message framing tokens of the provider's chat format
+type MessageCounter interface {
+	CountMessages(messages []Message) int
+}
+
+// ChatTokenizer counts both texts and whole conversations
+type ChatTokenizer interface {
+	Tokenizer
+	MessageCounter
+}
+
+// Chat format overhead documented by OpenAI: the <|start|>{role}<|message|>...<|end|> framing of every
+// message takes 3 tokens, a name adds 1, and every reply is primed with <|start|>assistant<|message|>
+const (
+	TokensPerMessage = 3
+	TokensPerName    = 1
+	TokensPerReply   = 3
+)
+
+// o200kPrefixes are the model name prefixes using o200k_base; they are matched before
+// cl100kPrefixes because gpt-4o starts with gpt-4
+var o200kPrefixes = []string{"gpt-4o", "chatgpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"}
+
+// cl100kPrefixes are the model name prefixes using cl100k_base
+var cl100kPrefixes = []string{"gpt-4", "gpt-3.5", "gpt-35", "text-embedding-ada-002", "text-embedding-3"}
+
+// EncodingForModel returns the BPE encoding of a model, or "" when it is not a known OpenAI model.
+// Model names with a provider prefix (such as openai/gpt-4o) are matched by the part after the slash
+func EncodingForModel(model string) string {
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, prefix := range o200kPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return O200KBase
+		}
+	}
+	for _, prefix := range cl100kPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return CL100KBase
+		}
+	}
+	return ""
+}
+
+// TokenizerForModel returns the tokenizer of a model: the shared BPETokenizer of its encoding for known
+// OpenAI models, ScriptHeuristicTokenizer for every other model.
+// The BPE vocabulary is read from TokenizerDirEnv (or the user cache directory) and is never downloaded
+// implicitly; until it is available there, the BPETokenizer counts with ScriptHeuristicTokenizer as well.
+// Call DownloadEncoding or set TokenizerDownloadEnv for tiktoken-exact counts
+func TokenizerForModel(model string) ChatTokenizer {
+	if encoding := EncodingForModel(model); encoding != "" {
+		if bpe, err := GetEncoding(encoding); err == nil {
+			return bpe
+		}
+	}
+	return ScriptHeuristicTokenizer{}
+}
+
+// ScriptHeuristicTokenizer estimates tokens by script when no model vocabulary is available:
+// about 4 bytes per token for ASCII, one token per Han, kana or Hangul character, and about
+// 2 characters per token for other non-ASCII scripts (Cyrillic, Arabic, ...)
+type ScriptHeuristicTokenizer struct{}
+
+var _ ChatTokenizer = ScriptHeuristicTokenizer{}
+
+// CountTokens implements Tokenizer
+func (ScriptHeuristicTokenizer) CountTokens(text string) int {
+	asciiBytes, cjkRunes, otherRunes := 0, 0, 0
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			asciiBytes++
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if isCJK(r) {
+			cjkRunes++
+		} else {
+			otherRunes++
+		}
+	}
+	return (asciiBytes+3)/4 + cjkRunes + (otherRunes+1)/2
+}
+
+// CountMessages implements MessageCounter
+func (h ScriptHeuristicTokenizer) CountMessages(messages []Message) int {
+	return countChatMessages(h, messages)
+}
+
+// isCJK reports whether r is a Han, kana or Hangul character
+func isCJK(r rune) bool {
+	if 0x4E00 <= r && r <= 0x9FFF { // common Han characters, without a Unicode table lookup
+		return true
+	}
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// countChatMessages counts a conversation in OpenAI's chat format, including roles, names, images and tool calls
+func countChatMessages(tokenizer Tokenizer, messages []Message) int {
+	total := TokensPerReply
+	for _, msg := range messages {
+		total += TokensPerMessage + tokenizer.CountTokens(string(msg.Role))
+		total += tokenizer.CountTokens(ContentText(msg.Content)) + EstimateImageTokens(msg.Content)
+		if msg.Name != "" {
+			total += TokensPerName + tokenizer.CountTokens(msg.Name)
+		}
+		for _, tc := range msg.ToolCalls {
+			total += tokenizer.CountTokens(tc.Function.Name) + tokenizer.CountTokens(tc.Function.Arguments)
+		}
+	}
+	return total
+}
+
+// EstimateImageTokens estimates the prompt tokens of the images in a message content
+func EstimateImageTokens(content interface{}) int {
+	tokens := 0
+	for _, part := range imageParts(content) {
+		tokens += estimatedImageTokens(part)
+	}
+	return tokens
+}
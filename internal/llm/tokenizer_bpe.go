@@ -0,0 +1,475 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tiktoken-compatible BPE encodings
+const (
+	CL100KBase = "cl100k_base" // gpt-4, gpt-3.5-turbo and text-embedding-3
+	O200KBase  = "o200k_base"  // gpt-4o, gpt-4.1 and the o-series
+)
+
+// Vocabulary lookup and download
+const (
+	// TokenizerDirEnv is the directory holding the vocabulary files (<encoding>.tiktoken).
+	// When unset, greensoulai/tokenizer under the user cache directory is used
+	TokenizerDirEnv = "GREENSOULAI_TOKENIZER_DIR"
+	// TokenizerDownloadEnv, when non-empty, lets the first count download a vocabulary missing locally
+	// (that count waits for the download). By default nothing is downloaded and counts fall back to
+	// ScriptHeuristicTokenizer; call DownloadEncoding at startup to fetch the vocabularies ahead of time
+	TokenizerDownloadEnv = "GREENSOULAI_TOKENIZER_DOWNLOAD"
+
+	encodingDownloadTimeout = 30 * time.Second
+)
+
+// encodingURLs are the vocabulary download URLs, the same ones tiktoken uses
+var encodingURLs = map[string]string{
+	CL100KBase: "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+	O200KBase:  "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+}
+
+// encodingHashes are the vocabularies' sha256 sums, the same ones tiktoken verifies.
+// A downloaded vocabulary that does not match is rejected
+var encodingHashes = map[string]string{
+	CL100KBase: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	O200KBase:  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+}
+
+// encodingSplits are the pre-tokenization rules of each encoding
+var encodingSplits = map[string]splitFunc{
+	CL100KBase: splitCL100K,
+	O200KBase:  splitO200K,
+}
+
+// heapMergeThreshold is the piece length above which merges use a heap, avoiding quadratic time
+// on long pieces such as a whole paragraph of Chinese
+const heapMergeThreshold = 256
+
+// BPETokenizer is a tiktoken-compatible byte-level BPE tokenizer.
+// The vocabulary is loaded from the local directory on the first count, and downloaded and cached when
+// TokenizerDownloadEnv is set. When loading fails, counts fall back to ScriptHeuristicTokenizer and Err reports why
+type BPETokenizer struct {
+	encoding string
+	split    splitFunc
+
+	once  sync.Once
+	load  func() (map[string]int, error)
+	ranks map[string]int
+	pairs []int32 // rank of each two-byte token indexed by b0<<8|b1, -1 when it is not in the vocabulary
+	err   error
+}
+
+var _ ChatTokenizer = (*BPETokenizer)(nil)
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = make(map[string]*BPETokenizer)
+)
+
+// GetEncoding returns the shared tokenizer of an encoding; each vocabulary is loaded once per process
+func GetEncoding(encoding string) (*BPETokenizer, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if bpe, ok := encodings[encoding]; ok {
+		return bpe, nil
+	}
+	split, ok := encodingSplits[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+	bpe := &BPETokenizer{encoding: encoding, split: split, load: func() (map[string]int, error) { return loadEncoding(encoding) }}
+	encodings[encoding] = bpe
+	return bpe, nil
+}
+
+// NewBPETokenizer creates a tokenizer from a tiktoken vocabulary, one base64 token and its rank per line
+func NewBPETokenizer(encoding string, r io.Reader) (*BPETokenizer, error) {
+	split, ok := encodingSplits[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+	ranks, err := parseRanks(r)
+	if err != nil {
+		return nil, err
+	}
+	bpe := &BPETokenizer{encoding: encoding, split: split}
+	bpe.once.Do(func() { bpe.setRanks(ranks) })
+	return bpe, nil
+}
+
+// Encoding returns the encoding name
+func (b *BPETokenizer) Encoding() string {
+	return b.encoding
+}
+
+// Err loads the vocabulary and returns why loading failed, or nil when it is available
+func (b *BPETokenizer) Err() error {
+	b.ensureLoaded()
+	return b.err
+}
+
+// CountTokens implements Tokenizer
+func (b *BPETokenizer) CountTokens(text string) int {
+	if !b.ensureLoaded() {
+		return ScriptHeuristicTokenizer{}.CountTokens(text)
+	}
+	count := 0
+	splitText(text, b.split, func(piece string) {
+		count += b.countPiece(piece)
+	})
+	return count
+}
+
+// CountMessages implements MessageCounter
+func (b *BPETokenizer) CountMessages(messages []Message) int {
+	return countChatMessages(b, messages)
+}
+
+// ensureLoaded loads the vocabulary and reports whether it is available
+func (b *BPETokenizer) ensureLoaded() bool {
+	b.once.Do(func() {
+		var ranks map[string]int
+		if ranks, b.err = b.load(); b.err == nil {
+			b.setRanks(ranks)
+		}
+	})
+	return b.err == nil
+}
+
+// setRanks sets the vocabulary and precomputes the ranks of all byte pairs so the first merges skip the map
+func (b *BPETokenizer) setRanks(ranks map[string]int) {
+	b.ranks = ranks
+	b.pairs = make([]int32, 1<<16)
+	for i := range b.pairs {
+		b.pairs[i] = -1
+	}
+	for token, rank := range ranks {
+		if len(token) == 2 {
+			b.pairs[int(token[0])<<8|int(token[1])] = int32(rank)
+		}
+	}
+}
+
+// countPiece returns the number of tokens a pre-tokenization piece merges into
+func (b *BPETokenizer) countPiece(piece string) int {
+	if len(piece) == 1 {
+		return 1
+	}
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+	if len(piece) > heapMergeThreshold {
+		return b.countPieceHeap(piece)
+	}
+	return b.countPieceLinear(piece)
+}
+
+// noRank is the rank of a part that is not in the vocabulary, larger than any rank
+const noRank = math.MaxInt
+
+// rank returns the rank of piece[start:end], or noRank when it is not in the vocabulary
+func (b *BPETokenizer) rank(piece string, start, end int) int {
+	if end-start == 2 {
+		if rank := b.pairs[int(piece[start])<<8|int(piece[start+1])]; rank >= 0 {
+			return int(rank)
+		}
+		return noRank
+	}
+	if rank, ok := b.ranks[piece[start:end]]; ok {
+		return rank
+	}
+	return noRank
+}
+
+// countPieceLinear matches tiktoken's _byte_pair_merge: it repeatedly merges the adjacent parts
+// with the lowest rank, the leftmost one on ties
+func (b *BPETokenizer) countPieceLinear(piece string) int {
+	// bounds[i] is where part i starts, ranks[i] is the rank of parts i and i+1 merged
+	var boundsBuf, ranksBuf [heapMergeThreshold + 1]int
+	bounds, ranks := boundsBuf[:], ranksBuf[:]
+	if len(piece) > heapMergeThreshold {
+		bounds, ranks = make([]int, len(piece)+1), make([]int, len(piece))
+	}
+	bounds, ranks = bounds[:len(piece)+1], ranks[:len(piece)-1]
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for i := range ranks {
+		ranks[i] = b.rank(piece, i, i+2)
+	}
+
+	for len(ranks) > 0 {
+		best, lowest := 0, ranks[0]
+		for i := 1; i < len(ranks); i++ {
+			if ranks[i] < lowest {
+				best, lowest = i, ranks[i]
+			}
+		}
+		if lowest == noRank {
+			break
+		}
+
+		copy(bounds[best+1:], bounds[best+2:])
+		bounds = bounds[:len(bounds)-1]
+		copy(ranks[best:], ranks[best+1:])
+		ranks = ranks[:len(ranks)-1]
+		if best < len(ranks) {
+			ranks[best] = b.rank(piece, bounds[best], bounds[best+2])
+		}
+		if best > 0 {
+			ranks[best-1] = b.rank(piece, bounds[best-1], bounds[best+1])
+		}
+	}
+	return len(bounds) - 1
+}
+
+// mergeCandidate is a merge of two adjacent parts
+type mergeCandidate struct {
+	rank  int
+	left  int // start of the left part
+	right int // start of the right part
+	end   int // end of the right part
+}
+
+func (c mergeCandidate) before(other mergeCandidate) bool {
+	if c.rank != other.rank {
+		return c.rank < other.rank
+	}
+	return c.left < other.left
+}
+
+// mergeHeap is a min-heap ordered by (rank, position); container/heap is not used so pushes do not allocate
+type mergeHeap []mergeCandidate
+
+func (h *mergeHeap) push(c mergeCandidate) {
+	*h = append(*h, c)
+	items := *h
+	for i := len(items) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !items[i].before(items[parent]) {
+			break
+		}
+		items[i], items[parent] = items[parent], items[i]
+		i = parent
+	}
+}
+
+func (h *mergeHeap) pop() mergeCandidate {
+	items := *h
+	top := items[0]
+	last := len(items) - 1
+	items[0] = items[last]
+	items = items[:last]
+	for i := 0; ; {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < len(items) && items[left].before(items[smallest]) {
+			smallest = left
+		}
+		if right < len(items) && items[right].before(items[smallest]) {
+			smallest = right
+		}
+		if smallest == i {
+			break
+		}
+		items[i], items[smallest] = items[smallest], items[i]
+		i = smallest
+	}
+	*h = items
+	return top
+}
+
+// countPieceHeap returns the same count as countPieceLinear in O(n log n) using a heap and a linked list.
+// Parts are identified by their start; candidates made stale by a merge are skipped when popped
+func (b *BPETokenizer) countPieceHeap(piece string) int {
+	n := len(piece)
+	next := make([]int, n) // start of the following part, n for the last one
+	prev := make([]int, n)
+	alive := make([]bool, n)
+	for i := 0; i < n; i++ {
+		next[i], prev[i], alive[i] = i+1, i-1, true
+	}
+
+	candidates := make(mergeHeap, 0, n)
+	push := func(left int) {
+		if left < 0 || next[left] >= n {
+			return
+		}
+		right := next[left]
+		if rank := b.rank(piece, left, next[right]); rank != noRank {
+			candidates.push(mergeCandidate{rank: rank, left: left, right: right, end: next[right]})
+		}
+	}
+	for i := 0; i < n-1; i++ {
+		push(i)
+	}
+
+	count := n
+	for len(candidates) > 0 {
+		c := candidates.pop()
+		if !alive[c.left] || !alive[c.right] || next[c.left] != c.right || next[c.right] != c.end {
+			continue
+		}
+		alive[c.right] = false
+		next[c.left] = c.end
+		if c.end < n {
+			prev[c.end] = c.left
+		}
+		count--
+		push(prev[c.left])
+		push(c.left)
+	}
+	return count
+}
+
+// encodingPath returns the path of an encoding's vocabulary in the local directory
+func encodingPath(encoding string) string {
+	dir := os.Getenv(TokenizerDirEnv)
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			cacheDir = os.TempDir()
+		}
+		dir = filepath.Join(cacheDir, "greensoulai", "tokenizer")
+	}
+	return filepath.Join(dir, encoding+".tiktoken")
+}
+
+// loadEncoding reads a vocabulary from the local directory, downloading a missing one only when
+// TokenizerDownloadEnv is set
+func loadEncoding(encoding string) (map[string]int, error) {
+	path := encodingPath(encoding)
+	if file, err := os.Open(path); err == nil {
+		defer file.Close()
+		ranks, err := parseRanks(file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s vocabulary %s: %w", encoding, path, err)
+		}
+		return ranks, nil
+	}
+
+	if os.Getenv(TokenizerDownloadEnv) == "" {
+		return nil, fmt.Errorf("%s vocabulary not found at %s (call DownloadEncoding or set %s to download it)", encoding, path, TokenizerDownloadEnv)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), encodingDownloadTimeout)
+	defer cancel()
+	return fetchEncoding(ctx, encoding, path)
+}
+
+// DownloadEncoding downloads an encoding's vocabulary, verifies its sha256 and caches it in the local
+// directory; nothing is downloaded when the vocabulary is already cached.
+// Call it before the first count (e.g. at startup) so counting never touches the network while tasks run
+func DownloadEncoding(ctx context.Context, encoding string) error {
+	if _, ok := encodingSplits[encoding]; !ok {
+		return fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+	path := encodingPath(encoding)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	_, err := fetchEncoding(ctx, encoding, path)
+	return err
+}
+
+// fetchEncoding downloads and verifies a vocabulary and writes it to path once it parses.
+// A failure to cache it does not affect the current use
+func fetchEncoding(ctx context.Context, encoding, path string) (map[string]int, error) {
+	data, err := downloadEncoding(ctx, encodingURLs[encoding])
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s vocabulary: %w", encoding, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != encodingHashes[encoding] {
+		return nil, fmt.Errorf("downloaded %s vocabulary has sha256 %s, expected %s", encoding, got, encodingHashes[encoding])
+	}
+	ranks, err := parseRanks(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s vocabulary: %w", encoding, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err == nil {
+			os.Rename(tmp, path)
+		}
+	}
+	return ranks, nil
+}
+
+// downloadEncoding downloads a vocabulary file with the shared HTTP client, so the proxy and TLS settings
+// of ConfigureHTTPClient apply
+func downloadEncoding(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := NewSharedHTTPClient(encodingDownloadTimeout).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseRanks parses a tiktoken vocabulary.
+// All tokens share one backing string so lookups touch less memory
+func parseRanks(r io.Reader) (map[string]int, error) {
+	var (
+		data    []byte
+		offsets []int
+		ranks   []int
+	)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a token and a rank", line)
+		}
+		token := make([]byte, base64.StdEncoding.DecodedLen(len(fields[0])))
+		n, err := base64.StdEncoding.Decode(token, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid token: %w", line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rank: %w", line, err)
+		}
+		offsets = append(offsets, len(data))
+		data = append(data, token[:n]...)
+		ranks = append(ranks, rank)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocabulary is empty")
+	}
+
+	tokens := string(data)
+	offsets = append(offsets, len(tokens))
+	result := make(map[string]int, len(ranks))
+	for i, rank := range ranks {
+		result[tokens[offsets[i]:offsets[i+1]]] = rank
+	}
+	return result, nil
+}
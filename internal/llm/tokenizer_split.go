@@ -0,0 +1,240 @@
+package llm
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitFunc returns the end of the pre-tokenization piece that starts at start.
+// It is equivalent to tiktoken's regular expression, written by hand because Go's regexp
+// does not support the \s+(?!\S) lookahead
+type splitFunc func(text string, start int) int
+
+// splitText splits text into pre-tokenization pieces and calls yield for each of them
+func splitText(text string, next splitFunc, yield func(piece string)) {
+	for start := 0; start < len(text); {
+		end := next(text, start)
+		if end <= start {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			end = start + size
+		}
+		yield(text[start:end])
+		start = end
+	}
+}
+
+// splitCL100K implements the cl100k_base pre-tokenization pattern:
+// (?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitCL100K(text string, start int) int {
+	r, size := utf8.DecodeRuneInString(text[start:])
+	if r == '\'' {
+		if end := matchContraction(text, start); end > start {
+			return end
+		}
+	}
+
+	if isLetter(r) {
+		return scanWhile(text, start, isLetter)
+	}
+	if r != '\r' && r != '\n' && !isNumber(r) && start+size < len(text) {
+		if next, _ := utf8.DecodeRuneInString(text[start+size:]); isLetter(next) {
+			return scanWhile(text, start+size, isLetter)
+		}
+	}
+
+	if isNumber(r) {
+		return scanNumbers(text, start)
+	}
+	if end := matchPunctuation(text, start, isNewline); end > start {
+		return end
+	}
+	return matchWhitespace(text, start)
+}
+
+// splitO200K implements the o200k_base pre-tokenization pattern:
+// [^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+// [^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+// \p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitO200K(text string, start int) int {
+	r, size := utf8.DecodeRuneInString(text[start:])
+
+	// Try the match with the optional leading character first; a letter cannot be the leading character
+	words := []int{start}
+	if r != '\r' && r != '\n' && !isLetter(r) && !isNumber(r) && start+size < len(text) {
+		words = []int{start + size, start}
+	}
+	for _, match := range []func(string, int) int{matchO200KLowerWord, matchO200KUpperWord} {
+		for _, word := range words {
+			if end := match(text, word); end > word {
+				return end
+			}
+		}
+	}
+
+	if isNumber(r) {
+		return scanNumbers(text, start)
+	}
+	if end := matchPunctuation(text, start, func(r rune) bool { return isNewline(r) || r == '/' }); end > start {
+		return end
+	}
+	return matchWhitespace(text, start)
+}
+
+// matchO200KLowerWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+ and an optional
+// contraction, returning start when it fails. The upper part is greedy and backs off one rune at a time
+// when no lower rune follows (Lm, Lo and M belong to both classes)
+func matchO200KLowerWord(text string, start int) int {
+	upperEnd := scanWhile(text, start, isUpperClass)
+	for pos := upperEnd; ; {
+		if pos < len(text) {
+			if r, _ := utf8.DecodeRuneInString(text[pos:]); isLowerClass(r) {
+				return matchContraction(text, scanWhile(text, pos, isLowerClass))
+			}
+		}
+		if pos == start {
+			return start
+		}
+		_, size := utf8.DecodeLastRuneInString(text[start:pos])
+		pos -= size
+	}
+}
+
+// matchO200KUpperWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]* and an optional
+// contraction, returning start when it fails
+func matchO200KUpperWord(text string, start int) int {
+	upperEnd := scanWhile(text, start, isUpperClass)
+	if upperEnd == start {
+		return start
+	}
+	return matchContraction(text, scanWhile(text, upperEnd, isLowerClass))
+}
+
+// matchContraction matches the case-insensitive 's|'t|'re|'ve|'m|'ll|'d, returning start when it fails
+func matchContraction(text string, start int) int {
+	if start >= len(text) || text[start] != '\'' {
+		return start
+	}
+	for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+		end := start + 1 + len(suffix)
+		if end <= len(text) && equalFoldASCII(text[start+1:end], suffix) {
+			return end
+		}
+	}
+	return start
+}
+
+// matchPunctuation matches " ?[^\s\p{L}\p{N}]+" followed by any number of trailing runes, returning start when it fails
+func matchPunctuation(text string, start int, trailing func(rune) bool) int {
+	pos := start
+	if text[pos] == ' ' {
+		pos++
+	}
+	end := scanWhile(text, pos, isPunctuation)
+	if end == pos {
+		return start
+	}
+	return scanWhile(text, end, trailing)
+}
+
+// matchWhitespace matches \s*[\r\n]+|\s+(?!\S)|\s+
+func matchWhitespace(text string, start int) int {
+	end := scanWhile(text, start, unicode.IsSpace)
+	if end == start {
+		return start
+	}
+	// \s*[\r\n]+: up to the last newline in the whitespace
+	for pos := end; pos > start; {
+		r, size := utf8.DecodeLastRuneInString(text[start:pos])
+		if isNewline(r) {
+			return pos
+		}
+		pos -= size
+	}
+	// \s+(?!\S): before a non-space rune the last whitespace is left as the prefix of the next piece
+	if end < len(text) {
+		_, size := utf8.DecodeLastRuneInString(text[start:end])
+		if end-size > start {
+			return end - size
+		}
+	}
+	return end
+}
+
+// scanNumbers matches \p{N}{1,3}
+func scanNumbers(text string, start int) int {
+	pos := start
+	for count := 0; count < 3 && pos < len(text); count++ {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if !isNumber(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+// scanWhile returns the end of the runes from start that satisfy match
+func scanWhile(text string, start int, match func(rune) bool) int {
+	pos := start
+	for pos < len(text) {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if !match(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+// equalFoldASCII compares s with a lower-case ASCII string, ignoring ASCII case
+func equalFoldASCII(s, lower string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != lower[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(r rune) bool {
+	if r < utf8.RuneSelf {
+		return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+	}
+	return unicode.IsLetter(r)
+}
+
+func isNumber(r rune) bool {
+	if r < utf8.RuneSelf {
+		return '0' <= r && r <= '9'
+	}
+	return unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+// isPunctuation matches [^\s\p{L}\p{N}]
+func isPunctuation(r rune) bool {
+	return !unicode.IsSpace(r) && !isLetter(r) && !isNumber(r)
+}
+
+// isUpperClass matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]
+func isUpperClass(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'A' <= r && r <= 'Z'
+	}
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLowerClass matches [\p{Ll}\p{Lm}\p{Lo}\p{M}]
+func isLowerClass(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z'
+	}
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
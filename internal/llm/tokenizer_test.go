@@ -0,0 +1,438 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+)
+
+// testVocabulary builds a tiktoken vocabulary of all 256 bytes followed by the merged tokens in order
+func testVocabulary(merges ...string) string {
+	var sb strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, token := range merges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+	return sb.String()
+}
+
+func newTestBPE(t testing.TB, encoding string, merges ...string) *BPETokenizer {
+	t.Helper()
+	bpe, err := NewBPETokenizer(encoding, strings.NewReader(testVocabulary(merges...)))
+	if err != nil {
+		t.Fatalf("failed to create BPE: %v", err)
+	}
+	return bpe
+}
+
+func splitPieces(text string, split splitFunc) []string {
+	var pieces []string
+	splitText(text, split, func(piece string) { pieces = append(pieces, piece) })
+	return pieces
+}
+
+func TestSplitCL100K(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello world's 12345 foo  bar", []string{"Hello", " world", "'s", " ", "123", "45", " foo", " ", " bar"}},
+		{"HelloWorld isn't", []string{"HelloWorld", " isn", "'t"}},
+		{"line\n\nnext  \n", []string{"line", "\n\n", "next", "  \n"}},
+		{"x = (a+b);\n", []string{"x", " =", " (", "a", "+b", ");\n"}},
+		{"你好，世界", []string{"你好", "，世界"}},
+		{"trailing   ", []string{"trailing", "   "}},
+	}
+	for _, tt := range tests {
+		if got := splitPieces(tt.text, splitCL100K); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCL100K(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitO200K(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"HelloWorld isn't", []string{"Hello", "World", " isn't"}},
+		{"HTTPServer 2024", []string{"HTTPServer", " ", "202", "4"}},
+		{"ABC", []string{"ABC"}},
+		{"path/to//\nfile", []string{"path", "/to", "//\n", "file"}},
+		{"line\n\nnext  \n", []string{"line", "\n\n", "next", "  \n"}},
+	}
+	for _, tt := range tests {
+		if got := splitPieces(tt.text, splitO200K); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitO200K(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBPECountTokens(t *testing.T) {
+	bpe := newTestBPE(t, CL100KBase, "he", "ll", "hell", "hello", " w", "or", " wor", "ld", " world")
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{"hello world", 2},
+		{"hello hello", 3}, // " hello" is not in the vocabulary, so it merges into " " and "hello"
+		{"help", 3},        // "he", "l" and "p"
+		{"你好", 6},          // one token per byte without merges
+	}
+	for _, tt := range tests {
+		if got := bpe.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBPEHeapMergeMatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := "abcd"
+	randomString := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+
+	var merges []string
+	seen := map[string]bool{}
+	for len(merges) < 60 {
+		token := randomString(2 + rng.Intn(5))
+		if !seen[token] {
+			seen[token] = true
+			merges = append(merges, token)
+		}
+	}
+	bpe := newTestBPE(t, CL100KBase, merges...)
+
+	for i := 0; i < 200; i++ {
+		piece := randomString(2 + rng.Intn(300))
+		if linear, heap := bpe.countPieceLinear(piece), bpe.countPieceHeap(piece); linear != heap {
+			t.Fatalf("piece %q: linear merge = %d, heap merge = %d", piece, linear, heap)
+		}
+	}
+}
+
+func TestBPEFallsBackToHeuristicWhenVocabularyUnavailable(t *testing.T) {
+	bpe := &BPETokenizer{encoding: CL100KBase, split: splitCL100K, load: func() (map[string]int, error) {
+		return nil, errors.New("offline")
+	}}
+
+	text := "hello world, 你好"
+	if got, want := bpe.CountTokens(text), (ScriptHeuristicTokenizer{}).CountTokens(text); got != want {
+		t.Errorf("CountTokens = %d, want heuristic %d", got, want)
+	}
+	if bpe.Err() == nil {
+		t.Error("expected Err to report the load failure")
+	}
+}
+
+func TestLoadEncodingFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(TokenizerDirEnv, dir)
+	t.Setenv(TokenizerDownloadEnv, "")
+
+	if _, err := loadEncoding(O200KBase); err == nil {
+		t.Fatal("expected an error for a missing vocabulary when downloads are not enabled")
+	}
+
+	path := filepath.Join(dir, CL100KBase+".tiktoken")
+	if err := os.WriteFile(path, []byte(testVocabulary("ab")), 0644); err != nil {
+		t.Fatalf("failed to write vocabulary: %v", err)
+	}
+	ranks, err := loadEncoding(CL100KBase)
+	if err != nil {
+		t.Fatalf("loadEncoding failed: %v", err)
+	}
+	if len(ranks) != 257 || ranks["ab"] != 256 {
+		t.Errorf("unexpected ranks: %d entries, ab=%d", len(ranks), ranks["ab"])
+	}
+
+	if _, err := NewBPETokenizer(CL100KBase, strings.NewReader("not-a-rank-line\n")); err == nil {
+		t.Error("expected an error for a malformed vocabulary")
+	}
+	if _, err := NewBPETokenizer("p50k_base", strings.NewReader(testVocabulary())); err == nil {
+		t.Error("expected an error for an unsupported encoding")
+	}
+}
+
+func TestDownloadVerifiesChecksum(t *testing.T) {
+	vocabulary := testVocabulary("ab")
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(vocabulary))
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256([]byte(vocabulary))
+	originalURL, originalHash := encodingURLs[CL100KBase], encodingHashes[CL100KBase]
+	defer func() { encodingURLs[CL100KBase], encodingHashes[CL100KBase] = originalURL, originalHash }()
+	encodingURLs[CL100KBase] = server.URL + "/cl100k_base.tiktoken"
+
+	dir := t.TempDir()
+	t.Setenv(TokenizerDirEnv, dir)
+	t.Setenv(TokenizerDownloadEnv, "")
+
+	// Counting does not touch the network by default
+	if _, err := loadEncoding(CL100KBase); err == nil {
+		t.Fatal("expected a missing vocabulary to fail without downloading")
+	}
+	if got := requests.Load(); got != 0 {
+		t.Fatalf("expected no download in the counting path, got %d requests", got)
+	}
+
+	encodingHashes[CL100KBase] = strings.Repeat("0", 64)
+	if err := DownloadEncoding(context.Background(), CL100KBase); err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, CL100KBase+".tiktoken")); !os.IsNotExist(err) {
+		t.Fatal("a vocabulary with a wrong checksum must not be cached")
+	}
+
+	encodingHashes[CL100KBase] = hex.EncodeToString(sum[:])
+	if err := DownloadEncoding(context.Background(), CL100KBase); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	ranks, err := loadEncoding(CL100KBase)
+	if err != nil {
+		t.Fatalf("expected the downloaded vocabulary to be cached: %v", err)
+	}
+	if ranks["ab"] != 256 {
+		t.Errorf("unexpected ranks from the cached vocabulary: ab=%d", ranks["ab"])
+	}
+	if err := DownloadEncoding(context.Background(), CL100KBase); err != nil {
+		t.Fatalf("Download with a cached vocabulary failed: %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected a cached vocabulary not to be downloaded again, got %d requests", got)
+	}
+
+	// With TokenizerDownloadEnv set, the first count downloads the missing vocabulary
+	t.Setenv(TokenizerDirEnv, t.TempDir())
+	t.Setenv(TokenizerDownloadEnv, "1")
+	if _, err := loadEncoding(CL100KBase); err != nil {
+		t.Fatalf("expected the opt-in download to succeed: %v", err)
+	}
+
+	if err := DownloadEncoding(context.Background(), "p50k_base"); err == nil {
+		t.Error("expected an error for an unsupported encoding")
+	}
+}
+
+func TestScriptHeuristicCountTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcdefgh", 2},
+		{"abcde", 2},
+		{"你好世界", 4},
+		{"こんにちは", 5},
+		{"привет", 3},
+		{"hi 你好", 3},
+	}
+	for _, tt := range tests {
+		if got := (ScriptHeuristicTokenizer{}).CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountMessagesIncludesChatOverhead(t *testing.T) {
+	h := ScriptHeuristicTokenizer{}
+
+	if got := h.CountMessages(nil); got != TokensPerReply {
+		t.Errorf("empty conversation = %d, want %d", got, TokensPerReply)
+	}
+
+	// user(1) + abcd(1) + 3 per message + 3 for the reply
+	messages := []Message{{Role: RoleUser, Content: "abcd"}}
+	if got := h.CountMessages(messages); got != 8 {
+		t.Errorf("single message = %d, want 8", got)
+	}
+
+	messages[0].Name = "bob"
+	if got := h.CountMessages(messages); got != 8+TokensPerName+1 {
+		t.Errorf("named message = %d, want %d", got, 8+TokensPerName+1)
+	}
+
+	messages = append(messages, Message{
+		Role:      RoleAssistant,
+		ToolCalls: []ToolCall{{Function: ToolCallFunction{Name: "search", Arguments: `{"q":"go"}`}}},
+	})
+	// assistant(3) + search(2) + {"q":"go"}(3) + 3 per message
+	if got := h.CountMessages(messages); got != 10+11 {
+		t.Errorf("conversation with tool call = %d, want %d", got, 10+11)
+	}
+
+	if got, want := EstimateTokens(h, messages), h.CountMessages(messages); got != want {
+		t.Errorf("EstimateTokens = %d, want the tokenizer's count %d", got, want)
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o":                 O200KBase,
+		"gpt-4o-mini":            O200KBase,
+		"openai/gpt-4.1":         O200KBase,
+		"o3-mini":                O200KBase,
+		"gpt-4":                  CL100KBase,
+		"GPT-4-Turbo":            CL100KBase,
+		"gpt-3.5-turbo":          CL100KBase,
+		"text-embedding-3-small": CL100KBase,
+		"claude-3-5-sonnet":      "",
+		"gemini-1.5-pro":         "",
+		"":                       "",
+	}
+	for model, want := range tests {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestTokenizerForModel(t *testing.T) {
+	bpe, ok := TokenizerForModel("gpt-4o").(*BPETokenizer)
+	if !ok || bpe.Encoding() != O200KBase {
+		t.Errorf("expected an o200k_base BPE for gpt-4o, got %#v", TokenizerForModel("gpt-4o"))
+	}
+	if again, _ := TokenizerForModel("gpt-4o-mini").(*BPETokenizer); again != bpe {
+		t.Error("expected models with the same encoding to share a tokenizer")
+	}
+	if _, ok := TokenizerForModel("claude-3-5-sonnet").(ScriptHeuristicTokenizer); !ok {
+		t.Errorf("expected the heuristic for non-OpenAI models, got %#v", TokenizerForModel("claude-3-5-sonnet"))
+	}
+	if _, err := GetEncoding("r50k_base"); err == nil {
+		t.Error("expected an error for an unsupported encoding")
+	}
+
+	// LLMs select the model's tokenizer without any registration
+	if _, ok := TokenizerForModel("gpt-4").(*BPETokenizer); !ok {
+		t.Errorf("expected a cl100k_base BPE for gpt-4, got %#v", TokenizerForModel("gpt-4"))
+	}
+	if _, ok := NewBaseLLM("openai", "gpt-4o-mini").GetTokenizer().(*BPETokenizer); !ok {
+		t.Error("expected BaseLLM.GetTokenizer to return the model's BPE tokenizer")
+	}
+}
+
+// TestBPEMatchesTiktoken checks exact counts against tiktoken's cl100k_base output.
+// testdata/cl100k_base_subset.tiktoken holds the 256 byte tokens at their cl100k_base ranks plus the merge
+// chains that build the tokens of these texts. The merges are numbered from 100256 in chain order rather than
+// with their real ranks, which only matters when several merges compete; here the token counts match tiktoken
+func TestBPEMatchesTiktoken(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "cl100k_base_subset.tiktoken"))
+	if err != nil {
+		t.Fatalf("failed to open vocabulary: %v", err)
+	}
+	defer file.Close()
+	bpe, err := NewBPETokenizer(CL100KBase, file)
+	if err != nil {
+		t.Fatalf("NewBPETokenizer failed: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want int // len(tiktoken.get_encoding("cl100k_base").encode(text))
+	}{
+		{"Hello, world!", 4},       // [9906, 11, 1917, 0]
+		{"hello world", 2},         // [15339, 1917]
+		{"This is a test.", 5},     // [2028, 374, 264, 1296, 13]
+		{"tiktoken is great!", 6},  // [83, 1609, 5963, 374, 2294, 0]
+		{"1234567", 3},             // "123", "456", "7"
+		{"I'm", 2},                 // "I", "'m"
+		{"\n\n", 1},                // [271]
+		{"hello world\n\nThis", 4}, // "hello", " world", "\n\n", "This"
+	}
+	for _, tt := range tests {
+		if got := bpe.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func benchmarkText(unit string) string {
+	return strings.Repeat(unit, 100*1024/len(unit)+1)[:100*1024]
+}
+
+// benchmarkBPE returns the real cl100k_base tokenizer when its vocabulary is cached locally, otherwise one with
+// a vocabulary of similar size in which every English word and every Han character of texts merges into one token
+func benchmarkBPE(b *testing.B, texts ...string) *BPETokenizer {
+	b.Setenv(TokenizerDownloadEnv, "")
+	if bpe, err := GetEncoding(CL100KBase); err == nil && bpe.Err() == nil {
+		return bpe
+	}
+
+	var merges []string
+	seen := map[string]bool{}
+	addChain := func(token string) {
+		for end := 2; end <= len(token); end++ {
+			if prefix := token[:end]; !seen[prefix] {
+				seen[prefix] = true
+				merges = append(merges, prefix)
+			}
+		}
+	}
+	for _, text := range texts {
+		splitText(text, splitCL100K, func(piece string) {
+			if utf8.ValidString(piece) && len(piece) == len([]rune(piece)) {
+				addChain(piece)
+				return
+			}
+			for _, r := range piece {
+				addChain(string(r))
+			}
+		})
+	}
+	for i := 0; len(merges) < 100000; i++ {
+		merges = append(merges, fmt.Sprintf("\x00filler%d", i))
+	}
+	return newTestBPE(b, CL100KBase, merges...)
+}
+
+func BenchmarkCountTokens100KB(b *testing.B) {
+	english := benchmarkText("The quick brown fox jumps over the lazy dog, and then it naps for 12345 seconds.\n")
+	chinese := benchmarkText("上下文窗口按模型的词表计算token数量，")
+	bpe := benchmarkBPE(b, english, chinese)
+
+	benchmarks := []struct {
+		name      string
+		tokenizer Tokenizer
+		text      string
+	}{
+		{"Heuristic/English", ScriptHeuristicTokenizer{}, english},
+		{"Heuristic/Chinese", ScriptHeuristicTokenizer{}, chinese},
+		{"BPE/English", bpe, english},
+		{"BPE/Chinese", bpe, chinese},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(bm.text)))
+			for i := 0; i < b.N; i++ {
+				bm.tokenizer.CountTokens(bm.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 同一组工具定义经过各提供商的请求构建后应保留名称、描述和参数结构
+func TestToolSchemaConversionAcrossProviders(t *testing.T) {
+	tools := []Tool{
+		{Type: "function", Function: ToolSchema{
+			Name:        "get_weather",
+			Description: "Get the current weather for a city",
+			Parameters: map[string]interface{}{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type":    "object",
+				"properties": map[string]interface{}{
+					"city": map[string]interface{}{"type": "string", "description": "City name"},
+					"unit": map[string]interface{}{"type": []interface{}{"string", "null"}, "enum": []interface{}{"celsius", "fahrenheit"}},
+					"days": map[string]interface{}{
+						"type":  "array",
+						"items": map[string]interface{}{"type": "integer", "minimum": 1},
+					},
+				},
+				"required":             []interface{}{"city"},
+				"additionalProperties": false,
+			},
+		}},
+		{Type: "function", Function: ToolSchema{
+			Name:        "current_time",
+			Description: "Get the current time",
+			Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+		}},
+	}
+	options := &CallOptions{Tools: tools}
+
+	openAI := NewOpenAILLM("gpt-4o").buildChatRequest(nil, options)
+	anthropic := NewAnthropicLLM("claude-sonnet-4").buildRequest("", nil, options)
+	gemini := NewGeminiLLM("gemini-2.0-flash").buildRequest(nil, nil, options)
+
+	if len(openAI.Tools) != 2 || len(anthropic.Tools) != 2 || len(gemini.Tools) != 1 || len(gemini.Tools[0].FunctionDeclarations) != 2 {
+		t.Fatalf("expected every tool to be converted: openai=%d anthropic=%d gemini=%+v", len(openAI.Tools), len(anthropic.Tools), gemini.Tools)
+	}
+
+	for i, tool := range tools {
+		declaration := gemini.Tools[0].FunctionDeclarations[i]
+		names := []string{openAI.Tools[i].Function.Name, anthropic.Tools[i].Name, declaration.Name}
+		descriptions := []string{openAI.Tools[i].Function.Description, anthropic.Tools[i].Description, declaration.Description}
+		for j := range names {
+			if names[j] != tool.Function.Name || descriptions[j] != tool.Function.Description {
+				t.Errorf("tool %d: provider %d changed the name or description: %q %q", i, j, names[j], descriptions[j])
+			}
+		}
+	}
+
+	// OpenAI和Anthropic接受完整的JSON Schema
+	if !reflect.DeepEqual(openAI.Tools[0].Function.Parameters, tools[0].Function.Parameters) {
+		t.Errorf("OpenAI parameters should be passed through: %v", openAI.Tools[0].Function.Parameters)
+	}
+	if !reflect.DeepEqual(anthropic.Tools[0].InputSchema, tools[0].Function.Parameters) {
+		t.Errorf("Anthropic input schema should be passed through: %v", anthropic.Tools[0].InputSchema)
+	}
+
+	// Gemini只接受OpenAPI子集
+	parameters := gemini.Tools[0].FunctionDeclarations[0].Parameters
+	if _, ok := parameters["additionalProperties"]; ok {
+		t.Errorf("Gemini parameters must not contain additionalProperties: %v", parameters)
+	}
+	if _, ok := parameters["$schema"]; ok {
+		t.Errorf("Gemini parameters must not contain $schema: %v", parameters)
+	}
+	properties := parameters["properties"].(map[string]interface{})
+	unit := properties["unit"].(map[string]interface{})
+	if unit["type"] != "string" || unit["nullable"] != true || len(unit["enum"].([]interface{})) != 2 {
+		t.Errorf("expected a nullable string enum, got %v", unit)
+	}
+	days := properties["days"].(map[string]interface{})
+	if items := days["items"].(map[string]interface{}); items["type"] != "integer" || items["minimum"] != 1 {
+		t.Errorf("expected array items to be converted, got %v", days)
+	}
+	if required := parameters["required"].([]interface{}); len(required) != 1 || required[0] != "city" {
+		t.Errorf("expected required fields to be kept, got %v", parameters["required"])
+	}
+	if gemini.Tools[0].FunctionDeclarations[1].Parameters != nil {
+		t.Errorf("Gemini tools without properties should omit parameters")
+	}
+}
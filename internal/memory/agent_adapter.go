@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+)
+
+// AgentMemoryKeyField agent.Memory适配器保存记忆键的metadata字段
+const AgentMemoryKeyField = "key"
+
+// AgentNamespaceField agent.Memory适配器保存命名空间的metadata字段，克隆的Agent在各自的命名空间中读写记忆
+const AgentNamespaceField = "namespace"
+
+// MatchesFilter 检查记忆项是否满足过滤条件
+// "agent"键匹配保存记忆的agent，其他键匹配metadata中的同名字段（字段不存在视为不匹配），值按字符串形式比较
+func MatchesFilter(item MemoryItem, filter map[string]interface{}) bool {
+	for key, expected := range filter {
+		var actual interface{}
+		if key == "agent" {
+			actual = item.Agent
+		} else {
+			var ok bool
+			if actual, ok = item.Metadata[key]; !ok {
+				return false
+			}
+		}
+		if fmt.Sprintf("%v", actual) != fmt.Sprintf("%v", expected) {
+			return false
+		}
+	}
+	return true
+}
+
+// AgentFilter 返回限定为agentRole所保存记忆的过滤条件，agentRole为空时不限定agent
+func AgentFilter(agentRole string, extra map[string]interface{}) map[string]interface{} {
+	filter := make(map[string]interface{}, len(extra)+1)
+	for k, v := range extra {
+		filter[k] = v
+	}
+	if agentRole != "" {
+		filter["agent"] = agentRole
+	}
+	return filter
+}
+
+// NamespacedAgentFilter 在AgentFilter的基础上限定命名空间，namespace为空时与AgentFilter相同
+func NamespacedAgentFilter(agentRole, namespace string, extra map[string]interface{}) map[string]interface{} {
+	filter := AgentFilter(agentRole, extra)
+	if namespace != "" {
+		filter[AgentNamespaceField] = namespace
+	}
+	return filter
+}
+
+// ToAgentMemoryItem 转换为agent.MemoryItem，优先使用metadata中保存的记忆键
+func ToAgentMemoryItem(item MemoryItem) agent.MemoryItem {
+	key := item.ID
+	if k, ok := item.Metadata[AgentMemoryKeyField].(string); ok && k != "" {
+		key = k
+	}
+	return agent.MemoryItem{
+		Key:       key,
+		Value:     item.Value,
+		Timestamp: item.CreatedAt,
+		Score:     item.Score,
+		Metadata:  item.Metadata,
+	}
+}
+
+// AgentAccessTracker 记录agent.Memory适配器的检索命中情况，供各记忆类型的适配器共用
+type AgentAccessTracker struct {
+	mu           sync.Mutex
+	hits         int
+	misses       int
+	lastAccessed time.Time
+}
+
+// Record 记录一次检索的命中情况
+func (t *AgentAccessTracker) Record(hit bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if hit {
+		t.hits++
+	} else {
+		t.misses++
+	}
+	t.lastAccessed = time.Now()
+}
+
+// Touch 更新最后访问时间
+func (t *AgentAccessTracker) Touch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastAccessed = time.Now()
+}
+
+// Stats 返回命中率和最后访问时间，totalItems由适配器从底层存储获取
+func (t *AgentAccessTracker) Stats(totalItems int) agent.MemoryStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := agent.MemoryStats{TotalItems: totalItems, LastAccessed: t.lastAccessed}
+	if total := t.hits + t.misses; total > 0 {
+		stats.HitRate = float64(t.hits) / float64(total)
+		stats.MissRate = float64(t.misses) / float64(total)
+	}
+	return stats
+}
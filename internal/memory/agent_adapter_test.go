@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesFilter(t *testing.T) {
+	item := MemoryItem{
+		Agent:    "researcher",
+		Metadata: map[string]interface{}{"key": "market", "approved": true, "iteration": 2},
+	}
+
+	assert.True(t, MatchesFilter(item, nil))
+	assert.True(t, MatchesFilter(item, map[string]interface{}{"agent": "researcher", "key": "market"}))
+	assert.True(t, MatchesFilter(item, map[string]interface{}{"approved": true, "iteration": 2}))
+	assert.False(t, MatchesFilter(item, map[string]interface{}{"agent": "writer"}))
+	assert.False(t, MatchesFilter(item, map[string]interface{}{"key": "style"}))
+	// 缺失的metadata字段不匹配
+	assert.False(t, MatchesFilter(item, map[string]interface{}{"task": "<nil>"}))
+}
+
+func TestAgentFilter(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{"agent": "writer", "key": "style"},
+		AgentFilter("writer", map[string]interface{}{"key": "style"}))
+	assert.Empty(t, AgentFilter("", nil))
+}
+
+func TestToAgentMemoryItem(t *testing.T) {
+	createdAt := time.Now()
+	item := ToAgentMemoryItem(MemoryItem{ID: "id-1", Value: "v", Score: 0.5, CreatedAt: createdAt,
+		Metadata: map[string]interface{}{AgentMemoryKeyField: "market"}})
+	assert.Equal(t, "market", item.Key)
+	assert.Equal(t, createdAt, item.Timestamp)
+
+	assert.Equal(t, "id-2", ToAgentMemoryItem(MemoryItem{ID: "id-2"}).Key)
+}
+
+func TestAgentAccessTracker(t *testing.T) {
+	var tracker AgentAccessTracker
+	assert.Equal(t, 0.0, tracker.Stats(0).HitRate)
+
+	tracker.Record(true)
+	tracker.Record(true)
+	tracker.Record(false)
+	tracker.Touch()
+
+	stats := tracker.Stats(7)
+	assert.Equal(t, 7, stats.TotalItems)
+	assert.InDelta(t, 2.0/3.0, stats.HitRate, 1e-9)
+	assert.InDelta(t, 1.0/3.0, stats.MissRate, 1e-9)
+	assert.False(t, stats.LastAccessed.IsZero())
+}
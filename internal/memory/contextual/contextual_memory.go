@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/memory"
 	"github.com/ynl/greensoulai/internal/memory/entity"
 	"github.com/ynl/greensoulai/internal/memory/external"
 	"github.com/ynl/greensoulai/internal/memory/long_term"
@@ -14,6 +16,12 @@ import (
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
+// charsPerToken 估算token数量时每个token对应的字符数
+const charsPerToken = 4
+
+// 确保ContextualMemory可以作为agent的组合记忆使用
+var _ agent.MemorySuite = (*ContextualMemory)(nil)
+
 // ContextualMemory 上下文记忆系统
 // 参考crewAI的ContextualMemory实现，统一管理所有记忆类型
 // 自动构建任务相关的最小且高相关性的上下文信息
@@ -48,7 +56,11 @@ type ContextualMemoryConfig struct {
 	// 上下文组装选项
 	EnableFormatting     bool `json:"enable_formatting"`      // 启用格式化输出
 	EnableSectionHeaders bool `json:"enable_section_headers"` // 启用章节标题
-	MaxContextLength     int  `json:"max_context_length"`     // 最大上下文长度
+	MaxContextLength     int  `json:"max_context_length"`     // 最大上下文长度（字符）
+	MaxContextTokens     int  `json:"max_context_tokens"`     // 上下文token预算，按约4字符/token估算，0表示不限制
+
+	// 并发查询选项
+	SourceTimeout time.Duration `json:"source_timeout"` // 单个记忆来源的查询超时，0表示不超时
 
 	// 过滤选项
 	FilterEmptyResults  bool `json:"filter_empty_results"` // 过滤空结果
@@ -73,6 +85,10 @@ func DefaultContextualMemoryConfig() ContextualMemoryConfig {
 		EnableFormatting:     true,
 		EnableSectionHeaders: true,
 		MaxContextLength:     8000, // 避免上下文过长
+		MaxContextTokens:     2000,
+
+		// 并发查询选项
+		SourceTimeout: 2 * time.Second,
 
 		// 过滤选项
 		FilterEmptyResults:  true,
@@ -137,55 +153,17 @@ func (cm *ContextualMemory) BuildContextForTask(ctx context.Context, task agent.
 		return "", nil
 	}
 
-	var contextParts []string
-
-	// 按照crewAI的顺序获取各类记忆上下文
-	// 1. 长期记忆上下文（历史数据）
-	if ltmContext, err := cm.fetchLTMContext(ctx, task.GetDescription()); err == nil && ltmContext != "" {
-		contextParts = append(contextParts, ltmContext)
-	} else if err != nil {
-		cm.logger.Warn("failed to fetch LTM context", logger.Field{Key: "error", Value: err})
-	}
-
-	// 2. 短期记忆上下文（最近洞察）
-	if stmContext, err := cm.fetchSTMContext(ctx, query); err == nil && stmContext != "" {
-		contextParts = append(contextParts, stmContext)
-	} else if err != nil {
-		cm.logger.Warn("failed to fetch STM context", logger.Field{Key: "error", Value: err})
-	}
-
-	// 3. 实体记忆上下文
-	if entityContext, err := cm.fetchEntityContext(ctx, query); err == nil && entityContext != "" {
-		contextParts = append(contextParts, entityContext)
-	} else if err != nil {
-		cm.logger.Warn("failed to fetch entity context", logger.Field{Key: "error", Value: err})
-	}
-
-	// 4. 外部记忆上下文
-	if externalContext, err := cm.fetchExternalContext(ctx, query); err == nil && externalContext != "" {
-		contextParts = append(contextParts, externalContext)
-	} else if err != nil {
-		cm.logger.Warn("failed to fetch external context", logger.Field{Key: "error", Value: err})
-	}
-
-	// 过滤和去重
-	if cm.config.FilterEmptyResults {
-		contextParts = cm.filterEmptyParts(contextParts)
-	}
-
-	if cm.config.EnableDeduplication {
-		contextParts = cm.deduplicateParts(contextParts)
-	}
+	// 并发查询各记忆来源，慢或不可用的来源在超时后被跳过
+	sections := cm.fetchSources(ctx, cm.memorySources(task.GetDescription(), query))
 
-	// 组装最终上下文
+	// 按固定顺序组装：短期记忆、实体、长期记忆、外部记忆，保证提示词稳定
+	contextParts, totalLength := cm.assembleSections(sections)
 	finalContext := strings.Join(contextParts, "\n")
 
-	// 检查长度限制
-	if cm.config.MaxContextLength > 0 && len(finalContext) > cm.config.MaxContextLength {
-		finalContext = finalContext[:cm.config.MaxContextLength]
-		cm.logger.Warn("context truncated due to length limit",
-			logger.Field{Key: "original_length", Value: len(strings.Join(contextParts, "\n"))},
-			logger.Field{Key: "max_length", Value: cm.config.MaxContextLength},
+	if len(finalContext) < totalLength {
+		cm.logger.Warn("context truncated due to budget limit",
+			logger.Field{Key: "original_length", Value: totalLength},
+			logger.Field{Key: "max_length", Value: cm.contextBudget()},
 		)
 	}
 
@@ -204,76 +182,214 @@ func (cm *ContextualMemory) BuildContextForTask(ctx context.Context, task agent.
 	return finalContext, nil
 }
 
-// fetchSTMContext 获取短期记忆上下文
-// 参考crewAI的_fetch_stm_context实现
-func (cm *ContextualMemory) fetchSTMContext(ctx context.Context, query string) (string, error) {
-	if cm.stm == nil {
-		return "", nil
-	}
+// memorySource 单个记忆来源的查询定义
+type memorySource struct {
+	name   string
+	header string
+	fetch  func(ctx context.Context) ([]string, error)
+}
 
-	cm.logger.Debug("fetching STM context", logger.Field{Key: "query", Value: query})
+// memorySection 单个记忆来源的查询结果，entries按来源的相关性排序
+type memorySection struct {
+	header  string
+	entries []string
+}
 
-	// 搜索短期记忆
-	results, err := cm.stm.Search(ctx, query, cm.config.DefaultSTMLimit, cm.config.STMScoreThreshold)
-	if err != nil {
-		return "", fmt.Errorf("STM search failed: %w", err)
+// memorySources 返回已配置的记忆来源，顺序即输出中章节的顺序
+func (cm *ContextualMemory) memorySources(description, query string) []memorySource {
+	var sources []memorySource
+	if cm.stm != nil {
+		sources = append(sources, memorySource{
+			name:   "short_term",
+			header: "Recent Insights:",
+			fetch:  func(ctx context.Context) ([]string, error) { return cm.fetchSTMContext(ctx, query) },
+		})
+	}
+	if cm.em != nil {
+		sources = append(sources, memorySource{
+			name:   "entity",
+			header: "Entities:",
+			fetch:  func(ctx context.Context) ([]string, error) { return cm.fetchEntityContext(ctx, query) },
+		})
+	}
+	if cm.ltm != nil {
+		sources = append(sources, memorySource{
+			name:   "long_term",
+			header: "Historical Data:",
+			fetch:  func(ctx context.Context) ([]string, error) { return cm.fetchLTMContext(ctx, description) },
+		})
+	}
+	if cm.exm != nil {
+		sources = append(sources, memorySource{
+			name:   "external",
+			header: "External memories:",
+			fetch:  func(ctx context.Context) ([]string, error) { return cm.fetchExternalContext(ctx, query) },
+		})
+	}
+	return sources
+}
+
+// fetchSources 并发查询所有记忆来源
+// 每个来源有独立的超时，超时或出错的来源记录警告后跳过，不阻塞其他来源
+func (cm *ContextualMemory) fetchSources(ctx context.Context, sources []memorySource) []memorySection {
+	type fetchResult struct {
+		index   int
+		entries []string
+		err     error
+	}
+
+	timeout := cm.config.SourceTimeout
+	results := make(chan fetchResult, len(sources)) // 带缓冲，超时后返回的goroutine不会泄漏
+	for i, source := range sources {
+		go func(i int, source memorySource) {
+			sourceCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				sourceCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			entries, err := source.fetch(sourceCtx)
+			results <- fetchResult{index: i, entries: entries, err: err}
+		}(i, source)
+	}
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	sections := make([]memorySection, len(sources))
+	received := make([]bool, len(sources))
+	for pending := len(sources); pending > 0; pending-- {
+		select {
+		case result := <-results:
+			received[result.index] = true
+			if result.err != nil {
+				cm.logger.Warn("failed to fetch memory context, skipping source",
+					logger.Field{Key: "source", Value: sources[result.index].name},
+					logger.Field{Key: "error", Value: result.err},
+				)
+				continue
+			}
+			sections[result.index] = memorySection{header: sources[result.index].header, entries: result.entries}
+		case <-deadline:
+			cm.warnSkippedSources(sources, received, "timeout")
+			return sections
+		case <-ctx.Done():
+			cm.warnSkippedSources(sources, received, ctx.Err().Error())
+			return sections
+		}
 	}
+	return sections
+}
 
-	if len(results) == 0 {
-		cm.logger.Debug("no STM results found")
-		return "", nil
+// warnSkippedSources 为尚未返回结果的记忆来源记录警告
+func (cm *ContextualMemory) warnSkippedSources(sources []memorySource, received []bool, reason string) {
+	for i, source := range sources {
+		if !received[i] {
+			cm.logger.Warn("memory source did not respond in time, skipping",
+				logger.Field{Key: "source", Value: source.name},
+				logger.Field{Key: "reason", Value: reason},
+				logger.Field{Key: "timeout", Value: cm.config.SourceTimeout},
+			)
+		}
 	}
+}
 
-	// 格式化结果为项目符号列表，与crewAI格式保持一致
-	var formattedResults []string
-	for _, result := range results {
-		// 尝试从metadata中获取context字段（与crewAI保持一致）
-		if contextStr, ok := result.Metadata["context"].(string); ok {
-			formattedResults = append(formattedResults, fmt.Sprintf("- %s", contextStr))
-		} else {
-			// 回退到使用Value字段
-			formattedResults = append(formattedResults, fmt.Sprintf("- %v", result.Value))
+// assembleSections 把各来源结果格式化为章节，去重并按预算截断
+// 截断以完整条目为单位，预算用尽后不再输出后续条目；返回章节列表和未截断时的总长度
+func (cm *ContextualMemory) assembleSections(sections []memorySection) ([]string, int) {
+	budget := cm.contextBudget()
+	seen := make(map[string]bool)
+
+	var parts []string
+	length, totalLength := 0, 0
+	exhausted := false
+	for _, section := range sections {
+		entries := section.entries
+		if cm.config.FilterEmptyResults {
+			entries = cm.filterEmptyParts(entries)
+		}
+
+		var lines []string
+		for _, entry := range entries {
+			if cm.config.EnableDeduplication {
+				if seen[entry] {
+					continue
+				}
+				seen[entry] = true
+			}
+
+			line := "- " + entry
+			if len(lines) == 0 && cm.config.EnableSectionHeaders {
+				line = section.header + "\n" + line
+			}
+			// 章节之间和条目之间都以换行分隔
+			lineLength := len(line)
+			if length > 0 || len(lines) > 0 {
+				lineLength++
+			}
+			totalLength += lineLength
+
+			if exhausted || (budget > 0 && length+lineLength > budget) {
+				exhausted = true
+				continue
+			}
+			lines = append(lines, line)
+			length += lineLength
+		}
+
+		if len(lines) > 0 {
+			parts = append(parts, strings.Join(lines, "\n"))
 		}
 	}
+	return parts, totalLength
+}
 
-	if len(formattedResults) == 0 {
-		return "", nil
+// contextBudget 返回上下文的最大字符数，取字符限制和token预算中较小者，0表示不限制
+func (cm *ContextualMemory) contextBudget() int {
+	budget := cm.config.MaxContextLength
+	if cm.config.MaxContextTokens > 0 {
+		tokenBudget := cm.config.MaxContextTokens * charsPerToken
+		if budget <= 0 || tokenBudget < budget {
+			budget = tokenBudget
+		}
 	}
+	return budget
+}
 
-	// 添加章节标题（与crewAI格式保持一致）
-	header := ""
-	if cm.config.EnableSectionHeaders {
-		header = "Recent Insights:\n"
+// fetchSTMContext 获取短期记忆上下文
+// 参考crewAI的_fetch_stm_context实现
+func (cm *ContextualMemory) fetchSTMContext(ctx context.Context, query string) ([]string, error) {
+	cm.logger.Debug("fetching STM context", logger.Field{Key: "query", Value: query})
+
+	// 搜索短期记忆
+	results, err := cm.stm.Search(ctx, query, cm.config.DefaultSTMLimit, cm.config.STMScoreThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("STM search failed: %w", err)
 	}
 
-	context := header + strings.Join(formattedResults, "\n")
+	entries := make([]string, 0, len(results))
+	for _, result := range results {
+		entries = append(entries, memoryItemText(result))
+	}
 
-	cm.logger.Debug("STM context fetched",
-		logger.Field{Key: "results_count", Value: len(results)},
-		logger.Field{Key: "formatted_count", Value: len(formattedResults)},
-	)
+	cm.logger.Debug("STM context fetched", logger.Field{Key: "results_count", Value: len(results)})
 
-	return context, nil
+	return entries, nil
 }
 
 // fetchLTMContext 获取长期记忆上下文
 // 参考crewAI的_fetch_ltm_context实现
-func (cm *ContextualMemory) fetchLTMContext(ctx context.Context, task string) (string, error) {
-	if cm.ltm == nil {
-		return "", nil
-	}
-
+func (cm *ContextualMemory) fetchLTMContext(ctx context.Context, task string) ([]string, error) {
 	cm.logger.Debug("fetching LTM context", logger.Field{Key: "task", Value: task})
 
 	// 使用长期记忆的专用搜索方法
 	results, err := cm.ltm.Search(ctx, task, cm.config.DefaultLTMLimit)
 	if err != nil {
-		return "", fmt.Errorf("LTM search failed: %w", err)
-	}
-
-	if len(results) == 0 {
-		cm.logger.Debug("no LTM results found")
-		return "", nil
+		return nil, fmt.Errorf("LTM search failed: %w", err)
 	}
 
 	// 提取建议列表，与crewAI逻辑保持一致
@@ -293,134 +409,62 @@ func (cm *ContextualMemory) fetchLTMContext(ctx context.Context, task string) (s
 	// 去重处理，与crewAI保持一致
 	suggestions = cm.removeDuplicateStrings(suggestions)
 
-	if len(suggestions) == 0 {
-		return "", nil
-	}
-
-	// 格式化为项目符号列表
-	formattedResults := make([]string, len(suggestions))
-	for i, suggestion := range suggestions {
-		formattedResults[i] = fmt.Sprintf("- %s", suggestion)
-	}
-
-	// 添加章节标题
-	header := ""
-	if cm.config.EnableSectionHeaders {
-		header = "Historical Data:\n"
-	}
-
-	context := header + strings.Join(formattedResults, "\n")
-
 	cm.logger.Debug("LTM context fetched",
 		logger.Field{Key: "results_count", Value: len(results)},
 		logger.Field{Key: "suggestions_count", Value: len(suggestions)},
 	)
 
-	return context, nil
+	return suggestions, nil
 }
 
 // fetchEntityContext 获取实体记忆上下文
 // 参考crewAI的_fetch_entity_context实现
-func (cm *ContextualMemory) fetchEntityContext(ctx context.Context, query string) (string, error) {
-	if cm.em == nil {
-		return "", nil
-	}
-
+func (cm *ContextualMemory) fetchEntityContext(ctx context.Context, query string) ([]string, error) {
 	cm.logger.Debug("fetching entity context", logger.Field{Key: "query", Value: query})
 
 	// 搜索实体记忆
 	results, err := cm.em.Search(ctx, query, cm.config.DefaultEntityLimit, cm.config.EntityScoreThreshold)
 	if err != nil {
-		return "", fmt.Errorf("entity memory search failed: %w", err)
-	}
-
-	if len(results) == 0 {
-		cm.logger.Debug("no entity results found")
-		return "", nil
+		return nil, fmt.Errorf("entity memory search failed: %w", err)
 	}
 
-	// 格式化结果，与crewAI格式保持一致
-	var formattedResults []string
+	entries := make([]string, 0, len(results))
 	for _, result := range results {
-		// 尝试从metadata中获取context字段
-		if contextStr, ok := result.Metadata["context"].(string); ok {
-			formattedResults = append(formattedResults, fmt.Sprintf("- %s", contextStr))
-		} else {
-			// 回退到使用Value字段
-			formattedResults = append(formattedResults, fmt.Sprintf("- %v", result.Value))
-		}
-	}
-
-	if len(formattedResults) == 0 {
-		return "", nil
-	}
-
-	// 添加章节标题
-	header := ""
-	if cm.config.EnableSectionHeaders {
-		header = "Entities:\n"
+		entries = append(entries, memoryItemText(result))
 	}
 
-	context := header + strings.Join(formattedResults, "\n")
-
-	cm.logger.Debug("entity context fetched",
-		logger.Field{Key: "results_count", Value: len(results)},
-		logger.Field{Key: "formatted_count", Value: len(formattedResults)},
-	)
+	cm.logger.Debug("entity context fetched", logger.Field{Key: "results_count", Value: len(results)})
 
-	return context, nil
+	return entries, nil
 }
 
 // fetchExternalContext 获取外部记忆上下文
 // 参考crewAI的_fetch_external_context实现
-func (cm *ContextualMemory) fetchExternalContext(ctx context.Context, query string) (string, error) {
-	if cm.exm == nil {
-		return "", nil
-	}
-
+func (cm *ContextualMemory) fetchExternalContext(ctx context.Context, query string) ([]string, error) {
 	cm.logger.Debug("fetching external context", logger.Field{Key: "query", Value: query})
 
 	// 搜索外部记忆
 	results, err := cm.exm.Search(ctx, query, cm.config.DefaultExternalLimit, cm.config.ExternalScoreThreshold)
 	if err != nil {
-		return "", fmt.Errorf("external memory search failed: %w", err)
+		return nil, fmt.Errorf("external memory search failed: %w", err)
 	}
 
-	if len(results) == 0 {
-		cm.logger.Debug("no external results found")
-		return "", nil
-	}
-
-	// 格式化结果，与crewAI格式保持一致
-	var formattedResults []string
+	entries := make([]string, 0, len(results))
 	for _, result := range results {
-		// 尝试从metadata中获取context字段
-		if contextStr, ok := result.Metadata["context"].(string); ok {
-			formattedResults = append(formattedResults, fmt.Sprintf("- %s", contextStr))
-		} else {
-			// 回退到使用Value字段
-			formattedResults = append(formattedResults, fmt.Sprintf("- %v", result.Value))
-		}
+		entries = append(entries, memoryItemText(result))
 	}
 
-	if len(formattedResults) == 0 {
-		return "", nil
-	}
+	cm.logger.Debug("external context fetched", logger.Field{Key: "results_count", Value: len(results)})
 
-	// 添加章节标题
-	header := ""
-	if cm.config.EnableSectionHeaders {
-		header = "External memories:\n"
-	}
-
-	context := header + strings.Join(formattedResults, "\n")
-
-	cm.logger.Debug("external context fetched",
-		logger.Field{Key: "results_count", Value: len(results)},
-		logger.Field{Key: "formatted_count", Value: len(formattedResults)},
-	)
+	return entries, nil
+}
 
-	return context, nil
+// memoryItemText 返回记忆项的文本，优先使用metadata中的context字段（与crewAI保持一致）
+func memoryItemText(item memory.MemoryItem) string {
+	if contextStr, ok := item.Metadata["context"].(string); ok {
+		return contextStr
+	}
+	return fmt.Sprintf("%v", item.Value)
 }
 
 // 辅助方法
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
@@ -98,6 +99,15 @@ func (m *MockTask) SetAssignedAgent(agent agent.Agent) error {
 	return args.Error(0)
 }
 
+func (m *MockTask) GetAgentRole() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+func (m *MockTask) SetAgentRole(role string) {
+	m.Called(role)
+}
+
 func (m *MockTask) IsAsyncExecution() bool {
 	args := m.Called()
 	return args.Bool(0)
@@ -130,6 +140,15 @@ func (m *MockTask) SetOutputFile(filename string) error {
 	return args.Error(0)
 }
 
+func (m *MockTask) IsOutputFileAppend() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *MockTask) SetOutputFileAppend(appendOutput bool) {
+	m.Called(appendOutput)
+}
+
 func (m *MockTask) GetCreateDirectory() bool {
 	args := m.Called()
 	return args.Bool(0)
@@ -157,6 +176,33 @@ func (m *MockTask) SetContextTasks(tasks []agent.Task) {
 	m.Called(tasks)
 }
 
+func (m *MockTask) GetOutputSchema() *agent.OutputSchema {
+	args := m.Called()
+	return args.Get(0).(*agent.OutputSchema)
+}
+
+func (m *MockTask) SetOutputSchema(schema *agent.OutputSchema) {
+	m.Called(schema)
+}
+
+func (m *MockTask) GetDependsOn() []string {
+	args := m.Called()
+	return args.Get(0).([]string)
+}
+
+func (m *MockTask) SetDependsOn(taskIDs []string) {
+	m.Called(taskIDs)
+}
+
+func (m *MockTask) IsCacheDisabled() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *MockTask) SetCacheDisabled(disabled bool) {
+	m.Called(disabled)
+}
+
 func (m *MockTask) GetRetryCount() int {
 	args := m.Called()
 	return args.Int(0)
@@ -193,6 +239,26 @@ func (m *MockTask) SetMarkdownOutput(markdown bool) {
 	m.Called(markdown)
 }
 
+func (m *MockTask) IsApprovalRequired() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *MockTask) SetApprovalRequired(required bool) {
+	m.Called(required)
+}
+
+func (m *MockTask) AddImage(source string) error {
+	args := m.Called(source)
+	return args.Error(0)
+}
+
+func (m *MockTask) GetImages() []llm.ContentPart {
+	args := m.Called()
+	images, _ := args.Get(0).([]llm.ContentPart)
+	return images
+}
+
 func (m *MockTask) SetMaxRetries(maxRetries int) {
 	m.Called(maxRetries)
 }
@@ -214,11 +280,27 @@ func (m *MockEventBus) Subscribe(eventType string, handler events.EventHandler)
 	return args.Error(0)
 }
 
+func (m *MockEventBus) SubscribeWithOptions(pattern string, handler events.EventHandler, opts ...events.SubscribeOption) (*events.Subscription, error) {
+	args := m.Called(pattern, handler)
+	subscription, _ := args.Get(0).(*events.Subscription)
+	return subscription, args.Error(1)
+}
+
 func (m *MockEventBus) Unsubscribe(eventType string, handler events.EventHandler) error {
 	args := m.Called(eventType, handler)
 	return args.Error(0)
 }
 
+func (m *MockEventBus) AttachSink(sink events.Sink) error {
+	args := m.Called(sink)
+	return args.Error(0)
+}
+
+func (m *MockEventBus) FlushSinks() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func (m *MockEventBus) GetHandlerCount(eventType string) int {
 	args := m.Called(eventType)
 	return args.Int(0)
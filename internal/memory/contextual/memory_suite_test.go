@@ -0,0 +1,151 @@
+package contextual
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/agent/agenttest"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/internal/memory/entity"
+	"github.com/ynl/greensoulai/internal/memory/long_term"
+	"github.com/ynl/greensoulai/internal/memory/short_term"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// stubStorage 返回固定结果的存储，delay不为0时模拟慢速来源
+type stubStorage struct {
+	items []memory.MemoryItem
+	delay time.Duration
+}
+
+func (s *stubStorage) Save(ctx context.Context, item memory.MemoryItem) error { return nil }
+
+func (s *stubStorage) Search(ctx context.Context, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if limit > 0 && len(s.items) > limit {
+		return s.items[:limit], nil
+	}
+	return s.items, nil
+}
+
+func (s *stubStorage) Delete(ctx context.Context, id string) error { return nil }
+func (s *stubStorage) Clear(ctx context.Context) error             { return nil }
+func (s *stubStorage) Close() error                                { return nil }
+
+func stubItems(values ...string) []memory.MemoryItem {
+	items := make([]memory.MemoryItem, len(values))
+	for i, value := range values {
+		items[i] = memory.MemoryItem{ID: value, Value: value, Score: 1}
+	}
+	return items
+}
+
+// newTestMemorySuite 创建包含短期、长期和实体记忆的上下文记忆
+func newTestMemorySuite(t *testing.T, stmStorage, emStorage memory.MemoryStorage, config *ContextualMemoryConfig) *ContextualMemory {
+	t.Helper()
+	testLogger := logger.NewTestLogger()
+	eventBus := events.NewEventBus(testLogger)
+
+	ltm := long_term.NewLongTermMemory(nil, filepath.Join(t.TempDir(), "ltm.db"), eventBus, testLogger)
+	t.Cleanup(func() { ltm.Close() })
+	require.NoError(t, ltm.SaveCompatible(context.Background(), "quarterly report review", map[string]interface{}{
+		"suggestions": []string{"cite revenue sources"},
+	}, "analyst"))
+
+	stm := short_term.NewShortTermMemory(nil, nil, stmStorage, "", eventBus, testLogger)
+	em := entity.NewEntityMemory(nil, nil, emStorage, "", eventBus, testLogger)
+	return NewContextualMemory(stm, ltm, em, nil, nil, testLogger, config)
+}
+
+func TestBuildContextForTask_SectionOrder(t *testing.T) {
+	cm := newTestMemorySuite(t,
+		&stubStorage{items: stubItems("revenue grew in Q3", "shared fact")},
+		&stubStorage{items: stubItems("ACME Corp", "shared fact")},
+		nil,
+	)
+
+	task := agent.NewBaseTask("quarterly report", "a report")
+	result, err := cm.BuildContextForTask(context.Background(), task, "")
+	require.NoError(t, err)
+
+	expected := strings.Join([]string{
+		"Recent Insights:",
+		"- revenue grew in Q3",
+		"- shared fact",
+		"Entities:",
+		"- ACME Corp",
+		"Historical Data:",
+		"- cite revenue sources",
+	}, "\n")
+	assert.Equal(t, expected, result)
+
+	// 多次构建结果一致
+	for i := 0; i < 5; i++ {
+		again, err := cm.BuildContextForTask(context.Background(), task, "")
+		require.NoError(t, err)
+		assert.Equal(t, result, again)
+	}
+}
+
+func TestBuildContextForTask_SlowSourceSkipped(t *testing.T) {
+	config := DefaultContextualMemoryConfig()
+	config.SourceTimeout = 100 * time.Millisecond
+	cm := newTestMemorySuite(t,
+		&stubStorage{items: stubItems("revenue grew in Q3"), delay: 5 * time.Second},
+		&stubStorage{items: stubItems("ACME Corp")},
+		&config,
+	)
+
+	start := time.Now()
+	result, err := cm.BuildContextForTask(context.Background(), agent.NewBaseTask("quarterly report", "a report"), "")
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), time.Second, "slow source should not block beyond its timeout")
+	assert.NotContains(t, result, "Recent Insights:")
+	assert.Contains(t, result, "- ACME Corp")
+	assert.Contains(t, result, "- cite revenue sources")
+}
+
+func TestBuildContextForTask_TokenBudget(t *testing.T) {
+	config := DefaultContextualMemoryConfig()
+	config.MaxContextTokens = 10 // 约40个字符
+	cm := newTestMemorySuite(t,
+		&stubStorage{items: stubItems("first insight", "second insight that does not fit")},
+		&stubStorage{items: stubItems("ACME Corp")},
+		&config,
+	)
+
+	result, err := cm.BuildContextForTask(context.Background(), agent.NewBaseTask("quarterly report", "a report"), "")
+	require.NoError(t, err)
+
+	// 以完整条目截断，预算用尽后不再输出后续章节
+	assert.Equal(t, "Recent Insights:\n- first insight", result)
+	assert.LessOrEqual(t, len(result), config.MaxContextTokens*charsPerToken)
+}
+
+func TestContextualMemoryUsedByBaseAgent(t *testing.T) {
+	cm := newTestMemorySuite(t,
+		&stubStorage{items: stubItems("revenue grew in Q3")},
+		&stubStorage{items: stubItems("ACME Corp")},
+		nil,
+	)
+
+	prompt := agenttest.FirstPrompt(t, agent.AgentConfig{Role: "analyst", MemorySuite: cm},
+		agent.NewBaseTask("quarterly report", "a report"))
+	assert.Contains(t, prompt, "Relevant Memory:")
+	assert.Contains(t, prompt, "Recent Insights:\n- revenue grew in Q3\nEntities:\n- ACME Corp")
+}
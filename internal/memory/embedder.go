@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// Embedder 文本嵌入接口，把文本转换为向量用于语义检索
+type Embedder interface {
+	// Embed 为每段文本返回一个向量，返回顺序与输入一致
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// 支持的嵌入提供商
+const (
+	EmbedderProviderOpenAI = "openai"
+	EmbedderProviderHash   = "hash" // 确定性的本地嵌入，用于测试和离线开发
+)
+
+// defaultHashEmbedderDimensions 哈希嵌入器的默认向量维度
+const defaultHashEmbedderDimensions = 256
+
+// NewEmbedder 根据嵌入器配置创建Embedder
+// 配置中直接提供Embedder时优先使用；config为nil时返回nil，调用方应回退到关键词检索。
+// provider为空或"default"时，有OpenAI API密钥则使用OpenAI嵌入，否则使用本地哈希嵌入。
+// 配置了Cache时返回的嵌入器会经过缓存
+func NewEmbedder(config *EmbedderConfig) (Embedder, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	embedder, err := newConfiguredEmbedder(config)
+	if err != nil || config.Cache == nil {
+		return embedder, err
+	}
+	if _, cached := embedder.(*CachedEmbedder); cached {
+		return embedder, nil
+	}
+	return NewCachedEmbedder(embedder, config.Cache), nil
+}
+
+// newConfiguredEmbedder 根据provider创建未经缓存的嵌入器
+func newConfiguredEmbedder(config *EmbedderConfig) (Embedder, error) {
+	if config.Embedder != nil {
+		return config.Embedder, nil
+	}
+
+	switch strings.ToLower(config.Provider) {
+	case "", "default":
+		if openAIAPIKey(config.Config) != "" {
+			return newOpenAIEmbedder(config.Config)
+		}
+		return newHashEmbedder(config.Config), nil
+	case EmbedderProviderOpenAI:
+		return newOpenAIEmbedder(config.Config)
+	case EmbedderProviderHash:
+		return newHashEmbedder(config.Config), nil
+	default:
+		return nil, fmt.Errorf("unsupported embedder provider: %s", config.Provider)
+	}
+}
+
+// openAIAPIKey 从配置或OPENAI_API_KEY环境变量读取API密钥
+func openAIAPIKey(config map[string]interface{}) string {
+	if apiKey := configString(config, "api_key"); apiKey != "" {
+		return apiKey
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// newOpenAIEmbedder 根据配置创建OpenAI嵌入器
+func newOpenAIEmbedder(config map[string]interface{}) (Embedder, error) {
+	apiKey := openAIAPIKey(config)
+	if apiKey == "" {
+		return nil, fmt.Errorf("openai embedder requires an api_key or OPENAI_API_KEY")
+	}
+
+	options := []llm.BaseLLMOption{llm.WithAPIKey(apiKey)}
+	if baseURL := configString(config, "base_url"); baseURL != "" {
+		options = append(options, llm.WithBaseURL(baseURL))
+	}
+	return llm.NewOpenAIEmbedder(configString(config, "model"), options...), nil
+}
+
+// newHashEmbedder 根据配置创建哈希嵌入器
+func newHashEmbedder(config map[string]interface{}) Embedder {
+	dimensions := defaultHashEmbedderDimensions
+	if d, ok := config["dimensions"].(int); ok && d > 0 {
+		dimensions = d
+	}
+	return NewHashEmbedder(dimensions)
+}
+
+// configString 从配置中读取字符串值
+func configString(config map[string]interface{}, key string) string {
+	if value, ok := config[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// HashEmbedder 基于词哈希的确定性嵌入器
+// 不需要API密钥，相同的文本总是得到相同的向量，共享词越多的文本相似度越高
+type HashEmbedder struct {
+	dimensions int
+}
+
+// NewHashEmbedder 创建哈希嵌入器
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultHashEmbedderDimensions
+	}
+	return &HashEmbedder{dimensions: dimensions}
+}
+
+// GetProvider 实现EmbedderModel接口
+func (e *HashEmbedder) GetProvider() string {
+	return EmbedderProviderHash
+}
+
+// GetModel 实现EmbedderModel接口，不同维度视为不同模型
+func (e *HashEmbedder) GetModel() string {
+	return fmt.Sprintf("hash-%d", e.dimensions)
+}
+
+// Embed 实现Embedder接口
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, e.dimensions)
+		for _, token := range tokenize(text) {
+			h := fnv.New32a()
+			h.Write([]byte(token))
+			vector[h.Sum32()%uint32(e.dimensions)]++
+		}
+		vectors[i] = normalize(vector)
+	}
+	return vectors, nil
+}
+
+// tokenize 把文本拆分为小写词
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// normalize 把向量缩放为单位长度
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// CosineSimilarity 计算两个向量的余弦相似度，维度不同或存在零向量时返回0
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
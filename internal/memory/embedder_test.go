@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+func TestHashEmbedderDeterministic(t *testing.T) {
+	embedder := NewHashEmbedder(64)
+	ctx := context.Background()
+
+	first, err := embedder.Embed(ctx, []string{"Go channels and goroutines", "Baking sourdough bread"})
+	require.NoError(t, err)
+	second, err := embedder.Embed(ctx, []string{"Go channels and goroutines"})
+	require.NoError(t, err)
+
+	require.Len(t, first, 2)
+	assert.Len(t, first[0], 64)
+	assert.Equal(t, first[0], second[0])
+
+	query, err := embedder.Embed(ctx, []string{"goroutines in Go"})
+	require.NoError(t, err)
+	related := CosineSimilarity(query[0], first[0])
+	unrelated := CosineSimilarity(query[0], first[1])
+	assert.Greater(t, related, unrelated)
+	assert.InDelta(t, 1.0, CosineSimilarity(first[0], second[0]), 1e-6)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.InDelta(t, -1.0, CosineSimilarity([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+	assert.Equal(t, 0.0, CosineSimilarity([]float32{1}, []float32{1, 0}))
+	assert.Equal(t, 0.0, CosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+	assert.Equal(t, 0.0, CosineSimilarity(nil, nil))
+}
+
+func TestNewEmbedder(t *testing.T) {
+	embedder, err := NewEmbedder(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, embedder)
+
+	// 没有API密钥时默认使用本地哈希嵌入
+	t.Setenv("OPENAI_API_KEY", "")
+	embedder, err = NewEmbedder(&EmbedderConfig{Provider: "default"})
+	assert.NoError(t, err)
+	assert.IsType(t, &HashEmbedder{}, embedder)
+
+	// 有API密钥时默认使用OpenAI嵌入
+	embedder, err = NewEmbedder(&EmbedderConfig{Config: map[string]interface{}{"api_key": "test-key"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &llm.OpenAIEmbedder{}, embedder)
+
+	embedder, err = NewEmbedder(&EmbedderConfig{Provider: "hash", Config: map[string]interface{}{"dimensions": 32}})
+	require.NoError(t, err)
+	vectors, err := embedder.Embed(context.Background(), []string{"text"})
+	require.NoError(t, err)
+	assert.Len(t, vectors[0], 32)
+
+	custom := NewHashEmbedder(8)
+	embedder, err = NewEmbedder(&EmbedderConfig{Provider: "openai", Embedder: custom})
+	require.NoError(t, err)
+	assert.Same(t, custom, embedder)
+
+	embedder, err = NewEmbedder(&EmbedderConfig{Provider: "openai", Config: map[string]interface{}{
+		"api_key": "test-key", "model": "text-embedding-3-large", "base_url": "http://localhost:9999/v1",
+	}})
+	require.NoError(t, err)
+	openAI, ok := embedder.(*llm.OpenAIEmbedder)
+	require.True(t, ok)
+	assert.Equal(t, "text-embedding-3-large", openAI.GetModel())
+	assert.Equal(t, "http://localhost:9999/v1", openAI.GetBaseURL())
+
+	_, err = NewEmbedder(&EmbedderConfig{Provider: "openai"})
+	assert.Error(t, err)
+
+	_, err = NewEmbedder(&EmbedderConfig{Provider: "unknown"})
+	assert.Error(t, err)
+}
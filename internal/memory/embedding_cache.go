@@ -0,0 +1,304 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// defaultEmbeddingCacheEntries 内存LRU默认保留的向量数量
+const defaultEmbeddingCacheEntries = 10000
+
+// EmbeddingCacheKey 嵌入缓存键，同一内容在不同提供商或模型下的向量互不复用
+type EmbeddingCacheKey struct {
+	Provider    string
+	Model       string
+	ContentHash string // 内容的SHA-256十六进制摘要
+}
+
+// NewEmbeddingCacheKey 根据提供商、模型和原始内容创建缓存键
+func NewEmbeddingCacheKey(provider, model, content string) EmbeddingCacheKey {
+	sum := sha256.Sum256([]byte(content))
+	return EmbeddingCacheKey{
+		Provider:    provider,
+		Model:       model,
+		ContentHash: hex.EncodeToString(sum[:]),
+	}
+}
+
+// EmbeddingCacheStore 嵌入缓存的持久化后端，内存LRU未命中时查询
+type EmbeddingCacheStore interface {
+	// Get 读取向量，不存在时返回false
+	Get(ctx context.Context, key EmbeddingCacheKey) ([]float32, bool, error)
+
+	// Put 写入向量，已存在时覆盖
+	Put(ctx context.Context, key EmbeddingCacheKey, vector []float32) error
+
+	// Close 关闭后端
+	Close() error
+}
+
+// EmbeddingCacheConfig 嵌入缓存配置
+type EmbeddingCacheConfig struct {
+	MaxEntries int                 `json:"max_entries"` // 内存LRU容量
+	Store      EmbeddingCacheStore `json:"-"`           // 可选的持久化后端，如SQLite
+}
+
+// DefaultEmbeddingCacheConfig 返回默认的嵌入缓存配置（仅内存）
+func DefaultEmbeddingCacheConfig() *EmbeddingCacheConfig {
+	return &EmbeddingCacheConfig{
+		MaxEntries: defaultEmbeddingCacheEntries,
+	}
+}
+
+// EmbeddingCacheStats 嵌入缓存统计
+type EmbeddingCacheStats struct {
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	Evictions   int64   `json:"evictions"`
+	StoreErrors int64   `json:"store_errors"`
+	Entries     int     `json:"entries"`
+	HitRate     float64 `json:"hit_rate"`
+}
+
+// embeddingCacheEntry LRU链表中的一项
+type embeddingCacheEntry struct {
+	key    EmbeddingCacheKey
+	vector []float32
+}
+
+// EmbeddingCache 按(提供商, 模型, 内容哈希)缓存嵌入向量
+// 内存中是LRU，配置Store后未命中的向量会再从持久化后端查找，写入时同时落盘。
+// 同一个缓存可以在多个知识源和记忆组件之间共享，模型是缓存键的一部分，
+// 同一提供商的不同模型可以同时使用，向量互不影响。
+type EmbeddingCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	store      EmbeddingCacheStore
+	entries    map[EmbeddingCacheKey]*list.Element
+	lru        *list.List
+
+	hits        int64
+	misses      int64
+	evictions   int64
+	storeErrors int64
+}
+
+// NewEmbeddingCache 创建嵌入缓存，config为nil时使用默认配置
+func NewEmbeddingCache(config *EmbeddingCacheConfig) *EmbeddingCache {
+	if config == nil {
+		config = DefaultEmbeddingCacheConfig()
+	}
+	maxEntries := config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultEmbeddingCacheEntries
+	}
+
+	return &EmbeddingCache{
+		maxEntries: maxEntries,
+		store:      config.Store,
+		entries:    make(map[EmbeddingCacheKey]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get 读取缓存的向量并更新命中统计
+func (c *EmbeddingCache) Get(ctx context.Context, key EmbeddingCacheKey) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		c.hits++
+		return copyVector(element.Value.(*embeddingCacheEntry).vector), true
+	}
+
+	if c.store != nil {
+		vector, ok, err := c.store.Get(ctx, key)
+		if err != nil {
+			c.storeErrors++
+		} else if ok {
+			c.add(key, vector)
+			c.hits++
+			return copyVector(vector), true
+		}
+	}
+
+	c.misses++
+	return nil, false
+}
+
+// Put 写入向量，配置了持久化后端时同时落盘
+func (c *EmbeddingCache) Put(ctx context.Context, key EmbeddingCacheKey, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	vector = copyVector(vector)
+	c.add(key, vector)
+
+	if c.store != nil {
+		if err := c.store.Put(ctx, key, vector); err != nil {
+			c.storeErrors++
+		}
+	}
+}
+
+// Invalidate 删除提供商的所有内存向量，持久化后端中的向量不受影响
+func (c *EmbeddingCache) Invalidate(provider string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeProvider(provider)
+}
+
+// Stats 返回缓存统计
+func (c *EmbeddingCache) Stats() EmbeddingCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := EmbeddingCacheStats{
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+		StoreErrors: c.storeErrors,
+		Entries:     c.lru.Len(),
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// Close 关闭持久化后端
+func (c *EmbeddingCache) Close() error {
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Close()
+}
+
+// removeProvider 删除提供商的所有内存向量
+func (c *EmbeddingCache) removeProvider(provider string) {
+	for key, element := range c.entries {
+		if key.Provider == provider {
+			c.lru.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// add 把向量加入LRU，超出容量时淘汰最久未使用的项
+func (c *EmbeddingCache) add(key EmbeddingCacheKey, vector []float32) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*embeddingCacheEntry).vector = vector
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&embeddingCacheEntry{key: key, vector: vector})
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingCacheEntry).key)
+		c.evictions++
+	}
+}
+
+// copyVector 复制向量，避免调用方修改缓存中的数据
+func copyVector(vector []float32) []float32 {
+	if vector == nil {
+		return nil
+	}
+	return append([]float32(nil), vector...)
+}
+
+// EmbedderModel 能报告提供商和模型名的嵌入器，缓存用它区分不同模型的向量
+type EmbedderModel interface {
+	GetProvider() string
+	GetModel() string
+}
+
+// CachedEmbedder 带缓存的嵌入器装饰器
+// 批量调用时只把未命中的文本发给底层嵌入器，返回顺序与输入一致
+type CachedEmbedder struct {
+	inner Embedder
+	cache *EmbeddingCache
+}
+
+// NewCachedEmbedder 用缓存包装嵌入器，cache为nil时创建一个仅内存的默认缓存
+func NewCachedEmbedder(inner Embedder, cache *EmbeddingCache) *CachedEmbedder {
+	if cache == nil {
+		cache = NewEmbeddingCache(nil)
+	}
+	return &CachedEmbedder{inner: inner, cache: cache}
+}
+
+// Cache 返回使用的缓存
+func (e *CachedEmbedder) Cache() *EmbeddingCache {
+	return e.cache
+}
+
+// Unwrap 返回被包装的嵌入器
+func (e *CachedEmbedder) Unwrap() Embedder {
+	return e.inner
+}
+
+// Embed 实现Embedder接口
+func (e *CachedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	provider, model := embedderIdentity(e.inner)
+
+	vectors := make([][]float32, len(texts))
+	keys := make([]EmbeddingCacheKey, len(texts))
+	// 未命中的文本去重后按首次出现的顺序发送，positions记录每段文本在输入中的所有位置
+	var missing []string
+	positions := make(map[EmbeddingCacheKey][]int)
+	for i, text := range texts {
+		keys[i] = NewEmbeddingCacheKey(provider, model, text)
+		if _, pending := positions[keys[i]]; pending {
+			positions[keys[i]] = append(positions[keys[i]], i)
+			continue
+		}
+		if vector, ok := e.cache.Get(ctx, keys[i]); ok {
+			vectors[i] = vector
+			continue
+		}
+		positions[keys[i]] = []int{i}
+		missing = append(missing, text)
+	}
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := e.inner.Embed(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(embedded), len(missing))
+	}
+
+	for j, text := range missing {
+		key := NewEmbeddingCacheKey(provider, model, text)
+		e.cache.Put(ctx, key, embedded[j])
+		for n, i := range positions[key] {
+			if n == 0 {
+				vectors[i] = embedded[j]
+			} else {
+				vectors[i] = copyVector(embedded[j])
+			}
+		}
+	}
+	return vectors, nil
+}
+
+// embedderIdentity 返回嵌入器的提供商和模型名，未实现EmbedderModel时用类型名作为提供商
+func embedderIdentity(embedder Embedder) (string, string) {
+	if m, ok := embedder.(EmbedderModel); ok {
+		return m.GetProvider(), m.GetModel()
+	}
+	return fmt.Sprintf("%T", embedder), ""
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEmbedder 记录每次调用收到的文本
+type countingEmbedder struct {
+	model string
+	calls [][]string
+}
+
+func (e *countingEmbedder) GetProvider() string { return "counting" }
+func (e *countingEmbedder) GetModel() string    { return e.model }
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls = append(e.calls, append([]string(nil), texts...))
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), float32(len(e.calls))}
+	}
+	return vectors, nil
+}
+
+func TestCachedEmbedderOnlyEmbedsMisses(t *testing.T) {
+	inner := &countingEmbedder{model: "small"}
+	embedder := NewCachedEmbedder(inner, NewEmbeddingCache(nil))
+	ctx := context.Background()
+
+	first, err := embedder.Embed(ctx, []string{"a", "bb"})
+	require.NoError(t, err)
+
+	second, err := embedder.Embed(ctx, []string{"ccc", "a", "ccc", "bb"})
+	require.NoError(t, err)
+
+	require.Len(t, inner.calls, 2)
+	assert.Equal(t, []string{"ccc"}, inner.calls[1], "only the uncached text should reach the provider, once")
+	require.Len(t, second, 4)
+	assert.Equal(t, first[0], second[1])
+	assert.Equal(t, first[1], second[3])
+	assert.Equal(t, []float32{3, 2}, second[0])
+	assert.Equal(t, second[0], second[2])
+
+	stats := embedder.Cache().Stats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(3), stats.Misses)
+	assert.Equal(t, 3, stats.Entries)
+	assert.InDelta(t, 0.4, stats.HitRate, 1e-9)
+
+	// 所有文本都命中时不调用底层嵌入器
+	_, err = embedder.Embed(ctx, []string{"bb", "a"})
+	require.NoError(t, err)
+	assert.Len(t, inner.calls, 2)
+}
+
+func TestEmbeddingCacheSharedAcrossModels(t *testing.T) {
+	cache := NewEmbeddingCache(nil)
+	ctx := context.Background()
+
+	small := &countingEmbedder{model: "small"}
+	large := &countingEmbedder{model: "large"}
+	smallEmbedder := NewCachedEmbedder(small, cache)
+	largeEmbedder := NewCachedEmbedder(large, cache)
+
+	_, err := smallEmbedder.Embed(ctx, []string{"hello"})
+	require.NoError(t, err)
+	_, err = largeEmbedder.Embed(ctx, []string{"hello"})
+	require.NoError(t, err)
+	assert.Len(t, large.calls, 1, "vectors from another model must not be reused")
+	assert.Equal(t, 2, cache.Stats().Entries, "both models should keep their vectors")
+
+	// 两个嵌入器交替使用，各自的向量都命中缓存
+	for i := 0; i < 2; i++ {
+		_, err = smallEmbedder.Embed(ctx, []string{"hello"})
+		require.NoError(t, err)
+		_, err = largeEmbedder.Embed(ctx, []string{"hello"})
+		require.NoError(t, err)
+	}
+	assert.Len(t, small.calls, 1)
+	assert.Len(t, large.calls, 1)
+	assert.Equal(t, int64(4), cache.Stats().Hits)
+
+	cache.Invalidate("counting")
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestEmbeddingCacheLRUEviction(t *testing.T) {
+	cache := NewEmbeddingCache(&EmbeddingCacheConfig{MaxEntries: 2})
+	ctx := context.Background()
+
+	keys := make([]EmbeddingCacheKey, 3)
+	for i := range keys {
+		keys[i] = NewEmbeddingCacheKey("p", "m", fmt.Sprintf("text-%d", i))
+	}
+
+	cache.Put(ctx, keys[0], []float32{0})
+	cache.Put(ctx, keys[1], []float32{1})
+	_, ok := cache.Get(ctx, keys[0])
+	require.True(t, ok)
+	cache.Put(ctx, keys[2], []float32{2})
+
+	_, ok = cache.Get(ctx, keys[1])
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, ok = cache.Get(ctx, keys[0])
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), cache.Stats().Evictions)
+}
+
+func TestNewEmbedderWrapsWithCache(t *testing.T) {
+	cache := NewEmbeddingCache(nil)
+	embedder, err := NewEmbedder(&EmbedderConfig{Provider: EmbedderProviderHash, Cache: cache})
+	require.NoError(t, err)
+
+	cached, ok := embedder.(*CachedEmbedder)
+	require.True(t, ok)
+	assert.Same(t, cache, cached.Cache())
+
+	_, err = embedder.Embed(context.Background(), []string{"shared"})
+	require.NoError(t, err)
+	key := NewEmbeddingCacheKey(EmbedderProviderHash, "hash-256", "shared")
+	_, ok = cache.Get(context.Background(), key)
+	assert.True(t, ok)
+}
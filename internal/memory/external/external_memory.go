@@ -68,8 +68,14 @@ const (
 	SourceTypeWebservice ExternalSourceType = "webservice"
 	SourceTypeCloud      ExternalSourceType = "cloud"
 	SourceTypeKnowledge  ExternalSourceType = "knowledge"
+	SourceTypeMem0       ExternalSourceType = "mem0"
 )
 
+// syncTargetSetter 需要知道Sync目标记忆的外部源，由AddSource自动设置
+type syncTargetSetter interface {
+	setSyncTarget(target *ExternalMemory)
+}
+
 // NewExternalMemory 创建外部记忆实例
 func NewExternalMemory(crew interface{}, embedderConfig *memory.EmbedderConfig, memStorage memory.MemoryStorage, path string, eventBus events.EventBus, logger logger.Logger) *ExternalMemory {
 	var storageInstance memory.MemoryStorage
@@ -83,12 +89,19 @@ func NewExternalMemory(crew interface{}, embedderConfig *memory.EmbedderConfig,
 
 	baseMemory := memory.NewBaseMemory(storageInstance, eventBus, logger)
 
-	return &ExternalMemory{
+	em := &ExternalMemory{
 		BaseMemory:   baseMemory,
 		sources:      make([]ExternalSource, 0),
 		syncInterval: 1 * time.Hour, // 默认每小时同步一次
 		autoSync:     false,
 	}
+
+	// mem0 provider自动注册mem0外部源，调用Connect后可用
+	if embedderConfig != nil && embedderConfig.Provider == Mem0Provider {
+		em.AddSource(NewMem0Source(Mem0Provider, embedderConfig, logger))
+	}
+
+	return em
 }
 
 // Save 保存外部记忆项
@@ -113,10 +126,24 @@ func (em *ExternalMemory) AddSource(source ExternalSource) error {
 		}
 	}
 
+	if setter, ok := source.(syncTargetSetter); ok {
+		setter.setSyncTarget(em)
+	}
+
 	em.sources = append(em.sources, source)
 	return nil
 }
 
+// findSource 按名称查找外部源
+func (em *ExternalMemory) findSource(sourceName string) ExternalSource {
+	for _, source := range em.sources {
+		if source.GetName() == sourceName {
+			return source
+		}
+	}
+	return nil
+}
+
 // RemoveSource 移除外部源
 func (em *ExternalMemory) RemoveSource(sourceName string) error {
 	for i, source := range em.sources {
@@ -190,7 +217,19 @@ func (em *ExternalMemory) FetchFromSource(ctx context.Context, sourceName, query
 }
 
 // SearchBySource 根据源搜索记忆
+// 源已连接时直接返回源的实时结果；源不可用或实时查询失败时在本地已同步的记忆中搜索
 func (em *ExternalMemory) SearchBySource(ctx context.Context, sourceName, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	if source := em.findSource(sourceName); source != nil && source.IsAvailable() {
+		liveResults, err := em.searchLive(ctx, source, query, limit, scoreThreshold)
+		if err == nil {
+			return liveResults, nil
+		}
+		em.GetLogger().Warn("live search failed, falling back to synced memories",
+			logger.Field{Key: "source", Value: sourceName},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+
 	// 执行基础搜索
 	results, err := em.Search(ctx, query, limit*2, scoreThreshold)
 	if err != nil {
@@ -223,6 +262,23 @@ func (em *ExternalMemory) SearchBySource(ctx context.Context, sourceName, query
 	return filteredResults, nil
 }
 
+// searchLive 从外部源实时获取记忆，有查询时过滤掉低于scoreThreshold的结果
+func (em *ExternalMemory) searchLive(ctx context.Context, source ExternalSource, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	items, err := source.Fetch(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]memory.MemoryItem, 0, len(items))
+	for _, item := range items {
+		if query != "" && item.Score < scoreThreshold {
+			continue
+		}
+		results = append(results, item.MemoryItem)
+	}
+	return results, nil
+}
+
 // SearchBySourceType 根据源类型搜索记忆
 func (em *ExternalMemory) SearchBySourceType(ctx context.Context, sourceType ExternalSourceType, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
 	// 执行基础搜索
@@ -0,0 +1,475 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// Mem0Provider mem0托管记忆服务的provider名称
+const Mem0Provider = "mem0"
+
+// DefaultMem0Endpoint mem0 API的默认地址
+const DefaultMem0Endpoint = "https://api.mem0.ai"
+
+const (
+	defaultMem0Timeout      = 30 * time.Second
+	defaultMem0MaxRetries   = 3
+	defaultMem0PageSize     = 100
+	defaultMem0RetryBackoff = 500 * time.Millisecond
+)
+
+var (
+	// ErrSourceRateLimited 外部源持续返回速率限制（HTTP 429）时返回，可用errors.Is判断
+	ErrSourceRateLimited = errors.New("external source rate limited")
+
+	// ErrSourceUnauthorized 外部源拒绝凭证（HTTP 401/403）时返回
+	ErrSourceUnauthorized = errors.New("external source rejected credentials")
+)
+
+// Mem0Source 基于mem0托管服务的外部源
+// API密钥等配置来自EmbedderConfig.Config：api_key（或MEM0_API_KEY环境变量）、endpoint、
+// user_id、agent_id、org_id、project_id、timeout（秒）和max_retries
+type Mem0Source struct {
+	name         string
+	endpoint     string
+	apiKey       string
+	userID       string
+	agentID      string
+	orgID        string
+	projectID    string
+	pageSize     int
+	maxRetries   int
+	retryBackoff time.Duration
+	client       *http.Client
+	logger       logger.Logger
+
+	mu        sync.RWMutex
+	connected bool
+	target    *ExternalMemory   // Sync的目标记忆，由ExternalMemory.AddSource设置
+	synced    map[string]string // 已同步的mem0记忆ID -> updated_at
+}
+
+// mem0Memory mem0 API返回的单条记忆
+type mem0Memory struct {
+	ID        string                 `json:"id"`
+	Memory    string                 `json:"memory"`
+	Score     float64                `json:"score"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	UserID    string                 `json:"user_id"`
+	AgentID   string                 `json:"agent_id"`
+	CreatedAt string                 `json:"created_at"`
+	UpdatedAt string                 `json:"updated_at"`
+}
+
+// mem0Page mem0分页列表响应
+type mem0Page struct {
+	Count   int          `json:"count"`
+	Next    *string      `json:"next"`
+	Results []mem0Memory `json:"results"`
+}
+
+// NewMem0Source 根据嵌入器配置创建mem0外部源
+func NewMem0Source(name string, config *memory.EmbedderConfig, log logger.Logger) *Mem0Source {
+	var settings map[string]interface{}
+	if config != nil {
+		settings = config.Config
+	}
+
+	apiKey := configString(settings, "api_key")
+	if apiKey == "" {
+		apiKey = os.Getenv("MEM0_API_KEY")
+	}
+	endpoint := configString(settings, "endpoint")
+	if endpoint == "" {
+		endpoint = DefaultMem0Endpoint
+	}
+	// 兼容以/v1结尾的地址，请求路径中会带上版本
+	endpoint = strings.TrimSuffix(strings.TrimSuffix(endpoint, "/"), "/v1")
+
+	timeout := defaultMem0Timeout
+	if seconds, ok := configInt(settings, "timeout"); ok && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	maxRetries := defaultMem0MaxRetries
+	if retries, ok := configInt(settings, "max_retries"); ok && retries >= 0 {
+		maxRetries = retries
+	}
+
+	return &Mem0Source{
+		name:         name,
+		endpoint:     endpoint,
+		apiKey:       apiKey,
+		userID:       configString(settings, "user_id"),
+		agentID:      configString(settings, "agent_id"),
+		orgID:        configString(settings, "org_id"),
+		projectID:    configString(settings, "project_id"),
+		pageSize:     defaultMem0PageSize,
+		maxRetries:   maxRetries,
+		retryBackoff: defaultMem0RetryBackoff,
+		client:       llm.NewSharedHTTPClient(timeout),
+		logger:       log,
+		synced:       make(map[string]string),
+	}
+}
+
+// GetName 获取源名称
+func (s *Mem0Source) GetName() string {
+	return s.name
+}
+
+// GetType 获取源类型
+func (s *Mem0Source) GetType() string {
+	return string(SourceTypeMem0)
+}
+
+// Connect 校验API密钥，成功后源变为可用
+func (s *Mem0Source) Connect(ctx context.Context) error {
+	if s.apiKey == "" {
+		return fmt.Errorf("mem0 source %s requires an api_key or MEM0_API_KEY", s.name)
+	}
+
+	if _, err := s.doRequest(ctx, http.MethodGet, "/v1/ping/", nil, nil); err != nil {
+		return fmt.Errorf("failed to connect to mem0: %w", err)
+	}
+
+	s.mu.Lock()
+	s.connected = true
+	s.mu.Unlock()
+	return nil
+}
+
+// Fetch 从mem0获取记忆：query非空时调用搜索接口，否则分页列出记忆，最多返回limit条
+func (s *Mem0Source) Fetch(ctx context.Context, query string, limit int) ([]ExternalMemoryItem, error) {
+	if !s.IsAvailable() {
+		return nil, fmt.Errorf("mem0 source %s is not connected", s.name)
+	}
+
+	var memories []mem0Memory
+	var err error
+	if query == "" {
+		memories, err = s.listMemories(ctx, limit)
+	} else {
+		memories, err = s.searchMemories(ctx, query, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ExternalMemoryItem, 0, len(memories))
+	for _, m := range memories {
+		items = append(items, s.toItem(m))
+	}
+	return items, nil
+}
+
+// Sync 把mem0中新增或更新过的记忆保存到本地外部记忆
+func (s *Mem0Source) Sync(ctx context.Context) error {
+	if !s.IsAvailable() {
+		return fmt.Errorf("mem0 source %s is not connected", s.name)
+	}
+
+	s.mu.RLock()
+	target := s.target
+	s.mu.RUnlock()
+	if target == nil {
+		return fmt.Errorf("mem0 source %s has no sync target", s.name)
+	}
+
+	memories, err := s.listMemories(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range memories {
+		s.mu.RLock()
+		syncedVersion, seen := s.synced[m.ID]
+		s.mu.RUnlock()
+		if seen && syncedVersion == m.UpdatedAt {
+			continue
+		}
+
+		item := s.toItem(m)
+		if err := target.Save(ctx, item.Value, item.Metadata, item.Agent); err != nil {
+			return fmt.Errorf("failed to save mem0 memory %s: %w", m.ID, err)
+		}
+
+		s.mu.Lock()
+		s.synced[m.ID] = m.UpdatedAt
+		s.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Disconnect 断开连接
+func (s *Mem0Source) Disconnect() error {
+	s.mu.Lock()
+	s.connected = false
+	s.mu.Unlock()
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// IsAvailable 检查源是否已连接
+func (s *Mem0Source) IsAvailable() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.connected
+}
+
+// setSyncTarget 设置Sync的目标记忆
+func (s *Mem0Source) setSyncTarget(target *ExternalMemory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.target = target
+}
+
+// searchMemories 调用mem0搜索接口
+func (s *Mem0Source) searchMemories(ctx context.Context, query string, limit int) ([]mem0Memory, error) {
+	request := map[string]interface{}{"query": query}
+	if limit > 0 {
+		request["top_k"] = limit
+	}
+	if s.userID != "" {
+		request["user_id"] = s.userID
+	}
+	if s.agentID != "" {
+		request["agent_id"] = s.agentID
+	}
+
+	body, err := s.doRequest(ctx, http.MethodPost, "/v1/memories/search/", nil, request)
+	if err != nil {
+		return nil, err
+	}
+
+	memories, _, err := decodeMem0Memories(body)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(memories) > limit {
+		memories = memories[:limit]
+	}
+	return memories, nil
+}
+
+// listMemories 按页列出mem0记忆，limit<=0表示获取全部
+func (s *Mem0Source) listMemories(ctx context.Context, limit int) ([]mem0Memory, error) {
+	var memories []mem0Memory
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("page", strconv.Itoa(page))
+		params.Set("page_size", strconv.Itoa(s.pageSize))
+		if s.userID != "" {
+			params.Set("user_id", s.userID)
+		}
+		if s.agentID != "" {
+			params.Set("agent_id", s.agentID)
+		}
+
+		body, err := s.doRequest(ctx, http.MethodGet, "/v1/memories/", params, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		pageMemories, hasNext, err := decodeMem0Memories(body)
+		if err != nil {
+			return nil, err
+		}
+		memories = append(memories, pageMemories...)
+
+		if limit > 0 && len(memories) >= limit {
+			return memories[:limit], nil
+		}
+		if !hasNext || len(pageMemories) == 0 {
+			return memories, nil
+		}
+	}
+}
+
+// decodeMem0Memories 解析mem0响应，兼容数组和{"results": [...]}两种格式，并返回是否还有下一页
+func decodeMem0Memories(body []byte) ([]mem0Memory, bool, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var memories []mem0Memory
+		if err := json.Unmarshal(trimmed, &memories); err != nil {
+			return nil, false, fmt.Errorf("failed to decode mem0 response: %w", err)
+		}
+		return memories, false, nil
+	}
+
+	var page mem0Page
+	if err := json.Unmarshal(trimmed, &page); err != nil {
+		return nil, false, fmt.Errorf("failed to decode mem0 response: %w", err)
+	}
+	return page.Results, page.Next != nil && *page.Next != "", nil
+}
+
+// doRequest 发送mem0请求，对传输错误、429和5xx按max_retries重试
+func (s *Mem0Source) doRequest(ctx context.Context, method, path string, params url.Values, payload interface{}) ([]byte, error) {
+	var payloadBytes []byte
+	if payload != nil {
+		var err error
+		payloadBytes, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal mem0 request: %w", err)
+		}
+	}
+
+	if params == nil {
+		params = url.Values{}
+	}
+	if s.orgID != "" {
+		params.Set("org_id", s.orgID)
+	}
+	if s.projectID != "" {
+		params.Set("project_id", s.projectID)
+	}
+	requestURL := s.endpoint + path
+	if len(params) > 0 {
+		requestURL += "?" + params.Encode()
+	}
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(payloadBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mem0 request: %w", err)
+		}
+		request.Header.Set("Authorization", "Token "+s.apiKey)
+		request.Header.Set("Content-Type", "application/json")
+
+		delay := s.retryBackoff << attempt
+		response, err := s.client.Do(request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("mem0 request failed: %w", err)
+		} else {
+			body, readErr := io.ReadAll(response.Body)
+			response.Body.Close()
+			if readErr != nil {
+				return nil, fmt.Errorf("failed to read mem0 response: %w", readErr)
+			}
+
+			switch {
+			case response.StatusCode >= 200 && response.StatusCode < 300:
+				return body, nil
+			case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+				return nil, fmt.Errorf("%w: mem0 returned %d: %s", ErrSourceUnauthorized, response.StatusCode, strings.TrimSpace(string(body)))
+			case response.StatusCode == http.StatusTooManyRequests:
+				lastErr = fmt.Errorf("%w: mem0 returned 429: %s", ErrSourceRateLimited, strings.TrimSpace(string(body)))
+				if retryAfter, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && retryAfter >= 0 {
+					delay = time.Duration(retryAfter) * time.Second
+				}
+			case response.StatusCode >= 500:
+				lastErr = fmt.Errorf("mem0 server error %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+			default:
+				return nil, fmt.Errorf("mem0 request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+			}
+		}
+
+		if attempt >= s.maxRetries {
+			if attempt > 0 {
+				return nil, fmt.Errorf("mem0 request failed after %d retries: %w", attempt, lastErr)
+			}
+			return nil, lastErr
+		}
+
+		if s.logger != nil {
+			s.logger.Warn("retrying mem0 request",
+				logger.Field{Key: "source", Value: s.name},
+				logger.Field{Key: "attempt", Value: attempt + 1},
+				logger.Field{Key: "error", Value: lastErr},
+			)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// toItem 把mem0记忆转换为外部记忆项
+func (s *Mem0Source) toItem(m mem0Memory) ExternalMemoryItem {
+	metadata := make(map[string]interface{}, len(m.Metadata)+3)
+	for key, value := range m.Metadata {
+		metadata[key] = value
+	}
+	metadata["source_name"] = s.name
+	metadata["source_type"] = string(SourceTypeMem0)
+	metadata["source_id"] = m.ID
+
+	createdAt := parseMem0Time(m.CreatedAt)
+	lastModified := parseMem0Time(m.UpdatedAt)
+	if lastModified.IsZero() {
+		lastModified = createdAt
+	}
+
+	return ExternalMemoryItem{
+		MemoryItem: memory.MemoryItem{
+			ID:        m.ID,
+			Value:     m.Memory,
+			Metadata:  metadata,
+			Agent:     m.AgentID,
+			CreatedAt: createdAt,
+			Score:     m.Score,
+		},
+		SourceName:   s.name,
+		SourceType:   string(SourceTypeMem0),
+		SourceID:     m.ID,
+		SyncTime:     time.Now(),
+		LastModified: lastModified,
+		Version:      m.UpdatedAt,
+	}
+}
+
+// parseMem0Time 解析mem0返回的时间，无法解析时返回零值
+func parseMem0Time(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// configString 从配置中读取字符串值
+func configString(config map[string]interface{}, key string) string {
+	if value, ok := config[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// configInt 从配置中读取整数值，兼容JSON解码得到的float64
+func configInt(config map[string]interface{}, key string) (int, bool) {
+	switch value := config[key].(type) {
+	case int:
+		return value, true
+	case int64:
+		return int(value), true
+	case float64:
+		return int(value), true
+	}
+	return 0, false
+}
@@ -0,0 +1,228 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// recordingStorage 记录保存的记忆项的存储
+type recordingStorage struct {
+	mu    sync.Mutex
+	items []memory.MemoryItem
+}
+
+func (s *recordingStorage) Save(ctx context.Context, item memory.MemoryItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, item)
+	return nil
+}
+
+func (s *recordingStorage) Search(ctx context.Context, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	return nil, nil
+}
+
+func (s *recordingStorage) Delete(ctx context.Context, id string) error { return nil }
+func (s *recordingStorage) Clear(ctx context.Context) error             { return nil }
+func (s *recordingStorage) Close() error                                { return nil }
+
+// fakeMem0Server 模拟mem0 API：校验Token，提供ping、搜索和分页列表接口
+func fakeMem0Server(t *testing.T, memories []mem0Memory, pageSize int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"detail": "Invalid API key"}`)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/v1/ping/":
+			fmt.Fprint(w, `{"status": "ok"}`)
+		case r.URL.Path == "/v1/memories/search/" && r.Method == http.MethodPost:
+			var request map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			assert.Equal(t, "alice", request["user_id"])
+			json.NewEncoder(w).Encode(memories)
+		case r.URL.Path == "/v1/memories/" && r.Method == http.MethodGet:
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			start := (page - 1) * pageSize
+			end := start + pageSize
+			if end > len(memories) {
+				end = len(memories)
+			}
+			response := map[string]interface{}{"count": len(memories), "results": memories[start:end], "next": nil}
+			if end < len(memories) {
+				response["next"] = fmt.Sprintf("/v1/memories/?page=%d", page+1)
+			}
+			json.NewEncoder(w).Encode(response)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestMem0Source(endpoint, apiKey string) *Mem0Source {
+	source := NewMem0Source("mem0", &memory.EmbedderConfig{
+		Provider: Mem0Provider,
+		Config: map[string]interface{}{
+			"api_key":     apiKey,
+			"endpoint":    endpoint,
+			"user_id":     "alice",
+			"max_retries": 0,
+		},
+	}, logger.NewTestLogger())
+	source.retryBackoff = 0
+	return source
+}
+
+var testMem0Memories = []mem0Memory{
+	{ID: "m1", Memory: "Alice prefers tea", Score: 0.9, CreatedAt: "2024-05-01T10:00:00Z", UpdatedAt: "2024-05-01T10:00:00Z"},
+	{ID: "m2", Memory: "Alice lives in Berlin", Score: 0.6, CreatedAt: "2024-05-02T10:00:00Z", UpdatedAt: "2024-05-03T10:00:00Z"},
+	{ID: "m3", Memory: "Alice works remotely", Score: 0.2, Metadata: map[string]interface{}{"topic": "work"}},
+}
+
+func TestMem0SourceConnect(t *testing.T) {
+	server := fakeMem0Server(t, testMem0Memories, 2)
+	ctx := context.Background()
+
+	source := newTestMem0Source(server.URL+"/v1", "test-key")
+	require.NoError(t, source.Connect(ctx))
+	assert.True(t, source.IsAvailable())
+	assert.Equal(t, string(SourceTypeMem0), source.GetType())
+
+	require.NoError(t, source.Disconnect())
+	assert.False(t, source.IsAvailable())
+
+	rejected := newTestMem0Source(server.URL, "wrong-key")
+	err := rejected.Connect(ctx)
+	assert.ErrorIs(t, err, ErrSourceUnauthorized)
+	assert.False(t, rejected.IsAvailable())
+
+	t.Setenv("MEM0_API_KEY", "")
+	assert.Error(t, newTestMem0Source(server.URL, "").Connect(ctx))
+}
+
+func TestMem0SourceFetch(t *testing.T) {
+	server := fakeMem0Server(t, testMem0Memories, 2)
+	ctx := context.Background()
+	source := newTestMem0Source(server.URL, "test-key")
+
+	_, err := source.Fetch(ctx, "tea", 5)
+	assert.Error(t, err, "fetch requires a connected source")
+	require.NoError(t, source.Connect(ctx))
+
+	items, err := source.Fetch(ctx, "tea", 2)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "Alice prefers tea", items[0].Value)
+	assert.Equal(t, "mem0", items[0].SourceName)
+	assert.Equal(t, string(SourceTypeMem0), items[0].SourceType)
+	assert.Equal(t, "m1", items[0].SourceID)
+	assert.Equal(t, "mem0", items[0].Metadata["source_name"])
+	assert.Equal(t, 0.9, items[0].Score)
+
+	// 空查询分页列出全部记忆
+	items, err = source.Fetch(ctx, "", 0)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "work", items[2].Metadata["topic"])
+}
+
+func TestMem0SourceSync(t *testing.T) {
+	server := fakeMem0Server(t, testMem0Memories, 2)
+	ctx := context.Background()
+
+	stored := &recordingStorage{}
+	testLogger := logger.NewTestLogger()
+	em := NewExternalMemory(nil, nil, stored, "", events.NewEventBus(testLogger), testLogger)
+	source := newTestMem0Source(server.URL, "test-key")
+	require.NoError(t, em.AddSource(source))
+
+	assert.Error(t, source.Sync(ctx), "sync requires a connected source")
+	require.NoError(t, source.Connect(ctx))
+	require.NoError(t, em.SyncSource(ctx, "mem0"))
+	require.Len(t, stored.items, 3)
+	assert.Equal(t, "m2", stored.items[1].Metadata["source_id"])
+
+	// 未变化的记忆不会重复同步
+	require.NoError(t, em.SyncAll(ctx))
+	assert.Len(t, stored.items, 3)
+}
+
+func TestMem0SourceRateLimit(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"detail": "Too many requests"}`)
+	}))
+	defer server.Close()
+
+	source := newTestMem0Source(server.URL, "test-key")
+	source.maxRetries = 2
+	err := source.Connect(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrSourceRateLimited))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestMem0SourceRetriesServerErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"status": "ok"}`)
+	}))
+	defer server.Close()
+
+	source := newTestMem0Source(server.URL, "test-key")
+	require.Error(t, source.Connect(context.Background()), "no retries configured")
+
+	source.maxRetries = 1
+	require.NoError(t, source.Connect(context.Background()))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestExternalMemorySearchBySourceLive(t *testing.T) {
+	server := fakeMem0Server(t, testMem0Memories, 2)
+	ctx := context.Background()
+	testLogger := logger.NewTestLogger()
+
+	em := NewExternalMemory(nil, &memory.EmbedderConfig{
+		Provider: Mem0Provider,
+		Config:   map[string]interface{}{"api_key": "test-key", "endpoint": server.URL, "user_id": "alice"},
+	}, &recordingStorage{}, "", events.NewEventBus(testLogger), testLogger)
+
+	// mem0 provider自动注册mem0外部源
+	require.Len(t, em.GetSources(), 1)
+	results, err := em.SearchBySource(ctx, Mem0Provider, "alice", 5, 0.5)
+	require.NoError(t, err)
+	assert.Empty(t, results, "disconnected source falls back to synced memories")
+
+	require.NoError(t, em.GetSources()[0].Connect(ctx))
+	results, err = em.SearchBySource(ctx, Mem0Provider, "alice", 5, 0.5)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Alice prefers tea", results[0].Value)
+	assert.Equal(t, "Alice lives in Berlin", results[1].Value)
+}
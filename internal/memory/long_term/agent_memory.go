@@ -0,0 +1,107 @@
+package long_term
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/memory"
+)
+
+// AgentMemory 把LongTermMemory适配为agent.Memory接口
+// 通过agent.SetMemory设置后，BaseAgent.queryMemory会直接从SQLite长期记忆中检索
+type AgentMemory struct {
+	ltm       *LongTermMemory
+	agentRole string
+	namespace string // 非空时只读写该命名空间的记忆
+	access    memory.AgentAccessTracker
+}
+
+// 确保AgentMemory实现了agent.NamespacedMemory接口
+var _ agent.NamespacedMemory = (*AgentMemory)(nil)
+
+// NewAgentMemory 创建agent记忆适配器，agentRole用于标记和过滤该agent保存的记忆
+func NewAgentMemory(ltm *LongTermMemory, agentRole string) *AgentMemory {
+	return &AgentMemory{
+		ltm:       ltm,
+		agentRole: agentRole,
+	}
+}
+
+// WithNamespace 返回共享同一长期记忆但只读写namespace命名空间的适配器，克隆Agent时使用
+func (m *AgentMemory) WithNamespace(namespace string) agent.Memory {
+	return &AgentMemory{
+		ltm:       m.ltm,
+		agentRole: m.agentRole,
+		namespace: namespace,
+	}
+}
+
+// filter 返回限定为当前agent和命名空间的过滤条件
+func (m *AgentMemory) filter(extra map[string]interface{}) map[string]interface{} {
+	return memory.NamespacedAgentFilter(m.agentRole, m.namespace, extra)
+}
+
+// Store 保存一条以key标识的记忆
+func (m *AgentMemory) Store(ctx context.Context, key string, value interface{}) error {
+	m.access.Touch()
+
+	metadata := map[string]interface{}{
+		memory.AgentMemoryKeyField: key,
+		"memory_type":              "long_term",
+	}
+	if m.namespace != "" {
+		metadata[memory.AgentNamespaceField] = m.namespace
+	}
+	return m.ltm.SaveCompatible(ctx, value, metadata, m.agentRole)
+}
+
+// Retrieve 按key获取最近保存的记忆
+func (m *AgentMemory) Retrieve(ctx context.Context, key string) (interface{}, error) {
+	filter := m.filter(map[string]interface{}{memory.AgentMemoryKeyField: key})
+	items, err := m.ltm.SearchWithFilter(ctx, "", filter, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		m.access.Record(false)
+		return nil, fmt.Errorf("memory not found: %s", key)
+	}
+
+	m.access.Record(true)
+	return items[0].Value, nil
+}
+
+// Search 按关键词搜索记忆
+func (m *AgentMemory) Search(ctx context.Context, query string, limit int) ([]agent.MemoryItem, error) {
+	items, err := m.ltm.SearchWithFilter(ctx, query, m.filter(nil), limit)
+	if err != nil {
+		return nil, err
+	}
+	m.access.Record(len(items) > 0)
+
+	results := make([]agent.MemoryItem, len(items))
+	for i, item := range items {
+		results[i] = memory.ToAgentMemoryItem(item)
+	}
+	return results, nil
+}
+
+// Clear 清除当前agent（和命名空间）保存的长期记忆，其他agent的记忆不受影响
+// agentRole和命名空间都为空时清除全部长期记忆
+func (m *AgentMemory) Clear(ctx context.Context) error {
+	if m.agentRole == "" && m.namespace == "" {
+		return m.ltm.Reset(ctx)
+	}
+	_, err := m.ltm.DeleteWithFilter(ctx, m.filter(nil))
+	return err
+}
+
+// GetStats 获取记忆统计信息，与Search一致只统计当前agent的记忆
+func (m *AgentMemory) GetStats() agent.MemoryStats {
+	total, err := m.ltm.CountWithFilter(context.Background(), m.filter(nil))
+	if err != nil {
+		total = 0
+	}
+	return m.access.Stats(total)
+}
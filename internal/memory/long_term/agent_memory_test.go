@@ -0,0 +1,111 @@
+package long_term
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/agent/agenttest"
+	"github.com/ynl/greensoulai/internal/memory/storage"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newTestAgentMemoryLTM(t *testing.T) *LongTermMemory {
+	t.Helper()
+	testLogger := logger.NewTestLogger()
+	ltm := NewLongTermMemory(nil, filepath.Join(t.TempDir(), storage.LTMDBFileName), events.NewEventBus(testLogger), testLogger)
+	t.Cleanup(func() { ltm.Close() })
+	return ltm
+}
+
+func TestAgentMemoryStoreRetrieveSearch(t *testing.T) {
+	ltm := newTestAgentMemoryLTM(t)
+	ctx := context.Background()
+
+	researcher := NewAgentMemory(ltm, "researcher")
+	writer := NewAgentMemory(ltm, "writer")
+
+	require.NoError(t, researcher.Store(ctx, "market", "The EV market grew 35% last year"))
+	require.NoError(t, writer.Store(ctx, "style", "Reports use short paragraphs"))
+
+	value, err := researcher.Retrieve(ctx, "market")
+	require.NoError(t, err)
+	assert.Equal(t, "The EV market grew 35% last year", value)
+
+	// 每个agent只能检索到自己的记忆
+	_, err = researcher.Retrieve(ctx, "style")
+	assert.Error(t, err)
+
+	items, err := researcher.Search(ctx, "EV market report", 5)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "market", items[0].Key)
+
+	stats := researcher.GetStats()
+	assert.Equal(t, 1, stats.TotalItems)
+	assert.InDelta(t, 2.0/3.0, stats.HitRate, 1e-9)
+	assert.False(t, stats.LastAccessed.IsZero())
+
+	// Clear只清除当前agent的记忆
+	require.NoError(t, researcher.Clear(ctx))
+	items, err = researcher.Search(ctx, "", 5)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	items, err = writer.Search(ctx, "", 5)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, 1, writer.GetStats().TotalItems)
+
+	// 未指定agent的适配器作用于全部记忆
+	shared := NewAgentMemory(ltm, "")
+	assert.Equal(t, 1, shared.GetStats().TotalItems)
+	require.NoError(t, shared.Clear(ctx))
+	items, err = writer.Search(ctx, "", 5)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestAgentMemoryNamespaces(t *testing.T) {
+	ltm := newTestAgentMemoryLTM(t)
+	ctx := context.Background()
+
+	researcher := NewAgentMemory(ltm, "researcher")
+	first := researcher.WithNamespace("run-1")
+	second := researcher.WithNamespace("run-2")
+
+	require.NoError(t, first.Store(ctx, "market", "The EV market grew 35% last year"))
+	require.NoError(t, second.Store(ctx, "market", "Battery prices fell 14%"))
+
+	// 各命名空间只能看到自己的记忆
+	value, err := first.Retrieve(ctx, "market")
+	require.NoError(t, err)
+	assert.Equal(t, "The EV market grew 35% last year", value)
+	value, err = second.Retrieve(ctx, "market")
+	require.NoError(t, err)
+	assert.Equal(t, "Battery prices fell 14%", value)
+	assert.Equal(t, 1, second.GetStats().TotalItems)
+
+	// 清除一个命名空间不影响另一个
+	require.NoError(t, first.Clear(ctx))
+	items, err := first.Search(ctx, "", 5)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Equal(t, 1, second.GetStats().TotalItems)
+	assert.Equal(t, 1, researcher.GetStats().TotalItems)
+}
+
+func TestAgentMemoryUsedByBaseAgent(t *testing.T) {
+	ltm := newTestAgentMemoryLTM(t)
+	ctx := context.Background()
+
+	mem := NewAgentMemory(ltm, "analyst")
+	require.NoError(t, mem.Store(ctx, "finding", "Quarterly revenue peaked in Q3"))
+
+	prompt := agenttest.FirstPrompt(t, agent.AgentConfig{Role: "analyst", Memory: mem},
+		agent.NewBaseTask("Summarize quarterly revenue trends", "a summary"))
+	assert.Contains(t, prompt, "Quarterly revenue peaked in Q3")
+}
@@ -2,6 +2,9 @@ package long_term
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ynl/greensoulai/internal/memory"
@@ -15,6 +18,7 @@ import (
 // 与Python版本保持业务逻辑一致
 type LongTermMemory struct {
 	*memory.BaseMemory
+	storage       memory.MemoryStorage
 	sqliteStorage *storage.LTMSQLiteStorage
 }
 
@@ -40,6 +44,7 @@ func NewLongTermMemory(memStorage memory.MemoryStorage, path string, eventBus ev
 
 	return &LongTermMemory{
 		BaseMemory:    baseMemory,
+		storage:       storageInstance,
 		sqliteStorage: sqliteStorage,
 	}
 }
@@ -126,7 +131,7 @@ func (ltm *LongTermMemory) Search(ctx context.Context, task string, latestN int)
 			// 转换为字典列表格式，与Python版本返回格式一致
 			results = make([]map[string]interface{}, len(memoryItems))
 			for i, item := range memoryItems {
-				if dictValue, ok := item.Value.(map[string]interface{}); ok {
+				if dictValue, ok := toDict(item.Value); ok {
 					results[i] = dictValue
 				} else {
 					// 如果不是字典格式，构造一个基本格式
@@ -197,6 +202,73 @@ func (ltm *LongTermMemory) Search(ctx context.Context, task string, latestN int)
 	return results, nil
 }
 
+// SearchWithFilter 按关键词和元数据过滤搜索原始记忆项
+// filter中的"agent"键匹配保存记忆的agent，其他键匹配metadata中的同名字段
+func (ltm *LongTermMemory) SearchWithFilter(ctx context.Context, query string, filter map[string]interface{}, limit int) ([]memory.MemoryItem, error) {
+	if ltm.sqliteStorage != nil {
+		return ltm.sqliteStorage.SearchWithFilter(ctx, query, filter, limit, 0.0)
+	}
+
+	// 自定义存储不支持过滤，搜索后在内存中过滤
+	items, err := ltm.BaseMemory.Search(ctx, query, limit, 0.0)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]memory.MemoryItem, 0, len(items))
+	for _, item := range items {
+		if memory.MatchesFilter(item, filter) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
+// fallbackFilterLimit 自定义存储在内存中过滤时最多检查的记忆数量
+const fallbackFilterLimit = 10000
+
+// DeleteWithFilter 删除满足过滤条件的记忆，返回删除的数量
+// 过滤条件与SearchWithFilter相同；filter不能为空，清除全部记忆请使用Reset
+func (ltm *LongTermMemory) DeleteWithFilter(ctx context.Context, filter map[string]interface{}) (int, error) {
+	if ltm.sqliteStorage != nil {
+		return ltm.sqliteStorage.DeleteWithFilter(ctx, filter)
+	}
+	if len(filter) == 0 {
+		return 0, fmt.Errorf("delete filter must not be empty")
+	}
+
+	items, err := ltm.SearchWithFilter(ctx, "", filter, fallbackFilterLimit)
+	if err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if err := ltm.storage.Delete(ctx, item.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
+
+// CountWithFilter 统计满足过滤条件的记忆数量
+func (ltm *LongTermMemory) CountWithFilter(ctx context.Context, filter map[string]interface{}) (int, error) {
+	if ltm.sqliteStorage != nil {
+		return ltm.sqliteStorage.CountWithFilter(ctx, filter)
+	}
+
+	items, err := ltm.SearchWithFilter(ctx, "", filter, fallbackFilterLimit)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// GetStats 获取长期记忆存储的统计信息
+func (ltm *LongTermMemory) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	if ltm.sqliteStorage != nil {
+		return ltm.sqliteStorage.GetStats(ctx)
+	}
+	return map[string]interface{}{}, nil
+}
+
 // SaveCompatible 兼容通用Memory接口的Save方法
 func (ltm *LongTermMemory) SaveCompatible(ctx context.Context, value interface{}, metadata map[string]interface{}, agent string) error {
 	return ltm.BaseMemory.Save(ctx, value, metadata, agent)
@@ -225,3 +297,21 @@ func (ltm *LongTermMemory) GetEventBus() events.EventBus {
 	}
 	return nil
 }
+
+// toDict 把存储的值还原为字典，SQLite存储中字典以JSON文本保存
+func toDict(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case string:
+		if !strings.HasPrefix(strings.TrimSpace(v), "{") {
+			return nil, false
+		}
+		var dict map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &dict); err != nil {
+			return nil, false
+		}
+		return dict, true
+	}
+	return nil, false
+}
@@ -2,6 +2,7 @@ package long_term
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -15,7 +16,7 @@ func TestNewLongTermMemory(t *testing.T) {
 	testEventBus := events.NewEventBus(testLogger)
 
 	// 创建长期记忆实例（可能会因为没有SQLite存储而失败，但测试基本结构）
-	ltm := NewLongTermMemory(nil, "test-path", testEventBus, testLogger)
+	ltm := NewLongTermMemory(nil, filepath.Join(t.TempDir(), "test-path"), testEventBus, testLogger)
 
 	// 基本验证
 	assert.NotNil(t, ltm)
@@ -25,7 +26,7 @@ func TestLongTermMemoryInterface(t *testing.T) {
 	testLogger := logger.NewConsoleLogger()
 	testEventBus := events.NewEventBus(testLogger)
 
-	ltm := NewLongTermMemory(nil, "test-path", testEventBus, testLogger)
+	ltm := NewLongTermMemory(nil, filepath.Join(t.TempDir(), "test-path"), testEventBus, testLogger)
 	assert.NotNil(t, ltm)
 
 	ctx := context.Background()
@@ -72,7 +73,7 @@ func TestLongTermMemoryConfiguration(t *testing.T) {
 	testLogger := logger.NewConsoleLogger()
 	testEventBus := events.NewEventBus(testLogger)
 
-	ltm := NewLongTermMemory(nil, "advanced-path", testEventBus, testLogger)
+	ltm := NewLongTermMemory(nil, filepath.Join(t.TempDir(), "advanced-path"), testEventBus, testLogger)
 
 	assert.NotNil(t, ltm)
 
@@ -51,6 +51,8 @@ type BaseMemory struct {
 type EmbedderConfig struct {
 	Provider string                 `json:"provider"`
 	Config   map[string]interface{} `json:"config"`
+	Embedder Embedder               `json:"-"` // 自定义嵌入器，设置后忽略Provider
+	Cache    *EmbeddingCache        `json:"-"` // 嵌入缓存，设置后包装创建的嵌入器，可在多个组件间共享
 }
 
 // MemoryStorage 记忆存储接口
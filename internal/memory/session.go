@@ -0,0 +1,331 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// 会话记忆的默认预算
+const (
+	defaultSessionMaxTurns  = 10
+	defaultSessionMaxTokens = 2000
+	// fallbackSummaryTokens 没有配置摘要LLM时，摘要按token预算保留最近的部分
+	fallbackSummaryTokens = 500
+)
+
+// SessionTurn 会话中的一轮：用户输入和Crew的最终回答
+type SessionTurn struct {
+	Input     string    `json:"input"`
+	Output    string    `json:"output"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SessionState 一个会话的状态：被移出窗口的轮次的滚动摘要和最近的轮次
+type SessionState struct {
+	ID        string        `json:"id"`
+	Summary   string        `json:"summary,omitempty"`
+	Turns     []SessionTurn `json:"turns"` // 按时间顺序，最早的在前
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Empty 会话是否还没有任何内容
+func (s *SessionState) Empty() bool {
+	return s == nil || (s.Summary == "" && len(s.Turns) == 0)
+}
+
+// Render 把摘要和最近的轮次渲染为注入任务上下文的文本，会话为空时返回空字符串
+func (s *SessionState) Render() string {
+	if s.Empty() {
+		return ""
+	}
+	var b strings.Builder
+	if s.Summary != "" {
+		b.WriteString("Summary of earlier conversation: ")
+		b.WriteString(s.Summary)
+	}
+	for _, turn := range s.Turns {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "User: %s\nAssistant: %s", turn.Input, turn.Output)
+	}
+	return b.String()
+}
+
+// clone 返回可以在锁外使用的副本
+func (s *SessionState) clone() *SessionState {
+	copied := *s
+	copied.Turns = append([]SessionTurn(nil), s.Turns...)
+	return &copied
+}
+
+// SessionStore 会话状态的持久化后端
+type SessionStore interface {
+	// Load 读取会话，不存在时返回false
+	Load(ctx context.Context, sessionID string) (*SessionState, bool, error)
+
+	// Save 保存会话，已存在时覆盖
+	Save(ctx context.Context, state *SessionState) error
+
+	// Delete 删除会话，不存在时不报错
+	Delete(ctx context.Context, sessionID string) error
+
+	// List 按ID排序返回所有会话ID
+	List(ctx context.Context) ([]string, error)
+
+	// Close 关闭后端
+	Close() error
+}
+
+// InMemorySessionStore 进程内的会话存储，进程退出后会话丢失
+type InMemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*SessionState
+}
+
+var _ SessionStore = (*InMemorySessionStore)(nil)
+
+// NewInMemorySessionStore 创建内存会话存储
+func NewInMemorySessionStore() *InMemorySessionStore {
+	return &InMemorySessionStore{sessions: make(map[string]*SessionState)}
+}
+
+// Load 实现SessionStore接口
+func (s *InMemorySessionStore) Load(ctx context.Context, sessionID string) (*SessionState, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, false, nil
+	}
+	return state.clone(), true, nil
+}
+
+// Save 实现SessionStore接口
+func (s *InMemorySessionStore) Save(ctx context.Context, state *SessionState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[state.ID] = state.clone()
+	return nil
+}
+
+// Delete 实现SessionStore接口
+func (s *InMemorySessionStore) Delete(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+// List 实现SessionStore接口
+func (s *InMemorySessionStore) List(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Close 实现SessionStore接口
+func (s *InMemorySessionStore) Close() error {
+	return nil
+}
+
+// SessionMemoryConfig 会话记忆配置
+type SessionMemoryConfig struct {
+	MaxTurns   int           `json:"max_turns"`  // 保留原文的最近轮数
+	MaxTokens  int           `json:"max_tokens"` // 最近轮次原文的token预算，超出时最早的轮次被移入摘要
+	Store      SessionStore  `json:"-"`          // 为nil时使用内存存储
+	SummaryLLM llm.LLM       `json:"-"`          // 把移出窗口的轮次合并进摘要，为nil时按token预算截取原文
+	Tokenizer  llm.Tokenizer `json:"-"`          // 为nil时使用启发式估算
+}
+
+// DefaultSessionMemoryConfig 返回默认的会话记忆配置（内存存储，不使用LLM摘要）
+func DefaultSessionMemoryConfig() *SessionMemoryConfig {
+	return &SessionMemoryConfig{
+		MaxTurns:  defaultSessionMaxTurns,
+		MaxTokens: defaultSessionMaxTokens,
+	}
+}
+
+// SessionMemory 按会话ID保存多次Kickoff之间的对话
+// 每次Kickoff前读取会话注入第一个任务的上下文，完成后追加本轮；
+// 超出轮数或token预算时最早的轮次被合并进滚动摘要。同一会话的追加是串行的
+type SessionMemory struct {
+	maxTurns   int
+	maxTokens  int
+	store      SessionStore
+	summaryLLM llm.LLM
+	tokenizer  llm.Tokenizer
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewSessionMemory 创建会话记忆，config为nil时使用默认配置
+func NewSessionMemory(config *SessionMemoryConfig) *SessionMemory {
+	if config == nil {
+		config = DefaultSessionMemoryConfig()
+	}
+	memory := &SessionMemory{
+		maxTurns:   config.MaxTurns,
+		maxTokens:  config.MaxTokens,
+		store:      config.Store,
+		summaryLLM: config.SummaryLLM,
+		tokenizer:  config.Tokenizer,
+		locks:      make(map[string]*sync.Mutex),
+	}
+	if memory.maxTurns <= 0 {
+		memory.maxTurns = defaultSessionMaxTurns
+	}
+	if memory.maxTokens <= 0 {
+		memory.maxTokens = defaultSessionMaxTokens
+	}
+	if memory.store == nil {
+		memory.store = NewInMemorySessionStore()
+	}
+	if memory.tokenizer == nil {
+		memory.tokenizer = llm.HeuristicTokenizer{}
+	}
+	return memory
+}
+
+// SetSummaryLLM 设置生成摘要的LLM，已设置时不覆盖
+func (m *SessionMemory) SetSummaryLLM(model llm.LLM) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.summaryLLM == nil {
+		m.summaryLLM = model
+	}
+}
+
+// sessionLock 返回会话的互斥锁
+func (m *SessionMemory) sessionLock(sessionID string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lock, ok := m.locks[sessionID]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[sessionID] = lock
+	}
+	return lock
+}
+
+// Load 读取会话，不存在时返回空会话
+func (m *SessionMemory) Load(ctx context.Context, sessionID string) (*SessionState, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID cannot be empty")
+	}
+	state, ok, err := m.store.Load(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session %s: %w", sessionID, err)
+	}
+	if !ok {
+		return &SessionState{ID: sessionID}, nil
+	}
+	return state, nil
+}
+
+// Append 追加一轮对话并保存，超出预算的最早轮次被合并进摘要
+// 摘要生成失败时退回按预算截取原文，不影响本轮的保存
+func (m *SessionMemory) Append(ctx context.Context, sessionID, input, output string) (*SessionState, error) {
+	lock := m.sessionLock(sessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	state, err := m.Load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	state.Turns = append(state.Turns, SessionTurn{Input: input, Output: output, CreatedAt: time.Now()})
+
+	if evicted := m.evict(state); len(evicted) > 0 {
+		state.Summary = m.summarize(ctx, state.Summary, evicted)
+	}
+	state.UpdatedAt = time.Now()
+
+	if err := m.store.Save(ctx, state); err != nil {
+		return nil, fmt.Errorf("failed to save session %s: %w", sessionID, err)
+	}
+	return state, nil
+}
+
+// evict 移出超出轮数或token预算的最早轮次，至少保留最新的一轮
+func (m *SessionMemory) evict(state *SessionState) []SessionTurn {
+	keep := 0
+	tokens := 0
+	for i := len(state.Turns) - 1; i >= 0; i-- {
+		turn := state.Turns[i]
+		tokens += m.tokenizer.CountTokens(turn.Input) + m.tokenizer.CountTokens(turn.Output)
+		if i < len(state.Turns)-1 && (len(state.Turns)-i > m.maxTurns || tokens > m.maxTokens) {
+			break
+		}
+		keep = len(state.Turns) - i
+	}
+
+	evicted := append([]SessionTurn(nil), state.Turns[:len(state.Turns)-keep]...)
+	state.Turns = append([]SessionTurn(nil), state.Turns[len(state.Turns)-keep:]...)
+	return evicted
+}
+
+// summarize 把移出的轮次合并进摘要，要求保留用户的偏好、决定和关键事实
+func (m *SessionMemory) summarize(ctx context.Context, summary string, evicted []SessionTurn) string {
+	transcript := (&SessionState{Turns: evicted}).Render()
+
+	m.mu.Lock()
+	summaryLLM := m.summaryLLM
+	m.mu.Unlock()
+
+	if summaryLLM != nil {
+		prompt := "Update the running summary of a conversation with the turns below. " +
+			"Keep the user's goals, preferences, decisions and key facts; drop small talk. " +
+			"Reply with the updated summary only, in at most a few sentences.\n\n" +
+			"Current summary:\n" + summary + "\n\nNew turns:\n" + transcript
+		response, err := summaryLLM.Call(ctx, []llm.Message{{Role: llm.RoleUser, Content: prompt}}, nil)
+		if err == nil && strings.TrimSpace(response.Content) != "" {
+			return strings.TrimSpace(response.Content)
+		}
+	}
+
+	// 没有摘要LLM或调用失败时保留最近的原文
+	combined := strings.TrimSpace(summary + "\n" + transcript)
+	runes := []rune(combined)
+	for len(runes) > 0 && m.tokenizer.CountTokens(string(runes)) > fallbackSummaryTokens {
+		runes = runes[len(runes)/4:]
+	}
+	return string(runes)
+}
+
+// Clear 删除会话
+func (m *SessionMemory) Clear(ctx context.Context, sessionID string) error {
+	lock := m.sessionLock(sessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return m.store.Delete(ctx, sessionID)
+}
+
+// Sessions 返回所有会话ID
+func (m *SessionMemory) Sessions(ctx context.Context) ([]string, error) {
+	return m.store.List(ctx)
+}
+
+// Close 关闭存储后端
+func (m *SessionMemory) Close() error {
+	return m.store.Close()
+}
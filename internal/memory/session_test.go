@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+)
+
+func TestSessionMemoryEvictsOldTurnsIntoSummary(t *testing.T) {
+	ctx := context.Background()
+	model := llmtest.NewScriptedLLM(llmtest.Replies("User is planning a trip to Kyoto in May.")...)
+	sessions := NewSessionMemory(&SessionMemoryConfig{MaxTurns: 2, SummaryLLM: model})
+
+	state, err := sessions.Load(ctx, "chat-1")
+	require.NoError(t, err)
+	assert.True(t, state.Empty())
+	assert.Equal(t, "", state.Render())
+
+	for _, input := range []string{"I want to visit Kyoto", "In May", "What should I pack?"} {
+		_, err := sessions.Append(ctx, "chat-1", input, "answer to "+input)
+		require.NoError(t, err)
+	}
+
+	state, err = sessions.Load(ctx, "chat-1")
+	require.NoError(t, err)
+	require.Len(t, state.Turns, 2)
+	assert.Equal(t, "In May", state.Turns[0].Input)
+	assert.Equal(t, "User is planning a trip to Kyoto in May.", state.Summary)
+
+	// 只有移出窗口的轮次交给摘要LLM
+	require.Equal(t, 1, model.CallCount())
+	prompt := model.Calls()[0].UserPrompt()
+	assert.Contains(t, prompt, "User: I want to visit Kyoto")
+	assert.NotContains(t, prompt, "What should I pack?")
+
+	rendered := state.Render()
+	assert.True(t, strings.HasPrefix(rendered, "Summary of earlier conversation: User is planning"))
+	assert.Contains(t, rendered, "User: What should I pack?\nAssistant: answer to What should I pack?")
+
+	require.NoError(t, sessions.Clear(ctx, "chat-1"))
+	ids, err := sessions.Sessions(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestSessionMemoryTokenBudgetWithoutSummaryLLM(t *testing.T) {
+	ctx := context.Background()
+	sessions := NewSessionMemory(&SessionMemoryConfig{MaxTurns: 10, MaxTokens: 20})
+
+	long := strings.Repeat("word ", 30)
+	_, err := sessions.Append(ctx, "chat-2", "first question", long)
+	require.NoError(t, err)
+
+	// 超出预算的单轮也会保留
+	state, err := sessions.Load(ctx, "chat-2")
+	require.NoError(t, err)
+	require.Len(t, state.Turns, 1)
+	assert.Empty(t, state.Summary)
+
+	state, err = sessions.Append(ctx, "chat-2", "second question", "short answer")
+	require.NoError(t, err)
+	require.Len(t, state.Turns, 1)
+	assert.Equal(t, "second question", state.Turns[0].Input)
+	// 没有摘要LLM时保留被移出轮次的原文
+	assert.Contains(t, state.Summary, "User: first question")
+}
@@ -0,0 +1,123 @@
+package short_term
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/memory"
+)
+
+// DefaultAgentScoreThreshold agent检索短期记忆时的默认相似度阈值，与Python版本一致
+const DefaultAgentScoreThreshold = 0.35
+
+// AgentMemory 把ShortTermMemory适配为agent.Memory接口
+// 通过agent.SetMemory设置后，BaseAgent.queryMemory按语义相似度检索与任务相关的短期记忆
+type AgentMemory struct {
+	stm       *ShortTermMemory
+	agentRole string
+	namespace string // 非空时只读写该命名空间的记忆
+	access    memory.AgentAccessTracker
+
+	mu             sync.Mutex
+	scoreThreshold float64
+}
+
+// 确保AgentMemory实现了agent.NamespacedMemory接口
+var _ agent.NamespacedMemory = (*AgentMemory)(nil)
+
+// NewAgentMemory 创建agent记忆适配器，agentRole用于标记和过滤该agent保存的记忆
+func NewAgentMemory(stm *ShortTermMemory, agentRole string) *AgentMemory {
+	return &AgentMemory{
+		stm:            stm,
+		agentRole:      agentRole,
+		scoreThreshold: DefaultAgentScoreThreshold,
+	}
+}
+
+// SetScoreThreshold 设置检索的相似度阈值，低于阈值的记忆不会返回
+func (m *AgentMemory) SetScoreThreshold(threshold float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scoreThreshold = threshold
+}
+
+// WithNamespace 返回共享同一短期记忆但只读写namespace命名空间的适配器，克隆Agent时使用
+func (m *AgentMemory) WithNamespace(namespace string) agent.Memory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &AgentMemory{
+		stm:            m.stm,
+		agentRole:      m.agentRole,
+		namespace:      namespace,
+		scoreThreshold: m.scoreThreshold,
+	}
+}
+
+// Store 保存一条以key标识的记忆
+func (m *AgentMemory) Store(ctx context.Context, key string, value interface{}) error {
+	m.access.Touch()
+
+	metadata := map[string]interface{}{
+		memory.AgentMemoryKeyField: key,
+	}
+	if m.namespace != "" {
+		metadata[memory.AgentNamespaceField] = m.namespace
+	}
+	return m.stm.Save(ctx, value, metadata, m.agentRole)
+}
+
+// Retrieve 按key获取最近保存的记忆
+func (m *AgentMemory) Retrieve(ctx context.Context, key string) (interface{}, error) {
+	filter := memory.NamespacedAgentFilter(m.agentRole, m.namespace, map[string]interface{}{memory.AgentMemoryKeyField: key})
+	items, err := m.stm.SearchWithFilter(ctx, "", filter, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		m.access.Record(false)
+		return nil, fmt.Errorf("memory not found: %s", key)
+	}
+
+	m.access.Record(true)
+	return items[0].Value, nil
+}
+
+// Search 按语义相似度搜索记忆
+func (m *AgentMemory) Search(ctx context.Context, query string, limit int) ([]agent.MemoryItem, error) {
+	m.mu.Lock()
+	threshold := m.scoreThreshold
+	m.mu.Unlock()
+
+	items, err := m.stm.SearchWithFilter(ctx, query, memory.NamespacedAgentFilter(m.agentRole, m.namespace, nil), limit, threshold)
+	if err != nil {
+		return nil, err
+	}
+	m.access.Record(len(items) > 0)
+
+	results := make([]agent.MemoryItem, len(items))
+	for i, item := range items {
+		results[i] = memory.ToAgentMemoryItem(item)
+	}
+	return results, nil
+}
+
+// Clear 清除当前agent（和命名空间）保存的短期记忆，其他agent的记忆不受影响
+// agentRole和命名空间都为空时清除全部短期记忆
+func (m *AgentMemory) Clear(ctx context.Context) error {
+	if m.agentRole == "" && m.namespace == "" {
+		return m.stm.Clear(ctx)
+	}
+	_, err := m.stm.DeleteWithFilter(ctx, memory.NamespacedAgentFilter(m.agentRole, m.namespace, nil))
+	return err
+}
+
+// GetStats 获取记忆统计信息，与Search一致只统计当前agent（和命名空间）的记忆
+func (m *AgentMemory) GetStats() agent.MemoryStats {
+	total, err := m.stm.CountWithFilter(context.Background(), memory.NamespacedAgentFilter(m.agentRole, m.namespace, nil))
+	if err != nil {
+		total = 0
+	}
+	return m.access.Stats(total)
+}
@@ -0,0 +1,101 @@
+package short_term
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/agent/agenttest"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newSemanticShortTermMemory(t *testing.T) *ShortTermMemory {
+	t.Helper()
+	testLogger := logger.NewTestLogger()
+	config := &memory.EmbedderConfig{Provider: memory.EmbedderProviderHash}
+	return NewShortTermMemory(nil, config, nil, "", events.NewEventBus(testLogger), testLogger)
+}
+
+func TestAgentMemorySemanticSearch(t *testing.T) {
+	stm := newSemanticShortTermMemory(t)
+	ctx := context.Background()
+
+	analyst := NewAgentMemory(stm, "analyst")
+	require.NoError(t, analyst.Store(ctx, "revenue", "Quarterly revenue grew twelve percent"))
+	require.NoError(t, analyst.Store(ctx, "baking", "Sourdough starter needs daily feeding"))
+	require.NoError(t, NewAgentMemory(stm, "writer").Store(ctx, "style", "Quarterly revenue summaries use tables"))
+
+	items, err := analyst.Search(ctx, "quarterly revenue trends", 5)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "revenue", items[0].Key)
+	assert.GreaterOrEqual(t, items[0].Score, DefaultAgentScoreThreshold)
+
+	// 降低阈值后返回更多结果，但仍只包含当前agent的记忆
+	analyst.SetScoreThreshold(0)
+	items, err = analyst.Search(ctx, "quarterly revenue trends", 5)
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	value, err := analyst.Retrieve(ctx, "baking")
+	require.NoError(t, err)
+	assert.Equal(t, "Sourdough starter needs daily feeding", value)
+
+	_, err = analyst.Retrieve(ctx, "style")
+	assert.Error(t, err)
+
+	stats := analyst.GetStats()
+	assert.Equal(t, 2, stats.TotalItems, "stats should only count the analyst's memories")
+	assert.False(t, stats.LastAccessed.IsZero())
+}
+
+func TestAgentMemoryClearIsScopedToAgent(t *testing.T) {
+	stm := newSemanticShortTermMemory(t)
+	ctx := context.Background()
+
+	analyst := NewAgentMemory(stm, "analyst")
+	writer := NewAgentMemory(stm, "writer")
+	clone := analyst.WithNamespace("clone-1")
+	require.NoError(t, analyst.Store(ctx, "revenue", "Quarterly revenue grew twelve percent"))
+	require.NoError(t, clone.Store(ctx, "draft", "Clone draft about revenue"))
+	require.NoError(t, writer.Store(ctx, "style", "Revenue summaries use tables"))
+
+	assert.Equal(t, 1, clone.GetStats().TotalItems)
+	assert.Equal(t, 1, writer.GetStats().TotalItems)
+
+	// 清除克隆的命名空间只删除克隆的记忆
+	require.NoError(t, clone.Clear(ctx))
+	assert.Equal(t, 0, clone.GetStats().TotalItems)
+	value, err := analyst.Retrieve(ctx, "revenue")
+	require.NoError(t, err)
+	assert.Equal(t, "Quarterly revenue grew twelve percent", value)
+
+	// 清除一个agent的记忆不影响其他agent
+	require.NoError(t, analyst.Clear(ctx))
+	_, err = analyst.Retrieve(ctx, "revenue")
+	assert.Error(t, err)
+	assert.Equal(t, 0, analyst.GetStats().TotalItems)
+
+	value, err = writer.Retrieve(ctx, "style")
+	require.NoError(t, err)
+	assert.Equal(t, "Revenue summaries use tables", value)
+	assert.Equal(t, 1, writer.GetStats().TotalItems)
+}
+
+func TestAgentMemoryUsedByBaseAgent(t *testing.T) {
+	stm := newSemanticShortTermMemory(t)
+	ctx := context.Background()
+
+	mem := NewAgentMemory(stm, "analyst")
+	require.NoError(t, mem.Store(ctx, "finding", "Quarterly revenue peaked in the third quarter"))
+	require.NoError(t, mem.Store(ctx, "unrelated", "The office coffee machine was replaced"))
+
+	prompt := agenttest.FirstPrompt(t, agent.AgentConfig{Role: "analyst", Memory: mem},
+		agent.NewBaseTask("Summarize quarterly revenue trends", "a summary"))
+	assert.Contains(t, prompt, "Quarterly revenue peaked in the third quarter")
+	assert.NotContains(t, prompt, "coffee machine", "unrelated memory should be filtered by the score threshold")
+}
@@ -15,6 +15,12 @@ import (
 type ShortTermMemory struct {
 	*memory.BaseMemory
 	memoryProvider string
+	storage        memory.MemoryStorage
+}
+
+// filterSearcher 支持按元数据过滤搜索的存储（如RAGStorage）
+type filterSearcher interface {
+	SearchWithFilter(ctx context.Context, query string, filter map[string]interface{}, limit int, scoreThreshold float64) ([]memory.MemoryItem, error)
 }
 
 // ShortTermMemoryItem 短期记忆项
@@ -53,6 +59,7 @@ func NewShortTermMemory(crew interface{}, embedderConfig *memory.EmbedderConfig,
 	return &ShortTermMemory{
 		BaseMemory:     baseMemory,
 		memoryProvider: memoryProvider,
+		storage:        storageInstance,
 	}
 }
 
@@ -103,71 +110,85 @@ func (stm *ShortTermMemory) SaveInteractionMemory(ctx context.Context, sessionID
 	return stm.Save(ctx, interaction, metadata, agent)
 }
 
-// SearchByTask 根据任务ID搜索记忆
-func (stm *ShortTermMemory) SearchByTask(ctx context.Context, taskID string, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
-	// 首先执行基础搜索
-	results, err := stm.Search(ctx, query, limit*2, scoreThreshold) // 获取更多结果进行过滤
+// SearchWithFilter 搜索满足元数据过滤条件的记忆
+// 配置了嵌入器时按语义相似度排序；filter中的"agent"键匹配保存记忆的agent，其他键匹配metadata
+func (stm *ShortTermMemory) SearchWithFilter(ctx context.Context, query string, filter map[string]interface{}, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	if searcher, ok := stm.storage.(filterSearcher); ok {
+		return searcher.SearchWithFilter(ctx, query, filter, limit, scoreThreshold)
+	}
+
+	// 存储不支持过滤时多取一些结果再过滤
+	results, err := stm.Search(ctx, query, limit*2, scoreThreshold)
 	if err != nil {
 		return nil, err
 	}
 
-	// 过滤出与指定任务相关的记忆
-	var filteredResults []memory.MemoryItem
+	filteredResults := make([]memory.MemoryItem, 0, limit)
 	for _, item := range results {
-		if item.Metadata != nil {
-			if itemTaskID, ok := item.Metadata["task_id"].(string); ok && itemTaskID == taskID {
-				filteredResults = append(filteredResults, item)
-				if len(filteredResults) >= limit {
-					break
-				}
-			}
+		if len(filteredResults) >= limit {
+			break
+		}
+		if memory.MatchesFilter(item, filter) {
+			filteredResults = append(filteredResults, item)
 		}
 	}
-
 	return filteredResults, nil
 }
 
+// SearchByTask 根据任务ID搜索记忆
+func (stm *ShortTermMemory) SearchByTask(ctx context.Context, taskID string, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	return stm.SearchWithFilter(ctx, query, map[string]interface{}{"task_id": taskID}, limit, scoreThreshold)
+}
+
 // SearchBySession 根据会话ID搜索记忆
 func (stm *ShortTermMemory) SearchBySession(ctx context.Context, sessionID string, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
-	// 首先执行基础搜索
-	results, err := stm.Search(ctx, query, limit*2, scoreThreshold)
-	if err != nil {
-		return nil, err
-	}
-
-	// 过滤出与指定会话相关的记忆
-	var filteredResults []memory.MemoryItem
-	for _, item := range results {
-		if item.Metadata != nil {
-			if itemSessionID, ok := item.Metadata["session_id"].(string); ok && itemSessionID == sessionID {
-				filteredResults = append(filteredResults, item)
-				if len(filteredResults) >= limit {
-					break
-				}
-			}
-		}
-	}
-
-	return filteredResults, nil
+	return stm.SearchWithFilter(ctx, query, map[string]interface{}{"session_id": sessionID}, limit, scoreThreshold)
 }
 
-// GetRecentMemories 获取最近的记忆项
+// GetRecentMemories 获取agent最近的记忆项
 func (stm *ShortTermMemory) GetRecentMemories(ctx context.Context, agent string, limit int) ([]memory.MemoryItem, error) {
-	// 使用通用查询获取记忆项
-	query := fmt.Sprintf("agent:%s", agent)
-	return stm.Search(ctx, query, limit, 0.1) // 使用较低的阈值获取更多结果
+	return stm.SearchWithFilter(ctx, "", map[string]interface{}{"agent": agent}, limit, 0)
 }
 
 // ClearSession 清除指定会话的记忆
 func (stm *ShortTermMemory) ClearSession(ctx context.Context, sessionID string) error {
-	// TODO: 实现根据会话ID删除记忆的功能
-	// 这需要存储层支持按元数据删除
-	return fmt.Errorf("session-specific clear not implemented yet")
+	_, err := stm.DeleteWithFilter(ctx, map[string]interface{}{"session_id": sessionID})
+	return err
 }
 
 // ClearTask 清除指定任务的记忆
 func (stm *ShortTermMemory) ClearTask(ctx context.Context, taskID string) error {
-	// TODO: 实现根据任务ID删除记忆的功能
-	// 这需要存储层支持按元数据删除
-	return fmt.Errorf("task-specific clear not implemented yet")
+	_, err := stm.DeleteWithFilter(ctx, map[string]interface{}{"task_id": taskID})
+	return err
+}
+
+// filterScanLimit 按过滤条件删除和统计时最多检查的记忆数量
+const filterScanLimit = 10000
+
+// DeleteWithFilter 删除满足过滤条件的记忆，返回删除的数量
+// 过滤条件与SearchWithFilter相同；filter不能为空，清除全部记忆请使用Clear
+func (stm *ShortTermMemory) DeleteWithFilter(ctx context.Context, filter map[string]interface{}) (int, error) {
+	if len(filter) == 0 {
+		return 0, fmt.Errorf("delete filter must not be empty")
+	}
+
+	items, err := stm.SearchWithFilter(ctx, "", filter, filterScanLimit, 0)
+	if err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if err := stm.storage.Delete(ctx, item.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
+
+// CountWithFilter 统计满足过滤条件的记忆数量
+func (stm *ShortTermMemory) CountWithFilter(ctx context.Context, filter map[string]interface{}) (int, error) {
+	items, err := stm.SearchWithFilter(ctx, "", filter, filterScanLimit, 0)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
 }
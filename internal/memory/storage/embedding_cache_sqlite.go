@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/ynl/greensoulai/internal/memory"
+)
+
+// EmbeddingCacheDBFileName 嵌入缓存数据库文件名
+const EmbeddingCacheDBFileName = "embedding_cache.db"
+
+// DefaultEmbeddingCacheDBPath 返回默认的嵌入缓存数据库路径（位于memory.StorageDir()下）
+func DefaultEmbeddingCacheDBPath() string {
+	return filepath.Join(memory.StorageDir(), EmbeddingCacheDBFileName)
+}
+
+// SQLiteEmbeddingCacheStore 嵌入缓存的SQLite持久化后端，向量以小端float32编码存储
+type SQLiteEmbeddingCacheStore struct {
+	dbPath string
+	db     *sql.DB
+}
+
+// NewSQLiteEmbeddingCacheStore 打开（必要时创建）嵌入缓存数据库，dbPath为空时使用默认路径
+func NewSQLiteEmbeddingCacheStore(dbPath string) (*SQLiteEmbeddingCacheStore, error) {
+	if dbPath == "" {
+		dbPath = DefaultEmbeddingCacheDBPath()
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	dsn := fmt.Sprintf("%s?_busy_timeout=%d&_journal_mode=WAL&_synchronous=NORMAL", dbPath, ltmBusyTimeoutMs)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS embeddings (
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (provider, model, content_hash)
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create embedding cache tables: %w", err)
+	}
+
+	return &SQLiteEmbeddingCacheStore{dbPath: dbPath, db: db}, nil
+}
+
+// DBPath 返回数据库文件路径
+func (s *SQLiteEmbeddingCacheStore) DBPath() string {
+	return s.dbPath
+}
+
+// Get 实现memory.EmbeddingCacheStore接口
+func (s *SQLiteEmbeddingCacheStore) Get(ctx context.Context, key memory.EmbeddingCacheKey) ([]float32, bool, error) {
+	var blob []byte
+	err := s.db.QueryRowContext(ctx,
+		`SELECT vector FROM embeddings WHERE provider = ? AND model = ? AND content_hash = ?`,
+		key.Provider, key.Model, key.ContentHash,
+	).Scan(&blob)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read embedding: %w", err)
+	}
+
+	vector, err := decodeVector(blob)
+	if err != nil {
+		return nil, false, err
+	}
+	return vector, true, nil
+}
+
+// Put 实现memory.EmbeddingCacheStore接口
+func (s *SQLiteEmbeddingCacheStore) Put(ctx context.Context, key memory.EmbeddingCacheKey, vector []float32) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO embeddings (provider, model, content_hash, vector) VALUES (?, ?, ?, ?)`,
+		key.Provider, key.Model, key.ContentHash, encodeVector(vector),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save embedding: %w", err)
+	}
+	return nil
+}
+
+// Count 返回持久化的向量数量
+func (s *SQLiteEmbeddingCacheStore) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM embeddings`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count embeddings: %w", err)
+	}
+	return count, nil
+}
+
+// Close 实现memory.EmbeddingCacheStore接口
+func (s *SQLiteEmbeddingCacheStore) Close() error {
+	return s.db.Close()
+}
+
+// encodeVector 把向量编码为小端float32字节序列
+func encodeVector(vector []float32) []byte {
+	blob := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(blob[4*i:], math.Float32bits(v))
+	}
+	return blob
+}
+
+// decodeVector 解码encodeVector生成的字节序列
+func decodeVector(blob []byte) ([]float32, error) {
+	if len(blob)%4 != 0 {
+		return nil, fmt.Errorf("invalid embedding blob length %d", len(blob))
+	}
+	vector := make([]float32, len(blob)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[4*i:]))
+	}
+	return vector, nil
+}
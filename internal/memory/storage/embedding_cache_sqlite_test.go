@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/memory"
+)
+
+func TestSQLiteEmbeddingCacheStorePersistsAcrossCaches(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), EmbeddingCacheDBFileName)
+	ctx := context.Background()
+	texts := []string{"goroutines", "channels"}
+
+	store, err := NewSQLiteEmbeddingCacheStore(dbPath)
+	require.NoError(t, err)
+	cache := memory.NewEmbeddingCache(&memory.EmbeddingCacheConfig{Store: store})
+	first, err := memory.NewCachedEmbedder(memory.NewHashEmbedder(32), cache).Embed(ctx, texts)
+	require.NoError(t, err)
+	require.NoError(t, cache.Close())
+
+	// 新进程：内存LRU为空，向量从SQLite读取
+	store, err = NewSQLiteEmbeddingCacheStore(dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+	cache = memory.NewEmbeddingCache(&memory.EmbeddingCacheConfig{Store: store})
+	second, err := memory.NewCachedEmbedder(memory.NewHashEmbedder(32), cache).Embed(ctx, texts)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, int64(2), cache.Stats().Hits)
+
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// 另一个模型（维度不同）的向量单独保存，不影响已有模型的向量
+	cache = memory.NewEmbeddingCache(&memory.EmbeddingCacheConfig{Store: store})
+	_, err = memory.NewCachedEmbedder(memory.NewHashEmbedder(64), cache).Embed(ctx, texts[:1])
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cache.Stats().Hits)
+
+	third, err := memory.NewCachedEmbedder(memory.NewHashEmbedder(32), cache).Embed(ctx, texts)
+	require.NoError(t, err)
+	assert.Equal(t, first, third)
+	assert.Equal(t, int64(2), cache.Stats().Hits)
+
+	count, err = store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
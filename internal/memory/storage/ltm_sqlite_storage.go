@@ -4,9 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,19 +19,54 @@ import (
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
+// LTMDBFileName 长期记忆数据库文件名
+const LTMDBFileName = "long_term_memory.db"
+
+// ltmBusyTimeoutMs 等待其他连接释放写锁的最长时间（毫秒）
+const ltmBusyTimeoutMs = 5000
+
+// ErrStorageNotInitialized 数据库未能成功打开时返回
+var ErrStorageNotInitialized = errors.New("SQLite storage is not initialized")
+
+// DefaultLTMDBPath 返回默认的长期记忆数据库路径（位于memory.StorageDir()下）
+func DefaultLTMDBPath() string {
+	return filepath.Join(memory.StorageDir(), LTMDBFileName)
+}
+
+// RemoveLTMDatabase 删除长期记忆数据库文件及其WAL/SHM附属文件，返回实际删除的文件
+func RemoveLTMDatabase(dbPath string) ([]string, error) {
+	if dbPath == "" {
+		dbPath = DefaultLTMDBPath()
+	}
+
+	removed := make([]string, 0, 3)
+	for _, path := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
 // LTMSQLiteStorage 长期记忆SQLite存储实现
+// 使用WAL模式和busy_timeout，同一crew中的多个agent（甚至多个进程）可以同时写入
 type LTMSQLiteStorage struct {
-	dbPath string
-	db     *sql.DB
-	logger logger.Logger
-	mu     sync.RWMutex // 读写锁保护并发访问
+	dbPath     string
+	db         *sql.DB
+	logger     logger.Logger
+	ftsEnabled bool
+	mu         sync.RWMutex // 读写锁保护并发访问
 }
 
 // NewLTMSQLiteStorage 创建SQLite存储实例
 func NewLTMSQLiteStorage(dbPath string, log logger.Logger) *LTMSQLiteStorage {
 	if dbPath == "" {
 		// 使用默认路径
-		dbPath = filepath.Join(".", "data", "long_term_memory.db")
+		dbPath = DefaultLTMDBPath()
 	}
 
 	storage := &LTMSQLiteStorage{
@@ -53,11 +92,18 @@ func (s *LTMSQLiteStorage) initialize() error {
 	}
 
 	// 打开数据库连接
-	db, err := sql.Open("sqlite3", s.dbPath)
+	// WAL允许读写并发；busy_timeout让写入在锁被占用时等待而不是立即返回"database is locked"；
+	// _txlock=immediate让事务在开始时就获取写锁，避免读锁升级为写锁时的死锁
+	dsn := fmt.Sprintf("%s?_busy_timeout=%d&_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate",
+		s.dbPath, ltmBusyTimeoutMs)
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 
+	// SQLite同一时间只允许一个写入者，单连接让本进程内的写入排队而不是互相竞争
+	db.SetMaxOpenConns(1)
+
 	// 测试连接
 	if err := db.Ping(); err != nil {
 		if err := db.Close(); err != nil {
@@ -88,7 +134,7 @@ func (s *LTMSQLiteStorage) createTables() error {
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		score REAL DEFAULT 0.0
 	);
-	
+
 	CREATE INDEX IF NOT EXISTS idx_agent ON long_term_memories(agent);
 	CREATE INDEX IF NOT EXISTS idx_created_at ON long_term_memories(created_at);
 	CREATE INDEX IF NOT EXISTS idx_score ON long_term_memories(score);
@@ -111,16 +157,16 @@ func (s *LTMSQLiteStorage) tryCreateFTSTables() {
 	CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts5(
 		id, value, metadata, agent, content='long_term_memories', content_rowid='rowid'
 	);
-	
+
 	-- 创建触发器以保持FTS表同步
 	CREATE TRIGGER IF NOT EXISTS memories_ai AFTER INSERT ON long_term_memories BEGIN
 		INSERT INTO memories_fts(id, value, metadata, agent) VALUES (new.id, new.value, new.metadata, new.agent);
 	END;
-	
+
 	CREATE TRIGGER IF NOT EXISTS memories_ad AFTER DELETE ON long_term_memories BEGIN
 		DELETE FROM memories_fts WHERE id = old.id;
 	END;
-	
+
 	CREATE TRIGGER IF NOT EXISTS memories_au AFTER UPDATE ON long_term_memories BEGIN
 		DELETE FROM memories_fts WHERE id = old.id;
 		INSERT INTO memories_fts(id, value, metadata, agent) VALUES (new.id, new.value, new.metadata, new.agent);
@@ -132,26 +178,44 @@ func (s *LTMSQLiteStorage) tryCreateFTSTables() {
 			logger.Field{Key: "error", Value: err.Error()},
 		)
 	} else {
+		s.ftsEnabled = true
 		s.logger.Info("FTS5 full-text search enabled")
 	}
 }
 
+// DBPath 返回数据库文件路径
+func (s *LTMSQLiteStorage) DBPath() string {
+	return s.dbPath
+}
+
 // Save 保存记忆项到SQLite
 func (s *LTMSQLiteStorage) Save(ctx context.Context, item memory.MemoryItem) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.db == nil {
+		return ErrStorageNotInitialized
+	}
+
 	// 序列化元数据
 	metadataJSON, err := json.Marshal(item.Metadata)
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	// 将值转换为字符串
-	valueStr := fmt.Sprintf("%v", item.Value)
+	// 将值转换为字符串，非字符串值保存为JSON以便读取时还原
+	valueStr, err := encodeValue(item.Value)
+	if err != nil {
+		return err
+	}
+
+	createdAt := item.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
 
 	insertSQL := `
-	INSERT OR REPLACE INTO long_term_memories 
+	INSERT OR REPLACE INTO long_term_memories
 	(id, value, metadata, agent, created_at, score)
 	VALUES (?, ?, ?, ?, ?, ?)
 	`
@@ -161,7 +225,7 @@ func (s *LTMSQLiteStorage) Save(ctx context.Context, item memory.MemoryItem) err
 		valueStr,
 		string(metadataJSON),
 		item.Agent,
-		item.CreatedAt.Format(time.RFC3339),
+		createdAt.Format(time.RFC3339Nano),
 		item.Score,
 	)
 
@@ -179,66 +243,45 @@ func (s *LTMSQLiteStorage) Save(ctx context.Context, item memory.MemoryItem) err
 
 // Search 搜索记忆项
 func (s *LTMSQLiteStorage) Search(ctx context.Context, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	return s.SearchWithFilter(ctx, query, nil, limit, scoreThreshold)
+}
+
+// SearchWithFilter 按关键词和元数据过滤搜索记忆项
+// filter中的"agent"键匹配agent列，其他键匹配metadata中同名字段的值；
+// query为空时只按过滤条件返回最近的记忆
+func (s *LTMSQLiteStorage) SearchWithFilter(ctx context.Context, query string, filter map[string]interface{}, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// 确保总是返回非nil的slice
-	results := make([]memory.MemoryItem, 0)
-
-	// 使用FTS进行全文搜索
-	searchSQL := `
-	SELECT m.id, m.value, m.metadata, m.agent, m.created_at, m.score
-	FROM memories_fts fts
-	JOIN long_term_memories m ON fts.id = m.id
-	WHERE memories_fts MATCH ? AND m.score >= ?
-	ORDER BY bm25(memories_fts) ASC, m.score DESC, m.created_at DESC
-	LIMIT ?
-	`
+	if s.db == nil {
+		return nil, ErrStorageNotInitialized
+	}
+	if limit <= 0 {
+		limit = 3
+	}
 
-	rows, err := s.db.QueryContext(ctx, searchSQL, query, scoreThreshold, limit)
+	filterSQL, filterArgs, err := buildFilterClause(filter)
 	if err != nil {
-		// 如果FTS搜索失败，尝试简单的LIKE搜索
-		return s.simpleLikeSearch(ctx, query, limit, scoreThreshold)
+		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var item memory.MemoryItem
-		var metadataJSON, createdAtStr string
-
-		err := rows.Scan(
-			&item.ID,
-			&item.Value,
-			&metadataJSON,
-			&item.Agent,
-			&createdAtStr,
-			&item.Score,
-		)
-		if err != nil {
-			s.logger.Error("failed to scan row", logger.Field{Key: "error", Value: err})
-			continue
-		}
 
-		// 反序列化元数据
-		if metadataJSON != "" {
-			if err := json.Unmarshal([]byte(metadataJSON), &item.Metadata); err != nil {
-				s.logger.Error("failed to unmarshal metadata", logger.Field{Key: "error", Value: err})
-				item.Metadata = make(map[string]interface{})
-			}
-		} else {
-			item.Metadata = make(map[string]interface{})
-		}
+	terms := searchTerms(query)
 
-		// 解析时间
-		if createdAt, err := time.Parse(time.RFC3339, createdAtStr); err == nil {
-			item.CreatedAt = createdAt
+	var results []memory.MemoryItem
+	if s.ftsEnabled && len(terms) > 0 {
+		results, err = s.ftsSearch(ctx, terms, filterSQL, filterArgs, limit, scoreThreshold)
+		if err != nil {
+			// 如果FTS搜索失败，尝试简单的LIKE搜索
+			s.logger.Debug("FTS search failed, falling back to LIKE search",
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+			results, err = s.likeSearch(ctx, terms, filterSQL, filterArgs, limit, scoreThreshold)
 		}
-
-		results = append(results, item)
+	} else {
+		results, err = s.likeSearch(ctx, terms, filterSQL, filterArgs, limit, scoreThreshold)
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
+	if err != nil {
+		return nil, err
 	}
 
 	s.logger.Debug("SQLite search completed",
@@ -249,64 +292,126 @@ func (s *LTMSQLiteStorage) Search(ctx context.Context, query string, limit int,
 	return results, nil
 }
 
-// simpleLikeSearch 简单的LIKE搜索作为后备方案
-func (s *LTMSQLiteStorage) simpleLikeSearch(ctx context.Context, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// ftsSearch 使用FTS5进行全文搜索，调用方需持有读锁
+func (s *LTMSQLiteStorage) ftsSearch(ctx context.Context, terms []string, filterSQL string, filterArgs []interface{}, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	// 每个词加引号避免FTS语法错误，任一词命中即可，bm25负责相关性排序
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
 
-	results := make([]memory.MemoryItem, 0)
+	searchSQL := `
+	SELECT m.id, m.value, m.metadata, m.agent, m.created_at, m.score
+	FROM memories_fts fts
+	JOIN long_term_memories m ON fts.id = m.id
+	WHERE memories_fts MATCH ? AND m.score >= ?` + filterSQL + `
+	ORDER BY bm25(memories_fts) ASC, m.score DESC, m.created_at DESC
+	LIMIT ?
+	`
+
+	args := []interface{}{strings.Join(quoted, " OR "), scoreThreshold}
+	args = append(args, filterArgs...)
+	args = append(args, limit)
+
+	return s.queryItems(ctx, searchSQL, args...)
+}
+
+// likeSearch 按词LIKE匹配作为后备方案，命中词越多越靠前，调用方需持有读锁
+func (s *LTMSQLiteStorage) likeSearch(ctx context.Context, terms []string, filterSQL string, filterArgs []interface{}, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	// matchSQL统计命中的词数，WHERE和ORDER BY各引用一次
+	var matchSQL string
+	var termArgs []interface{}
+	if len(terms) > 0 {
+		matchParts := make([]string, len(terms))
+		for i, term := range terms {
+			matchParts[i] = `(m.value LIKE ? ESCAPE '\' OR m.agent LIKE ? ESCAPE '\' OR m.metadata LIKE ? ESCAPE '\')`
+			like := "%" + escapeLike(term) + "%"
+			termArgs = append(termArgs, like, like, like)
+		}
+		matchSQL = strings.Join(matchParts, " + ")
+	}
 
 	searchSQL := `
-	SELECT id, value, metadata, agent, created_at, score
-	FROM long_term_memories
-	WHERE (value LIKE ? OR agent LIKE ? OR metadata LIKE ?) AND score >= ?
-	ORDER BY score DESC, created_at DESC
+	SELECT m.id, m.value, m.metadata, m.agent, m.created_at, m.score
+	FROM long_term_memories m
+	WHERE m.score >= ?` + filterSQL
+	args := []interface{}{scoreThreshold}
+	args = append(args, filterArgs...)
+	orderSQL := "m.score DESC, m.created_at DESC"
+	if len(terms) > 0 {
+		searchSQL += ` AND (` + matchSQL + `) > 0`
+		args = append(args, termArgs...)
+		orderSQL = "(" + matchSQL + ") DESC, " + orderSQL
+		args = append(args, termArgs...)
+	}
+	searchSQL += `
+	ORDER BY ` + orderSQL + `
 	LIMIT ?
 	`
+	args = append(args, limit)
 
-	likeQuery := "%" + query + "%"
-	rows, err := s.db.QueryContext(ctx, searchSQL, likeQuery, likeQuery, likeQuery, scoreThreshold, limit)
+	items, err := s.queryItems(ctx, searchSQL, args...)
 	if err != nil {
 		return nil, fmt.Errorf("simple search failed: %w", err)
 	}
+	return items, nil
+}
+
+// queryItems 执行查询并把结果行转换为记忆项
+func (s *LTMSQLiteStorage) queryItems(ctx context.Context, query string, args ...interface{}) ([]memory.MemoryItem, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
+	// 确保总是返回非nil的slice
+	results := make([]memory.MemoryItem, 0)
 	for rows.Next() {
 		var item memory.MemoryItem
-		var metadataJSON, createdAtStr string
-
-		err := rows.Scan(
-			&item.ID,
-			&item.Value,
-			&metadataJSON,
-			&item.Agent,
-			&createdAtStr,
-			&item.Score,
-		)
-		if err != nil {
+		var value string
+		var metadataJSON, agent sql.NullString
+		var createdAtStr string
+
+		if err := rows.Scan(&item.ID, &value, &metadataJSON, &agent, &createdAtStr, &item.Score); err != nil {
+			s.logger.Error("failed to scan row", logger.Field{Key: "error", Value: err})
 			continue
 		}
+		item.Value = value
+		item.Agent = agent.String
 
 		// 反序列化元数据
-		if metadataJSON != "" {
-			json.Unmarshal([]byte(metadataJSON), &item.Metadata)
-		} else {
-			item.Metadata = make(map[string]interface{})
+		item.Metadata = make(map[string]interface{})
+		if metadataJSON.String != "" && metadataJSON.String != "null" {
+			if err := json.Unmarshal([]byte(metadataJSON.String), &item.Metadata); err != nil {
+				s.logger.Error("failed to unmarshal metadata", logger.Field{Key: "error", Value: err})
+				item.Metadata = make(map[string]interface{})
+			}
 		}
 
 		// 解析时间
-		if createdAt, err := time.Parse(time.RFC3339, createdAtStr); err == nil {
+		if createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr); err == nil {
 			item.CreatedAt = createdAt
 		}
 
 		results = append(results, item)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
 	return results, nil
 }
 
 // Delete 删除记忆项
 func (s *LTMSQLiteStorage) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return ErrStorageNotInitialized
+	}
+
 	deleteSQL := `DELETE FROM long_term_memories WHERE id = ?`
 
 	result, err := s.db.ExecContext(ctx, deleteSQL, id)
@@ -330,25 +435,86 @@ func (s *LTMSQLiteStorage) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteWithFilter 删除满足过滤条件的记忆项，返回删除的数量
+// 过滤条件与SearchWithFilter相同；filter不能为空，清除全部记忆请使用Clear
+func (s *LTMSQLiteStorage) DeleteWithFilter(ctx context.Context, filter map[string]interface{}) (int, error) {
+	if len(filter) == 0 {
+		return 0, errors.New("delete filter must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return 0, ErrStorageNotInitialized
+	}
+
+	filterSQL, filterArgs, err := buildFilterClause(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	deleteSQL := `DELETE FROM long_term_memories WHERE id IN (
+		SELECT m.id FROM long_term_memories m WHERE 1 = 1` + filterSQL + `
+	)`
+	result, err := s.db.ExecContext(ctx, deleteSQL, filterArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete memories: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	s.logger.Debug("memory items deleted from SQLite",
+		logger.Field{Key: "deleted_count", Value: deleted},
+	)
+
+	return int(deleted), nil
+}
+
+// CountWithFilter 统计满足过滤条件的记忆项数量，filter为空时统计全部
+func (s *LTMSQLiteStorage) CountWithFilter(ctx context.Context, filter map[string]interface{}) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return 0, ErrStorageNotInitialized
+	}
+
+	filterSQL, filterArgs, err := buildFilterClause(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	countSQL := `SELECT COUNT(*) FROM long_term_memories m WHERE 1 = 1` + filterSQL
+	if err := s.db.QueryRowContext(ctx, countSQL, filterArgs...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count memories: %w", err)
+	}
+	return count, nil
+}
+
 // Clear 清除所有记忆项
 func (s *LTMSQLiteStorage) Clear(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 获取删除前的数量
-	var count int
-	countSQL := `SELECT COUNT(*) FROM long_term_memories`
-	s.db.QueryRowContext(ctx, countSQL).Scan(&count)
+	if s.db == nil {
+		return ErrStorageNotInitialized
+	}
 
 	// 删除所有记录
 	clearSQL := `DELETE FROM long_term_memories`
-	_, err := s.db.ExecContext(ctx, clearSQL)
+	result, err := s.db.ExecContext(ctx, clearSQL)
 	if err != nil {
 		return fmt.Errorf("failed to clear memories: %w", err)
 	}
 
+	deleted, _ := result.RowsAffected()
 	s.logger.Info("SQLite storage cleared",
-		logger.Field{Key: "deleted_count", Value: count},
+		logger.Field{Key: "deleted_count", Value: deleted},
 	)
 
 	return nil
@@ -361,23 +527,34 @@ func (s *LTMSQLiteStorage) Close() error {
 
 	if s.db != nil {
 		s.logger.Info("closing SQLite storage")
-		return s.db.Close()
+		err := s.db.Close()
+		s.db = nil
+		return err
 	}
 	return nil
 }
 
 // GetStats 获取存储统计信息
 func (s *LTMSQLiteStorage) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return nil, ErrStorageNotInitialized
+	}
+
 	stats := make(map[string]interface{})
 
 	// 总记录数
 	var totalCount int
-	s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM long_term_memories`).Scan(&totalCount)
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM long_term_memories`).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to count memories: %w", err)
+	}
 	stats["total_memories"] = totalCount
 
 	// 按agent统计
 	agentStats := make(map[string]int)
-	rows, err := s.db.QueryContext(ctx, `SELECT agent, COUNT(*) FROM long_term_memories GROUP BY agent`)
+	rows, err := s.db.QueryContext(ctx, `SELECT COALESCE(agent, ''), COUNT(*) FROM long_term_memories GROUP BY agent`)
 	if err == nil {
 		for rows.Next() {
 			var agent string
@@ -393,22 +570,31 @@ func (s *LTMSQLiteStorage) GetStats(ctx context.Context) (map[string]interface{}
 	// 最近创建的记忆数量（24小时内）
 	var recentCount int
 	s.db.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM long_term_memories WHERE created_at > datetime('now', '-24 hours')`).Scan(&recentCount)
+		`SELECT COUNT(*) FROM long_term_memories WHERE created_at > ?`,
+		time.Now().Add(-24*time.Hour).Format(time.RFC3339Nano)).Scan(&recentCount)
 	stats["recent_created"] = recentCount
 
 	// 平均分数
-	var avgScore float64
+	var avgScore sql.NullFloat64
 	s.db.QueryRowContext(ctx, `SELECT AVG(score) FROM long_term_memories WHERE score > 0`).Scan(&avgScore)
-	stats["average_score"] = avgScore
+	stats["average_score"] = avgScore.Float64
 
 	// 数据库信息
 	stats["db_path"] = s.dbPath
+	stats["fts_enabled"] = s.ftsEnabled
 
 	return stats, nil
 }
 
 // Vacuum 压缩数据库
 func (s *LTMSQLiteStorage) Vacuum(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return ErrStorageNotInitialized
+	}
+
 	_, err := s.db.ExecContext(ctx, `VACUUM`)
 	if err != nil {
 		return fmt.Errorf("failed to vacuum database: %w", err)
@@ -417,3 +603,89 @@ func (s *LTMSQLiteStorage) Vacuum(ctx context.Context) error {
 	s.logger.Info("database vacuumed successfully")
 	return nil
 }
+
+// encodeValue 把记忆值转换为可存储的文本
+func encodeValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case nil:
+		return "", nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal memory value: %w", err)
+	}
+	return string(data), nil
+}
+
+// metadataKeyPattern 限制可用于过滤的metadata键，键会被拼入JSON路径
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// buildFilterClause 把过滤条件转换为SQL条件片段（以" AND "开头）及其参数
+func buildFilterClause(filter map[string]interface{}) (string, []interface{}, error) {
+	if len(filter) == 0 {
+		return "", nil, nil
+	}
+
+	// 按键排序保证生成的SQL稳定
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var clause strings.Builder
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value := filter[key]
+		if key == "agent" {
+			clause.WriteString(" AND m.agent = ?")
+			args = append(args, fmt.Sprintf("%v", value))
+			continue
+		}
+
+		if !metadataKeyPattern.MatchString(key) {
+			return "", nil, fmt.Errorf("invalid metadata filter key: %q", key)
+		}
+		clause.WriteString(" AND json_extract(m.metadata, '$." + key + "') = ?")
+
+		// json_extract对布尔值返回0/1
+		if b, ok := value.(bool); ok {
+			if b {
+				value = 1
+			} else {
+				value = 0
+			}
+		}
+		args = append(args, value)
+	}
+	return clause.String(), args, nil
+}
+
+// searchTerms 把查询拆分为去重后的关键词，忽略过短的词
+func searchTerms(query string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r > 127)
+	})
+
+	seen := make(map[string]bool, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) < 2 || seen[field] {
+			continue
+		}
+		seen[field] = true
+		terms = append(terms, field)
+	}
+	return terms
+}
+
+// escapeLike 转义LIKE模式中的通配符
+func escapeLike(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(term)
+}
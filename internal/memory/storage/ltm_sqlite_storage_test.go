@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/memory"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newTestLTMStorage(t *testing.T, dbPath string) *LTMSQLiteStorage {
+	t.Helper()
+	storage := NewLTMSQLiteStorage(dbPath, logger.NewTestLogger())
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func TestLTMSQLiteStorageSaveAndSearch(t *testing.T) {
+	storage := newTestLTMStorage(t, filepath.Join(t.TempDir(), LTMDBFileName))
+	ctx := context.Background()
+
+	items := []memory.MemoryItem{
+		{ID: "1", Value: "Go concurrency patterns with channels", Agent: "researcher", Score: 0.9,
+			Metadata: map[string]interface{}{"topic": "go"}, CreatedAt: time.Now()},
+		{ID: "2", Value: "Python asyncio event loops", Agent: "researcher", Score: 0.5,
+			Metadata: map[string]interface{}{"topic": "python"}, CreatedAt: time.Now()},
+		{ID: "3", Value: map[string]interface{}{"task": "write go report", "quality": 8.0}, Agent: "writer",
+			Metadata: map[string]interface{}{"topic": "go", "approved": true}, CreatedAt: time.Now()},
+	}
+	for _, item := range items {
+		require.NoError(t, storage.Save(ctx, item))
+	}
+
+	// 多个关键词任一命中即可，命中越多越靠前
+	results, err := storage.Search(ctx, "go channels", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "1", results[0].ID)
+	assert.Equal(t, "go", results[0].Metadata["topic"])
+
+	// 非字符串值以JSON保存
+	assert.JSONEq(t, `{"task":"write go report","quality":8}`, results[1].Value.(string))
+
+	// 分数阈值
+	results, err = storage.Search(ctx, "asyncio", 10, 0.6)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// LIKE通配符按字面匹配
+	results, err = storage.Search(ctx, "100%", 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestLTMSQLiteStorageSearchWithFilter(t *testing.T) {
+	storage := newTestLTMStorage(t, filepath.Join(t.TempDir(), LTMDBFileName))
+	ctx := context.Background()
+
+	for i, agent := range []string{"researcher", "writer", "researcher"} {
+		require.NoError(t, storage.Save(ctx, memory.MemoryItem{
+			ID:        fmt.Sprintf("item-%d", i),
+			Value:     fmt.Sprintf("report draft %d", i),
+			Agent:     agent,
+			Metadata:  map[string]interface{}{"task": fmt.Sprintf("task-%d", i), "approved": i != 1},
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	results, err := storage.SearchWithFilter(ctx, "report", map[string]interface{}{"agent": "researcher"}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, item := range results {
+		assert.Equal(t, "researcher", item.Agent)
+	}
+
+	results, err = storage.SearchWithFilter(ctx, "", map[string]interface{}{"task": "task-1"}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "item-1", results[0].ID)
+
+	results, err = storage.SearchWithFilter(ctx, "", map[string]interface{}{"approved": true}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	// 空查询按创建时间倒序返回
+	results, err = storage.SearchWithFilter(ctx, "", nil, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "item-2", results[0].ID)
+
+	_, err = storage.SearchWithFilter(ctx, "", map[string]interface{}{"bad') OR 1=1 --": "x"}, 10, 0)
+	assert.Error(t, err)
+}
+
+func TestLTMSQLiteStorageConcurrentWrites(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), LTMDBFileName)
+
+	// 同一crew中的agent可能各自持有存储实例，指向同一个数据库文件
+	storages := []*LTMSQLiteStorage{
+		newTestLTMStorage(t, dbPath),
+		newTestLTMStorage(t, dbPath),
+		newTestLTMStorage(t, dbPath),
+	}
+	ctx := context.Background()
+
+	const writers, writesPerWriter = 12, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*writesPerWriter*2)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			storage := storages[w%len(storages)]
+			for i := 0; i < writesPerWriter; i++ {
+				err := storage.Save(ctx, memory.MemoryItem{
+					ID:        fmt.Sprintf("agent-%d-%d", w, i),
+					Value:     fmt.Sprintf("result %d from agent %d", i, w),
+					Agent:     fmt.Sprintf("agent-%d", w),
+					Metadata:  map[string]interface{}{"iteration": i},
+					CreatedAt: time.Now(),
+				})
+				if err != nil {
+					errs <- err
+				}
+				if _, err := storage.Search(ctx, "result", 5, 0); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent access failed: %v", err)
+	}
+
+	stats, err := storages[0].GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, writers*writesPerWriter, stats["total_memories"])
+}
+
+func TestLTMSQLiteStorageDeleteAndClear(t *testing.T) {
+	storage := newTestLTMStorage(t, filepath.Join(t.TempDir(), LTMDBFileName))
+	ctx := context.Background()
+
+	require.NoError(t, storage.Save(ctx, memory.MemoryItem{ID: "a", Value: "alpha"}))
+	require.NoError(t, storage.Save(ctx, memory.MemoryItem{ID: "b", Value: "beta"}))
+
+	require.NoError(t, storage.Delete(ctx, "a"))
+	assert.Error(t, storage.Delete(ctx, "a"))
+
+	require.NoError(t, storage.Clear(ctx))
+	results, err := storage.Search(ctx, "", 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// 关闭后的操作返回错误而不是panic
+	require.NoError(t, storage.Close())
+	assert.ErrorIs(t, storage.Save(ctx, memory.MemoryItem{ID: "c", Value: "gamma"}), ErrStorageNotInitialized)
+	_, err = storage.Search(ctx, "gamma", 1, 0)
+	assert.ErrorIs(t, err, ErrStorageNotInitialized)
+}
+
+func TestLTMSQLiteStorageDeleteAndCountWithFilter(t *testing.T) {
+	storage := newTestLTMStorage(t, filepath.Join(t.TempDir(), LTMDBFileName))
+	ctx := context.Background()
+
+	for i, agent := range []string{"researcher", "writer", "researcher"} {
+		require.NoError(t, storage.Save(ctx, memory.MemoryItem{
+			ID:    fmt.Sprintf("item-%d", i),
+			Value: fmt.Sprintf("note %d", i),
+			Agent: agent,
+		}))
+	}
+
+	count, err := storage.CountWithFilter(ctx, map[string]interface{}{"agent": "researcher"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	deleted, err := storage.DeleteWithFilter(ctx, map[string]interface{}{"agent": "researcher"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	count, err = storage.CountWithFilter(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// 空过滤条件不会清除全部记忆
+	_, err = storage.DeleteWithFilter(ctx, nil)
+	assert.Error(t, err)
+}
+
+func TestRemoveLTMDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), LTMDBFileName)
+
+	storage := NewLTMSQLiteStorage(dbPath, logger.NewTestLogger())
+	require.NoError(t, storage.Save(context.Background(), memory.MemoryItem{ID: "a", Value: "alpha"}))
+	require.NoError(t, storage.Close())
+
+	removed, err := RemoveLTMDatabase(dbPath)
+	require.NoError(t, err)
+	assert.Contains(t, removed, dbPath)
+	_, err = os.Stat(dbPath)
+	assert.True(t, os.IsNotExist(err))
+
+	// 再次删除不报错
+	removed, err = RemoveLTMDatabase(dbPath)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestDefaultLTMDBPath(t *testing.T) {
+	t.Setenv(memory.StorageDirEnv, "/tmp/greensoulai-storage")
+	assert.Equal(t, filepath.Join("/tmp/greensoulai-storage", LTMDBFileName), DefaultLTMDBPath())
+
+	t.Setenv(memory.StorageDirEnv, "")
+	assert.Equal(t, filepath.Join(memory.DefaultStorageDir, LTMDBFileName), DefaultLTMDBPath())
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/ynl/greensoulai/internal/llm"
 	"github.com/ynl/greensoulai/internal/memory"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
@@ -49,7 +50,7 @@ func NewMem0Storage(storageType string, crew interface{}, config map[string]inte
 		config:      config,
 		logger:      logger,
 		infer:       true, // 默认启用推理
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		httpClient:  llm.NewSharedHTTPClient(30 * time.Second),
 	}
 
 	// 验证存储类型
@@ -26,23 +26,36 @@ type RAGStorage struct {
 	mu    sync.RWMutex
 
 	// 向量存储相关
+	embedder   memory.Embedder
+	vectors    map[string][]float32 // 记忆项ID -> 嵌入向量
 	vectorDim  int
 	indexBuilt bool
 }
 
 // NewRAGStorage 创建RAG存储实例
-func NewRAGStorage(storageType string, embedderConfig *memory.EmbedderConfig, crew interface{}, path string, logger logger.Logger) *RAGStorage {
+func NewRAGStorage(storageType string, embedderConfig *memory.EmbedderConfig, crew interface{}, path string, log logger.Logger) *RAGStorage {
 	storage := &RAGStorage{
 		storageType:    storageType,
 		embedderConfig: embedderConfig,
 		crew:           crew,
 		path:           path,
-		logger:         logger,
+		logger:         log,
 		items:          make([]memory.MemoryItem, 0),
+		vectors:        make(map[string][]float32),
 		vectorDim:      384, // 默认向量维度
 		indexBuilt:     false,
 	}
 
+	// 根据嵌入器配置创建嵌入器，不可用时回退到关键词检索
+	embedder, err := memory.NewEmbedder(embedderConfig)
+	if err != nil {
+		storage.logger.Warn("embedder unavailable, falling back to keyword search",
+			logger.Field{Key: "type", Value: storageType},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+	storage.embedder = embedder
+
 	// 初始化存储
 	storage.initialize()
 
@@ -68,14 +81,30 @@ func (rs *RAGStorage) initialize() error {
 
 // Save 保存记忆项到存储
 func (rs *RAGStorage) Save(ctx context.Context, item memory.MemoryItem) error {
+	// 在锁外生成向量表示，嵌入模型调用可能较慢
+	var vector []float32
+	if rs.embedder != nil {
+		vectors, err := rs.embedder.Embed(ctx, []string{memoryText(item.Value)})
+		if err != nil {
+			return fmt.Errorf("failed to embed memory item: %w", err)
+		}
+		if len(vectors) != 1 {
+			return fmt.Errorf("embedder returned %d vectors for 1 text", len(vectors))
+		}
+		vector = vectors[0]
+	}
+
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
-	// 生成向量表示（简化实现，实际应使用嵌入模型）
 	item.Score = 0.0 // 初始分数
 
 	// 添加到内存存储
 	rs.items = append(rs.items, item)
+	if vector != nil {
+		rs.vectors[item.ID] = vector
+		rs.vectorDim = len(vector)
+	}
 
 	rs.logger.Debug("memory item saved to RAG storage",
 		logger.Field{Key: "id", Value: item.ID},
@@ -90,16 +119,49 @@ func (rs *RAGStorage) Save(ctx context.Context, item memory.MemoryItem) error {
 
 // Search 搜索记忆项
 func (rs *RAGStorage) Search(ctx context.Context, query string, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	return rs.SearchWithFilter(ctx, query, nil, limit, scoreThreshold)
+}
+
+// SearchWithFilter 搜索满足元数据过滤条件的记忆项
+// 配置了嵌入器时按查询向量与记忆向量的余弦相似度排序，否则按关键词相关性排序；
+// 只返回分数不低于scoreThreshold的记忆。filter中的"agent"键匹配agent字段，其他键匹配metadata；
+// query为空时不计算相关性，按创建时间倒序返回满足过滤条件的记忆
+func (rs *RAGStorage) SearchWithFilter(ctx context.Context, query string, filter map[string]interface{}, limit int, scoreThreshold float64) ([]memory.MemoryItem, error) {
+	// 在锁外计算查询向量
+	var queryVector []float32
+	if rs.embedder != nil && query != "" {
+		vectors, err := rs.embedder.Embed(ctx, []string{query})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+		if len(vectors) != 1 {
+			return nil, fmt.Errorf("embedder returned %d vectors for 1 text", len(vectors))
+		}
+		queryVector = vectors[0]
+	}
+
 	rs.mu.RLock()
 	defer rs.mu.RUnlock()
 
-	// 简化的搜索实现（实际应使用向量搜索）
-	var results []memory.MemoryItem
+	results := make([]memory.MemoryItem, 0)
 	queryLower := strings.ToLower(query)
 
 	for _, item := range rs.items {
-		// 简单的文本匹配
-		score := rs.calculateRelevanceScore(item, queryLower)
+		if !memory.MatchesFilter(item, filter) {
+			continue
+		}
+		if query == "" {
+			results = append(results, item)
+			continue
+		}
+
+		var score float64
+		if queryVector != nil {
+			score = memory.CosineSimilarity(queryVector, rs.vectors[item.ID])
+		} else {
+			// 简单的文本匹配
+			score = rs.calculateRelevanceScore(item, queryLower)
+		}
 
 		if score >= scoreThreshold {
 			item.Score = score
@@ -107,11 +169,16 @@ func (rs *RAGStorage) Search(ctx context.Context, query string, limit int, score
 		}
 	}
 
-	// 按分数排序（简化实现）
-	rs.sortByScore(results)
+	if query == "" {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].CreatedAt.After(results[j].CreatedAt)
+		})
+	} else {
+		rs.sortByScore(results)
+	}
 
 	// 限制结果数量
-	if len(results) > limit {
+	if limit >= 0 && len(results) > limit {
 		results = results[:limit]
 	}
 
@@ -119,6 +186,7 @@ func (rs *RAGStorage) Search(ctx context.Context, query string, limit int, score
 		logger.Field{Key: "query", Value: query},
 		logger.Field{Key: "results_count", Value: len(results)},
 		logger.Field{Key: "type", Value: rs.storageType},
+		logger.Field{Key: "semantic", Value: queryVector != nil},
 	)
 
 	return results, nil
@@ -133,6 +201,7 @@ func (rs *RAGStorage) Delete(ctx context.Context, id string) error {
 		if item.ID == id {
 			// 从切片中删除
 			rs.items = append(rs.items[:i], rs.items[i+1:]...)
+			delete(rs.vectors, id)
 			rs.logger.Debug("memory item deleted from RAG storage",
 				logger.Field{Key: "id", Value: id},
 			)
@@ -150,6 +219,7 @@ func (rs *RAGStorage) Clear(ctx context.Context) error {
 
 	count := len(rs.items)
 	rs.items = make([]memory.MemoryItem, 0)
+	rs.vectors = make(map[string][]float32)
 	rs.indexBuilt = false
 
 	rs.logger.Info("RAG storage cleared",
@@ -236,7 +306,7 @@ func (rs *RAGStorage) calculateRelevanceScore(item memory.MemoryItem, queryLower
 
 // sortByScore 按分数排序（高效实现）
 func (rs *RAGStorage) sortByScore(items []memory.MemoryItem) {
-	sort.Slice(items, func(i, j int) bool {
+	sort.SliceStable(items, func(i, j int) bool {
 		return items[i].Score > items[j].Score
 	})
 }
@@ -250,7 +320,16 @@ func (rs *RAGStorage) GetStats() map[string]interface{} {
 		"storage_type": rs.storageType,
 		"items_count":  len(rs.items),
 		"vector_dim":   rs.vectorDim,
+		"semantic":     rs.embedder != nil,
 		"index_built":  rs.indexBuilt,
 		"path":         rs.path,
 	}
 }
+
+// memoryText 返回用于嵌入的记忆文本
+func memoryText(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return fmt.Sprintf("%v", value)
+}
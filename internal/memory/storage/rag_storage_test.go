@@ -2,9 +2,12 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/ynl/greensoulai/internal/memory"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
@@ -135,3 +138,107 @@ func TestRAGStorageNilConfig(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, results)
 }
+
+// fixedEmbedder 按预设向量返回嵌入，用于精确验证相似度阈值
+type fixedEmbedder struct {
+	vectors map[string][]float32
+}
+
+func (e *fixedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		result[i] = e.vectors[text]
+	}
+	return result, nil
+}
+
+func TestRAGStorageSemanticSearch(t *testing.T) {
+	config := &memory.EmbedderConfig{Provider: memory.EmbedderProviderHash}
+	storage := NewRAGStorage("short_term", config, nil, "", logger.NewTestLogger())
+	ctx := context.Background()
+
+	values := []string{
+		"The quarterly revenue report shows strong growth",
+		"Customer churn increased in the enterprise segment",
+		"Sourdough bread needs a long fermentation",
+	}
+	for i, value := range values {
+		require.NoError(t, storage.Save(ctx, memory.MemoryItem{ID: fmt.Sprintf("item-%d", i), Value: value, Agent: "analyst"}))
+	}
+
+	results, err := storage.Search(ctx, "revenue growth report", 2, 0.1)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "item-0", results[0].ID)
+	assert.Greater(t, results[0].Score, 0.1)
+	for _, item := range results {
+		assert.NotEqual(t, "item-2", item.ID, "unrelated memory should fall below the threshold")
+	}
+
+	stats := storage.GetStats()
+	assert.Equal(t, true, stats["semantic"])
+	assert.Equal(t, 256, stats["vector_dim"])
+}
+
+func TestRAGStorageScoreThresholdIsExact(t *testing.T) {
+	embedder := &fixedEmbedder{vectors: map[string][]float32{
+		"query": {1, 0},
+		"same":  {1, 0},           // 相似度 1
+		"half":  {0.5, 0.8660254}, // 相似度 0.5
+		"none":  {0, 1},           // 相似度 0
+	}}
+	config := &memory.EmbedderConfig{Embedder: embedder}
+	storage := NewRAGStorage("short_term", config, nil, "", logger.NewTestLogger())
+	ctx := context.Background()
+
+	for _, value := range []string{"same", "half", "none"} {
+		require.NoError(t, storage.Save(ctx, memory.MemoryItem{ID: value, Value: value}))
+	}
+
+	results, err := storage.Search(ctx, "query", 10, 0.5)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "same", results[0].ID)
+	assert.InDelta(t, 1.0, results[0].Score, 1e-6)
+	assert.Equal(t, "half", results[1].ID)
+	assert.GreaterOrEqual(t, results[1].Score, 0.5-1e-6)
+
+	results, err = storage.Search(ctx, "query", 10, 0.51)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	results, err = storage.Search(ctx, "query", 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	// 删除后不再返回对应向量
+	require.NoError(t, storage.Delete(ctx, "same"))
+	results, err = storage.Search(ctx, "query", 10, 0.5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "half", results[0].ID)
+}
+
+func TestRAGStorageSearchWithFilter(t *testing.T) {
+	storage := NewRAGStorage("short_term", &memory.EmbedderConfig{Provider: memory.EmbedderProviderHash}, nil, "", logger.NewTestLogger())
+	ctx := context.Background()
+
+	now := time.Now()
+	require.NoError(t, storage.Save(ctx, memory.MemoryItem{ID: "a", Value: "draft outline", Agent: "writer",
+		Metadata: map[string]interface{}{"task_id": "t1"}, CreatedAt: now.Add(-time.Minute)}))
+	require.NoError(t, storage.Save(ctx, memory.MemoryItem{ID: "b", Value: "final draft", Agent: "writer",
+		Metadata: map[string]interface{}{"task_id": "t2"}, CreatedAt: now}))
+	require.NoError(t, storage.Save(ctx, memory.MemoryItem{ID: "c", Value: "draft data", Agent: "researcher",
+		Metadata: map[string]interface{}{"task_id": "t1"}, CreatedAt: now}))
+
+	results, err := storage.SearchWithFilter(ctx, "draft", map[string]interface{}{"task_id": "t1"}, 10, 0.1)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	// 空查询按创建时间倒序返回
+	results, err = storage.SearchWithFilter(ctx, "", map[string]interface{}{"agent": "writer"}, 10, 0.9)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "b", results[0].ID)
+	assert.Equal(t, "a", results[1].ID)
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/ynl/greensoulai/internal/memory"
+)
+
+// SessionDBFileName 会话记忆数据库文件名
+const SessionDBFileName = "sessions.db"
+
+// DefaultSessionDBPath 返回默认的会话记忆数据库路径（位于memory.StorageDir()下）
+func DefaultSessionDBPath() string {
+	return filepath.Join(memory.StorageDir(), SessionDBFileName)
+}
+
+// SQLiteSessionStore 会话记忆的SQLite持久化后端，每个会话一行，轮次以JSON存储
+type SQLiteSessionStore struct {
+	dbPath string
+	db     *sql.DB
+}
+
+var _ memory.SessionStore = (*SQLiteSessionStore)(nil)
+
+// NewSQLiteSessionStore 打开（必要时创建）会话数据库，dbPath为空时使用默认路径
+func NewSQLiteSessionStore(dbPath string) (*SQLiteSessionStore, error) {
+	if dbPath == "" {
+		dbPath = DefaultSessionDBPath()
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	dsn := fmt.Sprintf("%s?_busy_timeout=%d&_journal_mode=WAL&_synchronous=NORMAL", dbPath, ltmBusyTimeoutMs)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS sessions (
+		id TEXT PRIMARY KEY,
+		summary TEXT NOT NULL DEFAULT '',
+		turns TEXT NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sessions table: %w", err)
+	}
+
+	return &SQLiteSessionStore{dbPath: dbPath, db: db}, nil
+}
+
+// DBPath 返回数据库文件路径
+func (s *SQLiteSessionStore) DBPath() string {
+	return s.dbPath
+}
+
+// Load 实现memory.SessionStore接口
+func (s *SQLiteSessionStore) Load(ctx context.Context, sessionID string) (*memory.SessionState, bool, error) {
+	var summary, turns string
+	var updatedAt time.Time
+	err := s.db.QueryRowContext(ctx,
+		`SELECT summary, turns, updated_at FROM sessions WHERE id = ?`, sessionID,
+	).Scan(&summary, &turns, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	state := &memory.SessionState{ID: sessionID, Summary: summary, UpdatedAt: updatedAt}
+	if err := json.Unmarshal([]byte(turns), &state.Turns); err != nil {
+		return nil, false, fmt.Errorf("failed to decode session turns: %w", err)
+	}
+	return state, true, nil
+}
+
+// Save 实现memory.SessionStore接口
+func (s *SQLiteSessionStore) Save(ctx context.Context, state *memory.SessionState) error {
+	turns, err := json.Marshal(state.Turns)
+	if err != nil {
+		return fmt.Errorf("failed to encode session turns: %w", err)
+	}
+	updatedAt := state.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO sessions (id, summary, turns, updated_at) VALUES (?, ?, ?, ?)`,
+		state.ID, state.Summary, string(turns), updatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// Delete 实现memory.SessionStore接口
+func (s *SQLiteSessionStore) Delete(ctx context.Context, sessionID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// List 实现memory.SessionStore接口
+func (s *SQLiteSessionStore) List(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM sessions ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to read session id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Close 实现memory.SessionStore接口
+func (s *SQLiteSessionStore) Close() error {
+	return s.db.Close()
+}
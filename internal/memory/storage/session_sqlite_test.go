@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/memory"
+)
+
+func TestSQLiteSessionStorePersistsAcrossProcesses(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), SessionDBFileName)
+	ctx := context.Background()
+
+	store, err := NewSQLiteSessionStore(dbPath)
+	require.NoError(t, err)
+	sessions := memory.NewSessionMemory(&memory.SessionMemoryConfig{MaxTurns: 1, Store: store})
+	_, err = sessions.Append(ctx, "alice", "My name is Alice", "Nice to meet you, Alice")
+	require.NoError(t, err)
+	_, err = sessions.Append(ctx, "alice", "What is my name?", "Your name is Alice")
+	require.NoError(t, err)
+	_, err = sessions.Append(ctx, "bob", "Hello", "Hi Bob")
+	require.NoError(t, err)
+	require.NoError(t, sessions.Close())
+
+	// 新进程：会话从SQLite读取
+	store, err = NewSQLiteSessionStore(dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+
+	state, ok, err := store.Load(ctx, "alice")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, state.Turns, 1)
+	assert.Equal(t, "Your name is Alice", state.Turns[0].Output)
+	assert.Contains(t, state.Summary, "My name is Alice")
+	assert.False(t, state.UpdatedAt.IsZero())
+
+	ids, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, ids)
+
+	require.NoError(t, store.Delete(ctx, "alice"))
+	_, ok, err = store.Load(ctx, "alice")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ids, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, ids)
+}
@@ -0,0 +1,17 @@
+package memory
+
+import "os"
+
+// StorageDirEnv 指定记忆数据存储目录的环境变量
+const StorageDirEnv = "GREENSOULAI_STORAGE_DIR"
+
+// DefaultStorageDir 默认的记忆数据存储目录
+const DefaultStorageDir = "data"
+
+// StorageDir 返回记忆数据存储目录，优先使用GREENSOULAI_STORAGE_DIR环境变量
+func StorageDir() string {
+	if dir := os.Getenv(StorageDirEnv); dir != "" {
+		return dir
+	}
+	return DefaultStorageDir
+}
@@ -0,0 +1,296 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 文件写入模式
+const (
+	WriteModeCreate = "create" // 创建文件，已存在时覆盖
+	WriteModeAppend = "append" // 追加到文件末尾，不存在时创建
+)
+
+// TruncatedMarker 读取内容超过限制时追加的标记，让LLM知道内容不完整
+const TruncatedMarker = "[truncated]"
+
+// 搜索结果中单行片段的最大字符数
+const maxSnippetLength = 200
+
+// SearchMatch 目录搜索的一条结果
+type SearchMatch struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// NewFileReadTool 创建读取沙箱内文件的工具，超过读取限制的内容被截断并标记[truncated]
+func NewFileReadTool(root string, opts ...Option) (agent.Tool, error) {
+	box, err := newSandbox(root)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	const description = "Read the content of a file. The path is relative to the working directory."
+	schema := agent.NewToolSchema("file_read", description,
+		agent.ToolParameter{Name: "path", Type: "string", Description: "Path of the file to read", Required: true},
+	)
+
+	return agent.NewBaseToolWithSchema("file_read", description, schema,
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			path, _ := args["path"].(string)
+			target, err := box.resolve(path)
+			if err != nil {
+				return nil, err
+			}
+
+			file, err := os.Open(target)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %q: %w", path, err)
+			}
+			defer file.Close()
+
+			info, err := file.Stat()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %q: %w", path, err)
+			}
+			if info.IsDir() {
+				return nil, fmt.Errorf("%q is a directory", path)
+			}
+
+			content, truncated, err := readLimited(file, o.maxReadBytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %q: %w", path, err)
+			}
+			if !truncated {
+				return string(content), nil
+			}
+			// 截断位置可能落在多字节字符中间
+			for i := 0; i < utf8.UTFMax && len(content) > 0 && !utf8.Valid(content); i++ {
+				content = content[:len(content)-1]
+			}
+			return string(content) + "\n" + TruncatedMarker, nil
+		},
+	), nil
+}
+
+// NewFileWriteTool 创建写入沙箱内文件的工具
+// 支持create（覆盖）和append两种模式，写入后的文件不能超过最大文件大小
+func NewFileWriteTool(root string, opts ...Option) (agent.Tool, error) {
+	box, err := newSandbox(root)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	const description = "Write content to a file, creating parent directories as needed. " +
+		"The path is relative to the working directory."
+	schema := agent.NewToolSchema("file_write", description,
+		agent.ToolParameter{Name: "path", Type: "string", Description: "Path of the file to write", Required: true},
+		agent.ToolParameter{Name: "content", Type: "string", Description: "Content to write", Required: true},
+		agent.ToolParameter{Name: "mode", Type: "string", Description: "create overwrites the file, append adds to the end. Defaults to create",
+			Enum: []interface{}{WriteModeCreate, WriteModeAppend}},
+	)
+
+	return agent.NewBaseToolWithSchema("file_write", description, schema,
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			path, _ := args["path"].(string)
+			content, _ := args["content"].(string)
+			mode, _ := args["mode"].(string)
+			if mode == "" {
+				mode = WriteModeCreate
+			}
+			if mode != WriteModeCreate && mode != WriteModeAppend {
+				return nil, fmt.Errorf("unsupported write mode %q", mode)
+			}
+
+			target, err := box.resolve(path)
+			if err != nil {
+				return nil, err
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			unlock := lockFile(target)
+			defer unlock()
+
+			size := int64(len(content))
+			if mode == WriteModeAppend {
+				if info, err := os.Stat(target); err == nil {
+					size += info.Size()
+				}
+			}
+			if size > o.maxFileSize {
+				return nil, fmt.Errorf("writing %q would exceed the max file size of %d bytes", path, o.maxFileSize)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return nil, fmt.Errorf("failed to create directory for %q: %w", path, err)
+			}
+			flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if mode == WriteModeAppend {
+				flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+			}
+			file, err := os.OpenFile(target, flags, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %q: %w", path, err)
+			}
+			if _, err := file.WriteString(content); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to write %q: %w", path, err)
+			}
+			if err := file.Close(); err != nil {
+				return nil, fmt.Errorf("failed to write %q: %w", path, err)
+			}
+
+			if o.logger != nil {
+				o.logger.Debug("file written",
+					logger.Field{Key: "path", Value: box.relative(target)},
+					logger.Field{Key: "mode", Value: mode},
+					logger.Field{Key: "bytes", Value: len(content)},
+				)
+			}
+			return fmt.Sprintf("Wrote %d bytes to %s (%s)", len(content), box.relative(target), mode), nil
+		},
+	), nil
+}
+
+// NewDirectorySearchTool 创建搜索沙箱内文件的工具
+// pattern按glob匹配文件名（包含/时匹配相对路径），query为正则表达式时返回匹配的行和片段
+func NewDirectorySearchTool(root string, opts ...Option) (agent.Tool, error) {
+	box, err := newSandbox(root)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	const description = "Search files in a directory by glob pattern and optionally by content. " +
+		"Returns matching file paths with line numbers and snippets."
+	schema := agent.NewToolSchema("directory_search", description,
+		agent.ToolParameter{Name: "pattern", Type: "string", Description: "Glob pattern for file names, e.g. *.go. Defaults to all files"},
+		agent.ToolParameter{Name: "query", Type: "string", Description: "Regular expression to search for in file contents"},
+		agent.ToolParameter{Name: "path", Type: "string", Description: "Directory to search in. Defaults to the working directory"},
+	)
+
+	return agent.NewBaseToolWithSchema("directory_search", description, schema,
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			pattern, _ := args["pattern"].(string)
+			if pattern == "" {
+				pattern = "*"
+			}
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+
+			var query *regexp.Regexp
+			if text, _ := args["query"].(string); text != "" {
+				if query, err = regexp.Compile(text); err != nil {
+					return nil, fmt.Errorf("invalid query %q: %w", text, err)
+				}
+			}
+
+			dir, _ := args["path"].(string)
+			if dir == "" {
+				dir = "."
+			}
+			start, err := box.resolve(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			matches := make([]SearchMatch, 0)
+			err = filepath.WalkDir(start, func(path string, entry fs.DirEntry, walkErr error) error {
+				if walkErr != nil {
+					return nil
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if len(matches) >= o.maxSearchResults {
+					return filepath.SkipAll
+				}
+				if entry.IsDir() {
+					if entry.Name() == ".git" {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				// WalkDir不跟随符号链接，只搜索普通文件
+				if !entry.Type().IsRegular() {
+					return nil
+				}
+
+				rel := box.relative(path)
+				name := entry.Name()
+				if strings.Contains(pattern, "/") {
+					name = rel
+				}
+				if ok, _ := filepath.Match(pattern, name); !ok {
+					return nil
+				}
+
+				if query == nil {
+					matches = append(matches, SearchMatch{Path: rel})
+					return nil
+				}
+				found, err := grepFile(path, query, o.maxSearchResults-len(matches))
+				if err != nil {
+					return nil
+				}
+				for _, match := range found {
+					match.Path = rel
+					matches = append(matches, match)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			return matches, nil
+		},
+	), nil
+}
+
+// grepFile 返回文件中匹配正则的行，跳过二进制文件
+func grepFile(path string, query *regexp.Regexp, limit int) ([]SearchMatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(512)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	var matches []SearchMatch
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for lineNumber := 1; scanner.Scan() && len(matches) < limit; lineNumber++ {
+		line := scanner.Text()
+		if query.MatchString(line) {
+			snippet, _ := truncateRunes(strings.TrimSpace(line), maxSnippetLength)
+			matches = append(matches, SearchMatch{Line: lineNumber, Snippet: snippet})
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return matches, err
+	}
+	return matches, nil
+}
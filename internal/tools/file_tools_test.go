@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/agent"
+)
+
+func newFileToolsSandbox(t *testing.T) (string, string) {
+	t.Helper()
+	base := t.TempDir()
+	root := filepath.Join(base, "workspace")
+	outside := filepath.Join(base, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs"), 0o755))
+	require.NoError(t, os.MkdirAll(outside, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("top secret"), 0o644))
+	return root, outside
+}
+
+// mustTool 用法：mustTool(t)(NewFileReadTool(root))
+func mustTool(t *testing.T) func(agent.Tool, error) agent.Tool {
+	return func(tool agent.Tool, err error) agent.Tool {
+		t.Helper()
+		require.NoError(t, err)
+		return tool
+	}
+}
+
+func TestFileToolsRejectPathEscapes(t *testing.T) {
+	root, outside := newFileToolsSandbox(t)
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "missing.txt"), filepath.Join(root, "dangling")))
+
+	reader := mustTool(t)(NewFileReadTool(root))
+	writer := mustTool(t)(NewFileWriteTool(root))
+
+	for _, path := range []string{
+		"../../etc/passwd",
+		"../outside/secret.txt",
+		"docs/../../outside/secret.txt",
+		filepath.Join(outside, "secret.txt"),
+		"link/secret.txt",
+	} {
+		_, err := reader.Execute(context.Background(), map[string]interface{}{"path": path})
+		require.Error(t, err, path)
+		assert.Contains(t, err.Error(), "escapes sandbox root", path)
+	}
+
+	// 通过符号链接目录写入新文件同样被拒绝
+	_, err := writer.Execute(context.Background(), map[string]interface{}{"path": "link/new.txt", "content": "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "escapes sandbox root")
+	_, err = writer.Execute(context.Background(), map[string]interface{}{"path": "dangling", "content": "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dangling symlink")
+	_, statErr := os.Stat(filepath.Join(outside, "missing.txt"))
+	assert.True(t, os.IsNotExist(statErr))
+
+	_, err = NewFileReadTool(filepath.Join(root, "missing"))
+	assert.Error(t, err)
+}
+
+func TestFileReadToolTruncates(t *testing.T) {
+	root, _ := newFileToolsSandbox(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "notes.txt"), []byte("0123456789"), 0o644))
+
+	full, err := mustTool(t)(NewFileReadTool(root)).Execute(context.Background(), map[string]interface{}{"path": "docs/notes.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", full)
+
+	partial, err := mustTool(t)(NewFileReadTool(root, WithMaxReadBytes(4))).Execute(context.Background(), map[string]interface{}{"path": "docs/notes.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "0123\n"+TruncatedMarker, partial)
+
+	_, err = mustTool(t)(NewFileReadTool(root)).Execute(context.Background(), map[string]interface{}{"path": "docs"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is a directory")
+}
+
+func TestFileWriteToolModesAndLimit(t *testing.T) {
+	root, _ := newFileToolsSandbox(t)
+	writer := mustTool(t)(NewFileWriteTool(root, WithMaxFileSize(10)))
+
+	_, err := writer.Execute(context.Background(), map[string]interface{}{"path": "out/report.md", "content": "hello"})
+	require.NoError(t, err)
+	_, err = writer.Execute(context.Background(), map[string]interface{}{"path": "out/report.md", "content": " you", "mode": "append"})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(root, "out", "report.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello you", string(content))
+
+	_, err = writer.Execute(context.Background(), map[string]interface{}{"path": "out/report.md", "content": "!!", "mode": "append"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max file size of 10 bytes")
+
+	_, err = writer.Execute(context.Background(), map[string]interface{}{"path": "out/report.md", "content": "new"})
+	require.NoError(t, err)
+	content, _ = os.ReadFile(filepath.Join(root, "out", "report.md"))
+	assert.Equal(t, "new", string(content))
+}
+
+func TestFileWriteToolConcurrentAppends(t *testing.T) {
+	root, _ := newFileToolsSandbox(t)
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// 每个并行任务使用各自的工具实例，写入同一个文件
+			writer, err := NewFileWriteTool(root)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			line := fmt.Sprintf("task-%02d %s\n", i, strings.Repeat("x", 1000))
+			if _, err := writer.Execute(context.Background(), map[string]interface{}{"path": "log.txt", "content": line, "mode": "append"}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	content, err := os.ReadFile(filepath.Join(root, "log.txt"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	require.Len(t, lines, writers)
+	for _, line := range lines {
+		assert.Len(t, line, len("task-00 ")+1000)
+	}
+}
+
+func TestDirectorySearchTool(t *testing.T) {
+	root, outside := newFileToolsSandbox(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "a.md"), []byte("intro\nTODO: write tests\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "b.txt"), []byte("TODO later\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+
+	search := mustTool(t)(NewDirectorySearchTool(root))
+
+	result, err := search.Execute(context.Background(), map[string]interface{}{"pattern": "*.md"})
+	require.NoError(t, err)
+	assert.Equal(t, []SearchMatch{{Path: "docs/a.md"}}, result)
+
+	result, err = search.Execute(context.Background(), map[string]interface{}{"query": "TODO"})
+	require.NoError(t, err)
+	assert.Equal(t, []SearchMatch{
+		{Path: "docs/a.md", Line: 2, Snippet: "TODO: write tests"},
+		{Path: "docs/b.txt", Line: 1, Snippet: "TODO later"},
+	}, result)
+
+	// 不跟随指向沙箱外的符号链接
+	result, err = search.Execute(context.Background(), map[string]interface{}{"query": "secret"})
+	require.NoError(t, err)
+	assert.Empty(t, result)
+
+	_, err = search.Execute(context.Background(), map[string]interface{}{"path": "../outside"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "escapes sandbox root")
+}
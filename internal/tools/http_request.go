@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const httpRequestDescription = "Send an HTTP request to a URL and return the status code, response headers and body. " +
+	"Use it to call web APIs or fetch raw content."
+
+// 日志中需要隐藏值的请求头
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+}
+
+// NewHTTPRequestTool 创建HTTP请求工具
+// 参数：method（默认GET）、url、headers、body；响应体超过大小限制时截断
+func NewHTTPRequestTool(opts ...Option) agent.Tool {
+	o := newOptions(opts)
+	client := o.newClient()
+
+	schema := agent.NewToolSchema("http_request", httpRequestDescription,
+		agent.ToolParameter{Name: "url", Type: "string", Description: "The http or https URL to request", Required: true},
+		agent.ToolParameter{Name: "method", Type: "string", Description: "HTTP method, defaults to GET",
+			Enum: []interface{}{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}},
+		agent.ToolParameter{Name: "headers", Type: "object", Description: "Request headers as a JSON object of strings"},
+		agent.ToolParameter{Name: "body", Type: "string", Description: "Request body"},
+	)
+
+	return agent.NewBaseToolWithSchema("http_request", httpRequestDescription, schema,
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			rawURL, _ := args["url"].(string)
+			target, err := validateURL(rawURL)
+			if err != nil {
+				return nil, err
+			}
+
+			method := http.MethodGet
+			if m, ok := args["method"].(string); ok && m != "" {
+				method = strings.ToUpper(m)
+			}
+
+			var body io.Reader
+			if text, ok := args["body"].(string); ok && text != "" {
+				body = strings.NewReader(text)
+			}
+
+			ctx, cancel := o.withTimeout(ctx)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Header.Set("User-Agent", o.userAgent)
+			if headers, ok := args["headers"].(map[string]interface{}); ok {
+				for key, value := range headers {
+					req.Header.Set(key, fmt.Sprint(value))
+				}
+			}
+
+			if o.logger != nil {
+				o.logger.Debug("sending http request",
+					logger.Field{Key: "method", Value: method},
+					logger.Field{Key: "url", Value: target.Redacted()},
+					logger.Field{Key: "headers", Value: redactHeaders(req.Header)},
+				)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, fmt.Errorf("http request failed: %w", err)
+			}
+			defer resp.Body.Close()
+
+			content, truncated, err := readLimited(resp.Body, o.maxResponseBytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response body: %w", err)
+			}
+
+			responseHeaders := make(map[string]string, len(resp.Header))
+			for key := range resp.Header {
+				responseHeaders[key] = resp.Header.Get(key)
+			}
+
+			return map[string]interface{}{
+				"status_code": resp.StatusCode,
+				"headers":     responseHeaders,
+				"body":        string(content),
+				"truncated":   truncated,
+			}, nil
+		},
+	).WithNondeterministic()
+}
+
+// readLimited 最多读取limit字节，返回内容是否被截断
+func readLimited(r io.Reader, limit int64) ([]byte, bool, error) {
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(content)) > limit {
+		return content[:limit], true, nil
+	}
+	return content, false, nil
+}
+
+// redactHeaders 返回用于日志的请求头，敏感请求头的值被隐藏
+func redactHeaders(header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := header.Get(key)
+		if sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			value = "[REDACTED]"
+		}
+		parts = append(parts, key+": "+value)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+func TestHTTPRequestTool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Auth", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("echo:" + string(body)))
+	}))
+	defer server.Close()
+
+	tool := NewHTTPRequestTool()
+	schema := tool.GetSchema()
+	assert.Equal(t, "http_request", schema.Name)
+	assert.Equal(t, []string{"url"}, schema.Parameters["required"])
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{
+		"url":     server.URL,
+		"method":  "post",
+		"headers": map[string]interface{}{"Authorization": "Bearer secret"},
+		"body":    "hello",
+	})
+	require.NoError(t, err)
+
+	response := result.(map[string]interface{})
+	assert.Equal(t, http.StatusCreated, response["status_code"])
+	assert.Equal(t, "echo:hello", response["body"])
+	assert.Equal(t, false, response["truncated"])
+	headers := response["headers"].(map[string]string)
+	assert.Equal(t, "POST", headers["X-Method"])
+	assert.Equal(t, "Bearer secret", headers["X-Auth"])
+}
+
+func TestHTTPRequestToolLimitsResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	result, err := NewHTTPRequestTool(WithMaxResponseBytes(10)).Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	require.NoError(t, err)
+
+	response := result.(map[string]interface{})
+	assert.Equal(t, strings.Repeat("a", 10), response["body"])
+	assert.Equal(t, true, response["truncated"])
+}
+
+func TestHTTPRequestToolBlocksUnsafeTargets(t *testing.T) {
+	tool := NewHTTPRequestTool()
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"url": "file:///etc/passwd"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `url scheme "file" is not allowed`)
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"url": "http://169.254.169.254/latest/meta-data"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "link-local and unspecified addresses are blocked")
+
+	// 重定向到不允许的协议同样被拒绝
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `url scheme "ftp" is not allowed`)
+}
+
+func TestHTTPRequestToolUsesSharedProxy(t *testing.T) {
+	var proxied atomic.Int32
+	var requestURI atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		requestURI.Store(r.RequestURI)
+		_, _ = w.Write([]byte("via proxy"))
+	}))
+	defer proxy.Close()
+
+	previous := llm.SharedHTTPClientConfig()
+	defer llm.ConfigureHTTPClient(previous)
+	config := llm.DefaultHTTPClientConfig()
+	config.Proxy = proxy.URL
+	llm.ConfigureHTTPClient(config)
+
+	tool := NewHTTPRequestTool()
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"url": "http://upstream.test/page"})
+	require.NoError(t, err)
+	assert.Equal(t, "via proxy", result.(map[string]interface{})["body"])
+	assert.Equal(t, "http://upstream.test/page", requestURI.Load())
+
+	// 经代理转发时仍检查目标地址，被拒绝的请求不会发给代理
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"url": "http://169.254.169.254/latest/meta-data"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "link-local and unspecified addresses are blocked")
+	assert.Equal(t, int32(1), proxied.Load())
+}
+
+func TestHTTPRequestToolTimeoutAndCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	_, err := NewHTTPRequestTool(WithTimeout(50*time.Millisecond)).Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "deadline exceeded")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewHTTPRequestTool().Execute(ctx, map[string]interface{}{"url": server.URL})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "context canceled")
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Accept", "application/json")
+
+	redacted := redactHeaders(header)
+	assert.NotContains(t, redacted, "secret")
+	assert.Equal(t, "Accept: application/json, Authorization: [REDACTED]", redacted)
+}
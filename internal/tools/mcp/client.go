@@ -0,0 +1,312 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// DefaultRequestTimeout 调用方未设置截止时间时单个请求的超时时间
+const DefaultRequestTimeout = 60 * time.Second
+
+// ErrDisconnected 与服务器的连接已断开，进行中的请求不会再收到响应
+var ErrDisconnected = errors.New("mcp server disconnected")
+
+// clientInfo 在initialize请求中发送给服务器的客户端信息
+var clientInfo = Implementation{Name: "greensoulai", Version: "1.0.0"}
+
+// ClientOption 配置MCP客户端
+type ClientOption func(*Client)
+
+// WithRequestTimeout 设置请求超时时间
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		if timeout > 0 {
+			c.requestTimeout = timeout
+		}
+	}
+}
+
+// WithLogger 设置客户端日志
+func WithLogger(l logger.Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// Client MCP客户端，实现agent.ToolProvider
+// 设置到AgentConfig.ToolProviders后，Agent初始化时连接服务器并发现工具，关闭时断开连接
+type Client struct {
+	name           string
+	transport      Transport
+	requestTimeout time.Duration
+	logger         logger.Logger
+
+	mu         sync.Mutex
+	connected  bool
+	nextID     int64
+	pending    map[int64]chan *message
+	done       chan struct{} // 连接断开时关闭
+	serverInfo InitializeResult
+}
+
+// NewClient 创建MCP客户端，name为服务器名称，工具名冲突时用作前缀
+func NewClient(name string, transport Transport, opts ...ClientOption) *Client {
+	c := &Client{
+		name:           name,
+		transport:      transport,
+		requestTimeout: DefaultRequestTimeout,
+		logger:         logger.NewConsoleLogger(),
+		pending:        make(map[int64]chan *message),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetName 返回服务器名称
+func (c *Client) GetName() string {
+	return c.name
+}
+
+// ServerInfo 返回服务器在初始化时声明的信息
+func (c *Client) ServerInfo() InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.serverInfo
+}
+
+// Connect 建立连接并完成initialize握手，已连接时直接返回
+func (c *Client) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	if c.connected {
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	if err := c.transport.Start(ctx); err != nil {
+		return fmt.Errorf("failed to connect to mcp server %s: %w", c.name, err)
+	}
+
+	done := make(chan struct{})
+	c.mu.Lock()
+	c.done = done
+	c.connected = true
+	c.mu.Unlock()
+	go c.readLoop(c.transport.Receive(), done)
+
+	var result InitializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      clientInfo,
+	}, &result)
+	if err == nil {
+		err = c.notify(ctx, "notifications/initialized")
+	}
+	if err != nil {
+		_ = c.Close()
+		return fmt.Errorf("failed to initialize mcp server %s: %w", c.name, err)
+	}
+
+	c.mu.Lock()
+	c.serverInfo = result
+	c.mu.Unlock()
+	c.logger.Info("connected to mcp server",
+		logger.Field{Key: "server", Value: c.name},
+		logger.Field{Key: "server_name", Value: result.ServerInfo.Name},
+		logger.Field{Key: "protocol_version", Value: result.ProtocolVersion},
+	)
+	return nil
+}
+
+// ListTools 列出服务器提供的所有工具，自动处理分页
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var tools []ToolInfo
+	cursor := ""
+	for {
+		var page listToolsResult
+		if err := c.call(ctx, "tools/list", listToolsParams{Cursor: cursor}, &page); err != nil {
+			return nil, fmt.Errorf("failed to list tools of mcp server %s: %w", c.name, err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool 调用服务器上的工具；工具执行失败时返回IsError为true的结果而不是错误
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, fmt.Errorf("failed to call mcp tool %s/%s: %w", c.name, name, err)
+	}
+	return &result, nil
+}
+
+// DiscoverTools 连接服务器并把其工具包装为agent.Tool
+// 工具Execute时把调用转发给服务器，返回内容块的文本；服务器报告的工具错误作为错误返回
+func (c *Client) DiscoverTools(ctx context.Context) ([]agent.Tool, error) {
+	if err := c.Connect(ctx); err != nil {
+		return nil, err
+	}
+	infos, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := make([]agent.Tool, 0, len(infos))
+	for _, info := range infos {
+		tools = append(tools, c.wrapTool(info))
+	}
+	return tools, nil
+}
+
+// wrapTool 把服务器工具包装为agent.Tool，参数模式直接使用服务器声明的inputSchema
+func (c *Client) wrapTool(info ToolInfo) agent.Tool {
+	name := info.Name
+	schema := agent.ToolSchema{Name: name, Description: info.Description, Parameters: info.InputSchema}
+	return agent.NewBaseToolWithSchema(name, info.Description, schema,
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			result, err := c.CallTool(ctx, name, args)
+			if err != nil {
+				return nil, err
+			}
+			if result.IsError {
+				return nil, fmt.Errorf("mcp tool %s/%s failed: %s", c.name, name, result.Text())
+			}
+			return result.Text(), nil
+		},
+	)
+}
+
+// Close 断开与服务器的连接，进行中的请求返回ErrDisconnected
+// 服务器已经断开时同样关闭传输，保证stdio子进程被回收；传输关闭后不能再次连接
+func (c *Client) Close() error {
+	c.mu.Lock()
+	started := c.done != nil
+	c.connected = false
+	c.mu.Unlock()
+	if !started {
+		return nil
+	}
+	return c.transport.Close()
+}
+
+// call 发送请求并等待响应，result为nil时忽略响应内容
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+
+	c.mu.Lock()
+	if !c.connected {
+		c.mu.Unlock()
+		return ErrDisconnected
+	}
+	c.nextID++
+	id := c.nextID
+	responses := make(chan *message, 1)
+	c.pending[id] = responses
+	done := c.done
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(message{JSONRPC: jsonRPCVersion, ID: &id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	if err := c.transport.Send(ctx, data); err != nil {
+		return err
+	}
+
+	var response *message
+	select {
+	case response = <-responses:
+	case <-done:
+		// 断开前已经到达的响应仍然有效
+		select {
+		case response = <-responses:
+		default:
+			return ErrDisconnected
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("invalid %s response: %w", method, err)
+	}
+	return nil
+}
+
+// notify 发送不需要响应的通知
+func (c *Client) notify(ctx context.Context, method string) error {
+	data, err := json.Marshal(message{JSONRPC: jsonRPCVersion, Method: method})
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(ctx, data)
+}
+
+// readLoop 把服务器响应分发给等待中的请求，连接断开后关闭done
+func (c *Client) readLoop(messages <-chan []byte, done chan struct{}) {
+	defer func() {
+		c.mu.Lock()
+		c.connected = false
+		c.mu.Unlock()
+		close(done)
+	}()
+
+	for data := range messages {
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.logger.Warn("invalid message from mcp server",
+				logger.Field{Key: "server", Value: c.name},
+				logger.Field{Key: "error", Value: err},
+			)
+			continue
+		}
+
+		switch {
+		case msg.ID != nil && msg.Method == "":
+			c.mu.Lock()
+			responses, ok := c.pending[*msg.ID]
+			c.mu.Unlock()
+			if ok {
+				responses <- &msg
+			}
+		case msg.ID != nil && msg.Method == "ping":
+			// 服务器的保活请求需要响应
+			reply, _ := json.Marshal(message{JSONRPC: jsonRPCVersion, ID: msg.ID, Result: json.RawMessage("{}")})
+			_ = c.transport.Send(context.Background(), reply)
+		case msg.ID != nil:
+			reply, _ := json.Marshal(message{JSONRPC: jsonRPCVersion, ID: msg.ID,
+				Error: &RPCError{Code: -32601, Message: "method not found: " + msg.Method}})
+			_ = c.transport.Send(context.Background(), reply)
+		}
+	}
+}
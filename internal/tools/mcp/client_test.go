@@ -0,0 +1,167 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/llm/llmtest"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// nopWriteCloser 丢弃客户端发送的消息
+type nopWriteCloser struct{}
+
+func (nopWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (nopWriteCloser) Close() error                { return nil }
+
+func toolsByName(tools []agent.Tool) map[string]agent.Tool {
+	byName := make(map[string]agent.Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.GetName()] = tool
+	}
+	return byName
+}
+
+func TestClientDiscoverAndCallTools(t *testing.T) {
+	server := newFakeServer()
+	client, _ := startStreamServer(t, server)
+
+	tools, err := client.DiscoverTools(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "fake-server", client.ServerInfo().ServerInfo.Name)
+
+	// 分页返回的工具全部被发现
+	byName := toolsByName(tools)
+	require.Len(t, byName, 5)
+	echo := byName["echo"]
+	assert.Equal(t, "Echo the text back", echo.GetDescription())
+	assert.Equal(t, []string{"text"}, echo.GetSchema().Parameters["required"])
+
+	result, err := echo.Execute(context.Background(), map[string]interface{}{"text": "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result)
+
+	result, err = byName["image"].Execute(context.Background(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "a chart\n"+`{"data":"aGVsbG8=","mimeType":"image/png","type":"image"}`, result)
+
+	_, err = byName["fail"].Execute(context.Background(), map[string]interface{}{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mcp tool fake/fail failed: repository not found")
+
+	_, err = byName["broken"].Execute(context.Background(), map[string]interface{}{})
+	require.Error(t, err)
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, -32000, rpcErr.Code)
+	assert.Equal(t, 1, server.callCount("broken"))
+}
+
+func TestClientServerDisconnectMidCall(t *testing.T) {
+	server := newFakeServer()
+	client, stopped := startStreamServer(t, server)
+
+	tools, err := client.DiscoverTools(context.Background())
+	require.NoError(t, err)
+	byName := toolsByName(tools)
+
+	start := time.Now()
+	_, err = byName["hang"].Execute(context.Background(), map[string]interface{}{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrDisconnected), "unexpected error: %v", err)
+	assert.Less(t, time.Since(start), DefaultRequestTimeout)
+	<-stopped
+
+	// 断开后的调用立即失败
+	_, err = byName["echo"].Execute(context.Background(), map[string]interface{}{"text": "again"})
+	assert.True(t, errors.Is(err, ErrDisconnected), "unexpected error: %v", err)
+}
+
+func TestClientRequestTimeout(t *testing.T) {
+	clientReader, _ := io.Pipe()
+	client := NewClient("silent", NewStreamTransport(clientReader, nopWriteCloser{}), WithRequestTimeout(20*time.Millisecond))
+	defer client.Close()
+
+	err := client.Connect(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+}
+
+func TestSSETransport(t *testing.T) {
+	server := newFakeServer()
+	httpServer := startSSEServer(t, server)
+
+	client := NewClient("fake", NewSSETransport(httpServer.URL+"/sse", map[string]string{"Authorization": "Bearer token"}))
+	t.Cleanup(func() { _ = client.Close() })
+
+	tools, err := client.DiscoverTools(context.Background())
+	require.NoError(t, err)
+	byName := toolsByName(tools)
+	require.Len(t, byName, 5)
+
+	result, err := byName["echo"].Execute(context.Background(), map[string]interface{}{"text": "over sse"})
+	require.NoError(t, err)
+	assert.Equal(t, "over sse", result)
+
+	_, err = byName["hang"].Execute(context.Background(), map[string]interface{}{})
+	assert.True(t, errors.Is(err, ErrDisconnected), "unexpected error: %v", err)
+}
+
+func TestStdioTransport(t *testing.T) {
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	// 以测试二进制自身作为stdio服务器
+	client := NewClient("fake", NewStdioTransport(executable, []string{"-test.run=^$"}, fakeServerEnv+"=1"))
+	tools, err := client.DiscoverTools(context.Background())
+	require.NoError(t, err)
+
+	result, err := toolsByName(tools)["echo"].Execute(context.Background(), map[string]interface{}{"text": "from a process"})
+	require.NoError(t, err)
+	assert.Equal(t, "from a process", result)
+	require.NoError(t, client.Close())
+}
+
+func TestAgentToolProviderLifecycle(t *testing.T) {
+	server := newFakeServer()
+	client, stopped := startStreamServer(t, server)
+
+	localEcho := agent.NewBaseTool("echo", "Local echo", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "local", nil
+	})
+	worker, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:          "Maintainer",
+		Goal:          "Triage issues",
+		Backstory:     "Knows the repository",
+		LLM:           llmtest.NewScriptedLLM(llmtest.Reply{Content: "Final Answer: done"}),
+		Tools:         []agent.Tool{localEcho},
+		ToolProviders: []agent.ToolProvider{client},
+		Logger:        logger.NewTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, worker.Initialize())
+	byName := toolsByName(worker.GetTools())
+	require.Len(t, byName, 6)
+	assert.Same(t, localEcho, byName["echo"])
+	require.Contains(t, byName, "fake_echo")
+	assert.Equal(t, "fake_echo", byName["fake_echo"].GetSchema().Name)
+
+	// 重名的远程工具以前缀名称调用，仍然转发到服务器上的原工具
+	toolCtx := agent.NewToolExecutionContext(worker, agent.NewBaseTask("triage", "labels"))
+	result, err := toolCtx.ExecuteTool(context.Background(), "fake_echo", map[string]interface{}{"text": "remote"})
+	require.NoError(t, err)
+	assert.Equal(t, "remote", result)
+
+	// 关闭Agent时断开服务器并移除远程工具
+	require.NoError(t, worker.Close())
+	<-stopped
+	assert.Equal(t, []agent.Tool{localEcho}, worker.GetTools())
+}
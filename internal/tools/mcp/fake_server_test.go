@@ -0,0 +1,218 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeServerEnv 设置后测试二进制作为stdio MCP服务器运行
+const fakeServerEnv = "GREENSOULAI_FAKE_MCP_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "1" {
+		newFakeServer().serveStream(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeServer 进程内的MCP服务器，提供以下工具：
+// echo返回text参数，image返回文本和图片内容块，fail返回isError结果，
+// broken返回JSON-RPC错误，hang不响应并断开连接
+type fakeServer struct {
+	tools    []ToolInfo
+	pageSize int
+
+	mu    sync.Mutex
+	calls []string
+}
+
+type fakeRequest struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+func newFakeServer() *fakeServer {
+	textSchema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+		"required":   []interface{}{"text"},
+	}
+	emptySchema := map[string]interface{}{"type": "object"}
+	return &fakeServer{
+		pageSize: 2,
+		tools: []ToolInfo{
+			{Name: "echo", Description: "Echo the text back", InputSchema: textSchema},
+			{Name: "image", Description: "Return an image", InputSchema: emptySchema},
+			{Name: "fail", Description: "Always fails", InputSchema: emptySchema},
+			{Name: "broken", Description: "Protocol error", InputSchema: emptySchema},
+			{Name: "hang", Description: "Never answers", InputSchema: emptySchema},
+		},
+	}
+}
+
+// handle 处理一条请求，返回响应（通知返回nil）以及是否需要断开连接
+func (s *fakeServer) handle(data []byte) (*message, bool) {
+	var req fakeRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ID == nil {
+		return nil, false
+	}
+	reply := func(result interface{}) *message {
+		raw, _ := json.Marshal(result)
+		return &message{JSONRPC: jsonRPCVersion, ID: req.ID, Result: raw}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return reply(InitializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}},
+			ServerInfo:      Implementation{Name: "fake-server", Version: "0.1.0"},
+		}), false
+	case "tools/list":
+		var params listToolsParams
+		_ = json.Unmarshal(req.Params, &params)
+		start, _ := strconv.Atoi(params.Cursor)
+		end := start + s.pageSize
+		result := listToolsResult{}
+		if end < len(s.tools) {
+			result.NextCursor = strconv.Itoa(end)
+		} else {
+			end = len(s.tools)
+		}
+		result.Tools = s.tools[start:end]
+		return reply(result), false
+	case "tools/call":
+		var params callToolParams
+		_ = json.Unmarshal(req.Params, &params)
+		s.mu.Lock()
+		s.calls = append(s.calls, params.Name)
+		s.mu.Unlock()
+
+		switch params.Name {
+		case "echo":
+			return reply(map[string]interface{}{
+				"content": []map[string]interface{}{{"type": "text", "text": fmt.Sprint(params.Arguments["text"])}},
+			}), false
+		case "image":
+			return reply(map[string]interface{}{
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "a chart"},
+					{"type": "image", "data": "aGVsbG8=", "mimeType": "image/png"},
+				},
+			}), false
+		case "fail":
+			return reply(map[string]interface{}{
+				"content": []map[string]interface{}{{"type": "text", "text": "repository not found"}},
+				"isError": true,
+			}), false
+		case "broken":
+			return &message{JSONRPC: jsonRPCVersion, ID: req.ID, Error: &RPCError{Code: -32000, Message: "internal failure"}}, false
+		case "hang":
+			return nil, true
+		}
+		return &message{JSONRPC: jsonRPCVersion, ID: req.ID, Error: &RPCError{Code: -32602, Message: "unknown tool " + params.Name}}, false
+	}
+	return &message{JSONRPC: jsonRPCVersion, ID: req.ID, Error: &RPCError{Code: -32601, Message: "method not found"}}, false
+}
+
+// serveStream 按行读取请求并写回响应，直到输入结束或需要断开
+func (s *fakeServer) serveStream(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		response, disconnect := s.handle(scanner.Bytes())
+		if disconnect {
+			return
+		}
+		if response != nil {
+			data, _ := json.Marshal(response)
+			_, _ = out.Write(append(data, '\n'))
+		}
+	}
+}
+
+// callCount 返回工具被调用的次数
+func (s *fakeServer) callCount(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, call := range s.calls {
+		if call == name {
+			count++
+		}
+	}
+	return count
+}
+
+// startStreamServer 通过管道连接进程内服务器，返回客户端和服务器结束信号
+func startStreamServer(t *testing.T, server *fakeServer) (*Client, <-chan struct{}) {
+	t.Helper()
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		server.serveStream(serverReader, serverWriter)
+		// 断开连接：关闭服务器两端的管道
+		_ = serverWriter.Close()
+		_ = serverReader.Close()
+	}()
+
+	client := NewClient("fake", NewStreamTransport(clientReader, clientWriter))
+	t.Cleanup(func() { _ = client.Close() })
+	return client, stopped
+}
+
+// startSSEServer 通过HTTP+SSE提供进程内服务器
+func startSSEServer(t *testing.T, server *fakeServer) *httptest.Server {
+	t.Helper()
+	outgoing := make(chan []byte, 16)
+	disconnect := make(chan struct{})
+	var once sync.Once
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\nevent: endpoint\ndata: /messages?session=1\n\n")
+		flusher.Flush()
+		for {
+			select {
+			case data := <-outgoing:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+				flusher.Flush()
+			case <-disconnect:
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		response, shouldDisconnect := server.handle(body)
+		if shouldDisconnect {
+			once.Do(func() { close(disconnect) })
+			return
+		}
+		if response != nil {
+			data, _ := json.Marshal(response)
+			outgoing <- data
+		}
+	})
+
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
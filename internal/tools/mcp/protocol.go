@@ -0,0 +1,128 @@
+// Package mcp 实现Model Context Protocol客户端，把外部MCP服务器提供的工具包装为agent.Tool
+// 支持stdio和SSE两种传输方式
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ProtocolVersion 客户端使用的MCP协议版本
+const ProtocolVersion = "2024-11-05"
+
+const jsonRPCVersion = "2.0"
+
+// JSON-RPC消息，请求、响应和通知共用一个结构
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError 服务器返回的JSON-RPC错误
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// Implementation 客户端或服务器的名称和版本
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type initializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      Implementation         `json:"clientInfo"`
+}
+
+// InitializeResult 服务器对initialize请求的响应
+type InitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      Implementation         `json:"serverInfo"`
+	Instructions    string                 `json:"instructions,omitempty"`
+}
+
+// ToolInfo 服务器声明的工具
+type ToolInfo struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+type listToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type listToolsResult struct {
+	Tools      []ToolInfo `json:"tools"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+type callToolParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// CallToolResult 工具调用结果，IsError为true表示工具执行失败，错误信息在Content中
+type CallToolResult struct {
+	Content []ContentBlock `json:"content"`
+	IsError bool           `json:"isError,omitempty"`
+}
+
+// ContentBlock 工具返回的一个内容块，如text、image或resource
+// Raw保存原始JSON，非文本内容按原样返回给LLM
+type ContentBlock struct {
+	Type string          `json:"type"`
+	Text string          `json:"text,omitempty"`
+	Raw  json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON 解析内容块并保留原始JSON
+func (b *ContentBlock) UnmarshalJSON(data []byte) error {
+	type plain ContentBlock
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*b = ContentBlock(decoded)
+	b.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MarshalJSON 优先输出原始JSON
+func (b ContentBlock) MarshalJSON() ([]byte, error) {
+	if len(b.Raw) > 0 {
+		return b.Raw, nil
+	}
+	type plain ContentBlock
+	return json.Marshal(plain(b))
+}
+
+// Text 把内容块转换为文本：文本块直接拼接，其他内容块输出为JSON
+func (r *CallToolResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+			continue
+		}
+		data, err := json.Marshal(block)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, string(data))
+	}
+	return strings.Join(parts, "\n")
+}
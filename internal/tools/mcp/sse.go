@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SSETransport 通过HTTP+SSE与MCP服务器通信
+// 服务器消息通过GET建立的事件流推送，首个endpoint事件给出客户端POST消息的地址
+type SSETransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu       sync.Mutex
+	endpoint string
+	cancel   context.CancelFunc
+	messages chan []byte
+}
+
+// NewSSETransport 创建SSE传输，headers会附加到所有请求上（如Authorization）
+func NewSSETransport(sseURL string, headers map[string]string) *SSETransport {
+	return &SSETransport{
+		url:      sseURL,
+		headers:  headers,
+		client:   &http.Client{},
+		messages: make(chan []byte, 16),
+	}
+}
+
+// Start 建立事件流并等待服务器发送endpoint事件
+func (t *SSETransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	if t.cancel != nil {
+		t.mu.Unlock()
+		return nil
+	}
+	// 事件流的生命周期由Close控制，不随Start的ctx结束
+	streamCtx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.url, nil)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to create sse request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to connect to mcp sse endpoint: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("failed to connect to mcp sse endpoint: status %d", resp.StatusCode)
+	}
+
+	endpoint := make(chan string, 1)
+	go t.readEvents(resp.Body, endpoint)
+
+	select {
+	case e, ok := <-endpoint:
+		if !ok {
+			cancel()
+			return fmt.Errorf("mcp sse stream closed before endpoint event")
+		}
+		resolved, err := resolveEndpoint(t.url, e)
+		if err != nil {
+			cancel()
+			return err
+		}
+		t.mu.Lock()
+		t.endpoint = resolved
+		t.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
+
+// readEvents 解析事件流：endpoint事件交给Start，message事件转发为服务器消息
+func (t *SSETransport) readEvents(body io.ReadCloser, endpoint chan<- string) {
+	defer close(t.messages)
+	defer body.Close()
+
+	endpointSent := false
+	defer func() {
+		if !endpointSent {
+			close(endpoint)
+		}
+	}()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10<<20)
+	event, data := "message", []string(nil)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				payload := strings.Join(data, "\n")
+				if event == "endpoint" && !endpointSent {
+					endpoint <- payload
+					endpointSent = true
+				} else if event == "message" {
+					t.messages <- []byte(payload)
+				}
+			}
+			event, data = "message", nil
+		case strings.HasPrefix(line, ":"):
+			// 注释行，服务器用于保活
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// Send 把消息POST到服务器给出的地址
+func (t *SSETransport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	endpoint := t.endpoint
+	t.mu.Unlock()
+	if endpoint == "" {
+		return fmt.Errorf("mcp sse transport is not started")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(message))
+	if err != nil {
+		return fmt.Errorf("failed to create mcp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send mcp message: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send mcp message: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Receive 返回服务器消息通道
+func (t *SSETransport) Receive() <-chan []byte {
+	return t.messages
+}
+
+// Close 断开事件流
+func (t *SSETransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return nil
+}
+
+func (t *SSETransport) setHeaders(req *http.Request) {
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// resolveEndpoint 把endpoint事件中的相对地址解析为绝对地址，只允许与事件流同源
+func resolveEndpoint(base, endpoint string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid mcp sse url %q: %w", base, err)
+	}
+	resolved, err := baseURL.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("invalid mcp endpoint %q: %w", endpoint, err)
+	}
+	if resolved.Scheme != baseURL.Scheme || resolved.Host != baseURL.Host {
+		return "", fmt.Errorf("mcp endpoint %q must have the same origin as %q", endpoint, base)
+	}
+	return resolved.String(), nil
+}
@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ErrTransportClosed 传输已关闭
+var ErrTransportClosed = errors.New("mcp transport closed")
+
+// Transport 在客户端和MCP服务器之间传递JSON-RPC消息
+type Transport interface {
+	// Start 建立连接，之后服务器发来的消息从Receive返回的通道读取
+	Start(ctx context.Context) error
+	// Send 发送一条JSON-RPC消息
+	Send(ctx context.Context, message []byte) error
+	// Receive 返回服务器消息通道，连接断开时通道被关闭
+	Receive() <-chan []byte
+	Close() error
+}
+
+// StreamTransport 基于读写流的传输，每行一条JSON-RPC消息
+// stdio传输基于它实现，测试中也可以直接连接到进程内的服务器
+type StreamTransport struct {
+	reader   io.Reader
+	writer   io.WriteCloser
+	messages chan []byte
+
+	writeMu   sync.Mutex
+	startOnce sync.Once
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewStreamTransport 创建流传输，从reader读取服务器消息，向writer写入客户端消息
+func NewStreamTransport(reader io.Reader, writer io.WriteCloser) *StreamTransport {
+	return &StreamTransport{
+		reader:   reader,
+		writer:   writer,
+		messages: make(chan []byte, 16),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Start 开始读取服务器消息
+func (t *StreamTransport) Start(ctx context.Context) error {
+	t.startOnce.Do(func() {
+		go t.readLoop()
+	})
+	return nil
+}
+
+func (t *StreamTransport) readLoop() {
+	defer close(t.messages)
+
+	reader := bufio.NewReader(t.reader)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			select {
+			case t.messages <- line:
+			case <-t.closed:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Send 写入一行消息
+func (t *StreamTransport) Send(ctx context.Context, message []byte) error {
+	select {
+	case <-t.closed:
+		return ErrTransportClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.writer.Write(append(append([]byte(nil), message...), '\n')); err != nil {
+		return fmt.Errorf("failed to write mcp message: %w", err)
+	}
+	return nil
+}
+
+// Receive 返回服务器消息通道
+func (t *StreamTransport) Receive() <-chan []byte {
+	return t.messages
+}
+
+// Close 关闭写入端，reader实现了io.Closer时同时关闭读取端
+func (t *StreamTransport) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		err = t.writer.Close()
+		if closer, ok := t.reader.(io.Closer); ok {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
+
+// StdioTransport 启动MCP服务器子进程，通过其标准输入输出通信
+type StdioTransport struct {
+	command string
+	args    []string
+	env     []string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stream *StreamTransport
+}
+
+// NewStdioTransport 创建stdio传输，env为追加到当前进程环境变量之后的KEY=VALUE列表
+func NewStdioTransport(command string, args []string, env ...string) *StdioTransport {
+	return &StdioTransport{command: command, args: args, env: env}
+}
+
+// Start 启动子进程
+func (t *StdioTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cmd != nil {
+		return nil
+	}
+
+	// 子进程生命周期由Close控制，不随Start的ctx结束
+	cmd := exec.Command(t.command, t.args...)
+	if len(t.env) > 0 {
+		cmd.Env = append(cmd.Environ(), t.env...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin of mcp server: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout of mcp server: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start mcp server %q: %w", t.command, err)
+	}
+
+	t.cmd = cmd
+	t.stream = NewStreamTransport(stdout, stdin)
+	return t.stream.Start(ctx)
+}
+
+// Send 向子进程写入消息
+func (t *StdioTransport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	stream := t.stream
+	t.mu.Unlock()
+	if stream == nil {
+		return fmt.Errorf("mcp stdio transport is not started")
+	}
+	return stream.Send(ctx, message)
+}
+
+// Receive 返回子进程输出的消息通道
+func (t *StdioTransport) Receive() <-chan []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stream == nil {
+		return nil
+	}
+	return t.stream.Receive()
+}
+
+// Close 关闭标准输入让服务器退出，超时未退出时结束子进程
+func (t *StdioTransport) Close() error {
+	t.mu.Lock()
+	cmd, stream := t.cmd, t.stream
+	t.mu.Unlock()
+	if cmd == nil {
+		return nil
+	}
+
+	err := stream.Close()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		<-done
+	}
+	return err
+}
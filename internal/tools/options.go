@@ -0,0 +1,235 @@
+// Package tools 提供可以直接交给Agent使用的内置工具，如HTTP请求、网页抓取和沙箱内的文件读写
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// 内置工具的默认配置
+const (
+	DefaultTimeout          = 30 * time.Second
+	DefaultMaxResponseBytes = 1 << 20 // 1MB
+	DefaultMaxContentLength = 20000   // 网页正文最多返回的字符数
+	DefaultUserAgent        = "GreenSoulAI/1.0 (+https://github.com/ynl/greensoulai)"
+	DefaultMaxFileSize      = 10 << 20  // 写入后文件的最大字节数
+	DefaultMaxReadBytes     = 100 << 10 // 读取文件最多返回的字节数
+	DefaultMaxSearchResults = 100
+	maxRedirects            = 10
+)
+
+// Option 配置内置工具
+type Option func(*options)
+
+type options struct {
+	timeout          time.Duration
+	maxResponseBytes int64
+	maxContentLength int
+	userAgent        string
+	allowLinkLocal   bool
+	maxFileSize      int64
+	maxReadBytes     int64
+	maxSearchResults int
+	logger           logger.Logger
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		timeout:          DefaultTimeout,
+		maxResponseBytes: DefaultMaxResponseBytes,
+		maxContentLength: DefaultMaxContentLength,
+		userAgent:        DefaultUserAgent,
+		maxFileSize:      DefaultMaxFileSize,
+		maxReadBytes:     DefaultMaxReadBytes,
+		maxSearchResults: DefaultMaxSearchResults,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithTimeout 设置单次调用的超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout > 0 {
+			o.timeout = timeout
+		}
+	}
+}
+
+// WithMaxResponseBytes 设置读取响应体的最大字节数，超出部分被截断
+func WithMaxResponseBytes(n int64) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxResponseBytes = n
+		}
+	}
+}
+
+// WithMaxContentLength 设置网页抓取返回正文的最大字符数
+func WithMaxContentLength(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxContentLength = n
+		}
+	}
+}
+
+// WithUserAgent 设置请求使用的User-Agent
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		if userAgent != "" {
+			o.userAgent = userAgent
+		}
+	}
+}
+
+// WithAllowLinkLocal 允许访问链路本地地址（如169.254.169.254云元数据服务），默认禁止以防SSRF
+func WithAllowLinkLocal() Option {
+	return func(o *options) {
+		o.allowLinkLocal = true
+	}
+}
+
+// WithMaxFileSize 设置文件写入后的最大字节数
+func WithMaxFileSize(n int64) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxFileSize = n
+		}
+	}
+}
+
+// WithMaxReadBytes 设置读取文件最多返回的字节数，超出部分截断并标记[truncated]
+func WithMaxReadBytes(n int64) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxReadBytes = n
+		}
+	}
+}
+
+// WithMaxSearchResults 设置目录搜索最多返回的结果数
+func WithMaxSearchResults(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxSearchResults = n
+		}
+	}
+}
+
+// WithLogger 设置记录工具日志的Logger
+func WithLogger(l logger.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// validateURL 只允许http和https地址
+func validateURL(rawURL string) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("url scheme %q is not allowed, only http and https are supported", parsed.Scheme)
+	}
+	if parsed.Hostname() == "" {
+		return nil, fmt.Errorf("url %q has no host", rawURL)
+	}
+	return parsed, nil
+}
+
+// checkAddress 拒绝链路本地和未指定地址
+func (o *options) checkAddress(ip net.IP) error {
+	if o.allowLinkLocal {
+		return nil
+	}
+	if ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("address %s is not allowed: link-local and unspecified addresses are blocked", ip)
+	}
+	return nil
+}
+
+// newClient 创建带SSRF防护的HTTP客户端，每个工具创建一次，调用之间复用连接
+// 代理、TLS配置（如自定义CA）和连接池大小沿用llm.ConfigureHTTPClient设置的共享配置。
+// 直连时地址在DNS解析后、建立连接前检查；经代理转发时连接的是代理，因此在交给代理之前解析并检查目标地址。
+// 重定向目标同样需要通过协议和地址检查
+func (o *options) newClient() *http.Client {
+	transport := llm.NewHTTPTransport(llm.SharedHTTPClientConfig())
+
+	dialer := &net.Dialer{
+		Timeout: o.timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("unexpected dial address %q", address)
+			}
+			return o.checkAddress(ip)
+		},
+	}
+	transport.DialContext = dialer.DialContext
+
+	proxy := transport.Proxy
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxy(req)
+		if err != nil || proxyURL == nil {
+			return proxyURL, err
+		}
+		if err := o.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		return proxyURL, nil
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			_, err := validateURL(req.URL.String())
+			return err
+		},
+	}
+}
+
+// checkHost 解析主机名并检查所有地址，用于经代理转发的请求
+// 本地无法解析的主机名（如只有代理能解析的内网域名）交给代理处理
+func (o *options) checkHost(ctx context.Context, host string) error {
+	if o.allowLinkLocal {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return o.checkAddress(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := o.checkAddress(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withTimeout 为单次调用设置超时，同时保留调用方的取消信号
+func (o *options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, o.timeout)
+}
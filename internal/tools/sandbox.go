@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// sandbox 把文件工具的访问限制在根目录内
+type sandbox struct {
+	root string // 已解析符号链接的绝对路径
+}
+
+// newSandbox 创建沙箱，根目录必须是已存在的目录
+func newSandbox(root string) (*sandbox, error) {
+	if root == "" {
+		return nil, fmt.Errorf("sandbox root is required")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox root %q: %w", root, err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox root %q: %w", root, err)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox root %q: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("sandbox root %q is not a directory", root)
+	}
+	return &sandbox{root: resolved}, nil
+}
+
+// resolve 把相对于根目录的路径解析为绝对路径
+// 路径经过Clean和符号链接解析后仍必须位于根目录内；不存在的部分按最近的已存在上级目录解析
+func (s *sandbox) resolve(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("path is required")
+	}
+
+	target := filepath.Clean(path)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(s.root, target)
+	}
+	if !s.contains(target) {
+		return "", fmt.Errorf("path %q escapes sandbox root", path)
+	}
+
+	existing, rest := target, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			target = filepath.Join(resolved, rest)
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to resolve path %q: %w", path, err)
+		}
+		// 悬空的符号链接在写入时会被跟随到未知位置
+		if _, lstatErr := os.Lstat(existing); lstatErr == nil {
+			return "", fmt.Errorf("path %q contains a dangling symlink", path)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	if !s.contains(target) {
+		return "", fmt.Errorf("path %q escapes sandbox root", path)
+	}
+	return target, nil
+}
+
+// contains 判断路径是否位于根目录内
+func (s *sandbox) contains(path string) bool {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// relative 返回相对于根目录的路径，用于工具输出
+func (s *sandbox) relative(path string) string {
+	if rel, err := filepath.Rel(s.root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// fileLocks 按路径串行化写入，多个任务并行写同一文件时互不覆盖
+var fileLocks sync.Map
+
+func lockFile(path string) func() {
+	value, _ := fileLocks.LoadOrStore(path, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
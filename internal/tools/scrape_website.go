@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+const scrapeWebsiteDescription = "Fetch a web page and return its title and readable text content. " +
+	"Use it to read articles, documentation or any public web page."
+
+// 内容不可读、需要整体跳过的元素
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "iframe": true,
+}
+
+// 转换为换行的块级元素
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "ul": true, "ol": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true, "pre": true,
+	"section": true, "article": true, "header": true, "footer": true, "nav": true, "main": true,
+	"blockquote": true, "dd": true, "dt": true, "figcaption": true,
+}
+
+// NewScrapeWebsiteTool 创建网页抓取工具
+// 抓取url指定的网页，去掉HTML标签后返回标题和正文，正文超过最大长度时截断
+func NewScrapeWebsiteTool(opts ...Option) agent.Tool {
+	o := newOptions(opts)
+	client := o.newClient()
+
+	schema := agent.NewToolSchema("scrape_website", scrapeWebsiteDescription,
+		agent.ToolParameter{Name: "url", Type: "string", Description: "The http or https URL of the page to read", Required: true},
+	)
+
+	return agent.NewBaseToolWithSchema("scrape_website", scrapeWebsiteDescription, schema,
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			rawURL, _ := args["url"].(string)
+			target, err := validateURL(rawURL)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx, cancel := o.withTimeout(ctx)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Header.Set("User-Agent", o.userAgent)
+			req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.8")
+
+			if o.logger != nil {
+				o.logger.Debug("scraping website", logger.Field{Key: "url", Value: target.Redacted()})
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch %s: %w", target.Redacted(), err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode >= http.StatusBadRequest {
+				return nil, fmt.Errorf("failed to fetch %s: status %d", target.Redacted(), resp.StatusCode)
+			}
+
+			content, _, err := readLimited(resp.Body, o.maxResponseBytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", target.Redacted(), err)
+			}
+
+			title, text := htmlToText(string(content))
+			text, truncated := truncateRunes(text, o.maxContentLength)
+
+			return map[string]interface{}{
+				"url":       resp.Request.URL.String(),
+				"title":     title,
+				"content":   text,
+				"truncated": truncated,
+			}, nil
+		},
+	).WithNondeterministic()
+}
+
+// htmlToText 从HTML中提取标题和可读文本
+// 跳过脚本、样式和注释，块级元素转换为换行，解码HTML实体并压缩空白
+func htmlToText(document string) (string, string) {
+	var title string
+	var text strings.Builder
+
+	rest := document
+	for {
+		start := strings.IndexByte(rest, '<')
+		if start < 0 {
+			text.WriteString(rest)
+			break
+		}
+		text.WriteString(rest[:start])
+		rest = rest[start:]
+
+		if strings.HasPrefix(rest, "<!--") {
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				break
+			}
+			rest = rest[end+3:]
+			continue
+		}
+
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			break
+		}
+		name, closing := tagName(rest[1:end])
+		selfClosing := strings.HasSuffix(rest[:end], "/")
+		rest = rest[end+1:]
+
+		switch {
+		case closing:
+			if blockElements[name] {
+				text.WriteByte('\n')
+			}
+		case name == "title":
+			content, remaining := elementContent(rest, name)
+			if title == "" {
+				title = normalizeWhitespace(html.UnescapeString(content))
+			}
+			rest = remaining
+		case skippedElements[name] && !selfClosing:
+			_, rest = elementContent(rest, name)
+		case blockElements[name]:
+			text.WriteByte('\n')
+		}
+	}
+
+	lines := strings.Split(html.UnescapeString(text.String()), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = normalizeWhitespace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return title, strings.Join(kept, "\n")
+}
+
+// tagName 解析标签名，返回小写名称以及是否为结束标签
+func tagName(tag string) (string, bool) {
+	closing := strings.HasPrefix(tag, "/")
+	tag = strings.TrimPrefix(tag, "/")
+	if end := strings.IndexAny(tag, " \t\r\n/"); end >= 0 {
+		tag = tag[:end]
+	}
+	return strings.ToLower(tag), closing
+}
+
+// elementContent 返回元素结束标签之前的内容和结束标签之后的剩余文档
+func elementContent(document, name string) (string, string) {
+	for offset := 0; ; {
+		i := strings.Index(document[offset:], "</")
+		if i < 0 {
+			return document, ""
+		}
+		end := offset + i
+		candidate := document[end+2:]
+		if len(candidate) >= len(name) && strings.EqualFold(candidate[:len(name)], name) {
+			remaining := document[end:]
+			if tagEnd := strings.IndexByte(remaining, '>'); tagEnd >= 0 {
+				return document[:end], remaining[tagEnd+1:]
+			}
+			return document[:end], ""
+		}
+		offset = end + 2
+	}
+}
+
+// normalizeWhitespace 把连续空白压缩为一个空格
+func normalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncateRunes 按字符截断文本，返回是否发生截断
+func truncateRunes(s string, limit int) (string, bool) {
+	if utf8.RuneCountInString(s) <= limit {
+		return s, false
+	}
+	runes := []rune(s)
+	return string(runes[:limit]), true
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Go &amp; Agents</title>
+  <style>body { color: red; }</style>
+  <script>var tracking = "ignore me";</script>
+</head>
+<body>
+  <!-- navigation omitted -->
+  <h1>Building   agents</h1>
+  <p>Agents use <a href="/tools">tools</a> to act.</p>
+  <ul><li>Search</li><li>Scrape</li></ul>
+  <SCRIPT type="text/javascript">alert("x")</SCRIPT>
+</body>
+</html>`
+
+func TestHTMLToText(t *testing.T) {
+	title, text := htmlToText(testPage)
+	assert.Equal(t, "Go & Agents", title)
+	assert.Equal(t, "Building agents\nAgents use tools to act.\nSearch\nScrape", text)
+}
+
+func TestScrapeWebsiteTool(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	tool := NewScrapeWebsiteTool(WithUserAgent("test-agent/1.0"), WithMaxContentLength(15))
+	assert.Equal(t, []string{"url"}, tool.GetSchema().Parameters["required"])
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	require.NoError(t, err)
+
+	page := result.(map[string]interface{})
+	assert.Equal(t, "test-agent/1.0", userAgent)
+	assert.Equal(t, "Go & Agents", page["title"])
+	assert.Equal(t, "Building agents", page["content"])
+	assert.Equal(t, true, page["truncated"])
+}
+
+func TestScrapeWebsiteToolErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	tool := NewScrapeWebsiteTool()
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 404")
+
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"url": "http://[fe80::1]/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "link-local and unspecified addresses are blocked")
+}
@@ -1,97 +1,75 @@
 package training
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
-// FeedbackCollector 人工反馈收集器
-type FeedbackCollector struct {
-	logger logger.Logger
+// FeedbackCollector 人工反馈收集器，在每次训练迭代后收集对输出的反馈
+type FeedbackCollector interface {
+	// CollectFeedback 收集指定迭代输出的反馈，timeout为等待反馈的最长时间
+	CollectFeedback(ctx context.Context, iterationID string, outputs interface{}, timeout time.Duration) (*HumanFeedback, error)
 }
 
-// NewFeedbackCollector 创建新的反馈收集器
-func NewFeedbackCollector(logger logger.Logger) *FeedbackCollector {
-	return &FeedbackCollector{
-		logger: logger,
+// ConsoleFeedbackCollector 通过HumanInputHandler交互式收集质量评分和改进建议
+type ConsoleFeedbackCollector struct {
+	inputHandler agent.HumanInputHandler
+	logger       logger.Logger
+}
+
+// NewFeedbackCollector 创建使用控制台输入的反馈收集器
+func NewFeedbackCollector(logger logger.Logger) *ConsoleFeedbackCollector {
+	return NewConsoleFeedbackCollector(agent.NewConsoleInputHandler(logger), logger)
+}
+
+// NewConsoleFeedbackCollector 创建使用指定人工输入处理器的反馈收集器
+func NewConsoleFeedbackCollector(inputHandler agent.HumanInputHandler, logger logger.Logger) *ConsoleFeedbackCollector {
+	return &ConsoleFeedbackCollector{
+		inputHandler: inputHandler,
+		logger:       logger,
 	}
 }
 
-// CollectFeedback 收集人工反馈
-func (fc *FeedbackCollector) CollectFeedback(ctx context.Context, iterationID string, outputs interface{}, timeout time.Duration) (*HumanFeedback, error) {
+// CollectFeedback 展示迭代输出并收集质量评分和改进建议
+// 超时未输入时返回中性反馈，训练可以继续；上下文被取消时返回错误
+func (fc *ConsoleFeedbackCollector) CollectFeedback(ctx context.Context, iterationID string, outputs interface{}, timeout time.Duration) (*HumanFeedback, error) {
 	fc.logger.Info("collecting human feedback",
 		logger.Field{Key: "iteration_id", Value: iterationID},
 		logger.Field{Key: "timeout", Value: timeout},
 	)
 
-	// 创建反馈对象
-	feedback := &HumanFeedback{
-		IterationID: iterationID,
-		Timestamp:   time.Now(),
-		Categories:  make(map[string]float64),
-		Tags:        make([]string, 0),
-		Issues:      make([]string, 0),
-	}
-
 	// 显示输出内容
-	fmt.Printf("\n" + strings.Repeat("=", 80) + "\n")
+	fmt.Printf("\n%s\n", strings.Repeat("=", 80))
 	fmt.Printf("🤖 TRAINING ITERATION OUTPUT\n")
 	fmt.Printf("Iteration ID: %s\n", iterationID)
-	fmt.Printf(strings.Repeat("-", 80) + "\n")
-
-	// 格式化输出显示
-	outputStr := fc.formatOutput(outputs)
-	fmt.Printf("Output:\n%s\n", outputStr)
-	fmt.Printf(strings.Repeat("-", 80) + "\n")
+	fmt.Printf("%s\n", strings.Repeat("-", 80))
+	fmt.Printf("Output:\n%s\n", fc.formatOutput(outputs))
+	fmt.Printf("%s\n", strings.Repeat("-", 80))
 
 	// 创建超时上下文
-	feedbackCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	// 在goroutine中收集反馈
-	feedbackChan := make(chan *HumanFeedback, 1)
-	errorChan := make(chan error, 1)
-
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				errorChan <- fmt.Errorf("feedback collection panicked: %v", r)
-			}
-		}()
-
-		collectedFeedback, err := fc.collectFeedbackInteractive(feedback)
-		if err != nil {
-			errorChan <- err
-			return
-		}
-
-		feedbackChan <- collectedFeedback
-	}()
+	feedbackCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		feedbackCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
-	// 等待反馈或超时
-	select {
-	case feedback := <-feedbackChan:
+	feedback, err := fc.collectFeedbackInteractive(feedbackCtx, iterationID)
+	if err == nil {
 		fc.logger.Info("feedback collected successfully",
 			logger.Field{Key: "iteration_id", Value: iterationID},
 			logger.Field{Key: "quality_score", Value: feedback.QualityScore},
 		)
 		return feedback, nil
+	}
 
-	case err := <-errorChan:
-		fc.logger.Error("feedback collection error",
-			logger.Field{Key: "iteration_id", Value: iterationID},
-			logger.Field{Key: "error", Value: err},
-		)
-		return nil, err
-
-	case <-feedbackCtx.Done():
+	if ctx.Err() == nil && feedbackCtx.Err() != nil {
 		fc.logger.Warn("feedback collection timeout",
 			logger.Field{Key: "iteration_id", Value: iterationID},
 			logger.Field{Key: "timeout", Value: timeout},
@@ -110,85 +88,54 @@ func (fc *FeedbackCollector) CollectFeedback(ctx context.Context, iterationID st
 			Issues:        []string{},
 		}, nil
 	}
-}
 
-// collectFeedbackInteractive 交互式收集反馈
-func (fc *FeedbackCollector) collectFeedbackInteractive(feedback *HumanFeedback) (*HumanFeedback, error) {
-	reader := bufio.NewReader(os.Stdin)
+	fc.logger.Error("feedback collection error",
+		logger.Field{Key: "iteration_id", Value: iterationID},
+		logger.Field{Key: "error", Value: err},
+	)
+	return nil, err
+}
 
-	// 收集质量分数
-	fmt.Printf("\n📊 Please provide feedback (press Enter to skip any question):\n\n")
+// collectFeedbackInteractive 依次询问质量评分和改进建议
+func (fc *ConsoleFeedbackCollector) collectFeedbackInteractive(ctx context.Context, iterationID string) (*HumanFeedback, error) {
+	metadata := map[string]interface{}{"iteration_id": iterationID}
 
-	qualityScore, err := fc.askForScore(reader, "Quality Score (1-10)", 5.0)
+	scoreResponse, err := fc.inputHandler.RequestInput(ctx, agent.HumanInputRequest{
+		Type:     agent.HumanInputRequestInput,
+		Prompt:   "Quality Score (1-10) [default: 5.0]",
+		Metadata: metadata,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get quality score: %w", err)
 	}
-	feedback.QualityScore = qualityScore
 
-	// 收集准确性分数
-	accuracyScore, err := fc.askForScore(reader, "Accuracy Score (1-10)", 5.0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get accuracy score: %w", err)
-	}
-	feedback.AccuracyScore = accuracyScore
-
-	// 收集有用性分数
-	usefulnessScore, err := fc.askForScore(reader, "Usefulness Score (1-10)", 5.0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get usefulness score: %w", err)
-	}
-	feedback.Usefulness = usefulnessScore
-
-	// 收集文本反馈
-	comments, err := fc.askForText(reader, "Comments/Feedback")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get comments: %w", err)
-	}
-	feedback.Comments = comments
-
-	// 收集改进建议
-	suggestions, err := fc.askForText(reader, "Suggestions for improvement")
+	suggestionsResponse, err := fc.inputHandler.RequestInput(ctx, agent.HumanInputRequest{
+		Type:     agent.HumanInputRequestInput,
+		Prompt:   "Suggestions for improvement (press Enter to skip)",
+		Metadata: metadata,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get suggestions: %w", err)
 	}
-	feedback.Suggestions = suggestions
-
-	// 收集问题
-	issues, err := fc.askForList(reader, "Issues/Problems (comma-separated)")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get issues: %w", err)
-	}
-	feedback.Issues = issues
-
-	// 收集标签
-	tags, err := fc.askForList(reader, "Tags (comma-separated)")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get tags: %w", err)
-	}
-	feedback.Tags = tags
-
-	// 设置验证信息
-	feedback.Verified = true
-	feedback.VerifiedBy = "human"
 
-	fmt.Printf("\n✅ Feedback collected successfully!\n")
-	fmt.Printf(strings.Repeat("=", 80) + "\n\n")
-
-	return feedback, nil
+	return &HumanFeedback{
+		IterationID:  iterationID,
+		Timestamp:    time.Now(),
+		QualityScore: fc.parseScore(scoreResponse.Text, 5.0),
+		Suggestions:  strings.TrimSpace(suggestionsResponse.Text),
+		Categories:   make(map[string]float64),
+		Tags:         make([]string, 0),
+		Issues:       make([]string, 0),
+		Verified:     true,
+		VerifiedBy:   "human",
+	}, nil
 }
 
-// askForScore 询问评分
-func (fc *FeedbackCollector) askForScore(reader *bufio.Reader, prompt string, defaultValue float64) (float64, error) {
-	fmt.Printf("%s [default: %.1f]: ", prompt, defaultValue)
-
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		return 0, err
-	}
-
+// parseScore 解析1-10分的评分，空输入或无效输入使用默认值，超出范围的分数截断到边界
+func (fc *ConsoleFeedbackCollector) parseScore(input string, defaultValue float64) float64 {
 	input = strings.TrimSpace(input)
 	if input == "" {
-		return defaultValue, nil
+		return defaultValue
 	}
 
 	score, err := strconv.ParseFloat(input, 64)
@@ -197,7 +144,7 @@ func (fc *FeedbackCollector) askForScore(reader *bufio.Reader, prompt string, de
 			logger.Field{Key: "input", Value: input},
 			logger.Field{Key: "default", Value: defaultValue},
 		)
-		return defaultValue, nil
+		return defaultValue
 	}
 
 	// 确保分数在1-10范围内
@@ -207,47 +154,11 @@ func (fc *FeedbackCollector) askForScore(reader *bufio.Reader, prompt string, de
 		score = 10
 	}
 
-	return score, nil
-}
-
-// askForText 询问文本输入
-func (fc *FeedbackCollector) askForText(reader *bufio.Reader, prompt string) (string, error) {
-	fmt.Printf("%s: ", prompt)
-
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		return "", err
-	}
-
-	return strings.TrimSpace(input), nil
-}
-
-// askForList 询问列表输入
-func (fc *FeedbackCollector) askForList(reader *bufio.Reader, prompt string) ([]string, error) {
-	text, err := fc.askForText(reader, prompt)
-	if err != nil {
-		return nil, err
-	}
-
-	if text == "" {
-		return []string{}, nil
-	}
-
-	items := strings.Split(text, ",")
-	result := make([]string, 0, len(items))
-
-	for _, item := range items {
-		item = strings.TrimSpace(item)
-		if item != "" {
-			result = append(result, item)
-		}
-	}
-
-	return result, nil
+	return score
 }
 
 // formatOutput 格式化输出内容
-func (fc *FeedbackCollector) formatOutput(outputs interface{}) string {
+func (fc *ConsoleFeedbackCollector) formatOutput(outputs interface{}) string {
 	switch v := outputs.(type) {
 	case string:
 		return v
@@ -269,7 +180,7 @@ func (fc *FeedbackCollector) formatOutput(outputs interface{}) string {
 }
 
 // CollectBatchFeedback 批量收集反馈（非交互式）
-func (fc *FeedbackCollector) CollectBatchFeedback(ctx context.Context, iterationID string, outputs interface{}, feedbackData map[string]interface{}) (*HumanFeedback, error) {
+func (fc *ConsoleFeedbackCollector) CollectBatchFeedback(ctx context.Context, iterationID string, outputs interface{}, feedbackData map[string]interface{}) (*HumanFeedback, error) {
 	feedback := &HumanFeedback{
 		IterationID: iterationID,
 		Timestamp:   time.Now(),
@@ -328,7 +239,7 @@ func (fc *FeedbackCollector) CollectBatchFeedback(ctx context.Context, iteration
 }
 
 // ValidateFeedback 验证反馈数据
-func (fc *FeedbackCollector) ValidateFeedback(feedback *HumanFeedback) error {
+func (fc *ConsoleFeedbackCollector) ValidateFeedback(feedback *HumanFeedback) error {
 	if feedback.QualityScore < 1 || feedback.QualityScore > 10 {
 		return fmt.Errorf("quality score must be between 1 and 10, got %.2f", feedback.QualityScore)
 	}
@@ -2,12 +2,15 @@ package training
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
@@ -386,3 +389,40 @@ func TestFeedbackTimestamp(t *testing.T) {
 	assert.True(t, feedback.Timestamp.After(beforeTime) || feedback.Timestamp.Equal(beforeTime))
 	assert.True(t, feedback.Timestamp.Before(afterTime) || feedback.Timestamp.Equal(afterTime))
 }
+
+// TestConsoleFeedbackCollectorUsesInputHandler 通过HumanInputHandler收集质量评分和改进建议
+func TestConsoleFeedbackCollectorUsesInputHandler(t *testing.T) {
+	testLogger := logger.NewTestLogger()
+	collector := NewConsoleFeedbackCollector(agent.NewMockInputHandler([]string{"12", "多引用数据"}, testLogger), testLogger)
+
+	feedback, err := collector.CollectFeedback(context.Background(), "iter-1", "output", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "iter-1", feedback.IterationID)
+	assert.Equal(t, 10.0, feedback.QualityScore) // 超出范围截断为10
+	assert.Equal(t, "多引用数据", feedback.Suggestions)
+	assert.Equal(t, "human", feedback.VerifiedBy)
+}
+
+// TestJSONLFeedbackCollector 按顺序读取反馈，用完后返回错误
+func TestJSONLFeedbackCollector(t *testing.T) {
+	testLogger := logger.NewTestLogger()
+	path := filepath.Join(t.TempDir(), "feedback.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"quality_score": 7, "suggestions": "补充示例"}`+"\n"), 0644))
+
+	collector, err := NewJSONLFeedbackCollector(path, testLogger)
+	require.NoError(t, err)
+
+	feedback, err := collector.CollectFeedback(context.Background(), "iter-1", "output", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, feedback.QualityScore)
+	assert.Equal(t, "补充示例", feedback.Suggestions)
+	assert.Equal(t, "jsonl", feedback.VerifiedBy)
+
+	_, err = collector.CollectFeedback(context.Background(), "iter-2", "output", time.Second)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"quality_score": 7}`+"\n"+`{"quality_score": 0}`+"\n"), 0644))
+	_, err = NewJSONLFeedbackCollector(path, testLogger)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ":2:")
+}
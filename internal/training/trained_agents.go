@@ -0,0 +1,168 @@
+package training
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// TrainedAgentData 训练结束时为某个Agent总结出的改进指令，对应Python版本trained_agents_data中的条目
+type TrainedAgentData struct {
+	Suggestions  []string `json:"suggestions"`   // 之后执行任务时要遵循的指令
+	Quality      float64  `json:"quality"`       // 训练期间输出的综合质量，0-10分
+	FinalSummary string   `json:"final_summary"` // 训练反馈的总结
+}
+
+// iterationTaskOutput 迭代输出中单个任务的输出，对应crew.TrainingOutputs中tasks的元素
+type iterationTaskOutput struct {
+	Description string `json:"description"`
+	Agent       string `json:"agent"`
+	Raw         string `json:"raw"`
+}
+
+// maxSummaryOutputLength 总结提示中每个任务输出保留的最大字符数
+const maxSummaryOutputLength = 2000
+
+// AgentInstructionSummarizer 训练结束时让LLM把人工反馈提炼为每个Agent的改进指令
+type AgentInstructionSummarizer struct {
+	llm    llm.LLM
+	logger logger.Logger
+}
+
+// NewAgentInstructionSummarizer 创建改进指令总结器
+func NewAgentInstructionSummarizer(summaryLLM llm.LLM, logger logger.Logger) *AgentInstructionSummarizer {
+	return &AgentInstructionSummarizer{
+		llm:    summaryLLM,
+		logger: logger,
+	}
+}
+
+// Summarize 按Agent角色汇总有反馈的迭代，为每个角色生成改进指令
+// 单个角色总结失败只记录警告；所有角色都失败时返回错误
+func (s *AgentInstructionSummarizer) Summarize(ctx context.Context, data *TrainingData) (map[string]*TrainedAgentData, error) {
+	if s.llm == nil {
+		return nil, fmt.Errorf("summary LLM not configured")
+	}
+
+	sessions := s.agentSessions(data)
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("no iteration with human feedback to summarize")
+	}
+
+	roles := make([]string, 0, len(sessions))
+	for role := range sessions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	trained := make(map[string]*TrainedAgentData, len(roles))
+	var lastErr error
+	for _, role := range roles {
+		agentData, err := s.summarizeAgent(ctx, role, sessions[role])
+		if err != nil {
+			lastErr = err
+			s.logger.Warn("failed to summarize trained instructions",
+				logger.Field{Key: "agent_role", Value: role},
+				logger.Field{Key: "error", Value: err},
+			)
+			continue
+		}
+		trained[role] = agentData
+	}
+
+	if len(trained) == 0 {
+		return nil, fmt.Errorf("failed to summarize trained instructions: %w", lastErr)
+	}
+	return trained, nil
+}
+
+// agentSessions 按Agent角色整理每次有反馈的迭代中该Agent的任务输出
+func (s *AgentInstructionSummarizer) agentSessions(data *TrainingData) map[string][]string {
+	sessions := make(map[string][]string)
+	for _, iteration := range data.Iterations {
+		if iteration.Feedback == nil || !iteration.Success {
+			continue
+		}
+		for _, task := range taskOutputs(iteration.Outputs) {
+			if task.Agent == "" {
+				continue
+			}
+			raw := task.Raw
+			if len(raw) > maxSummaryOutputLength {
+				raw = raw[:maxSummaryOutputLength] + "..."
+			}
+			feedback := iteration.Feedback
+			sessions[task.Agent] = append(sessions[task.Agent], fmt.Sprintf(`Iteration %d:
+Task: %s
+Agent Output:
+%s
+Human Quality Score: %.1f/10
+Human Suggestions: %s
+Human Comments: %s`, iteration.Index+1, task.Description, raw, feedback.QualityScore, feedback.Suggestions, feedback.Comments))
+		}
+	}
+	return sessions
+}
+
+// summarizeAgent 调用LLM为单个Agent总结改进指令
+func (s *AgentInstructionSummarizer) summarizeAgent(ctx context.Context, role string, sessions []string) (*TrainedAgentData, error) {
+	query := fmt.Sprintf(`You are reviewing the training sessions of an AI agent with the role "%s".
+For each iteration you get the task, the agent's output and the human feedback on the crew's result.
+
+%s
+
+Based on the human feedback, write concise, actionable instructions the agent must follow in future tasks.
+Return only a JSON object: {"suggestions": ["<instruction>", ...], "quality": <overall quality from 0 to 10>, "final_summary": "<summary of the feedback>"}`,
+		role, strings.Join(sessions, "\n\n"))
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are an expert coach distilling human feedback into instructions for AI agents."},
+		{Role: llm.RoleUser, Content: query},
+	}
+
+	response, err := s.llm.Call(ctx, messages, &llm.CallOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call LLM: %w", err)
+	}
+
+	var agentData TrainedAgentData
+	if err := json.Unmarshal([]byte(jsonObject(response.Content)), &agentData); err != nil {
+		return nil, fmt.Errorf("invalid summary response: %w", err)
+	}
+	if len(agentData.Suggestions) == 0 {
+		return nil, fmt.Errorf("summary response contains no suggestions")
+	}
+	return &agentData, nil
+}
+
+// taskOutputs 从迭代输出中取出各任务的输出，兼容执行时的输出和从文件加载的输出
+func taskOutputs(outputs interface{}) []iterationTaskOutput {
+	values, ok := outputs.(map[string]interface{})
+	if !ok || values["tasks"] == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(values["tasks"])
+	if err != nil {
+		return nil
+	}
+	var tasks []iterationTaskOutput
+	if err := json.Unmarshal(encoded, &tasks); err != nil {
+		return nil
+	}
+	return tasks
+}
+
+// jsonObject 取出回复中的JSON对象，回复可能包含markdown代码块或说明文字
+func jsonObject(content string) string {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return content
+	}
+	return content[start : end+1]
+}
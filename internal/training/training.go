@@ -3,6 +3,8 @@ package training
 import (
 	"context"
 	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
 )
 
 // TrainingHandler 定义训练处理器接口
@@ -96,15 +98,20 @@ type TrainingData struct {
 	Config    *TrainingConfig `json:"config"`
 
 	// 训练会话信息
-	SessionID string `json:"session_id"`
-	CrewName  string `json:"crew_name"`
-	TotalRuns int    `json:"total_runs"`
+	SessionID  string `json:"session_id"`
+	CrewName   string `json:"crew_name"`
+	TotalRuns  int    `json:"total_runs"`
+	Status     string `json:"status"`                // running、completed或stopped
+	StopReason string `json:"stop_reason,omitempty"` // 会话停止的原因，如interrupted
 
 	// 迭代数据
 	Iterations []*IterationData `json:"iterations"`
 
 	// 汇总信息
 	Summary *TrainingSummary `json:"summary"`
+
+	// 训练结束时按Agent角色总结出的改进指令，可通过crew的LoadTraining应用到Agent
+	TrainedAgents map[string]*TrainedAgentData `json:"trained_agents,omitempty"`
 }
 
 // IterationData 单次迭代数据
@@ -121,6 +128,12 @@ type IterationData struct {
 	Success bool                   `json:"success"`
 	Error   string                 `json:"error,omitempty"`
 
+	// 本次迭代的token使用量
+	TokensUsed int `json:"tokens_used"`
+
+	// 本次迭代的使用统计，执行输出中带有usage时记录
+	Usage *agent.UsageMetrics `json:"usage,omitempty"`
+
 	// 反馈数据
 	Feedback *HumanFeedback `json:"feedback,omitempty"`
 
@@ -246,8 +259,9 @@ type TrainingSummary struct {
 	AverageFeedback float64 `json:"average_feedback"`
 
 	// 资源使用
-	TotalTokens   int `json:"total_tokens"`
-	AverageTokens int `json:"average_tokens"`
+	TotalTokens   int                 `json:"total_tokens"`
+	AverageTokens int                 `json:"average_tokens"`
+	Usage         *agent.UsageMetrics `json:"usage,omitempty"` // 各迭代使用统计的合计
 
 	// 建议
 	Recommendations []string `json:"recommendations"`
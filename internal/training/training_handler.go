@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
@@ -31,10 +32,23 @@ type CrewTrainingHandler struct {
 	dataMu   sync.RWMutex
 
 	// 反馈收集
-	feedbackCollector *FeedbackCollector
+	feedbackCollector FeedbackCollector
 	metricsAnalyzer   *MetricsAnalyzer
+
+	// 训练完成时把反馈总结为Agent改进指令，为空时不总结
+	instructionSummarizer *AgentInstructionSummarizer
+
+	// 本次会话是否已备份过训练文件，备份只在会话第一次保存时进行
+	backedUp bool
 }
 
+// 训练会话停止的原因
+const (
+	StopReasonCompleted   = "completed"   // 所有迭代执行完成或触发早停
+	StopReasonManual      = "manual_stop" // 调用StopTraining手动停止
+	StopReasonInterrupted = "interrupted" // 上下文被取消，如用户按下Ctrl+C
+)
+
 // NewCrewTrainingHandler 创建新的训练处理器
 func NewCrewTrainingHandler(eventBus events.EventBus, logger logger.Logger) *CrewTrainingHandler {
 	return &CrewTrainingHandler{
@@ -50,6 +64,16 @@ func NewCrewTrainingHandler(eventBus events.EventBus, logger logger.Logger) *Cre
 	}
 }
 
+// SetFeedbackCollector 设置人工反馈收集器，默认使用控制台收集器
+func (th *CrewTrainingHandler) SetFeedbackCollector(collector FeedbackCollector) {
+	th.feedbackCollector = collector
+}
+
+// SetInstructionSummarizer 设置改进指令总结器，训练完成时为每个Agent总结改进指令
+func (th *CrewTrainingHandler) SetInstructionSummarizer(summarizer *AgentInstructionSummarizer) {
+	th.instructionSummarizer = summarizer
+}
+
 // StartTraining 开始训练过程
 func (th *CrewTrainingHandler) StartTraining(ctx context.Context, config *TrainingConfig) error {
 	th.statusMu.Lock()
@@ -78,9 +102,11 @@ func (th *CrewTrainingHandler) StartTraining(ctx context.Context, config *Traini
 		Config:     config,
 		SessionID:  uuid.New().String(),
 		TotalRuns:  0,
+		Status:     "running",
 		Iterations: make([]*IterationData, 0, config.Iterations),
 		Summary:    &TrainingSummary{},
 	}
+	th.backedUp = false
 	th.dataMu.Unlock()
 
 	// 发射训练开始事件
@@ -144,10 +170,23 @@ func (th *CrewTrainingHandler) ExecuteIteration(ctx context.Context, executeFunc
 		err = nil
 	}
 
+	// 执行被中断时迭代不完整，不记录也不收集反馈
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		th.logger.Warn("training iteration interrupted",
+			logger.Field{Key: "iteration", Value: iterationIndex},
+		)
+		return nil, ctxErr
+	}
+
 	duration := time.Since(startTime)
 	iteration.Duration = duration
 	iteration.Outputs = outputs
 	iteration.Success = err == nil
+	iteration.TokensUsed = tokensUsed(outputs)
+	if usage := usageMetrics(outputs); usage != nil {
+		iteration.Usage = usage
+		iteration.TokensUsed = usage.TotalTokens
+	}
 
 	if err != nil {
 		iteration.Error = err.Error()
@@ -171,7 +210,7 @@ func (th *CrewTrainingHandler) ExecuteIteration(ctx context.Context, executeFunc
 	}
 
 	// 收集人工反馈
-	if th.config.CollectFeedback {
+	if th.config.CollectFeedback && th.feedbackCollector != nil {
 		feedback, feedbackErr := th.feedbackCollector.CollectFeedback(ctx, iterationID, outputs, th.config.FeedbackTimeout)
 		if feedbackErr != nil {
 			th.logger.Warn("failed to collect feedback",
@@ -179,7 +218,9 @@ func (th *CrewTrainingHandler) ExecuteIteration(ctx context.Context, executeFunc
 				logger.Field{Key: "error", Value: feedbackErr},
 			)
 		} else if feedback != nil {
+			feedback.IterationID = iterationID
 			iteration.Feedback = feedback
+			th.eventBus.Emit(ctx, th, NewTrainingFeedbackCollectedEvent(th.trainingData.SessionID, iterationID, feedback))
 		}
 	}
 
@@ -190,8 +231,8 @@ func (th *CrewTrainingHandler) ExecuteIteration(ctx context.Context, executeFunc
 	th.trainingData.UpdatedAt = time.Now()
 	th.dataMu.Unlock()
 
-	// 自动保存
-	if th.config.AutoSave && (iterationIndex%th.config.SaveInterval == 0) {
+	// 自动保存，保存间隔不大于1时每次迭代后都保存
+	if th.config.AutoSave && (th.config.SaveInterval <= 1 || iterationIndex%th.config.SaveInterval == 0) {
 		if saveErr := th.SaveTrainingData(ctx, th.trainingData); saveErr != nil {
 			th.logger.Error("failed to auto-save training data",
 				logger.Field{Key: "iteration", Value: iterationIndex},
@@ -266,7 +307,7 @@ func (th *CrewTrainingHandler) AnalyzePerformance(ctx context.Context, iteration
 	return nil, fmt.Errorf("iteration not found: %s", iterationID)
 }
 
-// SaveTrainingData 保存训练数据
+// SaveTrainingData 保存训练数据，先写入临时文件再重命名，中断时不会留下写了一半的文件
 func (th *CrewTrainingHandler) SaveTrainingData(ctx context.Context, data *TrainingData) error {
 	th.dataMu.RLock()
 	defer th.dataMu.RUnlock()
@@ -285,8 +326,9 @@ func (th *CrewTrainingHandler) SaveTrainingData(ctx context.Context, data *Train
 	}
 
 	// 备份现有文件
-	if th.config.BackupCount > 0 {
+	if th.config.BackupCount > 0 && !th.backedUp {
 		th.createBackup(filename)
+		th.backedUp = true
 	}
 
 	// 序列化数据
@@ -296,7 +338,7 @@ func (th *CrewTrainingHandler) SaveTrainingData(ctx context.Context, data *Train
 	}
 
 	// 写入文件
-	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
+	if err := writeFileAtomic(filename, jsonData); err != nil {
 		return fmt.Errorf("failed to write training data: %w", err)
 	}
 
@@ -314,6 +356,22 @@ func (th *CrewTrainingHandler) LoadTrainingData(ctx context.Context, filename st
 		return nil, fmt.Errorf("filename cannot be empty")
 	}
 
+	data, err := ReadTrainingData(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	th.logger.Info("training data loaded",
+		logger.Field{Key: "filename", Value: filename},
+		logger.Field{Key: "iterations", Value: len(data.Iterations)},
+		logger.Field{Key: "session_id", Value: data.SessionID},
+	)
+
+	return data, nil
+}
+
+// ReadTrainingData 读取训练数据文件，文件不存在时返回的错误满足errors.Is(err, fs.ErrNotExist)
+func ReadTrainingData(filename string) (*TrainingData, error) {
 	// 读取文件
 	jsonData, err := os.ReadFile(filename)
 	if err != nil {
@@ -326,12 +384,6 @@ func (th *CrewTrainingHandler) LoadTrainingData(ctx context.Context, filename st
 		return nil, fmt.Errorf("failed to unmarshal training data: %w", err)
 	}
 
-	th.logger.Info("training data loaded",
-		logger.Field{Key: "filename", Value: filename},
-		logger.Field{Key: "iterations", Value: len(data.Iterations)},
-		logger.Field{Key: "session_id", Value: data.SessionID},
-	)
-
 	return &data, nil
 }
 
@@ -358,6 +410,11 @@ func (th *CrewTrainingHandler) GetTrainingStatus(ctx context.Context) *TrainingS
 
 // StopTraining 停止训练
 func (th *CrewTrainingHandler) StopTraining(ctx context.Context) error {
+	return th.StopTrainingWithReason(ctx, StopReasonManual)
+}
+
+// StopTrainingWithReason 停止训练并记录停止原因，已收集的数据会随训练总结一起保存
+func (th *CrewTrainingHandler) StopTrainingWithReason(ctx context.Context, reason string) error {
 	th.statusMu.Lock()
 	if !th.status.IsRunning {
 		th.statusMu.Unlock()
@@ -377,6 +434,33 @@ func (th *CrewTrainingHandler) StopTraining(ctx context.Context) error {
 	// 生成训练总结
 	th.generateTrainingSummary()
 
+	// 只有完整结束的训练才总结改进指令
+	var trainedAgents map[string]*TrainedAgentData
+	if reason == StopReasonCompleted && th.instructionSummarizer != nil {
+		th.dataMu.RLock()
+		summarized, err := th.instructionSummarizer.Summarize(ctx, th.trainingData)
+		th.dataMu.RUnlock()
+		if err != nil {
+			th.logger.Warn("trained agent instructions not generated",
+				logger.Field{Key: "error", Value: err},
+			)
+		}
+		trainedAgents = summarized
+	}
+
+	th.dataMu.Lock()
+	if trainedAgents != nil {
+		th.trainingData.TrainedAgents = trainedAgents
+	}
+	th.trainingData.StopReason = reason
+	if reason == StopReasonCompleted {
+		th.trainingData.Status = "completed"
+	} else {
+		th.trainingData.Status = "stopped"
+	}
+	th.trainingData.UpdatedAt = time.Now()
+	th.dataMu.Unlock()
+
 	// 最终保存
 	if th.config.AutoSave {
 		if err := th.SaveTrainingData(ctx, th.trainingData); err != nil {
@@ -387,12 +471,13 @@ func (th *CrewTrainingHandler) StopTraining(ctx context.Context) error {
 	}
 
 	// 发射训练停止事件
-	stopEvent := NewTrainingStoppedEvent(th.trainingData.SessionID, "manual_stop")
+	stopEvent := NewTrainingStoppedEvent(th.trainingData.SessionID, reason)
 	th.eventBus.Emit(ctx, th, stopEvent)
 
 	th.logger.Info("training stopped",
 		logger.Field{Key: "session_id", Value: th.trainingData.SessionID},
-		logger.Field{Key: "completed_iterations", Value: th.status.CurrentIteration},
+		logger.Field{Key: "reason", Value: reason},
+		logger.Field{Key: "completed_iterations", Value: len(th.trainingData.Iterations)},
 	)
 
 	return nil
@@ -415,6 +500,7 @@ func (th *CrewTrainingHandler) generateTrainingSummary() {
 	var successfulRuns, failedRuns int
 	var totalScore, totalTokens float64
 	var scores []float64
+	usage := &agent.UsageMetrics{}
 
 	for _, iteration := range iterations {
 		totalDuration += iteration.Duration
@@ -437,7 +523,12 @@ func (th *CrewTrainingHandler) generateTrainingSummary() {
 		}
 
 		// 收集token使用
-		if iteration.Metrics != nil {
+		if iteration.Usage != nil {
+			usage.Add(iteration.Usage)
+		}
+		if iteration.TokensUsed > 0 {
+			totalTokens += float64(iteration.TokensUsed)
+		} else if iteration.Metrics != nil {
 			totalTokens += float64(iteration.Metrics.TokensUsed)
 		}
 	}
@@ -470,6 +561,9 @@ func (th *CrewTrainingHandler) generateTrainingSummary() {
 	if len(iterations) > 0 {
 		summary.AverageTokens = int(totalTokens / float64(len(iterations)))
 	}
+	if usage.Runs > 0 {
+		summary.Usage = usage
+	}
 
 	// 生成建议
 	summary.Recommendations = th.generateRecommendations(summary)
@@ -516,6 +610,59 @@ func (th *CrewTrainingHandler) createBackup(filename string) {
 	}
 }
 
+// writeFileAtomic 在同一目录写入临时文件后重命名为目标文件
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // 重命名成功后删除不存在的文件，无副作用
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, filename)
+}
+
+// tokensUsed 从执行输出中获取token使用量，输出为包含tokens_used的map时有效
+func tokensUsed(outputs interface{}) int {
+	values, ok := outputs.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	switch tokens := values["tokens_used"].(type) {
+	case int:
+		return tokens
+	case int64:
+		return int(tokens)
+	case float64:
+		return int(tokens)
+	}
+	return 0
+}
+
+// usageMetrics 从执行输出中获取使用统计，输出为包含usage的map时有效
+func usageMetrics(outputs interface{}) *agent.UsageMetrics {
+	values, ok := outputs.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	usage, _ := values["usage"].(*agent.UsageMetrics)
+	return usage
+}
+
 // copyFile 复制文件
 func (th *CrewTrainingHandler) copyFile(src, dst string) error {
 	data, err := os.ReadFile(src)
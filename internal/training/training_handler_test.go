@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/ynl/greensoulai/internal/agent"
 	"github.com/ynl/greensoulai/pkg/events"
 	"github.com/ynl/greensoulai/pkg/logger"
 )
@@ -533,3 +534,141 @@ func TestConcurrentAccess(t *testing.T) {
 		<-done
 	}
 }
+
+// TestRunTrainingSessionPersistsEachIteration 每次迭代后原子保存，包含输入、输出、反馈和token使用量
+func TestRunTrainingSessionPersistsEachIteration(t *testing.T) {
+	testLogger := logger.NewTestLogger()
+	handler := NewCrewTrainingHandler(events.NewEventBus(testLogger), testLogger)
+
+	feedbackFile := filepath.Join(t.TempDir(), "feedback.jsonl")
+	require.NoError(t, os.WriteFile(feedbackFile, []byte(`{"quality_score": 6, "suggestions": "更简洁"}
+
+{"quality_score": 9, "suggestions": "保持"}
+`), 0644))
+	collector, err := NewJSONLFeedbackCollector(feedbackFile, testLogger)
+	require.NoError(t, err)
+	handler.SetFeedbackCollector(collector)
+
+	filename := filepath.Join(t.TempDir(), "training.json")
+	config := CreateSimpleTrainingConfig(2, filename)
+	config.Inputs = map[string]interface{}{"topic": "Go"}
+	config.SaveInterval = 1
+	config.BackupCount = 0
+
+	var savedAfterFirst *TrainingData
+	runs := 0
+	executeFunc := func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		runs++
+		if runs == 2 {
+			// 第二次迭代开始时第一次迭代已经写入文件
+			savedAfterFirst, err = handler.LoadTrainingData(ctx, filename)
+			require.NoError(t, err)
+		}
+		return map[string]interface{}{"raw": fmt.Sprintf("output %d", runs), "tokens_used": 100 * runs}, nil
+	}
+
+	summary, err := NewTrainingUtils(testLogger).RunTrainingSession(context.Background(), handler, config, executeFunc)
+	require.NoError(t, err)
+	assert.Equal(t, 300, summary.TotalTokens)
+	assert.Equal(t, 7.5, summary.AverageFeedback)
+
+	require.Len(t, savedAfterFirst.Iterations, 1)
+	assert.Equal(t, "running", savedAfterFirst.Status)
+
+	data, err := handler.LoadTrainingData(context.Background(), filename)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", data.Status)
+	assert.Equal(t, StopReasonCompleted, data.StopReason)
+	require.Len(t, data.Iterations, 2)
+
+	second := data.Iterations[1]
+	assert.Equal(t, "Go", second.Inputs["topic"])
+	assert.Equal(t, "output 2", second.Outputs.(map[string]interface{})["raw"])
+	assert.Equal(t, 200, second.TokensUsed)
+	require.NotNil(t, second.Feedback)
+	assert.Equal(t, 9.0, second.Feedback.QualityScore)
+	assert.Equal(t, "保持", second.Feedback.Suggestions)
+	assert.Equal(t, second.IterationID, second.Feedback.IterationID)
+
+	// 临时文件不会留在目录中
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestRunTrainingSessionInterrupted 中断时保存已完成的迭代并标记为interrupted
+func TestRunTrainingSessionInterrupted(t *testing.T) {
+	testLogger := logger.NewTestLogger()
+	bus := events.NewEventBus(testLogger)
+	handler := NewCrewTrainingHandler(bus, testLogger)
+
+	var stopReason string
+	_, err := bus.SubscribeWithOptions(TrainingStoppedEventType, func(ctx context.Context, event events.Event) error {
+		stopReason = event.(*TrainingStoppedEvent).Reason
+		return nil
+	}, events.WithSyncDelivery())
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "training.json")
+	config := CreateSimpleTrainingConfig(5, filename)
+	config.CollectFeedback = false
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	executeFunc := func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		runs++
+		if runs == 2 {
+			cancel() // 模拟执行中按下Ctrl+C
+			return nil, ctx.Err()
+		}
+		return "first output", nil
+	}
+
+	_, err = NewTrainingUtils(testLogger).RunTrainingSession(ctx, handler, config, executeFunc)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StopReasonInterrupted, stopReason)
+
+	data, err := handler.LoadTrainingData(context.Background(), filename)
+	require.NoError(t, err)
+	assert.Equal(t, "stopped", data.Status)
+	assert.Equal(t, StopReasonInterrupted, data.StopReason)
+	require.Len(t, data.Iterations, 1)
+	assert.Equal(t, "first output", data.Iterations[0].Outputs)
+	assert.Equal(t, 1, data.Summary.TotalIterations)
+}
+
+// TestRunTrainingSessionAggregatesUsage 执行输出中的使用统计按迭代记录并合计到总结
+func TestRunTrainingSessionAggregatesUsage(t *testing.T) {
+	testLogger := logger.NewTestLogger()
+	handler := NewCrewTrainingHandler(events.NewEventBus(testLogger), testLogger)
+
+	config := CreateSimpleTrainingConfig(2, filepath.Join(t.TempDir(), "training.json"))
+	config.CollectFeedback = false
+
+	executeFunc := func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		usage := &agent.UsageMetrics{}
+		usage.RecordTask(&agent.TaskOutput{
+			Agent:      "Writer",
+			Model:      "gpt-4o-mini",
+			IsValid:    true,
+			TokensUsed: 150,
+			Cost:       0.01,
+			LLMStats:   agent.LLMCallStats{Calls: 2, CacheHits: 1},
+		})
+		usage.AddRun(time.Second)
+		return map[string]interface{}{"raw": "output", "usage": usage}, nil
+	}
+
+	summary, err := NewTrainingUtils(testLogger).RunTrainingSession(context.Background(), handler, config, executeFunc)
+	require.NoError(t, err)
+	assert.Equal(t, 300, summary.TotalTokens)
+	require.NotNil(t, summary.Usage)
+	assert.Equal(t, 2, summary.Usage.Runs)
+	assert.Equal(t, 2, summary.Usage.SuccessfulTasks)
+	assert.Equal(t, 4, summary.Usage.LLMCalls)
+	assert.Equal(t, 2, summary.Usage.CacheHits)
+	assert.Equal(t, 300, summary.Usage.ModelUsage["gpt-4o-mini"].TotalTokens)
+	assert.Equal(t, 2, summary.Usage.AgentUsage["Writer"].Tasks)
+}
@@ -56,15 +56,16 @@ func (tu *TrainingUtils) RunTrainingSession(
 	for i := 0; i < config.Iterations; i++ {
 		select {
 		case <-ctx.Done():
-			tu.logger.Info("training cancelled by context")
-			handler.StopTraining(ctx)
-			return nil, ctx.Err()
+			return tu.interruptTraining(ctx, trainingHandler)
 		default:
 		}
 
 		// 执行迭代
 		iteration, err := trainingHandler.ExecuteIteration(ctx, executeFunc, i)
 		if err != nil {
+			if ctx.Err() != nil {
+				return tu.interruptTraining(ctx, trainingHandler)
+			}
 			tu.logger.Error("training iteration failed",
 				logger.Field{Key: "iteration", Value: i},
 				logger.Field{Key: "error", Value: err},
@@ -110,7 +111,7 @@ func (tu *TrainingUtils) RunTrainingSession(
 	}
 
 	// 停止训练并获取总结
-	if err := handler.StopTraining(ctx); err != nil {
+	if err := trainingHandler.StopTrainingWithReason(ctx, StopReasonCompleted); err != nil {
 		tu.logger.Error("failed to stop training gracefully",
 			logger.Field{Key: "error", Value: err})
 	}
@@ -130,6 +131,26 @@ func (tu *TrainingUtils) RunTrainingSession(
 	return nil, fmt.Errorf("no training summary available")
 }
 
+// interruptTraining 上下文被取消时保存已收集的数据，并把会话标记为interrupted
+func (tu *TrainingUtils) interruptTraining(ctx context.Context, handler *CrewTrainingHandler) (*TrainingSummary, error) {
+	tu.logger.Info("training interrupted, saving collected data")
+
+	// 原上下文已取消，停止时的保存和事件使用不可取消的上下文
+	if err := handler.StopTrainingWithReason(context.WithoutCancel(ctx), StopReasonInterrupted); err != nil {
+		tu.logger.Error("failed to stop interrupted training",
+			logger.Field{Key: "error", Value: err})
+	}
+
+	var summary *TrainingSummary
+	handler.dataMu.RLock()
+	if handler.trainingData != nil {
+		summary = handler.trainingData.Summary
+	}
+	handler.dataMu.RUnlock()
+
+	return summary, ctx.Err()
+}
+
 // ValidateTrainingConfig 验证训练配置
 func (tu *TrainingUtils) ValidateTrainingConfig(config *TrainingConfig) error {
 	if config == nil {
@@ -208,10 +229,14 @@ func (tu *TrainingUtils) GenerateTrainingReport(data *TrainingData) *TrainingRep
 		Config:          data.Config,
 		Summary:         data.Summary,
 		Status:          "completed",
+		StopReason:      data.StopReason,
 		Insights:        make([]string, 0),
 		Warnings:        make([]string, 0),
 		Recommendations: make([]string, 0),
 	}
+	if data.Status != "" {
+		report.Status = data.Status
+	}
 
 	// 生成洞察
 	if data.Summary != nil {
@@ -266,6 +291,7 @@ type TrainingReport struct {
 	Config          *TrainingConfig  `json:"config"`
 	Summary         *TrainingSummary `json:"summary"`
 	Status          string           `json:"status"`
+	StopReason      string           `json:"stop_reason,omitempty"`
 	Message         string           `json:"message,omitempty"`
 	Insights        []string         `json:"insights"`
 	Warnings        []string         `json:"warnings"`
@@ -0,0 +1,86 @@
+// Package agent 对外公开的Agent、Task和Tool API
+// greensoulai的实现位于internal/agent，模块外的代码（如create生成的项目）无法直接导入，
+// 这里的类型都是内部类型的别名，可以与其他greensoulai包直接互用
+package agent
+
+import (
+	"context"
+
+	"github.com/ynl/greensoulai/internal/agent"
+)
+
+// Agent相关类型
+type (
+	Agent           = agent.Agent
+	AgentConfig     = agent.AgentConfig
+	BaseAgent       = agent.BaseAgent
+	ExecutionConfig = agent.ExecutionConfig
+)
+
+// Task相关类型
+type (
+	Task         = agent.Task
+	BaseTask     = agent.BaseTask
+	TaskOption   = agent.TaskOption
+	TaskOutput   = agent.TaskOutput
+	OutputFormat = agent.OutputFormat
+)
+
+// Tool相关类型
+type (
+	Tool       = agent.Tool
+	BaseTool   = agent.BaseTool
+	ToolSchema = agent.ToolSchema
+)
+
+// 任务输出格式
+const (
+	OutputFormatRAW      = agent.OutputFormatRAW
+	OutputFormatJSON     = agent.OutputFormatJSON
+	OutputFormatPydantic = agent.OutputFormatPydantic
+)
+
+// NewBaseAgent 创建Agent
+func NewBaseAgent(config AgentConfig) (*BaseAgent, error) {
+	return agent.NewBaseAgent(config)
+}
+
+// DefaultExecutionConfig 返回默认的Agent执行配置
+func DefaultExecutionConfig() ExecutionConfig {
+	return agent.DefaultExecutionConfig()
+}
+
+// NewTaskWithOptions 创建任务，选项见WithName、WithOutputFormat等
+func NewTaskWithOptions(description, expectedOutput string, options ...TaskOption) *BaseTask {
+	return agent.NewTaskWithOptions(description, expectedOutput, options...)
+}
+
+// WithName 设置任务名称，任务ID由名称派生
+func WithName(name string) TaskOption {
+	return agent.WithName(name)
+}
+
+// WithMarkdown 要求任务以Markdown格式输出
+func WithMarkdown(markdown bool) TaskOption {
+	return agent.WithMarkdown(markdown)
+}
+
+// WithOutputFormat 设置任务输出格式
+func WithOutputFormat(format OutputFormat) TaskOption {
+	return agent.WithOutputFormat(format)
+}
+
+// WithOutputFile 把任务输出写入文件
+func WithOutputFile(filename string) TaskOption {
+	return agent.WithOutputFile(filename)
+}
+
+// WithOutputFileAppend 设置输出文件是否以追加方式写入
+func WithOutputFileAppend(appendOutput bool) TaskOption {
+	return agent.WithOutputFileAppend(appendOutput)
+}
+
+// NewBaseTool 创建由handler实现的工具
+func NewBaseTool(name, description string, handler func(ctx context.Context, args map[string]interface{}) (interface{}, error)) *BaseTool {
+	return agent.NewBaseTool(name, description, handler)
+}
@@ -0,0 +1,32 @@
+// Package crew 对外公开的Crew API
+// greensoulai的实现位于internal/crew，模块外的代码（如create生成的项目）无法直接导入，
+// 这里的类型都是内部类型的别名，Agent和Task使用pkg/agent中的类型
+package crew
+
+import (
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// Crew相关类型
+type (
+	Crew       = crew.Crew
+	CrewConfig = crew.CrewConfig
+	BaseCrew   = crew.BaseCrew
+	CrewOutput = crew.CrewOutput
+	Process    = crew.Process
+)
+
+// Crew的执行模式
+const (
+	ProcessSequential   = crew.ProcessSequential
+	ProcessHierarchical = crew.ProcessHierarchical
+	ProcessParallel     = crew.ProcessParallel
+	ProcessConsensus    = crew.ProcessConsensus
+)
+
+// NewBaseCrew 创建Crew，config为nil时使用默认配置
+func NewBaseCrew(config *CrewConfig, eventBus events.EventBus, logger logger.Logger) *BaseCrew {
+	return crew.NewBaseCrew(config, eventBus, logger)
+}
@@ -2,15 +2,19 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/ynl/greensoulai/pkg/logger"
 )
 
 // eventBus 事件总线实现
+// 处理器按订阅模式分组保存，模式可以是精确的事件类型或通配符（如"agent_*"）
 type eventBus struct {
-	handlers map[string][]EventHandler
+	handlers map[string][]*Subscription
+	sinks    []Sink
 	mu       sync.RWMutex
 	logger   logger.Logger
 }
@@ -18,62 +22,131 @@ type eventBus struct {
 // scopedEventBus 作用域事件总线，用于临时处理器管理
 type scopedEventBus struct {
 	*eventBus
-	originalHandlers map[string][]EventHandler
+	originalHandlers map[string][]*Subscription
 }
 
 // NewEventBus 创建新的事件总线
 func NewEventBus(logger logger.Logger) EventBus {
 	return &eventBus{
-		handlers: make(map[string][]EventHandler),
+		handlers: make(map[string][]*Subscription),
 		logger:   logger,
 	}
 }
 
 // Emit 发射事件
+// 处理器在锁外调用，处理器中可以再次发射事件或订阅；处理器的panic会被恢复并记录
 func (eb *eventBus) Emit(ctx context.Context, source interface{}, event Event) error {
-	eb.mu.RLock()
-	handlers, exists := eb.handlers[event.GetType()]
-	eb.mu.RUnlock()
+	eb.writeSinks(source, event)
 
-	if !exists {
+	subscriptions := eb.matchingSubscriptions(event.GetType())
+	if len(subscriptions) == 0 {
 		return nil
 	}
 
 	eb.logger.Debug("emitting event",
 		logger.Field{Key: "event_type", Value: event.GetType()},
-		logger.Field{Key: "handler_count", Value: len(handlers)},
+		logger.Field{Key: "handler_count", Value: len(subscriptions)},
 		logger.Field{Key: "source_fingerprint", Value: event.GetSourceFingerprint()},
 		logger.Field{Key: "source_type", Value: event.GetSourceType()},
 	)
 
-	for _, handler := range handlers {
-		go func(h EventHandler) {
-			if err := h(ctx, event); err != nil {
-				eb.logger.Error("event handler error",
-					logger.Field{Key: "error", Value: err},
-					logger.Field{Key: "event_type", Value: event.GetType()},
-				)
-			}
-		}(handler)
+	for _, sub := range subscriptions {
+		sub.deliver(ctx, event)
 	}
 
 	return nil
 }
 
-// Subscribe 订阅事件
-func (eb *eventBus) Subscribe(eventType string, handler EventHandler) error {
+// writeSinks 把事件写入所有附加的Sink，写入失败只记录警告
+func (eb *eventBus) writeSinks(source interface{}, event Event) {
+	eb.mu.RLock()
+	sinks := eb.sinks
+	eb.mu.RUnlock()
+	if len(sinks) == 0 {
+		return
+	}
+
+	recorded := sourcedEvent{Event: event, source: source}
+	for _, sink := range sinks {
+		if err := sink.Write(recorded); err != nil {
+			eb.logger.Warn("failed to write event to sink",
+				logger.Field{Key: "event_type", Value: event.GetType()},
+				logger.Field{Key: "error", Value: err},
+			)
+		}
+	}
+}
+
+// AttachSink 附加Sink，之后发射的每个事件都会写入该Sink
+func (eb *eventBus) AttachSink(sink Sink) error {
+	if sink == nil {
+		return fmt.Errorf("event sink cannot be nil")
+	}
+
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
-	if eb.handlers[eventType] == nil {
-		eb.handlers[eventType] = make([]EventHandler, 0)
+	// 写时复制，Emit持有的快照不受影响
+	sinks := make([]Sink, 0, len(eb.sinks)+1)
+	eb.sinks = append(append(sinks, eb.sinks...), sink)
+	return nil
+}
+
+// FlushSinks 刷新所有实现了Flusher的Sink
+func (eb *eventBus) FlushSinks() error {
+	eb.mu.RLock()
+	sinks := eb.sinks
+	eb.mu.RUnlock()
+
+	var errs []error
+	for _, sink := range sinks {
+		if flusher, ok := sink.(Flusher); ok {
+			if err := flusher.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// matchingSubscriptions 返回匹配事件类型的订阅快照，按订阅顺序排列
+func (eb *eventBus) matchingSubscriptions(eventType string) []*Subscription {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	var matched []*Subscription
+	for _, subs := range eb.handlers {
+		for _, sub := range subs {
+			if sub.matches(eventType) {
+				matched = append(matched, sub)
+			}
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].id < matched[j].id })
+	return matched
+}
+
+// Subscribe 订阅事件，eventType可以是通配符模式，处理器在独立的goroutine中调用
+func (eb *eventBus) Subscribe(eventType string, handler EventHandler) error {
+	_, err := eb.SubscribeWithOptions(eventType, handler)
+	return err
+}
+
+// SubscribeWithOptions 按投递选项订阅事件，返回可用于取消订阅的句柄
+func (eb *eventBus) SubscribeWithOptions(pattern string, handler EventHandler, opts ...SubscribeOption) (*Subscription, error) {
+	sub, err := newSubscription(eb, pattern, handler, opts)
+	if err != nil {
+		return nil, err
 	}
-	eb.handlers[eventType] = append(eb.handlers[eventType], handler)
+
+	eb.mu.Lock()
+	eb.handlers[pattern] = append(eb.handlers[pattern], sub)
+	eb.mu.Unlock()
 
 	eb.logger.Info("event handler registered",
-		logger.Field{Key: "event_type", Value: eventType},
+		logger.Field{Key: "event_type", Value: pattern},
 	)
-	return nil
+	return sub, nil
 }
 
 // RegisterHandler 注册事件处理器（别名方法，匹配crewAI API）
@@ -83,26 +156,44 @@ func (eb *eventBus) RegisterHandler(eventType string, handler EventHandler) erro
 
 // Unsubscribe 取消订阅
 func (eb *eventBus) Unsubscribe(eventType string, handler EventHandler) error {
-	eb.mu.Lock()
-	defer eb.mu.Unlock()
-
+	eb.mu.RLock()
 	handlers, exists := eb.handlers[eventType]
+	eb.mu.RUnlock()
 	if !exists {
 		return fmt.Errorf("no handlers for event type: %s", eventType)
 	}
 
 	// 移除指定的处理器
-	for i, h := range handlers {
-		if fmt.Sprintf("%p", h) == fmt.Sprintf("%p", handler) {
-			eb.handlers[eventType] = append(handlers[:i], handlers[i+1:]...)
+	for _, sub := range handlers {
+		if fmt.Sprintf("%p", sub.handler) == fmt.Sprintf("%p", handler) {
+			return eb.removeSubscription(sub)
+		}
+	}
+
+	return fmt.Errorf("handler not found for event type: %s", eventType)
+}
+
+// removeSubscription 移除订阅并停止其投递goroutine
+func (eb *eventBus) removeSubscription(target *Subscription) error {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	handlers := eb.handlers[target.pattern]
+	for i, sub := range handlers {
+		if sub == target {
+			eb.handlers[target.pattern] = append(handlers[:i:i], handlers[i+1:]...)
+			if len(eb.handlers[target.pattern]) == 0 {
+				delete(eb.handlers, target.pattern)
+			}
+			target.stop()
 			eb.logger.Info("event handler unregistered",
-				logger.Field{Key: "event_type", Value: eventType},
+				logger.Field{Key: "event_type", Value: target.pattern},
 			)
 			return nil
 		}
 	}
 
-	return fmt.Errorf("handler not found for event type: %s", eventType)
+	return fmt.Errorf("subscription not found for event type: %s", target.pattern)
 }
 
 // GetHandlerCount 获取指定事件类型的处理器数量
@@ -131,127 +222,35 @@ func (eb *eventBus) GetRegisteredEventTypes() []string {
 
 // WithScopedHandlers 创建作用域事件总线，用于临时处理器管理
 func (eb *eventBus) WithScopedHandlers() EventBus {
-	eb.mu.RLock()
-	originalHandlers := make(map[string][]EventHandler)
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	originalHandlers := make(map[string][]*Subscription)
 	for k, v := range eb.handlers {
-		originalHandlers[k] = make([]EventHandler, len(v))
+		originalHandlers[k] = make([]*Subscription, len(v))
 		copy(originalHandlers[k], v)
 	}
-	eb.mu.RUnlock()
 
-	scoped := &scopedEventBus{
+	// 清空当前处理器
+	eb.handlers = make(map[string][]*Subscription)
+
+	return &scopedEventBus{
 		eventBus:         eb,
 		originalHandlers: originalHandlers,
 	}
-
-	// 清空当前处理器
-	eb.mu.Lock()
-	eb.handlers = make(map[string][]EventHandler)
-	eb.mu.Unlock()
-
-	return scoped
 }
 
-// Close 关闭作用域事件总线，恢复原始处理器
+// Close 关闭作用域事件总线，停止作用域内的订阅并恢复原始处理器
 func (seb *scopedEventBus) Close() {
-	seb.mu.Lock()
-	seb.handlers = seb.originalHandlers
-	seb.mu.Unlock()
-}
-
-// 实现作用域事件总线的其他方法
-func (seb *scopedEventBus) Subscribe(eventType string, handler EventHandler) error {
 	seb.mu.Lock()
 	defer seb.mu.Unlock()
 
-	if seb.handlers[eventType] == nil {
-		seb.handlers[eventType] = make([]EventHandler, 0)
-	}
-	seb.handlers[eventType] = append(seb.handlers[eventType], handler)
-
-	seb.logger.Info("scoped event handler registered",
-		logger.Field{Key: "event_type", Value: eventType},
-	)
-	return nil
-}
-
-func (seb *scopedEventBus) RegisterHandler(eventType string, handler EventHandler) error {
-	return seb.Subscribe(eventType, handler)
-}
-
-func (seb *scopedEventBus) Unsubscribe(eventType string, handler EventHandler) error {
-	seb.mu.Lock()
-	defer seb.mu.Unlock()
-
-	handlers, exists := seb.handlers[eventType]
-	if !exists {
-		return fmt.Errorf("no handlers for event type: %s", eventType)
-	}
-
-	// 移除指定的处理器
-	for i, h := range handlers {
-		if fmt.Sprintf("%p", h) == fmt.Sprintf("%p", handler) {
-			seb.handlers[eventType] = append(handlers[:i], handlers[i+1:]...)
-			seb.logger.Info("scoped event handler unregistered",
-				logger.Field{Key: "event_type", Value: eventType},
-			)
-			return nil
+	for _, subs := range seb.handlers {
+		for _, sub := range subs {
+			sub.stop()
 		}
 	}
-
-	return fmt.Errorf("handler not found for event type: %s", eventType)
-}
-
-func (seb *scopedEventBus) GetHandlerCount(eventType string) int {
-	seb.mu.RLock()
-	defer seb.mu.RUnlock()
-
-	handlers, exists := seb.handlers[eventType]
-	if !exists {
-		return 0
-	}
-	return len(handlers)
-}
-
-func (seb *scopedEventBus) GetRegisteredEventTypes() []string {
-	seb.mu.RLock()
-	defer seb.mu.RUnlock()
-
-	types := make([]string, 0, len(seb.handlers))
-	for eventType := range seb.handlers {
-		types = append(types, eventType)
-	}
-	return types
-}
-
-func (seb *scopedEventBus) Emit(ctx context.Context, source interface{}, event Event) error {
-	seb.mu.RLock()
-	handlers, exists := seb.handlers[event.GetType()]
-	seb.mu.RUnlock()
-
-	if !exists {
-		return nil
-	}
-
-	seb.logger.Debug("emitting scoped event",
-		logger.Field{Key: "event_type", Value: event.GetType()},
-		logger.Field{Key: "handler_count", Value: len(handlers)},
-		logger.Field{Key: "source_fingerprint", Value: event.GetSourceFingerprint()},
-		logger.Field{Key: "source_type", Value: event.GetSourceType()},
-	)
-
-	for _, handler := range handlers {
-		go func(h EventHandler) {
-			if err := h(ctx, event); err != nil {
-				seb.logger.Error("scoped event handler error",
-					logger.Field{Key: "error", Value: err},
-					logger.Field{Key: "event_type", Value: event.GetType()},
-				)
-			}
-		}(handler)
-	}
-
-	return nil
+	seb.handlers = seb.originalHandlers
 }
 
 func (seb *scopedEventBus) WithScopedHandlers() EventBus {
@@ -2,6 +2,10 @@ package events
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -398,3 +402,209 @@ func TestEventBus_NewEventTypes(t *testing.T) {
 		})
 	}
 }
+
+// recordingLogger 记录错误和警告日志
+type recordingLogger struct {
+	*logger.ConsoleLogger
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *recordingLogger) Error(msg string, fields ...logger.Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := msg
+	for _, field := range fields {
+		entry += fmt.Sprintf(" %s=%v", field.Key, field.Value)
+	}
+	l.errors = append(l.errors, entry)
+}
+
+func (l *recordingLogger) errorLogs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.errors...)
+}
+
+func TestEventBus_WildcardSubscriptions(t *testing.T) {
+	eventBus := NewEventBus(logger.NewTestLogger())
+
+	var mu sync.Mutex
+	var agentEvents, evaluationEvents []string
+	if _, err := eventBus.SubscribeWithOptions("agent_*", func(ctx context.Context, event Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		agentEvents = append(agentEvents, event.GetType())
+		return nil
+	}, WithSyncDelivery()); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if _, err := eventBus.SubscribeWithOptions("evaluation.task.*", func(ctx context.Context, event Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		evaluationEvents = append(evaluationEvents, event.GetType())
+		return nil
+	}, WithSyncDelivery()); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	for _, eventType := range []string{EventTypeAgentStarted, EventTypeTaskStarted, "evaluation.task.scored", "evaluation.crew.scored", EventTypeAgentCompleted} {
+		eventBus.Emit(context.Background(), nil, &BaseEvent{Type: eventType, Timestamp: time.Now()})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(agentEvents) != fmt.Sprint([]string{EventTypeAgentStarted, EventTypeAgentCompleted}) {
+		t.Errorf("unexpected agent events: %v", agentEvents)
+	}
+	if fmt.Sprint(evaluationEvents) != "[evaluation.task.scored]" {
+		t.Errorf("unexpected evaluation events: %v", evaluationEvents)
+	}
+
+	if _, err := eventBus.SubscribeWithOptions("agent_[", func(ctx context.Context, event Event) error { return nil }); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestEventBus_SubscriptionHandle(t *testing.T) {
+	eventBus := NewEventBus(logger.NewTestLogger())
+
+	var count int32
+	sub, err := eventBus.SubscribeWithOptions("handle_event", func(ctx context.Context, event Event) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	}, WithSyncDelivery())
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	event := &BaseEvent{Type: "handle_event", Timestamp: time.Now()}
+	eventBus.Emit(context.Background(), nil, event)
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	eventBus.Emit(context.Background(), nil, event)
+
+	if count != 1 {
+		t.Errorf("expected 1 delivery before unsubscribe, got %d", count)
+	}
+	if eventBus.GetHandlerCount("handle_event") != 0 {
+		t.Error("expected no handlers after unsubscribe")
+	}
+	if err := sub.Unsubscribe(); err == nil {
+		t.Error("expected error when unsubscribing twice")
+	}
+}
+
+func TestEventBus_AsyncDeliveryPolicies(t *testing.T) {
+	eventBus := NewEventBus(logger.NewTestLogger())
+
+	// 丢弃策略：处理器阻塞时发射方不等待，多余的事件被丢弃
+	release := make(chan struct{})
+	var dropped int32
+	_, err := eventBus.SubscribeWithOptions("slow_event", func(ctx context.Context, event Event) error {
+		<-release
+		atomic.AddInt32(&dropped, 1)
+		return nil
+	}, WithAsyncDelivery(1, OverflowDrop))
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		eventBus.Emit(context.Background(), nil, &BaseEvent{Type: "slow_event", Timestamp: time.Now()})
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("emit blocked on a slow handler for %v", elapsed)
+	}
+	close(release)
+
+	// 阻塞策略：所有事件按顺序投递
+	received := make(chan int, 20)
+	_, err = eventBus.SubscribeWithOptions("ordered_event", func(ctx context.Context, event Event) error {
+		time.Sleep(time.Millisecond)
+		received <- event.GetPayload()["seq"].(int)
+		return nil
+	}, WithAsyncDelivery(2, OverflowBlock))
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		eventBus.Emit(context.Background(), nil, &BaseEvent{Type: "ordered_event", Payload: map[string]interface{}{"seq": i}})
+	}
+	for i := 0; i < 20; i++ {
+		select {
+		case seq := <-received:
+			if seq != i {
+				t.Fatalf("expected event %d, got %d", i, seq)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not delivered", i)
+		}
+	}
+
+	if delivered := atomic.LoadInt32(&dropped); delivered >= 10 {
+		t.Errorf("expected some events to be dropped, all %d delivered", delivered)
+	}
+}
+
+func TestEventBus_ReentrantEmit(t *testing.T) {
+	eventBus := NewEventBus(logger.NewTestLogger())
+	done := make(chan struct{})
+
+	// 同步处理器发射另一个事件
+	eventBus.SubscribeWithOptions("outer", func(ctx context.Context, event Event) error {
+		return eventBus.Emit(ctx, nil, &BaseEvent{Type: "inner"})
+	}, WithSyncDelivery())
+
+	// 异步处理器在队列已满时向自己的订阅发射事件
+	var depth int32
+	eventBus.SubscribeWithOptions("inner", func(ctx context.Context, event Event) error {
+		if atomic.AddInt32(&depth, 1) < 5 {
+			eventBus.Emit(ctx, nil, &BaseEvent{Type: "inner"})
+			eventBus.Emit(ctx, nil, &BaseEvent{Type: "inner"})
+			return nil
+		}
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+		return nil
+	}, WithAsyncDelivery(1, OverflowBlock))
+
+	eventBus.Emit(context.Background(), nil, &BaseEvent{Type: "outer"})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("re-entrant emit deadlocked")
+	}
+}
+
+func TestEventBus_HandlerPanicRecovered(t *testing.T) {
+	log := &recordingLogger{ConsoleLogger: logger.NewTestLogger()}
+	eventBus := NewEventBus(log)
+
+	var handled int32
+	eventBus.SubscribeWithOptions("panicky_event", func(ctx context.Context, event Event) error {
+		panic("handler exploded")
+	}, WithSyncDelivery())
+	eventBus.SubscribeWithOptions("panicky_event", func(ctx context.Context, event Event) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}, WithSyncDelivery())
+
+	if err := eventBus.Emit(context.Background(), nil, &BaseEvent{Type: "panicky_event"}); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+
+	if handled != 1 {
+		t.Error("expected later handlers to run after a panic")
+	}
+	logs := log.errorLogs()
+	if len(logs) != 1 || !strings.Contains(logs[0], "event_type=panicky_event") || !strings.Contains(logs[0], "handler exploded") {
+		t.Errorf("expected panic to be logged with the event type, got: %v", logs)
+	}
+}
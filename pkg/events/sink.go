@@ -0,0 +1,335 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 事件持久化
+// ============================================================================
+
+// ErrSinkClosed 向已关闭的Sink写入事件
+var ErrSinkClosed = errors.New("event sink is closed")
+
+// Sink 事件记录的目的地，附加到EventBus后接收每一个发射的事件
+// 实现必须可以被多个goroutine并发调用
+type Sink interface {
+	Write(event Event) error
+	Close() error
+}
+
+// Flusher 带缓冲的Sink实现该接口，EventBus.FlushSinks时被调用
+type Flusher interface {
+	Flush() error
+}
+
+// EventRecord 事件的可序列化记录
+type EventRecord struct {
+	Type              string                 `json:"type"`
+	Timestamp         time.Time              `json:"timestamp"`
+	SourceID          string                 `json:"source_id,omitempty"`
+	SourceType        string                 `json:"source_type,omitempty"`
+	SourceFingerprint string                 `json:"source_fingerprint,omitempty"`
+	Payload           map[string]interface{} `json:"payload,omitempty"`
+}
+
+// NewEventRecord 把事件转换为记录
+// 负载包括事件的Payload和具体事件类型上的导出字段，无法序列化为JSON的值退化为字符串表示
+func NewEventRecord(event Event) EventRecord {
+	record := EventRecord{
+		Type:              event.GetType(),
+		Timestamp:         event.GetTimestamp(),
+		SourceID:          sourceID(event),
+		SourceType:        event.GetSourceType(),
+		SourceFingerprint: event.GetSourceFingerprint(),
+	}
+
+	payload := make(map[string]interface{})
+	for key, value := range event.GetPayload() {
+		payload[key] = serializableValue(value)
+	}
+	for key, value := range eventFields(event) {
+		if _, exists := payload[key]; !exists {
+			payload[key] = serializableValue(value)
+		}
+	}
+	if len(payload) > 0 {
+		record.Payload = payload
+	}
+	return record
+}
+
+// Event 把记录还原为事件，来源为记录中的来源标识
+func (r EventRecord) Event() Event {
+	event := &BaseEvent{
+		Type:              r.Type,
+		Timestamp:         r.Timestamp,
+		Payload:           r.Payload,
+		SourceFingerprint: r.SourceFingerprint,
+		SourceType:        r.SourceType,
+	}
+	if r.SourceID != "" {
+		event.Source = r.SourceID
+	}
+	return event
+}
+
+// sourceID 返回来源的ID（crew、agent等实现了GetID的来源），否则返回来源指纹
+func sourceID(event Event) string {
+	switch source := event.GetSource().(type) {
+	case interface{ GetID() string }:
+		if id := source.GetID(); id != "" {
+			return id
+		}
+	case string:
+		if source != "" {
+			return source
+		}
+	}
+	return event.GetSourceFingerprint()
+}
+
+// eventFields 读取具体事件类型上的导出字段，嵌入的BaseEvent不计入
+func eventFields(event Event) map[string]interface{} {
+	if sourced, ok := event.(sourcedEvent); ok {
+		event = sourced.Event
+	}
+	v := reflect.ValueOf(event)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		fields[name] = v.Field(i).Interface()
+	}
+	return fields
+}
+
+// serializableValue 通过JSON往返得到可序列化的值，失败时退化为字符串表示
+func serializableValue(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return generic
+}
+
+// sourcedEvent 事件未设置来源时，使用Emit传入的来源
+type sourcedEvent struct {
+	Event
+	source interface{}
+}
+
+func (e sourcedEvent) GetSource() interface{} {
+	if source := e.Event.GetSource(); source != nil {
+		return source
+	}
+	return e.source
+}
+
+// ============================================================================
+// JSONL文件Sink
+// ============================================================================
+
+// JSONLFileSink 把事件逐行追加写入JSONL文件，写入经过缓冲，Flush或Close时落盘
+type JSONLFileSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	closed bool
+}
+
+var _ Sink = (*JSONLFileSink)(nil)
+var _ Flusher = (*JSONLFileSink)(nil)
+
+// NewJSONLFileSink 打开（必要时创建）文件，新记录追加到已有内容之后
+func NewJSONLFileSink(path string) (*JSONLFileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log %s: %w", path, err)
+	}
+	return &JSONLFileSink{
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}, nil
+}
+
+// Write 序列化事件并写入一行
+func (s *JSONLFileSink) Write(event Event) error {
+	data, err := json.Marshal(NewEventRecord(event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal event %s: %w", event.GetType(), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	if _, err := s.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write event %s: %w", event.GetType(), err)
+	}
+	return nil
+}
+
+// Flush 把缓冲的记录写入文件并同步到磁盘
+func (s *JSONLFileSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	return s.flushLocked()
+}
+
+func (s *JSONLFileSink) flushLocked() error {
+	if err := s.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush event log: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync event log: %w", err)
+	}
+	return nil
+}
+
+// Close 刷新缓冲并关闭文件，重复调用无副作用
+func (s *JSONLFileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	flushErr := s.flushLocked()
+	if err := s.file.Close(); err != nil && flushErr == nil {
+		return fmt.Errorf("failed to close event log: %w", err)
+	}
+	return flushErr
+}
+
+// ============================================================================
+// 内存环形缓冲Sink
+// ============================================================================
+
+// RingBufferSink 在内存中保留最近的capacity条事件记录
+type RingBufferSink struct {
+	mu      sync.Mutex
+	records []EventRecord
+	next    int
+	full    bool
+	closed  bool
+}
+
+var _ Sink = (*RingBufferSink)(nil)
+
+// NewRingBufferSink 创建环形缓冲Sink，capacity<=0时为1
+func NewRingBufferSink(capacity int) *RingBufferSink {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &RingBufferSink{records: make([]EventRecord, capacity)}
+}
+
+// Write 保存事件记录，缓冲满时覆盖最旧的记录
+func (s *RingBufferSink) Write(event Event) error {
+	record := NewEventRecord(event)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	s.records[s.next] = record
+	s.next = (s.next + 1) % len(s.records)
+	if s.next == 0 {
+		s.full = true
+	}
+	return nil
+}
+
+// Records 按写入顺序返回保留的记录
+func (s *RingBufferSink) Records() []EventRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.full {
+		return append([]EventRecord(nil), s.records[:s.next]...)
+	}
+	records := make([]EventRecord, 0, len(s.records))
+	records = append(records, s.records[s.next:]...)
+	return append(records, s.records[:s.next]...)
+}
+
+// Close 停止接收事件，已保留的记录仍可读取
+func (s *RingBufferSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+// ============================================================================
+// 回放
+// ============================================================================
+
+// Replay 读取JSONL事件记录，按记录顺序把还原的事件交给处理器
+// 遇到无法解析的记录或处理器返回错误时停止
+func Replay(reader io.Reader, handler EventHandler) error {
+	decoder := json.NewDecoder(reader)
+	ctx := context.Background()
+
+	for n := 1; ; n++ {
+		var record EventRecord
+		if err := decoder.Decode(&record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode event record %d: %w", n, err)
+		}
+		if err := handler(ctx, record.Event()); err != nil {
+			return fmt.Errorf("replay handler failed on event record %d (%s): %w", n, record.Type, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// identifiedSource 带ID的事件来源，模拟crew或agent
+type identifiedSource struct{ id string }
+
+func (s *identifiedSource) GetID() string { return s.id }
+
+// stepEvent 带具体字段的事件类型
+type stepEvent struct {
+	BaseEvent
+	Step    int         `json:"step"`
+	Handler interface{} `json:"handler"`
+}
+
+func TestJSONLFileSinkAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewJSONLFileSink(path)
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	eventBus := NewEventBus(logger.NewTestLogger())
+	if err := eventBus.AttachSink(sink); err != nil {
+		t.Fatalf("failed to attach sink: %v", err)
+	}
+
+	// 并行任务同时发射事件
+	source := &identifiedSource{id: "crew-1"}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			eventBus.Emit(context.Background(), source, &BaseEvent{
+				Type:      "task_completed",
+				Timestamp: time.Now(),
+				Payload:   map[string]interface{}{"index": i},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	// 无法序列化的负载退化为字符串
+	eventBus.Emit(context.Background(), nil, &stepEvent{
+		BaseEvent: BaseEvent{Type: "step", Timestamp: time.Now(), SourceFingerprint: "fp-1"},
+		Step:      7,
+		Handler:   make(chan int),
+	})
+
+	if err := eventBus.FlushSinks(); err != nil {
+		t.Fatalf("failed to flush sinks: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open event log: %v", err)
+	}
+	defer file.Close()
+
+	var replayed []Event
+	err = Replay(file, func(ctx context.Context, event Event) error {
+		replayed = append(replayed, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(replayed) != 51 {
+		t.Fatalf("expected 51 replayed events, got %d", len(replayed))
+	}
+
+	seen := make(map[float64]bool)
+	for _, event := range replayed[:50] {
+		if event.GetType() != "task_completed" || event.GetSource() != "crew-1" {
+			t.Errorf("expected task_completed from crew-1, got %s from %v", event.GetType(), event.GetSource())
+		}
+		seen[event.GetPayload()["index"].(float64)] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("expected 50 distinct events, got %d", len(seen))
+	}
+
+	step := replayed[50]
+	if step.GetSource() != "fp-1" || step.GetPayload()["step"] != float64(7) {
+		t.Errorf("expected step event with fingerprint source, got %v %v", step.GetSource(), step.GetPayload())
+	}
+	if handler, ok := step.GetPayload()["handler"].(string); !ok || handler == "" {
+		t.Errorf("expected non-serializable field as string, got %v", step.GetPayload()["handler"])
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("failed to close sink: %v", err)
+	}
+	if err := sink.Write(&BaseEvent{Type: "late"}); !errors.Is(err, ErrSinkClosed) {
+		t.Errorf("expected ErrSinkClosed, got %v", err)
+	}
+}
+
+func TestRingBufferSink(t *testing.T) {
+	sink := NewRingBufferSink(3)
+	eventBus := NewEventBus(logger.NewTestLogger())
+	eventBus.AttachSink(sink)
+
+	if len(sink.Records()) != 0 {
+		t.Error("expected empty buffer")
+	}
+	for i := 0; i < 5; i++ {
+		eventBus.Emit(context.Background(), nil, &BaseEvent{Type: fmt.Sprintf("event_%d", i)})
+	}
+
+	records := sink.Records()
+	if len(records) != 3 {
+		t.Fatalf("expected 3 retained records, got %d", len(records))
+	}
+	for i, record := range records {
+		if record.Type != fmt.Sprintf("event_%d", i+2) {
+			t.Errorf("expected oldest-first order, got %s at %d", record.Type, i)
+		}
+	}
+
+	sink.Close()
+	if err := sink.Write(&BaseEvent{Type: "late"}); !errors.Is(err, ErrSinkClosed) {
+		t.Errorf("expected ErrSinkClosed, got %v", err)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	input := `{"type":"first"}` + "\n" + `not json` + "\n"
+	var count int
+	err := Replay(strings.NewReader(input), func(ctx context.Context, event Event) error {
+		count++
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("expected decode error for record 2, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 event before the corrupted record, got %d", count)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":"a"}` + "\n" + `{"type":"b"}` + "\n")
+	stop := errors.New("stop")
+	err = Replay(&buf, func(ctx context.Context, event Event) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("expected handler error, got %v", err)
+	}
+}
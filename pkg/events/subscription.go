@@ -0,0 +1,223 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// DeliveryMode 事件投递方式
+type DeliveryMode int
+
+const (
+	// DeliveryGoroutine 每个事件在独立的goroutine中调用处理器（Subscribe的默认方式）
+	DeliveryGoroutine DeliveryMode = iota
+	// DeliverySync 在Emit调用中同步调用处理器
+	DeliverySync
+	// DeliveryAsync 事件进入有界队列，由订阅专属的goroutine按顺序调用处理器
+	DeliveryAsync
+)
+
+// OverflowPolicy 异步队列满时的处理策略
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 阻塞发射方直到队列有空位或发射方的上下文结束
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop 丢弃新事件并记录警告
+	OverflowDrop
+)
+
+// DefaultQueueSize 异步投递默认的队列长度
+const DefaultQueueSize = 64
+
+// SubscribeOption 订阅选项
+type SubscribeOption func(*subscriptionOptions)
+
+type subscriptionOptions struct {
+	mode      DeliveryMode
+	queueSize int
+	overflow  OverflowPolicy
+}
+
+// WithSyncDelivery 在Emit调用中同步投递
+func WithSyncDelivery() SubscribeOption {
+	return func(o *subscriptionOptions) { o.mode = DeliverySync }
+}
+
+// WithAsyncDelivery 通过有界队列异步顺序投递，queueSize<=0时使用DefaultQueueSize
+func WithAsyncDelivery(queueSize int, overflow OverflowPolicy) SubscribeOption {
+	return func(o *subscriptionOptions) {
+		if queueSize <= 0 {
+			queueSize = DefaultQueueSize
+		}
+		o.mode = DeliveryAsync
+		o.queueSize = queueSize
+		o.overflow = overflow
+	}
+}
+
+// Subscription 订阅句柄，用于取消订阅
+type Subscription struct {
+	id      uint64
+	pattern string
+	handler EventHandler
+	options subscriptionOptions
+	bus     *eventBus
+
+	queue   chan queuedEvent
+	done    chan struct{}
+	stopped sync.Once
+}
+
+type queuedEvent struct {
+	ctx   context.Context
+	event Event
+}
+
+// dispatchKey 标记由某个异步订阅的投递goroutine发出的上下文，用于识别重入
+type dispatchKey struct{}
+
+var subscriptionIDs uint64
+
+func newSubscription(bus *eventBus, pattern string, handler EventHandler, opts []SubscribeOption) (*Subscription, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("event type pattern cannot be empty")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid event type pattern %q: %w", pattern, err)
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("event handler cannot be nil")
+	}
+
+	sub := &Subscription{
+		id:      atomic.AddUint64(&subscriptionIDs, 1),
+		pattern: pattern,
+		handler: handler,
+		bus:     bus,
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&sub.options)
+	}
+	if sub.options.mode == DeliveryAsync {
+		sub.queue = make(chan queuedEvent, sub.options.queueSize)
+		go sub.run()
+	}
+	return sub, nil
+}
+
+// Pattern 返回订阅的事件类型模式
+func (s *Subscription) Pattern() string { return s.pattern }
+
+// Unsubscribe 取消订阅，异步队列中尚未投递的事件被丢弃
+func (s *Subscription) Unsubscribe() error {
+	return s.bus.removeSubscription(s)
+}
+
+// matches 事件类型是否匹配订阅模式，支持*、?和[]通配符
+func (s *Subscription) matches(eventType string) bool {
+	if s.pattern == eventType {
+		return true
+	}
+	if !strings.ContainsAny(s.pattern, "*?[") {
+		return false
+	}
+	matched, _ := path.Match(s.pattern, eventType)
+	return matched
+}
+
+// deliver 按订阅的投递方式投递事件
+func (s *Subscription) deliver(ctx context.Context, event Event) {
+	switch s.options.mode {
+	case DeliverySync:
+		s.invoke(ctx, event)
+	case DeliveryAsync:
+		s.enqueue(ctx, event)
+	default:
+		go s.invoke(ctx, event)
+	}
+}
+
+func (s *Subscription) enqueue(ctx context.Context, event Event) {
+	item := queuedEvent{ctx: ctx, event: event}
+
+	select {
+	case s.queue <- item:
+		return
+	case <-s.done:
+		return
+	default:
+	}
+
+	// 队列已满
+	if s.options.overflow == OverflowDrop {
+		s.bus.logger.Warn("event dropped, subscription queue is full",
+			logger.Field{Key: "event_type", Value: event.GetType()},
+			logger.Field{Key: "pattern", Value: s.pattern},
+			logger.Field{Key: "queue_size", Value: s.options.queueSize},
+		)
+		return
+	}
+
+	block := func() {
+		select {
+		case s.queue <- item:
+		case <-s.done:
+		case <-ctx.Done():
+			s.bus.logger.Warn("event dropped, emitter context ended while queue was full",
+				logger.Field{Key: "event_type", Value: event.GetType()},
+				logger.Field{Key: "pattern", Value: s.pattern},
+			)
+		}
+	}
+
+	// 处理器向自己的订阅发射事件时不能阻塞投递goroutine，否则会死锁
+	if ctx.Value(dispatchKey{}) == s {
+		go block()
+		return
+	}
+	block()
+}
+
+// run 异步订阅的投递循环
+func (s *Subscription) run() {
+	for {
+		select {
+		case item := <-s.queue:
+			s.invoke(context.WithValue(item.ctx, dispatchKey{}, s), item.event)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// invoke 调用处理器，记录错误并恢复panic
+func (s *Subscription) invoke(ctx context.Context, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.bus.logger.Error("event handler panicked",
+				logger.Field{Key: "event_type", Value: event.GetType()},
+				logger.Field{Key: "pattern", Value: s.pattern},
+				logger.Field{Key: "panic", Value: fmt.Sprint(r)},
+			)
+		}
+	}()
+
+	if err := s.handler(ctx, event); err != nil {
+		s.bus.logger.Error("event handler error",
+			logger.Field{Key: "error", Value: err},
+			logger.Field{Key: "event_type", Value: event.GetType()},
+		)
+	}
+}
+
+func (s *Subscription) stop() {
+	s.stopped.Do(func() { close(s.done) })
+}
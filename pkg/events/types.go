@@ -3,6 +3,8 @@ package events
 import (
 	"context"
 	"time"
+
+	"github.com/ynl/greensoulai/pkg/security"
 )
 
 // EventType 事件类型常量
@@ -41,6 +43,7 @@ const (
 	// Tool Events
 	EventTypeToolUsageStarted       = "tool_usage_started"
 	EventTypeToolUsageFinished      = "tool_usage_finished"
+	EventTypeToolUsageProgress      = "tool_usage_progress"
 	EventTypeToolUsageError         = "tool_usage_error"
 	EventTypeToolExecutionError     = "tool_execution_error"
 	EventTypeToolSelectionError     = "tool_selection_error"
@@ -88,7 +91,13 @@ type EventHandler func(ctx context.Context, event Event) error
 type EventBus interface {
 	Emit(ctx context.Context, source interface{}, event Event) error
 	Subscribe(eventType string, handler EventHandler) error
+	// SubscribeWithOptions 按投递选项订阅匹配模式的事件，返回的句柄可用于取消订阅
+	SubscribeWithOptions(pattern string, handler EventHandler, opts ...SubscribeOption) (*Subscription, error)
 	Unsubscribe(eventType string, handler EventHandler) error
+	// AttachSink 附加事件记录Sink，每个发射的事件都会写入
+	AttachSink(sink Sink) error
+	// FlushSinks 刷新附加的Sink中缓冲的记录
+	FlushSinks() error
 	GetHandlerCount(eventType string) int
 	GetRegisteredEventTypes() []string
 	// 新增方法以匹配crewAI功能
@@ -135,6 +144,27 @@ func (e *BaseEvent) GetFingerprintMetadata() map[string]interface{} {
 	return e.FingerprintMetadata
 }
 
+// SetSourceFingerprint 设置事件源的安全指纹，并以<sourceType>_fingerprint为键加入Payload
+func (e *BaseEvent) SetSourceFingerprint(sourceType string, fingerprint *security.Fingerprint) {
+	if fingerprint == nil {
+		return
+	}
+
+	e.SourceFingerprint = fingerprint.GetUUID()
+	e.SourceType = sourceType
+	if len(fingerprint.Metadata) > 0 {
+		e.FingerprintMetadata = make(map[string]interface{}, len(fingerprint.Metadata))
+		for key, value := range fingerprint.Metadata {
+			e.FingerprintMetadata[key] = value
+		}
+	}
+
+	if e.Payload == nil {
+		e.Payload = make(map[string]interface{})
+	}
+	e.Payload[sourceType+"_fingerprint"] = fingerprint.GetUUID()
+}
+
 // 具体事件类型 - 更新以匹配crewAI
 type AgentExecutionStartedEvent struct {
 	BaseEvent
@@ -0,0 +1,147 @@
+// Package agentflow 将Agent和Crew的执行接入flow工作流
+// 独立成子包是为了让flow保持轻量，不依赖Agent和Crew系统
+package agentflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/pkg/flow"
+)
+
+// TaskOutputKey 返回AgentJob在FlowState中保存*agent.TaskOutput的键
+func TaskOutputKey(jobID string) string { return jobID + ".task_output" }
+
+// CrewOutputKey 返回CrewJob在FlowState中保存*crew.CrewOutput的键
+func CrewOutputKey(jobID string) string { return jobID + ".crew_output" }
+
+// GetTaskOutput 从工作流状态中读取AgentJob的任务输出
+func GetTaskOutput(state flow.FlowState, jobID string) (*agent.TaskOutput, bool) {
+	value, exists := state.Get(TaskOutputKey(jobID))
+	if !exists {
+		return nil, false
+	}
+	output, ok := value.(*agent.TaskOutput)
+	return output, ok
+}
+
+// GetCrewOutput 从工作流状态中读取CrewJob的Crew输出
+func GetCrewOutput(state flow.FlowState, jobID string) (*crew.CrewOutput, bool) {
+	value, exists := state.Get(CrewOutputKey(jobID))
+	if !exists {
+		return nil, false
+	}
+	output, ok := value.(*crew.CrewOutput)
+	return output, ok
+}
+
+// ============================================================================
+// AgentJob - 由Agent执行任务的作业
+// ============================================================================
+
+// AgentJob 在工作流中由Agent执行一个任务
+// 任务输出保存在FlowState的TaskOutputKey(id)下，作业结果为输出的Raw文本
+type AgentJob struct {
+	id               string
+	agent            agent.Agent
+	task             agent.Task
+	contextFromState func(state flow.FlowState) map[string]interface{}
+}
+
+var _ flow.StatefulJob = (*AgentJob)(nil)
+
+// NewAgentJob 创建Agent作业
+func NewAgentJob(id string, a agent.Agent, task agent.Task) *AgentJob {
+	return &AgentJob{id: id, agent: a, task: task}
+}
+
+// WithContextFromState 设置执行前从工作流状态生成任务上下文的函数，生成的上下文合并到任务已有的上下文中
+func (j *AgentJob) WithContextFromState(fn func(state flow.FlowState) map[string]interface{}) *AgentJob {
+	j.contextFromState = fn
+	return j
+}
+
+func (j *AgentJob) ID() string { return j.id }
+
+// Execute 在没有工作流状态时执行任务
+func (j *AgentJob) Execute(ctx context.Context) (interface{}, error) {
+	return j.ExecuteWithState(ctx, flow.NewFlowState())
+}
+
+// ExecuteWithState 执行任务并将输出保存到工作流状态
+func (j *AgentJob) ExecuteWithState(ctx context.Context, state flow.FlowState) (interface{}, error) {
+	if j.agent == nil || j.task == nil {
+		return nil, fmt.Errorf("agent job %s requires an agent and a task", j.id)
+	}
+
+	if j.contextFromState != nil {
+		taskContext := make(map[string]interface{})
+		for key, value := range j.task.GetContext() {
+			taskContext[key] = value
+		}
+		for key, value := range j.contextFromState(state) {
+			taskContext[key] = value
+		}
+		j.task.SetContext(taskContext)
+	}
+
+	output, err := j.agent.Execute(ctx, j.task)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s failed to execute task for job %s: %w", j.agent.GetRole(), j.id, err)
+	}
+
+	state.Set(TaskOutputKey(j.id), output)
+	return output.Raw, nil
+}
+
+// ============================================================================
+// CrewJob - 由Crew执行的作业
+// ============================================================================
+
+// CrewJob 在工作流中运行一个Crew
+// Kickoff的输入由工作流状态生成；输出保存在FlowState的CrewOutputKey(id)下，
+// 其JSON字段合并到工作流状态中，作业结果为输出的Raw文本
+type CrewJob struct {
+	id              string
+	crew            crew.Crew
+	inputsFromState func(state flow.FlowState) map[string]interface{}
+}
+
+var _ flow.StatefulJob = (*CrewJob)(nil)
+
+// NewCrewJob 创建Crew作业，inputsFromState为nil时以空输入启动Crew
+func NewCrewJob(id string, c crew.Crew, inputsFromState func(state flow.FlowState) map[string]interface{}) *CrewJob {
+	return &CrewJob{id: id, crew: c, inputsFromState: inputsFromState}
+}
+
+func (j *CrewJob) ID() string { return j.id }
+
+// Execute 在没有工作流状态时运行Crew
+func (j *CrewJob) Execute(ctx context.Context) (interface{}, error) {
+	return j.ExecuteWithState(ctx, flow.NewFlowState())
+}
+
+// ExecuteWithState 运行Crew并将输出合并到工作流状态
+func (j *CrewJob) ExecuteWithState(ctx context.Context, state flow.FlowState) (interface{}, error) {
+	if j.crew == nil {
+		return nil, fmt.Errorf("crew job %s requires a crew", j.id)
+	}
+
+	inputs := make(map[string]interface{})
+	if j.inputsFromState != nil {
+		inputs = j.inputsFromState(state)
+	}
+
+	output, err := j.crew.Kickoff(ctx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("crew job %s failed: %w", j.id, err)
+	}
+
+	state.Set(CrewOutputKey(j.id), output)
+	if len(output.JSON) > 0 {
+		state.SetAll(output.JSON)
+	}
+	return output.Raw, nil
+}
@@ -0,0 +1,200 @@
+package agentflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/flow"
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// MockLLM 按提示中的关键字返回预设回答，记录收到的提示
+type MockLLM struct {
+	responses map[string]string
+	err       error
+	mu        sync.Mutex
+	prompts   []string
+}
+
+func NewMockLLM(responses map[string]string) *MockLLM {
+	return &MockLLM{responses: responses}
+}
+
+func (m *MockLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var prompt strings.Builder
+	for _, message := range messages {
+		if content, ok := message.Content.(string); ok {
+			prompt.WriteString(content)
+			prompt.WriteString("\n")
+		}
+	}
+
+	m.mu.Lock()
+	m.prompts = append(m.prompts, prompt.String())
+	m.mu.Unlock()
+
+	for keyword, response := range m.responses {
+		if strings.Contains(prompt.String(), keyword) {
+			return &llm.Response{Content: response, Usage: llm.Usage{TotalTokens: 10}}, nil
+		}
+	}
+	return &llm.Response{Content: "no answer"}, nil
+}
+
+func (m *MockLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	return nil, errors.New("streaming not supported")
+}
+
+func (m *MockLLM) GetModel() string                     { return "mock-model" }
+func (m *MockLLM) SupportsFunctionCalling() bool        { return false }
+func (m *MockLLM) GetContextWindowSize() int            { return 4096 }
+func (m *MockLLM) GetTokenizer() llm.Tokenizer          { return llm.HeuristicTokenizer{} }
+func (m *MockLLM) SetEventBus(eventBus events.EventBus) {}
+func (m *MockLLM) Close() error                         { return nil }
+
+func (m *MockLLM) promptContaining(keyword string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, prompt := range m.prompts {
+		if strings.Contains(prompt, keyword) {
+			return prompt
+		}
+	}
+	return ""
+}
+
+func newTestAgent(t *testing.T, role string, mockLLM llm.LLM) agent.Agent {
+	t.Helper()
+	a, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      role,
+		Goal:      "Analyze collected data",
+		Backstory: "An experienced analyst",
+		LLM:       mockLLM,
+		Logger:    logger.NewTestLogger(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	return a
+}
+
+// TestAgentJobsInWorkflow 测试三个分析作业由Agent并行执行，输出保存到工作流状态
+func TestAgentJobsInWorkflow(t *testing.T) {
+	mockLLM := NewMockLLM(map[string]string{
+		"quality":   "Quality score: 85%",
+		"sentiment": "Positive sentiment: 78%",
+		"topics":    "Main topics: AI, technology, innovation",
+	})
+
+	collect := flow.NewStatefulJob("data-collection", func(ctx context.Context, state flow.FlowState) (interface{}, error) {
+		state.Set("records", "1000 customer reviews")
+		return "collected", nil
+	})
+
+	recordsContext := func(state flow.FlowState) map[string]interface{} {
+		records, _ := state.GetString("records")
+		return map[string]interface{}{"records": records}
+	}
+	analyses := map[string]string{
+		"quality-analysis":   "Assess the quality of the records",
+		"sentiment-analysis": "Measure the sentiment of the records",
+		"topic-analysis":     "Extract the main topics of the records",
+	}
+
+	workflow := flow.NewWorkflow("analysis").AddJob(collect, flow.Immediately())
+	for id, description := range analyses {
+		job := NewAgentJob(id, newTestAgent(t, "Analyst", mockLLM), agent.NewBaseTask(description, "A short finding")).
+			WithContextFromState(recordsContext)
+		workflow.AddJob(job, flow.After("data-collection"))
+	}
+
+	result, err := workflow.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Metrics.MaxConcurrency != 3 {
+		t.Errorf("Expected the three analysis jobs to run in one batch, got concurrency %d", result.Metrics.MaxConcurrency)
+	}
+	if result.AllResults["quality-analysis"] != "Quality score: 85%" {
+		t.Errorf("Expected job result to be the raw output, got: %v", result.AllResults["quality-analysis"])
+	}
+
+	output, ok := GetTaskOutput(result.FinalState, "sentiment-analysis")
+	if !ok {
+		t.Fatal("Expected task output to be stored in flow state")
+	}
+	if output.Raw != "Positive sentiment: 78%" || output.TokensUsed != 10 {
+		t.Errorf("Unexpected task output: %+v", output)
+	}
+
+	if prompt := mockLLM.promptContaining("topics"); !strings.Contains(prompt, "1000 customer reviews") {
+		t.Errorf("Expected task context from flow state in prompt, got: %s", prompt)
+	}
+}
+
+// TestAgentJobFailure 测试Agent执行失败时作业返回错误
+func TestAgentJobFailure(t *testing.T) {
+	mockLLM := NewMockLLM(nil)
+	mockLLM.err = errors.New("provider unavailable")
+
+	job := NewAgentJob("analysis", newTestAgent(t, "Analyst", mockLLM), agent.NewBaseTask("Analyze", "Findings"))
+	_, err := job.Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "provider unavailable") {
+		t.Fatalf("Expected agent error to be returned, got: %v", err)
+	}
+}
+
+// TestCrewJob 测试Crew作业从状态生成输入并把输出合并回状态
+func TestCrewJob(t *testing.T) {
+	mockLLM := NewMockLLM(map[string]string{"Write a report": "Report: all good"})
+
+	reportCrew := crew.NewBaseCrew(&crew.CrewConfig{Name: "reporting", Process: crew.ProcessSequential}, events.NewEventBus(logger.NewTestLogger()), logger.NewTestLogger())
+	if err := reportCrew.AddAgent(newTestAgent(t, "Writer", mockLLM)); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	if err := reportCrew.AddTask(agent.NewBaseTask("Write a report", "A report")); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := reportCrew.AddAfterKickoffCallback(func(ctx context.Context, c crew.Crew, output *crew.CrewOutput) (*crew.CrewOutput, error) {
+		output.JSON = map[string]interface{}{"approved": true}
+		return output, nil
+	}); err != nil {
+		t.Fatalf("Failed to add callback: %v", err)
+	}
+
+	state := flow.NewFlowStateWithData(map[string]interface{}{"topic": "quarterly sales"})
+	job := NewCrewJob("report", reportCrew, func(state flow.FlowState) map[string]interface{} {
+		topic, _ := state.GetString("topic")
+		return map[string]interface{}{"topic": topic}
+	})
+
+	result, err := job.ExecuteWithState(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result != "Report: all good" {
+		t.Errorf("Expected raw crew output, got: %v", result)
+	}
+	if output, ok := GetCrewOutput(state, "report"); !ok || len(output.TasksOutput) != 1 {
+		t.Errorf("Expected crew output in flow state, got: %v", output)
+	}
+	if approved, _ := state.GetBool("approved"); !approved {
+		t.Error("Expected crew JSON output to be merged into flow state")
+	}
+	if prompt := mockLLM.promptContaining("Write a report"); !strings.Contains(prompt, "quarterly sales") {
+		t.Errorf("Expected crew inputs from flow state in prompt, got: %s", prompt)
+	}
+}
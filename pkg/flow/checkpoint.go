@@ -0,0 +1,272 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// ============================================================================
+// 检查点 - 工作流暂停和恢复
+// ============================================================================
+
+var (
+	// ErrCheckpointNotFound 存储中没有该工作流的检查点
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+	// ErrCheckpointCorrupted 检查点数据无法解析
+	ErrCheckpointCorrupted = errors.New("checkpoint corrupted")
+)
+
+// Checkpoint 工作流检查点，每个批次完成后保存
+// 作业结果和状态值以JSON保存，恢复后数字变为float64，结构体变为map；无法序列化的值会被丢弃并记录警告
+type Checkpoint struct {
+	Workflow string                       `json:"workflow"`
+	Results  map[string]interface{}       `json:"results"`  // 成功作业的结果
+	Failures map[string]CheckpointFailure `json:"failures"` // 以ContinueOnError策略失败的作业
+	State    map[string]interface{}       `json:"state"`    // FlowState快照
+	Trace    []CheckpointExecution        `json:"trace"`
+	Batches  int                          `json:"batches"`
+	SavedAt  time.Time                    `json:"saved_at"`
+}
+
+// CheckpointFailure 检查点中的作业失败记录
+type CheckpointFailure struct {
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+}
+
+// CheckpointExecution 检查点中的作业执行记录，结果保存在Checkpoint.Results中
+type CheckpointExecution struct {
+	JobID     string        `json:"job_id"`
+	StartTime time.Time     `json:"start_time"`
+	EndTime   time.Time     `json:"end_time"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+	BatchID   int           `json:"batch_id"`
+	Attempts  int           `json:"attempts"`
+}
+
+// CheckpointStore 检查点存储接口
+type CheckpointStore interface {
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+	// Load 加载工作流的检查点，不存在时返回ErrCheckpointNotFound，无法解析时返回ErrCheckpointCorrupted
+	Load(ctx context.Context, workflow string) (*Checkpoint, error)
+	Delete(ctx context.Context, workflow string) error
+}
+
+// WorkflowOption 工作流配置选项
+type WorkflowOption func(*ParallelEngine)
+
+// WithCheckpointStore 每个批次完成后将已完成的作业结果、工作流状态和执行追踪保存到存储
+func WithCheckpointStore(store CheckpointStore) WorkflowOption {
+	return func(e *ParallelEngine) { e.checkpointStore = store }
+}
+
+// WithLogger 设置工作流日志记录器
+func WithLogger(log logger.Logger) WorkflowOption {
+	return func(e *ParallelEngine) { e.logger = log }
+}
+
+// ResumeWorkflow 从存储中的检查点恢复工作流
+// 作业函数无法持久化，调用方需要重新添加与原工作流相同的作业；Run会跳过检查点中已完成的作业并恢复FlowState。
+// 没有检查点时从头开始执行，检查点损坏时返回错误
+func ResumeWorkflow(ctx context.Context, name string, store CheckpointStore, opts ...WorkflowOption) (Workflow, error) {
+	checkpoint, err := store.Load(ctx, name)
+	if err != nil && !errors.Is(err, ErrCheckpointNotFound) {
+		return nil, fmt.Errorf("failed to resume workflow %s: %w", name, err)
+	}
+
+	engine := newParallelEngine(name, append([]WorkflowOption{WithCheckpointStore(store)}, opts...)...)
+	engine.resumeFrom = checkpoint
+	return engine, nil
+}
+
+// snapshot 生成检查点，无法序列化的值被丢弃并记录警告
+func (e *ParallelEngine) snapshot(result *ExecutionResult, batches int) *Checkpoint {
+	checkpoint := &Checkpoint{
+		Workflow: e.name,
+		Results:  make(map[string]interface{}),
+		Failures: make(map[string]CheckpointFailure),
+		State:    make(map[string]interface{}),
+		Batches:  batches,
+		SavedAt:  time.Now(),
+	}
+
+	for id, value := range result.AllResults {
+		if failure, ok := value.(*JobFailure); ok {
+			checkpoint.Failures[id] = CheckpointFailure{Attempts: failure.Attempts, Error: failure.Err.Error()}
+			continue
+		}
+		// 作业结果无法序列化时保存为null，作业仍视为已完成
+		checkpoint.Results[id] = e.serializable("job result", id, value)
+	}
+
+	for key, value := range result.FinalState.GetAll() {
+		if value = e.serializable("state value", key, value); value != nil {
+			checkpoint.State[key] = value
+		}
+	}
+
+	for _, exec := range result.JobTrace {
+		record := CheckpointExecution{
+			JobID:     exec.JobID,
+			StartTime: exec.StartTime,
+			EndTime:   exec.EndTime,
+			Duration:  exec.Duration,
+			BatchID:   exec.BatchID,
+			Attempts:  exec.Attempts,
+		}
+		if exec.Error != nil {
+			record.Error = exec.Error.Error()
+		}
+		checkpoint.Trace = append(checkpoint.Trace, record)
+	}
+
+	return checkpoint
+}
+
+// serializable 检查值能否序列化为JSON，不能时返回nil并记录警告
+func (e *ParallelEngine) serializable(kind, key string, value interface{}) interface{} {
+	if _, err := json.Marshal(value); err != nil {
+		e.logger.Warn("Dropping value that cannot be serialized to the checkpoint",
+			logger.Field{Key: "workflow", Value: e.name},
+			logger.Field{Key: "kind", Value: kind},
+			logger.Field{Key: "key", Value: key},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+		return nil
+	}
+	return value
+}
+
+// restore 用检查点初始化执行结果和工作流状态，返回已执行的批次数
+func (c *Checkpoint) restore(result *ExecutionResult) int {
+	result.FinalState.SetAll(c.State)
+
+	for id, value := range c.Results {
+		result.AllResults[id] = value
+	}
+	for id, failure := range c.Failures {
+		err := errors.New(failure.Error)
+		result.AllResults[id] = &JobFailure{JobID: id, Attempts: failure.Attempts, Err: err}
+		result.FailedJobs[id] = err
+	}
+
+	for _, record := range c.Trace {
+		exec := JobExecution{
+			JobID:     record.JobID,
+			StartTime: record.StartTime,
+			EndTime:   record.EndTime,
+			Duration:  record.Duration,
+			BatchID:   record.BatchID,
+			Attempts:  record.Attempts,
+		}
+		if record.Error != "" {
+			exec.Error = errors.New(record.Error)
+		} else {
+			exec.Result = c.Results[record.JobID]
+			result.SucceededJobs = append(result.SucceededJobs, record.JobID)
+			result.FinalResult = exec.Result
+		}
+		result.JobTrace = append(result.JobTrace, exec)
+	}
+
+	return c.Batches
+}
+
+// ============================================================================
+// 文件检查点存储
+// ============================================================================
+
+// FileCheckpointStore 将检查点以JSON文件保存在目录中，每个工作流一个文件
+// 写入先写临时文件再重命名，进程在写入过程中退出也不会留下不完整的检查点
+type FileCheckpointStore struct {
+	dir string
+}
+
+var _ CheckpointStore = (*FileCheckpointStore)(nil)
+
+// NewFileCheckpointStore 创建文件检查点存储，目录不存在时自动创建
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+// Path 返回工作流检查点文件的路径
+func (s *FileCheckpointStore) Path(workflow string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(workflow)
+	return filepath.Join(s.dir, name+".checkpoint.json")
+}
+
+func (s *FileCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	if err := writeFileAtomic(s.Path(checkpoint.Workflow), data); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic 先写同目录的临时文件再重命名，进程在写入过程中退出也不会留下不完整的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后删除不会生效
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+func (s *FileCheckpointStore) Load(ctx context.Context, workflow string) (*Checkpoint, error) {
+	path := s.Path(workflow)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to read checkpoint %s: %w", path, err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCheckpointCorrupted, path, err)
+	}
+	if checkpoint.Workflow != workflow {
+		return nil, fmt.Errorf("%w: %s belongs to workflow %q", ErrCheckpointCorrupted, path, checkpoint.Workflow)
+	}
+	return &checkpoint, nil
+}
+
+func (s *FileCheckpointStore) Delete(ctx context.Context, workflow string) error {
+	if err := os.Remove(s.Path(workflow)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func newTestCheckpointStore(t *testing.T) *FileCheckpointStore {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint store: %v", err)
+	}
+	return store
+}
+
+// addCheckpointJobs 添加两个批次的作业，report作业在failReport为true时失败
+func addCheckpointJobs(workflow Workflow, collectCalls *int32, failReport bool) Workflow {
+	collect := NewStatefulJob("collect", func(ctx context.Context, state FlowState) (interface{}, error) {
+		atomic.AddInt32(collectCalls, 1)
+		state.Set("count", 42)
+		return map[string]interface{}{"records": 42}, nil
+	})
+	report := NewStatefulJob("report", func(ctx context.Context, state FlowState) (interface{}, error) {
+		if failReport {
+			return nil, errors.New("process crashed")
+		}
+		count, _ := state.GetFloat64("count")
+		return count * 2, nil
+	})
+
+	return workflow.
+		AddJob(collect, Immediately()).
+		AddJob(report, After("collect"))
+}
+
+func TestCheckpointResume(t *testing.T) {
+	ctx := context.Background()
+	store := newTestCheckpointStore(t)
+	var collectCalls int32
+
+	workflow := addCheckpointJobs(NewWorkflow("nightly", WithCheckpointStore(store)), &collectCalls, true)
+	if _, err := workflow.Run(ctx); err == nil {
+		t.Fatal("Expected the first run to fail")
+	}
+
+	checkpoint, err := store.Load(ctx, "nightly")
+	if err != nil {
+		t.Fatalf("Expected checkpoint after first batch, got: %v", err)
+	}
+	if _, saved := checkpoint.Results["report"]; saved || len(checkpoint.Trace) != 1 {
+		t.Errorf("Expected only the completed batch in the checkpoint, got: %+v", checkpoint)
+	}
+
+	resumed, err := ResumeWorkflow(ctx, "nightly", store)
+	if err != nil {
+		t.Fatalf("Expected resume to succeed, got: %v", err)
+	}
+	result, err := addCheckpointJobs(resumed, &collectCalls, false).Run(ctx)
+	if err != nil {
+		t.Fatalf("Expected resumed run to succeed, got: %v", err)
+	}
+
+	if collectCalls != 1 {
+		t.Errorf("Expected completed job to be skipped on resume, ran %d times", collectCalls)
+	}
+	if result.FinalResult != float64(84) {
+		t.Errorf("Expected report to use rehydrated state, got: %v", result.FinalResult)
+	}
+	if len(result.JobTrace) != 2 || result.JobTrace[1].BatchID != 2 {
+		t.Errorf("Expected restored trace followed by the resumed batch, got: %+v", result.JobTrace)
+	}
+}
+
+func TestResumeWithoutCheckpointStartsFresh(t *testing.T) {
+	var collectCalls int32
+	workflow, err := ResumeWorkflow(context.Background(), "fresh", newTestCheckpointStore(t))
+	if err != nil {
+		t.Fatalf("Expected missing checkpoint to start fresh, got: %v", err)
+	}
+
+	if _, err := addCheckpointJobs(workflow, &collectCalls, false).Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if collectCalls != 1 {
+		t.Errorf("Expected collect to run once, ran %d times", collectCalls)
+	}
+}
+
+func TestResumeCorruptedCheckpoint(t *testing.T) {
+	store := newTestCheckpointStore(t)
+	if err := os.WriteFile(store.Path("broken"), []byte(`{"workflow": "broken", "results": {`), 0o644); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+
+	_, err := ResumeWorkflow(context.Background(), "broken", store)
+	if !errors.Is(err, ErrCheckpointCorrupted) {
+		t.Fatalf("Expected corrupted checkpoint error, got: %v", err)
+	}
+}
+
+func TestCheckpointDropsNonSerializableState(t *testing.T) {
+	ctx := context.Background()
+	store := newTestCheckpointStore(t)
+
+	job := NewStatefulJob("job", func(ctx context.Context, state FlowState) (interface{}, error) {
+		state.Set("name", "report")
+		state.Set("callback", func() {})
+		return "done", nil
+	})
+
+	_, err := NewWorkflow("lossy", WithCheckpointStore(store), WithLogger(logger.NewTestLogger())).
+		AddJob(job, Immediately()).
+		Run(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	checkpoint, err := store.Load(ctx, "lossy")
+	if err != nil {
+		t.Fatalf("Expected checkpoint, got: %v", err)
+	}
+	if checkpoint.State["name"] != "report" {
+		t.Errorf("Expected serializable state to be saved, got: %v", checkpoint.State)
+	}
+	if _, saved := checkpoint.State["callback"]; saved {
+		t.Error("Expected non-serializable state value to be dropped")
+	}
+
+	// 写入通过临时文件和重命名完成，不应留下临时文件
+	entries, _ := os.ReadDir(filepath.Dir(store.Path("lossy")))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the checkpoint file, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,211 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// 条件触发器 - 基于工作流状态和作业结果的分支
+// ============================================================================
+
+// StateTrigger 需要读取工作流状态的触发器，引擎在每个调度周期用当前状态重新评估
+type StateTrigger interface {
+	Trigger
+	ReadyWithState(completed JobResults, state FlowState) bool
+}
+
+// DependentTrigger 声明所依赖作业的触发器，引擎据此判断作业是否还可能就绪
+type DependentTrigger interface {
+	Trigger
+	Dependencies() []string
+}
+
+// triggerReady 评估触发器，支持状态时传入当前工作流状态
+func triggerReady(trigger Trigger, completed JobResults, state FlowState) bool {
+	if stateTrigger, ok := trigger.(StateTrigger); ok {
+		return stateTrigger.ReadyWithState(completed, state)
+	}
+	return trigger.Ready(completed)
+}
+
+// triggerDependencies 返回触发器依赖的作业ID
+func triggerDependencies(trigger Trigger) []string {
+	if dependent, ok := trigger.(DependentTrigger); ok {
+		return dependent.Dependencies()
+	}
+	return nil
+}
+
+// failureHandler 处理上游作业失败的触发器
+// 被处理的作业失败时引擎记录错误而不终止工作流，让补救作业可以执行
+type failureHandler interface {
+	handledFailures() []string
+}
+
+// triggerHandledFailures 返回触发器会处理其失败的作业ID
+func triggerHandledFailures(trigger Trigger) []string {
+	if handler, ok := trigger.(failureHandler); ok {
+		return handler.handledFailures()
+	}
+	return nil
+}
+
+// WhenStateTrigger 工作流状态满足条件时触发
+type WhenStateTrigger struct {
+	predicate func(state FlowState) bool
+}
+
+// Ready 没有工作流状态时无法评估条件，始终返回false
+func (t WhenStateTrigger) Ready(completed JobResults) bool { return false }
+
+func (t WhenStateTrigger) ReadyWithState(completed JobResults, state FlowState) bool {
+	return state != nil && t.predicate(state)
+}
+
+func (t WhenStateTrigger) String() string { return "when-state" }
+
+// FailureTrigger 在指定作业失败后触发
+type FailureTrigger struct{ jobID string }
+
+func (t FailureTrigger) Ready(completed JobResults) bool {
+	_, failed := completed.Failure(t.jobID)
+	return failed
+}
+
+func (t FailureTrigger) String() string            { return fmt.Sprintf("on-failure:%s", t.jobID) }
+func (t FailureTrigger) Dependencies() []string    { return []string{t.jobID} }
+func (t FailureTrigger) handledFailures() []string { return []string{t.jobID} }
+
+// WhenState 工作流状态满足条件时触发，通常与After组合使用：
+//
+//	flow.AllOf(flow.After("score"), flow.WhenState(func(s flow.FlowState) bool {
+//		v, _ := s.GetFloat64("quality")
+//		return v > 0.8
+//	}))
+func WhenState(predicate func(state FlowState) bool) Trigger {
+	return WhenStateTrigger{predicate: predicate}
+}
+
+// OnSuccess 在指定作业成功完成后触发，与After相同
+func OnSuccess(jobID string) Trigger { return After(jobID) }
+
+// OnFailure 在指定作业失败后触发，用于路由到补救作业
+// 存在OnFailure触发器时，该作业失败不会终止工作流，而是记录在FailedJobs中
+func OnFailure(jobID string) Trigger { return FailureTrigger{jobID} }
+
+// ============================================================================
+// 死锁检测
+// ============================================================================
+
+// ErrWorkflowDeadlock 工作流中存在永远无法就绪的作业
+var ErrWorkflowDeadlock = errors.New("workflow deadlock")
+
+// DeadlockError 描述无法就绪的作业及其触发条件
+type DeadlockError struct {
+	Jobs       []string            // 无法就绪的作业ID
+	Triggers   map[string]string   // 作业ID到触发条件的描述
+	Unknown    map[string][]string // 作业ID到触发条件引用的、工作流中不存在的作业
+	Incomplete map[string][]string // 作业ID到触发条件引用的、未成功完成的作业（失败、跳过或同样无法就绪）
+}
+
+func (e *DeadlockError) Error() string {
+	parts := make([]string, len(e.Jobs))
+	for i, id := range e.Jobs {
+		detail := e.Triggers[id]
+		if unknown := e.Unknown[id]; len(unknown) > 0 {
+			detail += "; unknown: " + strings.Join(unknown, ", ")
+		}
+		if incomplete := e.Incomplete[id]; len(incomplete) > 0 {
+			detail += "; incomplete: " + strings.Join(incomplete, ", ")
+		}
+		parts[i] = fmt.Sprintf("%s (%s)", id, detail)
+	}
+	return fmt.Sprintf("%s: jobs can never become ready: %s", ErrWorkflowDeadlock, strings.Join(parts, ", "))
+}
+
+func (e *DeadlockError) Is(target error) bool { return target == ErrWorkflowDeadlock }
+
+// classifyPendingJobs 在没有作业就绪时区分未执行的作业
+// 依赖的作业都已结束（完成或跳过）的作业是未选中的分支，记为跳过；
+// 其余作业依赖未执行或不存在的作业（如循环依赖），永远无法就绪。
+// 严格模式下依赖的作业都已结束但没有一个成功的作业同样视为无法就绪
+func classifyPendingJobs(pending []jobWithTrigger, completed JobResults, known map[string]bool, strict bool) (skipped []string, deadlocked *DeadlockError) {
+	settled := make(map[string]bool, len(completed))
+	for id := range completed {
+		settled[id] = true
+	}
+
+	remaining := pending
+	for changed := true; changed; {
+		changed = false
+		var next []jobWithTrigger
+		for _, jt := range remaining {
+			deps := triggerDependencies(jt.trigger)
+			if allSettled(deps, settled) && (!strict || len(deps) == 0 || anySucceeded(deps, completed)) {
+				settled[jt.job.ID()] = true
+				skipped = append(skipped, jt.job.ID())
+				changed = true
+				continue
+			}
+			next = append(next, jt)
+		}
+		remaining = next
+	}
+
+	if len(remaining) == 0 {
+		return skipped, nil
+	}
+
+	deadlocked = &DeadlockError{
+		Triggers:   make(map[string]string, len(remaining)),
+		Unknown:    make(map[string][]string),
+		Incomplete: make(map[string][]string),
+	}
+	for _, jt := range remaining {
+		id := jt.job.ID()
+		deadlocked.Jobs = append(deadlocked.Jobs, id)
+		deadlocked.Triggers[id] = jt.trigger.String()
+		for _, dep := range triggerDependencies(jt.trigger) {
+			switch {
+			case !known[dep]:
+				deadlocked.Unknown[id] = append(deadlocked.Unknown[id], dep)
+			case !completed.Succeeded(dep):
+				deadlocked.Incomplete[id] = append(deadlocked.Incomplete[id], dep)
+			}
+		}
+	}
+	sort.Strings(deadlocked.Jobs)
+	return skipped, deadlocked
+}
+
+// unknownDependencies 返回触发条件引用的、不在known中的作业ID
+func unknownDependencies(trigger Trigger, known map[string]bool) []string {
+	var unknown []string
+	for _, dep := range triggerDependencies(trigger) {
+		if !known[dep] {
+			unknown = append(unknown, dep)
+		}
+	}
+	return unknown
+}
+
+func anySucceeded(ids []string, completed JobResults) bool {
+	for _, id := range ids {
+		if completed.Succeeded(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func allSettled(ids []string, settled map[string]bool) bool {
+	for _, id := range ids {
+		if !settled[id] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,296 @@
+package flow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// 工作流结构描述 - 导出为Graphviz DOT和Mermaid流程图
+// ============================================================================
+
+const (
+	// GraphStartNode 图中表示工作流开始的节点，立即触发的作业从它连出
+	GraphStartNode = "__start__"
+	// GraphStateNode 图中表示工作流状态的节点，WhenState条件从它连出
+	GraphStateNode = "__state__"
+)
+
+// WorkflowGraph 工作流的静态结构：作业、触发条件和依赖
+// 作业按添加顺序排列，边按目标作业和触发器结构的顺序排列，同一工作流的导出结果总是相同
+type WorkflowGraph struct {
+	Name  string
+	Jobs  []GraphJob
+	Edges []GraphEdge
+}
+
+// GraphJob 图中的作业节点
+type GraphJob struct {
+	ID          string
+	Trigger     string // 触发条件的描述
+	Entry       bool   // 工作流开始时立即执行
+	FanOut      bool   // 运行时扇出子作业
+	Undefined   bool   // 被触发条件引用但没有添加到工作流
+	Unreachable bool   // 触发条件永远无法满足，作业不会执行
+}
+
+// GraphEdge 触发依赖：From完成（或失败、状态满足）后可能触发To
+type GraphEdge struct {
+	From  string // 作业ID、GraphStartNode或GraphStateNode
+	To    string
+	Kind  string // 叶子触发器类型：immediate、after、after-completion、on-failure、when-state
+	Label string // 包含外层组合的标签，如all-of、any-of/on-failure
+}
+
+// Describe 返回工作流的静态结构
+func (e *ParallelEngine) Describe() *WorkflowGraph {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	graph := &WorkflowGraph{Name: e.name}
+	defined := make(map[string]bool, len(e.jobs))
+	for _, jt := range e.jobs {
+		defined[jt.job.ID()] = true
+	}
+
+	for _, jt := range e.jobs {
+		_, fanOut := jt.job.(FanOutJob)
+		_, immediate := jt.trigger.(ImmediateTrigger)
+		graph.Jobs = append(graph.Jobs, GraphJob{
+			ID:      jt.job.ID(),
+			Trigger: jt.trigger.String(),
+			Entry:   immediate,
+			FanOut:  fanOut,
+		})
+		graph.Edges = append(graph.Edges, triggerEdges(jt.trigger, jt.job.ID(), nil)...)
+	}
+
+	// 引用了不存在的作业时补充节点，便于在图中发现拼写错误
+	for _, edge := range graph.Edges {
+		if edge.From == GraphStartNode || edge.From == GraphStateNode || defined[edge.From] {
+			continue
+		}
+		defined[edge.From] = true
+		graph.Jobs = append(graph.Jobs, GraphJob{ID: edge.From, Undefined: true})
+	}
+
+	reachable := reachableJobs(e.jobs)
+	for i := range graph.Jobs {
+		graph.Jobs[i].Unreachable = !reachable[graph.Jobs[i].ID]
+	}
+	return graph
+}
+
+// triggerEdges 把触发器展开为依赖边，path记录外层的all-of/any-of组合
+func triggerEdges(trigger Trigger, to string, path []string) []GraphEdge {
+	edge := func(from, kind string) []GraphEdge {
+		return []GraphEdge{{From: from, To: to, Kind: kind, Label: edgeLabel(path, kind)}}
+	}
+
+	switch t := trigger.(type) {
+	case ImmediateTrigger:
+		return edge(GraphStartNode, "immediate")
+	case AfterTrigger:
+		if t.anyOutcome {
+			return edge(t.jobID, "after-completion")
+		}
+		return edge(t.jobID, "after")
+	case FailureTrigger:
+		return edge(t.jobID, "on-failure")
+	case WhenStateTrigger:
+		return edge(GraphStateNode, "when-state")
+	case AllOfTrigger:
+		return childEdges(t.triggers, to, append(path, t.String()))
+	case AnyOfTrigger:
+		return childEdges(t.triggers, to, append(path, t.String()))
+	}
+
+	// 自定义触发器：按声明的依赖连边，标签使用触发器的描述
+	var edges []GraphEdge
+	for _, dep := range triggerDependencies(trigger) {
+		edges = append(edges, edge(dep, trigger.String())...)
+	}
+	return edges
+}
+
+func childEdges(triggers []Trigger, to string, path []string) []GraphEdge {
+	var edges []GraphEdge
+	for _, child := range triggers {
+		edges = append(edges, triggerEdges(child, to, path[:len(path):len(path)])...)
+	}
+	return edges
+}
+
+// edgeLabel 组合内的普通After只显示组合类型，其他触发器附加自身类型
+func edgeLabel(path []string, kind string) string {
+	if kind == "after" && len(path) > 0 {
+		return strings.Join(path, "/")
+	}
+	return strings.Join(append(path[:len(path):len(path)], kind), "/")
+}
+
+// reachableJobs 静态分析可能执行的作业：从立即触发的作业出发，反复加入触发条件可能满足的作业
+// 状态条件和没有声明依赖的自定义触发器视为可能满足
+func reachableJobs(jobs []jobWithTrigger) map[string]bool {
+	reachable := make(map[string]bool, len(jobs))
+	for changed := true; changed; {
+		changed = false
+		for _, jt := range jobs {
+			if !reachable[jt.job.ID()] && triggerPossible(jt.trigger, reachable) {
+				reachable[jt.job.ID()] = true
+				changed = true
+			}
+		}
+	}
+	return reachable
+}
+
+func triggerPossible(trigger Trigger, reachable map[string]bool) bool {
+	switch t := trigger.(type) {
+	case ImmediateTrigger, WhenStateTrigger:
+		return true
+	case AllOfTrigger:
+		for _, child := range t.triggers {
+			if !triggerPossible(child, reachable) {
+				return false
+			}
+		}
+		return true
+	case AnyOfTrigger:
+		for _, child := range t.triggers {
+			if triggerPossible(child, reachable) {
+				return true
+			}
+		}
+		return false
+	}
+	return allSettled(triggerDependencies(trigger), reachable)
+}
+
+// UnreachableJobs 返回永远不会执行的作业ID
+func (g *WorkflowGraph) UnreachableJobs() []string {
+	var ids []string
+	for _, job := range g.Jobs {
+		if job.Unreachable {
+			ids = append(ids, job.ID)
+		}
+	}
+	return ids
+}
+
+// usesNode 图中是否有从指定伪节点连出的边
+func (g *WorkflowGraph) usesNode(id string) bool {
+	for _, edge := range g.Edges {
+		if edge.From == id {
+			return true
+		}
+	}
+	return false
+}
+
+// label 节点文字，附加作业的特殊状态
+func (job GraphJob) label() string {
+	var notes []string
+	if job.FanOut {
+		notes = append(notes, "fan-out")
+	}
+	if job.Undefined {
+		notes = append(notes, "undefined")
+	} else if job.Unreachable {
+		notes = append(notes, "unreachable")
+	}
+	if len(notes) == 0 {
+		return job.ID
+	}
+	return fmt.Sprintf("%s\n(%s)", job.ID, strings.Join(notes, ", "))
+}
+
+// DOT 导出为Graphviz DOT，不可达的作业以红色虚线框标出
+func (g *WorkflowGraph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	if g.usesNode(GraphStartNode) {
+		fmt.Fprintf(&b, "  %s [label=\"start\", shape=circle];\n", dotQuote(GraphStartNode))
+	}
+	if g.usesNode(GraphStateNode) {
+		fmt.Fprintf(&b, "  %s [label=\"flow state\", shape=diamond];\n", dotQuote(GraphStateNode))
+	}
+
+	for _, job := range g.Jobs {
+		attrs := []string{"label=" + dotQuote(job.label())}
+		if job.Unreachable {
+			attrs = append(attrs, `style="dashed,filled"`, `color="#d9534f"`, `fillcolor="#f8d7da"`)
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(job.ID), strings.Join(attrs, ", "))
+	}
+
+	for _, edge := range g.Edges {
+		attrs := []string{"label=" + dotQuote(edge.Label)}
+		switch edge.Kind {
+		case "on-failure":
+			attrs = append(attrs, `color="#d9534f"`, "style=dashed")
+		case "after-completion":
+			attrs = append(attrs, "style=dashed")
+		case "when-state":
+			attrs = append(attrs, "style=dotted")
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(edge.From), dotQuote(edge.To), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// Mermaid 导出为Mermaid流程图，可以直接粘贴到PR描述中
+// 节点使用生成的ID（j1、j2…），避免作业ID中的特殊字符和Mermaid保留字
+func (g *WorkflowGraph) Mermaid() string {
+	ids := make(map[string]string, len(g.Jobs)+2)
+	ids[GraphStartNode] = "start"
+	ids[GraphStateNode] = "state"
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	if g.usesNode(GraphStartNode) {
+		b.WriteString("    start((start))\n")
+	}
+	if g.usesNode(GraphStateNode) {
+		b.WriteString("    state{\"flow state\"}\n")
+	}
+
+	var unreachable []string
+	for i, job := range g.Jobs {
+		id := fmt.Sprintf("j%d", i+1)
+		ids[job.ID] = id
+		fmt.Fprintf(&b, "    %s[%s]\n", id, mermaidQuote(job.label()))
+		if job.Unreachable {
+			unreachable = append(unreachable, id)
+		}
+	}
+
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		switch edge.Kind {
+		case "on-failure", "after-completion", "when-state":
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "    %s %s|%s| %s\n", ids[edge.From], arrow, mermaidQuote(edge.Label), ids[edge.To])
+	}
+
+	if len(unreachable) > 0 {
+		b.WriteString("    classDef unreachable fill:#f8d7da,stroke:#d9534f,stroke-dasharray: 5 5\n")
+		fmt.Fprintf(&b, "    class %s unreachable\n", strings.Join(unreachable, ","))
+	}
+	return b.String()
+}
+
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	return `"` + strings.ReplaceAll(s, "\n", "<br/>") + `"`
+}
@@ -0,0 +1,138 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// assertGolden 比较输出与testdata中的golden文件，-update时重写golden文件
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output does not match %s:\n%s", golden, got)
+	}
+}
+
+func noopJob(id string) Job {
+	return NewJob(id, func(ctx context.Context) (interface{}, error) { return id, nil })
+}
+
+// newGraphTestWorkflow 覆盖所有触发器类型的工作流，orphan依赖不存在的作业，loop_a和loop_b互相依赖
+func newGraphTestWorkflow() Workflow {
+	highQuality := WhenState(func(state FlowState) bool { return true })
+	return NewWorkflow("review-pipeline").
+		AddJob(noopJob("fetch"), Immediately()).
+		AddJob(NewMapJob("score", "items", func(index int, item interface{}) Job { return noopJob("child") }), After("fetch")).
+		AddJob(noopJob("publish"), AllOf(After("score"), highQuality)).
+		AddJob(noopJob("notify"), AnyOf(After("publish"), OnFailure("score"))).
+		AddJob(noopJob("cleanup"), AfterCompletion("publish")).
+		AddJob(noopJob("orphan"), After("fetchh")).
+		AddJob(noopJob("loop_a"), After("loop_b")).
+		AddJob(noopJob("loop_b"), AfterJobs("fetch", "loop_a"))
+}
+
+func TestWorkflowDescribe(t *testing.T) {
+	graph := newGraphTestWorkflow().Describe()
+
+	if graph.Name != "review-pipeline" || len(graph.Jobs) != 9 {
+		t.Fatalf("unexpected graph: %+v", graph)
+	}
+	if !graph.Jobs[0].Entry || !graph.Jobs[1].FanOut {
+		t.Errorf("expected fetch to be an entry and score to fan out: %+v", graph.Jobs[:2])
+	}
+	if undefined := graph.Jobs[8]; undefined.ID != "fetchh" || !undefined.Undefined {
+		t.Errorf("expected the misspelled dependency as an undefined node, got %+v", undefined)
+	}
+
+	expected := []string{"orphan", "loop_a", "loop_b", "fetchh"}
+	if got := graph.UnreachableJobs(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected unreachable jobs %v, got %v", expected, got)
+	}
+
+	labels := make(map[string]string)
+	for _, edge := range graph.Edges {
+		labels[edge.From+"->"+edge.To] = edge.Label
+	}
+	for edge, label := range map[string]string{
+		GraphStartNode + "->fetch":   "immediate",
+		"fetch->score":               "after",
+		"score->publish":             "all-of",
+		GraphStateNode + "->publish": "all-of/when-state",
+		"publish->notify":            "any-of",
+		"score->notify":              "any-of/on-failure",
+		"publish->cleanup":           "after-completion",
+	} {
+		if labels[edge] != label {
+			t.Errorf("expected edge %s labelled %q, got %q", edge, label, labels[edge])
+		}
+	}
+}
+
+func TestWorkflowGraphExports(t *testing.T) {
+	graph := newGraphTestWorkflow().Describe()
+	assertGolden(t, "review_pipeline.dot", graph.DOT())
+	assertGolden(t, "review_pipeline.mmd", graph.Mermaid())
+
+	// 多次导出结果相同
+	if again := newGraphTestWorkflow().Describe(); again.DOT() != graph.DOT() || again.Mermaid() != graph.Mermaid() {
+		t.Error("expected deterministic exports")
+	}
+}
+
+func TestExecutionResultTimeline(t *testing.T) {
+	origin := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return origin.Add(time.Duration(ms) * time.Millisecond) }
+	trace := func(id string, batch, start, end, attempts int, err error) JobExecution {
+		return JobExecution{
+			JobID: id, BatchID: batch, StartTime: at(start), EndTime: at(end),
+			Duration: at(end).Sub(at(start)), Attempts: attempts, Error: err,
+		}
+	}
+
+	// 同一批次内按完成顺序记录，时间线按作业ID排序
+	result := &ExecutionResult{
+		JobTrace: []JobExecution{
+			trace("fetch", 1, 0, 100, 1, nil),
+			trace("summarize", 2, 100, 250, 1, nil),
+			trace("analyze", 2, 100, 300, 3, errors.New("timeout")),
+			trace("report", 3, 300, 400, 1, nil),
+		},
+		SkippedJobs: []string{"publish", "notify"},
+	}
+	result.JobTrace[0].FanOut = 5
+
+	timeline := result.Timeline()
+	if timeline.Span != 400*time.Millisecond || timeline.Entries[1].JobID != "analyze" {
+		t.Fatalf("unexpected timeline: %+v", timeline)
+	}
+	assertGolden(t, "timeline.mmd", timeline.Mermaid())
+	assertGolden(t, "timeline.txt", timeline.String())
+
+	empty := (&ExecutionResult{}).Timeline()
+	if len(empty.Entries) != 0 || empty.String() != "" {
+		t.Errorf("expected an empty timeline, got %+v", empty)
+	}
+}
@@ -0,0 +1,169 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ============================================================================
+// MapJob - 对运行时生成的列表逐项扇出执行
+// ============================================================================
+
+// DefaultMapConcurrency MapJob默认的最大并发数
+const DefaultMapConcurrency = 4
+
+// FanOutJob 会扇出执行子作业的作业，引擎在指标中记录扇出宽度
+type FanOutJob interface {
+	Job
+	FanOutWidth() int
+}
+
+// MapJobOption MapJob配置选项
+type MapJobOption func(*MapJob)
+
+// WithMapConcurrency 设置同时执行的子作业数量上限
+func WithMapConcurrency(n int) MapJobOption {
+	return func(m *MapJob) {
+		if n > 0 {
+			m.concurrency = n
+		}
+	}
+}
+
+// WithResultKey 设置保存结果列表的状态键，默认为"<id>.results"
+func WithResultKey(key string) MapJobOption {
+	return func(m *MapJob) { m.resultKey = key }
+}
+
+// MapJob 从FlowState读取列表，为每一项创建子作业并以有限并发执行，按输入顺序把结果列表写回状态
+// 子作业按自身的执行策略重试；以ContinueOnError策略失败的子作业在结果中记为*JobFailure，
+// 其他失败会取消尚未完成的子作业并使MapJob失败
+type MapJob struct {
+	id          string
+	itemsKey    string
+	resultKey   string
+	makeJob     func(index int, item interface{}) Job
+	concurrency int
+	width       int64
+}
+
+var _ StatefulJob = (*MapJob)(nil)
+var _ FanOutJob = (*MapJob)(nil)
+
+// NewMapJob 创建扇出作业
+func NewMapJob(id, itemsKey string, makeJob func(index int, item interface{}) Job, opts ...MapJobOption) *MapJob {
+	m := &MapJob{
+		id:          id,
+		itemsKey:    itemsKey,
+		resultKey:   id + ".results",
+		makeJob:     makeJob,
+		concurrency: DefaultMapConcurrency,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *MapJob) ID() string { return m.id }
+
+// ResultKey 返回保存结果列表的状态键
+func (m *MapJob) ResultKey() string { return m.resultKey }
+
+// FanOutWidth 返回最近一次执行的子作业数量
+func (m *MapJob) FanOutWidth() int { return int(atomic.LoadInt64(&m.width)) }
+
+// Execute 没有工作流状态时无法读取列表
+func (m *MapJob) Execute(ctx context.Context) (interface{}, error) {
+	return nil, fmt.Errorf("map job %s requires flow state to read %q", m.id, m.itemsKey)
+}
+
+// ExecuteWithState 对列表中的每一项执行子作业，返回按输入顺序排列的结果列表
+func (m *MapJob) ExecuteWithState(ctx context.Context, state FlowState) (interface{}, error) {
+	items, err := m.readItems(state)
+	if err != nil {
+		return nil, err
+	}
+	atomic.StoreInt64(&m.width, int64(len(items)))
+
+	results := make([]interface{}, len(items))
+	if len(items) == 0 {
+		state.Set(m.resultKey, results)
+		return results, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	semaphore := make(chan struct{}, m.concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i, item := range items {
+		job := m.makeJob(i, item)
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(index int, job Job) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			result, err, attempts := runJobWithPolicy(ctx, job, state)
+			if err == nil {
+				results[index] = result
+				return
+			}
+			if jobPolicyOf(job).ErrorPolicy == ContinueOnError {
+				results[index] = &JobFailure{JobID: job.ID(), Attempts: attempts, Err: err}
+				return
+			}
+			once.Do(func() {
+				firstErr = fmt.Errorf("map job %s: item %d (%s) failed: %w", m.id, index, job.ID(), err)
+				cancel()
+			})
+		}(i, job)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("map job %s cancelled: %w", m.id, err)
+	}
+
+	state.Set(m.resultKey, results)
+	return results, nil
+}
+
+// readItems 从状态读取列表，支持任意切片类型
+func (m *MapJob) readItems(state FlowState) ([]interface{}, error) {
+	value, exists := state.Get(m.itemsKey)
+	if !exists {
+		return nil, fmt.Errorf("map job %s: %w: %s", m.id, ErrStateKeyNotFound, m.itemsKey)
+	}
+	if items, ok := value.([]interface{}); ok {
+		return items, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("map job %s: state value %s is %T, not a slice", m.id, m.itemsKey, value)
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
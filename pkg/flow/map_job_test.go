@@ -0,0 +1,157 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fetchJob 模拟抓取URL的子作业，记录最大并发数
+func fetchJob(active, maxActive *int32) func(index int, item interface{}) Job {
+	return func(index int, item interface{}) Job {
+		return NewJob(fmt.Sprintf("fetch-%d", index), func(ctx context.Context) (interface{}, error) {
+			current := atomic.AddInt32(active, 1)
+			defer atomic.AddInt32(active, -1)
+			for {
+				peak := atomic.LoadInt32(maxActive)
+				if current <= peak || atomic.CompareAndSwapInt32(maxActive, peak, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return strings.ToUpper(item.(string)), nil
+		})
+	}
+}
+
+func TestMapJobFanOut(t *testing.T) {
+	var active, maxActive int32
+
+	discover := NewStatefulJob("discover", func(ctx context.Context, state FlowState) (interface{}, error) {
+		urls := make([]string, 10)
+		for i := range urls {
+			urls[i] = fmt.Sprintf("url-%d", i)
+		}
+		state.Set("urls", urls)
+		return len(urls), nil
+	})
+	fetch := NewMapJob("fetch", "urls", fetchJob(&active, &maxActive), WithMapConcurrency(3))
+
+	result, err := NewWorkflow("fan-out").
+		AddJob(discover, Immediately()).
+		AddJob(fetch, After("discover")).
+		Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	results, ok := result.FinalState.GetSlice(fetch.ResultKey())
+	if !ok || len(results) != 10 {
+		t.Fatalf("Expected 10 results in state, got: %v", results)
+	}
+	for i, r := range results {
+		if r != fmt.Sprintf("URL-%d", i) {
+			t.Errorf("Expected results in input order, got %v at %d", r, i)
+		}
+	}
+	if maxActive > 3 {
+		t.Errorf("Expected at most 3 concurrent fetches, got: %d", maxActive)
+	}
+	if result.Metrics.FanOut["fetch"] != 10 {
+		t.Errorf("Expected fan-out width 10, got: %v", result.Metrics.FanOut)
+	}
+}
+
+func TestMapJobEmptyInput(t *testing.T) {
+	state := NewFlowStateWithData(map[string]interface{}{"urls": []interface{}{}})
+	job := NewMapJob("fetch", "urls", func(index int, item interface{}) Job {
+		t.Fatal("No job should be created for an empty list")
+		return nil
+	}, WithResultKey("pages"))
+
+	done := make(chan struct{})
+	var result interface{}
+	var err error
+	go func() {
+		result, err = job.ExecuteWithState(context.Background(), state)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected empty input to complete immediately")
+	}
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if results, ok := result.([]interface{}); !ok || len(results) != 0 {
+		t.Errorf("Expected empty result slice, got: %v", result)
+	}
+	if pages, ok := state.GetSlice("pages"); !ok || len(pages) != 0 {
+		t.Errorf("Expected empty results under the result key, got: %v", pages)
+	}
+}
+
+func TestMapJobPartialFailures(t *testing.T) {
+	var attempts int32
+	makeJob := func(stopOnError bool) func(index int, item interface{}) Job {
+		return func(index int, item interface{}) Job {
+			job := NewJob(fmt.Sprintf("item-%d", index), func(ctx context.Context) (interface{}, error) {
+				switch item {
+				case "flaky":
+					if atomic.AddInt32(&attempts, 1) == 1 {
+						return nil, errors.New("temporary")
+					}
+				case "broken":
+					return nil, errors.New("permanent")
+				}
+				return item, nil
+			}).WithRetry(1, time.Millisecond)
+			if stopOnError {
+				return job
+			}
+			return job.WithErrorPolicy(ContinueOnError)
+		}
+	}
+	items := []interface{}{"ok", "flaky", "broken"}
+
+	state := NewFlowStateWithData(map[string]interface{}{"items": items})
+	result, err := NewMapJob("tolerant", "items", makeJob(false)).ExecuteWithState(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Expected ContinueOnError items not to fail the map job, got: %v", err)
+	}
+	results := result.([]interface{})
+	if results[0] != "ok" || results[1] != "flaky" {
+		t.Errorf("Expected successful and retried results, got: %v", results)
+	}
+	if failure, ok := results[2].(*JobFailure); !ok || failure.Attempts != 2 {
+		t.Errorf("Expected failure record for the broken item, got: %v", results[2])
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	state = NewFlowStateWithData(map[string]interface{}{"items": items})
+	_, err = NewMapJob("strict", "items", makeJob(true)).ExecuteWithState(context.Background(), state)
+	if err == nil || !strings.Contains(err.Error(), "item-2") {
+		t.Fatalf("Expected the broken item to fail the map job, got: %v", err)
+	}
+	if _, stored := state.Get("strict.results"); stored {
+		t.Error("Expected no results to be stored when the map job fails")
+	}
+}
+
+func TestMapJobInvalidInput(t *testing.T) {
+	job := NewMapJob("fetch", "urls", func(index int, item interface{}) Job { return nil })
+
+	if _, err := job.ExecuteWithState(context.Background(), NewFlowState()); !errors.Is(err, ErrStateKeyNotFound) {
+		t.Errorf("Expected missing items error, got: %v", err)
+	}
+	state := NewFlowStateWithData(map[string]interface{}{"urls": "not a list"})
+	if _, err := job.ExecuteWithState(context.Background(), state); err == nil {
+		t.Error("Expected error for non-slice items")
+	}
+}
@@ -0,0 +1,162 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// 作业执行策略 - 重试、超时和错误处理
+// ============================================================================
+
+// ErrorPolicy 作业失败时的处理策略
+type ErrorPolicy int
+
+const (
+	// StopOnError 作业失败时终止整个工作流（默认）
+	StopOnError ErrorPolicy = iota
+	// ContinueOnError 记录作业错误，批次中的其他作业和后续触发继续执行
+	ContinueOnError
+)
+
+func (p ErrorPolicy) String() string {
+	switch p {
+	case ContinueOnError:
+		return "continue-on-error"
+	default:
+		return "stop-on-error"
+	}
+}
+
+// JobPolicy 作业执行策略
+type JobPolicy struct {
+	MaxRetries  int           // 失败后的最大重试次数
+	Backoff     time.Duration // 首次重试前的等待时间，之后每次翻倍
+	Timeout     time.Duration // 每次执行的超时时间，0表示不限制
+	ErrorPolicy ErrorPolicy   // 重试耗尽后的处理策略
+}
+
+// PolicyJob 带执行策略的作业，引擎按策略执行此类作业
+type PolicyJob interface {
+	Job
+	Policy() JobPolicy
+}
+
+// JobFailure 以ContinueOnError策略失败的作业在JobResults中的结果
+// After触发器不会因失败的作业就绪，AfterCompletion触发器可以通过JobResults.Failure检查失败原因
+type JobFailure struct {
+	JobID    string
+	Attempts int
+	Err      error
+}
+
+func (f *JobFailure) Error() string {
+	return fmt.Sprintf("job %s failed after %d attempts: %v", f.JobID, f.Attempts, f.Err)
+}
+
+func (f *JobFailure) Unwrap() error { return f.Err }
+
+// Failure 返回作业的失败记录，作业未完成或成功时返回false
+func (r JobResults) Failure(jobID string) (*JobFailure, bool) {
+	failure, ok := r[jobID].(*JobFailure)
+	return failure, ok
+}
+
+// Succeeded 作业是否已成功完成
+func (r JobResults) Succeeded(jobID string) bool {
+	result, exists := r[jobID]
+	if !exists {
+		return false
+	}
+	_, failed := result.(*JobFailure)
+	return !failed
+}
+
+// WithRetry 设置失败后的重试次数和首次重试前的等待时间
+func (j SimpleJob) WithRetry(maxRetries int, backoff time.Duration) SimpleJob {
+	j.policy.MaxRetries = maxRetries
+	j.policy.Backoff = backoff
+	return j
+}
+
+// WithTimeout 设置每次执行的超时时间
+func (j SimpleJob) WithTimeout(timeout time.Duration) SimpleJob {
+	j.policy.Timeout = timeout
+	return j
+}
+
+// WithErrorPolicy 设置重试耗尽后的处理策略
+func (j SimpleJob) WithErrorPolicy(policy ErrorPolicy) SimpleJob {
+	j.policy.ErrorPolicy = policy
+	return j
+}
+
+// Policy 返回作业的执行策略
+func (j SimpleJob) Policy() JobPolicy { return j.policy }
+
+// policyJobWrapper 为任意作业附加执行策略
+type policyJobWrapper struct {
+	job    Job
+	policy JobPolicy
+}
+
+func (p policyJobWrapper) ID() string        { return p.job.ID() }
+func (p policyJobWrapper) Policy() JobPolicy { return p.policy }
+
+func (p policyJobWrapper) Execute(ctx context.Context) (interface{}, error) {
+	return p.job.Execute(ctx)
+}
+
+func (p policyJobWrapper) ExecuteWithState(ctx context.Context, state FlowState) (interface{}, error) {
+	return WrapJob(p.job).ExecuteWithState(ctx, state)
+}
+
+// WithPolicy 为作业附加执行策略，适用于有状态作业和作业组等任意作业
+func WithPolicy(job Job, policy JobPolicy) Job {
+	return policyJobWrapper{job: job, policy: policy}
+}
+
+// jobPolicyOf 返回作业的执行策略，未设置时为默认策略（不重试、不超时、失败即终止）
+func jobPolicyOf(job Job) JobPolicy {
+	if policyJob, ok := job.(PolicyJob); ok {
+		return policyJob.Policy()
+	}
+	return JobPolicy{}
+}
+
+// runJobWithPolicy 按策略执行作业，返回结果、错误和执行次数
+func runJobWithPolicy(ctx context.Context, job Job, state FlowState) (interface{}, error, int) {
+	policy := jobPolicyOf(job)
+
+	attempt := 0
+	for {
+		attempt++
+		result, err := executeJobOnce(ctx, job, state, policy.Timeout)
+		if err == nil || attempt > policy.MaxRetries || ctx.Err() != nil {
+			return result, err, attempt
+		}
+
+		delay := policy.Backoff << (attempt - 1)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, err, attempt
+		}
+	}
+}
+
+// executeJobOnce 执行一次作业，设置了超时时使用派生的上下文
+func executeJobOnce(ctx context.Context, job Job, state FlowState, timeout time.Duration) (interface{}, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// 优先使用支持状态传递的接口
+	if statefulJob, ok := job.(StatefulJob); ok {
+		return statefulJob.ExecuteWithState(ctx, state)
+	}
+	return job.Execute(ctx)
+}
@@ -0,0 +1,226 @@
+package flow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// 执行计划 - cron表达式和固定间隔
+// ============================================================================
+
+// Schedule 工作流的执行计划
+type Schedule interface {
+	// Next 返回晚于t的下一次执行时间，没有下一次时返回零值
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// scheduleDescriptors 预定义的cron描述符
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames   = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	weekdayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// ParseSchedule 解析执行计划
+// 支持5个字段的cron表达式（分 时 日 月 周，字段可使用*、列表、范围、步长以及jan、mon等名称），
+// @yearly、@monthly、@weekly、@daily、@hourly等描述符，以及"@every 30m"形式的固定间隔。
+// cron表达式按传给Next的时间所在的时区计算
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be at least 1s", spec)
+		}
+		return Every(interval), nil
+	}
+
+	expr := spec
+	if descriptor, ok := scheduleDescriptors[strings.ToLower(spec)]; ok {
+		expr = descriptor
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("invalid schedule %q: unknown descriptor", spec)
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(fields))
+	}
+
+	schedule := &cronSchedule{spec: spec}
+	var err error
+	if schedule.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", spec, err)
+	}
+	if schedule.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", spec, err)
+	}
+	if schedule.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %w", spec, err)
+	}
+	if schedule.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", spec, err)
+	}
+	// 周日可以写作0或7
+	if schedule.dow, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %w", spec, err)
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.domAny = fields[2] == "*" || fields[2] == "?"
+	schedule.dowAny = fields[4] == "*" || fields[4] == "?"
+	return schedule, nil
+}
+
+// MustParseSchedule 与ParseSchedule相同，解析失败时panic，用于固定的执行计划
+func MustParseSchedule(spec string) Schedule {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// Every 返回固定间隔的执行计划，执行时间对齐到间隔的整数倍（如每小时的整点）
+func Every(interval time.Duration) Schedule {
+	return intervalSchedule{interval: interval}
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	if s.interval <= 0 {
+		return time.Time{}
+	}
+	return t.Truncate(s.interval).Add(s.interval)
+}
+
+func (s intervalSchedule) String() string { return "@every " + s.interval.String() }
+
+// cronSchedule 解析后的cron表达式，每个字段为允许取值的位集合
+type cronSchedule struct {
+	spec                          string
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+func (s *cronSchedule) String() string { return s.spec }
+
+// Next 逐级跳过不匹配的月、日、时、分，最多向后查找5年
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.Year() + 5
+
+	for t.Year() <= limit {
+		if !hasBit(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !hasBit(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !hasBit(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches 日和周都有限制时满足其一即可，与标准cron一致
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := hasBit(s.dom, t.Day())
+	dowMatch := hasBit(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func hasBit(bits uint64, n int) bool {
+	return bits&(1<<uint(n)) != 0
+}
+
+// parseCronField 解析一个cron字段，返回允许取值的位集合
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			low, high = min, max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			low, high = value, value
+			if step > 1 {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return n, nil
+}
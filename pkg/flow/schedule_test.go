@@ -0,0 +1,90 @@
+package flow
+
+import (
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// 执行计划测试
+// ============================================================================
+
+func TestParseScheduleCron(t *testing.T) {
+	base := time.Date(2026, 3, 14, 10, 30, 45, 0, time.UTC) // 周六
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 14, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 14, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2026, 3, 15, 9, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2026, 3, 14, 13, 0, 0, 0, time.UTC)},
+		{"30 8 * * mon-fri", time.Date(2026, 3, 16, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan,jul *", time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 31 * *", time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// 日和周都有限制时满足其一即可
+		{"0 0 20 * mon", time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		schedule, err := ParseSchedule(tc.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", tc.spec, err)
+			continue
+		}
+		if got := schedule.Next(base); !got.Equal(tc.want) {
+			t.Errorf("%q: expected next run %v, got %v", tc.spec, tc.want, got)
+		}
+		if schedule.String() != tc.spec {
+			t.Errorf("expected String() %q, got %q", tc.spec, schedule.String())
+		}
+	}
+}
+
+func TestParseScheduleEvery(t *testing.T) {
+	schedule, err := ParseSchedule("@every 15m")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+	base := time.Date(2026, 3, 14, 10, 31, 0, 0, time.UTC)
+	next := schedule.Next(base)
+	if want := time.Date(2026, 3, 14, 10, 45, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("expected %v, got %v", want, next)
+	}
+	if next = schedule.Next(next); !next.Equal(time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected 11:00, got %v", next)
+	}
+	if schedule.String() != "@every 15m0s" {
+		t.Errorf("unexpected String(): %s", schedule.String())
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@often",
+		"@every 10ms",
+		"@every soon",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
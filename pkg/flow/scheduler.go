@@ -0,0 +1,611 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+// ============================================================================
+// 定时执行 - 按执行计划重复运行工作流
+// ============================================================================
+
+// OverlapPolicy 上一次执行尚未结束时到达的执行如何处理
+type OverlapPolicy int
+
+const (
+	// OverlapSkip 跳过本次执行（默认）
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue 排队，上一次执行结束后依次执行
+	OverlapQueue
+)
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapQueue:
+		return "queue"
+	default:
+		return "skip"
+	}
+}
+
+// ParseOverlapPolicy 解析重叠策略名称，空字符串表示默认的skip
+func ParseOverlapPolicy(name string) (OverlapPolicy, error) {
+	switch strings.ToLower(name) {
+	case "", "skip":
+		return OverlapSkip, nil
+	case "queue":
+		return OverlapQueue, nil
+	default:
+		return OverlapSkip, fmt.Errorf("invalid overlap policy %q: use skip or queue", name)
+	}
+}
+
+// 每次定时执行写入FlowState的元数据
+const (
+	ScheduleStateName          = "schedule_name"
+	ScheduleStateScheduledTime = "scheduled_time" // RFC3339格式的计划执行时间
+	ScheduleStateRunSequence   = "run_sequence"   // 该计划的第几次执行，从1开始
+	ScheduleStateCatchUp       = "catch_up"       // 是否为补跑的错过的执行
+)
+
+// ScheduleOption 执行计划的配置选项
+type ScheduleOption func(*scheduledWorkflow)
+
+// WithStateTemplate 设置每次执行的初始状态，每次执行使用副本，并加入计划时间和执行序号等元数据
+func WithStateTemplate(template map[string]interface{}) ScheduleOption {
+	return func(w *scheduledWorkflow) { w.template = template }
+}
+
+// WithOverlapPolicy 设置上一次执行尚未结束时的处理策略
+func WithOverlapPolicy(policy OverlapPolicy) ScheduleOption {
+	return func(w *scheduledWorkflow) { w.overlap = policy }
+}
+
+// WithCatchUp 启动时补跑进程停止期间错过的执行，最多补跑最近的max次；需要ScheduleStore记录上次执行的时间
+func WithCatchUp(max int) ScheduleOption {
+	return func(w *scheduledWorkflow) { w.catchUp = max }
+}
+
+// SchedulerOption 调度器配置选项
+type SchedulerOption func(*Scheduler)
+
+// WithRunSink 添加接收执行记录的RunSink，未设置时使用日志记录
+func WithRunSink(sink RunSink) SchedulerOption {
+	return func(s *Scheduler) { s.sinks = append(s.sinks, sink) }
+}
+
+// WithScheduleStore 设置保存每个计划上次执行时间和执行序号的存储，用于补跑和延续执行序号
+func WithScheduleStore(store ScheduleStore) SchedulerOption {
+	return func(s *Scheduler) { s.store = store }
+}
+
+// WithSchedulerLogger 设置调度器日志记录器
+func WithSchedulerLogger(log logger.Logger) SchedulerOption {
+	return func(s *Scheduler) { s.logger = log }
+}
+
+// clock 调度器使用的时钟，测试中替换为可控的时钟
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Scheduler 按执行计划重复运行工作流
+// 每次执行使用新的FlowState，以计划的状态模板和执行元数据为初始内容；同一计划的执行不会重叠，
+// 按OverlapPolicy跳过或排队。执行结果写入RunSink。Stop停止调度并等待正在进行的执行完成
+type Scheduler struct {
+	entries []*scheduledWorkflow
+	sinks   []RunSink
+	store   ScheduleStore
+	logger  logger.Logger
+	clock   clock
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	stop    chan struct{}
+	loops   sync.WaitGroup
+	runs    sync.WaitGroup
+}
+
+// scheduledWorkflow 一个执行计划及其运行状态
+type scheduledWorkflow struct {
+	name     string
+	workflow Workflow
+	schedule Schedule
+	template map[string]interface{}
+	overlap  OverlapPolicy
+	catchUp  int
+
+	// 以下字段由Scheduler.mu保护
+	running  bool
+	queue    []occurrence
+	sequence int
+}
+
+// occurrence 一次计划的执行
+type occurrence struct {
+	scheduled time.Time
+	catchUp   bool
+}
+
+// NewScheduler 创建调度器
+func NewScheduler(opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		logger: logger.NewConsoleLogger(),
+		clock:  realClock{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.sinks) == 0 {
+		s.sinks = []RunSink{NewLogRunSink(s.logger)}
+	}
+	return s
+}
+
+// Add 添加执行计划，name在调度器中唯一，用于执行记录和ScheduleStore。必须在Start之前调用
+func (s *Scheduler) Add(name string, workflow Workflow, schedule Schedule, opts ...ScheduleOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.started:
+		return fmt.Errorf("cannot add schedule %s: scheduler already started", name)
+	case name == "":
+		return fmt.Errorf("schedule name is required")
+	case workflow == nil:
+		return fmt.Errorf("schedule %s has no workflow", name)
+	case schedule == nil:
+		return fmt.Errorf("schedule %s has no schedule", name)
+	}
+	for _, entry := range s.entries {
+		if entry.name == name {
+			return fmt.Errorf("duplicate schedule name: %s", name)
+		}
+	}
+
+	entry := &scheduledWorkflow{name: name, workflow: workflow, schedule: schedule}
+	for _, opt := range opts {
+		opt(entry)
+	}
+	if entry.catchUp > 0 && s.store == nil {
+		s.logger.Warn("schedule catch-up needs a schedule store, missed runs will not be caught up",
+			logger.Field{Key: "schedule", Value: name})
+	}
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+// Start 开始调度，立即返回。ctx取消时停止调度并取消正在进行的执行；
+// 启用补跑时，进程停止期间错过的执行在启动后依次执行
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("scheduler already started")
+	}
+	if len(s.entries) == 0 {
+		return fmt.Errorf("scheduler has no schedules")
+	}
+
+	// 先加载所有计划的记录，加载失败时不启动任何计划
+	lastScheduled := make([]time.Time, len(s.entries))
+	if s.store != nil {
+		for i, entry := range s.entries {
+			record, err := s.store.Load(ctx, entry.name)
+			if err != nil {
+				return fmt.Errorf("failed to load schedule %s: %w", entry.name, err)
+			}
+			if record != nil {
+				lastScheduled[i] = record.LastScheduled
+				entry.sequence = record.Sequence
+			}
+		}
+	}
+
+	s.started = true
+	s.stop = make(chan struct{})
+	for i, entry := range s.entries {
+		s.loops.Add(1)
+		go s.loop(ctx, entry, lastScheduled[i])
+	}
+	return nil
+}
+
+// Stop 停止调度并等待正在进行的执行完成，排队等待的执行被丢弃
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.started || s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	s.loops.Wait()
+
+	s.mu.Lock()
+	for _, entry := range s.entries {
+		if len(entry.queue) > 0 {
+			s.logger.Warn("dropping queued scheduled runs on stop",
+				logger.Field{Key: "schedule", Value: entry.name},
+				logger.Field{Key: "count", Value: len(entry.queue)})
+			entry.queue = nil
+		}
+	}
+	s.mu.Unlock()
+
+	s.runs.Wait()
+}
+
+// loop 补跑错过的执行，然后在每个计划时间触发执行
+func (s *Scheduler) loop(ctx context.Context, entry *scheduledWorkflow, lastScheduled time.Time) {
+	defer s.loops.Done()
+
+	now := s.clock.Now()
+	if entry.catchUp > 0 && !lastScheduled.IsZero() {
+		s.catchUp(ctx, entry, lastScheduled, now)
+	}
+
+	next := entry.schedule.Next(now)
+	for !next.IsZero() {
+		select {
+		case <-s.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(next.Sub(s.clock.Now())):
+		}
+
+		s.dispatch(ctx, entry, occurrence{scheduled: next})
+
+		// 进程挂起等原因落后于计划时，跳过已经过去的执行时间
+		now := s.clock.Now()
+		if next = entry.schedule.Next(next); !next.IsZero() && !next.After(now) {
+			next = entry.schedule.Next(now)
+		}
+	}
+	s.logger.Info("schedule has no further runs", logger.Field{Key: "schedule", Value: entry.name})
+}
+
+// catchUp 依次执行上次记录之后、now之前错过的执行，最多最近的entry.catchUp次
+func (s *Scheduler) catchUp(ctx context.Context, entry *scheduledWorkflow, lastScheduled, now time.Time) {
+	var missed []time.Time
+	total := 0
+	for t := entry.schedule.Next(lastScheduled); !t.IsZero() && !t.After(now); t = entry.schedule.Next(t) {
+		total++
+		missed = append(missed, t)
+		if len(missed) > entry.catchUp {
+			missed = missed[1:]
+		}
+	}
+	if total == 0 {
+		return
+	}
+
+	s.logger.Info("catching up missed scheduled runs",
+		logger.Field{Key: "schedule", Value: entry.name},
+		logger.Field{Key: "missed", Value: total},
+		logger.Field{Key: "catch_up", Value: len(missed)})
+	for _, t := range missed {
+		s.dispatch(ctx, entry, occurrence{scheduled: t, catchUp: true})
+	}
+}
+
+// dispatch 开始一次执行；同一计划正在执行时按重叠策略跳过或排队，补跑的执行总是排队
+func (s *Scheduler) dispatch(ctx context.Context, entry *scheduledWorkflow, occ occurrence) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	if !entry.running {
+		s.startLocked(ctx, entry, occ)
+		s.mu.Unlock()
+		return
+	}
+	if entry.overlap == OverlapQueue || occ.catchUp {
+		entry.queue = append(entry.queue, occ)
+		s.mu.Unlock()
+		return
+	}
+	sequence := entry.sequence
+	s.mu.Unlock()
+
+	s.logger.Warn("skipping scheduled run, previous run still in progress",
+		logger.Field{Key: "schedule", Value: entry.name},
+		logger.Field{Key: "scheduled_time", Value: occ.scheduled})
+	s.saveRecord(ctx, entry.name, occ.scheduled, sequence)
+	s.emit(ctx, &RunRecord{
+		Schedule:      entry.name,
+		ScheduledTime: occ.scheduled,
+		Status:        RunSkipped,
+		Error:         "previous run still in progress",
+	})
+}
+
+// startLocked 在新的goroutine中执行，调用方必须持有s.mu
+func (s *Scheduler) startLocked(ctx context.Context, entry *scheduledWorkflow, occ occurrence) {
+	entry.running = true
+	entry.sequence++
+	s.runs.Add(1)
+	go s.run(ctx, entry, occ, entry.sequence)
+}
+
+// run 以新的FlowState执行一次工作流并记录结果，之后开始排队的下一次执行
+func (s *Scheduler) run(ctx context.Context, entry *scheduledWorkflow, occ occurrence, sequence int) {
+	defer s.runs.Done()
+	s.saveRecord(ctx, entry.name, occ.scheduled, sequence)
+
+	state := make(map[string]interface{}, len(entry.template)+4)
+	for k, v := range entry.template {
+		state[k] = v
+	}
+	state[ScheduleStateName] = entry.name
+	state[ScheduleStateScheduledTime] = occ.scheduled.Format(time.RFC3339)
+	state[ScheduleStateRunSequence] = sequence
+	state[ScheduleStateCatchUp] = occ.catchUp
+
+	record := &RunRecord{
+		Schedule:      entry.name,
+		Sequence:      sequence,
+		ScheduledTime: occ.scheduled,
+		StartTime:     s.clock.Now(),
+		CatchUp:       occ.catchUp,
+	}
+	result, err := entry.workflow.Run(WithInitialState(ctx, state))
+	record.EndTime = s.clock.Now()
+	record.Duration = record.EndTime.Sub(record.StartTime)
+	record.Result = result
+	record.Status = RunSucceeded
+	if result != nil {
+		record.SucceededJobs = result.SucceededJobs
+		for id, jobErr := range result.FailedJobs {
+			if record.FailedJobs == nil {
+				record.FailedJobs = make(map[string]string)
+			}
+			record.FailedJobs[id] = jobErr.Error()
+		}
+	}
+	if err != nil {
+		record.Status = RunFailed
+		record.Error = err.Error()
+	}
+	s.emit(ctx, record)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.running = false
+	if !s.stopped && len(entry.queue) > 0 {
+		next := entry.queue[0]
+		entry.queue = entry.queue[1:]
+		s.startLocked(ctx, entry, next)
+	}
+}
+
+// saveRecord 记录计划最后处理的执行时间和执行序号，保存失败只记录警告
+func (s *Scheduler) saveRecord(ctx context.Context, name string, scheduled time.Time, sequence int) {
+	if s.store == nil {
+		return
+	}
+	record := &ScheduleRecord{Schedule: name, LastScheduled: scheduled, Sequence: sequence, UpdatedAt: time.Now()}
+	if err := s.store.Save(ctx, record); err != nil {
+		s.logger.Warn("failed to save schedule record",
+			logger.Field{Key: "schedule", Value: name},
+			logger.Field{Key: "error", Value: err})
+	}
+}
+
+// emit 把执行记录写入所有RunSink，写入失败只记录警告
+func (s *Scheduler) emit(ctx context.Context, record *RunRecord) {
+	for _, sink := range s.sinks {
+		if err := sink.Record(ctx, record); err != nil {
+			s.logger.Warn("failed to record scheduled run",
+				logger.Field{Key: "schedule", Value: record.Schedule},
+				logger.Field{Key: "error", Value: err})
+		}
+	}
+}
+
+// ============================================================================
+// 执行记录
+// ============================================================================
+
+// RunStatus 定时执行的结果
+type RunStatus string
+
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunSkipped   RunStatus = "skipped" // 上一次执行尚未结束，按OverlapSkip策略跳过
+)
+
+// RunRecord 一次定时执行的记录
+type RunRecord struct {
+	Schedule      string            `json:"schedule"`
+	Sequence      int               `json:"sequence,omitempty"` // 跳过的执行没有序号
+	ScheduledTime time.Time         `json:"scheduled_time"`
+	StartTime     time.Time         `json:"start_time"`
+	EndTime       time.Time         `json:"end_time"`
+	Duration      time.Duration     `json:"duration"`
+	Status        RunStatus         `json:"status"`
+	CatchUp       bool              `json:"catch_up,omitempty"`
+	SucceededJobs []string          `json:"succeeded_jobs,omitempty"`
+	FailedJobs    map[string]string `json:"failed_jobs,omitempty"`
+	Error         string            `json:"error,omitempty"`
+
+	Result *ExecutionResult `json:"-"` // 工作流的执行结果，跳过的执行为nil
+}
+
+// RunSink 接收定时执行的记录
+type RunSink interface {
+	Record(ctx context.Context, record *RunRecord) error
+}
+
+// LogRunSink 把执行记录写入日志
+type LogRunSink struct {
+	logger logger.Logger
+}
+
+// NewLogRunSink 创建日志RunSink
+func NewLogRunSink(log logger.Logger) *LogRunSink {
+	return &LogRunSink{logger: log}
+}
+
+func (s *LogRunSink) Record(ctx context.Context, record *RunRecord) error {
+	fields := []logger.Field{
+		{Key: "schedule", Value: record.Schedule},
+		{Key: "status", Value: string(record.Status)},
+		{Key: "scheduled_time", Value: record.ScheduledTime},
+	}
+	switch record.Status {
+	case RunFailed:
+		s.logger.Error("scheduled run failed", append(fields,
+			logger.Field{Key: "sequence", Value: record.Sequence},
+			logger.Field{Key: "error", Value: record.Error})...)
+	case RunSkipped:
+		s.logger.Warn("scheduled run skipped", fields...)
+	default:
+		s.logger.Info("scheduled run completed", append(fields,
+			logger.Field{Key: "sequence", Value: record.Sequence},
+			logger.Field{Key: "duration", Value: record.Duration})...)
+	}
+	return nil
+}
+
+// JSONLRunSink 把执行记录以每行一个JSON对象追加到文件
+type JSONLRunSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewJSONLRunSink 打开（必要时创建）JSONL文件，目录不存在时自动创建
+func NewJSONLRunSink(path string) (*JSONLRunSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create run log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run log: %w", err)
+	}
+	return &JSONLRunSink{file: file}, nil
+}
+
+func (s *JSONLRunSink) Record(ctx context.Context, record *RunRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write run record: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭文件
+func (s *JSONLRunSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ============================================================================
+// 计划记录存储
+// ============================================================================
+
+// ScheduleRecord 计划最后处理（执行或跳过）的执行时间和已执行的次数
+type ScheduleRecord struct {
+	Schedule      string    `json:"schedule"`
+	LastScheduled time.Time `json:"last_scheduled"`
+	Sequence      int       `json:"sequence"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ScheduleStore 保存计划记录，调度器据此补跑错过的执行并延续执行序号
+type ScheduleStore interface {
+	// Load 返回计划的记录，没有记录时返回nil
+	Load(ctx context.Context, schedule string) (*ScheduleRecord, error)
+	Save(ctx context.Context, record *ScheduleRecord) error
+}
+
+// FileScheduleStore 将计划记录以JSON文件保存在目录中，每个计划一个文件
+type FileScheduleStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+var _ ScheduleStore = (*FileScheduleStore)(nil)
+
+// NewFileScheduleStore 创建文件计划记录存储，目录不存在时自动创建
+func NewFileScheduleStore(dir string) (*FileScheduleStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create schedule directory: %w", err)
+	}
+	return &FileScheduleStore{dir: dir}, nil
+}
+
+// Path 返回计划记录文件的路径
+func (s *FileScheduleStore) Path(schedule string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(schedule)
+	return filepath.Join(s.dir, name+".schedule.json")
+}
+
+func (s *FileScheduleStore) Load(ctx context.Context, schedule string) (*ScheduleRecord, error) {
+	path := s.Path(schedule)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read schedule record %s: %w", path, err)
+	}
+
+	var record ScheduleRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule record %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// Save 保存记录，不会用更早的执行时间覆盖已有的记录
+func (s *FileScheduleStore) Save(ctx context.Context, record *ScheduleRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.Load(ctx, record.Schedule)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.LastScheduled.After(record.LastScheduled) {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule record: %w", err)
+	}
+	if err := writeFileAtomic(s.Path(record.Schedule), data); err != nil {
+		return fmt.Errorf("failed to save schedule record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,440 @@
+package flow
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
+)
+
+func TestParseOverlapPolicy(t *testing.T) {
+	for name, want := range map[string]OverlapPolicy{"": OverlapSkip, "skip": OverlapSkip, "Queue": OverlapQueue} {
+		got, err := ParseOverlapPolicy(name)
+		if err != nil || got != want {
+			t.Errorf("ParseOverlapPolicy(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseOverlapPolicy("parallel"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+// ============================================================================
+// 调度器测试
+// ============================================================================
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	added   chan struct{}
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, added: make(chan struct{}, 100)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+	} else {
+		c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	}
+	c.added <- struct{}{}
+	return ch
+}
+
+// Advance 推进时间并触发到期的等待
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	remaining := c.waiters[:0]
+	for _, w := range c.waiters {
+		if !w.at.After(c.now) {
+			w.ch <- c.now
+		} else {
+			remaining = append(remaining, w)
+		}
+	}
+	c.waiters = remaining
+}
+
+// waitForTimer 等待调度循环开始等待下一次执行
+func (c *fakeClock) waitForTimer(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.added:
+	case <-time.After(2 * time.Second):
+		t.Fatal("scheduler did not wait for the next run")
+	}
+}
+
+// memorySink 在内存中收集执行记录
+type memorySink struct {
+	mu      sync.Mutex
+	records []*RunRecord
+	notify  chan *RunRecord
+}
+
+func newMemorySink() *memorySink {
+	return &memorySink{notify: make(chan *RunRecord, 100)}
+}
+
+func (s *memorySink) Record(ctx context.Context, record *RunRecord) error {
+	s.mu.Lock()
+	s.records = append(s.records, record)
+	s.mu.Unlock()
+	s.notify <- record
+	return nil
+}
+
+func (s *memorySink) next(t *testing.T) *RunRecord {
+	t.Helper()
+	select {
+	case record := <-s.notify:
+		return record
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for run record")
+		return nil
+	}
+}
+
+func newTestScheduler(clock *fakeClock, opts ...SchedulerOption) *Scheduler {
+	s := NewScheduler(append([]SchedulerOption{WithSchedulerLogger(logger.NewTestLogger())}, opts...)...)
+	s.clock = clock
+	return s
+}
+
+func TestSchedulerSeedsState(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, 3, 14, 10, 0, 30, 0, time.UTC))
+	sink := newMemorySink()
+	scheduler := newTestScheduler(clock, WithRunSink(sink))
+
+	states := make(chan map[string]interface{}, 10)
+	workflow := NewWorkflow("report").AddJob(NewStatefulJob("collect", func(ctx context.Context, state FlowState) (interface{}, error) {
+		states <- state.GetAll()
+		state.Set("region", "changed")
+		return "done", nil
+	}), Immediately())
+
+	template := map[string]interface{}{"region": "eu"}
+	if err := scheduler.Add("report", workflow, MustParseSchedule("* * * * *"), WithStateTemplate(template)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer scheduler.Stop()
+
+	for i := 1; i <= 2; i++ {
+		clock.waitForTimer(t)
+		clock.Advance(time.Minute)
+
+		state := <-states
+		if state["region"] != "eu" {
+			t.Errorf("run %d: expected fresh state from template, got region %v", i, state["region"])
+		}
+		if state[ScheduleStateRunSequence] != i {
+			t.Errorf("expected run sequence %d, got %v", i, state[ScheduleStateRunSequence])
+		}
+		want := time.Date(2026, 3, 14, 10, i, 0, 0, time.UTC).Format(time.RFC3339)
+		if state[ScheduleStateScheduledTime] != want {
+			t.Errorf("expected scheduled time %s, got %v", want, state[ScheduleStateScheduledTime])
+		}
+		if state[ScheduleStateName] != "report" || state[ScheduleStateCatchUp] != false {
+			t.Errorf("unexpected metadata: %v", state)
+		}
+
+		record := sink.next(t)
+		if record.Status != RunSucceeded || record.Sequence != i || record.Result == nil {
+			t.Errorf("unexpected record: %+v", record)
+		}
+	}
+	if template["region"] != "eu" {
+		t.Error("template should not be modified by runs")
+	}
+}
+
+// blockingWorkflow 返回在release关闭前不会结束的工作流
+func blockingWorkflow(started chan<- int, release <-chan struct{}) Workflow {
+	return NewWorkflow("blocking").AddJob(NewStatefulJob("wait", func(ctx context.Context, state FlowState) (interface{}, error) {
+		seq, _ := state.Get(ScheduleStateRunSequence)
+		started <- seq.(int)
+		<-release
+		return nil, nil
+	}), Immediately())
+}
+
+func TestSchedulerOverlapSkip(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC))
+	sink := newMemorySink()
+	scheduler := newTestScheduler(clock, WithRunSink(sink))
+
+	started := make(chan int, 10)
+	release := make(chan struct{})
+	if err := scheduler.Add("slow", blockingWorkflow(started, release), Every(time.Minute)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	clock.waitForTimer(t)
+	clock.Advance(time.Minute)
+	if seq := <-started; seq != 1 {
+		t.Errorf("expected first run, got %d", seq)
+	}
+
+	clock.waitForTimer(t)
+	clock.Advance(time.Minute)
+	if record := sink.next(t); record.Status != RunSkipped {
+		t.Errorf("expected skipped run while first run is in progress, got %s", record.Status)
+	}
+
+	close(release)
+	if record := sink.next(t); record.Status != RunSucceeded || record.Sequence != 1 {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	clock.waitForTimer(t)
+	clock.Advance(time.Minute)
+	if seq := <-started; seq != 2 {
+		t.Errorf("expected next run to have sequence 2, got %d", seq)
+	}
+	scheduler.Stop()
+}
+
+func TestSchedulerOverlapQueue(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC))
+	sink := newMemorySink()
+	scheduler := newTestScheduler(clock, WithRunSink(sink))
+
+	started := make(chan int, 10)
+	release := make(chan struct{})
+	if err := scheduler.Add("slow", blockingWorkflow(started, release), Every(time.Minute), WithOverlapPolicy(OverlapQueue)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	clock.waitForTimer(t)
+	clock.Advance(time.Minute)
+	<-started
+
+	clock.waitForTimer(t)
+	clock.Advance(time.Minute)
+	clock.waitForTimer(t)
+
+	select {
+	case seq := <-started:
+		t.Fatalf("run %d started while previous run in progress", seq)
+	default:
+	}
+
+	close(release)
+	if seq := <-started; seq != 2 {
+		t.Errorf("expected queued run with sequence 2, got %d", seq)
+	}
+	first, second := sink.next(t), sink.next(t)
+	if first.Status != RunSucceeded || second.Status != RunSucceeded {
+		t.Errorf("expected both runs to succeed, got %s and %s", first.Status, second.Status)
+	}
+	if want := time.Date(2026, 3, 14, 10, 2, 0, 0, time.UTC); !second.ScheduledTime.Equal(want) {
+		t.Errorf("expected queued run scheduled at %v, got %v", want, second.ScheduledTime)
+	}
+	scheduler.Stop()
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileScheduleStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileScheduleStore failed: %v", err)
+	}
+	last := time.Date(2026, 3, 14, 4, 0, 0, 0, time.UTC)
+	if err := store.Save(context.Background(), &ScheduleRecord{Schedule: "hourly", LastScheduled: last, Sequence: 7}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// 04:00之后错过了05:00到10:00共6次执行，最多补跑最近的2次
+	clock := newFakeClock(time.Date(2026, 3, 14, 10, 30, 0, 0, time.UTC))
+	sink := newMemorySink()
+	scheduler := newTestScheduler(clock, WithRunSink(sink), WithScheduleStore(store))
+
+	workflow := NewWorkflow("hourly").AddJob(NewJob("noop", func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}), Immediately())
+	if err := scheduler.Add("hourly", workflow, MustParseSchedule("@hourly"), WithCatchUp(2)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	first, second := sink.next(t), sink.next(t)
+	if !first.CatchUp || !second.CatchUp {
+		t.Error("expected catch-up runs")
+	}
+	if !first.ScheduledTime.Equal(time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)) ||
+		!second.ScheduledTime.Equal(time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the two most recent missed runs, got %v and %v", first.ScheduledTime, second.ScheduledTime)
+	}
+	if first.Sequence != 8 || second.Sequence != 9 {
+		t.Errorf("expected sequence to continue from store, got %d and %d", first.Sequence, second.Sequence)
+	}
+	scheduler.Stop()
+
+	record, err := store.Load(context.Background(), "hourly")
+	if err != nil || record == nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !record.LastScheduled.Equal(second.ScheduledTime) || record.Sequence != 9 {
+		t.Errorf("unexpected stored record: %+v", record)
+	}
+
+	select {
+	case extra := <-sink.notify:
+		t.Errorf("unexpected extra run: %+v", extra)
+	default:
+	}
+}
+
+func TestSchedulerStopWaitsForInFlightRun(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC))
+	sink := newMemorySink()
+	scheduler := newTestScheduler(clock, WithRunSink(sink))
+
+	started := make(chan int, 1)
+	release := make(chan struct{})
+	if err := scheduler.Add("slow", blockingWorkflow(started, release), Every(time.Minute)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	clock.waitForTimer(t)
+	clock.Advance(time.Minute)
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		scheduler.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before the in-flight run completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return after the run completed")
+	}
+	if record := sink.next(t); record.Status != RunSucceeded {
+		t.Errorf("expected in-flight run to complete, got %s", record.Status)
+	}
+}
+
+func TestSchedulerAddValidation(t *testing.T) {
+	scheduler := newTestScheduler(newFakeClock(time.Now()))
+	workflow := NewWorkflow("wf")
+
+	if err := scheduler.Start(context.Background()); err == nil {
+		t.Error("expected error starting scheduler without schedules")
+	}
+	if err := scheduler.Add("", workflow, Every(time.Minute)); err == nil {
+		t.Error("expected error for empty name")
+	}
+	if err := scheduler.Add("a", workflow, nil); err == nil {
+		t.Error("expected error for nil schedule")
+	}
+	if err := scheduler.Add("a", workflow, Every(time.Minute)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := scheduler.Add("a", workflow, Every(time.Minute)); err == nil {
+		t.Error("expected error for duplicate name")
+	}
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer scheduler.Stop()
+	if err := scheduler.Add("b", workflow, Every(time.Minute)); err == nil {
+		t.Error("expected error adding schedule after start")
+	}
+	if err := scheduler.Start(context.Background()); err == nil {
+		t.Error("expected error starting twice")
+	}
+}
+
+func TestJSONLRunSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs", "schedule.jsonl")
+	sink, err := NewJSONLRunSink(path)
+	if err != nil {
+		t.Fatalf("NewJSONLRunSink failed: %v", err)
+	}
+
+	scheduled := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	records := []*RunRecord{
+		{Schedule: "report", Sequence: 1, ScheduledTime: scheduled, Status: RunSucceeded, SucceededJobs: []string{"collect"}},
+		{Schedule: "report", Sequence: 2, ScheduledTime: scheduled.Add(time.Hour), Status: RunFailed,
+			FailedJobs: map[string]string{"collect": "boom"}, Error: "boom"},
+	}
+	for _, record := range records {
+		if err := sink.Record(context.Background(), record); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open run log: %v", err)
+	}
+	defer file.Close()
+
+	var lines []RunRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, record)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[1].Status != RunFailed || lines[1].FailedJobs["collect"] != "boom" || !lines[1].ScheduledTime.Equal(scheduled.Add(time.Hour)) {
+		t.Errorf("unexpected record: %+v", lines[1])
+	}
+}
@@ -0,0 +1,189 @@
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ============================================================================
+// 状态变更通知
+// ============================================================================
+
+// StateChange 状态键的一次变更
+type StateChange struct {
+	Key     string
+	Value   interface{} // 新值，删除时为nil
+	Deleted bool
+}
+
+// stateWatcher 单个订阅者，内部队列保证变更不丢失且按顺序投递，写入方不会因读取慢而阻塞
+type stateWatcher struct {
+	ch     chan StateChange
+	mu     sync.Mutex
+	queue  []StateChange
+	signal chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newStateWatcher() *stateWatcher {
+	w := &stateWatcher{
+		ch:     make(chan StateChange),
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *stateWatcher) enqueue(change StateChange) {
+	w.mu.Lock()
+	w.queue = append(w.queue, change)
+	w.mu.Unlock()
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// run 按入队顺序投递变更，停止后关闭通道，未投递的变更被丢弃
+func (w *stateWatcher) run() {
+	defer close(w.ch)
+	for {
+		w.mu.Lock()
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			select {
+			case <-w.signal:
+				continue
+			case <-w.done:
+				return
+			}
+		}
+		change := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+
+		select {
+		case w.ch <- change:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *stateWatcher) stop() {
+	w.once.Do(func() { close(w.done) })
+}
+
+// Watch 订阅键的变更，Set、Delete等修改该键的操作按发生顺序投递到通道
+// 调用返回的cancel函数或工作流结束（CloseWatchers）时通道关闭，尚未读取的变更被丢弃
+func (fs *BaseFlowState) Watch(key string) (<-chan StateChange, func()) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	w := newStateWatcher()
+	if fs.watchersClosed {
+		w.stop()
+		return w.ch, func() {}
+	}
+	if fs.watchers == nil {
+		fs.watchers = make(map[string][]*stateWatcher)
+	}
+	fs.watchers[key] = append(fs.watchers[key], w)
+
+	cancel := func() {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		watchers := fs.watchers[key]
+		for i, existing := range watchers {
+			if existing == w {
+				fs.watchers[key] = append(watchers[:i:i], watchers[i+1:]...)
+				break
+			}
+		}
+		w.stop()
+	}
+	return w.ch, cancel
+}
+
+// CloseWatchers 关闭所有订阅通道，之后的Watch调用返回已关闭的通道。引擎在工作流结束时调用
+func (fs *BaseFlowState) CloseWatchers() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.watchersClosed = true
+	for _, watchers := range fs.watchers {
+		for _, w := range watchers {
+			w.stop()
+		}
+	}
+	fs.watchers = nil
+}
+
+// notifyLocked 通知键的订阅者，调用方必须持有写锁，从而保证同一个键的变更按顺序入队
+func (fs *BaseFlowState) notifyLocked(change StateChange) {
+	for _, w := range fs.watchers[change.Key] {
+		w.enqueue(change)
+	}
+}
+
+// ============================================================================
+// 泛型访问
+// ============================================================================
+
+// ErrStateKeyNotFound 工作流状态中没有该键
+var ErrStateKeyNotFound = errors.New("state key not found")
+
+// GetAs 按类型读取状态值，键不存在或类型不匹配时返回false
+func GetAs[T any](state FlowState, key string) (T, bool) {
+	var zero T
+	value, exists := state.Get(key)
+	if !exists {
+		return zero, false
+	}
+	typed, ok := value.(T)
+	if !ok {
+		return zero, false
+	}
+	return typed, true
+}
+
+// SetStruct 以JSON形式保存结构体，值在作业间传递和保存到检查点时不依赖具体类型
+func SetStruct(state FlowState, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state value %s: %w", key, err)
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return fmt.Errorf("failed to convert state value %s: %w", key, err)
+	}
+	state.Set(key, generic)
+	return nil
+}
+
+// GetStruct 通过JSON转换将状态值读取为结构体，兼容SetStruct保存的值和从检查点恢复的值
+func GetStruct[T any](state FlowState, key string) (T, error) {
+	var result T
+	value, exists := state.Get(key)
+	if !exists {
+		return result, fmt.Errorf("%w: %s", ErrStateKeyNotFound, key)
+	}
+	if typed, ok := value.(T); ok {
+		return typed, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal state value %s: %w", key, err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("state value %s cannot be converted to %T: %w", key, result, err)
+	}
+	return result, nil
+}
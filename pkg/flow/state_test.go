@@ -0,0 +1,156 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// drain 读取通道直到关闭，超时返回false
+func drain(ch <-chan StateChange, timeout time.Duration) ([]StateChange, bool) {
+	var changes []StateChange
+	deadline := time.After(timeout)
+	for {
+		select {
+		case change, ok := <-ch:
+			if !ok {
+				return changes, true
+			}
+			changes = append(changes, change)
+		case <-deadline:
+			return changes, false
+		}
+	}
+}
+
+func TestFlowStateWatchOrder(t *testing.T) {
+	state := NewFlowState()
+	changes, cancel := state.Watch("counter")
+
+	// 写入方不等待读取方
+	for i := 0; i < 100; i++ {
+		state.Set("counter", i)
+	}
+	state.Set("other", "ignored")
+	state.Delete("counter")
+
+	for i := 0; i < 100; i++ {
+		change := <-changes
+		if change.Value != i {
+			t.Fatalf("Expected change %d in order, got: %v", i, change.Value)
+		}
+	}
+	if change := <-changes; !change.Deleted || change.Key != "counter" {
+		t.Errorf("Expected delete notification, got: %+v", change)
+	}
+
+	cancel()
+	if _, closed := drain(changes, time.Second); !closed {
+		t.Error("Expected channel to close after cancel")
+	}
+}
+
+func TestFlowStateWatchBetweenConcurrentJobs(t *testing.T) {
+	var unread <-chan StateChange
+
+	waiter := NewStatefulJob("waiter", func(ctx context.Context, state FlowState) (interface{}, error) {
+		changes, cancel := state.Watch("status")
+		defer cancel()
+		state.Set("waiter_ready", true)
+		for change := range changes {
+			if change.Value == "done" {
+				return "saw done", nil
+			}
+		}
+		return nil, errors.New("watch closed before done")
+	})
+	producer := NewStatefulJob("producer", func(ctx context.Context, state FlowState) (interface{}, error) {
+		// 订阅后不读取，工作流结束时通道也必须关闭
+		unread, _ = state.Watch("status")
+		for {
+			if ready, _ := state.GetBool("waiter_ready"); ready {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		state.Set("status", "working")
+		state.Set("status", "done")
+		return "produced", nil
+	})
+
+	result, err := NewWorkflow("watch").
+		AddJob(waiter, Immediately()).
+		AddJob(producer, Immediately()).
+		Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.AllResults["waiter"] != "saw done" {
+		t.Errorf("Expected waiter to observe the change, got: %v", result.AllResults["waiter"])
+	}
+
+	if _, closed := drain(unread, time.Second); !closed {
+		t.Error("Expected watch channel to close when the workflow ends")
+	}
+	if _, closed := drain(watchAfterClose(result.FinalState), time.Second); !closed {
+		t.Error("Expected Watch after the workflow ended to return a closed channel")
+	}
+}
+
+func watchAfterClose(state FlowState) <-chan StateChange {
+	ch, _ := state.Watch("status")
+	return ch
+}
+
+type reportSummary struct {
+	Title string   `json:"title"`
+	Score float64  `json:"score"`
+	Tags  []string `json:"tags"`
+}
+
+func TestFlowStateTypedAccess(t *testing.T) {
+	state := NewFlowState()
+	state.Set("count", 3)
+	state.Set("direct", reportSummary{Title: "direct"})
+
+	if count, ok := GetAs[int](state, "count"); !ok || count != 3 {
+		t.Errorf("Expected count 3, got: %v, %v", count, ok)
+	}
+	if _, ok := GetAs[string](state, "count"); ok {
+		t.Error("Expected type mismatch to return false")
+	}
+	if _, ok := GetAs[int](state, "missing"); ok {
+		t.Error("Expected missing key to return false")
+	}
+
+	summary := reportSummary{Title: "Q3", Score: 0.9, Tags: []string{"sales"}}
+	if err := SetStruct(state, "summary", summary); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := state.GetMap("summary"); !ok {
+		t.Error("Expected struct to be stored as a JSON map")
+	}
+
+	restored, err := GetStruct[reportSummary](state, "summary")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if fmt.Sprint(restored) != fmt.Sprint(summary) {
+		t.Errorf("Expected %+v, got: %+v", summary, restored)
+	}
+
+	if direct, err := GetStruct[reportSummary](state, "direct"); err != nil || direct.Title != "direct" {
+		t.Errorf("Expected direct struct value, got: %+v, %v", direct, err)
+	}
+	if _, err := GetStruct[reportSummary](state, "missing"); !errors.Is(err, ErrStateKeyNotFound) {
+		t.Errorf("Expected ErrStateKeyNotFound, got: %v", err)
+	}
+	if _, err := GetStruct[reportSummary](state, "count"); err == nil {
+		t.Error("Expected conversion error for incompatible value")
+	}
+	if err := SetStruct(state, "bad", make(chan int)); err == nil {
+		t.Error("Expected error for non-serializable value")
+	}
+}
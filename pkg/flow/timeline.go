@@ -0,0 +1,175 @@
+package flow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// 执行时间线 - 从JobTrace生成Mermaid甘特图和文本时间线
+// ============================================================================
+
+// timelineBarWidth 文本时间线中进度条的宽度
+const timelineBarWidth = 40
+
+// Timeline 工作流一次执行的时间线，按批次和作业ID排序
+type Timeline struct {
+	Entries []TimelineEntry
+	Skipped []string      // 条件未满足而未执行的作业
+	Span    time.Duration // 从第一个作业开始到最后一个作业结束
+}
+
+// TimelineEntry 时间线中的一次作业执行，时间相对于第一个作业的开始时间
+type TimelineEntry struct {
+	JobID    string
+	BatchID  int
+	Start    time.Duration
+	Duration time.Duration
+	Attempts int
+	FanOut   int
+	Failed   bool
+}
+
+// Timeline 从作业执行追踪生成时间线
+// 同一批次内的作业按ID排序，相同的JobTrace总是生成相同的输出
+func (r *ExecutionResult) Timeline() *Timeline {
+	timeline := &Timeline{Skipped: append([]string(nil), r.SkippedJobs...)}
+	sort.Strings(timeline.Skipped)
+	if len(r.JobTrace) == 0 {
+		return timeline
+	}
+
+	origin := r.JobTrace[0].StartTime
+	var end time.Time
+	for _, exec := range r.JobTrace {
+		if exec.StartTime.Before(origin) {
+			origin = exec.StartTime
+		}
+		if exec.EndTime.After(end) {
+			end = exec.EndTime
+		}
+	}
+
+	for _, exec := range r.JobTrace {
+		timeline.Entries = append(timeline.Entries, TimelineEntry{
+			JobID:    exec.JobID,
+			BatchID:  exec.BatchID,
+			Start:    exec.StartTime.Sub(origin),
+			Duration: exec.Duration,
+			Attempts: exec.Attempts,
+			FanOut:   exec.FanOut,
+			Failed:   exec.Error != nil,
+		})
+	}
+	sort.SliceStable(timeline.Entries, func(i, j int) bool {
+		a, b := timeline.Entries[i], timeline.Entries[j]
+		if a.BatchID != b.BatchID {
+			return a.BatchID < b.BatchID
+		}
+		return a.JobID < b.JobID
+	})
+	timeline.Span = end.Sub(origin)
+	return timeline
+}
+
+// summary 作业名称后附加的耗时、重试和扇出信息
+func (e TimelineEntry) summary() string {
+	parts := []string{formatTimelineDuration(e.Duration)}
+	if e.Attempts > 1 {
+		parts = append(parts, fmt.Sprintf("%d attempts", e.Attempts))
+	}
+	if e.FanOut > 0 {
+		parts = append(parts, fmt.Sprintf("fan-out %d", e.FanOut))
+	}
+	if e.Failed {
+		parts = append(parts, "failed")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatTimelineDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// Mermaid 导出为Mermaid甘特图，每个批次一个section，失败的作业标记为crit
+func (t *Timeline) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	b.WriteString("    dateFormat x\n")
+	b.WriteString("    axisFormat %S.%L\n")
+
+	batch := 0
+	for i, entry := range t.Entries {
+		if entry.BatchID != batch {
+			batch = entry.BatchID
+			fmt.Fprintf(&b, "    section Batch %d\n", batch)
+		}
+		status := "done"
+		if entry.Failed {
+			status = "crit"
+		}
+		start := entry.Start.Milliseconds()
+		end := (entry.Start + entry.Duration).Milliseconds()
+		if end <= start {
+			end = start + 1 // 保证极短的作业也能显示
+		}
+		name := strings.NewReplacer(":", "-", "#", "-", ";", "-").Replace(entry.JobID)
+		fmt.Fprintf(&b, "    %s (%s) :%s, t%d, %d, %d\n", name, entry.summary(), status, i+1, start, end)
+	}
+
+	if len(t.Skipped) > 0 {
+		fmt.Fprintf(&b, "    %%%% skipped: %s\n", strings.Join(t.Skipped, ", "))
+	}
+	return b.String()
+}
+
+// String 返回文本时间线，进度条按总时长缩放：
+//
+//	batch 1
+//	  fetch    [##########..............................] 120ms
+func (t *Timeline) String() string {
+	nameWidth := 0
+	for _, entry := range t.Entries {
+		if len(entry.JobID) > nameWidth {
+			nameWidth = len(entry.JobID)
+		}
+	}
+
+	var b strings.Builder
+	batch := 0
+	for _, entry := range t.Entries {
+		if entry.BatchID != batch {
+			batch = entry.BatchID
+			fmt.Fprintf(&b, "batch %d\n", batch)
+		}
+		fmt.Fprintf(&b, "  %-*s [%s] %s\n", nameWidth, entry.JobID, t.bar(entry), entry.summary())
+	}
+	if len(t.Skipped) > 0 {
+		fmt.Fprintf(&b, "skipped: %s\n", strings.Join(t.Skipped, ", "))
+	}
+	return b.String()
+}
+
+// bar 作业在总时长中所占位置的进度条，至少显示一格
+func (t *Timeline) bar(entry TimelineEntry) string {
+	if t.Span <= 0 {
+		return strings.Repeat("#", timelineBarWidth)
+	}
+	scale := func(d time.Duration) int {
+		return int(int64(d) * timelineBarWidth / int64(t.Span))
+	}
+	start := scale(entry.Start)
+	if start >= timelineBarWidth {
+		start = timelineBarWidth - 1
+	}
+	width := scale(entry.Duration)
+	if width < 1 {
+		width = 1
+	}
+	if start+width > timelineBarWidth {
+		width = timelineBarWidth - start
+	}
+	return strings.Repeat(".", start) + strings.Repeat("#", width) + strings.Repeat(".", timelineBarWidth-start-width)
+}
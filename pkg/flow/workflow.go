@@ -4,9 +4,13 @@ package flow
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/ynl/greensoulai/pkg/logger"
 )
 
 // ============================================================================
@@ -45,6 +49,10 @@ type FlowState interface {
 	// 克隆和合并
 	Clone() FlowState
 	Merge(other FlowState)
+
+	// 变更通知
+	Watch(key string) (<-chan StateChange, func())
+	CloseWatchers()
 }
 
 // Job 定义工作流中的作业单元 - 可以并行执行
@@ -71,9 +79,13 @@ type Workflow interface {
 	AddJob(job Job, trigger Trigger) Workflow
 	Run(ctx context.Context) (*ExecutionResult, error)
 	RunAsync(ctx context.Context) <-chan *ExecutionResult
+	Describe() *WorkflowGraph
+	// Validate 不执行作业，检查重复的作业ID和触发条件引用的不存在的作业
+	Validate() error
 }
 
 // JobResults 已完成作业的结果集
+// 以ContinueOnError策略失败的作业记录为*JobFailure
 type JobResults map[string]interface{}
 
 // ============================================================================
@@ -82,13 +94,16 @@ type JobResults map[string]interface{}
 
 // ExecutionResult 工作流执行结果
 type ExecutionResult struct {
-	FinalResult interface{}      // 最后完成的作业结果
-	AllResults  JobResults       // 所有作业结果
-	FinalState  FlowState        // 最终工作流状态 - 包含作业间传递的数据
-	JobTrace    []JobExecution   // 作业执行追踪
-	Metrics     *ParallelMetrics // 并行执行指标
-	Duration    time.Duration    // 总执行时间
-	Error       error            // 执行错误
+	FinalResult   interface{}      // 最后成功完成的作业结果
+	AllResults    JobResults       // 所有作业结果
+	SucceededJobs []string         // 成功完成的作业ID，按完成顺序
+	FailedJobs    map[string]error // 失败的作业及其错误
+	SkippedJobs   []string         // 条件未满足而未执行的作业ID
+	FinalState    FlowState        // 最终工作流状态 - 包含作业间传递的数据
+	JobTrace      []JobExecution   // 作业执行追踪
+	Metrics       *ParallelMetrics // 并行执行指标
+	Duration      time.Duration    // 总执行时间
+	Error         error            // 执行错误
 }
 
 // JobExecution 单个作业执行记录
@@ -100,6 +115,8 @@ type JobExecution struct {
 	Result    interface{}
 	Error     error
 	BatchID   int // 所属的并行批次ID
+	Attempts  int // 执行次数，包括重试
+	FanOut    int // 扇出作业执行的子作业数量
 }
 
 // ParallelMetrics 并行执行指标
@@ -111,6 +128,8 @@ type ParallelMetrics struct {
 	ParallelEfficiency float64        // 并行效率
 	SerialTime         time.Duration  // 假设串行执行的时间
 	ParallelTime       time.Duration  // 实际并行执行时间
+	Retries            int            // 所有作业的重试总次数
+	FanOut             map[string]int // 扇出作业ID到子作业数量
 }
 
 // BatchMetrics 批次执行指标
@@ -129,10 +148,16 @@ type BatchMetrics struct {
 // ParallelEngine 并行作业执行引擎
 // 注意：Engine设计用于编排和执行工作流作业，不同于Agent的任务执行
 type ParallelEngine struct {
-	name      string
-	jobs      []jobWithTrigger
-	maxCycles int
-	mu        sync.RWMutex
+	name            string
+	jobs            []jobWithTrigger
+	jobErrs         []error // AddJob拒绝的作业，Validate和Run返回这些错误
+	maxCycles       int
+	timeout         time.Duration // >0时整个工作流的执行时限
+	strict          bool          // 停止时有依赖的作业都未成功的作业也视为死锁
+	checkpointStore CheckpointStore
+	resumeFrom      *Checkpoint // 恢复执行时加载的检查点
+	logger          logger.Logger
+	mu              sync.RWMutex
 }
 
 type jobWithTrigger struct {
@@ -140,20 +165,73 @@ type jobWithTrigger struct {
 	trigger Trigger
 }
 
+// DefaultMaxCycles 默认的最大调度周期数，每个周期并行执行一批就绪的作业
+const DefaultMaxCycles = 100
+
+// ErrDuplicateJob 工作流中已有相同ID的作业
+var ErrDuplicateJob = errors.New("duplicate job id")
+
+// ErrMaxCyclesExceeded 调度周期用完时仍有未执行的作业
+var ErrMaxCyclesExceeded = errors.New("workflow exceeded max cycles")
+
 // NewWorkflow 创建新的并行工作流
-func NewWorkflow(name string) Workflow {
-	return &ParallelEngine{
+func NewWorkflow(name string, opts ...WorkflowOption) Workflow {
+	return newParallelEngine(name, opts...)
+}
+
+func newParallelEngine(name string, opts ...WorkflowOption) *ParallelEngine {
+	engine := &ParallelEngine{
 		name:      name,
 		jobs:      make([]jobWithTrigger, 0),
-		maxCycles: 100,
+		maxCycles: DefaultMaxCycles,
+		logger:    logger.NewConsoleLogger(),
 	}
+	for _, opt := range opts {
+		opt(engine)
+	}
+	return engine
+}
+
+// WithMaxCycles 设置最大调度周期数，<=0时使用DefaultMaxCycles
+// 作业链比周期数长时Run返回ErrMaxCyclesExceeded
+func WithMaxCycles(n int) WorkflowOption {
+	return func(e *ParallelEngine) {
+		if n <= 0 {
+			n = DefaultMaxCycles
+		}
+		e.maxCycles = n
+	}
+}
+
+// WithOverallTimeout 设置整个工作流的执行时限，Run在派生的ctx中执行作业，超时后不再调度新的作业
+func WithOverallTimeout(timeout time.Duration) WorkflowOption {
+	return func(e *ParallelEngine) { e.timeout = timeout }
+}
+
+// WithStrictMode 开启严格模式：工作流停止时，依赖的作业全部失败或从未执行的作业不再记为跳过的分支，
+// 而是与引用不存在作业的作业一起以DeadlockError报告，便于发现触发条件中的错误
+func WithStrictMode() WorkflowOption {
+	return func(e *ParallelEngine) { e.strict = true }
 }
 
 // AddJob 添加作业和触发条件
+// 已有相同ID的作业时拒绝添加，错误由Validate和Run返回
 func (e *ParallelEngine) AddJob(job Job, trigger Trigger) Workflow {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	for _, jt := range e.jobs {
+		if jt.job.ID() == job.ID() {
+			err := fmt.Errorf("%w: %s in workflow %s", ErrDuplicateJob, job.ID(), e.name)
+			e.jobErrs = append(e.jobErrs, err)
+			e.logger.Warn("duplicate job rejected",
+				logger.Field{Key: "workflow", Value: e.name},
+				logger.Field{Key: "job_id", Value: job.ID()},
+			)
+			return e
+		}
+	}
+
 	e.jobs = append(e.jobs, jobWithTrigger{
 		job:     job,
 		trigger: trigger,
@@ -161,48 +239,141 @@ func (e *ParallelEngine) AddJob(job Job, trigger Trigger) Workflow {
 	return e
 }
 
+// ErrUnknownJobReference 触发条件引用了工作流中不存在的作业
+var ErrUnknownJobReference = errors.New("unknown job reference")
+
+// Validate 不执行作业，检查AddJob拒绝的重复作业和触发条件引用的不存在的作业
+// 只能检查声明了依赖的触发条件（After、AllOf、OnFailure等），自定义触发器无法检查
+func (e *ParallelEngine) Validate() error {
+	known := e.jobIDs()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	errs := append([]error(nil), e.jobErrs...)
+	for _, jt := range e.jobs {
+		if unknown := unknownDependencies(jt.trigger, known); len(unknown) > 0 {
+			errs = append(errs, fmt.Errorf("%w: job %s (%s) references %s",
+				ErrUnknownJobReference, jt.job.ID(), jt.trigger.String(), strings.Join(unknown, ", ")))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Run 执行工作流 - 重点：并行执行所有就绪的作业
 func (e *ParallelEngine) Run(ctx context.Context) (*ExecutionResult, error) {
 	startTime := time.Now()
 
-	// 创建工作流状态 - 支持作业间数据传递
-	flowState := NewFlowState()
+	// 创建工作流状态 - 支持作业间数据传递，工作流结束时关闭所有状态订阅
+	// ctx中有WithInitialState设置的初始数据时以其为初始状态
+	flowState := NewFlowStateWithData(initialStateFrom(ctx))
+	defer flowState.CloseWatchers()
 
 	result := &ExecutionResult{
 		AllResults: make(JobResults),
+		FailedJobs: make(map[string]error),
 		FinalState: flowState,
 		JobTrace:   make([]JobExecution, 0),
 		Metrics:    &ParallelMetrics{},
 	}
 
+	// AddJob拒绝的作业说明工作流定义有误，不执行任何作业
+	e.mu.RLock()
+	jobErr := errors.Join(e.jobErrs...)
+	e.mu.RUnlock()
+	if jobErr != nil {
+		result.Error = jobErr
+		result.Duration = time.Since(startTime)
+		return result, jobErr
+	}
+
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
 	var totalSerialTime time.Duration
 	cycle := 0
 	batchID := 0
+	stopped := false
+
+	// 从检查点恢复：已完成的作业不会再次执行
+	if e.resumeFrom != nil {
+		batchID = e.resumeFrom.restore(result)
+	}
 
 	for cycle < e.maxCycles {
 		cycle++
 
+		// 超时或取消后不再调度新的作业
+		if err := ctx.Err(); err != nil {
+			err = fmt.Errorf("workflow %s stopped after %d batches: %w", e.name, batchID, err)
+			result.Error = err
+			result.Duration = time.Since(startTime)
+			return result, err
+		}
+
 		// 🚀 关键：获取所有就绪的作业（可能有多个）
-		readyJobs := e.getReadyJobs(result.AllResults)
+		// 触发条件每个周期用最新的结果和状态重新评估
+		readyJobs := e.getReadyJobs(result.AllResults, flowState)
 		if len(readyJobs) == 0 {
-			break // 没有更多就绪的作业
+			// 没有更多就绪的作业，区分未选中的分支和永远无法就绪的作业
+			skipped, deadlock := classifyPendingJobs(e.getPendingJobs(result.AllResults), result.AllResults, e.jobIDs(), e.strict)
+			result.SkippedJobs = skipped
+			if deadlock != nil {
+				result.Error = deadlock
+				result.Duration = time.Since(startTime)
+				return result, deadlock
+			}
+			stopped = true
+			break
 		}
 
 		// 🚀 关键：并行执行所有就绪的作业
 		batchID++
 		batchResults, batchMetrics, err := e.executeJobBatch(ctx, readyJobs, batchID, flowState)
+
+		// 更新结果 - 失败的作业记录为JobFailure，不会满足After触发器
+		for _, jobExec := range batchResults {
+			result.JobTrace = append(result.JobTrace, jobExec)
+			result.Metrics.Retries += jobExec.Attempts - 1
+			if jobExec.FanOut > 0 {
+				if result.Metrics.FanOut == nil {
+					result.Metrics.FanOut = make(map[string]int)
+				}
+				result.Metrics.FanOut[jobExec.JobID] = jobExec.FanOut
+			}
+			totalSerialTime += jobExec.Duration
+
+			if jobExec.Error != nil {
+				result.FailedJobs[jobExec.JobID] = jobExec.Error
+				result.AllResults[jobExec.JobID] = &JobFailure{
+					JobID:    jobExec.JobID,
+					Attempts: jobExec.Attempts,
+					Err:      jobExec.Error,
+				}
+				continue
+			}
+			result.AllResults[jobExec.JobID] = jobExec.Result
+			result.SucceededJobs = append(result.SucceededJobs, jobExec.JobID)
+			result.FinalResult = jobExec.Result // 最后一个成功的作为最终结果
+		}
+
 		if err != nil {
 			result.Error = err
 			result.Duration = time.Since(startTime)
 			return result, err
 		}
 
-		// 更新结果
-		for _, jobExec := range batchResults {
-			result.AllResults[jobExec.JobID] = jobExec.Result
-			result.JobTrace = append(result.JobTrace, jobExec)
-			result.FinalResult = jobExec.Result // 最后一个作为最终结果
-			totalSerialTime += jobExec.Duration
+		// 批次成功后保存检查点，失败的批次会在恢复后重新执行
+		if e.checkpointStore != nil {
+			if err := e.checkpointStore.Save(ctx, e.snapshot(result, batchID)); err != nil {
+				err = fmt.Errorf("failed to save checkpoint for workflow %s: %w", e.name, err)
+				result.Error = err
+				result.Duration = time.Since(startTime)
+				return result, err
+			}
 		}
 
 		// 更新批次指标
@@ -212,6 +383,15 @@ func (e *ParallelEngine) Run(ctx context.Context) (*ExecutionResult, error) {
 		}
 	}
 
+	// 周期用完时仍有未执行的作业，不能当作正常结束
+	if pending := e.getPendingJobs(result.AllResults); !stopped && len(pending) > 0 {
+		err := fmt.Errorf("%w: workflow %s stopped after %d cycles with %d pending jobs",
+			ErrMaxCyclesExceeded, e.name, e.maxCycles, len(pending))
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result, err
+	}
+
 	// 完善执行指标
 	result.Duration = time.Since(startTime)
 	result.Metrics.TotalJobs = len(result.JobTrace)
@@ -243,25 +423,61 @@ func (e *ParallelEngine) RunAsync(ctx context.Context) <-chan *ExecutionResult {
 }
 
 // getReadyJobs 获取所有就绪的作业 - 强调可能有多个并行就绪
-func (e *ParallelEngine) getReadyJobs(completed JobResults) []Job {
+func (e *ParallelEngine) getReadyJobs(completed JobResults, state FlowState) []Job {
+	var ready []Job
+
+	for _, jt := range e.getPendingJobs(completed) {
+		// 检查触发条件
+		if triggerReady(jt.trigger, completed, state) {
+			ready = append(ready, jt.job)
+		}
+	}
+
+	return ready
+}
+
+// jobIDs 返回工作流中所有作业的ID
+func (e *ParallelEngine) jobIDs() map[string]bool {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
-	var ready []Job
+	ids := make(map[string]bool, len(e.jobs))
+	for _, jt := range e.jobs {
+		ids[jt.job.ID()] = true
+	}
+	return ids
+}
+
+// getPendingJobs 获取尚未执行的作业
+func (e *ParallelEngine) getPendingJobs(completed JobResults) []jobWithTrigger {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 
+	var pending []jobWithTrigger
 	for _, jt := range e.jobs {
-		// 跳过已完成的作业
-		if _, done := completed[jt.job.ID()]; done {
-			continue
+		if _, done := completed[jt.job.ID()]; !done {
+			pending = append(pending, jt)
 		}
+	}
+	return pending
+}
 
-		// 检查触发条件
-		if jt.trigger.Ready(completed) {
-			ready = append(ready, jt.job)
-		}
+// continuesOnError 作业失败时是否继续工作流：作业策略为ContinueOnError，或有OnFailure触发器处理该作业的失败
+func (e *ParallelEngine) continuesOnError(job Job) bool {
+	if jobPolicyOf(job).ErrorPolicy == ContinueOnError {
+		return true
 	}
 
-	return ready
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, jt := range e.jobs {
+		for _, id := range triggerHandledFailures(jt.trigger) {
+			if id == job.ID() {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // executeJobBatch 批量并行执行作业 - 核心并行逻辑
@@ -286,19 +502,17 @@ func (e *ParallelEngine) executeJobBatch(ctx context.Context, jobs []Job, batchI
 				BatchID:   batchID,
 			}
 
-			// 执行作业 - 优先使用支持状态传递的接口
-			var result interface{}
-			var err error
-			if statefulJob, ok := j.(StatefulJob); ok {
-				result, err = statefulJob.ExecuteWithState(ctx, state)
-			} else {
-				result, err = j.Execute(ctx)
-			}
+			// 按作业策略执行，包括重试和超时
+			result, err, attempts := runJobWithPolicy(ctx, j, state)
 
 			execution.EndTime = time.Now()
 			execution.Duration = execution.EndTime.Sub(execution.StartTime)
 			execution.Result = result
 			execution.Error = err
+			execution.Attempts = attempts
+			if fanOut, ok := j.(FanOutJob); ok {
+				execution.FanOut = fanOut.FanOutWidth()
+			}
 
 			resultChan <- execution
 		}(job)
@@ -313,8 +527,9 @@ func (e *ParallelEngine) executeJobBatch(ctx context.Context, jobs []Job, batchI
 	// 收集结果
 	var executions []JobExecution
 	for execution := range resultChan {
-		if execution.Error != nil {
-			return nil, BatchMetrics{}, fmt.Errorf("job %s failed: %w", execution.JobID, execution.Error)
+		if execution.Error != nil && !e.continuesOnError(jobByID(jobs, execution.JobID)) {
+			executions = append(executions, execution)
+			return executions, BatchMetrics{}, fmt.Errorf("job %s failed: %w", execution.JobID, execution.Error)
 		}
 		executions = append(executions, execution)
 	}
@@ -342,21 +557,32 @@ func (e *ParallelEngine) executeJobBatch(ctx context.Context, jobs []Job, batchI
 	return executions, batchMetrics, nil
 }
 
+// jobByID 在批次中查找作业
+func jobByID(jobs []Job, id string) Job {
+	for _, job := range jobs {
+		if job.ID() == id {
+			return job
+		}
+	}
+	return nil
+}
+
 // ============================================================================
 // 作业实现 - 清晰表达这是并行执行单元
 // ============================================================================
 
 // SimpleJob 简单作业实现
 type SimpleJob struct {
-	id string
-	fn func(ctx context.Context) (interface{}, error)
+	id     string
+	fn     func(ctx context.Context) (interface{}, error)
+	policy JobPolicy
 }
 
 func (j SimpleJob) ID() string                                       { return j.id }
 func (j SimpleJob) Execute(ctx context.Context) (interface{}, error) { return j.fn(ctx) }
 
-// NewJob 创建简单作业
-func NewJob(id string, fn func(ctx context.Context) (interface{}, error)) Job {
+// NewJob 创建简单作业，可以通过WithRetry、WithTimeout和WithErrorPolicy设置执行策略
+func NewJob(id string, fn func(ctx context.Context) (interface{}, error)) SimpleJob {
 	return SimpleJob{id: id, fn: fn}
 }
 
@@ -371,21 +597,39 @@ func (ImmediateTrigger) Ready(completed JobResults) bool { return true }
 func (ImmediateTrigger) String() string                  { return "immediate" }
 
 // AfterTrigger 在指定作业完成后触发
-type AfterTrigger struct{ jobID string }
+// 默认只在作业成功时触发；anyOutcome为true时作业以ContinueOnError策略失败后也触发
+type AfterTrigger struct {
+	jobID      string
+	anyOutcome bool
+}
 
 func (t AfterTrigger) Ready(completed JobResults) bool {
-	_, exists := completed[t.jobID]
-	return exists
+	if t.anyOutcome {
+		_, exists := completed[t.jobID]
+		return exists
+	}
+	return completed.Succeeded(t.jobID)
 }
 
-func (t AfterTrigger) String() string { return fmt.Sprintf("after:%s", t.jobID) }
+func (t AfterTrigger) String() string {
+	if t.anyOutcome {
+		return fmt.Sprintf("after-completion:%s", t.jobID)
+	}
+	return fmt.Sprintf("after:%s", t.jobID)
+}
+
+func (t AfterTrigger) Dependencies() []string { return []string{t.jobID} }
 
 // AllOfTrigger 所有指定作业都完成后触发（AND逻辑）
 type AllOfTrigger struct{ triggers []Trigger }
 
 func (t AllOfTrigger) Ready(completed JobResults) bool {
+	return t.ReadyWithState(completed, nil)
+}
+
+func (t AllOfTrigger) ReadyWithState(completed JobResults, state FlowState) bool {
 	for _, trigger := range t.triggers {
-		if !trigger.Ready(completed) {
+		if !triggerReady(trigger, completed, state) {
 			return false
 		}
 	}
@@ -393,13 +637,23 @@ func (t AllOfTrigger) Ready(completed JobResults) bool {
 }
 
 func (t AllOfTrigger) String() string { return "all-of" }
+func (t AllOfTrigger) Dependencies() []string {
+	return collectTriggerIDs(t.triggers, triggerDependencies)
+}
+func (t AllOfTrigger) handledFailures() []string {
+	return collectTriggerIDs(t.triggers, triggerHandledFailures)
+}
 
 // AnyOfTrigger 任一指定作业完成后触发（OR逻辑）
 type AnyOfTrigger struct{ triggers []Trigger }
 
 func (t AnyOfTrigger) Ready(completed JobResults) bool {
+	return t.ReadyWithState(completed, nil)
+}
+
+func (t AnyOfTrigger) ReadyWithState(completed JobResults, state FlowState) bool {
 	for _, trigger := range t.triggers {
-		if trigger.Ready(completed) {
+		if triggerReady(trigger, completed, state) {
 			return true
 		}
 	}
@@ -407,6 +661,21 @@ func (t AnyOfTrigger) Ready(completed JobResults) bool {
 }
 
 func (t AnyOfTrigger) String() string { return "any-of" }
+func (t AnyOfTrigger) Dependencies() []string {
+	return collectTriggerIDs(t.triggers, triggerDependencies)
+}
+func (t AnyOfTrigger) handledFailures() []string {
+	return collectTriggerIDs(t.triggers, triggerHandledFailures)
+}
+
+// collectTriggerIDs 汇总子触发器的作业ID
+func collectTriggerIDs(triggers []Trigger, ids func(Trigger) []string) []string {
+	var all []string
+	for _, trigger := range triggers {
+		all = append(all, ids(trigger)...)
+	}
+	return all
+}
 
 // ============================================================================
 // 便捷构造函数 - 语义清晰的API
@@ -415,8 +684,15 @@ func (t AnyOfTrigger) String() string { return "any-of" }
 // Immediately 立即就绪触发器
 func Immediately() Trigger { return ImmediateTrigger{} }
 
-// After 在指定作业完成后触发
-func After(jobID string) Trigger { return AfterTrigger{jobID} }
+// After 在指定作业成功完成后触发
+func After(jobID string) Trigger { return AfterTrigger{jobID: jobID} }
+
+// AfterSuccess 在指定作业成功完成后触发，与After相同
+func AfterSuccess(jobID string) Trigger { return After(jobID) }
+
+// AfterCompletion 在指定作业结束后触发，无论成功还是失败
+// 作业可以通过JobResults.Failure检查上游是否失败
+func AfterCompletion(jobID string) Trigger { return AfterTrigger{jobID: jobID, anyOutcome: true} }
 
 // AllOf 所有作业都完成后触发
 func AllOf(triggers ...Trigger) Trigger { return AllOfTrigger{triggers} }
@@ -513,37 +789,7 @@ func NewSequentialChain(id string, jobs ...Job) Job {
 	return SequentialJobChain{id: id, jobs: jobs}
 }
 
-// ============================================================================
-// 与Agent系统集成的便捷作业类型
-// ============================================================================
-
-// AgentJobAdapter 将Agent任务适配为工作流作业
-// 这样可以在工作流中执行Agent任务，概念层次清晰：
-// Workflow Job -> 包含 -> Agent Task
-type AgentJobAdapter struct {
-	id          string
-	description string
-	// 注意：这里可以引用 agent.Agent 和 agent.Task
-	// 但现在先用interface{}避免循环导入，后续可以重构
-}
-
-func (aja AgentJobAdapter) ID() string { return aja.id }
-
-func (aja AgentJobAdapter) Execute(ctx context.Context) (interface{}, error) {
-	// TODO: 集成Agent系统
-	// agent := getAgent()
-	// task := createAgentTask()
-	// return agent.Execute(ctx, task)
-	return fmt.Sprintf("Agent job %s executed: %s", aja.id, aja.description), nil
-}
-
-// NewAgentJob 创建Agent作业适配器
-func NewAgentJob(id, description string) Job {
-	return AgentJobAdapter{
-		id:          id,
-		description: description,
-	}
-}
+// Agent和Crew作业见agentflow子包，flow本身不依赖Agent系统
 
 // ============================================================================
 // FlowState 实现 - 线程安全的状态存储
@@ -551,8 +797,10 @@ func NewAgentJob(id, description string) Job {
 
 // BaseFlowState FlowState接口的基础实现
 type BaseFlowState struct {
-	data map[string]interface{}
-	mu   sync.RWMutex
+	data           map[string]interface{}
+	watchers       map[string][]*stateWatcher
+	watchersClosed bool
+	mu             sync.RWMutex
 }
 
 // NewFlowState 创建新的工作流状态
@@ -562,6 +810,20 @@ func NewFlowState() FlowState {
 	}
 }
 
+// initialStateKey ctx中本次执行的初始状态
+type initialStateKey struct{}
+
+// WithInitialState 返回带有初始状态的ctx，Run以这些数据作为本次执行FlowState的初始内容
+// 同一个工作流多次执行（如定时执行）时，每次执行可以使用不同的初始状态
+func WithInitialState(ctx context.Context, data map[string]interface{}) context.Context {
+	return context.WithValue(ctx, initialStateKey{}, data)
+}
+
+func initialStateFrom(ctx context.Context) map[string]interface{} {
+	data, _ := ctx.Value(initialStateKey{}).(map[string]interface{})
+	return data
+}
+
 // NewFlowStateWithData 使用初始数据创建工作流状态
 func NewFlowStateWithData(initialData map[string]interface{}) FlowState {
 	state := &BaseFlowState{
@@ -585,12 +847,16 @@ func (fs *BaseFlowState) Set(key string, value interface{}) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 	fs.data[key] = value
+	fs.notifyLocked(StateChange{Key: key, Value: value})
 }
 
 func (fs *BaseFlowState) Delete(key string) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
-	delete(fs.data, key)
+	if _, exists := fs.data[key]; exists {
+		delete(fs.data, key)
+		fs.notifyLocked(StateChange{Key: key, Deleted: true})
+	}
 }
 
 func (fs *BaseFlowState) Keys() []string {
@@ -664,6 +930,7 @@ func (fs *BaseFlowState) SetAll(data map[string]interface{}) {
 	defer fs.mu.Unlock()
 	for k, v := range data {
 		fs.data[k] = v
+		fs.notifyLocked(StateChange{Key: k, Value: v})
 	}
 }
 
@@ -680,6 +947,9 @@ func (fs *BaseFlowState) GetAll() map[string]interface{} {
 func (fs *BaseFlowState) Clear() {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
+	for k := range fs.data {
+		fs.notifyLocked(StateChange{Key: k, Deleted: true})
+	}
 	fs.data = make(map[string]interface{})
 }
 
@@ -690,10 +960,12 @@ func (fs *BaseFlowState) CompareAndSwap(key string, old, new interface{}) bool {
 	if current, exists := fs.data[key]; exists {
 		if current == old {
 			fs.data[key] = new
+			fs.notifyLocked(StateChange{Key: key, Value: new})
 			return true
 		}
 	} else if old == nil {
 		fs.data[key] = new
+		fs.notifyLocked(StateChange{Key: key, Value: new})
 		return true
 	}
 	return false
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -418,40 +419,6 @@ func TestAfterAnyJob(t *testing.T) {
 	}
 }
 
-// ============================================================================
-// Agent集成测试
-// ============================================================================
-
-func TestAgentJobAdapter(t *testing.T) {
-	ctx := context.Background()
-
-	agentJob := NewAgentJob("agent-task-1", "Analyze sentiment of text")
-
-	workflow := NewWorkflow("agent-integration-test").
-		AddJob(agentJob, Immediately())
-
-	result, err := workflow.Run(ctx)
-
-	if err != nil {
-		t.Fatalf("Unexpected error: %v", err)
-	}
-
-	// 验证Agent作业执行
-	if result.FinalResult == nil {
-		t.Error("Expected agent job to return a result")
-	}
-
-	// 验证结果包含预期信息
-	resultStr, ok := result.FinalResult.(string)
-	if !ok {
-		t.Fatal("Expected agent job result to be string")
-	}
-
-	if !contains(resultStr, "agent-task-1") {
-		t.Errorf("Expected result to contain job ID, got: %s", resultStr)
-	}
-}
-
 // ============================================================================
 // 边界条件测试
 // ============================================================================
@@ -503,6 +470,421 @@ func TestContextCancellation(t *testing.T) {
 	}
 }
 
+// ============================================================================
+// 执行策略测试
+// ============================================================================
+
+func TestJobRetryWithBackoff(t *testing.T) {
+	var calls int32
+	job := NewJob("flaky", func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, errors.New("temporary failure")
+		}
+		return "ok", nil
+	}).WithRetry(3, time.Millisecond)
+
+	result, err := NewWorkflow("retry-test").AddJob(job, Immediately()).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected retries to recover, got: %v", err)
+	}
+
+	if result.FinalResult != "ok" {
+		t.Errorf("Expected 'ok', got: %v", result.FinalResult)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got: %d", calls)
+	}
+	if result.JobTrace[0].Attempts != 3 {
+		t.Errorf("Expected 3 attempts in trace, got: %d", result.JobTrace[0].Attempts)
+	}
+	if result.Metrics.Retries != 2 {
+		t.Errorf("Expected 2 retries in metrics, got: %d", result.Metrics.Retries)
+	}
+}
+
+func TestJobRetryExhausted(t *testing.T) {
+	var calls int32
+	job := NewJob("broken", func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("permanent failure")
+	}).WithRetry(2, time.Millisecond)
+
+	result, err := NewWorkflow("retry-exhausted").AddJob(job, Immediately()).Run(context.Background())
+	if err == nil {
+		t.Fatal("Expected error after retries are exhausted")
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got: %d", calls)
+	}
+	if _, failed := result.FailedJobs["broken"]; !failed {
+		t.Error("Expected failed job to be recorded in FailedJobs")
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	job := NewJob("slow", func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-time.After(time.Second):
+			return "too late", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}).WithTimeout(20 * time.Millisecond)
+
+	start := time.Now()
+	_, err := NewWorkflow("timeout-test").AddJob(job, Immediately()).Run(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected job to time out quickly, took: %v", elapsed)
+	}
+}
+
+func TestContinueOnError(t *testing.T) {
+	failure := errors.New("fetch failed")
+
+	fetch := NewJob("fetch", func(ctx context.Context) (interface{}, error) {
+		return nil, failure
+	}).WithErrorPolicy(ContinueOnError)
+	other := NewJob("other", func(ctx context.Context) (interface{}, error) {
+		return "other done", nil
+	})
+	process := NewJob("process", func(ctx context.Context) (interface{}, error) {
+		return "processed", nil
+	})
+
+	var upstreamFailed atomic.Bool
+	cleanup := NewJob("cleanup", func(ctx context.Context) (interface{}, error) {
+		return "cleaned", nil
+	})
+	checkFailure := triggerFunc(func(completed JobResults) bool {
+		if !AfterCompletion("fetch").Ready(completed) {
+			return false
+		}
+		if f, ok := completed.Failure("fetch"); ok && errors.Is(f, failure) {
+			upstreamFailed.Store(true)
+		}
+		return true
+	})
+
+	result, err := NewWorkflow("continue-test").
+		AddJob(fetch, Immediately()).
+		AddJob(other, Immediately()).
+		AddJob(process, After("fetch")).
+		AddJob(cleanup, checkFailure).
+		Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected workflow to continue, got: %v", err)
+	}
+
+	if !errors.Is(result.FailedJobs["fetch"], failure) {
+		t.Errorf("Expected fetch failure to be recorded, got: %v", result.FailedJobs)
+	}
+	if _, ran := result.AllResults["process"]; ran {
+		t.Error("After trigger should not fire for a failed job")
+	}
+	if result.AllResults["cleanup"] != "cleaned" {
+		t.Errorf("AfterCompletion trigger should fire for a failed job, got: %v", result.AllResults["cleanup"])
+	}
+	if !upstreamFailed.Load() {
+		t.Error("Expected trigger to see the failure in JobResults")
+	}
+	if len(result.SucceededJobs) != 2 {
+		t.Errorf("Expected 2 succeeded jobs, got: %v", result.SucceededJobs)
+	}
+	if result.FinalResult != "cleaned" {
+		t.Errorf("Expected final result from last succeeded job, got: %v", result.FinalResult)
+	}
+}
+
+func TestWithPolicyWrapsStatefulJob(t *testing.T) {
+	var calls int32
+	job := WithPolicy(NewStatefulJob("stateful", func(ctx context.Context, state FlowState) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil, errors.New("first attempt fails")
+		}
+		state.Set("seen", true)
+		return "stored", nil
+	}), JobPolicy{MaxRetries: 1})
+
+	result, err := NewWorkflow("wrapped").AddJob(job, Immediately()).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if seen, _ := result.FinalState.Get("seen"); seen != true {
+		t.Error("Expected wrapped stateful job to receive the flow state")
+	}
+}
+
+// ============================================================================
+// 条件分支测试
+// ============================================================================
+
+func TestWhenStateBranching(t *testing.T) {
+	score := NewStatefulJob("score", func(ctx context.Context, state FlowState) (interface{}, error) {
+		state.Set("quality", 0.9)
+		return "scored", nil
+	})
+	publish := NewJob("publish", func(ctx context.Context) (interface{}, error) {
+		return "published", nil
+	})
+	revise := NewJob("revise", func(ctx context.Context) (interface{}, error) {
+		return "revised", nil
+	})
+	archive := NewJob("archive", func(ctx context.Context) (interface{}, error) {
+		return "archived", nil
+	})
+
+	highQuality := func(s FlowState) bool {
+		v, _ := s.GetFloat64("quality")
+		return v > 0.8
+	}
+
+	result, err := NewWorkflow("branching").
+		AddJob(score, Immediately()).
+		AddJob(publish, AllOf(After("score"), WhenState(highQuality))).
+		AddJob(revise, AllOf(After("score"), WhenState(func(s FlowState) bool { return !highQuality(s) }))).
+		AddJob(archive, After("revise")).
+		Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.AllResults["publish"] != "published" {
+		t.Errorf("Expected publish branch to run, got: %v", result.AllResults)
+	}
+	if _, ran := result.AllResults["revise"]; ran {
+		t.Error("Expected revise branch to be skipped")
+	}
+	if len(result.SkippedJobs) != 2 {
+		t.Errorf("Expected revise and archive to be skipped, got: %v", result.SkippedJobs)
+	}
+}
+
+func TestWhenStateReevaluatedEachCycle(t *testing.T) {
+	first := NewJob("first", func(ctx context.Context) (interface{}, error) {
+		return "first", nil
+	})
+	flag := NewStatefulJob("flag", func(ctx context.Context, state FlowState) (interface{}, error) {
+		state.Set("ready", true)
+		return "flagged", nil
+	})
+	waiter := NewJob("waiter", func(ctx context.Context) (interface{}, error) {
+		return "done waiting", nil
+	})
+
+	result, err := NewWorkflow("reevaluate").
+		AddJob(first, Immediately()).
+		AddJob(flag, After("first")).
+		AddJob(waiter, WhenState(func(s FlowState) bool {
+			ready, _ := s.GetBool("ready")
+			return ready
+		})).
+		Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.AllResults["waiter"] != "done waiting" {
+		t.Errorf("Expected waiter to run once state changed, got: %v", result.AllResults)
+	}
+	if result.Metrics.ParallelBatches != 3 {
+		t.Errorf("Expected 3 batches, got: %d", result.Metrics.ParallelBatches)
+	}
+}
+
+func TestOnFailureRoutesToRemediation(t *testing.T) {
+	deploy := NewJob("deploy", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("deploy failed")
+	})
+	notify := NewJob("notify", func(ctx context.Context) (interface{}, error) {
+		return "notified", nil
+	})
+	rollback := NewJob("rollback", func(ctx context.Context) (interface{}, error) {
+		return "rolled back", nil
+	})
+
+	result, err := NewWorkflow("remediation").
+		AddJob(deploy, Immediately()).
+		AddJob(notify, OnSuccess("deploy")).
+		AddJob(rollback, OnFailure("deploy")).
+		Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected failure to be handled, got: %v", err)
+	}
+
+	if result.FailedJobs["deploy"] == nil {
+		t.Error("Expected deploy failure to be recorded")
+	}
+	if result.AllResults["rollback"] != "rolled back" {
+		t.Errorf("Expected rollback to run, got: %v", result.AllResults["rollback"])
+	}
+	if _, ran := result.AllResults["notify"]; ran {
+		t.Error("Expected OnSuccess trigger not to fire")
+	}
+}
+
+func TestDeadlockDetection(t *testing.T) {
+	noop := func(ctx context.Context) (interface{}, error) { return nil, nil }
+
+	result, err := NewWorkflow("cycle").
+		AddJob(NewJob("start", noop), Immediately()).
+		AddJob(NewJob("a", noop), AllOf(After("start"), After("b"))).
+		AddJob(NewJob("b", noop), After("a")).
+		AddJob(NewJob("orphan", noop), After("missing")).
+		Run(context.Background())
+
+	if !errors.Is(err, ErrWorkflowDeadlock) {
+		t.Fatalf("Expected deadlock error, got: %v", err)
+	}
+
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) {
+		t.Fatalf("Expected DeadlockError, got: %T", err)
+	}
+	if fmt.Sprint(deadlock.Jobs) != "[a b orphan]" {
+		t.Errorf("Expected deadlocked jobs [a b orphan], got: %v", deadlock.Jobs)
+	}
+	if len(result.JobTrace) != 1 || result.JobTrace[0].JobID != "start" {
+		t.Errorf("Expected start job to run before the deadlock was detected, got: %v", result.JobTrace)
+	}
+}
+
+func TestStrictModeDeadlockDiagnostics(t *testing.T) {
+	noop := func(ctx context.Context) (interface{}, error) { return nil, nil }
+	newWorkflow := func(opts ...WorkflowOption) Workflow {
+		return NewWorkflow("strict", opts...).
+			AddJob(NewJob("fetch", func(ctx context.Context) (interface{}, error) {
+				return nil, errors.New("fetch failed")
+			}), Immediately()).
+			AddJob(NewJob("fallback", noop), OnFailure("fetch")).
+			AddJob(NewJob("report", noop), After("fetch"))
+	}
+
+	// 非严格模式下依赖失败作业的作业是未选中的分支
+	result, err := newWorkflow().Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected non-strict run to succeed, got: %v", err)
+	}
+	if fmt.Sprint(result.SkippedJobs) != "[report]" {
+		t.Errorf("Expected report to be skipped, got: %v", result.SkippedJobs)
+	}
+
+	_, err = newWorkflow(WithStrictMode()).
+		AddJob(NewJob("publish", noop), AllOf(After("report"), After("fetch-data"))).
+		Run(context.Background())
+
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) {
+		t.Fatalf("Expected DeadlockError in strict mode, got: %v", err)
+	}
+	if fmt.Sprint(deadlock.Jobs) != "[publish report]" {
+		t.Errorf("Expected stuck jobs [publish report], got: %v", deadlock.Jobs)
+	}
+	if fmt.Sprint(deadlock.Incomplete["report"]) != "[fetch]" {
+		t.Errorf("Expected fetch to be incomplete for report, got: %v", deadlock.Incomplete)
+	}
+	if fmt.Sprint(deadlock.Unknown["publish"]) != "[fetch-data]" || fmt.Sprint(deadlock.Incomplete["publish"]) != "[report]" {
+		t.Errorf("Expected unknown fetch-data and incomplete report for publish, got: %v %v", deadlock.Unknown, deadlock.Incomplete)
+	}
+	if !strings.Contains(err.Error(), "unknown: fetch-data") {
+		t.Errorf("Expected unknown references in the error message, got: %v", err)
+	}
+}
+
+func TestWorkflowValidate(t *testing.T) {
+	var runs int32
+	job := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&runs, 1)
+		return nil, nil
+	}
+
+	workflow := NewWorkflow("invalid").
+		AddJob(NewJob("extract", job), Immediately()).
+		AddJob(NewJob("extract", job), Immediately()).
+		AddJob(NewJob("load", job), After("transfrom"))
+
+	err := workflow.Validate()
+	if !errors.Is(err, ErrDuplicateJob) || !errors.Is(err, ErrUnknownJobReference) {
+		t.Fatalf("Expected duplicate and unknown reference errors, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "load (after:transfrom) references transfrom") {
+		t.Errorf("Expected the unknown reference to be described, got: %v", err)
+	}
+
+	if _, err := workflow.Run(context.Background()); !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("Expected Run to reject duplicate jobs, got: %v", err)
+	}
+	if atomic.LoadInt32(&runs) != 0 {
+		t.Errorf("Expected no job to run, got %d runs", runs)
+	}
+	var extracts int
+	for _, node := range workflow.Describe().Jobs {
+		if node.ID == "extract" {
+			extracts++
+		}
+	}
+	if extracts != 1 {
+		t.Errorf("Expected the duplicate job not to be added, got %d extract jobs", extracts)
+	}
+
+	valid := NewWorkflow("valid").
+		AddJob(NewJob("extract", job), Immediately()).
+		AddJob(NewJob("load", job), After("extract"))
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid workflow, got: %v", err)
+	}
+}
+
+func TestWithMaxCycles(t *testing.T) {
+	noop := func(ctx context.Context) (interface{}, error) { return nil, nil }
+	newChain := func(opts ...WorkflowOption) Workflow {
+		return NewWorkflow("chain", opts...).
+			AddJob(NewJob("a", noop), Immediately()).
+			AddJob(NewJob("b", noop), After("a")).
+			AddJob(NewJob("c", noop), After("b"))
+	}
+
+	result, err := newChain(WithMaxCycles(2)).Run(context.Background())
+	if !errors.Is(err, ErrMaxCyclesExceeded) {
+		t.Fatalf("Expected max cycles error, got: %v", err)
+	}
+	if len(result.JobTrace) != 2 {
+		t.Errorf("Expected 2 jobs to run within 2 cycles, got: %v", result.JobTrace)
+	}
+
+	if _, err := newChain(WithMaxCycles(3)).Run(context.Background()); err != nil {
+		t.Errorf("Expected chain to finish within 3 cycles, got: %v", err)
+	}
+}
+
+func TestWithOverallTimeout(t *testing.T) {
+	result, err := NewWorkflow("slow", WithOverallTimeout(20*time.Millisecond)).
+		AddJob(NewJob("slow", func(ctx context.Context) (interface{}, error) {
+			time.Sleep(50 * time.Millisecond) // 不响应ctx的作业
+			return "done", nil
+		}), Immediately()).
+		AddJob(NewJob("next", func(ctx context.Context) (interface{}, error) {
+			return "next", nil
+		}), After("slow")).
+		Run(context.Background())
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got: %v", err)
+	}
+	if _, ran := result.AllResults["next"]; ran {
+		t.Error("Expected no job to be scheduled after the timeout")
+	}
+}
+
+// triggerFunc 用函数实现的触发器
+type triggerFunc func(completed JobResults) bool
+
+func (f triggerFunc) Ready(completed JobResults) bool { return f(completed) }
+func (f triggerFunc) String() string                  { return "func" }
+
 // ============================================================================
 // 性能基准测试
 // ============================================================================
@@ -645,18 +1027,3 @@ func TestMixedJobTypes(t *testing.T) {
 		t.Errorf("Expected stateful state data, got %s, exists: %v", data, exists)
 	}
 }
-
-// ============================================================================
-// 工具函数
-// ============================================================================
-
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (len(substr) == 0 || func() bool {
-		for i := 0; i <= len(s)-len(substr); i++ {
-			if s[i:i+len(substr)] == substr {
-				return true
-			}
-		}
-		return false
-	}())
-}
@@ -0,0 +1,42 @@
+// Package llm 对外公开的LLM API
+// greensoulai的实现位于internal/llm，模块外的代码（如create生成的项目）无法直接导入，
+// 这里的类型都是内部类型的别名，可以直接传给pkg/agent和pkg/crew
+package llm
+
+import (
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// LLM相关类型
+type (
+	LLM           = llm.LLM
+	Message       = llm.Message
+	Role          = llm.Role
+	Response      = llm.Response
+	CallOptions   = llm.CallOptions
+	BaseLLMOption = llm.BaseLLMOption
+	OpenAILLM     = llm.OpenAILLM
+)
+
+// 消息角色
+const (
+	RoleSystem    = llm.RoleSystem
+	RoleUser      = llm.RoleUser
+	RoleAssistant = llm.RoleAssistant
+	RoleTool      = llm.RoleTool
+)
+
+// NewOpenAILLM 创建OpenAI（及兼容接口）的LLM
+func NewOpenAILLM(model string, options ...BaseLLMOption) *OpenAILLM {
+	return llm.NewOpenAILLM(model, options...)
+}
+
+// WithAPIKey 设置API密钥
+func WithAPIKey(apiKey string) BaseLLMOption {
+	return llm.WithAPIKey(apiKey)
+}
+
+// WithBaseURL 设置API地址，用于兼容OpenAI接口的服务
+func WithBaseURL(baseURL string) BaseLLMOption {
+	return llm.WithBaseURL(baseURL)
+}
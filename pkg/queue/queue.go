@@ -0,0 +1,132 @@
+// Package queue 持久化的kickoff任务队列，用于在多台机器上以worker方式执行Crew
+//
+// 队列提供至少一次（at-least-once）的投递语义：Dequeue取出的请求在VisibilityTimeout内对其他worker不可见，
+// worker处理完成后Ack删除；处理失败时Nack，请求在RetryDelay后重新投递；worker崩溃时请求在可见性超时后重新投递。
+// 重试MaxRetries次后仍失败的请求转入死信，不再投递，可以通过DeadLetters查看。
+//
+// 内置SQLite实现（NewSQLiteQueue），适合单机或共享文件系统上的多进程；
+// 以redis构建标签编译时提供Redis实现（NewRedisQueue，依赖github.com/redis/go-redis/v9），适合多台机器。
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// KickoffRequest 一次排队的kickoff
+type KickoffRequest struct {
+	ID         string                 `json:"id"`                   // 为空时Enqueue生成
+	CrewName   string                 `json:"crew_name"`            // worker据此选择要执行的Crew
+	Inputs     map[string]interface{} `json:"inputs,omitempty"`     // Kickoff的输入
+	SessionID  string                 `json:"session_id,omitempty"` // 会话ID，为空时不读取也不记录会话
+	EnqueuedAt time.Time              `json:"enqueued_at"`
+}
+
+// Delivery Dequeue取出的一次投递，Ack或Nack时原样传回
+type Delivery struct {
+	Request  KickoffRequest
+	Attempts int    // 第几次投递，从1开始
+	Receipt  string // 本次投递的凭据，可见性超时后重新投递的请求凭据不同，旧凭据的Ack返回ErrLeaseExpired
+}
+
+// DeadLetter 超过最大重试次数的请求
+type DeadLetter struct {
+	Request   KickoffRequest `json:"request"`
+	Attempts  int            `json:"attempts"`
+	LastError string         `json:"last_error,omitempty"`
+	DeadAt    time.Time      `json:"dead_at"`
+}
+
+// Queue kickoff请求队列
+type Queue interface {
+	// Enqueue 加入请求，返回请求ID
+	Enqueue(ctx context.Context, request KickoffRequest) (string, error)
+	// Dequeue 阻塞直到取出一个请求、ctx结束或队列关闭
+	Dequeue(ctx context.Context) (*Delivery, error)
+	// Ack 确认请求处理完成，从队列中删除
+	Ack(ctx context.Context, delivery *Delivery) error
+	// Nack 报告处理失败，未超过最大重试次数时重新投递，否则转入死信
+	Nack(ctx context.Context, delivery *Delivery, cause error) error
+	// DeadLetters 返回死信中的请求
+	DeadLetters(ctx context.Context) ([]*DeadLetter, error)
+	Close() error
+}
+
+// ResultStatus 排队的kickoff的状态
+type ResultStatus string
+
+const (
+	ResultQueued    ResultStatus = "queued"
+	ResultRunning   ResultStatus = "running"
+	ResultCompleted ResultStatus = "completed"
+	ResultFailed    ResultStatus = "failed"
+)
+
+// Result 排队的kickoff的状态和输出
+type Result struct {
+	ID         string          `json:"id"`
+	Status     ResultStatus    `json:"status"`
+	Output     json.RawMessage `json:"output,omitempty"` // CrewOutput.ToJSON的结果
+	Error      string          `json:"error,omitempty"`
+	Attempts   int             `json:"attempts"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// ResultStore 保存排队的kickoff的结果，同一个ID的结果整体覆盖
+type ResultStore interface {
+	SaveResult(ctx context.Context, result *Result) error
+	// GetResult 返回请求的结果，不存在时返回false
+	GetResult(ctx context.Context, id string) (*Result, bool, error)
+}
+
+// Options 队列的投递配置
+type Options struct {
+	VisibilityTimeout time.Duration // 取出的请求多久未Ack后重新投递，应大于worker的单次执行超时
+	MaxRetries        int           // 首次投递之外的最大重试次数，超过后转入死信
+	RetryDelay        time.Duration // Nack后多久重新投递
+	PollInterval      time.Duration // 队列为空时Dequeue轮询的间隔
+}
+
+// DefaultOptions 返回默认配置
+func DefaultOptions() *Options {
+	return &Options{
+		VisibilityTimeout: 35 * time.Minute,
+		MaxRetries:        3,
+		RetryDelay:        5 * time.Second,
+		PollInterval:      time.Second,
+	}
+}
+
+// withDefaults 用默认值补全未设置的字段
+func (o *Options) withDefaults() Options {
+	defaults := DefaultOptions()
+	if o == nil {
+		return *defaults
+	}
+	options := *o
+	if options.VisibilityTimeout <= 0 {
+		options.VisibilityTimeout = defaults.VisibilityTimeout
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.RetryDelay < 0 {
+		options.RetryDelay = 0
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaults.PollInterval
+	}
+	return options
+}
+
+var (
+	// ErrQueueClosed 队列已关闭
+	ErrQueueClosed = errors.New("queue is closed")
+	// ErrLeaseExpired 投递的可见性已超时，请求可能已经投递给其他worker
+	ErrLeaseExpired = errors.New("delivery lease expired")
+	// ErrDuplicateRequest 队列中已有相同ID的请求
+	ErrDuplicateRequest = errors.New("duplicate kickoff request")
+)
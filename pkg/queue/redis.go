@@ -0,0 +1,334 @@
+//go:build redis
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis中的键，均以前缀开头：
+//
+//	{prefix}:pending    等待投递的请求ID（list）
+//	{prefix}:scheduled  已投递未确认或等待重试的请求ID，分数为重新可见的时间（zset，毫秒）
+//	{prefix}:jobs       请求ID到KickoffRequest的JSON（hash）
+//	{prefix}:attempts   请求ID到投递次数（hash）
+//	{prefix}:receipts   请求ID到当前投递的凭据（hash）
+//	{prefix}:dead       请求ID到死信信息（hash）
+//	{prefix}:results    请求ID到Result的JSON（hash）
+const (
+	redisPending   = "pending"
+	redisScheduled = "scheduled"
+	redisJobs      = "jobs"
+	redisAttempts  = "attempts"
+	redisReceipts  = "receipts"
+	redisDead      = "dead"
+	redisResults   = "results"
+)
+
+// DefaultRedisPrefix 默认的键前缀
+const DefaultRedisPrefix = "greensoulai:queue"
+
+var redisEnqueueScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('LPUSH', KEYS[2], ARGV[1])
+return 1
+`)
+
+// 先把到期的请求移回pending，再取出一个；投递次数超过上限的请求转入死信
+var redisDequeueScript = redis.NewScript(`
+local pending, scheduled, jobs, attempts, receipts, dead = KEYS[1], KEYS[2], KEYS[3], KEYS[4], KEYS[5], KEYS[6]
+local now, visibility, receipt, maxRetries = tonumber(ARGV[1]), tonumber(ARGV[2]), ARGV[3], tonumber(ARGV[4])
+
+for _, id in ipairs(redis.call('ZRANGEBYSCORE', scheduled, '-inf', now, 'LIMIT', 0, 100)) do
+	redis.call('ZREM', scheduled, id)
+	redis.call('HDEL', receipts, id)
+	redis.call('LPUSH', pending, id)
+end
+
+while true do
+	local id = redis.call('RPOP', pending)
+	if not id then
+		return false
+	end
+	local job = redis.call('HGET', jobs, id)
+	if job then
+		local count = tonumber(redis.call('HGET', attempts, id) or '0')
+		if count > maxRetries then
+			redis.call('HSET', dead, id, cjson.encode({attempts = count, last_error = ARGV[5], dead_at = now}))
+		else
+			count = redis.call('HINCRBY', attempts, id, 1)
+			redis.call('HSET', receipts, id, receipt)
+			redis.call('ZADD', scheduled, now + visibility, id)
+			return {id, count, job}
+		end
+	end
+end
+`)
+
+var redisAckScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 1
+`)
+
+var redisNackScript = redis.NewScript(`
+local receipts, scheduled, attempts, dead = KEYS[1], KEYS[2], KEYS[3], KEYS[4]
+local id, receipt, now, delay, maxRetries = ARGV[1], ARGV[2], tonumber(ARGV[3]), tonumber(ARGV[4]), tonumber(ARGV[5])
+if redis.call('HGET', receipts, id) ~= receipt then
+	return 0
+end
+redis.call('HDEL', receipts, id)
+local count = tonumber(redis.call('HGET', attempts, id) or '0')
+if count > maxRetries then
+	redis.call('ZREM', scheduled, id)
+	redis.call('HSET', dead, id, cjson.encode({attempts = count, last_error = ARGV[6], dead_at = now}))
+else
+	redis.call('ZADD', scheduled, now + delay, id)
+end
+return 1
+`)
+
+// RedisQueue 基于Redis的队列和结果存储，多台机器上的worker共享同一个Redis
+// 出队、确认和重新投递都在Lua脚本中原子完成
+type RedisQueue struct {
+	client  redis.UniversalClient
+	prefix  string
+	options Options
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var (
+	_ Queue       = (*RedisQueue)(nil)
+	_ ResultStore = (*RedisQueue)(nil)
+)
+
+// NewRedisQueue 在client上创建队列，prefix为空时使用DefaultRedisPrefix，options为nil时使用DefaultOptions
+// client由调用方管理，Close不会关闭它
+func NewRedisQueue(client redis.UniversalClient, prefix string, options *Options) (*RedisQueue, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &RedisQueue{client: client, prefix: prefix, options: options.withDefaults(), closed: make(chan struct{})}, nil
+}
+
+func (q *RedisQueue) key(name string) string {
+	return q.prefix + ":" + name
+}
+
+// Enqueue 实现Queue接口
+func (q *RedisQueue) Enqueue(ctx context.Context, request KickoffRequest) (string, error) {
+	if request.ID == "" {
+		request.ID = uuid.New().String()
+	}
+	if request.EnqueuedAt.IsZero() {
+		request.EnqueuedAt = time.Now()
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode kickoff request: %w", err)
+	}
+
+	added, err := redisEnqueueScript.Run(ctx, q.client,
+		[]string{q.key(redisJobs), q.key(redisPending)}, request.ID, string(data)).Int()
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue kickoff request: %w", err)
+	}
+	if added == 0 {
+		return "", fmt.Errorf("%w: %s", ErrDuplicateRequest, request.ID)
+	}
+	return request.ID, nil
+}
+
+// Dequeue 实现Queue接口，队列为空时每PollInterval轮询一次
+func (q *RedisQueue) Dequeue(ctx context.Context) (*Delivery, error) {
+	ticker := time.NewTicker(q.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.closed:
+			return nil, ErrQueueClosed
+		default:
+		}
+
+		delivery, err := q.tryDequeue(ctx)
+		if err != nil && q.isClosed() {
+			return nil, ErrQueueClosed
+		}
+		if err != nil || delivery != nil {
+			return delivery, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.closed:
+			return nil, ErrQueueClosed
+		case <-ticker.C:
+		}
+	}
+}
+
+// tryDequeue 取出一个可见的请求，没有时返回nil
+func (q *RedisQueue) tryDequeue(ctx context.Context) (*Delivery, error) {
+	receipt := uuid.New().String()
+	keys := []string{
+		q.key(redisPending), q.key(redisScheduled), q.key(redisJobs),
+		q.key(redisAttempts), q.key(redisReceipts), q.key(redisDead),
+	}
+	values, err := redisDequeueScript.Run(ctx, q.client, keys,
+		time.Now().UnixMilli(), q.options.VisibilityTimeout.Milliseconds(), receipt, q.options.MaxRetries, ErrLeaseExpired.Error(),
+	).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue kickoff request: %w", err)
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected dequeue reply: %v", values)
+	}
+
+	attempts, _ := values[1].(int64)
+	job, _ := values[2].(string)
+	delivery := &Delivery{Attempts: int(attempts), Receipt: receipt}
+	if err := json.Unmarshal([]byte(job), &delivery.Request); err != nil {
+		return nil, fmt.Errorf("failed to decode kickoff request %v: %w", values[0], err)
+	}
+	return delivery, nil
+}
+
+// Ack 实现Queue接口
+func (q *RedisQueue) Ack(ctx context.Context, delivery *Delivery) error {
+	keys := []string{q.key(redisReceipts), q.key(redisScheduled), q.key(redisJobs), q.key(redisAttempts)}
+	acked, err := redisAckScript.Run(ctx, q.client, keys, delivery.Request.ID, delivery.Receipt).Int()
+	if err != nil {
+		return fmt.Errorf("failed to ack kickoff request: %w", err)
+	}
+	if acked == 0 {
+		return fmt.Errorf("%w: %s", ErrLeaseExpired, delivery.Request.ID)
+	}
+	return nil
+}
+
+// Nack 实现Queue接口，重新投递的请求在RetryDelay后可见
+func (q *RedisQueue) Nack(ctx context.Context, delivery *Delivery, cause error) error {
+	lastError := ""
+	if cause != nil {
+		lastError = cause.Error()
+	}
+
+	keys := []string{q.key(redisReceipts), q.key(redisScheduled), q.key(redisAttempts), q.key(redisDead)}
+	nacked, err := redisNackScript.Run(ctx, q.client, keys,
+		delivery.Request.ID, delivery.Receipt, time.Now().UnixMilli(), q.options.RetryDelay.Milliseconds(), q.options.MaxRetries, lastError,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to nack kickoff request: %w", err)
+	}
+	if nacked == 0 {
+		return fmt.Errorf("%w: %s", ErrLeaseExpired, delivery.Request.ID)
+	}
+	return nil
+}
+
+// redisDeadLetter 死信hash中的值
+type redisDeadLetter struct {
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error"`
+	DeadAt    int64  `json:"dead_at"` // 毫秒
+}
+
+// DeadLetters 实现Queue接口，按进入死信的时间排序
+func (q *RedisQueue) DeadLetters(ctx context.Context) ([]*DeadLetter, error) {
+	entries, err := q.client.HGetAll(ctx, q.key(redisDead)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]*DeadLetter, 0, len(entries))
+	for id, value := range entries {
+		var dead redisDeadLetter
+		if err := json.Unmarshal([]byte(value), &dead); err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter %s: %w", id, err)
+		}
+		letter := &DeadLetter{Attempts: dead.Attempts, LastError: dead.LastError, DeadAt: time.UnixMilli(dead.DeadAt)}
+		job, err := q.client.HGet(ctx, q.key(redisJobs), id).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dead letter %s: %w", id, err)
+		}
+		if err := json.Unmarshal([]byte(job), &letter.Request); err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter %s: %w", id, err)
+		}
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].DeadAt.Before(letters[j].DeadAt) })
+	return letters, nil
+}
+
+// SaveResult 实现ResultStore接口
+func (q *RedisQueue) SaveResult(ctx context.Context, result *Result) error {
+	if result.UpdatedAt.IsZero() {
+		result.UpdatedAt = time.Now()
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode kickoff result: %w", err)
+	}
+	if err := q.client.HSet(ctx, q.key(redisResults), result.ID, string(data)).Err(); err != nil {
+		return fmt.Errorf("failed to save kickoff result: %w", err)
+	}
+	return nil
+}
+
+// GetResult 实现ResultStore接口
+func (q *RedisQueue) GetResult(ctx context.Context, id string) (*Result, bool, error) {
+	data, err := q.client.HGet(ctx, q.key(redisResults), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read kickoff result: %w", err)
+	}
+
+	var result Result
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil, false, fmt.Errorf("failed to decode kickoff result: %w", err)
+	}
+	return &result, true, nil
+}
+
+func (q *RedisQueue) isClosed() bool {
+	select {
+	case <-q.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 停止队列，阻塞中的Dequeue返回ErrQueueClosed
+func (q *RedisQueue) Close() error {
+	q.closeOnce.Do(func() { close(q.closed) })
+	return nil
+}
@@ -0,0 +1,318 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteBusyTimeoutMs 多个进程共享数据库文件时等待写锁的时间
+const sqliteBusyTimeoutMs = 5000
+
+// 请求在queue_jobs表中的状态
+const (
+	jobPending = "pending" // 等待投递或已投递未确认
+	jobDead    = "dead"    // 超过最大重试次数
+)
+
+// SQLiteQueue 基于SQLite的队列和结果存储
+// 同一个数据库文件可以被多个进程同时打开，出队在IMMEDIATE事务中完成，同一个请求不会同时投递给两个worker
+type SQLiteQueue struct {
+	dbPath  string
+	db      *sql.DB
+	options Options
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var (
+	_ Queue       = (*SQLiteQueue)(nil)
+	_ ResultStore = (*SQLiteQueue)(nil)
+)
+
+// NewSQLiteQueue 打开（必要时创建）队列数据库，options为nil时使用DefaultOptions
+func NewSQLiteQueue(dbPath string, options *Options) (*SQLiteQueue, error) {
+	if dbPath == "" {
+		return nil, fmt.Errorf("queue database path cannot be empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	dsn := fmt.Sprintf("%s?_busy_timeout=%d&_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate", dbPath, sqliteBusyTimeoutMs)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS queue_jobs (
+		id TEXT PRIMARY KEY,
+		request TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		receipt TEXT NOT NULL DEFAULT '',
+		visible_at INTEGER NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		enqueued_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_queue_jobs_visible ON queue_jobs(status, visible_at, enqueued_at);
+	CREATE TABLE IF NOT EXISTS queue_results (
+		id TEXT PRIMARY KEY,
+		result TEXT NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create queue tables: %w", err)
+	}
+
+	return &SQLiteQueue{dbPath: dbPath, db: db, options: options.withDefaults(), closed: make(chan struct{})}, nil
+}
+
+// DBPath 返回数据库文件路径
+func (q *SQLiteQueue) DBPath() string {
+	return q.dbPath
+}
+
+// Enqueue 实现Queue接口
+func (q *SQLiteQueue) Enqueue(ctx context.Context, request KickoffRequest) (string, error) {
+	if request.ID == "" {
+		request.ID = uuid.New().String()
+	}
+	if request.EnqueuedAt.IsZero() {
+		request.EnqueuedAt = time.Now()
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode kickoff request: %w", err)
+	}
+
+	now := time.Now().UnixNano()
+	result, err := q.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO queue_jobs (id, request, status, visible_at, enqueued_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		request.ID, string(data), jobPending, now, request.EnqueuedAt.UnixNano(), now,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue kickoff request: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return "", fmt.Errorf("%w: %s", ErrDuplicateRequest, request.ID)
+	}
+	return request.ID, nil
+}
+
+// Dequeue 实现Queue接口，队列为空时每PollInterval轮询一次
+func (q *SQLiteQueue) Dequeue(ctx context.Context) (*Delivery, error) {
+	ticker := time.NewTicker(q.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.closed:
+			return nil, ErrQueueClosed
+		default:
+		}
+
+		delivery, err := q.tryDequeue(ctx)
+		if err != nil && q.isClosed() {
+			return nil, ErrQueueClosed
+		}
+		if err != nil || delivery != nil {
+			return delivery, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.closed:
+			return nil, ErrQueueClosed
+		case <-ticker.C:
+		}
+	}
+}
+
+// tryDequeue 取出最早可见的请求，没有时返回nil
+// 已投递MaxRetries+1次仍未确认的请求（worker多次崩溃）转入死信
+func (q *SQLiteQueue) tryDequeue(ctx context.Context) (*Delivery, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin dequeue: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for {
+		var id, data string
+		var attempts int
+		err := tx.QueryRowContext(ctx,
+			`SELECT id, request, attempts FROM queue_jobs WHERE status = ? AND visible_at <= ? ORDER BY enqueued_at, id LIMIT 1`,
+			jobPending, now.UnixNano(),
+		).Scan(&id, &data, &attempts)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, tx.Commit()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to dequeue kickoff request: %w", err)
+		}
+
+		if attempts > q.options.MaxRetries {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE queue_jobs SET status = ?, receipt = '', last_error = ?, updated_at = ? WHERE id = ?`,
+				jobDead, ErrLeaseExpired.Error(), now.UnixNano(), id,
+			); err != nil {
+				return nil, fmt.Errorf("failed to dead-letter kickoff request: %w", err)
+			}
+			continue
+		}
+
+		delivery := &Delivery{Attempts: attempts + 1, Receipt: uuid.New().String()}
+		if err := json.Unmarshal([]byte(data), &delivery.Request); err != nil {
+			return nil, fmt.Errorf("failed to decode kickoff request %s: %w", id, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE queue_jobs SET attempts = ?, receipt = ?, visible_at = ?, updated_at = ? WHERE id = ?`,
+			delivery.Attempts, delivery.Receipt, now.Add(q.options.VisibilityTimeout).UnixNano(), now.UnixNano(), id,
+		); err != nil {
+			return nil, fmt.Errorf("failed to lease kickoff request: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit dequeue: %w", err)
+		}
+		return delivery, nil
+	}
+}
+
+// Ack 实现Queue接口
+func (q *SQLiteQueue) Ack(ctx context.Context, delivery *Delivery) error {
+	result, err := q.db.ExecContext(ctx,
+		`DELETE FROM queue_jobs WHERE id = ? AND receipt = ? AND status = ?`,
+		delivery.Request.ID, delivery.Receipt, jobPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to ack kickoff request: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: %s", ErrLeaseExpired, delivery.Request.ID)
+	}
+	return nil
+}
+
+// Nack 实现Queue接口，重新投递的请求在RetryDelay后可见
+func (q *SQLiteQueue) Nack(ctx context.Context, delivery *Delivery, cause error) error {
+	lastError := ""
+	if cause != nil {
+		lastError = cause.Error()
+	}
+
+	now := time.Now()
+	status, visibleAt := jobPending, now.Add(q.options.RetryDelay)
+	if delivery.Attempts > q.options.MaxRetries {
+		status = jobDead
+	}
+	result, err := q.db.ExecContext(ctx,
+		`UPDATE queue_jobs SET status = ?, receipt = '', visible_at = ?, last_error = ?, updated_at = ? WHERE id = ? AND receipt = ? AND status = ?`,
+		status, visibleAt.UnixNano(), lastError, now.UnixNano(), delivery.Request.ID, delivery.Receipt, jobPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to nack kickoff request: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: %s", ErrLeaseExpired, delivery.Request.ID)
+	}
+	return nil
+}
+
+// DeadLetters 实现Queue接口，按进入死信的时间排序
+func (q *SQLiteQueue) DeadLetters(ctx context.Context) ([]*DeadLetter, error) {
+	rows, err := q.db.QueryContext(ctx,
+		`SELECT request, attempts, last_error, updated_at FROM queue_jobs WHERE status = ? ORDER BY updated_at, id`, jobDead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []*DeadLetter
+	for rows.Next() {
+		var data string
+		var deadAt int64
+		letter := &DeadLetter{}
+		if err := rows.Scan(&data, &letter.Attempts, &letter.LastError, &deadAt); err != nil {
+			return nil, fmt.Errorf("failed to read dead letter: %w", err)
+		}
+		if err := json.Unmarshal([]byte(data), &letter.Request); err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter: %w", err)
+		}
+		letter.DeadAt = time.Unix(0, deadAt)
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
+
+// SaveResult 实现ResultStore接口
+func (q *SQLiteQueue) SaveResult(ctx context.Context, result *Result) error {
+	if result.UpdatedAt.IsZero() {
+		result.UpdatedAt = time.Now()
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode kickoff result: %w", err)
+	}
+	if _, err := q.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO queue_results (id, result, updated_at) VALUES (?, ?, ?)`,
+		result.ID, string(data), result.UpdatedAt.UnixNano(),
+	); err != nil {
+		return fmt.Errorf("failed to save kickoff result: %w", err)
+	}
+	return nil
+}
+
+// GetResult 实现ResultStore接口
+func (q *SQLiteQueue) GetResult(ctx context.Context, id string) (*Result, bool, error) {
+	var data string
+	err := q.db.QueryRowContext(ctx, `SELECT result FROM queue_results WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read kickoff result: %w", err)
+	}
+
+	var result Result
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil, false, fmt.Errorf("failed to decode kickoff result: %w", err)
+	}
+	return &result, true, nil
+}
+
+func (q *SQLiteQueue) isClosed() bool {
+	select {
+	case <-q.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 关闭队列，阻塞中的Dequeue返回ErrQueueClosed
+func (q *SQLiteQueue) Close() error {
+	var err error
+	q.closeOnce.Do(func() {
+		close(q.closed)
+		err = q.db.Close()
+	})
+	return err
+}
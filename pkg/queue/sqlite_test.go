@@ -0,0 +1,170 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestQueue(t *testing.T, options *Options) *SQLiteQueue {
+	t.Helper()
+	q, err := NewSQLiteQueue(filepath.Join(t.TempDir(), "queue.db"), options)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func dequeueNow(t *testing.T, q Queue) *Delivery {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	delivery, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("dequeue failed: %v", err)
+	}
+	return delivery
+}
+
+func TestSQLiteQueueEnqueueDequeueAck(t *testing.T) {
+	q := newTestQueue(t, &Options{PollInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	first, err := q.Enqueue(ctx, KickoffRequest{CrewName: "research", Inputs: map[string]interface{}{"topic": "Go"}, SessionID: "s1"})
+	if err != nil || first == "" {
+		t.Fatalf("enqueue failed: %q %v", first, err)
+	}
+	if _, err := q.Enqueue(ctx, KickoffRequest{ID: "second", CrewName: "research"}); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if _, err := q.Enqueue(ctx, KickoffRequest{ID: "second"}); !errors.Is(err, ErrDuplicateRequest) {
+		t.Errorf("expected duplicate request error, got %v", err)
+	}
+
+	delivery := dequeueNow(t, q)
+	if delivery.Request.ID != first || delivery.Attempts != 1 {
+		t.Fatalf("expected the first request on its first attempt, got %+v", delivery)
+	}
+	if delivery.Request.Inputs["topic"] != "Go" || delivery.Request.SessionID != "s1" || delivery.Request.CrewName != "research" {
+		t.Errorf("request fields were not preserved: %+v", delivery.Request)
+	}
+	if err := q.Ack(ctx, delivery); err != nil {
+		t.Fatalf("ack failed: %v", err)
+	}
+	if err := q.Ack(ctx, delivery); !errors.Is(err, ErrLeaseExpired) {
+		t.Errorf("expected a second ack to fail, got %v", err)
+	}
+
+	if delivery := dequeueNow(t, q); delivery.Request.ID != "second" {
+		t.Errorf("expected the second request, got %+v", delivery)
+	}
+
+	// 队列为空时阻塞到ctx结束
+	emptyCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := q.Dequeue(emptyCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected dequeue to wait for the context, got %v", err)
+	}
+}
+
+func TestSQLiteQueueRetriesThenDeadLetters(t *testing.T) {
+	q := newTestQueue(t, &Options{MaxRetries: 1, PollInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+	if _, err := q.Enqueue(ctx, KickoffRequest{ID: "flaky", CrewName: "research"}); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		delivery := dequeueNow(t, q)
+		if delivery.Attempts != attempt {
+			t.Fatalf("expected attempt %d, got %d", attempt, delivery.Attempts)
+		}
+		if err := q.Nack(ctx, delivery, errors.New("boom")); err != nil {
+			t.Fatalf("nack failed: %v", err)
+		}
+	}
+
+	emptyCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if delivery, err := q.Dequeue(emptyCtx); err == nil {
+		t.Fatalf("expected the request to be dead-lettered, got %+v", delivery)
+	}
+
+	letters, err := q.DeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("failed to list dead letters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Request.ID != "flaky" || letters[0].Attempts != 2 || letters[0].LastError != "boom" {
+		t.Errorf("unexpected dead letters: %+v", letters)
+	}
+}
+
+func TestSQLiteQueueVisibilityTimeout(t *testing.T) {
+	q := newTestQueue(t, &Options{VisibilityTimeout: 50 * time.Millisecond, MaxRetries: 1, PollInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+	if _, err := q.Enqueue(ctx, KickoffRequest{ID: "crashy"}); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	// worker取出后没有确认，可见性超时后重新投递，旧的凭据失效
+	lost := dequeueNow(t, q)
+	redelivered := dequeueNow(t, q)
+	if redelivered.Request.ID != "crashy" || redelivered.Attempts != 2 {
+		t.Fatalf("expected a redelivery after the visibility timeout, got %+v", redelivered)
+	}
+	if err := q.Ack(ctx, lost); !errors.Is(err, ErrLeaseExpired) {
+		t.Errorf("expected the stale receipt to be rejected, got %v", err)
+	}
+
+	// 超过最大重试次数仍未确认时转入死信
+	time.Sleep(60 * time.Millisecond)
+	emptyCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if delivery, err := q.Dequeue(emptyCtx); err == nil {
+		t.Fatalf("expected the request to be dead-lettered, got %+v", delivery)
+	}
+	if letters, _ := q.DeadLetters(ctx); len(letters) != 1 || letters[0].LastError != ErrLeaseExpired.Error() {
+		t.Errorf("unexpected dead letters: %+v", letters)
+	}
+}
+
+func TestSQLiteQueueResultsAndClose(t *testing.T) {
+	q := newTestQueue(t, nil)
+	ctx := context.Background()
+
+	if _, ok, err := q.GetResult(ctx, "missing"); ok || err != nil {
+		t.Errorf("expected no result, got %v %v", ok, err)
+	}
+	if err := q.SaveResult(ctx, &Result{ID: "k1", Status: ResultQueued}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := q.SaveResult(ctx, &Result{ID: "k1", Status: ResultCompleted, Output: []byte(`{"raw":"done"}`), Attempts: 1}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	result, ok, err := q.GetResult(ctx, "k1")
+	if err != nil || !ok {
+		t.Fatalf("expected a result, got %v %v", ok, err)
+	}
+	if result.Status != ResultCompleted || string(result.Output) != `{"raw":"done"}` || result.UpdatedAt.IsZero() {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.Dequeue(ctx)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrQueueClosed) {
+			t.Errorf("expected ErrQueueClosed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("dequeue did not return after close")
+	}
+}
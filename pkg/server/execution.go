@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/pkg/queue"
+)
+
+// ExecutionStatus 一次kickoff的执行状态
+type ExecutionStatus string
+
+const (
+	StatusQueued    ExecutionStatus = "queued" // 已加入队列，等待worker执行
+	StatusRunning   ExecutionStatus = "running"
+	StatusCompleted ExecutionStatus = "completed"
+	StatusFailed    ExecutionStatus = "failed"
+)
+
+// streamEvent 已序列化的事件，Seq从1开始，作为SSE的事件ID
+type streamEvent struct {
+	Seq  int
+	Type string
+	Data []byte
+}
+
+// execution 一次kickoff的状态、已完成的任务输出和进度记录
+type execution struct {
+	id        string
+	createdAt time.Time
+
+	mu          sync.Mutex
+	status      ExecutionStatus
+	finishedAt  time.Time
+	taskOutputs []*agent.TaskOutput
+	output      *crew.CrewOutput
+	err         error
+	events      []streamEvent // 序列化后的crew.CrewProgress
+	changed     chan struct{} // 状态或事件变化时关闭并替换，用于唤醒等待的SSE连接
+	done        chan struct{} // 执行结束时关闭
+	crew        crew.Crew     // 执行kickoff的Crew副本，供GET /status查询
+}
+
+func newExecution(id string) *execution {
+	return &execution{
+		id:        id,
+		createdAt: time.Now(),
+		status:    StatusRunning,
+		changed:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// notifyLocked 唤醒等待变化的SSE连接，调用方需持有锁
+func (e *execution) notifyLocked() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// setCrew 记录执行kickoff的Crew副本
+func (e *execution) setCrew(c crew.Crew) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.crew = c
+}
+
+// crewStatus 返回执行中的kickoff的Crew状态，执行已结束时返回false
+func (e *execution) crewStatus() (crew.CrewStatus, bool) {
+	e.mu.Lock()
+	c, running := e.crew, e.status == StatusRunning
+	e.mu.Unlock()
+	if c == nil || !running {
+		return crew.CrewStatus{}, false
+	}
+	return c.Status(), true
+}
+
+// addTaskOutput 记录一个已完成任务的输出
+func (e *execution) addTaskOutput(output *agent.TaskOutput) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.taskOutputs = append(e.taskOutputs, output)
+}
+
+// addProgress 序列化并记录一条执行进度
+func (e *execution) addProgress(progress crew.CrewProgress) {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, streamEvent{Seq: len(e.events) + 1, Type: string(progress.Type), Data: data})
+	e.notifyLocked()
+}
+
+// finish 记录执行结果
+func (e *execution) finish(output *crew.CrewOutput, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.output = output
+	e.err = err
+	e.finishedAt = time.Now()
+	e.status = StatusCompleted
+	if err != nil {
+		e.status = StatusFailed
+	}
+	close(e.done)
+	e.notifyLocked()
+}
+
+// eventsSince 返回序号大于after的事件、下次变化时关闭的通道以及执行是否已结束
+func (e *execution) eventsSince(after int) ([]streamEvent, <-chan struct{}, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var pending []streamEvent
+	if after < len(e.events) {
+		pending = append(pending, e.events[after:]...)
+	}
+	return pending, e.changed, e.status != StatusRunning
+}
+
+// finishedBefore 报告执行是否在deadline之前结束
+func (e *execution) finishedBefore(deadline time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status != StatusRunning && e.finishedAt.Before(deadline)
+}
+
+// ExecutionResponse POST /kickoff和GET /kickoff/{id}返回的执行状态
+type ExecutionResponse struct {
+	ID          string          `json:"id"`
+	Status      ExecutionStatus `json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	TasksOutput json.RawMessage `json:"tasks_output"`     // 已完成任务的输出，执行中时为部分结果
+	Output      json.RawMessage `json:"output,omitempty"` // CrewOutput.ToJSON的结果，执行结束后才有
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts,omitempty"` // 排队的kickoff已执行的次数，失败后可能重试
+}
+
+// snapshot 返回执行的当前状态
+func (e *execution) snapshot() (*ExecutionResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	response := &ExecutionResponse{ID: e.id, Status: e.status, CreatedAt: e.createdAt}
+	if e.status != StatusRunning {
+		finishedAt := e.finishedAt
+		response.FinishedAt = &finishedAt
+	}
+	if e.err != nil {
+		response.Error = e.err.Error()
+	}
+
+	tasks, err := crew.TaskOutputsToJSON(e.taskOutputs)
+	if err != nil {
+		return nil, err
+	}
+	response.TasksOutput = tasks
+
+	if e.output != nil {
+		output, err := e.output.ToJSON()
+		if err != nil {
+			return nil, err
+		}
+		response.Output = output
+	}
+	return response, nil
+}
+
+// newQueuedResponse 把结果存储中排队的kickoff转为执行状态
+func newQueuedResponse(result *queue.Result) *ExecutionResponse {
+	response := &ExecutionResponse{
+		ID:          result.ID,
+		Status:      ExecutionStatus(result.Status),
+		CreatedAt:   result.EnqueuedAt,
+		TasksOutput: json.RawMessage("[]"),
+		Output:      result.Output,
+		Error:       result.Error,
+		Attempts:    result.Attempts,
+	}
+	if result.Status == queue.ResultCompleted || result.Status == queue.ResultFailed {
+		finishedAt := result.UpdatedAt
+		response.FinishedAt = &finishedAt
+	}
+
+	var output struct {
+		TasksOutput json.RawMessage `json:"tasks_output"`
+	}
+	if len(result.Output) > 0 && json.Unmarshal(result.Output, &output) == nil && len(output.TasksOutput) > 0 {
+		response.TasksOutput = output.TasksOutput
+	}
+	return response
+}
@@ -0,0 +1,633 @@
+// Package server 把配置好的Crew发布为HTTP服务
+//
+// 接口：
+//
+//	POST /kickoff              执行Crew，请求体为{"inputs": {...}, "session_id": "..."}；?async=true时立即返回执行ID
+//	GET  /kickoff/{id}         查询执行状态和已完成任务的输出
+//	GET  /kickoff/{id}/events  以SSE推送该执行的进度（crew.CrewProgress）
+//	GET  /status               本进程中执行中的kickoff的实时状态（crew.CrewStatus），用于诊断卡住的执行
+//	GET  /healthz              健康检查，不需要鉴权
+//
+// 每次kickoff都在Crew的副本上执行，并发请求互不影响；
+// 带session_id的kickoff共享Crew的会话记忆，可以引用同一会话中之前的回答。
+// 配置了ServerConfig.Queue时，异步kickoff加入队列由crew.Worker执行，查询时从结果存储读取状态和输出
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/queue"
+)
+
+// ServerConfig HTTP服务配置
+type ServerConfig struct {
+	Addr            string        `json:"addr"`
+	APIKeys         []string      `json:"-"`                // 允许的API Key，为空时不鉴权
+	MaxConcurrent   int           `json:"max_concurrent"`   // 同时执行的kickoff上限，超出时返回429，0表示不限
+	RequestTimeout  time.Duration `json:"request_timeout"`  // 单次kickoff的执行超时，0表示不限
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"` // 关闭时等待执行中的kickoff完成的时间
+	ExecutionTTL    time.Duration `json:"execution_ttl"`    // 已结束的执行保留多久以供查询
+	MaxBodyBytes    int64         `json:"max_body_bytes"`   // 请求体大小上限
+
+	Queue    queue.Queue       `json:"-"`         // 设置后?async=true的kickoff加入队列，由worker执行，不在本进程执行
+	CrewName string            `json:"crew_name"` // 加入队列的请求中的Crew名称，worker据此选择Crew
+	Results  queue.ResultStore `json:"-"`         // 查询排队的kickoff的结果，为nil时使用实现了ResultStore的Queue
+}
+
+// DefaultServerConfig 返回默认配置
+func DefaultServerConfig() *ServerConfig {
+	return &ServerConfig{
+		Addr:            ":8080",
+		MaxConcurrent:   4,
+		RequestTimeout:  30 * time.Minute,
+		ShutdownTimeout: time.Minute,
+		ExecutionTTL:    time.Hour,
+		MaxBodyBytes:    1 << 20,
+	}
+}
+
+// sseHeartbeatInterval SSE连接的心跳间隔，避免代理关闭空闲连接
+const sseHeartbeatInterval = 15 * time.Second
+
+// ErrServerClosed 服务正在关闭，不再接受新的kickoff
+var ErrServerClosed = errors.New("server is shutting down")
+
+// Server 把一个Crew发布为REST/SSE接口
+type Server struct {
+	crew   crew.Crew
+	config ServerConfig
+	logger logger.Logger
+
+	handler http.Handler
+	slots   chan struct{}     // 并发上限，MaxConcurrent为0时为nil
+	results queue.ResultStore // 排队的kickoff的结果，未配置队列时为nil
+
+	baseCtx    context.Context // 异步kickoff的父ctx，强制关闭时取消
+	cancelBase context.CancelFunc
+	closing    chan struct{} // 开始关闭时关闭，用于结束SSE连接
+
+	mu         sync.Mutex
+	executions map[string]*execution
+	draining   bool
+	inflight   sync.WaitGroup
+	httpServer *http.Server
+}
+
+// NewServer 创建服务，每次kickoff通过Crew副本的KickoffWithProgress执行，进度通过SSE推送
+func NewServer(c crew.Crew, config *ServerConfig, log logger.Logger) (*Server, error) {
+	if c == nil {
+		return nil, fmt.Errorf("crew cannot be nil")
+	}
+	if config == nil {
+		config = DefaultServerConfig()
+	}
+	if config.MaxConcurrent < 0 {
+		return nil, fmt.Errorf("max concurrent kickoffs cannot be negative")
+	}
+	if log == nil {
+		log = logger.NewConsoleLogger()
+	}
+
+	baseCtx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		crew:       c,
+		config:     *config,
+		logger:     log,
+		baseCtx:    baseCtx,
+		cancelBase: cancel,
+		closing:    make(chan struct{}),
+		executions: make(map[string]*execution),
+	}
+	if s.config.MaxBodyBytes <= 0 {
+		s.config.MaxBodyBytes = DefaultServerConfig().MaxBodyBytes
+	}
+	if config.MaxConcurrent > 0 {
+		s.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	if config.Queue != nil {
+		s.results = config.Results
+		if s.results == nil {
+			store, ok := config.Queue.(queue.ResultStore)
+			if !ok {
+				return nil, fmt.Errorf("a result store is required when the queue does not store results")
+			}
+			s.results = store
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.Handle("/kickoff", s.authenticate(http.HandlerFunc(s.handleKickoff)))
+	mux.Handle("/kickoff/", s.authenticate(http.HandlerFunc(s.handleExecution)))
+	mux.Handle("/status", s.authenticate(http.HandlerFunc(s.handleStatus)))
+	s.handler = mux
+	return s, nil
+}
+
+// Handler 返回服务的http.Handler，可以挂到已有的HTTP服务上
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// ListenAndServe 在config.Addr上监听，ctx结束时优雅关闭：
+// 不再接受新的kickoff，等待执行中的kickoff最多ShutdownTimeout，超时后取消它们
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:              s.config.Addr,
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.mu.Lock()
+	s.httpServer = httpServer
+	s.mu.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info("crew server listening", logger.Field{Key: "addr", Value: s.config.Addr})
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			s.Shutdown(context.Background())
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx := context.Background()
+	if s.config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.config.ShutdownTimeout)
+		defer cancel()
+	}
+	return s.Shutdown(shutdownCtx)
+}
+
+// Shutdown 优雅关闭服务
+// 新的kickoff返回503，SSE连接收到关闭通知后断开，然后等待执行中的kickoff完成；
+// ctx结束时取消仍在执行的kickoff并返回ctx的错误
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	alreadyDraining := s.draining
+	s.draining = true
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	if !alreadyDraining {
+		close(s.closing)
+		s.logger.Info("crew server shutting down, draining in-flight kickoffs")
+	}
+
+	var shutdownErr error
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			shutdownErr = err
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		s.logger.Warn("shutdown timeout reached, cancelling in-flight kickoffs")
+		s.cancelBase()
+		<-drained
+		if shutdownErr == nil {
+			shutdownErr = ctx.Err()
+		}
+	}
+
+	s.cancelBase()
+	return shutdownErr
+}
+
+// kickoffRequest POST /kickoff的请求体
+type kickoffRequest struct {
+	Inputs    map[string]interface{} `json:"inputs"`
+	SessionID string                 `json:"session_id,omitempty"` // 会话ID，为空时不读取也不记录会话
+}
+
+// errorResponse 错误响应
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleKickoff 处理POST /kickoff
+func (s *Server) handleKickoff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var request kickoffRequest
+	body := http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes)
+	if err := json.NewDecoder(body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	async := r.URL.Query().Get("async") == "true"
+	if async && s.config.Queue != nil {
+		s.enqueue(w, r, request)
+		return
+	}
+	parent := r.Context()
+	if async {
+		parent = s.baseCtx
+	}
+
+	exec, err := s.start(crew.WithSession(parent, request.SessionID), request.Inputs)
+	switch {
+	case errors.Is(err, ErrServerClosed):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, errTooManyKickoffs):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if async {
+		w.Header().Set("Location", "/kickoff/"+exec.id)
+		s.writeExecution(w, http.StatusAccepted, exec)
+		return
+	}
+
+	<-exec.done
+	status := http.StatusOK
+	if exec.err != nil {
+		status = http.StatusInternalServerError
+		if errors.Is(exec.err, context.DeadlineExceeded) {
+			status = http.StatusGatewayTimeout
+		}
+	}
+	s.writeExecution(w, status, exec)
+}
+
+// handleExecution 处理GET /kickoff/{id}和GET /kickoff/{id}/events
+func (s *Server) handleExecution(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/kickoff/"), "/")
+	exec := s.lookup(id)
+	if exec == nil {
+		s.handleQueuedExecution(w, r, id, rest)
+		return
+	}
+
+	switch rest {
+	case "":
+		s.writeExecution(w, http.StatusOK, exec)
+	case "events":
+		s.streamEvents(w, r, exec)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// StatusResponse GET /status的响应
+type StatusResponse struct {
+	Executions []ExecutionCrewStatus `json:"executions"` // 按开始时间排列
+}
+
+// ExecutionCrewStatus 一次执行中的kickoff及其Crew副本的状态
+type ExecutionCrewStatus struct {
+	ID        string          `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Crew      crew.CrewStatus `json:"crew"`
+}
+
+// handleStatus 处理GET /status，返回本进程中执行中的kickoff的状态，排队给worker的kickoff不在其中
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.mu.Lock()
+	running := make([]*execution, 0, len(s.executions))
+	for _, exec := range s.executions {
+		running = append(running, exec)
+	}
+	s.mu.Unlock()
+	sort.Slice(running, func(i, j int) bool { return running[i].createdAt.Before(running[j].createdAt) })
+
+	response := StatusResponse{Executions: make([]ExecutionCrewStatus, 0, len(running))}
+	for _, exec := range running {
+		if status, ok := exec.crewStatus(); ok {
+			response.Executions = append(response.Executions, ExecutionCrewStatus{ID: exec.id, CreatedAt: exec.createdAt, Crew: status})
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// enqueue 把异步kickoff加入队列，先记录queued状态再入队，避免覆盖worker写入的状态
+func (s *Server) enqueue(w http.ResponseWriter, r *http.Request, request kickoffRequest) {
+	s.mu.Lock()
+	draining := s.draining
+	s.mu.Unlock()
+	if draining {
+		writeError(w, http.StatusServiceUnavailable, ErrServerClosed.Error())
+		return
+	}
+
+	queued := queue.KickoffRequest{
+		ID:         uuid.New().String(),
+		CrewName:   s.config.CrewName,
+		Inputs:     request.Inputs,
+		SessionID:  request.SessionID,
+		EnqueuedAt: time.Now(),
+	}
+	result := &queue.Result{ID: queued.ID, Status: queue.ResultQueued, EnqueuedAt: queued.EnqueuedAt}
+	if err := s.results.SaveResult(r.Context(), result); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to record kickoff: %v", err))
+		return
+	}
+	if _, err := s.config.Queue.Enqueue(r.Context(), queued); err != nil {
+		result.Status, result.Error, result.UpdatedAt = queue.ResultFailed, err.Error(), time.Time{}
+		_ = s.results.SaveResult(context.WithoutCancel(r.Context()), result)
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to enqueue kickoff: %v", err))
+		return
+	}
+
+	s.logger.Info("kickoff queued",
+		logger.Field{Key: "kickoff_id", Value: queued.ID},
+		logger.Field{Key: "crew_name", Value: queued.CrewName},
+	)
+	w.Header().Set("Location", "/kickoff/"+queued.ID)
+	writeJSON(w, http.StatusAccepted, newQueuedResponse(result))
+}
+
+// handleQueuedExecution 查询排队的kickoff，不在本进程执行，没有进度事件
+func (s *Server) handleQueuedExecution(w http.ResponseWriter, r *http.Request, id, rest string) {
+	var result *queue.Result
+	if s.results != nil {
+		found, ok, err := s.results.GetResult(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to read kickoff %s: %v", id, err))
+			return
+		}
+		if ok {
+			result = found
+		}
+	}
+	if result == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("kickoff %s not found", id))
+		return
+	}
+
+	switch rest {
+	case "":
+		writeJSON(w, http.StatusOK, newQueuedResponse(result))
+	case "events":
+		writeError(w, http.StatusNotFound, fmt.Sprintf("progress events are not available for queued kickoff %s", id))
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+var errTooManyKickoffs = errors.New("too many concurrent kickoffs")
+
+// start 在Crew的副本上开始一次kickoff
+func (s *Server) start(parent context.Context, inputs map[string]interface{}) (*execution, error) {
+	s.mu.Lock()
+	if s.draining {
+		s.mu.Unlock()
+		return nil, ErrServerClosed
+	}
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			s.mu.Unlock()
+			return nil, errTooManyKickoffs
+		}
+	}
+	s.inflight.Add(1)
+	s.pruneLocked()
+	exec := newExecution(uuid.New().String())
+	s.executions[exec.id] = exec
+	s.mu.Unlock()
+
+	release := func() {
+		if s.slots != nil {
+			<-s.slots
+		}
+		s.inflight.Done()
+	}
+
+	clone, err := s.crew.Clone()
+	if err != nil {
+		release()
+		s.mu.Lock()
+		delete(s.executions, exec.id)
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to clone crew: %w", err)
+	}
+	exec.setCrew(clone)
+	_ = clone.AddAfterTaskHook(func(ctx context.Context, task agent.Task, output *agent.TaskOutput) (*agent.TaskOutput, error) {
+		exec.addTaskOutput(output)
+		return output, nil
+	})
+
+	// 同步kickoff的ctx来自请求，强制关闭时也要能取消
+	ctx, cancel := context.WithCancel(parent)
+	stopCancelOnShutdown := context.AfterFunc(s.baseCtx, cancel)
+	cancelTimeout := context.CancelFunc(func() {})
+	if s.config.RequestTimeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, s.config.RequestTimeout)
+	}
+	cleanup := func() {
+		cancelTimeout()
+		stopCancelOnShutdown()
+		cancel()
+		release()
+	}
+
+	progress, err := clone.KickoffWithProgress(ctx, inputs)
+	if err != nil {
+		cleanup()
+		s.mu.Lock()
+		delete(s.executions, exec.id)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	s.logger.Info("kickoff started", logger.Field{Key: "kickoff_id", Value: exec.id})
+	go func() {
+		defer cleanup()
+
+		var output *crew.CrewOutput
+		var err error
+		for p := range progress {
+			exec.addProgress(p)
+			if p.Type == crew.ProgressKickoffFinished {
+				output, err = p.Output, p.Err
+			}
+		}
+		exec.finish(output, err)
+
+		fields := []logger.Field{
+			{Key: "kickoff_id", Value: exec.id},
+			{Key: "duration", Value: time.Since(exec.createdAt)},
+		}
+		if err != nil {
+			s.logger.Error("kickoff failed", append(fields, logger.Field{Key: "error", Value: err})...)
+		} else {
+			s.logger.Info("kickoff completed", fields...)
+		}
+	}()
+	return exec, nil
+}
+
+// lookup 按ID查找执行
+func (s *Server) lookup(id string) *execution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.executions[id]
+}
+
+// pruneLocked 删除结束超过ExecutionTTL的执行，调用方需持有锁
+func (s *Server) pruneLocked() {
+	if s.config.ExecutionTTL <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-s.config.ExecutionTTL)
+	for id, exec := range s.executions {
+		if exec.finishedBefore(deadline) {
+			delete(s.executions, id)
+		}
+	}
+}
+
+// streamEvents 以SSE推送执行的进度
+// 先补发已记录的进度（支持Last-Event-ID续传），执行结束后发送done事件并关闭连接
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, exec *execution) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	next := 0
+	if lastID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil && lastID > 0 {
+		next = lastID
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		pending, changed, finished := exec.eventsSince(next)
+		for _, event := range pending {
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, event.Data)
+			next = event.Seq
+		}
+		if finished {
+			snapshot, err := exec.snapshot()
+			if err == nil {
+				data, _ := json.Marshal(map[string]interface{}{"id": snapshot.ID, "status": snapshot.Status, "error": snapshot.Error})
+				fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// writeExecution 写出执行状态
+func (s *Server) writeExecution(w http.ResponseWriter, status int, exec *execution) {
+	snapshot, err := exec.snapshot()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to export kickoff: %v", err))
+		return
+	}
+	writeJSON(w, status, snapshot)
+}
+
+// authenticate 校验Authorization: Bearer <key>或X-API-Key请求头，未配置API Key时不鉴权
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if len(s.config.APIKeys) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			key = strings.TrimSpace(bearer)
+		}
+
+		for _, allowed := range s.config.APIKeys {
+			if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(allowed)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "invalid or missing API key")
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,476 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ynl/greensoulai/internal/agent"
+	"github.com/ynl/greensoulai/internal/crew"
+	"github.com/ynl/greensoulai/internal/llm"
+	"github.com/ynl/greensoulai/pkg/events"
+	"github.com/ynl/greensoulai/pkg/logger"
+	"github.com/ynl/greensoulai/pkg/queue"
+)
+
+// gatedLLM 前freeCalls次调用立即返回，之后的调用等待gate关闭或ctx结束
+type gatedLLM struct {
+	mu        sync.Mutex
+	calls     int
+	freeCalls int
+	gate      chan struct{}
+}
+
+func newGatedLLM(freeCalls int) *gatedLLM {
+	return &gatedLLM{freeCalls: freeCalls, gate: make(chan struct{})}
+}
+
+func (m *gatedLLM) release() { close(m.gate) }
+
+func (m *gatedLLM) Call(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (*llm.Response, error) {
+	m.mu.Lock()
+	m.calls++
+	free := m.calls <= m.freeCalls
+	m.mu.Unlock()
+
+	if !free {
+		select {
+		case <-m.gate:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &llm.Response{
+		Content:      "answer",
+		Model:        "mock-model",
+		FinishReason: "stop",
+		Usage:        llm.Usage{PromptTokens: 5, CompletionTokens: 5, TotalTokens: 10},
+	}, nil
+}
+
+func (m *gatedLLM) CallStream(ctx context.Context, messages []llm.Message, options *llm.CallOptions) (<-chan llm.StreamResponse, error) {
+	return nil, llm.ErrStreamingNotSupported
+}
+func (m *gatedLLM) GetModel() string                     { return "mock-model" }
+func (m *gatedLLM) SupportsFunctionCalling() bool        { return false }
+func (m *gatedLLM) GetContextWindowSize() int            { return 4096 }
+func (m *gatedLLM) GetTokenizer() llm.Tokenizer          { return llm.HeuristicTokenizer{} }
+func (m *gatedLLM) SetEventBus(eventBus events.EventBus) {}
+func (m *gatedLLM) Close() error                         { return nil }
+
+// newTestServer 创建一个两任务Crew的服务
+func newTestServer(t *testing.T, model llm.LLM, configure func(*ServerConfig)) (*Server, *httptest.Server) {
+	t.Helper()
+
+	log := logger.NewTestLogger()
+	bus := events.NewEventBus(log)
+	c := crew.NewBaseCrew(crew.DefaultCrewConfig(), bus, log)
+
+	writer, err := agent.NewBaseAgent(agent.AgentConfig{
+		Role:      "Writer",
+		Goal:      "Write",
+		Backstory: "Writes",
+		LLM:       model,
+		EventBus:  bus,
+		Logger:    log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	_ = c.AddAgent(writer)
+	_ = c.AddTask(agent.NewTaskWithOptions("Draft about {topic}", "A draft", agent.WithName("draft"), agent.WithAssignedAgent(writer)))
+	_ = c.AddTask(agent.NewTaskWithOptions("Polish", "An article", agent.WithName("polish"), agent.WithAssignedAgent(writer)))
+
+	config := DefaultServerConfig()
+	if configure != nil {
+		configure(config)
+	}
+	srv, err := NewServer(c, config, log)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	httpServer := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		httpServer.Close()
+		_ = srv.Shutdown(context.Background())
+	})
+	return srv, httpServer
+}
+
+func doRequest(t *testing.T, method, url, body string, headers map[string]string) (*http.Response, *ExecutionResponse) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var execution ExecutionResponse
+	_ = json.NewDecoder(resp.Body).Decode(&execution)
+	return resp, &execution
+}
+
+func taskCount(t *testing.T, execution *ExecutionResponse) int {
+	t.Helper()
+	var tasks []map[string]interface{}
+	if err := json.Unmarshal(execution.TasksOutput, &tasks); err != nil {
+		t.Fatalf("invalid tasks_output: %v", err)
+	}
+	return len(tasks)
+}
+
+func TestKickoffSync(t *testing.T) {
+	model := newGatedLLM(100)
+	_, httpServer := newTestServer(t, model, nil)
+
+	resp, execution := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff", `{"inputs": {"topic": "Go"}}`, nil)
+	if resp.StatusCode != http.StatusOK || execution.Status != StatusCompleted {
+		t.Fatalf("expected a completed kickoff, got %d %+v", resp.StatusCode, execution)
+	}
+	var output struct {
+		Raw     string `json:"raw"`
+		Success bool   `json:"success"`
+	}
+	if err := json.Unmarshal(execution.Output, &output); err != nil || output.Raw != "answer" || !output.Success {
+		t.Errorf("unexpected output %s: %v", execution.Output, err)
+	}
+	if taskCount(t, execution) != 2 {
+		t.Errorf("expected 2 task outputs, got %s", execution.TasksOutput)
+	}
+
+	// 同步执行结束后仍可按ID查询
+	resp, status := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID, "", nil)
+	if resp.StatusCode != http.StatusOK || status.Status != StatusCompleted {
+		t.Errorf("expected the finished kickoff to be queryable, got %d %+v", resp.StatusCode, status)
+	}
+
+	resp, _ = doRequest(t, http.MethodPost, httpServer.URL+"/kickoff", `{"inputs": [1]}`, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid body, got %d", resp.StatusCode)
+	}
+	resp, _ = doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/missing", "", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown kickoff, got %d", resp.StatusCode)
+	}
+}
+
+func TestKickoffAsyncReportsPartialOutputsAndEvents(t *testing.T) {
+	model := newGatedLLM(1)
+	_, httpServer := newTestServer(t, model, nil)
+
+	resp, execution := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff?async=true", `{"inputs": {"topic": "Go"}}`, nil)
+	if resp.StatusCode != http.StatusAccepted || execution.ID == "" || resp.Header.Get("Location") != "/kickoff/"+execution.ID {
+		t.Fatalf("expected an accepted async kickoff, got %d %+v", resp.StatusCode, execution)
+	}
+
+	// 第一个任务完成、第二个任务阻塞时，状态为running并带有部分输出
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, status := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID, "", nil)
+		if status.Status == StatusRunning && taskCount(t, status) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a running kickoff with one task output, got %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 进度流先补发已记录的进度，执行结束后发送done
+	streamResp, err := http.Get(httpServer.URL + "/kickoff/" + execution.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer streamResp.Body.Close()
+	if streamResp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", streamResp.Header.Get("Content-Type"))
+	}
+	model.release()
+
+	var eventTypes []string
+	scanner := bufio.NewScanner(streamResp.Body)
+	for scanner.Scan() {
+		if eventType, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	joined := strings.Join(eventTypes, ",")
+	for _, want := range []string{"task_started", "agent_thinking", "task_completed", "kickoff_finished"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %s in the event stream, got %s", want, joined)
+		}
+	}
+	if eventTypes[len(eventTypes)-1] != "done" {
+		t.Errorf("expected the stream to end with done, got %s", joined)
+	}
+
+	_, status := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID, "", nil)
+	if status.Status != StatusCompleted || status.FinishedAt == nil || taskCount(t, status) != 2 {
+		t.Errorf("expected a completed kickoff, got %+v", status)
+	}
+}
+
+func TestStatusReportsRunningKickoffs(t *testing.T) {
+	model := newGatedLLM(1)
+	_, httpServer := newTestServer(t, model, nil)
+
+	_, execution := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff?async=true", `{"inputs": {"topic": "Go"}}`, nil)
+
+	// 第二个任务阻塞在LLM调用中
+	var status StatusResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Get(httpServer.URL + "/status")
+		if err != nil {
+			t.Fatal(err)
+		}
+		status = StatusResponse{}
+		_ = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if len(status.Executions) == 1 && status.Executions[0].Crew.CompletedTasks == 1 &&
+			len(status.Executions[0].Crew.RunningTasks) == 1 && status.Executions[0].Crew.RunningTasks[0].Step != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a running kickoff blocked in the second task, got %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	running := status.Executions[0]
+	if running.ID != execution.ID || !running.Crew.Executing || running.Crew.PendingTasks != 0 {
+		t.Errorf("unexpected execution status %+v", running)
+	}
+	task := running.Crew.RunningTasks[0]
+	if task.Index != 1 || task.Agent != "Writer" || task.Step.Kind != agent.StepKindLLMCall || task.Step.Attempt != 1 {
+		t.Errorf("unexpected running task %+v (step %+v)", task, task.Step)
+	}
+
+	model.release()
+	doneDeadline := time.Now().Add(2 * time.Second)
+	for {
+		_, finished := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID, "", nil)
+		if finished.Status == StatusCompleted {
+			break
+		}
+		if time.Now().After(doneDeadline) {
+			t.Fatalf("expected the kickoff to finish, got %+v", finished)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	resp, err := http.Get(httpServer.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	status = StatusResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil || len(status.Executions) != 0 {
+		t.Errorf("expected finished kickoffs to be left out, got %+v (%v)", status, err)
+	}
+}
+
+func TestConcurrentKickoffsUseClones(t *testing.T) {
+	model := newGatedLLM(0)
+	_, httpServer := newTestServer(t, model, func(config *ServerConfig) { config.MaxConcurrent = 2 })
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		resp, execution := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff?async=true", `{}`, nil)
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("kickoff %d: expected 202, got %d", i, resp.StatusCode)
+		}
+		ids = append(ids, execution.ID)
+	}
+
+	resp, _ := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff?async=true", `{}`, nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected 429 above the concurrency limit, got %d", resp.StatusCode)
+	}
+
+	model.release()
+	for _, id := range ids {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			_, status := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+id, "", nil)
+			if status.Status == StatusCompleted {
+				break
+			}
+			if status.Status == StatusFailed || time.Now().After(deadline) {
+				t.Fatalf("kickoff %s did not complete: %+v", id, status)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	_, httpServer := newTestServer(t, newGatedLLM(100), func(config *ServerConfig) { config.APIKeys = []string{"secret"} })
+
+	resp, _ := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff", `{}`, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a key, got %d", resp.StatusCode)
+	}
+	resp, _ = doRequest(t, http.MethodPost, httpServer.URL+"/kickoff", `{}`, map[string]string{"X-API-Key": "wrong"})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a wrong key, got %d", resp.StatusCode)
+	}
+	resp, _ = doRequest(t, http.MethodPost, httpServer.URL+"/kickoff", `{}`, map[string]string{"X-API-Key": "secret"})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 with X-API-Key, got %d", resp.StatusCode)
+	}
+	resp, _ = doRequest(t, http.MethodPost, httpServer.URL+"/kickoff", `{}`, map[string]string{"Authorization": "Bearer secret"})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 with a bearer token, got %d", resp.StatusCode)
+	}
+	resp, _ = doRequest(t, http.MethodGet, httpServer.URL+"/healthz", "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the health check to skip auth, got %d", resp.StatusCode)
+	}
+}
+
+func TestKickoffTimeout(t *testing.T) {
+	_, httpServer := newTestServer(t, newGatedLLM(0), func(config *ServerConfig) { config.RequestTimeout = 50 * time.Millisecond })
+
+	resp, execution := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff", `{}`, nil)
+	if resp.StatusCode != http.StatusGatewayTimeout || execution.Status != StatusFailed || execution.Error == "" {
+		t.Errorf("expected a timed out kickoff, got %d %+v", resp.StatusCode, execution)
+	}
+}
+
+func TestShutdownDrainsInFlightKickoffs(t *testing.T) {
+	model := newGatedLLM(0)
+	srv, httpServer := newTestServer(t, model, nil)
+
+	_, execution := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff?async=true", `{}`, nil)
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- srv.Shutdown(context.Background()) }()
+
+	// 关闭期间拒绝新的kickoff
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, _ := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff?async=true", `{}`, nil)
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 503 while draining, got %d", resp.StatusCode)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("shutdown returned before the kickoff finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	model.release()
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+	_, status := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID, "", nil)
+	if status.Status != StatusCompleted {
+		t.Errorf("expected the drained kickoff to complete, got %+v", status)
+	}
+}
+
+func TestShutdownCancelsAfterTimeout(t *testing.T) {
+	srv, httpServer := newTestServer(t, newGatedLLM(0), nil)
+
+	_, execution := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff?async=true", `{}`, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the shutdown to time out, got %v", err)
+	}
+
+	_, status := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID, "", nil)
+	if status.Status != StatusFailed {
+		t.Errorf("expected the cancelled kickoff to fail, got %+v", status)
+	}
+}
+
+func TestKickoffAsyncEnqueuesForWorkers(t *testing.T) {
+	q, err := queue.NewSQLiteQueue(filepath.Join(t.TempDir(), "queue.db"), &queue.Options{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	defer q.Close()
+
+	model := newGatedLLM(100)
+	srv, httpServer := newTestServer(t, model, func(config *ServerConfig) {
+		config.Queue = q
+		config.CrewName = "writers"
+	})
+
+	resp, execution := doRequest(t, http.MethodPost, httpServer.URL+"/kickoff?async=true", `{"inputs": {"topic": "Go"}, "session_id": "s1"}`, nil)
+	if resp.StatusCode != http.StatusAccepted || execution.Status != StatusQueued || resp.Header.Get("Location") != "/kickoff/"+execution.ID {
+		t.Fatalf("expected a queued kickoff, got %d %+v", resp.StatusCode, execution)
+	}
+	if _, status := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID, "", nil); status.Status != StatusQueued {
+		t.Errorf("expected the kickoff to stay queued without a worker, got %+v", status)
+	}
+	if resp, _ := doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID+"/events", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected no progress events for a queued kickoff, got %d", resp.StatusCode)
+	}
+	model.mu.Lock()
+	calls := model.calls
+	model.mu.Unlock()
+	if calls != 0 {
+		t.Errorf("a queued kickoff must not run in the server, got %d LLM calls", calls)
+	}
+
+	var crewNames []string
+	worker, err := crew.NewWorker(q, func(ctx context.Context, name string) (crew.Crew, error) {
+		crewNames = append(crewNames, name)
+		return srv.crew.Clone()
+	}, nil, logger.NewTestLogger())
+	if err != nil {
+		t.Fatalf("failed to create worker: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- worker.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var status *ExecutionResponse
+	for {
+		_, status = doRequest(t, http.MethodGet, httpServer.URL+"/kickoff/"+execution.ID, "", nil)
+		if status.Status == StatusCompleted || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Errorf("worker did not stop gracefully: %v", err)
+	}
+
+	if status.Status != StatusCompleted || status.FinishedAt == nil || status.Attempts != 1 {
+		t.Fatalf("expected the worker to complete the kickoff, got %+v", status)
+	}
+	if taskCount(t, status) != 2 || !strings.Contains(string(status.Output), `"raw":"answer"`) {
+		t.Errorf("unexpected output: %s", status.Output)
+	}
+	if len(crewNames) != 1 || crewNames[0] != "writers" {
+		t.Errorf("expected the configured crew name in the queued request, got %v", crewNames)
+	}
+}
@@ -0,0 +1,69 @@
+// Package tokenizer 对外公开按模型计算token数的API，供上下文窗口管理和dry run成本估算使用
+// 实现位于internal/llm，这里的类型都是内部类型的别名，LLM的GetTokenizer返回的是同一个tokenizer
+//
+// OpenAI模型使用与tiktoken兼容的BPE编码（cl100k_base、o200k_base），词表在第一次计数时从本地目录
+// （DirEnv，默认为用户缓存目录下的greensoulai/tokenizer）加载，默认不访问网络；
+// 本地没有词表时计数回退为启发式估算，需要与tiktoken一致的计数时先调用Download或设置DownloadEnv
+package tokenizer
+
+import (
+	"context"
+	"io"
+
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+// Tokenizer相关类型
+type (
+	// Tokenizer 计算文本和对话的token数
+	Tokenizer = llm.ChatTokenizer
+	// BPE 与tiktoken兼容的字节级BPE tokenizer
+	BPE = llm.BPETokenizer
+	// Heuristic 没有模型词表时按文字种类的启发式估算
+	Heuristic = llm.ScriptHeuristicTokenizer
+)
+
+// tiktoken兼容的BPE编码
+const (
+	CL100KBase = llm.CL100KBase
+	O200KBase  = llm.O200KBase
+)
+
+// 词表的查找和下载，见llm.TokenizerDirEnv和llm.TokenizerDownloadEnv
+const (
+	DirEnv      = llm.TokenizerDirEnv
+	DownloadEnv = llm.TokenizerDownloadEnv
+)
+
+// OpenAI对话格式的token开销
+const (
+	TokensPerMessage = llm.TokensPerMessage
+	TokensPerName    = llm.TokensPerName
+	TokensPerReply   = llm.TokensPerReply
+)
+
+// ForModel 返回模型的tokenizer，与llm.TokenizerForModel相同：已知的OpenAI模型使用对应的BPE编码，其他模型使用Heuristic
+// 本地没有词表时BPE也按Heuristic计数，不会隐式下载
+func ForModel(model string) Tokenizer {
+	return llm.TokenizerForModel(model)
+}
+
+// EncodingForModel 返回模型使用的BPE编码名称，不是已知的OpenAI模型时返回空字符串
+func EncodingForModel(model string) string {
+	return llm.EncodingForModel(model)
+}
+
+// GetEncoding 返回编码的共享BPE tokenizer，同一进程中每个编码的词表只加载一次
+func GetEncoding(encoding string) (*BPE, error) {
+	return llm.GetEncoding(encoding)
+}
+
+// NewBPE 从tiktoken格式的词表创建BPE tokenizer
+func NewBPE(encoding string, r io.Reader) (*BPE, error) {
+	return llm.NewBPETokenizer(encoding, r)
+}
+
+// Download 下载编码的词表并校验sha256后缓存到本地目录，本地已有词表时不下载
+func Download(ctx context.Context, encoding string) error {
+	return llm.DownloadEncoding(ctx, encoding)
+}
@@ -0,0 +1,24 @@
+package tokenizer
+
+import (
+	"testing"
+
+	"github.com/ynl/greensoulai/internal/llm"
+)
+
+func TestForModelMatchesLLMTokenizer(t *testing.T) {
+	bpe, ok := ForModel("gpt-4o").(*BPE)
+	if !ok || bpe.Encoding() != O200KBase {
+		t.Fatalf("expected an o200k_base BPE for gpt-4o, got %#v", ForModel("gpt-4o"))
+	}
+	// 不需要导入本包，LLM的GetTokenizer返回同一个共享的tokenizer
+	if got := llm.NewBaseLLM("openai", "gpt-4o-mini").GetTokenizer(); got != Tokenizer(bpe) {
+		t.Errorf("expected BaseLLM.GetTokenizer to return the shared BPE, got %#v", got)
+	}
+	if _, ok := ForModel("claude-3-5-sonnet").(Heuristic); !ok {
+		t.Errorf("expected the heuristic for non-OpenAI models, got %#v", ForModel("claude-3-5-sonnet"))
+	}
+	if EncodingForModel("gpt-4") != CL100KBase {
+		t.Errorf("expected cl100k_base for gpt-4, got %q", EncodingForModel("gpt-4"))
+	}
+}
@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Recorder 在内存中记录span的Tracer，用于测试和调试
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewRecorder 创建内存Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// RecordedEvent span上记录的事件
+type RecordedEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+// RecordedSpan Recorder记录的span
+type RecordedSpan struct {
+	recorder *Recorder
+
+	Name       string
+	Parent     *RecordedSpan
+	Start      time.Time
+	EndTime    time.Time
+	Attributes map[string]interface{}
+	Events     []RecordedEvent
+	Err        error
+	Ended      bool
+}
+
+// Start 实现Tracer，ctx中已有的RecordedSpan成为新span的父span
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := SpanFromContext(ctx).(*RecordedSpan)
+	span := &RecordedSpan{
+		recorder:   r,
+		Name:       name,
+		Parent:     parent,
+		Start:      time.Now(),
+		Attributes: make(map[string]interface{}),
+	}
+	span.SetAttributes(attrs...)
+
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return ctx, span
+}
+
+// Spans 返回按开始顺序排列的所有span
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan(nil), r.spans...)
+}
+
+// SpansNamed 返回指定名称的span
+func (r *Recorder) SpansNamed(name string) []*RecordedSpan {
+	var spans []*RecordedSpan
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// Reset 清空已记录的span
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+// SetAttributes 实现Span
+func (s *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+// AddEvent 实现Span
+func (s *RecordedSpan) AddEvent(name string, attrs ...Attribute) {
+	event := RecordedEvent{Name: name, Time: time.Now(), Attributes: make(map[string]interface{}, len(attrs))}
+	for _, attr := range attrs {
+		event.Attributes[attr.Key] = attr.Value
+	}
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.Events = append(s.Events, event)
+}
+
+// RecordError 实现Span
+func (s *RecordedSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.Err = err
+}
+
+// End 实现Span
+func (s *RecordedSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	if !s.Ended {
+		s.Ended = true
+		s.EndTime = time.Now()
+	}
+}
+
+// IsRecording 实现Span
+func (s *RecordedSpan) IsRecording() bool {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	return !s.Ended
+}
+
+// EventsNamed 返回指定名称的事件
+func (s *RecordedSpan) EventsNamed(name string) []RecordedEvent {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	var events []RecordedEvent
+	for _, event := range s.Events {
+		if event.Name == name {
+			events = append(events, event)
+		}
+	}
+	return events
+}
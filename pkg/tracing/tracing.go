@@ -0,0 +1,135 @@
+// Package tracing 为crew、task、agent和LLM调用提供可选的分布式追踪钩子
+//
+// 包本身不依赖任何追踪SDK：默认Tracer什么都不做，Start直接返回原ctx，
+// 未启用追踪时几乎没有开销。需要OpenTelemetry时使用contrib/otel中的适配器：
+//
+//	tracing.SetTracer(otel.NewTracer(provider))
+//
+// span层级为 crew.kickoff → task.execute → agent.execute → llm.call，
+// 父子关系通过ctx传递，调用方传入的ctx中已有的span会成为crew.kickoff的父span
+package tracing
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// span名称
+const (
+	SpanCrewKickoff  = "crew.kickoff"
+	SpanTaskExecute  = "task.execute"
+	SpanAgentExecute = "agent.execute"
+	SpanLLMCall      = "llm.call"
+)
+
+// span事件名称
+const (
+	EventLLMRetry         = "llm.retry"
+	EventGuardrailAttempt = "guardrail.attempt"
+	EventToolCall         = "tool.call"
+)
+
+// Attribute span属性或事件属性
+// Value支持string、bool、int、int64、float64和[]string，其他类型由适配器转换为字符串
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String 创建字符串属性
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int 创建整数属性
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int64 创建64位整数属性
+func Int64(key string, value int64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Float64 创建浮点数属性
+func Float64(key string, value float64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Bool 创建布尔属性
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// Strings 创建字符串列表属性
+func Strings(key string, value []string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Span 一段被追踪的操作
+type Span interface {
+	// SetAttributes 设置属性，同名属性被覆盖
+	SetAttributes(attrs ...Attribute)
+	// AddEvent 记录一个带时间戳的事件，如重试或护栏校验
+	AddEvent(name string, attrs ...Attribute)
+	// RecordError 记录错误并把span标记为失败，err为nil时忽略
+	RecordError(err error)
+	// End 结束span，之后的调用被忽略
+	End()
+	// IsRecording 报告span是否在记录，调用方可据此跳过昂贵的属性计算
+	IsRecording() bool
+}
+
+// Tracer 创建span，实现需要把新span放入返回的ctx以建立父子关系
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// tracerHolder 包装Tracer以便存入atomic.Value（要求类型一致）
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var globalTracer atomic.Value
+
+// SetTracer 设置全局Tracer，nil恢复为不做任何事的默认Tracer
+func SetTracer(tracer Tracer) {
+	globalTracer.Store(tracerHolder{tracer: tracer})
+}
+
+// GetTracer 返回全局Tracer，未设置时返回nil
+func GetTracer() Tracer {
+	holder, _ := globalTracer.Load().(tracerHolder)
+	return holder.tracer
+}
+
+// Enabled 报告是否设置了全局Tracer
+func Enabled() bool {
+	return GetTracer() != nil
+}
+
+// spanKey ctx中当前span的键
+type spanKey struct{}
+
+// Start 使用全局Tracer开始一个span，返回携带该span的ctx
+// 未设置Tracer时返回原ctx和空操作span
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	tracer := GetTracer()
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := tracer.Start(ctx, name, attrs...)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext 返回ctx中的当前span，不存在时返回空操作span
+func SpanFromContext(ctx context.Context) Span {
+	if ctx != nil {
+		if span, ok := ctx.Value(spanKey{}).(Span); ok {
+			return span
+		}
+	}
+	return noopSpan{}
+}
+
+// AddEvent 向ctx中的当前span添加事件
+func AddEvent(ctx context.Context, name string, attrs ...Attribute) {
+	SpanFromContext(ctx).AddEvent(name, attrs...)
+}
+
+// noopSpan 未启用追踪时使用的空操作span
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute)    {}
+func (noopSpan) AddEvent(string, ...Attribute) {}
+func (noopSpan) RecordError(error)             {}
+func (noopSpan) End()                          {}
+func (noopSpan) IsRecording() bool             { return false }
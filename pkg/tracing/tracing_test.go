@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStartWithoutTracer(t *testing.T) {
+	SetTracer(nil)
+
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, SpanCrewKickoff, String("crew.name", "demo"))
+	if spanCtx != ctx {
+		t.Error("expected the context to be returned unchanged without a tracer")
+	}
+	if span.IsRecording() || Enabled() {
+		t.Error("expected a non-recording span without a tracer")
+	}
+
+	// 空操作span的所有方法都可以安全调用
+	span.SetAttributes(Int("n", 1))
+	span.AddEvent(EventLLMRetry)
+	span.RecordError(errors.New("ignored"))
+	span.End()
+	AddEvent(ctx, EventToolCall)
+	if SpanFromContext(ctx).IsRecording() {
+		t.Error("expected no span in a plain context")
+	}
+}
+
+func TestRecorderNestsSpans(t *testing.T) {
+	recorder := NewRecorder()
+	SetTracer(recorder)
+	defer SetTracer(nil)
+
+	ctx, crewSpan := Start(context.Background(), SpanCrewKickoff, String("crew.name", "demo"))
+	taskCtx, taskSpan := Start(ctx, SpanTaskExecute)
+	AddEvent(taskCtx, EventGuardrailAttempt, Int("guardrail.attempt", 1), Bool("guardrail.passed", true))
+	taskSpan.RecordError(errors.New("boom"))
+	taskSpan.End()
+	crewSpan.End()
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	crew, task := spans[0], spans[1]
+	if task.Parent != crew || crew.Parent != nil {
+		t.Error("expected the task span to be a child of the crew span")
+	}
+	if crew.Attributes["crew.name"] != "demo" || !crew.Ended || crew.IsRecording() {
+		t.Errorf("unexpected crew span: %+v", crew)
+	}
+	if task.Err == nil || task.Err.Error() != "boom" {
+		t.Errorf("expected the task error to be recorded, got %v", task.Err)
+	}
+
+	events := task.EventsNamed(EventGuardrailAttempt)
+	if len(events) != 1 || events[0].Attributes["guardrail.passed"] != true {
+		t.Errorf("unexpected guardrail events: %+v", events)
+	}
+	if len(recorder.SpansNamed(SpanTaskExecute)) != 1 {
+		t.Error("expected to find the task span by name")
+	}
+
+	recorder.Reset()
+	if len(recorder.Spans()) != 0 {
+		t.Error("expected Reset to clear the recorded spans")
+	}
+}